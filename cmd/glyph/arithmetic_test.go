@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRouteIntegerOverflow checks that an integer overflow fails the route
+// in both execution modes instead of wrapping around in compiled routes
+func TestRouteIntegerOverflow(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, `@ GET /next/:n {
+  $ x = 41
+  if n == "max" {
+    x = 9223372036854775807
+  }
+  > {y: x + 1, z: -x}
+}
+`, mode.interpreted)
+			status, body := getBody(t, srv.URL+"/next/41")
+			assert.Equal(t, http.StatusOK, status)
+			assert.JSONEq(t, `{"y": 42, "z": -41}`, body)
+
+			status, _ = getBody(t, srv.URL+"/next/max")
+			assert.Equal(t, http.StatusInternalServerError, status)
+		})
+	}
+}
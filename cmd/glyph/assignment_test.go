@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRouteAssignsFieldsAndIndices checks that routes assigning to fields
+// and array elements compile instead of falling back to the interpreter, and
+// return the same mutated structure in both execution modes
+func TestRouteAssignsFieldsAndIndices(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, `@ POST /users/rename {
+  $ users = [{name: "Ada", tags: ["math"]}, {name: "Bob", tags: []}]
+  users[0].name = input.name
+  users[0].tags[-1] = "admin"
+  $ second = users[1]
+  second.active = false
+  $ result = {users: users, count: 0}
+  result.count = 2
+  > result
+}
+`, mode.interpreted)
+			status, body := postJSON(t, srv, "/users/rename", `{"name": "Grace"}`)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, map[string]interface{}{
+				"users": []interface{}{
+					map[string]interface{}{"name": "Grace", "tags": []interface{}{"admin"}},
+					map[string]interface{}{"name": "Bob", "tags": []interface{}{}, "active": false},
+				},
+				"count": float64(2),
+			}, body)
+		})
+	}
+}
+
+// TestConstTypeChecked checks that both execution modes check a const
+// declared in a route against its type annotation
+func TestConstTypeChecked(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, `@ POST /limit {
+  const LIMIT: float = input.limit
+  const NAME: str? = input.name
+  > {limit: LIMIT, name: NAME}
+}
+`, mode.interpreted)
+			status, body := postJSON(t, srv, "/limit", `{"limit": 5}`)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, map[string]interface{}{"limit": float64(5), "name": nil}, body)
+
+			status, _ = postJSON(t, srv, "/limit", `{"limit": "five"}`)
+			assert.Equal(t, http.StatusInternalServerError, status)
+			status, _ = postJSON(t, srv, "/limit", `{"limit": 5, "name": 1}`)
+			assert.Equal(t, http.StatusInternalServerError, status)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/interpreter"
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+// backgroundShutdownTimeout bounds how long shutdown waits for background
+// blocks that are still running
+const backgroundShutdownTimeout = 10 * time.Second
+
+// backgroundTasks runs the background blocks of every route, interpreted or
+// compiled, so shutdown can wait for them
+var backgroundTasks = &interpreter.BackgroundTasks{}
+
+// startVMBackground runs the background blocks a compiled route reached.
+// Errors and panics are logged with the route.
+func startVMBackground(route *ast.Route, tasks []vm.BackgroundTask) {
+	label := fmt.Sprintf("%s %s", route.Method, route.Path)
+	for _, task := range tasks {
+		backgroundTasks.Go(label, task.Run)
+	}
+}
+
+// waitForBackgroundTasks waits, up to backgroundShutdownTimeout, for the
+// background blocks still running
+func waitForBackgroundTasks() {
+	if !backgroundTasks.Wait(backgroundShutdownTimeout) {
+		printWarning(fmt.Sprintf("Background tasks still running after %s, stopping anyway", backgroundShutdownTimeout))
+	}
+}
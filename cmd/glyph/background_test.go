@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// heldLog is a log that holds back the report of a failed background block
+// until it is released
+type heldLog struct {
+	syncBuffer
+	release chan struct{}
+	once    sync.Once
+}
+
+func (l *heldLog) Write(p []byte) (int, error) {
+	if bytes.Contains(p, []byte("[BACKGROUND]")) {
+		<-l.release
+	}
+	return l.syncBuffer.Write(p)
+}
+
+// Release lets the held report through
+func (l *heldLog) Release() {
+	l.once.Do(func() { close(l.release) })
+}
+
+// TestBackgroundRunsAfterResponse checks in both execution modes that a
+// route responds while its background block is still running, and that the
+// block's failure is logged with the route
+func TestBackgroundRunsAfterResponse(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			logs := &heldLog{release: make(chan struct{})}
+			log.SetOutput(logs)
+			t.Cleanup(func() { log.SetOutput(os.Stderr) })
+			// A route that waited for its block would respond only now
+			timer := time.AfterFunc(2*time.Second, logs.Release)
+			defer timer.Stop()
+
+			srcFile := filepath.Join(t.TempDir(), "main.glyph")
+			require.NoError(t, os.WriteFile(srcFile, []byte(`@ POST /signup {
+  $ email = input.email
+  background {
+    $ sent = send_welcome_email(email)
+  }
+  > {created: true}
+}
+`), 0644))
+			program, err := loadProgram(srcFile)
+			require.NoError(t, err)
+			routes, err := setupRoutes(program, mode.interpreted)
+			require.NoError(t, err)
+			require.Equal(t, !mode.interpreted, routes.useCompiler)
+
+			srv := httptest.NewServer(createHandler(routes.router))
+			defer srv.Close()
+			status, body := postJSON(t, srv, "/signup", `{"email": "ada@example.com"}`)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, map[string]interface{}{"created": true}, body)
+			assert.NotContains(t, logs.String(), "[BACKGROUND]", "the response does not wait for the block")
+
+			logs.Release()
+			require.True(t, backgroundTasks.Wait(5*time.Second))
+			assert.Contains(t, logs.String(), "[BACKGROUND] POST /signup: ")
+			assert.Contains(t, logs.String(), "send_welcome_email")
+		})
+	}
+}
@@ -0,0 +1,529 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/bundle"
+	"github.com/glyphlang/glyph/pkg/compiler"
+	"github.com/glyphlang/glyph/pkg/decompiler"
+	"github.com/glyphlang/glyph/pkg/formatter"
+	"github.com/glyphlang/glyph/pkg/interpreter"
+	"github.com/glyphlang/glyph/pkg/metrics"
+	"github.com/glyphlang/glyph/pkg/server"
+	"github.com/glyphlang/glyph/pkg/vm"
+	"github.com/glyphlang/glyph/pkg/websocket"
+	"github.com/spf13/cobra"
+)
+
+// runBuild handles the build command: it compiles every route of a program
+// into a bundle that glyph run serves without the source
+func runBuild(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
+	output, _ := cmd.Flags().GetString("output")
+	optLevel, _ := cmd.Flags().GetUint8("opt-level")
+	strip, _ := cmd.Flags().GetBool("strip")
+
+	printInfo(fmt.Sprintf("Building %s... (opt-level: %d)", filePath, optLevel))
+	start := time.Now()
+
+	program, err := loadProgram(filePath)
+	if err != nil {
+		return fmt.Errorf("parse failed: %w", err)
+	}
+
+	c := compiler.NewCompilerWithOptLevel(optimizationLevel(optLevel))
+	b, err := buildBundle(program, c, bundleOptions{strip: strip, embedStatic: true})
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = changeExtension(filePath, ".bundle")
+	}
+	if err := b.WriteFile(output); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	printSuccess(fmt.Sprintf("Built %s", output))
+	printInfo(fmt.Sprintf("Build time: %s", time.Since(start)))
+	printInfo(fmt.Sprintf("Routes: %d, WebSocket routes: %d, static directories: %d",
+		len(b.Manifest.Routes), len(b.Manifest.WebSockets), len(b.Manifest.Static)))
+	if strip {
+		printInfo("Debug info stripped")
+	}
+	return nil
+}
+
+// optimizationLevel maps the -O flag to a compiler optimization level
+func optimizationLevel(level uint8) compiler.OptimizationLevel {
+	switch level {
+	case 0:
+		return compiler.OptNone
+	case 3:
+		return compiler.OptAggressive
+	default:
+		return compiler.OptBasic
+	}
+}
+
+// bundleOptions control what buildBundle puts in a bundle
+type bundleOptions struct {
+	strip       bool // leave out debug info
+	embedStatic bool // embed the files of @ static directories
+}
+
+// buildBundle compiles the routes and error handlers of program with c into
+// a bundle. Routes must compile: unlike glyph run there is no interpreter
+// to fall back to, so routes that inject a database are an error, and so
+// are cron tasks, queue workers and event handlers, which run on the
+// interpreter.
+func buildBundle(program *interpreter.Program, c *compiler.Compiler, opts bundleOptions) (*bundle.Bundle, error) {
+	module := program.Module
+	baseDir := filepath.Dir(program.Entry)
+	c.SetFunctions(programFunctions(program))
+	c.SetMiddlewares(compiler.ModuleMiddlewares(module))
+	c.SetConstants(compiler.ModuleConstants(module))
+	c.SetEnums(compiler.ModuleEnums(module))
+
+	if _, err := programErrorHandlers(module); err != nil {
+		return nil, err
+	}
+	hash, err := programSourceHash(program)
+	if err != nil {
+		return nil, err
+	}
+	b := bundle.New(bundle.Manifest{
+		CompilerVersion: version,
+		SourceHash:      hash,
+		Entry:           filepath.Base(program.Entry),
+		Stripped:        opts.strip,
+		Sessions:        usesSessions(module),
+	})
+
+	// The declarations entry holds what serving needs besides bytecode:
+	// types and enums for input validation, and route headers without
+	// their bodies
+	decls := &ast.Module{}
+	for _, item := range module.Items {
+		switch it := item.(type) {
+		case *ast.TypeDef, *ast.EnumDef:
+			decls.Items = append(decls.Items, item)
+
+		case *ast.Route:
+			for _, injection := range it.Injections {
+				if isDatabaseType(injection.Type) {
+					return nil, fmt.Errorf("route %s %s injects a database, which compiled routes do not support; serve it from source with glyph run", it.Method, it.Path)
+				}
+			}
+			bytecode, err := c.CompileRouteCached(it, "")
+			if err != nil {
+				return nil, fmt.Errorf("compilation failed for %s %s: %w", it.Method, it.Path, err)
+			}
+			name := fmt.Sprintf("routes/%d.glyphc", len(b.Manifest.Routes))
+			if err := b.Add(name, bytecode); err != nil {
+				return nil, err
+			}
+			route := bundle.Route{Method: it.Method.String(), Path: it.Path, Bytecode: name}
+			if !opts.strip {
+				route.Source = sourceLocation(program, item, it.Pos.Line)
+			}
+			b.Manifest.Routes = append(b.Manifest.Routes, route)
+
+			header := *it
+			header.Body = nil
+			decls.Items = append(decls.Items, &header)
+
+		case *ast.WebSocketRoute:
+			compiled, err := c.CompileWebSocketRoute(it)
+			if err != nil {
+				return nil, fmt.Errorf("compilation failed for WebSocket route %s: %w", it.Path, err)
+			}
+			ws := bundle.WebSocketRoute{Path: it.Path}
+			prefix := fmt.Sprintf("websockets/%d/", len(b.Manifest.WebSockets))
+			for _, handler := range []struct {
+				event    string
+				bytecode []byte
+				entry    *string
+			}{
+				{"connect", compiled.OnConnect, &ws.OnConnect},
+				{"message", compiled.OnMessage, &ws.OnMessage},
+				{"disconnect", compiled.OnDisconnect, &ws.OnDisconnect},
+				{"error", compiled.OnError, &ws.OnError},
+			} {
+				if handler.bytecode == nil {
+					continue
+				}
+				*handler.entry = prefix + handler.event + ".glyphc"
+				if err := b.Add(*handler.entry, handler.bytecode); err != nil {
+					return nil, err
+				}
+			}
+			if !opts.strip {
+				ws.Source = sourceLocation(program, item, 0)
+			}
+			b.Manifest.WebSockets = append(b.Manifest.WebSockets, ws)
+
+		case *ast.ErrorHandler:
+			bytecode, err := c.CompileErrorHandler(it)
+			if err != nil {
+				return nil, fmt.Errorf("compilation failed for @ %s: %w", it.Kind, err)
+			}
+			handler := bundle.ErrorHandler{Kind: string(it.Kind), Bytecode: "errors/" + string(it.Kind) + ".glyphc"}
+			if err := b.Add(handler.Bytecode, bytecode); err != nil {
+				return nil, err
+			}
+			if !opts.strip {
+				handler.Source = sourceLocation(program, item, it.Pos.Line)
+			}
+			b.Manifest.ErrorHandlers = append(b.Manifest.ErrorHandlers, handler)
+
+		case *ast.StaticRoute:
+			if !opts.embedStatic {
+				continue
+			}
+			rootDir := it.RootDir
+			if !filepath.IsAbs(rootDir) {
+				rootDir = filepath.Join(baseDir, rootDir)
+			}
+			dir := fmt.Sprintf("static/%d", len(b.Manifest.Static))
+			if err := addStaticFiles(b, dir, rootDir); err != nil {
+				return nil, fmt.Errorf("static route %s: %w", it.Path, err)
+			}
+			b.Manifest.Static = append(b.Manifest.Static, bundle.StaticDir{Path: it.Path, Dir: dir})
+
+		case *ast.CronTask:
+			return nil, fmt.Errorf("cron task %s runs on the interpreter, which bundles do not include; serve it from source with glyph run", interpreter.CronTaskName(it))
+		case *ast.QueueWorker:
+			return nil, fmt.Errorf("queue worker %s runs on the interpreter, which bundles do not include; serve it from source with glyph run", it.QueueName)
+		case *ast.EventHandler:
+			return nil, fmt.Errorf("event handler %s runs on the interpreter, which bundles do not include; serve it from source with glyph run", it.EventType)
+		}
+	}
+
+	if len(b.Manifest.Routes) == 0 && len(b.Manifest.WebSockets) == 0 {
+		return nil, fmt.Errorf("no routes found in module")
+	}
+
+	source := formatter.New(formatter.Compact).Format(decls)
+	if err := b.Add(bundle.DeclarationsName, []byte(source)); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// programSourceHash returns the hex SHA-256 of every file of program and
+// its path relative to the entry file
+func programSourceHash(program *interpreter.Program) (string, error) {
+	baseDir := filepath.Dir(program.Entry)
+	h := sha256.New()
+	for _, file := range program.Files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		rel, err := filepath.Rel(baseDir, file)
+		if err != nil {
+			rel = file
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", filepath.ToSlash(rel), len(source))
+		h.Write(source)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// sourceLocation returns file:line for an item of program, or just the file
+// if line is unknown (0). The file is relative to the entry file.
+func sourceLocation(program *interpreter.Program, item ast.Item, line int) string {
+	file := program.FileOf(item)
+	if rel, err := filepath.Rel(filepath.Dir(program.Entry), file); err == nil {
+		file = rel
+	}
+	file = filepath.ToSlash(file)
+	if line == 0 {
+		return file
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+// addStaticFiles adds the regular files under rootDir to b below dir
+func addStaticFiles(b *bundle.Bundle, dir, rootDir string) error {
+	return filepath.WalkDir(rootDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(rootDir, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return b.Add(dir+"/"+filepath.ToSlash(rel), data)
+	})
+}
+
+// setupBundleRoutes registers the compiled routes of b. The route headers
+// and types come from the bundle's declarations, which are listed in the
+// same order as the manifest's routes.
+func setupBundleRoutes(b *bundle.Bundle) (*websocket.Server, *server.Router, error) {
+	source, ok := b.File(bundle.DeclarationsName)
+	if !ok {
+		return nil, nil, fmt.Errorf("bundle has no %s", bundle.DeclarationsName)
+	}
+	module, err := parseSource(string(source))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid bundle declarations: %w", err)
+	}
+	var routes []*ast.Route
+	for _, item := range module.Items {
+		if route, ok := item.(*ast.Route); ok {
+			routes = append(routes, route)
+		}
+	}
+	if len(routes) != len(b.Manifest.Routes) {
+		return nil, nil, fmt.Errorf("bundle declares %d routes but its manifest lists %d", len(routes), len(b.Manifest.Routes))
+	}
+	if _, err := sessionManager(); err != nil {
+		return nil, nil, err
+	}
+
+	wsServer := newWebSocketServer()
+	router, err := newRouter()
+	if err != nil {
+		return nil, nil, err
+	}
+	types := moduleTypeChecker(module)
+
+	for i, entry := range b.Manifest.Routes {
+		route := routes[i]
+		if route.Method.String() != entry.Method || route.Path != entry.Path {
+			return nil, nil, fmt.Errorf("bundle route %d is %s %s in the manifest but %s %s in the declarations", i, entry.Method, entry.Path, route.Method, route.Path)
+		}
+		bytecode, err := bundleBytecode(b, entry.Bytecode)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := registerCompiledRoute(router, route, bytecode, types, wsServer.GetHub(), nil, nil); err != nil {
+			return nil, nil, fmt.Errorf("failed to register route %s %s: %w", route.Method, route.Path, err)
+		}
+		printInfo(fmt.Sprintf("Compiled route: %s %s", route.Method, route.Path))
+	}
+
+	for _, entry := range b.Manifest.WebSockets {
+		compiled := &compiler.CompiledWebSocketRoute{Path: entry.Path}
+		for _, handler := range []struct {
+			entry    string
+			bytecode *[]byte
+		}{
+			{entry.OnConnect, &compiled.OnConnect},
+			{entry.OnMessage, &compiled.OnMessage},
+			{entry.OnDisconnect, &compiled.OnDisconnect},
+			{entry.OnError, &compiled.OnError},
+		} {
+			if handler.entry == "" {
+				continue
+			}
+			if *handler.bytecode, err = bundleBytecode(b, handler.entry); err != nil {
+				return nil, nil, err
+			}
+		}
+		registerCompiledWebSocketRoute(wsServer, entry.Path, compiled, types)
+		printInfo(fmt.Sprintf("Compiled WebSocket route: %s", entry.Path))
+	}
+
+	for _, entry := range b.Manifest.ErrorHandlers {
+		kind := ast.ErrorHandlerKind(entry.Kind)
+		if kind != ast.NotFoundHandler && kind != ast.OnErrorHandler {
+			return nil, nil, fmt.Errorf("bundle has an unknown error handler %q", entry.Kind)
+		}
+		bytecode, err := bundleBytecode(b, entry.Bytecode)
+		if err != nil {
+			return nil, nil, err
+		}
+		route := errorHandlerRoute(&ast.ErrorHandler{Kind: kind})
+		setErrorHandler(router, kind, createCompiledRouteHandler(route, bytecode, types, wsServer.GetHub(), nil, nil))
+		printInfo(fmt.Sprintf("Compiled error handler: @ %s", kind))
+	}
+
+	return wsServer, router, nil
+}
+
+// bundleBytecode returns the verified bytecode in the entry name of b
+func bundleBytecode(b *bundle.Bundle, name string) ([]byte, error) {
+	bytecode, ok := b.File(name)
+	if !ok {
+		return nil, fmt.Errorf("bundle has no %s", name)
+	}
+	if err := vm.Verify(bytecode); err != nil {
+		return nil, fmt.Errorf("bundle entry %s failed verification: %w", name, err)
+	}
+	return bytecode, nil
+}
+
+// startBundleServer serves the routes, WebSocket routes and static files of
+// b, like startServer does for source files
+func startBundleServer(b *bundle.Bundle, port int, logFormat server.LogFormat, withMetrics bool) (*http.Server, *websocket.Server, error) {
+	// The route bodies are not in the bundle, so the build recorded
+	// whether they use sessions
+	if err := checkSessionSecret(b.Manifest.Sessions, true); err != nil {
+		return nil, nil, err
+	}
+	mux, wsServer, err := bundleMux(b, port, withMetrics)
+	if err != nil {
+		return nil, nil, err
+	}
+	srv := newHTTPServer(mux, port, logFormat)
+	listenInBackground(srv, port, "bundle")
+	return srv, wsServer, nil
+}
+
+// bundleMux returns a mux serving everything in b. port is only used in
+// the endpoint messages.
+func bundleMux(b *bundle.Bundle, port int, withMetrics bool) (*http.ServeMux, *websocket.Server, error) {
+	wsServer, router, err := setupBundleRoutes(b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if withMetrics {
+		m := metrics.NewMetrics(metrics.DefaultConfig())
+		serverMetrics.Store(m)
+		if err := enableMetrics(m, router, wsServer.GetHub()); err != nil {
+			return nil, nil, err
+		}
+		printInfo(fmt.Sprintf("Metrics: http://localhost:%d%s", port, metrics.MetricsPath))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", createHandler(router))
+	for _, entry := range b.Manifest.WebSockets {
+		mux.HandleFunc(server.ConvertPatternToMuxFormat(entry.Path), wsServer.HandleWebSocketWithPattern(entry.Path))
+		printInfo(fmt.Sprintf("WebSocket endpoint: ws://localhost:%d%s", port, entry.Path))
+	}
+	for _, dir := range b.Manifest.Static {
+		pattern := dir.Path
+		if !strings.HasSuffix(pattern, "/") {
+			pattern += "/"
+		}
+		mux.Handle(pattern, bundleStaticHandler(b, dir))
+		printInfo(fmt.Sprintf("Static files: http://localhost:%d%s (bundled)", port, dir.Path))
+	}
+	return mux, wsServer, nil
+}
+
+// bundleStaticHandler serves the files of a static directory embedded in b.
+// A request for a directory serves its index.html.
+func bundleStaticHandler(b *bundle.Bundle, dir bundle.StaticDir) http.Handler {
+	prefix := strings.TrimSuffix(dir.Path, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Cleaning a rooted path removes any .. elements
+		name := path.Join(dir.Dir, path.Clean("/"+strings.TrimPrefix(r.URL.Path, prefix)))
+		data, ok := b.File(name)
+		if !ok {
+			name = path.Join(name, "index.html")
+			if data, ok = b.File(name); !ok {
+				http.Error(w, "Not Found", http.StatusNotFound)
+				return
+			}
+		}
+
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", contentType)
+		http.ServeContent(w, r, path.Base(name), time.Time{}, bytes.NewReader(data))
+	})
+}
+
+// decompileBundle decompiles every route and error handler of a bundle into
+// one file, each preceded by its method, path and source location. Stripped bundles have
+// no source locations and are refused.
+func decompileBundle(data []byte, filePath, output string, disasmOnly bool) error {
+	b, err := bundle.Read(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	if b.Manifest.Stripped {
+		return fmt.Errorf("%s was built with --strip and has no debug info to decompile; rebuild it without --strip to decompile it", filePath)
+	}
+	printInfo(fmt.Sprintf("Bundle built by glyph %s from %s", b.Manifest.CompilerVersion, b.Manifest.Entry))
+	printInfo(fmt.Sprintf("Routes: %d, WebSocket routes: %d", len(b.Manifest.Routes), len(b.Manifest.WebSockets)))
+
+	type section struct {
+		title string
+		entry string
+	}
+	var sections []section
+	for _, r := range b.Manifest.Routes {
+		sections = append(sections, section{fmt.Sprintf("%s %s (%s)", r.Method, r.Path, r.Source), r.Bytecode})
+	}
+	for _, ws := range b.Manifest.WebSockets {
+		for _, handler := range []struct{ event, entry string }{
+			{"connect", ws.OnConnect}, {"message", ws.OnMessage}, {"disconnect", ws.OnDisconnect}, {"error", ws.OnError},
+		} {
+			if handler.entry != "" {
+				sections = append(sections, section{fmt.Sprintf("WS %s on %s (%s)", ws.Path, handler.event, ws.Source), handler.entry})
+			}
+		}
+	}
+	for _, h := range b.Manifest.ErrorHandlers {
+		sections = append(sections, section{fmt.Sprintf("@ %s (%s)", h.Kind, h.Source), h.Bytecode})
+	}
+
+	var source, disasm strings.Builder
+	dec := decompiler.NewDecompiler()
+	for i, sec := range sections {
+		bytecode, ok := b.File(sec.entry)
+		if !ok {
+			return fmt.Errorf("bundle has no %s", sec.entry)
+		}
+		result, err := dec.Decompile(bytecode)
+		if err != nil {
+			return fmt.Errorf("decompilation of %s failed: %w", sec.title, err)
+		}
+		if i > 0 {
+			source.WriteString("\n")
+			disasm.WriteString("\n")
+		}
+		source.WriteString("# " + sec.title + "\n")
+		source.WriteString(result.Format())
+		disasm.WriteString("# " + sec.title + "\n")
+		disasm.WriteString(result.FormatDisassembly())
+	}
+
+	if !disasmOnly {
+		if output == "" {
+			output = changeExtension(filePath, ".glyph")
+		}
+		if err := os.WriteFile(output, []byte(source.String()), 0600); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		printSuccess(fmt.Sprintf("Decompiled to %s", output))
+	}
+
+	fmt.Println()
+	fmt.Print(disasm.String())
+	return nil
+}
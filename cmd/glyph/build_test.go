@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/bundle"
+	"github.com/glyphlang/glyph/pkg/server"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBuildCmd returns a build command with its flags, for calling runBuild
+func newBuildCmd(output string, strip bool) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("output", output, "")
+	cmd.Flags().Uint8("opt-level", 2, "")
+	cmd.Flags().Bool("strip", strip, "")
+	return cmd
+}
+
+// buildSource writes source as main.glyph, plus any extra files, to a
+// temporary directory and builds it into a bundle in another one. The
+// source directory is removed afterwards, so the bundle is all that is left.
+func buildSource(t *testing.T, source string, extra map[string]string, strip bool) string {
+	t.Helper()
+	srcDir := t.TempDir()
+	for name, contents := range extra {
+		path := filepath.Join(srcDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+	}
+	entry := filepath.Join(srcDir, "main.glyph")
+	require.NoError(t, os.WriteFile(entry, []byte(source), 0600))
+
+	output := filepath.Join(t.TempDir(), "app.bundle")
+	require.NoError(t, runBuild(newBuildCmd(output, strip), []string{entry}))
+	require.NoError(t, os.RemoveAll(srcDir))
+	return output
+}
+
+// serveBundle serves the bundle at path the way glyph run does
+func serveBundle(t *testing.T, path string) *httptest.Server {
+	t.Helper()
+	b, err := bundle.Open(path)
+	require.NoError(t, err)
+	mux, _, err := bundleMux(b, 0, false)
+	require.NoError(t, err)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func getBody(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestBuildRestAPITemplateServesFromBundle(t *testing.T) {
+	output := buildSource(t, getRestAPITemplate(), nil, false)
+
+	b, err := bundle.Open(output)
+	require.NoError(t, err)
+	assert.Equal(t, version, b.Manifest.CompilerVersion)
+	assert.Equal(t, "main.glyph", b.Manifest.Entry)
+	assert.Len(t, b.Manifest.SourceHash, 64)
+	assert.False(t, b.Manifest.Stripped)
+	require.Len(t, b.Manifest.Routes, 2)
+	assert.Equal(t, "GET", b.Manifest.Routes[0].Method)
+	assert.Equal(t, "/api/users", b.Manifest.Routes[0].Path)
+	assert.Equal(t, "main.glyph:9", b.Manifest.Routes[0].Source)
+
+	srv := serveBundle(t, output)
+
+	status, body := getBody(t, srv.URL+"/health")
+	assert.Equal(t, http.StatusOK, status)
+	var health map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(body), &health))
+	assert.Equal(t, "ok", health["status"])
+
+	status, body = getBody(t, srv.URL+"/api/users")
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `[]`, body)
+
+	status, _ = getBody(t, srv.URL+"/missing")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestBuildIsReproducible(t *testing.T) {
+	first, err := os.ReadFile(buildSource(t, getRestAPITemplate(), nil, false))
+	require.NoError(t, err)
+	second, err := os.ReadFile(buildSource(t, getRestAPITemplate(), nil, false))
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+}
+
+func TestBuildValidatesInputAndServesStaticFiles(t *testing.T) {
+	source := `@ static /assets "./public"
+
+: Signup {
+  name: str!
+  plan: str = "free"
+}
+
+@ POST /signup {
+  < input: Signup
+  > {name: input.name, plan: input.plan}
+}
+
+@ GET /search {
+  ? q: str!
+  ? page: int = 1
+  > {q: q, page: page}
+}
+`
+	output := buildSource(t, source, map[string]string{
+		"public/index.html":  "<h1>home</h1>",
+		"public/css/app.css": "body {}",
+	}, false)
+	srv := serveBundle(t, output)
+
+	resp, err := http.Post(srv.URL+"/signup", "application/json", strings.NewReader(`{"name": "ada"}`))
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"name": "ada", "plan": "free"}`, string(body))
+
+	resp, err = http.Post(srv.URL+"/signup", "application/json", strings.NewReader(`{"plan": "pro"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	status, body2 := getBody(t, srv.URL+"/search?q=glyph")
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"q": "glyph", "page": 1}`, body2)
+	status, _ = getBody(t, srv.URL+"/search")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, html := getBody(t, srv.URL+"/assets/")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "<h1>home</h1>", html)
+	status, css := getBody(t, srv.URL+"/assets/css/app.css")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "body {}", css)
+	status, _ = getBody(t, srv.URL+"/assets/../main.glyph")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestBuildServesErrorHandlers(t *testing.T) {
+	output := buildSource(t, errorHandlersSource, nil, false)
+
+	b, err := bundle.Open(output)
+	require.NoError(t, err)
+	require.Len(t, b.Manifest.ErrorHandlers, 2)
+	assert.Equal(t, "notfound", b.Manifest.ErrorHandlers[0].Kind)
+	assert.Equal(t, "main.glyph:15", b.Manifest.ErrorHandlers[0].Source)
+	assert.Equal(t, "onerror", b.Manifest.ErrorHandlers[1].Kind)
+
+	srv := serveBundle(t, output)
+
+	status, body := getBody(t, srv.URL+"/missing")
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.JSONEq(t, `{"error": "nothing at /missing"}`, body)
+
+	status, body = getBody(t, srv.URL+"/boom")
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.JSONEq(t, `{"error": "Something went wrong", "status": 500}`, body)
+}
+
+func TestBuildRejectsDatabaseRoutes(t *testing.T) {
+	dir := t.TempDir()
+	entry := filepath.Join(dir, "main.glyph")
+	require.NoError(t, os.WriteFile(entry, []byte(getRestCrudTemplate("app")), 0600))
+
+	err := runBuild(newBuildCmd(filepath.Join(dir, "app.bundle"), false), []string{entry})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "injects a database")
+}
+
+// TestBuildRejectsBackgroundWork checks that programs with cron tasks, queue
+// workers or event handlers are not built into bundles that would serve
+// their routes without them
+func TestBuildRejectsBackgroundWork(t *testing.T) {
+	for _, tt := range []struct {
+		name, source, want string
+	}{
+		{"queue", `@ queue emails {
+  > message
+}`, "queue worker emails"},
+		{"cron", `@ cron "0 * * * *" {
+  > "ok"
+}`, "cron task"},
+		{"event", `@ on "user.created" {
+  > event
+}`, "event handler user.created"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			entry := filepath.Join(dir, "main.glyph")
+			source := tt.source + "\n\n@ POST /send {\n  > {ok: true}\n}\n"
+			require.NoError(t, os.WriteFile(entry, []byte(source), 0600))
+
+			err := runBuild(newBuildCmd(filepath.Join(dir, "app.bundle"), false), []string{entry})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+			assert.NoFileExists(t, filepath.Join(dir, "app.bundle"))
+		})
+	}
+}
+
+// TestBuildRecordsSessions checks that a bundle remembers that its routes
+// use sessions, so that glyph run requires SESSION_SECRET without the
+// route bodies
+func TestBuildRecordsSessions(t *testing.T) {
+	path := buildSource(t, sessionSource, nil, false)
+	b, err := bundle.Open(path)
+	require.NoError(t, err)
+	assert.True(t, b.Manifest.Sessions)
+
+	t.Setenv("SESSION_SECRET", "")
+	_, _, err = startBundleServer(b, 0, server.LogFormatText, false)
+	assert.ErrorIs(t, err, errNoSessionSecret)
+
+	plain, err := bundle.Open(buildSource(t, "@ GET /ping {\n  > {ok: true}\n}\n", nil, false))
+	require.NoError(t, err)
+	assert.False(t, plain.Manifest.Sessions)
+}
+
+func TestRunRejectsTamperedBundle(t *testing.T) {
+	output := buildSource(t, getRestAPITemplate(), nil, false)
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+
+	// Corrupt a byte inside the compressed entries
+	data[len(data)/3] ^= 0xff
+	require.NoError(t, os.WriteFile(output, data, 0600))
+
+	cmd := &cobra.Command{}
+	cmd.Flags().Uint16("port", 0, "")
+	cmd.Flags().Bool("bytecode", false, "")
+	cmd.Flags().Bool("interpret", false, "")
+	cmd.Flags().String("log-format", "text", "")
+	cmd.Flags().Bool("metrics", false, "")
+	err = runRun(cmd, []string{output})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), output)
+}
+
+func TestDecompileBundle(t *testing.T) {
+	output := buildSource(t, getRestAPITemplate(), nil, false)
+	decompiled := filepath.Join(t.TempDir(), "app.glyph")
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("output", decompiled, "")
+	cmd.Flags().Bool("disasm", false, "")
+	require.NoError(t, runDecompile(cmd, []string{output}))
+
+	source, err := os.ReadFile(decompiled)
+	require.NoError(t, err)
+	assert.Contains(t, string(source), "# GET /api/users (main.glyph:9)")
+	assert.Contains(t, string(source), "# GET /health (main.glyph:14)")
+
+	// The decompiled routes are valid source
+	_, err = parseSource(string(source))
+	assert.NoError(t, err)
+}
+
+func TestDecompileRefusesStrippedBundle(t *testing.T) {
+	output := buildSource(t, getRestAPITemplate(), nil, true)
+
+	b, err := bundle.Open(output)
+	require.NoError(t, err)
+	assert.True(t, b.Manifest.Stripped)
+	assert.Empty(t, b.Manifest.Routes[0].Source)
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("output", filepath.Join(t.TempDir(), "app.glyph"), "")
+	cmd.Flags().Bool("disasm", false, "")
+	err = runDecompile(cmd, []string{output})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "was built with --strip")
+
+	// A stripped bundle still serves
+	srv := serveBundle(t, output)
+	status, _ := getBody(t, srv.URL+"/health")
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestCompileMultipleRoutesWritesBundle(t *testing.T) {
+	dir := t.TempDir()
+	entry := filepath.Join(dir, "main.glyph")
+	require.NoError(t, os.WriteFile(entry, []byte(getRestAPITemplate()), 0600))
+	output := filepath.Join(dir, "main.glyphc")
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("output", output, "")
+	cmd.Flags().Uint8("opt-level", 2, "")
+	cmd.Flags().Bool("no-cache", true, "")
+	require.NoError(t, runCompile(cmd, []string{entry}))
+
+	b, err := bundle.Open(output)
+	require.NoError(t, err)
+	require.Len(t, b.Manifest.Routes, 2)
+	assert.Equal(t, "/api/users", b.Manifest.Routes[0].Path)
+	assert.Equal(t, "/health", b.Manifest.Routes[1].Path)
+}
+
+func TestInitTemplatesParse(t *testing.T) {
+	for name, source := range map[string]string{
+		"hello-world": getHelloWorldTemplate(),
+		"rest-api":    getRestAPITemplate(),
+		"crud":        getRestCrudTemplate("app"),
+	} {
+		_, err := parseSource(source)
+		assert.NoError(t, err, name)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEnvBuiltinInRoutes checks env() in both execution modes, including
+// the GLYPH_ENV_PREFIXES allow-list
+func TestEnvBuiltinInRoutes(t *testing.T) {
+	t.Setenv("APP_REGION", "eu-west")
+	t.Setenv("GLYPH_ENV_PREFIXES", "APP_")
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, `@ GET /config {
+  > {region: env("APP_REGION"), port: env("APP_PORT", "8080")}
+}
+
+@ GET /home {
+  > {home: env("HOME")}
+}
+`, mode.interpreted)
+			status, body := getBody(t, srv.URL+"/config")
+			assert.Equal(t, http.StatusOK, status)
+			assert.JSONEq(t, `{"region": "eu-west", "port": "8080"}`, body)
+
+			status, _ = getBody(t, srv.URL+"/home")
+			assert.Equal(t, http.StatusInternalServerError, status)
+		})
+	}
+}
+
+// TestJSONBuiltinsInRoutes checks json.parse() and json.stringify() in both
+// execution modes
+func TestJSONBuiltinsInRoutes(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, `@ GET /roundtrip {
+  $ encoded = json.stringify({name: "Ada", tags: ["a"]})
+  $ decoded = json.parse(encoded)
+  > {encoded: encoded, next: decoded.tags[0], count: json.parse("41") + 1}
+}
+`, mode.interpreted)
+			status, body := getBody(t, srv.URL+"/roundtrip")
+			assert.Equal(t, http.StatusOK, status)
+			assert.JSONEq(t, `{"encoded": "{\"name\":\"Ada\",\"tags\":[\"a\"]}", "next": "a", "count": 42}`, body)
+		})
+	}
+}
+
+// TestCryptoBuiltinsInRoutes checks hash.sha256(), hmac.sha256() and the
+// bcrypt password functions in both execution modes
+func TestCryptoBuiltinsInRoutes(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, `@ GET /digest {
+  $ stored = bcrypt.hash("correct horse")
+  > {
+    sha: hash.sha256("abc"),
+    mac: hmac.sha256("key", "The quick brown fox jumps over the lazy dog"),
+    match: bcrypt.verify("correct horse", stored),
+    mismatch: crypto.verify("wrong horse", stored)
+  }
+}
+`, mode.interpreted)
+			status, body := getBody(t, srv.URL+"/digest")
+			assert.Equal(t, http.StatusOK, status)
+			assert.JSONEq(t, `{
+  "sha": "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+  "mac": "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+  "match": true,
+  "mismatch": false
+}`, body)
+		})
+	}
+}
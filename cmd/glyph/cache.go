@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/glyphlang/glyph/pkg/cache"
+)
+
+var (
+	sharedValueCache     *cache.ValueCache
+	sharedValueCacheErr  error
+	sharedValueCacheOnce sync.Once
+)
+
+// valueCache returns the process-wide cache injected as `% cache: Cache`,
+// shared by every interpreter and compiled route. Values are held in memory
+// unless CACHE_URL names a Redis server.
+func valueCache() (*cache.ValueCache, error) {
+	sharedValueCacheOnce.Do(func() {
+		store, err := cache.Open(os.Getenv("CACHE_URL"))
+		if err != nil {
+			sharedValueCacheErr = fmt.Errorf("CACHE_URL: %w", err)
+			return
+		}
+		sharedValueCache = cache.NewValueCache(store)
+	})
+	return sharedValueCache, sharedValueCacheErr
+}
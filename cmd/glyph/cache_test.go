@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCacheRememberQueriesOnce checks that a route using cache.remember
+// runs its database query once across repeated requests
+func TestCacheRememberQueriesOnce(t *testing.T) {
+	srv := startInputValidationServer(t, `! loadProducts() {
+  $ loads = cache.get("test:remember:loads") ?? 0
+  $ counted = cache.set("test:remember:loads", loads + 1)
+  > db.products.all()
+}
+
+@ POST /products {
+  % db: Database
+  % cache: Cache
+  $ products = cache.remember("test:remember:products", 60, loadProducts)
+  > {products: products, loads: cache.get("test:remember:loads")}
+}
+`, true)
+
+	for n := 0; n < 3; n++ {
+		status, body := postJSON(t, srv, "/products", `{}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, map[string]interface{}{"products": []interface{}{}, "loads": float64(1)}, body)
+	}
+}
+
+// TestCacheInjection checks cache.get, cache.set and cache.delete in both
+// execution modes, with the injection under a name other than cache
+func TestCacheInjection(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, `@ POST /count/:name {
+  % store: Cache
+  $ key = "test:count:" + name
+  $ current = store.get(key) ?? {count: 0}
+  $ saved = store.set(key, {count: current.count + 1}, 60)
+  > store.get(key)
+}
+
+@ POST /reset/:name {
+  % store: Cache
+  > {deleted: store.delete("test:count:" + name)}
+}
+`, mode.interpreted)
+
+			postJSON(t, srv, "/reset/"+mode.name, `{}`)
+			for n := 1; n <= 2; n++ {
+				status, body := postJSON(t, srv, "/count/"+mode.name, `{}`)
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, map[string]interface{}{"count": float64(n)}, body)
+			}
+			status, body := postJSON(t, srv, "/reset/"+mode.name, `{}`)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, map[string]interface{}{"deleted": true}, body)
+			_, body = postJSON(t, srv, "/count/"+mode.name, `{}`)
+			assert.Equal(t, map[string]interface{}{"count": float64(1)}, body)
+		})
+	}
+}
@@ -1,11 +1,22 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/compiler"
+	"github.com/glyphlang/glyph/pkg/database"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -89,6 +100,37 @@ func TestCompileOptLevels(t *testing.T) {
 	}
 }
 
+func TestCompileRouteCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcFile := filepath.Join(tmpDir, "test.glyph")
+	err := os.WriteFile(srcFile, []byte(`@ GET /cached {
+  > {text: "cache me"}
+}
+`), 0644)
+	require.NoError(t, err)
+
+	compile := func(noCache bool) []byte {
+		outFile := filepath.Join(tmpDir, "out.glyphc")
+		cmd := &cobra.Command{}
+		cmd.Flags().String("output", outFile, "")
+		cmd.Flags().Uint8("opt-level", 2, "")
+		cmd.Flags().Bool("no-cache", noCache, "")
+		require.NoError(t, runCompile(cmd, []string{srcFile}))
+		bytecode, err := os.ReadFile(outFile)
+		require.NoError(t, err)
+		return bytecode
+	}
+
+	first := compile(false)
+	before := routeCache().Stats()
+	assert.Equal(t, first, compile(false))
+	assert.Equal(t, before.Hits()+1, routeCache().Stats().Hits(), "second compile should hit the cache")
+
+	before = routeCache().Stats()
+	assert.Equal(t, first, compile(true))
+	assert.Equal(t, before, routeCache().Stats(), "--no-cache should bypass the cache")
+}
+
 // --- Compile error cases ---
 
 func TestCompileNonExistentFile(t *testing.T) {
@@ -309,8 +351,10 @@ func TestRunTestCommand_NoTests(t *testing.T) {
 
 	cmd := &cobra.Command{}
 	cmd.Flags().Bool("verbose", false, "")
+	cmd.Flags().String("run", "", "")
 	cmd.Flags().String("filter", "", "")
 	cmd.Flags().Bool("fail-fast", false, "")
+	cmd.Flags().Bool("json", false, "")
 	err = runTest(cmd, []string{srcFile})
 	require.NoError(t, err)
 }
@@ -318,8 +362,10 @@ func TestRunTestCommand_NoTests(t *testing.T) {
 func TestRunTestCommand_NonExistentFile(t *testing.T) {
 	cmd := &cobra.Command{}
 	cmd.Flags().Bool("verbose", false, "")
+	cmd.Flags().String("run", "", "")
 	cmd.Flags().String("filter", "", "")
 	cmd.Flags().Bool("fail-fast", false, "")
+	cmd.Flags().Bool("json", false, "")
 	err := runTest(cmd, []string{"/tmp/does-not-exist.glyph"})
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to read file")
@@ -374,7 +420,8 @@ func TestParseCommandArgs_FlagArgs(t *testing.T) {
 	}
 
 	args := []string{"--name", "Alice"}
-	result := parseCommandArgs(args, params)
+	result, err := parseCommandArgs(args, params)
+	require.NoError(t, err)
 	assert.Equal(t, "Alice", result["name"])
 }
 
@@ -384,7 +431,8 @@ func TestParseCommandArgs_FlagEqualsFormat(t *testing.T) {
 	}
 
 	args := []string{"--name=Bob"}
-	result := parseCommandArgs(args, params)
+	result, err := parseCommandArgs(args, params)
+	require.NoError(t, err)
 	assert.Equal(t, "Bob", result["name"])
 }
 
@@ -394,12 +442,14 @@ func TestParseCommandArgs_PositionalArgs(t *testing.T) {
 	}
 
 	args := []string{"myfile.txt"}
-	result := parseCommandArgs(args, params)
+	result, err := parseCommandArgs(args, params)
+	require.NoError(t, err)
 	assert.Equal(t, "myfile.txt", result["input"])
 }
 
 func TestParseCommandArgs_Empty(t *testing.T) {
-	result := parseCommandArgs([]string{}, nil)
+	result, err := parseCommandArgs([]string{}, nil)
+	require.NoError(t, err)
 	assert.Empty(t, result)
 }
 
@@ -410,11 +460,187 @@ func TestParseCommandArgs_MultipleFlags(t *testing.T) {
 	}
 
 	args := []string{"--name", "Alice", "--age", "30"}
-	result := parseCommandArgs(args, params)
+	result, err := parseCommandArgs(args, params)
+	require.NoError(t, err)
 	assert.Equal(t, "Alice", result["name"])
 	assert.Equal(t, "30", result["age"])
 }
 
+func TestParseCommandArgs_TypedValues(t *testing.T) {
+	params := []ast.CommandParam{
+		{Name: "count", Type: ast.IntType{}, IsFlag: true},
+		{Name: "ratio", Type: ast.FloatType{}, IsFlag: true},
+		{Name: "verbose", Type: ast.BoolType{}, IsFlag: true},
+		{Name: "dry", Type: ast.OptionalType{InnerType: ast.BoolType{}}, IsFlag: true},
+	}
+
+	args := []string{"--count", "3", "--ratio=0.5", "--verbose", "--dry=false"}
+	result, err := parseCommandArgs(args, params)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), result["count"])
+	assert.Equal(t, 0.5, result["ratio"])
+	assert.Equal(t, true, result["verbose"])
+	assert.Equal(t, false, result["dry"])
+}
+
+func TestParseCommandArgs_InvalidValue(t *testing.T) {
+	params := []ast.CommandParam{
+		{Name: "count", Type: ast.IntType{}, IsFlag: true},
+	}
+
+	_, err := parseCommandArgs([]string{"--count", "many"}, params)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid value "many" for count: expected int`)
+}
+
+func TestParseCommandArgs_RepeatedArrayFlag(t *testing.T) {
+	params := []ast.CommandParam{
+		{Name: "port", Type: ast.ArrayType{ElementType: ast.IntType{}}, IsFlag: true},
+	}
+
+	result, err := parseCommandArgs([]string{"--port", "80", "--port=443"}, params)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(80), int64(443)}, result["port"])
+}
+
+func TestParseCommandArgs_MultiplePositional(t *testing.T) {
+	params := []ast.CommandParam{
+		{Name: "src", Type: ast.StringType{}, Required: true},
+		{Name: "force", Type: ast.BoolType{}, IsFlag: true},
+		{Name: "dst", Type: ast.StringType{}, Required: true},
+	}
+
+	result, err := parseCommandArgs([]string{"a.txt", "--force", "b.txt"}, params)
+	require.NoError(t, err)
+	assert.Equal(t, "a.txt", result["src"])
+	assert.Equal(t, "b.txt", result["dst"])
+	assert.Equal(t, true, result["force"])
+}
+
+func TestParseCommandArgs_PositionalArrayTakesRest(t *testing.T) {
+	params := []ast.CommandParam{
+		{Name: "op", Type: ast.StringType{}},
+		{Name: "values", Type: ast.ArrayType{ElementType: ast.IntType{}}},
+	}
+
+	result, err := parseCommandArgs([]string{"sum", "1", "2", "3"}, params)
+	require.NoError(t, err)
+	assert.Equal(t, "sum", result["op"])
+	assert.Equal(t, []interface{}{int64(1), int64(2), int64(3)}, result["values"])
+}
+
+func TestParseCommandArgs_DoubleDashEndsFlags(t *testing.T) {
+	params := []ast.CommandParam{
+		{Name: "pattern", Type: ast.StringType{}},
+	}
+
+	result, err := parseCommandArgs([]string{"--", "--literal"}, params)
+	require.NoError(t, err)
+	assert.Equal(t, "--literal", result["pattern"])
+}
+
+func TestParseCommandArgs_Errors(t *testing.T) {
+	params := []ast.CommandParam{
+		{Name: "name", Type: ast.StringType{}, Required: true},
+		{Name: "times", Type: ast.IntType{}, IsFlag: true},
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"unknown flag", []string{"Bob", "--bogus", "1"}, "unknown flag: --bogus"},
+		{"extra positional", []string{"Bob", "Alice"}, "unexpected argument: Alice"},
+		{"missing required", []string{"--times", "2"}, "missing required argument(s): name"},
+		{"missing flag value", []string{"Bob", "--times"}, "flag --times needs a value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseCommandArgs(tt.args, params)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestParseCommandArgs_RequiredWithDefault(t *testing.T) {
+	params := []ast.CommandParam{
+		{Name: "env", Type: ast.StringType{}, Required: true, IsFlag: true,
+			Default: ast.LiteralExpr{Value: ast.StringLiteral{Value: "dev"}}},
+	}
+
+	result, err := parseCommandArgs(nil, params)
+	require.NoError(t, err)
+	assert.NotContains(t, result, "env")
+}
+
+// --- command usage ---
+
+func TestFormatCommandParam(t *testing.T) {
+	assert.Equal(t, "name: str!", formatCommandParam(ast.CommandParam{Name: "name", Type: ast.StringType{}, Required: true}))
+	assert.Equal(t, "--times: int", formatCommandParam(ast.CommandParam{Name: "times", Type: ast.IntType{}, IsFlag: true}))
+	assert.Equal(t, "input", formatCommandParam(ast.CommandParam{Name: "input"}))
+}
+
+func TestCommandUsage(t *testing.T) {
+	cmd := ast.Command{
+		Name:        "greet",
+		Description: "Greet someone",
+		Params: []ast.CommandParam{
+			{Name: "name", Type: ast.StringType{}, Required: true, Description: "Who to greet"},
+			{Name: "times", Type: ast.IntType{}, IsFlag: true,
+				Default: ast.LiteralExpr{Value: ast.IntLiteral{Value: 1}}},
+			{Name: "greeting", Type: ast.StringType{}, IsFlag: true,
+				Default: ast.LiteralExpr{Value: ast.StringLiteral{Value: "Hello"}}},
+		},
+	}
+
+	usage := commandUsage(cmd)
+	assert.Contains(t, usage, "Usage: glyph exec <file> greet <name> [flags]")
+	assert.Contains(t, usage, "Greet someone")
+	assert.Contains(t, usage, "Arguments:\n  name: str!  Who to greet\n")
+	assert.Contains(t, usage, "Flags:\n  --times: int = 1\n  --greeting: str = \"Hello\"\n")
+}
+
+func TestWantsCommandHelp(t *testing.T) {
+	assert.True(t, wantsCommandHelp([]string{"--help"}))
+	assert.True(t, wantsCommandHelp([]string{"Bob", "-h"}))
+	assert.False(t, wantsCommandHelp([]string{"--", "--help"}))
+	assert.False(t, wantsCommandHelp([]string{"Bob"}))
+}
+
+func TestRunExec_Help(t *testing.T) {
+	srcFile := filepath.Join(t.TempDir(), "cli.glyph")
+	err := os.WriteFile(srcFile, []byte(`! greet "Greet someone" name: str! "Who to greet" --loud: bool = false {
+  > name
+}
+`), 0644)
+	require.NoError(t, err)
+
+	// --help prints usage instead of running the command, so the missing
+	// required name is not an error
+	cmd := &cobra.Command{}
+	err = runExec(cmd, []string{srcFile, "greet", "--help"})
+	require.NoError(t, err)
+}
+
+func TestRunExec_ArgumentError(t *testing.T) {
+	srcFile := filepath.Join(t.TempDir(), "cli.glyph")
+	err := os.WriteFile(srcFile, []byte(`! greet name: str! {
+  > name
+}
+`), 0644)
+	require.NoError(t, err)
+
+	cmd := &cobra.Command{}
+	err = runExec(cmd, []string{srcFile, "greet", "--bogus"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown flag: --bogus")
+	assert.Contains(t, err.Error(), "Usage: glyph exec <file> greet <name>")
+}
+
 // --- indexOf ---
 
 func TestIndexOf(t *testing.T) {
@@ -457,27 +683,94 @@ func TestParseSource_MultipleRoutes(t *testing.T) {
 
 // --- Run command with bytecode ---
 
-func TestRunBytecode(t *testing.T) {
+// compileValidSource compiles validSource into a .glyphc file and returns
+// its path
+func compileValidSource(t *testing.T) string {
+	t.Helper()
 	tmpDir := t.TempDir()
-
 	srcFile := filepath.Join(tmpDir, "test.glyph")
-	err := os.WriteFile(srcFile, []byte(validSource), 0644)
-	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(srcFile, []byte(validSource), 0644))
 
 	compiledFile := filepath.Join(tmpDir, "test.glyphc")
 	cmd := &cobra.Command{}
 	cmd.Flags().String("output", compiledFile, "")
 	cmd.Flags().Uint8("opt-level", 0, "")
-	err = runCompile(cmd, []string{srcFile})
+	require.NoError(t, runCompile(cmd, []string{srcFile}))
+	return compiledFile
+}
+
+// runCompiledServer runs glyph run on compiledFile until GET /hello
+// answers, then interrupts it and returns that response
+func runCompiledServer(t *testing.T, compiledFile string, bytecode bool) (int, string) {
+	t.Helper()
+
+	// Keep the interrupts sent below from stopping the test binary
+	sigChan := make(chan os.Signal, 1)
+	signalNotify(sigChan)
+	defer signal.Stop(sigChan)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
 	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	require.NoError(t, ln.Close())
 
-	// Run the bytecode
 	runCmd := &cobra.Command{}
-	runCmd.Flags().Uint16("port", 0, "")
-	runCmd.Flags().Bool("bytecode", true, "")
+	runCmd.Flags().Uint16("port", uint16(port), "")
+	runCmd.Flags().Bool("bytecode", bytecode, "")
 	runCmd.Flags().Bool("interpret", false, "")
-	err = runRun(runCmd, []string{compiledFile})
+	runCmd.Flags().String("log-format", "text", "")
+	runCmd.Flags().Bool("metrics", false, "")
+	done := make(chan error, 1)
+	go func() { done <- runRun(runCmd, []string{compiledFile}) }()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/hello", port)
+	var status int
+	var body string
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(url)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		status, body = resp.StatusCode, string(data)
+		return err == nil
+	}, 5*time.Second, 20*time.Millisecond)
+
+	self, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	for {
+		require.NoError(t, self.Signal(os.Interrupt))
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+			return status, body
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func TestRunBytecode(t *testing.T) {
+	status, body := runCompiledServer(t, compileValidSource(t), true)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"text": "Hello, World!"}`, body)
+}
+
+func TestRunBytecode_RouteBytecodeWithoutManifest(t *testing.T) {
+	module, err := parseSource(validSource)
+	require.NoError(t, err)
+	bytecode, err := compiler.NewCompiler().CompileRoute(module.Items[0].(*ast.Route))
 	require.NoError(t, err)
+	routeFile := filepath.Join(t.TempDir(), "route.glyphc")
+	require.NoError(t, os.WriteFile(routeFile, bytecode, 0644))
+
+	cmd := &cobra.Command{}
+	cmd.Flags().Uint16("port", 0, "")
+	cmd.Flags().Bool("bytecode", true, "")
+	cmd.Flags().Bool("interpret", false, "")
+	err = runRun(cmd, []string{routeFile})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "without a route manifest")
 }
 
 func TestRunBytecode_NonExistentFile(t *testing.T) {
@@ -502,28 +795,256 @@ func TestRunBytecode_InvalidBytecode(t *testing.T) {
 	cmd.Flags().Bool("interpret", false, "")
 	err = runRun(cmd, []string{badFile})
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "bytecode execution failed")
+	assert.Contains(t, err.Error(), "failed verification")
 }
 
 func TestRunAutoDetectBytecode(t *testing.T) {
+	// Without --bytecode, the compiled file is detected by its contents
+	status, body := runCompiledServer(t, compileValidSource(t), false)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"text": "Hello, World!"}`, body)
+}
+
+// --- fmt ---
+
+func TestRunFmt(t *testing.T) {
 	tmpDir := t.TempDir()
+	messy := "@ GET /hello   {\n      > {text: \"hi\"}   # greet\n}"
+	canonical := "@ GET /hello {\n  > {text: \"hi\"} # greet\n}\n"
 
-	srcFile := filepath.Join(tmpDir, "test.glyph")
-	err := os.WriteFile(srcFile, []byte(validSource), 0644)
+	srcFile := filepath.Join(tmpDir, "app.glyph")
+	require.NoError(t, os.WriteFile(srcFile, []byte(messy), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "node_modules"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "node_modules", "skip.glyph"), []byte("@ GET /x {"), 0644))
+
+	newCmd := func(check, write bool) (*cobra.Command, *strings.Builder) {
+		cmd := &cobra.Command{}
+		cmd.Flags().Bool("check", check, "")
+		cmd.Flags().Bool("write", write, "")
+		out := &strings.Builder{}
+		cmd.SetOut(out)
+		return cmd, out
+	}
+
+	// Default prints the formatted source
+	cmd, out := newCmd(false, false)
+	require.NoError(t, runFmt(cmd, []string{srcFile}))
+	assert.Equal(t, canonical, out.String())
+
+	// --check lists the file and fails
+	cmd, out = newCmd(true, false)
+	err := runFmt(cmd, []string{tmpDir})
+	assert.Error(t, err)
+	assert.Contains(t, out.String(), srcFile)
+
+	// --write formats in place, after which --check passes
+	cmd, _ = newCmd(false, true)
+	require.NoError(t, runFmt(cmd, []string{tmpDir}))
+	data, err := os.ReadFile(srcFile)
 	require.NoError(t, err)
+	assert.Equal(t, canonical, string(data))
 
-	compiledFile := filepath.Join(tmpDir, "test.glyphc")
-	cmd := &cobra.Command{}
-	cmd.Flags().String("output", compiledFile, "")
-	cmd.Flags().Uint8("opt-level", 0, "")
-	err = runCompile(cmd, []string{srcFile})
+	cmd, _ = newCmd(true, false)
+	assert.NoError(t, runFmt(cmd, []string{tmpDir}))
+}
+
+func TestRunCheck(t *testing.T) {
+	tmpDir := t.TempDir()
+	newCmd := func(jsonOut, strict bool) (*cobra.Command, *strings.Builder) {
+		cmd := &cobra.Command{}
+		cmd.Flags().Bool("json", jsonOut, "")
+		cmd.Flags().Bool("strict", strict, "")
+		out := &strings.Builder{}
+		cmd.SetOut(out)
+		return cmd, out
+	}
+
+	// Undefined names are errors
+	badFile := filepath.Join(tmpDir, "bad.glyph")
+	require.NoError(t, os.WriteFile(badFile, []byte("@ GET /x {\n  > {text: msg}\n}\n"), 0644))
+	cmd, out := newCmd(false, false)
+	assert.Error(t, runCheck(cmd, []string{badFile}))
+	assert.Contains(t, out.String(), badFile+":2:12: error: undefined variable: msg")
+
+	// --json reports diagnostics as an array
+	cmd, out = newCmd(true, false)
+	assert.Error(t, runCheck(cmd, []string{badFile}))
+	var diags []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out.String()), &diags))
+	require.Len(t, diags, 1)
+	assert.Equal(t, "error", diags[0]["severity"])
+	assert.Equal(t, float64(2), diags[0]["line"])
+
+	// Parse errors are reported with their position
+	brokenFile := filepath.Join(tmpDir, "broken.glyph")
+	require.NoError(t, os.WriteFile(brokenFile, []byte("@ GET /x {\n"), 0644))
+	cmd, out = newCmd(false, false)
+	assert.Error(t, runCheck(cmd, []string{brokenFile}))
+	assert.Contains(t, out.String(), brokenFile+":")
+
+	// Warnings only fail with --strict
+	warnFile := filepath.Join(tmpDir, "warn.glyph")
+	require.NoError(t, os.WriteFile(warnFile, []byte("@ GET /users/:id {\n  > {}\n}\n"), 0644))
+	cmd, out = newCmd(false, false)
+	assert.NoError(t, runCheck(cmd, []string{warnFile}))
+	assert.Contains(t, out.String(), "warning: path parameter 'id'")
+
+	cmd, _ = newCmd(false, true)
+	assert.Error(t, runCheck(cmd, []string{warnFile}))
+
+	// A clean file produces an empty JSON array
+	cleanFile := filepath.Join(tmpDir, "clean.glyph")
+	require.NoError(t, os.WriteFile(cleanFile, []byte("@ GET /x {\n  > {text: \"hi\"}\n}\n"), 0644))
+	cmd, out = newCmd(true, false)
+	require.NoError(t, runCheck(cmd, []string{cleanFile}))
+	assert.Equal(t, "[]", strings.TrimSpace(out.String()))
+}
+
+func TestRunMigrate(t *testing.T) {
+	tmpDir := t.TempDir()
+	migrationsDir := filepath.Join(tmpDir, "migrations")
+	require.NoError(t, os.Mkdir(migrationsDir, 0755))
+	for name, content := range map[string]string{
+		"001_create_notes.up.sql":   "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT);",
+		"001_create_notes.down.sql": "DROP TABLE notes;",
+		"002_seed_notes.up.sql":     "INSERT INTO notes (body) VALUES ('hello');",
+		"002_seed_notes.down.sql":   "DELETE FROM notes;",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(migrationsDir, name), []byte(content), 0644))
+	}
+	connStr := "sqlite://" + filepath.ToSlash(filepath.Join(tmpDir, "app.db"))
+
+	newCmd := func(args ...string) (*cobra.Command, *strings.Builder) {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("database", "", "")
+		cmd.Flags().Int("down", 0, "")
+		require.NoError(t, cmd.Flags().Parse(args))
+		out := &strings.Builder{}
+		cmd.SetOut(out)
+		return cmd, out
+	}
+
+	cmd, out := newCmd("--database", connStr)
+	require.NoError(t, runMigrate(cmd, []string{migrationsDir}))
+	assert.Equal(t, "Applied 001_create_notes\nApplied 002_seed_notes\n", out.String())
+
+	// Running again applies nothing
+	cmd, out = newCmd("--database", connStr)
+	require.NoError(t, runMigrate(cmd, []string{migrationsDir}))
+	assert.Empty(t, out.String())
+
+	// The connection string can come from DATABASE_URL
+	t.Setenv("DATABASE_URL", connStr)
+	cmd, out = newCmd("--down", "2")
+	require.NoError(t, runMigrate(cmd, []string{migrationsDir}))
+	assert.Equal(t, "Rolled back 002_seed_notes\nRolled back 001_create_notes\n", out.String())
+
+	// --down 0 is rejected rather than treated as "apply"
+	cmd, _ = newCmd("--down", "0")
+	assert.Error(t, runMigrate(cmd, []string{migrationsDir}))
+
+	t.Setenv("DATABASE_URL", "")
+	cmd, _ = newCmd()
+	assert.ErrorContains(t, runMigrate(cmd, []string{migrationsDir}), "DATABASE_URL")
+}
+
+func TestRunDBSync(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcFile := filepath.Join(tmpDir, "main.glyph")
+	source := `: User {
+  id: int!
+  name: str!
+  email: str
+}
+
+: Post {
+  id: int!
+  tags: [str]
+}
+
+@ GET /users -> User {
+  > {id: 1, name: "Ada"}
+}
+`
+	require.NoError(t, os.WriteFile(srcFile, []byte(source), 0644))
+	connStr := "sqlite://" + filepath.ToSlash(filepath.Join(tmpDir, "app.db"))
+
+	newCmd := func() (*cobra.Command, *strings.Builder) {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("database", connStr, "")
+		out := &strings.Builder{}
+		cmd.SetOut(out)
+		return cmd, out
+	}
+
+	// Post is skipped because [str] has no column mapping
+	cmd, out := newCmd()
+	require.NoError(t, runDBSync(cmd, []string{srcFile}))
+	assert.Equal(t, "Created users from User\n", out.String())
+
+	db := database.NewSQLiteDB(&database.Config{Driver: "sqlite", Database: filepath.Join(tmpDir, "app.db")})
+	require.NoError(t, db.Connect(context.Background()))
+	defer db.Close()
+	_, err := db.Exec(context.Background(), "INSERT INTO users (id, name) VALUES (1, 'Ada')")
 	require.NoError(t, err)
+	_, err = db.Exec(context.Background(), "INSERT INTO users (id) VALUES (2)")
+	assert.Error(t, err, "name is NOT NULL")
 
-	// Run without --bytecode flag, should auto-detect from .glyphc extension
-	runCmd := &cobra.Command{}
-	runCmd.Flags().Uint16("port", 0, "")
-	runCmd.Flags().Bool("bytecode", false, "")
-	runCmd.Flags().Bool("interpret", false, "")
-	err = runRun(runCmd, []string{compiledFile})
+	// Existing tables are left alone
+	cmd, out = newCmd()
+	require.NoError(t, runDBSync(cmd, []string{srcFile}))
+	assert.Empty(t, out.String())
+
+	cmd, _ = newCmd()
+	assert.Error(t, runDBSync(cmd, []string{filepath.Join(tmpDir, "missing.glyph")}))
+}
+
+// --- OpenAPI command ---
+
+func newOpenAPICommand() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().StringP("output", "o", "", "")
+	cmd.Flags().StringP("format", "f", "yaml", "")
+	cmd.Flags().String("title", "", "")
+	cmd.Flags().String("api-version", "1.0.0", "")
+	return cmd
+}
+
+func TestOpenAPIFormatFromOutputExtension(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcFile := filepath.Join(tmpDir, "shop.glyph")
+	require.NoError(t, os.WriteFile(srcFile, []byte("import \"./types.glyph\"\n\n@ GET /users/:id -> User {\n  > {id: 1, name: \"a\"}\n}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "types.glyph"), []byte(": User {\n  id: int!\n  name: str!\n}\n"), 0644))
+
+	jsonFile := filepath.Join(tmpDir, "openapi.json")
+	cmd := newOpenAPICommand()
+	require.NoError(t, cmd.Flags().Set("output", jsonFile))
+	require.NoError(t, runOpenAPI(cmd, []string{srcFile}))
+
+	data, err := os.ReadFile(jsonFile)
+	require.NoError(t, err)
+	var spec map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &spec))
+	assert.Equal(t, "3.1.0", spec["openapi"])
+	assert.Equal(t, "shop API", spec["info"].(map[string]interface{})["title"])
+	assert.Contains(t, spec["paths"], "/users/{id}")
+	schemas := spec["components"].(map[string]interface{})["schemas"]
+	assert.Contains(t, schemas, "User", "imported types become schemas")
+
+	yamlFile := filepath.Join(tmpDir, "openapi.yaml")
+	cmd = newOpenAPICommand()
+	require.NoError(t, cmd.Flags().Set("output", yamlFile))
+	require.NoError(t, runOpenAPI(cmd, []string{srcFile}))
+	data, err = os.ReadFile(yamlFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "openapi: 3.1.0")
+
+	// An explicit --format wins over the extension
+	cmd = newOpenAPICommand()
+	require.NoError(t, cmd.Flags().Set("output", jsonFile))
+	require.NoError(t, cmd.Flags().Set("format", "yaml"))
+	require.NoError(t, runOpenAPI(cmd, []string{srcFile}))
+	data, err = os.ReadFile(jsonFile)
 	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "openapi: 3.1.0"))
 }
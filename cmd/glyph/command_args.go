@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+)
+
+// parseCommandArgs maps CLI arguments onto a command's parameters. Positional
+// arguments fill the non-flag parameters in declaration order, and any
+// parameter can also be set by name with --name value or --name=value. Values
+// are coerced to the parameter's declared type: bool flags need no value,
+// array parameters collect repeated flags, and a positional array parameter
+// takes the remaining positional arguments. Unknown flags, surplus positional
+// arguments and missing required parameters are errors.
+func parseCommandArgs(args []string, params []ast.CommandParam) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+
+	byName := make(map[string]*ast.CommandParam, len(params))
+	var positional []*ast.CommandParam
+	for i := range params {
+		byName[params[i].Name] = &params[i]
+		if !params[i].IsFlag {
+			positional = append(positional, &params[i])
+		}
+	}
+
+	positionalIdx := 0
+	flagsDone := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if arg == "--" && !flagsDone {
+			flagsDone = true
+			continue
+		}
+
+		// Flag arguments: --name value, --name=value or a bare --name for bools
+		if !flagsDone && len(arg) > 2 && arg[:2] == "--" {
+			name, value := arg[2:], ""
+			hasValue := false
+			if eqIdx := indexOf(name, '='); eqIdx != -1 {
+				name, value = name[:eqIdx], name[eqIdx+1:]
+				hasValue = true
+			}
+
+			param, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown flag: --%s", name)
+			}
+
+			if !hasValue {
+				if isBoolType(param.Type) {
+					value = "true"
+				} else if i+1 < len(args) {
+					i++
+					value = args[i]
+				} else {
+					return nil, fmt.Errorf("flag --%s needs a value", name)
+				}
+			}
+
+			if err := setCommandArg(result, param, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// Positional argument
+		if positionalIdx >= len(positional) {
+			return nil, fmt.Errorf("unexpected argument: %s", arg)
+		}
+		param := positional[positionalIdx]
+		if err := setCommandArg(result, param, arg); err != nil {
+			return nil, err
+		}
+		if _, isArray := unwrapOptional(param.Type).(ast.ArrayType); !isArray {
+			positionalIdx++
+		}
+	}
+
+	var missing []string
+	for _, param := range params {
+		if _, ok := result[param.Name]; !ok && param.Required && param.Default == nil {
+			missing = append(missing, param.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required argument(s): %s", strings.Join(missing, ", "))
+	}
+
+	return result, nil
+}
+
+// setCommandArg coerces value to param's type and stores it in result,
+// appending to the existing list for array parameters
+func setCommandArg(result map[string]interface{}, param *ast.CommandParam, value string) error {
+	if arr, ok := unwrapOptional(param.Type).(ast.ArrayType); ok {
+		elem, err := coerceCommandArg(param.Name, arr.ElementType, value)
+		if err != nil {
+			return err
+		}
+		list, _ := result[param.Name].([]interface{})
+		result[param.Name] = append(list, elem)
+		return nil
+	}
+
+	v, err := coerceCommandArg(param.Name, param.Type, value)
+	if err != nil {
+		return err
+	}
+	result[param.Name] = v
+	return nil
+}
+
+// coerceCommandArg converts a CLI argument to the interpreter value for type t.
+// Untyped and string parameters keep the raw string.
+func coerceCommandArg(name string, t ast.Type, value string) (interface{}, error) {
+	switch unwrapOptional(t).(type) {
+	case ast.IntType:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s: expected int", value, name)
+		}
+		return n, nil
+	case ast.FloatType:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s: expected float", value, name)
+		}
+		return f, nil
+	case ast.BoolType:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s: expected bool", value, name)
+		}
+		return b, nil
+	default:
+		return value, nil
+	}
+}
+
+// unwrapOptional returns the inner type of an optional type
+func unwrapOptional(t ast.Type) ast.Type {
+	if opt, ok := t.(ast.OptionalType); ok {
+		return opt.InnerType
+	}
+	return t
+}
+
+// isBoolType reports whether t is bool or bool?
+func isBoolType(t ast.Type) bool {
+	_, ok := unwrapOptional(t).(ast.BoolType)
+	return ok
+}
+
+// wantsCommandHelp reports whether the command's arguments ask for usage
+func wantsCommandHelp(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if arg == "--help" || arg == "-h" {
+			return true
+		}
+	}
+	return false
+}
+
+// formatCommandParam formats a parameter the way it is declared, e.g.
+// --times: int!
+func formatCommandParam(p ast.CommandParam) string {
+	paramStr := p.Name
+	if p.Type != nil {
+		paramStr += ": " + typeToString(p.Type)
+	}
+	if p.Required {
+		paramStr += "!"
+	}
+	if p.IsFlag {
+		paramStr = "--" + paramStr
+	}
+	return paramStr
+}
+
+// commandUsage returns the usage text glyph exec prints for --help and for
+// argument errors
+func commandUsage(cmd ast.Command) string {
+	var sb strings.Builder
+
+	var args, flags []ast.CommandParam
+	for _, p := range cmd.Params {
+		if p.IsFlag {
+			flags = append(flags, p)
+		} else {
+			args = append(args, p)
+		}
+	}
+
+	sb.WriteString("Usage: glyph exec <file> " + cmd.Name)
+	for _, p := range args {
+		if p.Required && p.Default == nil {
+			sb.WriteString(" <" + p.Name + ">")
+		} else {
+			sb.WriteString(" [" + p.Name + "]")
+		}
+	}
+	if len(flags) > 0 {
+		sb.WriteString(" [flags]")
+	}
+	sb.WriteString("\n")
+
+	if cmd.Description != "" {
+		sb.WriteString("\n  " + cmd.Description + "\n")
+	}
+
+	writeSection := func(title string, params []ast.CommandParam) {
+		if len(params) == 0 {
+			return
+		}
+		sb.WriteString("\n" + title + ":\n")
+		for _, p := range params {
+			line := "  " + formatCommandParam(p)
+			if p.Default != nil {
+				if v, ok := evalLiteralExpr(p.Default); ok {
+					if s, isString := v.(string); isString {
+						line += fmt.Sprintf(" = %q", s)
+					} else {
+						line += fmt.Sprintf(" = %v", v)
+					}
+				}
+			}
+			if p.Description != "" {
+				line += "  " + p.Description
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+	writeSection("Arguments", args)
+	writeSection("Flags", flags)
+
+	return sb.String()
+}
@@ -1,18 +1,22 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
-	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/bundle"
 	"github.com/glyphlang/glyph/pkg/compiler"
+	"github.com/glyphlang/glyph/pkg/database"
 	"github.com/glyphlang/glyph/pkg/decompiler"
+	"github.com/glyphlang/glyph/pkg/interpreter"
 	"github.com/glyphlang/glyph/pkg/lsp"
-	"github.com/glyphlang/glyph/pkg/parser"
+	"github.com/glyphlang/glyph/pkg/server"
 	"github.com/glyphlang/glyph/pkg/vm"
 	"github.com/spf13/cobra"
 )
@@ -22,6 +26,7 @@ func runCompile(cmd *cobra.Command, args []string) error {
 	filePath := args[0]
 	output, _ := cmd.Flags().GetString("output")
 	optLevel, _ := cmd.Flags().GetUint8("opt-level")
+	noCache, _ := cmd.Flags().GetBool("no-cache")
 
 	printInfo(fmt.Sprintf("Compiling %s... (opt-level: %d)", filePath, optLevel))
 
@@ -34,43 +39,32 @@ func runCompile(cmd *cobra.Command, args []string) error {
 	// Measure compilation time
 	start := time.Now()
 
-	// Parse source code
-	module, err := parseSource(string(source))
+	// Parse source code and everything it imports
+	program, err := loadProgram(filePath)
 	if err != nil {
 		return fmt.Errorf("parse failed: %w", err)
 	}
+	module := program.Module
 
-	// Determine optimization level
-	var optLevelEnum compiler.OptimizationLevel
-	switch optLevel {
-	case 0:
-		optLevelEnum = compiler.OptNone
-	case 1, 2:
-		optLevelEnum = compiler.OptBasic
-	case 3:
-		optLevelEnum = compiler.OptAggressive
-	default:
-		optLevelEnum = compiler.OptBasic
+	c := compiler.NewCompilerWithOptLevel(optimizationLevel(optLevel))
+	c.SetFunctions(programFunctions(program))
+	c.SetMiddlewares(compiler.ModuleMiddlewares(module))
+	if !noCache {
+		c.SetCache(routeCache())
 	}
 
-	// Compile module (for now, just compile the first route)
-	c := compiler.NewCompilerWithOptLevel(optLevelEnum)
-	var bytecode []byte
-
-	// Find first route and compile it
-	for _, item := range module.Items {
-		if route, ok := item.(*ast.Route); ok {
-			bytecode, err = c.CompileRoute(route)
-			if err != nil {
-				return fmt.Errorf("compilation failed: %w", err)
-			}
-			break
-		}
+	// Every module compiles to a bundle, whose manifest tells glyph run
+	// which route each piece of bytecode serves
+	b, err := buildBundle(program, c, bundleOptions{})
+	if err != nil {
+		return err
 	}
-
-	if bytecode == nil {
-		return fmt.Errorf("no routes found in module")
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		return err
 	}
+	compiled := buf.Bytes()
+	printInfo(fmt.Sprintf("Compiled %d routes into a bundle (use glyph build to include static files)", len(b.Manifest.Routes)+len(b.Manifest.WebSockets)))
 
 	compilationTime := time.Since(start)
 
@@ -80,15 +74,15 @@ func runCompile(cmd *cobra.Command, args []string) error {
 	}
 
 	// Write bytecode to file with restricted permissions (owner read/write only)
-	if err := os.WriteFile(output, bytecode, 0600); err != nil {
+	if err := os.WriteFile(output, compiled, 0600); err != nil {
 		return fmt.Errorf("failed to write output: %w", err)
 	}
 
 	printSuccess(fmt.Sprintf("Compiled to %s", output))
 	printInfo(fmt.Sprintf("Compilation time: %s", compilationTime))
-	printInfo(fmt.Sprintf("Output size: %d bytes", len(bytecode)))
+	printInfo(fmt.Sprintf("Output size: %d bytes", len(compiled)))
 	printInfo(fmt.Sprintf("Source size: %d bytes", len(source)))
-	compressionRatio := (1.0 - float64(len(bytecode))/float64(len(source))) * 100.0
+	compressionRatio := (1.0 - float64(len(compiled))/float64(len(source))) * 100.0
 	if compressionRatio > 0 {
 		printInfo(fmt.Sprintf("Compression: %.1f%%", compressionRatio))
 	}
@@ -109,6 +103,9 @@ func runDecompile(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
+	if bundle.IsBundle(bytecode) {
+		return decompileBundle(bytecode, filePath, output, disasmOnly)
+	}
 
 	// Use the decompiler package
 	dec := decompiler.NewDecompiler()
@@ -149,97 +146,131 @@ func runDecompile(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// runTest handles the test command - executes test blocks in a GLYPH file.
+// runTest handles the test command - executes test blocks in a GLYPH file,
+// or in every GLYPH file under a directory. Each block runs against a fresh
+// MockDatabase, so tests never need a real database.
 // Argument count is validated by cobra.ExactArgs(1) before this function is called.
 // printWarning, printInfo are defined in this file (see helper functions section).
 func runTest(cmd *cobra.Command, args []string) error {
-	filePath := args[0]
+	path := args[0]
 	verbose, err := cmd.Flags().GetBool("verbose")
 	if err != nil {
 		return fmt.Errorf("invalid flag --verbose: %w", err)
 	}
-	filter, err := cmd.Flags().GetString("filter")
+	pattern, err := cmd.Flags().GetString("run")
 	if err != nil {
-		return fmt.Errorf("invalid flag --filter: %w", err)
+		return fmt.Errorf("invalid flag --run: %w", err)
+	}
+	if pattern == "" {
+		if pattern, err = cmd.Flags().GetString("filter"); err != nil {
+			return fmt.Errorf("invalid flag --filter: %w", err)
+		}
 	}
 	failFast, err := cmd.Flags().GetBool("fail-fast")
 	if err != nil {
 		return fmt.Errorf("invalid flag --fail-fast: %w", err)
 	}
-
-	// Read and parse source file
-	source, err := os.ReadFile(filePath)
+	jsonOutput, err := cmd.Flags().GetBool("json")
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return fmt.Errorf("invalid flag --json: %w", err)
 	}
 
-	// Determine lexer type based on file extension
-	var tokens []parser.Token
-	if filepath.Ext(filePath) == ".glyphx" {
-		lexer := parser.NewExpandedLexer(string(source))
-		tokens, err = lexer.Tokenize()
-	} else {
-		lexer := parser.NewLexer(string(source))
-		tokens, err = lexer.Tokenize()
-	}
+	files, err := testFiles(path)
 	if err != nil {
-		return fmt.Errorf("lexer error: %w", err)
-	}
-
-	p := parser.NewParserWithSource(tokens, string(source))
-	module, err := p.Parse()
-	if err != nil {
-		return fmt.Errorf("parse error: %w", err)
-	}
-
-	// Create interpreter with module resolution support and load module
-	interp := newConfiguredInterpreter()
-	basePath := filepath.Dir(filePath)
-	if basePath == "" {
-		basePath = "."
-	}
-	if err := interp.LoadModuleWithPath(*module, basePath); err != nil {
-		return fmt.Errorf("load error: %w", err)
-	}
-
-	tests := interp.GetTestBlocks()
-	if len(tests) == 0 {
-		printWarning("No test blocks found in " + filePath)
-		return nil
+		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	// Run tests
-	results := interp.RunTests(filter)
-	if len(results) == 0 {
-		printWarning("No tests matched filter: " + filter)
-		return nil
-	}
+	greenCheck := color.New(color.FgGreen).SprintFunc()
+	redX := color.New(color.FgRed).SprintFunc()
 
-	// Display results
+	var results []testFileResult
 	passed := 0
 	failed := 0
+	found := false
 
-	greenCheck := color.New(color.FgGreen).SprintFunc()
-	redX := color.New(color.FgRed).SprintFunc()
+files:
+	for _, file := range files {
+		interp, err := loadTestFile(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		tests := interp.GetTestBlocks()
+		if len(tests) == 0 {
+			continue
+		}
+		found = true
+		if !jsonOutput && len(files) > 1 {
+			fmt.Println(file)
+		}
 
-	for _, r := range results {
-		if r.Passed {
-			passed++
-			if verbose {
-				fmt.Printf("  %s %s (%s)\n", greenCheck("PASS"), r.Name, r.Duration)
+		for _, test := range tests {
+			if !interpreter.MatchesTestFilter(test.Name, pattern) {
+				continue
 			}
-		} else {
+			interp.SetDatabaseHandler(database.NewMockDatabase())
+			r := interp.RunTest(test)
+			results = append(results, testFileResult{
+				File:       file,
+				Name:       r.Name,
+				Passed:     r.Passed,
+				Error:      r.Error,
+				DurationMs: float64(r.Duration.Microseconds()) / 1000,
+			})
+
+			if r.Passed {
+				passed++
+				if jsonOutput {
+					continue
+				}
+				if verbose {
+					fmt.Printf("  %s %s (%s)\n", greenCheck("PASS"), r.Name, r.Duration)
+				} else {
+					fmt.Printf("  %s %s\n", greenCheck("PASS"), r.Name)
+				}
+				continue
+			}
+
 			failed++
-			fmt.Printf("  %s %s\n", redX("FAIL"), r.Name)
-			if r.Error != "" {
-				fmt.Printf("       %s\n", r.Error)
+			if !jsonOutput {
+				fmt.Printf("  %s %s\n", redX("FAIL"), r.Name)
+				if r.Error != "" {
+					fmt.Printf("       %s\n", r.Error)
+				}
 			}
 			if failFast {
-				break
+				break files
 			}
 		}
 	}
 
+	if jsonOutput {
+		if results == nil {
+			results = []testFileResult{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(map[string]interface{}{
+			"passed": passed,
+			"failed": failed,
+			"tests":  results,
+		}); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d test(s) failed", failed)
+		}
+		return nil
+	}
+
+	if !found {
+		printWarning("No test blocks found in " + path)
+		return nil
+	}
+	if len(results) == 0 {
+		printWarning("No tests matched pattern: " + pattern)
+		return nil
+	}
+
 	// Summary
 	fmt.Println()
 	total := passed + failed
@@ -257,47 +288,67 @@ func runRun(cmd *cobra.Command, args []string) error {
 	port, _ := cmd.Flags().GetUint16("port")
 	useBytecode, _ := cmd.Flags().GetBool("bytecode")
 	useInterpreter, _ := cmd.Flags().GetBool("interpret")
+	logFormat, err := logFormatFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Bundles from glyph build or glyph compile are
+	// detected by their contents, whatever the file is called
+	if data, err := os.ReadFile(filePath); err == nil && bundle.IsBundle(data) {
+		b, err := bundle.Read(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filePath, err)
+		}
+		printInfo(fmt.Sprintf("Starting server for bundle %s (built by glyph %s)...", filePath, b.Manifest.CompilerVersion))
+		srv, wsServer, err := startBundleServer(b, int(port), logFormat, metricsEnabled(cmd))
+		if err != nil {
+			return err
+		}
+		return waitForShutdown(srv, wsServer)
+	}
 
 	// Check if file is bytecode based on extension or flag
 	if !useBytecode {
 		useBytecode = filepath.Ext(filePath) == ".glyphc"
 	}
 
+	// Bytecode that is not a bundle holds one route body without the
+	// method and path it serves, so there is nothing to route requests to
 	if useBytecode {
-		printInfo(fmt.Sprintf("Running bytecode %s...", filePath))
-
-		// Read bytecode file
 		bytecode, err := os.ReadFile(filePath)
 		if err != nil {
 			return fmt.Errorf("failed to read bytecode file: %w", err)
 		}
-
-		// Execute bytecode using VM
-		start := time.Now()
-		vmInstance := vm.NewVM()
-		result, err := vmInstance.Execute(bytecode)
-		execTime := time.Since(start)
-
-		if err != nil {
-			return fmt.Errorf("bytecode execution failed: %w", err)
+		if err := vm.Verify(bytecode); err != nil {
+			return fmt.Errorf("%s failed verification: %w", filePath, err)
 		}
-
-		printSuccess("Bytecode executed successfully")
-		printInfo(fmt.Sprintf("Execution time: %s", execTime))
-		printInfo(fmt.Sprintf("Result: %v", result))
-
-		return nil
+		return fmt.Errorf("%s holds the bytecode of a single route without a route manifest; recompile it with glyph compile or glyph build to serve it", filePath)
 	}
 
 	// Running source file - use shared server startup logic
 	printInfo(fmt.Sprintf("Starting server for %s...", filePath))
-	srv, err := startServer(filePath, int(port), useInterpreter)
+	srv, wsServer, err := startServer(filePath, int(port), useInterpreter, logFormat, metricsEnabled(cmd))
 	if err != nil {
 		return err
 	}
 
 	// Setup graceful shutdown
-	return waitForShutdown(srv)
+	return waitForShutdown(srv, wsServer)
+}
+
+// logFormatFlag reads and validates the --log-format flag
+func logFormatFlag(cmd *cobra.Command) (server.LogFormat, error) {
+	value, _ := cmd.Flags().GetString("log-format")
+	return server.ParseLogFormat(value)
+}
+
+// watchExtsOrDefault returns exts, or the default watched extensions when empty
+func watchExtsOrDefault(exts []string) []string {
+	if len(exts) == 0 {
+		return defaultWatchExts
+	}
+	return exts
 }
 
 // runDev handles the dev command
@@ -306,6 +357,10 @@ func runDev(cmd *cobra.Command, args []string) error {
 	port, _ := cmd.Flags().GetUint16("port")
 	watch, _ := cmd.Flags().GetBool("watch")
 	openBrowser, _ := cmd.Flags().GetBool("open")
+	logFormat, err := logFormatFlag(cmd)
+	if err != nil {
+		return err
+	}
 
 	printInfo(fmt.Sprintf("Starting development server on port %d...", port))
 
@@ -315,10 +370,21 @@ func runDev(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get absolute path: %w", err)
 	}
 
+	watchDirs, _ := cmd.Flags().GetStringSlice("watch-dir")
+	for i, dir := range watchDirs {
+		if watchDirs[i], err = filepath.Abs(dir); err != nil {
+			return fmt.Errorf("invalid watch directory %s: %w", dir, err)
+		}
+	}
+	watchExts, _ := cmd.Flags().GetStringSlice("watch-ext")
+
 	// Create hot reload manager
 	manager := &hotReloadManager{
+		watchDirs:       watchDirs,
+		watchExts:       watchExts,
 		filePath:        absPath,
 		port:            int(port),
+		logFormat:       logFormat,
 		liveReloadConns: make(map[*liveReloadConn]bool),
 	}
 
@@ -329,8 +395,11 @@ func runDev(cmd *cobra.Command, args []string) error {
 
 	// Setup file watching if enabled
 	if watch {
-		printInfo(fmt.Sprintf("Watching %s for changes...", absPath))
-		go manager.watchForChanges()
+		if err := manager.startWatching(); err != nil {
+			return err
+		}
+		printInfo(fmt.Sprintf("Watching %s for changes to %s files...",
+			strings.Join(manager.watchRoots(), ", "), strings.Join(watchExtsOrDefault(manager.watchExts), ", ")))
 	}
 
 	// Open browser if requested
@@ -445,21 +514,19 @@ func runExec(cmd *cobra.Command, args []string) error {
 	cmdName := args[1]
 	cmdArgs := args[2:]
 
-	// Read source file
-	source, err := os.ReadFile(filePath)
+	// Read and parse the source file and everything it imports
+	program, err := loadProgram(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return err
 	}
-
-	// Parse source
-	module, err := parseSource(string(source))
-	if err != nil {
-		return fmt.Errorf("parse error: %w", err)
+	if _, err := programDatabase(); err != nil {
+		return err
 	}
 
 	// Create interpreter and load module
 	interp := newConfiguredInterpreter()
-	if err := interp.LoadModule(*module); err != nil {
+	program.Prime(interp.GetModuleResolver())
+	if err := interp.LoadModuleWithPath(*program.Module, filepath.Dir(program.Entry)); err != nil {
 		return fmt.Errorf("failed to load module: %w", err)
 	}
 
@@ -478,8 +545,16 @@ func runExec(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("command '%s' not found. Available commands: %v", cmdName, available)
 	}
 
+	if wantsCommandHelp(cmdArgs) {
+		fmt.Print(commandUsage(glyphCmd))
+		return nil
+	}
+
 	// Parse command arguments
-	argsMap := parseCommandArgs(cmdArgs, glyphCmd.Params)
+	argsMap, err := parseCommandArgs(cmdArgs, glyphCmd.Params)
+	if err != nil {
+		return fmt.Errorf("%w\n\n%s", err, commandUsage(glyphCmd))
+	}
 
 	// Execute command
 	start := time.Now()
@@ -504,49 +579,6 @@ func runExec(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// parseCommandArgs parses CLI arguments into a map based on command parameters
-func parseCommandArgs(args []string, params []ast.CommandParam) map[string]interface{} {
-	result := make(map[string]interface{})
-
-	positionalIdx := 0
-	i := 0
-	for i < len(args) {
-		arg := args[i]
-
-		// Check for flag arguments (--name value or --name=value)
-		if len(arg) > 2 && arg[:2] == "--" {
-			flagPart := arg[2:]
-			var name, value string
-
-			if eqIdx := indexOf(flagPart, '='); eqIdx != -1 {
-				name = flagPart[:eqIdx]
-				value = flagPart[eqIdx+1:]
-			} else {
-				name = flagPart
-				if i+1 < len(args) {
-					i++
-					value = args[i]
-				}
-			}
-
-			result[name] = value
-		} else {
-			// Positional argument
-			for _, param := range params {
-				if !param.IsFlag && positionalIdx == 0 {
-					result[param.Name] = arg
-					positionalIdx++
-					break
-				}
-			}
-			positionalIdx++
-		}
-		i++
-	}
-
-	return result
-}
-
 // indexOf returns the index of char c in string s, or -1 if not found
 func indexOf(s string, c byte) int {
 	for i := 0; i < len(s); i++ {
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -110,6 +111,94 @@ func expandDirectory(dirPath, outputDir string) error {
 	})
 }
 
+// runFmt handles the fmt command - rewrites source files in canonical form
+func runFmt(cmd *cobra.Command, args []string) error {
+	check, _ := cmd.Flags().GetBool("check")
+	write, _ := cmd.Flags().GetBool("write")
+	if check && write {
+		return fmt.Errorf("--check and --write cannot be used together")
+	}
+
+	var files []string
+	for _, arg := range args {
+		found, err := collectFmtFiles(arg)
+		if err != nil {
+			return err
+		}
+		files = append(files, found...)
+	}
+
+	var unformatted []string
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+
+		mode := formatter.Compact
+		if filepath.Ext(file) == ".glyphx" {
+			mode = formatter.Expanded
+		}
+		formatted, err := formatter.FormatSource(string(source), mode)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		switch {
+		case check:
+			if formatted != string(source) {
+				unformatted = append(unformatted, file)
+				fmt.Fprintln(cmd.OutOrStdout(), file)
+			}
+		case write:
+			if formatted == string(source) {
+				continue
+			}
+			if err := os.WriteFile(file, []byte(formatted), 0600); err != nil {
+				return fmt.Errorf("failed to write %s: %w", file, err)
+			}
+			printSuccess(fmt.Sprintf("Formatted %s", file))
+		default:
+			fmt.Fprint(cmd.OutOrStdout(), formatted)
+		}
+	}
+
+	if len(unformatted) > 0 {
+		return fmt.Errorf("%d file(s) need formatting", len(unformatted))
+	}
+	return nil
+}
+
+// collectFmtFiles returns path itself, or every .glyph and .glyphx file
+// below it when path is a directory. Hidden and dependency directories are skipped.
+func collectFmtFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access path: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != path && (skippedWatchDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(p); ext == ".glyph" || ext == ".glyphx" {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
 // runCompact handles the compact command - converts human-readable .glyphx to compact .glyph
 func runCompact(cmd *cobra.Command, args []string) error {
 	filePath := args[0]
@@ -208,23 +297,26 @@ func runOpenAPI(cmd *cobra.Command, args []string) error {
 	title, _ := cmd.Flags().GetString("title")
 	apiVersion, _ := cmd.Flags().GetString("api-version")
 
-	// Read source file
-	source, err := os.ReadFile(filePath)
+	// Parse the file and everything it imports, so imported types resolve
+	program, err := loadProgram(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
-	}
-
-	// Parse source
-	module, err := parseSource(string(source))
-	if err != nil {
-		return fmt.Errorf("parse error: %w", err)
+		return err
 	}
+	module := program.Module
 
 	// Default title from filename
 	if title == "" {
-		base := filepath.Base(filePath)
-		ext := filepath.Ext(base)
-		title = base[:len(base)-len(ext)] + " API"
+		title = defaultAPITitle(filePath)
+	}
+
+	// Without --format, the output file's extension picks the format
+	if output != "" && !cmd.Flags().Changed("format") {
+		switch strings.ToLower(filepath.Ext(output)) {
+		case ".json":
+			format = "json"
+		case ".yaml", ".yml":
+			format = "yaml"
+		}
 	}
 
 	// Generate spec
@@ -249,6 +341,12 @@ func runOpenAPI(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// defaultAPITitle derives an API title from the source file name
+func defaultAPITitle(filePath string) string {
+	base := filepath.Base(filePath)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + " API"
+}
+
 // runDocs handles the docs command
 func runDocs(cmd *cobra.Command, args []string) error {
 	filePath := args[0]
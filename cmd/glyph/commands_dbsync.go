@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/database"
+	"github.com/spf13/cobra"
+)
+
+// tableCreator is implemented by the databases that can create tables from
+// a column schema
+type tableCreator interface {
+	TableExists(ctx context.Context, table string) (bool, error)
+	CreateTable(ctx context.Context, table string, schema map[string]string) error
+}
+
+// runDBSync creates a table for every type declared in a GLYPH file whose
+// table does not exist yet. Existing tables are left untouched.
+func runDBSync(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
+	source, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	module, err := parseSource(string(source))
+	if err != nil {
+		return err
+	}
+
+	db, err := connectDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	creator, ok := db.(tableCreator)
+	if !ok {
+		return fmt.Errorf("db:sync does not support %s databases", db.Driver())
+	}
+
+	ctx := context.Background()
+	out := cmd.OutOrStdout()
+	created := 0
+	for _, item := range module.Items {
+		typeDef, ok := item.(*ast.TypeDef)
+		if !ok {
+			continue
+		}
+		columns, err := database.ColumnsForType(*typeDef, db.Driver())
+		if err != nil {
+			printWarning(fmt.Sprintf("Skipping %s: %v", typeDef.Name, err))
+			continue
+		}
+
+		table := database.TableName(typeDef.Name)
+		exists, err := creator.TableExists(ctx, table)
+		if err != nil {
+			return fmt.Errorf("failed to check table %s: %w", table, err)
+		}
+		if exists {
+			continue
+		}
+
+		schema := make(map[string]string, len(columns))
+		for _, col := range columns {
+			schema[col.Name] = col.Type
+		}
+		if err := creator.CreateTable(ctx, table, schema); err != nil {
+			return fmt.Errorf("failed to create table %s: %w", table, err)
+		}
+		fmt.Fprintf(out, "Created %s from %s\n", table, typeDef.Name)
+		created++
+	}
+
+	if created == 0 {
+		printSuccess("All tables exist")
+	} else {
+		printSuccess(fmt.Sprintf("Created %d table(s)", created))
+	}
+	return nil
+}
@@ -2,13 +2,16 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/glyphlang/glyph/pkg/ast"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/glyphlang/glyph/pkg/analysis"
 	glyphcontext "github.com/glyphlang/glyph/pkg/context"
+	"github.com/glyphlang/glyph/pkg/parser"
 	"github.com/glyphlang/glyph/pkg/validate"
 	"github.com/spf13/cobra"
 )
@@ -52,17 +55,7 @@ func runListCommands(cmd *cobra.Command, args []string) error {
 		for name, cmd := range commands {
 			var params []string
 			for _, p := range cmd.Params {
-				paramStr := p.Name
-				if p.Type != nil {
-					paramStr += ": " + typeToString(p.Type)
-				}
-				if p.Required {
-					paramStr += "!"
-				}
-				if p.IsFlag {
-					paramStr = "--" + paramStr
-				}
-				params = append(params, paramStr)
+				params = append(params, formatCommandParam(p))
 			}
 			fmt.Printf("  @ command %s %s\n", name, strings.Join(params, " "))
 			if cmd.Description != "" {
@@ -420,6 +413,85 @@ func runValidate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runCheck analyzes a GLYPH file and everything it imports without running it
+func runCheck(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	strict, _ := cmd.Flags().GetBool("strict")
+
+	diags := checkFile(filePath)
+	errorCount := 0
+	for i := range diags {
+		if strict && diags[i].Severity == analysis.SeverityWarning {
+			diags[i].Severity = analysis.SeverityError
+		}
+		if diags[i].Severity == analysis.SeverityError {
+			errorCount++
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	if jsonOut {
+		if diags == nil {
+			diags = []analysis.Diagnostic{}
+		}
+		output, err := json.MarshalIndent(diags, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to serialize diagnostics: %w", err)
+		}
+		fmt.Fprintln(out, string(output))
+	} else {
+		for _, d := range diags {
+			fmt.Fprintln(out, d.String())
+		}
+	}
+
+	if errorCount > 0 {
+		return fmt.Errorf("%d error(s) found", errorCount)
+	}
+	if !jsonOut && len(diags) == 0 {
+		printSuccess(fmt.Sprintf("No problems found in %s", filePath))
+	}
+	return nil
+}
+
+// checkFile loads filePath with its imports and runs the analysis pass.
+// Load failures are returned as a single diagnostic.
+func checkFile(filePath string) []analysis.Diagnostic {
+	program, err := loadProgram(filePath)
+	if err != nil {
+		diag := analysis.Diagnostic{
+			File:     filePath,
+			Severity: analysis.SeverityError,
+			Message:  err.Error(),
+		}
+		var parseErr *parser.ParseError
+		var lexErr *parser.LexError
+		if errors.As(err, &parseErr) {
+			diag.Line, diag.Column, diag.Message = parseErr.Line, parseErr.Column, parseErr.Message
+		} else if errors.As(err, &lexErr) {
+			diag.Line, diag.Column, diag.Message = lexErr.Line, lexErr.Column, lexErr.Message
+		}
+		return []analysis.Diagnostic{diag}
+	}
+
+	// Report paths the way the user named the entry file
+	entryDir := filepath.Dir(filePath)
+	return analysis.Check(program.Module, analysis.Options{
+		File: filePath,
+		FileOf: func(item ast.Item) string {
+			file := program.FileOf(item)
+			if file == program.Entry {
+				return filePath
+			}
+			if rel, err := filepath.Rel(filepath.Dir(program.Entry), file); err == nil {
+				return filepath.Join(entryDir, rel)
+			}
+			return file
+		},
+	})
+}
+
 // validateFile validates a single file and returns the result
 func validateFile(filePath string) *validate.ValidationResult {
 	source, err := os.ReadFile(filePath)
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/glyphlang/glyph/pkg/database"
+	"github.com/spf13/cobra"
+)
+
+// connectDatabase connects to the database named by the --database flag,
+// falling back to DATABASE_URL
+func connectDatabase(cmd *cobra.Command) (database.Database, error) {
+	connStr, _ := cmd.Flags().GetString("database")
+	if connStr == "" {
+		connStr = os.Getenv("DATABASE_URL")
+	}
+	if connStr == "" {
+		return nil, fmt.Errorf("no database given: use --database or set DATABASE_URL")
+	}
+
+	config, err := database.ParseConnectionString(connStr)
+	if err != nil {
+		return nil, err
+	}
+	db, err := database.NewDatabase(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Connect(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", config.SafeConnectionString(), err)
+	}
+	return db, nil
+}
+
+// runMigrate applies pending migrations from a directory, or rolls back the
+// most recent ones with --down
+func runMigrate(cmd *cobra.Command, args []string) error {
+	dir := args[0]
+	down, _ := cmd.Flags().GetInt("down")
+
+	migrations, err := database.LoadMigrations(dir)
+	if err != nil {
+		return err
+	}
+
+	db, err := connectDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	migrator := database.NewMigrator(db, migrations)
+	out := cmd.OutOrStdout()
+
+	if cmd.Flags().Changed("down") {
+		rolledBack, err := migrator.Down(ctx, down)
+		for _, m := range rolledBack {
+			fmt.Fprintf(out, "Rolled back %03d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		printSuccess(fmt.Sprintf("Rolled back %d migration(s)", len(rolledBack)))
+		return nil
+	}
+
+	applied, err := migrator.Up(ctx)
+	for _, m := range applied {
+		fmt.Fprintf(out, "Applied %03d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		printSuccess("Database is up to date")
+	} else {
+		printSuccess(fmt.Sprintf("Applied %d migration(s)", len(applied)))
+	}
+	return nil
+}
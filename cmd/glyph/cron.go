@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/interpreter"
+	"github.com/glyphlang/glyph/pkg/scheduler"
+	"github.com/spf13/cobra"
+)
+
+// startCronScheduler schedules the program's cron tasks and starts running
+// them. Tasks run on an interpreter of their own, so they are scheduled in
+// compiled mode too. It returns nil if the program has no cron tasks.
+func startCronScheduler(program *interpreter.Program) (*scheduler.Scheduler, error) {
+	hasTasks := false
+	for _, item := range program.Module.Items {
+		if _, ok := item.(*ast.CronTask); ok {
+			hasTasks = true
+			break
+		}
+	}
+	if !hasTasks {
+		return nil, nil
+	}
+
+	interp := newConfiguredInterpreter()
+	program.Prime(interp.GetModuleResolver())
+	if err := interp.LoadModuleWithPath(*program.Module, filepath.Dir(program.Entry)); err != nil {
+		return nil, fmt.Errorf("failed to load module for cron tasks: %w", err)
+	}
+
+	sched := scheduler.New()
+	for _, task := range interp.GetCronTasks() {
+		task := task
+		name := interpreter.CronTaskName(&task)
+
+		// Schedules are evaluated in UTC unless the task names a timezone
+		loc := time.UTC
+		if task.Timezone != "" {
+			var err error
+			if loc, err = time.LoadLocation(task.Timezone); err != nil {
+				return nil, fmt.Errorf("cron task %s: unknown timezone %q", name, task.Timezone)
+			}
+		}
+
+		overlap, err := scheduler.ParseOverlap(task.Overlap)
+		if err != nil {
+			return nil, fmt.Errorf("cron task %s: %w", name, err)
+		}
+		err = sched.AddWithOverlap(name, task.Schedule, loc, overlap, func(ctx context.Context) {
+			runCronTask(interp, &task, name)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cron task %s: %w", name, err)
+		}
+		next, _ := sched.Next(name)
+		printInfo(fmt.Sprintf("Cron task: %s (%s), next run %s", name, task.Schedule, next.Format(time.RFC3339)))
+	}
+
+	sched.Start()
+	return sched, nil
+}
+
+// runCronTask executes a cron task, retrying failures up to task.Retries
+// times, and logs the outcome. It returns the last error if every attempt
+// failed.
+func runCronTask(interp *interpreter.Interpreter, task *ast.CronTask, name string) error {
+	attempts := task.Retries + 1
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		start := time.Now()
+		var result interface{}
+		result, err = executeCronTask(interp, task)
+		if err == nil {
+			printInfo(fmt.Sprintf("Cron task %s completed in %s: %v", name, time.Since(start), result))
+			return nil
+		}
+		printError(fmt.Errorf("cron task %s failed (attempt %d/%d): %w", name, attempt, attempts, err))
+	}
+	return fmt.Errorf("cron task %s failed: %w", name, err)
+}
+
+// executeCronTask runs a task, converting a panic into an error so a
+// failing task cannot stop the server
+func executeCronTask(interp *interpreter.Interpreter, task *ast.CronTask) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return interp.ExecuteCronTask(task)
+}
+
+// runCronRun runs one cron task of a file immediately, for example in CI.
+// It fails if the task fails on every attempt.
+func runCronRun(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
+	taskName := args[1]
+
+	// Read and parse the source file and everything it imports
+	program, err := loadProgram(filePath)
+	if err != nil {
+		return err
+	}
+	if err := checkDatabase(program.Module); err != nil {
+		return err
+	}
+
+	interp := newConfiguredInterpreter()
+	program.Prime(interp.GetModuleResolver())
+	if err := interp.LoadModuleWithPath(*program.Module, filepath.Dir(program.Entry)); err != nil {
+		return fmt.Errorf("failed to load module: %w", err)
+	}
+
+	tasks := interp.GetCronTasks()
+	var available []string
+	for _, task := range tasks {
+		task := task
+		name := interpreter.CronTaskName(&task)
+		if name == taskName {
+			return runCronTask(interp, &task, name)
+		}
+		available = append(available, name)
+	}
+	if len(available) == 0 {
+		return fmt.Errorf("no cron tasks found in %s", filePath)
+	}
+	return fmt.Errorf("cron task '%s' not found. Available cron tasks: %v", taskName, available)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/interpreter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadCronProgram writes source to a temporary file and loads it
+func loadCronProgram(t *testing.T, source string) *interpreter.Program {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "main.glyph")
+	require.NoError(t, os.WriteFile(path, []byte(source), 0644))
+	program, err := loadProgram(path)
+	require.NoError(t, err)
+	return program
+}
+
+func TestStartCronScheduler(t *testing.T) {
+	program := loadCronProgram(t, `* "*/5 * * * *" health_check {
+  > {status: "healthy"}
+}
+
+* "0 0 * * *" {
+  > "nightly"
+}
+
+@ GET /hello {
+  > {text: "hi"}
+}
+`)
+
+	sched, err := startCronScheduler(program)
+	require.NoError(t, err)
+	require.NotNil(t, sched)
+	defer sched.Stop()
+
+	next, ok := sched.Next("health_check")
+	require.True(t, ok)
+	assert.Zero(t, next.Minute()%5)
+	_, ok = sched.Next(`"0 0 * * *"`)
+	assert.True(t, ok, "anonymous tasks are named by their schedule")
+}
+
+func TestStartCronScheduler_NoTasks(t *testing.T) {
+	sched, err := startCronScheduler(loadCronProgram(t, validSource))
+	require.NoError(t, err)
+	assert.Nil(t, sched)
+	sched.Stop() // Safe on nil
+}
+
+func TestStartCronScheduler_InvalidSchedule(t *testing.T) {
+	_, err := startCronScheduler(loadCronProgram(t, `* "every day" cleanup {
+  > "done"
+}
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cron task cleanup")
+	assert.Contains(t, err.Error(), "expected 5 fields")
+}
+
+func TestRunCronTask(t *testing.T) {
+	program := loadCronProgram(t, `* "0 0 * * *" nightly {
+  > {done: true}
+}
+`)
+	interp := newConfiguredInterpreter()
+	require.NoError(t, interp.LoadModule(*program.Module))
+	tasks := interp.GetCronTasks()
+	require.Len(t, tasks, 1)
+
+	result, err := executeCronTask(interp, &tasks[0])
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"done": true}, result)
+	runCronTask(interp, &tasks[0], "nightly")
+}
+
+func TestRunCronRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.glyph")
+	require.NoError(t, os.WriteFile(path, []byte(`* "0 0 * * *" nightly {
+  > {done: true}
+}
+
+* "0 * * * *" broken {
+  + retries(1)
+  $ x = missing.field
+  > x
+}
+`), 0644))
+
+	assert.NoError(t, runCronRun(nil, []string{path, "nightly"}))
+
+	err := runCronRun(nil, []string{path, "broken"})
+	assert.ErrorContains(t, err, "cron task broken failed")
+
+	err = runCronRun(nil, []string{path, "weekly"})
+	assert.ErrorContains(t, err, "cron task 'weekly' not found")
+	assert.ErrorContains(t, err, "[nightly broken]")
+}
+
+func TestStartCronScheduler_Overlap(t *testing.T) {
+	sched, err := startCronScheduler(loadCronProgram(t, `* "*/5 * * * *" sync {
+  + overlap(queue)
+  > "synced"
+}
+`))
+	require.NoError(t, err)
+	defer sched.Stop()
+	_, ok := sched.Next("sync")
+	assert.True(t, ok)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/database"
+)
+
+var (
+	// databaseURL is the --database flag of run, dev, exec and cron run
+	databaseURL string
+
+	sharedDatabase     *database.InterpreterDatabase
+	sharedDatabaseErr  error
+	sharedDatabaseOnce sync.Once
+)
+
+// programDatabase returns the process-wide database injected as
+// `% db: Database`, connected to the --database flag or DATABASE_URL. It
+// returns nil when neither is set, and interpreters fall back to an
+// in-memory MockDatabase.
+func programDatabase() (*database.InterpreterDatabase, error) {
+	sharedDatabaseOnce.Do(func() {
+		connStr := databaseURL
+		if connStr == "" {
+			connStr = os.Getenv("DATABASE_URL")
+		}
+		if connStr == "" {
+			return
+		}
+		config, err := database.ParseConnectionString(connStr)
+		if err != nil {
+			sharedDatabaseErr = fmt.Errorf("invalid database URL: %w", err)
+			return
+		}
+		handler, err := database.NewHandlerFromString(connStr)
+		if err != nil {
+			sharedDatabaseErr = fmt.Errorf("database %s: %w", config, err)
+			return
+		}
+		sharedDatabase = database.NewInterpreterDatabase(handler)
+	})
+	return sharedDatabase, sharedDatabaseErr
+}
+
+// checkDatabase connects the database if the module injects one, and warns
+// when no URL is configured so data only lives in memory
+func checkDatabase(module *ast.Module) error {
+	if !usesDatabase(module) {
+		return nil
+	}
+	db, err := programDatabase()
+	if err != nil {
+		return err
+	}
+	if db == nil {
+		printWarning("No DATABASE_URL set, using an in-memory mock database")
+	}
+	return nil
+}
+
+// usesDatabase reports whether any route, handler, resolver or worker injects
+// a Database
+func usesDatabase(module *ast.Module) bool {
+	for _, item := range module.Items {
+		var injections []ast.Injection
+		switch item := item.(type) {
+		case *ast.Route:
+			injections = item.Injections
+		case *ast.CronTask:
+			injections = item.Injections
+		case *ast.EventHandler:
+			injections = item.Injections
+		case *ast.QueueWorker:
+			injections = item.Injections
+		case *ast.GRPCHandler:
+			injections = item.Injections
+		case *ast.GraphQLResolver:
+			injections = item.Injections
+		}
+		for _, injection := range injections {
+			if isDatabaseType(injection.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isDatabaseType(t ast.Type) bool {
+	if _, ok := t.(ast.DatabaseType); ok {
+		return true
+	}
+	named, ok := t.(ast.NamedType)
+	return ok && named.Name == "Database"
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/interpreter"
+	"github.com/glyphlang/glyph/pkg/server"
+)
+
+// errorScopeKey is the request context key of a request's errorScope
+type errorScopeKey struct{}
+
+// errorScope is how the error responses of a request are written. onError
+// is the program's @ onerror handler, or nil for the built-in responses.
+// While that handler runs, err holds the error it answers and an error of
+// its own sets failed instead of being sent, so the built-in response is
+// sent in its place and the handler cannot loop.
+type errorScope struct {
+	onError server.RouteHandler
+	err     map[string]interface{}
+	failed  bool
+}
+
+// requestErrorScope returns the errorScope of ctx's request, or nil
+func requestErrorScope(ctx *server.Context) *errorScope {
+	scope, _ := ctx.Request.Context().Value(errorScopeKey{}).(*errorScope)
+	return scope
+}
+
+// routeError returns the error the @ onerror handler running for ctx
+// answers, or nil outside the handler
+func routeError(ctx *server.Context) map[string]interface{} {
+	if scope := requestErrorScope(ctx); scope != nil {
+		return scope.err
+	}
+	return nil
+}
+
+// programErrorHandlers returns the @ notfound and @ onerror handlers of
+// module by kind. A program may define at most one of each.
+func programErrorHandlers(module *ast.Module) (map[ast.ErrorHandlerKind]*ast.ErrorHandler, error) {
+	handlers := make(map[ast.ErrorHandlerKind]*ast.ErrorHandler)
+	for _, item := range module.Items {
+		if h, ok := item.(*ast.ErrorHandler); ok {
+			if _, exists := handlers[h.Kind]; exists {
+				return nil, fmt.Errorf("duplicate @ %s handler", h.Kind)
+			}
+			handlers[h.Kind] = h
+		}
+	}
+	return handlers, nil
+}
+
+// setErrorHandler makes handler answer on router for errors of kind
+func setErrorHandler(router *server.Router, kind ast.ErrorHandlerKind, handler server.RouteHandler) {
+	switch kind {
+	case ast.NotFoundHandler:
+		router.SetNotFound(handler)
+	case ast.OnErrorHandler:
+		router.SetErrorHandler(handler)
+	}
+}
+
+// registerErrorHandlers makes the @ notfound and @ onerror handlers of
+// module answer on router, run by interp
+func registerErrorHandlers(router *server.Router, module *ast.Module, interp *interpreter.Interpreter) error {
+	handlers, err := programErrorHandlers(module)
+	if err != nil {
+		return err
+	}
+	for kind, h := range handlers {
+		setErrorHandler(router, kind, createErrorRouteHandler(h, interp))
+	}
+	return nil
+}
+
+// errorHandlerRoute returns the route an error handler runs as. Its path
+// names the handler in metrics and traces.
+func errorHandlerRoute(h *ast.ErrorHandler) *ast.Route {
+	return &ast.Route{Path: "@" + string(h.Kind), Method: ast.Get, Body: h.Body}
+}
+
+// createErrorRouteHandler creates the handler of an error handler run by
+// the interpreter, which matches the route's path against the request's
+func createErrorRouteHandler(h *ast.ErrorHandler, interp *interpreter.Interpreter) server.RouteHandler {
+	return func(ctx *server.Context) error {
+		route := errorHandlerRoute(h)
+		route.Path = ctx.Request.URL.Path
+		return createRouteHandler(route, interp)(ctx)
+	}
+}
+
+// errorStatusWriter sends status in place of the 200 an error handler
+// answers with unless it sets another status
+type errorStatusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader sends the error's status for a 200
+func (w *errorStatusWriter) WriteHeader(statusCode int) {
+	if statusCode == http.StatusOK {
+		statusCode = w.status
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write sends the error's status first if no status was sent
+func (w *errorStatusWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// runErrorHandler runs handler in place of an error response with status
+func runErrorHandler(ctx *server.Context, handler server.RouteHandler, status int) error {
+	hctx := *ctx
+	hctx.ResponseWriter = &errorStatusWriter{ResponseWriter: ctx.ResponseWriter, status: status}
+	err := handler(&hctx)
+	ctx.StatusCode = hctx.StatusCode
+	if ctx.StatusCode == http.StatusOK {
+		ctx.StatusCode = status
+	}
+	return err
+}
+
+// writeErrorResponse sends an error response with status and message. body
+// is the built-in JSON body, which is sent as an HTML page to clients that
+// prefer one. The program's @ onerror handler, if it has one, answers in
+// its place with err set to the status, message and path of the error and
+// the other fields of body. Should that handler fail, the built-in response
+// is sent.
+func writeErrorResponse(ctx *server.Context, status int, message string, body map[string]interface{}) error {
+	scope := requestErrorScope(ctx)
+	if scope != nil && scope.err != nil {
+		// Inside the onerror handler, whose caller answers instead
+		scope.failed = true
+		return nil
+	}
+
+	if scope != nil && scope.onError != nil {
+		routeErr := make(map[string]interface{}, len(body)+3)
+		for k, v := range body {
+			if k != "error" {
+				routeErr[k] = v
+			}
+		}
+		routeErr["status"] = int64(status)
+		routeErr["message"] = message
+		routeErr["path"] = ctx.Request.URL.Path
+
+		handlerScope := &errorScope{err: routeErr}
+		hctx := *ctx
+		hctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), errorScopeKey{}, handlerScope))
+		err := runErrorHandler(&hctx, scope.onError, status)
+		if err == nil && !handlerScope.failed {
+			ctx.StatusCode = hctx.StatusCode
+			return nil
+		}
+		if err != nil {
+			printError(fmt.Errorf("@ onerror handler failed: %w", err))
+		}
+		if sw, ok := ctx.ResponseWriter.(*server.StatusWriter); ok && sw.WroteHeader() {
+			return nil
+		}
+	}
+
+	ctx.StatusCode = status
+	header := ctx.ResponseWriter.Header()
+	if server.PrefersHTML(ctx.Request.Header.Get("Accept")) {
+		header.Set("Content-Type", "text/html; charset=utf-8")
+		ctx.ResponseWriter.WriteHeader(status)
+		_, err := fmt.Fprintf(ctx.ResponseWriter, errorPage, status, http.StatusText(status), html.EscapeString(message))
+		return err
+	}
+	header.Set("Content-Type", "application/json")
+	ctx.ResponseWriter.WriteHeader(status)
+	return json.NewEncoder(ctx.ResponseWriter).Encode(body)
+}
+
+// errorPage is the built-in error response for browsers, filled in with
+// the status code, its text and the message
+const errorPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>%[1]d %[2]s</title></head>
+<body>
+<h1>%[1]d %[2]s</h1>
+<p>%[3]s</p>
+</body>
+</html>
+`
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const errorHandlersSource = `: User {
+  name: str!
+}
+
+@ GET /boom {
+  $ items = [1]
+  > items[5]
+}
+
+@ POST /users {
+  < input: User
+  > {name: input.name}
+}
+
+@ notfound {
+  > {error: "nothing at " + request.path}
+}
+
+@ onerror {
+  if err.status >= 500 {
+    > {error: "Something went wrong", status: err.status}
+  }
+  > {error: err.message, status: err.status, fields: err.fields}
+}
+`
+
+// TestCustomNotFound checks that @ notfound answers unmatched paths with a
+// 404 in both execution modes
+func TestCustomNotFound(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, errorHandlersSource, mode.interpreted)
+
+			status, body := getBody(t, srv.URL+"/missing")
+			assert.Equal(t, http.StatusNotFound, status)
+			assert.JSONEq(t, `{"error": "nothing at /missing"}`, body)
+		})
+	}
+}
+
+// TestOnErrorMasksInternalMessages checks that @ onerror shapes the error
+// responses of both execution modes, hiding the message of server errors
+func TestOnErrorMasksInternalMessages(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, errorHandlersSource, mode.interpreted)
+
+			status, body := getBody(t, srv.URL+"/boom")
+			assert.Equal(t, http.StatusInternalServerError, status)
+			assert.JSONEq(t, `{"error": "Something went wrong", "status": 500}`, body)
+
+			status, decoded := postJSON(t, srv, "/users", `{}`)
+			assert.Equal(t, http.StatusBadRequest, status)
+			assert.Equal(t, map[string]interface{}{
+				"error":  "input validation failed",
+				"status": float64(400),
+				"fields": []interface{}{
+					map[string]interface{}{"field": "name", "message": "missing required field"},
+				},
+			}, decoded)
+		})
+	}
+}
+
+// TestFailingOnErrorFallsBackToBuiltIn checks that an error inside @ onerror
+// sends the built-in response instead of running the handler again
+func TestFailingOnErrorFallsBackToBuiltIn(t *testing.T) {
+	source := `@ GET /boom {
+  $ items = [1]
+  > items[5]
+}
+
+@ onerror {
+  $ items = []
+  > items[err.status]
+}
+`
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, source, mode.interpreted)
+
+			status, body := getBody(t, srv.URL+"/boom")
+			assert.Equal(t, http.StatusInternalServerError, status)
+			assert.JSONEq(t, `{"error": "Internal server error"}`, body)
+
+			status, body = getBody(t, srv.URL+"/missing")
+			assert.Equal(t, http.StatusNotFound, status)
+			assert.JSONEq(t, `{"error": "Route not found", "path": "/missing"}`, body)
+		})
+	}
+}
+
+// TestBuiltInErrorPrefersHTML checks that the built-in error responses are
+// HTML pages for clients that prefer HTML
+func TestBuiltInErrorPrefersHTML(t *testing.T) {
+	srv := startInputValidationServer(t, "@ GET /ok {\n  > {ok: true}\n}\n", true)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/missing", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body := readBody(t, resp)
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, body, "<h1>404 Not Found</h1>")
+	assert.Contains(t, body, "<p>Route not found</p>")
+}
+
+// TestMethodNotAllowed checks that a path requested with a method it has no
+// route for is answered with a 405 listing its methods, unlike an unknown
+// path
+func TestMethodNotAllowed(t *testing.T) {
+	source := "@ GET /health {\n  > {ok: true}\n}\n\n@ POST /health {\n  > {ok: true}\n}\n"
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, source, mode.interpreted)
+
+			req, err := http.NewRequest(http.MethodDelete, srv.URL+"/health", nil)
+			require.NoError(t, err)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			body := readBody(t, resp)
+			assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+			assert.Equal(t, "GET, HEAD, OPTIONS, POST", resp.Header.Get("Allow"))
+			assert.JSONEq(t, `{"error": "Method not allowed", "method": "DELETE", "path": "/health"}`, body)
+
+			status, body := getBody(t, srv.URL+"/missing")
+			assert.Equal(t, http.StatusNotFound, status)
+			assert.JSONEq(t, `{"error": "Route not found", "path": "/missing"}`, body)
+		})
+	}
+}
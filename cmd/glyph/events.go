@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/interpreter"
+)
+
+// startEventBus makes the events emitted with emit() on interp be handled in
+// the background, so a route does not wait for its event handlers. Handler
+// errors and panics are logged with the event type. Messages published on a
+// Redis channel named after an event type are delivered as events too. It
+// returns nil if there are no event handlers.
+func startEventBus(interp *interpreter.Interpreter) *interpreter.EventBus {
+	handlers := interp.GetAllEventHandlers()
+	if len(handlers) == 0 {
+		return nil
+	}
+
+	eventTypes := make([]string, 0, len(handlers))
+	for eventType := range handlers {
+		eventTypes = append(eventTypes, eventType)
+	}
+	sort.Strings(eventTypes)
+	for _, eventType := range eventTypes {
+		printInfo(fmt.Sprintf("Event handlers: %s (%d)", eventType, len(handlers[eventType])))
+	}
+	bus := interpreter.NewEventBus(interp, nil)
+	// setupRoutes reports a REDIS_URL that cannot be opened
+	if client, err := redisClient(); err == nil {
+		if err := subscribeEvents(bus, client, eventTypes); err != nil {
+			printWarning(fmt.Sprintf("Redis events unavailable: %v", err))
+		}
+	}
+	return bus
+}
+
+// startProgramEventBus starts the event handlers of a program on an
+// interpreter of their own, for compiled mode. It returns nil if the program
+// has no event handlers.
+func startProgramEventBus(program *interpreter.Program) (*interpreter.EventBus, error) {
+	hasHandlers := false
+	for _, item := range program.Module.Items {
+		if _, ok := item.(*ast.EventHandler); ok {
+			hasHandlers = true
+			break
+		}
+	}
+	if !hasHandlers {
+		return nil, nil
+	}
+
+	interp := newConfiguredInterpreter()
+	program.Prime(interp.GetModuleResolver())
+	if err := interp.LoadModuleWithPath(*program.Module, filepath.Dir(program.Entry)); err != nil {
+		return nil, fmt.Errorf("failed to load module for event handlers: %w", err)
+	}
+	return startEventBus(interp), nil
+}
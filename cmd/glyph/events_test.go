@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a bytes.Buffer safe for the log package to write to from
+// several goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// TestSignupEmitsUserCreated checks that in both execution modes a route's
+// emit() reaches every handler of the event, and that handler failures are
+// logged with the event type instead of failing the route
+func TestSignupEmitsUserCreated(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			var logs syncBuffer
+			log.SetOutput(&logs)
+			t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+			srcFile := filepath.Join(t.TempDir(), "main.glyph")
+			require.NoError(t, os.WriteFile(srcFile, []byte(`~ "user.created" {
+  $ r = send_welcome_email(event.email)
+}
+
+~ "user.created" {
+  $ r = record_signup(event.email)
+}
+
+@ POST /signup {
+  $ sent = emit("user.created", {email: input.email})
+  > {created: true, email: input.email}
+}
+`), 0644))
+			program, err := loadProgram(srcFile)
+			require.NoError(t, err)
+			routes, err := setupRoutes(program, mode.interpreted)
+			require.NoError(t, err)
+			require.Equal(t, !mode.interpreted, routes.useCompiler)
+			require.NotNil(t, routes.events)
+
+			srv := httptest.NewServer(createHandler(routes.router))
+			defer srv.Close()
+			status, body := postJSON(t, srv, "/signup", `{"email": "ada@example.com"}`)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, map[string]interface{}{"created": true, "email": "ada@example.com"}, body)
+
+			// Closing the bus waits for the queued event to be handled
+			routes.events.Close()
+			assert.Contains(t, logs.String(), `event "user.created" handler failed`)
+			assert.Contains(t, logs.String(), "send_welcome_email")
+			assert.Contains(t, logs.String(), "record_signup")
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRouteCallsFunctions checks that routes calling module functions are
+// compiled rather than falling back to the interpreter, and answer the same
+// in both execution modes
+func TestRouteCallsFunctions(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, `! slugify(title: string): string {
+  > lower(replace(trim(title), " ", "-"))
+}
+
+! describe(title: string, draft: bool = false): object {
+  $ slug = slugify(title)
+  if draft {
+    > {slug: slug, status: "draft"}
+  }
+  > {slug: slug, status: "published"}
+}
+
+@ POST /posts {
+  $ post = describe(input.title)
+  $ draft = describe(input.title, true)
+  > {post: post, draft: draft.status}
+}
+`, mode.interpreted)
+			status, body := postJSON(t, srv, "/posts", `{"title": " Hello World "}`)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, map[string]interface{}{
+				"post":  map[string]interface{}{"slug": "hello-world", "status": "published"},
+				"draft": "draft",
+			}, body)
+		})
+	}
+}
+
+// TestRouteUsesClosures checks that lambdas capture the variables of the
+// scope they are created in, each call of a factory getting its own. The
+// compiler does not support lambdas, so run falls back to the interpreter.
+func TestRouteUsesClosures(t *testing.T) {
+	srcFile := filepath.Join(t.TempDir(), "main.glyph")
+	require.NoError(t, os.WriteFile(srcFile, []byte(`! makeCounter(start: int = 0) {
+  $ count = start
+  > () => {
+    count = count + 1
+    > count
+  }
+}
+
+@ GET /counters {
+  $ a = makeCounter()
+  $ b = makeCounter(10)
+  a()
+  a()
+  $ factor = 3
+  $ scaled = map([1, 2, 3], (n) => n * factor)
+  > {a: a(), b: b(), scaled: scaled}
+}
+`), 0644))
+	program, err := loadProgram(srcFile)
+	require.NoError(t, err)
+
+	routes, err := setupRoutes(program, false)
+	require.NoError(t, err)
+	assert.False(t, routes.useCompiler)
+
+	srv := httptest.NewServer(createHandler(routes.router))
+	t.Cleanup(srv.Close)
+	status, body := getBody(t, srv.URL+"/counters")
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"a": 3, "b": 11, "scaled": [3, 6, 9]}`, body)
+}
+
+// TestFunctionTypesChecked checks that both execution modes check the
+// arguments and results of module functions against their annotations,
+// including arguments only known at runtime
+func TestFunctionTypesChecked(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, `! pick(x: int): int {
+  return "nope"
+}
+
+! double(x: int): int {
+  return x * 2
+}
+
+! label(name: str, suffix: str?): str {
+  if suffix == null {
+    return name
+  }
+  return name + suffix
+}
+
+@ POST /pick {
+  > {value: pick(input.n)}
+}
+
+@ POST /double {
+  > {value: double(input.n)}
+}
+
+@ GET /double/literal {
+  > {value: double("ab")}
+}
+
+@ GET /label {
+  > {plain: label("a"), suffixed: label("b", "!")}
+}
+`, mode.interpreted)
+			// A string argument for an int parameter
+			status, _ := postJSON(t, srv, "/pick", `{"n": "str"}`)
+			assert.Equal(t, http.StatusInternalServerError, status)
+			status, _ = getBody(t, srv.URL+"/double/literal")
+			assert.Equal(t, http.StatusInternalServerError, status)
+
+			// A string result from an int function
+			status, _ = postJSON(t, srv, "/pick", `{"n": 1}`)
+			assert.Equal(t, http.StatusInternalServerError, status)
+
+			// JSON numbers are accepted as ints, and omitted optional
+			// arguments as null
+			status, body := postJSON(t, srv, "/double", `{"n": 21}`)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, map[string]interface{}{"value": float64(42)}, body)
+			status, text := getBody(t, srv.URL+"/label")
+			assert.Equal(t, http.StatusOK, status)
+			assert.JSONEq(t, `{"plain": "a", "suffixed": "b!"}`, text)
+		})
+	}
+}
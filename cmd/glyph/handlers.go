@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,6 +13,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"syscall"
@@ -19,12 +21,18 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/compiler"
 	"github.com/glyphlang/glyph/pkg/database"
 	"github.com/glyphlang/glyph/pkg/interpreter"
 	"github.com/glyphlang/glyph/pkg/parser"
 	"github.com/glyphlang/glyph/pkg/server"
+	"github.com/glyphlang/glyph/pkg/session"
+	"github.com/glyphlang/glyph/pkg/tracing"
 	"github.com/glyphlang/glyph/pkg/vm"
 	"github.com/glyphlang/glyph/pkg/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // parseSource parses GLYPH source using the Go parser
@@ -45,12 +53,50 @@ func parseSource(source string) (*ast.Module, error) {
 	return module, nil
 }
 
-// newConfiguredInterpreter creates an interpreter with common configuration
-// including mock database for development/demo purposes.
+// loadProgram parses filePath and every file it imports, merged into one module
+func loadProgram(filePath string) (*interpreter.Program, error) {
+	return interpreter.NewProgramLoader(parseSource).Load(filePath)
+}
+
+// programFunctions returns the functions of program for the compiler,
+// including those callable through an import's alias or namespace
+func programFunctions(program *interpreter.Program) []*ast.Function {
+	return compiler.AliasFunctions(compiler.ModuleFunctions(program.Module), program.FunctionAliases())
+}
+
+// newConfiguredInterpreter creates an interpreter with common configuration,
+// using the configured database or, without one, a mock database for
+// development/demo purposes.
 func newConfiguredInterpreter() *interpreter.Interpreter {
 	interp := interpreter.NewInterpreter()
-	mockDB := database.NewMockDatabase()
-	interp.SetDatabaseHandler(mockDB)
+	// checkDatabase reports a database that cannot be connected
+	if db, err := programDatabase(); err == nil && db != nil {
+		interp.SetDatabaseHandler(db)
+	} else {
+		interp.SetDatabaseHandler(database.NewMockDatabase())
+	}
+	interp.SetBackgroundTasks(backgroundTasks)
+	// setupRoutes reports a CACHE_URL that cannot be opened
+	if c, err := valueCache(); err == nil {
+		interp.SetCacheHandler(c)
+	}
+	// setupRoutes reports a REDIS_URL that cannot be opened
+	if client, err := redisClient(); err == nil {
+		interp.SetRedisHandler(client)
+	}
+	// setupRoutes reports SMTP settings that are invalid
+	if m, err := mailer(); err == nil {
+		interp.SetProviderHandler("Mailer", m)
+	}
+	if t := programTemplates.Load(); t != nil {
+		interp.SetTemplates(t)
+	}
+	interp.SetHTTPHandler(httpClient())
+	// setupRoutes reports invalid limits
+	if l, err := executionLimits(); err == nil {
+		interp.SetMaxLoopIterations(l.maxLoopIterations)
+	}
+	interp.SetEnvPrefixes(envPrefixes())
 
 	// Set up the parse function for module resolution
 	interp.GetModuleResolver().SetParseFunc(func(source string) (*ast.Module, error) {
@@ -79,9 +125,12 @@ func registerRoute(router *server.Router, route *ast.Route, interp *interpreter.
 	return router.RegisterRoute(serverRoute)
 }
 
-// registerCompiledRoute registers a compiled route with the router
-func registerCompiledRoute(router *server.Router, route *ast.Route, bytecode []byte, wsHub *websocket.Hub) error {
-	handler := createCompiledRouteHandler(route, bytecode, wsHub)
+// registerCompiledRoute registers a compiled route with the router. types
+// holds the module's type definitions for validating declared input types.
+// queues and events, if non-nil, receive the messages passed to
+// queue.publish() and the events passed to emit().
+func registerCompiledRoute(router *server.Router, route *ast.Route, bytecode []byte, types *interpreter.TypeChecker, wsHub *websocket.Hub, queues *interpreter.QueueRunner, events *interpreter.EventBus) error {
+	handler := createCompiledRouteHandler(route, bytecode, types, wsHub, queues, events)
 
 	serverRoute := &server.Route{
 		Method:  convertHTTPMethod(route.Method),
@@ -92,22 +141,89 @@ func registerCompiledRoute(router *server.Router, route *ast.Route, bytecode []b
 	return router.RegisterRoute(serverRoute)
 }
 
-// createCompiledRouteHandler creates an HTTP handler that executes compiled bytecode
-func createCompiledRouteHandler(route *ast.Route, bytecode []byte, wsHub *websocket.Hub) server.RouteHandler {
+// vmPool holds the VMs that execute compiled routes and WebSocket events
+var vmPool = vm.NewPool(256)
+
+// trustProxy is the --trust-proxy flag of run and dev: request.ip is taken
+// from X-Forwarded-For or X-Real-IP, as set by a reverse proxy
+var trustProxy bool
+
+// envPrefixes returns the prefixes of GLYPH_ENV_PREFIXES (comma-separated,
+// e.g. "APP_,PUBLIC_"), which limit the variables env() may read. Handlers
+// read them once, when they are created.
+func envPrefixes() []string {
+	var prefixes []string
+	for _, prefix := range strings.Split(os.Getenv("GLYPH_ENV_PREFIXES"), ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// createCompiledRouteHandler creates an HTTP handler that executes compiled
+// bytecode. If types is non-nil, the request body is validated against the
+// route's declared input type before the bytecode runs, and the functions
+// the route calls check their arguments and results. If queues is non-nil,
+// queue.publish() and enqueue() publish to it; if events is non-nil, emit()
+// sends events to it.
+func createCompiledRouteHandler(route *ast.Route, bytecode []byte, types *interpreter.TypeChecker, wsHub *websocket.Hub, queues *interpreter.QueueRunner, events *interpreter.EventBus) server.RouteHandler {
+	functionTypes := newFunctionTypeChecker(types)
+	prefixes := envPrefixes()
 	return func(ctx *server.Context) error {
-		// Create VM instance
-		vmInstance := vm.NewVM()
+		defer recoverRoute(ctx)
+
+		// Borrow a VM; it is reset when released
+		vmInstance := vmPool.Acquire()
+		defer vmPool.Release(vmInstance)
+		vmInstance.SetTypeChecker(functionTypes)
 
 		// Set up WebSocket stats handler if hub is available
 		if wsHub != nil {
 			wsHandler := websocket.NewVMStatsHandler(wsHub)
 			vmInstance.SetWebSocketHandler(wsHandler)
 		}
+		if queues != nil {
+			vmInstance.SetQueuePublisher(queues)
+		}
+		if events != nil {
+			vmInstance.SetEventEmitter(events)
+		}
+		if c, err := valueCache(); err == nil {
+			vmInstance.SetCache(c)
+		}
+		if client, err := redisClient(); err == nil {
+			vmInstance.SetRedis(client)
+		}
+		if m, err := mailer(); err == nil {
+			vmInstance.SetMailer(m)
+		}
+		if t := programTemplates.Load(); t != nil {
+			vmInstance.SetTemplates(t)
+		}
+		vmInstance.SetEnvPrefixes(prefixes)
+		if l, err := executionLimits(); err == nil {
+			vmInstance.SetMaxSteps(l.maxSteps)
+		}
+		state, err := beginSession(ctx)
+		if err != nil {
+			return writeRouteError(ctx, err)
+		}
+		vmInstance.SetResponseCookies(state)
+		vmInstance.SetSession(state.Session())
+		var stream *routeStream
+		if route.Method == ast.SSE {
+			stream = newRouteStream(ctx, state)
+			vmInstance.SetStreamWriter(stream)
+		}
 
 		// Inject path parameters into VM locals
 		for key, value := range ctx.PathParams {
 			vmInstance.SetLocal(key, vm.StringValue{Val: value})
 		}
+		if routeErr := routeError(ctx); routeErr != nil {
+			vmInstance.SetLocal("err", interfaceToValue(routeErr))
+		}
 
 		// Inject query parameters as 'query' object (and individual declared
 		// params) so compiled routes can read query.X the same as interpreted
@@ -116,13 +232,7 @@ func createCompiledRouteHandler(route *ast.Route, bytecode []byte, wsHub *websoc
 		rawQuery := map[string][]string(ctx.Request.URL.Query())
 		queryParams, qErr := interpreter.ProcessQueryParams(rawQuery, route.QueryParams)
 		if qErr != nil {
-			// Same pattern as success responses below (ctx.StatusCode +
-			// WriteHeader): ctx.StatusCode is for middleware/logging, but
-			// the actual status line needs WriteHeader to flip from 200.
-			ctx.StatusCode = http.StatusBadRequest
-			ctx.ResponseWriter.Header().Set("Content-Type", "application/json")
-			ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
-			if encErr := json.NewEncoder(ctx.ResponseWriter).Encode(map[string]interface{}{
+			if encErr := writeBadRequest(ctx, map[string]interface{}{
 				"error": qErr.Error(),
 			}); encErr != nil {
 				return fmt.Errorf("failed to encode query-param error response: %w", encErr)
@@ -151,7 +261,8 @@ func createCompiledRouteHandler(route *ast.Route, bytecode []byte, wsHub *websoc
 			}
 		}
 
-		// Parse and inject request body as 'input' for POST/PUT/PATCH requests
+		// Parse request body as 'input' for POST/PUT/PATCH requests
+		var bodyMap map[string]interface{}
 		if ctx.Request.Method == "POST" || ctx.Request.Method == "PUT" || ctx.Request.Method == "PATCH" {
 			contentType := ctx.Request.Header.Get("Content-Type")
 			shouldParseJSON := contentType == "" ||
@@ -162,17 +273,29 @@ func createCompiledRouteHandler(route *ast.Route, bytecode []byte, wsHub *websoc
 				const maxBodySize = 10 * 1024 * 1024
 				limitedReader := io.LimitReader(ctx.Request.Body, maxBodySize)
 
-				var bodyMap map[string]interface{}
 				decoder := json.NewDecoder(limitedReader)
-				if err := decoder.Decode(&bodyMap); err == nil {
-					vmInstance.SetLocal("input", interfaceToValue(bodyMap))
-				} else {
-					vmInstance.SetLocal("input", vm.NullValue{})
+				if err := decoder.Decode(&bodyMap); err != nil {
+					bodyMap = nil
 				}
 				ctx.Request.Body.Close()
-			} else {
-				vmInstance.SetLocal("input", vm.NullValue{})
 			}
+		}
+
+		// Validate the body against the declared input type the same way
+		// Interpreter.ExecuteRoute does, before any bytecode runs
+		if types != nil {
+			if typeDef, ok := types.RouteInputTypeDef(route); ok {
+				bodyMap = applyLiteralDefaults(bodyMap, typeDef)
+				if validationErr := types.ValidateInput(bodyMap, typeDef); validationErr != nil {
+					if encErr := writeBadRequest(ctx, validationErr.Response().Body); encErr != nil {
+						return fmt.Errorf("failed to encode input validation response: %w", encErr)
+					}
+					return nil
+				}
+			}
+		}
+		if bodyMap != nil {
+			vmInstance.SetLocal("input", interfaceToValue(bodyMap))
 		} else {
 			vmInstance.SetLocal("input", vm.NullValue{})
 		}
@@ -187,38 +310,187 @@ func createCompiledRouteHandler(route *ast.Route, bytecode []byte, wsHub *websoc
 		}
 		vmInstance.SetLocal("headers", vm.ObjectValue{Val: headerObj})
 
+		// Expose request metadata as 'request' object, with the same
+		// fields as in interpreter mode
+		cookieObj := make(map[string]vm.Value)
+		for name, value := range state.Cookies() {
+			cookieObj[name] = vm.StringValue{Val: value}
+		}
+		paramObj := make(map[string]vm.Value, len(ctx.PathParams))
+		for key, value := range ctx.PathParams {
+			paramObj[key] = vm.StringValue{Val: value}
+		}
+		vmInstance.SetLocal("request", vm.ObjectValue{Val: map[string]vm.Value{
+			"id":      vm.StringValue{Val: ctx.RequestID},
+			"method":  vm.StringValue{Val: ctx.Request.Method},
+			"path":    vm.StringValue{Val: ctx.Request.URL.Path},
+			"params":  vm.ObjectValue{Val: paramObj},
+			"query":   vm.ObjectValue{Val: queryObj},
+			"headers": vm.ObjectValue{Val: headerObj},
+			"ip":      vm.StringValue{Val: server.ClientIP(ctx.Request, trustProxy)},
+			"cookies": vm.ObjectValue{Val: cookieObj},
+		}})
+
 		// Execute compiled bytecode
+		_, span := tracing.StartSpan(ctx.Request.Context(), "vm.execute",
+			trace.WithAttributes(attribute.String("http.route", route.Path)))
+		vmStart := time.Now()
 		result, err := vmInstance.Execute(bytecode)
+		if m := serverMetrics.Load(); m != nil {
+			m.RecordVMExecution(ctx.Request.Method, route.Path, time.Since(vmStart))
+		}
 		if err != nil {
-			// Log full error server-side, return generic message to client
-			printError(fmt.Errorf("bytecode execution failed: %w", err))
-			ctx.StatusCode = http.StatusInternalServerError
-			ctx.ResponseWriter.Header().Set("Content-Type", "application/json")
-			return json.NewEncoder(ctx.ResponseWriter).Encode(map[string]interface{}{
-				"error": "Internal server error",
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		if err != nil && stream != nil && stream.started() {
+			return stream.finish(err)
+		}
+		var errorResp *vm.ErrorResponse
+		if errors.As(err, &errorResp) {
+			return server.Send(ctx, errorResp.StatusCode, map[string]interface{}{
+				"error": errorResp.Message,
 			})
 		}
+		if err != nil {
+			return writeRouteError(ctx, fmt.Errorf("bytecode execution failed: %w", err))
+		}
+		startVMBackground(route, vmInstance.BackgroundTasks())
+		if stream != nil {
+			return stream.finish(nil)
+		}
+		if err := applySession(ctx, state); err != nil {
+			return writeRouteError(ctx, err)
+		}
+
+		// A union return type picks the status from the variant returned
+		status := http.StatusOK
+		if types != nil {
+			status = types.UnionReturnStatus(vm.ValueToInterface(result), route.ReturnType)
+		}
+
+		// A page from render() is sent as HTML
+		if page, ok := result.(vm.HTMLValue); ok {
+			ctx.StatusCode = status
+			ctx.ResponseWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
+			ctx.ResponseWriter.WriteHeader(status)
+			_, writeErr := ctx.ResponseWriter.Write([]byte(page.Val))
+			return writeErr
+		}
+
+		// Set response, encoded according to the Accept header
+		return server.Send(ctx, status, result)
+	}
+}
+
+// writeBadRequest sends body, whose error field is the message, as a 400
+// error response
+func writeBadRequest(ctx *server.Context, body interface{}) error {
+	fields, _ := body.(map[string]interface{})
+	message, _ := fields["error"].(string)
+	return writeErrorResponse(ctx, http.StatusBadRequest, message, fields)
+}
+
+// applyLiteralDefaults returns a copy of body with the literal defaults of
+// typeDef filled in for missing fields. A nil body is treated as empty.
+// Non-literal defaults need the interpreter and are left unset.
+func applyLiteralDefaults(body map[string]interface{}, typeDef ast.TypeDef) map[string]interface{} {
+	result := make(map[string]interface{}, len(body))
+	for k, v := range body {
+		result[k] = v
+	}
+	for _, field := range typeDef.Fields {
+		if _, exists := result[field.Name]; !exists && field.Default != nil {
+			if val, ok := evalLiteralExpr(field.Default); ok {
+				result[field.Name] = val
+			}
+		}
+	}
+	return result
+}
+
+// recoverRoute converts a panic in a route handler into a 500 response so
+// the server keeps serving. It must be deferred directly by the handler.
+func recoverRoute(ctx *server.Context) {
+	if r := recover(); r != nil {
+		writeRouteError(ctx, &interpreter.RoutePanicError{
+			Method: ctx.Request.Method,
+			Path:   ctx.Request.URL.Path,
+			Value:  r,
+			Stack:  debug.Stack(),
+		})
+	}
+}
+
+// writeRouteError logs err (with the request ID and stack trace for
+// panics, which are counted in the metrics) and sends a generic error
+// response, keeping internal details out of it. The status is 508 when the
+// route hit an execution limit, 504 when a database query ran out of time
+// and 500 otherwise. The message of a template that failed to render names
+// the template.
+func writeRouteError(ctx *server.Context, err error) error {
+	var panicErr *interpreter.RoutePanicError
+	if errors.As(err, &panicErr) {
+		printError(fmt.Errorf("%w (request %s)\n%s", err, ctx.RequestID, panicErr.Stack))
+		if m := serverMetrics.Load(); m != nil {
+			m.RecordPanic(ctx.Request.Method, ctx.RoutePattern)
+		}
+	} else {
+		printError(err)
+	}
 
-		// Set response
-		ctx.StatusCode = http.StatusOK
-		ctx.ResponseWriter.Header().Set("Content-Type", "application/json")
-		return json.NewEncoder(ctx.ResponseWriter).Encode(result)
+	// A route that ran out of steps or loop iterations was most likely
+	// stuck in a loop
+	status, message := http.StatusInternalServerError, "Internal server error"
+	var stepErr *vm.StepLimitError
+	var loopErr *interpreter.LoopLimitError
+	var timeoutErr *database.QueryTimeoutError
+	var tmplErr *templateError
+	if errors.As(err, &stepErr) || errors.As(err, &loopErr) {
+		status, message = http.StatusLoopDetected, "Execution limit exceeded"
+	} else if errors.As(err, &timeoutErr) {
+		status, message = http.StatusGatewayTimeout, "Database query timed out"
+	} else if errors.As(err, &tmplErr) {
+		message = fmt.Sprintf("Failed to render template %q", tmplErr.name)
 	}
+
+	return writeErrorResponse(ctx, status, message, map[string]interface{}{
+		"error": message,
+	})
 }
 
 // createRouteHandler creates an HTTP handler for a route
 func createRouteHandler(route *ast.Route, interp *interpreter.Interpreter) server.RouteHandler {
 	return func(ctx *server.Context) error {
+		defer recoverRoute(ctx)
+
+		state, err := beginSession(ctx)
+		if err != nil {
+			return writeRouteError(ctx, err)
+		}
+		var stream *routeStream
+		if route.Method == ast.SSE {
+			stream = newRouteStream(ctx, state)
+		}
+
 		// Execute route body using the interpreter
-		response, err := executeRoute(route, ctx, interp)
+		response, err := executeRoute(route, ctx, interp, stream, state)
+		if err != nil && stream != nil && stream.started() {
+			return stream.finish(err)
+		}
+		var validationErr *interpreter.InputValidationError
+		if errors.As(err, &validationErr) {
+			return writeBadRequest(ctx, validationErr.Response().Body)
+		}
 		if err != nil {
-			// Log full error server-side, return generic message to client
-			printError(fmt.Errorf("route execution error: %w", err))
-			ctx.StatusCode = http.StatusInternalServerError
-			ctx.ResponseWriter.Header().Set("Content-Type", "application/json")
-			return json.NewEncoder(ctx.ResponseWriter).Encode(map[string]interface{}{
-				"error": "Internal server error",
-			})
+			return writeRouteError(ctx, fmt.Errorf("route execution error: %w", err))
+		}
+		if stream != nil {
+			return stream.finish(nil)
+		}
+		if err := applySession(ctx, state); err != nil {
+			return writeRouteError(ctx, err)
 		}
 
 		// Check for redirect response (Location header set by interpreter)
@@ -248,15 +520,16 @@ func createRouteHandler(route *ast.Route, interp *interpreter.Interpreter) serve
 			}
 		}
 
-		// Default JSON response
-		ctx.StatusCode = http.StatusOK
-		ctx.ResponseWriter.Header().Set("Content-Type", "application/json")
-		return json.NewEncoder(ctx.ResponseWriter).Encode(response.Body)
+		// Default response: JSON unless the client asks for another
+		// registered encoding via the Accept header
+		return server.Send(ctx, response.StatusCode, response.Body)
 	}
 }
 
-// executeRoute executes a route's body and returns the full interpreter response.
-func executeRoute(route *ast.Route, ctx *server.Context, interp *interpreter.Interpreter) (*interpreter.Response, error) {
+// executeRoute executes a route's body and returns the full interpreter
+// response. stream, if non-nil, receives the events of an SSE route, and
+// state, if non-nil, holds the request's cookies and session.
+func executeRoute(route *ast.Route, ctx *server.Context, interp *interpreter.Interpreter, stream *routeStream, state *session.Request) (*interpreter.Response, error) {
 	// Parse request body for POST/PUT/PATCH/DELETE requests.
 	// RFC 7231 permits DELETE to carry a body, and some APIs rely on it.
 	var requestBody interface{}
@@ -286,13 +559,30 @@ func executeRoute(route *ast.Route, ctx *server.Context, interp *interpreter.Int
 		}
 	}
 
-	// Create request object for interpreter
+	// Create request object for interpreter. The query string stays on the
+	// path, where ExecuteRoute reads the query parameters from.
+	path := ctx.Request.URL.Path
+	if ctx.Request.URL.RawQuery != "" {
+		path += "?" + ctx.Request.URL.RawQuery
+	}
 	request := &interpreter.Request{
-		Path:    ctx.Request.URL.Path,
+		Path:    path,
 		Method:  ctx.Request.Method,
 		Params:  ctx.PathParams,
 		Body:    requestBody,
 		Headers: make(map[string]string),
+		ID:      ctx.RequestID,
+		IP:      server.ClientIP(ctx.Request, trustProxy),
+		Context: ctx.Request.Context(),
+		Error:   routeError(ctx),
+	}
+	if stream != nil {
+		request.SSEWriter = stream
+	}
+	if state != nil {
+		request.Cookies = state.Cookies()
+		request.ResponseCookies = state
+		request.Session = state.Session()
 	}
 
 	// Copy headers
@@ -377,42 +667,74 @@ func createHandler(router *server.Router) http.HandlerFunc {
 			}
 		}
 
+		// Attach a request ID (propagated from X-Request-ID when present)
+		r, requestID := server.WithRequestID(r)
+		w.Header().Set(server.RequestIDHeader, requestID)
+		// Error responses go through the program's @ onerror handler
+		if onError := router.ErrorHandler(); onError != nil {
+			r = r.WithContext(context.WithValue(r.Context(), errorScopeKey{}, &errorScope{onError: onError}))
+		}
+
 		method := server.HTTPMethod(r.Method)
 		route, params, err := router.Match(method, r.URL.Path)
 
-		if err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Route not found",
-				"path":  r.URL.Path,
-			})
-			return
-		}
-
 		// Create context
 		ctx := &server.Context{
 			Request:        r,
-			ResponseWriter: w,
+			ResponseWriter: server.NewStatusWriter(w),
 			PathParams:     params,
 			StatusCode:     http.StatusOK,
+			RequestID:      requestID,
 		}
 
-		// Execute handler
-		if err := route.Handler(ctx); err != nil {
+		var handler server.RouteHandler
+		var notAllowed *server.MethodNotAllowedError
+		if notFound := router.NotFound(); err == nil {
+			ctx.RoutePattern = route.Path
+			handler = router.Handler(route)
+		} else if errors.As(err, &notAllowed) {
+			w.Header().Set("Allow", notAllowed.AllowHeader())
+			writeErrorResponse(ctx, http.StatusMethodNotAllowed, "Method not allowed", map[string]interface{}{
+				"error":  "Method not allowed",
+				"method": r.Method,
+				"path":   r.URL.Path,
+			})
+			return
+		} else if notFound != nil {
+			// @ notfound answers with 404 unless it sets another status
+			handler = func(ctx *server.Context) error {
+				return runErrorHandler(ctx, notFound, http.StatusNotFound)
+			}
+		} else {
+			writeErrorResponse(ctx, http.StatusNotFound, "Route not found", map[string]interface{}{
+				"error": "Route not found",
+				"path":  r.URL.Path,
+			})
+			return
+		}
+
+		// Execute handler. Middleware that failed may have answered already.
+		if err := handler(ctx); err != nil {
 			printError(fmt.Errorf("handler error for %s %s: %w", r.Method, r.URL.Path, err))
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{
+			if ctx.ResponseWriter.(*server.StatusWriter).WroteHeader() {
+				return
+			}
+			writeErrorResponse(ctx, http.StatusInternalServerError, "Internal server error", map[string]interface{}{
 				"error": "Internal server error",
 			})
 		}
 	}
 }
 
-// loggingMiddleware logs HTTP requests
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+// loggingMiddleware logs HTTP requests. The text format prints colorized
+// request lines for interactive use; the JSON format emits one structured
+// line per request for log pipelines. Both assign an X-Request-ID.
+func loggingMiddleware(next http.Handler, format server.LogFormat) http.Handler {
+	if format == server.LogFormatJSON {
+		return server.AccessLogHandler(next, server.JSONAccessLogger(os.Stdout))
+	}
+
+	return server.RequestIDHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
 		// Log request
@@ -424,7 +746,7 @@ func loggingMiddleware(next http.Handler) http.Handler {
 		// Log duration
 		duration := time.Since(start)
 		printDuration(duration)
-	})
+	}))
 }
 
 // signalNotify is a helper to register for interrupt signals.
@@ -434,7 +756,7 @@ func signalNotify(sigChan chan<- os.Signal) {
 }
 
 // waitForShutdown waits for interrupt signal and gracefully shuts down the server
-func waitForShutdown(srv *http.Server) error {
+func waitForShutdown(srv *http.Server, wsServer *websocket.Server) error {
 	// Setup signal handling
 	sigChan := make(chan os.Signal, 1)
 	signalNotify(sigChan)
@@ -449,14 +771,29 @@ func waitForShutdown(srv *http.Server) error {
 	defer cancel()
 
 	// Attempt graceful shutdown
-	if err := srv.Shutdown(ctx); err != nil {
+	if err := shutdownServer(ctx, srv, wsServer); err != nil {
 		return fmt.Errorf("server shutdown failed: %w", err)
 	}
+	waitForBackgroundTasks()
+	shutdownTracing(ctx)
 
 	printSuccess("Server stopped gracefully")
 	return nil
 }
 
+// shutdownServer closes the WebSocket connections of wsServer, if any, and
+// then shuts srv down. http.Server does not track hijacked connections, so
+// WebSocket clients are sent a going-away close frame first rather than
+// being left open while Shutdown waits.
+func shutdownServer(ctx context.Context, srv *http.Server, wsServer *websocket.Server) error {
+	if wsServer != nil {
+		if err := wsServer.Shutdown(ctx); err != nil {
+			printWarning(fmt.Sprintf("WebSocket connections did not close in time: %v", err))
+		}
+	}
+	return srv.Shutdown(ctx)
+}
+
 // convertHTTPMethod converts ast.HttpMethod to server.HTTPMethod
 func convertHTTPMethod(method ast.HttpMethod) server.HTTPMethod {
 	switch method {
@@ -491,15 +828,17 @@ func getHelloWorldTemplate() string {
   timestamp: int
 }
 
-@ GET /hello
+@ GET /hello {
   > {text: "Hello, World!", timestamp: 1234567890}
+}
 
-@ GET /greet/:name -> Message
+@ GET /greet/:name -> Message {
   $ message = {
     text: "Hello, " + name + "!",
     timestamp: time.now()
   }
   > message
+}
 `
 }
 
@@ -512,12 +851,20 @@ func getRestAPITemplate() string {
   email: str!
 }
 
-@ GET /api/users -> List[User]
+@ GET /api/users -> List[User] {
   + auth(jwt)
   > []
+}
 
-@ GET /health
+@ GET /health {
   > {status: "ok", timestamp: now()}
+}
+
+# Run with: glyph seed main.glyph --database sqlite://./app.db
+seed! users {
+  db.users.firstOrCreate({email: "ada@example.com"}, {name: "Ada Lovelace"})
+  db.users.firstOrCreate({email: "grace@example.com"}, {name: "Grace Hopper"})
+}
 `
 }
 
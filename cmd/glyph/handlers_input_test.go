@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const inputValidationSource = `: CreateUser {
+  name: str!
+  age: int!
+  admin: bool = false
+}
+
+@ POST /users {
+  < input: CreateUser
+  > {name: input.name, age: input.age, admin: input.admin}
+}
+`
+
+// serverOptions adjusts the server started by startInputValidationServer
+type serverOptions struct {
+	dir   string                                      // Directory to write main.glyph to, holding any files it uses; a new temporary one if empty
+	setup func(router *server.Router)                 // Called once the routes are registered
+	wrap  func(handler http.HandlerFunc) http.Handler // Wraps the handler being served
+}
+
+// startInputValidationServer serves source compiled or interpreted, with
+// at most one serverOptions
+func startInputValidationServer(t *testing.T, source string, interpreted bool, options ...serverOptions) *httptest.Server {
+	t.Helper()
+	var opts serverOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.dir == "" {
+		opts.dir = t.TempDir()
+	}
+	srcFile := filepath.Join(opts.dir, "main.glyph")
+	require.NoError(t, os.WriteFile(srcFile, []byte(source), 0644))
+	program, err := loadProgram(srcFile)
+	require.NoError(t, err)
+
+	routes, err := setupRoutes(program, interpreted)
+	require.NoError(t, err)
+	require.Equal(t, !interpreted, routes.useCompiler)
+	if opts.setup != nil {
+		opts.setup(routes.router)
+	}
+
+	handler := createHandler(routes.router)
+	var served http.Handler = handler
+	if opts.wrap != nil {
+		served = opts.wrap(handler)
+	}
+	srv := httptest.NewServer(served)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// postJSON sends body to path and decodes the JSON response
+func postJSON(t *testing.T, srv *httptest.Server, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var decoded map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+	return resp.StatusCode, decoded
+}
+
+// executionModes are the two ways a route can run
+var executionModes = []struct {
+	name        string
+	interpreted bool
+}{
+	{"compiled", false},
+	{"interpreted", true},
+}
+
+// TestRouteInputValidation checks that both execution modes reject a body
+// that does not match the declared input type with the same 400 response
+func TestRouteInputValidation(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, inputValidationSource, mode.interpreted)
+
+			status, body := postJSON(t, srv, "/users", `{"age": 36}`)
+			assert.Equal(t, http.StatusBadRequest, status)
+			assert.Equal(t, map[string]interface{}{
+				"error": "input validation failed",
+				"fields": []interface{}{
+					map[string]interface{}{"field": "name", "message": "missing required field"},
+				},
+			}, body)
+
+			status, body = postJSON(t, srv, "/users", `{"name": "Ada", "age": "old"}`)
+			assert.Equal(t, http.StatusBadRequest, status)
+			assert.Equal(t, map[string]interface{}{
+				"error": "input validation failed",
+				"fields": []interface{}{
+					map[string]interface{}{"field": "age", "message": "type mismatch: expected int, got string"},
+				},
+			}, body)
+
+			status, body = postJSON(t, srv, "/users", `{"name": "Ada", "age": 36}`)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, map[string]interface{}{"name": "Ada", "age": float64(36), "admin": false}, body)
+		})
+	}
+}
+
+// TestRouteReadsConstantsAndEnumValues checks that both execution modes
+// read module constants and list an enum's values
+func TestRouteReadsConstantsAndEnumValues(t *testing.T) {
+	const source = `: Status = "pending" | "shipped" | "delivered"
+
+const MAX_PAGE_SIZE = 100
+const DEFAULT_PAGE_SIZE = MAX_PAGE_SIZE / 4
+
+@ GET /meta {
+  > {max: MAX_PAGE_SIZE, page: DEFAULT_PAGE_SIZE, statuses: Status.values()}
+}
+`
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, source, mode.interpreted)
+
+			status, body := getBody(t, srv.URL+"/meta")
+			assert.Equal(t, http.StatusOK, status)
+			assert.JSONEq(t, `{"max": 100, "page": 25, "statuses": ["pending", "shipped", "delivered"]}`, body)
+		})
+	}
+}
+
+// TestRouteInputEnum checks that both execution modes reject a value outside
+// an enum and can switch on a valid one
+func TestRouteInputEnum(t *testing.T) {
+	const source = `: Status = "pending" | "shipped" | "delivered"
+
+: UpdateOrder {
+  status: Status!
+}
+
+@ POST /orders {
+  < input: UpdateOrder
+  switch input.status {
+    case "pending" {
+      > {next: "shipped"}
+    }
+    default {
+      > {next: "delivered"}
+    }
+  }
+}
+`
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, source, mode.interpreted)
+
+			status, body := postJSON(t, srv, "/orders", `{"status": "lost"}`)
+			assert.Equal(t, http.StatusBadRequest, status)
+			assert.Equal(t, map[string]interface{}{
+				"error": "input validation failed",
+				"fields": []interface{}{
+					map[string]interface{}{
+						"field":   "status",
+						"message": `invalid Status: expected one of "pending", "shipped", "delivered", got "lost"`,
+					},
+				},
+			}, body)
+
+			status, body = postJSON(t, srv, "/orders", `{"status": "pending"}`)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, map[string]interface{}{"next": "shipped"}, body)
+		})
+	}
+}
@@ -50,7 +50,7 @@ func invokeCompiledRoute(t *testing.T, route *ast.Route, bytecode []byte, method
 		PathParams:     map[string]string{},
 		StatusCode:     http.StatusOK,
 	}
-	handler := createCompiledRouteHandler(route, bytecode, nil)
+	handler := createCompiledRouteHandler(route, bytecode, nil, nil, nil, nil)
 	require.NoError(t, handler(ctx), "handler error")
 	return rec
 }
@@ -135,7 +135,7 @@ func TestCompiledRouteHeadersAccess(t *testing.T) {
 		PathParams:     map[string]string{},
 		StatusCode:     http.StatusOK,
 	}
-	handler := createCompiledRouteHandler(route, bytecode, nil)
+	handler := createCompiledRouteHandler(route, bytecode, nil, nil, nil, nil)
 	require.NoError(t, handler(ctx), "handler error")
 
 	assert.Equal(t, http.StatusOK, rec.Code, "body=%s", rec.Body.String())
@@ -190,3 +190,104 @@ func TestCompiledRouteQueryStringDefault(t *testing.T) {
 	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
 	assert.Equal(t, "name", body["sort"], "string default should be applied")
 }
+
+// TestCompiledRouteRequestID verifies that compiled routes can read request.id.
+func TestCompiledRouteRequestID(t *testing.T) {
+	src := `@ GET /api/id {
+  > {id: request.id}
+}`
+	route, bytecode := compileFirstRoute(t, src)
+	req := httptest.NewRequest("GET", "/api/id", nil)
+	rec := httptest.NewRecorder()
+	ctx := &server.Context{
+		Request:        req,
+		ResponseWriter: rec,
+		PathParams:     map[string]string{},
+		StatusCode:     http.StatusOK,
+		RequestID:      "req-abc",
+	}
+	require.NoError(t, createCompiledRouteHandler(route, bytecode, nil, nil, nil, nil)(ctx))
+
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, "req-abc", body["id"])
+}
+
+// TestRouteRequestObject verifies that both execution modes expose the
+// method, path, params, query, headers and client IP of the request as the
+// request object, and that request.header ignores the case of its name
+func TestRouteRequestObject(t *testing.T) {
+	src := `@ GET /api/echo/:name {
+  > {method: request.method, path: request.path, name: request.params.name, page: request.query.page, agent: request.header("user-agent"), missing: request.header("X-Missing"), direct: request.headers["User-Agent"], ip: request.ip}
+}`
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, src, mode.interpreted)
+
+			req, err := http.NewRequest("GET", srv.URL+"/api/echo/ada?page=2", nil)
+			require.NoError(t, err)
+			req.Header.Set("User-Agent", "glyph-test/1.0")
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, "GET", body["method"])
+			assert.Equal(t, "/api/echo/ada", body["path"])
+			assert.Equal(t, "ada", body["name"])
+			assert.Equal(t, float64(2), body["page"])
+			assert.Equal(t, "glyph-test/1.0", body["agent"])
+			assert.Nil(t, body["missing"])
+			assert.Equal(t, "glyph-test/1.0", body["direct"])
+			assert.Equal(t, "127.0.0.1", body["ip"])
+		})
+	}
+}
+
+// TestCreateHandlerPropagatesRequestID verifies that createHandler reuses an
+// incoming X-Request-ID and exposes it to interpreted routes as request.id.
+func TestCreateHandlerPropagatesRequestID(t *testing.T) {
+	module, err := parseSource(`@ GET /api/id {
+  > {id: request.id}
+}`)
+	require.NoError(t, err)
+	interp := newConfiguredInterpreter()
+	require.NoError(t, interp.LoadModule(*module))
+
+	router := server.NewRouter()
+	require.NoError(t, registerRoute(router, module.Items[0].(*ast.Route), interp))
+
+	handler := loggingMiddleware(createHandler(router), server.LogFormatText)
+	req := httptest.NewRequest("GET", "/api/id", nil)
+	req.Header.Set(server.RequestIDHeader, "trace-7")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "trace-7", rec.Header().Get(server.RequestIDHeader))
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, "trace-7", body["id"])
+}
+
+// TestCompiledRouteDoesNotLeakLocals verifies that pooled VMs do not carry
+// variables from one request into the next
+func TestCompiledRouteDoesNotLeakLocals(t *testing.T) {
+	setRoute, setBytecode := compileFirstRoute(t, `@ GET /set {
+  $ id = "token"
+  > {ok: true}
+}`)
+	// invokeCompiledRoute binds no path parameters, so id is only defined
+	// if it leaked from the previous request
+	readRoute, readBytecode := compileFirstRoute(t, `@ GET /read/:id {
+  > {leaked: id}
+}`)
+
+	rec := invokeCompiledRoute(t, setRoute, setBytecode, "GET", "/set")
+	require.Equal(t, http.StatusOK, rec.Code, "body=%s", rec.Body.String())
+
+	rec = invokeCompiledRoute(t, readRoute, readBytecode, "GET", "/read")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code, "body=%s", rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "token")
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/metrics"
+	"github.com/glyphlang/glyph/pkg/server"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// panickingDB is a database handler whose query methods panic, like a
+// driver returning a nil row
+type panickingDB struct{}
+
+func (panickingDB) Ping() interface{} {
+	panic("nil QueryRow result")
+}
+
+// TestInterpretedRoutePanicReturns500 verifies that a panicking route is
+// answered with a generic 500, counted as a panic, and that the server keeps
+// serving
+func TestInterpretedRoutePanicReturns500(t *testing.T) {
+	m := metrics.NewMetrics(metrics.DefaultConfig())
+	serverMetrics.Store(m)
+	t.Cleanup(func() { serverMetrics.Store(nil) })
+
+	module, err := parseSource(`@ GET /boom {
+  % db: Database
+  $ result = db.Ping()
+  > {result: result}
+}
+
+@ GET /ok {
+  > {ok: true}
+}
+`)
+	require.NoError(t, err)
+
+	interp := newConfiguredInterpreter()
+	interp.SetDatabaseHandler(panickingDB{})
+	require.NoError(t, interp.LoadModule(*module))
+
+	router := server.NewRouter()
+	for _, item := range module.Items {
+		if route, ok := item.(*ast.Route); ok {
+			require.NoError(t, registerRoute(router, route, interp))
+		}
+	}
+	srv := httptest.NewServer(createHandler(router))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/boom")
+	require.NoError(t, err)
+	body := readBody(t, resp)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Contains(t, body, "Internal server error")
+	assert.NotContains(t, body, "nil QueryRow result")
+
+	resp, err = http.Get(srv.URL + "/ok")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, readBody(t, resp), "true")
+
+	assert.Equal(t, 1, testutil.CollectAndCount(m.GetRegistry(), "glyphlang_http_route_panics_total"))
+}
+
+// TestMiddlewarePanicReturns500 verifies that the router recovers from a
+// panic outside the route handler with a single 500 and keeps serving
+func TestMiddlewarePanicReturns500(t *testing.T) {
+	module, err := parseSource(`@ GET /boom {
+  > {ok: true}
+}
+
+@ GET /ok {
+  > {ok: true}
+}
+`)
+	require.NoError(t, err)
+	interp := newConfiguredInterpreter()
+	require.NoError(t, interp.LoadModule(*module))
+
+	router, err := newRouter()
+	require.NoError(t, err)
+	router.Use(func(next server.RouteHandler) server.RouteHandler {
+		return func(ctx *server.Context) error {
+			if ctx.RoutePattern == "/boom" {
+				panic("middleware failed")
+			}
+			return next(ctx)
+		}
+	})
+	for _, item := range module.Items {
+		require.NoError(t, registerRoute(router, item.(*ast.Route), interp))
+	}
+	srv := httptest.NewServer(createHandler(router))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/boom")
+	require.NoError(t, err)
+	body := readBody(t, resp)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.NotContains(t, body, "middleware failed")
+	var payload map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(body), &payload), "one JSON body, got %q", body)
+
+	resp, err = http.Get(srv.URL + "/ok")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+// readBody reads and closes a response body
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(b)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const unionReturnSource = `: Message {
+  id: int!
+  text: str!
+}
+
+: Error {
+  code: int!
+  message: str!
+}
+
+: NotFound {
+  message: str!
+}
+
+@ POST /messages -> Message | Error | NotFound {
+  if input.text == "" {
+    > {code: 422, message: "text is required"}
+  }
+  if input.text == "missing" {
+    > {message: "no such thread"}
+  }
+  > {id: 1, text: input.text}
+}
+`
+
+// TestRouteUnionReturnStatus checks that both execution modes answer with
+// the status of the union variant a route returns
+func TestRouteUnionReturnStatus(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, unionReturnSource, mode.interpreted)
+
+			status, body := postJSON(t, srv, "/messages", `{"text": "hello"}`)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, map[string]interface{}{"id": float64(1), "text": "hello"}, body)
+
+			status, body = postJSON(t, srv, "/messages", `{"text": ""}`)
+			assert.Equal(t, http.StatusUnprocessableEntity, status)
+			assert.Equal(t, map[string]interface{}{"code": float64(422), "message": "text is required"}, body)
+
+			status, body = postJSON(t, srv, "/messages", `{"text": "missing"}`)
+			assert.Equal(t, http.StatusNotFound, status)
+			assert.Equal(t, "no such thread", body["message"])
+		})
+	}
+}
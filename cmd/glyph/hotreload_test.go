@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestReloadManager writes source to a temp file and starts a dev server
+// on an ephemeral port.
+func newTestReloadManager(t *testing.T, source string) *hotReloadManager {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "main.glyph")
+	require.NoError(t, os.WriteFile(file, []byte(source), 0600))
+
+	m := &hotReloadManager{
+		filePath:        file,
+		port:            0,
+		liveReloadConns: make(map[*liveReloadConn]bool),
+	}
+	require.NoError(t, m.startServer())
+	t.Cleanup(func() { m.server.Close() })
+	return m
+}
+
+// fetchVersion requests /version and decodes the "v" field
+func fetchVersion(client *http.Client, base string) (float64, error) {
+	resp, err := client.Get(base + "/version")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, err
+	}
+	v, _ := body["v"].(float64)
+	return v, nil
+}
+
+// TestHotReloadSwapsHandlerWithoutDroppingConnections fires requests
+// continuously across reloads and expects zero connection errors.
+func TestHotReloadSwapsHandlerWithoutDroppingConnections(t *testing.T) {
+	m := newTestReloadManager(t, "@ GET /version {\n  > {v: 1}\n}\n")
+	base := fmt.Sprintf("http://%s", m.addr.String())
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	var (
+		stop     atomic.Bool
+		requests atomic.Int64
+		errs     []error
+		errMu    sync.Mutex
+		wg       sync.WaitGroup
+	)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				if _, err := fetchVersion(client, base); err != nil {
+					errMu.Lock()
+					errs = append(errs, err)
+					errMu.Unlock()
+				}
+				requests.Add(1)
+			}
+		}()
+	}
+
+	for v := 2; v <= 4; v++ {
+		time.Sleep(20 * time.Millisecond)
+		src := fmt.Sprintf("@ GET /version {\n  > {v: %d}\n}\n", v)
+		require.NoError(t, os.WriteFile(m.filePath, []byte(src), 0600))
+		m.reload()
+	}
+	time.Sleep(20 * time.Millisecond)
+	stop.Store(true)
+	wg.Wait()
+
+	assert.Empty(t, errs, "connection errors during reload")
+	assert.Greater(t, requests.Load(), int64(0))
+
+	v, err := fetchVersion(client, base)
+	require.NoError(t, err)
+	assert.Equal(t, float64(4), v)
+}
+
+// TestHotReloadParseErrorKeepsPreviousVersion verifies that a broken edit
+// keeps serving the previous router and pushes an error event to browsers.
+func TestHotReloadParseErrorKeepsPreviousVersion(t *testing.T) {
+	m := newTestReloadManager(t, "@ GET /version {\n  > {v: 1}\n}\n")
+	base := fmt.Sprintf("http://%s", m.addr.String())
+
+	resp, err := http.Get(base + "/__livereload")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+	readEvent := func() string {
+		var lines []string
+		for {
+			line, err := events.ReadString('\n')
+			require.NoError(t, err)
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+	assert.Contains(t, readEvent(), "connected")
+	require.Eventually(t, func() bool {
+		m.liveReloadMu.Lock()
+		defer m.liveReloadMu.Unlock()
+		return len(m.liveReloadConns) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(m.filePath, []byte("@ GET /version {\n  > {v: \n"), 0600))
+	m.reload()
+
+	event := readEvent()
+	assert.True(t, strings.HasPrefix(event, "event: error\n"), "got %q", event)
+	assert.Contains(t, event, `"action":"error"`)
+	assert.Contains(t, event, "parse error")
+
+	// A page opened while the error stands gets it on connecting
+	late, err := http.Get(base + "/__livereload")
+	require.NoError(t, err)
+	lateEvents := bufio.NewReader(late.Body)
+	var lateLines []string
+	for len(lateLines) < 4 {
+		line, err := lateEvents.ReadString('\n')
+		require.NoError(t, err)
+		lateLines = append(lateLines, line)
+	}
+	late.Body.Close()
+	assert.Equal(t, "event: error\n", lateLines[3])
+
+	v, err := fetchVersion(http.DefaultClient, base)
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), v)
+
+	// Fixing the file reloads normally
+	require.NoError(t, os.WriteFile(m.filePath, []byte("@ GET /version {\n  > {v: 2}\n}\n"), 0600))
+	m.reload()
+	assert.Contains(t, readEvent(), `"action":"reload"`)
+
+	v, err = fetchVersion(http.DefaultClient, base)
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), v)
+}
+
+// TestWatchReloadsOnNonEntryFile verifies that touching any watched file in
+// the project tree (including new subdirectories) triggers exactly one
+// debounced reload.
+func TestWatchReloadsOnNonEntryFile(t *testing.T) {
+	m := newTestReloadManager(t, "@ GET /version {\n  > {v: 1}\n}\n")
+	dir := filepath.Dir(m.filePath)
+	other := filepath.Join(dir, "types.glyph")
+	require.NoError(t, os.WriteFile(other, []byte(": User {\n  id: int!\n}\n"), 0600))
+
+	require.NoError(t, m.startWatching())
+	t.Cleanup(func() { m.watcher.Close() })
+
+	// A burst of writes to the non-entry file collapses into one reload
+	for i := 0; i < 5; i++ {
+		require.NoError(t, os.WriteFile(other, []byte(fmt.Sprintf(": User {\n  id: int!\n}\n# %d\n", i)), 0600))
+	}
+	require.Eventually(t, func() bool { return m.reloads.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, int64(1), m.reloads.Load())
+
+	// Unrelated extensions are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hi"), 0600))
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, int64(1), m.reloads.Load())
+
+	// Files in subdirectories created after startup are watched too
+	sub := filepath.Join(dir, "routes")
+	require.NoError(t, os.Mkdir(sub, 0750))
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, os.WriteFile(filepath.Join(sub, "users.glyph"), []byte("# users\n"), 0600))
+	require.Eventually(t, func() bool { return m.reloads.Load() == 2 }, 2*time.Second, 10*time.Millisecond)
+}
+
+// TestWatchExtensions verifies custom --watch-ext values
+func TestWatchExtensions(t *testing.T) {
+	m := &hotReloadManager{}
+	assert.True(t, m.isWatchedFile("/app/main.glyph"))
+	assert.True(t, m.isWatchedFile("/app/legacy.abc"))
+	assert.False(t, m.isWatchedFile("/app/.env"))
+
+	m.watchExts = []string{"env", ".json"}
+	assert.True(t, m.isWatchedFile("/app/config.JSON"))
+	assert.True(t, m.isWatchedFile("/app/.env"))
+	assert.False(t, m.isWatchedFile("/app/main.glyph"))
+}
+
+// TestDevServerServesImportedFiles verifies that routes and types from
+// imported files are served, and that editing an imported file outside the
+// watch roots triggers a reload
+func TestDevServerServesImportedFiles(t *testing.T) {
+	root := t.TempDir()
+	app := filepath.Join(root, "app")
+	shared := filepath.Join(root, "shared")
+	require.NoError(t, os.Mkdir(app, 0750))
+	require.NoError(t, os.Mkdir(shared, 0750))
+
+	entry := filepath.Join(app, "main.glyph")
+	routes := filepath.Join(shared, "routes.glyph")
+	require.NoError(t, os.WriteFile(entry, []byte("import \"./types.glyph\"\nimport \"../shared/routes.glyph\"\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(app, "types.glyph"), []byte(": User {\n  name: str!\n}\n"), 0600))
+	require.NoError(t, os.WriteFile(routes, []byte("@ GET /version {\n  > {v: 1}\n}\n"), 0600))
+
+	m := &hotReloadManager{
+		filePath:        entry,
+		port:            0,
+		liveReloadConns: make(map[*liveReloadConn]bool),
+	}
+	require.NoError(t, m.startServer())
+	t.Cleanup(func() { m.server.Close() })
+	require.NoError(t, m.startWatching())
+	t.Cleanup(func() { m.watcher.Close() })
+
+	base := fmt.Sprintf("http://%s", m.addr.String())
+	client := &http.Client{Timeout: 5 * time.Second}
+	v, err := fetchVersion(client, base)
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), v)
+
+	require.NoError(t, os.WriteFile(routes, []byte("@ GET /version {\n  > {v: 2}\n}\n"), 0600))
+	require.Eventually(t, func() bool {
+		v, err := fetchVersion(client, base)
+		return err == nil && v == 2
+	}, 3*time.Second, 20*time.Millisecond)
+}
+
+// TestDevServerWatchesFileThatFailedToLoad verifies that a newly imported
+// file outside the watch roots is watched even though it fails to parse, so
+// fixing it reloads
+func TestDevServerWatchesFileThatFailedToLoad(t *testing.T) {
+	root := t.TempDir()
+	app := filepath.Join(root, "app")
+	lib := filepath.Join(root, "lib")
+	require.NoError(t, os.Mkdir(app, 0750))
+	require.NoError(t, os.Mkdir(lib, 0750))
+
+	entry := filepath.Join(app, "main.glyph")
+	require.NoError(t, os.WriteFile(entry, []byte("@ GET /version {\n  > {v: 1}\n}\n"), 0600))
+	m := &hotReloadManager{
+		filePath:        entry,
+		port:            0,
+		liveReloadConns: make(map[*liveReloadConn]bool),
+	}
+	require.NoError(t, m.startServer())
+	t.Cleanup(func() { m.server.Close() })
+	require.NoError(t, m.startWatching())
+	t.Cleanup(func() { m.watcher.Close() })
+
+	broken := filepath.Join(lib, "version.glyph")
+	require.NoError(t, os.WriteFile(broken, []byte("@ GET /version {\n  > {v: \n"), 0600))
+	require.NoError(t, os.WriteFile(entry, []byte("import \"../lib/version.glyph\"\n"), 0600))
+	require.Eventually(t, func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.importDirs[lib]
+	}, 3*time.Second, 20*time.Millisecond)
+
+	base := fmt.Sprintf("http://%s", m.addr.String())
+	client := &http.Client{Timeout: 5 * time.Second}
+	v, err := fetchVersion(client, base)
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), v, "previous version keeps serving")
+
+	require.NoError(t, os.WriteFile(broken, []byte("@ GET /version {\n  > {v: 2}\n}\n"), 0600))
+	require.Eventually(t, func() bool {
+		v, err := fetchVersion(client, base)
+		return err == nil && v == 2
+	}, 3*time.Second, 20*time.Millisecond)
+}
+
+// TestDevServerServesOpenAPI verifies that the dev server serves the spec of
+// the current source at /__openapi.json and a Swagger UI page at /__docs.
+func TestDevServerServesOpenAPI(t *testing.T) {
+	m := newTestReloadManager(t, "@ GET /version {\n  > {v: 1}\n}\n")
+	base := fmt.Sprintf("http://%s", m.addr.String())
+
+	fetchPaths := func() map[string]interface{} {
+		resp, err := http.Get(base + "/__openapi.json")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		var spec map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&spec))
+		assert.Equal(t, "3.1.0", spec["openapi"])
+		return spec["paths"].(map[string]interface{})
+	}
+
+	paths := fetchPaths()
+	assert.Contains(t, paths, "/version")
+	assert.NotContains(t, paths, "/items/{id}")
+
+	src := "@ GET /version {\n  > {v: 2}\n}\n\n@ GET /items/:id {\n  > {id: id}\n}\n"
+	require.NoError(t, os.WriteFile(m.filePath, []byte(src), 0600))
+	m.reload()
+	assert.Contains(t, fetchPaths(), "/items/{id}")
+
+	resp, err := http.Get(base + "/__docs")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	page, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(page), "SwaggerUIBundle")
+	assert.Contains(t, string(page), "/__openapi.json")
+}
+
+// TestDevServerShutdownClosesWebSocketAndLiveReload verifies that shutting
+// the dev server down closes open WebSocket and live reload connections
+// instead of waiting for the shutdown timeout.
+func TestDevServerShutdownClosesWebSocketAndLiveReload(t *testing.T) {
+	m := newTestReloadManager(t, "@ ws /echo {\n  on message {\n    ws.send(input)\n  }\n}\n")
+	base := fmt.Sprintf("http://%s", m.addr.String())
+
+	client, _, err := gorilla.DefaultDialer.Dial(fmt.Sprintf("ws://%s/echo", m.addr.String()), nil)
+	require.NoError(t, err)
+	defer client.Close()
+	hub := m.app.Load().wsServer.GetHub()
+	require.Eventually(t, func() bool { return hub.GetConnectionCount() == 1 }, time.Second, 5*time.Millisecond)
+
+	resp, err := http.Get(base + "/__livereload")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Eventually(t, func() bool {
+		m.liveReloadMu.Lock()
+		defer m.liveReloadMu.Unlock()
+		return len(m.liveReloadConns) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	require.NoError(t, m.shutdown(ctx))
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = client.ReadMessage()
+	var closeErr *gorilla.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, gorilla.CloseGoingAway, closeErr.Code)
+
+	// The stream ends after the shutdown event
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "event: server-shutdown\n")
+}
+
+// TestHotReloadAsksWebSocketClientsToReconnect verifies that a reload sends
+// open WebSocket connections a reload message and a service-restart close,
+// and that a client reconnecting afterwards reaches the reloaded routes
+func TestHotReloadAsksWebSocketClientsToReconnect(t *testing.T) {
+	m := newTestReloadManager(t, "@ ws /echo {\n  on message {\n    ws.send(\"v1\")\n  }\n}\n")
+	wsURL := fmt.Sprintf("ws://%s/echo", m.addr.String())
+
+	client, _, err := gorilla.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer client.Close()
+	hub := m.app.Load().wsServer.GetHub()
+	require.Eventually(t, func() bool { return hub.GetConnectionCount() == 1 }, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(m.filePath, []byte("@ ws /echo {\n  on message {\n    ws.send(\"v2\")\n  }\n}\n"), 0600))
+	m.reload()
+
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := client.ReadMessage()
+	require.NoError(t, err)
+	var notice map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &notice))
+	assert.Equal(t, "reload", notice["type"])
+
+	_, _, err = client.ReadMessage()
+	var closeErr *gorilla.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, gorilla.CloseServiceRestart, closeErr.Code)
+
+	// The old hub stops once its client has gone
+	require.Eventually(t, func() bool { return hub.GetConnectionCount() == 0 }, 2*time.Second, 5*time.Millisecond)
+
+	reconnected, _, err := gorilla.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer reconnected.Close()
+	require.NoError(t, reconnected.WriteMessage(gorilla.TextMessage, []byte(`{"type":"text","data":"hi"}`)))
+	reconnected.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err = reconnected.ReadMessage()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "v2")
+}
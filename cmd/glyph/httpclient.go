@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/httpclient"
+)
+
+var (
+	sharedHTTPClient     *httpclient.Handler
+	sharedHTTPClientOnce sync.Once
+)
+
+// httpClient returns the process-wide handler behind the http.* builtins.
+// GLYPH_HTTP_TIMEOUT (a duration such as "10s") and
+// GLYPH_HTTP_MAX_RESPONSE_SIZE (bytes) override the defaults; an invalid
+// value is reported and the default is used.
+func httpClient() *httpclient.Handler {
+	sharedHTTPClientOnce.Do(func() {
+		var cfg httpclient.Config
+		if v := os.Getenv("GLYPH_HTTP_TIMEOUT"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				printWarning(fmt.Sprintf("GLYPH_HTTP_TIMEOUT: invalid duration %q, using %s", v, httpclient.DefaultTimeout))
+			} else {
+				cfg.Timeout = d
+			}
+		}
+		if v := os.Getenv("GLYPH_HTTP_MAX_RESPONSE_SIZE"); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n <= 0 {
+				printWarning(fmt.Sprintf("GLYPH_HTTP_MAX_RESPONSE_SIZE: invalid size %q, using %d bytes", v, httpclient.DefaultMaxResponseSize))
+			} else {
+				cfg.MaxResponseSize = n
+			}
+		}
+		sharedHTTPClient = httpclient.NewHandlerWithConfig(cfg)
+	})
+	return sharedHTTPClient
+}
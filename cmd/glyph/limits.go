@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/glyphlang/glyph/pkg/interpreter"
+)
+
+// defaultMaxSteps is how many instructions a compiled route may execute
+// unless GLYPH_MAX_STEPS says otherwise
+const defaultMaxSteps = 100_000_000
+
+// limits bounds how much work one route execution may do, so an infinite
+// loop fails the request instead of hanging it
+type limits struct {
+	maxSteps          int // Instructions per compiled route execution
+	maxLoopIterations int // Iterations per interpreted while or for loop
+}
+
+var (
+	sharedLimits     limits
+	sharedLimitsErr  error
+	sharedLimitsOnce sync.Once
+)
+
+// executionLimits returns the limits set by GLYPH_MAX_STEPS and
+// GLYPH_MAX_LOOP_ITERATIONS; 0 removes a limit
+func executionLimits() (limits, error) {
+	sharedLimitsOnce.Do(func() {
+		sharedLimits = limits{maxSteps: defaultMaxSteps, maxLoopIterations: interpreter.DefaultMaxLoopIterations}
+		for name, dst := range map[string]*int{
+			"GLYPH_MAX_STEPS":           &sharedLimits.maxSteps,
+			"GLYPH_MAX_LOOP_ITERATIONS": &sharedLimits.maxLoopIterations,
+		} {
+			v := os.Getenv(name)
+			if v == "" {
+				continue
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				sharedLimitsErr = fmt.Errorf("%s: expected a non-negative integer, got %q", name, v)
+				return
+			}
+			*dst = n
+		}
+	})
+	return sharedLimits, sharedLimitsErr
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestInfiniteLoopHitsExecutionLimit checks that in both execution modes a
+// route stuck in a loop fails with 508 instead of hanging
+func TestInfiniteLoopHitsExecutionLimit(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, `@ POST /spin {
+  $ n = 0
+  while true {
+    n = n + 1
+  }
+  > {n: n}
+}
+
+@ POST /count {
+  $ n = 0
+  for item in [1, 2, 3] {
+    n = n + item
+  }
+  > {n: n}
+}
+`, mode.interpreted)
+
+			status, body := postJSON(t, srv, "/spin", `{}`)
+			assert.Equal(t, http.StatusLoopDetected, status)
+			assert.Equal(t, map[string]interface{}{"error": "Execution limit exceeded"}, body)
+
+			// Routes that finish are unaffected
+			status, body = postJSON(t, srv, "/count", `{}`)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, map[string]interface{}{"n": float64(6)}, body)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/email"
+)
+
+// mailboxLimit is how many captured messages /__mailbox keeps
+const mailboxLimit = 100
+
+var (
+	sharedMailer     *email.Mailer
+	sharedMailbox    *email.CaptureProvider
+	sharedMailerErr  error
+	sharedMailerOnce sync.Once
+)
+
+// mailer returns the process-wide mailer injected as `% mail: Mailer`. It
+// sends through SMTP_HOST when that is set; otherwise messages are logged
+// and captured for /__mailbox. SMTP_FROM is the default sender.
+func mailer() (*email.Mailer, error) {
+	sharedMailerOnce.Do(func() {
+		cfg, ok, err := email.SMTPConfigFromEnv()
+		if err != nil {
+			sharedMailerErr = err
+			return
+		}
+		var provider email.Provider
+		if ok {
+			provider = email.NewSMTPProvider(cfg)
+		} else {
+			sharedMailbox = email.NewCaptureProvider(mailboxLimit)
+			provider = sharedMailbox
+		}
+		client := email.NewClient(provider)
+		client.SetDefaults(email.MessageDefaults{From: os.Getenv("SMTP_FROM")})
+		sharedMailer = email.NewMailer(client)
+	})
+	return sharedMailer, sharedMailerErr
+}
+
+// mailbox returns the captured messages' store, or nil when mail is sent
+// through SMTP
+func mailbox() *email.CaptureProvider {
+	mailer()
+	return sharedMailbox
+}
+
+// mailboxMessage is a captured message as listed by /__mailbox
+type mailboxMessage struct {
+	From    string    `json:"from"`
+	To      []string  `json:"to"`
+	CC      []string  `json:"cc,omitempty"`
+	ReplyTo string    `json:"replyTo,omitempty"`
+	Subject string    `json:"subject"`
+	Text    string    `json:"text,omitempty"`
+	HTML    string    `json:"html,omitempty"`
+	SentAt  time.Time `json:"sentAt"`
+}
+
+// handleMailbox lists the messages captured in development, newest first,
+// as a page or, when the Accept header asks for it, as JSON. DELETE clears
+// them.
+func handleMailbox(w http.ResponseWriter, r *http.Request) {
+	box := mailbox()
+	if box == nil {
+		http.Error(w, "mail is sent through SMTP_HOST, so none is captured", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodDelete:
+		box.Clear()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", "GET, HEAD, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	captured := box.Messages()
+	messages := make([]mailboxMessage, len(captured))
+	for i, msg := range captured {
+		messages[len(captured)-1-i] = mailboxMessage{
+			From: msg.From, To: msg.To, CC: msg.CC, ReplyTo: msg.ReplyTo,
+			Subject: msg.Subject, Text: msg.Body, HTML: msg.HTML, SentAt: msg.SentAt,
+		}
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(messages)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>Mailbox</title>
+    <style>
+        body { font-family: sans-serif; margin: 2em; }
+        article { border: 1px solid #ccc; border-radius: 4px; margin-bottom: 1.5em; padding: 1em; }
+        dl { display: grid; grid-template-columns: max-content auto; gap: 0.25em 1em; margin: 0 0 1em; }
+        dt { color: #666; }
+        pre { background: #f6f6f6; padding: 0.75em; white-space: pre-wrap; }
+        iframe { border: 1px solid #eee; width: 100%; height: 20em; }
+    </style>
+</head>
+<body>
+    <h1>Mailbox</h1>
+`)
+	if len(messages) == 0 {
+		fmt.Fprint(w, "    <p>No messages have been sent.</p>\n")
+	}
+	for _, msg := range messages {
+		fmt.Fprintf(w, "    <article>\n        <h2>%s</h2>\n        <dl>\n", html.EscapeString(msg.Subject))
+		for _, field := range [][2]string{
+			{"From", msg.From},
+			{"To", strings.Join(msg.To, ", ")},
+			{"Cc", strings.Join(msg.CC, ", ")},
+			{"Reply-To", msg.ReplyTo},
+			{"Sent", msg.SentAt.Format(time.RFC1123)},
+		} {
+			if field[1] != "" {
+				fmt.Fprintf(w, "            <dt>%s</dt><dd>%s</dd>\n", field[0], html.EscapeString(field[1]))
+			}
+		}
+		fmt.Fprint(w, "        </dl>\n")
+		if msg.Text != "" {
+			fmt.Fprintf(w, "        <pre>%s</pre>\n", html.EscapeString(msg.Text))
+		}
+		if msg.HTML != "" {
+			fmt.Fprintf(w, "        <iframe sandbox srcdoc=\"%s\"></iframe>\n", html.EscapeString(msg.HTML))
+		}
+		fmt.Fprint(w, "    </article>\n")
+	}
+	fmt.Fprint(w, `    <script src="/__livereload.js"></script>
+</body>
+</html>
+`)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMailSendCapturesInDevelopment checks mail.send in both execution
+// modes without SMTP_HOST, where messages are captured for /__mailbox, and
+// that a failed send is a result the route can inspect
+func TestMailSendCapturesInDevelopment(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, `@ POST /signup {
+  % mail: Mailer
+  > mail.send({
+    to: input.email,
+    from: "noreply@example.com",
+    subject: 'Welcome, {{name}}',
+    text: 'Hi {{name}}, thanks for signing up',
+    data: {name: input.name}
+  })
+}
+
+@ POST /broken {
+  % mail: Mailer
+  $ result = mail.send({to: "not-an-address", subject: "Hi", text: "Hello"})
+  if result.sent {
+    > {ok: true}
+  }
+  > {ok: false, error: result.error}
+}
+`, mode.interpreted)
+			box := mailbox()
+			require.NotNil(t, box)
+			box.Clear()
+
+			status, body := postJSON(t, srv, "/signup", `{"email": "ada@example.com", "name": "Ada"}`)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, map[string]interface{}{"sent": true}, body)
+
+			sent := box.Messages()
+			require.Len(t, sent, 1)
+			assert.Equal(t, []string{"ada@example.com"}, sent[0].To)
+			assert.Equal(t, "Welcome, Ada", sent[0].Subject)
+			assert.Equal(t, "Hi Ada, thanks for signing up", sent[0].Body)
+
+			status, body = postJSON(t, srv, "/broken", `{}`)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, false, body["ok"])
+			assert.Contains(t, body["error"], "invalid email address")
+		})
+	}
+}
+
+func TestMailboxEndpoint(t *testing.T) {
+	m, err := mailer()
+	require.NoError(t, err)
+	box := mailbox()
+	require.NotNil(t, box)
+	box.Clear()
+	for _, subject := range []string{"first", "second <b>"} {
+		result, err := m.Send(map[string]interface{}{"to": "ada@example.com", "from": "noreply@example.com", "subject": subject, "html": "<p>hi</p>"})
+		require.NoError(t, err)
+		require.Equal(t, true, result["sent"])
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/__mailbox", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	handleMailbox(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var messages []map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &messages))
+	require.Len(t, messages, 2)
+	assert.Equal(t, "second <b>", messages[0]["subject"])
+	assert.Equal(t, "first", messages[1]["subject"])
+
+	rec = httptest.NewRecorder()
+	handleMailbox(rec, httptest.NewRequest(http.MethodGet, "/__mailbox", nil))
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "<h2>second &lt;b&gt;</h2>")
+	assert.Contains(t, rec.Body.String(), `srcdoc="&lt;p&gt;hi&lt;/p&gt;"`)
+
+	rec = httptest.NewRecorder()
+	handleMailbox(rec, httptest.NewRequest(http.MethodDelete, "/__mailbox", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, box.Messages())
+}
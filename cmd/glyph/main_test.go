@@ -15,6 +15,18 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func TestMain(m *testing.M) {
+	// Keep the compiled route cache out of the user's cache directory
+	dir, err := os.MkdirTemp("", "glyph-cache-")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("GLYPH_CACHE_DIR", dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestChangeExtension(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -139,7 +151,7 @@ func TestExecuteRoute(t *testing.T) {
 	interp := interpreter.NewInterpreter()
 
 	// Execute the route - empty body returns a Response with nil Body
-	result, err := executeRoute(route, ctx, interp)
+	result, err := executeRoute(route, ctx, interp, nil, nil)
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	assert.Equal(t, 200, result.StatusCode)
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/glyphlang/glyph/pkg/metrics"
+	"github.com/glyphlang/glyph/pkg/server"
+	"github.com/glyphlang/glyph/pkg/websocket"
+	"github.com/spf13/cobra"
+)
+
+// serverMetrics, when set, records the bytecode execution time of compiled
+// routes
+var serverMetrics atomic.Pointer[metrics.Metrics]
+
+// metricsEnabled reports whether glyph run serves /metrics: the --metrics
+// flag, or GLYPH_METRICS set to 1 or true
+func metricsEnabled(cmd *cobra.Command) bool {
+	if enabled, _ := cmd.Flags().GetBool("metrics"); enabled {
+		return true
+	}
+	switch strings.ToLower(os.Getenv("GLYPH_METRICS")) {
+	case "1", "true":
+		return true
+	}
+	return false
+}
+
+// enableMetrics records request metrics for every route on router, exports
+// the open connections of hub, and serves them at /metrics
+func enableMetrics(m *metrics.Metrics, router *server.Router, hub *websocket.Hub) error {
+	router.Use(metrics.MetricsMiddleware(m))
+	if hub != nil {
+		if err := m.TrackWebSocketConnections(hub.GetConnectionCount); err != nil {
+			return fmt.Errorf("failed to register WebSocket metrics: %w", err)
+		}
+	}
+	if err := metrics.RegisterMetricsRoute(router, m); err != nil {
+		return fmt.Errorf("failed to register %s: %w", metrics.MetricsPath, err)
+	}
+	return nil
+}
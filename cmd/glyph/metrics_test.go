@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const metricsSource = `@ GET /users/:id {
+  > {id: id}
+}
+`
+
+// TestMetricsEndpoint checks that /metrics reports requests by route
+// pattern in both execution modes, and VM time for compiled routes
+func TestMetricsEndpoint(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			m := metrics.NewMetrics(metrics.DefaultConfig())
+			serverMetrics.Store(m)
+			t.Cleanup(func() { serverMetrics.Store(nil) })
+
+			srcFile := filepath.Join(t.TempDir(), "main.glyph")
+			require.NoError(t, os.WriteFile(srcFile, []byte(metricsSource), 0644))
+			program, err := loadProgram(srcFile)
+			require.NoError(t, err)
+			routes, err := setupRoutes(program, mode.interpreted)
+			require.NoError(t, err)
+			require.NoError(t, enableMetrics(m, routes.router, routes.wsServer.GetHub()))
+
+			srv := httptest.NewServer(createHandler(routes.router))
+			t.Cleanup(srv.Close)
+
+			for _, path := range []string{"/users/1", "/users/2", "/users/3"} {
+				resp, err := http.Get(srv.URL + path)
+				require.NoError(t, err)
+				resp.Body.Close()
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+			}
+
+			resp, err := http.Get(srv.URL + "/metrics")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			scrape := string(body)
+
+			assert.Contains(t, scrape, `glyphlang_http_requests_total{method="GET",path="/users/:id",status="200"} 3`)
+			assert.Contains(t, scrape, `glyphlang_http_request_duration_seconds_count{method="GET",path="/users/:id",status="200"} 3`)
+			assert.NotContains(t, scrape, `path="/users/1"`)
+			assert.Contains(t, scrape, "glyphlang_http_requests_in_flight 1")
+			assert.Contains(t, scrape, "glyphlang_websocket_connections 0")
+			if mode.interpreted {
+				assert.NotContains(t, scrape, "glyphlang_vm_execution_duration_seconds_count")
+			} else {
+				assert.Contains(t, scrape, `glyphlang_vm_execution_duration_seconds_count{method="GET",path="/users/:id"} 3`)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNamedMiddleware checks that both execution modes run a route's named
+// middleware first and answer with its error when it rejects the request
+func TestNamedMiddleware(t *testing.T) {
+	src := `@ middleware requireKey {
+  if headers["X-Api-Key"] != "secret" {
+    error(401, "missing api key")
+  }
+  $ client = "trusted"
+}
+
+@ GET /data {
+  + requireKey
+  > {client: client}
+}
+`
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, src, mode.interpreted)
+
+			get := func(key string) (int, map[string]interface{}) {
+				req, err := http.NewRequest(http.MethodGet, srv.URL+"/data", nil)
+				require.NoError(t, err)
+				req.Header.Set("X-Api-Key", key)
+				resp, err := http.DefaultClient.Do(req)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				var body map[string]interface{}
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+				return resp.StatusCode, body
+			}
+
+			status, body := get("secret")
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, map[string]interface{}{"client": "trusted"}, body)
+
+			status, body = get("wrong")
+			assert.Equal(t, http.StatusUnauthorized, status)
+			assert.Equal(t, map[string]interface{}{"error": "missing api key"}, body)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/interpreter"
+	"github.com/glyphlang/glyph/pkg/queue"
+)
+
+// startProgramQueueRunner starts the queue workers of a program on an
+// interpreter of their own, for compiled mode. It returns nil if the program
+// has no queue workers.
+func startProgramQueueRunner(program *interpreter.Program) (*interpreter.QueueRunner, error) {
+	hasWorkers := false
+	for _, item := range program.Module.Items {
+		if _, ok := item.(*ast.QueueWorker); ok {
+			hasWorkers = true
+			break
+		}
+	}
+	if !hasWorkers {
+		return nil, nil
+	}
+
+	interp := newConfiguredInterpreter()
+	program.Prime(interp.GetModuleResolver())
+	if err := interp.LoadModuleWithPath(*program.Module, filepath.Dir(program.Entry)); err != nil {
+		return nil, fmt.Errorf("failed to load module for queue workers: %w", err)
+	}
+	return startQueueRunner(interp)
+}
+
+// startQueueRunner starts delivering messages passed to enqueue() or
+// queue.publish() to the queue workers loaded into interp. Messages are held in memory unless
+// GLYPH_QUEUE_URL names a Redis server. It returns nil if there are no queue
+// workers.
+func startQueueRunner(interp *interpreter.Interpreter) (*interpreter.QueueRunner, error) {
+	workers := interp.GetQueueWorkers()
+	if len(workers) == 0 {
+		return nil, nil
+	}
+
+	q, err := queue.Open(os.Getenv("GLYPH_QUEUE_URL"))
+	if err != nil {
+		return nil, fmt.Errorf("GLYPH_QUEUE_URL: %w", err)
+	}
+
+	names := make([]string, 0, len(workers))
+	for name := range workers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// Messages left in flight by a previous run are delivered again
+		if rq, ok := q.(*queue.RedisQueue); ok {
+			if moved, err := rq.Recover(context.Background(), name); err != nil {
+				q.Close()
+				return nil, fmt.Errorf("queue %s: %w", name, err)
+			} else if moved > 0 {
+				printInfo(fmt.Sprintf("Queue %s: recovered %d unacknowledged message(s)", name, moved))
+			}
+		}
+		worker := workers[name]
+		printInfo(fmt.Sprintf("Queue worker: %s (concurrency %d, retries %d)", name, max(worker.Concurrency, 1), worker.MaxRetries))
+	}
+
+	runner := interpreter.NewQueueRunner(interp, q)
+	runner.OnDeadLetter = func(d interpreter.DeadLetter) {
+		printError(fmt.Errorf("queue %s: message dead-lettered after %d attempt(s): %w", d.Queue, d.Attempts, d.Err))
+	}
+	runner.OnProcessed = func(queueName string, result interface{}, elapsed time.Duration) {
+		printInfo(fmt.Sprintf("Queue worker %s completed in %s: %v", queueName, elapsed, result))
+	}
+	runner.OnError = func(queueName string, err error) {
+		printError(fmt.Errorf("queue %s: %w", queueName, err))
+	}
+	runner.Start()
+	return runner, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupRoutes_QueueWorkers(t *testing.T) {
+	for _, mode := range []struct {
+		name        string
+		interpreted bool
+	}{{"compiled", false}, {"interpreted", true}} {
+		t.Run(mode.name, func(t *testing.T) {
+			program := loadCronProgram(t, `& "email.send" {
+  + retries(0)
+  > undefined_handler(message.to)
+}
+
+@ POST /emails {
+  $ queued = queue.publish("email.send", {to: input.to})
+  > {queued: queued}
+}
+
+@ POST /jobs {
+  $ queued = enqueue("email.send", {to: "ops@example.com"})
+  > {queued: queued}
+}
+`)
+
+			routes, err := setupRoutes(program, mode.interpreted)
+			require.NoError(t, err)
+			require.NotNil(t, routes.queues)
+			defer routes.queues.Stop()
+			assert.Equal(t, !mode.interpreted, routes.useCompiler, "queue workers do not force interpreter mode")
+
+			for _, path := range []string{"/emails", "/jobs"} {
+				req := httptest.NewRequest("POST", path, strings.NewReader(`{"to": "ada@example.com"}`))
+				req.Header.Set("Content-Type", "application/json")
+				rec := httptest.NewRecorder()
+				createHandler(routes.router)(rec, req)
+				require.Equal(t, 200, rec.Code, rec.Body.String())
+				assert.Contains(t, rec.Body.String(), `"queued":true`)
+			}
+
+			// The worker fails, so the messages end up dead-lettered
+			require.Eventually(t, func() bool { return len(routes.queues.DeadLetters()) == 2 }, 5*time.Second, 10*time.Millisecond)
+			var recipients []interface{}
+			for _, dead := range routes.queues.DeadLetters() {
+				assert.Equal(t, "email.send", dead.Queue)
+				assert.Equal(t, 1, dead.Attempts)
+				recipients = append(recipients, dead.Message.(map[string]interface{})["to"])
+			}
+			assert.ElementsMatch(t, []interface{}{"ada@example.com", "ops@example.com"}, recipients)
+		})
+	}
+}
+
+func TestSetupRoutes_NoQueueWorkers(t *testing.T) {
+	routes, err := setupRoutes(loadCronProgram(t, validSource), true)
+	require.NoError(t, err)
+	assert.Nil(t, routes.queues)
+}
+
+func TestSetupRoutes_QueueURL(t *testing.T) {
+	t.Setenv("GLYPH_QUEUE_URL", "amqp://localhost")
+	_, err := setupRoutes(loadCronProgram(t, `& "jobs" {
+  > message
+}
+`))
+	assert.ErrorContains(t, err, `GLYPH_QUEUE_URL: unsupported queue URL scheme "amqp"`)
+}
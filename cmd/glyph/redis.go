@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/glyphlang/glyph/pkg/interpreter"
+	"github.com/glyphlang/glyph/pkg/redis"
+)
+
+var (
+	sharedRedisClient     redis.Client
+	sharedRedisClientErr  error
+	sharedRedisClientOnce sync.Once
+)
+
+// redisClient returns the process-wide client injected as `% redis: Redis`,
+// shared by every interpreter and compiled route. It connects to REDIS_URL,
+// or keeps everything in memory when that is unset.
+func redisClient() (redis.Client, error) {
+	sharedRedisClientOnce.Do(func() {
+		client, err := redis.Open(os.Getenv("REDIS_URL"))
+		if err != nil {
+			sharedRedisClientErr = fmt.Errorf("REDIS_URL: %w", err)
+			return
+		}
+		sharedRedisClient = client
+	})
+	return sharedRedisClient, sharedRedisClientErr
+}
+
+// subscribeEvents delivers messages published on Redis channels named after
+// the bus's event types as events of that type, so several servers sharing
+// a Redis see each other's events. Subscribing stops when the bus is closed.
+func subscribeEvents(bus *interpreter.EventBus, client redis.Client, eventTypes []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-bus.Done()
+		cancel()
+	}()
+	err := client.Listen(ctx, func(channel string, message interface{}) {
+		if err := bus.Emit(channel, message); err != nil {
+			printWarning(fmt.Sprintf("Redis event %s dropped: %v", channel, err))
+		}
+	}, eventTypes...)
+	if err != nil {
+		cancel()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRedisRateLimit checks a route rate-limited with redis.incr and
+// redis.expire in both execution modes, using the in-memory Redis that
+// serves when REDIS_URL is unset
+func TestRedisRateLimit(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, `@ POST /limited/:client {
+  % redis: Redis
+  $ key = "test:ratelimit:" + client
+  $ hits = redis.incr(key)
+  if hits == 1 {
+    $ expiring = redis.expire(key, 60)
+  }
+  if hits > 3 {
+    > {allowed: false, hits: hits}
+  }
+  > {allowed: true, hits: hits}
+}
+`, mode.interpreted)
+
+			client := "/limited/" + mode.name
+			for n := 1; n <= 5; n++ {
+				status, body := postJSON(t, srv, client, `{}`)
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, map[string]interface{}{"allowed": n <= 3, "hits": float64(n)}, body)
+			}
+			// Other clients have their own limit
+			_, body := postJSON(t, srv, client+"-other", `{}`)
+			assert.Equal(t, map[string]interface{}{"allowed": true, "hits": float64(1)}, body)
+		})
+	}
+}
+
+// TestRedisHashes checks hset, hget and objects round-tripping through JSON,
+// with the injection under a name other than redis and mixed-case methods
+func TestRedisHashes(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, `@ POST /profile/:name {
+  % store: Redis
+  $ key = "test:profile:" + name
+  $ added = store.hset(key, "name", name, "visits", 2)
+  $ saved = store.set(key + ":prefs", {theme: "dark"})
+  > {added: added, name: store.hget(key, "name"), visits: store.hGet(key, "visits"), prefs: store.get(key + ":prefs")}
+}
+`, mode.interpreted)
+
+			status, body := postJSON(t, srv, "/profile/"+mode.name, `{}`)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, map[string]interface{}{
+				"added":  float64(2),
+				"name":   mode.name,
+				"visits": float64(2),
+				"prefs":  map[string]interface{}{"theme": "dark"},
+			}, body)
+		})
+	}
+}
+
+// TestRedisPublishDeliversEvents checks that a message published on a
+// channel named after an event type reaches that event's handlers
+func TestRedisPublishDeliversEvents(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			var logs syncBuffer
+			log.SetOutput(&logs)
+			t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+			srcFile := filepath.Join(t.TempDir(), "main.glyph")
+			require.NoError(t, os.WriteFile(srcFile, []byte(`~ "order.placed" {
+  $ r = ship_order(event.id)
+}
+
+@ POST /orders {
+  % redis: Redis
+  $ receivers = redis.publish("order.placed", {id: 7})
+  > {receivers: receivers}
+}
+`), 0644))
+			program, err := loadProgram(srcFile)
+			require.NoError(t, err)
+			routes, err := setupRoutes(program, mode.interpreted)
+			require.NoError(t, err)
+			require.NotNil(t, routes.events)
+
+			srv := httptest.NewServer(createHandler(routes.router))
+			defer srv.Close()
+			status, body := postJSON(t, srv, "/orders", `{}`)
+			assert.Equal(t, http.StatusOK, status)
+			assert.GreaterOrEqual(t, body["receivers"], float64(1))
+
+			// Closing the bus waits for the delivered event to be handled
+			routes.events.Close()
+			assert.Contains(t, logs.String(), `event "order.placed" handler failed`)
+			assert.Contains(t, logs.String(), "ship_order")
+		})
+	}
+}
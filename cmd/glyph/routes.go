@@ -5,24 +5,82 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/glyphlang/glyph/pkg/ast"
 	"github.com/glyphlang/glyph/pkg/compiler"
+	"github.com/glyphlang/glyph/pkg/interpreter"
+	"github.com/glyphlang/glyph/pkg/metrics"
+	"github.com/glyphlang/glyph/pkg/parser"
 	"github.com/glyphlang/glyph/pkg/server"
+	"github.com/glyphlang/glyph/pkg/vm"
 	"github.com/glyphlang/glyph/pkg/web"
 	"github.com/glyphlang/glyph/pkg/websocket"
 )
 
+var (
+	sharedRouteCache     *compiler.RouteCache
+	sharedRouteCacheOnce sync.Once
+)
+
+// routeCache returns the process-wide compiled route cache, stored under
+// compiler.DefaultCacheDir. If no cache directory is available the cache
+// is kept in memory only.
+func routeCache() *compiler.RouteCache {
+	sharedRouteCacheOnce.Do(func() {
+		dir, err := compiler.DefaultCacheDir()
+		if err != nil {
+			dir = ""
+		}
+		sharedRouteCache = compiler.NewRouteCache(dir, 512)
+	})
+	return sharedRouteCache
+}
+
+// routeSetup is what setupRoutes sets up for a program
+type routeSetup struct {
+	useCompiler bool // Routes run compiled rather than on the interpreter
+	wsServer    *websocket.Server
+	router      *server.Router
+	queues      *interpreter.QueueRunner // Running queue runner, or nil if the program has no queue workers
+	events      *interpreter.EventBus    // Delivers emitted events, or nil if the program has no event handlers
+}
+
 // setupRoutes handles the common logic of determining execution mode, compiling routes,
-// and setting up the router. Used by both startServer and startDevServerInternal.
-// filePath is the path to the source file, used for resolving relative module imports.
-func setupRoutes(module *ast.Module, filePath string, forceInterpreter ...bool) (useCompiler bool, compiledRoutes map[string][]byte, wsServer *websocket.Server, router *server.Router, err error) {
-	useCompiler = true
+// and setting up the router. Used by both startServer and hotReloadManager.buildApp.
+// program is the entry file merged with everything it imports.
+func setupRoutes(program *interpreter.Program, forceInterpreter ...bool) (setup *routeSetup, err error) {
+	module := program.Module
+	useCompiler := true
 	if len(forceInterpreter) > 0 && forceInterpreter[0] {
 		useCompiler = false
 	}
-	compiledRoutes = make(map[string][]byte)
+	compiledRoutes := make(map[string][]byte)
+
+	if _, err = valueCache(); err != nil {
+		return
+	}
+	if _, err = redisClient(); err != nil {
+		return
+	}
+	if _, err = mailer(); err != nil {
+		return
+	}
+	if _, err = executionLimits(); err != nil {
+		return
+	}
+	if _, err = sessionManager(); err != nil {
+		return
+	}
+	if err = checkDatabase(module); err != nil {
+		return
+	}
+	useProgramTemplates(program)
+	errorHandlers, err := programErrorHandlers(module)
+	if err != nil {
+		return
+	}
 
 	// Check if any route has database injection - VM doesn't support db method calls
 	for _, item := range module.Items {
@@ -46,11 +104,17 @@ func setupRoutes(module *ast.Module, filePath string, forceInterpreter ...bool)
 	}
 
 	// Try to compile routes if using compiler mode
+	compiledErrorHandlers := make(map[ast.ErrorHandlerKind][]byte)
 	if useCompiler {
 		c := compiler.NewCompilerWithOptLevel(compiler.OptBasic)
+		c.SetFunctions(programFunctions(program))
+		c.SetMiddlewares(compiler.ModuleMiddlewares(module))
+		c.SetConstants(compiler.ModuleConstants(module))
+		c.SetEnums(compiler.ModuleEnums(module))
+		c.SetCache(routeCache())
 		for _, item := range module.Items {
 			if route, ok := item.(*ast.Route); ok {
-				bytecode, compileErr := c.CompileRoute(route)
+				bytecode, compileErr := c.CompileRouteCached(route, compiler.HashRoute(route))
 				if compileErr != nil {
 					// Semantic errors (like redeclaration) should fail completely, not fall back
 					if compiler.IsSemanticError(compileErr) {
@@ -64,25 +128,52 @@ func setupRoutes(module *ast.Module, filePath string, forceInterpreter ...bool)
 				compiledRoutes[route.Path] = bytecode
 			}
 		}
+		for kind, h := range errorHandlers {
+			if !useCompiler {
+				break
+			}
+			bytecode, compileErr := c.CompileErrorHandler(h)
+			if compileErr != nil {
+				if compiler.IsSemanticError(compileErr) {
+					err = fmt.Errorf("compilation error for @ %s: %v", kind, compileErr)
+					return
+				}
+				printWarning(fmt.Sprintf("Compilation failed for @ %s: %v, falling back to interpreter", kind, compileErr))
+				useCompiler = false
+				break
+			}
+			compiledErrorHandlers[kind] = bytecode
+		}
 	}
 
-	// Create WebSocket server with CORS-aware origin checking
-	var wsConfig *websocket.Config
-	if corsOrigin := os.Getenv("GLYPH_CORS_ORIGIN"); corsOrigin != "" {
-		wsConfig = websocket.DefaultConfig()
-		wsConfig.AllowedOrigins = []string{corsOrigin}
-	}
-	wsServer = websocket.NewServer(wsConfig)
+	wsServer := newWebSocketServer()
 
 	// Create router and register routes
-	router = server.NewRouter()
+	router, err := newRouter()
+	if err != nil {
+		return
+	}
 	interp := newConfiguredInterpreter()
+	var queues *interpreter.QueueRunner
+	var events *interpreter.EventBus
 
 	if useCompiler {
+		// Compiled routes validate their input against the module's types
+		types := moduleTypeChecker(module)
+
+		// Queue workers and event handlers run on interpreters of their own
+		if queues, err = startProgramQueueRunner(program); err != nil {
+			return
+		}
+		if events, err = startProgramEventBus(program); err != nil {
+			queues.Stop()
+			return
+		}
+
 		for _, item := range module.Items {
 			if route, ok := item.(*ast.Route); ok {
 				bytecode := compiledRoutes[route.Path]
-				regErr := registerCompiledRoute(router, route, bytecode, wsServer.GetHub())
+				regErr := registerCompiledRoute(router, route, bytecode, types, wsServer.GetHub(), queues, events)
 				if regErr != nil {
 					printWarning(fmt.Sprintf("Failed to register route %s: %v", route.Path, regErr))
 				} else {
@@ -90,9 +181,16 @@ func setupRoutes(module *ast.Module, filePath string, forceInterpreter ...bool)
 				}
 			}
 		}
+		for kind, bytecode := range compiledErrorHandlers {
+			handler := createCompiledRouteHandler(errorHandlerRoute(errorHandlers[kind]), bytecode, types, wsServer.GetHub(), queues, events)
+			setErrorHandler(router, kind, handler)
+		}
 
 		// Compile and register WebSocket routes
 		c := compiler.NewCompilerWithOptLevel(compiler.OptBasic)
+		c.SetFunctions(programFunctions(program))
+		c.SetConstants(compiler.ModuleConstants(module))
+		c.SetEnums(compiler.ModuleEnums(module))
 		for _, item := range module.Items {
 			if wsRoute, ok := item.(*ast.WebSocketRoute); ok {
 				compiledWs, compileErr := c.CompileWebSocketRoute(wsRoute)
@@ -100,14 +198,15 @@ func setupRoutes(module *ast.Module, filePath string, forceInterpreter ...bool)
 					printWarning(fmt.Sprintf("Failed to compile WebSocket route %s: %v", wsRoute.Path, compileErr))
 					continue
 				}
-				registerCompiledWebSocketRoute(wsServer, wsRoute.Path, compiledWs)
+				registerCompiledWebSocketRoute(wsServer, wsRoute.Path, compiledWs, types)
 				printInfo(fmt.Sprintf("Compiled WebSocket route: %s", wsRoute.Path))
 			}
 		}
 	} else {
 		// Use interpreter mode
-		// Pass the directory of the source file for proper module resolution
-		basePath := filepath.Dir(filePath)
+		// Reuse the files parsed by the loader when processing import statements
+		program.Prime(interp.GetModuleResolver())
+		basePath := filepath.Dir(program.Entry)
 		if loadErr := interp.LoadModuleWithPath(*module, basePath); loadErr != nil {
 			err = fmt.Errorf("failed to load module: %w", loadErr)
 			return
@@ -120,35 +219,136 @@ func setupRoutes(module *ast.Module, filePath string, forceInterpreter ...bool)
 				}
 			}
 		}
+		if err = registerErrorHandlers(router, module, interp); err != nil {
+			return
+		}
+		if queues, err = startQueueRunner(interp); err != nil {
+			return
+		}
+		events = startEventBus(interp)
 	}
 
-	return useCompiler, compiledRoutes, wsServer, router, nil
+	return &routeSetup{useCompiler: useCompiler, wsServer: wsServer, router: router, queues: queues, events: events}, nil
+}
+
+// newWebSocketServer creates the WebSocket server, checking origins against
+// GLYPH_CORS_ORIGIN when it is set
+func newWebSocketServer() *websocket.Server {
+	var wsConfig *websocket.Config
+	if corsOrigin := os.Getenv("GLYPH_CORS_ORIGIN"); corsOrigin != "" {
+		wsConfig = websocket.DefaultConfig()
+		wsConfig.AllowedOrigins = []string{corsOrigin}
+	}
+	return websocket.NewServer(wsConfig)
+}
+
+// newRouter creates the router routes are registered on, with request
+// tracing when it is enabled and recovery from panics in middleware. Route
+// handlers recover from their own panics.
+func newRouter() (*server.Router, error) {
+	router := server.NewRouter()
+	if err := serverTracing(); err != nil {
+		return nil, err
+	}
+	if tracingEnabled() {
+		// First, so request spans enclose every other middleware
+		router.Use(server.TracingMiddleware())
+	}
+	router.Use(server.RecoveryMiddleware())
+	return router, nil
+}
+
+// moduleTypeChecker returns a type checker for the type and enum
+// definitions of module, which compiled routes validate their input with
+func moduleTypeChecker(module *ast.Module) *interpreter.TypeChecker {
+	typeDefs := make(map[string]ast.TypeDef)
+	enumDefs := make(map[string]ast.EnumDef)
+	for _, item := range module.Items {
+		switch it := item.(type) {
+		case *ast.TypeDef:
+			typeDefs[it.Name] = *it
+		case *ast.EnumDef:
+			enumDefs[it.Name] = *it
+		}
+	}
+	types := interpreter.NewTypeChecker()
+	types.SetTypeDefs(typeDefs)
+	types.SetEnumDefs(enumDefs)
+	return types
+}
+
+// functionTypeChecker checks the arguments and results of compiled functions
+// against their type annotations, which the VM passes in source form
+type functionTypeChecker struct {
+	types  *interpreter.TypeChecker
+	parsed sync.Map // Type source to its ast.Type
+}
+
+// newFunctionTypeChecker returns a checker for the types of types, or nil
+// when types is nil
+func newFunctionTypeChecker(types *interpreter.TypeChecker) vm.TypeChecker {
+	if types == nil {
+		return nil
+	}
+	return &functionTypeChecker{types: types}
+}
+
+// CheckType implements vm.TypeChecker
+func (f *functionTypeChecker) CheckType(value interface{}, typ string) error {
+	parsed, ok := f.parsed.Load(typ)
+	if !ok {
+		tokens, err := parser.NewLexer(typ).Tokenize()
+		if err != nil {
+			return err
+		}
+		t, err := parser.NewParser(tokens).ParseType()
+		if err != nil {
+			return err
+		}
+		parsed, _ = f.parsed.LoadOrStore(typ, t)
+	}
+	return f.types.CheckType(value, parsed.(ast.Type))
 }
 
 // startServer is the unified server startup function used by both 'run' and 'dev' commands.
 // It handles database injection detection and automatic fallback to interpreter mode.
-func startServer(filePath string, port int, forceInterpreter bool) (*http.Server, error) {
-	// Read source file
-	source, err := os.ReadFile(filePath)
+// When withMetrics is set, request, VM and WebSocket metrics are served at
+// /metrics.
+func startServer(filePath string, port int, forceInterpreter bool, logFormat server.LogFormat, withMetrics bool) (*http.Server, *websocket.Server, error) {
+	// Read and parse the entry file and everything it imports
+	program, err := loadProgram(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, nil, err
+	}
+	module := program.Module
+	if err := checkSessionSecret(usesSessions(module), true); err != nil {
+		return nil, nil, err
 	}
 
-	// Parse the source
-	module, err := parseSource(string(source))
-	if err != nil {
-		return nil, fmt.Errorf("parse error: %w", err)
+	var m *metrics.Metrics
+	if withMetrics {
+		m = metrics.NewMetrics(metrics.DefaultConfig())
+		serverMetrics.Store(m)
 	}
 
 	// Use shared logic for route compilation/interpretation
-	useCompiler, _, wsServer, router, err := setupRoutes(module, filePath, forceInterpreter)
+	routes, err := setupRoutes(program, forceInterpreter)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if m != nil {
+		if err := enableMetrics(m, routes.router, routes.wsServer.GetHub()); err != nil {
+			routes.queues.Stop()
+			routes.events.Close()
+			return nil, nil, err
+		}
+		printInfo(fmt.Sprintf("Metrics: http://localhost:%d%s", port, metrics.MetricsPath))
 	}
 
 	// Create HTTP server
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", createHandler(router))
+	mux.HandleFunc("/", createHandler(routes.router))
 
 	// Register WebSocket routes with HTTP mux
 	for _, item := range module.Items {
@@ -156,31 +356,56 @@ func startServer(filePath string, port int, forceInterpreter bool) (*http.Server
 			path := wsRoute.Path
 			// Convert :param to {param} for Go's http.ServeMux pattern matching
 			muxPattern := server.ConvertPatternToMuxFormat(path)
-			mux.HandleFunc(muxPattern, wsServer.HandleWebSocketWithPattern(path))
+			mux.HandleFunc(muxPattern, routes.wsServer.HandleWebSocketWithPattern(path))
 			printInfo(fmt.Sprintf("WebSocket endpoint: ws://localhost:%d%s", port, path))
 		}
 	}
 
 	// Register static file routes
 	if err := registerStaticRoutes(mux, module, filePath, port); err != nil {
-		return nil, err
+		routes.queues.Stop()
+		routes.events.Close()
+		return nil, nil, err
 	}
 
-	srv := &http.Server{
+	cron, err := startCronScheduler(program)
+	if err != nil {
+		routes.queues.Stop()
+		routes.events.Close()
+		return nil, nil, err
+	}
+
+	srv := newHTTPServer(mux, port, logFormat)
+	srv.RegisterOnShutdown(cron.Stop)
+	srv.RegisterOnShutdown(routes.queues.Stop)
+	srv.RegisterOnShutdown(routes.events.Close)
+
+	mode := "compiled"
+	if !routes.useCompiler {
+		mode = "interpreted"
+	}
+	listenInBackground(srv, port, mode)
+
+	return srv, routes.wsServer, nil
+}
+
+// newHTTPServer creates the HTTP server for mux on port, logging requests
+// in logFormat
+func newHTTPServer(mux *http.ServeMux, port int, logFormat server.LogFormat) *http.Server {
+	return &http.Server{
 		Addr:           fmt.Sprintf(":%d", port),
-		Handler:        loggingMiddleware(mux),
+		Handler:        loggingMiddleware(mux, logFormat),
 		ReadTimeout:    15 * time.Second,
 		WriteTimeout:   15 * time.Second,
 		IdleTimeout:    60 * time.Second,
 		MaxHeaderBytes: 1 << 20, // 1 MB
 	}
+}
 
-	// Start server in background
+// listenInBackground starts srv in a goroutine and gives it a moment to
+// start listening. mode describes how routes run, for the startup message.
+func listenInBackground(srv *http.Server, port int, mode string) {
 	go func() {
-		mode := "compiled"
-		if !useCompiler {
-			mode = "interpreted"
-		}
 		printSuccess(fmt.Sprintf("Server listening on http://localhost:%d (%s mode)", port, mode))
 		printInfo("Press Ctrl+C to stop")
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -190,8 +415,6 @@ func startServer(filePath string, port int, forceInterpreter bool) (*http.Server
 
 	// Give server time to start
 	time.Sleep(100 * time.Millisecond)
-
-	return srv, nil
 }
 
 // registerStaticRoutes registers any @ static directives from the module on the mux.
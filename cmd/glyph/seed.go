@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/database"
+	"github.com/glyphlang/glyph/pkg/interpreter"
+	"github.com/spf13/cobra"
+)
+
+// runSeed runs the seed blocks of a file against the database, each in a
+// transaction of its own. With --fresh the migrations are rolled back and
+// applied again first; with --only just the named block runs.
+func runSeed(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
+	only, _ := cmd.Flags().GetString("only")
+	fresh, _ := cmd.Flags().GetBool("fresh")
+
+	program, err := loadProgram(filePath)
+	if err != nil {
+		return err
+	}
+
+	interp := newConfiguredInterpreter()
+	program.Prime(interp.GetModuleResolver())
+	if err := interp.LoadModuleWithPath(*program.Module, filepath.Dir(program.Entry)); err != nil {
+		return fmt.Errorf("failed to load module: %w", err)
+	}
+
+	blocks, err := selectSeedBlocks(interp.GetSeedBlocks(), only, filePath)
+	if err != nil {
+		return err
+	}
+
+	db, err := connectDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if fresh {
+		dir, _ := cmd.Flags().GetString("migrations")
+		if !cmd.Flags().Changed("migrations") {
+			dir = filepath.Join(filepath.Dir(filePath), dir)
+		}
+		if err := refreshMigrations(ctx, db, dir); err != nil {
+			return err
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	seedDB := database.NewInterpreterDatabase(database.NewHandler(db))
+	for _, block := range blocks {
+		name := interpreter.SeedBlockName(&block)
+		err := seedDB.Transaction(ctx, func(tx *database.InterpreterDatabase) error {
+			_, err := interp.ExecuteSeedBlock(&block, tx)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("seed %s failed, its changes were rolled back: %w", name, err)
+		}
+		fmt.Fprintf(out, "Seeded %s\n", name)
+	}
+	printSuccess(fmt.Sprintf("Ran %d seed block(s)", len(blocks)))
+	return nil
+}
+
+// selectSeedBlocks returns the block named only, or every block if only is
+// empty
+func selectSeedBlocks(blocks []ast.SeedBlock, only, filePath string) ([]ast.SeedBlock, error) {
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no seed blocks found in %s", filePath)
+	}
+	if only == "" {
+		return blocks, nil
+	}
+
+	var available []string
+	for _, block := range blocks {
+		if block.Name == only {
+			return []ast.SeedBlock{block}, nil
+		}
+		if block.Name != "" {
+			available = append(available, block.Name)
+		}
+	}
+	return nil, fmt.Errorf("seed block '%s' not found. Available seed blocks: %v", only, available)
+}
+
+// refreshMigrations rolls back every applied migration in dir and applies
+// them all again, leaving empty tables
+func refreshMigrations(ctx context.Context, db database.Database, dir string) error {
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("--fresh needs a migrations directory: %w", err)
+	}
+	migrations, err := database.LoadMigrations(dir)
+	if err != nil {
+		return err
+	}
+
+	migrator := database.NewMigrator(db, migrations)
+	applied, err := migrator.Applied(ctx)
+	if err != nil {
+		return err
+	}
+	if len(applied) > 0 {
+		if _, err := migrator.Down(ctx, len(applied)); err != nil {
+			return err
+		}
+	}
+	reapplied, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+	printInfo(fmt.Sprintf("Rolled back %d and applied %d migration(s)", len(applied), len(reapplied)))
+	return nil
+}
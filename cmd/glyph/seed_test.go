@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/database"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSeedCommand(t *testing.T, args ...string) (*cobra.Command, *strings.Builder) {
+	t.Helper()
+	cmd := &cobra.Command{}
+	cmd.Flags().String("database", "", "")
+	cmd.Flags().String("only", "", "")
+	cmd.Flags().Bool("fresh", false, "")
+	cmd.Flags().String("migrations", "migrations", "")
+	require.NoError(t, cmd.Flags().Parse(args))
+	out := &strings.Builder{}
+	cmd.SetOut(out)
+	return cmd, out
+}
+
+// queryCount returns the result of a COUNT query on the database at connStr
+func queryCount(t *testing.T, connStr, query string) int64 {
+	t.Helper()
+	db, err := database.NewDatabaseFromString(connStr)
+	require.NoError(t, err)
+	require.NoError(t, db.Connect(context.Background()))
+	defer db.Close()
+
+	var n int64
+	require.NoError(t, db.QueryRow(context.Background(), query).Scan(&n))
+	return n
+}
+
+// TestRunSeed_RestAPITemplate syncs and seeds the rest-api template, which
+// seeds two users no matter how often it runs
+func TestRunSeed_RestAPITemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcFile := filepath.Join(tmpDir, "main.glyph")
+	require.NoError(t, os.WriteFile(srcFile, []byte(getRestAPITemplate()), 0644))
+	connStr := "sqlite://" + filepath.ToSlash(filepath.Join(tmpDir, "app.db"))
+
+	syncCmd := &cobra.Command{}
+	syncCmd.Flags().String("database", connStr, "")
+	syncCmd.SetOut(&strings.Builder{})
+	require.NoError(t, runDBSync(syncCmd, []string{srcFile}))
+
+	for range 2 {
+		cmd, out := newSeedCommand(t, "--database", connStr)
+		require.NoError(t, runSeed(cmd, []string{srcFile}))
+		assert.Equal(t, "Seeded users\n", out.String())
+	}
+	assert.Equal(t, int64(2), queryCount(t, connStr, "SELECT COUNT(*) FROM users"))
+	assert.Equal(t, int64(1), queryCount(t, connStr, "SELECT COUNT(*) FROM users WHERE email = 'ada@example.com' AND name = 'Ada Lovelace'"))
+}
+
+func TestRunSeed_Only(t *testing.T) {
+	connStr := newSQLiteFile(t)
+	srcFile := filepath.Join(t.TempDir(), "main.glyph")
+	require.NoError(t, os.WriteFile(srcFile, []byte(`seed! admins {
+  db.users.create({name: "root"})
+}
+
+seed! demo {
+  db.users.create({name: "demo"})
+}
+`), 0644))
+
+	cmd, out := newSeedCommand(t, "--database", connStr, "--only", "demo")
+	require.NoError(t, runSeed(cmd, []string{srcFile}))
+	assert.Equal(t, "Seeded demo\n", out.String())
+	assert.Equal(t, int64(0), queryCount(t, connStr, "SELECT COUNT(*) FROM users WHERE name = 'root'"))
+	assert.Equal(t, int64(1), queryCount(t, connStr, "SELECT COUNT(*) FROM users WHERE name = 'demo'"))
+
+	cmd, _ = newSeedCommand(t, "--database", connStr, "--only", "missing")
+	assert.ErrorContains(t, runSeed(cmd, []string{srcFile}), "Available seed blocks: [admins demo]")
+}
+
+func TestRunSeed_RollsBackAFailedBlock(t *testing.T) {
+	connStr := newSQLiteFile(t)
+	srcFile := filepath.Join(t.TempDir(), "main.glyph")
+	require.NoError(t, os.WriteFile(srcFile, []byte(`seed! users {
+  db.users.create({name: "ada"})
+  db.users.create({name: "bob"})
+  $ broken = 1 / 0
+}
+`), 0644))
+
+	cmd, out := newSeedCommand(t, "--database", connStr)
+	err := runSeed(cmd, []string{srcFile})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "seed users failed, its changes were rolled back")
+	assert.Contains(t, err.Error(), "division by zero")
+	assert.Empty(t, out.String())
+	assert.Equal(t, int64(0), queryCount(t, connStr, "SELECT COUNT(*) FROM users"))
+}
+
+func TestRunSeed_Fresh(t *testing.T) {
+	tmpDir := t.TempDir()
+	migrationsDir := filepath.Join(tmpDir, "migrations")
+	require.NoError(t, os.Mkdir(migrationsDir, 0755))
+	for name, content := range map[string]string{
+		"001_create_notes.up.sql":   "CREATE TABLE notes (id INTEGER PRIMARY KEY AUTOINCREMENT, body TEXT);",
+		"001_create_notes.down.sql": "DROP TABLE notes;",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(migrationsDir, name), []byte(content), 0644))
+	}
+	srcFile := filepath.Join(tmpDir, "main.glyph")
+	require.NoError(t, os.WriteFile(srcFile, []byte(`seed! {
+  db.notes.create({body: "hello"})
+}
+`), 0644))
+	connStr := "sqlite://" + filepath.ToSlash(filepath.Join(tmpDir, "app.db"))
+
+	// Without --fresh seeding adds to the table; with it the table is
+	// recreated first. The migrations directory defaults to the file's.
+	for _, args := range [][]string{{"--fresh"}, nil, {"--fresh"}} {
+		cmd, out := newSeedCommand(t, append([]string{"--database", connStr}, args...)...)
+		require.NoError(t, runSeed(cmd, []string{srcFile}))
+		assert.Equal(t, "Seeded seed\n", out.String())
+	}
+	assert.Equal(t, int64(1), queryCount(t, connStr, "SELECT COUNT(*) FROM notes"))
+
+	cmd, _ := newSeedCommand(t, "--database", connStr, "--fresh", "--migrations", filepath.Join(tmpDir, "missing"))
+	assert.ErrorContains(t, runSeed(cmd, []string{srcFile}), "--fresh needs a migrations directory")
+}
+
+func TestRunSeed_NoSeedBlocks(t *testing.T) {
+	srcFile := filepath.Join(t.TempDir(), "main.glyph")
+	require.NoError(t, os.WriteFile(srcFile, []byte("@ GET /health {\n  > {ok: true}\n}\n"), 0644))
+
+	cmd, _ := newSeedCommand(t, "--database", newSQLiteFile(t))
+	assert.ErrorContains(t, runSeed(cmd, []string{srcFile}), "no seed blocks found")
+}
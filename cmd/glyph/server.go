@@ -2,30 +2,64 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/glyphlang/glyph/pkg/ast"
+	"html"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/glyphlang/glyph/pkg/ast"
 	"github.com/glyphlang/glyph/pkg/compiler"
+	"github.com/glyphlang/glyph/pkg/interpreter"
+	"github.com/glyphlang/glyph/pkg/openapi"
+	"github.com/glyphlang/glyph/pkg/scheduler"
 	"github.com/glyphlang/glyph/pkg/server"
 	"github.com/glyphlang/glyph/pkg/vm"
 	"github.com/glyphlang/glyph/pkg/websocket"
 )
 
-// hotReloadManager manages server lifecycle for hot reload
+// hotReloadManager manages server lifecycle for hot reload.
+// A single http.Server stays bound for the lifetime of the dev session;
+// reloads build a fresh application handler and swap it in atomically so
+// the port is never re-bound and in-flight requests finish on the handler
+// they started with.
 type hotReloadManager struct {
 	filePath        string
 	port            int
+	logFormat       server.LogFormat
 	server          *http.Server
+	addr            net.Addr
+	app             atomic.Pointer[devApp]
 	mu              sync.Mutex
 	watcher         *fsnotify.Watcher
+	watchDirs       []string        // Directories watched recursively (default: entry file's directory)
+	watchExts       []string        // Extensions that trigger a reload (default: .glyph, .abc)
+	importDirs      map[string]bool // Directories of imported files outside the watch roots
+	loadedFiles     []string        // Files read by the last build, even a failed one
+	reloads         atomic.Int64
 	liveReloadConns map[*liveReloadConn]bool
 	liveReloadMu    sync.Mutex
+	reloadErr       string // Message of the last failed reload, until one succeeds
+}
+
+// devApp is one generation of the application handler built from source
+type devApp struct {
+	handler     http.Handler
+	module      *ast.Module // Source of the /__openapi.json spec
+	useCompiler bool
+	wsServer    *websocket.Server
+	cron        *scheduler.Scheduler     // Nil if the program has no cron tasks
+	queues      *interpreter.QueueRunner // Nil if the program has no queue workers
+	events      *interpreter.EventBus    // Nil if the program has no event handlers
 }
 
 // liveReloadConn represents a live reload SSE connection
@@ -35,60 +69,121 @@ type liveReloadConn struct {
 	done    chan struct{}
 }
 
-// startServer starts or restarts the server
+// startServer builds the application and, on first call, binds the listener.
+// Subsequent calls rebuild the application and swap it in without touching
+// the listener. On error the previously loaded application keeps serving.
 func (m *hotReloadManager) startServer() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Stop existing server if running
+	app, err := m.buildApp()
+	if err != nil {
+		return err
+	}
+	// Only the current generation's cron tasks, queue workers and event
+	// handlers run. WebSocket clients of the previous generation are told
+	// to reconnect once the new one is serving, so they land on it.
+	if old := m.app.Swap(app); old != nil {
+		old.cron.Stop()
+		old.queues.Stop()
+		old.events.Close()
+		go reloadWebSockets(old.wsServer)
+	}
+
 	if m.server != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		m.server.Shutdown(ctx)
-		time.Sleep(100 * time.Millisecond) // Allow port to be released
+		return nil
 	}
 
-	// Start dev server with live reload support
-	srv, err := m.startDevServerInternal()
+	// Stable outer mux: live reload and API docs endpoints survive reloads,
+	// everything else is delegated to the current application generation.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/__livereload", m.handleLiveReload)
+	mux.HandleFunc("/__livereload.js", m.handleLiveReloadScript)
+	mux.HandleFunc("/__openapi.json", m.handleOpenAPI)
+	mux.HandleFunc("/__docs", m.handleAPIDocs)
+	mux.HandleFunc("/__mailbox", handleMailbox)
+	mux.HandleFunc("/", m.serveApp)
+
+	// Bind synchronously so port conflicts surface as an error here
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", m.port))
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to listen on port %d: %w", m.port, err)
+	}
+	m.addr = ln.Addr()
+	if tcpAddr, ok := m.addr.(*net.TCPAddr); ok {
+		m.port = tcpAddr.Port
 	}
 
+	srv := &http.Server{
+		Handler:        loggingMiddleware(mux, m.logFormat),
+		ReadTimeout:    15 * time.Second,
+		WriteTimeout:   15 * time.Second,
+		IdleTimeout:    60 * time.Second,
+		MaxHeaderBytes: 1 << 20, // 1 MB
+	}
 	m.server = srv
+
+	mode := "compiled"
+	if !app.useCompiler {
+		mode = "interpreted"
+	}
+	printSuccess(fmt.Sprintf("Dev server listening on http://localhost:%d (%s mode)", m.port, mode))
+	printInfo("Live reload enabled at /__livereload")
+	printInfo(fmt.Sprintf("API docs at http://localhost:%d/__docs", m.port))
+	if mailbox() != nil {
+		printInfo(fmt.Sprintf("Captured mail at http://localhost:%d/__mailbox", m.port))
+	}
+	printInfo("Press Ctrl+C to stop")
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			printError(fmt.Errorf("server error: %w", err))
+		}
+	}()
+
 	return nil
 }
 
-// startDevServerInternal starts the development server with live reload support
-func (m *hotReloadManager) startDevServerInternal() (*http.Server, error) {
-	// Read source file
-	source, err := os.ReadFile(m.filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+// serveApp dispatches a request to the current application generation
+func (m *hotReloadManager) serveApp(w http.ResponseWriter, r *http.Request) {
+	app := m.app.Load()
+	if app == nil {
+		http.Error(w, "application not loaded", http.StatusServiceUnavailable)
+		return
 	}
+	app.handler.ServeHTTP(w, r)
+}
 
-	// Parse the source
-	module, err := parseSource(string(source))
+// buildApp parses the source file and builds the application handler
+func (m *hotReloadManager) buildApp() (*devApp, error) {
+	// Read and parse the entry file and everything it imports. The files
+	// read are watched even if loading fails, so fixing them reloads.
+	loader := interpreter.NewProgramLoader(parseSource)
+	program, err := loader.Load(m.filePath)
+	m.loadedFiles = loader.Visited()
 	if err != nil {
-		return nil, fmt.Errorf("parse error: %w", err)
+		return nil, err
+	}
+	module := program.Module
+	if err := checkSessionSecret(usesSessions(module), false); err != nil {
+		return nil, err
 	}
 
 	// Use shared logic for route compilation/interpretation
-	useCompiler, _, wsServer, router, err := setupRoutes(module, m.filePath)
+	before := routeCache().Stats()
+	routes, err := setupRoutes(program)
 	if err != nil {
 		return nil, err
 	}
+	if routes.useCompiler {
+		after := routeCache().Stats()
+		printInfo(fmt.Sprintf("Route cache: %d hit(s), %d compiled", after.Hits()-before.Hits(), after.Misses-before.Misses))
+	}
 
-	// Create HTTP server with live reload support
 	mux := http.NewServeMux()
 
-	// Live reload SSE endpoint
-	mux.HandleFunc("/__livereload", m.handleLiveReload)
-
-	// Live reload script endpoint
-	mux.HandleFunc("/__livereload.js", m.handleLiveReloadScript)
-
 	// Main application handler
-	mux.HandleFunc("/", createHandler(router))
+	mux.HandleFunc("/", createHandler(routes.router))
 
 	// Register WebSocket routes
 	for _, item := range module.Items {
@@ -96,43 +191,42 @@ func (m *hotReloadManager) startDevServerInternal() (*http.Server, error) {
 			path := wsRoute.Path
 			// Convert :param to {param} for Go's http.ServeMux pattern matching
 			muxPattern := server.ConvertPatternToMuxFormat(path)
-			mux.HandleFunc(muxPattern, wsServer.HandleWebSocketWithPattern(path))
+			mux.HandleFunc(muxPattern, routes.wsServer.HandleWebSocketWithPattern(path))
 			printInfo(fmt.Sprintf("WebSocket endpoint: ws://localhost:%d%s", m.port, path))
 		}
 	}
 
 	// Register static file routes
 	if err := registerStaticRoutes(mux, module, m.filePath, m.port); err != nil {
+		routes.queues.Stop()
+		routes.events.Close()
 		return nil, err
 	}
 
-	srv := &http.Server{
-		Addr:           fmt.Sprintf(":%d", m.port),
-		Handler:        loggingMiddleware(mux),
-		ReadTimeout:    15 * time.Second,
-		WriteTimeout:   15 * time.Second,
-		IdleTimeout:    60 * time.Second,
-		MaxHeaderBytes: 1 << 20, // 1 MB
+	cron, err := startCronScheduler(program)
+	if err != nil {
+		routes.queues.Stop()
+		routes.events.Close()
+		return nil, err
 	}
 
-	// Start server in background
-	go func() {
-		mode := "compiled"
-		if !useCompiler {
-			mode = "interpreted"
-		}
-		printSuccess(fmt.Sprintf("Dev server listening on http://localhost:%d (%s mode)", m.port, mode))
-		printInfo("Live reload enabled at /__livereload")
-		printInfo("Press Ctrl+C to stop")
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			printError(fmt.Errorf("server error: %w", err))
-		}
-	}()
-
-	// Give server time to start
-	time.Sleep(100 * time.Millisecond)
+	return &devApp{handler: mux, module: module, useCompiler: routes.useCompiler, wsServer: routes.wsServer, cron: cron, queues: routes.queues, events: routes.events}, nil
+}
 
-	return srv, nil
+// reloadWebSockets sends every client of a replaced WebSocket server a
+// reload message and closes its connection with a service-restart frame
+func reloadWebSockets(wsServer *websocket.Server) {
+	if wsServer == nil {
+		return
+	}
+	if n := wsServer.GetHub().GetConnectionCount(); n > 0 {
+		printInfo(fmt.Sprintf("Asking %d WebSocket client(s) to reconnect", n))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := wsServer.Reload(ctx); err != nil {
+		printWarning(fmt.Sprintf("WebSocket connections did not close in time: %v", err))
+	}
 }
 
 // handleLiveReload handles Server-Sent Events for live reload
@@ -156,22 +250,31 @@ func (m *hotReloadManager) handleLiveReload(w http.ResponseWriter, r *http.Reque
 		done:    make(chan struct{}),
 	}
 
-	// Register connection
+	// Send the connected event, and the error of a failed reload so a page
+	// opened after it shows the overlay too, while holding the lock, so
+	// broadcasts never write to the connection concurrently with this
+	// handler
 	m.liveReloadMu.Lock()
-	m.liveReloadConns[conn] = true
-	m.liveReloadMu.Unlock()
-
-	// Send initial connected event
 	fmt.Fprintf(w, "event: connected\ndata: {\"status\":\"connected\"}\n\n")
+	if m.reloadErr != "" {
+		writeLiveReloadEvent(w, "error", map[string]string{"action": "error", "message": m.reloadErr})
+	}
 	flusher.Flush()
+	m.liveReloadConns[conn] = true
+	m.liveReloadMu.Unlock()
 
-	// Wait for disconnect
-	<-r.Context().Done()
+	// Wait for the client to disconnect or the server to close the stream
+	select {
+	case <-r.Context().Done():
+	case <-conn.done:
+	}
 
-	// Unregister connection
+	// Unregister connection, unless closeLiveReloadConns already has
 	m.liveReloadMu.Lock()
-	delete(m.liveReloadConns, conn)
-	close(conn.done)
+	if m.liveReloadConns[conn] {
+		delete(m.liveReloadConns, conn)
+		close(conn.done)
+	}
 	m.liveReloadMu.Unlock()
 }
 
@@ -187,18 +290,102 @@ func (m *hotReloadManager) handleLiveReloadScript(w http.ResponseWriter, r *http
             window.location.reload();
         }
     };
+    // Failed reloads arrive as error events with data; a lost connection
+    // fires the same listener without any
+    es.addEventListener('error', function(e) {
+        if (!e.data) {
+            console.log('[LiveReload] Connection lost. Retrying...');
+            return;
+        }
+        var data = JSON.parse(e.data);
+        console.error('[LiveReload] ' + data.message);
+        showOverlay(data.message);
+    });
+    function showOverlay(message) {
+        var el = document.getElementById('__glyph_error_overlay');
+        if (!el) {
+            el = document.createElement('pre');
+            el.id = '__glyph_error_overlay';
+            el.style.cssText = 'position:fixed;top:0;left:0;right:0;bottom:0;margin:0;padding:24px;' +
+                'background:rgba(20,20,20,0.95);color:#ff6b6b;font:14px monospace;' +
+                'white-space:pre-wrap;z-index:2147483647;overflow:auto';
+            el.onclick = function() { el.remove(); };
+            document.body.appendChild(el);
+        }
+        el.textContent = 'Reload failed (serving previous version)\n\n' + message;
+    }
     es.addEventListener('connected', function(e) {
         console.log('[LiveReload] Connected');
     });
-    es.onerror = function() {
-        console.log('[LiveReload] Connection lost. Retrying...');
-    };
+    es.addEventListener('server-shutdown', function(e) {
+        console.log('[LiveReload] Server stopped');
+        es.close();
+    });
 })();`, m.port)
 	w.Write([]byte(script))
 }
 
+// handleOpenAPI serves the OpenAPI spec of the current application generation
+func (m *hotReloadManager) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	app := m.app.Load()
+	if app == nil {
+		http.Error(w, "application not loaded", http.StatusServiceUnavailable)
+		return
+	}
+	spec := openapi.GenerateFromModule(app.module, defaultAPITitle(m.filePath), "1.0.0")
+	data, err := spec.ToJSON()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(data)
+}
+
+// handleAPIDocs serves a Swagger UI page for /__openapi.json
+func (m *hotReloadManager) handleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>%s</title>
+    <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+    <div id="swagger-ui"></div>
+    <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+    <script>
+        window.ui = SwaggerUIBundle({url: '/__openapi.json', dom_id: '#swagger-ui'});
+    </script>
+    <script src="/__livereload.js"></script>
+</body>
+</html>
+`, html.EscapeString(defaultAPITitle(m.filePath)))
+}
+
 // notifyLiveReload sends a reload notification to all connected clients
 func (m *hotReloadManager) notifyLiveReload() {
+	m.liveReloadMu.Lock()
+	m.reloadErr = ""
+	m.liveReloadMu.Unlock()
+	m.broadcastLiveReload("", map[string]string{"action": "reload"})
+}
+
+// notifyLiveReloadError sends a failed-reload error event so browsers can
+// show an error overlay while the previous version keeps serving. Clients
+// that connect before the next successful reload get it too.
+func (m *hotReloadManager) notifyLiveReloadError(err error) {
+	m.liveReloadMu.Lock()
+	m.reloadErr = err.Error()
+	m.liveReloadMu.Unlock()
+	m.broadcastLiveReload("error", map[string]string{"action": "error", "message": err.Error()})
+}
+
+// broadcastLiveReload sends a JSON SSE message to all connected clients,
+// as the named event, or as a plain message if event is empty
+func (m *hotReloadManager) broadcastLiveReload(event string, payload map[string]string) {
 	m.liveReloadMu.Lock()
 	defer m.liveReloadMu.Unlock()
 
@@ -207,30 +394,152 @@ func (m *hotReloadManager) notifyLiveReload() {
 		case <-conn.done:
 			continue
 		default:
-			fmt.Fprintf(conn.writer, "data: {\"action\":\"reload\"}\n\n")
+			writeLiveReloadEvent(conn.writer, event, payload)
 			conn.flusher.Flush()
 		}
 	}
 }
 
-// watchForChanges watches the file and triggers reload on changes
-func (m *hotReloadManager) watchForChanges() {
-	watcher, err := fsnotify.NewWatcher()
+// writeLiveReloadEvent writes payload to w as a JSON SSE message, named
+// event unless event is empty
+func writeLiveReloadEvent(w io.Writer, event string, payload map[string]string) {
+	data, err := json.Marshal(payload)
 	if err != nil {
-		printError(fmt.Errorf("failed to create watcher: %w", err))
 		return
 	}
-	m.watcher = watcher
+	if event != "" {
+		fmt.Fprintf(w, "event: %s\n", event)
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
+
+// closeLiveReloadConns sends every live reload client a server-shutdown
+// event, so the script stops reconnecting, and ends their SSE streams
+func (m *hotReloadManager) closeLiveReloadConns() {
+	m.liveReloadMu.Lock()
+	defer m.liveReloadMu.Unlock()
+
+	for conn := range m.liveReloadConns {
+		fmt.Fprintf(conn.writer, "event: server-shutdown\ndata: {\"status\":\"shutdown\"}\n\n")
+		conn.flusher.Flush()
+		delete(m.liveReloadConns, conn)
+		close(conn.done)
+	}
+}
+
+// defaultWatchExts are the file extensions that trigger a reload by default
+var defaultWatchExts = []string{".glyph", ".abc"}
+
+// skippedWatchDirs are directory names never descended into while watching
+var skippedWatchDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+}
+
+// startWatching sets up a recursive watcher over the watch directories
+// (the entry file's directory by default) and starts the event loop
+func (m *hotReloadManager) startWatching() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
 
-	// Watch the file's directory (more reliable for editors that do atomic saves)
-	dir := filepath.Dir(m.filePath)
-	filename := filepath.Base(m.filePath)
+	for _, dir := range m.watchRoots() {
+		if err := addWatchTree(watcher, dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("failed to watch directory %s: %w", dir, err)
+		}
+	}
+
+	m.watcher = watcher
+	m.watchImportedFiles()
+	go m.watchForChanges(watcher)
+	return nil
+}
 
-	if err := watcher.Add(dir); err != nil {
-		printError(fmt.Errorf("failed to watch directory: %w", err))
+// watchImportedFiles adds the directories of imported files that live
+// outside the watch roots, so editing them also triggers a reload. After a
+// failed reload these include the file that failed to load.
+func (m *hotReloadManager) watchImportedFiles() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.watcher == nil {
 		return
 	}
+	for _, file := range m.loadedFiles {
+		dir := filepath.Dir(file)
+		if m.isUnderWatchRoot(dir) || m.importDirs[dir] {
+			continue
+		}
+		if err := m.watcher.Add(dir); err != nil {
+			printError(fmt.Errorf("failed to watch directory %s: %w", dir, err))
+			continue
+		}
+		if m.importDirs == nil {
+			m.importDirs = make(map[string]bool)
+		}
+		m.importDirs[dir] = true
+	}
+}
 
+// isUnderWatchRoot reports whether dir is already covered by a recursive watch
+func (m *hotReloadManager) isUnderWatchRoot(dir string) bool {
+	for _, root := range m.watchRoots() {
+		rel, err := filepath.Rel(root, dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchRoots returns the directories to watch recursively
+func (m *hotReloadManager) watchRoots() []string {
+	if len(m.watchDirs) > 0 {
+		return m.watchDirs
+	}
+	return []string{filepath.Dir(m.filePath)}
+}
+
+// addWatchTree adds root and all of its subdirectories to the watcher.
+// Hidden directories and dependency folders are skipped.
+func addWatchTree(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		if path != root && (skippedWatchDirs[name] || strings.HasPrefix(name, ".")) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// isWatchedFile reports whether a change to path should trigger a reload
+func (m *hotReloadManager) isWatchedFile(path string) bool {
+	exts := m.watchExts
+	if len(exts) == 0 {
+		exts = defaultWatchExts
+	}
+	ext := filepath.Ext(path)
+	for _, want := range exts {
+		if !strings.HasPrefix(want, ".") {
+			want = "." + want
+		}
+		if strings.EqualFold(ext, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchForChanges reacts to file system events and triggers reloads.
+// Bursts of events across any number of files collapse into one reload.
+func (m *hotReloadManager) watchForChanges(watcher *fsnotify.Watcher) {
 	// Debounce timer to avoid multiple reloads
 	var debounceTimer *time.Timer
 	debounceDelay := 100 * time.Millisecond
@@ -239,16 +548,28 @@ func (m *hotReloadManager) watchForChanges() {
 		select {
 		case event, ok := <-watcher.Events:
 			if !ok {
+				if debounceTimer != nil {
+					debounceTimer.Stop()
+				}
 				return
 			}
 
-			// Only react to our file
-			if filepath.Base(event.Name) != filename {
+			// Start watching directories created after startup
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := addWatchTree(watcher, event.Name); err != nil {
+						printError(fmt.Errorf("failed to watch directory: %w", err))
+					}
+					continue
+				}
+			}
+
+			if !m.isWatchedFile(event.Name) {
 				continue
 			}
 
-			// React to write or create events (create happens with atomic saves)
-			if event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+			// React to writes, creates (atomic saves), removes and renames
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
 				// Debounce: reset timer on each event
 				if debounceTimer != nil {
 					debounceTimer.Stop()
@@ -269,14 +590,18 @@ func (m *hotReloadManager) watchForChanges() {
 
 // reload reloads the server with updated code
 func (m *hotReloadManager) reload() {
+	m.reloads.Add(1)
 	printWarning("\nFile changed, reloading...")
 	start := time.Now()
 
 	if err := m.startServer(); err != nil {
 		printError(fmt.Errorf("reload failed: %w", err))
 		printWarning("Server still running with previous version")
+		m.watchImportedFiles()
+		m.notifyLiveReloadError(err)
 	} else {
 		printSuccess(fmt.Sprintf("Hot reload complete (%s)", time.Since(start)))
+		m.watchImportedFiles()
 		// Notify all connected browsers to reload
 		m.notifyLiveReload()
 	}
@@ -291,6 +616,20 @@ func (m *hotReloadManager) waitForShutdown() error {
 
 	printWarning("\nShutting down server...")
 
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := m.shutdown(ctx); err != nil {
+		return err
+	}
+
+	printSuccess("Server stopped gracefully")
+	return nil
+}
+
+// shutdown stops watching and shuts the dev server down. Live reload streams
+// and WebSocket connections are closed before the http.Server is shut
+// down, since Shutdown would otherwise wait on them until ctx expires.
+func (m *hotReloadManager) shutdown(ctx context.Context) error {
 	// Close watcher
 	if m.watcher != nil {
 		m.watcher.Close()
@@ -300,33 +639,56 @@ func (m *hotReloadManager) waitForShutdown() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	app := m.app.Load()
+	if app != nil {
+		app.cron.Stop()
+		app.queues.Stop()
+	}
+
+	m.closeLiveReloadConns()
 	if m.server != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		if err := m.server.Shutdown(ctx); err != nil {
+		var wsServer *websocket.Server
+		if app != nil {
+			wsServer = app.wsServer
+		}
+		if err := shutdownServer(ctx, m.server, wsServer); err != nil {
 			return fmt.Errorf("server shutdown failed: %w", err)
 		}
 	}
-
-	printSuccess("Server stopped gracefully")
+	waitForBackgroundTasks()
+	shutdownTracing(context.Background())
 	return nil
 }
 
-// registerCompiledWebSocketRoute registers a compiled WebSocket route with event handlers
-func registerCompiledWebSocketRoute(wsServer *websocket.Server, path string, compiled *compiler.CompiledWebSocketRoute) {
+// registerCompiledWebSocketRoute registers a compiled WebSocket route with
+// event handlers. types holds the module's type definitions, which the
+// functions the handlers call check their arguments and results against.
+// Like route handlers, the event handlers may read only the environment
+// variables allowed by GLYPH_ENV_PREFIXES.
+func registerCompiledWebSocketRoute(wsServer *websocket.Server, path string, compiled *compiler.CompiledWebSocketRoute, types *interpreter.TypeChecker) {
 	hub := wsServer.GetHub()
+	checker := newFunctionTypeChecker(types)
+	prefixes := envPrefixes()
 
 	// Register connect handler for this specific route
 	if len(compiled.OnConnect) > 0 {
 		hub.OnConnectForRoute(path, func(conn *websocket.Connection) error {
-			return executeWebSocketBytecode(compiled.OnConnect, conn, hub, nil)
+			err := executeWebSocketBytecode(compiled.OnConnect, conn, hub, nil, checker, prefixes)
+			var rejected *websocket.RejectError
+			if err != nil && !errors.As(err, &rejected) {
+				// Fail closed: the handler may have stopped before checking
+				// the client's credentials
+				printWarning(fmt.Sprintf("WebSocket connect handler for %s failed: %v", path, err))
+				return &websocket.RejectError{Code: 1011, Reason: "connect handler failed"} // 1011: internal error
+			}
+			return err
 		})
 	}
 
 	// Register disconnect handler for this specific route
 	if len(compiled.OnDisconnect) > 0 {
 		hub.OnDisconnectForRoute(path, func(conn *websocket.Connection) error {
-			return executeWebSocketBytecode(compiled.OnDisconnect, conn, hub, nil)
+			return executeWebSocketBytecode(compiled.OnDisconnect, conn, hub, nil, checker, prefixes)
 		})
 	}
 
@@ -338,21 +700,24 @@ func registerCompiledWebSocketRoute(wsServer *websocket.Server, path string, com
 			if ctx.Conn.RoutePattern() != routePath {
 				return nil // Skip - not for this route
 			}
-			return executeWebSocketBytecode(compiled.OnMessage, ctx.Conn, hub, ctx.Message)
+			return executeWebSocketBytecode(compiled.OnMessage, ctx.Conn, hub, ctx.Message, checker, prefixes)
 		})
 		hub.OnMessage(websocket.MessageTypeText, func(ctx *websocket.MessageContext) error {
 			if ctx.Conn.RoutePattern() != routePath {
 				return nil // Skip - not for this route
 			}
-			return executeWebSocketBytecode(compiled.OnMessage, ctx.Conn, hub, ctx.Message)
+			return executeWebSocketBytecode(compiled.OnMessage, ctx.Conn, hub, ctx.Message, checker, prefixes)
 		})
 	}
 }
 
 // executeWebSocketBytecode executes compiled WebSocket event bytecode
-func executeWebSocketBytecode(bytecode []byte, conn *websocket.Connection, hub *websocket.Hub, msg *websocket.Message) error {
-	// Create VM instance
-	vmInstance := vm.NewVM()
+func executeWebSocketBytecode(bytecode []byte, conn *websocket.Connection, hub *websocket.Hub, msg *websocket.Message, types vm.TypeChecker, envPrefixes []string) error {
+	// Borrow a VM; releasing it also clears the connection's handler
+	vmInstance := vmPool.Acquire()
+	defer vmPool.Release(vmInstance)
+	vmInstance.SetTypeChecker(types)
+	vmInstance.SetEnvPrefixes(envPrefixes)
 
 	// Create WebSocket handler adapter
 	wsHandler := websocket.NewVMHandler(conn, hub)
@@ -366,6 +731,10 @@ func executeWebSocketBytecode(bytecode []byte, conn *websocket.Connection, hub *
 		vmInstance.SetLocal(key, vm.StringValue{Val: value})
 	}
 
+	// Expose the upgrade request's query parameters and headers
+	vmInstance.SetLocal("query", firstValues(conn.Query))
+	vmInstance.SetLocal("headers", firstValues(conn.Header))
+
 	// Set input data if message is provided
 	if msg != nil {
 		vmInstance.SetLocal("input", convertMessageToValue(msg))
@@ -376,6 +745,18 @@ func executeWebSocketBytecode(bytecode []byte, conn *websocket.Connection, hub *
 	return err
 }
 
+// firstValues converts query parameters or headers to an object holding the
+// first value of each key
+func firstValues(values map[string][]string) vm.ObjectValue {
+	obj := make(map[string]vm.Value, len(values))
+	for k, vals := range values {
+		if len(vals) > 0 {
+			obj[k] = vm.StringValue{Val: vals[0]}
+		}
+	}
+	return vm.ObjectValue{Val: obj}
+}
+
 // convertMessageToValue converts a WebSocket message to a VM Value
 func convertMessageToValue(msg *websocket.Message) vm.Value {
 	if msg == nil {
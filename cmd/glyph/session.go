@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/server"
+	"github.com/glyphlang/glyph/pkg/session"
+)
+
+var (
+	sharedSessions     *session.Manager
+	sharedSessionsErr  error
+	sharedSessionsOnce sync.Once
+)
+
+// sessionManager returns the process-wide manager behind request.cookies,
+// setCookie and session.*. Sessions are signed with SESSION_SECRET, or with
+// a random key that lasts until the server stops when it is unset, which
+// checkSessionSecret only allows outside glyph run. They are
+// kept in the cookie itself unless SESSION_STORE=cache keeps them in the
+// shared cache (Redis when CACHE_URL is set).
+func sessionManager() (*session.Manager, error) {
+	sharedSessionsOnce.Do(func() {
+		secret := []byte(os.Getenv("SESSION_SECRET"))
+		if len(secret) == 0 {
+			secret = make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				sharedSessionsErr = fmt.Errorf("SESSION_SECRET: %w", err)
+				return
+			}
+		}
+		var opts []session.Option
+		switch store := os.Getenv("SESSION_STORE"); store {
+		case "", "cookie":
+		case "cache":
+			c, err := valueCache()
+			if err != nil {
+				sharedSessionsErr = err
+				return
+			}
+			opts = append(opts, session.WithStore(c))
+		default:
+			sharedSessionsErr = fmt.Errorf(`SESSION_STORE: unknown store %q (want "cookie" or "cache")`, store)
+			return
+		}
+		m, err := session.NewManager(secret, opts...)
+		if err != nil {
+			sharedSessionsErr = fmt.Errorf("SESSION_SECRET: %w", err)
+			return
+		}
+		sharedSessions = m
+	})
+	return sharedSessions, sharedSessionsErr
+}
+
+// errNoSessionSecret stops glyph run from serving a program that uses
+// sessions with a key that is lost when the server stops
+var errNoSessionSecret = errors.New("SESSION_SECRET is not set: the program uses sessions, which need a key of at least 32 bytes that lasts across restarts")
+
+// checkSessionSecret reports a program that uses session.* while
+// SESSION_SECRET is unset: with an error when strict, as for glyph run, and
+// otherwise with a warning, as for glyph dev
+func checkSessionSecret(sessions, strict bool) error {
+	if os.Getenv("SESSION_SECRET") != "" || !sessions {
+		return nil
+	}
+	if strict {
+		return errNoSessionSecret
+	}
+	printWarning("No SESSION_SECRET set, signing sessions with a random key that changes on every restart")
+	return nil
+}
+
+// usesSessions reports whether anything in module calls a session.* builtin
+func usesSessions(module *ast.Module) bool {
+	return callsSession(reflect.ValueOf(module))
+}
+
+var functionCallType = reflect.TypeOf(ast.FunctionCallExpr{})
+
+// callsSession walks the AST node v looking for a session.* call
+func callsSession(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return !v.IsNil() && callsSession(v.Elem())
+	case reflect.Struct:
+		if v.Type() == functionCallType && strings.HasPrefix(v.FieldByName("Name").String(), "session.") {
+			return true
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() && callsSession(v.Field(i)) {
+				return true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if callsSession(v.Index(i)) {
+				return true
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if callsSession(iter.Value()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// beginSession starts the cookie and session state of a route's request
+func beginSession(ctx *server.Context) (*session.Request, error) {
+	m, err := sessionManager()
+	if err != nil {
+		return nil, err
+	}
+	return m.Begin(ctx.Request), nil
+}
+
+// applySession saves the route's session and adds the cookies it set to the
+// response. It must run before the response header is written.
+func applySession(ctx *server.Context, state *session.Request) error {
+	if state == nil {
+		return nil
+	}
+	return state.Apply(ctx.ResponseWriter)
+}
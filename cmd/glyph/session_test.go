@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sessionSource = `@ POST /login {
+  session.regenerate()
+  session.set("user", {name: input.name, visits: 1})
+  setCookie("theme", "dark", {maxAge: 3600, httpOnly: false})
+  > {ok: true}
+}
+
+@ GET /me {
+  $ user = session.get("user")
+  if user == null {
+    > {user: null, cookies: request.cookies}
+  }
+  > {user: user.name, visits: user.visits, cookies: request.cookies}
+}
+
+@ POST /logout {
+  session.destroy()
+  clearCookie("theme")
+  > {ok: true}
+}
+`
+
+// TestSessionLoginLogout runs a login/logout flow against a browser-like
+// cookie jar in both execution modes, and checks that a tampered session
+// cookie is ignored
+func TestSessionLoginLogout(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, sessionSource, mode.interpreted)
+			jar, err := cookiejar.New(nil)
+			require.NoError(t, err)
+			client := &http.Client{Jar: jar}
+
+			get := func(client *http.Client) map[string]interface{} {
+				resp, err := client.Get(srv.URL + "/me")
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+				var body map[string]interface{}
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+				return body
+			}
+			post := func(path, body string) *http.Response {
+				resp, err := client.Post(srv.URL+path, "application/json", strings.NewReader(body))
+				require.NoError(t, err)
+				resp.Body.Close()
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+				return resp
+			}
+
+			noCookies := map[string]interface{}{}
+			assert.Equal(t, map[string]interface{}{"user": nil, "cookies": noCookies}, get(client))
+
+			resp := post("/login", `{"name": "ada"}`)
+			var sessionCookie *http.Cookie
+			for _, c := range resp.Cookies() {
+				if c.Name == "glyph_session" {
+					sessionCookie = c
+				}
+			}
+			require.NotNil(t, sessionCookie)
+			assert.True(t, sessionCookie.HttpOnly)
+			assert.Equal(t, http.SameSiteLaxMode, sessionCookie.SameSite)
+
+			me := get(client)
+			assert.Equal(t, "ada", me["user"])
+			assert.Equal(t, float64(1), me["visits"])
+			assert.Equal(t, "dark", me["cookies"].(map[string]interface{})["theme"])
+
+			// A cookie whose payload was changed fails its signature
+			forged := *sessionCookie
+			forged.Value = "x" + forged.Value
+			forgedJar, err := cookiejar.New(nil)
+			require.NoError(t, err)
+			forgedJar.SetCookies(resp.Request.URL, []*http.Cookie{&forged})
+			assert.Nil(t, get(&http.Client{Jar: forgedJar})["user"])
+
+			post("/logout", `{}`)
+			assert.Equal(t, map[string]interface{}{"user": nil, "cookies": noCookies}, get(client))
+		})
+	}
+}
+
+func TestSetCookieRejectsBadOptions(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, `@ GET /bad {
+  setCookie("theme", "dark", {sameSite: "sometimes"})
+  > {ok: true}
+}
+`, mode.interpreted)
+			resp, err := http.Get(srv.URL + "/bad")
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+			assert.Empty(t, resp.Header.Values("Set-Cookie"))
+		})
+	}
+}
+
+// TestSessionResponseHeaders checks the Set-Cookie headers sent by a route
+// that saves its session and sets a cookie
+func TestSessionResponseHeaders(t *testing.T) {
+	srv := startInputValidationServer(t, sessionSource, true)
+	resp, err := http.Post(srv.URL+"/login", "application/json", strings.NewReader(`{"name": "ada"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	headers := resp.Header.Values("Set-Cookie")
+	require.Len(t, headers, 2)
+	assert.Equal(t, "theme=dark; Path=/; Max-Age=3600; SameSite=Lax", headers[0])
+	assert.True(t, strings.HasPrefix(headers[1], "glyph_session="))
+	assert.Contains(t, headers[1], "Max-Age=86400; HttpOnly; SameSite=Lax")
+}
+
+// TestSessionSecretRequired checks that glyph run refuses a program using
+// sessions without SESSION_SECRET, that glyph dev only warns, and that
+// programs without sessions need no secret
+func TestSessionSecretRequired(t *testing.T) {
+	t.Setenv("SESSION_SECRET", "")
+	dir := t.TempDir()
+	withSessions := filepath.Join(dir, "sessions.glyph")
+	require.NoError(t, os.WriteFile(withSessions, []byte(sessionSource), 0644))
+	withoutSessions := filepath.Join(dir, "plain.glyph")
+	require.NoError(t, os.WriteFile(withoutSessions, []byte("@ GET /ping {\n  > {ok: true}\n}\n"), 0644))
+
+	_, _, err := startServer(withSessions, 0, true, server.LogFormatText, false)
+	assert.ErrorIs(t, err, errNoSessionSecret)
+
+	program, err := loadProgram(withSessions)
+	require.NoError(t, err)
+	assert.True(t, usesSessions(program.Module))
+	assert.NoError(t, checkSessionSecret(usesSessions(program.Module), false))
+
+	program, err = loadProgram(withoutSessions)
+	require.NoError(t, err)
+	assert.False(t, usesSessions(program.Module))
+	assert.NoError(t, checkSessionSecret(usesSessions(program.Module), true))
+
+	t.Setenv("SESSION_SECRET", "0123456789abcdef0123456789abcdef")
+	program, err = loadProgram(withSessions)
+	require.NoError(t, err)
+	assert.NoError(t, checkSessionSecret(usesSessions(program.Module), true))
+}
+
+// TestBeginSessionReportsMisconfiguration checks that a route does not run
+// without sessions when they cannot be set up
+func TestBeginSessionReportsMisconfiguration(t *testing.T) {
+	t.Setenv("SESSION_STORE", "disk")
+	resetSharedSessions(t)
+	_, err := beginSession(&server.Context{Request: httptest.NewRequest(http.MethodGet, "/", nil)})
+	assert.ErrorContains(t, err, `unknown store "disk"`)
+}
+
+// TestSessionRegenerateWithStore checks that logging in with a server-side
+// store moves the session to a new ID, so an ID planted before the login no
+// longer reaches it
+func TestSessionRegenerateWithStore(t *testing.T) {
+	t.Setenv("SESSION_STORE", "cache")
+	resetSharedSessions(t)
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, sessionSource, mode.interpreted)
+			sessionCookie := func(resp *http.Response) *http.Cookie {
+				for _, c := range resp.Cookies() {
+					if c.Name == "glyph_session" {
+						return c
+					}
+				}
+				return nil
+			}
+
+			// A session the attacker started and handed to the victim
+			planted := sessionCookie(postWithCookie(t, srv.URL+"/login", `{"name": "mallory"}`, nil))
+			require.NotNil(t, planted)
+
+			resp := postWithCookie(t, srv.URL+"/login", `{"name": "ada"}`, planted)
+			loggedIn := sessionCookie(resp)
+			require.NotNil(t, loggedIn)
+			assert.NotEqual(t, planted.Value, loggedIn.Value)
+
+			jar, err := cookiejar.New(nil)
+			require.NoError(t, err)
+			jar.SetCookies(resp.Request.URL, []*http.Cookie{planted})
+			resp, err = (&http.Client{Jar: jar}).Get(srv.URL + "/me")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Nil(t, body["user"])
+		})
+	}
+}
+
+// postWithCookie posts body to url, sending cookie when it is not nil
+func postWithCookie(t *testing.T, url, body string, cookie *http.Cookie) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	return resp
+}
+
+// resetSharedSessions makes sessionManager read the environment again, and
+// again after the test
+func resetSharedSessions(t *testing.T) {
+	reset := func() {
+		sharedSessions, sharedSessionsErr, sharedSessionsOnce = nil, nil, sync.Once{}
+	}
+	reset()
+	t.Cleanup(reset)
+}
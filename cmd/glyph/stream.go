@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/glyphlang/glyph/pkg/interpreter"
+	"github.com/glyphlang/glyph/pkg/server"
+	"github.com/glyphlang/glyph/pkg/session"
+	"github.com/glyphlang/glyph/pkg/sse"
+)
+
+// routeStream is the response of an SSE route, which yield sends events to
+// in both the interpreter and the VM. Events are sent as Server-Sent Events,
+// or as newline-delimited JSON when the Accept header asks for
+// application/x-ndjson. Each event is flushed as it is sent.
+//
+// The stream is opened by the first event, so a route that fails before
+// yielding anything still gets an ordinary error response.
+type routeStream struct {
+	ctx    *server.Context
+	state  *session.Request      // Cookies and session, sent when the stream opens
+	writer interpreter.SSEWriter // nil until the stream is opened
+}
+
+func newRouteStream(ctx *server.Context, state *session.Request) *routeStream {
+	return &routeStream{ctx: ctx, state: state}
+}
+
+// SendEvent sends one event. Once the client has disconnected it fails
+// without writing, which stops the route.
+func (s *routeStream) SendEvent(data interface{}, eventType string) error {
+	if err := s.ctx.Request.Context().Err(); err != nil {
+		return fmt.Errorf("client disconnected: %w", err)
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+	return s.writer.SendEvent(data, eventType)
+}
+
+// open writes the response headers for the stream's format. Cookies set
+// and session changes made after it has opened are not sent.
+func (s *routeStream) open() error {
+	if s.writer != nil {
+		return nil
+	}
+	if err := applySession(s.ctx, s.state); err != nil {
+		return err
+	}
+	if acceptsNDJSON(s.ctx.Request.Header.Get("Accept")) {
+		w, err := sse.NewNDJSONWriter(s.ctx.ResponseWriter)
+		if err != nil {
+			return err
+		}
+		s.writer = w
+		return nil
+	}
+	w, err := sse.NewWriter(s.ctx.ResponseWriter)
+	if err != nil {
+		return err
+	}
+	s.writer = w
+	return nil
+}
+
+// started reports whether the response has begun, after which an error can
+// no longer be sent as a status code
+func (s *routeStream) started() bool {
+	return s.writer != nil
+}
+
+// finish ends the stream once the route has run. A route that yielded
+// nothing still answers with an empty stream. An error after the stream has
+// started is logged, unless it is the client having gone away.
+func (s *routeStream) finish(err error) error {
+	if err == nil {
+		return s.open()
+	}
+	if s.ctx.Request.Context().Err() == nil {
+		printError(fmt.Errorf("stream %s %s ended with an error: %w", s.ctx.Request.Method, s.ctx.Request.URL.Path, err))
+	}
+	return nil
+}
+
+// acceptsNDJSON reports whether an Accept header asks for newline-delimited
+// JSON
+func acceptsNDJSON(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if strings.EqualFold(mediaType, "application/x-ndjson") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const streamSource = `@ SSE /ticks {
+  $ i = 0
+  while i < 5 {
+    i = i + 1
+    yield {n: i, at: time.now()}
+  }
+}
+
+@ SSE /forever {
+  $ i = 0
+  while true {
+    i = i + 1
+    yield i
+  }
+}
+`
+
+// flushRecorder records what was written to a response between flushes
+type flushRecorder struct {
+	http.ResponseWriter
+	flusher http.Flusher
+
+	mu      sync.Mutex
+	pending strings.Builder
+	flushed []string
+}
+
+func (f *flushRecorder) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	f.pending.Write(p)
+	f.mu.Unlock()
+	return f.ResponseWriter.Write(p)
+}
+
+func (f *flushRecorder) Flush() {
+	f.mu.Lock()
+	f.flushed = append(f.flushed, f.pending.String())
+	f.pending.Reset()
+	f.mu.Unlock()
+	f.flusher.Flush()
+}
+
+// recordFlushes wraps the served handler so that done receives the flushes
+// of each request once its handler has returned
+func recordFlushes(done chan []string) serverOptions {
+	return serverOptions{wrap: func(handler http.HandlerFunc) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorder := &flushRecorder{ResponseWriter: w, flusher: w.(http.Flusher)}
+			handler(recorder, r)
+			recorder.mu.Lock()
+			defer recorder.mu.Unlock()
+			done <- recorder.flushed
+		})
+	}}
+}
+
+// TestSSERouteStreamsEvents reads a route's five events as they are sent,
+// checking that each one is flushed on its own rather than at the end
+func TestSSERouteStreamsEvents(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			done := make(chan []string, 16)
+			srv := startInputValidationServer(t, streamSource, mode.interpreted, recordFlushes(done))
+
+			resp, err := http.Get(srv.URL + "/ticks")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+			assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+
+			var events []map[string]interface{}
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+				if !ok {
+					continue
+				}
+				var event map[string]interface{}
+				require.NoError(t, json.Unmarshal([]byte(data), &event))
+				events = append(events, event)
+			}
+			require.NoError(t, scanner.Err())
+
+			require.Len(t, events, 5)
+			now := float64(time.Now().Unix())
+			for i, event := range events {
+				assert.Equal(t, float64(i+1), event["n"])
+				assert.InDelta(t, now, event["at"], 5)
+			}
+			// Every event is flushed as it is yielded, after the headers
+			flushes := <-done
+			require.Len(t, flushes, 6)
+			assert.Empty(t, flushes[0])
+			for i, flush := range flushes[1:] {
+				assert.Equal(t, 1, strings.Count(flush, "data: "), "flush %d: %q", i, flush)
+				assert.Contains(t, flush, fmt.Sprintf(`"n":%d`, i+1))
+			}
+		})
+	}
+}
+
+func TestSSERouteStreamsNDJSON(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			srv := startInputValidationServer(t, streamSource, mode.interpreted)
+
+			req, err := http.NewRequest(http.MethodGet, srv.URL+"/ticks", nil)
+			require.NoError(t, err)
+			req.Header.Set("Accept", "application/x-ndjson")
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+			var lines []map[string]interface{}
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				var line map[string]interface{}
+				require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+				lines = append(lines, line)
+			}
+			require.Len(t, lines, 5)
+			assert.Equal(t, float64(5), lines[4]["n"])
+		})
+	}
+}
+
+// TestSSERouteStopsWhenClientDisconnects checks that a route that would
+// stream forever returns once its client has gone away
+func TestSSERouteStopsWhenClientDisconnects(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			done := make(chan []string, 16)
+			srv := startInputValidationServer(t, streamSource, mode.interpreted, recordFlushes(done))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/forever", nil)
+			require.NoError(t, err)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+
+			reader := bufio.NewReader(resp.Body)
+			for received := 0; received < 2; {
+				line, err := reader.ReadString('\n')
+				require.NoError(t, err)
+				if strings.HasPrefix(line, "data: ") {
+					received++
+				}
+			}
+			cancel()
+			resp.Body.Close()
+
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+				t.Fatal("route kept streaming after the client disconnected")
+			}
+		})
+	}
+}
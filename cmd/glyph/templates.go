@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/interpreter"
+	"github.com/glyphlang/glyph/pkg/web"
+)
+
+// templatesDirName is the directory next to the entry file that render()
+// reads its templates from
+const templatesDirName = "templates"
+
+// programTemplates, when set, are the templates of the program being
+// served, used by render() and renderString()
+var programTemplates atomic.Pointer[pageTemplates]
+
+// templateError is a template that could not be loaded or executed. Its
+// route answers with a 500 naming the template.
+type templateError struct {
+	name string
+	err  error
+}
+
+func (e *templateError) Error() string {
+	return fmt.Sprintf("template %q: %v", e.name, e.err)
+}
+
+func (e *templateError) Unwrap() error {
+	return e.err
+}
+
+// templateFile is the version of a template file that was last parsed
+type templateFile struct {
+	modTime time.Time
+	size    int64
+}
+
+// pageTemplates renders the html/template files of a directory for
+// render() and inline templates for renderString(). A file is parsed on
+// first use and again whenever it changes, so edits show up without a
+// restart.
+type pageTemplates struct {
+	dir    string
+	engine *web.TemplateEngine
+
+	mu     sync.Mutex
+	parsed map[string]templateFile
+}
+
+// newPageTemplates returns the templates of dir, which need not exist yet
+func newPageTemplates(dir string) *pageTemplates {
+	return &pageTemplates{
+		dir:    dir,
+		engine: web.NewTemplateEngineFromStrings(),
+		parsed: make(map[string]templateFile),
+	}
+}
+
+// useProgramTemplates makes render() read the templates directory next to
+// the entry file of program
+func useProgramTemplates(program *interpreter.Program) {
+	dir := filepath.Join(filepath.Dir(program.Entry), templatesDirName)
+	programTemplates.Store(newPageTemplates(dir))
+}
+
+// Render renders the template file name with data. A name without an
+// extension is taken to be an .html file.
+func (t *pageTemplates) Render(name string, data interface{}) (string, error) {
+	file := name
+	if filepath.Ext(file) == "" {
+		file += ".html"
+	}
+	if !filepath.IsLocal(file) {
+		return "", &templateError{name: name, err: fmt.Errorf("not a path inside %s/", templatesDirName)}
+	}
+	if err := t.load(file); err != nil {
+		return "", &templateError{name: name, err: err}
+	}
+	page, err := t.engine.RenderString(file, data)
+	if err != nil {
+		return "", &templateError{name: name, err: err}
+	}
+	return page, nil
+}
+
+// RenderString renders the inline template tmpl with data
+func (t *pageTemplates) RenderString(tmpl string, data interface{}) (string, error) {
+	engine := web.NewTemplateEngineFromStrings()
+	if err := engine.LoadTemplateString("inline", tmpl); err != nil {
+		return "", &templateError{name: "inline", err: err}
+	}
+	rendered, err := engine.RenderString("inline", data)
+	if err != nil {
+		return "", &templateError{name: "inline", err: err}
+	}
+	return rendered, nil
+}
+
+// load parses file unless the version parsed last is current
+func (t *pageTemplates) load(file string) error {
+	path := filepath.Join(t.dir, file)
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s does not exist", path)
+	}
+	if err != nil {
+		return err
+	}
+	current := templateFile{modTime: info.ModTime(), size: info.Size()}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if parsed, ok := t.parsed[file]; ok && parsed.modTime.Equal(current.modTime) && parsed.size == current.size {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := t.engine.LoadTemplateString(file, string(content)); err != nil {
+		return err
+	}
+	t.parsed[file] = current
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTemplates writes templates to the templates directory of a new
+// source directory, and returns the source directory and the templates
+// directory
+func writeTemplates(t *testing.T, templates map[string]string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	templatesDir := filepath.Join(dir, templatesDirName)
+	require.NoError(t, os.Mkdir(templatesDir, 0755))
+	for name, content := range templates {
+		require.NoError(t, os.WriteFile(filepath.Join(templatesDir, name), []byte(content), 0644))
+	}
+	return dir, templatesDir
+}
+
+// getPage fetches url and returns its status, Content-Type and body
+func getPage(t *testing.T, url string) (int, string, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	return resp.StatusCode, resp.Header.Get("Content-Type"), readBody(t, resp)
+}
+
+// TestRenderUserList checks that render() serves a page of users from the
+// database, escaping their names
+func TestRenderUserList(t *testing.T) {
+	useTestDatabase(t, "")
+	t.Setenv("DATABASE_URL", "")
+
+	dir, _ := writeTemplates(t, map[string]string{
+		"users.html": `<h1>{{.title}}</h1>
+<ul>{{range .users}}<li id="user-{{.id}}">{{.name}}</li>{{end}}</ul>`,
+	})
+	srv := startInputValidationServer(t, `: NewUser {
+  name: str!
+}
+
+@ POST /users {
+  % db: Database
+  < input: NewUser
+  > db.users.create({name: input.name})
+}
+
+@ GET /users {
+  % db: Database
+  > render("users", {title: "Users", users: db.users.all()})
+}
+`, true, serverOptions{dir: dir})
+
+	for _, name := range []string{"Ada", "<script>alert(1)</script>"} {
+		status, _ := postJSON(t, srv, "/users", `{"name": "`+name+`"}`)
+		require.Equal(t, http.StatusOK, status)
+	}
+
+	status, contentType, body := getPage(t, srv.URL+"/users")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "text/html; charset=utf-8", contentType)
+	assert.Equal(t, `<h1>Users</h1>
+<ul><li id="user-1">Ada</li><li id="user-2">&lt;script&gt;alert(1)&lt;/script&gt;</li></ul>`, body)
+}
+
+const templatesSource = `@ GET /hello/:name {
+  > render("hello.html", {name: name})
+}
+
+@ GET /missing {
+  > render("missing", {})
+}
+
+@ GET /broken {
+  > render("broken", {})
+}
+
+@ GET /inline {
+  > {body: renderString('<p>Hi {{.name}}</p>', {name: "<Ann>"})}
+}
+`
+
+// TestRenderTemplates checks render() and renderString() in both execution
+// modes, including the 500 of a template that cannot be rendered
+func TestRenderTemplates(t *testing.T) {
+	for _, mode := range executionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			dir, templatesDir := writeTemplates(t, map[string]string{
+				"hello.html":  `<p>Hello {{.name}}</p>`,
+				"broken.html": `{{template "nothing"}}`,
+			})
+			srv := startInputValidationServer(t, templatesSource, mode.interpreted, serverOptions{dir: dir})
+
+			status, contentType, body := getPage(t, srv.URL+"/hello/%3Cb%3E")
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, "text/html; charset=utf-8", contentType)
+			assert.Equal(t, `<p>Hello &lt;b&gt;</p>`, body)
+
+			// An edited template is parsed again
+			hello := filepath.Join(templatesDir, "hello.html")
+			require.NoError(t, os.WriteFile(hello, []byte(`<h1>Hi {{.name}}</h1>`), 0644))
+			require.NoError(t, os.Chtimes(hello, time.Now(), time.Now().Add(time.Second)))
+			_, _, body = getPage(t, srv.URL+"/hello/ada")
+			assert.Equal(t, `<h1>Hi ada</h1>`, body)
+
+			status, body = getBody(t, srv.URL+"/missing")
+			assert.Equal(t, http.StatusInternalServerError, status)
+			assert.JSONEq(t, `{"error": "Failed to render template \"missing\""}`, body)
+
+			status, body = getBody(t, srv.URL+"/broken")
+			assert.Equal(t, http.StatusInternalServerError, status)
+			assert.JSONEq(t, `{"error": "Failed to render template \"broken\""}`, body)
+
+			status, body = getBody(t, srv.URL+"/inline")
+			assert.Equal(t, http.StatusOK, status)
+			assert.JSONEq(t, `{"body": "<p>Hi &lt;Ann&gt;</p>"}`, body)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/interpreter"
+	"github.com/glyphlang/glyph/pkg/jsonvalue"
+	"github.com/glyphlang/glyph/pkg/parser"
+	"github.com/glyphlang/glyph/pkg/server"
+)
+
+// testFileResult is the outcome of one test block, as reported by
+// glyph test --json
+type testFileResult struct {
+	File       string  `json:"file"`
+	Name       string  `json:"name"`
+	Passed     bool    `json:"passed"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// testFiles returns the GLYPH files glyph test runs for path: the file
+// itself, or every .glyph and .glyphx file under a directory
+func testFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(p); ext == ".glyph" || ext == ".glyphx" {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// loadTestFile parses filePath and loads it into an interpreter whose
+// request() calls are served by the file's routes in-process, without
+// opening a socket
+func loadTestFile(filePath string) (*interpreter.Interpreter, error) {
+	source, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// Determine lexer type based on file extension
+	var tokens []parser.Token
+	if filepath.Ext(filePath) == ".glyphx" {
+		tokens, err = parser.NewExpandedLexer(string(source)).Tokenize()
+	} else {
+		tokens, err = parser.NewLexer(string(source)).Tokenize()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lexer error: %w", err)
+	}
+
+	module, err := parser.NewParserWithSource(tokens, string(source)).Parse()
+	if err != nil {
+		return nil, fmt.Errorf("parse error: %w", err)
+	}
+
+	interp := newConfiguredInterpreter()
+	if err := interp.LoadModuleWithPath(*module, filepath.Dir(filePath)); err != nil {
+		return nil, fmt.Errorf("load error: %w", err)
+	}
+
+	router := server.NewRouter()
+	for _, item := range module.Items {
+		if route, ok := item.(*ast.Route); ok {
+			if err := registerRoute(router, route, interp); err != nil {
+				return nil, fmt.Errorf("failed to register route %s: %w", route.Path, err)
+			}
+		}
+	}
+	if err := registerErrorHandlers(router, module, interp); err != nil {
+		return nil, err
+	}
+	interp.SetTestRequester(testRequester(createHandler(router)))
+
+	return interp, nil
+}
+
+// testRequester returns a request() implementation that serves each request
+// with handler and returns {status, body, headers}. JSON response bodies are
+// decoded; any other body is returned as a string.
+func testRequester(handler http.Handler) interpreter.TestRequestFunc {
+	return func(method, path string, body interface{}) (map[string]interface{}, error) {
+		var reader io.Reader
+		if body != nil {
+			data, err := json.Marshal(body)
+			if err != nil {
+				return nil, fmt.Errorf("request() body is not JSON-encodable: %w", err)
+			}
+			reader = bytes.NewReader(data)
+		}
+		req := httptest.NewRequest(method, path, reader)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		headers := make(map[string]interface{}, len(rec.Header()))
+		for name, values := range rec.Header() {
+			headers[name] = strings.Join(values, ", ")
+		}
+
+		var respBody interface{} = rec.Body.String()
+		if strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") && rec.Body.Len() > 0 {
+			decoded, err := jsonvalue.Decode(rec.Body.Bytes())
+			if err != nil {
+				return nil, fmt.Errorf("request() got invalid JSON from %s %s: %w", method, path, err)
+			}
+			respBody = decoded
+		}
+
+		return map[string]interface{}{
+			"status":  int64(rec.Code),
+			"body":    respBody,
+			"headers": headers,
+		}, nil
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const routeTestsSource = `@ GET /users/:id {
+  > {id: id, name: "alice"}
+}
+
+@ POST /users {
+  > {created: input.name}
+}
+
+test! "gets a user" {
+  $ res = request("GET", "/users/7")
+  assertEqual(res.status, 200)
+  assertEqual(res.body, {id: "7", name: "alice"})
+  assertContains(res.headers, "Content-Type")
+}
+
+test "creates a user" {
+  $ res = request("POST", "/users", {name: "bob"})
+  assertEqual(res.body.created, "bob")
+}
+
+test "unknown route" {
+  $ res = request("GET", "/missing")
+  assertEqual(res.status, 404)
+}
+`
+
+func TestLoadTestFile_RequestsRunInProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_test.glyph")
+	require.NoError(t, os.WriteFile(path, []byte(routeTestsSource), 0644))
+
+	interp, err := loadTestFile(path)
+	require.NoError(t, err)
+
+	results := interp.RunTests("")
+	require.Len(t, results, 3)
+	for _, r := range results {
+		assert.True(t, r.Passed, "%s: %s", r.Name, r.Error)
+	}
+}
+
+func TestTestFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "nested"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".hidden"), 0755))
+	for _, name := range []string{"a.glyph", "nested/b.glyphx", ".hidden/c.glyph", "notes.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+
+	files, err := testFiles(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a.glyph"), filepath.Join(dir, "nested", "b.glyphx")}, files)
+
+	files, err = testFiles(filepath.Join(dir, "a.glyph"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a.glyph")}, files)
+
+	_, err = testFiles(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
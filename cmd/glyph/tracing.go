@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/glyphlang/glyph/pkg/tracing"
+)
+
+var (
+	sharedTracer     *tracing.TracerProvider
+	sharedTracerErr  error
+	sharedTracerOnce sync.Once
+)
+
+// serverTracing installs the process-wide tracer provider configured by the
+// standard OTEL_* environment variables. Tracing is off unless
+// OTEL_EXPORTER_OTLP_ENDPOINT is set, leaving the global no-op provider in
+// place so spans cost next to nothing.
+func serverTracing() error {
+	sharedTracerOnce.Do(func() {
+		config := tracing.ConfigFromEnv()
+		if !config.Enabled {
+			return
+		}
+		tp, err := tracing.InitTracing(config)
+		if err != nil {
+			sharedTracerErr = fmt.Errorf("failed to initialize tracing: %w", err)
+			return
+		}
+		sharedTracer = tp
+		printInfo(fmt.Sprintf("Tracing enabled, exporting spans for %s over OTLP", config.ServiceName))
+	})
+	return sharedTracerErr
+}
+
+// tracingEnabled reports whether serverTracing installed a tracer provider
+func tracingEnabled() bool {
+	return sharedTracer != nil
+}
+
+// shutdownTracing flushes the spans still buffered for export
+func shutdownTracing(ctx context.Context) {
+	if sharedTracer == nil {
+		return
+	}
+	if err := sharedTracer.Shutdown(ctx); err != nil {
+		printWarning(fmt.Sprintf("Failed to flush traces: %v", err))
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/database"
+	"github.com/glyphlang/glyph/pkg/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// useInMemoryTracing installs a tracer provider recording to an in-memory
+// exporter for the rest of the test
+func useInMemoryTracing(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+		tp.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+// traced enables request tracing on the server of startInputValidationServer
+var traced = serverOptions{setup: func(router *server.Router) {
+	router.Use(server.TracingMiddleware())
+}}
+
+// spanNamed returns the recorded span called name
+func spanNamed(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	t.Fatalf("no span named %q in %v", name, names)
+	return tracetest.SpanStub{}
+}
+
+// spanAttr returns the value of the attribute key of span
+func spanAttr(span tracetest.SpanStub, key string) attribute.Value {
+	for _, attr := range span.Attributes {
+		if string(attr.Key) == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing_RequestSpanHierarchy(t *testing.T) {
+	exporter := useInMemoryTracing(t)
+
+	connStr := newSQLiteFile(t)
+	db, err := database.NewHandlerFromString(connStr)
+	require.NoError(t, err)
+	_, err = database.NewInterpreterDatabase(db).Table("users").Create(map[string]interface{}{"name": "ann"})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	useTestDatabase(t, connStr)
+
+	var auditTraceparent string
+	audit := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auditTraceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer audit.Close()
+
+	srv := startInputValidationServer(t, fmt.Sprintf(`@ GET /users/:id {
+  %% db: Database
+  $ user = db.users.get(parseInt(id))
+  $ logged = http.get("%s/audit")
+  > {name: user.name, audit: logged.status}
+}
+`, audit.URL), true, traced)
+
+	// Continue the trace of an upstream caller
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const parentSpanID = "00f067aa0ba902b7"
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/users/1", nil)
+	require.NoError(t, err)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentSpanID+"-01")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	spans := exporter.GetSpans()
+	root := spanNamed(t, spans, "GET /users/:id")
+	execute := spanNamed(t, spans, "interpreter.execute")
+	query := spanNamed(t, spans, "SELECT users")
+	client := spanNamed(t, spans, "HTTP GET")
+
+	// The request span continues the incoming trace
+	assert.Equal(t, trace.SpanKindServer, root.SpanKind)
+	assert.Equal(t, traceID, root.SpanContext.TraceID().String())
+	assert.Equal(t, parentSpanID, root.Parent.SpanID().String())
+	assert.Equal(t, "/users/:id", spanAttr(root, "http.route").AsString())
+	assert.Equal(t, int64(200), spanAttr(root, "http.status_code").AsInt64())
+
+	// Execution, the query and the outgoing call nest beneath it
+	assert.Equal(t, root.SpanContext.SpanID(), execute.Parent.SpanID())
+	assert.Equal(t, execute.SpanContext.SpanID(), query.Parent.SpanID())
+	assert.Equal(t, execute.SpanContext.SpanID(), client.Parent.SpanID())
+	for _, span := range []tracetest.SpanStub{execute, query, client} {
+		assert.Equal(t, traceID, span.SpanContext.TraceID().String(), span.Name)
+	}
+
+	assert.Equal(t, trace.SpanKindClient, query.SpanKind)
+	assert.Equal(t, "sqlite", spanAttr(query, "db.system").AsString())
+	assert.Contains(t, spanAttr(query, "db.statement").AsString(), `FROM "users"`)
+	assert.NotContains(t, spanAttr(query, "db.statement").AsString(), "ann")
+
+	// The outgoing call carries the trace to the audit service
+	assert.Equal(t, trace.SpanKindClient, client.SpanKind)
+	assert.Contains(t, auditTraceparent, traceID)
+	assert.Contains(t, auditTraceparent, client.SpanContext.SpanID().String())
+}
+
+func TestTracing_CompiledRoute(t *testing.T) {
+	exporter := useInMemoryTracing(t)
+
+	srv := startInputValidationServer(t, `@ GET /hello {
+  > {ok: true}
+}
+`, false, traced)
+	resp, err := http.Get(srv.URL + "/hello")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	spans := exporter.GetSpans()
+	root := spanNamed(t, spans, "GET /hello")
+	execute := spanNamed(t, spans, "vm.execute")
+	assert.False(t, root.Parent.IsValid())
+	assert.Equal(t, root.SpanContext.SpanID(), execute.Parent.SpanID())
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/server"
+	"github.com/glyphlang/glyph/pkg/websocket"
+	gorilla "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startChatExample serves the WebSocket routes of the chat example and
+// returns the server's ws:// URL and hub
+func startChatExample(t *testing.T) (string, *websocket.Hub) {
+	t.Helper()
+	return startWebSocketProgram(t, filepath.Join("..", "..", "examples", "websocket-chat", "main.glyph"))
+}
+
+// startWebSocketProgram serves the compiled WebSocket routes of the program
+// at path and returns the server's ws:// URL and hub
+func startWebSocketProgram(t *testing.T, path string) (string, *websocket.Hub) {
+	t.Helper()
+	program, err := loadProgram(path)
+	require.NoError(t, err)
+	routes, err := setupRoutes(program)
+	require.NoError(t, err)
+	require.True(t, routes.useCompiler)
+	t.Cleanup(func() { routes.wsServer.Shutdown(context.Background()) })
+
+	mux := http.NewServeMux()
+	for _, item := range program.Module.Items {
+		if wsRoute, ok := item.(*ast.WebSocketRoute); ok {
+			mux.HandleFunc(server.ConvertPatternToMuxFormat(wsRoute.Path), routes.wsServer.HandleWebSocketWithPattern(wsRoute.Path))
+		}
+	}
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return "ws" + strings.TrimPrefix(ts.URL, "http"), routes.wsServer.GetHub()
+}
+
+// dialChat connects a client to the chat example
+func dialChat(t *testing.T, url string) *gorilla.Conn {
+	t.Helper()
+	client, _, err := gorilla.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// TestWebSocketChatRooms runs the chat example with two rooms and checks
+// that a message reaches only the clients in the sender's room
+func TestWebSocketChatRooms(t *testing.T) {
+	wsURL, hub := startChatExample(t)
+	alice, bob, carol := dialChat(t, wsURL+"/rooms/1"), dialChat(t, wsURL+"/rooms/1"), dialChat(t, wsURL+"/rooms/2")
+
+	require.Eventually(t, func() bool {
+		return len(hub.RoomMembers("room:1")) == 2 && len(hub.RoomMembers("room:2")) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, alice.WriteMessage(gorilla.TextMessage, []byte(`{"type":"text","data":"hello room 1"}`)))
+	for _, client := range []*gorilla.Conn{alice, bob} {
+		client.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, data, err := client.ReadMessage()
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "hello room 1")
+	}
+	carol.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, _, err := carol.ReadMessage()
+	assert.Error(t, err, "room 2 must not receive room 1 messages")
+
+	// Leaving on disconnect empties the room
+	carol.Close()
+	assert.Eventually(t, func() bool { return len(hub.RoomMembers("room:2")) == 0 }, 2*time.Second, 10*time.Millisecond)
+}
+
+// TestWebSocketChatAuth runs the authenticated chat example: a bad token is
+// rejected on connect, and a good one's user ID is seen by every message
+func TestWebSocketChatAuth(t *testing.T) {
+	wsURL, hub := startChatExample(t)
+
+	rejected := dialChat(t, wsURL+"/secure?token=wrong")
+	rejected.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := rejected.ReadMessage()
+	var closeErr *gorilla.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, 4001, closeErr.Code)
+	assert.Equal(t, "invalid token", closeErr.Text)
+
+	// A missing query parameter is null, so it is rejected the same way
+	missing := dialChat(t, wsURL+"/secure")
+	missing.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = missing.ReadMessage()
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, 4001, closeErr.Code)
+
+	client := dialChat(t, wsURL+"/secure?token=secret-token")
+	for _, text := range []string{"first", "second"} {
+		require.NoError(t, client.WriteMessage(gorilla.TextMessage, []byte(`{"type":"text","data":"`+text+`"}`)))
+		client.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, data, err := client.ReadMessage()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"user":"user-42","text":"`+text+`"}`, string(data))
+	}
+	assert.Equal(t, 1, hub.GetConnectionCount())
+}
+
+// TestWebSocketConnectFailureCloses checks that a connect handler failing
+// part-way closes the connection with an internal error
+func TestWebSocketConnectFailureCloses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.glyph")
+	require.NoError(t, os.WriteFile(path, []byte(`@ ws /profile {
+  on connect {
+    $ user = query.user
+    ws.setState(client, "userId", user.id)
+  }
+}`), 0644))
+	wsURL, _ := startWebSocketProgram(t, path)
+
+	client := dialChat(t, wsURL+"/profile")
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := client.ReadMessage()
+	var closeErr *gorilla.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, 1011, closeErr.Code)
+}
+
+// TestShutdownServerClosesWebSockets shuts down a server with a chat client
+// connected and checks that the client gets a going-away close frame, not
+// a reset
+func TestShutdownServerClosesWebSockets(t *testing.T) {
+	program, err := loadProgram(filepath.Join("..", "..", "examples", "websocket-chat", "main.glyph"))
+	require.NoError(t, err)
+	routes, err := setupRoutes(program)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	for _, item := range program.Module.Items {
+		if wsRoute, ok := item.(*ast.WebSocketRoute); ok {
+			mux.HandleFunc(server.ConvertPatternToMuxFormat(wsRoute.Path), routes.wsServer.HandleWebSocketWithPattern(wsRoute.Path))
+		}
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	client := dialChat(t, "ws"+strings.TrimPrefix(ts.URL, "http")+"/rooms/1")
+	require.Eventually(t, func() bool { return routes.wsServer.GetHub().GetConnectionCount() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	readErrs := make(chan error, 1)
+	go func() {
+		for {
+			if _, _, err := client.ReadMessage(); err != nil {
+				readErrs <- err
+				return
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, shutdownServer(ctx, ts.Config, routes.wsServer))
+
+	var closeErr *gorilla.CloseError
+	require.ErrorAs(t, <-readErrs, &closeErr, "the client sees a close frame rather than a reset")
+	assert.Equal(t, gorilla.CloseGoingAway, closeErr.Code)
+}
+
+// TestWebSocketEnvPrefixes checks that WebSocket event handlers may read
+// only the environment variables allowed by GLYPH_ENV_PREFIXES
+func TestWebSocketEnvPrefixes(t *testing.T) {
+	t.Setenv("APP_REGION", "eu-west")
+	t.Setenv("SECRET_TOKEN", "hunter2")
+	t.Setenv("GLYPH_ENV_PREFIXES", "APP_")
+	path := filepath.Join(t.TempDir(), "main.glyph")
+	require.NoError(t, os.WriteFile(path, []byte(`@ ws /env {
+  on message {
+    if input == "secret" {
+      ws.send(env("SECRET_TOKEN"))
+    }
+    ws.send(env("APP_REGION"))
+  }
+}
+`), 0644))
+	wsURL, _ := startWebSocketProgram(t, path)
+	client := dialChat(t, wsURL+"/env")
+
+	// Reading SECRET_TOKEN fails the handler, so only the second message
+	// is answered
+	require.NoError(t, client.WriteMessage(gorilla.TextMessage, []byte(`{"type":"text","data":"secret"}`)))
+	require.NoError(t, client.WriteMessage(gorilla.TextMessage, []byte(`{"type":"text","data":"region"}`)))
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := client.ReadMessage()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "eu-west")
+	assert.NotContains(t, string(data), "hunter2")
+	client.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, data, err = client.ReadMessage()
+	assert.Error(t, err, "unexpected message %s", data)
+}
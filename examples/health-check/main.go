@@ -65,10 +65,19 @@ func main() {
 	db := NewSimulatedDatabase()
 	cache := NewSimulatedCache()
 
-	// Create health manager with custom timeout
+	// Create health manager with custom timeout. Checks run in the
+	// background every 5 seconds, so probes are served the latest results
+	// instead of waiting on the slow database check.
 	healthManager := server.NewHealthManager(
-		server.WithHealthCheckTimeout(5 * time.Second),
+		server.WithHealthCheckTimeout(5*time.Second),
+		server.WithPeriodicChecks(5*time.Second),
 	)
+	defer healthManager.Shutdown()
+
+	// Log components changing status
+	healthManager.OnStatusChange(func(component string, old, new server.HealthStatus) {
+		log.Printf("health: %s changed from %s to %s", component, old, new)
+	})
 
 	// Register database health checker
 	dbChecker := server.NewDatabaseHealthChecker("database", db.Ping)
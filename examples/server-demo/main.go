@@ -89,10 +89,12 @@ func main() {
 				{"id": 2, "name": "Jane Smith", "email": "jane@example.com"},
 			}
 
-			// Support query params (QueryParams is now map[string][]string)
-			if pages := ctx.QueryParams["page"]; len(pages) > 0 {
-				log.Printf("Filtering by page: %s", pages[0])
+			// Typed query params, with a default when absent
+			page, err := ctx.QueryInt("page", 1)
+			if err != nil {
+				return server.SendError(ctx, 400, err.Error())
 			}
+			log.Printf("Listing page: %d", page)
 
 			return server.SendJSON(ctx, 200, users)
 		},
@@ -122,19 +124,23 @@ func main() {
 		Method: server.POST,
 		Path:   "/api/users",
 		Handler: func(ctx *server.Context) error {
-			// Get data from request body
-			name, nameOk := ctx.Body["name"].(string)
-			email, emailOk := ctx.Body["email"].(string)
-
-			if !nameOk || !emailOk {
+			// Decode the request body into a struct
+			var input struct {
+				Name  string `json:"name"`
+				Email string `json:"email"`
+			}
+			if err := ctx.BindJSON(&input); err != nil {
+				return server.SendError(ctx, 400, err.Error())
+			}
+			if input.Name == "" || input.Email == "" {
 				return server.SendError(ctx, 400, "name and email are required")
 			}
 
 			// Mock creating user
 			user := map[string]interface{}{
 				"id":         123,
-				"name":       name,
-				"email":      email,
+				"name":       input.Name,
+				"email":      input.Email,
 				"created_at": time.Now().Unix(),
 			}
 
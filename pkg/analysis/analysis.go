@@ -0,0 +1,901 @@
+// Package analysis checks a parsed GLYPH module without running it. Names are
+// resolved with the interpreter's own Environment so that scoping matches
+// execution; calls are checked against function signatures, literals against
+// declared types, and routes and functions that can never run are reported.
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/interpreter"
+	"github.com/glyphlang/glyph/pkg/server"
+)
+
+// Severity of a diagnostic
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a single problem found by Check. Line and Column are 1-based,
+// or 0 when the position is unknown.
+type Diagnostic struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// String formats the diagnostic as file:line:column: severity: message
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Message)
+}
+
+// Options configures Check
+type Options struct {
+	File    string                // File reported for items FileOf does not know
+	FileOf  func(ast.Item) string // File an item was declared in, for merged programs
+	Partial bool                  // Imports were not loaded, so names they provide are unknown
+}
+
+// Check analyzes module and returns its diagnostics ordered by position
+func Check(module *ast.Module, opts Options) []Diagnostic {
+	c := &checker{
+		opts:       opts,
+		types:      make(map[string]*ast.TypeDef),
+		functions:  make(map[string]*ast.Function),
+		middleware: make(map[string]*ast.MiddlewareDef),
+		constants:  make(map[string]bool),
+		enums:      make(map[string]*ast.EnumDef),
+		namespaces: make(map[string]bool),
+		used:       make(map[string]bool),
+		typeCheck:  interpreter.NewTypeChecker(),
+	}
+	c.run(module)
+
+	sort.SliceStable(c.diags, func(a, b int) bool {
+		da, db := c.diags[a], c.diags[b]
+		if da.File != db.File {
+			return da.File < db.File
+		}
+		if da.Line != db.Line {
+			return da.Line < db.Line
+		}
+		return da.Column < db.Column
+	})
+	return c.diags
+}
+
+// HasErrors reports whether any diagnostic is an error
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+type checker struct {
+	opts       Options
+	types      map[string]*ast.TypeDef
+	functions  map[string]*ast.Function
+	middleware map[string]*ast.MiddlewareDef
+	constants  map[string]bool
+	enums      map[string]*ast.EnumDef
+	namespaces map[string]bool
+	used       map[string]bool // Functions referenced from outside their own body
+	opaque     bool            // Names may come from somewhere we cannot see
+	typeCheck  *interpreter.TypeChecker
+	diags      []Diagnostic
+}
+
+// body is the code being checked: a function, route, command or handler
+type body struct {
+	file       string
+	pos        ast.Pos         // Position of the owning item, used when a node has none
+	function   string          // Name of the enclosing function, if any
+	returnType ast.Type        // Declared return type, if any
+	refs       map[string]bool // Every name the body reads or writes
+	opaque     bool            // A macro invocation may define names
+}
+
+func (c *checker) run(module *ast.Module) {
+	global := interpreter.NewEnvironment()
+
+	// Declarations are visible everywhere, as they are after LoadModule
+	for _, item := range module.Items {
+		switch it := item.(type) {
+		case *ast.TypeDef:
+			c.types[it.Name] = it
+		case *ast.EnumDef:
+			c.enums[it.Name] = it
+		case *ast.Function:
+			c.functions[it.Name] = it
+			global.Define(it.Name, it)
+		case *ast.MiddlewareDef:
+			c.middleware[it.Name] = it
+		case *ast.ConstDecl:
+			if c.constants[it.Name] {
+				b := c.newBody(c.fileOf(item), it.Pos)
+				c.errorAt(b, it.Pos, "constant '%s' is already defined", it.Name)
+			}
+			c.constants[it.Name] = true
+			global.Define(it.Name, nil)
+		case *ast.ImportStatement:
+			if it.Selective {
+				for _, name := range it.Names {
+					local := name.Name
+					if name.Alias != "" {
+						local = name.Alias
+					}
+					global.Define(local, nil)
+				}
+			} else {
+				namespace := interpreter.ImportNamespace(it)
+				c.namespaces[namespace] = true
+				global.Define(namespace, nil)
+			}
+			if c.opts.Partial {
+				c.opaque = true
+			}
+		case *ast.MacroInvocation:
+			c.opaque = true
+		}
+	}
+	// Selectively imported functions are callable under their local name
+	for _, item := range module.Items {
+		if it, ok := item.(*ast.ImportStatement); ok && it.Selective {
+			for _, name := range it.Names {
+				if fn, ok := c.functions[name.Name]; ok && name.Alias != "" {
+					global.Define(name.Alias, fn)
+				}
+			}
+		}
+	}
+
+	hasEntryPoint := false
+	for _, item := range module.Items {
+		file := c.fileOf(item)
+		switch it := item.(type) {
+		case *ast.TypeDef:
+			c.checkTypeDef(it, file)
+		case *ast.ConstDecl:
+			b := c.newBody(file, it.Pos)
+			c.expr(it.Value, global, b)
+			c.checkValue(it.Value, it.Type, fmt.Sprintf("constant '%s'", it.Name), b)
+		case *ast.Function:
+			c.checkFunction(it, global, file)
+		case *ast.Route:
+			hasEntryPoint = true
+			c.checkRoute(it, global, file)
+		case *ast.Command:
+			hasEntryPoint = true
+			c.checkCommand(it, global, file)
+		case *ast.CronTask:
+			hasEntryPoint = true
+			env := interpreter.NewChildEnvironment(global)
+			c.inject(it.Injections, env)
+			c.statements(it.Body, env, c.newBody(file, ast.Pos{}))
+		case *ast.EventHandler:
+			hasEntryPoint = true
+			env := interpreter.NewChildEnvironment(global)
+			env.Define("event", nil)
+			env.Define("input", nil)
+			c.inject(it.Injections, env)
+			c.statements(it.Body, env, c.newBody(file, ast.Pos{}))
+		case *ast.QueueWorker:
+			hasEntryPoint = true
+			env := interpreter.NewChildEnvironment(global)
+			env.Define("message", nil)
+			env.Define("input", nil)
+			c.inject(it.Injections, env)
+			c.statements(it.Body, env, c.newBody(file, ast.Pos{}))
+		case *ast.TestBlock:
+			hasEntryPoint = true
+			c.statements(it.Body, interpreter.NewChildEnvironment(global), c.newBody(file, ast.Pos{}))
+		case *ast.SeedBlock:
+			hasEntryPoint = true
+			env := interpreter.NewChildEnvironment(global)
+			env.Define("db", nil)
+			c.statements(it.Body, env, c.newBody(file, ast.Pos{}))
+		case *ast.MiddlewareDef:
+			c.checkMiddleware(it, global, file)
+		case *ast.WebSocketRoute, *ast.GRPCHandler, *ast.GraphQLResolver:
+			hasEntryPoint = true
+			c.walkUnchecked(it, global, file)
+		}
+	}
+
+	c.checkDeadRoutes(module)
+
+	// A module without entry points is a library whose callers are elsewhere
+	if hasEntryPoint {
+		for _, item := range module.Items {
+			if fn, ok := item.(*ast.Function); ok && !c.used[fn.Name] {
+				c.report(c.fileOf(item), fn.Pos, SeverityWarning, "function '%s' is never used", fn.Name)
+			}
+		}
+	}
+}
+
+func (c *checker) fileOf(item ast.Item) string {
+	if c.opts.FileOf != nil {
+		if file := c.opts.FileOf(item); file != "" {
+			return file
+		}
+	}
+	return c.opts.File
+}
+
+func (c *checker) newBody(file string, pos ast.Pos) *body {
+	return &body{file: file, pos: pos, refs: make(map[string]bool)}
+}
+
+func (c *checker) report(file string, pos ast.Pos, severity Severity, format string, args ...interface{}) {
+	c.diags = append(c.diags, Diagnostic{
+		File:     file,
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// errorAt reports an error at pos, or at the owning item when pos is unset
+func (c *checker) errorAt(b *body, pos ast.Pos, format string, args ...interface{}) {
+	if !pos.HasPos() {
+		pos = b.pos
+	}
+	c.report(b.file, pos, SeverityError, format, args...)
+}
+
+// inject defines injected dependencies, as injectDependency does
+func (c *checker) inject(injections []ast.Injection, env *interpreter.Environment) {
+	for _, injection := range injections {
+		env.Define(injection.Name, nil)
+	}
+}
+
+func (c *checker) checkTypeDef(typeDef *ast.TypeDef, file string) {
+	b := c.newBody(file, typeDef.Pos)
+	for _, field := range typeDef.Fields {
+		if field.Default != nil {
+			c.checkValue(field.Default, field.TypeAnnotation, fmt.Sprintf("default of field '%s' in type %s", field.Name, typeDef.Name), b)
+		}
+	}
+}
+
+func (c *checker) checkFunction(fn *ast.Function, global *interpreter.Environment, file string) {
+	b := c.newBody(file, fn.Pos)
+	b.function = fn.Name
+	b.returnType = fn.ReturnType
+
+	env := interpreter.NewChildEnvironment(global)
+	for _, param := range fn.Params {
+		if param.Default != nil {
+			c.expr(param.Default, env, b)
+		}
+		env.Define(param.Name, nil)
+	}
+	c.statements(fn.Body, env, b)
+}
+
+func (c *checker) checkRoute(route *ast.Route, global *interpreter.Environment, file string) {
+	b := c.newBody(file, route.Pos)
+	b.returnType = route.ReturnType
+
+	// Bindings in the order ExecuteRoute creates them
+	env := interpreter.NewChildEnvironment(global)
+	params := server.ExtractRouteParamNames(route.Path)
+	for _, param := range params {
+		env.DefineWithSource(param, nil, interpreter.BindingPathParam)
+	}
+	env.Define("query", nil)
+	for _, decl := range route.QueryParams {
+		if decl.Default != nil {
+			c.expr(decl.Default, env, b)
+		}
+		env.DefineWithSource(decl.Name, nil, interpreter.BindingQueryParam)
+	}
+	env.Define("input", nil)
+	env.Define("headers", nil)
+	env.Define("request", nil)
+	c.inject(route.Injections, env)
+	if route.Auth != nil {
+		env.Define("auth", nil)
+	}
+
+	// Middleware bodies run first in the same environment
+	for _, ref := range route.Middlewares {
+		mw, ok := c.middleware[ref.Name]
+		if !ok {
+			c.errorAt(b, ref.Pos, "undefined middleware '%s'", ref.Name)
+			continue
+		}
+		for _, stmt := range mw.Body {
+			if assign, ok := stmt.(ast.AssignStatement); ok {
+				env.Define(assign.Target, nil)
+			}
+		}
+	}
+
+	c.statements(route.Body, env, b)
+
+	if !b.opaque {
+		for _, param := range params {
+			if !b.refs[param] {
+				c.report(file, route.Pos, SeverityWarning, "path parameter '%s' of %s %s is never used", param, route.Method, route.Path)
+			}
+		}
+	}
+}
+
+// checkMiddleware checks a middleware body once, with the bindings every
+// route has. The names it may use from a route, such as path parameters and
+// injections, depend on the route, so undefined names are not reported.
+func (c *checker) checkMiddleware(mw *ast.MiddlewareDef, global *interpreter.Environment, file string) {
+	b := c.newBody(file, mw.Pos)
+	b.opaque = true
+
+	env := interpreter.NewChildEnvironment(global)
+	for _, name := range []string{"query", "input", "headers", "request", "auth"} {
+		env.Define(name, nil)
+	}
+	c.statements(mw.Body, env, b)
+}
+
+func (c *checker) checkCommand(cmd *ast.Command, global *interpreter.Environment, file string) {
+	b := c.newBody(file, cmd.Pos)
+	b.returnType = cmd.ReturnType
+
+	env := interpreter.NewChildEnvironment(global)
+	for _, param := range cmd.Params {
+		if param.Default != nil {
+			c.expr(param.Default, env, b)
+			c.checkValue(param.Default, param.Type, fmt.Sprintf("default of parameter '%s' in command %s", param.Name, cmd.Name), b)
+		}
+		env.Define(param.Name, nil)
+	}
+	c.statements(cmd.Body, env, b)
+}
+
+// checkDeadRoutes reports routes that an earlier route with the same method
+// always matches first; the router tries routes in declaration order
+func (c *checker) checkDeadRoutes(module *ast.Module) {
+	var earlier []*ast.Route
+	for _, item := range module.Items {
+		route, ok := item.(*ast.Route)
+		if !ok {
+			continue
+		}
+		for _, prev := range earlier {
+			if prev.Method == route.Method && shadows(prev.Path, route.Path) {
+				c.report(c.fileOf(item), route.Pos, SeverityWarning, "route %s %s is unreachable: %s %s is declared earlier and matches the same paths",
+					route.Method, route.Path, prev.Method, prev.Path)
+				break
+			}
+		}
+		earlier = append(earlier, route)
+	}
+}
+
+// shadows reports whether every path matched by pattern b is matched by a
+func shadows(a, b string) bool {
+	as := strings.FieldsFunc(a, func(r rune) bool { return r == '/' })
+	bs := strings.FieldsFunc(b, func(r rune) bool { return r == '/' })
+	if len(as) != len(bs) {
+		return false
+	}
+	for i := range as {
+		if strings.HasPrefix(as[i], ":") {
+			continue
+		}
+		if as[i] != bs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// walkUnchecked visits bodies whose runtime bindings the checker does not
+// model, only to record the functions they use
+func (c *checker) walkUnchecked(item ast.Item, global *interpreter.Environment, file string) {
+	var bodies [][]ast.Statement
+	switch it := item.(type) {
+	case *ast.WebSocketRoute:
+		for _, event := range it.Events {
+			bodies = append(bodies, event.Body)
+		}
+	case *ast.GRPCHandler:
+		bodies = append(bodies, it.Body)
+	case *ast.GraphQLResolver:
+		bodies = append(bodies, it.Body)
+	}
+	for _, stmts := range bodies {
+		b := c.newBody(file, ast.Pos{})
+		b.opaque = true
+		c.statements(stmts, interpreter.NewChildEnvironment(global), b)
+	}
+}
+
+func (c *checker) statements(stmts []ast.Statement, env *interpreter.Environment, b *body) {
+	for _, stmt := range stmts {
+		c.statement(stmt, env, b)
+	}
+}
+
+func (c *checker) statement(stmt ast.Statement, env *interpreter.Environment, b *body) {
+	switch s := stmt.(type) {
+	case ast.AssignStatement:
+		c.assign(s, env, b)
+	case ast.ReassignStatement:
+		c.reassign(s, env, b)
+	case ast.IndexAssignStatement:
+		c.expr(s.Target, env, b)
+		c.expr(s.Value, env, b)
+	case ast.DbQueryStatement:
+		for _, param := range s.Params {
+			c.expr(param, env, b)
+		}
+		env.Define(s.Var, nil)
+		b.refs[s.Var] = true
+	case ast.ReturnStatement:
+		c.expr(s.Value, env, b)
+		c.checkValue(s.Value, b.returnType, "return value", b)
+	case ast.IfStatement:
+		c.expr(s.Condition, env, b)
+		c.statements(s.ThenBlock, interpreter.NewChildEnvironment(env), b)
+		c.statements(s.ElseBlock, interpreter.NewChildEnvironment(env), b)
+	case ast.WhileStatement:
+		c.expr(s.Condition, env, b)
+		c.statements(s.Body, interpreter.NewChildEnvironment(env), b)
+	case ast.BackgroundStatement:
+		c.statements(s.Body, interpreter.NewChildEnvironment(env), b)
+	case ast.TryStatement:
+		c.statements(s.Body, interpreter.NewChildEnvironment(env), b)
+		catchEnv := interpreter.NewChildEnvironment(env)
+		if s.ErrorVar != "" {
+			catchEnv.Define(s.ErrorVar, nil)
+		}
+		c.statements(s.Catch, catchEnv, b)
+	case ast.ForStatement:
+		c.expr(s.Iterable, env, b)
+		loopEnv := interpreter.NewChildEnvironment(env)
+		if s.KeyVar != "" {
+			loopEnv.Define(s.KeyVar, nil)
+		}
+		loopEnv.Define(s.ValueVar, nil)
+		c.statements(s.Body, loopEnv, b)
+	case ast.SwitchStatement:
+		c.expr(s.Value, env, b)
+		for _, switchCase := range s.Cases {
+			c.expr(switchCase.Value, env, b)
+			c.statements(switchCase.Body, interpreter.NewChildEnvironment(env), b)
+		}
+		c.statements(s.Default, interpreter.NewChildEnvironment(env), b)
+	case ast.WsSendStatement:
+		c.expr(s.Client, env, b)
+		c.expr(s.Message, env, b)
+	case ast.WsBroadcastStatement:
+		c.expr(s.Message, env, b)
+		if s.Except != nil {
+			c.expr(*s.Except, env, b)
+		}
+	case ast.WsCloseStatement:
+		c.expr(s.Client, env, b)
+		c.expr(s.Reason, env, b)
+	case ast.ValidationStatement:
+		c.expr(s.Call, env, b)
+	case ast.ExpressionStatement:
+		c.expr(s.Expr, env, b)
+	case ast.YieldStatement:
+		c.expr(s.Value, env, b)
+	case ast.AssertStatement:
+		c.expr(s.Condition, env, b)
+		c.expr(s.Message, env, b)
+	case *ast.MacroInvocation:
+		b.opaque = true
+		for _, arg := range s.Args {
+			c.expr(arg, env, b)
+		}
+	}
+}
+
+// assign follows executeAssign: $x may not redeclare a name bound in the
+// same scope, and updates x in an outer scope when one exists
+func (c *checker) assign(s ast.AssignStatement, env *interpreter.Environment, b *body) {
+	pos := s.Pos
+	if root, _, dotted := strings.Cut(s.Target, "."); dotted {
+		b.refs[root] = true
+		if !env.Has(root) && !c.hidden(b) {
+			c.errorAt(b, pos, "cannot assign to field of undeclared variable '%s'", root)
+		}
+		c.expr(s.Value, env, b)
+		return
+	}
+
+	b.refs[s.Target] = true
+	if env.HasLocal(s.Target) {
+		source, _ := env.LocalSource(s.Target)
+		switch source {
+		case interpreter.BindingPathParam:
+			c.errorAt(b, pos, "cannot redeclare path parameter '%s' — it is already bound from the route pattern", s.Target)
+		case interpreter.BindingQueryParam:
+			c.errorAt(b, pos, "cannot redeclare query parameter '%s' — it is already bound from the route's query parameters", s.Target)
+		default:
+			c.errorAt(b, pos, "cannot redeclare variable '%s' in the same scope", s.Target)
+		}
+	} else if !s.Const && c.constants[s.Target] {
+		c.errorAt(b, pos, "cannot reassign constant '%s'", s.Target)
+	}
+	c.expr(s.Value, env, b)
+	if s.Const {
+		c.checkValue(s.Value, s.Type, fmt.Sprintf("constant '%s'", s.Target), b)
+	}
+	if !env.Has(s.Target) {
+		env.Define(s.Target, nil)
+	}
+}
+
+// reassign follows executeReassign: the variable must already exist and
+// must not be a constant
+func (c *checker) reassign(s ast.ReassignStatement, env *interpreter.Environment, b *body) {
+	pos := s.Pos
+	root, _, dotted := strings.Cut(s.Target, ".")
+	b.refs[root] = true
+	switch {
+	case !env.Has(root):
+		if !c.hidden(b) {
+			if dotted {
+				c.errorAt(b, pos, "cannot assign to field of undeclared variable '%s'", root)
+			} else {
+				c.errorAt(b, pos, "cannot assign to undeclared variable '%s'", root)
+			}
+		}
+	case !dotted && c.constants[root]:
+		c.errorAt(b, pos, "cannot reassign constant '%s'", root)
+	}
+	c.expr(s.Value, env, b)
+}
+
+// hidden reports whether names may be defined where the checker cannot see
+func (c *checker) hidden(b *body) bool {
+	return c.opaque || b.opaque
+}
+
+func (c *checker) expr(expr ast.Expr, env *interpreter.Environment, b *body) {
+	switch e := expr.(type) {
+	case nil:
+	case ast.LiteralExpr:
+	case ast.VariableExpr:
+		b.refs[e.Name] = true
+		value, err := env.Get(e.Name)
+		if err != nil {
+			if !c.hidden(b) {
+				c.errorAt(b, e.Pos, "undefined variable: %s", e.Name)
+			}
+			return
+		}
+		c.use(value, b)
+	case ast.BinaryOpExpr:
+		c.expr(e.Left, env, b)
+		c.expr(e.Right, env, b)
+	case ast.UnaryOpExpr:
+		c.expr(e.Right, env, b)
+	case ast.FieldAccessExpr:
+		c.expr(e.Object, env, b)
+	case ast.ArrayIndexExpr:
+		c.expr(e.Array, env, b)
+		c.expr(e.Index, env, b)
+	case ast.FunctionCallExpr:
+		c.call(e, nil, env, b)
+	case ast.ObjectExpr:
+		for _, field := range e.Fields {
+			c.expr(field.Value, env, b)
+		}
+	case ast.ArrayExpr:
+		for _, elem := range e.Elements {
+			c.expr(elem, env, b)
+		}
+	case ast.LambdaExpr:
+		lambdaEnv := interpreter.NewChildEnvironment(env)
+		for _, param := range e.Params {
+			lambdaEnv.Define(param.Name, nil)
+		}
+		c.expr(e.Body, lambdaEnv, b)
+		inner := *b
+		inner.returnType = nil
+		c.statements(e.Block, lambdaEnv, &inner)
+	case ast.PipeExpr:
+		c.expr(e.Left, env, b)
+		switch right := e.Right.(type) {
+		case ast.FunctionCallExpr:
+			c.call(right, e.Left, env, b)
+		case ast.VariableExpr:
+			c.call(ast.FunctionCallExpr{Name: right.Name, Pos: right.Pos}, e.Left, env, b)
+		default:
+			c.expr(e.Right, env, b)
+		}
+	case ast.TernaryExpr:
+		c.expr(e.Condition, env, b)
+		c.expr(e.Then, env, b)
+		c.expr(e.Else, env, b)
+	case ast.InterpolatedStringExpr:
+		for _, part := range e.Parts {
+			c.expr(part, env, b)
+		}
+	case ast.SpreadExpr:
+		c.expr(e.Value, env, b)
+	case ast.AsyncExpr:
+		c.statements(e.Body, interpreter.NewChildEnvironment(env), b)
+	case ast.AwaitExpr:
+		c.expr(e.Expr, env, b)
+	case ast.MatchExpr:
+		c.expr(e.Value, env, b)
+		for _, matchCase := range e.Cases {
+			caseEnv := interpreter.NewChildEnvironment(env)
+			bindPattern(matchCase.Pattern, caseEnv)
+			c.expr(matchCase.Guard, caseEnv, b)
+			c.expr(matchCase.Body, caseEnv, b)
+		}
+	case *ast.MacroInvocation:
+		b.opaque = true
+		for _, arg := range e.Args {
+			c.expr(arg, env, b)
+		}
+	}
+}
+
+// use marks a referenced function as used unless it refers to itself
+func (c *checker) use(value interface{}, b *body) {
+	if fn, ok := value.(*ast.Function); ok && fn.Name != b.function {
+		c.used[fn.Name] = true
+	}
+}
+
+// call checks a function call. piped is the value passed with |>, which
+// becomes the first argument.
+func (c *checker) call(call ast.FunctionCallExpr, piped ast.Expr, env *interpreter.Environment, b *body) {
+	args := call.Args
+	if piped != nil {
+		args = append([]ast.Expr{piped}, args...)
+	}
+	for _, arg := range call.Args {
+		c.expr(arg, env, b)
+	}
+	for _, arg := range call.NamedArgs {
+		c.expr(arg.Value, env, b)
+	}
+
+	// ws.* calls compile to WebSocket instructions
+	if interpreter.IsBuiltinFunction(call.Name) || strings.HasPrefix(call.Name, "ws.") {
+		return
+	}
+
+	// obj.method(...) and namespace.function(...)
+	if root, rest, dotted := strings.Cut(call.Name, "."); dotted {
+		b.refs[root] = true
+		if enum, ok := c.enums[root]; ok && !env.Has(root) {
+			switch {
+			case rest != "values":
+				c.errorAt(b, call.Pos, "enum %s has no method %s", root, rest)
+			case len(call.Args)+len(call.NamedArgs) > 0:
+				c.errorAt(b, call.Pos, "%s.values() takes no arguments", enum.Name)
+			}
+			return
+		}
+		if !env.Has(root) {
+			if !c.hidden(b) {
+				c.errorAt(b, call.Pos, "undefined object: %s", root)
+			}
+			return
+		}
+		if c.namespaces[root] && !strings.Contains(rest, ".") {
+			if fn, ok := c.functions[rest]; ok {
+				c.use(fn, b)
+				c.checkArgs(fn, args, call.NamedArgs, call.Pos, b)
+			}
+		}
+		return
+	}
+
+	b.refs[call.Name] = true
+	value, err := env.Get(call.Name)
+	if err != nil {
+		// The parser turns a.b.method(x) into method(a.b, x), which the
+		// interpreter dispatches to a method on the first argument
+		if len(call.Args) > 0 {
+			switch call.Args[0].(type) {
+			case ast.FieldAccessExpr, ast.ArrayIndexExpr, ast.FunctionCallExpr:
+				return
+			}
+		}
+		if !c.hidden(b) {
+			c.errorAt(b, call.Pos, "undefined function: %s", call.Name)
+		}
+		return
+	}
+	if fn, ok := value.(*ast.Function); ok {
+		c.use(fn, b)
+		c.checkArgs(fn, args, call.NamedArgs, call.Pos, b)
+	}
+}
+
+// checkArgs applies the argument binding rules of executeFunction and
+// checks literal arguments against the parameter types
+func (c *checker) checkArgs(fn *ast.Function, args []ast.Expr, named []ast.NamedArg, pos ast.Pos, b *body) {
+	bound, err := fn.BindArgs(args, named)
+	if err != nil {
+		c.errorAt(b, pos, "%s", err)
+		return
+	}
+	if len(fn.TypeParams) > 0 {
+		return
+	}
+	for idx, arg := range bound {
+		param := fn.Params[idx]
+		c.checkValue(arg, param.TypeAnnotation, fmt.Sprintf("argument %d (%s) of %s", idx+1, param.Name, fn.Name), b)
+	}
+}
+
+// checkValue checks literals in expr against the declared type t: scalar
+// literals must have a compatible type, and object literals for a declared
+// type must only use its fields and must set every required one
+func (c *checker) checkValue(expr ast.Expr, t ast.Type, what string, b *body) {
+	if expr == nil || t == nil {
+		return
+	}
+	if opt, ok := t.(ast.OptionalType); ok {
+		if lit, ok := expr.(ast.LiteralExpr); ok {
+			if _, isNull := lit.Value.(ast.NullLiteral); isNull {
+				return
+			}
+		}
+		t = opt.InnerType
+	}
+
+	switch e := expr.(type) {
+	case ast.LiteralExpr:
+		actual := literalType(e.Value)
+		if actual == nil {
+			return
+		}
+		switch t.(type) {
+		case ast.IntType, ast.StringType, ast.BoolType, ast.FloatType, ast.ArrayType, ast.UnionType:
+			if !c.typeCheck.TypesCompatible(actual, t) {
+				c.errorAt(b, ast.Pos{}, "%s: expected %s, got %s", what, c.typeCheck.TypeToString(t), c.typeCheck.TypeToString(actual))
+			}
+		case ast.NamedType:
+			if _, isType := c.types[t.(ast.NamedType).Name]; isType {
+				c.errorAt(b, ast.Pos{}, "%s: expected %s, got %s", what, c.typeCheck.TypeToString(t), c.typeCheck.TypeToString(actual))
+			}
+		}
+	case ast.ObjectExpr:
+		named, ok := t.(ast.NamedType)
+		if !ok {
+			return
+		}
+		typeDef, ok := c.types[named.Name]
+		if !ok || len(typeDef.TypeParams) > 0 {
+			return
+		}
+		set := make(map[string]bool)
+		spread := false
+		for _, field := range e.Fields {
+			// A spread may provide any of the fields
+			if _, ok := field.Value.(ast.SpreadExpr); ok {
+				spread = true
+				continue
+			}
+			set[field.Key] = true
+			decl := findField(typeDef, field.Key)
+			if decl == nil {
+				// Extra fields pass runtime validation but are usually a typo
+				pos := exprPos(field.Value)
+				if !pos.HasPos() {
+					pos = b.pos
+				}
+				c.report(b.file, pos, SeverityWarning, "%s: field '%s' is not declared in type %s", what, field.Key, typeDef.Name)
+				continue
+			}
+			c.checkValue(field.Value, decl.TypeAnnotation, fmt.Sprintf("field '%s' of %s", field.Key, typeDef.Name), b)
+		}
+		for _, field := range typeDef.Fields {
+			if field.Required && field.Default == nil && !set[field.Name] && !spread {
+				c.errorAt(b, ast.Pos{}, "%s: missing required field '%s' of type %s", what, field.Name, typeDef.Name)
+			}
+		}
+	case ast.ArrayExpr:
+		arr, ok := t.(ast.ArrayType)
+		if !ok {
+			return
+		}
+		for idx, elem := range e.Elements {
+			if _, ok := elem.(ast.SpreadExpr); ok {
+				continue
+			}
+			c.checkValue(elem, arr.ElementType, fmt.Sprintf("%s element %d", what, idx), b)
+		}
+	}
+}
+
+func findField(typeDef *ast.TypeDef, name string) *ast.Field {
+	for i := range typeDef.Fields {
+		if typeDef.Fields[i].Name == name {
+			return &typeDef.Fields[i]
+		}
+	}
+	return nil
+}
+
+// literalType is the type of a literal, or nil for null
+func literalType(lit ast.Literal) ast.Type {
+	switch lit.(type) {
+	case ast.IntLiteral:
+		return ast.IntType{}
+	case ast.StringLiteral:
+		return ast.StringType{}
+	case ast.BoolLiteral:
+		return ast.BoolType{}
+	case ast.FloatLiteral:
+		return ast.FloatType{}
+	}
+	return nil
+}
+
+// bindPattern defines the variables a match pattern binds
+func bindPattern(pattern ast.Pattern, env *interpreter.Environment) {
+	switch p := pattern.(type) {
+	case ast.VariablePattern:
+		env.Define(p.Name, nil)
+	case ast.ObjectPattern:
+		for _, field := range p.Fields {
+			if field.Pattern == nil {
+				env.Define(field.Key, nil)
+			} else {
+				bindPattern(field.Pattern, env)
+			}
+		}
+	case ast.TypePattern:
+		bindPattern(ast.ObjectPattern{Fields: p.Fields}, env)
+	case ast.ArrayPattern:
+		for _, elem := range p.Elements {
+			bindPattern(elem, env)
+		}
+		if p.Rest != nil {
+			env.Define(*p.Rest, nil)
+		}
+	}
+}
+
+// exprPos returns the position of expressions that record one
+func exprPos(expr ast.Expr) ast.Pos {
+	switch e := expr.(type) {
+	case ast.VariableExpr:
+		return e.Pos
+	case ast.BinaryOpExpr:
+		return e.Pos
+	case ast.UnaryOpExpr:
+		return e.Pos
+	case ast.FieldAccessExpr:
+		return e.Pos
+	case ast.ArrayIndexExpr:
+		return e.Pos
+	case ast.FunctionCallExpr:
+		return e.Pos
+	case ast.TernaryExpr:
+		return e.Pos
+	case ast.InterpolatedStringExpr:
+		return e.Pos
+	case ast.SpreadExpr:
+		return e.Pos
+	}
+	return ast.Pos{}
+}
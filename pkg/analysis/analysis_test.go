@@ -0,0 +1,313 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkSource parses source and returns its diagnostics
+func checkSource(t *testing.T, source string) []Diagnostic {
+	t.Helper()
+	tokens, err := parser.NewLexer(source).Tokenize()
+	require.NoError(t, err)
+	module, err := parser.NewParser(tokens).Parse()
+	require.NoError(t, err)
+	return Check(module, Options{File: "main.glyph"})
+}
+
+// messages formats diagnostics as "line:column severity: message"
+func messages(diags []Diagnostic) []string {
+	out := make([]string, len(diags))
+	for i, d := range diags {
+		out[i] = ast.Pos{Line: d.Line, Column: d.Column}.String() + " " + string(d.Severity) + ": " + d.Message
+	}
+	return out
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{
+			name: "clean program",
+			source: `: User {
+  name: str!
+  age: int = 0
+}
+
+! greet(name: str!): str {
+  > "Hello, " + name
+}
+
+@ GET /users/:id -> User {
+  $ label = greet(id)
+  for item in [1, 2] {
+    $ label = label + toString(item)
+  }
+  > {name: label}
+}
+`,
+		},
+		{
+			name: "undefined variable",
+			source: `@ GET /x {
+  if true {
+    $ msg = "hi"
+  }
+  > {text: msg}
+}
+`,
+			want: []string{"5:12 error: undefined variable: msg"},
+		},
+		{
+			name: "undefined function",
+			source: `@ GET /x {
+  > fromat("x")
+}
+`,
+			want: []string{"2:5 error: undefined function: fromat"},
+		},
+		{
+			name: "arity",
+			source: `! add(a: int!, b: int!): int {
+  > a + b
+}
+
+@ GET /x {
+  $ one = add(1)
+  $ three = add(1, 2, 3)
+  > one + three
+}
+`,
+			want: []string{
+				"6:11 error: missing required argument b in function add",
+				"7:13 error: function add expects at most 2 arguments, got 3",
+			},
+		},
+		{
+			name: "named arguments",
+			source: `! greet(name: str!, greeting: str = "Hello"): str {
+  > greeting + ", " + name
+}
+
+@ GET /x {
+  $ a = greet(greeting: "Hi", name: "Bob")
+  $ b = greet(greeting: "Hi")
+  $ c = greet("Bob", nmae: "Bob")
+  > a + b + c
+}
+`,
+			want: []string{
+				"7:9 error: missing required argument name in function greet",
+				"8:9 error: function greet has no parameter nmae",
+			},
+		},
+		{
+			name: "redeclaration and assignment",
+			source: `const LIMIT = 10
+
+@ GET /users/:id {
+  $ id = 1
+  $ n = 1
+  $ n = 2
+  count = 3
+  LIMIT = 5
+  > n
+}
+`,
+			want: []string{
+				"4:5 error: cannot redeclare path parameter 'id' — it is already bound from the route pattern",
+				"6:5 error: cannot redeclare variable 'n' in the same scope",
+				"7:3 error: cannot assign to undeclared variable 'count'",
+				"8:3 error: cannot reassign constant 'LIMIT'",
+			},
+		},
+		{
+			name: "constants and enums",
+			source: `: Status = "pending" | "shipped"
+
+const LIMIT = 10
+const LIMIT = 20
+
+@ GET /orders {
+  $ LIMIT = 5
+  $ all = Status.values()
+  > Status.count()
+}
+`,
+			want: []string{
+				"4:1 error: constant 'LIMIT' is already defined",
+				"7:5 error: cannot reassign constant 'LIMIT'",
+				"9:11 error: enum Status has no method count",
+			},
+		},
+		{
+			name: "typed constants",
+			source: `const N: int = "hello"
+
+@ GET /x {
+  const M: int = "hello"
+  const K: str = "ok"
+  > M
+}
+`,
+			want: []string{
+				"1:1 error: constant 'N': expected int, got string",
+				"3:1 error: constant 'M': expected int, got string",
+			},
+		},
+		{
+			name: "return type fields",
+			source: `: User {
+  name: str!
+  age: int
+}
+
+@ GET /x -> User {
+  > {age: "old", nmae: "bob"}
+}
+`,
+			want: []string{
+				"6:1 error: field 'age' of User: expected int, got string",
+				"6:1 warning: return value: field 'nmae' is not declared in type User",
+				"6:1 error: return value: missing required field 'name' of type User",
+			},
+		},
+		{
+			name: "unused path parameter and dead route",
+			source: `@ GET /users/:id {
+  > {id: id}
+}
+
+@ GET /users/me {
+  > {}
+}
+
+@ POST /users/:name {
+  > {}
+}
+`,
+			want: []string{
+				"5:1 warning: route GET /users/me is unreachable: GET /users/:id is declared earlier and matches the same paths",
+				"9:1 warning: path parameter 'name' of POST /users/:name is never used",
+			},
+		},
+		{
+			name: "unused function",
+			source: `! helper(): int {
+  > helper()
+}
+
+! used(): int {
+  > 1
+}
+
+@ GET /x {
+  > [1] |> map(used)
+}
+`,
+			want: []string{"1:1 warning: function 'helper' is never used"},
+		},
+		{
+			name: "try and catch blocks",
+			source: `@ GET /x {
+  try {
+    $ ratio = 1 / 0
+  } catch (e) {
+    > {error: e.message, ratio: ratio}
+  }
+  > {error: e.message}
+}
+`,
+			want: []string{
+				"5:33 error: undefined variable: ratio",
+				"7:13 error: undefined variable: e",
+			},
+		},
+		{
+			name: "seed blocks use helpers and db",
+			source: `! email(name: str!): str {
+  > name + "@example.com"
+}
+
+seed! users {
+  db.users.firstOrCreate({email: email("ada")}, {name: nme})
+}
+`,
+			want: []string{"6:56 error: undefined variable: nme"},
+		},
+		{
+			name: "named middleware",
+			source: `@ middleware requireAdmin {
+  if auth.user.role != "admin" {
+    error(403, "forbidden")
+  }
+  $ admin = auth.user
+}
+
+@ GET /admin {
+  + auth(jwt)
+  + requireAdmin
+  + missing
+  > {admin: admin, other: nope}
+}
+`,
+			want: []string{
+				"11:5 error: undefined middleware 'missing'",
+				"12:27 error: undefined variable: nope",
+			},
+		},
+		{
+			name: "library without entry points",
+			source: `! helper(): int {
+  > 1
+}
+`,
+		},
+		{
+			name: "method calls on expressions",
+			source: `@ GET /x {
+  % db: Database
+  $ users = db.users.all()
+  > input.items.filter("active", true)
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := messages(checkSource(t, tt.source))
+			if len(tt.want) == 0 {
+				assert.Empty(t, got)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestCheckPartialImports tests that names from imports that were not loaded
+// are not reported
+func TestCheckPartialImports(t *testing.T) {
+	tokens, err := parser.NewLexer("import \"./lib\"\n@ GET /x {\n  > helper()\n}\n").Tokenize()
+	require.NoError(t, err)
+	module, err := parser.NewParser(tokens).Parse()
+	require.NoError(t, err)
+
+	assert.Empty(t, Check(module, Options{Partial: true}))
+	assert.Len(t, Check(module, Options{}), 1)
+}
+
+func TestDiagnosticString(t *testing.T) {
+	d := Diagnostic{File: "main.glyph", Line: 3, Column: 7, Severity: SeverityError, Message: "undefined variable: x"}
+	assert.Equal(t, "main.glyph:3:7: error: undefined variable: x", d.String())
+	assert.True(t, HasErrors([]Diagnostic{d}))
+	assert.False(t, HasErrors([]Diagnostic{{Severity: SeverityWarning}}))
+}
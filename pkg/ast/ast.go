@@ -21,6 +21,7 @@ type TypeDef struct {
 	Fields     []Field
 	Traits     []string    // Trait names this type implements
 	Methods    []MethodDef // Method implementations (for trait conformance)
+	Pos        Pos         // Position of the leading token
 }
 
 func (TypeDef) isItem() {}
@@ -42,6 +43,25 @@ type TraitDef struct {
 
 func (TraitDef) isItem() {}
 
+// EnumDef represents an enum: a named, closed set of string values
+// Example: : Status = "pending" | "shipped" | "delivered"
+type EnumDef struct {
+	Name   string
+	Values []string
+}
+
+func (EnumDef) isItem() {}
+
+// Has reports whether value is one of the enum's values
+func (e EnumDef) Has(value string) bool {
+	for _, v := range e.Values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 // MethodDef represents a method implementation on a type
 type MethodDef struct {
 	Name       string
@@ -60,11 +80,53 @@ type Route struct {
 	RateLimit   *RateLimit
 	Injections  []Injection
 	QueryParams []QueryParamDecl
+	Middlewares []MiddlewareRef // named middleware applied with + name, in order
 	Body        []Statement
+	Pos         Pos // Position of the leading @
 }
 
 func (Route) isItem() {}
 
+// MiddlewareRef names a middleware definition applied to a route
+type MiddlewareRef struct {
+	Name string
+	Pos  Pos
+}
+
+// MiddlewareDef represents a named middleware whose body runs before the
+// body of each route that applies it, in the route's environment. A return
+// or error in the body answers the request without running the route.
+// Example: @ middleware requireAdmin { if auth.user.role != "admin" { error(403, "forbidden") } }
+type MiddlewareDef struct {
+	Name string
+	Body []Statement
+	Pos  Pos // Position of the leading @
+}
+
+func (MiddlewareDef) isItem() {}
+
+// ErrorHandlerKind is the kind of response an ErrorHandler answers with
+type ErrorHandlerKind string
+
+const (
+	// NotFoundHandler answers requests that match no route
+	NotFoundHandler ErrorHandlerKind = "notfound"
+	// OnErrorHandler answers in place of the built-in error responses
+	OnErrorHandler ErrorHandlerKind = "onerror"
+)
+
+// ErrorHandler represents an @ notfound or @ onerror handler. Its body runs
+// like a route body; in an onerror handler err holds the status, message and
+// path of the error being answered.
+// Example: @ onerror { > {error: err.message, status: err.status} }
+type ErrorHandler struct {
+	Kind ErrorHandlerKind
+	Body []Statement
+	Pos  Pos // Position of the leading @
+}
+
+func (ErrorHandler) isItem() {}
+
 // Function represents a function definition
 // Example: ! map<T, U>(arr: [T], fn: (T) -> U): [U]
 type Function struct {
@@ -73,6 +135,7 @@ type Function struct {
 	Params     []Field
 	ReturnType Type
 	Body       []Statement
+	Pos        Pos // Position of the leading !
 }
 
 func (Function) isItem() {}
@@ -93,6 +156,7 @@ type Field struct {
 	Required       bool
 	Default        Expr              // nil if no default value
 	Annotations    []FieldAnnotation // nil when no annotations are present
+	Pos            Pos               // Position of the field name
 }
 
 // Type represents a type annotation
@@ -115,6 +179,7 @@ type OptionalType struct {
 
 type NamedType struct {
 	Name string
+	Pos  Pos // Position of the type name
 }
 
 type DatabaseType struct{}
@@ -238,10 +303,14 @@ type Statement interface {
 	isNode()
 }
 
-// AssignStatement represents variable declaration with $ syntax
+// AssignStatement represents variable declaration with $ syntax, or with
+// const syntax for a binding that cannot be reassigned
 type AssignStatement struct {
 	Target string
 	Value  Expr
+	Const  bool // Declared with const rather than $
+	Type   Type // Type annotation of a const (nil if type is inferred)
+	Pos    Pos  // Position of the target name
 }
 
 func (AssignStatement) isStatement() {}
@@ -250,6 +319,7 @@ func (AssignStatement) isStatement() {}
 type ReassignStatement struct {
 	Target string
 	Value  Expr
+	Pos    Pos // Position of the target name
 }
 
 func (ReassignStatement) isStatement() {}
@@ -382,6 +452,27 @@ type YieldStatement struct {
 
 func (YieldStatement) isStatement() {}
 
+// BackgroundStatement represents a background block: background { ... } or
+// defer! { ... }. The body runs in a goroutine after the route's response is
+// produced, with a copy of the route's variables.
+type BackgroundStatement struct {
+	Body []Statement
+	Pos  Pos
+}
+
+func (BackgroundStatement) isStatement() {}
+
+// TryStatement represents try { ... } catch (e) { ... }. An error raised
+// by the body runs the catch block with the error bound to ErrorVar.
+type TryStatement struct {
+	Body     []Statement
+	ErrorVar string // Name bound to the caught error (empty if not bound)
+	Catch    []Statement
+	Pos      Pos
+}
+
+func (TryStatement) isStatement() {}
+
 // AssertStatement represents an assertion in a test block
 // Example: assert(condition) or assert(condition, "message")
 type AssertStatement struct {
@@ -449,11 +540,13 @@ type UnaryOpExpr struct {
 
 func (UnaryOpExpr) isExpr() {}
 
-// FieldAccessExpr represents field access
+// FieldAccessExpr represents field access. With Optional (obj?.field) a
+// null object gives null instead of an error.
 type FieldAccessExpr struct {
-	Object Expr
-	Field  string
-	Pos    Pos
+	Object   Expr
+	Field    string
+	Optional bool
+	Pos      Pos
 }
 
 func (FieldAccessExpr) isExpr() {}
@@ -470,14 +563,58 @@ func (ArrayIndexExpr) isExpr() {}
 // FunctionCallExpr represents a function call
 // Example: map<int, string>(arr, fn)
 type FunctionCallExpr struct {
-	Name     string
-	TypeArgs []Type // Type arguments for generic function calls (e.g., <int, string>)
-	Args     []Expr
-	Pos      Pos
+	Name      string
+	TypeArgs  []Type // Type arguments for generic function calls (e.g., <int, string>)
+	Args      []Expr
+	NamedArgs []NamedArg // Arguments passed by parameter name, after Args
+	Pos       Pos
 }
 
 func (FunctionCallExpr) isExpr() {}
 
+// NamedArg is an argument passed by the name of the parameter it binds to
+// Example: greet(name: "Bob")
+type NamedArg struct {
+	Name  string
+	Value Expr
+	Pos   Pos
+}
+
+// BindArgs matches the positional and named arguments of a call to fn's
+// parameters. The result holds each parameter's argument, or nil where the
+// call leaves it to its default or to null. It is an error to pass too many
+// positional arguments, to name a parameter fn does not have or one that
+// already has an argument, or to leave out a required parameter.
+func (fn *Function) BindArgs(args []Expr, named []NamedArg) ([]Expr, error) {
+	if len(args) > len(fn.Params) {
+		return nil, fmt.Errorf("function %s expects at most %d arguments, got %d", fn.Name, len(fn.Params), len(args))
+	}
+	bound := make([]Expr, len(fn.Params))
+	copy(bound, args)
+	for _, arg := range named {
+		idx := -1
+		for n, param := range fn.Params {
+			if param.Name == arg.Name {
+				idx = n
+				break
+			}
+		}
+		if idx < 0 {
+			return nil, fmt.Errorf("function %s has no parameter %s", fn.Name, arg.Name)
+		}
+		if bound[idx] != nil {
+			return nil, fmt.Errorf("argument %s of function %s is given more than once", arg.Name, fn.Name)
+		}
+		bound[idx] = arg.Value
+	}
+	for idx, param := range fn.Params {
+		if bound[idx] == nil && param.Required && param.Default == nil {
+			return nil, fmt.Errorf("missing required argument %s in function %s", param.Name, fn.Name)
+		}
+	}
+	return bound, nil
+}
+
 // ObjectExpr represents an object literal
 type ObjectExpr struct {
 	Fields []ObjectField
@@ -485,19 +622,30 @@ type ObjectExpr struct {
 
 func (ObjectExpr) isExpr() {}
 
-// ObjectField represents a field in an object literal
+// ObjectField represents a field in an object literal. A spread field
+// ({...base}) has an empty Key and a SpreadExpr Value.
 type ObjectField struct {
 	Key   string
 	Value Expr
 }
 
-// ArrayExpr represents an array literal
+// ArrayExpr represents an array literal. Elements may be SpreadExprs.
 type ArrayExpr struct {
 	Elements []Expr
 }
 
 func (ArrayExpr) isExpr() {}
 
+// SpreadExpr represents ...value in an array or object literal: the elements
+// of an array, or the fields of an object, in place
+type SpreadExpr struct {
+	Value Expr
+	Pos   Pos
+}
+
+func (SpreadExpr) isExpr() {}
+func (SpreadExpr) isNode() {}
+
 // LambdaExpr represents an anonymous function (lambda/arrow function)
 // Example: (x) => x * 2, (a, b) => a + b
 type LambdaExpr struct {
@@ -517,6 +665,28 @@ type PipeExpr struct {
 
 func (PipeExpr) isExpr() {}
 
+// TernaryExpr represents a conditional expression: cond ? then : else
+// Only the branch selected by the boolean condition is evaluated.
+type TernaryExpr struct {
+	Condition Expr
+	Then      Expr
+	Else      Expr
+	Pos       Pos
+}
+
+func (TernaryExpr) isExpr() {}
+
+// InterpolatedStringExpr represents a string with embedded expressions:
+// "Hello, {name}!". Parts holds the string literals and embedded expressions
+// in order; the values of the expressions are converted to strings.
+type InterpolatedStringExpr struct {
+	Parts []Expr
+	Pos   Pos
+}
+
+func (InterpolatedStringExpr) isExpr() {}
+func (InterpolatedStringExpr) isNode() {}
+
 // Literal represents a literal value
 type Literal interface {
 	isLiteral()
@@ -564,6 +734,7 @@ const (
 	And
 	Or
 	Mod
+	Coalesce // ??: the left operand unless it is null, else the right
 )
 
 func (op BinOp) String() string {
@@ -594,6 +765,8 @@ func (op BinOp) String() string {
 		return "||"
 	case Mod:
 		return "%"
+	case Coalesce:
+		return "??"
 	default:
 		return "UNKNOWN"
 	}
@@ -661,17 +834,19 @@ type Command struct {
 	Params      []CommandParam
 	ReturnType  Type
 	Body        []Statement
+	Pos         Pos // Position of the leading token
 }
 
 func (Command) isItem() {}
 
 // CommandParam represents a CLI command parameter with optional default
 type CommandParam struct {
-	Name     string
-	Type     Type
-	Required bool
-	Default  Expr // nil if no default
-	IsFlag   bool // true for --flag style args
+	Name        string
+	Type        Type
+	Required    bool
+	Default     Expr   // nil if no default
+	IsFlag      bool   // true for --flag style args
+	Description string // optional help text shown by glyph exec --help
 }
 
 // CronTask represents a scheduled task
@@ -681,6 +856,7 @@ type CronTask struct {
 	Schedule   string // cron expression
 	Timezone   string // optional timezone (default UTC)
 	Retries    int    // number of retries on failure
+	Overlap    string // "skip" (default) or "queue": what to do when a run is due while the last is still going
 	Injections []Injection
 	Body       []Statement
 }
@@ -844,6 +1020,7 @@ type ConstDecl struct {
 	Name  string // The constant name
 	Value Expr   // The constant value expression
 	Type  Type   // Optional type annotation (nil if type is inferred)
+	Pos   Pos    // Position of the const keyword
 }
 
 func (ConstDecl) isItem() {}
@@ -858,6 +1035,17 @@ type TestBlock struct {
 
 func (TestBlock) isItem() {}
 
+// SeedBlock represents a block of statements that fills the database with
+// data, run by glyph seed with the database bound to db
+// Compact syntax: seed! name { body }
+// Expanded syntax: seed name { body }
+type SeedBlock struct {
+	Name string // optional; selects the block with glyph seed --only
+	Body []Statement
+}
+
+func (SeedBlock) isItem() {}
+
 // AsyncExpr represents an async block expression: async { ... }
 // The block is executed asynchronously and returns a Future
 type AsyncExpr struct {
@@ -943,6 +1131,16 @@ type ArrayPattern struct {
 
 func (ArrayPattern) isPattern() {}
 
+// TypePattern matches a value of a named type, such as one variant of a
+// union, and destructures its fields
+// Example: Error {message} or NotFound {}
+type TypePattern struct {
+	TypeName string
+	Fields   []ObjectPatternField
+}
+
+func (TypePattern) isPattern() {}
+
 // MacroDef represents a macro definition
 // Example: macro! log(level, msg) { ... }
 type MacroDef struct {
@@ -1057,6 +1255,8 @@ func (WsCloseStatement) isNode()     {}
 func (ValidationStatement) isNode()  {}
 func (ExpressionStatement) isNode()  {}
 func (YieldStatement) isNode()       {}
+func (BackgroundStatement) isNode()  {}
+func (TryStatement) isNode()         {}
 func (WebSocketEvent) isNode()       {}
 func (LiteralExpr) isNode()          {}
 func (VariableExpr) isNode()         {}
@@ -1070,6 +1270,7 @@ func (ArrayExpr) isNode()            {}
 func (QuoteExpr) isNode()            {}
 func (UnquoteExpr) isNode()          {}
 func (MatchExpr) isNode()            {}
+func (TernaryExpr) isNode()          {}
 func (LiteralPattern) isNode()       {}
 func (VariablePattern) isNode()      {}
 func (WildcardPattern) isNode()      {}
@@ -1079,5 +1280,8 @@ func (AsyncExpr) isNode()            {}
 func (AwaitExpr) isNode()            {}
 func (LambdaExpr) isNode()           {}
 func (TestBlock) isNode()            {}
+func (SeedBlock) isNode()            {}
+func (MiddlewareDef) isNode()        {}
+func (ErrorHandler) isNode()         {}
 func (AssertStatement) isNode()      {}
 func (ProviderDef) isNode()          {}
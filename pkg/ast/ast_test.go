@@ -235,6 +235,7 @@ func TestPattern_Interface(t *testing.T) {
 		WildcardPattern{},
 		ObjectPattern{},
 		ArrayPattern{},
+		TypePattern{},
 	}
 
 	if len(patterns) == 0 {
@@ -0,0 +1,278 @@
+// Package bundle reads and writes GLYPH application bundles. A bundle is a
+// single zip file holding everything glyph run needs to serve an application
+// without its source: the compiled routes, the declarations used to validate
+// input, the static assets, and a manifest recording the compiler version,
+// the source hash and the SHA-256 digest of every other entry.
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// FormatVersion is the bundle layout version written to the manifest
+const FormatVersion = 1
+
+const (
+	// ManifestName is the entry holding the manifest
+	ManifestName = "manifest.json"
+	// DeclarationsName is the entry holding the type definitions, enums and
+	// route headers (without bodies) as GLYPH source
+	DeclarationsName = "declarations.glyph"
+)
+
+// zipComment marks a zip file as a bundle
+const zipComment = "glyph bundle"
+
+// maxEntrySize bounds the uncompressed size of one entry read from a bundle
+const maxEntrySize = 256 << 20
+
+// ErrNotBundle is returned by Read for data that is not a bundle
+var ErrNotBundle = errors.New("not a glyph bundle")
+
+// Manifest describes a bundle's contents
+type Manifest struct {
+	Format          int    `json:"format"`
+	CompilerVersion string `json:"compiler_version"`
+	// SourceHash is the hex SHA-256 of the source files the bundle was
+	// built from
+	SourceHash string `json:"source_hash"`
+	// Entry is the base name of the entry source file
+	Entry string `json:"entry"`
+	// Stripped is set for bundles built without debug info
+	Stripped bool `json:"stripped,omitempty"`
+	// Sessions is set when the program calls session.* builtins, whose
+	// cookies glyph run will only sign with SESSION_SECRET
+	Sessions      bool             `json:"sessions,omitempty"`
+	Routes        []Route          `json:"routes"`
+	WebSockets    []WebSocketRoute `json:"websockets,omitempty"`
+	Static        []StaticDir      `json:"static,omitempty"`
+	ErrorHandlers []ErrorHandler   `json:"error_handlers,omitempty"`
+	// Files maps every entry except the manifest to its hex SHA-256
+	Files map[string]string `json:"files"`
+}
+
+// Route is an HTTP route compiled into the bundle
+type Route struct {
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Bytecode string `json:"bytecode"` // entry holding the route's bytecode
+	// Source is the route's file:line, omitted from stripped bundles
+	Source string `json:"source,omitempty"`
+}
+
+// WebSocketRoute is a WebSocket route compiled into the bundle. Each event
+// names the entry holding its handler's bytecode, or is empty if the route
+// has no handler for it.
+type WebSocketRoute struct {
+	Path         string `json:"path"`
+	OnConnect    string `json:"on_connect,omitempty"`
+	OnMessage    string `json:"on_message,omitempty"`
+	OnDisconnect string `json:"on_disconnect,omitempty"`
+	OnError      string `json:"on_error,omitempty"`
+	Source       string `json:"source,omitempty"`
+}
+
+// ErrorHandler is an @ notfound or @ onerror handler compiled into the
+// bundle
+type ErrorHandler struct {
+	Kind     string `json:"kind"`     // notfound or onerror
+	Bytecode string `json:"bytecode"` // entry holding the handler's bytecode
+	Source   string `json:"source,omitempty"`
+}
+
+// StaticDir is an @ static directory embedded in the bundle
+type StaticDir struct {
+	Path string `json:"path"` // URL prefix
+	Dir  string `json:"dir"`  // entry directory holding the files
+}
+
+// Bundle is a bundle's manifest and entries
+type Bundle struct {
+	Manifest Manifest
+	files    map[string][]byte
+}
+
+// New returns an empty bundle with manifest m. Entries are added with Add,
+// which also records their digests in the manifest.
+func New(m Manifest) *Bundle {
+	m.Format = FormatVersion
+	m.Files = make(map[string]string)
+	return &Bundle{Manifest: m, files: make(map[string][]byte)}
+}
+
+// Add adds the entry name. Names are slash-separated paths as accepted by
+// fs.ValidPath.
+func (b *Bundle) Add(name string, data []byte) error {
+	if !fs.ValidPath(name) || name == "." || name == ManifestName {
+		return fmt.Errorf("invalid bundle entry name %q", name)
+	}
+	if _, exists := b.files[name]; exists {
+		return fmt.Errorf("duplicate bundle entry %q", name)
+	}
+	b.files[name] = data
+	b.Manifest.Files[name] = digest(data)
+	return nil
+}
+
+// File returns the contents of the entry name
+func (b *Bundle) File(name string) ([]byte, bool) {
+	data, ok := b.files[name]
+	return data, ok
+}
+
+// Write writes b as a zip file. Entries are written in name order after the
+// manifest, so building the same application twice gives the same bytes.
+func (b *Bundle) Write(w io.Writer) error {
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	zw := zip.NewWriter(w)
+	if err := zw.SetComment(zipComment); err != nil {
+		return err
+	}
+	if err := writeEntry(zw, ManifestName, manifest); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(b.files))
+	for name := range b.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writeEntry(zw, name, b.files[name]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// WriteFile writes b to path, readable by its owner only
+func (b *Bundle) WriteFile(path string) error {
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0600)
+}
+
+// writeEntry writes one deflated entry with a zero modification time
+func writeEntry(zw *zip.Writer, name string, data []byte) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// IsBundle reports whether data looks like a bundle. Read does the full
+// check.
+func IsBundle(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04")) &&
+		bytes.HasSuffix(data, []byte(zipComment))
+}
+
+// Read parses a bundle and verifies its integrity: every entry must be
+// listed in the manifest with a matching digest, and every listed entry
+// must be present.
+func Read(data []byte) (*Bundle, error) {
+	if !IsBundle(data) {
+		return nil, ErrNotBundle
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("corrupt bundle: %w", err)
+	}
+
+	b := &Bundle{files: make(map[string][]byte)}
+	var manifest []byte
+	for _, f := range zr.File {
+		contents, err := readEntry(f)
+		if err != nil {
+			return nil, err
+		}
+		if f.Name == ManifestName {
+			manifest = contents
+			continue
+		}
+		if _, exists := b.files[f.Name]; exists {
+			return nil, fmt.Errorf("corrupt bundle: duplicate entry %s", f.Name)
+		}
+		b.files[f.Name] = contents
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("corrupt bundle: missing %s", ManifestName)
+	}
+	if err := json.Unmarshal(manifest, &b.Manifest); err != nil {
+		return nil, fmt.Errorf("corrupt bundle: invalid manifest: %w", err)
+	}
+	if b.Manifest.Format != FormatVersion {
+		return nil, fmt.Errorf("unsupported bundle format %d (this glyph reads format %d)", b.Manifest.Format, FormatVersion)
+	}
+
+	for name, contents := range b.files {
+		want, listed := b.Manifest.Files[name]
+		if !listed {
+			return nil, fmt.Errorf("integrity check failed: %s is not in the manifest", name)
+		}
+		if digest(contents) != want {
+			return nil, fmt.Errorf("integrity check failed: %s does not match its digest", name)
+		}
+	}
+	for name := range b.Manifest.Files {
+		if _, ok := b.files[name]; !ok {
+			return nil, fmt.Errorf("integrity check failed: %s is missing", name)
+		}
+	}
+	return b, nil
+}
+
+// Open reads and verifies the bundle at path
+func Open(path string) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Read(data)
+}
+
+// readEntry returns the contents of f, which are checked against the CRC
+// in the zip header as they are read
+func readEntry(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > maxEntrySize {
+		return nil, fmt.Errorf("corrupt bundle: %s is too large", f.Name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("corrupt bundle: %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	contents, err := io.ReadAll(io.LimitReader(rc, maxEntrySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("corrupt bundle: %s: %w", f.Name, err)
+	}
+	if len(contents) > maxEntrySize {
+		return nil, fmt.Errorf("corrupt bundle: %s is too large", f.Name)
+	}
+	return contents, nil
+}
+
+// digest returns the hex SHA-256 of data
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
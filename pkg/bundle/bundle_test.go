@@ -0,0 +1,180 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleBundle(t *testing.T) *Bundle {
+	t.Helper()
+	b := New(Manifest{
+		CompilerVersion: "1.2.3",
+		SourceHash:      "abc",
+		Entry:           "main.glyph",
+		Routes:          []Route{{Method: "GET", Path: "/hello", Bytecode: "routes/0.glyphc", Source: "main.glyph:1"}},
+		Static:          []StaticDir{{Path: "/assets", Dir: "static/0"}},
+	})
+	require.NoError(t, b.Add("routes/0.glyphc", []byte("GLYP\x01bytecode")))
+	require.NoError(t, b.Add(DeclarationsName, []byte("@ GET /hello {\n}\n")))
+	require.NoError(t, b.Add("static/0/index.html", []byte("<h1>hi</h1>")))
+	return b
+}
+
+func encode(t *testing.T, b *Bundle) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, b.Write(&buf))
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	data := encode(t, sampleBundle(t))
+	assert.True(t, IsBundle(data))
+
+	b, err := Read(data)
+	require.NoError(t, err)
+	assert.Equal(t, FormatVersion, b.Manifest.Format)
+	assert.Equal(t, "1.2.3", b.Manifest.CompilerVersion)
+	assert.Equal(t, "main.glyph", b.Manifest.Entry)
+	assert.Equal(t, []Route{{Method: "GET", Path: "/hello", Bytecode: "routes/0.glyphc", Source: "main.glyph:1"}}, b.Manifest.Routes)
+	assert.Len(t, b.Manifest.Files, 3)
+
+	html, ok := b.File("static/0/index.html")
+	require.True(t, ok)
+	assert.Equal(t, "<h1>hi</h1>", string(html))
+	_, ok = b.File("missing")
+	assert.False(t, ok)
+}
+
+func TestWriteIsDeterministic(t *testing.T) {
+	assert.Equal(t, encode(t, sampleBundle(t)), encode(t, sampleBundle(t)))
+}
+
+func TestAddRejectsBadNames(t *testing.T) {
+	b := New(Manifest{})
+	for _, name := range []string{ManifestName, "../escape", "/abs", "", "."} {
+		assert.Error(t, b.Add(name, nil), name)
+	}
+	require.NoError(t, b.Add("a.txt", nil))
+	assert.Error(t, b.Add("a.txt", nil))
+}
+
+func TestReadRejectsNonBundles(t *testing.T) {
+	_, err := Read([]byte("GLYP\x01raw bytecode"))
+	assert.ErrorIs(t, err, ErrNotBundle)
+
+	// A plain zip file is not a bundle either
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	_, err = zw.Create("file.txt")
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	_, err = Read(buf.Bytes())
+	assert.ErrorIs(t, err, ErrNotBundle)
+}
+
+// rewrite re-encodes a bundle's zip entries, letting edit change or drop
+// them, while keeping the bundle comment
+func rewrite(t *testing.T, data []byte, edit func(name string, contents []byte) ([]byte, bool)) []byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	require.NoError(t, zw.SetComment(zipComment))
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		contents, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+
+		contents, keep := edit(f.Name, contents)
+		if !keep {
+			continue
+		}
+		fw, err := zw.Create(f.Name)
+		require.NoError(t, err)
+		_, err = fw.Write(contents)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestReadVerifiesIntegrity(t *testing.T) {
+	data := encode(t, sampleBundle(t))
+
+	tampered := rewrite(t, data, func(name string, contents []byte) ([]byte, bool) {
+		if name == "routes/0.glyphc" {
+			return []byte("GLYP\x01evil"), true
+		}
+		return contents, true
+	})
+	_, err := Read(tampered)
+	assert.ErrorContains(t, err, "routes/0.glyphc does not match its digest")
+
+	missing := rewrite(t, data, func(name string, contents []byte) ([]byte, bool) {
+		return contents, name != "static/0/index.html"
+	})
+	_, err = Read(missing)
+	assert.ErrorContains(t, err, "static/0/index.html is missing")
+
+	noManifest := rewrite(t, data, func(name string, contents []byte) ([]byte, bool) {
+		return contents, name != ManifestName
+	})
+	_, err = Read(noManifest)
+	assert.ErrorContains(t, err, "missing manifest.json")
+}
+
+func TestReadRejectsUnlistedEntries(t *testing.T) {
+	data := encode(t, sampleBundle(t))
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	require.NoError(t, zw.SetComment(zipComment))
+	for _, f := range zr.File {
+		require.NoError(t, zw.Copy(f))
+	}
+	fw, err := zw.Create("extra.glyphc")
+	require.NoError(t, err)
+	_, err = fw.Write([]byte("smuggled"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	_, err = Read(buf.Bytes())
+	assert.ErrorContains(t, err, "extra.glyphc is not in the manifest")
+}
+
+func TestReadRejectsCorruption(t *testing.T) {
+	data := encode(t, sampleBundle(t))
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	offset, err := zr.File[0].DataOffset()
+	require.NoError(t, err)
+
+	// Flip a byte in the compressed contents of the first entry
+	corrupt := append([]byte(nil), data...)
+	corrupt[offset+2] ^= 0xff
+	_, err = Read(corrupt)
+	assert.Error(t, err)
+}
+
+func TestReadRejectsNewerFormat(t *testing.T) {
+	data := rewrite(t, encode(t, sampleBundle(t)), func(name string, contents []byte) ([]byte, bool) {
+		if name == ManifestName {
+			return bytes.Replace(contents, []byte(`"format": 1`), []byte(`"format": 99`), 1), true
+		}
+		return contents, true
+	})
+	_, err := Read(data)
+	assert.ErrorContains(t, err, "unsupported bundle format 99")
+}
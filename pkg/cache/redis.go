@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// RedisKeyPrefix prefixes the Redis keys of every cached value
+const RedisKeyPrefix = "glyph:cache:"
+
+// RedisClient is the subset of the go-redis client used by RedisStore
+type RedisClient interface {
+	Get(ctx context.Context, key string) *goredis.StringCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *goredis.StatusCmd
+	Del(ctx context.Context, keys ...string) *goredis.IntCmd
+	Close() error
+}
+
+// RedisStore is a Store kept in Redis, shared by every process that uses the
+// same server
+type RedisStore struct {
+	client RedisClient
+	ctx    context.Context
+}
+
+// NewRedisStore creates a store that keeps values through client
+func NewRedisStore(client RedisClient) *RedisStore {
+	return &RedisStore{client: client, ctx: context.Background()}
+}
+
+// NewRedisStoreFromURL creates a store from a Redis URL (e.g., "redis://localhost:6379/0")
+func NewRedisStoreFromURL(redisURL string) (*RedisStore, error) {
+	opts, err := goredis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	return NewRedisStore(goredis.NewClient(opts)), nil
+}
+
+// Get returns the value stored under key
+func (s *RedisStore) Get(key string) ([]byte, bool, error) {
+	data, err := s.client.Get(s.ctx, RedisKeyPrefix+key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Set stores value under key
+func (s *RedisStore) Set(key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(s.ctx, RedisKeyPrefix+key, value, ttl).Err()
+}
+
+// Delete removes the value stored under key
+func (s *RedisStore) Delete(key string) error {
+	return s.client.Del(s.ctx, RedisKeyPrefix+key).Err()
+}
+
+// Close closes the Redis connection
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/jsonvalue"
+)
+
+// ========================================
+// Value Cache (the % cache: Cache injectable)
+// ========================================
+
+// DefaultMaxEntries is the capacity of the in-memory store opened by Open
+const DefaultMaxEntries = 10000
+
+// Store holds encoded values for a ValueCache. A ttl of zero means the value
+// does not expire.
+type Store interface {
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(key string) error
+	Close() error
+}
+
+// Open returns the store for a URL: "memory://" or an empty string for an
+// in-process store, or a "redis://" or "rediss://" URL for a Redis store
+func Open(rawURL string) (Store, error) {
+	if rawURL == "" {
+		return NewMemoryStore(DefaultMaxEntries), nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache URL: %w", err)
+	}
+	switch u.Scheme {
+	case "memory":
+		return NewMemoryStore(DefaultMaxEntries), nil
+	case "redis", "rediss":
+		return NewRedisStoreFromURL(rawURL)
+	default:
+		return nil, fmt.Errorf("unsupported cache URL scheme %q", u.Scheme)
+	}
+}
+
+// ValueCache caches the interpreter's values in a Store. Values are stored
+// as JSON, so every read returns a fresh copy and the same types come back
+// from memory and Redis: objects, arrays, strings, booleans, null, and
+// numbers as int64 when whole and float64 otherwise.
+type ValueCache struct {
+	store Store
+
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is a Remember call in progress for one key
+type flight struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// NewValueCache creates a cache that keeps its values in store
+func NewValueCache(store Store) *ValueCache {
+	return &ValueCache{store: store, flights: make(map[string]*flight)}
+}
+
+// Get returns the value stored under key, and whether there was one
+func (c *ValueCache) Get(key string) (interface{}, bool, error) {
+	data, ok, err := c.store.Get(key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	value, err := decodeValue(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set stores value under key for ttl, or without expiry if ttl is zero
+func (c *ValueCache) Set(key string, value interface{}, ttl time.Duration) error {
+	data, err := encodeValue(value)
+	if err != nil {
+		return err
+	}
+	return c.store.Set(key, data, ttl)
+}
+
+// Delete removes the value stored under key
+func (c *ValueCache) Delete(key string) error {
+	return c.store.Delete(key)
+}
+
+// Remember returns the value stored under key. On a miss it stores the
+// result of fn for ttl and returns it. Concurrent misses on the same key
+// share one call of fn; if fn fails nothing is stored and every caller gets
+// the error.
+func (c *ValueCache) Remember(key string, ttl time.Duration, fn func() (interface{}, error)) (interface{}, error) {
+	if value, ok, err := c.Get(key); err != nil || ok {
+		return value, err
+	}
+
+	c.mu.Lock()
+	if f, ok := c.flights[key]; ok {
+		c.mu.Unlock()
+		<-f.done
+		if f.err != nil {
+			return nil, f.err
+		}
+		return decodeValue(f.data)
+	}
+	f := &flight{done: make(chan struct{})}
+	c.flights[key] = f
+	c.mu.Unlock()
+
+	f.data, f.err = c.fill(key, ttl, fn)
+
+	c.mu.Lock()
+	delete(c.flights, key)
+	c.mu.Unlock()
+	close(f.done)
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	return decodeValue(f.data)
+}
+
+// fill calls fn and stores its result, converting a panic into an error so
+// that callers waiting on the flight are released
+func (c *ValueCache) fill(key string, ttl time.Duration, fn func() (interface{}, error)) (data []byte, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	value, err := fn()
+	if err != nil {
+		return nil, err
+	}
+	if data, err = encodeValue(value); err != nil {
+		return nil, err
+	}
+	if err := c.store.Set(key, data, ttl); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Close closes the store
+func (c *ValueCache) Close() error {
+	return c.store.Close()
+}
+
+func encodeValue(value interface{}) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("value is not JSON-encodable: %w", err)
+	}
+	return data, nil
+}
+
+// decodeValue parses a stored value. Whole numbers decode as int64 and other
+// numbers as float64, matching the interpreter's number types.
+func decodeValue(data []byte) (interface{}, error) {
+	value, err := jsonvalue.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cached value: %w", err)
+	}
+	return value, nil
+}
+
+// ========================================
+// In-Memory Store
+// ========================================
+
+// MemoryStore is a Store held in an LRUCache, for development and single
+// process deployments
+type MemoryStore struct {
+	lru *LRUCache
+}
+
+// NewMemoryStore creates a store that holds at most maxEntries values,
+// evicting the least recently used
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{lru: NewLRUCache(WithCapacity(maxEntries), WithDefaultTTL(0))}
+}
+
+// Get returns the value stored under key
+func (s *MemoryStore) Get(key string) ([]byte, bool, error) {
+	value, ok := s.lru.Get(key)
+	if !ok {
+		return nil, false, nil
+	}
+	return value.([]byte), true, nil
+}
+
+// Set stores value under key
+func (s *MemoryStore) Set(key string, value []byte, ttl time.Duration) error {
+	return s.lru.Set(key, value, ttl)
+}
+
+// Delete removes the value stored under key
+func (s *MemoryStore) Delete(key string) error {
+	return s.lru.Delete(key)
+}
+
+// Close stops the store's cleanup goroutine
+func (s *MemoryStore) Close() error {
+	s.lru.Close()
+	return nil
+}
@@ -0,0 +1,249 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestValueCache_RoundTrip(t *testing.T) {
+	c := NewValueCache(NewMemoryStore(10))
+	defer c.Close()
+
+	value := map[string]interface{}{
+		"name":  "ada",
+		"age":   int64(36),
+		"score": 9.5,
+		"tags":  []interface{}{"a", int64(1), nil, true},
+	}
+	if err := c.Set("user", value, 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	got, ok, err := c.Get("user")
+	if err != nil || !ok {
+		t.Fatalf("Get = %v, %v, %v", got, ok, err)
+	}
+	if !reflect.DeepEqual(got, value) {
+		t.Errorf("Expected %v, got %v", value, got)
+	}
+
+	// Every read is a copy
+	got.(map[string]interface{})["name"] = "changed"
+	again, _, _ := c.Get("user")
+	if again.(map[string]interface{})["name"] != "ada" {
+		t.Error("Changing a read value changed the cached value")
+	}
+
+	if _, ok, _ := c.Get("missing"); ok {
+		t.Error("Get returned true for a missing key")
+	}
+	if err := c.Delete("user"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok, _ := c.Get("user"); ok {
+		t.Error("Get returned true for a deleted key")
+	}
+
+	if err := c.Set("fn", func() {}, 0); err == nil {
+		t.Error("Expected an error for a value that is not JSON-encodable")
+	}
+}
+
+func TestValueCache_TTLAndEviction(t *testing.T) {
+	c := NewValueCache(NewMemoryStore(2))
+	defer c.Close()
+
+	c.Set("short", "v", 20*time.Millisecond)
+	c.Set("forever", "v", 0)
+	time.Sleep(40 * time.Millisecond)
+	if _, ok, _ := c.Get("short"); ok {
+		t.Error("Expected the value to expire")
+	}
+	if _, ok, _ := c.Get("forever"); !ok {
+		t.Error("A value without a ttl should not expire")
+	}
+
+	// The least recently used value is evicted at capacity
+	c.Set("a", int64(1), 0)
+	c.Get("forever")
+	c.Set("b", int64(2), 0)
+	if _, ok, _ := c.Get("a"); ok {
+		t.Error("Expected the least recently used value to be evicted")
+	}
+	if _, ok, _ := c.Get("forever"); !ok {
+		t.Error("A recently used value should not be evicted")
+	}
+}
+
+func TestValueCache_RememberSingleFlight(t *testing.T) {
+	c := NewValueCache(NewMemoryStore(10))
+	defer c.Close()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		calls.Add(1)
+		<-release
+		return map[string]interface{}{"n": int64(1)}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 10)
+	for n := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := c.Remember("key", time.Minute, fn)
+			if err != nil {
+				t.Errorf("Remember failed: %v", err)
+			}
+			results[n] = value
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 call, got %d", calls.Load())
+	}
+	for _, value := range results {
+		if !reflect.DeepEqual(value, map[string]interface{}{"n": int64(1)}) {
+			t.Errorf("Unexpected result %v", value)
+		}
+	}
+
+	value, err := c.Remember("key", time.Minute, func() (interface{}, error) {
+		t.Error("A cached value should not call fn")
+		return nil, nil
+	})
+	if err != nil || !reflect.DeepEqual(value, map[string]interface{}{"n": int64(1)}) {
+		t.Errorf("Remember = %v, %v", value, err)
+	}
+}
+
+func TestValueCache_RememberError(t *testing.T) {
+	c := NewValueCache(NewMemoryStore(10))
+	defer c.Close()
+
+	_, err := c.Remember("key", time.Minute, func() (interface{}, error) {
+		return nil, errors.New("db down")
+	})
+	if err == nil || err.Error() != "db down" {
+		t.Errorf("Expected the fn error, got %v", err)
+	}
+	_, err = c.Remember("key", time.Minute, func() (interface{}, error) {
+		panic("bug")
+	})
+	if err == nil || err.Error() != "panic: bug" {
+		t.Errorf("Expected the panic as an error, got %v", err)
+	}
+	if _, ok, _ := c.Get("key"); ok {
+		t.Error("A failed fn should not store a value")
+	}
+
+	value, err := c.Remember("key", time.Minute, func() (interface{}, error) { return "ok", nil })
+	if err != nil || value != "ok" {
+		t.Errorf("Remember = %v, %v", value, err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	for _, rawURL := range []string{"", "memory://"} {
+		store, err := Open(rawURL)
+		if err != nil {
+			t.Fatalf("Open(%q) failed: %v", rawURL, err)
+		}
+		if _, ok := store.(*MemoryStore); !ok {
+			t.Errorf("Open(%q) = %T, want *MemoryStore", rawURL, store)
+		}
+		store.Close()
+	}
+
+	store, err := Open("redis://localhost:6379/0")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, ok := store.(*RedisStore); !ok {
+		t.Errorf("Open = %T, want *RedisStore", store)
+	}
+	store.Close()
+
+	if _, err := Open("memcached://localhost"); err == nil {
+		t.Error("Expected an error for an unsupported scheme")
+	}
+}
+
+// fakeRedis implements the commands used by RedisStore in memory
+type fakeRedis struct {
+	mu     sync.Mutex
+	values map[string]string
+	ttls   map[string]time.Duration
+}
+
+func (f *fakeRedis) Get(ctx context.Context, key string) *goredis.StringCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cmd := goredis.NewStringCmd(ctx)
+	if v, ok := f.values[key]; ok {
+		cmd.SetVal(v)
+	} else {
+		cmd.SetErr(goredis.Nil)
+	}
+	return cmd
+}
+
+func (f *fakeRedis) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *goredis.StatusCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values[key] = string(value.([]byte))
+	f.ttls[key] = expiration
+	cmd := goredis.NewStatusCmd(ctx)
+	cmd.SetVal("OK")
+	return cmd
+}
+
+func (f *fakeRedis) Del(ctx context.Context, keys ...string) *goredis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, key := range keys {
+		delete(f.values, key)
+	}
+	cmd := goredis.NewIntCmd(ctx)
+	cmd.SetVal(int64(len(keys)))
+	return cmd
+}
+
+func (f *fakeRedis) Close() error { return nil }
+
+func TestRedisStore(t *testing.T) {
+	fake := &fakeRedis{values: make(map[string]string), ttls: make(map[string]time.Duration)}
+	c := NewValueCache(NewRedisStore(fake))
+
+	if err := c.Set("user", map[string]interface{}{"id": int64(7)}, time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if fake.values["glyph:cache:user"] != `{"id":7}` {
+		t.Errorf("Unexpected stored value %q", fake.values["glyph:cache:user"])
+	}
+	if fake.ttls["glyph:cache:user"] != time.Minute {
+		t.Errorf("Expected a one minute ttl, got %v", fake.ttls["glyph:cache:user"])
+	}
+
+	got, ok, err := c.Get("user")
+	if err != nil || !ok || !reflect.DeepEqual(got, map[string]interface{}{"id": int64(7)}) {
+		t.Errorf("Get = %v, %v, %v", got, ok, err)
+	}
+	if err := c.Delete("user"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok, err := c.Get("user"); ok || err != nil {
+		t.Errorf("Expected a miss after Delete, got %v, %v", ok, err)
+	}
+}
@@ -440,6 +440,11 @@ func (g *PythonGenerator) writeExpr(sb *strings.Builder, expr ir.ExprIR) {
 			if i > 0 {
 				sb.WriteString(", ")
 			}
+			if f.Value.Kind == ir.ExprSpread {
+				sb.WriteString("**")
+				g.writeExpr(sb, f.Value.Spread.Value)
+				continue
+			}
 			fmt.Fprintf(sb, "%q: ", f.Key)
 			g.writeExpr(sb, f.Value)
 		}
@@ -486,6 +491,39 @@ func (g *PythonGenerator) writeExpr(sb *strings.Builder, expr ir.ExprIR) {
 			sb.WriteString("await ")
 			g.writeExpr(sb, expr.Await.Expr)
 		}
+	case ir.ExprTernary:
+		if expr.Ternary != nil {
+			// Python conditional: (then if cond else else)
+			sb.WriteString("(")
+			g.writeExpr(sb, expr.Ternary.Then)
+			sb.WriteString(" if ")
+			g.writeExpr(sb, expr.Ternary.Condition)
+			sb.WriteString(" else ")
+			g.writeExpr(sb, expr.Ternary.Else)
+			sb.WriteString(")")
+		}
+	case ir.ExprInterpolation:
+		if expr.Interp != nil {
+			sb.WriteString("(")
+			for i, part := range expr.Interp.Parts {
+				if i > 0 {
+					sb.WriteString(" + ")
+				}
+				if part.Kind == ir.ExprString {
+					g.writeExpr(sb, part)
+					continue
+				}
+				sb.WriteString("str(")
+				g.writeExpr(sb, part)
+				sb.WriteString(")")
+			}
+			sb.WriteString(")")
+		}
+	case ir.ExprSpread:
+		if expr.Spread != nil {
+			sb.WriteString("*")
+			g.writeExpr(sb, expr.Spread.Value)
+		}
 	}
 }
 
@@ -485,7 +485,9 @@ func (g *TypeScriptServerGenerator) tsWriteExpr(sb *strings.Builder, expr ir.Exp
 			if i > 0 {
 				sb.WriteString(", ")
 			}
-			fmt.Fprintf(sb, "%s: ", f.Key)
+			if f.Value.Kind != ir.ExprSpread {
+				fmt.Fprintf(sb, "%s: ", f.Key)
+			}
 			g.tsWriteExpr(sb, f.Value)
 		}
 		sb.WriteString(" }")
@@ -530,6 +532,36 @@ func (g *TypeScriptServerGenerator) tsWriteExpr(sb *strings.Builder, expr ir.Exp
 			sb.WriteString("await ")
 			g.tsWriteExpr(sb, expr.Await.Expr)
 		}
+	case ir.ExprTernary:
+		if expr.Ternary != nil {
+			sb.WriteString("(")
+			g.tsWriteExpr(sb, expr.Ternary.Condition)
+			sb.WriteString(" ? ")
+			g.tsWriteExpr(sb, expr.Ternary.Then)
+			sb.WriteString(" : ")
+			g.tsWriteExpr(sb, expr.Ternary.Else)
+			sb.WriteString(")")
+		}
+	case ir.ExprInterpolation:
+		if expr.Interp != nil {
+			sb.WriteString("(\"\"")
+			for _, part := range expr.Interp.Parts {
+				sb.WriteString(" + ")
+				if part.Kind == ir.ExprString {
+					g.tsWriteExpr(sb, part)
+					continue
+				}
+				sb.WriteString("String(")
+				g.tsWriteExpr(sb, part)
+				sb.WriteString(")")
+			}
+			sb.WriteString(")")
+		}
+	case ir.ExprSpread:
+		if expr.Spread != nil {
+			sb.WriteString("...")
+			g.tsWriteExpr(sb, expr.Spread.Value)
+		}
 	}
 }
 
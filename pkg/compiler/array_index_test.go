@@ -152,10 +152,23 @@ func TestArrayIndexBoundsError(t *testing.T) {
 	}
 }
 
+func TestArrayIndexNegative(t *testing.T) {
+	result, err := compileAndRun(t, `@ GET /test {
+  $ arr = [1, 2, 3]
+  > arr[-1] + arr[0 - 3]
+}`)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result != (vm.IntValue{Val: 4}) {
+		t.Errorf("Expected 4, got %v", result)
+	}
+}
+
 func TestArrayIndexNegativeError(t *testing.T) {
 	code := `@ GET /test {
   $ arr = [1, 2, 3]
-  $ val = arr[0 - 1]
+  $ val = arr[0 - 4]
   > val
 }`
 
@@ -188,8 +201,8 @@ func TestArrayIndexNegativeError(t *testing.T) {
 	}
 
 	// Check that error mentions bounds
-	if !strings.Contains(err.Error(), "index out of bounds") || !strings.Contains(err.Error(), "-1") {
-		t.Errorf("Expected bounds error with index -1, got: %v", err)
+	if !strings.Contains(err.Error(), "index out of bounds") || !strings.Contains(err.Error(), "-4") {
+		t.Errorf("Expected bounds error with index -4, got: %v", err)
 	}
 }
 
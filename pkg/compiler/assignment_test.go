@@ -0,0 +1,171 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+func TestFieldAndIndexAssignmentOnVM(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want vm.Value
+	}{
+		{
+			name: "object field",
+			code: `@ PUT /test {
+  $ user = {name: "ada"}
+  user.name = "grace"
+  > user.name
+}`,
+			want: vm.StringValue{Val: "grace"},
+		},
+		{
+			name: "new object field",
+			code: `@ PUT /test {
+  $ user = {name: "ada"}
+  user.active = true
+  > user.active
+}`,
+			want: vm.BoolValue{Val: true},
+		},
+		{
+			name: "array element",
+			code: `@ PUT /test {
+  $ items = [1, 2, 3]
+  items[1] = 20
+  items[-1] = 30
+  > items[0] + items[1] + items[2]
+}`,
+			want: vm.IntValue{Val: 51},
+		},
+		{
+			name: "object key",
+			code: `@ PUT /test {
+  $ limits = {free: 10}
+  $ plan = "pro"
+  limits[plan] = 100
+  > limits.pro
+}`,
+			want: vm.IntValue{Val: 100},
+		},
+		{
+			name: "field of an element",
+			code: `@ PUT /test {
+  $ users = [{name: "ada"}, {name: "bob"}]
+  users[1].name = "grace"
+  > users[1].name
+}`,
+			want: vm.StringValue{Val: "grace"},
+		},
+		{
+			name: "element of a nested field",
+			code: `@ PUT /test {
+  $ user = {profile: {tags: ["a", "b"]}}
+  user.profile.tags[0] = "admin"
+  > user.profile.tags[0]
+}`,
+			want: vm.StringValue{Val: "admin"},
+		},
+		{
+			name: "dotted declaration target",
+			code: `@ PUT /test {
+  $ user = {address: {city: "Lisbon"}}
+  $ user.address.city = "Porto"
+  > user.address.city
+}`,
+			want: vm.StringValue{Val: "Porto"},
+		},
+		{
+			name: "references share the mutation",
+			code: `@ PUT /test {
+  $ users = [{name: "ada"}]
+  $ first = users[0]
+  first.name = "grace"
+  > users[0].name
+}`,
+			want: vm.StringValue{Val: "grace"},
+		},
+		{
+			name: "assignment in a loop",
+			code: `@ PUT /test {
+  $ counts = {a: 0, b: 0}
+  for key in ["a", "b", "a"] {
+    counts[key] = counts[key] + 1
+  }
+  > counts.a * 10 + counts.b
+}`,
+			want: vm.IntValue{Val: 21},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := compileAndRun(t, tt.code)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Expected %#v, got %#v", tt.want, result)
+			}
+		})
+	}
+}
+
+// Assignment errors use the interpreter's messages, so a route fails the
+// same way in either execution mode
+func TestFieldAndIndexAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{
+			name: "field of null",
+			code: `@ PUT /test {
+  $ user = {profile: null}
+  user.profile.bio = "hi"
+  > user
+}`,
+			want: "cannot assign field 'bio' on null",
+		},
+		{
+			name: "field of a non-object",
+			code: `@ PUT /test {
+  $ count = 3
+  count.value = 1
+  > count
+}`,
+			want: "cannot assign field 'value' on int",
+		},
+		{
+			name: "index out of range",
+			code: `@ PUT /test {
+  $ items = [1, 2]
+  items[2] = 3
+  > items
+}`,
+			want: "array index out of bounds: 2 (length: 2)",
+		},
+		{
+			name: "index of a non-collection",
+			code: `@ PUT /test {
+  $ name = "ada"
+  name[0] = "A"
+  > name
+}`,
+			want: "cannot index-assign to string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileAndRun(t, tt.code)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package compiler
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/parser"
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+// recordingEmitter records the events passed to emit()
+type recordingEmitter struct {
+	mu     sync.Mutex
+	events []interface{}
+}
+
+func (r *recordingEmitter) Emit(eventType string, data interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, data)
+	return nil
+}
+
+func TestBackgroundCompilation(t *testing.T) {
+	code := `@ POST /signup {
+  $ count = 1
+  background {
+    $ seen = count
+    if seen > 0 {
+      $ sent = emit("first", seen)
+    }
+  }
+  defer! {
+    $ sent = emit("second", count + 10)
+  }
+  count = 5
+  > count
+}`
+	tokens, err := parser.NewLexer(code).Tokenize()
+	if err != nil {
+		t.Fatalf("Tokenize failed: %v", err)
+	}
+	module, err := parser.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	bytecode, err := NewCompiler().Compile(module)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	events := &recordingEmitter{}
+	vmInstance := vm.NewVM()
+	vmInstance.SetEventEmitter(events)
+	result, err := vmInstance.Execute(bytecode)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result != (vm.IntValue{Val: 5}) {
+		t.Errorf("Expected 5, got %#v", result)
+	}
+
+	tasks := vmInstance.BackgroundTasks()
+	if len(tasks) != 2 {
+		t.Fatalf("Expected 2 background tasks, got %d", len(tasks))
+	}
+	if len(events.events) != 0 {
+		t.Fatalf("Background blocks ran during the route: %v", events.events)
+	}
+
+	// Blocks see the variables as they were when reached
+	vmInstance.Reset()
+	for _, task := range tasks {
+		if err := task.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+	want := []interface{}{int64(1), int64(11)}
+	if len(events.events) != len(want) {
+		t.Fatalf("Expected events %v, got %v", want, events.events)
+	}
+	for n := range want {
+		if events.events[n] != want[n] {
+			t.Errorf("Event %d: expected %v, got %v", n, want[n], events.events[n])
+		}
+	}
+}
+
+func TestBackgroundCompilation_Error(t *testing.T) {
+	tokens, _ := parser.NewLexer(`@ GET /test {
+  background {
+    $ r = missingFunction()
+  }
+  > 1
+}`).Tokenize()
+	module, err := parser.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	bytecode, err := NewCompiler().Compile(module)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	vmInstance := vm.NewVM()
+	if _, err := vmInstance.Execute(bytecode); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	tasks := vmInstance.BackgroundTasks()
+	if len(tasks) != 1 {
+		t.Fatalf("Expected 1 background task, got %d", len(tasks))
+	}
+	if err := tasks[0].Run(); err == nil || !strings.Contains(err.Error(), "missingFunction") {
+		t.Errorf("Expected an error naming missingFunction, got %v", err)
+	}
+}
+
+func TestBackgroundCompilation_CopiesObjects(t *testing.T) {
+	module := parseModule(t, `@ POST /counter {
+  $ counter = {n: 3, tags: ["a"]}
+  background {
+    counter.n = 100
+    counter.tags[0] = "b"
+  }
+  > counter
+}`)
+	bytecode, err := NewCompiler().Compile(module)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	vmInstance := vm.NewVM()
+	result, err := vmInstance.Execute(bytecode)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	tasks := vmInstance.BackgroundTasks()
+	if len(tasks) != 1 {
+		t.Fatalf("Expected 1 background task, got %d", len(tasks))
+	}
+	if err := tasks[0].Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// The block changes its own copy, not the response
+	got := vm.ValueToInterface(result)
+	want := map[string]interface{}{"n": int64(3), "tags": []interface{}{"a"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected response %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,293 @@
+package compiler
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/cache"
+)
+
+// CacheVersion is part of every route cache key. Bump it whenever the
+// format of cache entries changes. Changes to the bytecode the compiler
+// emits are covered by compilerBuild.
+const CacheVersion = 1
+
+// compilerBuild identifies the build of glyph doing the compiling and is
+// part of every route cache key, so bytecode cached by one build is never
+// loaded by another whose code generation may differ. A release or a clean
+// checkout is identified by its version and commit, any other build by a
+// hash of its executable. If neither is available, entries are only reused
+// within the process.
+var compilerBuild = sync.OnceValue(func() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		var revision, modified string
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				revision = setting.Value
+			case "vcs.modified":
+				modified = setting.Value
+			}
+		}
+		if revision != "" && modified == "false" {
+			return info.Main.Version + "@" + revision
+		}
+		if revision == "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			return info.Main.Version
+		}
+	}
+	if exe, err := os.Executable(); err == nil {
+		if f, err := os.Open(exe); err == nil {
+			defer f.Close()
+			h := sha256.New()
+			if _, err := io.Copy(h, f); err == nil {
+				return "exe:" + hex.EncodeToString(h.Sum(nil))
+			}
+		}
+	}
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	return "process:" + hex.EncodeToString(nonce)
+})
+
+// RouteCache stores compiled route bytecode on disk, with an in-memory LRU
+// layer in front for processes that recompile the same routes repeatedly,
+// such as the dev server on hot reload. Unreadable or corrupt entries are
+// treated as misses, so the cache never causes a compilation to fail.
+type RouteCache struct {
+	dir    string // Empty for an in-memory cache
+	memory *cache.LRUCache
+
+	memoryHits atomic.Uint64
+	diskHits   atomic.Uint64
+	misses     atomic.Uint64
+}
+
+// RouteCacheStats counts cache lookups
+type RouteCacheStats struct {
+	MemoryHits uint64
+	DiskHits   uint64
+	Misses     uint64
+}
+
+// Hits returns the lookups served from memory or disk
+func (s RouteCacheStats) Hits() uint64 {
+	return s.MemoryHits + s.DiskHits
+}
+
+// NewRouteCache creates a cache that keeps up to capacity routes in memory
+// and persists them under dir. An empty dir keeps the cache in memory only.
+func NewRouteCache(dir string, capacity int) *RouteCache {
+	return &RouteCache{
+		dir:    dir,
+		memory: cache.NewLRUCache(cache.WithCapacity(capacity), cache.WithDefaultTTL(-1)),
+	}
+}
+
+// DefaultCacheDir returns the route cache directory: $GLYPH_CACHE_DIR, or
+// glyph/bytecode under the user's cache directory
+func DefaultCacheDir() (string, error) {
+	if dir := os.Getenv("GLYPH_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "glyph", "bytecode"), nil
+}
+
+// Stats returns the lookup counts since the cache was created
+func (rc *RouteCache) Stats() RouteCacheStats {
+	return RouteCacheStats{
+		MemoryHits: rc.memoryHits.Load(),
+		DiskHits:   rc.diskHits.Load(),
+		Misses:     rc.misses.Load(),
+	}
+}
+
+// Close stops the memory layer's background cleanup
+func (rc *RouteCache) Close() {
+	rc.memory.Close()
+}
+
+// get looks key up in memory, then on disk
+func (rc *RouteCache) get(key string) ([]byte, bool) {
+	if value, ok := rc.memory.Get(key); ok {
+		rc.memoryHits.Add(1)
+		return value.([]byte), true
+	}
+	if rc.dir != "" {
+		if bytecode, ok := rc.readFile(key); ok {
+			rc.memory.Set(key, bytecode, 0)
+			rc.diskHits.Add(1)
+			return bytecode, true
+		}
+	}
+	rc.misses.Add(1)
+	return nil, false
+}
+
+// put stores bytecode in memory and, best effort, on disk
+func (rc *RouteCache) put(key string, bytecode []byte) {
+	rc.memory.Set(key, bytecode, 0)
+	if rc.dir != "" {
+		rc.writeFile(key, bytecode)
+	}
+}
+
+// Cache files hold a SHA-256 checksum of the bytecode followed by the
+// bytecode, so truncated or corrupted files are detected and recompiled
+func (rc *RouteCache) readFile(key string) ([]byte, bool) {
+	data, err := os.ReadFile(filepath.Join(rc.dir, key))
+	if err != nil || len(data) < sha256.Size {
+		return nil, false
+	}
+	sum, bytecode := data[:sha256.Size], data[sha256.Size:]
+	if actual := sha256.Sum256(bytecode); !bytes.Equal(sum, actual[:]) {
+		os.Remove(filepath.Join(rc.dir, key))
+		return nil, false
+	}
+	return bytecode, true
+}
+
+func (rc *RouteCache) writeFile(key string, bytecode []byte) {
+	if err := os.MkdirAll(rc.dir, 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(rc.dir, key+".tmp*")
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(bytecode)
+	_, err = tmp.Write(append(sum[:], bytecode...))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	// Rename so concurrent readers never see a partial file
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(rc.dir, key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// SetCache makes CompileRouteCached use rc; nil disables caching
+func (c *Compiler) SetCache(rc *RouteCache) {
+	c.cache = rc
+}
+
+// CompileRouteCached compiles a route, reusing cached bytecode when the
+// same route was compiled before by this build of the compiler at the same
+// optimization level. sourceHash identifies the route's AST and is normally
+// HashRoute(route); it is computed when empty. Without a cache set by
+// SetCache this is CompileRoute. The returned bytecode may be shared and
+// must not be modified.
+func (c *Compiler) CompileRouteCached(route *ast.Route, sourceHash string) ([]byte, error) {
+	if c.cache == nil {
+		return c.CompileRoute(route)
+	}
+	if sourceHash == "" {
+		sourceHash = HashRoute(route)
+	}
+
+	// The route's bytecode includes the module functions it calls, the
+	// middleware it applies and the constants and enum values it reads
+	keySum := sha256.Sum256([]byte(fmt.Sprintf("route:v%d:%s:opt%d:%s:%s:%s:%s:%s", CacheVersion, compilerBuild(), c.optimizer.level, sourceHash, c.functionsHash, c.middlewaresHash, c.constantsHash, c.enumsHash)))
+	key := hex.EncodeToString(keySum[:])
+	if bytecode, ok := c.cache.get(key); ok {
+		return bytecode, nil
+	}
+
+	bytecode, err := c.CompileRoute(route)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.put(key, bytecode)
+	return bytecode, nil
+}
+
+// HashRoute returns a hash of a route's AST. Source positions are ignored,
+// so moving a route within a file does not change its hash.
+func HashRoute(route *ast.Route) string {
+	sum := sha256.Sum256(appendValue(nil, reflect.ValueOf(route)))
+	return hex.EncodeToString(sum[:])
+}
+
+var posType = reflect.TypeOf(ast.Pos{})
+
+// appendValue appends a description of v to buf that includes concrete type
+// names, so values that print alike but compile differently (such as an
+// int and a float literal) hash differently
+func appendValue(buf []byte, v reflect.Value) []byte {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return append(buf, "nil;"...)
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return append(buf, "nil;"...)
+		}
+		if v.Kind() == reflect.Interface {
+			buf = append(buf, v.Elem().Type().String()...)
+			buf = append(buf, ':')
+		}
+		return appendValue(buf, v.Elem())
+	case reflect.Struct:
+		// The type name fixes the field layout, so field names are omitted
+		buf = append(buf, v.Type().String()...)
+		buf = append(buf, '{')
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Field(i); field.Type() != posType {
+				buf = appendValue(buf, field)
+			}
+		}
+		return append(buf, '}')
+	case reflect.Slice, reflect.Array:
+		buf = append(buf, '[')
+		buf = strconv.AppendInt(buf, int64(v.Len()), 10)
+		buf = append(buf, ':')
+		for i := 0; i < v.Len(); i++ {
+			buf = appendValue(buf, v.Index(i))
+		}
+		return append(buf, ']')
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		buf = append(buf, "map["...)
+		buf = strconv.AppendInt(buf, int64(v.Len()), 10)
+		buf = append(buf, ':')
+		for _, k := range keys {
+			buf = appendValue(buf, k)
+			buf = appendValue(buf, v.MapIndex(k))
+		}
+		return append(buf, ']')
+	case reflect.String:
+		buf = strconv.AppendQuote(buf, v.String())
+	case reflect.Bool:
+		buf = strconv.AppendBool(buf, v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf = strconv.AppendInt(buf, v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		buf = strconv.AppendUint(buf, v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		buf = strconv.AppendFloat(buf, v.Float(), 'b', -1, 64)
+	default:
+		// Functions and channels do not appear in the AST
+		buf = append(buf, v.Kind().String()...)
+	}
+	return append(buf, ';')
+}
@@ -0,0 +1,79 @@
+package compiler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/parser"
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+// mapCache is a vm.Cache kept in a map
+type mapCache struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+func (m *mapCache) Get(key string) (interface{}, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.values[key]
+	return value, ok, nil
+}
+
+func (m *mapCache) Set(key string, value interface{}, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+func (m *mapCache) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+func TestCacheInjectionCompilation(t *testing.T) {
+	tokens, err := parser.NewLexer(`@ GET /visits {
+  % store: Cache
+  $ count = (store.get("visits") ?? 0) + 1
+  $ saved = store.set("visits", count, 60)
+  $ removed = store.delete("other")
+  > count
+}`).Tokenize()
+	if err != nil {
+		t.Fatalf("Tokenize failed: %v", err)
+	}
+	module, err := parser.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	bytecode, err := NewCompiler().Compile(module)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	c := &mapCache{values: map[string]interface{}{"other": "x"}}
+	for want := int64(1); want <= 3; want++ {
+		vmInstance := vm.NewVM()
+		vmInstance.SetCache(c)
+		result, err := vmInstance.Execute(bytecode)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if result != (vm.IntValue{Val: want}) {
+			t.Errorf("Expected %d, got %#v", want, result)
+		}
+	}
+	if _, ok := c.values["other"]; ok {
+		t.Error("Expected store.delete to remove the key")
+	}
+
+	// Without a cache the call fails
+	if _, err := vm.NewVM().Execute(bytecode); err == nil {
+		t.Error("Expected an error when no cache is configured")
+	}
+}
@@ -0,0 +1,266 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/parser"
+)
+
+// parseRoutes parses source and returns its routes
+func parseRoutes(tb testing.TB, source string) []*ast.Route {
+	tb.Helper()
+	tokens, err := parser.NewLexer(source).Tokenize()
+	if err != nil {
+		tb.Fatalf("Lexer error: %v", err)
+	}
+	module, err := parser.NewParser(tokens).Parse()
+	if err != nil {
+		tb.Fatalf("Parser error: %v", err)
+	}
+	var routes []*ast.Route
+	for _, item := range module.Items {
+		if route, ok := item.(*ast.Route); ok {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// routeFixture generates a module with n routes of a few statements each
+func routeFixture(n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, `@ GET /route%d/:id {
+  $ total = %d
+  $ limit = total * 2 + 1
+  if limit > 10 {
+    $ total = total + limit
+  }
+  > {route: %d, id: id, total: total}
+}
+`, i, i, i)
+	}
+	return sb.String()
+}
+
+func TestCompileRouteCached(t *testing.T) {
+	route := parseRoutes(t, routeFixture(1))[0]
+	want, err := NewCompiler().CompileRoute(route)
+	if err != nil {
+		t.Fatalf("CompileRoute() error: %v", err)
+	}
+
+	dir := t.TempDir()
+	rc := NewRouteCache(dir, 16)
+	defer rc.Close()
+	c := NewCompiler()
+	c.SetCache(rc)
+
+	for i := 0; i < 2; i++ {
+		got, err := c.CompileRouteCached(route, "")
+		if err != nil {
+			t.Fatalf("CompileRouteCached() error: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Compile %d: cached bytecode differs from CompileRoute", i+1)
+		}
+	}
+	if stats := rc.Stats(); stats != (RouteCacheStats{MemoryHits: 1, Misses: 1}) {
+		t.Errorf("Expected 1 memory hit and 1 miss, got %+v", stats)
+	}
+
+	// A fresh cache over the same directory is served from disk
+	disk := NewRouteCache(dir, 16)
+	defer disk.Close()
+	c.SetCache(disk)
+	got, err := c.CompileRouteCached(route, "")
+	if err != nil {
+		t.Fatalf("CompileRouteCached() error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("Bytecode read from disk differs from CompileRoute")
+	}
+	if stats := disk.Stats(); stats != (RouteCacheStats{DiskHits: 1}) {
+		t.Errorf("Expected 1 disk hit, got %+v", stats)
+	}
+}
+
+func TestCompileRouteCached_CorruptEntry(t *testing.T) {
+	route := parseRoutes(t, routeFixture(1))[0]
+	dir := t.TempDir()
+	c := NewCompiler()
+	rc := NewRouteCache(dir, 16)
+	defer rc.Close()
+	c.SetCache(rc)
+	want, err := c.CompileRouteCached(route, "")
+	if err != nil {
+		t.Fatalf("CompileRouteCached() error: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one cache file, got %d (%v)", len(entries), err)
+	}
+	path := filepath.Join(dir, entries[0].Name())
+	data, _ := os.ReadFile(path)
+	data[len(data)-1] ^= 0xFF
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fresh := NewRouteCache(dir, 16)
+	defer fresh.Close()
+	c.SetCache(fresh)
+	got, err := c.CompileRouteCached(route, "")
+	if err != nil {
+		t.Fatalf("CompileRouteCached() error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("Expected the corrupt entry to be recompiled")
+	}
+	if stats := fresh.Stats(); stats.Misses != 1 || stats.Hits() != 0 {
+		t.Errorf("Expected a miss for the corrupt entry, got %+v", stats)
+	}
+
+	// The recompiled bytecode replaced the corrupt file
+	repaired := NewRouteCache(dir, 16)
+	defer repaired.Close()
+	c.SetCache(repaired)
+	if _, err := c.CompileRouteCached(route, ""); err != nil {
+		t.Fatalf("CompileRouteCached() error: %v", err)
+	}
+	if repaired.Stats().DiskHits != 1 {
+		t.Errorf("Expected the entry to be rewritten, got %+v", repaired.Stats())
+	}
+}
+
+func TestCompileRouteCached_KeyIncludesOptLevel(t *testing.T) {
+	route := parseRoutes(t, routeFixture(1))[0]
+	rc := NewRouteCache("", 16)
+	defer rc.Close()
+
+	for _, level := range []OptimizationLevel{OptNone, OptBasic, OptAggressive} {
+		c := NewCompilerWithOptLevel(level)
+		c.SetCache(rc)
+		if _, err := c.CompileRouteCached(route, ""); err != nil {
+			t.Fatalf("CompileRouteCached() error: %v", err)
+		}
+	}
+	if stats := rc.Stats(); stats.Misses != 3 || stats.Hits() != 0 {
+		t.Errorf("Expected a miss per optimization level, got %+v", stats)
+	}
+}
+
+func TestCompileRouteCached_KeyIncludesBuild(t *testing.T) {
+	route := parseRoutes(t, routeFixture(1))[0]
+	dir := t.TempDir()
+	c := NewCompiler()
+	rc := NewRouteCache(dir, 16)
+	defer rc.Close()
+	c.SetCache(rc)
+	if _, err := c.CompileRouteCached(route, ""); err != nil {
+		t.Fatalf("CompileRouteCached() error: %v", err)
+	}
+	if compilerBuild() == "" {
+		t.Error("Expected the build of the compiler to be identified")
+	}
+
+	// Another build of glyph does not load what this one cached
+	build := compilerBuild
+	defer func() { compilerBuild = build }()
+	compilerBuild = func() string { return "v9.9.9@other" }
+	other := NewRouteCache(dir, 16)
+	defer other.Close()
+	c.SetCache(other)
+	if _, err := c.CompileRouteCached(route, ""); err != nil {
+		t.Fatalf("CompileRouteCached() error: %v", err)
+	}
+	if stats := other.Stats(); stats.Misses != 1 || stats.Hits() != 0 {
+		t.Errorf("Expected a miss for another build, got %+v", stats)
+	}
+}
+
+func TestHashRoute(t *testing.T) {
+	hash := func(source string) string {
+		return HashRoute(parseRoutes(t, source)[0])
+	}
+
+	base := hash("@ GET /a {\n  > {n: 1}\n}\n")
+	if moved := hash("\n\n@ GET /a {\n    > {n: 1}\n}\n"); moved != base {
+		t.Error("Expected source positions not to affect the hash")
+	}
+	if float := hash("@ GET /a {\n  > {n: 1.0}\n}\n"); float == base {
+		t.Error("Expected int and float literals to hash differently")
+	}
+	if other := hash("@ GET /b {\n  > {n: 1}\n}\n"); other == base {
+		t.Error("Expected different paths to hash differently")
+	}
+}
+
+func BenchmarkCompileRoutes(b *testing.B) {
+	routes := parseRoutes(b, routeFixture(50))
+	hashes := make([]string, len(routes))
+	for i, route := range routes {
+		hashes[i] = HashRoute(route)
+	}
+
+	compileAll := func(b *testing.B, c *Compiler) {
+		for i, route := range routes {
+			if _, err := c.CompileRouteCached(route, hashes[i]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.Run("uncached", func(b *testing.B) {
+		c := NewCompiler()
+		for i := 0; i < b.N; i++ {
+			compileAll(b, c)
+		}
+	})
+
+	b.Run("memory", func(b *testing.B) {
+		rc := NewRouteCache("", 64)
+		defer rc.Close()
+		c := NewCompiler()
+		c.SetCache(rc)
+		compileAll(b, c)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			compileAll(b, c)
+		}
+	})
+
+	b.Run("disk", func(b *testing.B) {
+		dir := b.TempDir()
+		warm := NewRouteCache(dir, 64)
+		c := NewCompiler()
+		c.SetCache(warm)
+		compileAll(b, c)
+		warm.Close()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			// A cold memory layer each time, as in a fresh process
+			b.StopTimer()
+			rc := NewRouteCache(dir, 64)
+			c.SetCache(rc)
+			b.StartTimer()
+			compileAll(b, c)
+			rc.Close()
+		}
+	})
+
+	b.Run("hash", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, route := range routes {
+				HashRoute(route)
+			}
+		}
+	})
+}
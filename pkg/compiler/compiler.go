@@ -42,6 +42,24 @@ type Compiler struct {
 	optimizer     *Optimizer
 	macroExpander *MacroExpander
 	loopStack     []loopContext
+	cache         *RouteCache              // Used by CompileRouteCached; nil disables caching
+	builtinNames  map[string]string        // Injections of type Cache, Redis or Mailer, mapped to the builtin namespace of their methods
+	functions     map[string]*ast.Function // Module functions, set by SetFunctions
+	functionsHash string                   // Hash of functions, part of the route cache key
+	calls         *functionCalls           // Functions called by the code being compiled; nil in async and background bodies
+	function      *ast.Function            // Function whose body is being compiled, where return is OpRet; nil elsewhere
+
+	// Named middleware, set by SetMiddlewares, and their hash, part of the
+	// route cache key
+	middlewares     map[string]*ast.MiddlewareDef
+	middlewaresHash string
+
+	// Module constants and enums, set by SetConstants and SetEnums, and
+	// their hashes, part of the route cache key
+	moduleConsts  map[string]moduleConstant
+	constantsHash string
+	enums         map[string]*ast.EnumDef
+	enumsHash     string
 }
 
 // NewCompiler creates a new compiler instance
@@ -75,7 +93,44 @@ func (c *Compiler) Reset() {
 	c.symbolTable = NewGlobalSymbolTable()
 	c.labelCounter = 0
 	c.loopStack = nil
-	// Keep the optimizer with its current settings
+	c.builtinNames = nil
+	c.calls = newFunctionCalls()
+	c.function = nil
+	// Keep the optimizer, functions, middlewares, constants and enums with
+	// their current settings
+}
+
+// defineInjections adds injected dependencies to the symbol table
+func (c *Compiler) defineInjections(injections []ast.Injection) {
+	for _, injection := range injections {
+		nameIdx := c.addConstant(vm.StringValue{Val: injection.Name})
+		c.symbolTable.Define(injection.Name, nameIdx)
+		if namespace := builtinNamespace(injection.Type); namespace != "" {
+			if c.builtinNames == nil {
+				c.builtinNames = make(map[string]string)
+			}
+			c.builtinNames[injection.Name] = namespace
+		}
+	}
+}
+
+// builtinNamespace returns the namespace of the builtins that implement an
+// injected type's methods, or "" if its methods are not builtins
+func builtinNamespace(typ ast.Type) string {
+	switch t := typ.(type) {
+	case ast.RedisType:
+		return "redis"
+	case ast.NamedType:
+		switch t.Name {
+		case "Cache":
+			return "cache"
+		case "Redis":
+			return "redis"
+		case "Mailer":
+			return "mail"
+		}
+	}
+	return ""
 }
 
 // Compile compiles an AST module to bytecode
@@ -86,6 +141,19 @@ func (c *Compiler) Compile(module *ast.Module) ([]byte, error) {
 		return nil, fmt.Errorf("macro expansion failed: %w", err)
 	}
 
+	if c.functions == nil {
+		c.SetFunctions(ModuleFunctions(expandedModule))
+	}
+	if c.middlewares == nil {
+		c.SetMiddlewares(ModuleMiddlewares(expandedModule))
+	}
+	if c.moduleConsts == nil {
+		c.SetConstants(ModuleConstants(expandedModule))
+	}
+	if c.enums == nil {
+		c.SetEnums(ModuleEnums(expandedModule))
+	}
+
 	// For now, compile the first route we find
 	for _, item := range expandedModule.Items {
 		if route, ok := item.(*ast.Route); ok {
@@ -121,6 +189,12 @@ func (c *Compiler) Compile(module *ast.Module) ([]byte, error) {
 
 // CompileRoute compiles a route to bytecode
 func (c *Compiler) CompileRoute(route *ast.Route) ([]byte, error) {
+	return c.compileRoute(route)
+}
+
+// compileRoute compiles a route to bytecode. builtins names variables the
+// runtime injects in addition to the usual request variables.
+func (c *Compiler) compileRoute(route *ast.Route, builtins ...string) ([]byte, error) {
 	// Reset compiler state for each route
 	c.Reset()
 
@@ -140,10 +214,7 @@ func (c *Compiler) CompileRoute(route *ast.Route) ([]byte, error) {
 	}
 
 	// Add injections to symbol table
-	for _, injection := range route.Injections {
-		nameIdx := c.addConstant(vm.StringValue{Val: injection.Name})
-		c.symbolTable.Define(injection.Name, nameIdx)
-	}
+	c.defineInjections(route.Injections)
 
 	// Add built-in request variables that are auto-injected at runtime
 	// These use DefineBuiltin so user code can shadow them with typed parameters
@@ -155,6 +226,10 @@ func (c *Compiler) CompileRoute(route *ast.Route) ([]byte, error) {
 	headersIdx := c.addConstant(vm.StringValue{Val: "headers"})
 	c.symbolTable.DefineBuiltin("headers", headersIdx)
 
+	// request - Request metadata such as request.id (always available)
+	requestIdx := c.addConstant(vm.StringValue{Val: "request"})
+	c.symbolTable.DefineBuiltin("request", requestIdx)
+
 	// input - Request body (always available, may be nil)
 	inputIdx := c.addConstant(vm.StringValue{Val: "input"})
 	c.symbolTable.DefineBuiltin("input", inputIdx)
@@ -168,9 +243,16 @@ func (c *Compiler) CompileRoute(route *ast.Route) ([]byte, error) {
 		authIdx := c.addConstant(vm.StringValue{Val: "auth"})
 		c.symbolTable.DefineBuiltin("auth", authIdx)
 	}
+	for _, name := range builtins {
+		c.symbolTable.DefineBuiltin(name, c.addConstant(vm.StringValue{Val: name}))
+	}
 
-	// Optimize route body before compilation
-	optimizedBody := c.optimizer.OptimizeStatements(route.Body)
+	// Optimize route body, with its middleware inlined, before compilation
+	body, err := c.routeBody(route)
+	if err != nil {
+		return nil, err
+	}
+	optimizedBody := c.optimizeBody(body)
 
 	// Compile optimized route body
 	for _, stmt := range optimizedBody {
@@ -202,7 +284,7 @@ func (c *Compiler) CompileCommand(cmd *ast.Command) ([]byte, error) {
 	}
 
 	// Optimize and compile body
-	optimizedBody := c.optimizer.OptimizeStatements(cmd.Body)
+	optimizedBody := c.optimizeBody(cmd.Body)
 	for _, stmt := range optimizedBody {
 		if err := c.compileStatement(stmt); err != nil {
 			return nil, err
@@ -224,13 +306,10 @@ func (c *Compiler) CompileCronTask(task *ast.CronTask) ([]byte, error) {
 	c.symbolTable = c.symbolTable.EnterScope(RouteScope)
 
 	// Add injections to symbol table
-	for _, injection := range task.Injections {
-		nameIdx := c.addConstant(vm.StringValue{Val: injection.Name})
-		c.symbolTable.Define(injection.Name, nameIdx)
-	}
+	c.defineInjections(task.Injections)
 
 	// Optimize and compile body
-	optimizedBody := c.optimizer.OptimizeStatements(task.Body)
+	optimizedBody := c.optimizeBody(task.Body)
 	for _, stmt := range optimizedBody {
 		if err := c.compileStatement(stmt); err != nil {
 			return nil, err
@@ -258,13 +337,10 @@ func (c *Compiler) CompileEventHandler(handler *ast.EventHandler) ([]byte, error
 	c.symbolTable.Define("input", inputIdx)
 
 	// Add injections to symbol table
-	for _, injection := range handler.Injections {
-		nameIdx := c.addConstant(vm.StringValue{Val: injection.Name})
-		c.symbolTable.Define(injection.Name, nameIdx)
-	}
+	c.defineInjections(handler.Injections)
 
 	// Optimize and compile body
-	optimizedBody := c.optimizer.OptimizeStatements(handler.Body)
+	optimizedBody := c.optimizeBody(handler.Body)
 	for _, stmt := range optimizedBody {
 		if err := c.compileStatement(stmt); err != nil {
 			return nil, err
@@ -292,13 +368,10 @@ func (c *Compiler) CompileQueueWorker(worker *ast.QueueWorker) ([]byte, error) {
 	c.symbolTable.Define("input", inputIdx)
 
 	// Add injections to symbol table
-	for _, injection := range worker.Injections {
-		nameIdx := c.addConstant(vm.StringValue{Val: injection.Name})
-		c.symbolTable.Define(injection.Name, nameIdx)
-	}
+	c.defineInjections(worker.Injections)
 
 	// Optimize and compile body
-	optimizedBody := c.optimizer.OptimizeStatements(worker.Body)
+	optimizedBody := c.optimizeBody(worker.Body)
 	for _, stmt := range optimizedBody {
 		if err := c.compileStatement(stmt); err != nil {
 			return nil, err
@@ -312,6 +385,16 @@ func (c *Compiler) CompileQueueWorker(worker *ast.QueueWorker) ([]byte, error) {
 	return c.buildBytecode()
 }
 
+// optimizeBody runs the optimization passes for the compiler's level over a
+// body before it is compiled
+func (c *Compiler) optimizeBody(body []ast.Statement) []ast.Statement {
+	body = c.optimizer.OptimizeStatements(body)
+	if c.optimizer.level >= OptAggressive {
+		body = eliminateDeadCode(body)
+	}
+	return body
+}
+
 // normalizeStatement converts pointer-typed statements to their value form.
 // The parser produces value types, but other call sites (JIT, LSP, tests)
 // construct pointer types. This normalizer lets compileStatement use a single
@@ -340,6 +423,10 @@ func normalizeStatement(stmt ast.Statement) ast.Statement {
 		return *s
 	case *ast.ContinueStatement:
 		return *s
+	case *ast.BackgroundStatement:
+		return *s
+	case *ast.IndexAssignStatement:
+		return *s
 	default:
 		return stmt
 	}
@@ -367,12 +454,18 @@ func (c *Compiler) compileStatement(stmt ast.Statement) error {
 		return c.compileExpressionStatement(&s)
 	case ast.ReassignStatement:
 		return c.compileReassignStatement(&s)
+	case ast.IndexAssignStatement:
+		return c.compileIndexAssignStatement(&s)
 	case ast.BreakStatement:
 		_ = s
 		return c.compileBreakStatement()
 	case ast.ContinueStatement:
 		_ = s
 		return c.compileContinueStatement()
+	case ast.BackgroundStatement:
+		return c.compileBackgroundStatement(&s)
+	case ast.YieldStatement:
+		return c.compileYieldStatement(&s)
 	default:
 		return fmt.Errorf("unsupported statement type: %T", stmt)
 	}
@@ -380,6 +473,11 @@ func (c *Compiler) compileStatement(stmt ast.Statement) error {
 
 // compileAssignStatement compiles variable assignment
 func (c *Compiler) compileAssignStatement(stmt *ast.AssignStatement) error {
+	// $ obj.field = value assigns a field of an existing object
+	if strings.Contains(stmt.Target, ".") {
+		return c.compileIndexAssignStatement(&ast.IndexAssignStatement{Target: fieldPathTarget(stmt.Target), Value: stmt.Value})
+	}
+
 	// Check for redeclaration in current scope (issue #70)
 	// Variables declared with $ cannot be redeclared in the same scope
 	// Built-in variables (query, input, ws, auth) can be shadowed by user declarations
@@ -395,11 +493,23 @@ func (c *Compiler) compileAssignStatement(stmt *ast.AssignStatement) error {
 		}
 		return &SemanticError{Message: fmt.Sprintf("cannot redeclare variable '%s' in the same scope", stmt.Target)}
 	}
+	parent, existsInParent := c.symbolTable.Resolve(stmt.Target)
+	if existsInParent && parent.ReadOnly && !stmt.Const {
+		return &SemanticError{Message: fmt.Sprintf("cannot reassign constant '%s'", stmt.Target)}
+	}
+	if _, isConstant := c.moduleConsts[stmt.Target]; isConstant && !existsInParent && !stmt.Const {
+		return &SemanticError{Message: fmt.Sprintf("cannot reassign constant '%s'", stmt.Target)}
+	}
 
 	// Compile the value expression
 	if err := c.compileExpression(stmt.Value); err != nil {
 		return err
 	}
+	if stmt.Const && stmt.Type != nil {
+		if err := c.emitTypeCheck(stmt.Type, fmt.Sprintf("constant %s type mismatch", stmt.Target), 0); err != nil {
+			return err
+		}
+	}
 
 	// Add variable name to constants
 	nameIdx := c.addConstant(vm.StringValue{Val: stmt.Target})
@@ -408,8 +518,11 @@ func (c *Compiler) compileAssignStatement(stmt *ast.AssignStatement) error {
 	c.emitWithOperand(vm.OpStoreVar, uint32(nameIdx))
 
 	// Only define a new symbol if it doesn't exist in any parent scope
-	// If it exists in a parent scope, this is an assignment to that variable
-	if _, existsInParent := c.symbolTable.Resolve(stmt.Target); !existsInParent {
+	// If it exists in a parent scope, this is an assignment to that variable.
+	// A const always declares a new symbol.
+	if stmt.Const {
+		c.symbolTable.Define(stmt.Target, nameIdx).ReadOnly = true
+	} else if !existsInParent {
 		c.symbolTable.Define(stmt.Target, nameIdx)
 	}
 
@@ -418,10 +531,21 @@ func (c *Compiler) compileAssignStatement(stmt *ast.AssignStatement) error {
 
 // compileReassignStatement compiles variable reassignment (without $ prefix)
 func (c *Compiler) compileReassignStatement(stmt *ast.ReassignStatement) error {
+	if strings.Contains(stmt.Target, ".") {
+		return c.compileIndexAssignStatement(&ast.IndexAssignStatement{Target: fieldPathTarget(stmt.Target), Value: stmt.Value})
+	}
+
 	// Check that the variable exists (must be previously declared)
-	if _, exists := c.symbolTable.Resolve(stmt.Target); !exists {
+	symbol, exists := c.symbolTable.Resolve(stmt.Target)
+	if _, isConstant := c.moduleConsts[stmt.Target]; isConstant && !exists {
+		return &SemanticError{Message: fmt.Sprintf("cannot reassign constant '%s'", stmt.Target)}
+	}
+	if !exists {
 		return &SemanticError{Message: fmt.Sprintf("cannot assign to undeclared variable '%s'", stmt.Target)}
 	}
+	if symbol.ReadOnly {
+		return &SemanticError{Message: fmt.Sprintf("cannot reassign constant '%s'", stmt.Target)}
+	}
 
 	// Compile the value expression
 	if err := c.compileExpression(stmt.Value); err != nil {
@@ -437,6 +561,64 @@ func (c *Compiler) compileReassignStatement(stmt *ast.ReassignStatement) error {
 	return nil
 }
 
+// compileIndexAssignStatement compiles assignment to a field or an index
+// (user.name = value, items[0] = value). The value is compiled first, then
+// the container and the key, and SET_FIELD or SET_INDEX stores it in place.
+func (c *Compiler) compileIndexAssignStatement(stmt *ast.IndexAssignStatement) error {
+	if err := c.compileExpression(stmt.Value); err != nil {
+		return err
+	}
+
+	switch target := stmt.Target.(type) {
+	case ast.FieldAccessExpr:
+		return c.compileFieldStore(&target)
+	case *ast.FieldAccessExpr:
+		return c.compileFieldStore(target)
+	case ast.ArrayIndexExpr:
+		return c.compileIndexStore(&target)
+	case *ast.ArrayIndexExpr:
+		return c.compileIndexStore(target)
+	default:
+		return fmt.Errorf("invalid assignment target: %T", stmt.Target)
+	}
+}
+
+// compileFieldStore compiles the object and field name of a field
+// assignment and emits the store
+func (c *Compiler) compileFieldStore(target *ast.FieldAccessExpr) error {
+	if err := c.compileExpression(target.Object); err != nil {
+		return err
+	}
+	fieldIdx := c.addConstant(vm.StringValue{Val: target.Field})
+	c.emitWithOperand(vm.OpPush, uint32(fieldIdx))
+	c.emit(vm.OpSetField)
+	return nil
+}
+
+// compileIndexStore compiles the container and index of an index
+// assignment and emits the store
+func (c *Compiler) compileIndexStore(target *ast.ArrayIndexExpr) error {
+	if err := c.compileExpression(target.Array); err != nil {
+		return err
+	}
+	if err := c.compileExpression(target.Index); err != nil {
+		return err
+	}
+	c.emit(vm.OpSetIndex)
+	return nil
+}
+
+// fieldPathTarget converts a dotted assignment target such as "user.address.city"
+// into the field access expression it names
+func fieldPathTarget(path string) ast.Expr {
+	parts := strings.Split(path, ".")
+	var target ast.Expr = ast.VariableExpr{Name: parts[0]}
+	for _, field := range parts[1:] {
+		target = ast.FieldAccessExpr{Object: target, Field: field}
+	}
+	return target
+}
+
 // compileReturnStatement compiles return statement
 func (c *Compiler) compileReturnStatement(stmt *ast.ReturnStatement) error {
 	// Compile return value
@@ -444,9 +626,11 @@ func (c *Compiler) compileReturnStatement(stmt *ast.ReturnStatement) error {
 		return err
 	}
 
-	// Emit return instruction
+	// Emit return instruction; a function returns to its caller
+	if c.function != nil {
+		return c.emitFunctionReturn()
+	}
 	c.emit(vm.OpReturn)
-
 	return nil
 }
 
@@ -773,6 +957,14 @@ func (c *Compiler) compileExpression(expr ast.Expr) error {
 		return c.compileUnaryOp(e)
 	case ast.UnaryOpExpr:
 		return c.compileUnaryOp(&e)
+	case *ast.TernaryExpr:
+		return c.compileTernary(e)
+	case ast.TernaryExpr:
+		return c.compileTernary(&e)
+	case *ast.InterpolatedStringExpr:
+		return c.compileInterpolatedString(e)
+	case ast.InterpolatedStringExpr:
+		return c.compileInterpolatedString(&e)
 	case *ast.MatchExpr:
 		return c.compileMatchExpr(e)
 	case ast.MatchExpr:
@@ -821,6 +1013,9 @@ func (c *Compiler) compileVariable(expr *ast.VariableExpr) error {
 	// Look up symbol
 	symbol, ok := c.symbolTable.Resolve(expr.Name)
 	if !ok {
+		if constant, isConstant := c.moduleConsts[expr.Name]; isConstant {
+			return c.compileConstant(expr.Name, constant)
+		}
 		return fmt.Errorf("undefined variable: %s", expr.Name)
 	}
 
@@ -832,6 +1027,13 @@ func (c *Compiler) compileVariable(expr *ast.VariableExpr) error {
 
 // compileBinaryOp compiles binary operation
 func (c *Compiler) compileBinaryOp(expr *ast.BinaryOpExpr) error {
+	if expr.Op == ast.Coalesce {
+		return c.compileCoalesce(expr)
+	}
+	if folded, err := c.compileFolded(expr); folded || err != nil {
+		return err
+	}
+
 	// Compile left operand
 	if err := c.compileExpression(expr.Left); err != nil {
 		return err
@@ -877,8 +1079,100 @@ func (c *Compiler) compileBinaryOp(expr *ast.BinaryOpExpr) error {
 	return nil
 }
 
+// compileCoalesce compiles left ?? right. The right operand is only
+// evaluated when the left one is null.
+func (c *Compiler) compileCoalesce(expr *ast.BinaryOpExpr) error {
+	// Store the left value in a temporary variable
+	leftVarName := fmt.Sprintf("__coalesce_%d", c.labelCounter)
+	c.labelCounter++
+	leftVarIdx := c.addConstant(vm.StringValue{Val: leftVarName})
+	c.symbolTable.Define(leftVarName, leftVarIdx)
+
+	if err := c.compileExpression(expr.Left); err != nil {
+		return err
+	}
+	c.emitWithOperand(vm.OpStoreVar, uint32(leftVarIdx))
+
+	// left != null
+	c.emitWithOperand(vm.OpLoadVar, uint32(leftVarIdx))
+	nullIdx := c.addConstant(vm.NullValue{})
+	c.emitWithOperand(vm.OpPush, uint32(nullIdx))
+	c.emit(vm.OpNe)
+
+	jumpToRight := len(c.code)
+	c.emitWithOperand(vm.OpJumpIfFalse, 0)
+
+	// Not null: the result is the left value
+	c.emitWithOperand(vm.OpLoadVar, uint32(leftVarIdx))
+	jumpToEnd := len(c.code)
+	c.emitWithOperand(vm.OpJump, 0)
+
+	// Null: the result is the right value
+	c.patchJump(jumpToRight, uint32(len(c.code)))
+	if err := c.compileExpression(expr.Right); err != nil {
+		return err
+	}
+	c.patchJump(jumpToEnd, uint32(len(c.code)))
+
+	return nil
+}
+
+// compileTernary compiles cond ? then : else. OpJumpIfFalse rejects a
+// condition that is not a boolean, as in a while loop.
+func (c *Compiler) compileTernary(expr *ast.TernaryExpr) error {
+	if err := c.compileExpression(expr.Condition); err != nil {
+		return err
+	}
+	jumpToElse := len(c.code)
+	c.emitWithOperand(vm.OpJumpIfFalse, 0)
+
+	if err := c.compileExpression(expr.Then); err != nil {
+		return err
+	}
+	jumpToEnd := len(c.code)
+	c.emitWithOperand(vm.OpJump, 0)
+
+	c.patchJump(jumpToElse, uint32(len(c.code)))
+	if err := c.compileExpression(expr.Else); err != nil {
+		return err
+	}
+	c.patchJump(jumpToEnd, uint32(len(c.code)))
+
+	return nil
+}
+
+// compileFolded pushes the value of a literal-only expression computed at
+// compile time, which is only done at OptAggressive. It reports whether the
+// expression was folded.
+func (c *Compiler) compileFolded(expr ast.Expr) (bool, error) {
+	if c.optimizer.level < OptAggressive {
+		return false, nil
+	}
+	lit, ok, err := foldConstant(expr)
+	if !ok || err != nil {
+		return false, err
+	}
+	return true, c.compileLiteralValue(lit)
+}
+
+// compileInterpolatedString compiles "text{expr}..." as join([parts], ""),
+// which converts each part to a string
+func (c *Compiler) compileInterpolatedString(expr *ast.InterpolatedStringExpr) error {
+	return c.compileFunctionCall(&ast.FunctionCallExpr{
+		Name: "join",
+		Args: []ast.Expr{
+			ast.ArrayExpr{Elements: expr.Parts},
+			ast.LiteralExpr{Value: ast.StringLiteral{Value: ""}},
+		},
+	})
+}
+
 // compileUnaryOp compiles unary operation
 func (c *Compiler) compileUnaryOp(expr *ast.UnaryOpExpr) error {
+	if folded, err := c.compileFolded(expr); folded || err != nil {
+		return err
+	}
+
 	// Compile the operand
 	if err := c.compileExpression(expr.Right); err != nil {
 		return err
@@ -899,8 +1193,27 @@ func (c *Compiler) compileUnaryOp(expr *ast.UnaryOpExpr) error {
 
 // compileObject compiles object literal
 func (c *Compiler) compileObject(expr *ast.ObjectExpr) error {
-	// Compile each field (key-value pairs)
+	// Fields before the first spread, and between spreads, are built as
+	// objects and merged in order, so later fields override earlier ones
+	count := 0
+	spreads := 0
 	for _, field := range expr.Fields {
+		if spread, ok := field.Value.(ast.SpreadExpr); ok {
+			if spreads == 0 {
+				c.emitWithOperand(vm.OpBuildObject, uint32(count))
+			} else if count > 0 {
+				c.emitWithOperand(vm.OpBuildObject, uint32(count))
+				c.emit(vm.OpSpread)
+			}
+			if err := c.compileExpression(spread.Value); err != nil {
+				return err
+			}
+			c.emit(vm.OpSpread)
+			count = 0
+			spreads++
+			continue
+		}
+
 		// Push key (field name)
 		keyIdx := c.addConstant(vm.StringValue{Val: field.Key})
 		c.emitWithOperand(vm.OpPush, uint32(keyIdx))
@@ -909,25 +1222,56 @@ func (c *Compiler) compileObject(expr *ast.ObjectExpr) error {
 		if err := c.compileExpression(field.Value); err != nil {
 			return err
 		}
+		count++
 	}
 
 	// Emit build object instruction
-	c.emitWithOperand(vm.OpBuildObject, uint32(len(expr.Fields)))
+	if spreads == 0 || count > 0 {
+		c.emitWithOperand(vm.OpBuildObject, uint32(count))
+		if spreads > 0 {
+			c.emit(vm.OpSpread)
+		}
+	}
 
 	return nil
 }
 
 // compileArray compiles array literal
 func (c *Compiler) compileArray(expr *ast.ArrayExpr) error {
-	// Compile each element
+	// Elements before the first spread, and between spreads, are built as
+	// arrays and joined in order
+	count := 0
+	spreads := 0
 	for _, elem := range expr.Elements {
+		if spread, ok := elem.(ast.SpreadExpr); ok {
+			if spreads == 0 {
+				c.emitWithOperand(vm.OpBuildArray, uint32(count))
+			} else if count > 0 {
+				c.emitWithOperand(vm.OpBuildArray, uint32(count))
+				c.emit(vm.OpSpread)
+			}
+			if err := c.compileExpression(spread.Value); err != nil {
+				return err
+			}
+			c.emit(vm.OpSpread)
+			count = 0
+			spreads++
+			continue
+		}
+
 		if err := c.compileExpression(elem); err != nil {
 			return err
 		}
+		count++
 	}
 
 	// Emit build array instruction
-	c.emitWithOperand(vm.OpBuildArray, uint32(len(expr.Elements)))
+	if spreads == 0 || count > 0 {
+		c.emitWithOperand(vm.OpBuildArray, uint32(count))
+		if spreads > 0 {
+			c.emit(vm.OpSpread)
+		}
+	}
 
 	return nil
 }
@@ -943,8 +1287,12 @@ func (c *Compiler) compileFieldAccess(expr *ast.FieldAccessExpr) error {
 	fieldIdx := c.addConstant(vm.StringValue{Val: expr.Field})
 	c.emitWithOperand(vm.OpPush, uint32(fieldIdx))
 
-	// Emit get field instruction
-	c.emit(vm.OpGetField)
+	// Emit get field instruction; optional access gives null on null
+	if expr.Optional {
+		c.emit(vm.OpGetFieldOpt)
+	} else {
+		c.emit(vm.OpGetField)
+	}
 
 	return nil
 }
@@ -982,8 +1330,34 @@ func (c *Compiler) compileFunctionCall(expr *ast.FunctionCallExpr) error {
 		}
 	}
 
+	if fn, ok := c.functions[expr.Name]; ok {
+		return c.compileUserFunctionCall(fn, expr)
+	}
+	if len(expr.NamedArgs) > 0 {
+		return fmt.Errorf("%s does not take named arguments", expr.Name)
+	}
+
+	// Enum methods, unless a variable shadows the enum's name
+	if object, method, ok := strings.Cut(expr.Name, "."); ok && c.enums[object] != nil {
+		if _, shadowed := c.symbolTable.Resolve(object); !shadowed {
+			return c.compileEnumMethod(c.enums[object], method, expr)
+		}
+	}
+
+	// Methods of an injected cache, Redis or mailer are the cache.*,
+	// redis.* or mail.* builtins, whatever the injection is called. Redis method names are
+	// case-insensitive, as in the interpreter.
+	name := expr.Name
+	if object, method, ok := strings.Cut(name, "."); ok && c.builtinNames[object] != "" {
+		namespace := c.builtinNames[object]
+		if namespace == "redis" {
+			method = strings.ToLower(method)
+		}
+		name = namespace + "." + method
+	}
+
 	// Push function name first (it will be at bottom of stack)
-	fnNameIdx := c.addConstant(vm.StringValue{Val: expr.Name})
+	fnNameIdx := c.addConstant(vm.StringValue{Val: name})
 	c.emitWithOperand(vm.OpPush, uint32(fnNameIdx))
 
 	// Compile arguments in order (they will be on top of function name)
@@ -1091,6 +1465,11 @@ func (c *Compiler) patchJump(offset int, target uint32) {
 // buildBytecode constructs the final bytecode with header.
 // Returns an error if any constant cannot be serialized.
 func (c *Compiler) buildBytecode() ([]byte, error) {
+	// The functions the code calls follow it
+	if err := c.compileCalledFunctions(); err != nil {
+		return nil, err
+	}
+
 	bytecode := []byte{0x47, 0x4C, 0x59, 0x50} // Magic "GLYP"
 
 	// Version (little-endian u32)
@@ -1133,11 +1512,12 @@ func (c *Compiler) buildBytecode() ([]byte, error) {
 
 // adjustJumpTargets adjusts all jump instruction operands by adding the header offset
 func (c *Compiler) adjustJumpTargets(headerOffset uint32) {
-	// Jump opcodes that have a target operand
+	// Jump and call opcodes that have a target operand
 	jumpOpcodes := map[byte]bool{
 		byte(vm.OpJump):        true,
 		byte(vm.OpJumpIfFalse): true,
 		byte(vm.OpJumpIfTrue):  true,
+		byte(vm.OpCallFunc):    true,
 	}
 
 	i := 0
@@ -1154,6 +1534,13 @@ func (c *Compiler) adjustJumpTargets(headerOffset uint32) {
 				binary.LittleEndian.PutUint32(c.code[i:i+4], newTarget)
 			}
 			i += 4
+		} else if opcode == byte(vm.OpAsync) || opcode == byte(vm.OpBackground) {
+			// Async and background bodies run on their own without the
+			// header, so their jumps stay relative to the start of the body
+			if i+4 <= len(c.code) {
+				i += int(binary.LittleEndian.Uint32(c.code[i : i+4]))
+			}
+			i += 4
 		} else if hasOperand(opcode) {
 			// Skip operand for other instructions with operands
 			i += 4
@@ -1173,9 +1560,13 @@ func hasOperand(opcode byte) bool {
 		byte(vm.OpJumpIfTrue):  true,
 		byte(vm.OpIterNext):    true,
 		byte(vm.OpCall):        true,
+		byte(vm.OpCallFunc):    true,
+		byte(vm.OpEnter):       true,
+		byte(vm.OpCheckType):   true,
 		byte(vm.OpBuildObject): true,
 		byte(vm.OpBuildArray):  true,
 		byte(vm.OpAsync):       true,
+		byte(vm.OpBackground):  true,
 	}
 	return withOperand[opcode]
 }
@@ -1410,6 +1801,10 @@ func (c *Compiler) compilePatternMatch(pattern ast.Pattern, matchVarIdx int) ([]
 			c.emitWithOperand(vm.OpStoreVar, uint32(restIdx))
 		}
 
+	case ast.TypePattern:
+		// The VM has no type definitions to tell union variants apart
+		return nil, fmt.Errorf("type pattern %s {...} is only supported by the interpreter", p.TypeName)
+
 	default:
 		return nil, fmt.Errorf("unsupported pattern type: %T", pattern)
 	}
@@ -1449,6 +1844,8 @@ func (c *Compiler) compileAsyncExpr(expr *ast.AsyncExpr) error {
 		code:        make([]byte, 0),
 		symbolTable: c.symbolTable, // Share symbol table for variable access
 		constants:   c.constants,
+		optimizer:   c.optimizer,
+		functions:   c.functions,
 	}
 
 	// Compile the async body statements
@@ -1476,6 +1873,46 @@ func (c *Compiler) compileAsyncExpr(expr *ast.AsyncExpr) error {
 	return nil
 }
 
+// compileBackgroundStatement compiles a background block. Like an async
+// body, the block is compiled inline after OpBackground; the VM skips it and
+// hands it to the host to run after the response.
+func (c *Compiler) compileBackgroundStatement(stmt *ast.BackgroundStatement) error {
+	// Variables declared in the block are local to it
+	bodyCompiler := &Compiler{
+		code:         make([]byte, 0),
+		symbolTable:  c.symbolTable.EnterScope(BlockScope),
+		constants:    c.constants,
+		labelCounter: c.labelCounter,
+		optimizer:    c.optimizer,
+		functions:    c.functions,
+	}
+
+	for _, s := range stmt.Body {
+		if err := bodyCompiler.compileStatement(s); err != nil {
+			return err
+		}
+	}
+	bodyCompiler.emit(vm.OpHalt)
+
+	c.constants = bodyCompiler.constants
+	c.labelCounter = bodyCompiler.labelCounter
+
+	c.emitWithOperand(vm.OpBackground, uint32(len(bodyCompiler.code)))
+	c.code = append(c.code, bodyCompiler.code...)
+
+	return nil
+}
+
+// compileYieldStatement compiles a yield statement, which sends its value
+// to the stream the host installed on the VM
+func (c *Compiler) compileYieldStatement(stmt *ast.YieldStatement) error {
+	if err := c.compileExpression(stmt.Value); err != nil {
+		return fmt.Errorf("failed to compile yield value: %w", err)
+	}
+	c.emit(vm.OpYield)
+	return nil
+}
+
 // compileAwaitExpr compiles an await expression
 func (c *Compiler) compileAwaitExpr(expr *ast.AwaitExpr) error {
 	// Compile the expression being awaited (should produce a future)
@@ -429,6 +429,36 @@ func TestCompileReassignmentUndeclaredVariable(t *testing.T) {
 	}
 }
 
+func TestCompileReassignConstant(t *testing.T) {
+	// Test: const x = 1, x = 2 should fail
+	route := &ast.Route{
+		Body: []ast.Statement{
+			&ast.AssignStatement{
+				Target: "x",
+				Value:  &ast.LiteralExpr{Value: ast.IntLiteral{Value: 1}},
+				Const:  true,
+			},
+			&ast.ReassignStatement{
+				Target: "x",
+				Value:  &ast.LiteralExpr{Value: ast.IntLiteral{Value: 2}},
+			},
+		},
+	}
+
+	c := NewCompiler()
+	_, err := c.CompileRoute(route)
+	if err == nil {
+		t.Fatal("Expected constant reassignment error, got nil")
+	}
+	if !IsSemanticError(err) {
+		t.Errorf("Expected SemanticError, got %T: %v", err, err)
+	}
+	expectedMsg := "cannot reassign constant 'x'"
+	if err.Error() != expectedMsg {
+		t.Errorf("Expected error message %q, got %q", expectedMsg, err.Error())
+	}
+}
+
 func TestCompileArithmeticExpression(t *testing.T) {
 	// Test: $ result = 5 + 3 * 2, > result
 	// Expected: 11 (5 + (3 * 2))
@@ -3546,6 +3576,32 @@ func TestCompileMatchExpr_VariablePattern(t *testing.T) {
 	}
 }
 
+func TestCompileMatchExpr_TypePatternUnsupported(t *testing.T) {
+	// match x { Error {message} => message } needs the interpreter's type definitions
+	c := NewCompiler()
+	c.symbolTable = c.symbolTable.EnterScope(RouteScope)
+	xIdx := c.addConstant(vm.StringValue{Val: "x"})
+	c.symbolTable.Define("x", xIdx)
+
+	matchExpr := &ast.MatchExpr{
+		Value: &ast.VariableExpr{Name: "x"},
+		Cases: []ast.MatchCase{
+			{
+				Pattern: ast.TypePattern{TypeName: "Error", Fields: []ast.ObjectPatternField{{Key: "message"}}},
+				Body:    &ast.VariableExpr{Name: "message"},
+			},
+		},
+	}
+
+	err := c.compileMatchExpr(matchExpr)
+	if err == nil || !strings.Contains(err.Error(), "only supported by the interpreter") {
+		t.Fatalf("expected an interpreter-only error, got %v", err)
+	}
+	if IsSemanticError(err) {
+		t.Error("type patterns should fall back to the interpreter, not fail compilation")
+	}
+}
+
 func TestCompileMatchExpr_WildcardPattern(t *testing.T) {
 	// match x { _ => 99 }
 	c := NewCompiler()
@@ -3969,6 +4025,47 @@ func TestCompileAsyncExpr_ValueType(t *testing.T) {
 	}
 }
 
+// TestCompileAsyncExpr_ControlFlow checks that jumps inside an async body
+// are not relocated by the header size, since the body runs on its own
+func TestCompileAsyncExpr_ControlFlow(t *testing.T) {
+	// $ f = async { if false { > 1 } > 2 }
+	// > await f
+	route := &ast.Route{
+		Body: []ast.Statement{
+			&ast.AssignStatement{
+				Target: "f",
+				Value: &ast.AsyncExpr{
+					Body: []ast.Statement{
+						&ast.IfStatement{
+							Condition: &ast.LiteralExpr{Value: ast.BoolLiteral{Value: false}},
+							ThenBlock: []ast.Statement{
+								&ast.ReturnStatement{Value: &ast.LiteralExpr{Value: ast.IntLiteral{Value: 1}}},
+							},
+						},
+						&ast.ReturnStatement{Value: &ast.LiteralExpr{Value: ast.IntLiteral{Value: 2}}},
+					},
+				},
+			},
+			&ast.ReturnStatement{
+				Value: &ast.AwaitExpr{Expr: &ast.VariableExpr{Name: "f"}},
+			},
+		},
+	}
+
+	bytecode, err := NewCompilerWithOptLevel(OptNone).CompileRoute(route)
+	if err != nil {
+		t.Fatalf("CompileRoute() error: %v", err)
+	}
+
+	result, err := vm.NewVM().Execute(bytecode)
+	if err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if intVal, ok := result.(vm.IntValue); !ok || intVal.Val != 2 {
+		t.Errorf("Expected IntValue{2}, got %v", result)
+	}
+}
+
 func TestCompileAwaitExpr_Basic(t *testing.T) {
 	// await someExpr
 	c := NewCompiler()
@@ -0,0 +1,180 @@
+package compiler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+)
+
+// moduleConstant is the compile-time value of a module constant. value is
+// built only from literals, arrays and objects, or is nil when the constant
+// needs the runtime (a function call, say), so routes that read it are left
+// to the interpreter. err is the error computing it, such as a literal
+// division by zero.
+type moduleConstant struct {
+	value ast.Expr
+	err   error
+}
+
+// SetConstants makes the module's constants readable from the code this
+// compiler compiles. Each is computed once, in declaration order, so a
+// constant may use the ones declared before it; references to it compile
+// to its value, whose literals go in the constant pool.
+func (c *Compiler) SetConstants(decls []*ast.ConstDecl) {
+	c.moduleConsts = make(map[string]moduleConstant, len(decls))
+	values := make(map[string]ast.Expr, len(decls))
+	for _, decl := range decls {
+		value, ok, err := constantValue(decl.Value, values)
+		if !ok {
+			value = nil
+		}
+		c.moduleConsts[decl.Name] = moduleConstant{value: value, err: err}
+		if value != nil {
+			values[decl.Name] = value
+		}
+	}
+	c.constantsHash = hashDecls(decls)
+}
+
+// ModuleConstants returns the constants declared in a module
+func ModuleConstants(module *ast.Module) []*ast.ConstDecl {
+	var decls []*ast.ConstDecl
+	for _, item := range module.Items {
+		if decl, ok := item.(*ast.ConstDecl); ok {
+			decls = append(decls, decl)
+		}
+	}
+	return decls
+}
+
+// SetEnums makes the module's enums known to the code this compiler
+// compiles, for Status.values()
+func (c *Compiler) SetEnums(enums []*ast.EnumDef) {
+	c.enums = make(map[string]*ast.EnumDef, len(enums))
+	for _, enum := range enums {
+		c.enums[enum.Name] = enum
+	}
+	c.enumsHash = hashDecls(enums)
+}
+
+// ModuleEnums returns the enums defined in a module
+func ModuleEnums(module *ast.Module) []*ast.EnumDef {
+	var enums []*ast.EnumDef
+	for _, item := range module.Items {
+		if enum, ok := item.(*ast.EnumDef); ok {
+			enums = append(enums, enum)
+		}
+	}
+	return enums
+}
+
+// hashDecls returns a hash of a list of declarations in order, or "" when
+// there are none
+func hashDecls[T any](decls []T) string {
+	if len(decls) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(appendValue(nil, reflect.ValueOf(decls)))
+	return hex.EncodeToString(sum[:])
+}
+
+// constantValue computes expr at compile time, reading the constants in
+// values. ok is false for an expression that needs the runtime.
+func constantValue(expr ast.Expr, values map[string]ast.Expr) (ast.Expr, bool, error) {
+	switch e := expr.(type) {
+	case ast.LiteralExpr:
+		return e, true, nil
+	case *ast.LiteralExpr:
+		return *e, true, nil
+	case ast.VariableExpr:
+		value, ok := values[e.Name]
+		return value, ok, nil
+	case *ast.VariableExpr:
+		value, ok := values[e.Name]
+		return value, ok, nil
+	case ast.BinaryOpExpr:
+		return constantValue(&e, values)
+	case *ast.BinaryOpExpr:
+		left, ok, err := constantValue(e.Left, values)
+		if !ok || err != nil {
+			return nil, false, err
+		}
+		right, ok, err := constantValue(e.Right, values)
+		if !ok || err != nil {
+			return nil, false, err
+		}
+		return foldedLiteral(&ast.BinaryOpExpr{Op: e.Op, Left: left, Right: right, Pos: e.Pos})
+	case ast.UnaryOpExpr:
+		return constantValue(&e, values)
+	case *ast.UnaryOpExpr:
+		right, ok, err := constantValue(e.Right, values)
+		if !ok || err != nil {
+			return nil, false, err
+		}
+		return foldedLiteral(&ast.UnaryOpExpr{Op: e.Op, Right: right})
+	case ast.ArrayExpr:
+		return constantValue(&e, values)
+	case *ast.ArrayExpr:
+		elements := make([]ast.Expr, len(e.Elements))
+		for i, elem := range e.Elements {
+			value, ok, err := constantValue(elem, values)
+			if !ok || err != nil {
+				return nil, false, err
+			}
+			elements[i] = value
+		}
+		return ast.ArrayExpr{Elements: elements}, true, nil
+	case ast.ObjectExpr:
+		return constantValue(&e, values)
+	case *ast.ObjectExpr:
+		fields := make([]ast.ObjectField, len(e.Fields))
+		for i, field := range e.Fields {
+			value, ok, err := constantValue(field.Value, values)
+			if !ok || err != nil {
+				return nil, false, err
+			}
+			fields[i] = ast.ObjectField{Key: field.Key, Value: value}
+		}
+		return ast.ObjectExpr{Fields: fields}, true, nil
+	}
+	return nil, false, nil
+}
+
+// foldedLiteral folds an operation on computed constants into a literal
+func foldedLiteral(expr ast.Expr) (ast.Expr, bool, error) {
+	lit, ok, err := foldConstant(expr)
+	if !ok || err != nil {
+		return nil, false, err
+	}
+	return ast.LiteralExpr{Value: lit}, true, nil
+}
+
+// compileConstant compiles a reference to the module constant name
+func (c *Compiler) compileConstant(name string, constant moduleConstant) error {
+	if constant.err != nil {
+		return constant.err
+	}
+	if constant.value == nil {
+		return fmt.Errorf("constant %s cannot be computed at compile time", name)
+	}
+	return c.compileExpression(constant.value)
+}
+
+// compileEnumMethod compiles a call of a method of an enum: values(), the
+// array of the enum's values in declaration order
+func (c *Compiler) compileEnumMethod(enum *ast.EnumDef, method string, expr *ast.FunctionCallExpr) error {
+	if method != "values" {
+		return &SemanticError{Message: fmt.Sprintf("enum %s has no method %s", enum.Name, method)}
+	}
+	if len(expr.Args) > 0 {
+		return &SemanticError{Message: fmt.Sprintf("%s.values() takes no arguments", enum.Name)}
+	}
+	elements := make([]ast.Expr, len(enum.Values))
+	for i, value := range enum.Values {
+		elements[i] = ast.LiteralExpr{Value: ast.StringLiteral{Value: value}}
+	}
+	return c.compileArray(&ast.ArrayExpr{Elements: elements})
+}
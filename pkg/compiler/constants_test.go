@@ -0,0 +1,100 @@
+package compiler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+const constantsCode = `: Status = "pending" | "shipped"
+
+const MAX = 100
+const LIMIT = MAX * 2
+const ROLES = ["admin", "user"]
+
+@ GET /x {
+  > {limit: LIMIT, roles: ROLES, statuses: Status.values()}
+}`
+
+func TestCompileModuleConstants(t *testing.T) {
+	c := NewCompiler()
+	bytecode, err := c.Compile(parseModule(t, constantsCode))
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	result, err := vm.NewVM().Execute(bytecode)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	want := vm.ObjectValue{Val: map[string]vm.Value{
+		"limit":    vm.IntValue{Val: 200},
+		"roles":    vm.ArrayValue{Val: []vm.Value{vm.StringValue{Val: "admin"}, vm.StringValue{Val: "user"}}},
+		"statuses": vm.ArrayValue{Val: []vm.Value{vm.StringValue{Val: "pending"}, vm.StringValue{Val: "shipped"}}},
+	}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Expected %#v, got %#v", want, result)
+	}
+
+	// LIMIT is folded into the constant pool rather than loaded by name
+	folded := false
+	for _, constant := range c.constants {
+		if constant == (vm.StringValue{Val: "LIMIT"}) {
+			t.Errorf("Expected no load of LIMIT by name")
+		}
+		if constant == (vm.IntValue{Val: 200}) {
+			folded = true
+		}
+	}
+	if !folded {
+		t.Errorf("Expected LIMIT folded to 200 in the constant pool, got %v", c.constants)
+	}
+}
+
+func TestCompileAssignModuleConstant(t *testing.T) {
+	for _, body := range []string{"MAX = 5\n  > MAX", "$ MAX = 5\n  > MAX"} {
+		_, err := NewCompiler().Compile(parseModule(t, "const MAX = 100\n\n@ GET /x {\n  "+body+"\n}"))
+		if err == nil || !IsSemanticError(err) {
+			t.Fatalf("Expected a semantic error for %q, got %v", body, err)
+		}
+		if err.Error() != "cannot reassign constant 'MAX'" {
+			t.Errorf("Expected cannot reassign constant 'MAX', got %q", err.Error())
+		}
+	}
+
+	// A const of the route shadows the module's
+	bytecode, err := NewCompiler().Compile(parseModule(t, "const MAX = 100\n\n@ GET /x {\n  const MAX = 5\n  > MAX\n}"))
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	result, err := vm.NewVM().Execute(bytecode)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result != (vm.IntValue{Val: 5}) {
+		t.Errorf("Expected 5, got %#v", result)
+	}
+}
+
+func TestCompileRuntimeConstant(t *testing.T) {
+	// A constant computed by a function call is left to the interpreter
+	_, err := NewCompiler().Compile(parseModule(t, "const STARTED = now()\n\n@ GET /x {\n  > STARTED\n}"))
+	if err == nil || IsSemanticError(err) {
+		t.Fatalf("Expected a non-semantic error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "constant STARTED cannot be computed at compile time") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestCompileEnumUnknownMethod(t *testing.T) {
+	_, err := NewCompiler().Compile(parseModule(t, ": Status = \"pending\" | \"shipped\"\n\n@ GET /x {\n  > Status.count()\n}"))
+	if err == nil || !IsSemanticError(err) {
+		t.Fatalf("Expected a semantic error, got %v", err)
+	}
+	if err.Error() != "enum Status has no method count" {
+		t.Errorf("Expected enum Status has no method count, got %q", err.Error())
+	}
+}
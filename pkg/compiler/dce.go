@@ -0,0 +1,95 @@
+package compiler
+
+import "github.com/glyphlang/glyph/pkg/ast"
+
+// eliminateDeadCode removes statements that can never run: the branch an if
+// with a constant condition does not take, a while loop whose condition is
+// constant false, and whatever follows a return, break or continue in the
+// same block. It is only run at OptAggressive.
+func eliminateDeadCode(stmts []ast.Statement) []ast.Statement {
+	result := make([]ast.Statement, 0, len(stmts))
+	for _, stmt := range stmts {
+		switch s := normalizeStatement(stmt).(type) {
+		case ast.IfStatement:
+			taken, isConst := constantCondition(s.Condition)
+			if !isConst {
+				s.ThenBlock = eliminateDeadCode(s.ThenBlock)
+				s.ElseBlock = eliminateDeadCode(s.ElseBlock)
+				result = append(result, s)
+				break
+			}
+			branch := s.ElseBlock
+			if taken {
+				branch = s.ThenBlock
+			}
+			branch = eliminateDeadCode(branch)
+			if declaresVariables(branch) {
+				// Keep the block scope the branch's variables are declared in
+				result = append(result, ast.IfStatement{
+					Condition: ast.LiteralExpr{Value: ast.BoolLiteral{Value: true}},
+					ThenBlock: branch,
+				})
+			} else {
+				result = append(result, branch...)
+			}
+		case ast.WhileStatement:
+			if taken, isConst := constantCondition(s.Condition); isConst && !taken {
+				break
+			}
+			s.Body = eliminateDeadCode(s.Body)
+			result = append(result, s)
+		case ast.ForStatement:
+			s.Body = eliminateDeadCode(s.Body)
+			result = append(result, s)
+		case ast.SwitchStatement:
+			cases := make([]ast.SwitchCase, len(s.Cases))
+			for i, c := range s.Cases {
+				cases[i] = ast.SwitchCase{Value: c.Value, Body: eliminateDeadCode(c.Body)}
+			}
+			s.Cases = cases
+			s.Default = eliminateDeadCode(s.Default)
+			result = append(result, s)
+		case ast.BackgroundStatement:
+			s.Body = eliminateDeadCode(s.Body)
+			result = append(result, s)
+		default:
+			result = append(result, stmt)
+		}
+
+		if len(result) > 0 && endsBlock(result[len(result)-1]) {
+			break
+		}
+	}
+	return result
+}
+
+// constantCondition reports the value of a condition that folds to a
+// boolean literal
+func constantCondition(cond ast.Expr) (value bool, ok bool) {
+	lit, ok, err := foldConstant(cond)
+	if !ok || err != nil {
+		return false, false
+	}
+	b, ok := lit.(ast.BoolLiteral)
+	return b.Value, ok
+}
+
+// endsBlock reports whether stmt unconditionally leaves its block
+func endsBlock(stmt ast.Statement) bool {
+	switch normalizeStatement(stmt).(type) {
+	case ast.ReturnStatement, ast.BreakStatement, ast.ContinueStatement:
+		return true
+	}
+	return false
+}
+
+// declaresVariables reports whether stmts declare a variable in their own
+// block, as opposed to a nested one
+func declaresVariables(stmts []ast.Statement) bool {
+	for _, stmt := range stmts {
+		if _, ok := normalizeStatement(stmt).(ast.AssignStatement); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+func TestDeadCodeEliminationAtOptAggressive(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		kept    []string
+		dropped []string
+		want    vm.Value
+	}{
+		{
+			name: "constant true condition keeps only the then branch",
+			body: `if true {
+    > "then"
+  } else {
+    > "else"
+  }`,
+			kept:    []string{"then"},
+			dropped: []string{"else"},
+			want:    vm.StringValue{Val: "then"},
+		},
+		{
+			name: "folded false condition keeps only the else branch",
+			body: `if 1 > 2 {
+    log("never")
+  } else if false {
+    log("also never")
+  }
+  > "done"`,
+			kept:    []string{"done"},
+			dropped: []string{"never", "also never"},
+			want:    vm.StringValue{Val: "done"},
+		},
+		{
+			name: "code after a return",
+			body: `$ x = 1
+  > x
+  log("unreachable")
+  > "after"`,
+			dropped: []string{"unreachable", "after"},
+			want:    vm.IntValue{Val: 1},
+		},
+		{
+			name: "code after a return taken from a constant branch",
+			body: `if !false {
+    > "early"
+  }
+  > "late"`,
+			kept:    []string{"early"},
+			dropped: []string{"late"},
+			want:    vm.StringValue{Val: "early"},
+		},
+		{
+			name: "loop that never runs and code after break",
+			body: `while false {
+    log("loop")
+  }
+  $ n = 0
+  while n < 3 {
+    n = n + 1
+    break
+    log("after break")
+  }
+  > n`,
+			dropped: []string{"loop", "after break"},
+			want:    vm.IntValue{Val: 1},
+		},
+		{
+			name: "branch declaring variables keeps its scope",
+			body: `if true {
+    $ label = "inner"
+    $ copy = label
+  } else {
+    $ label = "other"
+  }
+  $ label = "outer"
+  > label`,
+			kept:    []string{"inner", "outer"},
+			dropped: []string{"other"},
+			want:    vm.StringValue{Val: "outer"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := "@ GET /dce {\n  " + tt.body + "\n}"
+
+			basic, basicCompiler, err := compileAt(t, OptBasic, source)
+			if err != nil {
+				t.Fatalf("Compile at OptBasic failed: %v", err)
+			}
+			aggressive, c, err := compileAt(t, OptAggressive, source)
+			if err != nil {
+				t.Fatalf("Compile at OptAggressive failed: %v", err)
+			}
+
+			for _, s := range tt.kept {
+				if !hasConstant(c.constants, vm.StringValue{Val: s}) {
+					t.Errorf("Expected %q in the constant pool: %v", s, c.constants)
+				}
+			}
+			for _, s := range tt.dropped {
+				if hasConstant(c.constants, vm.StringValue{Val: s}) {
+					t.Errorf("Expected %q to be eliminated: %v", s, c.constants)
+				}
+				if !hasConstant(basicCompiler.constants, vm.StringValue{Val: s}) {
+					t.Errorf("Expected OptBasic to keep %q: %v", s, basicCompiler.constants)
+				}
+			}
+			if len(aggressive) >= len(basic) {
+				t.Errorf("Expected smaller bytecode at OptAggressive: %d >= %d bytes", len(aggressive), len(basic))
+			}
+
+			got, err := vm.NewVM().Execute(aggressive)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDeadCodeEliminationKeepsRuntimeConditions(t *testing.T) {
+	source := `@ GET /dce/:id {
+  if id == "1" {
+    > "one"
+  } else {
+    > "other"
+  }
+}`
+	_, c, err := compileAt(t, OptAggressive, source)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	for _, s := range []string{"one", "other"} {
+		if !hasConstant(c.constants, vm.StringValue{Val: s}) {
+			t.Errorf("Expected %q to be kept: %v", s, c.constants)
+		}
+	}
+}
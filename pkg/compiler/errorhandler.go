@@ -0,0 +1,16 @@
+package compiler
+
+import (
+	"github.com/glyphlang/glyph/pkg/ast"
+)
+
+// CompileErrorHandler compiles the body of an @ notfound or @ onerror
+// handler like that of a route with no path parameters. In an onerror
+// handler err is injected with the error being answered.
+func (c *Compiler) CompileErrorHandler(h *ast.ErrorHandler) ([]byte, error) {
+	route := &ast.Route{Path: "/", Method: ast.Get, Body: h.Body}
+	if h.Kind == ast.OnErrorHandler {
+		return c.compileRoute(route, "err")
+	}
+	return c.compileRoute(route)
+}
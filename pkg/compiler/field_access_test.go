@@ -0,0 +1,151 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+func TestNestedFieldAccessOnVM(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want vm.Value
+	}{
+		{
+			name: "nested fields",
+			code: `@ GET /test {
+  $ order = {customer: {address: {city: "Lisbon"}}}
+  > order.customer.address.city
+}`,
+			want: vm.StringValue{Val: "Lisbon"},
+		},
+		{
+			name: "fields of array elements",
+			code: `@ GET /test {
+  $ users = [{name: "ada", tags: ["admin"]}, {name: "bob", tags: ["dev", "ops"]}]
+  $ bob = users[1]
+  > bob.tags[1]
+}`,
+			want: vm.StringValue{Val: "ops"},
+		},
+		{
+			name: "object indexed by key",
+			code: `@ GET /test {
+  $ limits = {free: 10, pro: 100}
+  $ plan = "pro"
+  > limits[plan]
+}`,
+			want: vm.IntValue{Val: 100},
+		},
+		{
+			name: "missing field is null",
+			code: `@ GET /test {
+  $ user = {name: "ada"}
+  > user.nickname
+}`,
+			want: vm.NullValue{},
+		},
+		{
+			name: "field of an array of objects in a loop",
+			code: `@ GET /test {
+  $ items = [{price: 3}, {price: 4}]
+  $ total = 0
+  for item in items {
+    $ total = total + item.price
+  }
+  > total
+}`,
+			want: vm.IntValue{Val: 7},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := compileAndRun(t, tt.code)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Expected %#v, got %#v", tt.want, result)
+			}
+		})
+	}
+}
+
+// The VM reports access errors with the interpreter's messages, so a route
+// fails the same way in either execution mode
+func TestFieldAccessErrorsMatchInterpreter(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{
+			name: "field of null",
+			code: `@ GET /test {
+  $ user = {profile: null}
+  > user.profile.bio
+}`,
+			want: "cannot access field bio on null",
+		},
+		{
+			name: "field of a non-object",
+			code: `@ GET /test {
+  $ count = 3
+  > count.value
+}`,
+			want: "cannot access field value on int",
+		},
+		{
+			name: "missing object key",
+			code: `@ GET /test {
+  $ limits = {free: 10}
+  > limits["pro"]
+}`,
+			want: `key "pro" not found in object`,
+		},
+		{
+			name: "index out of range",
+			code: `@ GET /test {
+  $ items = [1, 2]
+  > items[2]
+}`,
+			want: "array index out of bounds: 2 (length: 2)",
+		},
+		{
+			name: "non-integer array index",
+			code: `@ GET /test {
+  $ items = [1, 2]
+  > items["0"]
+}`,
+			want: "array index must be an integer, got string",
+		},
+		{
+			name: "non-string object key",
+			code: `@ GET /test {
+  $ limits = {free: 10}
+  > limits[0]
+}`,
+			want: "map key must be a string, got int",
+		},
+		{
+			name: "index of a non-collection",
+			code: `@ GET /test {
+  $ name = "ada"
+  > name[0]
+}`,
+			want: "cannot index string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileAndRun(t, tt.code)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package compiler
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+// foldConstant evaluates expr at compile time when it is built only from
+// literals, following the VM's arithmetic so the folded value is the one the
+// route would have computed. ok is false for anything left to the runtime,
+// including operations the VM would reject with a type error. Dividing by a
+// literal zero, or an integer overflow, is reported as an error instead of
+// being left to fail on every request.
+func foldConstant(expr ast.Expr) (lit ast.Literal, ok bool, err error) {
+	switch e := expr.(type) {
+	case ast.LiteralExpr:
+		return e.Value, true, nil
+	case *ast.LiteralExpr:
+		return e.Value, true, nil
+	case ast.BinaryOpExpr:
+		return foldBinary(&e)
+	case *ast.BinaryOpExpr:
+		return foldBinary(e)
+	case ast.UnaryOpExpr:
+		return foldUnary(&e)
+	case *ast.UnaryOpExpr:
+		return foldUnary(e)
+	}
+	return nil, false, nil
+}
+
+func foldBinary(expr *ast.BinaryOpExpr) (ast.Literal, bool, error) {
+	if expr.Op == ast.Coalesce {
+		return nil, false, nil
+	}
+	left, ok, err := foldConstant(expr.Left)
+	if !ok || err != nil {
+		return nil, false, err
+	}
+	right, ok, err := foldConstant(expr.Right)
+	if !ok || err != nil {
+		return nil, false, err
+	}
+
+	if (expr.Op == ast.Div || expr.Op == ast.Mod) && isNumber(left) && isZero(right) {
+		what := "division"
+		if expr.Op == ast.Mod {
+			what = "modulo"
+		}
+		return nil, false, foldError(expr.Pos, fmt.Sprintf("%s by zero in constant expression", what))
+	}
+
+	switch l := left.(type) {
+	case ast.IntLiteral:
+		switch r := right.(type) {
+		case ast.IntLiteral:
+			lit, ok, err := foldInts(expr.Op, l.Value, r.Value)
+			if err != nil {
+				return nil, false, foldError(expr.Pos, err.Error()+" in constant expression")
+			}
+			return lit, ok, nil
+		case ast.FloatLiteral:
+			lit, ok := foldFloats(expr.Op, float64(l.Value), r.Value)
+			return lit, ok, nil
+		}
+	case ast.FloatLiteral:
+		switch r := right.(type) {
+		case ast.FloatLiteral:
+			lit, ok := foldFloats(expr.Op, l.Value, r.Value)
+			return lit, ok, nil
+		case ast.IntLiteral:
+			lit, ok := foldFloats(expr.Op, l.Value, float64(r.Value))
+			return lit, ok, nil
+		}
+	case ast.StringLiteral:
+		r, isString := right.(ast.StringLiteral)
+		if !isString {
+			break
+		}
+		switch expr.Op {
+		case ast.Add:
+			return ast.StringLiteral{Value: l.Value + r.Value}, true, nil
+		case ast.Eq:
+			return ast.BoolLiteral{Value: l.Value == r.Value}, true, nil
+		case ast.Ne:
+			return ast.BoolLiteral{Value: l.Value != r.Value}, true, nil
+		}
+	case ast.BoolLiteral:
+		r, isBool := right.(ast.BoolLiteral)
+		if !isBool {
+			break
+		}
+		switch expr.Op {
+		case ast.And:
+			return ast.BoolLiteral{Value: l.Value && r.Value}, true, nil
+		case ast.Or:
+			return ast.BoolLiteral{Value: l.Value || r.Value}, true, nil
+		case ast.Eq:
+			return ast.BoolLiteral{Value: l.Value == r.Value}, true, nil
+		case ast.Ne:
+			return ast.BoolLiteral{Value: l.Value != r.Value}, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// foldInts folds an operation on two integers. An overflow is an error, as
+// it is when the VM computes the same operation.
+func foldInts(op ast.BinOp, a, b int64) (ast.Literal, bool, error) {
+	var arith func(a, b int64) (int64, error)
+	switch op {
+	case ast.Add:
+		arith = vm.AddInt
+	case ast.Sub:
+		arith = vm.SubInt
+	case ast.Mul:
+		arith = vm.MulInt
+	case ast.Div:
+		arith = vm.DivInt
+	}
+	if arith != nil {
+		result, err := arith(a, b)
+		if err != nil {
+			return nil, false, err
+		}
+		return ast.IntLiteral{Value: result}, true, nil
+	}
+	switch op {
+	case ast.Mod:
+		return ast.IntLiteral{Value: a % b}, true, nil
+	case ast.Eq:
+		return ast.BoolLiteral{Value: a == b}, true, nil
+	case ast.Ne:
+		return ast.BoolLiteral{Value: a != b}, true, nil
+	case ast.Lt:
+		return ast.BoolLiteral{Value: a < b}, true, nil
+	case ast.Le:
+		return ast.BoolLiteral{Value: a <= b}, true, nil
+	case ast.Gt:
+		return ast.BoolLiteral{Value: a > b}, true, nil
+	case ast.Ge:
+		return ast.BoolLiteral{Value: a >= b}, true, nil
+	}
+	return nil, false, nil
+}
+
+// foldFloats folds an operation on two numbers at least one of which is a
+// float. Equality is left to the runtime, which never considers an int
+// equal to a float.
+func foldFloats(op ast.BinOp, a, b float64) (ast.Literal, bool) {
+	switch op {
+	case ast.Add:
+		return ast.FloatLiteral{Value: a + b}, true
+	case ast.Sub:
+		return ast.FloatLiteral{Value: a - b}, true
+	case ast.Mul:
+		return ast.FloatLiteral{Value: a * b}, true
+	case ast.Div:
+		return ast.FloatLiteral{Value: a / b}, true
+	case ast.Mod:
+		return ast.FloatLiteral{Value: math.Mod(a, b)}, true
+	case ast.Lt:
+		return ast.BoolLiteral{Value: a < b}, true
+	case ast.Le:
+		return ast.BoolLiteral{Value: a <= b}, true
+	case ast.Gt:
+		return ast.BoolLiteral{Value: a > b}, true
+	case ast.Ge:
+		return ast.BoolLiteral{Value: a >= b}, true
+	}
+	return nil, false
+}
+
+func foldUnary(expr *ast.UnaryOpExpr) (ast.Literal, bool, error) {
+	operand, ok, err := foldConstant(expr.Right)
+	if !ok || err != nil {
+		return nil, false, err
+	}
+	switch v := operand.(type) {
+	case ast.IntLiteral:
+		if expr.Op == ast.Neg {
+			result, err := vm.NegInt(v.Value)
+			if err != nil {
+				return nil, false, foldError(expr.Pos, err.Error()+" in constant expression")
+			}
+			return ast.IntLiteral{Value: result}, true, nil
+		}
+	case ast.FloatLiteral:
+		if expr.Op == ast.Neg {
+			return ast.FloatLiteral{Value: -v.Value}, true, nil
+		}
+	case ast.BoolLiteral:
+		if expr.Op == ast.Not {
+			return ast.BoolLiteral{Value: !v.Value}, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// foldError is the semantic error for a constant expression at pos that
+// would fail every time it ran
+func foldError(pos ast.Pos, msg string) error {
+	if pos.HasPos() {
+		msg += fmt.Sprintf(" at line %d, column %d", pos.Line, pos.Column)
+	}
+	return &SemanticError{Message: msg}
+}
+
+func isNumber(lit ast.Literal) bool {
+	switch lit.(type) {
+	case ast.IntLiteral, ast.FloatLiteral:
+		return true
+	}
+	return false
+}
+
+func isZero(lit ast.Literal) bool {
+	switch v := lit.(type) {
+	case ast.IntLiteral:
+		return v.Value == 0
+	case ast.FloatLiteral:
+		return v.Value == 0
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+// compileAt compiles the single route in source at the given level and
+// returns its bytecode along with the compiler, whose constant pool is left
+// in place for inspection
+func compileAt(t *testing.T, level OptimizationLevel, source string) ([]byte, *Compiler, error) {
+	t.Helper()
+	routes := parseRoutes(t, source)
+	if len(routes) != 1 {
+		t.Fatalf("Expected one route, got %d", len(routes))
+	}
+	c := NewCompilerWithOptLevel(level)
+	bytecode, err := c.CompileRoute(routes[0])
+	return bytecode, c, err
+}
+
+func hasConstant(constants []vm.Value, want vm.Value) bool {
+	for _, c := range constants {
+		if c == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestConstantFoldingAtOptAggressive(t *testing.T) {
+	source := `@ GET /total {
+  > (10 + 20) * 2
+}`
+	basic, basicCompiler, err := compileAt(t, OptBasic, source)
+	if err != nil {
+		t.Fatalf("Compile at OptBasic failed: %v", err)
+	}
+	folded, foldedCompiler, err := compileAt(t, OptAggressive, source)
+	if err != nil {
+		t.Fatalf("Compile at OptAggressive failed: %v", err)
+	}
+
+	for _, operand := range []int64{10, 20, 2} {
+		if !hasConstant(basicCompiler.constants, vm.IntValue{Val: operand}) {
+			t.Errorf("Expected %d in the OptBasic constant pool: %v", operand, basicCompiler.constants)
+		}
+		if hasConstant(foldedCompiler.constants, vm.IntValue{Val: operand}) {
+			t.Errorf("Expected %d to be folded away at OptAggressive: %v", operand, foldedCompiler.constants)
+		}
+	}
+	if !hasConstant(foldedCompiler.constants, vm.IntValue{Val: 60}) {
+		t.Errorf("Expected the folded value 60 in the constant pool: %v", foldedCompiler.constants)
+	}
+	if hasConstant(basicCompiler.constants, vm.IntValue{Val: 60}) {
+		t.Errorf("OptBasic should not fold: %v", basicCompiler.constants)
+	}
+	if len(folded) >= len(basic) {
+		t.Errorf("Expected folded bytecode to be smaller: %d >= %d bytes", len(folded), len(basic))
+	}
+	if n := countOpcode(folded, vm.OpMul) + countOpcode(folded, vm.OpAdd); n != 0 {
+		t.Errorf("Expected no arithmetic left in folded bytecode, found %d instructions", n)
+	}
+
+	for _, bytecode := range [][]byte{basic, folded} {
+		result, err := vm.NewVM().Execute(bytecode)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if result != (vm.IntValue{Val: 60}) {
+			t.Errorf("Expected 60, got %v", result)
+		}
+	}
+}
+
+func TestConstantFoldingMatchesRuntime(t *testing.T) {
+	for _, expr := range []string{
+		"7 / 2",
+		"-7 % 3",
+		"1 + 2.5",
+		"7.5 % 2",
+		"10 / 4.0",
+		"-(3 * 2)",
+		`"glyph" + "lang"`,
+		`"a" == "a"`,
+		"!(1 < 2) || 2.5 >= 2",
+		"true && false == false",
+		"1 == 1.0",
+		"x * (2 + 3) - 1",
+	} {
+		source := "@ GET /expr {\n  $ x = 4\n  > " + expr + "\n}"
+		basic, _, err := compileAt(t, OptBasic, source)
+		if err != nil {
+			t.Fatalf("%s: compile at OptBasic failed: %v", expr, err)
+		}
+		folded, _, err := compileAt(t, OptAggressive, source)
+		if err != nil {
+			t.Fatalf("%s: compile at OptAggressive failed: %v", expr, err)
+		}
+
+		want, err := vm.NewVM().Execute(basic)
+		if err != nil {
+			t.Fatalf("%s: execute failed: %v", expr, err)
+		}
+		got, err := vm.NewVM().Execute(folded)
+		if err != nil {
+			t.Fatalf("%s: execute folded failed: %v", expr, err)
+		}
+		if got != want {
+			t.Errorf("%s: folded to %#v, runtime computes %#v", expr, got, want)
+		}
+	}
+}
+
+func TestConstantFoldingDivisionByZero(t *testing.T) {
+	for _, tc := range []struct {
+		expr string
+		want string
+	}{
+		{"10 / (5 - 5)", "division by zero in constant expression at line 3"},
+		{"1.5 % 0", "modulo by zero in constant expression at line 3"},
+		{"x + 1 / 0", "division by zero"},
+	} {
+		source := "@ GET /expr {\n  $ x = 1\n  > " + tc.expr + "\n}"
+
+		_, _, err := compileAt(t, OptAggressive, source)
+		if err == nil {
+			t.Errorf("%s: expected a compile error at OptAggressive", tc.expr)
+			continue
+		}
+		if !IsSemanticError(err) || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected a semantic error containing %q, got %v", tc.expr, tc.want, err)
+		}
+
+		// Without folding the division still fails, but only when it runs
+		if _, _, err := compileAt(t, OptBasic, source); err != nil {
+			t.Errorf("%s: OptBasic should leave the division to the runtime, got %v", tc.expr, err)
+		}
+	}
+
+	// An integer overflow is reported the same way
+	for _, tc := range []struct {
+		expr string
+		want string
+	}{
+		{"9223372036854775807 + 1", "integer overflow: 9223372036854775807 + 1 in constant expression at line 3"},
+		{"(0 - 9223372036854775807 - 1) / -1", "integer overflow: -9223372036854775808 / -1 in constant expression"},
+		{"-(0 - 9223372036854775807 - 1)", "integer overflow: -(-9223372036854775808) in constant expression"},
+	} {
+		source := "@ GET /expr {\n  $ x = 1\n  > " + tc.expr + "\n}"
+		_, _, err := compileAt(t, OptAggressive, source)
+		if err == nil || !IsSemanticError(err) || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected a semantic error containing %q, got %v", tc.expr, tc.want, err)
+		}
+
+		// Left to the runtime, the VM fails with the same overflow
+		bytecode, _, err := compileAt(t, OptBasic, source)
+		if err != nil {
+			t.Fatalf("%s: compile at OptBasic failed: %v", tc.expr, err)
+		}
+		if _, err := vm.NewVM().Execute(bytecode); err == nil || !strings.Contains(err.Error(), "integer overflow") {
+			t.Errorf("%s: expected a runtime integer overflow, got %v", tc.expr, err)
+		}
+	}
+
+	// A type error is not folded either, so it is still reported at runtime
+	bytecode, _, err := compileAt(t, OptAggressive, "@ GET /expr {\n  > \"a\" / 0\n}")
+	if err != nil {
+		t.Fatalf("Expected a string divided by zero to be left to the runtime, got %v", err)
+	}
+	if _, err := vm.NewVM().Execute(bytecode); err == nil || !strings.Contains(err.Error(), "type error") {
+		t.Errorf("Expected a runtime type error, got %v", err)
+	}
+}
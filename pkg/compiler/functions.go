@@ -0,0 +1,348 @@
+package compiler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+// functionCalls tracks the module functions called by the code being
+// compiled. Each one is compiled once, after the code that calls it.
+type functionCalls struct {
+	entries map[string]int   // Offset of each compiled function's OpEnter
+	sites   map[string][]int // Offsets of the OpCallFunc instructions calling each function
+	queue   []string         // Functions called but not compiled yet
+}
+
+func newFunctionCalls() *functionCalls {
+	return &functionCalls{entries: make(map[string]int), sites: make(map[string][]int)}
+}
+
+// SetFunctions makes the module's functions callable from the code this
+// compiler compiles. The bytecode of a route includes the functions it
+// calls, directly or through other functions.
+func (c *Compiler) SetFunctions(functions []*ast.Function) {
+	c.functions = make(map[string]*ast.Function, len(functions))
+	for _, fn := range functions {
+		c.functions[fn.Name] = fn
+	}
+	c.functionsHash = hashFunctions(functions)
+}
+
+// ModuleFunctions returns the functions declared in a module
+func ModuleFunctions(module *ast.Module) []*ast.Function {
+	var functions []*ast.Function
+	for _, item := range module.Items {
+		switch fn := item.(type) {
+		case *ast.Function:
+			functions = append(functions, fn)
+		case ast.Function:
+			functions = append(functions, &fn)
+		}
+	}
+	return functions
+}
+
+// AliasFunctions returns functions followed by a copy of each function
+// named in aliases under every alias of it, so that calls through an
+// import's alias or namespace compile like calls by the function's name
+func AliasFunctions(functions []*ast.Function, aliases map[string]string) []*ast.Function {
+	byName := make(map[string]*ast.Function, len(functions))
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+	names := make([]string, 0, len(aliases))
+	for alias := range aliases {
+		names = append(names, alias)
+	}
+	sort.Strings(names)
+
+	result := append([]*ast.Function(nil), functions...)
+	for _, alias := range names {
+		fn, ok := byName[aliases[alias]]
+		if !ok || byName[alias] != nil {
+			continue
+		}
+		aliased := *fn
+		aliased.Name = alias
+		result = append(result, &aliased)
+	}
+	return result
+}
+
+// hashFunctions returns a hash of the functions' ASTs, in name order, or ""
+// when there are none
+func hashFunctions(functions []*ast.Function) string {
+	if len(functions) == 0 {
+		return ""
+	}
+	sorted := append([]*ast.Function(nil), functions...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	var buf []byte
+	for _, fn := range sorted {
+		buf = appendValue(buf, reflect.ValueOf(fn))
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:])
+}
+
+// compileUserFunctionCall compiles a call to a module function: its
+// arguments in parameter order, with defaults or null for omitted optional
+// parameters, then OpCallFunc, whose target is patched once the function is
+// compiled
+func (c *Compiler) compileUserFunctionCall(fn *ast.Function, expr *ast.FunctionCallExpr) error {
+	if c.calls == nil {
+		return fmt.Errorf("calling function %s inside an async or background block is not supported by the compiler", fn.Name)
+	}
+	if len(fn.TypeParams) > 0 {
+		return fmt.Errorf("calling generic function %s is not supported by the compiler", fn.Name)
+	}
+	bound, err := fn.BindArgs(expr.Args, expr.NamedArgs)
+	if err != nil {
+		return err
+	}
+	if defaultsSeeParams(fn, bound) {
+		if err := c.compileArgsWithParams(fn, bound); err != nil {
+			return err
+		}
+	} else {
+		for idx, param := range fn.Params {
+			if err := c.compileArg(param, bound[idx]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, seen := c.calls.sites[fn.Name]; !seen {
+		c.calls.queue = append(c.calls.queue, fn.Name)
+	}
+	c.calls.sites[fn.Name] = append(c.calls.sites[fn.Name], len(c.code))
+	c.emitWithOperand(vm.OpCallFunc, 0)
+	return nil
+}
+
+// compileArg compiles the argument of param, or its default or null if arg
+// is nil
+func (c *Compiler) compileArg(param ast.Field, arg ast.Expr) error {
+	switch {
+	case arg != nil:
+		if err := c.compileExpression(arg); err != nil {
+			return fmt.Errorf("failed to compile function argument: %w", err)
+		}
+	case param.Default != nil:
+		if err := c.compileExpression(param.Default); err != nil {
+			return fmt.Errorf("failed to compile default for parameter %s: %w", param.Name, err)
+		}
+	default:
+		c.emitWithOperand(vm.OpPush, uint32(c.addConstant(vm.NullValue{})))
+	}
+	return nil
+}
+
+// defaultsSeeParams reports whether a call bound to bound uses the default
+// of a parameter that comes after another, which may refer to it
+func defaultsSeeParams(fn *ast.Function, bound []ast.Expr) bool {
+	for idx := 1; idx < len(fn.Params); idx++ {
+		if bound[idx] == nil && fn.Params[idx].Default != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// compileArgsWithParams compiles the arguments of a call whose defaults may
+// refer to earlier parameters, as the interpreter evaluates them in the
+// function's scope. Each value is stored in a temporary the parameter's name
+// resolves to while the later defaults compile, then all are pushed in
+// order. Arguments themselves compile in the caller's scope.
+func (c *Compiler) compileArgsWithParams(fn *ast.Function, bound []ast.Expr) error {
+	caller := c.symbolTable
+	params := caller.EnterScope(BlockScope)
+	defer func() { c.symbolTable = caller }()
+
+	temps := make([]int, len(fn.Params))
+	for idx, param := range fn.Params {
+		if bound[idx] != nil {
+			c.symbolTable = caller
+		} else {
+			c.symbolTable = params
+		}
+		if err := c.compileArg(param, bound[idx]); err != nil {
+			return err
+		}
+		temps[idx] = c.addConstant(vm.StringValue{Val: fmt.Sprintf("__arg_%d", c.labelCounter)})
+		c.labelCounter++
+		c.emitWithOperand(vm.OpStoreVar, uint32(temps[idx]))
+		params.Define(param.Name, temps[idx])
+	}
+	for _, temp := range temps {
+		c.emitWithOperand(vm.OpLoadVar, uint32(temp))
+	}
+	return nil
+}
+
+// compileCalledFunctions appends the functions called so far, and those
+// they call, to the code and points their calls at them
+func (c *Compiler) compileCalledFunctions() error {
+	if c.calls == nil {
+		return nil
+	}
+	for len(c.calls.queue) > 0 {
+		name := c.calls.queue[0]
+		c.calls.queue = c.calls.queue[1:]
+		if err := c.compileFunctionBody(c.functions[name]); err != nil {
+			return fmt.Errorf("function %s: %w", name, err)
+		}
+	}
+	for name, sites := range c.calls.sites {
+		for _, site := range sites {
+			c.patchJump(site, uint32(c.calls.entries[name]))
+		}
+	}
+	return nil
+}
+
+// compileFunctionBody compiles a function. It starts with its arguments on
+// the stack and runs with locals of its own, so it sees only its
+// parameters and the variables it declares. Arguments and results are
+// checked against the function's type annotations, as the interpreter
+// checks them.
+func (c *Compiler) compileFunctionBody(fn *ast.Function) error {
+	savedSymbols, savedLoops, savedFunction := c.symbolTable, c.loopStack, c.function
+	defer func() {
+		c.symbolTable, c.loopStack, c.function = savedSymbols, savedLoops, savedFunction
+	}()
+	c.symbolTable = NewGlobalSymbolTable().EnterScope(FunctionScope)
+	c.loopStack = nil
+	c.function = fn
+
+	c.calls.entries[fn.Name] = len(c.code)
+	c.emitWithOperand(vm.OpEnter, uint32(len(fn.Params)))
+	// The last argument is on top of the stack
+	for idx := len(fn.Params) - 1; idx >= 0; idx-- {
+		nameIdx := c.addConstant(vm.StringValue{Val: fn.Params[idx].Name})
+		c.symbolTable.Define(fn.Params[idx].Name, nameIdx)
+		c.emitWithOperand(vm.OpStoreVar, uint32(nameIdx))
+	}
+	// Check the arguments in order, storing back ints converted from floats
+	for idx, param := range fn.Params {
+		if param.TypeAnnotation == nil {
+			continue
+		}
+		nameIdx := c.addConstant(vm.StringValue{Val: param.Name})
+		flags := vm.TypeCheckArgument
+		if !param.Required {
+			flags |= vm.TypeCheckOptional
+		}
+		c.emitWithOperand(vm.OpLoadVar, uint32(nameIdx))
+		if err := c.emitTypeCheck(param.TypeAnnotation, fmt.Sprintf("argument %d (%s)", idx+1, param.Name), flags); err != nil {
+			return err
+		}
+		c.emitWithOperand(vm.OpStoreVar, uint32(nameIdx))
+	}
+
+	body := c.optimizeBody(fn.Body)
+	for _, stmt := range body {
+		if err := c.compileStatement(stmt); err != nil {
+			return err
+		}
+	}
+	// A function that ends without returning returns null
+	if !endsWithReturn(body) {
+		c.emitWithOperand(vm.OpPush, uint32(c.addConstant(vm.NullValue{})))
+		if err := c.emitFunctionReturn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emitFunctionReturn returns the value on top of the stack from the
+// function being compiled, checking it against its return type
+func (c *Compiler) emitFunctionReturn() error {
+	if c.function.ReturnType != nil {
+		label := fmt.Sprintf("return type mismatch in function %s", c.function.Name)
+		if err := c.emitTypeCheck(c.function.ReturnType, label, 0); err != nil {
+			return err
+		}
+	}
+	c.emit(vm.OpRet)
+	return nil
+}
+
+// emitTypeCheck checks the value on top of the stack against typ, failing
+// with label when it does not match
+func (c *Compiler) emitTypeCheck(typ ast.Type, label string, flags uint32) error {
+	source, ok := typeSource(typ)
+	if !ok {
+		return fmt.Errorf("checking values of type %s is not supported by the compiler", typeName(typ))
+	}
+	c.emitWithOperand(vm.OpPush, uint32(c.addConstant(vm.StringValue{Val: label})))
+	c.emitWithOperand(vm.OpPush, uint32(c.addConstant(vm.StringValue{Val: source})))
+	c.emitWithOperand(vm.OpCheckType, flags)
+	return nil
+}
+
+// typeSource returns typ as it is written in source, for vm.TypeChecker,
+// or false for types that cannot be checked outside the interpreter, such
+// as generic and function types
+func typeSource(typ ast.Type) (string, bool) {
+	switch t := typ.(type) {
+	case ast.IntType:
+		return "int", true
+	case ast.FloatType:
+		return "float", true
+	case ast.StringType:
+		return "str", true
+	case ast.BoolType:
+		return "bool", true
+	case ast.NamedType:
+		return t.Name, true
+	case ast.ArrayType:
+		if t.ElementType == nil {
+			return "", false
+		}
+		elem, ok := typeSource(t.ElementType)
+		return "[" + elem + "]", ok
+	case ast.OptionalType:
+		if _, isUnion := t.InnerType.(ast.UnionType); isUnion {
+			return "", false
+		}
+		inner, ok := typeSource(t.InnerType)
+		return inner + "?", ok
+	case ast.UnionType:
+		members := make([]string, len(t.Types))
+		for i, member := range t.Types {
+			if _, isUnion := member.(ast.UnionType); isUnion {
+				return "", false
+			}
+			source, ok := typeSource(member)
+			if !ok {
+				return "", false
+			}
+			members[i] = source
+		}
+		return strings.Join(members, " | "), len(members) > 0
+	}
+	return "", false
+}
+
+// typeName names a type in compiler errors
+func typeName(typ ast.Type) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", typ), "ast.")
+}
+
+// endsWithReturn reports whether the last statement of body is a return
+func endsWithReturn(body []ast.Statement) bool {
+	if len(body) == 0 {
+		return false
+	}
+	_, ok := normalizeStatement(body[len(body)-1]).(ast.ReturnStatement)
+	return ok
+}
@@ -0,0 +1,336 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/parser"
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+func parseModule(t *testing.T, code string) *ast.Module {
+	t.Helper()
+	tokens, err := parser.NewLexer(code).Tokenize()
+	if err != nil {
+		t.Fatalf("Tokenize failed: %v", err)
+	}
+	module, err := parser.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return module
+}
+
+func TestCompileRouteCallingFunctions(t *testing.T) {
+	module := parseModule(t, `! square(n: int): int {
+  > n * n
+}
+
+! sumOfSquares(a: int, b: int = 3): int {
+  $ total = square(a) + square(b)
+  > total
+}
+
+! factorial(n: int): int {
+  if n <= 1 {
+    > 1
+  }
+  > n * factorial(n - 1)
+}
+
+! noop() {
+  $ unused = 1
+}
+
+@ GET /calc {
+  $ n = 2
+  $ total = sumOfSquares(n)
+  > {total: total, fact: factorial(5), n: n, nothing: noop()}
+}`)
+	bytecode, err := NewCompiler().Compile(module)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := vm.NewVM().Execute(bytecode)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	obj, ok := result.(vm.ObjectValue)
+	if !ok {
+		t.Fatalf("Expected an object, got %#v", result)
+	}
+	want := map[string]vm.Value{
+		"total":   vm.IntValue{Val: 13},
+		"fact":    vm.IntValue{Val: 120},
+		"n":       vm.IntValue{Val: 2}, // Functions do not clobber the caller's locals
+		"nothing": vm.NullValue{},
+	}
+	for key, val := range want {
+		if obj.Val[key] != val {
+			t.Errorf("%s: expected %#v, got %#v", key, val, obj.Val[key])
+		}
+	}
+}
+
+func TestCompileAliasedFunctionCalls(t *testing.T) {
+	module := parseModule(t, `! square(n: int): int {
+  > n * n
+}
+
+@ GET /calc {
+  > {namespaced: models.square(3), aliased: sq(4)}
+}`)
+	c := NewCompiler()
+	c.SetFunctions(AliasFunctions(ModuleFunctions(module), map[string]string{
+		"models.square": "square",
+		"sq":            "square",
+		"missing":       "nope",
+	}))
+	bytecode, err := c.Compile(module)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := vm.NewVM().Execute(bytecode)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	obj, ok := result.(vm.ObjectValue)
+	if !ok {
+		t.Fatalf("Expected an object, got %#v", result)
+	}
+	if got := obj.Val["namespaced"]; got != (vm.IntValue{Val: 9}) {
+		t.Errorf("namespaced: expected 9, got %#v", got)
+	}
+	if got := obj.Val["aliased"]; got != (vm.IntValue{Val: 16}) {
+		t.Errorf("aliased: expected 16, got %#v", got)
+	}
+}
+
+func TestCompileNamedArguments(t *testing.T) {
+	module := parseModule(t, `! label(name: str!, greeting: str = "Hello", suffix: str = greeting + "!"): str {
+  > greeting + ", " + name + suffix
+}
+
+@ GET /labels {
+  $ name = "caller"
+  > {
+    positional: label("Ann"),
+    named: label(greeting: "Hi", name: "Bob"),
+    mixed: label("Cy", suffix: "?"),
+    shadowed: label(name: name)
+  }
+}`)
+	bytecode, err := NewCompiler().Compile(module)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	result, err := vm.NewVM().Execute(bytecode)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	obj, ok := result.(vm.ObjectValue)
+	if !ok {
+		t.Fatalf("Expected an object, got %#v", result)
+	}
+	want := map[string]vm.Value{
+		"positional": vm.StringValue{Val: "Hello, AnnHello!"},
+		"named":      vm.StringValue{Val: "Hi, BobHi!"},
+		"mixed":      vm.StringValue{Val: "Hello, Cy?"},
+		"shadowed":   vm.StringValue{Val: "Hello, callerHello!"},
+	}
+	for key, val := range want {
+		if obj.Val[key] != val {
+			t.Errorf("%s: expected %#v, got %#v", key, val, obj.Val[key])
+		}
+	}
+}
+
+func TestCompileFunctionCallErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"too many arguments", `! one(a: int): int {
+  > a
+}
+@ GET /x {
+  > one(1, 2)
+}`, "expects at most 1 arguments"},
+		{"missing argument", `! one(a: int!): int {
+  > a
+}
+@ GET /x {
+  > one()
+}`, "missing required argument a"},
+		{"missing named argument", `! two(a: int!, b: int!): int {
+  > a + b
+}
+@ GET /x {
+  > two(b: 1)
+}`, "missing required argument a in function two"},
+		{"unknown named argument", `! one(a: int!): int {
+  > a
+}
+@ GET /x {
+  > one(b: 1)
+}`, "function one has no parameter b"},
+		{"argument given twice", `! one(a: int!): int {
+  > a
+}
+@ GET /x {
+  > one(1, a: 2)
+}`, "argument a of function one is given more than once"},
+		{"route variable", `! leak(): int {
+  > secret
+}
+@ GET /x {
+  $ secret = 1
+  > leak()
+}`, "undefined variable: secret"},
+		{"async block", `! one(): int {
+  > 1
+}
+@ GET /x {
+  $ f = async {
+    > one()
+  }
+  > await f
+}`, "async or background block"},
+		{"generic function", `! first<T>(items: [T]): T {
+  > items[0]
+}
+@ GET /x {
+  > first([1])
+}`, "generic function first"},
+		{"function type", `! apply(f: (int) -> int, n: int): int {
+  > n
+}
+@ GET /x {
+  > apply(null, 1)
+}`, "checking values of type FunctionType"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCompiler().Compile(parseModule(t, tt.code))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// recordingTypes is a vm.TypeChecker that records the checks it is asked
+// to make and fails those of the type named fail
+type recordingTypes struct {
+	checks []string
+	fail   string
+}
+
+func (r *recordingTypes) CheckType(value interface{}, typ string) error {
+	r.checks = append(r.checks, fmt.Sprintf("%v %s", value, typ))
+	if typ == r.fail {
+		return fmt.Errorf("expected %s", typ)
+	}
+	return nil
+}
+
+func TestCompileFunctionTypeChecks(t *testing.T) {
+	module := parseModule(t, `! scale(n: int, factor: float?, tags: [str] | str): float {
+  if factor == null {
+    > n
+  }
+  > n * factor
+}
+
+@ GET /x {
+  > scale(input.n, null, ["a"])
+}`)
+	bytecode, err := NewCompiler().Compile(module)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	types := &recordingTypes{}
+	machine := vm.NewVM()
+	machine.SetTypeChecker(types)
+	machine.SetLocal("input", vm.ObjectValue{Val: map[string]vm.Value{"n": vm.FloatValue{Val: 4}}})
+	result, err := machine.Execute(bytecode)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	// The whole float argument is converted for the int parameter, and
+	// the omitted optional one is not checked
+	if result != (vm.IntValue{Val: 4}) {
+		t.Errorf("Expected 4, got %#v", result)
+	}
+	want := []string{"4 int", "[a] [str] | str", "4 float"}
+	if strings.Join(types.checks, ", ") != strings.Join(want, ", ") {
+		t.Errorf("Expected checks %q, got %q", want, types.checks)
+	}
+
+	machine = vm.NewVM()
+	machine.SetTypeChecker(&recordingTypes{fail: "int"})
+	machine.SetLocal("input", vm.ObjectValue{Val: map[string]vm.Value{"n": vm.StringValue{Val: "4"}}})
+	_, err = machine.Execute(bytecode)
+	if err == nil || !strings.Contains(err.Error(), "argument 1 (n): expected int") {
+		t.Errorf("Expected an argument type error, got %v", err)
+	}
+}
+
+func TestCompileRouteRunawayRecursion(t *testing.T) {
+	module := parseModule(t, `! forever(n: int): int {
+  > forever(n + 1)
+}
+
+@ GET /x {
+  > forever(0)
+}`)
+	bytecode, err := NewCompiler().Compile(module)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	_, err = vm.NewVM().Execute(bytecode)
+	if err == nil || !strings.Contains(err.Error(), "maximum call depth") {
+		t.Errorf("Expected a call depth error, got %v", err)
+	}
+}
+
+func TestCompileRouteCachedFunctionsInKey(t *testing.T) {
+	code := `! greeting(): string {
+  > "%s"
+}
+
+@ GET /x {
+  > greeting()
+}`
+	rc := NewRouteCache(t.TempDir(), 10)
+	run := func(word string) vm.Value {
+		module := parseModule(t, strings.Replace(code, "%s", word, 1))
+		c := NewCompiler()
+		c.SetCache(rc)
+		c.SetFunctions(ModuleFunctions(module))
+		route := module.Items[1].(*ast.Route)
+		bytecode, err := c.CompileRouteCached(route, HashRoute(route))
+		if err != nil {
+			t.Fatalf("Compile failed: %v", err)
+		}
+		result, err := vm.NewVM().Execute(bytecode)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		return result
+	}
+	if got := run("hello"); got != (vm.StringValue{Val: "hello"}) {
+		t.Errorf("Expected hello, got %#v", got)
+	}
+	// Changing only the function must not reuse the cached bytecode
+	if got := run("goodbye"); got != (vm.StringValue{Val: "goodbye"}) {
+		t.Errorf("Expected goodbye, got %#v", got)
+	}
+}
@@ -0,0 +1,21 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+func TestInterpolationCompilation(t *testing.T) {
+	result, err := compileAndRun(t, `@ GET /test {
+  $ order = {customer: {name: "Ada"}, total: 9.5, items: 3}
+  > "\{order} {order.customer.name} bought {order.items + 1} items for {order.total}"
+}`)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	want := vm.StringValue{Val: "{order} Ada bought 4 items for 9.5"}
+	if result != want {
+		t.Errorf("got %#v, want %#v", result, want)
+	}
+}
@@ -140,9 +140,32 @@ func (e *MacroExpander) expandItem(item ast.Item) (ast.Item, error) {
 			RateLimit:   it.RateLimit,
 			Injections:  it.Injections,
 			QueryParams: it.QueryParams,
+			Middlewares: it.Middlewares,
 			Body:        expandedBody,
 		}, nil
 
+	case *ast.MiddlewareDef:
+		expandedBody, err := e.expandStatements(it.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.MiddlewareDef{
+			Name: it.Name,
+			Body: expandedBody,
+			Pos:  it.Pos,
+		}, nil
+
+	case *ast.ErrorHandler:
+		expandedBody, err := e.expandStatements(it.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ErrorHandler{
+			Kind: it.Kind,
+			Body: expandedBody,
+			Pos:  it.Pos,
+		}, nil
+
 	case *ast.Command:
 		expandedBody, err := e.expandStatements(it.Body)
 		if err != nil {
@@ -256,6 +279,13 @@ func (e *MacroExpander) expandStatement(stmt ast.Statement) (ast.Statement, erro
 			Body:      body,
 		}, nil
 
+	case ast.BackgroundStatement:
+		body, err := e.expandStatements(s.Body)
+		if err != nil {
+			return nil, err
+		}
+		return ast.BackgroundStatement{Body: body, Pos: s.Pos}, nil
+
 	case ast.ForStatement:
 		body, err := e.expandStatements(s.Body)
 		if err != nil {
@@ -324,6 +354,8 @@ func (e *MacroExpander) substituteNode(node ast.Node, subs map[string]ast.Expr)
 		return ast.AssignStatement{
 			Target: e.substituteString(n.Target, subs),
 			Value:  subExpr,
+			Const:  n.Const,
+			Type:   n.Type,
 		}, nil
 
 	case ast.ReassignStatement:
@@ -376,6 +408,13 @@ func (e *MacroExpander) substituteNode(node ast.Node, subs map[string]ast.Expr)
 			Body:      body,
 		}, nil
 
+	case ast.BackgroundStatement:
+		body, err := e.substituteStatements(n.Body, subs)
+		if err != nil {
+			return nil, err
+		}
+		return ast.BackgroundStatement{Body: body, Pos: n.Pos}, nil
+
 	case ast.ForStatement:
 		iter, err := e.substituteExpr(n.Iterable, subs)
 		if err != nil {
@@ -467,6 +506,40 @@ func (e *MacroExpander) substituteExpr(expr ast.Expr, subs map[string]ast.Expr)
 			Right: right,
 		}, nil
 
+	case ast.TernaryExpr:
+		cond, err := e.substituteExpr(ex.Condition, subs)
+		if err != nil {
+			return nil, err
+		}
+		thenExpr, err := e.substituteExpr(ex.Then, subs)
+		if err != nil {
+			return nil, err
+		}
+		elseExpr, err := e.substituteExpr(ex.Else, subs)
+		if err != nil {
+			return nil, err
+		}
+		return ast.TernaryExpr{
+			Condition: cond,
+			Then:      thenExpr,
+			Else:      elseExpr,
+			Pos:       ex.Pos,
+		}, nil
+
+	case ast.InterpolatedStringExpr:
+		parts := make([]ast.Expr, len(ex.Parts))
+		for i, part := range ex.Parts {
+			subPart, err := e.substituteExpr(part, subs)
+			if err != nil {
+				return nil, err
+			}
+			parts[i] = subPart
+		}
+		return ast.InterpolatedStringExpr{
+			Parts: parts,
+			Pos:   ex.Pos,
+		}, nil
+
 	case ast.FunctionCallExpr:
 		subArgs := make([]ast.Expr, len(ex.Args))
 		for i, arg := range ex.Args {
@@ -476,9 +549,18 @@ func (e *MacroExpander) substituteExpr(expr ast.Expr, subs map[string]ast.Expr)
 			}
 			subArgs[i] = subArg
 		}
+		var subNamed []ast.NamedArg
+		for _, arg := range ex.NamedArgs {
+			value, err := e.substituteExpr(arg.Value, subs)
+			if err != nil {
+				return nil, err
+			}
+			subNamed = append(subNamed, ast.NamedArg{Name: arg.Name, Value: value, Pos: arg.Pos})
+		}
 		return ast.FunctionCallExpr{
-			Name: ex.Name,
-			Args: subArgs,
+			Name:      ex.Name,
+			Args:      subArgs,
+			NamedArgs: subNamed,
 		}, nil
 
 	case ast.FieldAccessExpr:
@@ -487,8 +569,9 @@ func (e *MacroExpander) substituteExpr(expr ast.Expr, subs map[string]ast.Expr)
 			return nil, err
 		}
 		return ast.FieldAccessExpr{
-			Object: obj,
-			Field:  ex.Field,
+			Object:   obj,
+			Field:    ex.Field,
+			Optional: ex.Optional,
 		}, nil
 
 	case ast.ArrayIndexExpr:
@@ -530,6 +613,16 @@ func (e *MacroExpander) substituteExpr(expr ast.Expr, subs map[string]ast.Expr)
 		}
 		return ast.ArrayExpr{Elements: subElems}, nil
 
+	case ast.SpreadExpr:
+		value, err := e.substituteExpr(ex.Value, subs)
+		if err != nil {
+			return nil, err
+		}
+		return ast.SpreadExpr{
+			Value: value,
+			Pos:   ex.Pos,
+		}, nil
+
 	case ast.LiteralExpr:
 		// Check if string literal contains parameter references for string interpolation
 		if strLit, ok := ex.Value.(ast.StringLiteral); ok {
@@ -0,0 +1,56 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+func TestMathBuiltinsCompiled(t *testing.T) {
+	result, err := compileAndRun(t, `@ GET /test {
+  $ price = 19.99
+  > {
+    floor: math.floor(price),
+    ceil: math.ceil(price),
+    round: math.round(2.5),
+    abs: math.abs(0 - 4),
+    min: math.min(3, 7),
+    max: math.max(3, 7.5),
+    pow: math.pow(2, 8),
+    sqrt: math.sqrt(81)
+  }
+}`)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	obj, ok := result.(vm.ObjectValue)
+	if !ok {
+		t.Fatalf("Expected ObjectValue, got %T", result)
+	}
+
+	want := map[string]vm.Value{
+		"floor": vm.FloatValue{Val: 19},
+		"ceil":  vm.FloatValue{Val: 20},
+		"round": vm.FloatValue{Val: 3},
+		"abs":   vm.IntValue{Val: 4},
+		"min":   vm.IntValue{Val: 3},
+		"max":   vm.FloatValue{Val: 7.5},
+		"pow":   vm.FloatValue{Val: 256},
+		"sqrt":  vm.FloatValue{Val: 9},
+	}
+	for key, w := range want {
+		if got := obj.Val[key]; got != w {
+			t.Errorf("%s = %#v, want %#v", key, got, w)
+		}
+	}
+}
+
+func TestMathBuiltinsCompiled_TypeError(t *testing.T) {
+	_, err := compileAndRun(t, `@ GET /test {
+  > math.round("2.5")
+}`)
+	if err == nil || !strings.Contains(err.Error(), "math.round() expects a numeric argument, got string") {
+		t.Errorf("expected a numeric argument error, got %v", err)
+	}
+}
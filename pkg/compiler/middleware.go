@@ -0,0 +1,67 @@
+package compiler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+)
+
+// SetMiddlewares makes the module's named middleware available to the
+// routes this compiler compiles. The bodies of the middleware a route
+// applies are compiled inline ahead of its own body.
+func (c *Compiler) SetMiddlewares(middlewares []*ast.MiddlewareDef) {
+	c.middlewares = make(map[string]*ast.MiddlewareDef, len(middlewares))
+	for _, mw := range middlewares {
+		c.middlewares[mw.Name] = mw
+	}
+	c.middlewaresHash = hashMiddlewares(middlewares)
+}
+
+// ModuleMiddlewares returns the named middleware defined in a module
+func ModuleMiddlewares(module *ast.Module) []*ast.MiddlewareDef {
+	var middlewares []*ast.MiddlewareDef
+	for _, item := range module.Items {
+		if mw, ok := item.(*ast.MiddlewareDef); ok {
+			middlewares = append(middlewares, mw)
+		}
+	}
+	return middlewares
+}
+
+// hashMiddlewares returns a hash of the middleware ASTs, in name order, or
+// "" when there are none
+func hashMiddlewares(middlewares []*ast.MiddlewareDef) string {
+	if len(middlewares) == 0 {
+		return ""
+	}
+	sorted := append([]*ast.MiddlewareDef(nil), middlewares...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	var buf []byte
+	for _, mw := range sorted {
+		buf = appendValue(buf, reflect.ValueOf(mw))
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:])
+}
+
+// routeBody returns the bodies of the route's named middleware, in the
+// order they are applied, followed by the route's own body. A return in a
+// middleware therefore answers the request without running the rest.
+func (c *Compiler) routeBody(route *ast.Route) ([]ast.Statement, error) {
+	if len(route.Middlewares) == 0 {
+		return route.Body, nil
+	}
+	var body []ast.Statement
+	for _, ref := range route.Middlewares {
+		mw, ok := c.middlewares[ref.Name]
+		if !ok {
+			return nil, &SemanticError{Message: fmt.Sprintf("undefined middleware '%s'", ref.Name)}
+		}
+		body = append(body, mw.Body...)
+	}
+	return append(body, route.Body...), nil
+}
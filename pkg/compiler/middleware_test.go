@@ -0,0 +1,104 @@
+package compiler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+const middlewareCode = `@ middleware requireKey {
+  if headers.key != "secret" {
+    error(401, "missing key")
+  }
+  $ trail = "key"
+}
+
+@ middleware tag {
+  trail = trail + ",tag"
+}
+
+@ GET /x {
+  + requireKey
+  + tag
+  > trail + ",route"
+}`
+
+func runMiddlewareRoute(t *testing.T, key string) (vm.Value, error) {
+	t.Helper()
+	bytecode, err := NewCompiler().Compile(parseModule(t, middlewareCode))
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	machine := vm.NewVM()
+	machine.SetLocal("headers", vm.ObjectValue{Val: map[string]vm.Value{"key": vm.StringValue{Val: key}}})
+	return machine.Execute(bytecode)
+}
+
+func TestCompileRouteMiddlewarePassThrough(t *testing.T) {
+	result, err := runMiddlewareRoute(t, "secret")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result != (vm.StringValue{Val: "key,tag,route"}) {
+		t.Errorf("Expected the middleware to run in order before the route, got %#v", result)
+	}
+}
+
+func TestCompileRouteMiddlewareShortCircuit(t *testing.T) {
+	_, err := runMiddlewareRoute(t, "wrong")
+	var errorResp *vm.ErrorResponse
+	if !errors.As(err, &errorResp) {
+		t.Fatalf("Expected an error response, got %v", err)
+	}
+	if errorResp.StatusCode != 401 || errorResp.Message != "missing key" {
+		t.Errorf("Expected 401 missing key, got %d %s", errorResp.StatusCode, errorResp.Message)
+	}
+}
+
+func TestCompileRouteUndefinedMiddleware(t *testing.T) {
+	_, err := NewCompiler().Compile(parseModule(t, `@ GET /x {
+  + missing
+  > 1
+}`))
+	if !IsSemanticError(err) || !strings.Contains(err.Error(), "undefined middleware 'missing'") {
+		t.Errorf("Expected an undefined middleware semantic error, got %v", err)
+	}
+}
+
+func TestCompileRouteCachedMiddlewaresInKey(t *testing.T) {
+	code := `@ middleware greet {
+  > "%s"
+}
+
+@ GET /x {
+  + greet
+  > "route"
+}`
+	rc := NewRouteCache(t.TempDir(), 10)
+	run := func(word string) vm.Value {
+		module := parseModule(t, strings.Replace(code, "%s", word, 1))
+		c := NewCompiler()
+		c.SetCache(rc)
+		c.SetMiddlewares(ModuleMiddlewares(module))
+		route := module.Items[1].(*ast.Route)
+		bytecode, err := c.CompileRouteCached(route, HashRoute(route))
+		if err != nil {
+			t.Fatalf("Compile failed: %v", err)
+		}
+		result, err := vm.NewVM().Execute(bytecode)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		return result
+	}
+	if got := run("hello"); got != (vm.StringValue{Val: "hello"}) {
+		t.Errorf("Expected hello, got %#v", got)
+	}
+	// Changing only the middleware must not reuse the cached bytecode
+	if got := run("goodbye"); got != (vm.StringValue{Val: "goodbye"}) {
+		t.Errorf("Expected goodbye, got %#v", got)
+	}
+}
@@ -94,9 +94,12 @@ func (o *Optimizer) OptimizeExpression(expr ast.Expr) ast.Expr {
 	case *ast.FieldAccessExpr:
 		// Optimize the object expression
 		return &ast.FieldAccessExpr{
-			Object: o.OptimizeExpression(e.Object),
-			Field:  e.Field,
+			Object:   o.OptimizeExpression(e.Object),
+			Field:    e.Field,
+			Optional: e.Optional,
 		}
+	case ast.SpreadExpr:
+		return ast.SpreadExpr{Value: o.OptimizeExpression(e.Value), Pos: e.Pos}
 	default:
 		return expr
 	}
@@ -160,6 +163,8 @@ func (o *Optimizer) OptimizeStatements(stmts []ast.Statement) []ast.Statement {
 			optimized := &ast.AssignStatement{
 				Target: s.Target,
 				Value:  optimizedValue,
+				Const:  s.Const,
+				Type:   s.Type,
 			}
 			result = append(result, optimized)
 
@@ -890,6 +895,11 @@ func getUsedVariablesInExpr(expr ast.Expr, used map[string]bool) {
 		for _, arg := range e.Args {
 			getUsedVariablesInExpr(arg, used)
 		}
+		for _, arg := range e.NamedArgs {
+			getUsedVariablesInExpr(arg.Value, used)
+		}
+	case ast.SpreadExpr:
+		getUsedVariablesInExpr(e.Value, used)
 	}
 }
 
@@ -1012,6 +1022,8 @@ func exprHasSideEffects(expr ast.Expr) bool {
 			}
 		}
 		return false
+	case ast.SpreadExpr:
+		return exprHasSideEffects(e.Value)
 	default:
 		return false
 	}
@@ -1124,7 +1136,7 @@ func (fi *FunctionInliner) ShouldInline(name string) bool {
 // InlineCall inlines a function call
 func (fi *FunctionInliner) InlineCall(call *ast.FunctionCallExpr) []ast.Statement {
 	candidate, ok := fi.candidates[call.Name]
-	if !ok {
+	if !ok || len(call.NamedArgs) > 0 {
 		return nil
 	}
 
@@ -1232,6 +1244,11 @@ func containsCallInExpr(expr ast.Expr, fnName string) bool {
 				return true
 			}
 		}
+		for _, arg := range e.NamedArgs {
+			if containsCallInExpr(arg.Value, fnName) {
+				return true
+			}
+		}
 	case ast.FunctionCallExpr:
 		if e.Name == fnName {
 			return true
@@ -1241,6 +1258,11 @@ func containsCallInExpr(expr ast.Expr, fnName string) bool {
 				return true
 			}
 		}
+		for _, arg := range e.NamedArgs {
+			if containsCallInExpr(arg.Value, fnName) {
+				return true
+			}
+		}
 	case *ast.BinaryOpExpr:
 		return containsCallInExpr(e.Left, fnName) || containsCallInExpr(e.Right, fnName)
 	case ast.BinaryOpExpr:
@@ -1273,6 +1295,8 @@ func containsCallInExpr(expr ast.Expr, fnName string) bool {
 		return containsCallInExpr(e.Object, fnName)
 	case ast.FieldAccessExpr:
 		return containsCallInExpr(e.Object, fnName)
+	case ast.SpreadExpr:
+		return containsCallInExpr(e.Value, fnName)
 	}
 	return false
 }
@@ -1293,11 +1317,15 @@ func substituteParamsInStmt(stmt ast.Statement, bindings map[string]ast.Expr) as
 		return &ast.AssignStatement{
 			Target: s.Target,
 			Value:  substituteParamsInExpr(s.Value, bindings),
+			Const:  s.Const,
+			Type:   s.Type,
 		}
 	case ast.AssignStatement:
 		return &ast.AssignStatement{
 			Target: s.Target,
 			Value:  substituteParamsInExpr(s.Value, bindings),
+			Const:  s.Const,
+			Type:   s.Type,
 		}
 	case *ast.ReassignStatement:
 		return &ast.ReassignStatement{
@@ -1401,31 +1429,48 @@ func substituteParamsInExpr(expr ast.Expr, bindings map[string]ast.Expr) ast.Exp
 		return &ast.ArrayExpr{Elements: elements}
 	case *ast.FieldAccessExpr:
 		return &ast.FieldAccessExpr{
-			Object: substituteParamsInExpr(e.Object, bindings),
-			Field:  e.Field,
+			Object:   substituteParamsInExpr(e.Object, bindings),
+			Field:    e.Field,
+			Optional: e.Optional,
 		}
 	case ast.FieldAccessExpr:
 		return &ast.FieldAccessExpr{
-			Object: substituteParamsInExpr(e.Object, bindings),
-			Field:  e.Field,
+			Object:   substituteParamsInExpr(e.Object, bindings),
+			Field:    e.Field,
+			Optional: e.Optional,
 		}
 	case *ast.FunctionCallExpr:
 		args := make([]ast.Expr, len(e.Args))
 		for i, arg := range e.Args {
 			args[i] = substituteParamsInExpr(arg, bindings)
 		}
-		return &ast.FunctionCallExpr{Name: e.Name, Args: args}
+		return &ast.FunctionCallExpr{Name: e.Name, Args: args, NamedArgs: substituteParamsInNamedArgs(e.NamedArgs, bindings)}
 	case ast.FunctionCallExpr:
 		args := make([]ast.Expr, len(e.Args))
 		for i, arg := range e.Args {
 			args[i] = substituteParamsInExpr(arg, bindings)
 		}
-		return &ast.FunctionCallExpr{Name: e.Name, Args: args}
+		return &ast.FunctionCallExpr{Name: e.Name, Args: args, NamedArgs: substituteParamsInNamedArgs(e.NamedArgs, bindings)}
+	case ast.SpreadExpr:
+		return ast.SpreadExpr{Value: substituteParamsInExpr(e.Value, bindings), Pos: e.Pos}
 	default:
 		return expr
 	}
 }
 
+// substituteParamsInNamedArgs substitutes parameters in the values of named
+// arguments
+func substituteParamsInNamedArgs(args []ast.NamedArg, bindings map[string]ast.Expr) []ast.NamedArg {
+	if len(args) == 0 {
+		return nil
+	}
+	result := make([]ast.NamedArg, len(args))
+	for i, arg := range args {
+		result[i] = ast.NamedArg{Name: arg.Name, Value: substituteParamsInExpr(arg.Value, bindings), Pos: arg.Pos}
+	}
+	return result
+}
+
 // ========================================
 // Advanced Optimization: Strength Reduction
 // ========================================
@@ -0,0 +1,113 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/parser"
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+func compileAndRun(t *testing.T, code string) (vm.Value, error) {
+	t.Helper()
+	tokens, err := parser.NewLexer(code).Tokenize()
+	if err != nil {
+		t.Fatalf("Tokenize failed: %v", err)
+	}
+	module, err := parser.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	bytecode, err := NewCompiler().Compile(module)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	return vm.NewVM().Execute(bytecode)
+}
+
+func TestCoalesceAndOptionalChainingCompilation(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want vm.Value
+	}{
+		{
+			name: "chaining through a missing field",
+			code: `@ GET /test {
+  $ user = {name: "ada"}
+  > user?.profile?.bio
+}`,
+			want: vm.NullValue{},
+		},
+		{
+			name: "chaining through a null value",
+			code: `@ GET /test {
+  $ user = null
+  > user?.profile?.bio
+}`,
+			want: vm.NullValue{},
+		},
+		{
+			name: "chaining through present fields",
+			code: `@ GET /test {
+  $ user = {profile: {bio: "hi"}}
+  > user?.profile?.bio
+}`,
+			want: vm.StringValue{Val: "hi"},
+		},
+		{
+			name: "coalescing a null to a default",
+			code: `@ GET /test {
+  $ user = {name: "ada"}
+  > user?.profile?.bio ?? "no bio"
+}`,
+			want: vm.StringValue{Val: "no bio"},
+		},
+		{
+			name: "coalescing keeps a non-null value",
+			code: `@ GET /test {
+  $ count = 0
+  > count ?? 10
+}`,
+			want: vm.IntValue{Val: 0},
+		},
+		{
+			name: "right side is not evaluated for a non-null value",
+			code: `@ GET /test {
+  > "set" ?? missingFunction()
+}`,
+			want: vm.StringValue{Val: "set"},
+		},
+		{
+			name: "coalescing chains",
+			code: `@ GET /test {
+  $ a = null
+  $ b = null
+  > a ?? b ?? 3
+}`,
+			want: vm.IntValue{Val: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := compileAndRun(t, tt.code)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Expected %#v, got %#v", tt.want, result)
+			}
+		})
+	}
+}
+
+func TestFieldAccessOnNullStillFails(t *testing.T) {
+	_, err := compileAndRun(t, `@ GET /test {
+  $ user = null
+  > user.profile
+}`)
+	if err == nil || !strings.Contains(err.Error(), "cannot access field profile on null") {
+		t.Fatalf("Expected a field access error, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package compiler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+func TestArraySpreadCompilation(t *testing.T) {
+	result, err := compileAndRun(t, `@ GET /test {
+  $ keys = [1]
+  $ more = [3, 4]
+  > [...keys, 2, ...more, ...more]
+}`)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	want := vm.ArrayValue{Val: []vm.Value{
+		vm.IntValue{Val: 1}, vm.IntValue{Val: 2},
+		vm.IntValue{Val: 3}, vm.IntValue{Val: 4},
+		vm.IntValue{Val: 3}, vm.IntValue{Val: 4},
+	}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got %#v, want %#v", result, want)
+	}
+}
+
+func TestObjectSpreadCompilation(t *testing.T) {
+	result, err := compileAndRun(t, `@ GET /test {
+  $ base = {name: "base", role: "user", active: true}
+  $ extra = {role: "admin"}
+  > {name: "first", ...base, ...extra, active: false}
+}`)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	want := vm.ObjectValue{Val: map[string]vm.Value{
+		"name":   vm.StringValue{Val: "base"},
+		"role":   vm.StringValue{Val: "admin"},
+		"active": vm.BoolValue{Val: false},
+	}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got %#v, want %#v", result, want)
+	}
+}
+
+func TestSpreadWrongTypeCompilation(t *testing.T) {
+	_, err := compileAndRun(t, `@ GET /test {
+  $ name = "Ada"
+  > {...name}
+}`)
+	if err == nil || !strings.Contains(err.Error(), "cannot spread string into object") {
+		t.Errorf("expected a spread type error, got %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package compiler
+
+import (
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+// CompileStats describes the bytecode produced for a route, for tooling
+// such as CI size checks and editor hovers
+type CompileStats struct {
+	BytecodeSize int            `json:"bytecodeSize"` // Total size in bytes, header included
+	Instructions int            `json:"instructions"` // Number of instructions, including async and background bodies
+	Constants    int            `json:"constants"`    // Size of the constant pool
+	Opcodes      map[string]int `json:"opcodes"`      // Number of instructions per opcode mnemonic
+}
+
+// CompileRouteWithStats compiles a route like CompileRoute and also returns
+// statistics about the bytecode
+func (c *Compiler) CompileRouteWithStats(route *ast.Route) ([]byte, CompileStats, error) {
+	bytecode, err := c.CompileRoute(route)
+	if err != nil {
+		return nil, CompileStats{}, err
+	}
+	return bytecode, c.stats(bytecode), nil
+}
+
+// stats counts the instructions of the code just compiled into bytecode
+func (c *Compiler) stats(bytecode []byte) CompileStats {
+	stats := CompileStats{
+		BytecodeSize: len(bytecode),
+		Constants:    len(c.constants),
+		Opcodes:      make(map[string]int),
+	}
+	for i := 0; i < len(c.code); i++ {
+		op := c.code[i]
+		stats.Instructions++
+		stats.Opcodes[vm.Opcode(op).String()]++
+		// Async and background bodies follow their instruction inline, so
+		// skipping just the operand counts their instructions too
+		if hasOperand(op) {
+			i += 4
+		}
+	}
+	return stats
+}
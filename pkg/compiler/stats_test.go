@@ -0,0 +1,63 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/decompiler"
+)
+
+func TestCompileRouteWithStats(t *testing.T) {
+	routes := parseRoutes(t, `@ GET /orders/:id {
+  $ total = 0
+  for item in [1, 2, 3] {
+    total = total + item
+  }
+  if total > 5 {
+    > {id: id, total: total, big: true}
+  }
+  > {id: id, total: total}
+}`)
+	bytecode, stats, err := NewCompiler().CompileRouteWithStats(routes[0])
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	if stats.BytecodeSize != len(bytecode) {
+		t.Errorf("Expected BytecodeSize %d, got %d", len(bytecode), stats.BytecodeSize)
+	}
+
+	// The counts agree with the disassembly
+	out, err := decompiler.NewDecompiler().Decompile(bytecode)
+	if err != nil {
+		t.Fatalf("Decompile failed: %v", err)
+	}
+	if stats.Instructions != len(out.Instructions) {
+		t.Errorf("Expected %d instructions, got %d", len(out.Instructions), stats.Instructions)
+	}
+	if stats.Constants != len(out.Constants) {
+		t.Errorf("Expected %d constants, got %d", len(out.Constants), stats.Constants)
+	}
+	want := make(map[string]int)
+	for _, in := range out.Instructions {
+		want[in.Opcode]++
+	}
+	if len(stats.Opcodes) != len(want) {
+		t.Errorf("Expected opcodes %v, got %v", want, stats.Opcodes)
+	}
+	for op, n := range want {
+		if stats.Opcodes[op] != n {
+			t.Errorf("Expected %d %s instructions, got %d", n, op, stats.Opcodes[op])
+		}
+	}
+	for _, op := range []string{"STORE_VAR", "ADD", "GET_ITER", "JUMP_IF_FALSE", "BUILD_OBJECT", "RETURN"} {
+		if stats.Opcodes[op] == 0 {
+			t.Errorf("Expected %s in the histogram: %v", op, stats.Opcodes)
+		}
+	}
+
+	// A failed compile returns no stats
+	routes = parseRoutes(t, "@ GET /bad {\n  $ x = 1\n  $ x = 2\n}")
+	if _, stats, err := NewCompiler().CompileRouteWithStats(routes[0]); err == nil || stats.Instructions != 0 {
+		t.Errorf("Expected an error and empty stats, got %v, %+v", err, stats)
+	}
+}
@@ -34,6 +34,7 @@ type Symbol struct {
 	ConstantIdx int  // Index of the constant if it's a constant value
 	IsConstant  bool // Whether this is a compile-time constant
 	IsBuiltin   bool // Whether this is a built-in variable (query, input, ws, auth)
+	ReadOnly    bool // Whether this was declared with const and cannot be reassigned
 	Source      SymbolSource
 }
 
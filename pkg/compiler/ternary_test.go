@@ -0,0 +1,53 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+func TestTernaryCompilation(t *testing.T) {
+	result, err := compileAndRun(t, `@ GET /test {
+  $ active = true
+  $ count = 0
+  > {status: active ? "on" : "off", label: count > 0 ? "some" : count == 0 ? "none" : "negative"}
+}`)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	obj, ok := result.(vm.ObjectValue)
+	if !ok {
+		t.Fatalf("Expected ObjectValue, got %T", result)
+	}
+	if got := obj.Val["status"]; got != (vm.StringValue{Val: "on"}) {
+		t.Errorf("status = %v, want on", got)
+	}
+	if got := obj.Val["label"]; got != (vm.StringValue{Val: "none"}) {
+		t.Errorf("label = %v, want none", got)
+	}
+}
+
+func TestTernaryShortCircuits(t *testing.T) {
+	// Dividing by zero in the branch that is not taken must not fail
+	result, err := compileAndRun(t, `@ GET /test {
+  $ n = 0
+  > n == 0 ? 0 : 10 / n
+}`)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result != (vm.IntValue{Val: 0}) {
+		t.Errorf("got %v, want 0", result)
+	}
+}
+
+func TestTernaryConditionMustBeBoolean(t *testing.T) {
+	_, err := compileAndRun(t, `@ GET /test {
+  $ n = 1
+  > n ? "a" : "b"
+}`)
+	if err == nil || !strings.Contains(err.Error(), "conditional jump requires boolean value") {
+		t.Errorf("expected a boolean condition error, got %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package compiler
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+func TestUUIDCompiled(t *testing.T) {
+	result, err := compileAndRun(t, `@ POST /orders {
+  > {id: uuid(), idempotencyKey: uuid()}
+}`)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	obj, ok := result.(vm.ObjectValue)
+	if !ok {
+		t.Fatalf("Expected ObjectValue, got %T", result)
+	}
+
+	v4 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	id, _ := obj.Val["id"].(vm.StringValue)
+	key, _ := obj.Val["idempotencyKey"].(vm.StringValue)
+	if !v4.MatchString(id.Val) || !v4.MatchString(key.Val) {
+		t.Errorf("expected v4 UUIDs, got %q and %q", id.Val, key.Val)
+	}
+	if id.Val == key.Val {
+		t.Errorf("expected distinct UUIDs, got %q twice", id.Val)
+	}
+}
@@ -54,6 +54,8 @@ func (c *Compiler) compileWebSocketEvent(event ast.WebSocketEvent, routePath str
 		symbolTable:  NewGlobalSymbolTable(),
 		labelCounter: 0,
 		optimizer:    c.optimizer,
+		functions:    c.functions,
+		calls:        newFunctionCalls(),
 	}
 
 	// Enter WebSocket scope
@@ -72,6 +74,12 @@ func (c *Compiler) compileWebSocketEvent(event ast.WebSocketEvent, routePath str
 	clientIdx := eventCompiler.addConstant(vm.StringValue{Val: "client"})
 	eventCompiler.symbolTable.Define("client", clientIdx)
 
+	// query, headers - the query parameters and headers of the upgrade request
+	queryIdx := eventCompiler.addConstant(vm.StringValue{Val: "query"})
+	eventCompiler.symbolTable.Define("query", queryIdx)
+	headersIdx := eventCompiler.addConstant(vm.StringValue{Val: "headers"})
+	eventCompiler.symbolTable.Define("headers", headersIdx)
+
 	// Extract and define path parameters from route path (e.g., :room from /chat/:room)
 	params := server.ExtractRouteParamNames(routePath)
 	for _, param := range params {
@@ -80,7 +88,7 @@ func (c *Compiler) compileWebSocketEvent(event ast.WebSocketEvent, routePath str
 	}
 
 	// Optimize event body before compilation
-	optimizedBody := c.optimizer.OptimizeStatements(event.Body)
+	optimizedBody := c.optimizeBody(event.Body)
 
 	// Compile event body
 	for _, stmt := range optimizedBody {
@@ -178,12 +186,19 @@ func (c *Compiler) compileFunctionCallForWs(expr *ast.FunctionCallExpr) (bool, e
 
 	case "ws.broadcast":
 		if len(expr.Args) < 1 || len(expr.Args) > 2 {
-			return true, fmt.Errorf("ws.broadcast requires 1 or 2 arguments")
+			return true, fmt.Errorf("ws.broadcast requires 1 or 2 arguments (message) or (room, message)")
 		}
-		if err := c.compileExpression(expr.Args[0]); err != nil {
-			return true, err
+		for _, arg := range expr.Args {
+			if err := c.compileExpression(arg); err != nil {
+				return true, err
+			}
+		}
+		// ws.broadcast(room, message) targets a single room
+		if len(expr.Args) == 2 {
+			c.emit(vm.OpWsBroadcastRoom)
+		} else {
+			c.emit(vm.OpWsBroadcast)
 		}
-		c.emit(vm.OpWsBroadcast)
 		return true, nil
 
 	case "ws.broadcast_to_room":
@@ -201,24 +216,68 @@ func (c *Compiler) compileFunctionCallForWs(expr *ast.FunctionCallExpr) (bool, e
 		c.emit(vm.OpWsBroadcastRoom)
 		return true, nil
 
-	case "ws.join":
-		if len(expr.Args) != 1 {
-			return true, fmt.Errorf("ws.join requires exactly 1 argument (room)")
+	case "ws.join", "ws.leave":
+		if len(expr.Args) < 1 || len(expr.Args) > 2 {
+			return true, fmt.Errorf("%s requires 1 or 2 arguments (room) or (client, room)", expr.Name)
 		}
-		if err := c.compileExpression(expr.Args[0]); err != nil {
-			return true, err
+		for _, arg := range expr.Args {
+			if err := c.compileExpression(arg); err != nil {
+				return true, err
+			}
+		}
+		// With a client argument, any connected client joins or leaves;
+		// otherwise the current connection does
+		switch {
+		case expr.Name == "ws.join" && len(expr.Args) == 2:
+			c.emit(vm.OpWsJoinClient)
+		case expr.Name == "ws.join":
+			c.emit(vm.OpWsJoinRoom)
+		case len(expr.Args) == 2:
+			c.emit(vm.OpWsLeaveClient)
+		default:
+			c.emit(vm.OpWsLeaveRoom)
 		}
-		c.emit(vm.OpWsJoinRoom)
 		return true, nil
 
-	case "ws.leave":
-		if len(expr.Args) != 1 {
-			return true, fmt.Errorf("ws.leave requires exactly 1 argument (room)")
+	case "ws.setState":
+		if len(expr.Args) != 3 {
+			return true, fmt.Errorf("ws.setState requires exactly 3 arguments (client, key, value)")
 		}
-		if err := c.compileExpression(expr.Args[0]); err != nil {
-			return true, err
+		for _, arg := range expr.Args {
+			if err := c.compileExpression(arg); err != nil {
+				return true, err
+			}
+		}
+		c.emit(vm.OpWsSetState)
+		return true, nil
+
+	case "ws.getState":
+		if len(expr.Args) != 2 {
+			return true, fmt.Errorf("ws.getState requires exactly 2 arguments (client, key)")
+		}
+		for _, arg := range expr.Args {
+			if err := c.compileExpression(arg); err != nil {
+				return true, err
+			}
+		}
+		c.emit(vm.OpWsGetState)
+		return true, nil
+
+	case "ws.reject":
+		if len(expr.Args) < 1 || len(expr.Args) > 2 {
+			return true, fmt.Errorf("ws.reject requires 1 or 2 arguments (reason) or (code, reason)")
+		}
+		// Without a code, reject with 1008 (policy violation)
+		if len(expr.Args) == 1 {
+			codeIdx := c.addConstant(vm.IntValue{Val: 1008})
+			c.emitWithOperand(vm.OpPush, uint32(codeIdx))
+		}
+		for _, arg := range expr.Args {
+			if err := c.compileExpression(arg); err != nil {
+				return true, err
+			}
 		}
-		c.emit(vm.OpWsLeaveRoom)
+		c.emit(vm.OpWsReject)
 		return true, nil
 
 	case "ws.close":
@@ -154,11 +154,11 @@ func TestCompileWsFunctionCalls(t *testing.T) {
 			expectError: false,
 		},
 		{
-			name:     "ws.broadcast with two arguments",
+			name:     "ws.broadcast to a room",
 			funcName: "ws.broadcast",
 			args: []ast.Expr{
+				&ast.LiteralExpr{Value: ast.StringLiteral{Value: "room:42"}},
 				&ast.LiteralExpr{Value: ast.StringLiteral{Value: "message"}},
-				&ast.LiteralExpr{Value: ast.StringLiteral{Value: "except-client"}},
 			},
 			expectError: false,
 		},
@@ -177,12 +177,21 @@ func TestCompileWsFunctionCalls(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name:     "ws.join with client and room",
+			funcName: "ws.join",
+			args: []ast.Expr{
+				&ast.LiteralExpr{Value: ast.StringLiteral{Value: "conn-1"}},
+				&ast.LiteralExpr{Value: ast.StringLiteral{Value: "room:42"}},
+			},
+			expectError: false,
+		},
 		{
 			name:        "ws.join with no arguments",
 			funcName:    "ws.join",
 			args:        []ast.Expr{},
 			expectError: true,
-			errorMsg:    "ws.join requires exactly 1 argument",
+			errorMsg:    "ws.join requires 1 or 2 arguments",
 		},
 		{
 			name:     "ws.leave with room name",
@@ -197,7 +206,7 @@ func TestCompileWsFunctionCalls(t *testing.T) {
 			funcName:    "ws.leave",
 			args:        []ast.Expr{},
 			expectError: true,
-			errorMsg:    "ws.leave requires exactly 1 argument",
+			errorMsg:    "ws.leave requires 1 or 2 arguments",
 		},
 		{
 			name:        "ws.close with no arguments",
@@ -758,6 +767,79 @@ func TestCompileNonWsFunctionCall(t *testing.T) {
 	}
 }
 
+// TestCompileWsRoomTargeting tests that the two-argument forms of ws.broadcast,
+// ws.join and ws.leave compile to their room and client opcodes
+func TestCompileWsRoomTargeting(t *testing.T) {
+	str := func(s string) ast.Expr { return &ast.LiteralExpr{Value: ast.StringLiteral{Value: s}} }
+	tests := []struct {
+		funcName string
+		args     []ast.Expr
+		want     vm.Opcode
+	}{
+		{"ws.broadcast", []ast.Expr{str("hi")}, vm.OpWsBroadcast},
+		{"ws.broadcast", []ast.Expr{str("room:42"), str("hi")}, vm.OpWsBroadcastRoom},
+		{"ws.join", []ast.Expr{str("room:42")}, vm.OpWsJoinRoom},
+		{"ws.join", []ast.Expr{str("conn-1"), str("room:42")}, vm.OpWsJoinClient},
+		{"ws.leave", []ast.Expr{str("room:42")}, vm.OpWsLeaveRoom},
+		{"ws.leave", []ast.Expr{str("conn-1"), str("room:42")}, vm.OpWsLeaveClient},
+	}
+
+	for _, tt := range tests {
+		c := NewCompiler()
+		c.symbolTable = c.symbolTable.EnterScope(RouteScope)
+
+		handled, err := c.compileFunctionCallForWs(&ast.FunctionCallExpr{Name: tt.funcName, Args: tt.args})
+		if err != nil || !handled {
+			t.Fatalf("%s with %d argument(s): handled=%v, err=%v", tt.funcName, len(tt.args), handled, err)
+		}
+		if got := vm.Opcode(c.code[len(c.code)-1]); got != tt.want {
+			t.Errorf("%s with %d argument(s) emitted opcode 0x%02X, want 0x%02X", tt.funcName, len(tt.args), byte(got), byte(tt.want))
+		}
+	}
+}
+
+// TestCompileWsStateAndReject tests the connection state and rejection functions
+func TestCompileWsStateAndReject(t *testing.T) {
+	str := func(s string) ast.Expr { return &ast.LiteralExpr{Value: ast.StringLiteral{Value: s}} }
+	num := func(n int64) ast.Expr { return &ast.LiteralExpr{Value: ast.IntLiteral{Value: n}} }
+	tests := []struct {
+		funcName    string
+		args        []ast.Expr
+		want        vm.Opcode
+		expectError bool
+	}{
+		{"ws.setState", []ast.Expr{str("conn-1"), str("userId"), num(42)}, vm.OpWsSetState, false},
+		{"ws.setState", []ast.Expr{str("userId"), num(42)}, 0, true},
+		{"ws.getState", []ast.Expr{str("conn-1"), str("userId")}, vm.OpWsGetState, false},
+		{"ws.getState", []ast.Expr{str("userId")}, 0, true},
+		{"ws.reject", []ast.Expr{str("invalid token")}, vm.OpWsReject, false},
+		{"ws.reject", []ast.Expr{num(4001), str("invalid token")}, vm.OpWsReject, false},
+		{"ws.reject", []ast.Expr{}, 0, true},
+	}
+
+	for _, tt := range tests {
+		c := NewCompiler()
+		c.symbolTable = c.symbolTable.EnterScope(RouteScope)
+
+		handled, err := c.compileFunctionCallForWs(&ast.FunctionCallExpr{Name: tt.funcName, Args: tt.args})
+		if !handled {
+			t.Fatalf("%s should be handled as WebSocket function", tt.funcName)
+		}
+		if tt.expectError {
+			if err == nil {
+				t.Errorf("%s with %d argument(s): expected error", tt.funcName, len(tt.args))
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s with %d argument(s): %v", tt.funcName, len(tt.args), err)
+		}
+		if got := vm.Opcode(c.code[len(c.code)-1]); got != tt.want {
+			t.Errorf("%s with %d argument(s) emitted opcode 0x%02X, want 0x%02X", tt.funcName, len(tt.args), byte(got), byte(tt.want))
+		}
+	}
+}
+
 // TestCompileWsRoomCount tests the ws.get_room_count function compilation
 func TestCompileWsRoomCount(t *testing.T) {
 	c := NewCompiler()
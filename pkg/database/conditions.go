@@ -0,0 +1,195 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// parseConditions reads column, operator, value triples into WHERE
+// conditions, rejecting operators that QueryBuilder would reject
+func parseConditions(conditions []interface{}) ([]WhereCondition, error) {
+	if len(conditions)%3 != 0 {
+		return nil, fmt.Errorf("invalid where conditions: expected triples of column, operator, value")
+	}
+
+	whereConds := make([]WhereCondition, 0, len(conditions)/3)
+	for i := 0; i < len(conditions); i += 3 {
+		column, ok := conditions[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string for column name, got %T", conditions[i])
+		}
+		operator, ok := conditions[i+1].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string for operator, got %T", conditions[i+1])
+		}
+		operator = strings.ToUpper(strings.TrimSpace(operator))
+		if !validOperators[operator] {
+			return nil, fmt.Errorf("invalid operator: %s", conditions[i+1])
+		}
+
+		whereConds = append(whereConds, WhereCondition{
+			Column:   column,
+			Operator: operator,
+			Value:    conditions[i+2],
+		})
+	}
+	return whereConds, nil
+}
+
+// matchesConditions reports whether record satisfies every condition
+func matchesConditions(record map[string]interface{}, conds []WhereCondition) bool {
+	for _, cond := range conds {
+		if !matchCondition(record[cond.Column], cond.Operator, cond.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchCondition evaluates "value operator operand" the way SQL does: a
+// comparison involving null is never true, so null is only matched by IS
+// and IS NOT. operator must be upper case.
+func matchCondition(value interface{}, operator string, operand interface{}) bool {
+	switch operator {
+	case "IS":
+		return valuesEqual(value, operand)
+	case "IS NOT":
+		return !valuesEqual(value, operand)
+	}
+
+	if value == nil || operand == nil {
+		return false
+	}
+
+	switch operator {
+	case "=":
+		return valuesEqual(value, operand)
+	case "!=", "<>":
+		return !valuesEqual(value, operand)
+	case "<", ">", "<=", ">=":
+		cmp, ok := compareValues(value, operand)
+		if !ok {
+			return false
+		}
+		switch operator {
+		case "<":
+			return cmp < 0
+		case ">":
+			return cmp > 0
+		case "<=":
+			return cmp <= 0
+		default:
+			return cmp >= 0
+		}
+	case "LIKE", "ILIKE":
+		s, ok := value.(string)
+		pattern, patternOK := operand.(string)
+		if !ok || !patternOK {
+			return false
+		}
+		return likeMatch(s, pattern, operator == "ILIKE")
+	case "IN", "NOT IN":
+		found := false
+		list := reflect.ValueOf(operand)
+		if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+			return false
+		}
+		for i := 0; i < list.Len(); i++ {
+			if valuesEqual(value, list.Index(i).Interface()) {
+				found = true
+				break
+			}
+		}
+		return found == (operator == "IN")
+	}
+	return false
+}
+
+// valuesEqual compares two column values, treating numbers of different Go
+// types as equal when they hold the same value
+func valuesEqual(a, b interface{}) bool {
+	if cmp, ok := compareValues(a, b); ok {
+		return cmp == 0
+	}
+	return a == b
+}
+
+// compareValues orders two numbers or two strings. ok is false for values
+// that cannot be ordered against each other.
+func compareValues(a, b interface{}) (cmp int, ok bool) {
+	if x, isNum := toFloat(a); isNum {
+		y, isNum := toFloat(b)
+		if !isNum {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	x, isStr := a.(string)
+	y, isStr2 := b.(string)
+	if !isStr || !isStr2 {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
+
+// toFloat converts any Go number to float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// likeMatch matches s against a SQL LIKE pattern, where % matches any run of
+// characters and _ matches exactly one. ILIKE ignores case.
+func likeMatch(s, pattern string, ignoreCase bool) bool {
+	var expr strings.Builder
+	expr.WriteString("^")
+	if ignoreCase {
+		expr.WriteString("(?i)")
+	}
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			expr.WriteString(".*")
+		case '_':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile("(?s)" + expr.String()).MatchString(s)
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchCondition(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		operator string
+		operand  interface{}
+		want     bool
+	}{
+		{"= equal", "a", "=", "a", true},
+		{"= different", "a", "=", "b", false},
+		{"= mixed number types", int64(3), "=", 3, true},
+		{"= null", nil, "=", nil, false},
+		{"!= different", "a", "!=", "b", true},
+		{"!= equal", int64(1), "!=", 1.0, false},
+		{"<> different", "a", "<>", "b", true},
+		{"!= null", nil, "!=", "a", false},
+		{"< numbers", int64(1), "<", int64(2), true},
+		{"< equal numbers", int64(2), "<", int64(2), false},
+		{"> numbers", 2.5, ">", int64(2), true},
+		{"> strings", "b", ">", "a", true},
+		{"> null", nil, ">", int64(0), false},
+		{"> mismatched types", "10", ">", int64(2), false},
+		{"<= equal", int64(2), "<=", int64(2), true},
+		{"<= greater", int64(3), "<=", int64(2), false},
+		{">= equal", "b", ">=", "b", true},
+		{">= less", int64(1), ">=", int64(2), false},
+		{"LIKE prefix", "alice@example.com", "LIKE", "alice%", true},
+		{"LIKE suffix", "alice@example.com", "LIKE", "%@example.com", true},
+		{"LIKE contains", "alice@example.com", "LIKE", "%@%", true},
+		{"LIKE single character", "cat", "LIKE", "c_t", true},
+		{"LIKE single character too short", "ct", "LIKE", "c_t", false},
+		{"LIKE exact", "cat", "LIKE", "cat", true},
+		{"LIKE anchored", "concat", "LIKE", "cat", false},
+		{"LIKE case sensitive", "Alice", "LIKE", "alice", false},
+		{"LIKE regexp characters are literal", "a.c", "LIKE", "a.c", true},
+		{"LIKE dot does not match any character", "abc", "LIKE", "a.c", false},
+		{"LIKE non-string", int64(1), "LIKE", "1", false},
+		{"ILIKE ignores case", "Alice", "ILIKE", "al%", true},
+		{"IN member", "b", "IN", []interface{}{"a", "b"}, true},
+		{"IN mixed number types", int64(2), "IN", []int{1, 2}, true},
+		{"IN non-member", "c", "IN", []interface{}{"a", "b"}, false},
+		{"IN not a list", "a", "IN", "a", false},
+		{"NOT IN non-member", "c", "NOT IN", []interface{}{"a", "b"}, true},
+		{"NOT IN member", "a", "NOT IN", []interface{}{"a", "b"}, false},
+		{"IS null", nil, "IS", nil, true},
+		{"IS null with value", "a", "IS", nil, false},
+		{"IS NOT null", "a", "IS NOT", nil, true},
+		{"IS NOT null with null", nil, "IS NOT", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchCondition(tt.value, tt.operator, tt.operand))
+		})
+	}
+}
+
+func TestParseConditions(t *testing.T) {
+	t.Run("normalizes operators", func(t *testing.T) {
+		conds, err := parseConditions([]interface{}{"name", " like ", "a%", "age", "not in", []interface{}{1}})
+		require.NoError(t, err)
+		require.Len(t, conds, 2)
+		assert.Equal(t, WhereCondition{Column: "name", Operator: "LIKE", Value: "a%"}, conds[0])
+		assert.Equal(t, "NOT IN", conds[1].Operator)
+	})
+
+	t.Run("rejects operators QueryBuilder rejects", func(t *testing.T) {
+		_, err := parseConditions([]interface{}{"name", "; DROP TABLE users", "a"})
+		assert.ErrorContains(t, err, "invalid operator")
+	})
+
+	t.Run("rejects incomplete triples", func(t *testing.T) {
+		_, err := parseConditions([]interface{}{"name", "="})
+		assert.ErrorContains(t, err, "triples")
+	})
+
+	t.Run("rejects non-string column", func(t *testing.T) {
+		_, err := parseConditions([]interface{}{1, "=", "a"})
+		assert.ErrorContains(t, err, "column name")
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"fmt"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -45,43 +47,70 @@ type Config struct {
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
 	ConnMaxIdleTime time.Duration
+	// StatementCacheSize is how many prepared statements Query, QueryRow
+	// and Exec keep for reuse; 0 disables the cache
+	StatementCacheSize int
+	// QueryTimeout limits how long a handler's queries may run and
+	// SlowQueryThreshold is the duration from which they are logged; 0
+	// turns either off
+	QueryTimeout       time.Duration
+	SlowQueryThreshold time.Duration
 }
 
 // ParseConnectionString parses a database connection string
 func ParseConnectionString(connStr string) (*Config, error) {
+	// SQLite connection strings carry a filesystem path rather than a URL.
+	// url.Parse would mangle Windows paths such as sqlite://C:\Users\x.db,
+	// so they are split off before URL parsing.
+	for _, driver := range []string{"sqlite", "sqlite3"} {
+		if rest, ok := strings.CutPrefix(connStr, driver+"://"); ok {
+			config := &Config{
+				Driver:             driver,
+				Database:           sqlitePath(rest),
+				SSLMode:            "prefer",
+				MaxOpenConns:       25,
+				MaxIdleConns:       5,
+				ConnMaxLifetime:    5 * time.Minute,
+				ConnMaxIdleTime:    5 * time.Minute,
+				StatementCacheSize: DefaultStatementCacheSize,
+			}
+			if _, params, ok := strings.Cut(rest, "?"); ok {
+				query, err := url.ParseQuery(params)
+				if err != nil {
+					return nil, fmt.Errorf("invalid connection string: %w", err)
+				}
+				if err := applyPoolParams(config, query); err != nil {
+					return nil, err
+				}
+			}
+			return config, nil
+		}
+	}
+
 	u, err := url.Parse(connStr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid connection string: %w", err)
 	}
 
-	// Validate that the connection string has a scheme (driver)
-	if u.Scheme == "" {
+	// Validate that the connection string has a scheme (driver). Without
+	// "://", url.Parse reads "host:port/db" as scheme "host" with an opaque rest.
+	if u.Scheme == "" || !strings.Contains(connStr, "://") {
 		return nil, fmt.Errorf("invalid connection string: missing database driver scheme")
 	}
 
 	config := &Config{
-		Driver:          u.Scheme,
-		Host:            u.Hostname(),
-		SSLMode:         "prefer",
-		MaxOpenConns:    25,
-		MaxIdleConns:    5,
-		ConnMaxLifetime: 5 * time.Minute,
-		ConnMaxIdleTime: 5 * time.Minute,
+		Driver:             u.Scheme,
+		Host:               u.Hostname(),
+		SSLMode:            "prefer",
+		MaxOpenConns:       25,
+		MaxIdleConns:       5,
+		ConnMaxLifetime:    5 * time.Minute,
+		ConnMaxIdleTime:    5 * time.Minute,
+		StatementCacheSize: DefaultStatementCacheSize,
 	}
 
-	// Handle SQLite file-based paths
-	switch config.Driver {
-	case "sqlite", "sqlite3":
-		// SQLite uses file paths: sqlite:///path/to/db.sqlite or sqlite://:memory:
-		config.Database = u.Path
-		if config.Database == "" {
-			config.Database = u.Host // Handle sqlite://:memory:
-		}
-		return config, nil
-	default:
-		if len(u.Path) > 1 {
-			config.Database = u.Path[1:] // Remove leading slash
-		}
+	if len(u.Path) > 1 {
+		config.Database = u.Path[1:] // Remove leading slash
 	}
 
 	// Parse port
@@ -116,10 +145,86 @@ func ParseConnectionString(connStr string) (*Config, error) {
 	if sslMode := query.Get("sslmode"); sslMode != "" {
 		config.SSLMode = sslMode
 	}
+	if err := applyPoolParams(config, query); err != nil {
+		return nil, err
+	}
 
 	return config, nil
 }
 
+// sqlitePath converts the part of a SQLite connection string after
+// "sqlite://" into a filesystem path. Backslashes become forward slashes,
+// which both Unix and Windows accept, and the slash in front of a drive
+// letter in the three-slash form (sqlite:///C:/x.db) is dropped. Query
+// parameters are left to ParseConnectionString. Examples:
+//
+//	/tmp/x.db       -> /tmp/x.db
+//	/C:/Users/x.db  -> C:/Users/x.db
+//	C:\Users\x.db   -> C:/Users/x.db
+//	./local.db      -> ./local.db
+//	:memory:        -> :memory:
+func sqlitePath(rest string) string {
+	if i := strings.IndexByte(rest, '?'); i >= 0 {
+		rest = rest[:i]
+	}
+	path := strings.ReplaceAll(rest, "\\", "/")
+	if len(path) >= 3 && path[0] == '/' && isDriveLetter(path[1]) && path[2] == ':' {
+		path = path[1:]
+	}
+	return path
+}
+
+func isDriveLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// applyPoolParams overrides the connection pool defaults from the
+// max_open_conns, max_idle_conns, statement_cache_size, conn_max_lifetime,
+// conn_max_idle_time, query_timeout and slow_query_threshold query
+// parameters. Other parameters are ignored.
+func applyPoolParams(config *Config, query url.Values) error {
+	for _, param := range []struct {
+		name string
+		dst  *int
+	}{
+		{"max_open_conns", &config.MaxOpenConns},
+		{"max_idle_conns", &config.MaxIdleConns},
+		{"statement_cache_size", &config.StatementCacheSize},
+	} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid %s %q: must be a non-negative integer", param.name, value)
+		}
+		*param.dst = n
+	}
+
+	for _, param := range []struct {
+		name string
+		dst  *time.Duration
+	}{
+		{"conn_max_lifetime", &config.ConnMaxLifetime},
+		{"conn_max_idle_time", &config.ConnMaxIdleTime},
+		{"query_timeout", &config.QueryTimeout},
+		{"slow_query_threshold", &config.SlowQueryThreshold},
+	} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid %s %q: must be a non-negative duration such as 5m", param.name, value)
+		}
+		*param.dst = d
+	}
+
+	return nil
+}
+
 // String returns a redacted connection string safe for logging.
 func (c *Config) String() string {
 	password := "****"
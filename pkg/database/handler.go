@@ -2,8 +2,15 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"log"
+	"maps"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
 // Handler manages database connections and operations for the interpreter
@@ -12,6 +19,7 @@ type Handler struct {
 	mu     sync.RWMutex
 	tables map[string]*TableHandler
 	ctx    context.Context
+	opts   *queryOptions
 }
 
 // NewHandler creates a new database handler
@@ -20,12 +28,31 @@ func NewHandler(db Database) *Handler {
 		db:     db,
 		tables: make(map[string]*TableHandler),
 		ctx:    context.Background(),
+		opts:   &queryOptions{},
 	}
 }
 
-// NewHandlerFromString creates a new handler from a connection string
+// WithContext returns a handler sharing h's connection and query settings
+// whose queries run with ctx, so they are traced and cancelled along with a
+// request
+func (h *Handler) WithContext(ctx context.Context) *Handler {
+	return &Handler{
+		db:     h.db,
+		tables: make(map[string]*TableHandler),
+		ctx:    ctx,
+		opts:   h.opts,
+	}
+}
+
+// NewHandlerFromString creates a new handler from a connection string. The
+// query_timeout and slow_query_threshold parameters of the string set the
+// handler's query timeout and slow query threshold.
 func NewHandlerFromString(connStr string) (*Handler, error) {
-	db, err := NewDatabaseFromString(connStr)
+	config, err := ParseConnectionString(connStr)
+	if err != nil {
+		return nil, err
+	}
+	db, err := NewDatabase(config)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +61,29 @@ func NewHandlerFromString(connStr string) (*Handler, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	return NewHandler(db), nil
+	h := NewHandler(db)
+	h.SetQueryTimeout(config.QueryTimeout)
+	h.SetSlowQueryThreshold(config.SlowQueryThreshold)
+	return h, nil
+}
+
+// SetQueryTimeout limits how long each query made through h, its tables and
+// its transactions may run; 0 removes the limit. A query that runs out of
+// time fails with a *QueryTimeoutError.
+func (h *Handler) SetQueryTimeout(d time.Duration) {
+	h.opts.timeout.Store(int64(d))
+}
+
+// SetSlowQueryThreshold logs the SQL and duration of every query made
+// through h that takes at least d; 0 turns the log off
+func (h *Handler) SetSlowQueryThreshold(d time.Duration) {
+	h.opts.slowThreshold.Store(int64(d))
+}
+
+// SetQueryLogger sets the logger slow queries are written to; nil means the
+// standard logger
+func (h *Handler) SetQueryLogger(logger *log.Logger) {
+	h.opts.logger.Store(logger)
 }
 
 // Table returns a table handler for the given table name
@@ -46,9 +95,11 @@ func (h *Handler) Table(name string) *TableHandler {
 		return handler
 	}
 
+	orm := NewORM(h.db, name)
+	orm.opts = h.opts
 	handler := &TableHandler{
 		db:   h.db,
-		orm:  NewORM(h.db, name),
+		orm:  orm,
 		name: name,
 		ctx:  h.ctx,
 	}
@@ -62,6 +113,93 @@ func (h *Handler) Close() error {
 	return h.db.Close()
 }
 
+// Transaction runs fn in a database transaction. The handler passed to fn
+// and every table handler obtained from it run their queries in the
+// transaction, which is rolled back if fn returns an error or panics and
+// committed otherwise. Drivers that allow a single connection, such as
+// SQLite, block queries made through h until the transaction ends.
+func (h *Handler) Transaction(ctx context.Context, fn func(tx *Handler) error) error {
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	txHandler := &Handler{
+		db:     &txDatabase{parent: h.db, tx: tx},
+		tables: make(map[string]*TableHandler),
+		ctx:    ctx,
+		opts:   h.opts,
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(txHandler); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("tx error: %v, rollback error: %v", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// txDatabase is a Database that runs queries in an open transaction. The
+// transaction is owned by Handler.Transaction, so it cannot be closed or
+// nested.
+type txDatabase struct {
+	parent Database
+	tx     *sql.Tx
+}
+
+func (d *txDatabase) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (d *txDatabase) Close() error {
+	return fmt.Errorf("cannot close the database inside a transaction")
+}
+
+func (d *txDatabase) Ping(ctx context.Context) error {
+	return d.parent.Ping(ctx)
+}
+
+func (d *txDatabase) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.tx.QueryContext(ctx, query, args...)
+}
+
+func (d *txDatabase) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return d.tx.QueryRowContext(ctx, query, args...)
+}
+
+func (d *txDatabase) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return d.tx.ExecContext(ctx, query, args...)
+}
+
+func (d *txDatabase) Begin(ctx context.Context) (*sql.Tx, error) {
+	return nil, fmt.Errorf("nested transactions are not supported")
+}
+
+func (d *txDatabase) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return nil, fmt.Errorf("nested transactions are not supported")
+}
+
+func (d *txDatabase) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	return d.tx.PrepareContext(ctx, query)
+}
+
+func (d *txDatabase) Stats() sql.DBStats {
+	return d.parent.Stats()
+}
+
+func (d *txDatabase) Driver() string {
+	return d.parent.Driver()
+}
+
 // TableHandler provides high-level database operations for GLYPH
 type TableHandler struct {
 	db   Database
@@ -178,6 +316,60 @@ func (t *TableHandler) Where(column string, operator string, value interface{})
 	return t.orm.NewQueryBuilder().Where(column, operator, value)
 }
 
+// With creates a query builder that loads each record's rows of table
+// whose foreignKey is the record's id, as an array field named after table
+func (t *TableHandler) With(table, foreignKey string) *QueryBuilder {
+	return t.orm.NewQueryBuilder().With(table, foreignKey)
+}
+
+// BelongsTo creates a query builder that loads the row of table whose id is
+// each record's foreignKey, as the field as
+func (t *TableHandler) BelongsTo(table, foreignKey, as string) *QueryBuilder {
+	return t.orm.NewQueryBuilder().BelongsTo(table, foreignKey, as)
+}
+
+// FirstOrCreate returns the first record whose columns equal those of
+// match, or creates one from values and match if there is none, so seeding
+// the same data twice does not duplicate it
+func (t *TableHandler) FirstOrCreate(match, values map[string]interface{}) (map[string]interface{}, error) {
+	if len(match) == 0 {
+		return nil, fmt.Errorf("firstOrCreate needs at least one column to match")
+	}
+
+	qb := t.orm.NewQueryBuilder()
+	for _, column := range sortedKeys(match) {
+		qb.WhereEq(column, match[column])
+	}
+	record, err := qb.First(t.ctx)
+	if err == nil {
+		return record, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return t.Create(mergeRecord(values, match))
+}
+
+// sortedKeys returns the keys of m in order, so queries built from a map
+// are the same every time
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mergeRecord returns a new record with the fields of values overridden by
+// those of match
+func mergeRecord(values, match map[string]interface{}) map[string]interface{} {
+	record := make(map[string]interface{}, len(values)+len(match))
+	maps.Copy(record, values)
+	maps.Copy(record, match)
+	return record
+}
+
 // FindWhere retrieves records matching conditions
 func (t *TableHandler) FindWhere(column string, value interface{}) ([]map[string]interface{}, error) {
 	return t.Filter(column, value)
@@ -356,3 +548,295 @@ func (m *MockTableHandler) Length() int64 {
 
 	return int64(len(m.db.data[m.name]))
 }
+
+// Where starts a query with a WHERE condition, like TableHandler.Where
+func (m *MockTableHandler) Where(column string, operator string, value interface{}) *MockQuery {
+	return m.query().Where(column, operator, value)
+}
+
+// OrderBy starts a query ordered by column, ASC or DESC
+func (m *MockTableHandler) OrderBy(column string, direction string) *MockQuery {
+	return m.query().OrderBy(column, direction)
+}
+
+// Limit starts a query returning at most limit records
+func (m *MockTableHandler) Limit(limit int) *MockQuery {
+	return m.query().Limit(limit)
+}
+
+// Offset starts a query skipping the first offset records
+func (m *MockTableHandler) Offset(offset int) *MockQuery {
+	return m.query().Offset(offset)
+}
+
+// With starts a query loading related records, like TableHandler.With
+func (m *MockTableHandler) With(table, foreignKey string) *MockQuery {
+	return m.query().With(table, foreignKey)
+}
+
+// BelongsTo starts a query loading a related record, like
+// TableHandler.BelongsTo
+func (m *MockTableHandler) BelongsTo(table, foreignKey, as string) *MockQuery {
+	return m.query().BelongsTo(table, foreignKey, as)
+}
+
+// FirstOrCreate returns the first record whose columns equal those of
+// match, or creates one from values and match, like
+// TableHandler.FirstOrCreate
+func (m *MockTableHandler) FirstOrCreate(match, values map[string]interface{}) (map[string]interface{}, error) {
+	if len(match) == 0 {
+		return nil, fmt.Errorf("firstOrCreate needs at least one column to match")
+	}
+
+	q := m.query()
+	for _, column := range sortedKeys(match) {
+		q.WhereEq(column, match[column])
+	}
+	record, err := q.First()
+	if err != nil {
+		return nil, err
+	}
+	if record != nil {
+		return record.(map[string]interface{}), nil
+	}
+	return m.Create(mergeRecord(values, match)), nil
+}
+
+// FilterWhere retrieves records matching column, operator, value triples.
+// The operators are those QueryBuilder accepts.
+func (m *MockTableHandler) FilterWhere(conditions ...interface{}) ([]interface{}, error) {
+	whereConds, err := parseConditions(conditions)
+	if err != nil {
+		return nil, err
+	}
+	q := m.query()
+	q.whereConds = whereConds
+	return q.Get()
+}
+
+// UpdateWhere merges data into every record matching the conditions and
+// returns how many were updated
+func (m *MockTableHandler) UpdateWhere(conditions []interface{}, data map[string]interface{}) (int64, error) {
+	whereConds, err := parseConditions(conditions)
+	if err != nil {
+		return 0, err
+	}
+
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	count := int64(0)
+	for _, record := range m.db.data[m.name] {
+		if matchesConditions(record, whereConds) {
+			for k, v := range data {
+				record[k] = v
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DeleteWhere deletes every record matching the conditions and returns how
+// many were deleted
+func (m *MockTableHandler) DeleteWhere(conditions ...interface{}) (int64, error) {
+	whereConds, err := parseConditions(conditions)
+	if err != nil {
+		return 0, err
+	}
+
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	kept := make([]map[string]interface{}, 0, len(m.db.data[m.name]))
+	for _, record := range m.db.data[m.name] {
+		if !matchesConditions(record, whereConds) {
+			kept = append(kept, record)
+		}
+	}
+	count := int64(len(m.db.data[m.name]) - len(kept))
+	m.db.data[m.name] = kept
+	return count, nil
+}
+
+// First retrieves the first record, or nil if the table is empty
+func (m *MockTableHandler) First() interface{} {
+	record, _ := m.query().First()
+	return record
+}
+
+// Last retrieves the record with the highest ID, or nil if the table is
+// empty
+func (m *MockTableHandler) Last() interface{} {
+	record, _ := m.query().OrderBy("id", "DESC").First()
+	return record
+}
+
+func (m *MockTableHandler) query() *MockQuery {
+	return &MockQuery{table: m}
+}
+
+// MockQuery is the MockTableHandler counterpart of QueryBuilder. Errors in
+// its clauses are reported when the query is run.
+type MockQuery struct {
+	table      *MockTableHandler
+	whereConds []WhereCondition
+	orderBy    string
+	descending bool
+	limit      int
+	offset     int
+	relations  []relation
+	err        error
+}
+
+// Where adds a WHERE condition
+func (q *MockQuery) Where(column string, operator string, value interface{}) *MockQuery {
+	whereConds, err := parseConditions([]interface{}{column, operator, value})
+	if err != nil {
+		if q.err == nil {
+			q.err = err
+		}
+		return q
+	}
+	q.whereConds = append(q.whereConds, whereConds...)
+	return q
+}
+
+// WhereEq is a shorthand for Where with "=" operator
+func (q *MockQuery) WhereEq(column string, value interface{}) *MockQuery {
+	return q.Where(column, "=", value)
+}
+
+// OrderBy orders the results by column, ASC or DESC. As in PostgreSQL, null
+// values sort after all others in ascending order.
+func (q *MockQuery) OrderBy(column string, direction string) *MockQuery {
+	switch strings.ToUpper(strings.TrimSpace(direction)) {
+	case "", "ASC":
+		q.descending = false
+	case "DESC":
+		q.descending = true
+	default:
+		if q.err == nil {
+			q.err = fmt.Errorf("invalid order direction: %s", direction)
+		}
+	}
+	q.orderBy = column
+	return q
+}
+
+// With loads each record's rows of table whose foreignKey is the record's
+// id, as an array field named after table
+func (q *MockQuery) With(table, foreignKey string) *MockQuery {
+	return q.addRelation(relation{table: table, foreignKey: foreignKey, as: table})
+}
+
+// BelongsTo loads the row of table whose id is each record's foreignKey, as
+// the field as
+func (q *MockQuery) BelongsTo(table, foreignKey, as string) *MockQuery {
+	return q.addRelation(relation{table: table, foreignKey: foreignKey, as: as, belongsTo: true})
+}
+
+func (q *MockQuery) addRelation(r relation) *MockQuery {
+	if err := r.validate(); err != nil {
+		if q.err == nil {
+			q.err = err
+		}
+		return q
+	}
+	q.relations = append(q.relations, r)
+	return q
+}
+
+// Limit sets the maximum number of records returned
+func (q *MockQuery) Limit(limit int) *MockQuery {
+	q.limit = limit
+	return q
+}
+
+// Offset sets the number of matching records skipped
+func (q *MockQuery) Offset(offset int) *MockQuery {
+	q.offset = offset
+	return q
+}
+
+// Get runs the query
+func (q *MockQuery) Get() ([]interface{}, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	db := q.table.db
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	matched := make([]map[string]interface{}, 0)
+	for _, record := range db.data[q.table.name] {
+		if matchesConditions(record, q.whereConds) {
+			matched = append(matched, record)
+		}
+	}
+
+	if q.orderBy != "" {
+		sort.SliceStable(matched, func(i, j int) bool {
+			a, b := matched[i][q.orderBy], matched[j][q.orderBy]
+			if a == nil || b == nil {
+				// null is the largest value, so it comes last only when ascending
+				if q.descending {
+					return a == nil && b != nil
+				}
+				return a != nil && b == nil
+			}
+			cmp, _ := compareValues(a, b)
+			if q.descending {
+				return cmp > 0
+			}
+			return cmp < 0
+		})
+	}
+
+	if q.offset > 0 {
+		if q.offset >= len(matched) {
+			matched = matched[:0]
+		} else {
+			matched = matched[q.offset:]
+		}
+	}
+	if q.limit > 0 && q.limit < len(matched) {
+		matched = matched[:q.limit]
+	}
+
+	if len(q.relations) > 0 {
+		// Attach related records to copies, leaving the stored ones as they are
+		for i, record := range matched {
+			matched[i] = maps.Clone(record)
+		}
+		for _, r := range q.relations {
+			r.attach(matched, db.data[r.table])
+		}
+	}
+
+	result := make([]interface{}, len(matched))
+	for i, record := range matched {
+		result[i] = record
+	}
+	return result, nil
+}
+
+// First runs the query and returns its first record, or nil if there is none
+func (q *MockQuery) First() (interface{}, error) {
+	records, err := q.Limit(1).Get()
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return records[0], nil
+}
+
+// Count runs the query and returns the number of matching records,
+// ignoring Limit and Offset
+func (q *MockQuery) Count() (int64, error) {
+	limit, offset := q.limit, q.offset
+	q.limit, q.offset = 0, 0
+	records, err := q.Get()
+	q.limit, q.offset = limit, offset
+	return int64(len(records)), err
+}
@@ -1,9 +1,12 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMockDatabase(t *testing.T) {
@@ -298,6 +301,184 @@ func TestMockTableHandler_Length(t *testing.T) {
 	})
 }
 
+// seedMockUsers creates a users table for the query tests
+func seedMockUsers() *MockTableHandler {
+	users := NewMockDatabase().Table("users")
+	users.Create(map[string]interface{}{"id": int64(1), "name": "Alice", "age": int64(31), "email": "alice@example.com"})
+	users.Create(map[string]interface{}{"id": int64(2), "name": "Bob", "age": int64(25), "email": "bob@test.org"})
+	users.Create(map[string]interface{}{"id": int64(3), "name": "Carol", "age": int64(42), "email": "carol@example.com"})
+	users.Create(map[string]interface{}{"id": int64(4), "name": "Dave", "age": nil, "email": "dave@example.com"})
+	return users
+}
+
+func recordNames(records []interface{}) []string {
+	names := make([]string, len(records))
+	for i, record := range records {
+		names[i] = record.(map[string]interface{})["name"].(string)
+	}
+	return names
+}
+
+func TestMockTableHandler_FilterWhere(t *testing.T) {
+	users := seedMockUsers()
+
+	t.Run("Single condition", func(t *testing.T) {
+		result, err := users.FilterWhere("age", ">", int64(30))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Alice", "Carol"}, recordNames(result))
+	})
+
+	t.Run("Conditions are combined with AND", func(t *testing.T) {
+		result, err := users.FilterWhere("email", "LIKE", "%@example.com", "age", "<", int64(40))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Alice"}, recordNames(result))
+	})
+
+	t.Run("Null only matches IS", func(t *testing.T) {
+		result, err := users.FilterWhere("age", "IS", nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Dave"}, recordNames(result))
+	})
+
+	t.Run("Invalid operator", func(t *testing.T) {
+		_, err := users.FilterWhere("age", "~", int64(1))
+		assert.ErrorContains(t, err, "invalid operator")
+	})
+}
+
+func TestMockTableHandler_Query(t *testing.T) {
+	users := seedMockUsers()
+
+	t.Run("Order ascending puts null last", func(t *testing.T) {
+		result, err := users.OrderBy("age", "asc").Get()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Bob", "Alice", "Carol", "Dave"}, recordNames(result))
+	})
+
+	t.Run("Order descending puts null first", func(t *testing.T) {
+		result, err := users.OrderBy("age", "DESC").Get()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Dave", "Carol", "Alice", "Bob"}, recordNames(result))
+	})
+
+	t.Run("Where with limit and offset", func(t *testing.T) {
+		result, err := users.Where("email", "LIKE", "%example%").OrderBy("name", "ASC").Offset(1).Limit(1).Get()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Carol"}, recordNames(result))
+	})
+
+	t.Run("Offset past the end", func(t *testing.T) {
+		result, err := users.Offset(10).Get()
+		require.NoError(t, err)
+		assert.Empty(t, result)
+	})
+
+	t.Run("Count ignores limit", func(t *testing.T) {
+		count, err := users.Where("age", "IS NOT", nil).Limit(1).Count()
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+	})
+
+	t.Run("First", func(t *testing.T) {
+		record, err := users.Where("name", "ILIKE", "c%").First()
+		require.NoError(t, err)
+		assert.Equal(t, "Carol", record.(map[string]interface{})["name"])
+
+		record, err = users.Where("name", "=", "Eve").First()
+		require.NoError(t, err)
+		assert.Nil(t, record)
+	})
+
+	t.Run("Invalid clauses are reported when run", func(t *testing.T) {
+		_, err := users.Where("age", "BETWEEN", int64(1)).Get()
+		assert.ErrorContains(t, err, "invalid operator")
+
+		_, err = users.OrderBy("age", "sideways").Get()
+		assert.ErrorContains(t, err, "invalid order direction")
+	})
+}
+
+func TestMockTableHandler_Relations(t *testing.T) {
+	db := NewMockDatabase()
+	users := db.Table("users")
+	users.Create(map[string]interface{}{"id": int64(1), "name": "Alice"})
+	users.Create(map[string]interface{}{"id": int64(2), "name": "Bob"})
+	posts := db.Table("posts")
+	posts.Create(map[string]interface{}{"id": int64(1), "user_id": int64(2), "title": "hello"})
+	posts.Create(map[string]interface{}{"id": int64(2), "user_id": float64(2), "title": "again"})
+	posts.Create(map[string]interface{}{"id": int64(3), "user_id": int64(9), "title": "orphan"})
+
+	t.Run("With attaches an array of related records", func(t *testing.T) {
+		result, err := users.With("posts", "user_id").Get()
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+		assert.Equal(t, []interface{}{}, result[0].(map[string]interface{})["posts"])
+		assert.Len(t, result[1].(map[string]interface{})["posts"], 2, "keys match across number types")
+
+		_, stored := users.Get(int64(2)).(map[string]interface{})["posts"]
+		assert.False(t, stored, "stored records are not modified")
+	})
+
+	t.Run("BelongsTo attaches the related record or null", func(t *testing.T) {
+		result, err := posts.BelongsTo("users", "user_id", "author").OrderBy("id", "ASC").Get()
+		require.NoError(t, err)
+		require.Len(t, result, 3)
+		assert.Equal(t, "Bob", result[0].(map[string]interface{})["author"].(map[string]interface{})["name"])
+		assert.Equal(t, "Bob", result[1].(map[string]interface{})["author"].(map[string]interface{})["name"])
+		assert.Nil(t, result[2].(map[string]interface{})["author"])
+	})
+
+	t.Run("Invalid relations are reported when run", func(t *testing.T) {
+		_, err := users.With("posts", "user id").Get()
+		assert.ErrorContains(t, err, "invalid relation foreign key")
+	})
+}
+
+func TestMockTableHandler_FirstLast(t *testing.T) {
+	users := NewMockDatabase().Table("users")
+	assert.Nil(t, users.First())
+	assert.Nil(t, users.Last())
+
+	users.Create(map[string]interface{}{"id": int64(2), "name": "Bob"})
+	users.Create(map[string]interface{}{"id": int64(1), "name": "Alice"})
+
+	assert.Equal(t, "Bob", users.First().(map[string]interface{})["name"])
+	assert.Equal(t, "Bob", users.Last().(map[string]interface{})["name"])
+}
+
+func TestMockTableHandler_UpdateWhere(t *testing.T) {
+	users := seedMockUsers()
+
+	count, err := users.UpdateWhere([]interface{}{"email", "LIKE", "%@example.com"}, map[string]interface{}{"verified": true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	assert.Equal(t, int64(3), users.Count("verified", true))
+	assert.Nil(t, users.Get(int64(2)).(map[string]interface{})["verified"])
+
+	count, err = users.UpdateWhere([]interface{}{"name", "=", "Eve"}, map[string]interface{}{"verified": true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	_, err = users.UpdateWhere([]interface{}{"name"}, map[string]interface{}{"verified": true})
+	assert.Error(t, err)
+}
+
+func TestMockTableHandler_DeleteWhere(t *testing.T) {
+	users := seedMockUsers()
+
+	count, err := users.DeleteWhere("age", "<", int64(40))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	remaining, err := users.FilterWhere()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Carol", "Dave"}, recordNames(remaining))
+
+	_, err = users.DeleteWhere("age", "<<", int64(40))
+	assert.ErrorContains(t, err, "invalid operator")
+	assert.Equal(t, int64(2), users.Length())
+}
+
 func TestNewHandler(t *testing.T) {
 	mockDB := &MockDB{}
 	handler := NewHandler(mockDB)
@@ -392,3 +573,125 @@ func TestConcurrentAccess(t *testing.T) {
 	// Verify data integrity
 	assert.Equal(t, int64(10), users.Length())
 }
+
+// newTransactionHandler returns a handler over an in-memory SQLite database
+// with users and posts tables. ORM.Create scans RETURNING * into the inserted
+// columns, so the tests insert every column.
+func newTransactionHandler(t *testing.T) *Handler {
+	t.Helper()
+	db := newInMemorySQLite(t)
+	ctx := context.Background()
+	_, err := db.Exec(ctx, `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)`)
+	require.NoError(t, err)
+	_, err = db.Exec(ctx, `CREATE TABLE posts (id INTEGER PRIMARY KEY, author_id INTEGER, title TEXT)`)
+	require.NoError(t, err)
+	return NewHandler(db)
+}
+
+func TestHandler_Transaction_Commit(t *testing.T) {
+	h := newTransactionHandler(t)
+
+	err := h.Transaction(context.Background(), func(tx *Handler) error {
+		user, err := tx.Table("users").Create(map[string]interface{}{"id": 1, "name": "Ada"})
+		if err != nil {
+			return err
+		}
+		_, err = tx.Table("posts").Create(map[string]interface{}{"id": 1, "author_id": user["id"], "title": "Notes"})
+		return err
+	})
+	require.NoError(t, err)
+
+	users, err := h.Table("users").Length()
+	require.NoError(t, err)
+	posts, err := h.Table("posts").Length()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), users)
+	assert.Equal(t, int64(1), posts)
+}
+
+func TestHandler_Transaction_RollbackOnError(t *testing.T) {
+	h := newTransactionHandler(t)
+	errFailed := errors.New("failed after inserts")
+
+	err := h.Transaction(context.Background(), func(tx *Handler) error {
+		user, err := tx.Table("users").Create(map[string]interface{}{"id": 1, "name": "Ada"})
+		require.NoError(t, err)
+		_, err = tx.Table("posts").Create(map[string]interface{}{"id": 1, "author_id": user["id"], "title": "Notes"})
+		require.NoError(t, err)
+
+		// Both rows are visible inside the transaction
+		count, err := tx.Table("users").Length()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+
+	users, err := h.Table("users").Length()
+	require.NoError(t, err)
+	posts, err := h.Table("posts").Length()
+	require.NoError(t, err)
+	assert.Zero(t, users, "user insert should be rolled back")
+	assert.Zero(t, posts, "post insert should be rolled back")
+}
+
+func TestHandler_Transaction_RollbackOnPanic(t *testing.T) {
+	h := newTransactionHandler(t)
+
+	assert.Panics(t, func() {
+		h.Transaction(context.Background(), func(tx *Handler) error {
+			_, err := tx.Table("users").Create(map[string]interface{}{"id": 1, "name": "Ada"})
+			require.NoError(t, err)
+			panic("boom")
+		})
+	})
+
+	users, err := h.Table("users").Length()
+	require.NoError(t, err)
+	assert.Zero(t, users)
+}
+
+func TestHandler_Transaction_NotNested(t *testing.T) {
+	h := newTransactionHandler(t)
+
+	err := h.Transaction(context.Background(), func(tx *Handler) error {
+		return tx.Transaction(context.Background(), func(*Handler) error { return nil })
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nested transactions are not supported")
+}
+
+func TestTableHandler_FirstOrCreate(t *testing.T) {
+	users := newSQLiteHandler(t).Table("users")
+	match := map[string]interface{}{"name": "ada"}
+
+	created, err := users.FirstOrCreate(match, nil)
+	require.NoError(t, err)
+	found, err := users.FirstOrCreate(match, nil)
+	require.NoError(t, err)
+	assert.Equal(t, created["id"], found["id"], "the existing row is returned")
+
+	count, err := users.Length()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	_, err = users.FirstOrCreate(map[string]interface{}{}, map[string]interface{}{"name": "bob"})
+	assert.ErrorContains(t, err, "needs at least one column")
+}
+
+func TestMockTableHandler_FirstOrCreate(t *testing.T) {
+	users := seedMockUsers()
+
+	found, err := users.FirstOrCreate(map[string]interface{}{"email": "bob@test.org"}, map[string]interface{}{"name": "Robert"})
+	require.NoError(t, err)
+	assert.Equal(t, "Bob", found["name"], "values are not applied to an existing record")
+
+	created, err := users.FirstOrCreate(map[string]interface{}{"email": "eve@test.org"}, map[string]interface{}{"name": "Eve", "email": "ignored"})
+	require.NoError(t, err)
+	assert.Equal(t, "Eve", created["name"])
+	assert.Equal(t, "eve@test.org", created["email"], "match takes precedence over values")
+	assert.Len(t, users.All(), 5)
+
+	_, err = users.FirstOrCreate(nil, nil)
+	assert.ErrorContains(t, err, "needs at least one column")
+}
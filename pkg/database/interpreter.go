@@ -0,0 +1,235 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// InterpreterDatabase exposes a Handler to GLYPH code with the methods of
+// MockDatabase, so db.users.all() and db.table("users").all() work the same
+// against a real database: records are returned as []interface{}, and a
+// missing record is null rather than an error.
+type InterpreterDatabase struct {
+	handler *Handler
+}
+
+// NewInterpreterDatabase creates the interpreter's view of handler
+func NewInterpreterDatabase(handler *Handler) *InterpreterDatabase {
+	return &InterpreterDatabase{handler: handler}
+}
+
+// WithContext returns a view of the database whose queries run with ctx. The
+// interpreter binds each route's request context this way; the result is an
+// interface{} so it can do so without importing this package.
+func (d *InterpreterDatabase) WithContext(ctx context.Context) interface{} {
+	return &InterpreterDatabase{handler: d.handler.WithContext(ctx)}
+}
+
+// Table returns the table with the given name
+func (d *InterpreterDatabase) Table(name string) *InterpreterTable {
+	return &InterpreterTable{table: d.handler.Table(name)}
+}
+
+// Transaction runs fn with a view of the database whose queries run in one
+// transaction, committed if fn succeeds and rolled back otherwise
+func (d *InterpreterDatabase) Transaction(ctx context.Context, fn func(tx *InterpreterDatabase) error) error {
+	return d.handler.Transaction(ctx, func(tx *Handler) error {
+		return fn(&InterpreterDatabase{handler: tx})
+	})
+}
+
+// Close closes the database connection
+func (d *InterpreterDatabase) Close() error {
+	return d.handler.Close()
+}
+
+// InterpreterTable provides the operations of MockTableHandler on a table
+type InterpreterTable struct {
+	table *TableHandler
+}
+
+// All retrieves all records
+func (t *InterpreterTable) All() ([]interface{}, error) {
+	return records(t.table.All())
+}
+
+// Get retrieves a record by ID, or nil if there is none
+func (t *InterpreterTable) Get(id interface{}) (interface{}, error) {
+	record, err := t.table.Get(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Create creates a new record
+func (t *InterpreterTable) Create(data map[string]interface{}) (map[string]interface{}, error) {
+	return t.table.Create(data)
+}
+
+// Update updates a record by ID, returning nil if there is none
+func (t *InterpreterTable) Update(id interface{}, data map[string]interface{}) (interface{}, error) {
+	record, err := t.table.Update(id, data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Delete deletes a record by ID, reporting whether there was one
+func (t *InterpreterTable) Delete(id interface{}) (bool, error) {
+	err := t.table.Delete(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// FirstOrCreate returns the first record matching the columns of match, or
+// creates one from values and match
+func (t *InterpreterTable) FirstOrCreate(match, values map[string]interface{}) (map[string]interface{}, error) {
+	return t.table.FirstOrCreate(match, values)
+}
+
+// Count counts records matching a condition
+func (t *InterpreterTable) Count(column string, value interface{}) (int64, error) {
+	return t.table.Count(column, value)
+}
+
+// CountWhere counts records matching column, value pairs
+func (t *InterpreterTable) CountWhere(conditions ...interface{}) (int64, error) {
+	return t.table.CountWhere(conditions...)
+}
+
+// Filter retrieves records matching a condition
+func (t *InterpreterTable) Filter(column string, value interface{}) ([]interface{}, error) {
+	return records(t.table.Filter(column, value))
+}
+
+// NextId returns the next available ID
+func (t *InterpreterTable) NextId() int64 {
+	return t.table.NextId()
+}
+
+// Length returns the total count of records
+func (t *InterpreterTable) Length() (int64, error) {
+	return t.table.Length()
+}
+
+// Where starts a query with a WHERE condition
+func (t *InterpreterTable) Where(column string, operator string, value interface{}) *InterpreterQuery {
+	return t.query().Where(column, operator, value)
+}
+
+// OrderBy starts a query ordered by column, ASC or DESC
+func (t *InterpreterTable) OrderBy(column string, direction string) *InterpreterQuery {
+	return t.query().OrderBy(column, direction)
+}
+
+// Limit starts a query returning at most limit records
+func (t *InterpreterTable) Limit(limit int) *InterpreterQuery {
+	return t.query().Limit(limit)
+}
+
+// Offset starts a query skipping the first offset records
+func (t *InterpreterTable) Offset(offset int) *InterpreterQuery {
+	return t.query().Offset(offset)
+}
+
+// With starts a query loading each record's rows of table whose foreignKey
+// is the record's id
+func (t *InterpreterTable) With(table, foreignKey string) *InterpreterQuery {
+	return t.query().With(table, foreignKey)
+}
+
+// BelongsTo starts a query loading the row of table whose id is each
+// record's foreignKey, as the field as
+func (t *InterpreterTable) BelongsTo(table, foreignKey, as string) *InterpreterQuery {
+	return t.query().BelongsTo(table, foreignKey, as)
+}
+
+func (t *InterpreterTable) query() *InterpreterQuery {
+	return &InterpreterQuery{qb: t.table.orm.NewQueryBuilder(), ctx: t.table.ctx}
+}
+
+// InterpreterQuery provides the operations of MockQuery on a QueryBuilder,
+// running it with the context of the table it was started from
+type InterpreterQuery struct {
+	qb  *QueryBuilder
+	ctx context.Context
+}
+
+// Where adds a WHERE condition
+func (q *InterpreterQuery) Where(column string, operator string, value interface{}) *InterpreterQuery {
+	q.qb.Where(column, operator, value)
+	return q
+}
+
+// OrderBy orders the results by column, ASC or DESC
+func (q *InterpreterQuery) OrderBy(column string, direction string) *InterpreterQuery {
+	q.qb.OrderBy(column, direction)
+	return q
+}
+
+// Limit sets the maximum number of records returned
+func (q *InterpreterQuery) Limit(limit int) *InterpreterQuery {
+	q.qb.Limit(limit)
+	return q
+}
+
+// Offset sets the number of matching records skipped
+func (q *InterpreterQuery) Offset(offset int) *InterpreterQuery {
+	q.qb.Offset(offset)
+	return q
+}
+
+// With loads each record's rows of table whose foreignKey is the record's id
+func (q *InterpreterQuery) With(table, foreignKey string) *InterpreterQuery {
+	q.qb.With(table, foreignKey)
+	return q
+}
+
+// BelongsTo loads the row of table whose id is each record's foreignKey
+func (q *InterpreterQuery) BelongsTo(table, foreignKey, as string) *InterpreterQuery {
+	q.qb.BelongsTo(table, foreignKey, as)
+	return q
+}
+
+// Get runs the query
+func (q *InterpreterQuery) Get() ([]interface{}, error) {
+	return records(q.qb.Get(q.ctx))
+}
+
+// First runs the query and returns its first record, or nil if there is none
+func (q *InterpreterQuery) First() (interface{}, error) {
+	record, err := q.qb.First(q.ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// records converts rows to the array type used by the interpreter
+func records(rows []map[string]interface{}, err error) ([]interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	result := make([]interface{}, len(rows))
+	for i, row := range rows {
+		result[i] = row
+	}
+	return result, nil
+}
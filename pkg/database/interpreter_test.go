@@ -0,0 +1,86 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newInterpreterTestDatabase(t *testing.T) *InterpreterDatabase {
+	t.Helper()
+	handler, err := NewHandlerFromString("sqlite://:memory:")
+	require.NoError(t, err)
+	_, err = handler.db.Exec(handler.ctx, `CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, role TEXT)`)
+	require.NoError(t, err)
+	db := NewInterpreterDatabase(handler)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestInterpreterDatabase_CRUD(t *testing.T) {
+	users := newInterpreterTestDatabase(t).Table("users")
+
+	created, err := users.Create(map[string]interface{}{"name": "ann", "role": "admin"})
+	require.NoError(t, err)
+	assert.Equal(t, "ann", created["name"])
+	assert.Equal(t, int64(1), created["id"])
+
+	_, err = users.Create(map[string]interface{}{"name": "bob", "role": "user"})
+	require.NoError(t, err)
+
+	all, err := users.All()
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	assert.IsType(t, map[string]interface{}{}, all[0])
+
+	got, err := users.Get(int64(1))
+	require.NoError(t, err)
+	assert.Equal(t, "ann", got.(map[string]interface{})["name"])
+
+	updated, err := users.Update(int64(2), map[string]interface{}{"role": "admin"})
+	require.NoError(t, err)
+	assert.Equal(t, "admin", updated.(map[string]interface{})["role"])
+
+	admins, err := users.Filter("role", "admin")
+	require.NoError(t, err)
+	assert.Len(t, admins, 2)
+
+	count, err := users.CountWhere("role", "admin", "name", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	deleted, err := users.Delete(int64(1))
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	length, err := users.Length()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), length)
+	assert.Equal(t, int64(2), users.NextId())
+}
+
+func TestInterpreterDatabase_MissingRecords(t *testing.T) {
+	users := newInterpreterTestDatabase(t).Table("users")
+
+	got, err := users.Get(int64(99))
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	updated, err := users.Update(int64(99), map[string]interface{}{"name": "x"})
+	require.NoError(t, err)
+	assert.Nil(t, updated)
+
+	deleted, err := users.Delete(int64(99))
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
+
+func TestInterpreterDatabase_Errors(t *testing.T) {
+	missing := newInterpreterTestDatabase(t).Table("missing")
+
+	_, err := missing.All()
+	assert.Error(t, err)
+	_, err = missing.Create(map[string]interface{}{"name": "x"})
+	assert.Error(t, err)
+}
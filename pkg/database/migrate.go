@@ -0,0 +1,314 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MigrationsTable records which migrations have been applied
+const MigrationsTable = "schema_migrations"
+
+// migrationFilePattern matches NNN_name.up.sql and NNN_name.down.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_-]+)\.(up|down)\.sql$`)
+
+// Migration is a numbered schema change read from a pair of SQL files
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string // Empty if the migration has no .down.sql file
+}
+
+// Migrator applies and rolls back migrations, tracking them in the
+// schema_migrations table. Each migration runs in its own transaction
+// together with its bookkeeping row, so a failing migration leaves no trace
+// (MySQL commits DDL statements implicitly, so there only the bookkeeping is
+// transactional).
+type Migrator struct {
+	db         Database
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator for the given migrations
+func NewMigrator(db Database, migrations []Migration) *Migrator {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{db: db, migrations: sorted}
+}
+
+// LoadMigrations reads the NNN_name.up.sql and NNN_name.down.sql files in
+// dir, sorted by version. Other files are ignored, but .sql files that do
+// not follow the naming scheme are reported so typos are not skipped
+// silently.
+func LoadMigrations(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q: expected NNN_name.up.sql or NNN_name.down.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", entry.Name(), err)
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no .up.sql file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Applied returns the versions recorded in schema_migrations, oldest first
+func (m *Migrator) Applied(ctx context.Context) ([]int64, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(ctx, "SELECT version FROM "+MigrationsTable+" ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []int64
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// Pending returns the migrations that have not been applied, oldest first
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[int64]bool, len(applied))
+	for _, version := range applied {
+		done[version] = true
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies all pending migrations in version order and returns the ones
+// it applied. It stops at the first failure; earlier migrations stay
+// applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range pending {
+		record := fmt.Sprintf("INSERT INTO %s (version, name) VALUES (%s, %s)", MigrationsTable, m.placeholder(1), m.placeholder(2))
+		if err := m.run(ctx, migration.Up, record, migration.Version, migration.Name); err != nil {
+			return applied, fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// Down rolls back the n most recently applied migrations, newest first, and
+// returns the ones it rolled back. Every migration to roll back must have a
+// .down.sql file; this is checked before anything is changed.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("number of migrations to roll back must be positive, got %d", n)
+	}
+
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	var targets []Migration
+	for i := len(applied) - 1; i >= 0 && len(targets) < n; i-- {
+		migration, ok := known[applied[i]]
+		if !ok {
+			return nil, fmt.Errorf("applied migration %d has no migration files", applied[i])
+		}
+		if migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s has no .down.sql file", migration.Version, migration.Name)
+		}
+		targets = append(targets, migration)
+	}
+
+	var rolledBack []Migration
+	for _, migration := range targets {
+		record := fmt.Sprintf("DELETE FROM %s WHERE version = %s", MigrationsTable, m.placeholder(1))
+		if err := m.run(ctx, migration.Down, record, migration.Version); err != nil {
+			return rolledBack, fmt.Errorf("rolling back migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		rolledBack = append(rolledBack, migration)
+	}
+	return rolledBack, nil
+}
+
+// run executes script and the bookkeeping statement in one transaction
+func (m *Migrator) run(ctx context.Context, script, record string, args ...interface{}) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	for _, stmt := range SplitSQLStatements(script) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update %s: %w", MigrationsTable, err)
+	}
+
+	return tx.Commit()
+}
+
+// ensureTable creates schema_migrations if it does not exist yet
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	query := "CREATE TABLE IF NOT EXISTS " + MigrationsTable + ` (
+	version BIGINT PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+	if _, err := m.db.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create %s table: %w", MigrationsTable, err)
+	}
+	return nil
+}
+
+// placeholder returns the nth bind parameter in the driver's syntax
+func (m *Migrator) placeholder(n int) string {
+	switch m.db.Driver() {
+	case "postgres", "postgresql":
+		return fmt.Sprintf("$%d", n)
+	default:
+		return "?"
+	}
+}
+
+// SplitSQLStatements splits a SQL script into statements at semicolons that
+// are outside quotes, comments and PostgreSQL dollar-quoted bodies. Empty
+// statements are dropped. Executing statements one at a time keeps scripts
+// portable to drivers, such as MySQL's, that reject multi-statement Exec.
+func SplitSQLStatements(script string) []string {
+	var stmts []string
+	start := 0
+	flush := func(end int) {
+		if stmt := strings.TrimSpace(script[start:end]); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			// Quotes are escaped by doubling, which reads as two strings
+			i++
+			for i < len(script) && script[i] != c {
+				i++
+			}
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end + 3
+			}
+		case c == '$':
+			// A dollar-quote tag is $$ or $name$
+			tagEnd := strings.IndexByte(script[i+1:], '$')
+			if tagEnd < 0 {
+				continue
+			}
+			tag := script[i : i+tagEnd+2]
+			if !isDollarTag(tag[1 : len(tag)-1]) {
+				continue
+			}
+			end := strings.Index(script[i+len(tag):], tag)
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += len(tag) + end + len(tag) - 1
+			}
+		case c == ';':
+			flush(i)
+			start = i + 1
+		}
+	}
+	if start < len(script) {
+		flush(len(script))
+	}
+	return stmts
+}
+
+// isDollarTag reports whether name can appear between the dollars of a
+// PostgreSQL dollar quote
+func isDollarTag(name string) bool {
+	for i, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
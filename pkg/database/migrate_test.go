@@ -0,0 +1,194 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeMigrations creates a migrations directory from file name -> content
+func writeMigrations(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	return dir
+}
+
+func newMigrator(t *testing.T, db *SQLiteDB, dir string) *Migrator {
+	t.Helper()
+	migrations, err := LoadMigrations(dir)
+	require.NoError(t, err)
+	return NewMigrator(db, migrations)
+}
+
+var userMigrations = map[string]string{
+	"001_create_users.up.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+-- Seed row; the semicolon in this comment is not a separator;
+INSERT INTO users (name) VALUES ('Ada; Lovelace');`,
+	"001_create_users.down.sql": "DROP TABLE users;",
+	"002_add_email.up.sql":      "ALTER TABLE users ADD COLUMN email TEXT;",
+	"002_add_email.down.sql":    "ALTER TABLE users DROP COLUMN email;",
+	"README.md":                 "not a migration",
+}
+
+func TestMigrator_UpAndDown(t *testing.T) {
+	ctx := context.Background()
+	db := newInMemorySQLite(t)
+	migrator := newMigrator(t, db, writeMigrations(t, userMigrations))
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+	assert.Equal(t, "create_users", applied[0].Name)
+	assert.Equal(t, "add_email", applied[1].Name)
+
+	versions, err := migrator.Applied(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, versions)
+
+	var name string
+	require.NoError(t, db.QueryRow(ctx, "SELECT name FROM users WHERE email IS NULL").Scan(&name))
+	assert.Equal(t, "Ada; Lovelace", name)
+
+	// Nothing left to apply
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	// Roll back only the latest migration
+	rolledBack, err := migrator.Down(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, rolledBack, 1)
+	assert.Equal(t, int64(2), rolledBack[0].Version)
+
+	exists, err := db.TableExists(ctx, "users")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	_, err = db.Exec(ctx, "SELECT email FROM users")
+	assert.Error(t, err, "email column should be dropped")
+
+	pending, err := migrator.Pending(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, int64(2), pending[0].Version)
+
+	// Rolling back more than is applied stops at the first migration
+	rolledBack, err = migrator.Down(ctx, 5)
+	require.NoError(t, err)
+	require.Len(t, rolledBack, 1)
+	exists, err = db.TableExists(ctx, "users")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	versions, err = migrator.Applied(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+}
+
+func TestMigrator_FailedMigrationIsRolledBack(t *testing.T) {
+	ctx := context.Background()
+	db := newInMemorySQLite(t)
+	migrator := newMigrator(t, db, writeMigrations(t, map[string]string{
+		"1_ok.up.sql":     "CREATE TABLE a (id INTEGER);",
+		"2_broken.up.sql": "CREATE TABLE b (id INTEGER); INSERT INTO missing VALUES (1);",
+	}))
+
+	applied, err := migrator.Up(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "migration 2_broken failed")
+	require.Len(t, applied, 1)
+
+	exists, err := db.TableExists(ctx, "b")
+	require.NoError(t, err)
+	assert.False(t, exists, "statements of a failed migration must be rolled back")
+
+	versions, err := migrator.Applied(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1}, versions)
+}
+
+func TestMigrator_DownRequiresDownFile(t *testing.T) {
+	ctx := context.Background()
+	db := newInMemorySQLite(t)
+	migrator := newMigrator(t, db, writeMigrations(t, map[string]string{
+		"1_a.up.sql":   "CREATE TABLE a (id INTEGER);",
+		"1_a.down.sql": "DROP TABLE a;",
+		"2_b.up.sql":   "CREATE TABLE b (id INTEGER);",
+	}))
+	_, err := migrator.Up(ctx)
+	require.NoError(t, err)
+
+	_, err = migrator.Down(ctx, 2)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has no .down.sql file")
+
+	// Nothing was rolled back
+	versions, err := migrator.Applied(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, versions)
+
+	_, err = migrator.Down(ctx, 0)
+	assert.Error(t, err)
+}
+
+func TestLoadMigrations_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "bad file name",
+			files: map[string]string{"create_users.up.sql": "SELECT 1"},
+			want:  "invalid migration file name",
+		},
+		{
+			name:  "missing up file",
+			files: map[string]string{"3_x.down.sql": "SELECT 1"},
+			want:  "has no .up.sql file",
+		},
+		{
+			name: "duplicate version",
+			files: map[string]string{
+				"4_one.up.sql": "SELECT 1",
+				"4_two.up.sql": "SELECT 1",
+			},
+			want: "is used by both",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadMigrations(writeMigrations(t, tt.files))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+
+	_, err := LoadMigrations(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestSplitSQLStatements(t *testing.T) {
+	script := `CREATE TABLE t (v TEXT DEFAULT 'a;b');
+/* block; comment */ INSERT INTO t VALUES ('it''s; fine');
+-- line; comment
+CREATE FUNCTION f() RETURNS trigger AS $body$ BEGIN RETURN NULL; END; $body$ LANGUAGE plpgsql;
+SELECT "odd;name" FROM t;;
+SELECT 1`
+
+	assert.Equal(t, []string{
+		`CREATE TABLE t (v TEXT DEFAULT 'a;b')`,
+		`/* block; comment */ INSERT INTO t VALUES ('it''s; fine')`,
+		`-- line; comment
+CREATE FUNCTION f() RETURNS trigger AS $body$ BEGIN RETURN NULL; END; $body$ LANGUAGE plpgsql`,
+		`SELECT "odd;name" FROM t`,
+		`SELECT 1`,
+	}, SplitSQLStatements(script))
+}
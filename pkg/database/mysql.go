@@ -41,6 +41,7 @@ func SanitizeMySQLIdentifiers(names []string) ([]string, error) {
 type MySQLDB struct {
 	config *Config
 	db     *sql.DB
+	stmts  *stmtCache
 }
 
 // NewMySQLDB creates a new MySQL database instance
@@ -72,6 +73,7 @@ func (m *MySQLDB) Connect(ctx context.Context) error {
 	}
 
 	m.db = db
+	m.stmts = newStmtCache(db, m.config.StatementCacheSize)
 	return nil
 }
 
@@ -80,6 +82,9 @@ func (m *MySQLDB) Close() error {
 	if m.db == nil {
 		return nil
 	}
+	if m.stmts != nil {
+		m.stmts.Close()
+	}
 	return m.db.Close()
 }
 
@@ -96,6 +101,9 @@ func (m *MySQLDB) Query(ctx context.Context, query string, args ...interface{})
 	if m.db == nil {
 		return nil, fmt.Errorf("database not connected")
 	}
+	if m.stmts != nil {
+		return m.stmts.Query(ctx, query, args...)
+	}
 	return m.db.QueryContext(ctx, query, args...)
 }
 
@@ -104,6 +112,9 @@ func (m *MySQLDB) QueryRow(ctx context.Context, query string, args ...interface{
 	if m.db == nil {
 		return &sql.Row{}
 	}
+	if m.stmts != nil {
+		return m.stmts.QueryRow(ctx, query, args...)
+	}
 	return m.db.QueryRowContext(ctx, query, args...)
 }
 
@@ -112,6 +123,9 @@ func (m *MySQLDB) Exec(ctx context.Context, query string, args ...interface{}) (
 	if m.db == nil {
 		return nil, fmt.Errorf("database not connected")
 	}
+	if m.stmts != nil {
+		return m.stmts.Exec(ctx, query, args...)
+	}
 	return m.db.ExecContext(ctx, query, args...)
 }
 
@@ -3,16 +3,21 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
 	"time"
 )
 
+// ErrNilRow is returned when a Database's QueryRow returns a nil row
+var ErrNilRow = errors.New("database returned no row")
+
 // ORM provides a simple Object-Relational Mapping layer
 type ORM struct {
 	db    Database
 	table string
+	opts  *queryOptions // timeout and slow query log; nil for neither
 }
 
 // NewORM creates a new ORM instance for a table
@@ -33,6 +38,14 @@ type QueryBuilder struct {
 	offset     int
 	offsetSet  bool
 	joins      []Join
+	relations  []relation
+}
+
+// validOperators are the comparison operators allowed in a WHERE condition
+var validOperators = map[string]bool{
+	"=": true, "!=": true, "<>": true, "<": true, ">": true,
+	"<=": true, ">=": true, "LIKE": true, "ILIKE": true,
+	"IN": true, "NOT IN": true, "IS": true, "IS NOT": true,
 }
 
 // WhereCondition represents a WHERE clause condition
@@ -131,6 +144,12 @@ func (qb *QueryBuilder) Build() (string, []interface{}, error) {
 		return "", nil, fmt.Errorf("invalid table name: %w", err)
 	}
 
+	for _, r := range qb.relations {
+		if err := r.validate(); err != nil {
+			return "", nil, err
+		}
+	}
+
 	// Sanitize select columns
 	sanitizedSelectCols := make([]string, len(qb.selectCols))
 	for i, col := range qb.selectCols {
@@ -174,31 +193,11 @@ func (qb *QueryBuilder) Build() (string, []interface{}, error) {
 	}
 
 	// Build WHERE clause
-	var args []interface{}
-	if len(qb.whereConds) > 0 {
-		query += " WHERE "
-		for i, cond := range qb.whereConds {
-			if i > 0 {
-				query += " AND "
-			}
-			sanitizedColumn, err := SanitizeIdentifier(cond.Column)
-			if err != nil {
-				return "", nil, fmt.Errorf("invalid where column %q: %w", cond.Column, err)
-			}
-			// Validate operator (only allow safe operators)
-			operator := strings.ToUpper(strings.TrimSpace(cond.Operator))
-			validOperators := map[string]bool{
-				"=": true, "!=": true, "<>": true, "<": true, ">": true,
-				"<=": true, ">=": true, "LIKE": true, "ILIKE": true,
-				"IN": true, "NOT IN": true, "IS": true, "IS NOT": true,
-			}
-			if !validOperators[operator] {
-				return "", nil, fmt.Errorf("invalid operator: %s", cond.Operator)
-			}
-			query += fmt.Sprintf("%s %s $%d", sanitizedColumn, operator, i+1)
-			args = append(args, cond.Value)
-		}
+	where, args, err := whereClause(qb.whereConds)
+	if err != nil {
+		return "", nil, err
 	}
+	query += where
 
 	// Build ORDER BY clause
 	if qb.orderBy != "" {
@@ -235,13 +234,83 @@ func (qb *QueryBuilder) Build() (string, []interface{}, error) {
 	return query, args, nil
 }
 
+// whereClause builds the WHERE clause, with its leading space, for conds
+// joined by AND, or "" if there are none. Columns are sanitized, operators
+// validated and values passed as $n placeholders. IN and NOT IN take a list,
+// which becomes one placeholder per element; a single value is a list of
+// one.
+func whereClause(conds []WhereCondition) (string, []interface{}, error) {
+	if len(conds) == 0 {
+		return "", nil, nil
+	}
+
+	var args []interface{}
+	clauses := make([]string, len(conds))
+	for i, cond := range conds {
+		sanitizedColumn, err := SanitizeIdentifier(cond.Column)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid where column %q: %w", cond.Column, err)
+		}
+		// Validate operator (only allow safe operators)
+		operator := strings.ToUpper(strings.TrimSpace(cond.Operator))
+		if !validOperators[operator] {
+			return "", nil, fmt.Errorf("invalid operator: %s", cond.Operator)
+		}
+
+		if operator != "IN" && operator != "NOT IN" {
+			args = append(args, cond.Value)
+			clauses[i] = fmt.Sprintf("%s %s $%d", sanitizedColumn, operator, len(args))
+			continue
+		}
+
+		values := listValues(cond.Value)
+		if len(values) == 0 {
+			// Nothing is in an empty list
+			if operator == "IN" {
+				clauses[i] = "1 = 0"
+			} else {
+				clauses[i] = "1 = 1"
+			}
+			continue
+		}
+		placeholders := make([]string, len(values))
+		for j, value := range values {
+			args = append(args, value)
+			placeholders[j] = fmt.Sprintf("$%d", len(args))
+		}
+		clauses[i] = fmt.Sprintf("%s %s (%s)", sanitizedColumn, operator, strings.Join(placeholders, ", "))
+	}
+	return " WHERE " + strings.Join(clauses, " AND "), args, nil
+}
+
+// listValues returns the elements of a slice or array value, or the value
+// itself as the only element. A []byte is a single value.
+func listValues(value interface{}) []interface{} {
+	v := reflect.ValueOf(value)
+	if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() == reflect.Uint8 {
+		return []interface{}{value}
+	}
+	values := make([]interface{}, v.Len())
+	for i := range values {
+		values[i] = v.Index(i).Interface()
+	}
+	return values
+}
+
 // Get executes the query and returns results
 func (qb *QueryBuilder) Get(ctx context.Context) ([]map[string]interface{}, error) {
 	query, args, err := qb.Build()
 	if err != nil {
 		return nil, err
 	}
-	return qb.orm.Query(ctx, query, args...)
+	results, err := qb.orm.Query(ctx, query, args...)
+	if err != nil || len(qb.relations) == 0 {
+		return results, err
+	}
+	if err := qb.loadRelations(ctx, results); err != nil {
+		return nil, err
+	}
+	return results, nil
 }
 
 // First executes the query and returns the first result
@@ -269,13 +338,21 @@ func (o *ORM) FindAll(ctx context.Context) ([]map[string]interface{}, error) {
 
 // Query executes a raw SQL query
 func (o *ORM) Query(ctx context.Context, query string, args ...interface{}) ([]map[string]interface{}, error) {
-	rows, err := o.db.Query(ctx, query, args...)
+	var results []map[string]interface{}
+	err := o.traceQuery(ctx, query, func(ctx context.Context) error {
+		rows, err := o.db.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		results, err = scanRows(rows)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	return scanRows(rows)
+	return results, nil
 }
 
 // Create inserts a new record
@@ -309,15 +386,26 @@ func (o *ORM) Create(ctx context.Context, data map[string]interface{}) (map[stri
 		i++
 	}
 
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING *",
+	// Return the inserted columns and the generated id, in the order they
+	// are scanned
+	returning := strings.Join(sanitizedColumns, ", ")
+	if _, ok := data["id"]; !ok {
+		returning += `, "id"`
+		columns = append(columns, "id")
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING %s",
 		sanitizedTable,
 		strings.Join(sanitizedColumns, ", "),
-		strings.Join(placeholders, ", "))
-
-	row := o.db.QueryRow(ctx, query, values...)
+		strings.Join(placeholders, ", "),
+		returning)
 
-	// Get column names for scanning
-	result, err := scanRow(row, columns)
+	var result map[string]interface{}
+	err = o.traceQuery(ctx, query, func(ctx context.Context) error {
+		var err error
+		result, err = scanRow(o.db.QueryRow(ctx, query, values...), columns)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -340,6 +428,7 @@ func (o *ORM) Update(ctx context.Context, id interface{}, data map[string]interf
 	setClauses := make([]string, 0, len(data))
 	values := make([]interface{}, 0, len(data)+1)
 	columns := make([]string, 0, len(data))
+	returning := make([]string, 0, len(data)+1)
 
 	i := 1
 	for col, val := range data {
@@ -351,22 +440,29 @@ func (o *ORM) Update(ctx context.Context, id interface{}, data map[string]interf
 		setClauses = append(setClauses, fmt.Sprintf("%s = $%d", sanitizedCol, i))
 		values = append(values, val)
 		columns = append(columns, col)
+		returning = append(returning, sanitizedCol)
 		i++
 	}
 
 	values = append(values, id)
+	returning = append(returning, `"id"`)
 
-	query := fmt.Sprintf("UPDATE %s SET %s WHERE \"id\" = $%d RETURNING *",
+	// Return the updated columns and the id, in the order they are scanned
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE \"id\" = $%d RETURNING %s",
 		sanitizedTable,
 		strings.Join(setClauses, ", "),
-		i)
-
-	row := o.db.QueryRow(ctx, query, values...)
+		i,
+		strings.Join(returning, ", "))
 
 	// Add id to columns for scanning
 	columns = append(columns, "id")
 
-	result, err := scanRow(row, columns)
+	var result map[string]interface{}
+	err = o.traceQuery(ctx, query, func(ctx context.Context) error {
+		var err error
+		result, err = scanRow(o.db.QueryRow(ctx, query, values...), columns)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -383,21 +479,23 @@ func (o *ORM) Delete(ctx context.Context, id interface{}) error {
 	}
 
 	query := fmt.Sprintf("DELETE FROM %s WHERE \"id\" = $1", sanitizedTable)
-	result, err := o.db.Exec(ctx, query, id)
-	if err != nil {
-		return err
-	}
+	return o.traceQuery(ctx, query, func(ctx context.Context) error {
+		result, err := o.db.Exec(ctx, query, id)
+		if err != nil {
+			return err
+		}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
-	}
+		if rowsAffected == 0 {
+			return sql.ErrNoRows
+		}
 
-	return nil
+		return nil
+	})
 }
 
 // Count counts records matching the WHERE conditions
@@ -408,37 +506,20 @@ func (o *ORM) Count(ctx context.Context, whereConds ...WhereCondition) (int64, e
 		return 0, fmt.Errorf("invalid table name: %w", err)
 	}
 
-	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", sanitizedTable)
-
-	var args []interface{}
-	if len(whereConds) > 0 {
-		query += " WHERE "
-		for i, cond := range whereConds {
-			if i > 0 {
-				query += " AND "
-			}
-			// Sanitize column name
-			sanitizedColumn, err := SanitizeIdentifier(cond.Column)
-			if err != nil {
-				return 0, fmt.Errorf("invalid where column %q: %w", cond.Column, err)
-			}
-			// Validate operator (only allow safe operators)
-			operator := strings.ToUpper(strings.TrimSpace(cond.Operator))
-			validOperators := map[string]bool{
-				"=": true, "!=": true, "<>": true, "<": true, ">": true,
-				"<=": true, ">=": true, "LIKE": true, "ILIKE": true,
-				"IN": true, "NOT IN": true, "IS": true, "IS NOT": true,
-			}
-			if !validOperators[operator] {
-				return 0, fmt.Errorf("invalid operator: %s", cond.Operator)
-			}
-			query += fmt.Sprintf("%s %s $%d", sanitizedColumn, operator, i+1)
-			args = append(args, cond.Value)
-		}
+	where, args, err := whereClause(whereConds)
+	if err != nil {
+		return 0, err
 	}
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", sanitizedTable, where)
 
 	var count int64
-	err = o.db.QueryRow(ctx, query, args...).Scan(&count)
+	err = o.traceQuery(ctx, query, func(ctx context.Context) error {
+		row := o.db.QueryRow(ctx, query, args...)
+		if row == nil {
+			return ErrNilRow
+		}
+		return row.Scan(&count)
+	})
 	return count, err
 }
 
@@ -611,6 +692,10 @@ func scanRows(rows *sql.Rows) ([]map[string]interface{}, error) {
 
 // scanRow scans a single row into a map
 func scanRow(row *sql.Row, columns []string) (map[string]interface{}, error) {
+	if row == nil {
+		return nil, ErrNilRow
+	}
+
 	values := make([]interface{}, len(columns))
 	valuePtrs := make([]interface{}, len(columns))
 
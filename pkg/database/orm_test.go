@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,8 +16,18 @@ type MockDB struct {
 	execResult  sql.Result
 	queryErr    error
 	execErr     error
+	nilRow      bool // QueryRow returns nil, like a misbehaving driver
 }
 
+// failingConnector is a driver.Connector whose connections always fail with
+// err, so rows queried through it report err from Scan
+type failingConnector struct {
+	err error
+}
+
+func (c failingConnector) Connect(ctx context.Context) (driver.Conn, error) { return nil, c.err }
+func (c failingConnector) Driver() driver.Driver                            { return nil }
+
 func (m *MockDB) Connect(ctx context.Context) error { return nil }
 func (m *MockDB) Close() error                      { return nil }
 func (m *MockDB) Ping(ctx context.Context) error    { return nil }
@@ -25,8 +36,19 @@ func (m *MockDB) Query(ctx context.Context, query string, args ...interface{}) (
 	return nil, m.queryErr
 }
 
+// QueryRow returns a row whose Scan reports queryErr, or sql.ErrNoRows when
+// queryErr is unset
 func (m *MockDB) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	return nil
+	if m.nilRow {
+		return nil
+	}
+	err := m.queryErr
+	if err == nil {
+		err = sql.ErrNoRows
+	}
+	db := sql.OpenDB(failingConnector{err: err})
+	defer db.Close()
+	return db.QueryRowContext(ctx, query, args...)
 }
 
 func (m *MockDB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
@@ -159,6 +181,29 @@ func TestQueryBuilder_Build(t *testing.T) {
 	}
 }
 
+func TestQueryBuilder_BuildWhereIn(t *testing.T) {
+	orm := NewORM(&MockDB{}, "users")
+
+	query, args, err := orm.NewQueryBuilder().
+		Where("status", "=", "active").
+		Where("id", "IN", []interface{}{int64(1), int64(2), int64(3)}).
+		Where("role", "not in", []string{"bot"}).
+		Build()
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "users" WHERE "status" = $1 AND "id" IN ($2, $3, $4) AND "role" NOT IN ($5)`, query)
+	assert.Equal(t, []interface{}{"active", int64(1), int64(2), int64(3), "bot"}, args)
+
+	query, args, err = orm.NewQueryBuilder().Where("id", "IN", []interface{}{}).Build()
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "users" WHERE 1 = 0`, query, "nothing is in an empty list")
+	assert.Empty(t, args)
+
+	query, args, err = orm.NewQueryBuilder().Where("id", "IN", int64(7)).Build()
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "users" WHERE "id" IN ($1)`, query, "a single value is a list of one")
+	assert.Equal(t, []interface{}{int64(7)}, args)
+}
+
 func TestWhereCondition(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -380,6 +425,33 @@ func TestORM_Count(t *testing.T) {
 	assert.Equal(t, "users", orm.table)
 }
 
+func TestORM_NilRow(t *testing.T) {
+	orm := NewORM(&MockDB{nilRow: true}, "users")
+	ctx := context.Background()
+
+	_, err := orm.Count(ctx)
+	assert.ErrorIs(t, err, ErrNilRow)
+
+	_, err = orm.Exists(ctx, WhereCondition{Column: "email", Operator: "=", Value: "a@b.c"})
+	assert.ErrorIs(t, err, ErrNilRow)
+
+	_, err = orm.Create(ctx, map[string]interface{}{"name": "test"})
+	assert.ErrorIs(t, err, ErrNilRow)
+
+	_, err = orm.Update(ctx, 1, map[string]interface{}{"name": "test"})
+	assert.ErrorIs(t, err, ErrNilRow)
+}
+
+func TestORM_QueryRowError(t *testing.T) {
+	orm := NewORM(&MockDB{}, "users")
+	_, err := orm.Count(context.Background())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	orm = NewORM(&MockDB{queryErr: assert.AnError}, "users")
+	_, err = orm.Create(context.Background(), map[string]interface{}{"name": "test"})
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
 func TestTimestamp(t *testing.T) {
 	ts := Timestamp()
 	assert.Greater(t, ts, int64(0))
@@ -58,6 +58,7 @@ func ValidateIdentifier(name string) error {
 type PostgresDB struct {
 	config *Config
 	db     *sql.DB
+	stmts  *stmtCache
 }
 
 // NewPostgresDB creates a new PostgreSQL database instance
@@ -89,6 +90,7 @@ func (p *PostgresDB) Connect(ctx context.Context) error {
 	}
 
 	p.db = db
+	p.stmts = newStmtCache(db, p.config.StatementCacheSize)
 	return nil
 }
 
@@ -97,6 +99,9 @@ func (p *PostgresDB) Close() error {
 	if p.db == nil {
 		return nil
 	}
+	if p.stmts != nil {
+		p.stmts.Close()
+	}
 	return p.db.Close()
 }
 
@@ -113,6 +118,9 @@ func (p *PostgresDB) Query(ctx context.Context, query string, args ...interface{
 	if p.db == nil {
 		return nil, fmt.Errorf("database not connected")
 	}
+	if p.stmts != nil {
+		return p.stmts.Query(ctx, query, args...)
+	}
 	return p.db.QueryContext(ctx, query, args...)
 }
 
@@ -122,6 +130,9 @@ func (p *PostgresDB) QueryRow(ctx context.Context, query string, args ...interfa
 		// Return a row that will error when scanned
 		return &sql.Row{}
 	}
+	if p.stmts != nil {
+		return p.stmts.QueryRow(ctx, query, args...)
+	}
 	return p.db.QueryRowContext(ctx, query, args...)
 }
 
@@ -130,6 +141,9 @@ func (p *PostgresDB) Exec(ctx context.Context, query string, args ...interface{}
 	if p.db == nil {
 		return nil, fmt.Errorf("database not connected")
 	}
+	if p.stmts != nil {
+		return p.stmts.Exec(ctx, query, args...)
+	}
 	return p.db.ExecContext(ctx, query, args...)
 }
 
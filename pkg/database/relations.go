@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// relation describes related records loaded along with a query's results.
+// A hasMany relation attaches the rows of table whose foreignKey is a
+// result's id as an array; a belongsTo relation attaches the row of table
+// whose id is the result's foreignKey, or null if there is none.
+type relation struct {
+	table      string
+	foreignKey string
+	as         string // field the related records are attached as
+	belongsTo  bool
+}
+
+// validate checks the relation's identifiers the way Build checks the
+// query's own
+func (r relation) validate() error {
+	if _, err := SanitizeIdentifier(r.table); err != nil {
+		return fmt.Errorf("invalid relation table %q: %w", r.table, err)
+	}
+	if _, err := SanitizeIdentifier(r.foreignKey); err != nil {
+		return fmt.Errorf("invalid relation foreign key %q: %w", r.foreignKey, err)
+	}
+	if r.as == "" {
+		return fmt.Errorf("relation with %q needs a field name", r.table)
+	}
+	return nil
+}
+
+// parentColumn is the column of the query's results that related records
+// are matched on
+func (r relation) parentColumn() string {
+	if r.belongsTo {
+		return r.foreignKey
+	}
+	return "id"
+}
+
+// relatedColumn is the column of the related table matched against
+// parentColumn
+func (r relation) relatedColumn() string {
+	if r.belongsTo {
+		return "id"
+	}
+	return r.foreignKey
+}
+
+// keys returns the distinct non-null values of parentColumn in parents
+func (r relation) keys(parents []map[string]interface{}) []interface{} {
+	seen := make(map[interface{}]bool)
+	var keys []interface{}
+	for _, parent := range parents {
+		value := parent[r.parentColumn()]
+		key := relationKey(value)
+		if value == nil || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, value)
+	}
+	return keys
+}
+
+// attach sets the relation's field on each parent from related, the records
+// of the related table matching the parents' keys
+func (r relation) attach(parents []map[string]interface{}, related []map[string]interface{}) {
+	byKey := make(map[interface{}][]interface{})
+	for _, record := range related {
+		key := relationKey(record[r.relatedColumn()])
+		byKey[key] = append(byKey[key], record)
+	}
+
+	for _, parent := range parents {
+		value := parent[r.parentColumn()]
+		var matches []interface{}
+		if value != nil {
+			matches = byKey[relationKey(value)]
+		}
+		switch {
+		case !r.belongsTo:
+			if matches == nil {
+				matches = []interface{}{}
+			}
+			parent[r.as] = matches
+		case len(matches) > 0:
+			parent[r.as] = matches[0]
+		default:
+			parent[r.as] = nil
+		}
+	}
+}
+
+// relationKey returns the map key for a key column value. Numbers of any
+// type compare equal, since drivers and scripts disagree on integer types.
+func relationKey(value interface{}) interface{} {
+	if f, ok := toFloat(value); ok {
+		return f
+	}
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return value
+}
+
+// With loads, for each result, the rows of table whose foreignKey column
+// holds the result's id, and attaches them as an array field named after
+// table. The rows for all results are fetched in one query.
+func (qb *QueryBuilder) With(table, foreignKey string) *QueryBuilder {
+	qb.relations = append(qb.relations, relation{table: table, foreignKey: foreignKey, as: table})
+	return qb
+}
+
+// BelongsTo loads, for each result, the row of table whose id is the
+// result's foreignKey column, and attaches it as the field as, or null if
+// there is none. The rows for all results are fetched in one query.
+func (qb *QueryBuilder) BelongsTo(table, foreignKey, as string) *QueryBuilder {
+	qb.relations = append(qb.relations, relation{table: table, foreignKey: foreignKey, as: as, belongsTo: true})
+	return qb
+}
+
+// loadRelations runs one IN query per relation for the related records of
+// results and attaches them
+func (qb *QueryBuilder) loadRelations(ctx context.Context, results []map[string]interface{}) error {
+	for _, r := range qb.relations {
+		keys := r.keys(results)
+		if len(keys) == 0 {
+			r.attach(results, nil)
+			continue
+		}
+
+		related := &ORM{db: qb.orm.db, table: r.table, opts: qb.orm.opts}
+		rows, err := related.NewQueryBuilder().Where(r.relatedColumn(), "IN", keys).Get(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", r.table, err)
+		}
+		r.attach(results, rows)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingDB is a Database that counts the queries run through it
+type countingDB struct {
+	Database
+	queries atomic.Int64
+}
+
+func (d *countingDB) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	d.queries.Add(1)
+	return d.Database.Query(ctx, query, args...)
+}
+
+func (d *countingDB) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	d.queries.Add(1)
+	return d.Database.QueryRow(ctx, query, args...)
+}
+
+func (d *countingDB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	d.queries.Add(1)
+	return d.Database.Exec(ctx, query, args...)
+}
+
+// newBlogHandler returns a handler on SQLite with users and their posts,
+// counting the queries made through it
+func newBlogHandler(t *testing.T) (*Handler, *countingDB) {
+	t.Helper()
+	base := newSQLiteHandler(t)
+	ctx := context.Background()
+	_, err := base.db.Exec(ctx, `CREATE TABLE posts (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER, title TEXT)`)
+	require.NoError(t, err)
+	_, err = base.db.Exec(ctx, `INSERT INTO users (name) VALUES ('ada'), ('bob'), ('grace')`)
+	require.NoError(t, err)
+	_, err = base.db.Exec(ctx, `INSERT INTO posts (user_id, title) VALUES (1, 'engines'), (3, 'compilers'), (1, 'notes'), (NULL, 'orphan')`)
+	require.NoError(t, err)
+
+	db := &countingDB{Database: base.db}
+	return NewHandler(db), db
+}
+
+func postTitles(t *testing.T, posts interface{}) []string {
+	t.Helper()
+	list, ok := posts.([]interface{})
+	require.True(t, ok, "posts is %T", posts)
+	titles := make([]string, len(list))
+	for i, post := range list {
+		titles[i] = post.(map[string]interface{})["title"].(string)
+	}
+	return titles
+}
+
+func TestQueryBuilder_With(t *testing.T) {
+	handler, db := newBlogHandler(t)
+
+	users, err := handler.Table("users").With("posts", "user_id").Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), db.queries.Load(), "one query for the users and one for their posts")
+
+	require.Len(t, users, 3)
+	assert.Equal(t, []string{"engines", "notes"}, postTitles(t, users[0]["posts"]))
+	assert.Equal(t, []string{}, postTitles(t, users[1]["posts"]), "no posts is an empty array")
+	assert.Equal(t, []string{"compilers"}, postTitles(t, users[2]["posts"]))
+}
+
+func TestQueryBuilder_BelongsTo(t *testing.T) {
+	handler, db := newBlogHandler(t)
+
+	posts, err := handler.Table("posts").BelongsTo("users", "user_id", "author").OrderBy("id", "ASC").Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), db.queries.Load())
+
+	require.Len(t, posts, 4)
+	assert.Equal(t, "ada", posts[0]["author"].(map[string]interface{})["name"])
+	assert.Equal(t, "grace", posts[1]["author"].(map[string]interface{})["name"])
+	assert.Equal(t, "ada", posts[2]["author"].(map[string]interface{})["name"])
+	assert.Contains(t, posts[3], "author")
+	assert.Nil(t, posts[3]["author"], "a null foreign key has no author")
+}
+
+func TestQueryBuilder_RelationsWithoutResults(t *testing.T) {
+	handler, db := newBlogHandler(t)
+
+	users, err := handler.Table("users").Where("name", "=", "eve").With("posts", "user_id").Get(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, users)
+	assert.Equal(t, int64(1), db.queries.Load(), "nothing to load relations for")
+}
+
+func TestQueryBuilder_FirstLoadsRelations(t *testing.T) {
+	handler, _ := newBlogHandler(t)
+
+	user, err := handler.Table("users").With("posts", "user_id").Where("name", "=", "grace").First(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"compilers"}, postTitles(t, user["posts"]))
+}
+
+func TestQueryBuilder_InvalidRelation(t *testing.T) {
+	handler, db := newBlogHandler(t)
+	users := handler.Table("users")
+
+	_, err := users.With("posts; DROP TABLE users", "user_id").Get(context.Background())
+	assert.ErrorContains(t, err, "invalid relation table")
+	_, err = users.BelongsTo("posts", "user_id--", "author").Get(context.Background())
+	assert.ErrorContains(t, err, "invalid relation foreign key")
+	_, err = users.BelongsTo("posts", "user_id", "").Get(context.Background())
+	assert.ErrorContains(t, err, "needs a field name")
+	assert.Equal(t, int64(0), db.queries.Load(), "invalid relations are rejected before querying")
+}
+
+func TestInterpreterTable_Relations(t *testing.T) {
+	handler, _ := newBlogHandler(t)
+	users := NewInterpreterDatabase(handler).Table("users")
+
+	records, err := users.With("posts", "user_id").OrderBy("name", "DESC").Limit(1).Get()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, []string{"compilers"}, postTitles(t, records[0].(map[string]interface{})["posts"]))
+
+	record, err := users.Where("name", "=", "eve").With("posts", "user_id").First()
+	require.NoError(t, err)
+	assert.Nil(t, record)
+}
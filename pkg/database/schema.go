@@ -0,0 +1,116 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+)
+
+// Column is a table column derived from a field of a GLYPH type
+type Column struct {
+	Name string
+	Type string // Column type with constraints, e.g. "TEXT NOT NULL"
+}
+
+// TableName returns the table that rows of a GLYPH type are stored in: the
+// lower-cased, pluralized type name, so ": User" maps to db.users
+func TableName(typeName string) string {
+	name := strings.ToLower(typeName)
+	switch {
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"),
+		strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !strings.ContainsRune("aeiou", rune(name[len(name)-2])):
+		return name[:len(name)-1] + "ies"
+	}
+	return name + "s"
+}
+
+// ColumnsForType maps the fields of a type to columns, in declaration order.
+// int, str, bool and float map to BIGINT, TEXT, BOOLEAN and DOUBLE
+// PRECISION (timestamp is an int); required fields are NOT NULL and a field
+// named id becomes the primary key. Fields of other types, such as arrays
+// or nested types, have no column mapping and are reported as errors.
+func ColumnsForType(typeDef ast.TypeDef, dialect string) ([]Column, error) {
+	switch dialect {
+	case "postgres", "postgresql", "sqlite", "sqlite3", "mysql":
+	default:
+		return nil, fmt.Errorf("unsupported SQL dialect: %s", dialect)
+	}
+	if len(typeDef.TypeParams) > 0 {
+		return nil, fmt.Errorf("type %s is generic and cannot be stored in a table", typeDef.Name)
+	}
+	if len(typeDef.Fields) == 0 {
+		return nil, fmt.Errorf("type %s has no fields", typeDef.Name)
+	}
+
+	columns := make([]Column, 0, len(typeDef.Fields))
+	for _, field := range typeDef.Fields {
+		fieldType := field.TypeAnnotation
+		required := field.Required
+		if opt, ok := fieldType.(ast.OptionalType); ok {
+			fieldType = opt.InnerType
+			required = false
+		}
+
+		var colType string
+		switch t := fieldType.(type) {
+		case ast.IntType:
+			colType = "BIGINT"
+		case ast.StringType:
+			colType = "TEXT"
+		case ast.BoolType:
+			colType = "BOOLEAN"
+		case ast.FloatType:
+			colType = "DOUBLE PRECISION"
+		case ast.NamedType:
+			if t.Name != "timestamp" {
+				return nil, fmt.Errorf("field %s.%s has type %s, which has no column mapping", typeDef.Name, field.Name, t.Name)
+			}
+			colType = "BIGINT"
+		default:
+			return nil, fmt.Errorf("field %s.%s has a type with no column mapping", typeDef.Name, field.Name)
+		}
+
+		if field.Name == "id" {
+			colType += " PRIMARY KEY"
+		} else if required {
+			colType += " NOT NULL"
+		}
+		columns = append(columns, Column{Name: field.Name, Type: colType})
+	}
+	return columns, nil
+}
+
+// DDLForType returns the CREATE TABLE statement for a type in the given
+// dialect (postgres, sqlite or mysql); see ColumnsForType for the mapping
+func DDLForType(typeDef ast.TypeDef, dialect string) (string, error) {
+	columns, err := ColumnsForType(typeDef, dialect)
+	if err != nil {
+		return "", err
+	}
+
+	quote := SanitizeIdentifier
+	switch dialect {
+	case "sqlite", "sqlite3":
+		quote = SanitizeSQLiteIdentifier
+	case "mysql":
+		quote = SanitizeMySQLIdentifier
+	}
+
+	table, err := quote(TableName(typeDef.Name))
+	if err != nil {
+		return "", fmt.Errorf("invalid table name for type %s: %w", typeDef.Name, err)
+	}
+	defs := make([]string, len(columns))
+	for i, col := range columns {
+		name, err := quote(col.Name)
+		if err != nil {
+			return "", fmt.Errorf("invalid column name %q: %w", col.Name, err)
+		}
+		defs[i] = "  " + name + " " + col.Type
+	}
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n)", table, strings.Join(defs, ",\n")), nil
+}
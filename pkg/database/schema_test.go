@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseTypeDef parses source containing a single type definition
+func parseTypeDef(t *testing.T, source string) ast.TypeDef {
+	t.Helper()
+	tokens, err := parser.NewLexer(source).Tokenize()
+	require.NoError(t, err)
+	module, err := parser.NewParser(tokens).Parse()
+	require.NoError(t, err)
+	require.Len(t, module.Items, 1)
+	typeDef, ok := module.Items[0].(*ast.TypeDef)
+	require.True(t, ok, "expected a type definition, got %T", module.Items[0])
+	return *typeDef
+}
+
+const userType = `: User {
+  id: int!
+  name: str!
+  email: str
+  score: float!
+  active: bool
+  nickname: str?
+  createdAt: timestamp!
+}`
+
+func TestDDLForType(t *testing.T) {
+	user := parseTypeDef(t, userType)
+
+	tests := []struct {
+		dialect string
+		want    string
+	}{
+		{"postgres", `CREATE TABLE IF NOT EXISTS "users" (
+  "id" BIGINT PRIMARY KEY,
+  "name" TEXT NOT NULL,
+  "email" TEXT,
+  "score" DOUBLE PRECISION NOT NULL,
+  "active" BOOLEAN,
+  "nickname" TEXT,
+  "createdAt" BIGINT NOT NULL
+)`},
+		{"mysql", "CREATE TABLE IF NOT EXISTS `users` (\n" +
+			"  `id` BIGINT PRIMARY KEY,\n" +
+			"  `name` TEXT NOT NULL,\n" +
+			"  `email` TEXT,\n" +
+			"  `score` DOUBLE PRECISION NOT NULL,\n" +
+			"  `active` BOOLEAN,\n" +
+			"  `nickname` TEXT,\n" +
+			"  `createdAt` BIGINT NOT NULL\n" +
+			")"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			ddl, err := DDLForType(user, tt.dialect)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ddl)
+		})
+	}
+}
+
+func TestDDLForType_RunsOnSQLite(t *testing.T) {
+	ctx := context.Background()
+	db := newInMemorySQLite(t)
+
+	ddl, err := DDLForType(parseTypeDef(t, userType), "sqlite")
+	require.NoError(t, err)
+	_, err = db.Exec(ctx, ddl)
+	require.NoError(t, err)
+
+	_, err = db.Exec(ctx, `INSERT INTO users (id, name, score, "createdAt") VALUES (1, 'Ada', 1.5, 0)`)
+	require.NoError(t, err)
+	_, err = db.Exec(ctx, `INSERT INTO users (id, score, "createdAt") VALUES (2, 1.5, 0)`)
+	assert.Error(t, err, "name is required")
+}
+
+func TestDDLForType_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		dialect string
+		want    string
+	}{
+		{"array field", ": Post { tags: [str] }", "postgres", "Post.tags has a type with no column mapping"},
+		{"nested type", ": Post { author: User! }", "postgres", "Post.author has type User"},
+		{"generic type", ": Box<T> { value: T }", "postgres", "generic"},
+		{"unknown dialect", ": Post { id: int! }", "oracle", "unsupported SQL dialect"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DDLForType(parseTypeDef(t, tt.source), tt.dialect)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestTableName(t *testing.T) {
+	for typeName, want := range map[string]string{
+		"User":     "users",
+		"Address":  "addresses",
+		"Box":      "boxes",
+		"Match":    "matches",
+		"Category": "categories",
+		"Day":      "days",
+	} {
+		assert.Equal(t, want, TableName(typeName), typeName)
+	}
+}
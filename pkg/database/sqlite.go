@@ -41,6 +41,7 @@ func SanitizeSQLiteIdentifiers(names []string) ([]string, error) {
 type SQLiteDB struct {
 	config *Config
 	db     *sql.DB
+	stmts  *stmtCache
 }
 
 // NewSQLiteDB creates a new SQLite database instance
@@ -83,6 +84,7 @@ func (s *SQLiteDB) Connect(ctx context.Context) error {
 	}
 
 	s.db = db
+	s.stmts = newStmtCache(db, s.config.StatementCacheSize)
 	return nil
 }
 
@@ -91,6 +93,9 @@ func (s *SQLiteDB) Close() error {
 	if s.db == nil {
 		return nil
 	}
+	if s.stmts != nil {
+		s.stmts.Close()
+	}
 	return s.db.Close()
 }
 
@@ -107,6 +112,9 @@ func (s *SQLiteDB) Query(ctx context.Context, query string, args ...interface{})
 	if s.db == nil {
 		return nil, fmt.Errorf("database not connected")
 	}
+	if s.stmts != nil {
+		return s.stmts.Query(ctx, query, args...)
+	}
 	return s.db.QueryContext(ctx, query, args...)
 }
 
@@ -115,6 +123,9 @@ func (s *SQLiteDB) QueryRow(ctx context.Context, query string, args ...interface
 	if s.db == nil {
 		return &sql.Row{}
 	}
+	if s.stmts != nil {
+		return s.stmts.QueryRow(ctx, query, args...)
+	}
 	return s.db.QueryRowContext(ctx, query, args...)
 }
 
@@ -123,6 +134,9 @@ func (s *SQLiteDB) Exec(ctx context.Context, query string, args ...interface{})
 	if s.db == nil {
 		return nil, fmt.Errorf("database not connected")
 	}
+	if s.stmts != nil {
+		return s.stmts.Exec(ctx, query, args...)
+	}
 	return s.db.ExecContext(ctx, query, args...)
 }
 
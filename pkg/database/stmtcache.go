@@ -0,0 +1,215 @@
+package database
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+)
+
+// DefaultStatementCacheSize is the number of prepared statements a
+// connection string keeps unless statement_cache_size says otherwise
+const DefaultStatementCacheSize = 100
+
+// stmtCache keeps up to size prepared statements keyed by SQL text, so a
+// query run repeatedly is prepared once and planned once. The least recently
+// used statement is evicted to make room for a new one. It is closed once no
+// query is starting on it; database/sql then waits for rows still reading
+// from it.
+//
+// Only single SELECT, INSERT, UPDATE, DELETE and WITH statements are
+// cached; schema changes and multi-statement scripts always run directly.
+// Preparing is an optimization, never a requirement: a query that cannot be
+// prepared runs directly. If the server rejects a prepare that the same
+// query then runs without, as PgBouncer does in transaction pooling mode,
+// the cache turns itself off. It also turns off when closed, so queries
+// still starting as their connection closes run directly.
+type stmtCache struct {
+	db   *sql.DB
+	size int
+
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	order    *list.List // of *stmtEntry, most recently used first
+	disabled bool       // Turned off by a rejected prepare or by Close
+}
+
+type stmtEntry struct {
+	query   string
+	stmt    *sql.Stmt
+	users   int  // callers between statement and release
+	evicted bool // closed as soon as the last user releases it
+}
+
+// newStmtCache returns a cache of statements prepared on db, or nil if size
+// is not positive. A nil cache runs every query directly.
+func newStmtCache(db *sql.DB, size int) *stmtCache {
+	if size <= 0 {
+		return nil
+	}
+	return &stmtCache{
+		db:      db,
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Query runs query through its cached statement
+func (c *stmtCache) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	entry, prepareErr := c.statement(ctx, query)
+	if entry == nil {
+		rows, err := c.db.QueryContext(ctx, query, args...)
+		c.directResult(prepareErr, err)
+		return rows, err
+	}
+	defer c.release(entry)
+	return entry.stmt.QueryContext(ctx, args...)
+}
+
+// QueryRow runs query through its cached statement
+func (c *stmtCache) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	entry, _ := c.statement(ctx, query)
+	if entry == nil {
+		return c.db.QueryRowContext(ctx, query, args...)
+	}
+	defer c.release(entry)
+	return entry.stmt.QueryRowContext(ctx, args...)
+}
+
+// Exec runs query through its cached statement
+func (c *stmtCache) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	entry, prepareErr := c.statement(ctx, query)
+	if entry == nil {
+		result, err := c.db.ExecContext(ctx, query, args...)
+		c.directResult(prepareErr, err)
+		return result, err
+	}
+	defer c.release(entry)
+	return entry.stmt.ExecContext(ctx, args...)
+}
+
+// Len returns the number of cached statements
+func (c *stmtCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Close closes every cached statement and leaves the cache empty and
+// turned off
+func (c *stmtCache) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disabled = true
+	for c.order.Len() > 0 {
+		c.evictLocked(c.order.Back())
+	}
+}
+
+// statement returns the entry for query, preparing the statement if it is
+// not cached, and holds it until release. It returns nil, with the prepare
+// error if there was one, when the query should run directly.
+func (c *stmtCache) statement(ctx context.Context, query string) (*stmtEntry, error) {
+	if !cacheableQuery(query) {
+		return nil, nil
+	}
+
+	c.mu.Lock()
+	if c.disabled {
+		c.mu.Unlock()
+		return nil, nil
+	}
+	if elem, ok := c.entries[query]; ok {
+		defer c.mu.Unlock()
+		return c.useLocked(elem), nil
+	}
+	c.mu.Unlock()
+
+	// Prepare without holding the lock, so a slow round trip does not
+	// block queries whose statements are already cached
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[query]; ok {
+		// Another caller prepared the same query first
+		stmt.Close()
+		return c.useLocked(elem), nil
+	}
+	if c.disabled {
+		stmt.Close()
+		return nil, nil
+	}
+	elem := c.order.PushFront(&stmtEntry{query: query, stmt: stmt})
+	c.entries[query] = elem
+	if c.order.Len() > c.size {
+		c.evictLocked(c.order.Back())
+	}
+	return c.useLocked(elem), nil
+}
+
+func (c *stmtCache) useLocked(elem *list.Element) *stmtEntry {
+	c.order.MoveToFront(elem)
+	entry := elem.Value.(*stmtEntry)
+	entry.users++
+	return entry
+}
+
+// release ends a use of entry, closing its statement if it was evicted
+// while in use
+func (c *stmtCache) release(entry *stmtEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.users--
+	if entry.evicted && entry.users == 0 {
+		entry.stmt.Close()
+	}
+}
+
+// evictLocked removes elem from the cache. Its statement is closed now if
+// no one is using it, and otherwise by the last release.
+func (c *stmtCache) evictLocked(elem *list.Element) {
+	entry := elem.Value.(*stmtEntry)
+	c.order.Remove(elem)
+	delete(c.entries, entry.query)
+	entry.evicted = true
+	if entry.users == 0 {
+		entry.stmt.Close()
+	}
+}
+
+// directResult turns the cache off when a query that could not be prepared
+// ran fine without preparing, since the server is rejecting prepares
+func (c *stmtCache) directResult(prepareErr, err error) {
+	if prepareErr == nil || err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disabled = true
+	for c.order.Len() > 0 {
+		c.evictLocked(c.order.Back())
+	}
+}
+
+// cacheableQuery reports whether query is a single statement worth preparing
+func cacheableQuery(query string) bool {
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	if strings.Contains(query, ";") {
+		return false
+	}
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH":
+		return true
+	}
+	return false
+}
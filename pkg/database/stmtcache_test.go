@@ -0,0 +1,305 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCachedSQLite opens a file-backed SQLite database with a statement cache
+// of the given size and an items table holding ids 1 to 10
+func newCachedSQLite(tb testing.TB, size int) *SQLiteDB {
+	tb.Helper()
+	db := NewSQLiteDB(&Config{
+		Driver:             "sqlite",
+		Database:           filepath.Join(tb.TempDir(), "cache.db"),
+		StatementCacheSize: size,
+	})
+	ctx := context.Background()
+	require.NoError(tb, db.Connect(ctx))
+	tb.Cleanup(func() { db.Close() })
+
+	_, err := db.Exec(ctx, `CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)`)
+	require.NoError(tb, err)
+	for i := 1; i <= 10; i++ {
+		_, err := db.Exec(ctx, `INSERT INTO items (id, name) VALUES (?, ?)`, i, fmt.Sprintf("item-%d", i))
+		require.NoError(tb, err)
+	}
+	return db
+}
+
+func itemName(t *testing.T, db *SQLiteDB, query string, id int) string {
+	t.Helper()
+	var name string
+	require.NoError(t, db.QueryRow(context.Background(), query, id).Scan(&name))
+	return name
+}
+
+func TestStatementCache_ReusesStatements(t *testing.T) {
+	db := newCachedSQLite(t, 10)
+	require.NotNil(t, db.stmts)
+	// The INSERT used for seeding is the only statement so far
+	assert.Equal(t, 1, db.stmts.Len())
+
+	for id := 1; id <= 3; id++ {
+		assert.Equal(t, fmt.Sprintf("item-%d", id), itemName(t, db, `SELECT name FROM items WHERE id = ?`, id))
+	}
+	assert.Equal(t, 2, db.stmts.Len())
+
+	rows, err := db.Query(context.Background(), `SELECT name FROM items WHERE id = ?`, 4)
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	rows.Close()
+
+	result, err := db.Exec(context.Background(), `UPDATE items SET name = ? WHERE id = ?`, "renamed", 4)
+	require.NoError(t, err)
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+	assert.Equal(t, 3, db.stmts.Len())
+}
+
+func TestStatementCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	db := newCachedSQLite(t, 2)
+	byID := `SELECT name FROM items WHERE id = ?`
+	byName := `SELECT id FROM items WHERE name = ?`
+	count := `SELECT COUNT(*) FROM items WHERE id > ?`
+
+	itemName(t, db, byID, 1)
+	var id, n int
+	require.NoError(t, db.QueryRow(context.Background(), byName, "item-2").Scan(&id))
+	itemName(t, db, byID, 1) // byID is now more recent than byName
+	require.NoError(t, db.QueryRow(context.Background(), count, 5).Scan(&n))
+
+	assert.Equal(t, 2, db.stmts.Len())
+	assert.Contains(t, db.stmts.entries, byID)
+	assert.Contains(t, db.stmts.entries, count)
+	assert.NotContains(t, db.stmts.entries, byName)
+
+	// An evicted query is prepared again when it is next used
+	require.NoError(t, db.QueryRow(context.Background(), byName, "item-3").Scan(&id))
+	assert.Equal(t, 3, id)
+	assert.Equal(t, 5, n)
+}
+
+func TestStatementCache_EvictionKeepsOpenRows(t *testing.T) {
+	db := newCachedSQLite(t, 1)
+	// A second connection lets the evicting query run while rows are open
+	db.db.SetMaxOpenConns(2)
+	ctx := context.Background()
+
+	rows, err := db.Query(ctx, `SELECT name FROM items WHERE id <= ? ORDER BY id`, 3)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	_, err = db.Exec(ctx, `UPDATE items SET name = name WHERE id = ?`, 1)
+	require.NoError(t, err)
+	assert.NotContains(t, db.stmts.entries, `SELECT name FROM items WHERE id <= ? ORDER BY id`)
+
+	var names []string
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"item-1", "item-2", "item-3"}, names)
+}
+
+func TestStatementCache_EvictionWaitsForUsers(t *testing.T) {
+	db := newCachedSQLite(t, 1)
+	ctx := context.Background()
+
+	entry, err := db.stmts.statement(ctx, `SELECT name FROM items WHERE id = ?`)
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+
+	// Preparing another query evicts the entry while it is in use
+	var n int
+	require.NoError(t, db.QueryRow(ctx, `SELECT COUNT(*) FROM items WHERE id > ?`, 0).Scan(&n))
+	assert.True(t, entry.evicted)
+
+	var name string
+	require.NoError(t, entry.stmt.QueryRowContext(ctx, 1).Scan(&name))
+	assert.Equal(t, "item-1", name)
+
+	db.stmts.release(entry)
+	assert.Error(t, entry.stmt.QueryRowContext(ctx, 1).Scan(&name), "the last release closes the statement")
+}
+
+func TestStatementCache_SkipsUncacheableQueries(t *testing.T) {
+	db := newCachedSQLite(t, 10)
+	ctx := context.Background()
+	before := db.stmts.Len()
+
+	_, err := db.Exec(ctx, `CREATE TABLE tags (name TEXT)`)
+	require.NoError(t, err)
+	_, err = db.Exec(ctx, `INSERT INTO tags (name) VALUES ('a'); INSERT INTO tags (name) VALUES ('b')`)
+	require.NoError(t, err)
+	assert.Equal(t, before, db.stmts.Len())
+
+	var n int
+	require.NoError(t, db.QueryRow(ctx, `SELECT COUNT(*) FROM tags`).Scan(&n))
+	assert.Equal(t, 2, n)
+}
+
+func TestCacheableQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"SELECT * FROM users WHERE id = $1", true},
+		{"  select *\nfrom users;", true},
+		{"INSERT INTO users (name) VALUES ($1) RETURNING *", true},
+		{"UPDATE users SET name = $1", true},
+		{"DELETE FROM users WHERE id = $1", true},
+		{"WITH recent AS (SELECT 1) SELECT * FROM recent", true},
+		{"CREATE TABLE users (id INTEGER)", false},
+		{"DROP TABLE users", false},
+		{"BEGIN", false},
+		{"SELECT 1; SELECT 2", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, cacheableQuery(tt.query), tt.query)
+	}
+}
+
+func TestStatementCache_PrepareFailures(t *testing.T) {
+	t.Run("an invalid query fails without disabling the cache", func(t *testing.T) {
+		db := newCachedSQLite(t, 10)
+		_, err := db.Query(context.Background(), `SELECT missing FROM items`)
+		assert.Error(t, err)
+		assert.False(t, db.stmts.disabled)
+	})
+
+	t.Run("rejected prepares turn the cache off", func(t *testing.T) {
+		db := newCachedSQLite(t, 10)
+		require.Equal(t, 1, db.stmts.Len())
+
+		// A prepare error followed by a successful direct query is what a
+		// server that does not support prepared statements looks like
+		db.stmts.directResult(errors.New("prepared statements are not supported"), nil)
+		assert.True(t, db.stmts.disabled)
+		assert.Equal(t, 0, db.stmts.Len())
+
+		assert.Equal(t, "item-1", itemName(t, db, `SELECT name FROM items WHERE id = ?`, 1))
+		assert.Equal(t, 0, db.stmts.Len())
+	})
+}
+
+func TestStatementCache_Disabled(t *testing.T) {
+	db := newCachedSQLite(t, 0)
+	assert.Nil(t, db.stmts)
+	assert.Equal(t, "item-2", itemName(t, db, `SELECT name FROM items WHERE id = ?`, 2))
+}
+
+func TestStatementCache_CloseInvalidates(t *testing.T) {
+	db := newCachedSQLite(t, 10)
+	ctx := context.Background()
+	cache := db.stmts
+	itemName(t, db, `SELECT name FROM items WHERE id = ?`, 1)
+
+	require.NoError(t, db.Close())
+	assert.Equal(t, 0, cache.Len())
+	_, err := db.Query(ctx, `SELECT name FROM items WHERE id = ?`, 1)
+	assert.Error(t, err)
+	assert.Equal(t, 0, cache.Len(), "a closed cache prepares nothing")
+
+	// Reconnecting starts a new cache on the new connection
+	require.NoError(t, db.Connect(ctx))
+	assert.Equal(t, "item-1", itemName(t, db, `SELECT name FROM items WHERE id = ?`, 1))
+	assert.Equal(t, 1, db.stmts.Len())
+}
+
+// TestStatementCache_Concurrent runs more distinct queries than the cache
+// holds from many goroutines, so statements are evicted while in use. Run
+// it with -race.
+func TestStatementCache_Concurrent(t *testing.T) {
+	db := newCachedSQLite(t, 3)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for g := 0; g < 10; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				id := (g+i)%10 + 1
+				// Five query texts compete for three cache slots
+				query := fmt.Sprintf(`SELECT name FROM items WHERE id = ? AND %d = %d`, i%5, i%5)
+				var name string
+				if err := db.QueryRow(ctx, query, id).Scan(&name); err != nil {
+					errs <- err
+					return
+				}
+				if name != fmt.Sprintf("item-%d", id) {
+					errs <- fmt.Errorf("got %s for id %d", name, id)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	assert.LessOrEqual(t, db.stmts.Len(), 3)
+}
+
+// TestStatementCache_CloseWhileQuerying closes the database while queries
+// are running on it. They may fail, but must not race with Close. Run it
+// with -race.
+func TestStatementCache_CloseWhileQuerying(t *testing.T) {
+	db := newCachedSQLite(t, 3)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for g := 0; g < 10; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			<-start
+			for i := 0; i < 50; i++ {
+				query := fmt.Sprintf(`SELECT name FROM items WHERE id = ? AND %d = %d`, i%5, i%5)
+				var name string
+				_ = db.QueryRow(ctx, query, (g+i)%10+1).Scan(&name)
+				if rows, err := db.Query(ctx, query, 1); err == nil {
+					rows.Close()
+				}
+				_, _ = db.Exec(ctx, `UPDATE items SET name = name WHERE id = ?`, 1)
+			}
+		}(g)
+	}
+	close(start)
+	require.NoError(t, db.Close())
+	wg.Wait()
+	assert.Equal(t, 0, db.stmts.Len())
+}
+
+// BenchmarkSQLiteQuery compares a repeated lookup with and without the
+// statement cache
+func BenchmarkSQLiteQuery(b *testing.B) {
+	for _, size := range []int{0, DefaultStatementCacheSize} {
+		b.Run(fmt.Sprintf("cache=%d", size), func(b *testing.B) {
+			db := newCachedSQLite(b, size)
+			ctx := context.Background()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				var name string
+				if err := db.QueryRow(ctx, `SELECT name FROM items WHERE id = ?`, i%10+1).Scan(&name); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// QueryTimeoutError is returned when a query runs longer than its handler's
+// query timeout. It wraps context.DeadlineExceeded.
+type QueryTimeoutError struct {
+	Query   string
+	Timeout time.Duration
+}
+
+func (e *QueryTimeoutError) Error() string {
+	return fmt.Sprintf("database query timed out after %s", e.Timeout)
+}
+
+func (e *QueryTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// queryOptions hold a Handler's query timeout and slow query logging
+// settings. They are shared with the table handlers, transactions and
+// request-bound copies made from the handler, so changing them applies to
+// queries already set up.
+type queryOptions struct {
+	timeout       atomic.Int64 // time.Duration; 0 means no timeout
+	slowThreshold atomic.Int64 // time.Duration; 0 means no slow query log
+	logger        atomic.Pointer[log.Logger]
+}
+
+// run calls fn, which executes query, with the query timeout applied to ctx.
+// It logs the query if it took at least the slow query threshold and turns
+// an expired timeout into a *QueryTimeoutError. A nil opts runs fn as is.
+func (opts *queryOptions) run(ctx context.Context, query string, fn func(ctx context.Context) error) error {
+	if opts == nil {
+		return fn(ctx)
+	}
+
+	queryCtx := ctx
+	timeout := time.Duration(opts.timeout.Load())
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		queryCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := fn(queryCtx)
+	elapsed := time.Since(start)
+
+	if threshold := time.Duration(opts.slowThreshold.Load()); threshold > 0 && elapsed >= threshold {
+		opts.logSlow(query, elapsed, threshold)
+	}
+
+	// A deadline of the caller's own, such as a request's, is reported as
+	// the caller's error rather than as this handler's timeout
+	if err != nil && timeout > 0 && ctx.Err() == nil && errors.Is(queryCtx.Err(), context.DeadlineExceeded) {
+		return &QueryTimeoutError{Query: query, Timeout: timeout}
+	}
+	return err
+}
+
+// logSlow reports a slow query. Only the SQL is logged; its arguments may
+// hold user data and appear as placeholders.
+func (opts *queryOptions) logSlow(query string, elapsed, threshold time.Duration) {
+	logger := opts.logger.Load()
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Printf("[DB] slow query took %s (threshold %s): %s", elapsed.Round(time.Millisecond), threshold, query)
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowQuery counts to a hundred million, which takes SQLite several seconds
+const slowQuery = `WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 100000000) SELECT COUNT(*) AS n FROM c`
+
+// newSQLiteHandler returns a handler on a file-backed SQLite database with a
+// users table
+func newSQLiteHandler(t *testing.T) *Handler {
+	t.Helper()
+	handler, err := NewHandlerFromString("sqlite://" + filepath.Join(t.TempDir(), "app.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { handler.Close() })
+
+	_, err = handler.db.Exec(context.Background(), `CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)`)
+	require.NoError(t, err)
+	return handler
+}
+
+func TestHandler_QueryTimeout(t *testing.T) {
+	handler := newSQLiteHandler(t)
+	handler.SetQueryTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+	_, err := handler.Table("users").Query(slowQuery)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second, "the query should be interrupted")
+
+	var timeoutErr *QueryTimeoutError
+	require.True(t, errors.As(err, &timeoutErr), "got %v", err)
+	assert.Equal(t, 50*time.Millisecond, timeoutErr.Timeout)
+	assert.Equal(t, slowQuery, timeoutErr.Query)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, "database query timed out after 50ms", err.Error())
+
+	// Queries that finish in time are unaffected
+	created, err := handler.Table("users").Create(map[string]interface{}{"name": "ada"})
+	require.NoError(t, err)
+	assert.Equal(t, "ada", created["name"])
+}
+
+func TestHandler_QueryTimeoutAppliesToExistingTables(t *testing.T) {
+	handler := newSQLiteHandler(t)
+	users := handler.Table("users")
+	request := handler.WithContext(context.Background()).Table("users")
+
+	handler.SetQueryTimeout(50 * time.Millisecond)
+
+	var timeoutErr *QueryTimeoutError
+	_, err := users.Query(slowQuery)
+	assert.True(t, errors.As(err, &timeoutErr), "got %v", err)
+	_, err = request.Query(slowQuery)
+	assert.True(t, errors.As(err, &timeoutErr), "got %v", err)
+
+	err = handler.Transaction(context.Background(), func(tx *Handler) error {
+		_, err := tx.Table("users").Query(slowQuery)
+		return err
+	})
+	assert.True(t, errors.As(err, &timeoutErr), "got %v", err)
+}
+
+func TestHandler_CallerDeadlineIsNotAQueryTimeout(t *testing.T) {
+	handler := newSQLiteHandler(t)
+	handler.SetQueryTimeout(time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := handler.WithContext(ctx).Table("users").Query(slowQuery)
+	require.Error(t, err)
+
+	var timeoutErr *QueryTimeoutError
+	assert.False(t, errors.As(err, &timeoutErr), "the request's deadline is not the handler's timeout")
+}
+
+func TestHandler_SlowQueryLog(t *testing.T) {
+	handler := newSQLiteHandler(t)
+	var logs bytes.Buffer
+	handler.SetQueryLogger(log.New(&logs, "", 0))
+
+	users := handler.Table("users")
+	_, err := users.Create(map[string]interface{}{"name": "secret-name"})
+	require.NoError(t, err)
+	assert.Empty(t, logs.String(), "no threshold, no log")
+
+	handler.SetSlowQueryThreshold(time.Nanosecond)
+	_, err = users.Filter("name", "secret-name")
+	require.NoError(t, err)
+
+	line := logs.String()
+	assert.Contains(t, line, "[DB] slow query took ")
+	assert.Contains(t, line, "(threshold 1ns)")
+	assert.Contains(t, line, `SELECT * FROM "users" WHERE "name" = $1`)
+	assert.NotContains(t, line, "secret-name", "arguments are redacted")
+
+	logs.Reset()
+	handler.SetSlowQueryThreshold(time.Hour)
+	_, err = users.Filter("name", "secret-name")
+	require.NoError(t, err)
+	assert.Empty(t, logs.String(), "fast queries are not logged")
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/glyphlang/glyph/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceQuery runs fn, which executes query, in a client span named after the
+// statement's operation and table, e.g. "SELECT users". The SQL is recorded
+// as db.statement; its arguments are not, since they may hold user data.
+// A missing row is not treated as a span error. The ORM's query timeout and
+// slow query log apply to fn.
+func (o *ORM) traceQuery(ctx context.Context, query string, fn func(ctx context.Context) error) error {
+	operation := queryOperation(query)
+	ctx, span := tracing.StartSpan(ctx, operation+" "+o.table, tracing.SpanKind.Client,
+		trace.WithAttributes(
+			attribute.String("db.system", o.db.Driver()),
+			attribute.String("db.operation", operation),
+			attribute.String("db.sql.table", o.table),
+			attribute.String("db.statement", query),
+		))
+	defer span.End()
+
+	err := o.opts.run(ctx, query, fn)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		tracing.SetError(ctx, err)
+	}
+	return err
+}
+
+// queryOperation returns the leading keyword of a SQL statement, upper-cased
+func queryOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "QUERY"
+	}
+	return strings.ToUpper(fields[0])
+}
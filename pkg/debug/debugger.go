@@ -479,6 +479,8 @@ func opcodeToString(opcode vm.Opcode) string {
 		return "ITER_HAS_NEXT"
 	case vm.OpGetIndex:
 		return "GET_INDEX"
+	case vm.OpSetIndex:
+		return "SET_INDEX"
 	case vm.OpReturn:
 		return "RETURN"
 	case vm.OpCall:
@@ -487,8 +489,14 @@ func opcodeToString(opcode vm.Opcode) string {
 		return "BUILD_OBJECT"
 	case vm.OpGetField:
 		return "GET_FIELD"
+	case vm.OpGetFieldOpt:
+		return "GET_FIELD_OPT"
+	case vm.OpSetField:
+		return "SET_FIELD"
 	case vm.OpBuildArray:
 		return "BUILD_ARRAY"
+	case vm.OpSpread:
+		return "SPREAD"
 	case vm.OpHttpReturn:
 		return "HTTP_RETURN"
 	case vm.OpWsSend:
@@ -6,6 +6,8 @@ import (
 	"math"
 	"strings"
 
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/formatter"
 	"github.com/glyphlang/glyph/pkg/vm"
 )
 
@@ -13,6 +15,7 @@ import (
 type Decompiler struct {
 	bytecode   []byte
 	constants  []vm.Value
+	code       []instruction
 	offset     int
 	codeStart  int
 	codeLength int
@@ -23,7 +26,8 @@ type DecompiledOutput struct {
 	Version      uint32
 	Constants    []ConstantInfo
 	Instructions []InstructionInfo
-	Source       string // Reconstructed source (best effort)
+	Route        *ast.Route // Reconstructed route with structured control flow
+	Source       string     // Reconstructed source (best effort)
 }
 
 // ConstantInfo represents a constant in the pool
@@ -51,6 +55,7 @@ func (d *Decompiler) Decompile(bytecode []byte) (*DecompiledOutput, error) {
 	d.bytecode = bytecode
 	d.offset = 0
 	d.constants = nil
+	d.code = nil
 
 	output := &DecompiledOutput{}
 
@@ -103,8 +108,9 @@ func (d *Decompiler) Decompile(bytecode []byte) (*DecompiledOutput, error) {
 		output.Instructions = append(output.Instructions, instrInfo)
 	}
 
-	// Generate reconstructed source
-	output.Source = d.reconstructSource(output)
+	// Recover control flow and generate reconstructed source
+	output.Route = newStructurer(d.code, d.codeLength, d.constants).route()
+	output.Source = output.reconstructSource()
 
 	return output, nil
 }
@@ -193,6 +199,7 @@ func (d *Decompiler) readInstruction() (InstructionInfo, error) {
 	d.offset++
 
 	info.Opcode = opcodeToString(opcode)
+	instr := instruction{offset: info.Offset, op: opcode}
 
 	// Handle operands
 	if hasOperand(opcode) {
@@ -203,10 +210,18 @@ func (d *Decompiler) readInstruction() (InstructionInfo, error) {
 		d.offset += 4
 		info.Operand = fmt.Sprintf("%d", operand)
 
+		// Jump targets are bytecode offsets; keep them as code offsets
+		// so they line up with instruction offsets
+		if isJump(opcode) {
+			operand -= uint32(d.codeStart)
+		}
+		instr.operand = operand
+
 		// Add helpful comments
 		info.Comment = d.getOperandComment(opcode, operand)
 	}
 
+	d.code = append(d.code, instr)
 	return info, nil
 }
 
@@ -232,13 +247,17 @@ func (d *Decompiler) getOperandComment(opcode vm.Opcode, operand uint32) string
 			}
 		}
 	case vm.OpJump, vm.OpJumpIfFalse, vm.OpJumpIfTrue:
-		return fmt.Sprintf("; -> offset %d", operand)
+		return fmt.Sprintf("; -> %04d", operand)
 	case vm.OpBuildObject:
 		return fmt.Sprintf("; %d fields", operand)
 	case vm.OpBuildArray:
 		return fmt.Sprintf("; %d elements", operand)
 	case vm.OpCall:
 		return fmt.Sprintf("; %d args", operand)
+	case vm.OpCallFunc:
+		return fmt.Sprintf("; -> %04d", operand)
+	case vm.OpEnter:
+		return fmt.Sprintf("; %d params", operand)
 	case vm.OpIterNext:
 		if operand != 0 {
 			return "; with key"
@@ -248,80 +267,48 @@ func (d *Decompiler) getOperandComment(opcode vm.Opcode, operand uint32) string
 	return ""
 }
 
-// reconstructSource attempts to reconstruct GlyphLang source
-func (d *Decompiler) reconstructSource(output *DecompiledOutput) string {
+// reconstructSource renders the reconstructed route as GlyphLang source.
+// Goto markers left by the structurer become labeled disassembly comments.
+func (o *DecompiledOutput) reconstructSource() string {
 	var sb strings.Builder
 
 	sb.WriteString("# Decompiled GlyphLang Source\n")
-	sb.WriteString(fmt.Sprintf("# Version: %d\n", output.Version))
-	sb.WriteString(fmt.Sprintf("# Constants: %d\n", len(output.Constants)))
-	sb.WriteString(fmt.Sprintf("# Instructions: %d\n\n", len(output.Instructions)))
-
-	// Extract route path from constants if possible
-	routePath := "/"
-	for _, c := range d.constants {
-		if sv, ok := c.(vm.StringValue); ok {
-			if len(sv.Val) > 0 && sv.Val[0] == '/' {
-				routePath = sv.Val
-				break
-			}
+	sb.WriteString(fmt.Sprintf("# Version: %d\n", o.Version))
+	sb.WriteString(fmt.Sprintf("# Constants: %d\n", len(o.Constants)))
+	sb.WriteString(fmt.Sprintf("# Instructions: %d\n", len(o.Instructions)))
+	sb.WriteString("# The HTTP method and path are not stored in bytecode; path parameters are inferred\n\n")
+
+	source := formatter.New(formatter.Compact).Format(&ast.Module{Items: []ast.Item{o.Route}})
+	for _, line := range strings.SplitAfter(source, "\n") {
+		start, end, ok := parseGotoMarker(line)
+		if !ok {
+			sb.WriteString(line)
+			continue
 		}
-	}
-
-	sb.WriteString(fmt.Sprintf("@ route %s\n", routePath))
-
-	// Track variables and build pseudo-source
-	variables := make(map[uint32]string)
-	indent := "  "
-
-	for _, instr := range output.Instructions {
-		switch instr.Opcode {
-		case "STORE_VAR":
-			if idx, err := parseOperand(instr.Operand); err == nil {
-				if int(idx) < len(d.constants) {
-					if sv, ok := d.constants[idx].(vm.StringValue); ok {
-						variables[idx] = sv.Val
-						// Don't output internal variables
-						if !strings.HasPrefix(sv.Val, "__") {
-							sb.WriteString(fmt.Sprintf("%s$ %s = ...\n", indent, sv.Val))
-						}
-					}
-				}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+		sb.WriteString(fmt.Sprintf("%s# L%04d: control flow could not be structured\n", indent, start))
+		for _, instr := range o.Instructions {
+			if instr.Offset < start || instr.Offset >= end {
+				continue
 			}
-		case "RETURN":
-			sb.WriteString(fmt.Sprintf("%s> ...\n", indent))
-		}
-	}
-
-	sb.WriteString("\n# --- Bytecode Disassembly ---\n\n")
-
-	// Add constant pool
-	sb.WriteString("# Constant Pool:\n")
-	for _, c := range output.Constants {
-		sb.WriteString(fmt.Sprintf("#   [%d] %s: %s\n", c.Index, c.Type, c.Value))
-	}
-	sb.WriteString("\n")
-
-	// Add instructions
-	sb.WriteString("# Instructions:\n")
-	for _, instr := range output.Instructions {
-		line := fmt.Sprintf("#   %04d: %-15s", instr.Offset, instr.Opcode)
-		if instr.Operand != "" {
-			line += fmt.Sprintf(" %s", instr.Operand)
-		}
-		if instr.Comment != "" {
-			line += fmt.Sprintf("  %s", instr.Comment)
+			text := fmt.Sprintf("%s#   L%04d: %s", indent, instr.Offset, instr.Opcode)
+			if instr.Operand != "" {
+				text += " " + instr.Operand
+			}
+			if instr.Comment != "" {
+				text += "  " + instr.Comment
+			}
+			sb.WriteString(text + "\n")
 		}
-		sb.WriteString(line + "\n")
 	}
 
 	return sb.String()
 }
 
-func parseOperand(s string) (uint32, error) {
-	var v uint32
-	_, err := fmt.Sscanf(s, "%d", &v)
-	return v, err
+// parseGotoMarker reports the code range of a formatted goto marker line
+func parseGotoMarker(line string) (start, end int, ok bool) {
+	_, err := fmt.Sscanf(strings.TrimSpace(line), GotoMarker+"(%d, %d)", &start, &end)
+	return start, end, err == nil
 }
 
 // opcodeToString converts an opcode to its string name
@@ -353,11 +340,19 @@ func opcodeToString(op vm.Opcode) string {
 		vm.OpIterNext:        "ITER_NEXT",
 		vm.OpIterHasNext:     "ITER_HAS_NEXT",
 		vm.OpGetIndex:        "GET_INDEX",
+		vm.OpSetIndex:        "SET_INDEX",
 		vm.OpReturn:          "RETURN",
 		vm.OpCall:            "CALL",
+		vm.OpCallFunc:        "CALL_FUNC",
+		vm.OpEnter:           "ENTER",
+		vm.OpRet:             "RET",
+		vm.OpCheckType:       "CHECK_TYPE",
 		vm.OpBuildObject:     "BUILD_OBJECT",
 		vm.OpGetField:        "GET_FIELD",
+		vm.OpGetFieldOpt:     "GET_FIELD_OPT",
+		vm.OpSetField:        "SET_FIELD",
 		vm.OpBuildArray:      "BUILD_ARRAY",
+		vm.OpSpread:          "SPREAD",
 		vm.OpHttpReturn:      "HTTP_RETURN",
 		vm.OpWsSend:          "WS_SEND",
 		vm.OpWsBroadcast:     "WS_BROADCAST",
@@ -369,6 +364,15 @@ func opcodeToString(op vm.Opcode) string {
 		vm.OpWsGetClients:    "WS_GET_CLIENTS",
 		vm.OpWsGetConnCount:  "WS_GET_CONN_COUNT",
 		vm.OpWsGetUptime:     "WS_GET_UPTIME",
+		vm.OpWsJoinClient:    "WS_JOIN_CLIENT",
+		vm.OpWsLeaveClient:   "WS_LEAVE_CLIENT",
+		vm.OpWsSetState:      "WS_SET_STATE",
+		vm.OpWsGetState:      "WS_GET_STATE",
+		vm.OpWsReject:        "WS_REJECT",
+		vm.OpAsync:           "ASYNC",
+		vm.OpAwait:           "AWAIT",
+		vm.OpBackground:      "BACKGROUND",
+		vm.OpYield:           "YIELD",
 		vm.OpHalt:            "HALT",
 	}
 
@@ -389,12 +393,22 @@ func hasOperand(op vm.Opcode) bool {
 		vm.OpJumpIfTrue:  true,
 		vm.OpIterNext:    true,
 		vm.OpCall:        true,
+		vm.OpCallFunc:    true,
+		vm.OpEnter:       true,
+		vm.OpCheckType:   true,
 		vm.OpBuildObject: true,
 		vm.OpBuildArray:  true,
+		vm.OpAsync:       true,
+		vm.OpBackground:  true,
 	}
 	return withOperand[op]
 }
 
+// isJump returns true if the opcode's operand is a jump target
+func isJump(op vm.Opcode) bool {
+	return op == vm.OpJump || op == vm.OpJumpIfFalse || op == vm.OpJumpIfTrue
+}
+
 // Format returns a formatted string representation
 func (o *DecompiledOutput) Format() string {
 	return o.Source
@@ -1,7 +1,14 @@
 package decompiler
 
 import (
+	"bytes"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/compiler"
+	"github.com/glyphlang/glyph/pkg/parser"
 )
 
 func TestDecompileValidBytecode(t *testing.T) {
@@ -181,6 +188,299 @@ func TestFormatDisassembly(t *testing.T) {
 	}
 }
 
+// roundTripCorpus holds routes whose bodies must survive
+// parse -> compile -> decompile -> parse unchanged
+var roundTripCorpus = map[string]string{
+	"straight line": `@ GET /users/:id {
+  $ user = {id: id, name: "Ada", tags: ["a", "b"]}
+  $ label = upper(user.name) + " #" + toString(id)
+  log(label)
+  > user
+}`,
+	"nested if": `@ GET /grade/:score {
+  $ grade = "F"
+  if score >= 90 {
+    grade = "A"
+  } else if score >= 80 {
+    grade = "B"
+    if score % 10 > 5 {
+      grade = "B+"
+    }
+  } else {
+    $ gap = (80 - score) * 2
+    if gap > 30 && !(score < 10) {
+      grade = "F-"
+    }
+  }
+  > {grade: grade, score: score}
+}`,
+	"while with break and continue": `@ GET /count {
+  $ i = 0
+  $ total = 0
+  while i < 100 {
+    i = i + 1
+    if i % 3 == 0 {
+      continue
+    }
+    if total > 50 {
+      break
+    }
+    total = total + i
+  }
+  > total
+}`,
+	"for loops": `@ GET /orders {
+  $ items = [1, 2, 3]
+  $ sum = 0
+  for item in items {
+    for k, v in {a: item, b: 2.5} {
+      if k == "a" {
+        sum = sum + v
+      } else {
+        continue
+      }
+    }
+    if sum > 10 {
+      break
+    }
+  }
+  > sum
+}`,
+	"loops inside branches": `@ POST /sync/:mode {
+  if mode == "full" {
+    $ n = 0
+    while n < 3 {
+      n = n + 1
+    }
+    > n
+  } else {
+    for x in input.items {
+      log(x)
+    }
+  }
+  > null
+}`,
+	"switch": `@ GET /status/:code {
+  $ message = ""
+  switch code {
+    case 200 {
+      message = "ok"
+    }
+    case 404 {
+      message = "missing"
+    }
+    default {
+      if code > 500 {
+        message = "error"
+      }
+    }
+  }
+  > message
+}`,
+	"conditional expressions": `@ GET /price/:qty {
+  $ unit = qty > 100 ? 0.8 : qty > 10 ? 0.9 : 1.0
+  $ currency = query.currency ?? headers.currency ?? "EUR"
+  > {total: (qty > 0 ? qty : 0) * unit, currency: currency}
+}`,
+	"match": `@ GET /label/:n {
+  $ label = match n {
+    0 => "zero"
+    x when x < 0 => "negative"
+    "many" => match query.kind {
+      "a" => 1
+      _ => 2
+    }
+    _ => n ?? "none"
+  }
+  > match label {
+    "zero" => null
+    other => other
+  }
+}`,
+	"yield": `@ GET /ticks {
+  $ i = 0
+  while i < 3 {
+    i = i + 1
+    yield {n: i}
+  }
+}`,
+	"spread": `@ GET /merge {
+  $ base = {name: "Ada", tags: ["a"]}
+  $ tags = [...base.tags, "b", ...base.tags]
+  > {id: 1, ...base, tags: tags}
+}`,
+	"field and index assignment": `@ PUT /users/:id {
+  $ users = [{name: "Ada", tags: ["a"]}]
+  users[0].name = "Grace"
+  users[0].tags[-1] = id
+  $ user = users[0]
+  user.active = true
+  > users
+}`,
+}
+
+func parseRoute(t *testing.T, source string) *ast.Route {
+	t.Helper()
+	tokens, err := parser.NewLexer(source).Tokenize()
+	if err != nil {
+		t.Fatalf("Tokenize failed: %v\n%s", err, source)
+	}
+	module, err := parser.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v\n%s", err, source)
+	}
+	for _, item := range module.Items {
+		if route, ok := item.(*ast.Route); ok {
+			return route
+		}
+	}
+	t.Fatalf("No route in source:\n%s", source)
+	return nil
+}
+
+func TestDecompileRoundTrip(t *testing.T) {
+	for name, source := range roundTripCorpus {
+		t.Run(name, func(t *testing.T) {
+			original := parseRoute(t, source)
+			bytecode, err := compiler.NewCompiler().CompileRoute(original)
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+
+			result, err := NewDecompiler().Decompile(bytecode)
+			if err != nil {
+				t.Fatalf("Decompile failed: %v", err)
+			}
+			formatted := result.Format()
+			if strings.Contains(formatted, GotoMarker) || strings.Contains(formatted, "could not be structured") {
+				t.Fatalf("Expected fully structured output, got:\n%s", formatted)
+			}
+
+			decompiled := parseRoute(t, formatted)
+			if !equivalentAST(reflect.ValueOf(original.Body), reflect.ValueOf(decompiled.Body)) {
+				t.Errorf("Round trip changed the route body.\nOriginal:\n%s\nDecompiled:\n%s", source, formatted)
+			}
+
+			// The reconstructed source must compile back to the same bytecode
+			recompiled, err := compiler.NewCompiler().CompileRoute(decompiled)
+			if err != nil {
+				t.Fatalf("Decompiled source does not compile: %v\n%s", err, formatted)
+			}
+			if !bytes.Equal(bytecode, recompiled) {
+				again, _ := NewDecompiler().Decompile(recompiled)
+				t.Errorf("Recompiled bytecode differs.\nOriginal:\n%s\nRecompiled:\n%s", result.FormatDisassembly(), again.FormatDisassembly())
+			}
+		})
+	}
+}
+
+func TestDecompileInfersRouteSignature(t *testing.T) {
+	route := parseRoute(t, `@ GET /users/:id {
+  + auth(jwt)
+  > {id: id, user: auth.user, q: query.page}
+}`)
+	bytecode, err := compiler.NewCompiler().CompileRoute(route)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	result, err := NewDecompiler().Decompile(bytecode)
+	if err != nil {
+		t.Fatalf("Decompile failed: %v", err)
+	}
+
+	if result.Route.Path != "/:id" {
+		t.Errorf("Expected path /:id, got %s", result.Route.Path)
+	}
+	if result.Route.Auth == nil {
+		t.Error("Expected auth middleware to be inferred from the auth variable")
+	}
+	if !contains(result.Format(), "+ auth(jwt)") {
+		t.Errorf("Expected auth middleware in source, got:\n%s", result.Format())
+	}
+}
+
+func TestDecompileUnstructuredFallsBackToGoto(t *testing.T) {
+	// PUSH 0; JUMP_IF_TRUE 0 (a backward conditional jump the compiler never
+	// emits); PUSH 0; RETURN
+	bytecode := []byte{
+		'G', 'L', 'Y', 'P',
+		0x01, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x00, 0x00,
+		0x03, 0x01, // Constant 0: true
+		0x10, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x00, 0x00, 0x00,
+		0x52, 0x12, 0x00, 0x00, 0x00, // JUMP_IF_TRUE to code offset 0 (bytecode offset 18)
+		0x01, 0x00, 0x00, 0x00, 0x00,
+		0x61,
+	}
+
+	result, err := NewDecompiler().Decompile(bytecode)
+	if err != nil {
+		t.Fatalf("Decompile failed: %v", err)
+	}
+
+	source := result.Format()
+	for _, want := range []string{
+		"# L0000: control flow could not be structured",
+		"#   L0005: JUMP_IF_TRUE 18  ; -> 0000",
+		"#   L0015: RETURN",
+	} {
+		if !contains(source, want) {
+			t.Errorf("Expected %q in source, got:\n%s", want, source)
+		}
+	}
+	if contains(source, GotoMarker) {
+		t.Errorf("Goto marker should be rendered as a comment, got:\n%s", source)
+	}
+
+	// The fallback is still valid source
+	parseRoute(t, source)
+}
+
+// equivalentAST reports whether two AST values are deeply equal, ignoring
+// source positions
+func equivalentAST(a, b reflect.Value) bool {
+	if a.IsValid() != b.IsValid() {
+		return false
+	}
+	if !a.IsValid() {
+		return true
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return equivalentAST(a.Elem(), b.Elem())
+	case reflect.Struct:
+		if a.Type() == reflect.TypeOf(ast.Pos{}) {
+			return true
+		}
+		for i := 0; i < a.NumField(); i++ {
+			if !equivalentAST(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equivalentAST(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a.Interface(), b.Interface())
+	}
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsHelper(s, substr))
 }
@@ -0,0 +1,776 @@
+package decompiler
+
+import (
+	"strings"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/vm"
+)
+
+// GotoMarker names the pseudo-call that stands in for a region of bytecode
+// whose control flow could not be structured. Its two integer arguments are
+// the start and end code offsets of the region; Format renders it as a
+// labeled disassembly comment.
+const GotoMarker = "__goto"
+
+// instruction is a decoded instruction. Jump operands are rebased from
+// bytecode offsets to code offsets.
+type instruction struct {
+	offset  int
+	op      vm.Opcode
+	operand uint32
+}
+
+// builtinVars are bound by the runtime for every route rather than declared
+// in the route path
+var builtinVars = map[string]bool{
+	"query":   true,
+	"headers": true,
+	"request": true,
+	"input":   true,
+	"ws":      true,
+	"auth":    true,
+}
+
+// loopTargets are the code offsets a break or continue jumps to
+type loopTargets struct {
+	continueAt int
+	breakAt    int
+}
+
+// structurer rebuilds statements from the jump patterns the compiler emits
+// for if, while, for and switch statements, and for ternary, ?? and match
+// expressions
+type structurer struct {
+	code       []instruction
+	codeLength int
+	index      map[int]int // code offset -> instruction index, including the end offset
+	constants  []vm.Value
+	scopes     []map[string]bool
+	loops      []loopTargets
+}
+
+func newStructurer(code []instruction, codeLength int, constants []vm.Value) *structurer {
+	s := &structurer{
+		code:       code,
+		codeLength: codeLength,
+		index:      make(map[int]int, len(code)+1),
+		constants:  constants,
+	}
+	for i, in := range code {
+		s.index[in.offset] = i
+	}
+	s.index[codeLength] = len(code)
+	return s
+}
+
+// route reconstructs a route from the whole instruction stream. Variables
+// that are read before they are ever written become path parameters, and a
+// free auth variable implies the auth middleware.
+func (s *structurer) route() *ast.Route {
+	route := &ast.Route{Method: ast.Get, Path: "/"}
+
+	params := s.freeVariables()
+	scope := make(map[string]bool)
+	var segments []string
+	for _, name := range params {
+		switch {
+		case name == "auth":
+			route.Auth = &ast.AuthConfig{AuthType: "jwt", Required: true}
+		case !builtinVars[name]:
+			segments = append(segments, ":"+name)
+			scope[name] = true
+		}
+	}
+	if len(segments) > 0 {
+		route.Path = "/" + strings.Join(segments, "/")
+	}
+
+	s.scopes = []map[string]bool{scope}
+	route.Body = s.block(0, len(s.code))
+	return route
+}
+
+// freeVariables returns, in order of first use, the names that are loaded
+// before any store to them
+func (s *structurer) freeVariables() []string {
+	seen := make(map[string]bool)
+	var free []string
+	for _, in := range s.code {
+		if in.op != vm.OpLoadVar && in.op != vm.OpStoreVar {
+			continue
+		}
+		name, ok := s.name(in.operand)
+		if !ok || seen[name] || strings.HasPrefix(name, "__") {
+			continue
+		}
+		seen[name] = true
+		if in.op == vm.OpLoadVar {
+			free = append(free, name)
+		}
+	}
+	return free
+}
+
+// block structures the instructions in [start, end). If a statement cannot
+// be recognized, the rest of the block becomes a goto marker.
+func (s *structurer) block(start, end int) []ast.Statement {
+	var stmts []ast.Statement
+	for i := start; i < end; {
+		stmt, next, ok := s.statement(i, end)
+		if !ok {
+			stmts = append(stmts, s.gotoMarker(i, end))
+			break
+		}
+		if stmt != nil {
+			stmts = append(stmts, stmt)
+		}
+		i = next
+	}
+	return stmts
+}
+
+// scopedBlock structures [start, end) in a new block scope
+func (s *structurer) scopedBlock(start, end int, declare ...string) []ast.Statement {
+	scope := make(map[string]bool)
+	for _, name := range declare {
+		scope[name] = true
+	}
+	s.scopes = append(s.scopes, scope)
+	defer func() { s.scopes = s.scopes[:len(s.scopes)-1] }()
+	return s.block(start, end)
+}
+
+// statement recognizes the statement starting at instruction i and returns
+// it along with the index of the instruction that follows it. A nil
+// statement with ok set means the instructions produce no source.
+func (s *structurer) statement(i, end int) (ast.Statement, int, bool) {
+	in := s.code[i]
+	switch in.op {
+	case vm.OpJump:
+		if len(s.loops) == 0 {
+			return nil, 0, false
+		}
+		loop := s.loops[len(s.loops)-1]
+		switch int(in.operand) {
+		case loop.breakAt:
+			return ast.BreakStatement{}, i + 1, true
+		case loop.continueAt:
+			return ast.ContinueStatement{}, i + 1, true
+		}
+		return nil, 0, false
+	case vm.OpHalt:
+		// Routes without a trailing return end in a halt
+		return nil, i + 1, i == len(s.code)-1
+	}
+
+	stack, j, ok := s.expressions(i, end, nil)
+	if !ok || j >= end {
+		return nil, 0, false
+	}
+	if len(stack) == 3 {
+		// A field or index store leaves the value, container and key
+		return s.indexAssign(stack, j)
+	}
+	if len(stack) != 1 {
+		return nil, 0, false
+	}
+	value := stack[0]
+
+	switch s.code[j].op {
+	case vm.OpStoreVar:
+		name, ok := s.name(s.code[j].operand)
+		if !ok {
+			return nil, 0, false
+		}
+		if strings.HasPrefix(name, "__switch_") {
+			return s.switchStatement(name, value, j+1, end)
+		}
+		if s.declared(name) {
+			return ast.ReassignStatement{Target: name, Value: value}, j + 1, true
+		}
+		s.scopes[len(s.scopes)-1][name] = true
+		return ast.AssignStatement{Target: name, Value: value}, j + 1, true
+	case vm.OpPop:
+		return ast.ExpressionStatement{Expr: value}, j + 1, true
+	case vm.OpReturn:
+		return ast.ReturnStatement{Value: value}, j + 1, true
+	case vm.OpYield:
+		return ast.YieldStatement{Value: value}, j + 1, true
+	case vm.OpGetIter:
+		return s.forStatement(value, j+1, end)
+	case vm.OpJumpIfFalse:
+		return s.conditional(value, i, j, end)
+	}
+	return nil, 0, false
+}
+
+// conditional recognizes the two shapes compiled from a condition at
+// instruction i whose JUMP_IF_FALSE is at j:
+//
+//	while: cond; JUMP_IF_FALSE end; body; JUMP i; end:
+//	if:    cond; JUMP_IF_FALSE else; then; JUMP end; else: else-body; end:
+func (s *structurer) conditional(cond ast.Expr, i, j, end int) (ast.Statement, int, bool) {
+	exit, ok := s.target(s.code[j], j+1, end)
+	if !ok || exit == j+1 {
+		return nil, 0, false
+	}
+	back := s.code[exit-1]
+	if back.op != vm.OpJump {
+		return nil, 0, false
+	}
+
+	if int(back.operand) == s.code[i].offset {
+		s.loops = append(s.loops, loopTargets{continueAt: s.code[i].offset, breakAt: int(s.code[j].operand)})
+		body := s.scopedBlock(j+1, exit-1)
+		s.loops = s.loops[:len(s.loops)-1]
+		return ast.WhileStatement{Condition: cond, Body: body}, exit, true
+	}
+
+	after, ok := s.target(back, exit, end)
+	if !ok {
+		return nil, 0, false
+	}
+	stmt := ast.IfStatement{
+		Condition: cond,
+		ThenBlock: s.scopedBlock(j+1, exit-1),
+	}
+	if after > exit {
+		stmt.ElseBlock = s.scopedBlock(exit, after)
+	}
+	return stmt, after, true
+}
+
+// forStatement recognizes the iterator protocol that follows GET_ITER at
+// instruction i:
+//
+//	STORE_VAR __iter; head: LOAD_VAR __iter; ITER_HAS_NEXT; JUMP_IF_FALSE end
+//	LOAD_VAR __iter; ITER_NEXT hasKey; STORE_VAR value; [STORE_VAR key]
+//	body; JUMP head; end:
+func (s *structurer) forStatement(iterable ast.Expr, i, end int) (ast.Statement, int, bool) {
+	if i+7 > end {
+		return nil, 0, false
+	}
+	store, head, hasNext, check, load, next, value := s.code[i], s.code[i+1], s.code[i+2], s.code[i+3], s.code[i+4], s.code[i+5], s.code[i+6]
+	iter, ok := s.name(store.operand)
+	if !ok || store.op != vm.OpStoreVar || !strings.HasPrefix(iter, "__iter_") ||
+		head.op != vm.OpLoadVar || head.operand != store.operand ||
+		hasNext.op != vm.OpIterHasNext || check.op != vm.OpJumpIfFalse ||
+		load.op != vm.OpLoadVar || load.operand != store.operand ||
+		next.op != vm.OpIterNext || value.op != vm.OpStoreVar {
+		return nil, 0, false
+	}
+
+	stmt := ast.ForStatement{Iterable: iterable}
+	if stmt.ValueVar, ok = s.name(value.operand); !ok {
+		return nil, 0, false
+	}
+	bodyStart := i + 7
+	if next.operand != 0 {
+		if bodyStart >= end || s.code[bodyStart].op != vm.OpStoreVar {
+			return nil, 0, false
+		}
+		if stmt.KeyVar, ok = s.name(s.code[bodyStart].operand); !ok {
+			return nil, 0, false
+		}
+		bodyStart++
+	}
+
+	exit, ok := s.target(check, bodyStart, end)
+	if !ok || exit == bodyStart {
+		return nil, 0, false
+	}
+	back := s.code[exit-1]
+	if back.op != vm.OpJump || int(back.operand) != head.offset {
+		return nil, 0, false
+	}
+
+	s.loops = append(s.loops, loopTargets{continueAt: head.offset, breakAt: int(check.operand)})
+	vars := []string{stmt.ValueVar}
+	if stmt.KeyVar != "" {
+		vars = append(vars, stmt.KeyVar)
+	}
+	stmt.Body = s.scopedBlock(bodyStart, exit-1, vars...)
+	s.loops = s.loops[:len(s.loops)-1]
+	return stmt, exit, true
+}
+
+// switchStatement recognizes the case chain that follows the store of the
+// switch value into temp at instruction i:
+//
+//	case: LOAD_VAR temp; value; EQ; JUMP_IF_FALSE next; body; JUMP end; next:
+//	...
+//	default-body; end:
+func (s *structurer) switchStatement(temp string, value ast.Expr, i, end int) (ast.Statement, int, bool) {
+	stmt := ast.SwitchStatement{Value: value}
+	after := -1
+	for i < end && s.code[i].op == vm.OpLoadVar {
+		if name, _ := s.name(s.code[i].operand); name != temp {
+			break
+		}
+		stack, j, ok := s.expressions(i+1, end, []ast.Expr{ast.VariableExpr{Name: temp}})
+		if !ok || j >= end || len(stack) != 1 || s.code[j].op != vm.OpJumpIfFalse {
+			return nil, 0, false
+		}
+		eq, isEq := stack[0].(ast.BinaryOpExpr)
+		if !isEq || eq.Op != ast.Eq {
+			return nil, 0, false
+		}
+
+		next, ok := s.target(s.code[j], j+1, end)
+		if !ok || next == j+1 || s.code[next-1].op != vm.OpJump {
+			return nil, 0, false
+		}
+		caseEnd, ok := s.target(s.code[next-1], next, end)
+		if !ok || (after >= 0 && caseEnd != after) {
+			return nil, 0, false
+		}
+		after = caseEnd
+
+		stmt.Cases = append(stmt.Cases, ast.SwitchCase{
+			Value: eq.Right,
+			Body:  s.scopedBlock(j+1, next-1),
+		})
+		i = next
+	}
+	if after < 0 {
+		return nil, 0, false
+	}
+	if i < after {
+		stmt.Default = s.scopedBlock(i, after)
+	}
+	return stmt, after, true
+}
+
+// indexAssign recognizes a SET_FIELD or SET_INDEX at j whose value,
+// container and key are on stack
+func (s *structurer) indexAssign(stack []ast.Expr, j int) (ast.Statement, int, bool) {
+	value, container, key := stack[0], stack[1], stack[2]
+	switch s.code[j].op {
+	case vm.OpSetField:
+		field, ok := stringLiteral(key)
+		if !ok {
+			return nil, 0, false
+		}
+		return ast.IndexAssignStatement{Target: ast.FieldAccessExpr{Object: container, Field: field}, Value: value}, j + 1, true
+	case vm.OpSetIndex:
+		return ast.IndexAssignStatement{Target: ast.ArrayIndexExpr{Array: container, Index: key}, Value: value}, j + 1, true
+	}
+	return nil, 0, false
+}
+
+// expressions simulates the operand stack from instruction i until it
+// reaches end or an instruction that is not part of an expression, returning
+// the stack and that instruction's index
+func (s *structurer) expressions(i, end int, stack []ast.Expr) ([]ast.Expr, int, bool) {
+	pop := func(n int) ([]ast.Expr, bool) {
+		if n > len(stack) {
+			return nil, false
+		}
+		popped := append([]ast.Expr(nil), stack[len(stack)-n:]...)
+		stack = stack[:len(stack)-n]
+		return popped, true
+	}
+
+	for ; i < end; i++ {
+		in := s.code[i]
+		if op, ok := binaryOps[in.op]; ok {
+			operands, ok := pop(2)
+			if !ok {
+				return nil, 0, false
+			}
+			stack = append(stack, ast.BinaryOpExpr{Op: op, Left: operands[0], Right: operands[1]})
+			continue
+		}
+
+		switch in.op {
+		case vm.OpPush:
+			lit, ok := s.literal(in.operand)
+			if !ok {
+				return nil, 0, false
+			}
+			stack = append(stack, ast.LiteralExpr{Value: lit})
+		case vm.OpLoadVar:
+			name, ok := s.name(in.operand)
+			if !ok {
+				return nil, 0, false
+			}
+			stack = append(stack, ast.VariableExpr{Name: name})
+		case vm.OpNot, vm.OpNeg:
+			operand, ok := pop(1)
+			if !ok {
+				return nil, 0, false
+			}
+			op := ast.Not
+			if in.op == vm.OpNeg {
+				op = ast.Neg
+			}
+			stack = append(stack, ast.UnaryOpExpr{Op: op, Right: operand[0]})
+		case vm.OpGetField, vm.OpGetFieldOpt:
+			operands, ok := pop(2)
+			if !ok {
+				return nil, 0, false
+			}
+			field, ok := stringLiteral(operands[1])
+			if !ok {
+				return nil, 0, false
+			}
+			stack = append(stack, ast.FieldAccessExpr{Object: operands[0], Field: field, Optional: in.op == vm.OpGetFieldOpt})
+		case vm.OpGetIndex:
+			operands, ok := pop(2)
+			if !ok {
+				return nil, 0, false
+			}
+			stack = append(stack, ast.ArrayIndexExpr{Array: operands[0], Index: operands[1]})
+		case vm.OpCall:
+			operands, ok := pop(int(in.operand) + 1)
+			if !ok {
+				return nil, 0, false
+			}
+			name, ok := stringLiteral(operands[0])
+			if !ok {
+				return nil, 0, false
+			}
+			stack = append(stack, ast.FunctionCallExpr{Name: name, Args: operands[1:]})
+		case vm.OpBuildArray:
+			elements, ok := pop(int(in.operand))
+			if !ok {
+				return nil, 0, false
+			}
+			stack = append(stack, ast.ArrayExpr{Elements: elements})
+		case vm.OpBuildObject:
+			operands, ok := pop(2 * int(in.operand))
+			if !ok {
+				return nil, 0, false
+			}
+			fields := make([]ast.ObjectField, 0, in.operand)
+			for k := 0; k < len(operands); k += 2 {
+				key, ok := stringLiteral(operands[k])
+				if !ok {
+					return nil, 0, false
+				}
+				fields = append(fields, ast.ObjectField{Key: key, Value: operands[k+1]})
+			}
+			stack = append(stack, ast.ObjectExpr{Fields: fields})
+		case vm.OpSpread:
+			operands, ok := pop(2)
+			if !ok {
+				return nil, 0, false
+			}
+			spread, ok := spreadInto(operands[0], operands[1])
+			if !ok {
+				return nil, 0, false
+			}
+			stack = append(stack, spread)
+		case vm.OpAwait:
+			operand, ok := pop(1)
+			if !ok {
+				return nil, 0, false
+			}
+			stack = append(stack, ast.AwaitExpr{Expr: operand[0]})
+		case vm.OpJumpIfFalse:
+			if len(stack) == 0 {
+				return stack, i, true
+			}
+			ternary, next, ok := s.ternary(stack[len(stack)-1], i, end)
+			if !ok {
+				return stack, i, true
+			}
+			stack[len(stack)-1] = ternary
+			i = next - 1
+		case vm.OpStoreVar:
+			name, _ := s.name(in.operand)
+			var expr ast.Expr
+			next, ok := 0, false
+			if len(stack) > 0 {
+				switch {
+				case strings.HasPrefix(name, "__coalesce_"):
+					expr, next, ok = s.coalesce(stack[len(stack)-1], i, end)
+				case strings.HasPrefix(name, "__match_"):
+					expr, next, ok = s.match(stack[len(stack)-1], i, end)
+				}
+			}
+			if !ok {
+				return stack, i, true
+			}
+			stack[len(stack)-1] = expr
+			i = next - 1
+		default:
+			return stack, i, true
+		}
+	}
+	return stack, end, true
+}
+
+// value structures [start, end) as exactly one expression
+func (s *structurer) value(start, end int) (ast.Expr, bool) {
+	stack, j, ok := s.expressions(start, end, nil)
+	if !ok || j != end || len(stack) != 1 {
+		return nil, false
+	}
+	return stack[0], true
+}
+
+// ternary recognizes the branches that follow the condition of a ternary
+// expression, whose JUMP_IF_FALSE is at instruction i:
+//
+//	cond; JUMP_IF_FALSE else; then; JUMP end; else: else-value; end:
+func (s *structurer) ternary(cond ast.Expr, i, end int) (ast.Expr, int, bool) {
+	elseAt, ok := s.target(s.code[i], i+2, end)
+	if !ok || s.code[elseAt-1].op != vm.OpJump {
+		return nil, 0, false
+	}
+	after, ok := s.target(s.code[elseAt-1], elseAt+1, end)
+	if !ok {
+		return nil, 0, false
+	}
+	then, ok := s.value(i+1, elseAt-1)
+	if !ok {
+		return nil, 0, false
+	}
+	otherwise, ok := s.value(elseAt, after)
+	if !ok {
+		return nil, 0, false
+	}
+	return ast.TernaryExpr{Condition: cond, Then: then, Else: otherwise}, after, true
+}
+
+// coalesce recognizes left ?? right from the store of the left value into a
+// temporary at instruction i:
+//
+//	STORE_VAR temp; LOAD_VAR temp; PUSH null; NE; JUMP_IF_FALSE right
+//	LOAD_VAR temp; JUMP end; right: right-value; end:
+func (s *structurer) coalesce(left ast.Expr, i, end int) (ast.Expr, int, bool) {
+	if i+7 > end {
+		return nil, 0, false
+	}
+	temp := s.code[i].operand
+	load, null, ne, check, reload, jump := s.code[i+1], s.code[i+2], s.code[i+3], s.code[i+4], s.code[i+5], s.code[i+6]
+	if load.op != vm.OpLoadVar || load.operand != temp ||
+		null.op != vm.OpPush || !s.isNull(null.operand) || ne.op != vm.OpNe ||
+		check.op != vm.OpJumpIfFalse || reload.op != vm.OpLoadVar || reload.operand != temp ||
+		jump.op != vm.OpJump {
+		return nil, 0, false
+	}
+	if right, ok := s.target(check, i+7, end); !ok || right != i+7 {
+		return nil, 0, false
+	}
+	after, ok := s.target(jump, i+8, end)
+	if !ok {
+		return nil, 0, false
+	}
+	right, ok := s.value(i+7, after)
+	if !ok {
+		return nil, 0, false
+	}
+	return ast.BinaryOpExpr{Op: ast.Coalesce, Left: left, Right: right}, after, true
+}
+
+// match recognizes the cases that follow the store of a match value into a
+// temporary at instruction i. Each case tests its pattern and optional
+// guard, jumping to the next case when either fails, and a final null is
+// the result when no case matches:
+//
+//	case: [pattern]; [guard; JUMP_IF_FALSE next]; body; JUMP end; next:
+//	...
+//	PUSH null; end:
+//
+// Where the match ends is only known from the case jumps, so each PUSH null
+// is tried in turn as the last instruction.
+func (s *structurer) match(value ast.Expr, i, end int) (ast.Expr, int, bool) {
+	for last := i + 1; last < end; last++ {
+		if in := s.code[last]; in.op != vm.OpPush || !s.isNull(in.operand) {
+			continue
+		}
+		if cases, ok := s.matchCases(s.code[i].operand, i+1, last); ok {
+			return ast.MatchExpr{Value: value, Cases: cases}, last + 1, true
+		}
+	}
+	return nil, 0, false
+}
+
+// matchCases structures the cases in [start, last), where last is the
+// PUSH null ending the match. Literal, variable and wildcard patterns are
+// recognized.
+func (s *structurer) matchCases(temp uint32, start, last int) ([]ast.MatchCase, bool) {
+	var cases []ast.MatchCase
+	for k := start; k < last; {
+		var c ast.MatchCase
+		next := -1
+
+		in := s.code[k]
+		switch {
+		case in.op == vm.OpLoadVar && in.operand == temp && k+1 < last && s.code[k+1].op == vm.OpStoreVar:
+			name, ok := s.name(s.code[k+1].operand)
+			if !ok {
+				return nil, false
+			}
+			c.Pattern = ast.VariablePattern{Name: name}
+			k += 2
+		case in.op == vm.OpLoadVar && in.operand == temp:
+			if k+3 >= last || s.code[k+1].op != vm.OpPush || s.code[k+2].op != vm.OpEq || s.code[k+3].op != vm.OpJumpIfFalse {
+				return nil, false
+			}
+			lit, ok := s.literal(s.code[k+1].operand)
+			if !ok {
+				return nil, false
+			}
+			if next, ok = s.target(s.code[k+3], k+4, last); !ok {
+				return nil, false
+			}
+			c.Pattern = ast.LiteralPattern{Value: lit}
+			k += 4
+		default:
+			c.Pattern = ast.WildcardPattern{}
+		}
+
+		stack, j, ok := s.expressions(k, last, nil)
+		if !ok || j >= last || len(stack) != 1 {
+			return nil, false
+		}
+		if s.code[j].op == vm.OpJumpIfFalse {
+			guardNext, ok := s.target(s.code[j], j+1, last)
+			if !ok || (next >= 0 && guardNext != next) {
+				return nil, false
+			}
+			next = guardNext
+			c.Guard = stack[0]
+			if stack, j, ok = s.expressions(j+1, last, nil); !ok || j >= last || len(stack) != 1 {
+				return nil, false
+			}
+		}
+
+		// The body jumps past the PUSH null ending the match
+		if s.code[j].op != vm.OpJump || (next >= 0 && j+1 != next) {
+			return nil, false
+		}
+		if _, ok := s.target(s.code[j], last+1, last+1); !ok {
+			return nil, false
+		}
+		c.Body = stack[0]
+		cases = append(cases, c)
+		k = j + 1
+	}
+	return cases, len(cases) > 0
+}
+
+// spreadInto rebuilds the literal a SPREAD instruction extends. The compiler
+// builds a literal with spreads in runs of plain elements or fields, so a
+// literal value is the next run and anything else is a spread.
+func spreadInto(target, value ast.Expr) (ast.Expr, bool) {
+	switch t := target.(type) {
+	case ast.ArrayExpr:
+		elements := append([]ast.Expr{}, t.Elements...)
+		if run, ok := value.(ast.ArrayExpr); ok {
+			elements = append(elements, run.Elements...)
+		} else {
+			elements = append(elements, ast.SpreadExpr{Value: value})
+		}
+		return ast.ArrayExpr{Elements: elements}, true
+	case ast.ObjectExpr:
+		fields := append([]ast.ObjectField{}, t.Fields...)
+		if run, ok := value.(ast.ObjectExpr); ok {
+			fields = append(fields, run.Fields...)
+		} else {
+			fields = append(fields, ast.ObjectField{Value: ast.SpreadExpr{Value: value}})
+		}
+		return ast.ObjectExpr{Fields: fields}, true
+	}
+	return nil, false
+}
+
+var binaryOps = map[vm.Opcode]ast.BinOp{
+	vm.OpAdd: ast.Add,
+	vm.OpSub: ast.Sub,
+	vm.OpMul: ast.Mul,
+	vm.OpDiv: ast.Div,
+	vm.OpMod: ast.Mod,
+	vm.OpEq:  ast.Eq,
+	vm.OpNe:  ast.Ne,
+	vm.OpLt:  ast.Lt,
+	vm.OpGt:  ast.Gt,
+	vm.OpGe:  ast.Ge,
+	vm.OpLe:  ast.Le,
+	vm.OpAnd: ast.And,
+	vm.OpOr:  ast.Or,
+}
+
+// target returns the instruction index a jump lands on, provided it lies
+// within [min, end]
+func (s *structurer) target(in instruction, min, end int) (int, bool) {
+	idx, ok := s.index[int(in.operand)]
+	if !ok || idx < min || idx > end {
+		return 0, false
+	}
+	return idx, true
+}
+
+// gotoMarker covers the instructions in [start, end)
+func (s *structurer) gotoMarker(start, end int) ast.Statement {
+	endOffset := s.codeLength
+	if end < len(s.code) {
+		endOffset = s.code[end].offset
+	}
+	return ast.ExpressionStatement{Expr: ast.FunctionCallExpr{
+		Name: GotoMarker,
+		Args: []ast.Expr{
+			ast.LiteralExpr{Value: ast.IntLiteral{Value: int64(s.code[start].offset)}},
+			ast.LiteralExpr{Value: ast.IntLiteral{Value: int64(endOffset)}},
+		},
+	}}
+}
+
+func (s *structurer) declared(name string) bool {
+	for _, scope := range s.scopes {
+		if scope[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// name returns the string constant at idx, as used by variable operands
+func (s *structurer) name(idx uint32) (string, bool) {
+	if int(idx) >= len(s.constants) {
+		return "", false
+	}
+	sv, ok := s.constants[idx].(vm.StringValue)
+	return sv.Val, ok
+}
+
+func (s *structurer) literal(idx uint32) (ast.Literal, bool) {
+	if int(idx) >= len(s.constants) {
+		return nil, false
+	}
+	switch v := s.constants[idx].(type) {
+	case vm.IntValue:
+		return ast.IntLiteral{Value: v.Val}, true
+	case vm.FloatValue:
+		return ast.FloatLiteral{Value: v.Val}, true
+	case vm.StringValue:
+		return ast.StringLiteral{Value: v.Val}, true
+	case vm.BoolValue:
+		return ast.BoolLiteral{Value: v.Val}, true
+	case vm.NullValue:
+		return ast.NullLiteral{}, true
+	}
+	return nil, false
+}
+
+// isNull reports whether the constant at idx is null
+func (s *structurer) isNull(idx uint32) bool {
+	if int(idx) >= len(s.constants) {
+		return false
+	}
+	_, ok := s.constants[idx].(vm.NullValue)
+	return ok
+}
+
+func stringLiteral(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(ast.LiteralExpr)
+	if !ok {
+		return "", false
+	}
+	str, ok := lit.Value.(ast.StringLiteral)
+	return str.Value, ok
+}
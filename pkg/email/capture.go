@@ -0,0 +1,58 @@
+package email
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CapturedMessage is a message kept by a CaptureProvider
+type CapturedMessage struct {
+	Message
+	SentAt time.Time
+}
+
+// CaptureProvider keeps messages in memory and logs them instead of sending
+// them, so development servers can show what would have been sent
+type CaptureProvider struct {
+	mu       sync.Mutex
+	limit    int
+	messages []CapturedMessage
+}
+
+// NewCaptureProvider creates a provider that keeps the latest limit
+// messages
+func NewCaptureProvider(limit int) *CaptureProvider {
+	return &CaptureProvider{limit: limit}
+}
+
+func (p *CaptureProvider) Name() string {
+	return "capture"
+}
+
+// Send logs msg and keeps a copy of it
+func (p *CaptureProvider) Send(msg *Message) error {
+	log.Printf("[MAIL] captured %q to %s", msg.Subject, strings.Join(msg.To, ", "))
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, CapturedMessage{Message: *msg, SentAt: time.Now()})
+	if len(p.messages) > p.limit {
+		p.messages = p.messages[len(p.messages)-p.limit:]
+	}
+	return nil
+}
+
+// Messages returns the kept messages, oldest first
+func (p *CaptureProvider) Messages() []CapturedMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]CapturedMessage(nil), p.messages...)
+}
+
+// Clear discards the kept messages
+func (p *CaptureProvider) Clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = nil
+}
@@ -0,0 +1,160 @@
+package email
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Mailer is the email API injected into routes as `% mail: Mailer`
+type Mailer struct {
+	client *Client
+}
+
+// NewMailer creates a Mailer that sends through client
+func NewMailer(client *Client) *Mailer {
+	return &Mailer{client: client}
+}
+
+// Send sends the message described by args, an object with to (an address
+// or a list of them), subject, text and/or html, and optionally from, cc,
+// bcc, replyTo and data. Each {{name}} in subject, text and html is replaced
+// by data's name field.
+//
+// A message that cannot be sent gives {sent: false, error: "..."} rather
+// than a Go error, so routes can handle the failure; success gives
+// {sent: true}. Only arguments of the wrong type are errors.
+func (m *Mailer) Send(args interface{}) (map[string]interface{}, error) {
+	fields, ok := args.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("mail.send() expects an object, got %T", args)
+	}
+	msg, err := messageFromFields(fields)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.client.Send(msg); err != nil {
+		return map[string]interface{}{"sent": false, "error": err.Error()}, nil
+	}
+	return map[string]interface{}{"sent": true}, nil
+}
+
+// Render replaces each {{name}} in template by data's name field
+func (m *Mailer) Render(template string, data interface{}) (string, error) {
+	vars, ok := data.(map[string]interface{})
+	if !ok && data != nil {
+		return "", fmt.Errorf("mail.render() data must be an object, got %T", data)
+	}
+	return RenderVars(template, vars, false), nil
+}
+
+// messageFromFields builds a message from the object given to mail.send
+func messageFromFields(fields map[string]interface{}) (*Message, error) {
+	msg := &Message{}
+	var err error
+	if msg.To, err = addressField(fields, "to"); err != nil {
+		return nil, err
+	}
+	if msg.CC, err = addressField(fields, "cc"); err != nil {
+		return nil, err
+	}
+	if msg.BCC, err = addressField(fields, "bcc"); err != nil {
+		return nil, err
+	}
+	strs := map[string]*string{
+		"from": &msg.From, "replyTo": &msg.ReplyTo, "subject": &msg.Subject,
+		"text": &msg.Body, "html": &msg.HTML,
+	}
+	for name, dst := range strs {
+		switch v := fields[name].(type) {
+		case nil:
+		case string:
+			*dst = v
+		default:
+			return nil, fmt.Errorf("mail.send() %s must be a string, got %T", name, v)
+		}
+	}
+
+	if data, ok := fields["data"]; ok && data != nil {
+		vars, ok := data.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("mail.send() data must be an object, got %T", data)
+		}
+		msg.Subject = RenderVars(msg.Subject, vars, false)
+		msg.Body = RenderVars(msg.Body, vars, false)
+		msg.HTML = RenderVars(msg.HTML, vars, true)
+	}
+	return msg, nil
+}
+
+// addressField reads a field holding an address or a list of addresses
+func addressField(fields map[string]interface{}, name string) ([]string, error) {
+	switch v := fields[name].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		addrs := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("mail.send() %s must hold strings, got %T", name, item)
+			}
+			addrs[i] = s
+		}
+		return addrs, nil
+	default:
+		return nil, fmt.Errorf("mail.send() %s must be a string or a list of strings, got %T", name, v)
+	}
+}
+
+// templateVar matches {{name}} and {{user.name}}, with optional spaces
+var templateVar = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)*)\s*\}\}`)
+
+// RenderVars replaces each {{name}} in template by the value of name in
+// vars, following dots into nested objects. Missing values render as an
+// empty string. With escapeHTML set, values are HTML-escaped.
+func RenderVars(template string, vars map[string]interface{}, escapeHTML bool) string {
+	return templateVar.ReplaceAllStringFunc(template, func(match string) string {
+		path := templateVar.FindStringSubmatch(match)[1]
+		var value interface{} = vars
+		for _, key := range strings.Split(path, ".") {
+			obj, ok := value.(map[string]interface{})
+			if !ok {
+				value = nil
+				break
+			}
+			value = obj[key]
+		}
+		s := formatTemplateValue(value)
+		if escapeHTML {
+			s = html.EscapeString(s)
+		}
+		return s
+	})
+}
+
+func formatTemplateValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
@@ -0,0 +1,77 @@
+package email
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMailerSendRendersTemplates(t *testing.T) {
+	capture := NewCaptureProvider(10)
+	client := NewClient(capture)
+	client.SetDefaults(MessageDefaults{From: "noreply@example.com"})
+	mailer := NewMailer(client)
+
+	result, err := mailer.Send(map[string]interface{}{
+		"to":      []interface{}{"ada@example.com"},
+		"subject": "Welcome, {{ user.name }}",
+		"text":    "You are user #{{user.id}}",
+		"html":    "<p>Hi {{user.name}}</p>",
+		"data": map[string]interface{}{
+			"user": map[string]interface{}{"name": "Ada <3", "id": int64(42)},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"sent": true}, result)
+
+	sent := capture.Messages()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "noreply@example.com", sent[0].From)
+	assert.Equal(t, "Welcome, Ada <3", sent[0].Subject)
+	assert.Equal(t, "You are user #42", sent[0].Body)
+	assert.Equal(t, "<p>Hi Ada &lt;3</p>", sent[0].HTML)
+}
+
+func TestMailerSendReportsFailures(t *testing.T) {
+	mock := NewMockProvider("test")
+	mailer := NewMailer(NewClient(mock))
+
+	// Invalid messages and delivery failures are results, not errors
+	result, err := mailer.Send(map[string]interface{}{"to": "not-an-address", "subject": "Hi", "text": "Hello"})
+	require.NoError(t, err)
+	assert.Equal(t, false, result["sent"])
+	assert.Contains(t, result["error"], "invalid email address")
+
+	mock.SendErr = errors.New("connection refused")
+	result, err = mailer.Send(map[string]interface{}{"to": "ada@example.com", "subject": "Hi", "text": "Hello"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"sent": false, "error": "connection refused"}, result)
+
+	// Arguments of the wrong type are errors
+	_, err = mailer.Send("ada@example.com")
+	assert.Error(t, err)
+	_, err = mailer.Send(map[string]interface{}{"to": int64(1)})
+	assert.Error(t, err)
+}
+
+func TestCaptureProviderKeepsLatest(t *testing.T) {
+	capture := NewCaptureProvider(2)
+	for _, subject := range []string{"one", "two", "three"} {
+		require.NoError(t, capture.Send(&Message{To: []string{"ada@example.com"}, Subject: subject}))
+	}
+	sent := capture.Messages()
+	require.Len(t, sent, 2)
+	assert.Equal(t, "two", sent[0].Subject)
+	assert.Equal(t, "three", sent[1].Subject)
+
+	capture.Clear()
+	assert.Empty(t, capture.Messages())
+}
+
+func TestRenderVars(t *testing.T) {
+	vars := map[string]interface{}{"name": "Ada", "score": 9.5, "tags": []interface{}{"a"}}
+	assert.Equal(t, "Ada scored 9.5 [\"a\"]; missing: ", RenderVars("{{name}} scored {{score}} {{tags}}; missing: {{nope.x}}", vars, false))
+	assert.Equal(t, "{{ not a var }}", RenderVars("{{ not a var }}", vars, false))
+}
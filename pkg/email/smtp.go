@@ -0,0 +1,245 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smtpTimeout bounds connecting to the server and sending one message
+const smtpTimeout = 30 * time.Second
+
+// SMTPConfigFromEnv reads SMTP_HOST, SMTP_PORT (default 587), SMTP_USERNAME,
+// SMTP_PASSWORD and SMTP_STARTTLS. STARTTLS is required unless SMTP_STARTTLS
+// is "false". ok is false when SMTP_HOST is unset.
+func SMTPConfigFromEnv() (cfg SMTPConfig, ok bool, err error) {
+	cfg.Host = os.Getenv("SMTP_HOST")
+	if cfg.Host == "" {
+		return cfg, false, nil
+	}
+	cfg.Port = 587
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		if cfg.Port, err = strconv.Atoi(v); err != nil {
+			return cfg, false, fmt.Errorf("SMTP_PORT: invalid port %q", v)
+		}
+	}
+	cfg.Username = os.Getenv("SMTP_USERNAME")
+	cfg.Password = os.Getenv("SMTP_PASSWORD")
+	cfg.UseTLS = !strings.EqualFold(os.Getenv("SMTP_STARTTLS"), "false")
+	return cfg, true, nil
+}
+
+// SMTPProvider sends messages through an SMTP server, upgrading the
+// connection with STARTTLS when the server offers it
+type SMTPProvider struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPProvider creates a provider for an SMTP server. With UseTLS set,
+// sending fails rather than falling back to plain text when the server does
+// not offer STARTTLS.
+func NewSMTPProvider(cfg SMTPConfig) *SMTPProvider {
+	return &SMTPProvider{cfg: cfg}
+}
+
+func (p *SMTPProvider) Name() string {
+	return "smtp"
+}
+
+// Send delivers msg to its To, CC and BCC recipients
+func (p *SMTPProvider) Send(msg *Message) error {
+	from, err := envelopeAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender: %w", err)
+	}
+	var recipients []string
+	for _, list := range [][]string{msg.To, msg.CC, msg.BCC} {
+		for _, addr := range list {
+			rcpt, err := envelopeAddress(addr)
+			if err != nil {
+				return fmt.Errorf("invalid recipient: %w", err)
+			}
+			recipients = append(recipients, rcpt)
+		}
+	}
+	data, err := buildMIME(msg, time.Now(), newMessageID(from))
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(p.cfg.Host, strconv.Itoa(p.cfg.Port))
+	conn, err := net.DialTimeout("tcp", addr, smtpTimeout)
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+	c, err := smtp.NewClient(conn, p.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: p.cfg.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	} else if p.cfg.UseTLS {
+		return fmt.Errorf("smtp: %s does not support STARTTLS", addr)
+	}
+	if p.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", p.cfg.Username, p.cfg.Password, p.cfg.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	for _, rcpt := range recipients {
+		if err := c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("smtp: recipient %s: %w", rcpt, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return c.Quit()
+}
+
+// envelopeAddress returns the bare address of "a@example.com" or
+// "Name <a@example.com>"
+func envelopeAddress(addr string) (string, error) {
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil {
+		return "", fmt.Errorf("%q: %w", addr, err)
+	}
+	return parsed.Address, nil
+}
+
+// newMessageID returns a unique Message-ID in the sender's domain
+func newMessageID(from string) string {
+	domain := "localhost"
+	if _, d, ok := strings.Cut(from, "@"); ok {
+		domain = d
+	}
+	var b [12]byte
+	rand.Read(b[:])
+	return "<" + hex.EncodeToString(b[:]) + "@" + domain + ">"
+}
+
+// buildMIME renders msg as an RFC 5322 message. Headers with non-ASCII text,
+// such as a UTF-8 subject, are encoded as RFC 2047 encoded-words, and bodies
+// are quoted-printable UTF-8. A message with both a text and an HTML body is
+// sent as multipart/alternative.
+func buildMIME(msg *Message, date time.Time, messageID string) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, sanitizeHeader(value))
+	}
+
+	header("From", formatAddressList([]string{msg.From}))
+	header("To", formatAddressList(msg.To))
+	if len(msg.CC) > 0 {
+		header("Cc", formatAddressList(msg.CC))
+	}
+	if msg.ReplyTo != "" {
+		header("Reply-To", formatAddressList([]string{msg.ReplyTo}))
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	header("MIME-Version", "1.0")
+	names := make([]string, 0, len(msg.Headers))
+	for name := range msg.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		header(textproto.CanonicalMIMEHeaderKey(sanitizeHeader(name)), mime.QEncoding.Encode("utf-8", msg.Headers[name]))
+	}
+
+	if msg.Body == "" || msg.HTML == "" {
+		contentType, body := "text/plain", msg.Body
+		if msg.HTML != "" {
+			contentType, body = "text/html", msg.HTML
+		}
+		header("Content-Type", contentType+"; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.Body},
+		{"text/html", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// formatAddressList formats addresses for a header, encoding non-ASCII
+// display names
+func formatAddressList(addrs []string) string {
+	formatted := make([]string, len(addrs))
+	for i, addr := range addrs {
+		if parsed, err := mail.ParseAddress(addr); err == nil {
+			formatted[i] = parsed.String()
+		} else {
+			formatted[i] = addr
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// sanitizeHeader removes line breaks, which would start a new header
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
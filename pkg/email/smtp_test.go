@@ -0,0 +1,197 @@
+package email
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smtpTestServer is a minimal SMTP server that accepts every message
+type smtpTestServer struct {
+	addr       *net.TCPAddr
+	startTLS   bool
+	from       chan string
+	recipients chan []string
+	data       chan string
+}
+
+func startSMTPTestServer(t *testing.T, startTLS bool) *smtpTestServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	s := &smtpTestServer{
+		addr:       ln.Addr().(*net.TCPAddr),
+		startTLS:   startTLS,
+		from:       make(chan string, 1),
+		recipients: make(chan []string, 1),
+		data:       make(chan string, 1),
+	}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s.serve(conn)
+	}()
+	return s
+}
+
+func (s *smtpTestServer) serve(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 localhost ESMTP test")
+	var rcpts []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		switch verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0]); verb {
+		case "EHLO":
+			if s.startTLS {
+				reply("250-localhost")
+				reply("250 STARTTLS")
+			} else {
+				reply("250 localhost")
+			}
+		case "MAIL":
+			s.from <- cmd
+			reply("250 OK")
+		case "RCPT":
+			rcpts = append(rcpts, cmd)
+			reply("250 OK")
+		case "DATA":
+			s.recipients <- rcpts
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.data <- data.String()
+			reply("250 OK queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func (s *smtpTestServer) config() SMTPConfig {
+	return SMTPConfig{Host: s.addr.IP.String(), Port: s.addr.Port}
+}
+
+func TestSMTPProviderSendsUTF8Message(t *testing.T) {
+	srv := startSMTPTestServer(t, false)
+	provider := NewSMTPProvider(srv.config())
+
+	err := provider.Send(&Message{
+		From:    "Glyph <noreply@example.com>",
+		To:      []string{"ada@example.com"},
+		BCC:     []string{"audit@example.com"},
+		Subject: "Willkommen, Zoë! 🎉",
+		Body:    "Grüße aus Glyph",
+		HTML:    "<p>Grüße aus Glyph</p>",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "MAIL FROM:<noreply@example.com>", <-srv.from)
+	assert.Equal(t, []string{"RCPT TO:<ada@example.com>", "RCPT TO:<audit@example.com>"}, <-srv.recipients)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(<-srv.data))
+	require.NoError(t, err)
+	rawSubject := parsed.Header.Get("Subject")
+	assert.True(t, strings.HasPrefix(rawSubject, "=?utf-8?q?"), "subject should be an encoded-word, got %q", rawSubject)
+	subject, err := new(mime.WordDecoder).DecodeHeader(rawSubject)
+	require.NoError(t, err)
+	assert.Equal(t, "Willkommen, Zoë! 🎉", subject)
+	assert.Equal(t, `"Glyph" <noreply@example.com>`, parsed.Header.Get("From"))
+	assert.Equal(t, "<ada@example.com>", parsed.Header.Get("To"))
+	assert.Empty(t, parsed.Header.Get("Bcc"))
+	assert.Equal(t, "1.0", parsed.Header.Get("MIME-Version"))
+	assert.Contains(t, parsed.Header.Get("Message-ID"), "@example.com>")
+	mediaType, _, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+}
+
+func TestSMTPProviderRequiresStartTLS(t *testing.T) {
+	srv := startSMTPTestServer(t, false)
+	cfg := srv.config()
+	cfg.UseTLS = true
+
+	err := NewSMTPProvider(cfg).Send(&Message{
+		From: "noreply@example.com", To: []string{"ada@example.com"}, Subject: "Hi", Body: "Hello",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not support STARTTLS")
+}
+
+func TestBuildMIMESinglePart(t *testing.T) {
+	date := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	data, err := buildMIME(&Message{
+		From:    "noreply@example.com",
+		To:      []string{"ada@example.com", "bob@example.com"},
+		Subject: "Plain\r\nBcc: injected@example.com",
+		Body:    "café",
+		Headers: map[string]string{"x-campaign": "signup"},
+	}, date, "<id@example.com>")
+	require.NoError(t, err)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	assert.Equal(t, "<ada@example.com>, <bob@example.com>", parsed.Header.Get("To"))
+	// Line breaks in the subject are encoded rather than starting a header
+	assert.Equal(t, "=?utf-8?q?Plain=0D=0ABcc:_injected@example.com?=", parsed.Header.Get("Subject"))
+	assert.Empty(t, parsed.Header.Get("Bcc"))
+	assert.Equal(t, "signup", parsed.Header.Get("X-Campaign"))
+	assert.Equal(t, "Fri, 02 Jan 2026 03:04:05 +0000", parsed.Header.Get("Date"))
+	assert.Equal(t, "text/plain; charset=utf-8", parsed.Header.Get("Content-Type"))
+	assert.Equal(t, "quoted-printable", parsed.Header.Get("Content-Transfer-Encoding"))
+	body, err := io.ReadAll(parsed.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "caf=C3=A9", string(body))
+}
+
+func TestSMTPConfigFromEnv(t *testing.T) {
+	t.Setenv("SMTP_HOST", "")
+	_, ok, err := SMTPConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	t.Setenv("SMTP_USERNAME", "user")
+	t.Setenv("SMTP_PASSWORD", "secret")
+	cfg, ok, err := SMTPConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, SMTPConfig{Host: "smtp.example.com", Port: 587, Username: "user", Password: "secret", UseTLS: true}, cfg)
+
+	t.Setenv("SMTP_PORT", strconv.Itoa(2525))
+	t.Setenv("SMTP_STARTTLS", "false")
+	cfg, _, err = SMTPConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 2525, cfg.Port)
+	assert.False(t, cfg.UseTLS)
+
+	t.Setenv("SMTP_PORT", "smtp")
+	_, _, err = SMTPConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,305 @@
+package formatter
+
+import (
+	"fmt"
+	"github.com/glyphlang/glyph/pkg/ast"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/glyphlang/glyph/pkg/parser"
+)
+
+// Canonical source formatting (glyph fmt)
+//
+// FormatSource works line by line on the original text so that comments and
+// line structure survive. It re-indents by bracket depth, collapses runs of
+// whitespace between tokens, orders route directives canonically, keeps at
+// most one blank line in a row (and exactly one between top-level items),
+// and ends the file with a single newline. The source is parsed before and
+// after formatting, and the two ASTs must match.
+
+// canonicalIndent is one indentation level
+const canonicalIndent = "  "
+
+// directiveRank orders the directives at the top of a block:
+// middleware first, then injections, then input declarations
+var directiveRank = map[Mode]map[string]int{
+	Compact:  {"+": 0, "%": 1, "<": 2},
+	Expanded: {"middleware": 0, "use": 1, "expects": 2},
+}
+
+// formattedLine is one output line before indentation is applied
+type formattedLine struct {
+	indent int
+	code   string // normalized code without the comment
+	text   string // code and comment joined
+}
+
+// FormatSource returns source in canonical form. mode selects the lexer
+// (compact .glyph or expanded .glyphx syntax); the syntax itself is never
+// converted.
+func FormatSource(source string, mode Mode) (string, error) {
+	before, err := parseForFormat(source, mode)
+	if err != nil {
+		return "", err
+	}
+
+	result := formatLines(source, mode)
+
+	after, err := parseForFormat(result, mode)
+	if err != nil {
+		return "", fmt.Errorf("formatted output does not parse: %w", err)
+	}
+	if !equivalentAST(reflect.ValueOf(before), reflect.ValueOf(after)) {
+		return "", fmt.Errorf("formatting would change the program")
+	}
+	return result, nil
+}
+
+// parseForFormat parses source with the lexer matching mode
+func parseForFormat(source string, mode Mode) (*ast.Module, error) {
+	var tokens []parser.Token
+	var err error
+	if mode == Expanded {
+		tokens, err = parser.NewExpandedLexer(source).Tokenize()
+	} else {
+		tokens, err = parser.NewLexer(source).Tokenize()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parser.NewParserWithSource(tokens, source).Parse()
+}
+
+// formatLines applies the canonical layout rules to every line
+func formatLines(source string, mode Mode) string {
+	var lines []formattedLine
+	// Open brackets; true when the bracket added an indentation level. A line
+	// adds at most one level however many brackets it leaves open, so
+	// foo({ ... }) indents its body once.
+	var open []bool
+	pendingBlank := false
+	closedItem := false
+
+	for _, raw := range strings.Split(source, "\n") {
+		code, comment, brackets, leadingClosers := splitLine(raw)
+		if code == "" && comment == "" {
+			pendingBlank = len(lines) > 0
+			continue
+		}
+
+		if leadingClosers > len(open) {
+			leadingClosers = len(open)
+		}
+		indent := countLevels(open[:len(open)-leadingClosers])
+
+		// Blank lines never follow an opening bracket or precede a closing one;
+		// top-level items are always separated by one
+		if len(lines) > 0 {
+			prev := lines[len(lines)-1]
+			opened := prev.code != "" && strings.ContainsAny(prev.code[len(prev.code)-1:], "{[(")
+			if (pendingBlank && !opened && leadingClosers == 0) || (closedItem && indent == 0) {
+				lines = append(lines, formattedLine{})
+			}
+		}
+		pendingBlank = false
+
+		text := code
+		if comment != "" {
+			if text != "" {
+				text += " "
+			}
+			text += comment
+		}
+		lines = append(lines, formattedLine{indent: indent, code: code, text: text})
+
+		low := len(open)
+		closed := false
+		for _, ch := range brackets {
+			if strings.ContainsRune("{[(", ch) {
+				open = append(open, false)
+			} else if len(open) > 0 {
+				open = open[:len(open)-1]
+				closed = true
+				if len(open) < low {
+					low = len(open)
+				}
+			}
+		}
+		if len(open) > low {
+			open[len(open)-1] = true
+		}
+		closedItem = closed && len(open) == 0
+	}
+
+	sortDirectives(lines, mode)
+
+	var out strings.Builder
+	for _, line := range lines {
+		if line.text != "" {
+			out.WriteString(strings.Repeat(canonicalIndent, line.indent))
+			out.WriteString(line.text)
+		}
+		out.WriteByte('\n')
+	}
+	return out.String()
+}
+
+// countLevels counts the brackets that added an indentation level
+func countLevels(open []bool) int {
+	n := 0
+	for _, level := range open {
+		if level {
+			n++
+		}
+	}
+	return n
+}
+
+// splitLine normalizes the whitespace of one line outside strings and splits
+// off its comment. It also returns the line's brackets in order and how many
+// closing brackets the line starts with.
+func splitLine(line string) (code string, comment string, brackets string, leadingClosers int) {
+	var b, br strings.Builder
+	var quote byte
+	pendingSpace := false
+	onlyClosers := true
+
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		if quote != 0 {
+			b.WriteByte(ch)
+			if ch == '\\' && i+1 < len(line) {
+				i++
+				b.WriteByte(line[i])
+			} else if ch == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		if ch == '#' || (ch == '/' && i+1 < len(line) && line[i+1] == '/') {
+			comment = strings.TrimRight(line[i:], " \t\r")
+			break
+		}
+		if ch == ' ' || ch == '\t' || ch == '\r' {
+			pendingSpace = b.Len() > 0
+			continue
+		}
+		if pendingSpace {
+			b.WriteByte(' ')
+			pendingSpace = false
+		}
+
+		switch ch {
+		case '"', '\'':
+			quote = ch
+		case '{', '[', '(':
+			br.WriteByte(ch)
+		case '}', ']', ')':
+			br.WriteByte(ch)
+			if onlyClosers {
+				leadingClosers++
+			}
+		}
+		if ch != '}' && ch != ']' && ch != ')' {
+			onlyClosers = false
+		}
+		b.WriteByte(ch)
+	}
+
+	return b.String(), comment, br.String(), leadingClosers
+}
+
+// sortDirectives stably reorders each run of directive lines that directly
+// follows an opening brace
+func sortDirectives(lines []formattedLine, mode Mode) {
+	ranks := directiveRank[mode]
+	rankOf := func(line formattedLine) (int, bool) {
+		word, _, _ := strings.Cut(line.code, " ")
+		rank, ok := ranks[word]
+		return rank, ok
+	}
+
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasSuffix(lines[i].code, "{") {
+			continue
+		}
+		start := i + 1
+		end := start
+		for end < len(lines) && lines[end].indent == lines[i].indent+1 {
+			if _, ok := rankOf(lines[end]); !ok {
+				break
+			}
+			end++
+		}
+		run := lines[start:end]
+		sort.SliceStable(run, func(a, b int) bool {
+			ra, _ := rankOf(run[a])
+			rb, _ := rankOf(run[b])
+			return ra < rb
+		})
+	}
+}
+
+// posType is skipped when comparing ASTs, since formatting moves tokens
+var posType = reflect.TypeOf(ast.Pos{})
+
+// equivalentAST reports whether two AST values are deeply equal, ignoring
+// source positions
+func equivalentAST(a, b reflect.Value) bool {
+	if a.IsValid() != b.IsValid() {
+		return false
+	}
+	if !a.IsValid() {
+		return true
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return equivalentAST(a.Elem(), b.Elem())
+	case reflect.Struct:
+		if a.Type() == posType {
+			return true
+		}
+		for i := 0; i < a.NumField(); i++ {
+			if !equivalentAST(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equivalentAST(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		iter := a.MapRange()
+		for iter.Next() {
+			if !equivalentAST(iter.Value(), b.MapIndex(iter.Key())) {
+				return false
+			}
+		}
+		return true
+	default:
+		if !a.CanInterface() {
+			return fmt.Sprint(a) == fmt.Sprint(b)
+		}
+		return reflect.DeepEqual(a.Interface(), b.Interface())
+	}
+}
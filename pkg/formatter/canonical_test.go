@@ -0,0 +1,199 @@
+package formatter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const messySource = `# Users API
+import   "./types"
+
+
+@ GET /users/:id   -> User {
+     % db: Database   # injected
+  + auth(jwt)
+        $ user = db.users.Get(id)
+
+
+    if user == null {
+  > {error: "not found"}
+    }
+  > user
+}
+: User {
+	name: str!
+  tags:  [str]
+}
+! greet(name: str) {
+  $ msg = format({
+        greeting: "Hello,   " + name
+  })
+  > msg
+}`
+
+const canonicalSource = `# Users API
+import "./types"
+
+@ GET /users/:id -> User {
+  + auth(jwt)
+  % db: Database # injected
+  $ user = db.users.Get(id)
+
+  if user == null {
+    > {error: "not found"}
+  }
+  > user
+}
+
+: User {
+  name: str!
+  tags: [str]
+}
+
+! greet(name: str) {
+  $ msg = format({
+    greeting: "Hello,   " + name
+  })
+  > msg
+}
+`
+
+func TestFormatSource(t *testing.T) {
+	got, err := FormatSource(messySource, Compact)
+	if err != nil {
+		t.Fatalf("FormatSource failed: %v", err)
+	}
+	if got != canonicalSource {
+		t.Errorf("FormatSource output mismatch\ngot:\n%s\nwant:\n%s", got, canonicalSource)
+	}
+}
+
+func TestFormatSource_ParseError(t *testing.T) {
+	if _, err := FormatSource("@ GET /broken {\n", Compact); err == nil {
+		t.Error("expected an error for source that does not parse")
+	}
+}
+
+func TestFormatSource_Expanded(t *testing.T) {
+	source := "route GET /ping {\n      use db: Database\n  middleware auth(jwt)\n      return {ok: true}\n}"
+	want := "route GET /ping {\n  middleware auth(jwt)\n  use db: Database\n  return {ok: true}\n}\n"
+
+	got, err := FormatSource(source, Expanded)
+	if err != nil {
+		t.Fatalf("FormatSource failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestFormatSource_RoundTrip checks fmt(fmt(x)) == fmt(x) and that the
+// formatted output parses to an equivalent AST for every example program
+func TestFormatSource_RoundTrip(t *testing.T) {
+	files, _ := filepath.Glob("../../examples/*/*.glyph")
+	files = append(files, "inline")
+	for _, file := range files {
+		source := messySource
+		if file != "inline" {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			source = string(data)
+			if _, err := parseForFormat(source, Compact); err != nil {
+				continue // examples may use syntax the parser does not support yet
+			}
+		}
+
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			once, err := FormatSource(source, Compact)
+			if err != nil {
+				t.Fatalf("FormatSource failed: %v", err)
+			}
+			twice, err := FormatSource(once, Compact)
+			if err != nil {
+				t.Fatalf("second FormatSource failed: %v", err)
+			}
+			if once != twice {
+				t.Errorf("formatting is not idempotent")
+			}
+
+			before, _ := parseForFormat(source, Compact)
+			after, _ := parseForFormat(once, Compact)
+			if !equivalentAST(reflect.ValueOf(before), reflect.ValueOf(after)) {
+				t.Errorf("formatted output parses to a different AST")
+			}
+
+			if strings.Count(source, "#") != strings.Count(once, "#") {
+				t.Errorf("comments were not preserved")
+			}
+		})
+	}
+}
+
+func TestFormatSource_CoalesceAndOptionalChaining(t *testing.T) {
+	source := "@ GET /bio {\n  > input.user?.profile?.bio  ??   \"no bio\"\n}"
+	want := "@ GET /bio {\n  > input.user?.profile?.bio ?? \"no bio\"\n}\n"
+
+	got, err := FormatSource(source, Compact)
+	if err != nil {
+		t.Fatalf("FormatSource failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatSource_InterpolatedString(t *testing.T) {
+	source := "@ GET /hello {\n  > \"Hello,   {user.name}! \\{x}\"\n}"
+
+	got, err := FormatSource(source, Compact)
+	if err != nil {
+		t.Fatalf("FormatSource failed: %v", err)
+	}
+	if got != source+"\n" {
+		t.Errorf("got:\n%s\nwant:\n%s", got, source)
+	}
+}
+
+func TestFormatSource_Spread(t *testing.T) {
+	source := "@ GET /merge {\n  > {...base,   tags: [...base.tags,    \"b\"]}\n}"
+	want := "@ GET /merge {\n  > {...base, tags: [...base.tags, \"b\"]}\n}\n"
+
+	got, err := FormatSource(source, Compact)
+	if err != nil {
+		t.Fatalf("FormatSource failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatSource_Ternary(t *testing.T) {
+	source := "@ GET /status {\n  > {status: active  ?   \"on\"  :  \"off\"}\n}"
+	want := "@ GET /status {\n  > {status: active ? \"on\" : \"off\"}\n}\n"
+
+	got, err := FormatSource(source, Compact)
+	if err != nil {
+		t.Fatalf("FormatSource failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatSource_FieldAndIndexAssignment(t *testing.T) {
+	source := "@ GET /rename {\n  $ user = {name: \"a\", tags: [\"x\"]}\n  user.name   =  \"b\"\n  user.tags[-1]   = \"y\"\n  > user\n}"
+	want := "@ GET /rename {\n  $ user = {name: \"a\", tags: [\"x\"]}\n  user.name = \"b\"\n  user.tags[-1] = \"y\"\n  > user\n}\n"
+
+	got, err := FormatSource(source, Compact)
+	if err != nil {
+		t.Fatalf("FormatSource failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
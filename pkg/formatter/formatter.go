@@ -22,6 +22,8 @@ package formatter
 import (
 	"fmt"
 	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/parser"
+	"strconv"
 	"strings"
 )
 
@@ -68,12 +70,20 @@ func (f *Formatter) formatItem(item ast.Item) {
 	switch v := item.(type) {
 	case *ast.TypeDef:
 		f.formatTypeDef(v)
+	case *ast.EnumDef:
+		f.formatEnumDef(v)
 	case *ast.Route:
 		f.formatRoute(v)
 	case *ast.Command:
 		f.formatCommand(v)
 	case *ast.CronTask:
 		f.formatCronTask(v)
+	case *ast.SeedBlock:
+		f.formatSeedBlock(v)
+	case *ast.MiddlewareDef:
+		f.formatMiddlewareDef(v)
+	case *ast.ErrorHandler:
+		f.formatErrorHandler(v)
 	case *ast.EventHandler:
 		f.formatEventHandler(v)
 	case *ast.QueueWorker:
@@ -91,6 +101,26 @@ func (f *Formatter) formatItem(item ast.Item) {
 	}
 }
 
+func (f *Formatter) formatEnumDef(ed *ast.EnumDef) {
+	if f.mode == Expanded {
+		f.write("type ")
+	} else {
+		f.write(": ")
+	}
+
+	f.write(ed.Name)
+	f.write(" = ")
+	for i, value := range ed.Values {
+		if i > 0 {
+			f.write(" | ")
+		}
+		f.write("\"")
+		f.write(escapeString(value))
+		f.write("\"")
+	}
+	f.writeln("")
+}
+
 func (f *Formatter) formatTypeDef(td *ast.TypeDef) {
 	if f.mode == Expanded {
 		f.write("type ")
@@ -126,6 +156,14 @@ func (f *Formatter) formatTypeDef(td *ast.TypeDef) {
 		if field.Required {
 			f.write("!")
 		}
+		for _, ann := range field.Annotations {
+			f.write(" ")
+			f.formatFieldAnnotation(ann)
+		}
+		if field.Default != nil {
+			f.write(" = ")
+			f.formatExpr(field.Default)
+		}
 		f.writeln("")
 	}
 
@@ -134,6 +172,44 @@ func (f *Formatter) formatTypeDef(td *ast.TypeDef) {
 	f.writeln("}")
 }
 
+// formatFieldAnnotation writes a validation annotation such as @minLen(2)
+// or @oneOf(["a", "b"])
+func (f *Formatter) formatFieldAnnotation(ann ast.FieldAnnotation) {
+	f.write("@")
+	f.write(ann.Name)
+	if len(ann.Params) == 0 {
+		return
+	}
+	f.write("(")
+	for i, param := range ann.Params {
+		if i > 0 {
+			f.write(", ")
+		}
+		switch v := param.(type) {
+		case int64:
+			f.write(strconv.FormatInt(v, 10))
+		case float64:
+			s := strconv.FormatFloat(v, 'f', -1, 64)
+			if !strings.ContainsAny(s, ".eE") {
+				s += ".0"
+			}
+			f.write(s)
+		case string:
+			f.write("\"" + escapeString(v) + "\"")
+		case []string:
+			f.write("[")
+			for j, item := range v {
+				if j > 0 {
+					f.write(", ")
+				}
+				f.write("\"" + escapeString(item) + "\"")
+			}
+			f.write("]")
+		}
+	}
+	f.write(")")
+}
+
 func (f *Formatter) formatRoute(r *ast.Route) {
 	if f.mode == Expanded {
 		f.write("route ")
@@ -145,22 +221,6 @@ func (f *Formatter) formatRoute(r *ast.Route) {
 	f.write(" ")
 	f.write(r.Path)
 
-	for _, qp := range r.QueryParams {
-		f.write(" ?")
-		f.write(qp.Name)
-		if qp.Type != nil {
-			f.write(": ")
-			f.formatType(qp.Type)
-		}
-		if qp.Required {
-			f.write("!")
-		}
-		if qp.Default != nil {
-			f.write(" = ")
-			f.formatExpr(qp.Default)
-		}
-	}
-
 	if r.ReturnType != nil {
 		f.write(" -> ")
 		f.formatType(r.ReturnType)
@@ -193,6 +253,16 @@ func (f *Formatter) formatRoute(r *ast.Route) {
 		f.writeln(")")
 	}
 
+	for _, mw := range r.Middlewares {
+		f.writeIndent()
+		if f.mode == Expanded {
+			f.write("middleware ")
+		} else {
+			f.write("+ ")
+		}
+		f.writeln(mw.Name)
+	}
+
 	for _, inj := range r.Injections {
 		f.writeIndent()
 		if f.mode == Expanded {
@@ -206,6 +276,36 @@ func (f *Formatter) formatRoute(r *ast.Route) {
 		f.writeln("")
 	}
 
+	if r.InputType != nil {
+		f.writeIndent()
+		if f.mode == Expanded {
+			f.write("expects ")
+		} else {
+			f.write("< ")
+		}
+		f.write("input: ")
+		f.formatType(r.InputType)
+		f.writeln("")
+	}
+
+	for _, qp := range r.QueryParams {
+		f.writeIndent()
+		f.write("? ")
+		f.write(qp.Name)
+		if qp.Type != nil {
+			f.write(": ")
+			f.formatType(qp.Type)
+		}
+		if qp.Required {
+			f.write("!")
+		}
+		if qp.Default != nil {
+			f.write(" = ")
+			f.formatExpr(qp.Default)
+		}
+		f.writeln("")
+	}
+
 	for _, stmt := range r.Body {
 		f.formatStatement(stmt)
 	}
@@ -247,6 +347,11 @@ func (f *Formatter) formatCommand(c *ast.Command) {
 			f.write(" = ")
 			f.formatExpr(p.Default)
 		}
+		if p.Description != "" {
+			f.write(" \"")
+			f.write(p.Description)
+			f.write("\"")
+		}
 	}
 
 	f.writeln(" {")
@@ -261,6 +366,60 @@ func (f *Formatter) formatCommand(c *ast.Command) {
 	f.writeln("}")
 }
 
+func (f *Formatter) formatSeedBlock(sb *ast.SeedBlock) {
+	if f.mode == Expanded {
+		f.write("seed")
+	} else {
+		f.write("seed!")
+	}
+	if sb.Name != "" {
+		f.write(" ")
+		f.write(sb.Name)
+	}
+	f.writeln(" {")
+	f.indent++
+	for _, stmt := range sb.Body {
+		f.formatStatement(stmt)
+	}
+	f.indent--
+	f.writeIndent()
+	f.writeln("}")
+}
+
+func (f *Formatter) formatMiddlewareDef(mw *ast.MiddlewareDef) {
+	if f.mode == Expanded {
+		f.write("middleware ")
+	} else {
+		f.write("@ middleware ")
+	}
+	f.write(mw.Name)
+	f.writeln(" {")
+	f.indent++
+	for _, stmt := range mw.Body {
+		f.formatStatement(stmt)
+	}
+	f.indent--
+	f.writeIndent()
+	f.writeln("}")
+}
+
+func (f *Formatter) formatErrorHandler(h *ast.ErrorHandler) {
+	if f.mode == Expanded {
+		f.write("route ")
+	} else {
+		f.write("@ ")
+	}
+	f.write(string(h.Kind))
+	f.writeln(" {")
+	f.indent++
+	for _, stmt := range h.Body {
+		f.formatStatement(stmt)
+	}
+	f.indent--
+	f.writeIndent()
+	f.writeln("}")
+}
+
 func (f *Formatter) formatCronTask(ct *ast.CronTask) {
 	if f.mode == Expanded {
 		f.write("cron ")
@@ -557,15 +716,20 @@ func (f *Formatter) formatStatement(stmt ast.Statement) {
 	switch v := stmt.(type) {
 	// Handle both pointer and value types for each statement
 	case ast.AssignStatement:
-		f.formatAssign(v.Target, v.Value)
+		f.formatAssign(v.Target, v.Value, v.Const, v.Type)
 	case *ast.AssignStatement:
-		f.formatAssign(v.Target, v.Value)
+		f.formatAssign(v.Target, v.Value, v.Const, v.Type)
 
 	case ast.ReassignStatement:
 		f.formatReassign(v.Target, v.Value)
 	case *ast.ReassignStatement:
 		f.formatReassign(v.Target, v.Value)
 
+	case ast.IndexAssignStatement:
+		f.formatIndexAssign(v.Target, v.Value)
+	case *ast.IndexAssignStatement:
+		f.formatIndexAssign(v.Target, v.Value)
+
 	case ast.ReturnStatement:
 		f.formatReturn(v.Value)
 	case *ast.ReturnStatement:
@@ -586,6 +750,16 @@ func (f *Formatter) formatStatement(stmt ast.Statement) {
 	case *ast.ForStatement:
 		f.formatFor(v.KeyVar, v.ValueVar, v.Iterable, v.Body)
 
+	case ast.BackgroundStatement:
+		f.formatBackground(v.Body)
+	case *ast.BackgroundStatement:
+		f.formatBackground(v.Body)
+
+	case ast.TryStatement:
+		f.formatTry(v.Body, v.ErrorVar, v.Catch)
+	case *ast.TryStatement:
+		f.formatTry(v.Body, v.ErrorVar, v.Catch)
+
 	case ast.SwitchStatement:
 		f.formatSwitch(v.Value, v.Cases, v.Default)
 	case *ast.SwitchStatement:
@@ -650,6 +824,21 @@ func (f *Formatter) formatStatement(stmt ast.Statement) {
 		}
 		f.writeln(")")
 
+	case ast.YieldStatement:
+		f.write("yield ")
+		f.formatExpr(v.Value)
+		f.writeln("")
+	case *ast.YieldStatement:
+		f.write("yield ")
+		f.formatExpr(v.Value)
+		f.writeln("")
+
+	case ast.BreakStatement, *ast.BreakStatement:
+		f.writeln("break")
+
+	case ast.ContinueStatement, *ast.ContinueStatement:
+		f.writeln("continue")
+
 	case ast.WsCloseStatement:
 		f.write("ws.close(")
 		f.formatExpr(v.Client)
@@ -671,13 +860,19 @@ func (f *Formatter) formatStatement(stmt ast.Statement) {
 
 // Statement formatting helpers
 
-func (f *Formatter) formatAssign(target string, value ast.Expr) {
-	if f.mode == Expanded {
+func (f *Formatter) formatAssign(target string, value ast.Expr, constant bool, typ ast.Type) {
+	if constant {
+		f.write("const ")
+	} else if f.mode == Expanded {
 		f.write("let ")
 	} else {
 		f.write("$ ")
 	}
 	f.write(target)
+	if typ != nil {
+		f.write(": ")
+		f.formatType(typ)
+	}
 	f.write(" = ")
 	f.formatExpr(value)
 	f.writeln("")
@@ -690,6 +885,13 @@ func (f *Formatter) formatReassign(target string, value ast.Expr) {
 	f.writeln("")
 }
 
+func (f *Formatter) formatIndexAssign(target, value ast.Expr) {
+	f.formatExpr(target)
+	f.write(" = ")
+	f.formatExpr(value)
+	f.writeln("")
+}
+
 func (f *Formatter) formatReturn(value ast.Expr) {
 	if f.mode == Expanded {
 		f.write("return ")
@@ -710,6 +912,19 @@ func (f *Formatter) formatIf(condition ast.Expr, thenBlock, elseBlock []ast.Stat
 	}
 	f.indent--
 	f.writeIndent()
+	if len(elseBlock) == 1 {
+		// An else block holding only an if statement is an else-if chain
+		switch v := elseBlock[0].(type) {
+		case ast.IfStatement:
+			f.write("} else ")
+			f.formatIf(v.Condition, v.ThenBlock, v.ElseBlock)
+			return
+		case *ast.IfStatement:
+			f.write("} else ")
+			f.formatIf(v.Condition, v.ThenBlock, v.ElseBlock)
+			return
+		}
+	}
 	if len(elseBlock) > 0 {
 		f.writeln("} else {")
 		f.indent++
@@ -735,6 +950,43 @@ func (f *Formatter) formatWhile(condition ast.Expr, body []ast.Statement) {
 	f.writeln("}")
 }
 
+func (f *Formatter) formatBackground(body []ast.Statement) {
+	if f.mode == Expanded {
+		f.writeln("background {")
+	} else {
+		f.writeln("defer! {")
+	}
+	f.indent++
+	for _, s := range body {
+		f.formatStatement(s)
+	}
+	f.indent--
+	f.writeIndent()
+	f.writeln("}")
+}
+
+func (f *Formatter) formatTry(body []ast.Statement, errorVar string, catchBlock []ast.Statement) {
+	f.writeln("try {")
+	f.indent++
+	for _, s := range body {
+		f.formatStatement(s)
+	}
+	f.indent--
+	f.writeIndent()
+	if errorVar != "" {
+		f.writeln("} catch (" + errorVar + ") {")
+	} else {
+		f.writeln("} catch {")
+	}
+	f.indent++
+	for _, s := range catchBlock {
+		f.formatStatement(s)
+	}
+	f.indent--
+	f.writeIndent()
+	f.writeln("}")
+}
+
 func (f *Formatter) formatFor(keyVar, valueVar string, iterable ast.Expr, body []ast.Statement) {
 	f.write("for ")
 	if keyVar != "" {
@@ -824,6 +1076,13 @@ func (f *Formatter) formatFunctionCall(call ast.FunctionCallExpr) {
 		}
 		f.formatExpr(arg)
 	}
+	for i, arg := range call.NamedArgs {
+		if i > 0 || len(call.Args) > 0 {
+			f.write(", ")
+		}
+		f.write(arg.Name + ": ")
+		f.formatExpr(arg.Value)
+	}
 	f.write(")")
 }
 
@@ -846,19 +1105,15 @@ func (f *Formatter) formatExpr(expr ast.Expr) {
 
 	case ast.UnaryOpExpr:
 		f.write(v.Op.String())
-		f.formatExpr(v.Right)
+		f.formatOperand(v.Right, unaryPrecedence)
 	case *ast.UnaryOpExpr:
 		f.write(v.Op.String())
-		f.formatExpr(v.Right)
+		f.formatOperand(v.Right, unaryPrecedence)
 
 	case ast.FieldAccessExpr:
-		f.formatExpr(v.Object)
-		f.write(".")
-		f.write(v.Field)
+		f.formatFieldAccess(v)
 	case *ast.FieldAccessExpr:
-		f.formatExpr(v.Object)
-		f.write(".")
-		f.write(v.Field)
+		f.formatFieldAccess(*v)
 
 	case ast.ArrayIndexExpr:
 		f.formatExpr(v.Array)
@@ -907,15 +1162,121 @@ func (f *Formatter) formatExpr(expr ast.Expr) {
 	case *ast.AwaitExpr:
 		f.write("await ")
 		f.formatExpr(v.Expr)
+
+	case ast.TernaryExpr:
+		f.formatTernary(v)
+	case *ast.TernaryExpr:
+		f.formatTernary(*v)
+
+	case ast.InterpolatedStringExpr:
+		f.formatInterpolatedString(v)
+	case *ast.InterpolatedStringExpr:
+		f.formatInterpolatedString(*v)
+
+	case ast.SpreadExpr:
+		f.write("...")
+		f.formatExpr(v.Value)
+	case *ast.SpreadExpr:
+		f.write("...")
+		f.formatExpr(v.Value)
 	}
 }
 
+// formatInterpolatedString formats "text{expr}...", writing the string
+// literals of the parts as text and the other parts inside braces
+func (f *Formatter) formatInterpolatedString(v ast.InterpolatedStringExpr) {
+	f.write("\"")
+	for _, part := range v.Parts {
+		if lit, ok := part.(ast.LiteralExpr); ok {
+			if s, ok := lit.Value.(ast.StringLiteral); ok {
+				f.write(escapeString(s.Value))
+				continue
+			}
+		}
+		f.write("{")
+		f.formatExpr(part)
+		f.write("}")
+	}
+	f.write("\"")
+}
+
+// unaryPrecedence binds tighter than any binary operator
+const unaryPrecedence = 30
+
+// ternaryPrecedence binds more loosely than any binary operator
+const ternaryPrecedence = 0
+
+// binaryPrecedence mirrors the parser's precedence climbing table
+func binaryPrecedence(op ast.BinOp) int {
+	switch op {
+	case ast.Mul, ast.Div, ast.Mod:
+		return 20
+	case ast.Add, ast.Sub:
+		return 10
+	case ast.Eq, ast.Ne, ast.Lt, ast.Le, ast.Gt, ast.Ge:
+		return 5
+	case ast.And:
+		return 3
+	case ast.Coalesce:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (f *Formatter) formatFieldAccess(v ast.FieldAccessExpr) {
+	f.formatExpr(v.Object)
+	if v.Optional {
+		f.write("?.")
+	} else {
+		f.write(".")
+	}
+	f.write(v.Field)
+}
+
+// formatTernary formats cond ? then : else. The else branch needs no
+// parentheses because conditional expressions are right-associative.
+func (f *Formatter) formatTernary(v ast.TernaryExpr) {
+	f.formatOperand(v.Condition, ternaryPrecedence+1)
+	f.write(" ? ")
+	f.formatExpr(v.Then)
+	f.write(" : ")
+	f.formatExpr(v.Else)
+}
+
 func (f *Formatter) formatBinaryOp(op ast.BinOp, left, right ast.Expr) {
-	f.formatExpr(left)
+	prec := binaryPrecedence(op)
+	f.formatOperand(left, prec)
 	f.write(" ")
 	f.write(op.String())
 	f.write(" ")
-	f.formatExpr(right)
+	// Binary operators are left-associative, so a right operand of equal
+	// precedence needs parentheses to keep its grouping
+	f.formatOperand(right, prec+1)
+}
+
+// formatOperand formats expr, wrapping it in parentheses when it is a binary
+// or conditional expression that binds more loosely than minPrecedence
+func (f *Formatter) formatOperand(expr ast.Expr, minPrecedence int) {
+	var prec int
+	switch v := expr.(type) {
+	case ast.BinaryOpExpr:
+		prec = binaryPrecedence(v.Op)
+	case *ast.BinaryOpExpr:
+		prec = binaryPrecedence(v.Op)
+	case ast.TernaryExpr, *ast.TernaryExpr:
+		prec = ternaryPrecedence
+	default:
+		f.formatExpr(expr)
+		return
+	}
+	if prec >= minPrecedence {
+		f.formatExpr(expr)
+		return
+	}
+	f.write("(")
+	f.formatExpr(expr)
+	f.write(")")
 }
 
 func (f *Formatter) formatObject(fields []ast.ObjectField) {
@@ -929,9 +1290,7 @@ func (f *Formatter) formatObject(fields []ast.ObjectField) {
 			if i > 0 {
 				f.write(", ")
 			}
-			f.write(field.Key)
-			f.write(": ")
-			f.formatExpr(field.Value)
+			f.formatObjectField(field)
 		}
 		f.write("}")
 	} else {
@@ -939,9 +1298,7 @@ func (f *Formatter) formatObject(fields []ast.ObjectField) {
 		f.indent++
 		for i, field := range fields {
 			f.writeIndent()
-			f.write(field.Key)
-			f.write(": ")
-			f.formatExpr(field.Value)
+			f.formatObjectField(field)
 			if i < len(fields)-1 {
 				f.write(",")
 			}
@@ -953,6 +1310,15 @@ func (f *Formatter) formatObject(fields []ast.ObjectField) {
 	}
 }
 
+// formatObjectField formats key: value, or ...value for a spread field
+func (f *Formatter) formatObjectField(field ast.ObjectField) {
+	if field.Key != "" {
+		f.write(field.Key)
+		f.write(": ")
+	}
+	f.formatExpr(field.Value)
+}
+
 func (f *Formatter) formatArray(elements []ast.Expr) {
 	if len(elements) == 0 {
 		f.write("[]")
@@ -1032,7 +1398,12 @@ func (f *Formatter) formatLiteral(lit ast.Literal) {
 	case ast.IntLiteral:
 		f.write(fmt.Sprintf("%d", v.Value))
 	case ast.FloatLiteral:
-		f.write(fmt.Sprintf("%g", v.Value))
+		// Keep a decimal point so the value reads back as a float
+		text := strconv.FormatFloat(v.Value, 'g', -1, 64)
+		if !strings.ContainsAny(text, ".eInfNa") {
+			text += ".0"
+		}
+		f.write(text)
 	case ast.StringLiteral:
 		f.write("\"")
 		f.write(escapeString(v.Value))
@@ -1112,6 +1483,9 @@ func (f *Formatter) formatPattern(p ast.Pattern) {
 		f.write(v.Name)
 	case ast.WildcardPattern:
 		f.write("_")
+	case ast.TypePattern:
+		f.write(v.TypeName + " ")
+		f.formatPattern(ast.ObjectPattern{Fields: v.Fields})
 	case ast.ObjectPattern:
 		f.write("{")
 		for i, field := range v.Fields {
@@ -1166,5 +1540,13 @@ func escapeString(s string) string {
 	s = strings.ReplaceAll(s, "\"", "\\\"")
 	s = strings.ReplaceAll(s, "\n", "\\n")
 	s = strings.ReplaceAll(s, "\t", "\\t")
-	return s
+	// Escape each { that would start an interpolation
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '{' && !parser.IsLiteralBrace(s, i) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
 }
@@ -2,6 +2,7 @@ package formatter
 
 import (
 	"github.com/glyphlang/glyph/pkg/ast"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -54,6 +55,62 @@ func TestFormatRoute(t *testing.T) {
 	}
 }
 
+func TestFormatConstStatement(t *testing.T) {
+	route := &ast.Route{
+		Method: ast.Get,
+		Path:   "/area",
+		Body: []ast.Statement{
+			ast.AssignStatement{
+				Target: "PI",
+				Value:  ast.LiteralExpr{Value: ast.FloatLiteral{Value: 3.14}},
+				Const:  true,
+			},
+			ast.AssignStatement{
+				Target: "SIDES",
+				Value:  ast.LiteralExpr{Value: ast.IntLiteral{Value: 4}},
+				Const:  true,
+				Type:   ast.IntType{},
+			},
+		},
+	}
+	module := &ast.Module{Items: []ast.Item{route}}
+
+	for _, mode := range []Mode{Compact, Expanded} {
+		output := New(mode).Format(module)
+		if !strings.Contains(output, "const PI = 3.14") {
+			t.Errorf("Output should contain 'const PI = 3.14', got: %s", output)
+		}
+		if !strings.Contains(output, "const SIDES: int = 4") {
+			t.Errorf("Output should contain 'const SIDES: int = 4', got: %s", output)
+		}
+	}
+}
+
+func TestFormatIndexAssignStatement(t *testing.T) {
+	route := &ast.Route{
+		Method: ast.Put,
+		Path:   "/users",
+		Body: []ast.Statement{
+			ast.IndexAssignStatement{
+				Target: ast.FieldAccessExpr{
+					Object: ast.ArrayIndexExpr{
+						Array: ast.VariableExpr{Name: "users"},
+						Index: ast.LiteralExpr{Value: ast.IntLiteral{Value: 0}},
+					},
+					Field: "name",
+				},
+				Value: ast.LiteralExpr{Value: ast.StringLiteral{Value: "ada"}},
+			},
+		},
+	}
+	module := &ast.Module{Items: []ast.Item{route}}
+
+	output := New(Compact).Format(module)
+	if !strings.Contains(output, `users[0].name = "ada"`) {
+		t.Errorf("Output should contain 'users[0].name = \"ada\"', got: %s", output)
+	}
+}
+
 func TestFormatTypeDef(t *testing.T) {
 	typeDef := &ast.TypeDef{
 		Name: "User",
@@ -88,6 +145,62 @@ func TestFormatTypeDef(t *testing.T) {
 	}
 }
 
+func TestFormatTypeDefAnnotationsAndDefaults(t *testing.T) {
+	source := `: Signup {
+  name: str! @minLen(2) @maxLen(50)
+  role: str @oneOf(["admin", "user"]) = "user"
+  score: float @range(0.0, 1.5)
+  active: bool = true
+}
+`
+	module, err := parseForFormat(source, Compact)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	formatted := New(Compact).Format(module)
+	for _, want := range []string{
+		`name: str! @minLen(2) @maxLen(50)`,
+		`role: str @oneOf(["admin", "user"]) = "user"`,
+		`score: float @range(0.0, 1.5)`,
+		`active: bool = true`,
+	} {
+		if !strings.Contains(formatted, want) {
+			t.Errorf("output should contain %q, got: %s", want, formatted)
+		}
+	}
+
+	reparsed, err := parseForFormat(formatted, Compact)
+	if err != nil {
+		t.Fatalf("formatted output does not parse: %v\n%s", err, formatted)
+	}
+	before := module.Items[0].(*ast.TypeDef).Fields
+	after := reparsed.Items[0].(*ast.TypeDef).Fields
+	for i := range before {
+		if !reflect.DeepEqual(before[i].Annotations, after[i].Annotations) {
+			t.Errorf("field %s: annotations %v became %v", before[i].Name, before[i].Annotations, after[i].Annotations)
+		}
+		if (before[i].Default == nil) != (after[i].Default == nil) {
+			t.Errorf("field %s: default lost in round trip", before[i].Name)
+		}
+	}
+}
+
+func TestFormatEnumDef(t *testing.T) {
+	module := &ast.Module{
+		Items: []ast.Item{&ast.EnumDef{Name: "Status", Values: []string{"pending", "shipped"}}},
+	}
+
+	compact := New(Compact).Format(module)
+	if want := `: Status = "pending" | "shipped"`; !strings.Contains(compact, want) {
+		t.Errorf("Compact output should contain '%s', got: %s", want, compact)
+	}
+
+	expanded := New(Expanded).Format(module)
+	if want := `type Status = "pending" | "shipped"`; !strings.Contains(expanded, want) {
+		t.Errorf("Expanded output should contain '%s', got: %s", want, expanded)
+	}
+}
+
 func TestFormatCommand(t *testing.T) {
 	cmd := &ast.Command{
 		Name: "hello",
@@ -282,6 +395,40 @@ func TestFormatIfStatement(t *testing.T) {
 	}
 }
 
+func TestFormatBackgroundStatement(t *testing.T) {
+	route := &ast.Route{
+		Method: ast.Post,
+		Path:   "/signup",
+		Body: []ast.Statement{
+			ast.BackgroundStatement{
+				Body: []ast.Statement{
+					ast.AssignStatement{
+						Target: "sent",
+						Value: ast.FunctionCallExpr{
+							Name: "send_welcome_email",
+							Args: []ast.Expr{ast.VariableExpr{Name: "email"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	module := &ast.Module{
+		Items: []ast.Item{route},
+	}
+
+	compact := New(Compact).Format(module)
+	if !strings.Contains(compact, "  defer! {\n    $ sent = send_welcome_email(email)\n  }\n") {
+		t.Errorf("Compact output should contain a defer! block, got: %s", compact)
+	}
+
+	expanded := New(Expanded).Format(module)
+	if !strings.Contains(expanded, "  background {\n    let sent = send_welcome_email(email)\n  }\n") {
+		t.Errorf("Expanded output should contain a background block, got: %s", expanded)
+	}
+}
+
 func TestFormatValidationStatement(t *testing.T) {
 	route := &ast.Route{
 		Method: ast.Post,
@@ -319,6 +466,33 @@ func TestFormatValidationStatement(t *testing.T) {
 	}
 }
 
+func TestFormatNamedArguments(t *testing.T) {
+	route := &ast.Route{
+		Method: ast.Get,
+		Path:   "/greet",
+		Body: []ast.Statement{
+			ast.ReturnStatement{
+				Value: ast.FunctionCallExpr{
+					Name: "greet",
+					Args: []ast.Expr{
+						ast.LiteralExpr{Value: ast.StringLiteral{Value: "Bob"}},
+					},
+					NamedArgs: []ast.NamedArg{
+						{Name: "greeting", Value: ast.LiteralExpr{Value: ast.StringLiteral{Value: "Hi"}}},
+						{Name: "suffix", Value: ast.VariableExpr{Name: "mark"}},
+					},
+				},
+			},
+		},
+	}
+
+	compact := New(Compact).Format(&ast.Module{Items: []ast.Item{route}})
+
+	if !strings.Contains(compact, `> greet("Bob", greeting: "Hi", suffix: mark)`) {
+		t.Errorf("Compact output should pass named arguments, got: %s", compact)
+	}
+}
+
 func TestFormatMiddlewareAndInjection(t *testing.T) {
 	route := &ast.Route{
 		Method: ast.Get,
@@ -374,6 +548,27 @@ func TestFormatMiddlewareAndInjection(t *testing.T) {
 	}
 }
 
+func TestFormatRouteInputType(t *testing.T) {
+	route := &ast.Route{
+		Method:    ast.Post,
+		Path:      "/api/users",
+		InputType: ast.NamedType{Name: "CreateUser"},
+		Body: []ast.Statement{
+			ast.ReturnStatement{Value: ast.VariableExpr{Name: "input"}},
+		},
+	}
+	module := &ast.Module{Items: []ast.Item{route}}
+
+	compact := New(Compact).Format(module)
+	if !strings.Contains(compact, "< input: CreateUser") {
+		t.Errorf("Compact output should contain '< input: CreateUser', got: %s", compact)
+	}
+	expanded := New(Expanded).Format(module)
+	if !strings.Contains(expanded, "expects input: CreateUser") {
+		t.Errorf("Expanded output should contain 'expects input: CreateUser', got: %s", expanded)
+	}
+}
+
 func TestEscapeString(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -624,6 +819,30 @@ func TestFormatWhile(t *testing.T) {
 	}
 }
 
+func TestFormatTry(t *testing.T) {
+	divide := ast.ReturnStatement{Value: ast.BinaryOpExpr{
+		Op: ast.Div, Left: ast.LiteralExpr{Value: ast.IntLiteral{Value: 1}}, Right: ast.LiteralExpr{Value: ast.IntLiteral{Value: 0}},
+	}}
+	fallback := ast.ReturnStatement{Value: ast.LiteralExpr{Value: ast.IntLiteral{Value: 0}}}
+
+	result := formatRouteBody(Compact, ast.TryStatement{
+		Body:     []ast.Statement{divide},
+		ErrorVar: "e",
+		Catch:    []ast.Statement{fallback},
+	})
+	if !strings.Contains(result, "  try {\n    > 1 / 0\n  } catch (e) {\n    > 0\n  }\n") {
+		t.Errorf("Try statement should format correctly, got: %s", result)
+	}
+
+	result = formatRouteBody(Compact, &ast.TryStatement{
+		Body:  []ast.Statement{divide},
+		Catch: []ast.Statement{fallback},
+	})
+	if !strings.Contains(result, "  } catch {\n") {
+		t.Errorf("Catch without a variable should format correctly, got: %s", result)
+	}
+}
+
 func TestFormatWhile_Pointer(t *testing.T) {
 	result := formatRouteBody(Compact,
 		&ast.WhileStatement{
@@ -792,6 +1011,100 @@ func TestFormatExpr_UnaryOp_Pointer(t *testing.T) {
 	}
 }
 
+func TestFormatExpr_Parentheses(t *testing.T) {
+	a, b, c := ast.VariableExpr{Name: "a"}, ast.VariableExpr{Name: "b"}, ast.VariableExpr{Name: "c"}
+	result := formatRouteBody(Compact,
+		ast.AssignStatement{Target: "x", Value: ast.BinaryOpExpr{Op: ast.Mul, Left: ast.BinaryOpExpr{Op: ast.Add, Left: a, Right: b}, Right: c}},
+		ast.AssignStatement{Target: "y", Value: ast.BinaryOpExpr{Op: ast.Sub, Left: a, Right: &ast.BinaryOpExpr{Op: ast.Sub, Left: b, Right: c}}},
+		ast.AssignStatement{Target: "z", Value: ast.BinaryOpExpr{Op: ast.Add, Left: ast.BinaryOpExpr{Op: ast.Mul, Left: a, Right: b}, Right: c}},
+		ast.AssignStatement{Target: "n", Value: ast.UnaryOpExpr{Op: ast.Not, Right: ast.BinaryOpExpr{Op: ast.And, Left: a, Right: b}}},
+	)
+	for _, want := range []string{"$ x = (a + b) * c", "$ y = a - (b - c)", "$ z = a * b + c", "$ n = !(a && b)"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q, got: %s", want, result)
+		}
+	}
+}
+
+func TestFormatExpr_Ternary(t *testing.T) {
+	a, b, c := ast.VariableExpr{Name: "a"}, ast.VariableExpr{Name: "b"}, ast.VariableExpr{Name: "c"}
+	one, two := ast.LiteralExpr{Value: ast.IntLiteral{Value: 1}}, ast.LiteralExpr{Value: ast.IntLiteral{Value: 2}}
+	result := formatRouteBody(Compact,
+		ast.AssignStatement{Target: "x", Value: ast.TernaryExpr{Condition: ast.BinaryOpExpr{Op: ast.Or, Left: a, Right: b}, Then: one, Else: two}},
+		ast.AssignStatement{Target: "y", Value: ast.TernaryExpr{Condition: a, Then: one, Else: &ast.TernaryExpr{Condition: b, Then: two, Else: c}}},
+		ast.AssignStatement{Target: "z", Value: ast.TernaryExpr{Condition: ast.TernaryExpr{Condition: a, Then: b, Else: c}, Then: one, Else: two}},
+		ast.AssignStatement{Target: "n", Value: ast.BinaryOpExpr{Op: ast.Add, Left: ast.TernaryExpr{Condition: a, Then: one, Else: two}, Right: one}},
+	)
+	for _, want := range []string{"$ x = a || b ? 1 : 2", "$ y = a ? 1 : b ? 2 : c", "$ z = (a ? b : c) ? 1 : 2", "$ n = (a ? 1 : 2) + 1"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q, got: %s", want, result)
+		}
+	}
+}
+
+func TestFormatExpr_InterpolatedString(t *testing.T) {
+	result := formatRouteBody(Compact,
+		ast.AssignStatement{Target: "x", Value: ast.InterpolatedStringExpr{Parts: []ast.Expr{
+			ast.LiteralExpr{Value: ast.StringLiteral{Value: "Hi {"}},
+			ast.FieldAccessExpr{Object: ast.VariableExpr{Name: "user"}, Field: "name"},
+			ast.LiteralExpr{Value: ast.StringLiteral{Value: "} ${p}"}},
+		}}},
+		ast.AssignStatement{Target: "y", Value: ast.LiteralExpr{Value: ast.StringLiteral{Value: "{json}"}}},
+	)
+	for _, want := range []string{`$ x = "Hi \{{user.name}} ${p}"`, `$ y = "\{json}"`} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q, got: %s", want, result)
+		}
+	}
+}
+
+func TestFormatExpr_Spread(t *testing.T) {
+	result := formatRouteBody(Compact,
+		ast.AssignStatement{Target: "x", Value: ast.ObjectExpr{Fields: []ast.ObjectField{
+			{Value: ast.SpreadExpr{Value: ast.VariableExpr{Name: "base"}}},
+			{Key: "tags", Value: ast.ArrayExpr{Elements: []ast.Expr{
+				ast.SpreadExpr{Value: ast.VariableExpr{Name: "tags"}},
+				ast.LiteralExpr{Value: ast.StringLiteral{Value: "new"}},
+			}}},
+		}}},
+	)
+	want := `$ x = {...base, tags: [...tags, "new"]}`
+	if !strings.Contains(result, want) {
+		t.Errorf("Expected %q, got: %s", want, result)
+	}
+}
+
+func TestFormatLoopControlAndFloats(t *testing.T) {
+	result := formatRouteBody(Compact,
+		ast.WhileStatement{
+			Condition: ast.LiteralExpr{Value: ast.BoolLiteral{Value: true}},
+			Body:      []ast.Statement{ast.ContinueStatement{}, &ast.BreakStatement{}},
+		},
+		ast.ReturnStatement{Value: ast.LiteralExpr{Value: ast.FloatLiteral{Value: 2}}},
+	)
+	for _, want := range []string{"    continue\n", "    break\n", "> 2.0\n"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q, got: %s", want, result)
+		}
+	}
+}
+
+func TestFormatElseIf(t *testing.T) {
+	result := formatRouteBody(Compact,
+		ast.IfStatement{
+			Condition: ast.VariableExpr{Name: "a"},
+			ThenBlock: []ast.Statement{ast.ReturnStatement{Value: ast.LiteralExpr{Value: ast.IntLiteral{Value: 1}}}},
+			ElseBlock: []ast.Statement{ast.IfStatement{
+				Condition: ast.VariableExpr{Name: "b"},
+				ThenBlock: []ast.Statement{ast.ReturnStatement{Value: ast.LiteralExpr{Value: ast.IntLiteral{Value: 2}}}},
+			}},
+		},
+	)
+	if !strings.Contains(result, "} else if b {") {
+		t.Errorf("Expected an else-if chain, got: %s", result)
+	}
+}
+
 func TestFormatExpr_FieldAccess(t *testing.T) {
 	result := formatRouteBody(Expanded,
 		ast.AssignStatement{Target: "name", Value: ast.FieldAccessExpr{Object: ast.VariableExpr{Name: "user"}, Field: "name"}},
@@ -1112,6 +1425,23 @@ func TestFormatPattern_ObjectPattern(t *testing.T) {
 	}
 }
 
+func TestFormatPattern_TypePattern(t *testing.T) {
+	result := formatRouteBody(Expanded,
+		ast.AssignStatement{Target: "result", Value: ast.MatchExpr{
+			Value: ast.VariableExpr{Name: "outcome"},
+			Cases: []ast.MatchCase{{
+				Pattern: ast.TypePattern{TypeName: "Error", Fields: []ast.ObjectPatternField{
+					{Key: "message"},
+				}},
+				Body: ast.VariableExpr{Name: "message"},
+			}},
+		}},
+	)
+	if !strings.Contains(result, "Error {message} =>") {
+		t.Errorf("Type pattern should format correctly, got: %s", result)
+	}
+}
+
 func TestFormatPattern_ArrayPatternWithRest(t *testing.T) {
 	result := formatRouteBody(Expanded,
 		ast.AssignStatement{Target: "result", Value: ast.MatchExpr{
@@ -1502,10 +1832,10 @@ func TestFormatRoute_QueryParamsAndReturnType(t *testing.T) {
 		},
 	}
 	result := formatViaModule(Expanded, route)
-	if !strings.Contains(result, "?page: int!") {
+	if !strings.Contains(result, "  ? page: int!\n") {
 		t.Errorf("Required query param should have !, got: %s", result)
 	}
-	if !strings.Contains(result, "?limit: int = 20") {
+	if !strings.Contains(result, "  ? limit: int = 20\n") {
 		t.Errorf("Query param with default should format correctly, got: %s", result)
 	}
 	if !strings.Contains(result, "-> UserList") {
@@ -1620,3 +1950,81 @@ func TestFormatQueueWorker_WithInjections(t *testing.T) {
 		t.Errorf("Expanded queue worker injections should use 'use', got: %s", expanded)
 	}
 }
+
+func TestFormatSeedBlock(t *testing.T) {
+	module := &ast.Module{Items: []ast.Item{
+		&ast.SeedBlock{
+			Name: "users",
+			Body: []ast.Statement{
+				ast.AssignStatement{Target: "n", Value: ast.LiteralExpr{Value: ast.IntLiteral{Value: 1}}},
+			},
+		},
+		&ast.SeedBlock{},
+	}}
+
+	compact := New(Compact).Format(module)
+	if !strings.Contains(compact, "seed! users {\n  $ n = 1\n}\n") {
+		t.Errorf("Compact output should contain a named seed! block, got: %s", compact)
+	}
+	if !strings.Contains(compact, "seed! {\n}\n") {
+		t.Errorf("Compact output should contain an anonymous seed! block, got: %s", compact)
+	}
+
+	expanded := New(Expanded).Format(module)
+	if !strings.Contains(expanded, "seed users {\n  let n = 1\n}\n") {
+		t.Errorf("Expanded output should contain 'seed users', got: %s", expanded)
+	}
+}
+
+func TestFormatErrorHandlers(t *testing.T) {
+	module := &ast.Module{Items: []ast.Item{
+		&ast.ErrorHandler{
+			Kind: ast.OnErrorHandler,
+			Body: []ast.Statement{
+				ast.AssignStatement{Target: "masked", Value: ast.LiteralExpr{Value: ast.BoolLiteral{Value: true}}},
+			},
+		},
+	}}
+
+	compact := New(Compact).Format(module)
+	if !strings.Contains(compact, "@ onerror {\n  $ masked = true\n}\n") {
+		t.Errorf("Compact output should contain the onerror handler, got: %s", compact)
+	}
+
+	expanded := New(Expanded).Format(module)
+	if !strings.Contains(expanded, "route onerror {\n  let masked = true\n}\n") {
+		t.Errorf("Expanded output should contain the onerror handler, got: %s", expanded)
+	}
+}
+
+func TestFormatMiddleware(t *testing.T) {
+	module := &ast.Module{Items: []ast.Item{
+		&ast.MiddlewareDef{
+			Name: "requireAdmin",
+			Body: []ast.Statement{
+				ast.AssignStatement{Target: "admin", Value: ast.LiteralExpr{Value: ast.BoolLiteral{Value: true}}},
+			},
+		},
+		&ast.Route{
+			Path:        "/admin",
+			Method:      ast.Get,
+			Middlewares: []ast.MiddlewareRef{{Name: "requireAdmin"}, {Name: "audit"}},
+		},
+	}}
+
+	compact := New(Compact).Format(module)
+	if !strings.Contains(compact, "@ middleware requireAdmin {\n  $ admin = true\n}\n") {
+		t.Errorf("Compact output should contain the middleware definition, got: %s", compact)
+	}
+	if !strings.Contains(compact, "@ GET /admin {\n  + requireAdmin\n  + audit\n}\n") {
+		t.Errorf("Compact output should apply the middleware in order, got: %s", compact)
+	}
+
+	expanded := New(Expanded).Format(module)
+	if !strings.Contains(expanded, "middleware requireAdmin {\n  let admin = true\n}\n") {
+		t.Errorf("Expanded output should contain 'middleware requireAdmin', got: %s", expanded)
+	}
+	if !strings.Contains(expanded, "  middleware requireAdmin\n  middleware audit\n") {
+		t.Errorf("Expanded output should apply the middleware in order, got: %s", expanded)
+	}
+}
@@ -2,6 +2,7 @@ package httpclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,38 +10,71 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/glyphlang/glyph/pkg/jsonvalue"
+	"github.com/glyphlang/glyph/pkg/tracing"
 )
 
-// maxResponseSize is the maximum response body size (50 MB).
-const maxResponseSize = 50 << 20
+// DefaultMaxResponseSize is the default maximum response body size (50 MB).
+const DefaultMaxResponseSize = 50 << 20
+
+// DefaultTimeout is the default request timeout.
+const DefaultTimeout = 30 * time.Second
+
+// Config configures a Handler. Zero fields use the defaults.
+type Config struct {
+	Timeout         time.Duration
+	MaxResponseSize int64
+}
 
 // Handler provides HTTP client capabilities for GlyphLang applications.
 // Methods are called via reflection from the interpreter (see database.go allowedMethods).
 type Handler struct {
-	client *http.Client
+	client          *http.Client
+	maxResponseSize int64
+	ctx             context.Context
 }
 
 // NewHandler creates a new HTTP client handler with default settings.
 // Redirects are followed by default (Go's http.Client default behavior).
 func NewHandler() *Handler {
-	return &Handler{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-	}
+	return NewHandlerWithConfig(Config{})
 }
 
 // NewHandlerWithTimeout creates a handler with a custom timeout.
 func NewHandlerWithTimeout(timeout time.Duration) *Handler {
+	return NewHandlerWithConfig(Config{Timeout: timeout})
+}
+
+// NewHandlerWithConfig creates a handler with a custom timeout and maximum
+// response size.
+func NewHandlerWithConfig(cfg Config) *Handler {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxResponseSize <= 0 {
+		cfg.MaxResponseSize = DefaultMaxResponseSize
+	}
 	return &Handler{
 		client: &http.Client{
-			Timeout: timeout,
+			Timeout: cfg.Timeout,
 		},
+		maxResponseSize: cfg.MaxResponseSize,
 	}
 }
 
+// WithContext returns a handler sharing h's client whose requests are made
+// with ctx. Each request is traced as a client span under ctx's span, and
+// the trace is passed on in the request headers.
+func (h *Handler) WithContext(ctx context.Context) *Handler {
+	bound := *h
+	bound.ctx = ctx
+	return &bound
+}
+
 // Get performs an HTTP GET request.
-// Called from GlyphLang code: http.get("https://api.example.com/data")
+// Called from GlyphLang code: http.get("https://api.example.com/data"),
+// http.get("https://api.example.com/data", {"Authorization": "Bearer token"})
 // or http.get("https://api.example.com/data", {headers: {"Authorization": "Bearer token"}})
 func (h *Handler) Get(args interface{}) (map[string]interface{}, error) {
 	reqURL, opts, err := parseRequestArgs(args)
@@ -51,7 +85,8 @@ func (h *Handler) Get(args interface{}) (map[string]interface{}, error) {
 }
 
 // Post performs an HTTP POST request.
-// Called from GlyphLang code: http.post("https://api.example.com/submit", {body: payload, headers: {…}})
+// Called from GlyphLang code: http.post("https://api.example.com/submit", payload, headers)
+// or http.post("https://api.example.com/submit", {body: payload, headers: {…}})
 func (h *Handler) Post(args interface{}) (map[string]interface{}, error) {
 	reqURL, opts, err := parseRequestArgs(args)
 	if err != nil {
@@ -201,7 +236,11 @@ func (h *Handler) doRequest(method, reqURL string, opts *requestOptions) (map[st
 		}
 	}
 
-	req, err := http.NewRequest(method, reqURL, bodyReader)
+	ctx := h.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -236,16 +275,24 @@ func (h *Handler) doRequest(method, reqURL string, opts *requestOptions) (map[st
 		client = &clientCopy
 	}
 
-	resp, err := client.Do(req)
+	ctx, span := tracing.TraceOutgoingRequest(ctx, req, "HTTP "+method)
+	defer span.End()
+	resp, err := client.Do(req.WithContext(ctx))
+	tracing.RecordOutgoingResponse(ctx, resp, err)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	// Read one byte past the limit so an oversized body is an error rather
+	// than silently truncated
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, h.maxResponseSize+1))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
+	if int64(len(respBody)) > h.maxResponseSize {
+		return nil, fmt.Errorf("response body exceeds maximum size of %d bytes", h.maxResponseSize)
+	}
 
 	// Build response headers map
 	respHeaders := make(map[string]interface{}, len(resp.Header))
@@ -264,9 +311,30 @@ func (h *Handler) doRequest(method, reqURL string, opts *requestOptions) (map[st
 	result := map[string]interface{}{
 		"status":  int64(resp.StatusCode),
 		"headers": respHeaders,
-		"body":    string(respBody),
+		"body":    decodeBody(resp.Header.Get("Content-Type"), respBody),
 		"ok":      resp.StatusCode >= 200 && resp.StatusCode < 300,
 	}
 
 	return result, nil
 }
+
+// decodeBody returns a JSON response body as parsed objects, arrays and
+// numbers, and any other body (or JSON that fails to parse) as a string.
+func decodeBody(contentType string, body []byte) interface{} {
+	if len(body) == 0 || !isJSONContentType(contentType) {
+		return string(body)
+	}
+
+	decoded, err := jsonvalue.Decode(body)
+	if err != nil {
+		return string(body)
+	}
+	return decoded
+}
+
+// isJSONContentType reports whether a Content-Type header names JSON,
+// including vendor types such as application/problem+json.
+func isJSONContentType(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
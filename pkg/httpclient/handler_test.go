@@ -336,3 +336,53 @@ func TestFloatTimeout(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 1*time.Second, opts.Timeout)
 }
+
+func TestJSONResponseParsed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		_, err := w.Write([]byte(`{"count": 2, "ratio": 0.5, "tags": ["a"]}`))
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	h := NewHandler()
+	result, err := h.Get(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"count": int64(2),
+		"ratio": 0.5,
+		"tags":  []interface{}{"a"},
+	}, result["body"])
+}
+
+func TestInvalidJSONResponseKeptAsString(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{oops`))
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	h := NewHandler()
+	result, err := h.Get(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "{oops", result["body"])
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(strings.Repeat("x", 11)))
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	h := NewHandlerWithConfig(Config{MaxResponseSize: 10})
+	_, err := h.Get(server.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds maximum size of 10 bytes")
+
+	h = NewHandlerWithConfig(Config{MaxResponseSize: 11})
+	result, err := h.Get(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("x", 11), result["body"])
+}
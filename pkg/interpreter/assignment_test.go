@@ -0,0 +1,100 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldAndIndexAssignment(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   interface{}
+	}{
+		{"object field", `@ PUT /t {
+  $ user = {name: "ada", role: "dev"}
+  user.name = "grace"
+  user.active = true
+  > user
+}`, map[string]interface{}{"name": "grace", "role": "dev", "active": true}},
+		{"array element", `@ PUT /t {
+  $ items = [1, 2, 3]
+  items[0] = 10
+  items[-1] = 30
+  > items
+}`, []interface{}{int64(10), int64(2), int64(30)}},
+		{"field of an element", `@ PUT /t {
+  $ users = [{name: "ada"}, {name: "bob"}]
+  users[1].name = "grace"
+  > users
+}`, []interface{}{map[string]interface{}{"name": "ada"}, map[string]interface{}{"name": "grace"}}},
+		{"element of a nested field", `@ PUT /t {
+  $ user = {profile: {tags: ["a", "b"]}}
+  user.profile.tags[-1] = "admin"
+  > user
+}`, map[string]interface{}{"profile": map[string]interface{}{"tags": []interface{}{"a", "admin"}}}},
+		{"keyword field name", `@ PUT /t {
+  $ event = {type: "created"}
+  event.type = "updated"
+  > event
+}`, map[string]interface{}{"type": "updated"}},
+		{"references share the mutation", `@ PUT /t {
+  $ users = [{name: "ada"}]
+  $ first = users[0]
+  first.name = "grace"
+  > users
+}`, []interface{}{map[string]interface{}{"name": "grace"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runRouteSource(t, tt.source)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
+
+func TestFieldAndIndexAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"field of null", `@ PUT /t {
+  $ user = {profile: null}
+  user.profile.bio = "hi"
+  > user
+}`, "cannot assign field 'bio' on null"},
+		{"field of a non-object", `@ PUT /t {
+  $ count = 3
+  count.value = 1
+  > count
+}`, "cannot assign field 'value' on int64"},
+		{"index past the end", `@ PUT /t {
+  $ items = [1, 2]
+  items[2] = 3
+  > items
+}`, "array index out of bounds: 2 (length: 2)"},
+		{"index of a non-collection", `@ PUT /t {
+  $ name = "ada"
+  name[0] = "A"
+  > name
+}`, "cannot index-assign to string"},
+		{"index of null", `@ PUT /t {
+  $ user = {tags: null}
+  user.tags[0] = "a"
+  > user
+}`, "cannot index-assign to null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runRouteSource(t, tt.source)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
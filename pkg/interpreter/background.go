@@ -0,0 +1,112 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"log"
+	"sync"
+	"time"
+)
+
+// BackgroundTasks runs background blocks in goroutines and lets the server
+// wait for them when it shuts down. The zero value is ready to use.
+type BackgroundTasks struct {
+	wg sync.WaitGroup
+}
+
+// Go runs fn in a goroutine. An error returned or a panic raised by fn is
+// logged with label, which names the route that started the task.
+func (b *BackgroundTasks) Go(label string, fn func() error) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer func() {
+			if p := recover(); p != nil {
+				log.Printf("[BACKGROUND] %s: panic: %v", label, p)
+			}
+		}()
+		if err := fn(); err != nil {
+			log.Printf("[BACKGROUND] %s: %v", label, err)
+		}
+	}()
+}
+
+// Wait waits up to timeout for the running tasks, reporting whether they
+// all finished
+func (b *BackgroundTasks) Wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// SetBackgroundTasks makes the interpreter run background blocks on tasks,
+// so several interpreters can share one set to wait for on shutdown
+func (i *Interpreter) SetBackgroundTasks(tasks *BackgroundTasks) {
+	i.background = tasks
+}
+
+// BackgroundTasks returns the tasks the interpreter runs background blocks on
+func (i *Interpreter) BackgroundTasks() *BackgroundTasks {
+	return i.background
+}
+
+// backgroundKey is the route environment variable holding its
+// pendingBackground
+const backgroundKey = "__background"
+
+// pendingBackground collects the background blocks a route runs into, to
+// start once the route has produced its result
+type pendingBackground struct {
+	label string
+	tasks []backgroundTask
+}
+
+// backgroundTask is a background block with a copy of the variables it was
+// reached with
+type backgroundTask struct {
+	body []Statement
+	env  *Environment
+}
+
+// executeBackground queues a background block to run after the route's
+// response is produced. Outside a route, the block starts at once.
+func (i *Interpreter) executeBackground(stmt BackgroundStatement, env *Environment) (interface{}, error) {
+	task := backgroundTask{body: stmt.Body, env: env.snapshot(i.globalEnv, backgroundKey, cookiesKey, sessionKey)}
+
+	if val, err := env.Get(backgroundKey); err == nil {
+		if pending, ok := val.(*pendingBackground); ok {
+			pending.tasks = append(pending.tasks, task)
+			return nil, nil
+		}
+	}
+
+	i.runBackground("background", task)
+	return nil, nil
+}
+
+// startBackground starts the background blocks a route ran into
+func (i *Interpreter) startBackground(pending *pendingBackground) {
+	for _, task := range pending.tasks {
+		i.runBackground(pending.label, task)
+	}
+}
+
+func (i *Interpreter) runBackground(label string, task backgroundTask) {
+	i.background.Go(label, func() error {
+		_, err := i.executeStatements(task.body, task.env)
+		if _, isReturn := unwrapReturn(err); isReturn {
+			return nil
+		}
+		return err
+	})
+}
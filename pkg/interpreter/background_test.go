@@ -0,0 +1,170 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withBackgroundHook registers a backgroundHook(value) builtin that calls
+// hook, so tests can observe and hold up background blocks
+func withBackgroundHook(t *testing.T, hook func(value interface{}) error) {
+	t.Helper()
+	builtinFuncs["backgroundHook"] = func(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+		value, err := i.EvaluateExpression(args[0], env)
+		if err != nil {
+			return nil, err
+		}
+		return nil, hook(value)
+	}
+	t.Cleanup(func() { delete(builtinFuncs, "backgroundHook") })
+}
+
+// executeRouteSource loads source and executes its first route
+func executeRouteSource(t *testing.T, interp *Interpreter, source string) (*Response, error) {
+	t.Helper()
+	module, err := parseLoaderSource(source)
+	require.NoError(t, err)
+	require.NoError(t, interp.LoadModule(*module))
+	route := module.Items[0].(*Route)
+	return interp.ExecuteRoute(route, &Request{Path: route.Path, Method: route.Method.String()})
+}
+
+func TestBackground_RunsAfterResponse(t *testing.T) {
+	release := make(chan struct{})
+	done := make(chan interface{}, 1)
+	withBackgroundHook(t, func(value interface{}) error {
+		<-release
+		done <- value
+		return nil
+	})
+	interp := NewInterpreter()
+
+	response, err := executeRouteSource(t, interp, `@ POST /signup {
+  $ email = "ada@example.com"
+  background {
+    $ r = backgroundHook(email)
+  }
+  > {created: true}
+}`)
+	require.NoError(t, err, "the route does not wait for its background block")
+	assert.Equal(t, map[string]interface{}{"created": true}, response.Body)
+	assert.Empty(t, done)
+
+	close(release)
+	assert.True(t, interp.BackgroundTasks().Wait(5*time.Second))
+	assert.Equal(t, "ada@example.com", <-done)
+}
+
+func TestBackground_CopiesVariables(t *testing.T) {
+	var mu sync.Mutex
+	var seen []interface{}
+	withBackgroundHook(t, func(value interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, value)
+		return nil
+	})
+	interp := NewInterpreter()
+
+	_, err := executeRouteSource(t, interp, `@ GET /copy {
+  $ user = {name: "ada", tags: ["new"]}
+  $ count = 1
+  defer! {
+    $ r = backgroundHook([user.name, user.tags[0], count])
+  }
+  $ user.name = "grace"
+  $ user.tags[0] = "changed"
+  count = 2
+  > count
+}`)
+	require.NoError(t, err)
+	require.True(t, interp.BackgroundTasks().Wait(5*time.Second))
+	assert.Equal(t, []interface{}{[]interface{}{"ada", "new", int64(1)}}, seen, "later changes do not reach the block")
+}
+
+func TestBackground_ErrorsAreLogged(t *testing.T) {
+	var logs syncLog
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	withBackgroundHook(t, func(value interface{}) error {
+		if value == "panic" {
+			panic("cache down")
+		}
+		return nil
+	})
+	interp := NewInterpreter()
+
+	_, err := executeRouteSource(t, interp, `@ GET /users/:id {
+  background {
+    $ r = missingFunction()
+  }
+  background {
+    $ r = backgroundHook("panic")
+  }
+  > {ok: true}
+}`)
+	require.NoError(t, err)
+	require.True(t, interp.BackgroundTasks().Wait(5*time.Second))
+	assert.Contains(t, logs.String(), "[BACKGROUND] GET /users/:id: ")
+	assert.Contains(t, logs.String(), "missingFunction")
+	assert.Contains(t, logs.String(), "panic: cache down")
+}
+
+func TestBackground_DroppedWhenRouteFails(t *testing.T) {
+	ran := make(chan interface{}, 1)
+	withBackgroundHook(t, func(value interface{}) error {
+		ran <- value
+		return nil
+	})
+	interp := NewInterpreter()
+
+	_, err := executeRouteSource(t, interp, `@ GET /fail {
+  background {
+    $ r = backgroundHook("ran")
+  }
+  $ x = missingFunction()
+  > x
+}`)
+	require.Error(t, err)
+	require.True(t, interp.BackgroundTasks().Wait(5*time.Second))
+	assert.Empty(t, ran)
+}
+
+func TestBackgroundTasks_WaitTimeout(t *testing.T) {
+	var tasks BackgroundTasks
+	release := make(chan struct{})
+	tasks.Go("test", func() error {
+		<-release
+		return nil
+	})
+	assert.False(t, tasks.Wait(10*time.Millisecond))
+	close(release)
+	assert.True(t, tasks.Wait(5*time.Second))
+}
+
+// syncLog is a log destination safe to write to from several goroutines
+type syncLog struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (l *syncLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf = append(l.buf, p...)
+	return len(p), nil
+}
+
+func (l *syncLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return string(l.buf)
+}
@@ -10,7 +10,6 @@ import (
 	"time"
 
 	. "github.com/glyphlang/glyph/pkg/ast"
-	"github.com/google/uuid"
 )
 
 // builtinFunc is the signature for all builtin function implementations.
@@ -22,52 +21,77 @@ var builtinFuncs map[string]builtinFunc
 
 func init() {
 	builtinFuncs = map[string]builtinFunc{
-		"time.now":   builtinTimeNow,
-		"now":        builtinNow,
-		"Ok":         builtinOk,
-		"Err":        builtinErr,
-		"upper":      builtinUpper,
-		"lower":      builtinLower,
-		"trim":       builtinTrim,
-		"split":      builtinSplit,
-		"join":       builtinJoin,
-		"contains":   builtinContains,
-		"replace":    builtinReplace,
-		"substring":  builtinSubstring,
-		"length":     builtinLength,
-		"startsWith": builtinStartsWith,
-		"endsWith":   builtinEndsWith,
-		"indexOf":    builtinIndexOf,
-		"charAt":     builtinCharAt,
-		"parseInt":   builtinParseInt,
-		"parseFloat": builtinParseFloat,
-		"toString":   builtinToString,
-		"abs":        builtinAbs,
-		"min":        builtinMin,
-		"max":        builtinMax,
-		"randomInt":  builtinRandomInt,
-		"generateId": builtinGenerateId,
-		"append":     builtinAppend,
-		"set":        builtinSet,
-		"remove":     builtinRemove,
-		"keys":       builtinKeys,
-		"map":        builtinMap,
-		"filter":     builtinFilter,
-		"reduce":     builtinReduce,
-		"find":       builtinFind,
-		"some":       builtinSome,
-		"every":      builtinEvery,
-		"sort":       builtinSort,
-		"reverse":    builtinReverse,
-		"flat":       builtinFlat,
-		"slice":      builtinSlice,
-		"text":       builtinText,
-		"html":       builtinHTML,
-		"blob":       builtinBlob,
-		"redirect":   builtinRedirect,
+		"time.now":       builtinTimeNow,
+		"now":            builtinNow,
+		"Ok":             builtinOk,
+		"Err":            builtinErr,
+		"upper":          builtinUpper,
+		"lower":          builtinLower,
+		"trim":           builtinTrim,
+		"split":          builtinSplit,
+		"join":           builtinJoin,
+		"contains":       builtinContains,
+		"replace":        builtinReplace,
+		"substring":      builtinSubstring,
+		"length":         builtinLength,
+		"startsWith":     builtinStartsWith,
+		"endsWith":       builtinEndsWith,
+		"indexOf":        builtinIndexOf,
+		"charAt":         builtinCharAt,
+		"parseInt":       builtinParseInt,
+		"parseFloat":     builtinParseFloat,
+		"toString":       builtinToString,
+		"abs":            builtinAbs,
+		"min":            builtinMin,
+		"max":            builtinMax,
+		"randomInt":      builtinRandomInt,
+		"generateId":     builtinGenerateId,
+		"append":         builtinAppend,
+		"set":            builtinSet,
+		"remove":         builtinRemove,
+		"keys":           builtinKeys,
+		"map":            builtinMap,
+		"filter":         builtinFilter,
+		"reduce":         builtinReduce,
+		"find":           builtinFind,
+		"some":           builtinSome,
+		"every":          builtinEvery,
+		"sort":           builtinSort,
+		"reverse":        builtinReverse,
+		"flat":           builtinFlat,
+		"slice":          builtinSlice,
+		"text":           builtinText,
+		"html":           builtinHTML,
+		"blob":           builtinBlob,
+		"redirect":       builtinRedirect,
+		"error":          builtinError,
+		"enqueue":        builtinEnqueue,
+		"queue.publish":  builtinQueuePublish,
+		"emit":           builtinEmit,
+		"request":        builtinRequest,
+		"request.header": builtinRequestHeader,
+		"assertEqual":    builtinAssertEqual,
+		"assertContains": builtinAssertContains,
 	}
 }
 
+// IsBuiltinFunction reports whether name is a function provided by the
+// interpreter rather than declared in GLYPH source
+func IsBuiltinFunction(name string) bool {
+	_, ok := builtinFuncs[name]
+	return ok
+}
+
+// BuiltinFunctionNames returns the names of the built-in functions, sorted
+func BuiltinFunctionNames() []string {
+	names := make([]string, 0, len(builtinFuncs))
+	for name := range builtinFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func builtinTimeNow(_ *Interpreter, _ []Expr, _ *Environment) (interface{}, error) {
 	return time.Now().Unix(), nil
 }
@@ -621,11 +645,11 @@ func builtinRandomInt(i *Interpreter, args []Expr, env *Environment) (interface{
 	return minVal + rand.Int63n(maxVal-minVal+1), nil
 }
 
-func builtinGenerateId(_ *Interpreter, args []Expr, _ *Environment) (interface{}, error) {
+func builtinGenerateId(i *Interpreter, args []Expr, _ *Environment) (interface{}, error) {
 	if len(args) != 0 {
 		return nil, fmt.Errorf("generateId() expects 0 arguments, got %d", len(args))
 	}
-	return uuid.New().String(), nil
+	return i.newUUID(), nil
 }
 
 func builtinAppend(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
@@ -1227,3 +1251,74 @@ func builtinRedirect(interp *Interpreter, args []Expr, env *Environment) (interf
 
 	return &RedirectResponse{URL: urlStr, StatusCode: statusCode}, nil
 }
+
+// builtinError implements error(status, message), which ends the route with
+// an error response
+func builtinError(interp *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("error() requires 2 arguments: error(statusCode, message)")
+	}
+
+	codeVal, err := interp.EvaluateExpression(args[0], env)
+	if err != nil {
+		return nil, err
+	}
+	var statusCode int
+	switch v := codeVal.(type) {
+	case int64:
+		statusCode = int(v)
+	case int:
+		statusCode = v
+	case float64:
+		statusCode = int(v)
+	default:
+		return nil, fmt.Errorf("error() first argument must be an integer status code, got %T", codeVal)
+	}
+	if statusCode < 400 || statusCode > 599 {
+		return nil, fmt.Errorf("error() status code must be between 400 and 599, got %d", statusCode)
+	}
+
+	msgVal, err := interp.EvaluateExpression(args[1], env)
+	if err != nil {
+		return nil, err
+	}
+	message, ok := msgVal.(string)
+	if !ok {
+		return nil, fmt.Errorf("error() second argument must be a string message, got %T", msgVal)
+	}
+
+	return nil, &ErrorResponse{StatusCode: statusCode, Message: message}
+}
+
+// builtinRequestHeader implements request.header(name), which returns the
+// value of a request header whatever the case of name, or null if the
+// request did not send it
+func builtinRequestHeader(interp *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("request.header() expects 1 argument (name), got %d", len(args))
+	}
+	nameVal, err := interp.EvaluateExpression(args[0], env)
+	if err != nil {
+		return nil, err
+	}
+	name, ok := nameVal.(string)
+	if !ok {
+		return nil, fmt.Errorf("request.header() name must be a string, got %T", nameVal)
+	}
+
+	request, err := env.Get("request")
+	if err != nil {
+		return nil, fmt.Errorf("request.header() can only be called while handling a request")
+	}
+	requestObj, _ := request.(map[string]interface{})
+	headers, ok := requestObj["headers"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("request.header() can only be called while handling a request")
+	}
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,107 @@
+package interpreter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	. "github.com/glyphlang/glyph/pkg/ast"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func init() {
+	builtinFuncs["hash.sha256"] = builtinHashSHA256
+	builtinFuncs["hmac.sha256"] = builtinHMACSHA256
+	builtinFuncs["bcrypt.hash"] = bcryptHash("bcrypt.hash")
+	builtinFuncs["bcrypt.verify"] = bcryptVerify("bcrypt.verify")
+	// crypto.hash and crypto.verify are the password functions of the
+	// language spec
+	builtinFuncs["crypto.hash"] = bcryptHash("crypto.hash")
+	builtinFuncs["crypto.verify"] = bcryptVerify("crypto.verify")
+}
+
+// stringArgs evaluates the arguments of name(), which takes len(params)
+// string arguments named by params
+func stringArgs(i *Interpreter, name string, params []string, args []Expr, env *Environment) ([]string, error) {
+	if len(args) != len(params) {
+		if len(params) == 1 {
+			return nil, fmt.Errorf("%s() expects 1 argument (%s), got %d", name, params[0], len(args))
+		}
+		return nil, fmt.Errorf("%s() expects %d arguments (%s), got %d", name, len(params), strings.Join(params, ", "), len(args))
+	}
+	values := make([]string, len(args))
+	for idx, arg := range args {
+		v, err := i.EvaluateExpression(arg, env)
+		if err != nil {
+			return nil, err
+		}
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s() %s must be a string, got %T", name, params[idx], v)
+		}
+		values[idx] = str
+	}
+	return values, nil
+}
+
+// builtinHashSHA256 implements hash.sha256(str): the hex SHA-256 digest
+func builtinHashSHA256(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	values, err := stringArgs(i, "hash.sha256", []string{"str"}, args, env)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(values[0]))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// builtinHMACSHA256 implements hmac.sha256(key, msg): the hex HMAC-SHA256 of
+// msg, e.g. to compare with a webhook signature header
+func builtinHMACSHA256(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	values, err := stringArgs(i, "hmac.sha256", []string{"key", "msg"}, args, env)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, []byte(values[0]))
+	mac.Write([]byte(values[1]))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// bcryptHash returns bcrypt.hash(password): a bcrypt hash of password at the
+// default cost, suitable for storing
+func bcryptHash(name string) builtinFunc {
+	return func(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+		values, err := stringArgs(i, name, []string{"password"}, args, env)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(values[0]), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("%s() failed: %w", name, err)
+		}
+		return string(hash), nil
+	}
+}
+
+// bcryptVerify returns bcrypt.verify(password, hash): whether password
+// matches a hash from bcrypt.hash. A malformed hash is an error rather than
+// a mismatch.
+func bcryptVerify(name string) builtinFunc {
+	return func(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+		values, err := stringArgs(i, name, []string{"password", "hash"}, args, env)
+		if err != nil {
+			return nil, err
+		}
+		err = bcrypt.CompareHashAndPassword([]byte(values[1]), []byte(values[0]))
+		switch {
+		case err == nil:
+			return true, nil
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return false, nil
+		default:
+			return nil, fmt.Errorf("%s() invalid hash: %w", name, err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCryptoBuiltins(t *testing.T) {
+	interp := loadTests(t, `test "sha256" {
+  assertEqual(hash.sha256("abc"), "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad")
+  assertEqual(hash.sha256(""), "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+}
+
+test "hmac sha256 known vector" {
+  assertEqual(hmac.sha256("key", "The quick brown fox jumps over the lazy dog"), "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8")
+}
+
+test "bcrypt" {
+  $ stored = bcrypt.hash("correct horse")
+  assertContains(stored, "$2a$")
+  assertEqual(bcrypt.verify("correct horse", stored), true)
+  assertEqual(bcrypt.verify("wrong horse", stored), false)
+  assertEqual(crypto.verify("correct horse", crypto.hash("correct horse")), true)
+}
+`)
+
+	for _, r := range interp.RunTests("") {
+		assert.True(t, r.Passed, "%s: %s", r.Name, r.Error)
+	}
+}
+
+func TestCryptoBuiltins_Errors(t *testing.T) {
+	long := "password-that-is-much-longer-than-the-seventy-two-bytes-bcrypt-can-hash-at-all"
+	for call, want := range map[string]string{
+		`hash.sha256(42)`:                 "hash.sha256() str must be a string, got int64",
+		`hmac.sha256("key")`:              "hmac.sha256() expects 2 arguments (key, msg), got 1",
+		`bcrypt.hash("` + long + `")`:     "bcrypt.hash() failed: bcrypt: password length exceeds 72 bytes",
+		`bcrypt.verify("pw", "not-hash")`: "bcrypt.verify() invalid hash",
+	} {
+		t.Run(call, func(t *testing.T) {
+			interp := loadTests(t, "test \"error\" {\n  "+call+"\n}\n")
+			results := interp.RunTests("")
+			require.Len(t, results, 1)
+			assert.False(t, results[0].Passed)
+			assert.Contains(t, results[0].Error, want)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package interpreter
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	. "github.com/glyphlang/glyph/pkg/ast"
+)
+
+func init() {
+	builtinFuncs["env"] = builtinEnv
+}
+
+// SetEnvPrefixes restricts env() to environment variables whose names start
+// with one of prefixes, e.g. "APP_", so GLYPH code cannot read arbitrary
+// secrets from the host. With no prefixes every variable is readable.
+func (i *Interpreter) SetEnvPrefixes(prefixes []string) {
+	i.envPrefixes = prefixes
+}
+
+// envAllowed reports whether env() may read the variable name
+func (i *Interpreter) envAllowed(name string) bool {
+	if len(i.envPrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range i.envPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinEnv implements env(name, default?): the value of an environment
+// variable as a string, or default (null if omitted) when it is unset
+func builtinEnv(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("env() expects 1-2 arguments (name, default?), got %d", len(args))
+	}
+	nameArg, err := i.EvaluateExpression(args[0], env)
+	if err != nil {
+		return nil, err
+	}
+	name, ok := nameArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("env() expects a string name, got %T", nameArg)
+	}
+	if !i.envAllowed(name) {
+		return nil, fmt.Errorf("env() cannot read '%s': only variables starting with %s are allowed",
+			name, strings.Join(i.envPrefixes, ", "))
+	}
+
+	if value, ok := os.LookupEnv(name); ok {
+		return value, nil
+	}
+	if len(args) == 2 {
+		return i.EvaluateExpression(args[1], env)
+	}
+	return nil, nil
+}
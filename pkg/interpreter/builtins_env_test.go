@@ -0,0 +1,85 @@
+package interpreter
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvBuiltin(t *testing.T) {
+	require.NoError(t, os.Setenv("GLYPH_TEST_API_KEY", "secret-123"))
+	defer os.Unsetenv("GLYPH_TEST_API_KEY")
+	os.Unsetenv("GLYPH_TEST_MISSING")
+
+	interp := loadTests(t, `test "present" {
+  assertEqual(env("GLYPH_TEST_API_KEY"), "secret-123")
+  assertEqual(env("GLYPH_TEST_API_KEY", "fallback"), "secret-123")
+}
+
+test "absent with default" {
+  assertEqual(env("GLYPH_TEST_MISSING", "fallback"), "fallback")
+  assertEqual(env("GLYPH_TEST_MISSING", 8080), 8080)
+}
+
+test "absent without default" {
+  assertEqual(env("GLYPH_TEST_MISSING"), null)
+}
+`)
+
+	for _, r := range interp.RunTests("") {
+		assert.True(t, r.Passed, "%s: %s", r.Name, r.Error)
+	}
+}
+
+func TestEnvBuiltin_EmptyValueIsSet(t *testing.T) {
+	require.NoError(t, os.Setenv("GLYPH_TEST_EMPTY", ""))
+	defer os.Unsetenv("GLYPH_TEST_EMPTY")
+
+	interp := loadTests(t, `test "empty" {
+  assertEqual(env("GLYPH_TEST_EMPTY", "fallback"), "")
+}
+`)
+
+	results := interp.RunTests("")
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Passed, results[0].Error)
+}
+
+func TestEnvBuiltin_Prefixes(t *testing.T) {
+	require.NoError(t, os.Setenv("APP_NAME", "glyph"))
+	defer os.Unsetenv("APP_NAME")
+
+	interp := loadTests(t, `test "allowed" {
+  assertEqual(env("APP_NAME"), "glyph")
+}
+
+test "denied" {
+  $ home = env("HOME")
+}
+`)
+	interp.SetEnvPrefixes([]string{"APP_", "PUBLIC_"})
+
+	results := interp.RunTests("")
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Passed, results[0].Error)
+	assert.False(t, results[1].Passed)
+	assert.Contains(t, results[1].Error, "env() cannot read 'HOME': only variables starting with APP_, PUBLIC_ are allowed")
+}
+
+func TestEnvBuiltin_Arguments(t *testing.T) {
+	interp := loadTests(t, `test "no args" {
+  $ v = env()
+}
+
+test "non-string name" {
+  $ v = env(42)
+}
+`)
+
+	results := interp.RunTests("")
+	require.Len(t, results, 2)
+	assert.Contains(t, results[0].Error, "env() expects 1-2 arguments")
+	assert.Contains(t, results[1].Error, "env() expects a string name")
+}
@@ -2,6 +2,7 @@ package interpreter
 
 import (
 	"fmt"
+	"strings"
 
 	. "github.com/glyphlang/glyph/pkg/ast"
 	"github.com/glyphlang/glyph/pkg/httpclient"
@@ -47,41 +48,108 @@ func builtinHTTPDelete(i *Interpreter, args []Expr, env *Environment) (interface
 }
 
 // callHTTPBuiltin evaluates arguments and delegates to the HTTP handler.
-// Accepts 1 argument: either a string URL or an options object.
+// Accepted forms:
+//
+//	http.get(url, headers?)            http.delete(url, headers?)
+//	http.post(url, body, headers?)     (likewise put and patch)
+//	http.get({url, headers, body, query, timeout, followRedirects})
+//	http.get(url, {headers, body, query, timeout, followRedirects})
+//
+// The last form predates the positional one: a second argument that is an
+// object with only option keys is taken as the options of the request.
+// The result is {status, headers, body, ok}, with a JSON body parsed. A
+// non-2xx status is returned, not raised; connection failures, timeouts and
+// oversized responses are errors.
 func callHTTPBuiltin(i *Interpreter, method string, args []Expr, env *Environment) (interface{}, error) {
-	if len(args) < 1 || len(args) > 2 {
-		return nil, fmt.Errorf("http.%s() expects 1-2 arguments, got %d", method, len(args))
-	}
+	name := "http." + strings.ToLower(method)
+	hasBody := method == "Post" || method == "Put" || method == "Patch"
 
-	// Evaluate the URL argument
-	urlArg, err := i.EvaluateExpression(args[0], env)
-	if err != nil {
-		return nil, err
+	maxArgs := 2
+	if hasBody {
+		maxArgs = 3
+	}
+	if len(args) < 1 || len(args) > maxArgs {
+		return nil, fmt.Errorf("%s() expects 1-%d arguments, got %d", name, maxArgs, len(args))
 	}
 
-	var requestArg interface{}
-
-	if len(args) == 2 {
-		// Two-arg form: http.post(url, {body: ..., headers: ...})
-		optsArg, err := i.EvaluateExpression(args[1], env)
+	values := make([]interface{}, len(args))
+	for idx, arg := range args {
+		v, err := i.EvaluateExpression(arg, env)
 		if err != nil {
 			return nil, err
 		}
-		optsMap, ok := optsArg.(map[string]interface{})
+		values[idx] = v
+	}
+
+	// Single-arg form: http.get(url) or http.get({url: ..., headers: ...})
+	requestArg := values[0]
+	if opts, ok := legacyHTTPOptions(values); ok {
+		urlStr, ok := values[0].(string)
 		if !ok {
-			return nil, fmt.Errorf("http.%s() second argument must be an object, got %T", method, optsArg)
+			return nil, fmt.Errorf("%s() first argument must be a string URL, got %T", name, values[0])
 		}
-		urlStr, ok := urlArg.(string)
+		opts["url"] = urlStr
+		requestArg = opts
+	} else if len(values) > 1 {
+		urlStr, ok := values[0].(string)
 		if !ok {
-			return nil, fmt.Errorf("http.%s() first argument must be a string URL when using two arguments, got %T", method, urlArg)
+			return nil, fmt.Errorf("%s() first argument must be a string URL, got %T", name, values[0])
 		}
-		optsMap["url"] = urlStr
-		requestArg = optsMap
-	} else {
-		// Single-arg form: http.get(url) or http.get({url: ..., headers: ...})
-		requestArg = urlArg
+		opts := map[string]interface{}{"url": urlStr}
+
+		headersIdx := 1
+		if hasBody {
+			opts["body"] = values[1]
+			headersIdx = 2
+		}
+		if headersIdx < len(values) && values[headersIdx] != nil {
+			headers, ok := values[headersIdx].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s() headers must be an object, got %T", name, values[headersIdx])
+			}
+			opts["headers"] = headers
+		}
+		requestArg = opts
 	}
 
-	h := getDefaultHTTPHandler()
+	h, ok := i.httpHandler.(*httpclient.Handler)
+	if !ok || h == nil {
+		h = getDefaultHTTPHandler()
+	}
+	// Requests made while serving a route join the route's trace
+	if ctx := env.lookupContext(); ctx != nil {
+		h = h.WithContext(ctx)
+	}
 	return CallMethod(h, method, requestArg)
 }
+
+// httpOptionKeys are the keys of the options object of
+// http.get(url, {headers, body, query, timeout, followRedirects})
+var httpOptionKeys = map[string]bool{
+	"headers":         true,
+	"body":            true,
+	"query":           true,
+	"timeout":         true,
+	"followRedirects": true,
+}
+
+// legacyHTTPOptions returns a copy of the options object of a call in the
+// form http.get(url, {headers: ...}), whose second and last argument is a
+// non-empty object with only option keys
+func legacyHTTPOptions(values []interface{}) (map[string]interface{}, bool) {
+	if len(values) != 2 {
+		return nil, false
+	}
+	obj, ok := values[1].(map[string]interface{})
+	if !ok || len(obj) == 0 {
+		return nil, false
+	}
+	opts := make(map[string]interface{}, len(obj)+1)
+	for k, v := range obj {
+		if !httpOptionKeys[k] {
+			return nil, false
+		}
+		opts[k] = v
+	}
+	return opts, true
+}
@@ -0,0 +1,150 @@
+package interpreter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHTTPTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"name":  "Ada",
+				"age":   36,
+				"token": r.Header.Get("Authorization"),
+			})
+		case "/echo":
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(body)
+		case "/missing":
+			http.Error(w, "not here", http.StatusNotFound)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/large":
+			_, _ = w.Write([]byte(strings.Repeat("x", 64)))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPBuiltin_GetParsesJSON(t *testing.T) {
+	server := newHTTPTestServer(t)
+	interp := loadTests(t, fmt.Sprintf(`test "get" {
+  $ res = http.get("%s/user", {Authorization: "Bearer abc"})
+  assertEqual(res.status, 200)
+  assertEqual(res.body.name, "Ada")
+  assertEqual(res.body.age, 36)
+  assertEqual(res.body.token, "Bearer abc")
+  assertEqual(res.ok, true)
+}
+`, server.URL))
+
+	results := interp.RunTests("")
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Passed, results[0].Error)
+}
+
+func TestHTTPBuiltin_PostSendsJSONBody(t *testing.T) {
+	server := newHTTPTestServer(t)
+	interp := loadTests(t, fmt.Sprintf(`test "post" {
+  $ res = http.post("%s/echo", {items: [1, 2]}, {trace: "1"})
+  assertEqual(res.status, 201)
+  assertEqual(res.body, {items: [1, 2]})
+}
+`, server.URL))
+
+	results := interp.RunTests("")
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Passed, results[0].Error)
+}
+
+func TestHTTPBuiltin_Non2xxIsNotAnError(t *testing.T) {
+	server := newHTTPTestServer(t)
+	interp := loadTests(t, fmt.Sprintf(`test "missing" {
+  $ res = http.get("%s/missing")
+  assertEqual(res.status, 404)
+  assertEqual(res.ok, false)
+  assertContains(res.body, "not here")
+}
+`, server.URL))
+
+	results := interp.RunTests("")
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Passed, results[0].Error)
+}
+
+func TestHTTPBuiltin_Errors(t *testing.T) {
+	server := newHTTPTestServer(t)
+	interp := loadTests(t, fmt.Sprintf(`test "timeout" {
+  $ res = http.get("%[1]s/slow")
+}
+
+test "too large" {
+  $ res = http.get("%[1]s/large")
+}
+
+test "connection refused" {
+  $ res = http.get("http://127.0.0.1:1/")
+}
+
+test "bad headers" {
+  $ res = http.get("%[1]s/user", "nope")
+}
+`, server.URL))
+	interp.SetHTTPHandler(httpclient.NewHandlerWithConfig(httpclient.Config{
+		Timeout:         50 * time.Millisecond,
+		MaxResponseSize: 16,
+	}))
+
+	results := interp.RunTests("")
+	require.Len(t, results, 4)
+	for _, r := range results {
+		assert.False(t, r.Passed, r.Name)
+	}
+	assert.Contains(t, results[0].Error, "request failed")
+	assert.Contains(t, results[1].Error, "exceeds maximum size of 16 bytes")
+	assert.Contains(t, results[2].Error, "request failed")
+	assert.Contains(t, results[3].Error, "headers must be an object")
+}
+
+func TestHTTPBuiltin_OptionsObject(t *testing.T) {
+	server := newHTTPTestServer(t)
+	interp := loadTests(t, fmt.Sprintf(`test "get with options" {
+  $ res = http.get("%[1]s/user", {headers: {Authorization: "Bearer abc"}})
+  assertEqual(res.body.token, "Bearer abc")
+}
+
+test "post with options" {
+  $ res = http.post("%[1]s/echo", {body: {items: [1]}, headers: {trace: "1"}})
+  assertEqual(res.status, 201)
+  assertEqual(res.body, {items: [1]})
+}
+
+test "post of a body with other keys" {
+  $ res = http.post("%[1]s/echo", {body: "x", name: "Ada"})
+  assertEqual(res.body, {body: "x", name: "Ada"})
+}
+`, server.URL))
+
+	results := interp.RunTests("")
+	require.Len(t, results, 3)
+	for _, r := range results {
+		assert.True(t, r.Passed, r.Name+": "+r.Error)
+	}
+}
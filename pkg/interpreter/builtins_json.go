@@ -0,0 +1,87 @@
+package interpreter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	. "github.com/glyphlang/glyph/pkg/ast"
+)
+
+func init() {
+	builtinFuncs["json.parse"] = builtinJSONParse
+	builtinFuncs["json.stringify"] = builtinJSONStringify
+}
+
+// builtinJSONParse implements json.parse(str): the decoded object, array or
+// scalar. Whole numbers become ints and other numbers floats.
+func builtinJSONParse(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("json.parse() expects 1 argument, got %d", len(args))
+	}
+	arg, err := i.EvaluateExpression(args[0], env)
+	if err != nil {
+		return nil, err
+	}
+	str, ok := arg.(string)
+	if !ok {
+		return nil, fmt.Errorf("json.parse() expects a string, got %T", arg)
+	}
+
+	dec := json.NewDecoder(strings.NewReader(str))
+	dec.UseNumber()
+	var decoded interface{}
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("json.parse() invalid JSON: %v", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("json.parse() invalid JSON: unexpected data after value")
+	}
+	return jsonToValue(decoded), nil
+}
+
+// builtinJSONStringify implements json.stringify(value): value encoded as a
+// compact JSON string
+func builtinJSONStringify(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("json.stringify() expects 1 argument, got %d", len(args))
+	}
+	value, err := i.EvaluateExpression(args[0], env)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return nil, fmt.Errorf("json.stringify() cannot encode %T: %v", value, err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// jsonToValue converts a value decoded with json.Decoder.UseNumber to the
+// interpreter's value types: int64 for whole numbers, float64 otherwise
+func jsonToValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, val := range v {
+			v[key] = jsonToValue(val)
+		}
+		return v
+	case []interface{}:
+		for idx, val := range v {
+			v[idx] = jsonToValue(val)
+		}
+		return v
+	default:
+		return v
+	}
+}
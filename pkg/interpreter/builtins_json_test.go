@@ -0,0 +1,67 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONBuiltins(t *testing.T) {
+	interp := loadTests(t, `test "round trip" {
+  $ user = {name: "Ada", age: 36, score: 9.5, admin: true, tags: ["a", "b"], address: {city: "London"}, manager: null}
+  $ decoded = json.parse(json.stringify(user))
+  assertEqual(decoded, user)
+  assertEqual(decoded.address.city, "London")
+  assertEqual(decoded.age + 1, 37)
+}
+
+test "stringify" {
+  assertEqual(json.stringify({id: 1}), '{"id":1}')
+  assertEqual(json.stringify([1, "two", false]), '[1,"two",false]')
+  assertEqual(json.stringify("<b>"), '"<b>"')
+  assertEqual(json.stringify(null), "null")
+}
+
+test "parse scalars" {
+  assertEqual(json.parse("42"), 42)
+  assertEqual(json.parse("1.5"), 1.5)
+  assertEqual(json.parse('"hi"'), "hi")
+  assertEqual(json.parse("true"), true)
+  assertEqual(json.parse("null"), null)
+  assertEqual(json.parse("[1, [2]]"), [1, [2]])
+}
+`)
+
+	for _, r := range interp.RunTests("") {
+		assert.True(t, r.Passed, "%s: %s", r.Name, r.Error)
+	}
+}
+
+func TestJSONParse_Invalid(t *testing.T) {
+	for name, input := range map[string]string{
+		"syntax":   `{"a": }`,
+		"trailing": `{} {}`,
+		"empty":    ``,
+	} {
+		t.Run(name, func(t *testing.T) {
+			interp := loadTests(t, `test "invalid" {
+  json.parse('`+input+`')
+}
+`)
+			results := interp.RunTests("")
+			if assert.Len(t, results, 1) {
+				assert.False(t, results[0].Passed)
+				assert.Contains(t, results[0].Error, "json.parse() invalid JSON")
+			}
+		})
+	}
+
+	interp := loadTests(t, `test "not a string" {
+  json.parse(42)
+}
+`)
+	results := interp.RunTests("")
+	if assert.Len(t, results, 1) {
+		assert.Contains(t, results[0].Error, "expects a string")
+	}
+}
@@ -0,0 +1,132 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+
+	. "github.com/glyphlang/glyph/pkg/ast"
+)
+
+func init() {
+	builtinFuncs["math.floor"] = mathRounding("floor", math.Floor)
+	builtinFuncs["math.ceil"] = mathRounding("ceil", math.Ceil)
+	builtinFuncs["math.round"] = mathRounding("round", math.Round)
+	builtinFuncs["math.abs"] = builtinMathAbs
+	builtinFuncs["math.min"] = mathMinMax("min", true)
+	builtinFuncs["math.max"] = mathMinMax("max", false)
+	builtinFuncs["math.pow"] = builtinMathPow
+	builtinFuncs["math.sqrt"] = builtinMathSqrt
+}
+
+// mathArgs evaluates the arguments of math.name(), which takes want numeric
+// arguments
+func mathArgs(i *Interpreter, name string, want int, args []Expr, env *Environment) ([]interface{}, error) {
+	if len(args) != want {
+		if want == 1 {
+			return nil, fmt.Errorf("math.%s() expects 1 argument, got %d", name, len(args))
+		}
+		return nil, fmt.Errorf("math.%s() expects %d arguments, got %d", name, want, len(args))
+	}
+	values := make([]interface{}, len(args))
+	for idx, arg := range args {
+		v, err := i.EvaluateExpression(arg, env)
+		if err != nil {
+			return nil, err
+		}
+		switch v.(type) {
+		case int64, float64:
+		default:
+			return nil, fmt.Errorf("math.%s() expects a numeric argument, got %T", name, v)
+		}
+		values[idx] = v
+	}
+	return values, nil
+}
+
+// toFloat converts an int64 or float64 argument to float64
+func toFloat(v interface{}) float64 {
+	if n, ok := v.(int64); ok {
+		return float64(n)
+	}
+	return v.(float64)
+}
+
+// mathRounding returns math.floor, math.ceil or math.round. Ints are
+// already whole and are returned unchanged; floats are rounded to a float.
+// math.round rounds halves away from zero.
+func mathRounding(name string, round func(float64) float64) builtinFunc {
+	return func(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+		values, err := mathArgs(i, name, 1, args, env)
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := values[0].(int64); ok {
+			return n, nil
+		}
+		return round(values[0].(float64)), nil
+	}
+}
+
+// builtinMathAbs implements math.abs(n), which keeps the type of n
+func builtinMathAbs(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	values, err := mathArgs(i, "abs", 1, args, env)
+	if err != nil {
+		return nil, err
+	}
+	if n, ok := values[0].(int64); ok {
+		if n == math.MinInt64 {
+			return nil, fmt.Errorf("math.abs() overflow: cannot negate minimum int64 value")
+		}
+		if n < 0 {
+			return -n, nil
+		}
+		return n, nil
+	}
+	return math.Abs(values[0].(float64)), nil
+}
+
+// mathMinMax returns math.min or math.max. Two ints give an int; if either
+// argument is a float the result is a float.
+func mathMinMax(name string, smaller bool) builtinFunc {
+	return func(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+		values, err := mathArgs(i, name, 2, args, env)
+		if err != nil {
+			return nil, err
+		}
+		a, aInt := values[0].(int64)
+		b, bInt := values[1].(int64)
+		if aInt && bInt {
+			if (a < b) == smaller {
+				return a, nil
+			}
+			return b, nil
+		}
+		if smaller {
+			return math.Min(toFloat(values[0]), toFloat(values[1])), nil
+		}
+		return math.Max(toFloat(values[0]), toFloat(values[1])), nil
+	}
+}
+
+// builtinMathPow implements math.pow(base, exponent), always a float
+func builtinMathPow(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	values, err := mathArgs(i, "pow", 2, args, env)
+	if err != nil {
+		return nil, err
+	}
+	return math.Pow(toFloat(values[0]), toFloat(values[1])), nil
+}
+
+// builtinMathSqrt implements math.sqrt(n), always a float. Negative numbers
+// are an error rather than NaN.
+func builtinMathSqrt(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	values, err := mathArgs(i, "sqrt", 1, args, env)
+	if err != nil {
+		return nil, err
+	}
+	f := toFloat(values[0])
+	if f < 0 {
+		return nil, fmt.Errorf("math.sqrt() of negative number %v", values[0])
+	}
+	return math.Sqrt(f), nil
+}
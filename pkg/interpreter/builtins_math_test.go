@@ -0,0 +1,68 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMathBuiltins(t *testing.T) {
+	interp := loadTests(t, `test "rounding" {
+  assertEqual(math.floor(2.7), 2.0)
+  assertEqual(math.floor(-2.2), -3.0)
+  assertEqual(math.ceil(2.2), 3.0)
+  assertEqual(math.ceil(-2.7), -2.0)
+  assertEqual(math.round(2.5), 3.0)
+  assertEqual(math.round(-2.5), -3.0)
+  assertEqual(math.round(2.49), 2.0)
+  assertEqual(math.floor(7), 7)
+  assertEqual(math.round(7), 7)
+}
+
+test "abs" {
+  assertEqual(math.abs(-5), 5)
+  assertEqual(math.abs(-1.5), 1.5)
+  assertEqual(math.abs(3), 3)
+}
+
+test "min and max" {
+  assertEqual(math.min(3, 7), 3)
+  assertEqual(math.max(3, 7), 7)
+  assertEqual(math.min(7, 3), 3)
+  assertEqual(math.max(7, 3), 7)
+  assertEqual(math.min(2.5, 1.5), 1.5)
+  assertEqual(math.max(2, 1.5), 2.0)
+  assertEqual(math.min(2, 1.5), 1.5)
+}
+
+test "pow and sqrt" {
+  assertEqual(math.pow(2, 10), 1024.0)
+  assertEqual(math.pow(4, 0.5), 2.0)
+  assertEqual(math.sqrt(16), 4.0)
+  assertEqual(math.sqrt(2.25), 1.5)
+}
+`)
+
+	for _, r := range interp.RunTests("") {
+		assert.True(t, r.Passed, "%s: %s", r.Name, r.Error)
+	}
+}
+
+func TestMathBuiltins_Errors(t *testing.T) {
+	for call, want := range map[string]string{
+		`math.floor("1.5")`: `math.floor() expects a numeric argument, got string`,
+		`math.max(1, "2")`:  `math.max() expects a numeric argument, got string`,
+		`math.min(1)`:       `math.min() expects 2 arguments, got 1`,
+		`math.sqrt(1, 2)`:   `math.sqrt() expects 1 argument, got 2`,
+		`math.sqrt(-4)`:     `math.sqrt() of negative number -4`,
+	} {
+		t.Run(call, func(t *testing.T) {
+			interp := loadTests(t, "test \"error\" {\n  "+call+"\n}\n")
+			results := interp.RunTests("")
+			if assert.Len(t, results, 1) {
+				assert.False(t, results[0].Passed)
+				assert.Contains(t, results[0].Error, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package interpreter
+
+import (
+	"fmt"
+
+	. "github.com/glyphlang/glyph/pkg/ast"
+)
+
+// cookiesKey and sessionKey are the route environment variables holding
+// the request's response cookies and session
+const (
+	cookiesKey = "__cookies"
+	sessionKey = "__session"
+)
+
+// ResponseCookies is the response behind setCookie and clearCookie
+type ResponseCookies interface {
+	SetCookie(name, value string, options map[string]interface{}) error
+	ClearCookie(name string) error
+}
+
+// Session is the request session behind the session.* builtins
+type Session interface {
+	Get(key string) (interface{}, error)
+	Set(key string, value interface{}) error
+	Regenerate() error
+	Destroy() error
+}
+
+func init() {
+	builtinFuncs["setCookie"] = builtinSetCookie
+	builtinFuncs["clearCookie"] = builtinClearCookie
+	builtinFuncs["session.get"] = builtinSessionGet
+	builtinFuncs["session.set"] = builtinSessionSet
+	builtinFuncs["session.regenerate"] = builtinSessionRegenerate
+	builtinFuncs["session.destroy"] = builtinSessionDestroy
+}
+
+// builtinSetCookie implements setCookie(name, value, options?), where
+// options may hold maxAge, httpOnly, secure, sameSite, path and domain
+func builtinSetCookie(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("setCookie() expects 2 or 3 arguments (name, value, options?), got %d", len(args))
+	}
+	cookies, err := requestCookies("setCookie", env)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(args))
+	for idx, arg := range args {
+		if values[idx], err = i.EvaluateExpression(arg, env); err != nil {
+			return nil, err
+		}
+	}
+	name, ok := values[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("setCookie() name must be a string, got %T", values[0])
+	}
+	value, ok := values[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("setCookie() value must be a string, got %T", values[1])
+	}
+	var options map[string]interface{}
+	if len(values) == 3 {
+		if options, ok = values[2].(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("setCookie() options must be an object, got %T", values[2])
+		}
+	}
+	if err := cookies.SetCookie(name, value, options); err != nil {
+		return nil, fmt.Errorf("setCookie(): %w", err)
+	}
+	return nil, nil
+}
+
+// builtinClearCookie implements clearCookie(name)
+func builtinClearCookie(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("clearCookie() expects 1 argument (name), got %d", len(args))
+	}
+	cookies, err := requestCookies("clearCookie", env)
+	if err != nil {
+		return nil, err
+	}
+	nameArg, err := i.EvaluateExpression(args[0], env)
+	if err != nil {
+		return nil, err
+	}
+	name, ok := nameArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("clearCookie() name must be a string, got %T", nameArg)
+	}
+	if err := cookies.ClearCookie(name); err != nil {
+		return nil, fmt.Errorf("clearCookie(): %w", err)
+	}
+	return nil, nil
+}
+
+// builtinSessionGet implements session.get(key): the stored value, or null
+func builtinSessionGet(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("session.get() expects 1 argument (key), got %d", len(args))
+	}
+	s, key, err := i.sessionArgs("get", args[0], env)
+	if err != nil {
+		return nil, err
+	}
+	value, err := s.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("session.get(): %w", err)
+	}
+	return value, nil
+}
+
+// builtinSessionSet implements session.set(key, value); a null value
+// removes the key
+func builtinSessionSet(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("session.set() expects 2 arguments (key, value), got %d", len(args))
+	}
+	s, key, err := i.sessionArgs("set", args[0], env)
+	if err != nil {
+		return nil, err
+	}
+	value, err := i.EvaluateExpression(args[1], env)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Set(key, value); err != nil {
+		return nil, fmt.Errorf("session.set(): %w", err)
+	}
+	return nil, nil
+}
+
+// builtinSessionRegenerate implements session.regenerate(): it keeps the
+// session's data under a new ID, as a route should after a login
+func builtinSessionRegenerate(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("session.regenerate() expects no arguments, got %d", len(args))
+	}
+	s, err := requestSession("regenerate", env)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Regenerate(); err != nil {
+		return nil, fmt.Errorf("session.regenerate(): %w", err)
+	}
+	return nil, nil
+}
+
+// builtinSessionDestroy implements session.destroy(): it empties the
+// session and deletes its cookie
+func builtinSessionDestroy(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("session.destroy() expects no arguments, got %d", len(args))
+	}
+	s, err := requestSession("destroy", env)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Destroy(); err != nil {
+		return nil, fmt.Errorf("session.destroy(): %w", err)
+	}
+	return nil, nil
+}
+
+// sessionArgs returns the request's session and the evaluated string key
+func (i *Interpreter) sessionArgs(method string, keyArg Expr, env *Environment) (Session, string, error) {
+	s, err := requestSession(method, env)
+	if err != nil {
+		return nil, "", err
+	}
+	keyVal, err := i.EvaluateExpression(keyArg, env)
+	if err != nil {
+		return nil, "", err
+	}
+	key, ok := keyVal.(string)
+	if !ok {
+		return nil, "", fmt.Errorf("session.%s() key must be a string, got %T", method, keyVal)
+	}
+	return s, key, nil
+}
+
+// requestCookies returns the response cookies of the route being executed
+func requestCookies(fn string, env *Environment) (ResponseCookies, error) {
+	if val, err := env.Get(cookiesKey); err == nil {
+		if cookies, ok := val.(ResponseCookies); ok {
+			return cookies, nil
+		}
+	}
+	return nil, fmt.Errorf("%s() can only be called while handling a request", fn)
+}
+
+// requestSession returns the session of the route being executed
+func requestSession(method string, env *Environment) (Session, error) {
+	if val, err := env.Get(sessionKey); err == nil {
+		if s, ok := val.(Session); ok {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("session.%s() can only be called while handling a request", method)
+}
@@ -0,0 +1,72 @@
+package interpreter
+
+import (
+	"fmt"
+
+	. "github.com/glyphlang/glyph/pkg/ast"
+)
+
+// Templates renders the HTML templates behind render(name, data) and
+// renderString(template, data)
+type Templates interface {
+	Render(name string, data interface{}) (string, error)
+	RenderString(template string, data interface{}) (string, error)
+}
+
+func init() {
+	builtinFuncs["render"] = builtinRender
+	builtinFuncs["renderString"] = builtinRenderString
+}
+
+// SetTemplates sets the templates used by render() and renderString()
+func (i *Interpreter) SetTemplates(templates Templates) {
+	i.templates = templates
+}
+
+// builtinRender implements render(name, data): the template file name
+// rendered with data, sent as an HTML response
+func builtinRender(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	name, data, err := i.templateArgs("render", "name", args, env)
+	if err != nil {
+		return nil, err
+	}
+	page, err := i.templates.Render(name, data)
+	if err != nil {
+		return nil, err
+	}
+	return &HTMLResponse{Body: page, StatusCode: 200}, nil
+}
+
+// builtinRenderString implements renderString(template, data): the inline
+// template rendered with data, as a string
+func builtinRenderString(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	template, data, err := i.templateArgs("renderString", "template", args, env)
+	if err != nil {
+		return nil, err
+	}
+	return i.templates.RenderString(template, data)
+}
+
+// templateArgs evaluates the arguments of the template builtin fn, whose
+// first argument, called param, is a string
+func (i *Interpreter) templateArgs(fn, param string, args []Expr, env *Environment) (string, interface{}, error) {
+	if len(args) != 2 {
+		return "", nil, fmt.Errorf("%s() expects 2 arguments (%s, data), got %d", fn, param, len(args))
+	}
+	if i.templates == nil {
+		return "", nil, fmt.Errorf("%s(): no templates are configured", fn)
+	}
+	first, err := i.EvaluateExpression(args[0], env)
+	if err != nil {
+		return "", nil, err
+	}
+	str, ok := first.(string)
+	if !ok {
+		return "", nil, fmt.Errorf("%s() %s must be a string, got %T", fn, param, first)
+	}
+	data, err := i.EvaluateExpression(args[1], env)
+	if err != nil {
+		return "", nil, err
+	}
+	return str, data, nil
+}
@@ -0,0 +1,62 @@
+package interpreter
+
+import (
+	"testing"
+
+	. "github.com/glyphlang/glyph/pkg/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTemplates records the data given to it and renders the name or
+// template followed by the data's name field
+type fakeTemplates struct {
+	data interface{}
+}
+
+func (f *fakeTemplates) Render(name string, data interface{}) (string, error) {
+	f.data = data
+	return name + ":" + data.(map[string]interface{})["name"].(string), nil
+}
+
+func (f *fakeTemplates) RenderString(template string, data interface{}) (string, error) {
+	f.data = data
+	return template + ":" + data.(map[string]interface{})["name"].(string), nil
+}
+
+func TestRenderBuiltin(t *testing.T) {
+	interp := NewInterpreter()
+	templates := &fakeTemplates{}
+	interp.SetTemplates(templates)
+	data := ObjectExpr{Fields: []ObjectField{{Key: "name", Value: strLit("ada")}}}
+
+	result, err := interp.EvaluateExpression(callExpr("render", strLit("users"), data), NewEnvironment())
+	require.NoError(t, err)
+	hr, ok := result.(*HTMLResponse)
+	require.True(t, ok, "expected *HTMLResponse, got %T", result)
+	assert.Equal(t, "users:ada", hr.Body)
+	assert.Equal(t, 200, hr.StatusCode)
+	assert.Equal(t, map[string]interface{}{"name": "ada"}, templates.data)
+
+	result, err = interp.EvaluateExpression(callExpr("renderString", strLit("hi"), data), NewEnvironment())
+	require.NoError(t, err)
+	assert.Equal(t, "hi:ada", result)
+}
+
+func TestRenderBuiltin_Errors(t *testing.T) {
+	interp := NewInterpreter()
+	env := NewEnvironment()
+
+	_, err := interp.EvaluateExpression(callExpr("render", strLit("users"), intLit(1)), env)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "render(): no templates are configured")
+
+	interp.SetTemplates(&fakeTemplates{})
+	_, err = interp.EvaluateExpression(callExpr("render", strLit("users")), env)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "render() expects 2 arguments (name, data), got 1")
+
+	_, err = interp.EvaluateExpression(callExpr("renderString", intLit(1), intLit(1)), env)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "renderString() template must be a string")
+}
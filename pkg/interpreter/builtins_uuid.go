@@ -0,0 +1,37 @@
+package interpreter
+
+import (
+	"fmt"
+
+	. "github.com/glyphlang/glyph/pkg/ast"
+	"github.com/google/uuid"
+)
+
+func init() {
+	builtinFuncs["uuid"] = builtinUUID
+}
+
+// UUIDFunc returns a new UUID string for uuid() and generateId()
+type UUIDFunc func() string
+
+// SetUUIDFunc replaces the source of uuid() and generateId(), so tests can
+// make generated IDs deterministic. A nil fn restores random v4 UUIDs.
+func (i *Interpreter) SetUUIDFunc(fn UUIDFunc) {
+	i.uuidFunc = fn
+}
+
+// newUUID returns the next UUID from the configured source
+func (i *Interpreter) newUUID() string {
+	if i != nil && i.uuidFunc != nil {
+		return i.uuidFunc()
+	}
+	return uuid.NewString()
+}
+
+// builtinUUID implements uuid(): a random (version 4) UUID string
+func builtinUUID(i *Interpreter, args []Expr, _ *Environment) (interface{}, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("uuid() expects 0 arguments, got %d", len(args))
+	}
+	return i.newUUID(), nil
+}
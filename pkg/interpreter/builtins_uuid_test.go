@@ -0,0 +1,62 @@
+package interpreter
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	. "github.com/glyphlang/glyph/pkg/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// uuidV4Pattern matches a lowercase version 4, variant 1 UUID
+var uuidV4Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestUUIDBuiltin_Format(t *testing.T) {
+	interp := NewInterpreter()
+	call := FunctionCallExpr{Name: "uuid"}
+
+	first, err := interp.EvaluateExpression(call, interp.globalEnv)
+	require.NoError(t, err)
+	second, err := interp.EvaluateExpression(call, interp.globalEnv)
+	require.NoError(t, err)
+
+	assert.Regexp(t, uuidV4Pattern, first)
+	assert.Regexp(t, uuidV4Pattern, second)
+	assert.NotEqual(t, first, second)
+}
+
+func TestUUIDBuiltin_Source(t *testing.T) {
+	interp := loadTests(t, `test "deterministic ids" {
+  assertEqual(uuid(), "id-1")
+  assertEqual({id: uuid()}, {id: "id-2"})
+  assertEqual(generateId(), "id-3")
+}
+`)
+	n := 0
+	interp.SetUUIDFunc(func() string {
+		n++
+		return fmt.Sprintf("id-%d", n)
+	})
+
+	for _, r := range interp.RunTests("") {
+		assert.True(t, r.Passed, "%s: %s", r.Name, r.Error)
+	}
+
+	interp.SetUUIDFunc(nil)
+	id, err := interp.EvaluateExpression(FunctionCallExpr{Name: "uuid"}, interp.globalEnv)
+	require.NoError(t, err)
+	assert.Regexp(t, uuidV4Pattern, id)
+}
+
+func TestUUIDBuiltin_Arguments(t *testing.T) {
+	interp := loadTests(t, `test "args" {
+  uuid(4)
+}
+`)
+	results := interp.RunTests("")
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Passed)
+	assert.Contains(t, results[0].Error, "uuid() expects 0 arguments, got 1")
+}
@@ -0,0 +1,115 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"fmt"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/cache"
+)
+
+// SetCacheHandler sets the cache for `% cache: Cache` injection
+func (i *Interpreter) SetCacheHandler(handler *cache.ValueCache) {
+	i.providerHandlers["Cache"] = handler
+}
+
+// evaluateCacheMethod handles method calls on an injected cache:
+// get(key), set(key, value, ttlSeconds?), delete(key) and
+// remember(key, ttlSeconds, fn). A ttl of 0 means no expiry.
+func (i *Interpreter) evaluateCacheMethod(c *cache.ValueCache, method string, args []interface{}, env *Environment) (interface{}, error) {
+	switch method {
+	case "get":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("cache.get() expects 1 argument (key), got %d", len(args))
+		}
+		key, err := cacheKey(method, args[0])
+		if err != nil {
+			return nil, err
+		}
+		value, _, err := c.Get(key)
+		if err != nil {
+			return nil, fmt.Errorf("cache.get(): %w", err)
+		}
+		return value, nil
+
+	case "set":
+		if len(args) != 2 && len(args) != 3 {
+			return nil, fmt.Errorf("cache.set() expects 2 or 3 arguments (key, value, ttlSeconds), got %d", len(args))
+		}
+		key, err := cacheKey(method, args[0])
+		if err != nil {
+			return nil, err
+		}
+		var ttl time.Duration
+		if len(args) == 3 {
+			if ttl, err = cacheTTL(method, args[2]); err != nil {
+				return nil, err
+			}
+		}
+		if err := c.Set(key, args[1], ttl); err != nil {
+			return nil, fmt.Errorf("cache.set(): %w", err)
+		}
+		return true, nil
+
+	case "delete":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("cache.delete() expects 1 argument (key), got %d", len(args))
+		}
+		key, err := cacheKey(method, args[0])
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Delete(key); err != nil {
+			return nil, fmt.Errorf("cache.delete(): %w", err)
+		}
+		return true, nil
+
+	case "remember":
+		if len(args) != 3 {
+			return nil, fmt.Errorf("cache.remember() expects 3 arguments (key, ttlSeconds, fn), got %d", len(args))
+		}
+		key, err := cacheKey(method, args[0])
+		if err != nil {
+			return nil, err
+		}
+		ttl, err := cacheTTL(method, args[1])
+		if err != nil {
+			return nil, err
+		}
+		value, err := c.Remember(key, ttl, func() (interface{}, error) {
+			// Functions run in the caller's scope, so they can use its
+			// injections such as db
+			switch fn := args[2].(type) {
+			case Function:
+				return i.executeFunctionWithValues(fn, nil, env)
+			case *Function:
+				return i.executeFunctionWithValues(*fn, nil, env)
+			}
+			return i.callCallable(args[2], nil)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cache.remember(): %w", err)
+		}
+		return value, nil
+
+	default:
+		return nil, fmt.Errorf("cache has no method '%s'", method)
+	}
+}
+
+func cacheKey(method string, arg interface{}) (string, error) {
+	key, ok := arg.(string)
+	if !ok {
+		return "", fmt.Errorf("cache.%s() key must be a string, got %T", method, arg)
+	}
+	return key, nil
+}
+
+func cacheTTL(method string, arg interface{}) (time.Duration, error) {
+	seconds, ok := arg.(int64)
+	if !ok || seconds < 0 {
+		return 0, fmt.Errorf("cache.%s() ttlSeconds must be a non-negative integer, got %v", method, arg)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
@@ -0,0 +1,101 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runCacheRoute loads source, injects a fresh in-memory cache and runs the
+// source's route n times, returning the last result
+func runCacheRoute(t *testing.T, source string, n int) (interface{}, error) {
+	t.Helper()
+	module, err := parseLoaderSource(source)
+	require.NoError(t, err)
+	interp := NewInterpreter()
+	require.NoError(t, interp.LoadModule(*module))
+	c := cache.NewValueCache(cache.NewMemoryStore(10))
+	t.Cleanup(func() { c.Close() })
+	interp.SetCacheHandler(c)
+
+	var route *Route
+	for _, item := range module.Items {
+		if r, ok := item.(*Route); ok {
+			route = r
+		}
+	}
+	require.NotNil(t, route)
+
+	var result interface{}
+	for range n {
+		if result, err = interp.ExecuteRouteSimple(route, map[string]string{}); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func TestCache_GetSetDelete(t *testing.T) {
+	result, err := runCacheRoute(t, `@ GET /test {
+  % cache: Cache
+  $ missing = cache.get("user")
+  $ stored = cache.set("user", {name: "ada", tags: [1, 2.5]}, 60)
+  $ user = cache.get("user")
+  $ deleted = cache.delete("user")
+  > {missing: missing, user: user, after: cache.get("user")}
+}`, 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"missing": nil,
+		"user": map[string]interface{}{
+			"name": "ada",
+			"tags": []interface{}{int64(1), 2.5},
+		},
+		"after": nil,
+	}, result)
+}
+
+func TestCache_Remember(t *testing.T) {
+	calls := 0
+	builtinFuncs["cacheHook"] = func(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+		calls++
+		return int64(calls), nil
+	}
+	t.Cleanup(func() { delete(builtinFuncs, "cacheHook") })
+
+	result, err := runCacheRoute(t, `! load(): object {
+  > {calls: cacheHook()}
+}
+
+@ GET /test {
+  % cache: Cache
+  > cache.remember("loaded", 60, load)
+}`, 3)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"calls": int64(1)}, result)
+	assert.Equal(t, 1, calls)
+}
+
+func TestCache_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		call string
+		want string
+	}{
+		{"key type", `cache.get(1)`, "cache.get() key must be a string, got int64"},
+		{"negative ttl", `cache.set("k", 1, -5)`, "cache.set() ttlSeconds must be a non-negative integer, got -5"},
+		{"argument count", `cache.remember("k", 60)`, "cache.remember() expects 3 arguments (key, ttlSeconds, fn), got 2"},
+		{"unknown method", `cache.flush()`, "cache has no method 'flush'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runCacheRoute(t, "@ GET /test {\n  % cache: Cache\n  > "+tt.call+"\n}", 1)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -262,3 +262,50 @@ func TestCoercion_NoCoercionForString(t *testing.T) {
 	_, err := interp.EvaluateExpression(expr, env)
 	assert.Error(t, err)
 }
+
+func TestIntegerOverflow(t *testing.T) {
+	const maxInt, minInt = int64(9223372036854775807), int64(-9223372036854775808)
+	tests := []struct {
+		name  string
+		left  int64
+		op    BinOp
+		right int64
+		want  string
+	}{
+		{"add", maxInt, Add, 1, "integer overflow: 9223372036854775807 + 1"},
+		{"sub", minInt, Sub, 1, "integer overflow: -9223372036854775808 - 1"},
+		{"mul", maxInt, Mul, 2, "integer overflow: 9223372036854775807 * 2"},
+		{"mul min by -1", -1, Mul, minInt, "integer overflow: -1 * -9223372036854775808"},
+		{"div", minInt, Div, -1, "integer overflow: -9223372036854775808 / -1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp := NewInterpreter()
+			expr := BinaryOpExpr{
+				Left:  LiteralExpr{Value: IntLiteral{Value: tt.left}},
+				Op:    tt.op,
+				Right: LiteralExpr{Value: IntLiteral{Value: tt.right}},
+			}
+			_, err := interp.EvaluateExpression(expr, NewEnvironment())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+
+	// Results at the edge of the range are still ints
+	interp := NewInterpreter()
+	result, err := interp.EvaluateExpression(BinaryOpExpr{
+		Left:  LiteralExpr{Value: IntLiteral{Value: maxInt - 1}},
+		Op:    Add,
+		Right: LiteralExpr{Value: IntLiteral{Value: 1}},
+	}, NewEnvironment())
+	require.NoError(t, err)
+	assert.Equal(t, maxInt, result)
+
+	_, err = interp.EvaluateExpression(UnaryOpExpr{
+		Op:    Neg,
+		Right: LiteralExpr{Value: IntLiteral{Value: minInt}},
+	}, NewEnvironment())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "integer overflow")
+}
@@ -229,3 +229,161 @@ func TestInterpreter_ConstantAccessibleInRoute(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "active", result)
 }
+
+// Test Set refuses to change a constant binding but updates a variable
+func TestEnvironment_SetConstant(t *testing.T) {
+	env := NewEnvironment()
+	env.DefineConst("PI", 3.14)
+	env.Define("count", int64(1))
+
+	child := NewChildEnvironment(env)
+	err := child.Set("PI", 3.0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot reassign constant 'PI'")
+	val, _ := env.Get("PI")
+	assert.Equal(t, 3.14, val)
+
+	require.NoError(t, child.Set("count", int64(2)))
+	val, _ = env.Get("count")
+	assert.Equal(t, int64(2), val)
+
+	assert.True(t, child.IsConstant("PI"))
+	assert.False(t, child.IsConstant("count"))
+
+	// A local variable shadowing a constant is not constant
+	child.Define("PI", 3.0)
+	assert.False(t, child.IsConstant("PI"))
+}
+
+// Test const statements inside a route body
+func TestInterpreter_ConstStatement(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    interface{}
+		wantErr string
+	}{
+		{
+			name: "const can be read",
+			body: "const RATE = 2\n  > RATE * 3",
+			want: int64(6),
+		},
+		{
+			name:    "const cannot be reassigned",
+			body:    "const RATE = 2\n  RATE = 3\n  > RATE",
+			wantErr: "cannot reassign constant 'RATE'",
+		},
+		{
+			name:    "const cannot be updated with $",
+			body:    "const RATE = 2\n  if true {\n    $ RATE = 3\n  }\n  > RATE",
+			wantErr: "cannot reassign constant 'RATE'",
+		},
+		{
+			name: "$ variable can be reassigned",
+			body: "$ rate = 2\n  rate = 3\n  > rate",
+			want: int64(3),
+		},
+		{
+			name: "const in an inner block shadows",
+			body: "$ rate = 2\n  if true {\n    const rate = 5\n  }\n  rate = 3\n  > rate",
+			want: int64(3),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, err := parseLoaderSource("@ GET /x {\n  " + tt.body + "\n}\n")
+			require.NoError(t, err)
+			interp := NewInterpreter()
+			require.NoError(t, interp.LoadModule(*module))
+
+			result, err := interp.ExecuteRouteSimple(module.Items[0].(*Route), nil)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
+
+// Test that a module constant cannot be declared twice
+func TestInterpreter_ConstantCannotBeRedefined(t *testing.T) {
+	module, err := parseLoaderSource("const MAX = 1\nconst MAX = 2\n")
+	require.NoError(t, err)
+
+	err = NewInterpreter().LoadModule(*module)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "constant MAX is already defined")
+}
+
+// Test module constants read and assigned from a route
+func TestInterpreter_ModuleConstantInRoute(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    interface{}
+		wantErr string
+	}{
+		{
+			name: "constants may use earlier ones",
+			body: "> LIMIT",
+			want: int64(200),
+		},
+		{
+			name:    "reassigning is an error",
+			body:    "MAX = 5\n  > MAX",
+			wantErr: "cannot reassign constant 'MAX'",
+		},
+		{
+			name:    "assigning with $ is an error",
+			body:    "$ MAX = 5\n  > MAX",
+			wantErr: "cannot reassign constant 'MAX'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, err := parseLoaderSource("const MAX = 100\nconst LIMIT = MAX * 2\n\n@ GET /x {\n  " + tt.body + "\n}\n")
+			require.NoError(t, err)
+			interp := NewInterpreter()
+			require.NoError(t, interp.LoadModule(*module))
+
+			var route *Route
+			for _, item := range module.Items {
+				if r, ok := item.(*Route); ok {
+					route = r
+				}
+			}
+			result, err := interp.ExecuteRouteSimple(route, nil)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
+
+// Test the values() helper of an enum
+func TestInterpreter_EnumValues(t *testing.T) {
+	module, err := parseLoaderSource(`: Status = "pending" | "shipped" | "delivered"`)
+	require.NoError(t, err)
+	interp := NewInterpreter()
+	require.NoError(t, interp.LoadModule(*module))
+	env := NewChildEnvironment(interp.globalEnv)
+
+	values, err := interp.EvaluateExpression(FunctionCallExpr{Name: "Status.values"}, env)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"pending", "shipped", "delivered"}, values)
+
+	_, err = interp.EvaluateExpression(FunctionCallExpr{Name: "Status.count"}, env)
+	assert.EqualError(t, err, "enum Status has no method count")
+
+	// A variable of the enum's name shadows it
+	env.Define("Status", map[string]interface{}{"values": "shadowed"})
+	_, err = interp.EvaluateExpression(FunctionCallExpr{Name: "Status.values"}, env)
+	assert.Error(t, err)
+}
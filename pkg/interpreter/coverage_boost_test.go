@@ -701,12 +701,19 @@ func TestEvaluateArrayIndexExpr_NegativeIndex(t *testing.T) {
 	arr := []interface{}{int64(10)}
 	env.Define("arr", arr)
 
-	_, err := interp.EvaluateExpression(ArrayIndexExpr{
+	result, err := interp.EvaluateExpression(ArrayIndexExpr{
 		Array: VariableExpr{Name: "arr"},
 		Index: LiteralExpr{Value: IntLiteral{Value: -1}},
 	}, env)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), result)
+
+	_, err = interp.EvaluateExpression(ArrayIndexExpr{
+		Array: VariableExpr{Name: "arr"},
+		Index: LiteralExpr{Value: IntLiteral{Value: -2}},
+	}, env)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "array index out of bounds")
+	assert.Contains(t, err.Error(), "array index out of bounds: -2 (length: 1)")
 }
 
 func TestEvaluateArrayIndexExpr_NonIntIndex(t *testing.T) {
@@ -3,6 +3,7 @@ package interpreter
 import (
 	. "github.com/glyphlang/glyph/pkg/ast"
 
+	"strings"
 	"testing"
 )
 
@@ -298,6 +299,23 @@ func TestLoadModuleWithCronTask(t *testing.T) {
 	}
 }
 
+// TestLoadModuleWithInvalidCronSchedule tests that a bad schedule fails the load
+func TestLoadModuleWithInvalidCronSchedule(t *testing.T) {
+	for _, task := range []*CronTask{
+		{Name: "cleanup", Schedule: "every day"},
+		{Schedule: "61 * * * *"},
+	} {
+		err := NewInterpreter().LoadModule(Module{Items: []Item{task}})
+		if err == nil {
+			t.Errorf("LoadModule with schedule %q should fail", task.Schedule)
+			continue
+		}
+		if want := "cron task " + CronTaskName(task); !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q should contain %q", err, want)
+		}
+	}
+}
+
 // TestLoadModuleWithEventHandler tests loading module with event handler
 func TestLoadModuleWithEventHandler(t *testing.T) {
 	interp := NewInterpreter()
@@ -3,6 +3,7 @@ package interpreter
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 // providerMethods provides per-provider method whitelists for scoped access control.
@@ -14,6 +15,8 @@ var providerMethods = map[string]map[string]bool{
 		"First": true, "All": true, "Where": true, "Count": true, "Save": true,
 		"Insert": true, "Select": true, "Limit": true, "Offset": true, "Order": true,
 		"Filter": true, "Table": true, "CountWhere": true, "NextId": true, "Length": true,
+		"FilterWhere": true, "UpdateWhere": true, "DeleteWhere": true, "OrderBy": true, "Last": true,
+		"With": true, "BelongsTo": true, "FirstOrCreate": true,
 	},
 	"Redis": {
 		"Get": true, "Set": true, "Del": true, "Exists": true, "Expire": true,
@@ -58,26 +61,35 @@ func RegisterProviderMethods(providerType string, methods []string) {
 // allowedMethods is a whitelist of safe methods that can be called via reflection
 var allowedMethods = map[string]bool{
 	// Database/ORM methods
-	"Get":        true,
-	"Find":       true,
-	"Create":     true,
-	"Update":     true,
-	"Delete":     true,
-	"First":      true,
-	"All":        true,
-	"Where":      true,
-	"Count":      true,
-	"Save":       true,
-	"Insert":     true,
-	"Select":     true,
-	"Limit":      true,
-	"Offset":     true,
-	"Order":      true,
-	"Filter":     true,
-	"Table":      true,
-	"CountWhere": true,
-	"NextId":     true,
-	"Length":     true,
+	"Get":         true,
+	"Find":        true,
+	"Create":      true,
+	"Update":      true,
+	"Delete":      true,
+	"First":       true,
+	"All":         true,
+	"Where":       true,
+	"Count":       true,
+	"Save":        true,
+	"Insert":      true,
+	"Select":      true,
+	"Limit":       true,
+	"Offset":      true,
+	"Order":       true,
+	"Filter":      true,
+	"Table":       true,
+	"CountWhere":  true,
+	"NextId":      true,
+	"Length":      true,
+	"FilterWhere": true,
+	"UpdateWhere": true,
+	"DeleteWhere": true,
+	"OrderBy":     true,
+	"Last":        true,
+	"With":        true,
+	"BelongsTo":   true,
+	// Idempotent seeding
+	"FirstOrCreate": true,
 	// Redis methods
 	"Set":       true,
 	"Del":       true,
@@ -127,6 +139,13 @@ var allowedMethods = map[string]bool{
 	"Embed":      true,
 	"ListModels": true,
 	"TokenCount": true,
+	// Mailer methods
+	"Send":   true,
+	"Render": true,
+	// HTTP client methods (Get and Delete are listed above)
+	"Post":  true,
+	"Put":   true,
+	"Patch": true,
 	// Common safe methods
 	"String": true,
 	"Int":    true,
@@ -139,18 +158,22 @@ var allowedMethods = map[string]bool{
 // CallMethod calls a method on an object using reflection
 // Only methods in the allowedMethods whitelist can be called for security
 func CallMethod(obj interface{}, methodName string, args ...interface{}) (interface{}, error) {
-	// Check if the method is in the whitelist
-	if !allowedMethods[methodName] {
-		return nil, fmt.Errorf("method %s is not allowed", methodName)
-	}
-
 	// Get the value and type of the object
 	objValue := reflect.ValueOf(obj)
 	if !objValue.IsValid() {
+		if !allowedMethods[methodName] {
+			return nil, fmt.Errorf("method %s is not allowed", methodName)
+		}
 		return nil, fmt.Errorf("cannot call method %s on null", methodName)
 	}
 	objType := objValue.Type()
 
+	// Check if the method is in the whitelist
+	methodName = resolveMethodName(objType, methodName)
+	if !allowedMethods[methodName] {
+		return nil, fmt.Errorf("method %s is not allowed", methodName)
+	}
+
 	// Find the method
 	method := objValue.MethodByName(methodName)
 	if !method.IsValid() {
@@ -194,10 +217,25 @@ func HasMethod(obj interface{}, methodName string) bool {
 	if !objValue.IsValid() {
 		return false
 	}
-	method := objValue.MethodByName(methodName)
+	method := objValue.MethodByName(resolveMethodName(objValue.Type(), methodName))
 	return method.IsValid()
 }
 
+// resolveMethodName returns the method of t that methodName refers to.
+// Scripts call methods in lower case, so redis.hgetAll and redis.hgetall
+// both find HGetAll; an exact match is preferred.
+func resolveMethodName(t reflect.Type, methodName string) string {
+	if _, ok := t.MethodByName(methodName); ok {
+		return methodName
+	}
+	for i := 0; i < t.NumMethod(); i++ {
+		if name := t.Method(i).Name; strings.EqualFold(name, methodName) {
+			return name
+		}
+	}
+	return methodName
+}
+
 // GetMethodNames returns all method names of an object
 func GetMethodNames(obj interface{}) []string {
 	objValue := reflect.ValueOf(obj)
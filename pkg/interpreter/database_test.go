@@ -162,6 +162,22 @@ func TestCallMethod_WithErrorReturn(t *testing.T) {
 	assert.Contains(t, err.Error(), "test error")
 }
 
+func TestCallMethod_CaseInsensitive(t *testing.T) {
+	obj := TestType{value: "hello"}
+
+	// Method names written in lower or mixed case resolve to the Go method,
+	// as with redis.hget for HGet
+	result, err := CallMethod(obj, "isZero")
+	require.NoError(t, err)
+	assert.Equal(t, false, result)
+	assert.True(t, HasMethod(obj, "iszero"))
+
+	// The whitelist still applies to the resolved name
+	_, err = CallMethod(obj, "dangerousMethod")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not allowed")
+}
+
 func TestHasMethod_SecurityContext(t *testing.T) {
 	obj := TestType{value: "test"}
 
@@ -1,6 +1,7 @@
 package interpreter
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -19,12 +20,13 @@ const (
 	BindingQueryParam
 )
 
-// binding stores a variable's value alongside the source of its binding.
-// Keeping both in a single map entry ensures the value and its source cannot
-// drift out of sync.
+// binding stores a variable's value alongside the source of its binding and
+// whether it may be reassigned. Keeping them in a single map entry ensures
+// they cannot drift out of sync.
 type binding struct {
-	value  interface{}
-	source BindingSource
+	value    interface{}
+	source   BindingSource
+	constant bool
 }
 
 // Environment manages variable scopes and bindings.
@@ -33,6 +35,7 @@ type binding struct {
 type Environment struct {
 	vars   map[string]binding
 	parent *Environment
+	ctx    context.Context // Request context, set on a route's environment
 }
 
 // NewEnvironment creates a new environment
@@ -51,6 +54,32 @@ func NewChildEnvironment(parent *Environment) *Environment {
 	}
 }
 
+// SetContext attaches ctx, typically the request's, to the environment and
+// the scopes nested in it
+func (e *Environment) SetContext(ctx context.Context) {
+	e.ctx = ctx
+}
+
+// Context returns the context attached to the nearest enclosing scope, or
+// context.Background() when there is none
+func (e *Environment) Context() context.Context {
+	if ctx := e.lookupContext(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// lookupContext returns the context attached to the nearest enclosing scope,
+// or nil
+func (e *Environment) lookupContext() context.Context {
+	for env := e; env != nil; env = env.parent {
+		if env.ctx != nil {
+			return env.ctx
+		}
+	}
+	return nil
+}
+
 // Define adds a new variable to the current environment as a user-declared
 // binding. For bindings that originate from the runtime (e.g. path or query
 // parameters), use DefineWithSource so diagnostics can report the origin.
@@ -67,6 +96,13 @@ func (e *Environment) DefineWithSource(name string, value interface{}, source Bi
 	e.vars[name] = binding{value: value, source: source}
 }
 
+// DefineConst adds a constant to the current environment. Set refuses to
+// change it, though a child scope may still declare its own variable of the
+// same name.
+func (e *Environment) DefineConst(name string, value interface{}) {
+	e.vars[name] = binding{value: value, source: BindingUser, constant: true}
+}
+
 // LocalSource returns the BindingSource for a variable defined in the current
 // scope. If the variable is not defined locally, it returns BindingUser and
 // false.
@@ -91,9 +127,12 @@ func (e *Environment) Get(name string) (interface{}, error) {
 }
 
 // Set updates a variable value in the environment or parent scopes.
-// The binding source is preserved.
+// The binding source is preserved. Constants cannot be updated.
 func (e *Environment) Set(name string, value interface{}) error {
 	if b, ok := e.vars[name]; ok {
+		if b.constant {
+			return fmt.Errorf("cannot reassign constant '%s'", name)
+		}
 		b.value = value
 		e.vars[name] = b
 		return nil
@@ -119,6 +158,18 @@ func (e *Environment) Has(name string) bool {
 	return false
 }
 
+// IsConstant reports whether the closest binding of name, in this or a
+// parent scope, is a constant
+func (e *Environment) IsConstant(name string) bool {
+	if b, ok := e.vars[name]; ok {
+		return b.constant
+	}
+	if e.parent != nil {
+		return e.parent.IsConstant(name)
+	}
+	return false
+}
+
 // HasLocal checks if a variable exists in the current scope only (no parent lookup)
 func (e *Environment) HasLocal(name string) bool {
 	_, ok := e.vars[name]
@@ -153,3 +204,45 @@ func (e *Environment) GetLocal() map[string]interface{} {
 	}
 	return result
 }
+
+// snapshot returns a new child of stop holding a copy of the variables
+// visible from e, up to but not including stop, except those named in skip.
+// Objects and arrays are copied too, so later changes made through e do not
+// reach the snapshot.
+func (e *Environment) snapshot(stop *Environment, skip ...string) *Environment {
+	result := NewChildEnvironment(stop)
+	result.ctx = e.lookupContext()
+	for env := e; env != nil && env != stop; env = env.parent {
+		for name, b := range env.vars {
+			if _, shadowed := result.vars[name]; shadowed {
+				continue
+			}
+			b.value = copyValue(b.value)
+			result.vars[name] = b
+		}
+	}
+	for _, name := range skip {
+		delete(result.vars, name)
+	}
+	return result
+}
+
+// copyValue returns a deep copy of the objects and arrays in v
+func copyValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			result[k] = copyValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(val))
+		for n, item := range val {
+			result[n] = copyValue(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
@@ -8,14 +8,41 @@ import (
 	"strings"
 	"sync/atomic"
 	"unicode"
+
+	"github.com/glyphlang/glyph/pkg/cache"
 )
 
+// positionError is an error annotated with the source position it arose at
+type positionError struct {
+	pos Pos
+	err error
+}
+
+func (e *positionError) Error() string {
+	return fmt.Sprintf("at %s: %v", e.pos, e.err)
+}
+
+func (e *positionError) Unwrap() error {
+	return e.err
+}
+
 // posError wraps an error with source position information when available.
 func posError(pos Pos, err error) error {
 	if err == nil || !pos.HasPos() {
 		return err
 	}
-	return fmt.Errorf("at %s: %w", pos, err)
+	return &positionError{pos: pos, err: err}
+}
+
+// errorMessage returns the message of err without its source positions
+func errorMessage(err error) string {
+	for {
+		posErr, ok := err.(*positionError)
+		if !ok {
+			return err.Error()
+		}
+		err = posErr.err
+	}
 }
 
 // capitalizeFirst capitalizes only the first letter of a string, preserving the rest.
@@ -62,6 +89,12 @@ func (i *Interpreter) EvaluateExpression(expr Expr, env *Environment) (interface
 		}
 		return val, nil
 
+	case TernaryExpr:
+		return i.evaluateTernary(e, env)
+
+	case InterpolatedStringExpr:
+		return i.evaluateInterpolatedString(e, env)
+
 	case FieldAccessExpr:
 		val, err := i.evaluateFieldAccess(e, env)
 		if err != nil {
@@ -112,6 +145,46 @@ func (i *Interpreter) EvaluateExpression(expr Expr, env *Environment) (interface
 	}
 }
 
+// evaluateTernary evaluates cond ? then : else, evaluating only the branch
+// the condition selects
+func (i *Interpreter) evaluateTernary(expr TernaryExpr, env *Environment) (interface{}, error) {
+	condition, err := i.EvaluateExpression(expr.Condition, env)
+	if err != nil {
+		return nil, err
+	}
+
+	condBool, ok := condition.(bool)
+	if !ok {
+		return nil, posError(expr.Pos, fmt.Errorf("conditional expression condition must be a boolean, got %T", condition))
+	}
+
+	if condBool {
+		return i.EvaluateExpression(expr.Then, env)
+	}
+	return i.EvaluateExpression(expr.Else, env)
+}
+
+// evaluateInterpolatedString concatenates the parts of "text{expr}...",
+// converting the value of each embedded expression to a string
+func (i *Interpreter) evaluateInterpolatedString(expr InterpolatedStringExpr, env *Environment) (interface{}, error) {
+	var sb strings.Builder
+	for _, part := range expr.Parts {
+		val, err := i.EvaluateExpression(part, env)
+		if err != nil {
+			return nil, err
+		}
+		switch v := val.(type) {
+		case string:
+			sb.WriteString(v)
+		case nil:
+			sb.WriteString("null")
+		default:
+			fmt.Fprintf(&sb, "%v", v)
+		}
+	}
+	return sb.String(), nil
+}
+
 // evaluateAsyncExpr executes an async block in a goroutine and returns a Future
 func (i *Interpreter) evaluateAsyncExpr(expr AsyncExpr, env *Environment) (interface{}, error) {
 	// Create a new Future to represent the pending result
@@ -201,12 +274,16 @@ func (i *Interpreter) evaluateArrayIndexExpr(expr ArrayIndexExpr, env *Environme
 			return nil, fmt.Errorf("array index must be an integer, got %T", indexVal)
 		}
 
-		// Check bounds
-		if index < 0 || int(index) >= len(arr) {
+		// Negative indices count from the end: arr[-1] is the last element
+		pos := index
+		if pos < 0 {
+			pos += int64(len(arr))
+		}
+		if pos < 0 || pos >= int64(len(arr)) {
 			return nil, fmt.Errorf("array index out of bounds: %d (length: %d)", index, len(arr))
 		}
 
-		return arr[index], nil
+		return arr[pos], nil
 	}
 
 	// Handle map/object indexing with string key
@@ -289,6 +366,13 @@ func (i *Interpreter) evaluateBinaryOp(expr BinaryOpExpr, env *Environment) (int
 		}
 		return rightBool, nil
 	}
+	if expr.Op == Coalesce {
+		left, err := i.EvaluateExpression(expr.Left, env)
+		if err != nil || left != nil {
+			return left, err
+		}
+		return i.EvaluateExpression(expr.Right, env)
+	}
 
 	left, err := i.EvaluateExpression(expr.Left, env)
 	if err != nil {
@@ -346,6 +430,9 @@ func (i *Interpreter) evaluateUnaryOp(expr UnaryOpExpr, env *Environment) (inter
 	case Neg:
 		switch v := right.(type) {
 		case int64:
+			if v == math.MinInt64 {
+				return nil, fmt.Errorf("integer overflow: -(%d)", v)
+			}
 			return -v, nil
 		case float64:
 			return -v, nil
@@ -386,7 +473,11 @@ func (i *Interpreter) evaluateAdd(left, right interface{}) (interface{}, error)
 	// Integer addition
 	if leftInt, ok := coercedLeft.(int64); ok {
 		if rightInt, ok := coercedRight.(int64); ok {
-			return leftInt + rightInt, nil
+			sum := leftInt + rightInt
+			if (leftInt >= 0) == (rightInt >= 0) && (sum >= 0) != (leftInt >= 0) {
+				return nil, fmt.Errorf("integer overflow: %d + %d", leftInt, rightInt)
+			}
+			return sum, nil
 		}
 	}
 
@@ -408,7 +499,11 @@ func (i *Interpreter) evaluateSub(left, right interface{}) (interface{}, error)
 	// Integer subtraction
 	if leftInt, ok := coercedLeft.(int64); ok {
 		if rightInt, ok := coercedRight.(int64); ok {
-			return leftInt - rightInt, nil
+			diff := leftInt - rightInt
+			if (leftInt >= 0) != (rightInt >= 0) && (diff >= 0) != (leftInt >= 0) {
+				return nil, fmt.Errorf("integer overflow: %d - %d", leftInt, rightInt)
+			}
+			return diff, nil
 		}
 	}
 
@@ -430,7 +525,11 @@ func (i *Interpreter) evaluateMul(left, right interface{}) (interface{}, error)
 	// Integer multiplication
 	if leftInt, ok := coercedLeft.(int64); ok {
 		if rightInt, ok := coercedRight.(int64); ok {
-			return leftInt * rightInt, nil
+			product := leftInt * rightInt
+			if leftInt != 0 && (product/leftInt != rightInt || (leftInt == -1 && rightInt == math.MinInt64)) {
+				return nil, fmt.Errorf("integer overflow: %d * %d", leftInt, rightInt)
+			}
+			return product, nil
 		}
 	}
 
@@ -455,6 +554,9 @@ func (i *Interpreter) evaluateDiv(left, right interface{}) (interface{}, error)
 			if rightInt == 0 {
 				return nil, fmt.Errorf("division by zero")
 			}
+			if leftInt == math.MinInt64 && rightInt == -1 {
+				return nil, fmt.Errorf("integer overflow: %d / %d", leftInt, rightInt)
+			}
 			return leftInt / rightInt, nil
 		}
 	}
@@ -623,7 +725,11 @@ func (i *Interpreter) evaluateFieldAccess(expr FieldAccessExpr, env *Environment
 
 	// Short-circuit on null before any reflection-based access to give a
 	// clean Glyph-level error instead of a Go panic or an opaque reflection error.
+	// Optional access (obj?.field) gives null instead.
 	if obj == nil {
+		if expr.Optional {
+			return nil, nil
+		}
 		return nil, fmt.Errorf("cannot access field %s on null", expr.Field)
 	}
 
@@ -666,10 +772,29 @@ func (i *Interpreter) evaluateFieldAccess(expr FieldAccessExpr, env *Environment
 	return nil, fmt.Errorf("cannot access field %s on %T", expr.Field, obj)
 }
 
+// enumMethod calls a method of an enum with argc arguments: values(), a
+// new array of the enum's values in declaration order
+func enumMethod(enumDef EnumDef, method string, argc int) (interface{}, error) {
+	if method != "values" {
+		return nil, fmt.Errorf("enum %s has no method %s", enumDef.Name, method)
+	}
+	if argc > 0 {
+		return nil, fmt.Errorf("%s.values() takes no arguments", enumDef.Name)
+	}
+	values := make([]interface{}, len(enumDef.Values))
+	for idx, value := range enumDef.Values {
+		values[idx] = value
+	}
+	return values, nil
+}
+
 // evaluateFunctionCall handles function calls and method calls
 func (i *Interpreter) evaluateFunctionCall(expr FunctionCallExpr, env *Environment) (interface{}, error) {
 	// Handle built-in functions via dispatch table
 	if fn, ok := builtinFuncs[expr.Name]; ok {
+		if len(expr.NamedArgs) > 0 {
+			return nil, fmt.Errorf("built-in function %s does not take named arguments", expr.Name)
+		}
 		return fn(i, expr.Args, env)
 	}
 
@@ -680,9 +805,13 @@ func (i *Interpreter) evaluateFunctionCall(expr FunctionCallExpr, env *Environme
 		objName := parts[0]
 		methodPath := parts[1]
 
-		// Get the object
+		// Get the object. An enum's name not shadowed by a variable
+		// calls one of its methods.
 		obj, err := env.Get(objName)
 		if err != nil {
+			if enumDef, ok := i.enumDefs[objName]; ok {
+				return enumMethod(enumDef, methodPath, len(expr.Args)+len(expr.NamedArgs))
+			}
 			return nil, fmt.Errorf("undefined object: %s", objName)
 		}
 
@@ -709,6 +838,16 @@ func (i *Interpreter) evaluateFunctionCall(expr FunctionCallExpr, env *Environme
 		// Now we have the final object and method name
 		methodName := methodPath
 
+		// Only the functions of a module namespace take named arguments
+		if len(expr.NamedArgs) > 0 {
+			objMap, _ := obj.(map[string]interface{})
+			switch objMap[methodName].(type) {
+			case Function, *Function, *LambdaClosure:
+			default:
+				return nil, fmt.Errorf("method %s does not take named arguments", expr.Name)
+			}
+		}
+
 		// Evaluate arguments
 		args := make([]interface{}, len(expr.Args))
 		for idx, arg := range expr.Args {
@@ -731,15 +870,23 @@ func (i *Interpreter) evaluateFunctionCall(expr FunctionCallExpr, env *Environme
 			return i.evaluateResultMethod(result, methodName, args, env)
 		}
 
+		// Handle injected cache methods (cache.get, cache.remember, etc.)
+		if c, ok := obj.(*cache.ValueCache); ok {
+			return i.evaluateCacheMethod(c, methodName, args, env)
+		}
+
 		// Check if obj is a map (module namespace) and methodName is a function
 		if objMap, ok := obj.(map[string]interface{}); ok {
 			if fn, exists := objMap[methodName]; exists {
 				// If it's a Function, execute it
 				if fnDef, ok := fn.(*Function); ok {
-					return i.executeFunction(*fnDef, expr.Args, env)
+					return i.executeFunctionCall(*fnDef, expr.Args, expr.NamedArgs, env)
 				}
 				if fnDef, ok := fn.(Function); ok {
-					return i.executeFunction(fnDef, expr.Args, env)
+					return i.executeFunctionCall(fnDef, expr.Args, expr.NamedArgs, env)
+				}
+				if closure, ok := fn.(*LambdaClosure); ok {
+					return i.callLambdaClosure(closure, args)
 				}
 				// If it's something else callable, continue to reflection
 			}
@@ -756,7 +903,7 @@ func (i *Interpreter) evaluateFunctionCall(expr FunctionCallExpr, env *Environme
 	if err != nil {
 		// Function not found - check if first arg is an object with this method
 		// This handles the parser's transformation of obj.method() -> method(obj)
-		if len(expr.Args) > 0 {
+		if len(expr.Args) > 0 && len(expr.NamedArgs) == 0 {
 			firstArg, evalErr := i.EvaluateExpression(expr.Args[0], env)
 			if evalErr == nil && firstArg != nil {
 				methodName := capitalizeFirst(expr.Name)
@@ -781,9 +928,28 @@ func (i *Interpreter) evaluateFunctionCall(expr FunctionCallExpr, env *Environme
 	if fnDef, ok := fn.(Function); ok {
 		// Check if this is a generic function
 		if len(fnDef.TypeParams) > 0 {
+			if len(expr.NamedArgs) > 0 {
+				return nil, fmt.Errorf("generic function %s does not take named arguments", expr.Name)
+			}
 			return i.executeGenericFunction(fnDef, expr.TypeArgs, expr.Args, env)
 		}
-		return i.executeFunction(fnDef, expr.Args, env)
+		return i.executeFunctionCall(fnDef, expr.Args, expr.NamedArgs, env)
+	}
+
+	// A lambda stored in a variable
+	if closure, ok := fn.(*LambdaClosure); ok {
+		if len(expr.NamedArgs) > 0 {
+			return nil, fmt.Errorf("lambda %s does not take named arguments", expr.Name)
+		}
+		args := make([]interface{}, len(expr.Args))
+		for idx, arg := range expr.Args {
+			val, err := i.EvaluateExpression(arg, env)
+			if err != nil {
+				return nil, err
+			}
+			args[idx] = val
+		}
+		return i.callLambdaClosure(closure, args)
 	}
 
 	return nil, fmt.Errorf("not a function: %s", expr.Name)
@@ -794,6 +960,22 @@ func (i *Interpreter) evaluateObjectExpr(expr ObjectExpr, env *Environment) (int
 	obj := make(map[string]interface{})
 
 	for _, field := range expr.Fields {
+		// Copy the fields of a spread object; later fields override them
+		if spread, ok := field.Value.(SpreadExpr); ok {
+			value, err := i.EvaluateExpression(spread.Value, env)
+			if err != nil {
+				return nil, err
+			}
+			fields, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, posError(spread.Pos, fmt.Errorf("cannot spread %T into an object", value))
+			}
+			for k, v := range fields {
+				obj[k] = v
+			}
+			continue
+		}
+
 		// Evaluate the field value expression
 		value, err := i.EvaluateExpression(field.Value, env)
 		if err != nil {
@@ -810,6 +992,20 @@ func (i *Interpreter) evaluateArrayExpr(expr ArrayExpr, env *Environment) (inter
 	arr := make([]interface{}, 0, len(expr.Elements))
 
 	for _, elem := range expr.Elements {
+		// Append the elements of a spread array
+		if spread, ok := elem.(SpreadExpr); ok {
+			value, err := i.EvaluateExpression(spread.Value, env)
+			if err != nil {
+				return nil, err
+			}
+			elements, ok := value.([]interface{})
+			if !ok {
+				return nil, posError(spread.Pos, fmt.Errorf("cannot spread %T into an array", value))
+			}
+			arr = append(arr, elements...)
+			continue
+		}
+
 		// Evaluate each element expression
 		value, err := i.EvaluateExpression(elem, env)
 		if err != nil {
@@ -847,23 +1043,21 @@ func (i *Interpreter) ApplyTypeDefaults(obj map[string]interface{}, typeDef Type
 
 // executeFunction executes a user-defined function
 func (i *Interpreter) executeFunction(fn Function, args []Expr, env *Environment) (interface{}, error) {
+	return i.executeFunctionCall(fn, args, nil, env)
+}
+
+// executeFunctionCall executes a user-defined function called with
+// positional and named arguments. Arguments are evaluated in env in
+// parameter order; defaults are evaluated in the function's environment, so
+// they can refer to earlier parameters.
+func (i *Interpreter) executeFunctionCall(fn Function, args []Expr, named []NamedArg, env *Environment) (interface{}, error) {
 	// Create a new environment for the function
 	fnEnv := NewChildEnvironment(env)
 
-	// Count required parameters (those marked required without defaults)
-	requiredCount := 0
-	for _, param := range fn.Params {
-		if param.Required && param.Default == nil {
-			requiredCount++
-		}
-	}
-
-	// Validate argument count
-	if len(args) < requiredCount {
-		return nil, fmt.Errorf("function %s expects at least %d arguments, got %d", fn.Name, requiredCount, len(args))
-	}
-	if len(args) > len(fn.Params) {
-		return nil, fmt.Errorf("function %s expects at most %d arguments, got %d", fn.Name, len(fn.Params), len(args))
+	// Match arguments to parameters, checking their count and names
+	bound, err := fn.BindArgs(args, named)
+	if err != nil {
+		return nil, err
 	}
 
 	// Evaluate arguments and bind to parameters
@@ -871,9 +1065,9 @@ func (i *Interpreter) executeFunction(fn Function, args []Expr, env *Environment
 		var argVal interface{}
 		var err error
 
-		if idx < len(args) {
+		if bound[idx] != nil {
 			// Argument was provided
-			argVal, err = i.EvaluateExpression(args[idx], env)
+			argVal, err = i.EvaluateExpression(bound[idx], env)
 			if err != nil {
 				return nil, err
 			}
@@ -883,12 +1077,8 @@ func (i *Interpreter) executeFunction(fn Function, args []Expr, env *Environment
 			if err != nil {
 				return nil, fmt.Errorf("error evaluating default for parameter %s: %v", param.Name, err)
 			}
-		} else if !param.Required {
-			// Optional parameter without default gets nil
-			argVal = nil
-		} else {
-			return nil, fmt.Errorf("missing required argument %s in function %s", param.Name, fn.Name)
 		}
+		// Optional parameters without a default get nil
 
 		// Auto-coerce float64 to int64 when parameter expects int.
 		// JSON numbers always arrive as float64 from HTTP request bodies,
@@ -1130,6 +1320,9 @@ func (i *Interpreter) matchPattern(pattern Pattern, value interface{}, env *Envi
 	case ArrayPattern:
 		return i.matchArrayPattern(p, value, env)
 
+	case TypePattern:
+		return i.matchTypePattern(p, value, env)
+
 	default:
 		return false, fmt.Errorf("unsupported pattern type: %T", pattern)
 	}
@@ -1208,6 +1401,26 @@ func (i *Interpreter) matchObjectPattern(pattern ObjectPattern, value interface{
 	return true, nil
 }
 
+// matchTypePattern matches a value of the pattern's named type, then
+// destructures its fields. Union variants are told apart structurally, as
+// for route return types: an object is a value of a type when it has the
+// type's required fields with the declared types.
+func (i *Interpreter) matchTypePattern(pattern TypePattern, value interface{}, env *Environment) (bool, error) {
+	_, isType := i.typeDefs[pattern.TypeName]
+	_, isEnum := i.enumDefs[pattern.TypeName]
+	if !isType && !isEnum {
+		return false, fmt.Errorf("unknown type '%s' in match pattern", pattern.TypeName)
+	}
+	if !i.typeChecker.matchesVariant(value, NamedType{Name: pattern.TypeName}) {
+		return false, nil
+	}
+	if len(pattern.Fields) == 0 {
+		// Enum values are strings, so there is nothing to destructure
+		return true, nil
+	}
+	return i.matchObjectPattern(ObjectPattern{Fields: pattern.Fields}, value, env)
+}
+
 // matchArrayPattern matches a value against an array destructuring pattern
 func (i *Interpreter) matchArrayPattern(pattern ArrayPattern, value interface{}, env *Environment) (bool, error) {
 	// Value must be a slice
@@ -1355,18 +1568,7 @@ func (i *Interpreter) executeFunctionWithValues(fn Function, argVals []interface
 	// Create a new environment for the function
 	fnEnv := NewChildEnvironment(env)
 
-	// Count required parameters (those marked required without defaults)
-	requiredCount := 0
-	for _, param := range fn.Params {
-		if param.Required && param.Default == nil {
-			requiredCount++
-		}
-	}
-
-	// Validate argument count
-	if len(argVals) < requiredCount {
-		return nil, fmt.Errorf("function %s expects at least %d arguments, got %d", fn.Name, requiredCount, len(argVals))
-	}
+	// Validate argument count; a missing required argument is named below
 	if len(argVals) > len(fn.Params) {
 		return nil, fmt.Errorf("function %s expects at most %d arguments, got %d", fn.Name, len(fn.Params), len(argVals))
 	}
@@ -1423,8 +1625,14 @@ func (i *Interpreter) executeFunctionWithValues(fn Function, argVals []interface
 	return result, nil
 }
 
-// callLambdaClosure executes a lambda closure with the given arguments
+// callLambdaClosure executes a lambda closure with the given arguments. The
+// lambda runs in a child of the environment it was defined in, so it reads
+// and reassigns the variables it captured, not copies of them.
 func (i *Interpreter) callLambdaClosure(closure *LambdaClosure, args []interface{}) (interface{}, error) {
+	if len(args) > len(closure.Lambda.Params) {
+		return nil, fmt.Errorf("lambda expects at most %d arguments, got %d", len(closure.Lambda.Params), len(args))
+	}
+
 	// Create a new environment for the lambda execution
 	lambdaEnv := NewChildEnvironment(closure.Env)
 
@@ -0,0 +1,229 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// EventQueueSize is the number of events of one type an EventBus holds
+// before EmitEvent reports the queue full
+const EventQueueSize = 1024
+
+// EventError is the failure of one @ event handler
+type EventError struct {
+	EventType string
+	Err       error
+}
+
+func (e *EventError) Error() string {
+	return fmt.Sprintf("event %q handler failed: %v", e.EventType, e.Err)
+}
+
+func (e *EventError) Unwrap() error {
+	return e.Err
+}
+
+// EventBus delivers the events emitted by an interpreter to its @ event
+// handlers in the background, so EmitEvent returns without waiting for them.
+// Each event type has its own queue and goroutine: events of one type are
+// handled one at a time in emit order, and a slow type does not hold up the
+// others.
+type EventBus struct {
+	interp *Interpreter
+	errs   chan<- error
+
+	mu     sync.Mutex
+	queues map[string]chan interface{}
+	closed bool
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewEventBus makes interp deliver events asynchronously until the bus is
+// closed. Only event types with handlers are queued, so the bus runs at most
+// one goroutine per declared event type. Handler errors are logged and, if
+// errs is non-nil, also sent to errs as *EventError when it has room.
+func NewEventBus(interp *Interpreter, errs chan<- error) *EventBus {
+	b := &EventBus{
+		interp: interp,
+		errs:   errs,
+		queues: make(map[string]chan interface{}),
+		done:   make(chan struct{}),
+	}
+	interp.eventBus.Store(b)
+	return b
+}
+
+// Emit queues an event for delivery to the handlers of its type
+func (b *EventBus) Emit(eventType string, eventData interface{}) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return fmt.Errorf("event bus is closed")
+	}
+	events, ok := b.queues[eventType]
+	if !ok && len(b.interp.eventHandlersFor(eventType)) == 0 {
+		return nil
+	}
+	if !ok {
+		events = make(chan interface{}, EventQueueSize)
+		b.queues[eventType] = events
+		b.wg.Add(1)
+		go b.deliver(eventType, events)
+	}
+	select {
+	case events <- eventData:
+		return nil
+	default:
+		return fmt.Errorf("event queue %q is full (%d events)", eventType, EventQueueSize)
+	}
+}
+
+// deliver runs the handlers for each queued event of one type
+func (b *EventBus) deliver(eventType string, events <-chan interface{}) {
+	defer b.wg.Done()
+	for eventData := range events {
+		for _, handler := range b.interp.eventHandlersFor(eventType) {
+			if err := b.interp.runEventHandler(&handler, eventData); err != nil {
+				b.report(&EventError{EventType: eventType, Err: err})
+			}
+		}
+	}
+}
+
+// report logs a handler error and passes it to the error channel
+func (b *EventBus) report(err *EventError) {
+	log.Printf("[EVENT] %v", err)
+	if b.errs == nil {
+		return
+	}
+	select {
+	case b.errs <- err:
+	default:
+	}
+}
+
+// Close stops accepting events, waits for the queued ones to be handled and
+// returns the interpreter to synchronous delivery. It is safe to call more
+// than once, and does nothing on a nil EventBus.
+func (b *EventBus) Close() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	close(b.done)
+	for _, events := range b.queues {
+		close(events)
+	}
+	b.mu.Unlock()
+
+	b.interp.eventBus.CompareAndSwap(b, nil)
+	b.wg.Wait()
+}
+
+// Done returns a channel that is closed when the bus is closed
+func (b *EventBus) Done() <-chan struct{} {
+	return b.done
+}
+
+// eventHandlersFor returns a copy of the handlers for an event type
+func (i *Interpreter) eventHandlersFor(eventType string) []EventHandler {
+	i.eventMu.RLock()
+	defer i.eventMu.RUnlock()
+	handlers := make([]EventHandler, len(i.eventHandlers[eventType]))
+	copy(handlers, i.eventHandlers[eventType])
+	return handlers
+}
+
+// runEventHandler executes a handler, converting a panic into an error so a
+// failing handler cannot stop the server
+func (i *Interpreter) runEventHandler(handler *EventHandler, eventData interface{}) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	_, err = i.ExecuteEventHandler(handler, eventData)
+	return err
+}
+
+// EmitEvent triggers all handlers for a given event type. While an EventBus
+// is attached the event is queued and handled in the background; otherwise
+// the handlers run before EmitEvent returns, except async ones, which run in
+// a goroutine of their own. A failing handler does not stop the others:
+// their errors are logged and returned together.
+func (i *Interpreter) EmitEvent(eventType string, eventData interface{}) error {
+	if bus := i.eventBus.Load(); bus != nil {
+		return bus.Emit(eventType, eventData)
+	}
+
+	var errs []error
+	for _, handler := range i.eventHandlersFor(eventType) {
+		if handler.Async {
+			go func(h EventHandler) {
+				if err := i.runEventHandler(&h, eventData); err != nil {
+					log.Printf("[EVENT] %v", &EventError{EventType: eventType, Err: err})
+				}
+			}(handler)
+			continue
+		}
+		if err := i.runEventHandler(&handler, eventData); err != nil {
+			eventErr := &EventError{EventType: eventType, Err: err}
+			log.Printf("[EVENT] %v", eventErr)
+			errs = append(errs, eventErr)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// EmitEventSync runs every handler for an event type, async ones included,
+// in declaration order before returning, whether or not an EventBus is
+// attached. It is meant for tests that need deterministic delivery. Handler
+// errors are logged and returned together.
+func (i *Interpreter) EmitEventSync(eventType string, eventData interface{}) error {
+	var errs []error
+	for _, handler := range i.eventHandlersFor(eventType) {
+		if err := i.runEventHandler(&handler, eventData); err != nil {
+			eventErr := &EventError{EventType: eventType, Err: err}
+			log.Printf("[EVENT] %v", eventErr)
+			errs = append(errs, eventErr)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// builtinEmit implements emit(eventType, data). Handler errors are logged
+// rather than failing the caller; emit fails only if the event cannot be
+// queued.
+func builtinEmit(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("emit() expects 2 arguments (event, data), got %d", len(args))
+	}
+	typeVal, err := i.EvaluateExpression(args[0], env)
+	if err != nil {
+		return nil, err
+	}
+	eventType, ok := typeVal.(string)
+	if !ok {
+		return nil, fmt.Errorf("emit() event type must be a string, got %T", typeVal)
+	}
+	eventData, err := i.EvaluateExpression(args[1], env)
+	if err != nil {
+		return nil, err
+	}
+
+	var eventErr *EventError
+	if err := i.EmitEvent(eventType, eventData); err != nil && !errors.As(err, &eventErr) {
+		return nil, fmt.Errorf("emit(): %w", err)
+	}
+	return true, nil
+}
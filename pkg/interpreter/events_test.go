@@ -0,0 +1,298 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withEventHook registers an eventHook(name, event) builtin that calls hook,
+// so tests can observe and control handler executions
+func withEventHook(t *testing.T, hook func(name string, event interface{}) error) {
+	t.Helper()
+	builtinFuncs["eventHook"] = func(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+		name, err := i.EvaluateExpression(args[0], env)
+		if err != nil {
+			return nil, err
+		}
+		event, err := i.EvaluateExpression(args[1], env)
+		if err != nil {
+			return nil, err
+		}
+		return nil, hook(name.(string), event)
+	}
+	t.Cleanup(func() { delete(builtinFuncs, "eventHook") })
+}
+
+func loadEventHandlers(t *testing.T, source string) *Interpreter {
+	t.Helper()
+	module, err := parseLoaderSource(source)
+	require.NoError(t, err)
+	interp := NewInterpreter()
+	require.NoError(t, interp.LoadModule(*module))
+	return interp
+}
+
+const eventHandlersSource = `~ "user.created" {
+  $ r = eventHook("first", event)
+}
+
+~ "user.created" {
+  $ r = eventHook("second", event)
+}
+`
+
+func TestEmitEvent_SyncHandlerFailureIsolated(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	withEventHook(t, func(name string, event interface{}) error {
+		mu.Lock()
+		calls = append(calls, name)
+		mu.Unlock()
+		if name == "first" {
+			return errors.New("smtp unavailable")
+		}
+		return nil
+	})
+	interp := loadEventHandlers(t, eventHandlersSource)
+
+	err := interp.EmitEvent("user.created", int64(1))
+	var eventErr *EventError
+	require.ErrorAs(t, err, &eventErr)
+	assert.Equal(t, "user.created", eventErr.EventType)
+	assert.ErrorContains(t, err, "smtp unavailable")
+	assert.Equal(t, []string{"first", "second"}, calls, "the second handler still runs")
+}
+
+func TestEventBus_OrderWithinType(t *testing.T) {
+	const events = 50
+	var mu sync.Mutex
+	var seen []interface{}
+	withEventHook(t, func(name string, event interface{}) error {
+		if name == "first" {
+			// Slow handling must not let later events overtake this one
+			time.Sleep(100 * time.Microsecond)
+			mu.Lock()
+			seen = append(seen, event)
+			mu.Unlock()
+		}
+		return nil
+	})
+	interp := loadEventHandlers(t, eventHandlersSource)
+	bus := NewEventBus(interp, nil)
+
+	for n := 0; n < events; n++ {
+		require.NoError(t, interp.EmitEvent("user.created", int64(n)))
+	}
+	bus.Close()
+
+	require.Len(t, seen, events)
+	for n, event := range seen {
+		assert.Equal(t, int64(n), event)
+	}
+}
+
+func TestEventBus_EmitDoesNotWait(t *testing.T) {
+	release := make(chan struct{})
+	handled := make(chan string, 2)
+	withEventHook(t, func(name string, event interface{}) error {
+		if event == "slow" {
+			<-release
+		}
+		handled <- event.(string)
+		return nil
+	})
+	interp := loadEventHandlers(t, `~ "email.send" {
+  $ r = eventHook("email", event)
+}
+
+~ "audit.log" {
+  $ r = eventHook("audit", event)
+}
+`)
+	bus := NewEventBus(interp, nil)
+	defer bus.Close()
+
+	require.NoError(t, interp.EmitEvent("email.send", "slow"))
+	require.NoError(t, interp.EmitEvent("audit.log", "fast"))
+	select {
+	case event := <-handled:
+		assert.Equal(t, "fast", event, "another event type is not held up")
+	case <-time.After(5 * time.Second):
+		t.Fatal("audit.log was not handled")
+	}
+	close(release)
+	assert.Equal(t, "slow", <-handled)
+}
+
+func TestEventBus_HandlerFailureIsolated(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	withEventHook(t, func(name string, event interface{}) error {
+		mu.Lock()
+		calls = append(calls, name)
+		mu.Unlock()
+		if name == "first" {
+			return errors.New("smtp unavailable")
+		}
+		return nil
+	})
+	interp := loadEventHandlers(t, eventHandlersSource)
+	errs := make(chan error, 10)
+	bus := NewEventBus(interp, errs)
+
+	require.NoError(t, interp.EmitEvent("user.created", int64(1)), "async delivery reports handler errors on the channel")
+	require.NoError(t, interp.EmitEvent("user.created", int64(2)))
+	bus.Close()
+
+	assert.Equal(t, []string{"first", "second", "first", "second"}, calls)
+	require.Len(t, errs, 2)
+	var eventErr *EventError
+	require.ErrorAs(t, <-errs, &eventErr)
+	assert.Equal(t, "user.created", eventErr.EventType)
+	assert.ErrorContains(t, eventErr.Err, "smtp unavailable")
+}
+
+func TestEventBus_Close(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	withEventHook(t, func(name string, event interface{}) error {
+		mu.Lock()
+		calls = append(calls, name)
+		mu.Unlock()
+		return nil
+	})
+	interp := loadEventHandlers(t, eventHandlersSource)
+	bus := NewEventBus(interp, nil)
+	bus.Close()
+	bus.Close()
+
+	// Once the bus is closed, events are delivered synchronously again
+	require.NoError(t, interp.EmitEvent("user.created", int64(1)))
+	assert.Equal(t, []string{"first", "second"}, calls)
+	assert.ErrorContains(t, bus.Emit("user.created", int64(2)), "event bus is closed")
+}
+
+func TestEmitBuiltin(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	withEventHook(t, func(name string, event interface{}) error {
+		mu.Lock()
+		calls = append(calls, name)
+		mu.Unlock()
+		if name == "first" {
+			return errors.New("smtp unavailable")
+		}
+		return nil
+	})
+	interp := loadEventHandlers(t, eventHandlersSource)
+
+	result, err := interp.EvaluateExpression(FunctionCallExpr{
+		Name: "emit",
+		Args: []Expr{
+			LiteralExpr{Value: StringLiteral{Value: "user.created"}},
+			ObjectExpr{Fields: []ObjectField{{Key: "id", Value: LiteralExpr{Value: IntLiteral{Value: 7}}}}},
+		},
+	}, NewEnvironment())
+	require.NoError(t, err, "handler errors are logged, not returned to the route")
+	assert.Equal(t, true, result)
+	assert.Equal(t, []string{"first", "second"}, calls)
+
+	_, err = interp.EvaluateExpression(FunctionCallExpr{
+		Name: "emit",
+		Args: []Expr{LiteralExpr{Value: IntLiteral{Value: 1}}, LiteralExpr{Value: IntLiteral{Value: 1}}},
+	}, NewEnvironment())
+	assert.ErrorContains(t, err, "emit() event type must be a string")
+}
+
+func TestEmitBuiltin_ClosedBus(t *testing.T) {
+	withEventHook(t, func(name string, event interface{}) error { return nil })
+	interp := loadEventHandlers(t, eventHandlersSource)
+	bus := NewEventBus(interp, nil)
+	defer bus.Close()
+	bus.mu.Lock()
+	bus.closed = true
+	bus.mu.Unlock()
+
+	_, err := interp.EvaluateExpression(FunctionCallExpr{
+		Name: "emit",
+		Args: []Expr{LiteralExpr{Value: StringLiteral{Value: "user.created"}}, LiteralExpr{Value: IntLiteral{Value: 1}}},
+	}, NewEnvironment())
+	assert.ErrorContains(t, err, "emit(): event bus is closed")
+}
+
+func TestEmitEventSync(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	withEventHook(t, func(name string, event interface{}) error {
+		mu.Lock()
+		calls = append(calls, name)
+		mu.Unlock()
+		if name == "second" {
+			panic("handler bug")
+		}
+		return nil
+	})
+	interp := loadEventHandlers(t, `~ "user.created" async {
+  $ r = eventHook("first", event)
+}
+
+~ "user.created" {
+  $ r = eventHook("second", event)
+}
+
+~ "user.created" {
+  $ r = eventHook("third", event)
+}
+`)
+	bus := NewEventBus(interp, nil)
+	defer bus.Close()
+
+	err := interp.EmitEventSync("user.created", int64(1))
+	assert.Equal(t, []string{"first", "second", "third"}, calls, "async handlers run in order too, bypassing the bus")
+	var eventErr *EventError
+	require.ErrorAs(t, err, &eventErr)
+	assert.Equal(t, "user.created", eventErr.EventType)
+	assert.ErrorContains(t, err, "panic: handler bug")
+}
+
+func TestEventBus_HandlerPanicIsolated(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	withEventHook(t, func(name string, event interface{}) error {
+		mu.Lock()
+		calls = append(calls, name)
+		mu.Unlock()
+		if name == "first" {
+			panic("handler bug")
+		}
+		return nil
+	})
+	interp := loadEventHandlers(t, eventHandlersSource)
+	errs := make(chan error, 10)
+	bus := NewEventBus(interp, errs)
+
+	require.NoError(t, interp.EmitEvent("user.created", int64(1)))
+	bus.Close()
+
+	assert.Equal(t, []string{"first", "second"}, calls)
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, <-errs, "panic: handler bug")
+}
+
+func TestEventBus_SkipsTypesWithoutHandlers(t *testing.T) {
+	interp := loadEventHandlers(t, eventHandlersSource)
+	bus := NewEventBus(interp, nil)
+	defer bus.Close()
+
+	require.NoError(t, bus.Emit("order.shipped", int64(1)))
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	assert.Empty(t, bus.queues, "no queue or goroutine for an event type without handlers")
+}
@@ -3,6 +3,7 @@ package interpreter
 import (
 	. "github.com/glyphlang/glyph/pkg/ast"
 
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -103,9 +104,15 @@ func (i *Interpreter) ExecuteStatement(stmt Statement, env *Environment) (interf
 	case YieldStatement:
 		return i.executeYield(s, env)
 
+	case BackgroundStatement:
+		return i.executeBackground(s, env)
+
 	case AssertStatement:
 		return i.executeAssert(s, env)
 
+	case TryStatement:
+		return i.executeTry(s, env)
+
 	case IndexAssignStatement:
 		return i.executeIndexAssign(s, env)
 
@@ -172,12 +179,23 @@ func (i *Interpreter) executeAssign(stmt AssignStatement, env *Environment) (int
 	if err != nil {
 		return nil, err
 	}
+	if stmt.Const && stmt.Type != nil {
+		if err := i.typeChecker.CheckType(value, stmt.Type); err != nil {
+			return nil, fmt.Errorf("constant %s type mismatch: %v", stmt.Target, err)
+		}
+	}
 
-	// If variable exists in any scope (including parent), update it
-	// Otherwise, define a new variable in current scope
-	if env.Has(stmt.Target) {
-		env.Set(stmt.Target, value)
-	} else {
+	// A const declaration always binds in the current scope. Otherwise, if
+	// the variable exists in any scope (including parent), update it;
+	// if not, define a new variable in current scope.
+	switch {
+	case stmt.Const:
+		env.DefineConst(stmt.Target, value)
+	case env.Has(stmt.Target):
+		if err := env.Set(stmt.Target, value); err != nil {
+			return nil, err
+		}
+	default:
 		env.Define(stmt.Target, value)
 	}
 	return value, nil
@@ -232,7 +250,7 @@ func (i *Interpreter) executeReassign(stmt ReassignStatement, env *Environment)
 	}
 
 	// Check if target is a constant (immutable)
-	if i.IsConstant(stmt.Target) {
+	if env.IsConstant(stmt.Target) {
 		return nil, fmt.Errorf("cannot reassign constant '%s'", stmt.Target)
 	}
 
@@ -242,7 +260,9 @@ func (i *Interpreter) executeReassign(stmt ReassignStatement, env *Environment)
 	}
 
 	// Update the existing variable
-	env.Set(stmt.Target, value)
+	if err := env.Set(stmt.Target, value); err != nil {
+		return nil, err
+	}
 	return value, nil
 }
 
@@ -306,8 +326,65 @@ func (i *Interpreter) executeIf(stmt IfStatement, env *Environment) (interface{}
 	return nil, nil
 }
 
-// maxWhileIterations is the safety limit for while loops to prevent infinite loops.
-const maxWhileIterations = 1_000_000
+// executeTry executes a try statement. If the body fails with an error the
+// catch block runs in its own environment, with the error bound as an
+// object holding its message.
+func (i *Interpreter) executeTry(stmt TryStatement, env *Environment) (interface{}, error) {
+	result, err := i.executeStatements(stmt.Body, NewChildEnvironment(env))
+	if err == nil || !isCatchable(err) {
+		return result, err
+	}
+
+	catchEnv := NewChildEnvironment(env)
+	if stmt.ErrorVar != "" {
+		catchEnv.Define(stmt.ErrorVar, map[string]interface{}{"message": errorMessage(err)})
+	}
+	return i.executeStatements(stmt.Catch, catchEnv)
+}
+
+// isCatchable reports whether a try statement may handle err. Return, break
+// and continue are control flow rather than errors, and validation failures,
+// error() responses and loop limits must still end the route with their own
+// status.
+func isCatchable(err error) bool {
+	switch err.(type) {
+	case *returnValue, *breakValue, *continueValue:
+		return false
+	}
+	var validationErr *ValidationError
+	var loopErr *LoopLimitError
+	var errorResp *ErrorResponse
+	return !errors.As(err, &validationErr) && !errors.As(err, &loopErr) && !errors.As(err, &errorResp)
+}
+
+// DefaultMaxLoopIterations is how many iterations a while or for loop may
+// run before it fails, unless SetMaxLoopIterations changes it
+const DefaultMaxLoopIterations = 1_000_000
+
+// LoopLimitError is returned when a loop runs more iterations than allowed,
+// such as a while loop whose condition never becomes false
+type LoopLimitError struct {
+	Loop  string // "while" or "for"
+	Limit int
+}
+
+func (e *LoopLimitError) Error() string {
+	return fmt.Sprintf("%s loop exceeded maximum iterations (%d)", e.Loop, e.Limit)
+}
+
+// SetMaxLoopIterations sets how many iterations each while or for loop may
+// run; 0 removes the limit
+func (i *Interpreter) SetMaxLoopIterations(n int) {
+	i.maxLoopIter = n
+}
+
+// checkLoopLimit fails once a loop has run the maximum number of iterations
+func (i *Interpreter) checkLoopLimit(loop string, iterations int) error {
+	if i.maxLoopIter > 0 && iterations >= i.maxLoopIter {
+		return &LoopLimitError{Loop: loop, Limit: i.maxLoopIter}
+	}
+	return nil
+}
 
 // executeWhile executes a while loop
 func (i *Interpreter) executeWhile(stmt WhileStatement, env *Environment) (interface{}, error) {
@@ -315,8 +392,8 @@ func (i *Interpreter) executeWhile(stmt WhileStatement, env *Environment) (inter
 
 	// Execute the loop until the condition is false
 	for iterations := 0; ; iterations++ {
-		if iterations >= maxWhileIterations {
-			return nil, fmt.Errorf("while loop exceeded maximum iterations (%d)", maxWhileIterations)
+		if err := i.checkLoopLimit("while", iterations); err != nil {
+			return nil, err
 		}
 
 		// Evaluate condition in parent environment (can access loop variables from previous iterations)
@@ -373,6 +450,9 @@ func (i *Interpreter) executeFor(stmt ForStatement, env *Environment) (interface
 	if arr, ok := iterable.([]interface{}); ok {
 		// Iterate over array
 		for index, element := range arr {
+			if err := i.checkLoopLimit("for", index); err != nil {
+				return nil, err
+			}
 			// Create a fresh environment for each iteration
 			loopEnv := NewChildEnvironment(env)
 
@@ -400,7 +480,12 @@ func (i *Interpreter) executeFor(stmt ForStatement, env *Environment) (interface
 		}
 	} else if obj, ok := iterable.(map[string]interface{}); ok {
 		// Iterate over object/map
+		iterations := 0
 		for key, value := range obj {
+			if err := i.checkLoopLimit("for", iterations); err != nil {
+				return nil, err
+			}
+			iterations++
 			// Create a fresh environment for each iteration
 			loopEnv := NewChildEnvironment(env)
 
@@ -620,7 +705,8 @@ func (i *Interpreter) executeAssert(stmt AssertStatement, env *Environment) (int
 	return true, nil
 }
 
-// executeIndexAssign handles assignment to indexed targets: arr[0] = value, obj.field[0] = value
+// executeIndexAssign handles assignment to field and index targets: arr[0] = value,
+// user.name = value, users[0].tags[1] = value
 func (i *Interpreter) executeIndexAssign(stmt IndexAssignStatement, env *Environment) (interface{}, error) {
 	value, err := i.EvaluateExpression(stmt.Value, env)
 	if err != nil {
@@ -653,10 +739,15 @@ func (i *Interpreter) assignToTarget(target Expr, value interface{}, env *Enviro
 			default:
 				return nil, fmt.Errorf("array index must be an integer, got %T", indexVal)
 			}
-			if index < 0 || int(index) >= len(c) {
+			// Negative indices count from the end, as they do when reading
+			pos := index
+			if pos < 0 {
+				pos += int64(len(c))
+			}
+			if pos < 0 || pos >= int64(len(c)) {
 				return nil, fmt.Errorf("array index out of bounds: %d (length: %d)", index, len(c))
 			}
-			c[index] = value
+			c[pos] = value
 			return value, nil
 
 		case map[string]interface{}:
@@ -667,6 +758,9 @@ func (i *Interpreter) assignToTarget(target Expr, value interface{}, env *Enviro
 			c[keyStr] = value
 			return value, nil
 
+		case nil:
+			return nil, fmt.Errorf("cannot index-assign to null")
+
 		default:
 			return nil, fmt.Errorf("cannot index-assign to %T", container)
 		}
@@ -676,6 +770,9 @@ func (i *Interpreter) assignToTarget(target Expr, value interface{}, env *Enviro
 		if err != nil {
 			return nil, err
 		}
+		if obj == nil {
+			return nil, fmt.Errorf("cannot assign field '%s' on null", t.Field)
+		}
 		objMap, ok := obj.(map[string]interface{})
 		if !ok {
 			return nil, fmt.Errorf("cannot assign field '%s' on %T", t.Field, obj)
@@ -3,6 +3,7 @@ package interpreter
 import (
 	. "github.com/glyphlang/glyph/pkg/ast"
 
+	"errors"
 	"fmt"
 	"testing"
 )
@@ -409,6 +410,41 @@ func TestWhileLoopNonBoolCondition(t *testing.T) {
 	}
 }
 
+// TestLoopIterationLimit tests that infinite while loops and long for
+// loops stop with a *LoopLimitError
+func TestLoopIterationLimit(t *testing.T) {
+	interp := NewInterpreter()
+	interp.SetMaxLoopIterations(100)
+	env := NewEnvironment()
+
+	_, err := interp.ExecuteStatement(WhileStatement{
+		Condition: LiteralExpr{Value: BoolLiteral{Value: true}},
+		Body:      []Statement{},
+	}, env)
+	var loopErr *LoopLimitError
+	if !errors.As(err, &loopErr) || loopErr.Loop != "while" || loopErr.Limit != 100 {
+		t.Fatalf("expected a while loop limit error, got %v", err)
+	}
+
+	items := make([]interface{}, 101)
+	env.Define("items", items)
+	forStmt := ForStatement{ValueVar: "item", Iterable: VariableExpr{Name: "items"}, Body: []Statement{}}
+	if _, err := interp.ExecuteStatement(forStmt, env); !errors.As(err, &loopErr) || loopErr.Loop != "for" {
+		t.Errorf("expected a for loop limit error, got %v", err)
+	}
+
+	// Loops within the limit, or with no limit, run to completion
+	env.Define("items", items[:100])
+	if _, err := interp.ExecuteStatement(forStmt, env); err != nil {
+		t.Errorf("expected 100 iterations to be allowed, got %v", err)
+	}
+	interp.SetMaxLoopIterations(0)
+	env.Define("items", make([]interface{}, DefaultMaxLoopIterations+1))
+	if _, err := interp.ExecuteStatement(forStmt, env); err != nil {
+		t.Errorf("expected no limit, got %v", err)
+	}
+}
+
 // TestIfStatementNonBoolCondition tests if statement with non-bool condition
 func TestIfStatementNonBoolCondition(t *testing.T) {
 	interp := NewInterpreter()
@@ -1373,6 +1409,41 @@ func TestExecuteRouteWithInjection(t *testing.T) {
 	}
 }
 
+// panickingHandler is a provider whose method panics during a route
+type panickingHandler struct{}
+
+func (panickingHandler) Ping() interface{} {
+	panic("nil QueryRow result")
+}
+
+// TestExecuteRouteRecoversPanic tests that a panic inside a route is returned
+// as a RoutePanicError instead of unwinding into the caller
+func TestExecuteRouteRecoversPanic(t *testing.T) {
+	interp := NewInterpreter()
+	interp.SetDatabaseHandler(panickingHandler{})
+
+	module, err := parseLoaderSource("@ GET /boom {\n  % db: Database\n  > db.Ping()\n}\n")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	route := module.Items[0].(*Route)
+
+	resp, err := interp.ExecuteRoute(route, &Request{Method: "GET", Path: "/boom"})
+	if resp != nil {
+		t.Errorf("expected nil response, got %v", resp)
+	}
+	panicErr, ok := err.(*RoutePanicError)
+	if !ok {
+		t.Fatalf("expected *RoutePanicError, got %T: %v", err, err)
+	}
+	if panicErr.Value != "nil QueryRow result" || len(panicErr.Stack) == 0 {
+		t.Errorf("unexpected panic error: %v", panicErr)
+	}
+	if panicErr.Error() != "panic in GET /boom: nil QueryRow result" {
+		t.Errorf("Error() = %q", panicErr.Error())
+	}
+}
+
 // TestArrayIndexExpr tests array indexing (unsupported currently)
 func TestArrayIndexExpr(t *testing.T) {
 	interp := NewInterpreter()
@@ -204,7 +204,7 @@ func TestInterpreter_ExecuteFunction_RequiredParamCount(t *testing.T) {
 	// Should fail with no arguments (missing required)
 	_, err = interp.executeFunction(fn, []Expr{}, env)
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "expects at least 1 argument")
+	assert.Contains(t, err.Error(), "missing required argument a in function test")
 }
 
 // Test that providing all arguments still works correctly
@@ -86,3 +86,32 @@ func TestInterpreter_HeadersMissing(t *testing.T) {
 	require.True(t, ok)
 	assert.Nil(t, body["val"])
 }
+
+// TestInterpreter_RequestObject verifies that route handlers can read the
+// method, path, params, query and IP of the request from 'request', and
+// headers via request.header whatever the case of the name
+func TestInterpreter_RequestObject(t *testing.T) {
+	module, err := parseLoaderSource(`@ GET /api/users/:id {
+  > {method: request.method, path: request.path, id: request.params.id, q: request.query.q, ip: request.ip, key: request.header("x-api-key"), missing: request.header("X-Missing")}
+}`)
+	require.NoError(t, err)
+	route := module.Items[0].(*Route)
+
+	response, err := NewInterpreter().ExecuteRoute(route, &Request{
+		Path:    "/api/users/42?q=ada",
+		Method:  "GET",
+		Headers: map[string]string{"X-Api-Key": "secret"},
+		IP:      "203.0.113.7",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"method":  "GET",
+		"path":    "/api/users/42",
+		"id":      "42",
+		"q":       "ada",
+		"ip":      "203.0.113.7",
+		"key":     "secret",
+		"missing": nil,
+	}, response.Body)
+}
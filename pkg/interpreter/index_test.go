@@ -0,0 +1,79 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexExpressions(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   interface{}
+	}{
+		{"positive index", `@ GET /t {
+  $ items = ["a", "b", "c"]
+  > items[1]
+}`, "b"},
+		{"negative index", `@ GET /t {
+  $ items = ["a", "b", "c"]
+  > items[-1] + items[-3]
+}`, "ca"},
+		{"computed index", `@ GET /t {
+  $ items = [10, 20, 30]
+  $ i = 2
+  > items[i - 1]
+}`, int64(20)},
+		{"string key", `@ GET /t {
+  $ limits = {free: 10, pro: 100}
+  > limits["pro"]
+}`, int64(100)},
+		{"field of an element", `@ GET /t {
+  $ users = [{name: "ada"}, {name: "bob"}]
+  > users[-1].name
+}`, "bob"},
+		{"nested index", `@ GET /t {
+  $ grid = [[1, 2], [3, 4]]
+  > grid[1][-2]
+}`, int64(3)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runRouteSource(t, tt.source)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
+
+func TestIndexExpressionErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"past the end", `@ GET /t {
+  $ items = [1, 2, 3]
+  > items[3]
+}`, "array index out of bounds: 3 (length: 3)"},
+		{"before the start", `@ GET /t {
+  $ items = [1, 2, 3]
+  > items[-4]
+}`, "array index out of bounds: -4 (length: 3)"},
+		{"missing key", `@ GET /t {
+  $ limits = {free: 10}
+  > limits["pro"]
+}`, `key "pro" not found in object`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runRouteSource(t, tt.source)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"math"
+	"strings"
+)
+
+// FieldError is one field of a request body that does not match the route's
+// declared input type
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// InputValidationError lists every field of a request body that does not
+// match the route's declared input type
+type InputValidationError struct {
+	TypeName string
+	Fields   []FieldError
+}
+
+func (e *InputValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for n, f := range e.Fields {
+		msgs[n] = f.Field + ": " + f.Message
+	}
+	return "input validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Response is the 400 response sent in place of running the route:
+// {"error": "input validation failed", "fields": [{"field", "message"}...]}
+func (e *InputValidationError) Response() *Response {
+	fields := make([]interface{}, len(e.Fields))
+	for n, f := range e.Fields {
+		fields[n] = map[string]interface{}{
+			"field":   f.Field,
+			"message": f.Message,
+		}
+	}
+	return &Response{
+		StatusCode: 400,
+		Body: map[string]interface{}{
+			"error":  "input validation failed",
+			"fields": fields,
+		},
+	}
+}
+
+// RouteInputTypeDef returns the TypeDef a route declares for its input, if
+// the route declares one and the type is known
+func (tc *TypeChecker) RouteInputTypeDef(route *Route) (TypeDef, bool) {
+	namedType, ok := route.InputType.(NamedType)
+	if !ok {
+		return TypeDef{}, false
+	}
+	typeDef, ok := tc.typeDefs[namedType.Name]
+	return typeDef, ok
+}
+
+// ValidateInput checks a request body against a TypeDef and reports every
+// offending field, in the order the TypeDef declares them. A nil body is
+// checked as an empty object. Fields with defaults are expected to have been
+// applied already; extra fields are allowed.
+func (tc *TypeChecker) ValidateInput(body map[string]interface{}, typeDef TypeDef) *InputValidationError {
+	var fields []FieldError
+	for _, field := range typeDef.Fields {
+		value, exists := body[field.Name]
+		if !exists {
+			if field.Required && field.Default == nil {
+				fields = append(fields, FieldError{Field: field.Name, Message: "missing required field"})
+			}
+			continue
+		}
+		if isJSONInt(value, field.TypeAnnotation) {
+			continue
+		}
+		if err := tc.CheckType(value, field.TypeAnnotation); err != nil {
+			fields = append(fields, FieldError{Field: field.Name, Message: err.Error()})
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return &InputValidationError{TypeName: typeDef.Name, Fields: fields}
+}
+
+// isJSONInt reports whether value is a whole JSON number given for an int
+// field. encoding/json decodes every number as float64.
+func isJSONInt(value interface{}, t Type) bool {
+	if opt, ok := t.(OptionalType); ok {
+		t = opt.InnerType
+	}
+	if _, ok := t.(IntType); !ok {
+		return false
+	}
+	f, ok := value.(float64)
+	return ok && f == math.Trunc(f) && !math.IsInf(f, 0)
+}
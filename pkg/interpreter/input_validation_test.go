@@ -0,0 +1,184 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const inputValidationSource = `: CreateUser {
+  name: str!
+  age: int!
+  admin: bool = false
+}
+
+@ POST /users {
+  < input: CreateUser
+  > {name: input.name, age: input.age, admin: input.admin}
+}
+`
+
+func loadInputValidationRoute(t *testing.T) (*Interpreter, *Route) {
+	t.Helper()
+	module, err := parseLoaderSource(inputValidationSource)
+	require.NoError(t, err)
+	interp := NewInterpreter()
+	require.NoError(t, interp.LoadModule(*module))
+	for _, item := range module.Items {
+		if route, ok := item.(*Route); ok {
+			return interp, route
+		}
+	}
+	t.Fatal("no route in source")
+	return nil, nil
+}
+
+// fieldNames returns the "field" of each entry in a validation response
+func fieldNames(t *testing.T, resp *Response) []string {
+	t.Helper()
+	body, ok := resp.Body.(map[string]interface{})
+	require.True(t, ok, "body is %T", resp.Body)
+	assert.Equal(t, "input validation failed", body["error"])
+	fields, ok := body["fields"].([]interface{})
+	require.True(t, ok, "fields is %T", body["fields"])
+	names := make([]string, len(fields))
+	for n, f := range fields {
+		names[n] = f.(map[string]interface{})["field"].(string)
+	}
+	return names
+}
+
+func TestExecuteRoute_InputMissingRequiredField(t *testing.T) {
+	interp, route := loadInputValidationRoute(t)
+
+	resp, err := interp.ExecuteRoute(route, &Request{
+		Path:   "/users",
+		Method: "POST",
+		Body:   map[string]interface{}{"admin": true},
+	})
+	var validationErr *InputValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "CreateUser", validationErr.TypeName)
+	require.NotNil(t, resp)
+	assert.Equal(t, 400, resp.StatusCode)
+	assert.Equal(t, []string{"name", "age"}, fieldNames(t, resp), "every missing field is reported")
+}
+
+func TestExecuteRoute_InputTypeMismatch(t *testing.T) {
+	interp, route := loadInputValidationRoute(t)
+
+	resp, err := interp.ExecuteRoute(route, &Request{
+		Path:   "/users",
+		Method: "POST",
+		Body:   map[string]interface{}{"name": "Ada", "age": "old", "admin": "yes"},
+	})
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, 400, resp.StatusCode)
+	assert.Equal(t, []string{"age", "admin"}, fieldNames(t, resp))
+	assert.ErrorContains(t, err, "age: type mismatch: expected int, got string")
+}
+
+func TestExecuteRoute_InputMissingBody(t *testing.T) {
+	interp, route := loadInputValidationRoute(t)
+
+	resp, err := interp.ExecuteRoute(route, &Request{Path: "/users", Method: "POST"})
+	require.Error(t, err)
+	assert.Equal(t, []string{"name", "age"}, fieldNames(t, resp))
+}
+
+func TestExecuteRoute_InputValid(t *testing.T) {
+	interp, route := loadInputValidationRoute(t)
+
+	// JSON numbers decode as float64; a whole number satisfies int
+	resp, err := interp.ExecuteRoute(route, &Request{
+		Path:   "/users",
+		Method: "POST",
+		Body:   map[string]interface{}{"name": "Ada", "age": float64(36)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, map[string]interface{}{"name": "Ada", "age": float64(36), "admin": false}, resp.Body)
+}
+
+func TestTypeChecker_ValidateInput_FractionalInt(t *testing.T) {
+	tc := NewTypeChecker()
+	typeDef := TypeDef{
+		Name:   "Page",
+		Fields: []Field{{Name: "size", TypeAnnotation: IntType{}, Required: true}},
+	}
+
+	assert.Nil(t, tc.ValidateInput(map[string]interface{}{"size": float64(20)}, typeDef))
+	err := tc.ValidateInput(map[string]interface{}{"size": 20.5}, typeDef)
+	require.NotNil(t, err)
+	assert.Equal(t, []FieldError{{Field: "size", Message: "type mismatch: expected int, got float"}}, err.Fields)
+}
+
+const enumSource = `: Status = "pending" | "shipped" | "delivered"
+
+: UpdateOrder {
+  status: Status!
+  note: str
+}
+
+@ POST /orders {
+  < input: UpdateOrder
+  switch input.status {
+    case "pending" {
+      > {next: "shipped"}
+    }
+    case "shipped" {
+      > {next: "delivered"}
+    }
+    default {
+      > {next: null}
+    }
+  }
+}
+`
+
+func TestExecuteRoute_InputEnum(t *testing.T) {
+	module, err := parseLoaderSource(enumSource)
+	require.NoError(t, err)
+	interp := NewInterpreter()
+	require.NoError(t, interp.LoadModule(*module))
+	var route *Route
+	for _, item := range module.Items {
+		if r, ok := item.(*Route); ok {
+			route = r
+		}
+	}
+	require.NotNil(t, route)
+
+	resp, err := interp.ExecuteRoute(route, &Request{
+		Path:   "/orders",
+		Method: "POST",
+		Body:   map[string]interface{}{"status": "pending"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"next": "shipped"}, resp.Body, "switch matches the enum value")
+
+	resp, err = interp.ExecuteRoute(route, &Request{
+		Path:   "/orders",
+		Method: "POST",
+		Body:   map[string]interface{}{"status": "lost"},
+	})
+	require.Error(t, err)
+	assert.Equal(t, 400, resp.StatusCode)
+	assert.Equal(t, []string{"status"}, fieldNames(t, resp))
+	assert.ErrorContains(t, err, `status: invalid Status: expected one of "pending", "shipped", "delivered", got "lost"`)
+}
+
+func TestTypeChecker_CheckTypeEnum(t *testing.T) {
+	tc := NewTypeChecker()
+	tc.SetEnumDefs(map[string]EnumDef{"Size": {Name: "Size", Values: []string{"s", "m", "l"}}})
+
+	assert.NoError(t, tc.CheckType("m", NamedType{Name: "Size"}))
+	assert.NoError(t, tc.CheckType(nil, OptionalType{InnerType: NamedType{Name: "Size"}}))
+	assert.NoError(t, tc.ValidateTypeReference(NamedType{Name: "Size"}))
+	assert.EqualError(t, tc.CheckType("xl", NamedType{Name: "Size"}), `invalid Size: expected one of "s", "m", "l", got "xl"`)
+	assert.EqualError(t, tc.CheckType(int64(2), NamedType{Name: "Size"}), `invalid Size: expected one of "s", "m", "l", got int`)
+}
@@ -0,0 +1,35 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpolation_Variable(t *testing.T) {
+	result, err := runRouteSource(t, `@ GET /t {
+  $ name = "Ada"
+  > "Hello, {name}!"
+}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, Ada!", result)
+}
+
+func TestInterpolation_NestedFieldAccess(t *testing.T) {
+	result, err := runRouteSource(t, `@ GET /t {
+  $ order = {customer: {name: "Ada"}, total: 9.5, items: 3, note: null}
+  > "{order.customer.name} bought {order.items + 1} items for {order.total} ({order.note})"
+}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Ada bought 4 items for 9.5 (null)", result)
+}
+
+func TestInterpolation_EscapedBrace(t *testing.T) {
+	result, err := runRouteSource(t, `@ GET /t {
+  $ n = 2
+  > "\{n} is {n}"
+}`)
+	require.NoError(t, err)
+	assert.Equal(t, "{n} is 2", result)
+}
@@ -3,11 +3,20 @@ package interpreter
 import (
 	. "github.com/glyphlang/glyph/pkg/ast"
 
+	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/glyphlang/glyph/pkg/scheduler"
+	"github.com/glyphlang/glyph/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // maxEvalDepth is the maximum recursion depth for expression evaluation.
@@ -18,6 +27,7 @@ type Interpreter struct {
 	globalEnv        *Environment
 	functions        map[string]Function
 	typeDefs         map[string]TypeDef
+	enumDefs         map[string]EnumDef
 	commands         map[string]Command
 	cronTasks        []CronTask
 	eventHandlers    map[string][]EventHandler
@@ -27,6 +37,12 @@ type Interpreter struct {
 	grpcHandlers     map[string]GRPCHandler     // key: method name
 	graphqlResolvers map[string]GraphQLResolver // key: "operation.fieldName"
 	testBlocks       []TestBlock
+	seedBlocks       []SeedBlock
+	middlewares      map[string]MiddlewareDef
+	testRequester    TestRequestFunc // Sends request() calls from test blocks to the routes
+	envPrefixes      []string        // When set, env() reads only variables with one of these prefixes
+	uuidFunc         UUIDFunc        // When set, the source of uuid() and generateId()
+	templates        Templates       // When set, the templates of render() and renderString()
 	typeChecker      *TypeChecker
 	dbHandler        interface{}              // Database handler for dependency injection
 	redisHandler     interface{}              // Redis handler for dependency injection
@@ -37,11 +53,18 @@ type Interpreter struct {
 	providerDefs     map[string]ProviderDef   // Provider contract definitions
 	moduleResolver   *ModuleResolver          // Module resolver for handling imports
 	importedModules  map[string]*LoadedModule // Imported modules by alias/name
-	constants        map[string]struct{}      // Tracks names that are constants (immutable)
 	contracts        map[string]ContractDef   // Contract definitions by name
 	traitDefs        map[string]TraitDef      // Trait definitions by name
 	macros           map[string]*MacroDef     // Macro definitions by name
 	evalDepth        int64                    // Current recursion depth for evaluation (atomic)
+	maxLoopIter      int                      // Iterations allowed per while or for loop; 0 means no limit
+
+	// queueRunner receives enqueue() messages while a QueueRunner is running
+	queueRunner atomic.Pointer[QueueRunner]
+	// eventBus, when set, delivers EmitEvent events asynchronously
+	eventBus atomic.Pointer[EventBus]
+	// background runs the background blocks of routes
+	background *BackgroundTasks
 }
 
 // NewInterpreter creates a new interpreter instance
@@ -51,9 +74,11 @@ func NewInterpreter() *Interpreter {
 		globalEnv:        NewEnvironment(),
 		functions:        make(map[string]Function),
 		typeDefs:         make(map[string]TypeDef),
+		enumDefs:         make(map[string]EnumDef),
 		commands:         make(map[string]Command),
 		cronTasks:        []CronTask{},
 		testBlocks:       []TestBlock{},
+		middlewares:      make(map[string]MiddlewareDef),
 		eventHandlers:    make(map[string][]EventHandler),
 		queueWorkers:     make(map[string]QueueWorker),
 		grpcServices:     make(map[string]GRPCService),
@@ -64,17 +89,17 @@ func NewInterpreter() *Interpreter {
 		providerDefs:     make(map[string]ProviderDef),
 		moduleResolver:   NewModuleResolver(),
 		importedModules:  make(map[string]*LoadedModule),
-		constants:        make(map[string]struct{}),
 		contracts:        make(map[string]ContractDef),
 		traitDefs:        make(map[string]TraitDef),
 		macros:           make(map[string]*MacroDef),
+		background:       &BackgroundTasks{},
+		maxLoopIter:      DefaultMaxLoopIterations,
 	}
 }
 
-// IsConstant checks if a name refers to a constant (immutable) binding
+// IsConstant checks if a global name refers to a constant (immutable) binding
 func (i *Interpreter) IsConstant(name string) bool {
-	_, ok := i.constants[name]
-	return ok
+	return i.globalEnv.IsConstant(name)
 }
 
 // SetModuleResolver sets a custom module resolver
@@ -96,6 +121,17 @@ type Request struct {
 	Headers   map[string]string
 	AuthData  map[string]interface{} // Authenticated user data from JWT
 	SSEWriter interface{}            // SSEWriter for SSE routes (implements executor.SSEWriter)
+	ID        string                 // Request ID (X-Request-ID)
+	IP        string                 // Client IP address
+	Context   context.Context        // Request context carrying its trace; nil means context.Background()
+	Cookies   map[string]string      // Cookies the request sent, by name
+	// Response cookies and session for setCookie, clearCookie and
+	// session.*; nil outside an HTTP server
+	ResponseCookies ResponseCookies
+	Session         Session
+	// Error is the status, message and path of the error an @ onerror
+	// handler answers, bound as err; nil for other routes
+	Error map[string]interface{}
 }
 
 // Response represents an HTTP response
@@ -127,6 +163,9 @@ func (i *Interpreter) LoadModuleWithPath(module Module, basePath string) error {
 		case *TypeDef:
 			i.typeDefs[it.Name] = *it
 
+		case *EnumDef:
+			i.enumDefs[it.Name] = *it
+
 		case *TraitDef:
 			i.traitDefs[it.Name] = *it
 
@@ -147,6 +186,10 @@ func (i *Interpreter) LoadModuleWithPath(module Module, basePath string) error {
 			i.commands[it.Name] = *it
 
 		case *CronTask:
+			// Reject a bad schedule now rather than when it is first due
+			if _, err := scheduler.Parse(it.Schedule); err != nil {
+				return fmt.Errorf("cron task %s: %w", CronTaskName(it), err)
+			}
 			i.cronTasks = append(i.cronTasks, *it)
 
 		case *EventHandler:
@@ -173,6 +216,12 @@ func (i *Interpreter) LoadModuleWithPath(module Module, basePath string) error {
 		case *TestBlock:
 			i.testBlocks = append(i.testBlocks, *it)
 
+		case *SeedBlock:
+			i.seedBlocks = append(i.seedBlocks, *it)
+
+		case *MiddlewareDef:
+			i.middlewares[it.Name] = *it
+
 		case *ProviderDef:
 			i.providerDefs[it.Name] = *it
 
@@ -193,7 +242,11 @@ func (i *Interpreter) LoadModuleWithPath(module Module, basePath string) error {
 			}
 
 		case *ConstDecl:
-			// Evaluate and store constant at module load time
+			// Evaluate and store constant at module load time. A constant
+			// cannot be declared twice, nor over an imported one.
+			if i.globalEnv.IsConstant(it.Name) {
+				return fmt.Errorf("constant %s is already defined", it.Name)
+			}
 			value, err := i.EvaluateExpression(it.Value, i.globalEnv)
 			if err != nil {
 				return fmt.Errorf("error evaluating constant %s: %v", it.Name, err)
@@ -203,20 +256,30 @@ func (i *Interpreter) LoadModuleWithPath(module Module, basePath string) error {
 					return fmt.Errorf("constant %s type mismatch: %v", it.Name, err)
 				}
 			}
-			i.globalEnv.Define(it.Name, value)
-			i.constants[it.Name] = struct{}{}
+			i.globalEnv.DefineConst(it.Name, value)
 
 		case *StaticRoute:
 			// Static routes are handled at the server/mux level, not by the interpreter.
 			continue
 
+		case *ErrorHandler:
+			// Error handlers are run by the server like routes when it answers
+			// with an error
+			continue
+
 		default:
 			return fmt.Errorf("unsupported item type: %T", item)
 		}
 	}
 
+	// Middleware may be defined after the routes that apply it
+	if err := i.checkMiddlewareRefs(module); err != nil {
+		return err
+	}
+
 	// Sync typeChecker with loaded types, functions, and traits
 	i.typeChecker.SetTypeDefs(i.typeDefs)
+	i.typeChecker.SetEnumDefs(i.enumDefs)
 	i.typeChecker.SetFunctions(i.functions)
 	i.typeChecker.SetTraitDefs(i.traitDefs)
 
@@ -263,6 +326,8 @@ func (i *Interpreter) processImport(importStmt *ImportStatement, basePath string
 				i.globalEnv.Define(importName, *exp)
 			case *TypeDef:
 				i.typeDefs[importName] = *exp
+			case *EnumDef:
+				i.enumDefs[importName] = *exp
 			case *Command:
 				i.commands[importName] = *exp
 			case *ConstDecl:
@@ -270,19 +335,15 @@ func (i *Interpreter) processImport(importStmt *ImportStatement, basePath string
 				if err != nil {
 					return fmt.Errorf("error evaluating constant %s: %v", exp.Name, err)
 				}
-				i.globalEnv.Define(importName, value)
+				i.globalEnv.DefineConst(importName, value)
 			default:
 				i.globalEnv.Define(importName, exp)
 			}
 		}
 	} else {
 		// Full module import: import "path" or import "path" as alias
-		// Determine the namespace name
-		namespace := importStmt.Alias
-		if namespace == "" {
-			// Extract name from path (e.g., "./utils" -> "utils")
-			namespace = extractModuleName(importStmt.Path)
-		}
+		// (e.g., "./utils" -> "utils")
+		namespace := ImportNamespace(importStmt)
 
 		// Store the loaded module
 		i.importedModules[namespace] = loadedModule
@@ -340,7 +401,7 @@ func (i *Interpreter) loadModuleInternals(mod *LoadedModule) error {
 				if exp.Value != nil {
 					value, evalErr := i.EvaluateExpression(exp.Value, i.globalEnv)
 					if evalErr == nil {
-						i.globalEnv.Define(name, value)
+						i.globalEnv.DefineConst(name, value)
 					}
 				}
 			}
@@ -349,6 +410,15 @@ func (i *Interpreter) loadModuleInternals(mod *LoadedModule) error {
 	return nil
 }
 
+// ImportNamespace returns the name a full-module import is bound to: its
+// alias, or the file name without extension
+func ImportNamespace(stmt *ImportStatement) string {
+	if stmt.Alias != "" {
+		return stmt.Alias
+	}
+	return extractModuleName(stmt.Path)
+}
+
 // extractModuleName extracts the module name from an import path
 func extractModuleName(path string) string {
 	// Remove .glyph extension if present
@@ -455,32 +525,35 @@ func resolveProviderType(t Type) string {
 // back to the generic provider registry for custom providers.
 func (i *Interpreter) injectDependency(injection Injection, env *Environment) {
 	providerType := resolveProviderType(injection.Type)
+	define := func(handler interface{}) {
+		env.Define(injection.Name, bindContext(handler, env))
+	}
 
 	// Legacy handler lookup for backward compatibility
 	switch providerType {
 	case "Database":
 		if i.dbHandler != nil {
-			env.Define(injection.Name, i.dbHandler)
+			define(i.dbHandler)
 			return
 		}
 	case "Redis":
 		if i.redisHandler != nil {
-			env.Define(injection.Name, i.redisHandler)
+			define(i.redisHandler)
 			return
 		}
 	case "MongoDB":
 		if i.mongoDBHandler != nil {
-			env.Define(injection.Name, i.mongoDBHandler)
+			define(i.mongoDBHandler)
 			return
 		}
 	case "LLM":
 		if i.llmHandler != nil {
-			env.Define(injection.Name, i.llmHandler)
+			define(i.llmHandler)
 			return
 		}
 	case "HTTP":
 		if i.httpHandler != nil {
-			env.Define(injection.Name, i.httpHandler)
+			define(i.httpHandler)
 			return
 		}
 	}
@@ -488,16 +561,74 @@ func (i *Interpreter) injectDependency(injection Injection, env *Environment) {
 	// Generic provider registry lookup
 	if providerType != "" {
 		if handler, ok := i.providerHandlers[providerType]; ok && handler != nil {
-			env.Define(injection.Name, handler)
+			define(handler)
 			return
 		}
 	}
 }
 
-// ExecuteRoute executes a route with the given request
-func (i *Interpreter) ExecuteRoute(route *Route, request *Request) (*Response, error) {
+// contextBinder is implemented by injected handlers that can run with a
+// request's context, such as the database, so their work is traced and
+// cancelled as part of the request
+type contextBinder interface {
+	WithContext(ctx context.Context) interface{}
+}
+
+// bindContext returns handler bound to the request context of env, or
+// handler itself when it cannot be bound or env has no request context
+func bindContext(handler interface{}, env *Environment) interface{} {
+	if binder, ok := handler.(contextBinder); ok {
+		if ctx := env.lookupContext(); ctx != nil {
+			return binder.WithContext(ctx)
+		}
+	}
+	return handler
+}
+
+// RoutePanicError is returned by ExecuteRoute when route execution panics
+type RoutePanicError struct {
+	Method string
+	Path   string
+	Value  interface{} // The recovered panic value
+	Stack  []byte      // Stack trace captured at the point of recovery
+}
+
+func (e *RoutePanicError) Error() string {
+	return fmt.Sprintf("panic in %s %s: %v", e.Method, e.Path, e.Value)
+}
+
+// ExecuteRoute executes a route with the given request. A panic during
+// execution is recovered and returned as a *RoutePanicError.
+func (i *Interpreter) ExecuteRoute(route *Route, request *Request) (resp *Response, err error) {
+	reqCtx := request.Context
+	if reqCtx == nil {
+		reqCtx = context.Background()
+	}
+	reqCtx, span := tracing.StartSpan(reqCtx, "interpreter.execute",
+		trace.WithAttributes(attribute.String("http.route", route.Path)))
+	// Deferred before the recovery below so a recovered panic is recorded
+	defer func() {
+		if err != nil {
+			tracing.SetError(reqCtx, err)
+		}
+		span.End()
+	}()
+
+	defer func() {
+		if r := recover(); r != nil {
+			resp = nil
+			err = &RoutePanicError{
+				Method: request.Method,
+				Path:   request.Path,
+				Value:  r,
+				Stack:  debug.Stack(),
+			}
+		}
+	}()
+
 	// Create a new environment for the route
 	routeEnv := NewChildEnvironment(i.globalEnv)
+	routeEnv.SetContext(reqCtx)
 
 	// Extract path parameters
 	params, err := extractPathParams(route.Path, request.Path)
@@ -509,6 +640,9 @@ func (i *Interpreter) ExecuteRoute(route *Route, request *Request) (*Response, e
 	for key, value := range params {
 		routeEnv.DefineWithSource(key, value, BindingPathParam)
 	}
+	if request.Error != nil {
+		routeEnv.Define("err", request.Error)
+	}
 
 	// Extract and process query parameters with type conversion
 	rawQueryParams, err := ExtractRawQueryParams(request.Path)
@@ -554,42 +688,31 @@ func (i *Interpreter) ExecuteRoute(route *Route, request *Request) (*Response, e
 	// Always add request body to environment (even if nil)
 	// This ensures 'input' variable is always available in routes
 	inputValue := request.Body
-	if inputValue != nil {
-		// If route has an InputType declared, apply defaults and validate
-		if route.InputType != nil {
-			if namedType, ok := route.InputType.(NamedType); ok {
-				if typeDef, exists := i.typeDefs[namedType.Name]; exists {
-					if inputObj, ok := inputValue.(map[string]interface{}); ok {
-						// Apply defaults for missing fields
-						inputWithDefaults, err := i.ApplyTypeDefaults(inputObj, typeDef, routeEnv)
-						if err != nil {
-							return &Response{
-								StatusCode: 400,
-								Body: map[string]interface{}{
-									"error": fmt.Sprintf("error applying defaults: %v", err),
-								},
-							}, err
-						}
-						inputValue = inputWithDefaults
-
-						// Validate input against the TypeDef
-						if err := i.typeChecker.ValidateObjectAgainstTypeDef(inputWithDefaults, typeDef); err != nil {
-							return &Response{
-								StatusCode: 400,
-								Body: map[string]interface{}{
-									"error": fmt.Sprintf("input validation failed: %v", err),
-								},
-							}, err
-						}
-					}
-				}
+	// If the route declares an input type, apply its defaults and validate
+	// the body before the handler runs. A missing body is checked as an
+	// empty object so its required fields are reported.
+	if typeDef, ok := i.typeChecker.RouteInputTypeDef(route); ok {
+		inputObj, isObj := inputValue.(map[string]interface{})
+		if inputValue == nil {
+			inputObj, isObj = map[string]interface{}{}, true
+		}
+		if isObj {
+			inputWithDefaults, err := i.ApplyTypeDefaults(inputObj, typeDef, routeEnv)
+			if err != nil {
+				return &Response{
+					StatusCode: 400,
+					Body: map[string]interface{}{
+						"error": fmt.Sprintf("error applying defaults: %v", err),
+					},
+				}, err
 			}
+			if validationErr := i.typeChecker.ValidateInput(inputWithDefaults, typeDef); validationErr != nil {
+				return validationErr.Response(), validationErr
+			}
+			inputValue = inputWithDefaults
 		}
-		routeEnv.Define("input", inputValue)
-	} else {
-		// Define input as nil/empty map for routes without body
-		routeEnv.Define("input", nil)
 	}
+	routeEnv.Define("input", inputValue)
 
 	// Bind request headers as 'headers' object so route handlers can
 	// read them via headers["Content-Type"] or headers.Authorization.
@@ -600,6 +723,33 @@ func (i *Interpreter) ExecuteRoute(route *Route, request *Request) (*Response, e
 	}
 	routeEnv.Define("headers", headersMap)
 
+	// Bind request metadata as 'request' object
+	cookies := make(map[string]interface{}, len(request.Cookies))
+	for k, v := range request.Cookies {
+		cookies[k] = v
+	}
+	pathParams := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		pathParams[k] = v
+	}
+	path, _, _ := strings.Cut(request.Path, "?")
+	routeEnv.Define("request", map[string]interface{}{
+		"id":      request.ID,
+		"method":  request.Method,
+		"path":    path,
+		"params":  pathParams,
+		"query":   queryParams,
+		"headers": headersMap,
+		"ip":      request.IP,
+		"cookies": cookies,
+	})
+	if request.ResponseCookies != nil {
+		routeEnv.Define(cookiesKey, request.ResponseCookies)
+	}
+	if request.Session != nil {
+		routeEnv.Define(sessionKey, request.Session)
+	}
+
 	// Handle dependency injections
 	for _, injection := range route.Injections {
 		i.injectDependency(injection, routeEnv)
@@ -633,12 +783,24 @@ func (i *Interpreter) ExecuteRoute(route *Route, request *Request) (*Response, e
 		routeEnv.Define("__sse_writer", request.SSEWriter)
 	}
 
-	// Execute route body
-	result, err := i.executeStatements(route.Body, routeEnv)
+	// Background blocks are collected while the body runs and started once
+	// it has produced its result
+	pending := &pendingBackground{label: fmt.Sprintf("%s %s", route.Method, route.Path)}
+	routeEnv.Define(backgroundKey, pending)
+
+	// Execute the named middleware, then the route body unless a middleware
+	// answered the request
+	result, handled, err := i.executeMiddlewares(route, routeEnv)
+	if err == nil && !handled {
+		result, err = i.executeStatements(route.Body, routeEnv)
+	}
 	if err != nil {
+		var errorResp *ErrorResponse
 		// Check if it's a return value
 		if val, isReturn := unwrapReturn(err); isReturn {
 			result = val
+		} else if errors.As(err, &errorResp) {
+			return errorResp.Response(), nil
 		} else {
 			return &Response{
 				StatusCode: 500,
@@ -649,6 +811,8 @@ func (i *Interpreter) ExecuteRoute(route *Route, request *Request) (*Response, e
 		}
 	}
 
+	i.startBackground(pending)
+
 	// SSE routes stream events via yield — no body is returned.
 	// Type checking is skipped because the yielded event types are
 	// validated individually by the SSEWriter, not as a single return value.
@@ -669,8 +833,9 @@ func (i *Interpreter) ExecuteRoute(route *Route, request *Request) (*Response, e
 		}, nil
 	}
 
-	// Validate return value matches declared return type
-	if route.ReturnType != nil {
+	// Validate return value matches declared return type. A middleware's
+	// answer is not the route's to check.
+	if route.ReturnType != nil && !handled {
 		if err := i.typeChecker.CheckType(result, route.ReturnType); err != nil {
 			return &Response{
 				StatusCode: 500,
@@ -709,9 +874,9 @@ func (i *Interpreter) ExecuteRoute(route *Route, request *Request) (*Response, e
 		}, nil
 	}
 
-	// Create response
+	// A union return type picks the status from the variant returned
 	response := &Response{
-		StatusCode: 200,
+		StatusCode: i.typeChecker.UnionReturnStatus(result, route.ReturnType),
 		Body:       result,
 		Headers:    make(map[string]string),
 	}
@@ -748,8 +913,11 @@ func (i *Interpreter) ExecuteRouteSimple(route *Route, pathParams map[string]str
 		routeEnv.Define("auth", authData)
 	}
 
-	// Execute route body
-	result, err := i.executeStatements(route.Body, routeEnv)
+	// Execute the named middleware, then the route body
+	result, handled, err := i.executeMiddlewares(route, routeEnv)
+	if err == nil && !handled {
+		result, err = i.executeStatements(route.Body, routeEnv)
+	}
 	if err != nil {
 		// Check if it's a return value
 		if val, isReturn := unwrapReturn(err); isReturn {
@@ -760,7 +928,7 @@ func (i *Interpreter) ExecuteRouteSimple(route *Route, pathParams map[string]str
 	}
 
 	// Validate return value matches declared return type
-	if route.ReturnType != nil {
+	if route.ReturnType != nil && !handled {
 		if err := i.typeChecker.CheckType(result, route.ReturnType); err != nil {
 			return nil, fmt.Errorf("return type mismatch in route %s %s: %v", route.Method, route.Path, err)
 		}
@@ -792,6 +960,15 @@ func (i *Interpreter) GetCommands() map[string]Command {
 	return i.commands
 }
 
+// CronTaskName returns the task's name, or its quoted schedule for
+// anonymous tasks
+func CronTaskName(task *CronTask) string {
+	if task.Name != "" {
+		return task.Name
+	}
+	return fmt.Sprintf("%q", task.Schedule)
+}
+
 // GetCronTasks returns all registered cron tasks
 func (i *Interpreter) GetCronTasks() []CronTask {
 	return i.cronTasks
@@ -927,6 +1104,36 @@ func (i *Interpreter) ExecuteCronTask(task *CronTask) (interface{}, error) {
 	return result, nil
 }
 
+// SeedBlockName returns the block's name, or "seed" for anonymous blocks
+func SeedBlockName(block *SeedBlock) string {
+	if block.Name != "" {
+		return block.Name
+	}
+	return "seed"
+}
+
+// GetSeedBlocks returns all registered seed blocks, in source order
+func (i *Interpreter) GetSeedBlocks() []SeedBlock {
+	return i.seedBlocks
+}
+
+// ExecuteSeedBlock runs a seed block with db bound to the given database
+func (i *Interpreter) ExecuteSeedBlock(block *SeedBlock, db interface{}) (interface{}, error) {
+	seedEnv := NewChildEnvironment(i.globalEnv)
+	seedEnv.Define("db", db)
+
+	result, err := i.executeStatements(block.Body, seedEnv)
+	if err != nil {
+		if val, isReturn := unwrapReturn(err); isReturn {
+			result = val
+		} else {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
 // ExecuteEventHandler executes an event handler with the given event data
 func (i *Interpreter) ExecuteEventHandler(handler *EventHandler, eventData interface{}) (interface{}, error) {
 	// Create a new environment for the handler
@@ -954,29 +1161,6 @@ func (i *Interpreter) ExecuteEventHandler(handler *EventHandler, eventData inter
 	return result, nil
 }
 
-// EmitEvent triggers all handlers for a given event type
-func (i *Interpreter) EmitEvent(eventType string, eventData interface{}) error {
-	i.eventMu.RLock()
-	handlers := make([]EventHandler, len(i.eventHandlers[eventType]))
-	copy(handlers, i.eventHandlers[eventType])
-	i.eventMu.RUnlock()
-
-	for _, handler := range handlers {
-		if handler.Async {
-			// In a real implementation, this would be executed asynchronously
-			go func(h EventHandler) {
-				i.ExecuteEventHandler(&h, eventData)
-			}(handler)
-		} else {
-			_, err := i.ExecuteEventHandler(&handler, eventData)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
 // ExecuteQueueWorker executes a queue worker with the given message
 func (i *Interpreter) ExecuteQueueWorker(worker *QueueWorker, message interface{}) (interface{}, error) {
 	// Create a new environment for the worker
@@ -1148,18 +1332,21 @@ func (i *Interpreter) GetTestBlocks() []TestBlock {
 func (i *Interpreter) RunTests(filter string) []TestResult {
 	var results []TestResult
 	for _, test := range i.testBlocks {
-		if filter != "" && !matchesFilter(test.Name, filter) {
+		if filter != "" && !MatchesTestFilter(test.Name, filter) {
 			continue
 		}
-		result := i.runSingleTest(test)
+		result := i.RunTest(test)
 		results = append(results, result)
 	}
 	return results
 }
 
-func (i *Interpreter) runSingleTest(test TestBlock) TestResult {
+// RunTest executes a test block in an environment of its own. The block sees
+// a copy of the module's globals, so nothing it assigns reaches the routes
+// it calls through request() or the blocks that run after it.
+func (i *Interpreter) RunTest(test TestBlock) TestResult {
 	start := time.Now()
-	testEnv := NewChildEnvironment(i.globalEnv)
+	testEnv := NewChildEnvironment(i.globalEnv.snapshot(nil))
 
 	_, err := i.executeStatements(test.Body, testEnv)
 	duration := time.Since(start)
@@ -1174,8 +1361,10 @@ func (i *Interpreter) runSingleTest(test TestBlock) TestResult {
 	return TestResult{Name: test.Name, Passed: true, Duration: duration}
 }
 
-// matchesFilter checks if a test name matches a filter string.
-func matchesFilter(name, filter string) bool {
+// MatchesTestFilter reports whether a test name matches a filter pattern. A
+// leading or trailing * anchors the rest of the pattern to the end or start
+// of the name; otherwise the pattern matches anywhere in it.
+func MatchesTestFilter(name, filter string) bool {
 	if filter == "" {
 		return true
 	}
@@ -954,6 +954,110 @@ func TestExecuteUserDefinedFunction(t *testing.T) {
 	assert.Equal(t, int64(12), result)
 }
 
+// greetFunction returns greet(name: str!, greeting: str = "Hello",
+// suffix: str = greeting), whose last default refers to an earlier parameter
+func greetFunction() Function {
+	return Function{
+		Name: "greet",
+		Params: []Field{
+			{Name: "name", TypeAnnotation: StringType{}, Required: true},
+			{Name: "greeting", TypeAnnotation: StringType{}, Default: LiteralExpr{Value: StringLiteral{Value: "Hello"}}},
+			{Name: "suffix", TypeAnnotation: StringType{}, Default: VariableExpr{Name: "greeting"}},
+		},
+		ReturnType: StringType{},
+		Body: []Statement{
+			ReturnStatement{
+				Value: BinaryOpExpr{
+					Op: Add,
+					Left: BinaryOpExpr{
+						Op:    Add,
+						Left:  VariableExpr{Name: "greeting"},
+						Right: VariableExpr{Name: "name"},
+					},
+					Right: VariableExpr{Name: "suffix"},
+				},
+			},
+		},
+	}
+}
+
+func TestExecuteUserDefinedFunction_OmittedArgs(t *testing.T) {
+	interp := NewInterpreter()
+	env := NewEnvironment()
+	env.Define("greet", greetFunction())
+
+	expr := FunctionCallExpr{
+		Name: "greet",
+		Args: []Expr{LiteralExpr{Value: StringLiteral{Value: "Ann"}}},
+	}
+
+	result, err := interp.EvaluateExpression(expr, env)
+
+	require.NoError(t, err)
+	assert.Equal(t, "HelloAnnHello", result)
+}
+
+func TestExecuteUserDefinedFunction_NamedArgs(t *testing.T) {
+	interp := NewInterpreter()
+	env := NewEnvironment()
+	env.Define("greet", greetFunction())
+	env.Define("greeting", "caller")
+
+	expr := FunctionCallExpr{
+		Name: "greet",
+		Args: []Expr{LiteralExpr{Value: StringLiteral{Value: "Bob"}}},
+		NamedArgs: []NamedArg{
+			{Name: "suffix", Value: LiteralExpr{Value: StringLiteral{Value: "!"}}},
+			{Name: "greeting", Value: VariableExpr{Name: "greeting"}},
+		},
+	}
+
+	result, err := interp.EvaluateExpression(expr, env)
+
+	require.NoError(t, err)
+	assert.Equal(t, "callerBob!", result)
+}
+
+func TestExecuteUserDefinedFunction_NamedArgErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []Expr
+		namedArgs []NamedArg
+		want      string
+	}{
+		{
+			name:      "missing required argument",
+			namedArgs: []NamedArg{{Name: "greeting", Value: LiteralExpr{Value: StringLiteral{Value: "Hi"}}}},
+			want:      "missing required argument name in function greet",
+		},
+		{
+			name:      "unknown parameter",
+			args:      []Expr{LiteralExpr{Value: StringLiteral{Value: "Ann"}}},
+			namedArgs: []NamedArg{{Name: "greting", Value: LiteralExpr{Value: StringLiteral{Value: "Hi"}}}},
+			want:      "function greet has no parameter greting",
+		},
+		{
+			name:      "argument given twice",
+			args:      []Expr{LiteralExpr{Value: StringLiteral{Value: "Ann"}}},
+			namedArgs: []NamedArg{{Name: "name", Value: LiteralExpr{Value: StringLiteral{Value: "Bob"}}}},
+			want:      "argument name of function greet is given more than once",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp := NewInterpreter()
+			env := NewEnvironment()
+			env.Define("greet", greetFunction())
+
+			_, err := interp.EvaluateExpression(FunctionCallExpr{Name: "greet", Args: tt.args, NamedArgs: tt.namedArgs}, env)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
 // Test Path Parameter Extraction
 
 func TestExtractPathParams_Simple(t *testing.T) {
@@ -0,0 +1,269 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Program is an entry file together with every file it imports, merged into
+// a single module
+type Program struct {
+	Entry   string                   // Absolute path of the entry file
+	Module  *Module                  // Merged module: imports, then dependencies' items, then the entry's
+	Files   []string                 // Every loaded file, dependencies before dependents, entry last
+	Modules map[string]*LoadedModule // Parsed files keyed by absolute path
+
+	origins map[Item]string
+}
+
+// FileOf returns the file an item of the merged module was declared in
+func (p *Program) FileOf(item Item) string {
+	return p.origins[item]
+}
+
+// FunctionAliases maps each name an import binds a function to, other than
+// its own, to the function's name: the alias of a selective import (from
+// "./m" import { f as g } binds g) and the namespaced name of a full import
+// (import "./m" binds m.f)
+func (p *Program) FunctionAliases() map[string]string {
+	aliases := make(map[string]string)
+	for _, item := range p.Module.Items {
+		stmt, ok := item.(*ImportStatement)
+		if !ok {
+			continue
+		}
+		loaded := p.Modules[stmt.Path]
+		if loaded == nil {
+			continue
+		}
+		if stmt.Selective {
+			for _, name := range stmt.Names {
+				if _, isFn := loaded.Exports[name.Name].(*Function); isFn && name.Alias != "" {
+					aliases[name.Alias] = name.Name
+				}
+			}
+			continue
+		}
+		namespace := ImportNamespace(stmt)
+		for name, exported := range loaded.Exports {
+			if _, isFn := exported.(*Function); isFn {
+				aliases[namespace+"."+name] = name
+			}
+		}
+	}
+	return aliases
+}
+
+// Prime stores every loaded file in the resolver's cache so that import
+// statements processed later reuse the already parsed modules
+func (p *Program) Prime(resolver *ModuleResolver) {
+	for path, mod := range p.Modules {
+		resolver.ModuleCache[path] = mod
+	}
+}
+
+// ProgramLoader loads an entry file and its imports. Import paths are
+// resolved relative to the importing file, each file is parsed once, and
+// import cycles are reported as errors.
+type ProgramLoader struct {
+	resolver *ModuleResolver
+	modules  map[string]*LoadedModule
+	order    []string
+	stack    []string
+	targets  map[*ImportStatement]string
+	read     []string
+}
+
+// NewProgramLoader creates a loader that parses files with parse
+func NewProgramLoader(parse func(source string) (*Module, error)) *ProgramLoader {
+	resolver := NewModuleResolver()
+	resolver.SetParseFunc(parse)
+	return &ProgramLoader{resolver: resolver}
+}
+
+// Load reads, parses and merges the entry file and everything it imports
+func (l *ProgramLoader) Load(entry string) (*Program, error) {
+	source, err := os.ReadFile(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return l.LoadSource(entry, string(source))
+}
+
+// LoadSource is like Load but uses source as the entry file's contents,
+// which lets editors load unsaved buffers
+func (l *ProgramLoader) LoadSource(entry string, source string) (*Program, error) {
+	path, err := filepath.Abs(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	l.modules = make(map[string]*LoadedModule)
+	l.order = nil
+	l.stack = nil
+	l.targets = make(map[*ImportStatement]string)
+	l.read = []string{path}
+
+	module, err := l.resolver.ParseFunc(source)
+	if err != nil {
+		return nil, fmt.Errorf("parse error in %s: %w", path, err)
+	}
+	if err := l.visit(path, module); err != nil {
+		return nil, err
+	}
+	return l.merge(path)
+}
+
+// Visited returns every file the last load read, in the order it read
+// them. Unlike Program.Files it is complete when loading failed, including
+// the file that did not parse, so a dev server can watch it for a fix.
+func (l *ProgramLoader) Visited() []string {
+	return l.read
+}
+
+// visit loads the imports of an already parsed file depth-first
+func (l *ProgramLoader) visit(path string, module *Module) error {
+	l.stack = append(l.stack, path)
+	defer func() {
+		l.stack = l.stack[:len(l.stack)-1]
+	}()
+
+	for _, item := range module.Items {
+		importStmt, ok := item.(*ImportStatement)
+		if !ok {
+			continue
+		}
+
+		target, err := l.resolver.resolvePath(importStmt.Path, filepath.Dir(path))
+		if err != nil {
+			return fmt.Errorf("%s: failed to import '%s': %w", path, importStmt.Path, err)
+		}
+		l.targets[importStmt] = target
+
+		for _, loading := range l.stack {
+			if loading == target {
+				chain := append(append([]string{}, l.stack...), target)
+				return fmt.Errorf("circular import: %s", strings.Join(chain, " -> "))
+			}
+		}
+		if _, done := l.modules[target]; done {
+			continue
+		}
+
+		content, err := os.ReadFile(target)
+		if err != nil {
+			return fmt.Errorf("failed to read module %s: %w", target, err)
+		}
+		l.read = append(l.read, target)
+		imported, err := l.resolver.ParseFunc(string(content))
+		if err != nil {
+			return fmt.Errorf("parse error in %s: %w", target, err)
+		}
+		if err := l.visit(target, imported); err != nil {
+			return err
+		}
+	}
+
+	l.modules[path] = &LoadedModule{
+		Path:      path,
+		Module:    module,
+		Exports:   l.resolver.extractExports(module),
+		Namespace: l.resolver.extractNamespace(module),
+	}
+	l.order = append(l.order, path)
+	return nil
+}
+
+// merge combines all loaded files into one module. Import statements are
+// kept (with resolved absolute paths) so namespaced access keeps working;
+// every other item is copied once, and a name declared in two files is an error.
+func (l *ProgramLoader) merge(entry string) (*Program, error) {
+	program := &Program{
+		Entry:   entry,
+		Files:   l.order,
+		Modules: l.modules,
+		origins: make(map[Item]string),
+	}
+
+	var imports, items []Item
+	seenImports := make(map[string]bool)
+	declared := make(map[string]string)
+
+	for _, path := range l.order {
+		for _, item := range l.modules[path].Module.Items {
+			switch it := item.(type) {
+			case *ImportStatement:
+				resolved := *it
+				resolved.Path = l.targets[it]
+				key := importKey(&resolved)
+				if seenImports[key] {
+					continue
+				}
+				seenImports[key] = true
+				imports = append(imports, &resolved)
+				continue
+			case *ModuleDecl:
+				if path != entry {
+					continue
+				}
+			}
+
+			if key := declarationKey(item); key != "" {
+				if prev, ok := declared[key]; ok && prev != path {
+					return nil, fmt.Errorf("duplicate %s: declared in %s and %s", key, prev, path)
+				}
+				// A constant declared twice in one file would otherwise
+				// keep the first value in compiled routes and fail to load
+				// in the interpreter
+				if _, isConst := item.(*ConstDecl); isConst && declared[key] == path {
+					return nil, fmt.Errorf("duplicate %s: declared twice in %s", key, path)
+				}
+				declared[key] = path
+			}
+			program.origins[item] = path
+			items = append(items, item)
+		}
+	}
+
+	program.Module = &Module{Items: append(imports, items...)}
+	return program, nil
+}
+
+// importKey identifies an import statement for de-duplication
+func importKey(stmt *ImportStatement) string {
+	names := make([]string, len(stmt.Names))
+	for i, name := range stmt.Names {
+		names[i] = name.Name + " as " + name.Alias
+	}
+	return fmt.Sprintf("%s|%s|%t|%s", stmt.Path, stmt.Alias, stmt.Selective, strings.Join(names, ","))
+}
+
+// declarationKey describes the name an item declares, or "" for items that
+// may appear in several files
+func declarationKey(item Item) string {
+	switch it := item.(type) {
+	case *TypeDef:
+		return fmt.Sprintf("type '%s'", it.Name)
+	case *EnumDef:
+		return fmt.Sprintf("type '%s'", it.Name)
+	case *TraitDef:
+		return fmt.Sprintf("trait '%s'", it.Name)
+	case *Function:
+		return fmt.Sprintf("function '%s'", it.Name)
+	case *Command:
+		return fmt.Sprintf("command '%s'", it.Name)
+	case *ConstDecl:
+		return fmt.Sprintf("constant '%s'", it.Name)
+	case *Route:
+		return fmt.Sprintf("route %s %s", it.Method.String(), it.Path)
+	case *WebSocketRoute:
+		return fmt.Sprintf("websocket route %s", it.Path)
+	case *ErrorHandler:
+		return fmt.Sprintf("@ %s handler", it.Kind)
+	}
+	return ""
+}
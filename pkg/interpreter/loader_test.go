@@ -0,0 +1,263 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseLoaderSource parses GLYPH source for loader tests
+func parseLoaderSource(source string) (*Module, error) {
+	tokens, err := parser.NewLexer(source).Tokenize()
+	if err != nil {
+		return nil, err
+	}
+	return parser.NewParser(tokens).Parse()
+}
+
+// writeLoaderFiles writes name -> source pairs into a temp directory
+func writeLoaderFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(source), 0600))
+	}
+	return dir
+}
+
+// TestProgramLoader_MergesImports tests that types, functions and routes from
+// imported files end up in one module
+func TestProgramLoader_MergesImports(t *testing.T) {
+	dir := writeLoaderFiles(t, map[string]string{
+		"main.glyph":      "import \"./types.glyph\"\nimport \"./routes.glyph\"\n",
+		"types.glyph":     ": User {\n  name: str!\n}\n",
+		"routes.glyph":    "import \"./lib/greet\"\n@ GET /hello {\n  > {message: greet(\"world\")}\n}\n",
+		"lib/greet.glyph": "! greet(name: str): str {\n  > \"Hello, \" + name\n}\n",
+	})
+
+	program, err := NewProgramLoader(parseLoaderSource).Load(filepath.Join(dir, "main.glyph"))
+	require.NoError(t, err)
+
+	entry, _ := filepath.Abs(filepath.Join(dir, "main.glyph"))
+	assert.Equal(t, entry, program.Entry)
+	require.Len(t, program.Files, 4)
+	assert.Equal(t, entry, program.Files[len(program.Files)-1])
+
+	var types, functions, routes []string
+	for _, item := range program.Module.Items {
+		switch it := item.(type) {
+		case *TypeDef:
+			types = append(types, it.Name)
+		case *Function:
+			functions = append(functions, it.Name)
+			assert.Equal(t, filepath.Join(filepath.Dir(entry), "lib", "greet.glyph"), program.FileOf(item))
+		case *Route:
+			routes = append(routes, it.Path)
+		case *ImportStatement:
+			assert.True(t, filepath.IsAbs(it.Path), "import path should be resolved: %s", it.Path)
+		}
+	}
+	assert.Equal(t, []string{"User"}, types)
+	assert.Equal(t, []string{"greet"}, functions)
+	assert.Equal(t, []string{"/hello"}, routes)
+}
+
+// TestProgramLoader_VisitedIncludesFailedFile tests that Visited lists the
+// files read before a parse error, and the file that failed
+func TestProgramLoader_VisitedIncludesFailedFile(t *testing.T) {
+	dir := writeLoaderFiles(t, map[string]string{
+		"main.glyph":          "import \"./types.glyph\"\nimport \"./lib/broken.glyph\"\n",
+		"types.glyph":         ": User {\n  name: str!\n}\n",
+		"lib/broken.glyph":    "@ GET /x {\n  > {v: \n",
+		"lib/unrelated.glyph": ": Other {\n  id: int!\n}\n",
+	})
+	entry, _ := filepath.Abs(filepath.Join(dir, "main.glyph"))
+
+	loader := NewProgramLoader(parseLoaderSource)
+	_, err := loader.Load(entry)
+	require.Error(t, err)
+	assert.Equal(t, []string{
+		entry,
+		filepath.Join(dir, "types.glyph"),
+		filepath.Join(dir, "lib", "broken.glyph"),
+	}, loader.Visited())
+
+	// A successful load lists every file it read
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib", "broken.glyph"), []byte("@ GET /x {\n  > {v: 1}\n}\n"), 0600))
+	program, err := loader.Load(entry)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, program.Files, loader.Visited())
+}
+
+// TestProgramLoader_ParsesEachFileOnce tests that diamond imports share one parse
+func TestProgramLoader_ParsesEachFileOnce(t *testing.T) {
+	dir := writeLoaderFiles(t, map[string]string{
+		"main.glyph":   "import \"./a\"\nimport \"./b\"\n",
+		"a.glyph":      "import \"./shared\"\n! a(): int {\n  > 1\n}\n",
+		"b.glyph":      "import \"./shared\"\n! b(): int {\n  > 2\n}\n",
+		"shared.glyph": "! shared(): int {\n  > 3\n}\n",
+	})
+
+	parses := 0
+	loader := NewProgramLoader(func(source string) (*Module, error) {
+		parses++
+		return parseLoaderSource(source)
+	})
+	program, err := loader.Load(filepath.Join(dir, "main.glyph"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, parses)
+	assert.Len(t, program.Files, 4)
+
+	// Imports are de-duplicated after path resolution
+	imports := 0
+	for _, item := range program.Module.Items {
+		if _, ok := item.(*ImportStatement); ok {
+			imports++
+		}
+	}
+	assert.Equal(t, 3, imports)
+
+	// Priming lets the interpreter reuse the parsed files
+	interp := NewInterpreter()
+	interp.GetModuleResolver().SetParseFunc(func(string) (*Module, error) {
+		t.Fatal("imported file parsed twice")
+		return nil, nil
+	})
+	program.Prime(interp.GetModuleResolver())
+	require.NoError(t, interp.LoadModuleWithPath(*program.Module, dir))
+
+	_, ok := interp.GetFunction("shared")
+	assert.True(t, ok)
+}
+
+// TestProgramLoader_ImportedTypeInScope tests that a type declared in an
+// imported file validates the input of a route in the importing file
+func TestProgramLoader_ImportedTypeInScope(t *testing.T) {
+	dir := writeLoaderFiles(t, map[string]string{
+		"main.glyph":   "import \"./models\"\n@ POST /users {\n  < input: User\n  > {name: input.name}\n}\n",
+		"models.glyph": ": User {\n  name: str!\n}\n",
+	})
+	program, err := NewProgramLoader(parseLoaderSource).Load(filepath.Join(dir, "main.glyph"))
+	require.NoError(t, err)
+
+	interp := NewInterpreter()
+	interp.GetModuleResolver().SetParseFunc(parseLoaderSource)
+	program.Prime(interp.GetModuleResolver())
+	require.NoError(t, interp.LoadModuleWithPath(*program.Module, dir))
+
+	var route *Route
+	for _, item := range program.Module.Items {
+		if r, ok := item.(*Route); ok {
+			route = r
+		}
+	}
+	require.NotNil(t, route)
+
+	response, err := interp.ExecuteRoute(route, &Request{Path: "/users", Method: "POST", Body: map[string]interface{}{"name": "ada"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "ada"}, response.Body)
+
+	response, _ = interp.ExecuteRoute(route, &Request{Path: "/users", Method: "POST", Body: map[string]interface{}{}})
+	assert.Equal(t, 400, response.StatusCode)
+}
+
+// TestProgram_FunctionAliases tests the names imports bind functions to
+func TestProgram_FunctionAliases(t *testing.T) {
+	dir := writeLoaderFiles(t, map[string]string{
+		"main.glyph":     "import \"./models\" as m\nfrom \"./lib/text\" import { shout as loud, quiet }\n",
+		"models.glyph":   ": User {\n  name: str!\n}\n! greet(u: User): str {\n  > u.name\n}\n",
+		"lib/text.glyph": "! shout(s: str): str {\n  > upper(s)\n}\n! quiet(s: str): str {\n  > lower(s)\n}\n",
+	})
+	program, err := NewProgramLoader(parseLoaderSource).Load(filepath.Join(dir, "main.glyph"))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"m.greet": "greet",
+		"loud":    "shout",
+	}, program.FunctionAliases())
+}
+
+// TestProgramLoader_Errors tests cycle, duplicate and missing-file errors
+func TestProgramLoader_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		contains []string
+	}{
+		{
+			name: "cycle",
+			files: map[string]string{
+				"main.glyph": "import \"./a\"\n",
+				"a.glyph":    "import \"./b\"\n",
+				"b.glyph":    "import \"./a\"\n",
+			},
+			contains: []string{"circular import", "a.glyph -> ", "b.glyph -> ", "a.glyph"},
+		},
+		{
+			name: "cycle through entry",
+			files: map[string]string{
+				"main.glyph": "import \"./a\"\n",
+				"a.glyph":    "import \"./main\"\n",
+			},
+			contains: []string{"circular import", "main.glyph -> ", "a.glyph -> "},
+		},
+		{
+			name: "duplicate type",
+			files: map[string]string{
+				"main.glyph":  "import \"./types\"\n: User {\n  id: int\n}\n",
+				"types.glyph": ": User {\n  name: str\n}\n",
+			},
+			contains: []string{"duplicate type 'User'", "types.glyph", "main.glyph"},
+		},
+		{
+			name: "duplicate route",
+			files: map[string]string{
+				"main.glyph":   "import \"./routes\"\n@ GET /users {\n  > {a: 1}\n}\n",
+				"routes.glyph": "@ GET /users {\n  > {a: 2}\n}\n",
+			},
+			contains: []string{"duplicate route GET /users", "routes.glyph", "main.glyph"},
+		},
+		{
+			name: "constant declared twice in one file",
+			files: map[string]string{
+				"main.glyph": "const MAX = 1\nconst MAX = 2\n",
+			},
+			contains: []string{"duplicate constant 'MAX'", "declared twice in", "main.glyph"},
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				"main.glyph": "import \"./missing\"\n",
+			},
+			contains: []string{"failed to import './missing'", "main.glyph"},
+		},
+		{
+			name: "parse error in import",
+			files: map[string]string{
+				"main.glyph":   "import \"./broken\"\n",
+				"broken.glyph": "@ GET /x {\n",
+			},
+			contains: []string{"parse error in", "broken.glyph"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeLoaderFiles(t, tt.files)
+			_, err := NewProgramLoader(parseLoaderSource).Load(filepath.Join(dir, "main.glyph"))
+			require.Error(t, err)
+			for _, want := range tt.contains {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
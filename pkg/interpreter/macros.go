@@ -112,6 +112,8 @@ func (i *Interpreter) substituteNode(node Node, subs map[string]Expr) (Node, err
 		return AssignStatement{
 			Target: i.substituteString(n.Target, subs),
 			Value:  subExpr,
+			Const:  n.Const,
+			Type:   n.Type,
 		}, nil
 
 	case ReassignStatement:
@@ -164,6 +166,24 @@ func (i *Interpreter) substituteNode(node Node, subs map[string]Expr) (Node, err
 			Body:      body,
 		}, nil
 
+	case BackgroundStatement:
+		body, err := i.substituteStatements(n.Body, subs)
+		if err != nil {
+			return nil, err
+		}
+		return BackgroundStatement{Body: body, Pos: n.Pos}, nil
+
+	case TryStatement:
+		body, err := i.substituteStatements(n.Body, subs)
+		if err != nil {
+			return nil, err
+		}
+		catchBlock, err := i.substituteStatements(n.Catch, subs)
+		if err != nil {
+			return nil, err
+		}
+		return TryStatement{Body: body, ErrorVar: n.ErrorVar, Catch: catchBlock, Pos: n.Pos}, nil
+
 	case ForStatement:
 		iter, err := i.substituteExpr(n.Iterable, subs)
 		if err != nil {
@@ -250,6 +270,32 @@ func (i *Interpreter) substituteExpr(expr Expr, subs map[string]Expr) (Expr, err
 		}
 		return UnaryOpExpr{Op: ex.Op, Right: right}, nil
 
+	case TernaryExpr:
+		cond, err := i.substituteExpr(ex.Condition, subs)
+		if err != nil {
+			return nil, err
+		}
+		thenExpr, err := i.substituteExpr(ex.Then, subs)
+		if err != nil {
+			return nil, err
+		}
+		elseExpr, err := i.substituteExpr(ex.Else, subs)
+		if err != nil {
+			return nil, err
+		}
+		return TernaryExpr{Condition: cond, Then: thenExpr, Else: elseExpr, Pos: ex.Pos}, nil
+
+	case InterpolatedStringExpr:
+		parts := make([]Expr, len(ex.Parts))
+		for idx, part := range ex.Parts {
+			subPart, err := i.substituteExpr(part, subs)
+			if err != nil {
+				return nil, err
+			}
+			parts[idx] = subPart
+		}
+		return InterpolatedStringExpr{Parts: parts, Pos: ex.Pos}, nil
+
 	case FunctionCallExpr:
 		subArgs := make([]Expr, len(ex.Args))
 		for idx, arg := range ex.Args {
@@ -259,14 +305,22 @@ func (i *Interpreter) substituteExpr(expr Expr, subs map[string]Expr) (Expr, err
 			}
 			subArgs[idx] = subArg
 		}
-		return FunctionCallExpr{Name: ex.Name, Args: subArgs}, nil
+		var subNamed []NamedArg
+		for _, arg := range ex.NamedArgs {
+			value, err := i.substituteExpr(arg.Value, subs)
+			if err != nil {
+				return nil, err
+			}
+			subNamed = append(subNamed, NamedArg{Name: arg.Name, Value: value, Pos: arg.Pos})
+		}
+		return FunctionCallExpr{Name: ex.Name, Args: subArgs, NamedArgs: subNamed}, nil
 
 	case FieldAccessExpr:
 		obj, err := i.substituteExpr(ex.Object, subs)
 		if err != nil {
 			return nil, err
 		}
-		return FieldAccessExpr{Object: obj, Field: ex.Field}, nil
+		return FieldAccessExpr{Object: obj, Field: ex.Field, Optional: ex.Optional}, nil
 
 	case ArrayIndexExpr:
 		arr, err := i.substituteExpr(ex.Array, subs)
@@ -301,6 +355,13 @@ func (i *Interpreter) substituteExpr(expr Expr, subs map[string]Expr) (Expr, err
 		}
 		return ArrayExpr{Elements: subElems}, nil
 
+	case SpreadExpr:
+		value, err := i.substituteExpr(ex.Value, subs)
+		if err != nil {
+			return nil, err
+		}
+		return SpreadExpr{Value: value, Pos: ex.Pos}, nil
+
 	case LiteralExpr:
 		if strLit, ok := ex.Value.(StringLiteral); ok {
 			newVal := i.substituteString(strLit.Value, subs)
@@ -0,0 +1,89 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const matchTypeSource = `: User {
+  id: int!
+  name: str!
+}
+
+: Error {
+  code: int!
+  message: str!
+}
+
+: Status = "active" | "banned"
+
+@ POST /describe {
+  > match input.value {
+    User {name} => "user " + name,
+    Error {message, code: 404} => "missing: " + message,
+    Error {message: m} when m != "" => "error: " + m,
+    Status {} => "status",
+    _ => "unknown"
+  }
+}
+`
+
+func TestMatchExpr_TypePattern(t *testing.T) {
+	module, err := parseLoaderSource(matchTypeSource)
+	require.NoError(t, err)
+	interp := NewInterpreter()
+	require.NoError(t, interp.LoadModule(*module))
+	route := module.Items[3].(*Route)
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{"first variant binds a field", map[string]interface{}{"id": int64(1), "name": "Ada"}, "user Ada"},
+		{"second variant with a nested literal", map[string]interface{}{"code": int64(404), "message": "no user"}, "missing: no user"},
+		{"second variant binding a renamed field", map[string]interface{}{"code": int64(500), "message": "boom"}, "error: boom"},
+		{"guard failure falls through", map[string]interface{}{"code": int64(500), "message": ""}, "unknown"},
+		{"missing required field is not the variant", map[string]interface{}{"id": int64(1)}, "unknown"},
+		{"wrong field type is not the variant", map[string]interface{}{"id": "1", "name": "Ada"}, "unknown"},
+		{"enum value", "banned", "status"},
+		{"value outside the enum", "deleted", "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := interp.ExecuteRoute(route, &Request{
+				Method: "POST",
+				Path:   "/describe",
+				Body:   map[string]interface{}{"value": tt.value},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, resp.Body)
+		})
+	}
+}
+
+func TestMatchExpr_TypePatternBindingsAreScopedToTheArm(t *testing.T) {
+	result, err := runRouteSource(t, `@ GET /t {
+  $ message = "outer"
+  $ inner = match {message: "inner"} {
+    {message} => message
+  }
+  > {inner: inner, outer: message}
+}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"inner": "inner", "outer": "outer"}, result)
+}
+
+func TestMatchExpr_TypePatternUnknownType(t *testing.T) {
+	_, err := runRouteSource(t, `@ GET /t {
+  > match {message: "x"} {
+    Missing {message} => message,
+    _ => "other"
+  }
+}`)
+	assert.ErrorContains(t, err, "unknown type 'Missing' in match pattern")
+}
@@ -0,0 +1,50 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"fmt"
+)
+
+// checkMiddlewareRefs reports a route that applies a middleware the loaded
+// modules do not define
+func (i *Interpreter) checkMiddlewareRefs(module Module) error {
+	for _, item := range module.Items {
+		route, ok := item.(*Route)
+		if !ok {
+			continue
+		}
+		for _, ref := range route.Middlewares {
+			if _, defined := i.middlewares[ref.Name]; !defined {
+				return fmt.Errorf("route %s %s: undefined middleware '%s'", route.Method, route.Path, ref.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// GetMiddleware returns the middleware definition named name
+func (i *Interpreter) GetMiddleware(name string) (MiddlewareDef, bool) {
+	mw, ok := i.middlewares[name]
+	return mw, ok
+}
+
+// executeMiddlewares runs the bodies of the route's named middleware in the
+// order they are applied, in the route's environment, so the route sees the
+// values they define. handled is true when one of them returned a value,
+// which answers the request in place of the route.
+func (i *Interpreter) executeMiddlewares(route *Route, env *Environment) (result interface{}, handled bool, err error) {
+	for _, ref := range route.Middlewares {
+		mw, ok := i.middlewares[ref.Name]
+		if !ok {
+			return nil, false, fmt.Errorf("undefined middleware '%s'", ref.Name)
+		}
+		if _, err := i.executeStatements(mw.Body, env); err != nil {
+			if val, isReturn := unwrapReturn(err); isReturn {
+				return val, true, nil
+			}
+			return nil, false, fmt.Errorf("middleware %s: %w", ref.Name, err)
+		}
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,163 @@
+package interpreter
+
+import (
+	"testing"
+
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const middlewareSource = `@ middleware requireAdmin {
+  if auth.user.role != "admin" {
+    error(403, "forbidden")
+  }
+  $ trail = "admin"
+}
+
+@ middleware tag {
+  trail = trail + ",tag"
+}
+
+@ middleware cached {
+  if query.cached == "yes" {
+    > {cached: true}
+  }
+}
+
+@ GET /admin -> str {
+  + auth(jwt)
+  + requireAdmin
+  + tag
+  > trail + ",route"
+}
+
+@ GET /report -> int {
+  + cached
+  > 42
+}
+
+@ GET /guarded {
+  + auth(jwt)
+  + requireAdmin
+  try {
+    > "route ran"
+  } catch err {
+    > "caught"
+  }
+}`
+
+// loadMiddlewareRoutes loads middlewareSource and returns its routes by path
+func loadMiddlewareRoutes(t *testing.T) (*Interpreter, map[string]*Route) {
+	t.Helper()
+	module, err := parseLoaderSource(middlewareSource)
+	require.NoError(t, err)
+	interp := NewInterpreter()
+	require.NoError(t, interp.LoadModule(*module))
+
+	routes := make(map[string]*Route)
+	for _, item := range module.Items {
+		if route, ok := item.(*Route); ok {
+			routes[route.Path] = route
+		}
+	}
+	return interp, routes
+}
+
+func authAs(role string) map[string]interface{} {
+	return map[string]interface{}{
+		"user": map[string]interface{}{"id": int64(1), "username": "ada", "role": role},
+	}
+}
+
+func TestMiddlewarePassThrough(t *testing.T) {
+	interp, routes := loadMiddlewareRoutes(t)
+
+	resp, err := interp.ExecuteRoute(routes["/admin"], &Request{Path: "/admin", Method: "GET", AuthData: authAs("admin")})
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "admin,tag,route", resp.Body, "middleware runs in + order and shares the route's environment")
+
+	resp, err = interp.ExecuteRoute(routes["/report"], &Request{Path: "/report", Method: "GET"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), resp.Body)
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	interp, routes := loadMiddlewareRoutes(t)
+
+	t.Run("error", func(t *testing.T) {
+		resp, err := interp.ExecuteRoute(routes["/admin"], &Request{Path: "/admin", Method: "GET", AuthData: authAs("viewer")})
+		require.NoError(t, err)
+		assert.Equal(t, 403, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{"error": "forbidden"}, resp.Body)
+	})
+
+	t.Run("return skips the route and its return type", func(t *testing.T) {
+		resp, err := interp.ExecuteRoute(routes["/report"], &Request{Path: "/report?cached=yes", Method: "GET"})
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{"cached": true}, resp.Body)
+	})
+
+	t.Run("error is not caught by the route", func(t *testing.T) {
+		resp, err := interp.ExecuteRoute(routes["/guarded"], &Request{Path: "/guarded", Method: "GET", AuthData: authAs("viewer")})
+		require.NoError(t, err)
+		assert.Equal(t, 403, resp.StatusCode)
+	})
+}
+
+func TestUndefinedMiddleware(t *testing.T) {
+	module, err := parseLoaderSource(`@ GET /t {
+  + missing
+  > 1
+}`)
+	require.NoError(t, err)
+
+	err = NewInterpreter().LoadModule(*module)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "undefined middleware 'missing'")
+}
+
+func TestErrorBuiltin(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		status  int
+		wantErr string
+	}{
+		{"from a function", `! deny(): str {
+  error(404, "no such thing")
+  > "unreachable"
+}
+
+@ GET /t {
+  > deny()
+}`, 404, ""},
+		{"bad status", `@ GET /t {
+  error(200, "fine")
+}`, 500, "between 400 and 599"},
+		{"message must be a string", `@ GET /t {
+  error(400, 1)
+}`, 500, "must be a string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, err := parseLoaderSource(tt.source)
+			require.NoError(t, err)
+			interp := NewInterpreter()
+			require.NoError(t, interp.LoadModule(*module))
+			route := module.Items[len(module.Items)-1].(*Route)
+
+			resp, err := interp.ExecuteRoute(route, &Request{Path: route.Path, Method: "GET"})
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
@@ -192,6 +192,8 @@ func (r *ModuleResolver) extractExports(module *Module) map[string]interface{} {
 		case *TypeDef:
 			// All top-level types are exported
 			exports[it.Name] = it
+		case *EnumDef:
+			exports[it.Name] = it
 		case *Route:
 			// Routes are exported by their path+method
 			key := fmt.Sprintf("%s:%s", it.Method.String(), it.Path)
@@ -0,0 +1,93 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runRouteSource parses a single route and executes it
+func runRouteSource(t *testing.T, source string) (interface{}, error) {
+	t.Helper()
+	module, err := parseLoaderSource(source)
+	require.NoError(t, err)
+	route := module.Items[0].(*Route)
+	response, err := NewInterpreter().ExecuteRoute(route, &Request{Path: route.Path, Method: "GET"})
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+func TestOptionalChaining(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   interface{}
+	}{
+		{"missing field", `@ GET /t {
+  $ user = {name: "ada"}
+  > user?.profile?.bio
+}`, nil},
+		{"null value", `@ GET /t {
+  $ user = null
+  > user?.profile?.bio
+}`, nil},
+		{"present fields", `@ GET /t {
+  $ user = {profile: {bio: "hi"}}
+  > user?.profile?.bio
+}`, "hi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runRouteSource(t, tt.source)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
+
+func TestOptionalChaining_PlainAccessOnNullFails(t *testing.T) {
+	_, err := runRouteSource(t, `@ GET /t {
+  $ user = null
+  > user?.profile.bio
+}`)
+	assert.ErrorContains(t, err, "bio", "only the ?. link tolerates null")
+}
+
+func TestCoalesce(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   interface{}
+	}{
+		{"null to default", `@ GET /t {
+  $ user = {name: "ada"}
+  > user?.profile?.bio ?? "no bio"
+}`, "no bio"},
+		{"falsy value is kept", `@ GET /t {
+  $ count = 0
+  > count ?? 10
+}`, int64(0)},
+		{"chained", `@ GET /t {
+  $ a = null
+  $ b = null
+  > a ?? b ?? 3
+}`, int64(3)},
+		{"right side is lazy", `@ GET /t {
+  > "set" ?? missingFunction()
+}`, "set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runRouteSource(t, tt.source)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
@@ -271,4 +271,90 @@ func TestInterpreter_LambdaClosure(t *testing.T) {
 		assert.NotNil(t, closure.Env)
 		assert.Equal(t, lambda, closure.Lambda)
 	})
+
+	t.Run("lambda stored in a variable is callable", func(t *testing.T) {
+		interp := newTestInterpreter()
+		env := NewEnvironment()
+		env.Define("factor", int64(3))
+
+		// $ triple = (x) => x * factor
+		_, err := interp.ExecuteStatement(AssignStatement{
+			Target: "triple",
+			Value: LambdaExpr{
+				Params: []Field{{Name: "x"}},
+				Body: BinaryOpExpr{
+					Op:    Mul,
+					Left:  VariableExpr{Name: "x"},
+					Right: VariableExpr{Name: "factor"},
+				},
+			},
+		}, env)
+		require.NoError(t, err)
+
+		call := FunctionCallExpr{Name: "triple", Args: []Expr{LiteralExpr{Value: IntLiteral{Value: 4}}}}
+		result, err := interp.EvaluateExpression(call, env)
+		require.NoError(t, err)
+		assert.Equal(t, int64(12), result)
+
+		// Captured variables are read when the lambda runs, not copied
+		require.NoError(t, env.Set("factor", int64(10)))
+		result, err = interp.EvaluateExpression(call, env)
+		require.NoError(t, err)
+		assert.Equal(t, int64(40), result)
+
+		call.Args = append(call.Args, LiteralExpr{Value: IntLiteral{Value: 5}})
+		_, err = interp.EvaluateExpression(call, env)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "lambda expects at most 1 arguments, got 2")
+	})
+
+	t.Run("counters from one factory hold independent state", func(t *testing.T) {
+		// ! makeCounter() {
+		//   $ count = 0
+		//   > () => {
+		//     count = count + 1
+		//     > count
+		//   }
+		// }
+		makeCounter := Function{
+			Name: "makeCounter",
+			Body: []Statement{
+				AssignStatement{Target: "count", Value: LiteralExpr{Value: IntLiteral{Value: 0}}},
+				ReturnStatement{Value: LambdaExpr{
+					Block: []Statement{
+						ReassignStatement{
+							Target: "count",
+							Value: BinaryOpExpr{
+								Op:    Add,
+								Left:  VariableExpr{Name: "count"},
+								Right: LiteralExpr{Value: IntLiteral{Value: 1}},
+							},
+						},
+						ReturnStatement{Value: VariableExpr{Name: "count"}},
+					},
+				}},
+			},
+		}
+
+		interp := newTestInterpreter()
+		env := NewEnvironment()
+		env.Define("makeCounter", makeCounter)
+		for _, name := range []string{"a", "b"} {
+			counter, err := interp.EvaluateExpression(FunctionCallExpr{Name: "makeCounter"}, env)
+			require.NoError(t, err)
+			env.Define(name, counter)
+		}
+
+		next := func(name string) interface{} {
+			result, err := interp.EvaluateExpression(FunctionCallExpr{Name: name}, env)
+			require.NoError(t, err)
+			return result
+		}
+		assert.Equal(t, int64(1), next("a"))
+		assert.Equal(t, int64(2), next("a"))
+		assert.Equal(t, int64(1), next("b"))
+		assert.Equal(t, int64(3), next("a"))
+		assert.Equal(t, int64(2), next("b"))
+		assert.False(t, env.Has("count"), "count lives in each factory call's scope")
+	})
 }
@@ -0,0 +1,274 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/queue"
+)
+
+// DeadLetter is a message whose worker failed on every attempt
+type DeadLetter struct {
+	Queue    string
+	Message  interface{}
+	Attempts int
+	Err      error
+}
+
+// QueueRunner delivers messages from a queue.Queue to the @ queue workers of
+// an interpreter. Each queue is served by Concurrency goroutines; a failed
+// message is returned to its queue with Nack up to MaxRetries times, after
+// an exponential backoff, and then dead-lettered.
+type QueueRunner struct {
+	interp  *Interpreter
+	queue   queue.Queue
+	workers map[string]QueueWorker
+
+	// OnDeadLetter, if set before Start, is called for each dead-lettered
+	// message
+	OnDeadLetter func(DeadLetter)
+	// OnProcessed, if set before Start, is called for each message a worker
+	// handles successfully, with the worker's result
+	OnProcessed func(queueName string, result interface{}, elapsed time.Duration)
+	// OnError, if set before Start, is called when the queue fails
+	OnError func(queueName string, err error)
+
+	mu          sync.Mutex
+	deadLetters []DeadLetter
+	started     bool
+	stopped     bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// backoff returns the delay before retry n (1-based)
+	backoff func(retry int) time.Duration
+}
+
+// NewQueueRunner creates a runner that consumes the queues of the workers
+// loaded into interp from q, and makes enqueue() publish to q. The runner
+// closes q when it stops.
+func NewQueueRunner(interp *Interpreter, q queue.Queue) *QueueRunner {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &QueueRunner{
+		interp:  interp,
+		queue:   q,
+		workers: interp.GetQueueWorkers(),
+		ctx:     ctx,
+		cancel:  cancel,
+		backoff: exponentialBackoff,
+	}
+	interp.queueRunner.Store(r)
+	return r
+}
+
+// exponentialBackoff waits 100ms before the first retry, doubling up to 30s
+func exponentialBackoff(retry int) time.Duration {
+	delay := 100 * time.Millisecond
+	for n := 1; n < retry && delay < 30*time.Second; n++ {
+		delay *= 2
+	}
+	return min(delay, 30*time.Second)
+}
+
+// Start starts the worker goroutines
+func (r *QueueRunner) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started || r.stopped {
+		return
+	}
+	r.started = true
+
+	for name, worker := range r.workers {
+		concurrency := worker.Concurrency
+		if concurrency < 1 {
+			concurrency = 1
+		}
+		for n := 0; n < concurrency; n++ {
+			r.wg.Add(1)
+			go r.work(name, worker)
+		}
+	}
+}
+
+// Stop stops the workers after their current message, waits for them and
+// closes the queue. Messages waiting for a retry are returned to the queue
+// at once. Stop on a nil QueueRunner does nothing.
+func (r *QueueRunner) Stop() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return
+	}
+	r.stopped = true
+	r.mu.Unlock()
+
+	r.cancel()
+	r.wg.Wait()
+	r.interp.queueRunner.CompareAndSwap(r, nil)
+	if err := r.queue.Close(); err != nil {
+		r.reportError("", err)
+	}
+}
+
+// Enqueue adds a message to the named queue
+func (r *QueueRunner) Enqueue(queueName string, message interface{}) error {
+	if _, ok := r.workers[queueName]; !ok {
+		return fmt.Errorf("no queue worker for %q", queueName)
+	}
+
+	r.mu.Lock()
+	stopped := r.stopped
+	r.mu.Unlock()
+	if stopped {
+		return fmt.Errorf("queue %q is stopped", queueName)
+	}
+
+	return r.queue.Enqueue(r.ctx, queueName, message)
+}
+
+// DeadLetters returns the messages that failed on every attempt
+func (r *QueueRunner) DeadLetters() []DeadLetter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make([]DeadLetter, len(r.deadLetters))
+	copy(result, r.deadLetters)
+	return result
+}
+
+func (r *QueueRunner) reportError(queueName string, err error) {
+	if r.OnError != nil {
+		r.OnError(queueName, err)
+	}
+}
+
+// work delivers messages from the named queue until the runner stops
+func (r *QueueRunner) work(name string, worker QueueWorker) {
+	defer r.wg.Done()
+	failures := 0
+	for {
+		msg, err := r.queue.Dequeue(r.ctx, name)
+		if err != nil {
+			if r.ctx.Err() != nil {
+				return
+			}
+			// Back off while the queue is unavailable
+			r.reportError(name, err)
+			failures++
+			if !r.sleep(r.backoff(failures)) {
+				return
+			}
+			continue
+		}
+		failures = 0
+		r.deliver(&worker, msg)
+	}
+}
+
+// deliver runs the worker on msg, then acknowledges it, returns it to the
+// queue for a retry, or dead-letters it
+func (r *QueueRunner) deliver(worker *QueueWorker, msg *queue.Message) {
+	start := time.Now()
+	result, err := r.execute(worker, msg.Body)
+	if err == nil {
+		if ackErr := r.queue.Ack(r.ctx, msg); ackErr != nil {
+			r.reportError(msg.Queue, ackErr)
+		}
+		if r.OnProcessed != nil {
+			r.OnProcessed(msg.Queue, result, time.Since(start))
+		}
+		return
+	}
+
+	attempts := msg.Attempts + 1
+	if attempts <= worker.MaxRetries {
+		r.sleep(r.backoff(attempts))
+		// Requeue even when stopping, so the retry is not lost
+		if nackErr := r.queue.Nack(context.Background(), msg); nackErr != nil {
+			r.reportError(msg.Queue, nackErr)
+		}
+		return
+	}
+
+	if ackErr := r.queue.Ack(r.ctx, msg); ackErr != nil {
+		r.reportError(msg.Queue, ackErr)
+	}
+	dead := DeadLetter{Queue: msg.Queue, Message: msg.Body, Attempts: attempts, Err: err}
+	r.mu.Lock()
+	r.deadLetters = append(r.deadLetters, dead)
+	r.mu.Unlock()
+	if r.OnDeadLetter != nil {
+		r.OnDeadLetter(dead)
+	}
+}
+
+// sleep waits for d, returning false if the runner stops first
+func (r *QueueRunner) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-r.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// execute runs the worker, converting a panic into an error
+func (r *QueueRunner) execute(worker *QueueWorker, message interface{}) (result interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return r.interp.ExecuteQueueWorker(worker, message)
+}
+
+// builtinEnqueue implements enqueue(queue, message)
+func builtinEnqueue(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	return i.publish("enqueue", args, env)
+}
+
+// builtinQueuePublish implements queue.publish(queue, message), the same
+// builtin as enqueue()
+func builtinQueuePublish(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	return i.publish("queue.publish", args, env)
+}
+
+// publish evaluates (queue, message) arguments and sends the message to the
+// running queue runner. name is the builtin's name, for errors.
+func (i *Interpreter) publish(name string, args []Expr, env *Environment) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("%s() expects 2 arguments (queue, message), got %d", name, len(args))
+	}
+	nameVal, err := i.EvaluateExpression(args[0], env)
+	if err != nil {
+		return nil, err
+	}
+	queueName, ok := nameVal.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s() queue name must be a string, got %T", name, nameVal)
+	}
+	message, err := i.EvaluateExpression(args[1], env)
+	if err != nil {
+		return nil, err
+	}
+
+	runner := i.queueRunner.Load()
+	if runner == nil {
+		return nil, fmt.Errorf("%s(): no queue runner is running", name)
+	}
+	if err := runner.Enqueue(queueName, message); err != nil {
+		return nil, fmt.Errorf("%s(): %w", name, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,302 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withQueueHook registers a queueHook(message) builtin that calls hook, so
+// tests can observe and control worker executions
+func withQueueHook(t *testing.T, hook func(message interface{}) (interface{}, error)) {
+	t.Helper()
+	builtinFuncs["queueHook"] = func(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+		message, err := i.EvaluateExpression(args[0], env)
+		if err != nil {
+			return nil, err
+		}
+		return hook(message)
+	}
+	t.Cleanup(func() { delete(builtinFuncs, "queueHook") })
+}
+
+// newQueueRunner loads source and returns a runner, started on an
+// in-memory queue, whose retries back off for 1ms
+func newQueueRunner(t *testing.T, source string) (*Interpreter, *QueueRunner) {
+	t.Helper()
+	module, err := parseLoaderSource(source)
+	require.NoError(t, err)
+	interp := NewInterpreter()
+	require.NoError(t, interp.LoadModule(*module))
+
+	runner := NewQueueRunner(interp, queue.NewMemoryQueue())
+	runner.backoff = func(int) time.Duration { return time.Millisecond }
+	runner.Start()
+	t.Cleanup(runner.Stop)
+	return interp, runner
+}
+
+func TestQueueRunner_ConcurrencyLimit(t *testing.T) {
+	var running, peak atomic.Int32
+	var done sync.WaitGroup
+	release := make(chan struct{})
+	withQueueHook(t, func(message interface{}) (interface{}, error) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		done.Done()
+		return message, nil
+	})
+
+	_, runner := newQueueRunner(t, `& "jobs" {
+  + concurrency(2)
+  > queueHook(message)
+}
+`)
+
+	const jobs = 6
+	done.Add(jobs)
+	for n := 0; n < jobs; n++ {
+		require.NoError(t, runner.Enqueue("jobs", int64(n)))
+	}
+
+	// Two workers pick up jobs and block; the rest wait in the queue
+	require.Eventually(t, func() bool { return running.Load() == 2 }, 5*time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(2), running.Load())
+
+	close(release)
+	done.Wait()
+	assert.Equal(t, int32(2), peak.Load(), "at most concurrency(2) jobs run at once")
+	assert.Empty(t, runner.DeadLetters())
+}
+
+func TestQueueRunner_RetryThenSucceed(t *testing.T) {
+	var attempts atomic.Int32
+	succeeded := make(chan interface{}, 1)
+	withQueueHook(t, func(message interface{}) (interface{}, error) {
+		if attempts.Add(1) < 3 {
+			return nil, errors.New("temporary failure")
+		}
+		succeeded <- message
+		return message, nil
+	})
+
+	_, runner := newQueueRunner(t, `& "email.send" {
+  + retries(3)
+  > queueHook(message.to)
+}
+`)
+	require.NoError(t, runner.Enqueue("email.send", map[string]interface{}{"to": "ada@example.com"}))
+
+	select {
+	case to := <-succeeded:
+		assert.Equal(t, "ada@example.com", to)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not delivered")
+	}
+	assert.Equal(t, int32(3), attempts.Load(), "two failures, then success")
+	assert.Empty(t, runner.DeadLetters())
+}
+
+func TestQueueRunner_DeadLetter(t *testing.T) {
+	var attempts atomic.Int32
+	withQueueHook(t, func(message interface{}) (interface{}, error) {
+		attempts.Add(1)
+		return nil, errors.New("permanent failure")
+	})
+
+	_, runner := newQueueRunner(t, `& "webhook.deliver" {
+  + retries(2)
+  > queueHook(message)
+}
+`)
+	dead := make(chan DeadLetter, 1)
+	runner.OnDeadLetter = func(d DeadLetter) { dead <- d }
+	require.NoError(t, runner.Enqueue("webhook.deliver", "payload"))
+
+	select {
+	case d := <-dead:
+		assert.Equal(t, "webhook.deliver", d.Queue)
+		assert.Equal(t, "payload", d.Message)
+		assert.Equal(t, 3, d.Attempts)
+		assert.ErrorContains(t, d.Err, "permanent failure")
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not dead-lettered")
+	}
+	assert.Equal(t, int32(3), attempts.Load())
+	assert.Len(t, runner.DeadLetters(), 1)
+}
+
+func TestQueueRunner_EnqueueBuiltin(t *testing.T) {
+	received := make(chan interface{}, 1)
+	withQueueHook(t, func(message interface{}) (interface{}, error) {
+		received <- message
+		return nil, nil
+	})
+
+	interp, _ := newQueueRunner(t, `& "report.generate" {
+  > queueHook(message.type)
+}
+`)
+	env := NewEnvironment()
+	result, err := interp.EvaluateExpression(FunctionCallExpr{
+		Name: "enqueue",
+		Args: []Expr{
+			LiteralExpr{Value: StringLiteral{Value: "report.generate"}},
+			ObjectExpr{Fields: []ObjectField{{Key: "type", Value: LiteralExpr{Value: StringLiteral{Value: "weekly"}}}}},
+		},
+	}, env)
+	require.NoError(t, err)
+	assert.Equal(t, true, result)
+
+	select {
+	case got := <-received:
+		assert.Equal(t, "weekly", got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not delivered")
+	}
+
+	_, err = interp.EvaluateExpression(FunctionCallExpr{
+		Name: "enqueue",
+		Args: []Expr{LiteralExpr{Value: StringLiteral{Value: "missing"}}, LiteralExpr{Value: IntLiteral{Value: 1}}},
+	}, env)
+	assert.ErrorContains(t, err, `no queue worker for "missing"`)
+}
+
+func TestQueueRunner_PublishBuiltin(t *testing.T) {
+	received := make(chan interface{}, 1)
+	withQueueHook(t, func(message interface{}) (interface{}, error) {
+		received <- message
+		return nil, nil
+	})
+
+	interp, _ := newQueueRunner(t, `& "email.send" {
+  > queueHook(message.to)
+}
+`)
+	result, err := interp.EvaluateExpression(FunctionCallExpr{
+		Name: "queue.publish",
+		Args: []Expr{
+			LiteralExpr{Value: StringLiteral{Value: "email.send"}},
+			ObjectExpr{Fields: []ObjectField{{Key: "to", Value: LiteralExpr{Value: StringLiteral{Value: "ada@example.com"}}}}},
+		},
+	}, NewEnvironment())
+	require.NoError(t, err)
+	assert.Equal(t, true, result)
+
+	select {
+	case got := <-received:
+		assert.Equal(t, "ada@example.com", got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not delivered")
+	}
+
+	_, err = interp.EvaluateExpression(FunctionCallExpr{
+		Name: "queue.publish",
+		Args: []Expr{LiteralExpr{Value: IntLiteral{Value: 1}}, LiteralExpr{Value: IntLiteral{Value: 1}}},
+	}, NewEnvironment())
+	assert.ErrorContains(t, err, "queue.publish() queue name must be a string")
+}
+
+func TestQueueRunner_OnProcessed(t *testing.T) {
+	withQueueHook(t, func(message interface{}) (interface{}, error) { return message, nil })
+	_, runner := newQueueRunner(t, `& "jobs" {
+  > queueHook(message)
+}
+`)
+	type processed struct {
+		queue  string
+		result interface{}
+	}
+	done := make(chan processed, 1)
+	runner.OnProcessed = func(queueName string, result interface{}, elapsed time.Duration) {
+		done <- processed{queueName, result}
+	}
+	require.NoError(t, runner.Enqueue("jobs", "payload"))
+
+	select {
+	case p := <-done:
+		assert.Equal(t, "jobs", p.queue)
+		assert.Equal(t, "payload", p.result)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not processed")
+	}
+}
+
+func TestQueueRunner_Stopped(t *testing.T) {
+	withQueueHook(t, func(message interface{}) (interface{}, error) { return nil, nil })
+	interp, runner := newQueueRunner(t, `& "jobs" {
+  > queueHook(message)
+}
+`)
+	runner.Stop()
+
+	assert.ErrorContains(t, runner.Enqueue("jobs", 1), "stopped")
+	_, err := interp.EvaluateExpression(FunctionCallExpr{
+		Name: "enqueue",
+		Args: []Expr{LiteralExpr{Value: StringLiteral{Value: "jobs"}}, LiteralExpr{Value: IntLiteral{Value: 1}}},
+	}, NewEnvironment())
+	assert.ErrorContains(t, err, "no queue runner is running")
+}
+
+func TestQueueRunner_StopRequeuesPendingRetry(t *testing.T) {
+	failed := make(chan struct{}, 1)
+	withQueueHook(t, func(message interface{}) (interface{}, error) {
+		failed <- struct{}{}
+		return nil, errors.New("temporary failure")
+	})
+
+	module, err := parseLoaderSource(`& "jobs" {
+  + retries(3)
+  > queueHook(message)
+}
+`)
+	require.NoError(t, err)
+	interp := NewInterpreter()
+	require.NoError(t, interp.LoadModule(*module))
+
+	q := queue.NewMemoryQueue()
+	runner := NewQueueRunner(interp, q)
+	runner.backoff = func(int) time.Duration { return time.Hour }
+	runner.Start()
+	require.NoError(t, runner.Enqueue("jobs", "payload"))
+
+	select {
+	case <-failed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not delivered")
+	}
+	// Stopping during the backoff puts the message back for the next run
+	runner.Stop()
+	require.Equal(t, 1, q.Len("jobs"))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg, err := q.Dequeue(ctx, "jobs")
+	require.NoError(t, err)
+	assert.Equal(t, "payload", msg.Body)
+	assert.Equal(t, 1, msg.Attempts)
+	assert.Empty(t, runner.DeadLetters())
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	assert.Equal(t, 100*time.Millisecond, exponentialBackoff(1))
+	assert.Equal(t, 200*time.Millisecond, exponentialBackoff(2))
+	assert.Equal(t, 800*time.Millisecond, exponentialBackoff(4))
+	assert.Equal(t, 30*time.Second, exponentialBackoff(20))
+}
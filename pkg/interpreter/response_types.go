@@ -42,3 +42,26 @@ func ValidateRedirect(rawURL string, statusCode int) error {
 		return fmt.Errorf("invalid redirect status code %d: must be 301, 302, 307, or 308", statusCode)
 	}
 }
+
+// ErrorResponse is returned as an error by error(status, message). It ends
+// the route, including from inside a middleware or a function it calls, and
+// answers with the status and {"error": message}.
+type ErrorResponse struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *ErrorResponse) Error() string {
+	return fmt.Sprintf("%d: %s", e.StatusCode, e.Message)
+}
+
+// Response returns the HTTP response e stands for
+func (e *ErrorResponse) Response() *Response {
+	return &Response{
+		StatusCode: e.StatusCode,
+		Body: map[string]interface{}{
+			"error": e.Message,
+		},
+		Headers: make(map[string]string),
+	}
+}
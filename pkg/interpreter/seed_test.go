@@ -0,0 +1,56 @@
+package interpreter
+
+import (
+	"testing"
+
+	. "github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteSeedBlock(t *testing.T) {
+	module, err := parseLoaderSource(`seed! users {
+  db.users.firstOrCreate({email: "ada@example.com"}, {name: "Ada"})
+  db.users.firstOrCreate({email: "grace@example.com"}, {name: "Grace"})
+}
+
+seed! {
+  $ count = db.users.count("name", "Ada")
+  > count
+}`)
+	require.NoError(t, err)
+
+	interp := NewInterpreter()
+	require.NoError(t, interp.LoadModule(*module))
+	blocks := interp.GetSeedBlocks()
+	require.Len(t, blocks, 2)
+	assert.Equal(t, "users", SeedBlockName(&blocks[0]))
+	assert.Equal(t, "seed", SeedBlockName(&blocks[1]))
+
+	db := database.NewMockDatabase()
+	for range 2 {
+		_, err := interp.ExecuteSeedBlock(&blocks[0], db)
+		require.NoError(t, err)
+	}
+	assert.Len(t, db.Table("users").All(), 2, "firstOrCreate does not duplicate rows")
+
+	result, err := interp.ExecuteSeedBlock(&blocks[1], db)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result)
+}
+
+func TestExecuteSeedBlock_DBIsScopedToTheBlock(t *testing.T) {
+	interp := NewInterpreter()
+	block := SeedBlock{Body: []Statement{
+		ReturnStatement{Value: VariableExpr{Name: "db"}},
+	}}
+
+	result, err := interp.ExecuteSeedBlock(&block, "seed-db")
+	require.NoError(t, err)
+	assert.Equal(t, "seed-db", result)
+
+	_, err = interp.globalEnv.Get("db")
+	assert.Error(t, err, "db is not left in the global environment")
+}
@@ -0,0 +1,61 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpread_ArrayConcatenation(t *testing.T) {
+	result, err := runRouteSource(t, `@ GET /t {
+  $ keys = ["a"]
+  $ more = ["c", "d"]
+  keys = [...keys, "b", ...more, ...[]]
+  > keys
+}`)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", "b", "c", "d"}, result)
+}
+
+func TestSpread_ArrayIsCopied(t *testing.T) {
+	result, err := runRouteSource(t, `@ GET /t {
+  $ base = [1, 2]
+  $ copy = [...base]
+  $ copy[0] = 9
+  > {base: base, copy: copy}
+}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"base": []interface{}{int64(1), int64(2)},
+		"copy": []interface{}{int64(9), int64(2)},
+	}, result)
+}
+
+func TestSpread_ObjectOverrideOrder(t *testing.T) {
+	result, err := runRouteSource(t, `@ GET /t {
+  $ base = {name: "base", role: "user", active: true}
+  $ extra = {role: "admin"}
+  > {name: "first", ...base, ...extra, active: false}
+}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":   "base",
+		"role":   "admin",
+		"active": false,
+	}, result)
+}
+
+func TestSpread_WrongType(t *testing.T) {
+	_, err := runRouteSource(t, `@ GET /t {
+  $ name = "Ada"
+  > [...name]
+}`)
+	assert.ErrorContains(t, err, "cannot spread string into an array")
+
+	_, err = runRouteSource(t, `@ GET /t {
+  $ items = [1]
+  > {...items}
+}`)
+	assert.ErrorContains(t, err, "cannot spread []interface {} into an object")
+}
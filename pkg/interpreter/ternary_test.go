@@ -0,0 +1,40 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTernary_ObjectFieldValue(t *testing.T) {
+	result, err := runRouteSource(t, `@ GET /t {
+  $ active = true
+  $ count = 0
+  > {status: active ? "on" : "off", label: count > 0 ? "some" : count == 0 ? "none" : "negative"}
+}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"status": "on", "label": "none"}, result)
+}
+
+func TestTernary_ShortCircuits(t *testing.T) {
+	// The branch that is not taken is never evaluated
+	result, err := runRouteSource(t, `@ GET /t {
+  > false ? missingFunction() : "else"
+}`)
+	require.NoError(t, err)
+	assert.Equal(t, "else", result)
+
+	result, err = runRouteSource(t, `@ GET /t {
+  > true ? "then" : missingFunction()
+}`)
+	require.NoError(t, err)
+	assert.Equal(t, "then", result)
+}
+
+func TestTernary_ConditionMustBeBoolean(t *testing.T) {
+	_, err := runRouteSource(t, `@ GET /t {
+  > 1 ? "a" : "b"
+}`)
+	assert.ErrorContains(t, err, "conditional expression condition must be a boolean, got int64")
+}
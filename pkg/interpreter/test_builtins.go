@@ -0,0 +1,170 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// TestRequestFunc sends a request to the program's routes and returns the
+// response as {status, body, headers}. body is nil when the request has none.
+type TestRequestFunc func(method, path string, body interface{}) (map[string]interface{}, error)
+
+// SetTestRequester sets where request() in test blocks sends its requests
+func (i *Interpreter) SetTestRequester(fn TestRequestFunc) {
+	i.testRequester = fn
+}
+
+// builtinRequest implements request(method, path, body?)
+func builtinRequest(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("request() expects 2 or 3 arguments (method, path, body), got %d", len(args))
+	}
+	if i.testRequester == nil {
+		return nil, fmt.Errorf("request() is only available in test blocks run by glyph test")
+	}
+	values, err := evaluateArgs(i, args, env)
+	if err != nil {
+		return nil, err
+	}
+	method, ok := values[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("request() method must be a string, got %T", values[0])
+	}
+	path, ok := values[1].(string)
+	if !ok || !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("request() path must be a string starting with /, got %v", values[1])
+	}
+	var body interface{}
+	if len(values) == 3 {
+		body = values[2]
+	}
+	return i.testRequester(strings.ToUpper(method), path, body)
+}
+
+// builtinAssertEqual implements assertEqual(actual, expected, message?).
+// Objects and arrays are compared element by element, and ints equal floats
+// of the same value.
+func builtinAssertEqual(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("assertEqual() expects 2 or 3 arguments (actual, expected, message), got %d", len(args))
+	}
+	values, err := evaluateArgs(i, args, env)
+	if err != nil {
+		return nil, err
+	}
+	if deepEqual(values[0], values[1]) {
+		return true, nil
+	}
+	return nil, assertionFailure(values, fmt.Sprintf("expected %s, got %s", formatTestValue(values[1]), formatTestValue(values[0])))
+}
+
+// builtinAssertContains implements assertContains(container, item, message?):
+// a substring of a string, an element of an array or a key of an object
+func builtinAssertContains(i *Interpreter, args []Expr, env *Environment) (interface{}, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("assertContains() expects 2 or 3 arguments (container, item, message), got %d", len(args))
+	}
+	values, err := evaluateArgs(i, args, env)
+	if err != nil {
+		return nil, err
+	}
+	container, item := values[0], values[1]
+
+	found := false
+	switch c := container.(type) {
+	case string:
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("assertContains() item must be a string when searching a string, got %T", item)
+		}
+		found = strings.Contains(c, s)
+	case []interface{}:
+		for _, elem := range c {
+			if deepEqual(elem, item) {
+				found = true
+				break
+			}
+		}
+	case map[string]interface{}:
+		key, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("assertContains() item must be a key string when searching an object, got %T", item)
+		}
+		_, found = c[key]
+	default:
+		return nil, fmt.Errorf("assertContains() expects a string, array or object, got %T", container)
+	}
+	if found {
+		return true, nil
+	}
+	return nil, assertionFailure(values, fmt.Sprintf("%s does not contain %s", formatTestValue(container), formatTestValue(item)))
+}
+
+// assertionFailure returns the AssertionError for a failed assertion, using
+// the optional message argument when there is one
+func assertionFailure(values []interface{}, detail string) error {
+	if len(values) == 3 {
+		if message, ok := values[2].(string); ok {
+			return &AssertionError{Message: message + ": " + detail}
+		}
+	}
+	return &AssertionError{Message: "assertion failed: " + detail}
+}
+
+func evaluateArgs(i *Interpreter, args []Expr, env *Environment) ([]interface{}, error) {
+	values := make([]interface{}, len(args))
+	for n, arg := range args {
+		val, err := i.EvaluateExpression(arg, env)
+		if err != nil {
+			return nil, err
+		}
+		values[n] = val
+	}
+	return values, nil
+}
+
+// deepEqual compares values the way == does, descending into objects and
+// arrays
+func deepEqual(a, b interface{}) bool {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for key, val := range av {
+			other, ok := bv[key]
+			if !ok || !deepEqual(val, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for n := range av {
+			if !deepEqual(av[n], bv[n]) {
+				return false
+			}
+		}
+		return true
+	}
+	if left, right, ok := CoerceNumeric(a, b); ok {
+		return left == right
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// formatTestValue renders a value for an assertion message
+func formatTestValue(v interface{}) string {
+	if data, err := json.Marshal(v); err == nil {
+		return string(data)
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,121 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTests(t *testing.T, source string) *Interpreter {
+	t.Helper()
+	module, err := parseLoaderSource(source)
+	require.NoError(t, err)
+	interp := NewInterpreter()
+	require.NoError(t, interp.LoadModule(*module))
+	return interp
+}
+
+func TestAssertEqual(t *testing.T) {
+	interp := loadTests(t, `test "equal" {
+  assertEqual(1 + 1, 2)
+  assertEqual(2, 2.0)
+  assertEqual({a: [1, "x"]}, {a: [1, "x"]})
+}
+
+test "not equal" {
+  assertEqual({a: 1}, {a: 2}, "objects")
+}
+`)
+
+	results := interp.RunTests("")
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Passed, results[0].Error)
+	assert.False(t, results[1].Passed)
+	assert.Contains(t, results[1].Error, `objects: expected {"a":2}, got {"a":1}`)
+}
+
+func TestAssertContains(t *testing.T) {
+	interp := loadTests(t, `test "contains" {
+  assertContains("hello world", "world")
+  assertContains([1, 2, 3], 2)
+  assertContains({id: 1}, "id")
+}
+
+test "missing element" {
+  assertContains([1, 2], 3)
+}
+
+test "wrong container" {
+  assertContains(5, 3)
+}
+`)
+
+	results := interp.RunTests("")
+	require.Len(t, results, 3)
+	assert.True(t, results[0].Passed, results[0].Error)
+	assert.False(t, results[1].Passed)
+	assert.Contains(t, results[1].Error, "assertion failed: [1,2] does not contain 3")
+	assert.False(t, results[2].Passed)
+	assert.Contains(t, results[2].Error, "expects a string, array or object")
+}
+
+func TestRequestBuiltin(t *testing.T) {
+	interp := loadTests(t, `test "request" {
+  $ res = request("post", "/users", {name: "bob"})
+  assertEqual(res.status, 201)
+  assertEqual(res.body.name, "bob")
+}
+`)
+
+	var gotMethod, gotPath string
+	var gotBody interface{}
+	interp.SetTestRequester(func(method, path string, body interface{}) (map[string]interface{}, error) {
+		gotMethod, gotPath, gotBody = method, path, body
+		return map[string]interface{}{"status": int64(201), "body": body, "headers": map[string]interface{}{}}, nil
+	})
+
+	results := interp.RunTests("")
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Passed, results[0].Error)
+	assert.Equal(t, "POST", gotMethod)
+	assert.Equal(t, "/users", gotPath)
+	assert.Equal(t, map[string]interface{}{"name": "bob"}, gotBody)
+}
+
+func TestRequestBuiltin_NoRequester(t *testing.T) {
+	interp := loadTests(t, `test "request" {
+  $ res = request("GET", "/")
+}
+`)
+
+	results := interp.RunTests("")
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Passed)
+	assert.Contains(t, results[0].Error, "only available in test blocks run by glyph test")
+}
+
+// TestRunTestGlobalsIsolated verifies a test block cannot change the globals
+// seen by the routes it calls or by later blocks
+func TestRunTestGlobalsIsolated(t *testing.T) {
+	interp := loadTests(t, `const config = {mode: "prod"}
+
+test "mutates" {
+  $ config.mode = "test"
+  assertEqual(config.mode, "test")
+}
+
+test "sees original" {
+  assertEqual(config.mode, "prod")
+}
+`)
+
+	results := interp.RunTests("")
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Passed, results[0].Error)
+	assert.True(t, results[1].Passed, results[1].Error)
+
+	config, err := interp.globalEnv.Get("config")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"mode": "prod"}, config)
+}
@@ -394,7 +394,7 @@ func TestMatchesFilter(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name+"_"+tt.filter, func(t *testing.T) {
-			got := matchesFilter(tt.name, tt.filter)
+			got := MatchesTestFilter(tt.name, tt.filter)
 			assert.Equal(t, tt.want, got)
 		})
 	}
@@ -0,0 +1,95 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   interface{}
+	}{
+		{"division by zero is caught", `@ GET /t {
+  try {
+    $ ratio = 10 / 0
+    > {ratio: ratio}
+  } catch (e) {
+    > {error: e.message}
+  }
+}`, map[string]interface{}{"error": "division by zero"}},
+		{"no error skips the catch block", `@ GET /t {
+  try {
+    $ ratio = 10 / 2
+    > {ratio: ratio}
+  } catch (e) {
+    > {error: e.message}
+  }
+}`, map[string]interface{}{"ratio": int64(5)}},
+		{"execution continues after the catch block", `@ GET /t {
+  $ status = "ok"
+  try {
+    $ items = [1]
+    $ missing = items[3]
+  } catch {
+    status = "recovered"
+  }
+  > status
+}`, "recovered"},
+		{"errors from nested blocks are caught", `@ GET /t {
+  try {
+    for n in [1, 0] {
+      $ q = 10 / n
+    }
+    > "unreachable"
+  } catch (err) {
+    > "caught: " + err.message
+  }
+}`, "caught: division by zero"},
+		{"the error variable is scoped to the catch block", `@ GET /t {
+  $ e = "outer"
+  try {
+    $ x = 1 / 0
+  } catch (e) {
+    $ seen = e.message
+  }
+  > e
+}`, "outer"},
+		{"errors in the catch block propagate to an outer try", `@ GET /t {
+  try {
+    try {
+      $ x = 1 / 0
+    } catch (e) {
+      $ y = 2 / 0
+    }
+  } catch (outer) {
+    > "outer: " + outer.message
+  }
+}`, "outer: division by zero"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runRouteSource(t, tt.source)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
+
+func TestTryCatchDoesNotCatchLoopLimits(t *testing.T) {
+	_, err := runRouteSource(t, `@ GET /t {
+  try {
+    while true {
+      $ x = 1
+    }
+  } catch (e) {
+    > "caught"
+  }
+}`)
+	var loopErr *LoopLimitError
+	assert.ErrorAs(t, err, &loopErr)
+}
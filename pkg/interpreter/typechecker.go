@@ -11,6 +11,7 @@ import (
 // TypeChecker validates type compatibility and performs type checking
 type TypeChecker struct {
 	typeDefs  map[string]TypeDef
+	enumDefs  map[string]EnumDef
 	functions map[string]Function
 	traitDefs map[string]TraitDef
 	// typeScope maps type parameter names to their resolved types during generic instantiation
@@ -21,6 +22,7 @@ type TypeChecker struct {
 func NewTypeChecker() *TypeChecker {
 	return &TypeChecker{
 		typeDefs:  make(map[string]TypeDef),
+		enumDefs:  make(map[string]EnumDef),
 		functions: make(map[string]Function),
 		traitDefs: make(map[string]TraitDef),
 		typeScope: make(map[string]Type),
@@ -32,6 +34,11 @@ func (tc *TypeChecker) SetTypeDefs(typeDefs map[string]TypeDef) {
 	tc.typeDefs = typeDefs
 }
 
+// SetEnumDefs updates the enum definitions map
+func (tc *TypeChecker) SetEnumDefs(enumDefs map[string]EnumDef) {
+	tc.enumDefs = enumDefs
+}
+
 // SetFunctions updates the functions map
 func (tc *TypeChecker) SetFunctions(functions map[string]Function) {
 	tc.functions = functions
@@ -96,7 +103,7 @@ func (tc *TypeChecker) CheckType(value interface{}, expectedType Type) error {
 		return nil // No type constraint
 	}
 
-	// Service types (Database, Redis, MongoDB, LLM) are injected by the
+	// Service types (Database, Redis, MongoDB, LLM, Cache) are injected by the
 	// runtime's dependency injection system which already guarantees the
 	// correct type. Skip runtime type checking for these because the
 	// injected Go values (e.g. *database.MockDatabase) have no
@@ -108,9 +115,21 @@ func (tc *TypeChecker) CheckType(value interface{}, expectedType Type) error {
 		return nil
 	case NamedType:
 		switch et.Name {
-		case "Database", "Redis", "MongoDB", "LLM":
+		case "Database", "Redis", "MongoDB", "LLM", "Cache", "Mailer":
 			return nil
 		}
+		if enumDef, ok := tc.enumDefs[et.Name]; ok {
+			return tc.checkEnumValue(value, enumDef)
+		}
+	case OptionalType:
+		if value == nil {
+			return nil
+		}
+		if inner, ok := et.InnerType.(NamedType); ok {
+			if enumDef, ok := tc.enumDefs[inner.Name]; ok {
+				return tc.checkEnumValue(value, enumDef)
+			}
+		}
 	}
 
 	actualType := GetRuntimeType(value)
@@ -301,7 +320,8 @@ func (tc *TypeChecker) ValidateTypeReference(t Type) error {
 	switch typ := t.(type) {
 	case NamedType:
 		// Check if the named type exists
-		if _, exists := tc.typeDefs[typ.Name]; !exists {
+		_, isEnum := tc.enumDefs[typ.Name]
+		if _, exists := tc.typeDefs[typ.Name]; !exists && !isEnum {
 			return fmt.Errorf("undefined type: %s", typ.Name)
 		}
 	case ArrayType:
@@ -353,6 +373,23 @@ func (tc *TypeChecker) ValidateObjectAgainstTypeDef(obj map[string]interface{},
 	return nil
 }
 
+// checkEnumValue validates that value is one of an enum's values
+func (tc *TypeChecker) checkEnumValue(value interface{}, enumDef EnumDef) error {
+	s, isString := value.(string)
+	if isString && enumDef.Has(s) {
+		return nil
+	}
+	got := fmt.Sprintf("%q", s)
+	if !isString {
+		got = tc.TypeToString(GetRuntimeType(value))
+	}
+	quoted := make([]string, len(enumDef.Values))
+	for n, v := range enumDef.Values {
+		quoted[n] = fmt.Sprintf("%q", v)
+	}
+	return fmt.Errorf("invalid %s: expected one of %s, got %s", enumDef.Name, strings.Join(quoted, ", "), got)
+}
+
 // ValidateArrayElements validates all elements of an array against a type
 func (tc *TypeChecker) ValidateArrayElements(elements []interface{}, elementType Type) error {
 	if elementType == nil {
@@ -0,0 +1,96 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"math"
+	"net/http"
+	"strings"
+)
+
+// UnionReturnStatus returns the HTTP status for a route result, picked by the
+// member of the route's declared union return type that the result matches.
+// Members are tried in order; a type definition matches an object that has
+// its required fields with the declared types. A result matching an error
+// member (one named like NotFound or Error) gets the 4xx/5xx code in its
+// status or code field, or else the status the member's name suggests.
+// Everything else, including routes without a union return type, gets 200.
+func (tc *TypeChecker) UnionReturnStatus(result interface{}, returnType Type) int {
+	union, ok := returnType.(UnionType)
+	if !ok {
+		return http.StatusOK
+	}
+	for _, member := range union.Types {
+		if !tc.matchesVariant(result, member) {
+			continue
+		}
+		named, ok := member.(NamedType)
+		if !ok {
+			return http.StatusOK
+		}
+		status, isError := errorVariantStatus(named.Name)
+		if !isError {
+			return http.StatusOK
+		}
+		if obj, ok := result.(map[string]interface{}); ok {
+			for _, key := range []string{"status", "code"} {
+				if code, ok := errorStatusCode(obj[key]); ok {
+					return code
+				}
+			}
+		}
+		return status
+	}
+	return http.StatusOK
+}
+
+// matchesVariant reports whether result is a value of the union member t
+func (tc *TypeChecker) matchesVariant(result interface{}, t Type) bool {
+	if named, ok := t.(NamedType); ok {
+		if typeDef, ok := tc.typeDefs[named.Name]; ok {
+			obj, ok := result.(map[string]interface{})
+			return ok && tc.ValidateInput(obj, typeDef) == nil
+		}
+	}
+	return tc.CheckType(result, t) == nil
+}
+
+// errorVariantStatus returns the status suggested by the name of an error
+// type, and whether the name is one of an error type at all
+func errorVariantStatus(name string) (int, bool) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.Contains(lower, "notfound"):
+		return http.StatusNotFound, true
+	case strings.Contains(lower, "unauthorized"):
+		return http.StatusUnauthorized, true
+	case strings.Contains(lower, "forbidden"):
+		return http.StatusForbidden, true
+	case strings.Contains(lower, "badrequest") || strings.Contains(lower, "validation"):
+		return http.StatusBadRequest, true
+	case strings.Contains(lower, "conflict"):
+		return http.StatusConflict, true
+	case strings.Contains(lower, "error"):
+		return http.StatusInternalServerError, true
+	}
+	return 0, false
+}
+
+// errorStatusCode returns v as an HTTP status if it is a 4xx or 5xx code
+func errorStatusCode(v interface{}) (int, bool) {
+	var code int
+	switch n := v.(type) {
+	case int64:
+		code = int(n)
+	case int:
+		code = n
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, false
+		}
+		code = int(n)
+	default:
+		return 0, false
+	}
+	return code, code >= 400 && code <= 599
+}
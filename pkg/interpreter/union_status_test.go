@@ -0,0 +1,81 @@
+package interpreter
+
+import (
+	. "github.com/glyphlang/glyph/pkg/ast"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const unionReturnSource = `: Message {
+  id: int!
+  text: str!
+}
+
+: Error {
+  code: int!
+  message: str!
+}
+
+: NotFound {
+  message: str!
+}
+
+@ POST /messages -> Message | Error | NotFound {
+  if input.text == "" {
+    > {code: 422, message: "text is required"}
+  }
+  if input.text == "missing" {
+    > {message: "no such thread"}
+  }
+  if input.text == "broken" {
+    > {code: 7, message: "something broke"}
+  }
+  > {id: 1, text: input.text}
+}
+`
+
+func TestUnionReturnStatus(t *testing.T) {
+	module, err := parseLoaderSource(unionReturnSource)
+	require.NoError(t, err)
+	interp := NewInterpreter()
+	require.NoError(t, interp.LoadModule(*module))
+	route := module.Items[3].(*Route)
+
+	tests := []struct {
+		name   string
+		text   string
+		status int
+	}{
+		{"success variant", "hello", 200},
+		{"error variant with code", "", 422},
+		{"error variant named NotFound", "missing", 404},
+		{"error variant with a code that is not a status", "broken", 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := interp.ExecuteRoute(route, &Request{
+				Method: "POST",
+				Path:   "/messages",
+				Body:   map[string]interface{}{"text": tt.text},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode, "body: %v", resp.Body)
+		})
+	}
+}
+
+func TestUnionReturnStatus_NotUnion(t *testing.T) {
+	tc := NewTypeChecker()
+	tc.SetTypeDefs(map[string]TypeDef{
+		"Error": {Name: "Error", Fields: []Field{{Name: "code", TypeAnnotation: IntType{}, Required: true}}},
+	})
+	result := map[string]interface{}{"code": int64(404)}
+
+	assert.Equal(t, 200, tc.UnionReturnStatus(result, NamedType{Name: "Error"}))
+	assert.Equal(t, 200, tc.UnionReturnStatus(result, nil))
+	assert.Equal(t, 404, tc.UnionReturnStatus(result, UnionType{Types: []Type{IntType{}, NamedType{Name: "Error"}}}))
+	assert.Equal(t, 200, tc.UnionReturnStatus(int64(7), UnionType{Types: []Type{IntType{}, NamedType{Name: "Error"}}}))
+}
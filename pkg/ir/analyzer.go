@@ -141,7 +141,7 @@ func (a *Analyzer) processItem(ir *ServiceIR, item ast.Item) error {
 		// Register as a known provider so injections can reference it
 		a.providers[prov.ProviderType] = prov
 
-	case *ast.TestBlock, *ast.ImportStatement, *ast.ModuleDecl,
+	case *ast.TestBlock, *ast.SeedBlock, *ast.MiddlewareDef, *ast.ErrorHandler, *ast.ImportStatement, *ast.ModuleDecl,
 		*ast.MacroDef, *ast.MacroInvocation, *ast.ContractDef,
 		*ast.TraitDef, *ast.StaticRoute:
 		// These are either handled elsewhere or not represented in the service IR
@@ -977,11 +977,45 @@ func (a *Analyzer) convertExpr(expr ast.Expr) ExprIR {
 			Kind:  ExprAwait,
 			Await: &AwaitExprIR{Expr: a.convertExpr(e.Expr)},
 		}
+	case *ast.TernaryExpr:
+		return a.convertTernaryExpr(e)
+	case ast.TernaryExpr:
+		return a.convertTernaryExpr(&e)
+	case *ast.InterpolatedStringExpr:
+		return a.convertInterpolatedString(e)
+	case ast.InterpolatedStringExpr:
+		return a.convertInterpolatedString(&e)
+	case *ast.SpreadExpr:
+		return ExprIR{Kind: ExprSpread, Spread: &SpreadExpr{Value: a.convertExpr(e.Value)}}
+	case ast.SpreadExpr:
+		return ExprIR{Kind: ExprSpread, Spread: &SpreadExpr{Value: a.convertExpr(e.Value)}}
 	default:
 		return ExprIR{Kind: ExprNull, IsNull: true}
 	}
 }
 
+func (a *Analyzer) convertTernaryExpr(t *ast.TernaryExpr) ExprIR {
+	return ExprIR{
+		Kind: ExprTernary,
+		Ternary: &TernaryExpr{
+			Condition: a.convertExpr(t.Condition),
+			Then:      a.convertExpr(t.Then),
+			Else:      a.convertExpr(t.Else),
+		},
+	}
+}
+
+func (a *Analyzer) convertInterpolatedString(s *ast.InterpolatedStringExpr) ExprIR {
+	parts := make([]ExprIR, len(s.Parts))
+	for i, part := range s.Parts {
+		parts[i] = a.convertExpr(part)
+	}
+	return ExprIR{
+		Kind:   ExprInterpolation,
+		Interp: &InterpolationExpr{Parts: parts},
+	}
+}
+
 func (a *Analyzer) convertLiteral(lit ast.Literal) ExprIR {
 	switch l := lit.(type) {
 	case ast.IntLiteral:
@@ -1046,6 +1080,10 @@ func (a *Analyzer) convertPattern(p ast.Pattern) PatternIR {
 			ir.Fields = append(ir.Fields, opf)
 		}
 		return ir
+	case ast.TypePattern:
+		ir := a.convertPattern(ast.ObjectPattern{Fields: pat.Fields})
+		ir.TypeName = pat.TypeName
+		return ir
 	case ast.ArrayPattern:
 		ir := PatternIR{Kind: PatternArray}
 		for _, el := range pat.Elements {
@@ -429,6 +429,9 @@ type ExprIR struct {
 	Match       *MatchExpr
 	Async       *AsyncExprIR
 	Await       *AwaitExprIR
+	Ternary     *TernaryExpr
+	Interp      *InterpolationExpr
+	Spread      *SpreadExpr
 }
 
 // ExprKind classifies the type of expression.
@@ -453,6 +456,9 @@ const (
 	ExprMatch
 	ExprAsync
 	ExprAwait
+	ExprTernary
+	ExprInterpolation
+	ExprSpread
 )
 
 // BinaryExpr describes a binary operation.
@@ -543,6 +549,25 @@ type PipeExpr struct {
 	Right ExprIR
 }
 
+// TernaryExpr describes a conditional expression (cond ? then : else).
+type TernaryExpr struct {
+	Condition ExprIR
+	Then      ExprIR
+	Else      ExprIR
+}
+
+// InterpolationExpr describes an interpolated string ("Hello, {name}!") as
+// its string literals and embedded expressions in order.
+type InterpolationExpr struct {
+	Parts []ExprIR
+}
+
+// SpreadExpr describes ...value in an array or object literal. A spread
+// object field has an empty key.
+type SpreadExpr struct {
+	Value ExprIR
+}
+
 // AsyncExprIR describes an async block.
 type AsyncExprIR struct {
 	Body []StmtIR
@@ -577,6 +602,7 @@ type PatternIR struct {
 	Fields   []ObjectPatternField
 	Elements []PatternIR
 	RestVar  string
+	TypeName string // Named type an object pattern requires, if any
 }
 
 // PatternKind classifies the type of pattern.
@@ -0,0 +1,57 @@
+// Package jsonvalue decodes JSON into the values GLYPH programs work with:
+// objects, arrays, strings, bools, null, and numbers as int64 when whole and
+// float64 otherwise, where encoding/json would make every number a float64.
+package jsonvalue
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Decode decodes the single JSON value in data
+func Decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("invalid character after top-level value")
+	}
+	return Normalize(value), nil
+}
+
+// Unmarshal decodes data into v like json.Unmarshal, but keeps numbers held
+// in interface{} fields as json.Number for Normalize
+func Unmarshal(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// Normalize converts the json.Numbers in a decoded value, in place, to int64
+// when whole and float64 otherwise
+func Normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, val := range v {
+			v[key] = Normalize(val)
+		}
+		return v
+	case []interface{}:
+		for i, val := range v {
+			v[i] = Normalize(val)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,45 @@
+package jsonvalue
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	got, err := Decode([]byte(`{"n": 3, "f": 1.5, "big": 1e3, "items": [1, "a", true, null]}`))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	want := map[string]interface{}{
+		"n":     int64(3),
+		"f":     1.5,
+		"big":   float64(1000),
+		"items": []interface{}{int64(1), "a", true, nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %#v, got %#v", want, got)
+	}
+
+	for _, data := range []string{``, `{`, `1 2`} {
+		if _, err := Decode([]byte(data)); err == nil {
+			t.Errorf("%q: expected an error", data)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	var payload struct {
+		Expires int64
+		Data    interface{}
+	}
+	if err := Unmarshal([]byte(`{"Expires": 10, "Data": {"count": 2}}`), &payload); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if payload.Expires != 10 {
+		t.Errorf("Expected Expires 10, got %d", payload.Expires)
+	}
+	want := map[string]interface{}{"count": int64(2)}
+	if got := Normalize(payload.Data); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %#v, got %#v", want, got)
+	}
+}
@@ -1302,6 +1302,31 @@ func TestWriteMessage(t *testing.T) {
 	}
 }
 
+// TestFormatDocumentCanonical tests that formatting uses the glyph fmt formatter
+func TestFormatDocumentCanonical(t *testing.T) {
+	dm := NewDocumentManager()
+	source := "@ GET /hello   {\n      > {text: \"hi\"}   # greet\n}"
+	doc, _ := dm.Open("file:///canonical.glyph", 1, source)
+
+	edits := FormatDocument(doc, FormattingOptions{TabSize: 2, InsertSpaces: true})
+	if len(edits) != 1 {
+		t.Fatalf("Expected a single whole-document edit, got %d", len(edits))
+	}
+	want := "@ GET /hello {\n  > {text: \"hi\"} # greet\n}\n"
+	if edits[0].NewText != want {
+		t.Errorf("NewText = %q, want %q", edits[0].NewText, want)
+	}
+	if edits[0].Range.End != (Position{Line: 2, Character: 1}) {
+		t.Errorf("Edit should cover the whole document, got %+v", edits[0].Range)
+	}
+
+	// Formatting canonical source is a no-op
+	doc, _ = dm.Open("file:///canonical2.glyph", 1, want)
+	if edits := FormatDocument(doc, FormattingOptions{TabSize: 2, InsertSpaces: true}); len(edits) != 0 {
+		t.Errorf("Expected no edits for canonical source, got %v", edits)
+	}
+}
+
 // TestFormatDocumentEdgeCases tests formatting edge cases
 func TestFormatDocumentEdgeCases(t *testing.T) {
 	dm := NewDocumentManager()
@@ -1529,82 +1554,6 @@ func TestFormatQueueWorkerHover(t *testing.T) {
 	}
 }
 
-// TestFindReferencesInStatements tests findReferencesInStatements
-func TestFindReferencesInStatements(t *testing.T) {
-	stmts := []ast.Statement{
-		ast.AssignStatement{
-			Target: "x",
-			Value:  ast.VariableExpr{Name: "y"},
-		},
-		ast.ReturnStatement{
-			Value: ast.VariableExpr{Name: "x"},
-		},
-	}
-
-	locs := findReferencesInStatements(stmts, "x", "file:///test.glyph")
-	// Should find references to x
-	_ = locs
-}
-
-// TestFindReferencesInStatement tests findReferencesInStatement
-func TestFindReferencesInStatement(t *testing.T) {
-	t.Run("assign statement", func(t *testing.T) {
-		stmt := ast.AssignStatement{
-			Target: "x",
-			Value:  ast.VariableExpr{Name: "y"},
-		}
-		locs := findReferencesInStatement(stmt, "y", "file:///test.glyph")
-		_ = locs
-	})
-
-	t.Run("return statement", func(t *testing.T) {
-		stmt := ast.ReturnStatement{
-			Value: ast.VariableExpr{Name: "x"},
-		}
-		locs := findReferencesInStatement(stmt, "x", "file:///test.glyph")
-		_ = locs
-	})
-
-	t.Run("if statement", func(t *testing.T) {
-		stmt := ast.IfStatement{
-			Condition: ast.VariableExpr{Name: "flag"},
-			ThenBlock: []ast.Statement{},
-		}
-		locs := findReferencesInStatement(stmt, "flag", "file:///test.glyph")
-		_ = locs
-	})
-}
-
-// TestFindReferencesInExpression tests findReferencesInExpression
-func TestFindReferencesInExpression(t *testing.T) {
-	t.Run("variable expression", func(t *testing.T) {
-		expr := ast.VariableExpr{Name: "x"}
-		locs := findReferencesInExpression(expr, "x", "file:///test.glyph")
-		if len(locs) == 0 {
-			t.Skip("Variable expression references might not be found")
-		}
-	})
-
-	t.Run("binary expression", func(t *testing.T) {
-		expr := ast.BinaryOpExpr{
-			Left:  ast.VariableExpr{Name: "a"},
-			Op:    ast.Add,
-			Right: ast.VariableExpr{Name: "b"},
-		}
-		locs := findReferencesInExpression(expr, "a", "file:///test.glyph")
-		_ = locs
-	})
-
-	t.Run("call expression", func(t *testing.T) {
-		expr := ast.FunctionCallExpr{
-			Name: "myFunc",
-			Args: []ast.Expr{ast.VariableExpr{Name: "x"}},
-		}
-		locs := findReferencesInExpression(expr, "x", "file:///test.glyph")
-		_ = locs
-	})
-}
-
 // TestGetReferencesWithRoutes tests GetReferences with route parameters
 func TestGetReferencesWithRoutes(t *testing.T) {
 	dm := NewDocumentManager()
@@ -1751,70 +1700,6 @@ func TestGetDocumentSymbolsWithCronAndEvents(t *testing.T) {
 	}
 }
 
-// TestFindReferencesInStatementMoreTypes tests more statement types
-func TestFindReferencesInStatementMoreTypes(t *testing.T) {
-	t.Run("while statement", func(t *testing.T) {
-		stmt := ast.WhileStatement{
-			Condition: ast.VariableExpr{Name: "running"},
-			Body:      []ast.Statement{},
-		}
-		locs := findReferencesInStatement(stmt, "running", "file:///test.glyph")
-		_ = locs
-	})
-
-	t.Run("for statement", func(t *testing.T) {
-		stmt := ast.ForStatement{
-			ValueVar: "item",
-			Iterable: ast.VariableExpr{Name: "items"},
-			Body:     []ast.Statement{},
-		}
-		locs := findReferencesInStatement(stmt, "items", "file:///test.glyph")
-		_ = locs
-	})
-
-	t.Run("switch statement", func(t *testing.T) {
-		stmt := ast.SwitchStatement{
-			Value: ast.VariableExpr{Name: "choice"},
-			Cases: []ast.SwitchCase{},
-		}
-		locs := findReferencesInStatement(stmt, "choice", "file:///test.glyph")
-		_ = locs
-	})
-}
-
-// TestFindReferencesInExpressionMoreTypes tests more expression types
-func TestFindReferencesInExpressionMoreTypes(t *testing.T) {
-	t.Run("unary expression", func(t *testing.T) {
-		expr := ast.UnaryOpExpr{
-			Op:    ast.Not,
-			Right: ast.VariableExpr{Name: "flag"},
-		}
-		locs := findReferencesInExpression(expr, "flag", "file:///test.glyph")
-		_ = locs
-	})
-
-	t.Run("object expression", func(t *testing.T) {
-		expr := ast.ObjectExpr{
-			Fields: []ast.ObjectField{
-				{Key: "name", Value: ast.VariableExpr{Name: "userName"}},
-			},
-		}
-		locs := findReferencesInExpression(expr, "userName", "file:///test.glyph")
-		_ = locs
-	})
-
-	t.Run("array expression", func(t *testing.T) {
-		expr := ast.ArrayExpr{
-			Elements: []ast.Expr{
-				ast.VariableExpr{Name: "x"},
-				ast.VariableExpr{Name: "y"},
-			},
-		}
-		locs := findReferencesInExpression(expr, "x", "file:///test.glyph")
-		_ = locs
-	})
-}
-
 // TestParseDocumentEdgeCases tests parseDocument with various inputs
 func TestParseDocumentEdgeCases(t *testing.T) {
 	dm := NewDocumentManager()
@@ -0,0 +1,572 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/interpreter"
+)
+
+// completionContext is what kind of name is expected at the cursor
+type completionContext int
+
+const (
+	completionDefault    completionContext = iota // Start of a declaration or statement
+	completionNone                                // Inside a string literal or comment
+	completionType                                // After -> or a field/parameter colon
+	completionExpression                          // Where a value is expected
+	completionMember                              // After receiver.
+	completionAnnotation                          // After + in a route
+)
+
+// expressionKeywords are keywords followed by an expression
+var expressionKeywords = map[string]bool{
+	"return": true, "if": true, "while": true, "in": true, "await": true, "yield": true, "assert": true,
+}
+
+// detectCompletionContext classifies the cursor position from the text
+// before it on the same line. For member completion it also returns the
+// receiver, e.g. "user.address" for "user.address.".
+func detectCompletionContext(doc *Document, pos Position) (completionContext, string) {
+	line := doc.GetLine(pos.Line)
+	if pos.Character < len(line) {
+		line = line[:pos.Character]
+	}
+
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return completionNone, ""
+		}
+	}
+	if quote != 0 {
+		return completionNone, ""
+	}
+
+	// Drop the partially typed name
+	end := len(line)
+	for end > 0 && isIdentifierChar(line[end-1]) {
+		end--
+	}
+	before := line[:end]
+
+	if strings.HasSuffix(before, ".") {
+		start := len(before) - 1
+		for start > 0 && (isIdentifierChar(before[start-1]) || before[start-1] == '.') {
+			start--
+		}
+		receiver := strings.Trim(before[start:len(before)-1], ".")
+		if receiver == "" || receiver[0] >= '0' && receiver[0] <= '9' {
+			return completionDefault, ""
+		}
+		return completionMember, receiver
+	}
+
+	trimmed := strings.TrimRight(before, " \t")
+	statement := strings.TrimSpace(trimmed)
+	switch {
+	case strings.HasSuffix(trimmed, "->"):
+		return completionType, ""
+	case statement == "+" || statement == "middleware":
+		return completionAnnotation, ""
+	case strings.HasSuffix(trimmed, ":"):
+		if isTypeColon(doc, pos.Line, strings.TrimRight(trimmed[:len(trimmed)-1], " \t")) {
+			return completionType, ""
+		}
+		return completionExpression, ""
+	case statement == "":
+		return completionDefault, ""
+	}
+
+	last := trimmed[len(trimmed)-1]
+	if strings.ContainsRune("=(,[+-*/%&|!<>?", rune(last)) {
+		return completionExpression, ""
+	}
+	word := trimmed
+	if i := strings.LastIndexAny(trimmed, " \t"); i >= 0 {
+		word = trimmed[i+1:]
+	}
+	if expressionKeywords[word] {
+		return completionExpression, ""
+	}
+	return completionDefault, ""
+}
+
+// isTypeColon reports whether the colon ending head (with the colon
+// removed) introduces a type annotation rather than an object literal value
+func isTypeColon(doc *Document, lineNum int, head string) bool {
+	end := len(head)
+	for end > 0 && isIdentifierChar(head[end-1]) {
+		end--
+	}
+	if end == len(head) {
+		return false
+	}
+	rest := strings.TrimSpace(head[:end])
+
+	switch rest {
+	case "$", "%", "<", "let", "?":
+		// $ x: T, % db: Database, < input: T
+		return true
+	case "":
+		// A field of a type definition, rather than of a multi-line object
+		header := itemHeader(doc, lineNum)
+		return strings.HasPrefix(header, ":") || strings.HasPrefix(header, "type ")
+	}
+
+	// A parameter inside the parentheses of a function or command signature
+	if strings.HasSuffix(rest, "(") || strings.HasSuffix(rest, ",") {
+		header := strings.TrimSpace(doc.GetLine(lineNum))
+		if strings.Count(head, "(") > strings.Count(head, ")") {
+			for _, prefix := range []string{"!", "=", "@", "func ", "command ", "route "} {
+				if strings.HasPrefix(header, prefix) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// itemHeader returns the line that starts the top-level item containing
+// lineNum: the nearest line at or above it that is not indented
+func itemHeader(doc *Document, lineNum int) string {
+	for i := lineNum; i >= 0; i-- {
+		line := doc.GetLine(i)
+		if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '}' {
+			continue
+		}
+		return line
+	}
+	return ""
+}
+
+// completionModule returns the AST to complete against, falling back to the
+// last one that parsed while the document is being edited
+func completionModule(doc *Document) *ast.Module {
+	if doc.AST != nil {
+		return doc.AST
+	}
+	return doc.LastValidAST
+}
+
+// typeDefs returns the types declared in the document and the files it
+// imports, by name
+func typeDefs(doc *Document) map[string]*ast.TypeDef {
+	types := make(map[string]*ast.TypeDef)
+	modules := []*ast.Module{completionModule(doc)}
+	if doc.Program != nil {
+		modules = append(modules, doc.Program.Module)
+	}
+	for _, module := range modules {
+		if module == nil {
+			continue
+		}
+		for _, item := range module.Items {
+			if typeDef, ok := item.(*ast.TypeDef); ok {
+				if _, seen := types[typeDef.Name]; !seen {
+					types[typeDef.Name] = typeDef
+				}
+			}
+		}
+	}
+	return types
+}
+
+// getTypeCompletions offers the built-in types and every declared type
+func getTypeCompletions(doc *Document) []CompletionItem {
+	return append(getBuiltinTypeCompletions(), getDefinedTypeCompletions(doc)...)
+}
+
+// getBuiltinTypeCompletions returns completions for the built-in types
+func getBuiltinTypeCompletions() []CompletionItem {
+	builtins := []struct {
+		name string
+		doc  string
+	}{
+		{"int", "64-bit signed integer"},
+		{"str", "UTF-8 string"},
+		{"string", "UTF-8 string (alias of str)"},
+		{"bool", "true or false"},
+		{"float", "64-bit floating point number"},
+	}
+
+	var items []CompletionItem
+	for _, t := range builtins {
+		items = append(items, CompletionItem{
+			Label:         t.name,
+			Kind:          CompletionItemKindClass,
+			Detail:        "Built-in type",
+			Documentation: t.doc,
+		})
+	}
+	return items
+}
+
+// getExpressionCompletions offers the declared and built-in functions
+func getExpressionCompletions(doc *Document) []CompletionItem {
+	var items []CompletionItem
+	seen := make(map[string]bool)
+
+	modules := []*ast.Module{completionModule(doc)}
+	if doc.Program != nil {
+		modules = append(modules, doc.Program.Module)
+	}
+	for _, module := range modules {
+		if module == nil {
+			continue
+		}
+		for _, item := range module.Items {
+			fn, ok := item.(*ast.Function)
+			if !ok || seen[fn.Name] {
+				continue
+			}
+			seen[fn.Name] = true
+			documentation := "Function declared in this file"
+			if doc.Program != nil && module == doc.Program.Module {
+				if file := doc.Program.FileOf(item); file != "" && file != doc.Program.Entry {
+					documentation = fmt.Sprintf("Function declared in %s", file)
+				}
+			}
+			items = append(items, CompletionItem{
+				Label:         fn.Name,
+				Kind:          CompletionItemKindFunction,
+				Detail:        formatFunctionSignature(fn),
+				Documentation: documentation,
+			})
+		}
+	}
+
+	for _, name := range interpreter.BuiltinFunctionNames() {
+		if seen[name] {
+			continue
+		}
+		info, ok := builtinFunctionDocs[name]
+		if !ok {
+			info = builtinFunctionDoc{name + "(...)", "Built-in function"}
+		}
+		items = append(items, CompletionItem{
+			Label:         name,
+			Kind:          CompletionItemKindFunction,
+			Detail:        info.signature,
+			Documentation: info.doc,
+		})
+	}
+	return items
+}
+
+// getAnnotationCompletions offers the route middleware annotations
+func getAnnotationCompletions() []CompletionItem {
+	annotations := []struct {
+		name    string
+		snippet string
+		detail  string
+		doc     string
+	}{
+		{"auth", "auth(${1:jwt})", "auth(method)", "Require authentication (jwt or apikey); binds auth in the route body"},
+		{"ratelimit", "ratelimit(${1:100}/${2:min})", "ratelimit(requests/window)", "Limit how often a client may call the route"},
+		{"cors", "cors(${1:*})", "cors(origins)", "Allow cross-origin requests from the given origins"},
+	}
+
+	var items []CompletionItem
+	for _, a := range annotations {
+		items = append(items, CompletionItem{
+			Label:            a.name,
+			Kind:             CompletionItemKindKeyword,
+			Detail:           a.detail,
+			Documentation:    a.doc,
+			InsertText:       a.snippet,
+			InsertTextFormat: 2,
+		})
+	}
+	return items
+}
+
+// getMemberCompletions offers the fields of receiver, when its shape can be
+// inferred from a declared type or an object literal
+func getMemberCompletions(doc *Document, pos Position, receiver string) []CompletionItem {
+	module := completionModule(doc)
+	if module == nil {
+		return nil
+	}
+	types := typeDefs(doc)
+	parts := strings.Split(receiver, ".")
+
+	shape, ok := rootShape(module, types, pos.Line+1, parts[0])
+	for _, field := range parts[1:] {
+		if !ok {
+			break
+		}
+		shape, ok = shape.field(types, field)
+	}
+	if !ok {
+		return nil
+	}
+
+	var items []CompletionItem
+	if shape.typeDef != nil {
+		for _, field := range shape.typeDef.Fields {
+			detail := formatType(field.TypeAnnotation)
+			if field.Required {
+				detail += "!"
+			}
+			items = append(items, CompletionItem{
+				Label:         field.Name,
+				Kind:          CompletionItemKindField,
+				Detail:        detail,
+				Documentation: fmt.Sprintf("Field of %s", shape.typeDef.Name),
+			})
+		}
+		return items
+	}
+	for _, field := range shape.object.Fields {
+		// The fields a spread adds are not known until it runs
+		if field.Key == "" {
+			continue
+		}
+		items = append(items, CompletionItem{
+			Label:         field.Key,
+			Kind:          CompletionItemKindField,
+			Detail:        "Object field",
+			Documentation: fmt.Sprintf("Field of the object literal assigned to %s", receiver),
+		})
+	}
+	return items
+}
+
+// valueShape is what is known about a value's fields: either its declared
+// type or the object literal it was built from
+type valueShape struct {
+	typeDef *ast.TypeDef
+	object  *ast.ObjectExpr
+}
+
+// shapeOfType returns the shape of a value of type t
+func shapeOfType(types map[string]*ast.TypeDef, t ast.Type) (valueShape, bool) {
+	if opt, ok := t.(ast.OptionalType); ok {
+		t = opt.InnerType
+	}
+	if named, ok := t.(ast.NamedType); ok {
+		if typeDef, ok := types[named.Name]; ok {
+			return valueShape{typeDef: typeDef}, true
+		}
+	}
+	return valueShape{}, false
+}
+
+// shapeOfExpr returns the shape of the value expr evaluates to
+func shapeOfExpr(module *ast.Module, types map[string]*ast.TypeDef, expr ast.Expr) (valueShape, bool) {
+	switch e := expr.(type) {
+	case ast.ObjectExpr:
+		return valueShape{object: &e}, true
+	case ast.FunctionCallExpr:
+		for _, item := range module.Items {
+			if fn, ok := item.(*ast.Function); ok && fn.Name == e.Name {
+				return shapeOfType(types, fn.ReturnType)
+			}
+		}
+	}
+	return valueShape{}, false
+}
+
+// field returns the shape of the named field
+func (s valueShape) field(types map[string]*ast.TypeDef, name string) (valueShape, bool) {
+	if s.typeDef != nil {
+		if decl := findTypeField(s.typeDef, name); decl != nil {
+			return shapeOfType(types, decl.TypeAnnotation)
+		}
+		return valueShape{}, false
+	}
+	for _, field := range s.object.Fields {
+		if field.Key == name {
+			if obj, ok := field.Value.(ast.ObjectExpr); ok {
+				return valueShape{object: &obj}, true
+			}
+			return valueShape{}, false
+		}
+	}
+	return valueShape{}, false
+}
+
+func findTypeField(typeDef *ast.TypeDef, name string) *ast.Field {
+	for i := range typeDef.Fields {
+		if typeDef.Fields[i].Name == name {
+			return &typeDef.Fields[i]
+		}
+	}
+	return nil
+}
+
+// rootShape infers the shape of the variable name as seen on line (1-based):
+// from the last assignment to it above the line, a typed parameter, or the
+// route's declared input type
+func rootShape(module *ast.Module, types map[string]*ast.TypeDef, line int, name string) (valueShape, bool) {
+	var enclosing ast.Item
+	for _, item := range module.Items {
+		var pos ast.Pos
+		switch it := item.(type) {
+		case *ast.Route:
+			pos = it.Pos
+		case *ast.Function:
+			pos = it.Pos
+		case *ast.Command:
+			pos = it.Pos
+		default:
+			continue
+		}
+		if pos.HasPos() && pos.Line <= line {
+			enclosing = item
+		}
+	}
+
+	var body []ast.Statement
+	switch it := enclosing.(type) {
+	case *ast.Route:
+		body = it.Body
+		if name == "input" && it.InputType != nil {
+			if shape, ok := shapeOfType(types, it.InputType); ok {
+				return shape, true
+			}
+		}
+	case *ast.Function:
+		body = it.Body
+		for _, param := range it.Params {
+			if param.Name == name {
+				return shapeOfType(types, param.TypeAnnotation)
+			}
+		}
+	case *ast.Command:
+		body = it.Body
+	default:
+		return valueShape{}, false
+	}
+
+	var value ast.Expr
+	var visit func(stmts []ast.Statement)
+	visit = func(stmts []ast.Statement) {
+		for _, stmt := range stmts {
+			switch s := stmt.(type) {
+			case ast.AssignStatement:
+				if s.Target == name && s.Pos.Line <= line {
+					value = s.Value
+				}
+			case ast.ReassignStatement:
+				if s.Target == name && s.Pos.Line <= line {
+					value = s.Value
+				}
+			case ast.IfStatement:
+				visit(s.ThenBlock)
+				visit(s.ElseBlock)
+			case ast.WhileStatement:
+				visit(s.Body)
+			case ast.ForStatement:
+				visit(s.Body)
+			case ast.BackgroundStatement:
+				visit(s.Body)
+			}
+		}
+	}
+	visit(body)
+	if value == nil {
+		return valueShape{}, false
+	}
+	return shapeOfExpr(module, types, value)
+}
+
+// formatFunctionSignature formats fn as name(param: type, ...): type
+func formatFunctionSignature(fn *ast.Function) string {
+	params := make([]string, len(fn.Params))
+	for i, param := range fn.Params {
+		params[i] = fmt.Sprintf("%s: %s", param.Name, formatType(param.TypeAnnotation))
+	}
+	signature := fmt.Sprintf("%s(%s)", fn.Name, strings.Join(params, ", "))
+	if fn.ReturnType != nil {
+		signature += ": " + formatType(fn.ReturnType)
+	}
+	return signature
+}
+
+// builtinFunctionDoc describes a built-in function for completion
+type builtinFunctionDoc struct {
+	signature string
+	doc       string
+}
+
+// builtinFunctionDocs documents the interpreter's built-in functions
+var builtinFunctionDocs = map[string]builtinFunctionDoc{
+	"now":            {"now(): int", "Current Unix time in seconds"},
+	"time.now":       {"time.now(): int", "Current Unix time in seconds"},
+	"Ok":             {"Ok(value): Result", "Wrap a value in a successful Result"},
+	"Err":            {"Err(error): Result", "Wrap an error in a failed Result"},
+	"upper":          {"upper(s: str): str", "Convert a string to upper case"},
+	"lower":          {"lower(s: str): str", "Convert a string to lower case"},
+	"trim":           {"trim(s: str): str", "Remove leading and trailing whitespace"},
+	"split":          {"split(s: str, sep: str): [str]", "Split a string on a separator"},
+	"join":           {"join(parts: [str], sep: str): str", "Join strings with a separator"},
+	"contains":       {"contains(s: str, sub: str): bool", "Report whether a string contains a substring"},
+	"replace":        {"replace(s: str, old: str, new: str): str", "Replace every occurrence of old with new"},
+	"substring":      {"substring(s: str, start: int, end: int): str", "The part of a string between two indexes"},
+	"length":         {"length(value: str | array): int", "Length of a string or array"},
+	"startsWith":     {"startsWith(s: str, prefix: str): bool", "Report whether a string starts with a prefix"},
+	"endsWith":       {"endsWith(s: str, suffix: str): bool", "Report whether a string ends with a suffix"},
+	"indexOf":        {"indexOf(s: str, sub: str): int", "Index of the first occurrence of a substring, or -1"},
+	"charAt":         {"charAt(s: str, index: int): str", "The character at an index"},
+	"parseInt":       {"parseInt(s: str): int", "Parse a string as an integer"},
+	"parseFloat":     {"parseFloat(s: str): float", "Parse a string as a float"},
+	"toString":       {"toString(value): str", "Convert a value to a string"},
+	"abs":            {"abs(n): int | float", "Absolute value of a number"},
+	"min":            {"min(a, b)", "The smaller of two numbers"},
+	"max":            {"max(a, b)", "The larger of two numbers"},
+	"randomInt":      {"randomInt(min: int, max: int): int", "Random integer between min and max"},
+	"generateId":     {"generateId(): str", "New unique identifier"},
+	"uuid":           {"uuid(): str", "New random (version 4) UUID"},
+	"hash.sha256":    {"hash.sha256(str: str): str", "Hex SHA-256 digest of a string"},
+	"hmac.sha256":    {"hmac.sha256(key: str, msg: str): str", "Hex HMAC-SHA256 of msg, e.g. to check a webhook signature"},
+	"bcrypt.hash":    {"bcrypt.hash(password: str): str", "bcrypt hash of a password, for storing"},
+	"bcrypt.verify":  {"bcrypt.verify(password: str, hash: str): bool", "Whether a password matches a bcrypt hash"},
+	"crypto.hash":    {"crypto.hash(password: str): str", "bcrypt hash of a password, for storing"},
+	"crypto.verify":  {"crypto.verify(password: str, hash: str): bool", "Whether a password matches a bcrypt hash"},
+	"append":         {"append(array, value): array", "Array with value added at the end"},
+	"set":            {"set(object, key: str, value): object", "Object with key set to value"},
+	"remove":         {"remove(object, key: str): object", "Object without key"},
+	"keys":           {"keys(object): [str]", "Keys of an object"},
+	"map":            {"map(array, fn): array", "Apply fn to every element"},
+	"filter":         {"filter(array, fn): array", "Elements for which fn returns true"},
+	"reduce":         {"reduce(array, fn, initial)", "Combine elements with fn, starting from initial"},
+	"find":           {"find(array, fn)", "First element for which fn returns true"},
+	"some":           {"some(array, fn): bool", "Report whether fn returns true for any element"},
+	"every":          {"every(array, fn): bool", "Report whether fn returns true for every element"},
+	"sort":           {"sort(array, compare?): array", "Sorted copy of an array"},
+	"reverse":        {"reverse(array): array", "Array in reverse order"},
+	"flat":           {"flat(array): array", "Flatten one level of nested arrays"},
+	"slice":          {"slice(array, start: int, end: int): array", "Elements between two indexes"},
+	"text":           {"text(body: str, status?: int)", "Plain text response"},
+	"html":           {"html(body: str, status?: int)", "HTML response"},
+	"blob":           {"blob(data, contentType: str, filename?: str)", "Binary response"},
+	"redirect":       {"redirect(url: str, status?: int)", "Redirect response"},
+	"render":         {"render(name: str, data)", "HTML response from a template in templates/"},
+	"renderString":   {"renderString(template: str, data): str", "Render an inline HTML template"},
+	"enqueue":        {"enqueue(queue: str, message): bool", "Send a message to a queue worker"},
+	"queue.publish":  {"queue.publish(queue: str, message): bool", "Send a message to a queue worker"},
+	"emit":           {"emit(event: str, data): bool", "Send an event to its event handlers"},
+	"env":            {"env(name: str, default?): str", "Value of an environment variable, or default (null) when unset"},
+	"json.parse":     {"json.parse(text: str)", "Decode a JSON string into an object, array or scalar"},
+	"json.stringify": {"json.stringify(value): str", "Encode a value as a JSON string"},
+	"math.floor":     {"math.floor(n): int | float", "Round a number down"},
+	"math.ceil":      {"math.ceil(n): int | float", "Round a number up"},
+	"math.round":     {"math.round(n): int | float", "Round a number to the nearest whole number, halves away from zero"},
+	"math.abs":       {"math.abs(n): int | float", "Absolute value of a number"},
+	"math.min":       {"math.min(a, b)", "The smaller of two numbers"},
+	"math.max":       {"math.max(a, b)", "The larger of two numbers"},
+	"math.pow":       {"math.pow(base, exponent): float", "base raised to the power exponent"},
+	"math.sqrt":      {"math.sqrt(n): float", "Square root of a non-negative number"},
+}
@@ -0,0 +1,185 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+)
+
+const completionFixture = `: Address {
+  city: str!
+}
+
+: User {
+  name: str!
+  address: Address
+}
+
+! greet(u: User): str {
+  > u.name
+}
+
+@ POST /users -> User {
+  < input: User
+  + auth(jwt)
+  $ payload = {title: "x", meta: {tags: "a"}}
+  $ note = "User "
+  > input
+}
+`
+
+// withLine replaces one line of completionFixture, simulating an edit in
+// progress
+func withLine(line int, text string) string {
+	lines := strings.Split(completionFixture, "\n")
+	lines[line] = text
+	return strings.Join(lines, "\n")
+}
+
+func TestCompletionContexts(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string // Typed over completionFixture, which is opened first
+		pos    Position
+		want   []string
+		absent []string
+		kind   CompletionItemKind // Kind every item must have, if set
+	}{
+		{
+			name:   "route return type",
+			pos:    Position{Line: 13, Character: 19},
+			want:   []string{"User", "Address", "int", "str"},
+			absent: []string{"greet", "route-get"},
+		},
+		{
+			name:   "field type",
+			pos:    Position{Line: 6, Character: 11},
+			want:   []string{"User", "Address", "bool"},
+			absent: []string{"greet"},
+		},
+		{
+			name:   "parameter type",
+			pos:    Position{Line: 9, Character: 11},
+			want:   []string{"User", "Address"},
+			absent: []string{"greet"},
+		},
+		{
+			name:   "input type",
+			pos:    Position{Line: 14, Character: 11},
+			want:   []string{"User"},
+			absent: []string{"greet"},
+		},
+		{
+			name:   "inside string literal",
+			pos:    Position{Line: 17, Character: 16},
+			absent: []string{"User", "greet", "route-get"},
+		},
+		{
+			name:   "after comment marker",
+			source: withLine(17, "  # returns a User"),
+			pos:    Position{Line: 17, Character: 18},
+			absent: []string{"User", "greet"},
+		},
+		{
+			name:   "object literal value",
+			pos:    Position{Line: 16, Character: 22},
+			want:   []string{"greet", "upper", "toString"},
+			absent: []string{"User", "int"},
+			kind:   CompletionItemKindFunction,
+		},
+		{
+			name:   "return expression",
+			pos:    Position{Line: 18, Character: 4},
+			want:   []string{"greet", "filter"},
+			absent: []string{"route-get", "User"},
+			kind:   CompletionItemKindFunction,
+		},
+		{
+			name:   "function argument",
+			source: withLine(18, "  > greet("),
+			pos:    Position{Line: 18, Character: 10},
+			want:   []string{"greet", "length"},
+			kind:   CompletionItemKindFunction,
+		},
+		{
+			name:   "route annotation",
+			pos:    Position{Line: 15, Character: 4},
+			want:   []string{"auth", "ratelimit", "cors"},
+			absent: []string{"greet", "User"},
+			kind:   CompletionItemKindKeyword,
+		},
+		{
+			name:   "member of typed parameter",
+			pos:    Position{Line: 10, Character: 6},
+			want:   []string{"name", "address"},
+			absent: []string{"city", "greet"},
+			kind:   CompletionItemKindField,
+		},
+		{
+			name:   "nested member",
+			source: withLine(10, "  > u.address.c"),
+			pos:    Position{Line: 10, Character: 15},
+			want:   []string{"city"},
+			absent: []string{"name"},
+			kind:   CompletionItemKindField,
+		},
+		{
+			name:   "member of route input",
+			source: withLine(18, "  > input."),
+			pos:    Position{Line: 18, Character: 10},
+			want:   []string{"name", "address"},
+			kind:   CompletionItemKindField,
+		},
+		{
+			name:   "member of object literal",
+			source: withLine(17, "  $ note = payload.meta."),
+			pos:    Position{Line: 17, Character: 24},
+			want:   []string{"tags"},
+			absent: []string{"title"},
+			kind:   CompletionItemKindField,
+		},
+		{
+			name:   "member of unknown receiver",
+			source: withLine(18, "  > missing."),
+			pos:    Position{Line: 18, Character: 12},
+			absent: []string{"name", "greet", "route-get"},
+		},
+		{
+			name: "start of declaration",
+			pos:  Position{Line: 3, Character: 0},
+			want: []string{"route-get", "typedef", "User"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm := NewDocumentManager()
+			doc, _ := dm.Open("file:///test.glyph", 1, completionFixture)
+			if tt.source != "" {
+				doc, _ = dm.Update("file:///test.glyph", 2, []TextDocumentContentChangeEvent{{Text: tt.source}})
+			}
+
+			items := GetCompletion(doc, tt.pos)
+			labels := make(map[string]bool)
+			for _, item := range items {
+				labels[item.Label] = true
+				if tt.kind != 0 && item.Kind != tt.kind {
+					t.Errorf("Expected kind %d for %q, got %d", tt.kind, item.Label, item.Kind)
+				}
+				if item.Kind != CompletionItemKindSnippet && item.Kind != CompletionItemKindKeyword &&
+					(item.Detail == "" || item.Documentation == "") {
+					t.Errorf("Completion %q is missing detail or documentation", item.Label)
+				}
+			}
+			for _, label := range tt.want {
+				if !labels[label] {
+					t.Errorf("Expected completion %q", label)
+				}
+			}
+			for _, label := range tt.absent {
+				if labels[label] {
+					t.Errorf("Unexpected completion %q", label)
+				}
+			}
+		})
+	}
+}
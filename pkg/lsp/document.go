@@ -3,9 +3,11 @@ package lsp
 import (
 	"fmt"
 	"github.com/glyphlang/glyph/pkg/ast"
+	"net/url"
 	"strings"
 	"sync"
 
+	"github.com/glyphlang/glyph/pkg/interpreter"
 	"github.com/glyphlang/glyph/pkg/parser"
 )
 
@@ -17,6 +19,15 @@ type Document struct {
 	Lines   []string
 	AST     *ast.Module
 	Errors  []parser.ParseError
+
+	// LastValidAST is the most recent AST that parsed; it is kept while the
+	// document has errors so that completion still sees its declarations
+	LastValidAST *ast.Module
+
+	// Program is the document merged with the files it imports (nil when it
+	// has no imports); ImportError records why the imports failed to load
+	Program     *interpreter.Program
+	ImportError error
 }
 
 // DocumentManager manages open documents and their cached data
@@ -182,7 +193,51 @@ func (dm *DocumentManager) parseDocument(doc *Document) {
 
 	// Success
 	doc.AST = module
+	doc.LastValidAST = module
 	doc.Errors = nil
+	loadImports(doc)
+}
+
+// loadImports resolves the document's imports relative to its file so that
+// types and functions declared in imported files are known
+func loadImports(doc *Document) {
+	doc.Program = nil
+	doc.ImportError = nil
+
+	hasImports := false
+	for _, item := range doc.AST.Items {
+		if _, ok := item.(*ast.ImportStatement); ok {
+			hasImports = true
+			break
+		}
+	}
+	path := uriToPath(doc.URI)
+	if !hasImports || path == "" {
+		return
+	}
+
+	loader := interpreter.NewProgramLoader(func(source string) (*ast.Module, error) {
+		tokens, err := parser.NewLexer(source).Tokenize()
+		if err != nil {
+			return nil, err
+		}
+		return parser.NewParser(tokens).Parse()
+	})
+	doc.Program, doc.ImportError = loader.LoadSource(path, doc.Content)
+}
+
+// uriToPath converts a file:// URI to a local path, or "" for other schemes
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return u.Path
+}
+
+// pathToURI converts a local path to a file:// URI
+func pathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: path}).String()
 }
 
 // GetWordAtPosition returns the word at a given position
@@ -5,6 +5,8 @@ import (
 	"github.com/glyphlang/glyph/pkg/ast"
 	"strings"
 
+	"github.com/glyphlang/glyph/pkg/analysis"
+	"github.com/glyphlang/glyph/pkg/formatter"
 	"github.com/glyphlang/glyph/pkg/server"
 )
 
@@ -37,13 +39,34 @@ func GetDiagnostics(doc *Document) []Diagnostic {
 		diagnostics = append(diagnostics, diagnostic)
 	}
 
+	// Imports that could not be resolved, parsed or merged
+	if doc.ImportError != nil {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range: Range{
+				Start: Position{Line: 0, Character: 0},
+				End:   Position{Line: 0, Character: 0},
+			},
+			Severity: DiagnosticSeverityError,
+			Source:   "glyph",
+			Message:  doc.ImportError.Error(),
+		})
+	}
+
 	// Type checking errors (if AST is available)
 	if doc.AST != nil {
-		typeErrors := checkTypes(doc.AST)
+		var typeErrors []Diagnostic
+		if doc.Program != nil {
+			typeErrors = checkTypes(doc.AST, doc.Program.Module)
+		} else {
+			typeErrors = checkTypes(doc.AST)
+		}
 		for _, err := range typeErrors {
 			diagnostics = append(diagnostics, err)
 		}
 
+		// Static analysis, shared with glyph check
+		diagnostics = append(diagnostics, getAnalysisDiagnostics(doc)...)
+
 		// Add optimizer hints
 		optimizerHints := getOptimizerHints(doc.AST)
 		diagnostics = append(diagnostics, optimizerHints...)
@@ -52,6 +75,39 @@ func GetDiagnostics(doc *Document) []Diagnostic {
 	return diagnostics
 }
 
+// getAnalysisDiagnostics runs the analysis pass over the document, merged
+// with its imports when they loaded, and keeps the results for this document
+func getAnalysisDiagnostics(doc *Document) []Diagnostic {
+	module := doc.AST
+	opts := analysis.Options{Partial: true}
+	if doc.Program != nil {
+		module = doc.Program.Module
+		opts = analysis.Options{FileOf: doc.Program.FileOf}
+	}
+
+	var diagnostics []Diagnostic
+	for _, d := range analysis.Check(module, opts) {
+		if doc.Program != nil && d.File != doc.Program.Entry {
+			continue
+		}
+		pos := Position{}
+		if d.Line > 0 {
+			pos = Position{Line: d.Line - 1, Character: d.Column - 1}
+		}
+		severity := DiagnosticSeverityWarning
+		if d.Severity == analysis.SeverityError {
+			severity = DiagnosticSeverityError
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    Range{Start: pos, End: Position{Line: pos.Line, Character: pos.Character + 1}},
+			Severity: severity,
+			Source:   "glyph",
+			Message:  d.Message,
+		})
+	}
+	return diagnostics
+}
+
 // GetHover returns hover information at a position
 func GetHover(doc *Document, pos Position) *Hover {
 	if doc.AST == nil {
@@ -213,6 +269,20 @@ func GetHover(doc *Document, pos Position) *Hover {
 // For .glyph files, returns compact syntax keywords and snippets.
 // The IsGlyphX() method on Document (defined in document.go:119) determines the syntax mode.
 func GetCompletion(doc *Document, pos Position) []CompletionItem {
+	context, receiver := detectCompletionContext(doc, pos)
+	switch context {
+	case completionNone:
+		return nil
+	case completionType:
+		return getTypeCompletions(doc)
+	case completionExpression:
+		return getExpressionCompletions(doc)
+	case completionMember:
+		return getMemberCompletions(doc, pos, receiver)
+	case completionAnnotation:
+		return getAnnotationCompletions()
+	}
+
 	if doc.IsGlyphX() {
 		return getExpandedCompletion(doc, pos)
 	}
@@ -226,7 +296,7 @@ func getCompactCompletion(doc *Document, pos Position) []CompletionItem {
 	// Add keywords
 	keywords := []string{
 		"route", "if", "else", "while", "for", "in", "switch", "case", "default",
-		"true", "false",
+		"background", "true", "false",
 	}
 
 	for _, kw := range keywords {
@@ -238,14 +308,7 @@ func getCompactCompletion(doc *Document, pos Position) []CompletionItem {
 	}
 
 	// Add built-in types
-	types := []string{"int", "str", "string", "bool", "float"}
-	for _, t := range types {
-		items = append(items, CompletionItem{
-			Label:  t,
-			Kind:   CompletionItemKindClass,
-			Detail: "Built-in type",
-		})
-	}
+	items = append(items, getBuiltinTypeCompletions()...)
 
 	// Add HTTP methods
 	methods := []string{"GET", "POST", "PUT", "DELETE", "PATCH"}
@@ -356,6 +419,7 @@ func getExpandedCompletion(doc *Document, pos Position) []CompletionItem {
 		{"switch", "Multi-way branch"},
 		{"case", "Switch case"},
 		{"default", "Default case"},
+		{"background", "Run a block after the response is sent"},
 		{"true", "Boolean true"},
 		{"false", "Boolean false"},
 		{"null", "Null value"},
@@ -374,14 +438,7 @@ func getExpandedCompletion(doc *Document, pos Position) []CompletionItem {
 	}
 
 	// Add built-in types
-	types := []string{"int", "str", "string", "bool", "float"}
-	for _, t := range types {
-		items = append(items, CompletionItem{
-			Label:  t,
-			Kind:   CompletionItemKindClass,
-			Detail: "Built-in type",
-		})
-	}
+	items = append(items, getBuiltinTypeCompletions()...)
 
 	// Add HTTP methods
 	methods := []string{"GET", "POST", "PUT", "DELETE", "PATCH"}
@@ -464,16 +521,27 @@ func getExpandedCompletion(doc *Document, pos Position) []CompletionItem {
 	return items
 }
 
-// getDefinedTypeCompletions returns completions for types defined in the document AST
+// getDefinedTypeCompletions returns completions for types defined in the
+// document and the files it imports
 func getDefinedTypeCompletions(doc *Document) []CompletionItem {
 	var items []CompletionItem
-	if doc.AST != nil {
-		for _, item := range doc.AST.Items {
-			if typeDef, ok := item.(*ast.TypeDef); ok {
+	seen := make(map[string]bool)
+	modules := []*ast.Module{completionModule(doc)}
+	if doc.Program != nil {
+		modules = append(modules, doc.Program.Module)
+	}
+	for _, module := range modules {
+		if module == nil {
+			continue
+		}
+		for _, item := range module.Items {
+			if typeDef, ok := item.(*ast.TypeDef); ok && !seen[typeDef.Name] {
+				seen[typeDef.Name] = true
 				items = append(items, CompletionItem{
-					Label:  typeDef.Name,
-					Kind:   CompletionItemKindStruct,
-					Detail: "Type definition",
+					Label:         typeDef.Name,
+					Kind:          CompletionItemKindStruct,
+					Detail:        "Type definition",
+					Documentation: formatTypeDefHover(typeDef),
 				})
 			}
 		}
@@ -481,31 +549,44 @@ func getDefinedTypeCompletions(doc *Document) []CompletionItem {
 	return items
 }
 
-// GetDefinition returns the definition location for a symbol at position
+// GetDefinition returns the declaration of the type, function or variable
+// at position
 func GetDefinition(doc *Document, pos Position) []Location {
 	if doc.AST == nil {
 		return nil
 	}
 
+	ix := buildIndex(doc)
+	if i := ix.at(pos); i >= 0 {
+		if decl, ok := ix.definition(i); ok {
+			return []Location{{URI: doc.URI, Range: decl.rng}}
+		}
+	}
+
 	word := doc.GetWordAtPosition(pos)
 	if word == "" {
 		return nil
 	}
 
-	// Find type definitions
-	for _, item := range doc.AST.Items {
-		if typeDef, ok := item.(*ast.TypeDef); ok {
-			if typeDef.Name == word {
-				// Return approximate location (we'd need position info in AST for exact location)
-				return []Location{
-					{
-						URI: doc.URI,
-						Range: Range{
-							Start: Position{Line: 0, Character: 0},
-							End:   Position{Line: 0, Character: len(word)},
-						},
+	// Fall back to types declared in imported files
+	if doc.Program != nil {
+		for _, item := range doc.Program.Module.Items {
+			typeDef, ok := item.(*ast.TypeDef)
+			if !ok || typeDef.Name != word {
+				continue
+			}
+			file := doc.Program.FileOf(item)
+			if file == "" || file == doc.Program.Entry {
+				continue
+			}
+			return []Location{
+				{
+					URI: pathToURI(file),
+					Range: Range{
+						Start: Position{Line: 0, Character: 0},
+						End:   Position{Line: 0, Character: len(word)},
 					},
-				}
+				},
 			}
 		}
 	}
@@ -742,240 +823,31 @@ func GetDocumentSymbols(doc *Document) []DocumentSymbol {
 	return symbols
 }
 
-// GetReferences returns all references to a symbol at position
+// GetReferences returns every use of the type, function or variable at
+// position within the document
 func GetReferences(doc *Document, pos Position, includeDeclaration bool) []Location {
 	if doc.AST == nil {
 		return nil
 	}
 
-	word := doc.GetWordAtPosition(pos)
-	if word == "" {
+	ix := buildIndex(doc)
+	i := ix.at(pos)
+	if i < 0 {
 		return nil
 	}
 
 	var locations []Location
-
-	// Find all references to this symbol in the AST
-	for _, item := range doc.AST.Items {
-		switch v := item.(type) {
-		case *ast.Route:
-			// Check if the word is a route parameter
-			params := server.ExtractRouteParamNames(v.Path)
-			for _, param := range params {
-				if param == word && includeDeclaration {
-					// This is the declaration in the route path
-					locations = append(locations, Location{
-						URI: doc.URI,
-						Range: Range{
-							Start: Position{Line: 0, Character: 0},
-							End:   Position{Line: 0, Character: 0},
-						},
-					})
-				}
-			}
-
-			// Check route body for variable references
-			locations = append(locations, findReferencesInStatements(v.Body, word, doc.URI)...)
-
-		case ast.Function:
-			// Check function parameters
-			for _, param := range v.Params {
-				if param.Name == word && includeDeclaration {
-					locations = append(locations, Location{
-						URI: doc.URI,
-						Range: Range{
-							Start: Position{Line: 0, Character: 0},
-							End:   Position{Line: 0, Character: 0},
-						},
-					})
-				}
-			}
-
-			// Check function body
-			locations = append(locations, findReferencesInStatements(v.Body, word, doc.URI)...)
-
-		case *ast.TypeDef:
-			// Check if this is the type definition
-			if v.Name == word && includeDeclaration {
-				locations = append(locations, Location{
-					URI: doc.URI,
-					Range: Range{
-						Start: Position{Line: 0, Character: 0},
-						End:   Position{Line: 0, Character: 0},
-					},
-				})
-			}
-		}
-	}
-
-	return locations
-}
-
-// findReferencesInStatements finds all references to a symbol in a list of statements
-func findReferencesInStatements(stmts []ast.Statement, symbol string, uri string) []Location {
-	var locations []Location
-
-	for _, stmt := range stmts {
-		locations = append(locations, findReferencesInStatement(stmt, symbol, uri)...)
+	for _, occ := range ix.references(i, includeDeclaration) {
+		locations = append(locations, Location{URI: doc.URI, Range: occ.rng})
 	}
-
-	return locations
-}
-
-// findReferencesInStatement finds all references to a symbol in a statement
-func findReferencesInStatement(stmt ast.Statement, symbol string, uri string) []Location {
-	var locations []Location
-
-	switch s := stmt.(type) {
-	case *ast.AssignStatement:
-		// Check if the assignment target is our symbol
-		if s.Target == symbol {
-			locations = append(locations, Location{
-				URI: uri,
-				Range: Range{
-					Start: Position{Line: 0, Character: 0},
-					End:   Position{Line: 0, Character: 0},
-				},
-			})
-		}
-		// Check the value expression
-		locations = append(locations, findReferencesInExpression(s.Value, symbol, uri)...)
-
-	case ast.AssignStatement:
-		if s.Target == symbol {
-			locations = append(locations, Location{
-				URI: uri,
-				Range: Range{
-					Start: Position{Line: 0, Character: 0},
-					End:   Position{Line: 0, Character: 0},
-				},
-			})
-		}
-		locations = append(locations, findReferencesInExpression(s.Value, symbol, uri)...)
-
-	case *ast.ReturnStatement:
-		locations = append(locations, findReferencesInExpression(s.Value, symbol, uri)...)
-
-	case ast.ReturnStatement:
-		locations = append(locations, findReferencesInExpression(s.Value, symbol, uri)...)
-
-	case *ast.IfStatement:
-		locations = append(locations, findReferencesInExpression(s.Condition, symbol, uri)...)
-		locations = append(locations, findReferencesInStatements(s.ThenBlock, symbol, uri)...)
-		locations = append(locations, findReferencesInStatements(s.ElseBlock, symbol, uri)...)
-
-	case ast.IfStatement:
-		locations = append(locations, findReferencesInExpression(s.Condition, symbol, uri)...)
-		locations = append(locations, findReferencesInStatements(s.ThenBlock, symbol, uri)...)
-		locations = append(locations, findReferencesInStatements(s.ElseBlock, symbol, uri)...)
-
-	case *ast.WhileStatement:
-		locations = append(locations, findReferencesInExpression(s.Condition, symbol, uri)...)
-		locations = append(locations, findReferencesInStatements(s.Body, symbol, uri)...)
-
-	case ast.WhileStatement:
-		locations = append(locations, findReferencesInExpression(s.Condition, symbol, uri)...)
-		locations = append(locations, findReferencesInStatements(s.Body, symbol, uri)...)
-
-	case *ast.ReassignStatement:
-		// Check if the reassignment target is our symbol
-		if s.Target == symbol {
-			locations = append(locations, Location{
-				URI: uri,
-				Range: Range{
-					Start: Position{Line: 0, Character: 0},
-					End:   Position{Line: 0, Character: 0},
-				},
-			})
-		}
-		// Check the value expression
-		locations = append(locations, findReferencesInExpression(s.Value, symbol, uri)...)
-
-	case ast.ReassignStatement:
-		if s.Target == symbol {
-			locations = append(locations, Location{
-				URI: uri,
-				Range: Range{
-					Start: Position{Line: 0, Character: 0},
-					End:   Position{Line: 0, Character: 0},
-				},
-			})
-		}
-		locations = append(locations, findReferencesInExpression(s.Value, symbol, uri)...)
-	}
-
-	return locations
-}
-
-// findReferencesInExpression finds all references to a symbol in an expression
-func findReferencesInExpression(expr ast.Expr, symbol string, uri string) []Location {
-	var locations []Location
-
-	switch e := expr.(type) {
-	case *ast.VariableExpr:
-		if e.Name == symbol {
-			locations = append(locations, Location{
-				URI: uri,
-				Range: Range{
-					Start: Position{Line: 0, Character: 0},
-					End:   Position{Line: 0, Character: 0},
-				},
-			})
-		}
-
-	case ast.VariableExpr:
-		if e.Name == symbol {
-			locations = append(locations, Location{
-				URI: uri,
-				Range: Range{
-					Start: Position{Line: 0, Character: 0},
-					End:   Position{Line: 0, Character: 0},
-				},
-			})
-		}
-
-	case *ast.BinaryOpExpr:
-		locations = append(locations, findReferencesInExpression(e.Left, symbol, uri)...)
-		locations = append(locations, findReferencesInExpression(e.Right, symbol, uri)...)
-
-	case ast.BinaryOpExpr:
-		locations = append(locations, findReferencesInExpression(e.Left, symbol, uri)...)
-		locations = append(locations, findReferencesInExpression(e.Right, symbol, uri)...)
-
-	case *ast.ObjectExpr:
-		for _, field := range e.Fields {
-			locations = append(locations, findReferencesInExpression(field.Value, symbol, uri)...)
-		}
-
-	case ast.ObjectExpr:
-		for _, field := range e.Fields {
-			locations = append(locations, findReferencesInExpression(field.Value, symbol, uri)...)
-		}
-
-	case *ast.ArrayExpr:
-		for _, elem := range e.Elements {
-			locations = append(locations, findReferencesInExpression(elem, symbol, uri)...)
-		}
-
-	case ast.ArrayExpr:
-		for _, elem := range e.Elements {
-			locations = append(locations, findReferencesInExpression(elem, symbol, uri)...)
-		}
-
-	case *ast.FieldAccessExpr:
-		locations = append(locations, findReferencesInExpression(e.Object, symbol, uri)...)
-
-	case ast.FieldAccessExpr:
-		locations = append(locations, findReferencesInExpression(e.Object, symbol, uri)...)
-	}
-
 	return locations
 }
 
 // Helper functions
 
-// checkTypes performs basic type checking and returns diagnostics
-func checkTypes(module *ast.Module) []Diagnostic {
+// checkTypes performs basic type checking and returns diagnostics. Types
+// declared in the imported modules count as defined.
+func checkTypes(module *ast.Module, imported ...*ast.Module) []Diagnostic {
 	var diagnostics []Diagnostic
 
 	// For now, just check for undefined types in fields
@@ -987,9 +859,14 @@ func checkTypes(module *ast.Module) []Diagnostic {
 	knownTypes["float"] = true
 
 	// Collect defined types
-	for _, item := range module.Items {
-		if typeDef, ok := item.(*ast.TypeDef); ok {
-			knownTypes[typeDef.Name] = true
+	for _, m := range append([]*ast.Module{module}, imported...) {
+		for _, item := range m.Items {
+			switch it := item.(type) {
+			case *ast.TypeDef:
+				knownTypes[it.Name] = true
+			case *ast.EnumDef:
+				knownTypes[it.Name] = true
+			}
 		}
 	}
 
@@ -1114,25 +991,26 @@ func checkTypes(module *ast.Module) []Diagnostic {
 func getKeywordInfo(keyword string) string {
 	keywordDocs := map[string]string{
 		// Shared keywords (same in both syntaxes)
-		"route":   "**route** - Defines an HTTP route handler\n\nCompact: `@ route /path [METHOD]`\nExpanded: `route /path [METHOD]`",
-		"GET":     "**GET** - HTTP GET method for retrieving resources",
-		"POST":    "**POST** - HTTP POST method for creating resources",
-		"PUT":     "**PUT** - HTTP PUT method for replacing resources",
-		"DELETE":  "**DELETE** - HTTP DELETE method for removing resources",
-		"PATCH":   "**PATCH** - HTTP PATCH method for partial updates",
-		"command": "**command** - Defines a CLI command\n\nCompact: `! command name`\nExpanded: `command name`",
-		"cron":    "**cron** - Defines a scheduled task\n\nCompact: `* cron \"schedule\"`\nExpanded: `cron \"schedule\"`",
-		"event":   "**event** - Defines an event handler\n\nCompact: `~ event \"type\"`\nExpanded: `handle \"type\"`",
-		"queue":   "**queue** - Defines a message queue worker\n\nCompact: `& queue \"name\"`\nExpanded: `queue \"name\"`",
-		"if":      "**if** - Conditional statement\n\nExample:\n```glyph\nif condition {\n  > {success: true}\n}\n```",
-		"else":    "**else** - Alternative branch for if statement",
-		"while":   "**while** - Loop that executes while condition is true",
-		"for":     "**for** - Iterates over arrays or objects",
-		"switch":  "**switch** - Multi-way branch statement",
-		"case":    "**case** - Branch in switch statement",
-		"default": "**default** - Default branch in switch statement",
-		"true":    "**true** - Boolean literal",
-		"false":   "**false** - Boolean literal",
+		"route":      "**route** - Defines an HTTP route handler\n\nCompact: `@ route /path [METHOD]`\nExpanded: `route /path [METHOD]`",
+		"GET":        "**GET** - HTTP GET method for retrieving resources",
+		"POST":       "**POST** - HTTP POST method for creating resources",
+		"PUT":        "**PUT** - HTTP PUT method for replacing resources",
+		"DELETE":     "**DELETE** - HTTP DELETE method for removing resources",
+		"PATCH":      "**PATCH** - HTTP PATCH method for partial updates",
+		"command":    "**command** - Defines a CLI command\n\nCompact: `! command name`\nExpanded: `command name`",
+		"cron":       "**cron** - Defines a scheduled task\n\nCompact: `* cron \"schedule\"`\nExpanded: `cron \"schedule\"`",
+		"event":      "**event** - Defines an event handler\n\nCompact: `~ event \"type\"`\nExpanded: `handle \"type\"`",
+		"queue":      "**queue** - Defines a message queue worker\n\nCompact: `& queue \"name\"`\nExpanded: `queue \"name\"`",
+		"if":         "**if** - Conditional statement\n\nExample:\n```glyph\nif condition {\n  > {success: true}\n}\n```",
+		"else":       "**else** - Alternative branch for if statement",
+		"while":      "**while** - Loop that executes while condition is true",
+		"for":        "**for** - Iterates over arrays or objects",
+		"switch":     "**switch** - Multi-way branch statement",
+		"case":       "**case** - Branch in switch statement",
+		"default":    "**default** - Default branch in switch statement",
+		"background": "**background** - Runs a block after the response is sent, with a copy of the route's variables\n\nCompact: `defer! { ... }`\nExpanded: `background { ... }`",
+		"true":       "**true** - Boolean literal",
+		"false":      "**false** - Boolean literal",
 		// Expanded-only keywords (map to compact symbols)
 		"type":       "**type** - Define a type definition\n\nExpanded form of `:` in compact syntax\n\nExample:\n```glyphx\ntype User {\n  name: str!\n  email: str!\n}\n```",
 		"let":        "**let** - Declare a variable\n\nExpanded form of `$` in compact syntax\n\nExample:\n```glyphx\nlet result = db.query()\n```",
@@ -1798,12 +1676,40 @@ func extractSuggestion(msg string) string {
 // Document Formatting
 // ========================================
 
-// FormatDocument formats the entire document
+// FormatDocument formats the entire document with the canonical formatter
+// used by glyph fmt. Documents that do not parse only get re-indented.
 func FormatDocument(doc *Document, options FormattingOptions) []TextEdit {
 	if doc.Content == "" {
 		return nil
 	}
 
+	mode := formatter.Compact
+	if doc.IsGlyphX() {
+		mode = formatter.Expanded
+	}
+	formatted, err := formatter.FormatSource(doc.Content, mode)
+	if err != nil {
+		return formatIndentation(doc, options)
+	}
+	if formatted == doc.Content {
+		return nil
+	}
+
+	last := len(doc.Lines) - 1
+	return []TextEdit{
+		{
+			Range: Range{
+				Start: Position{Line: 0, Character: 0},
+				End:   Position{Line: last, Character: len(doc.Lines[last])},
+			},
+			NewText: formatted,
+		},
+	}
+}
+
+// formatIndentation fixes brace indentation line by line
+func formatIndentation(doc *Document, options FormattingOptions) []TextEdit {
+
 	var edits []TextEdit
 	lines := strings.Split(doc.Content, "\n")
 	indent := "  " // Default 2 spaces
@@ -1,6 +1,8 @@
 package lsp
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -199,6 +201,49 @@ func TestGetDefinition(t *testing.T) {
 	}
 }
 
+func TestImportedTypes(t *testing.T) {
+	dir := t.TempDir()
+	typesFile := filepath.Join(dir, "types.glyph")
+	if err := os.WriteFile(typesFile, []byte(": User {\n  name: str!\n}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dm := NewDocumentManager()
+	source := `import "./types"
+
+: Post {
+  author: User
+}
+`
+	doc, _ := dm.Open(pathToURI(filepath.Join(dir, "main.glyph")), 1, source)
+	if doc.ImportError != nil {
+		t.Fatalf("Unexpected import error: %v", doc.ImportError)
+	}
+
+	for _, diag := range GetDiagnostics(doc) {
+		if strings.Contains(diag.Message, "Undefined type") {
+			t.Errorf("Imported type reported as undefined: %s", diag.Message)
+		}
+	}
+
+	definitions := GetDefinition(doc, Position{Line: 3, Character: 11})
+	if len(definitions) != 1 || definitions[0].URI != pathToURI(typesFile) {
+		t.Errorf("Expected definition in %s, got %v", typesFile, definitions)
+	}
+
+	// A missing import is reported as a diagnostic
+	doc, _ = dm.Update(doc.URI, 2, []TextDocumentContentChangeEvent{{Text: `import "./missing"`}})
+	found := false
+	for _, diag := range GetDiagnostics(doc) {
+		if strings.Contains(diag.Message, "failed to import") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected a diagnostic for the missing import")
+	}
+}
+
 func TestGetDocumentSymbols(t *testing.T) {
 	dm := NewDocumentManager()
 
@@ -503,3 +548,34 @@ func TestDiagnosticsWithHint(t *testing.T) {
 	// This is optional - not all errors have hints
 	_ = foundHint
 }
+
+func TestAnalysisDiagnostics(t *testing.T) {
+	dm := NewDocumentManager()
+	source := `@ GET /users/:id {
+  > {text: msg}
+}
+`
+	doc, _ := dm.Open("file:///test.glyph", 1, source)
+
+	var undefined, unused bool
+	for _, diag := range GetDiagnostics(doc) {
+		if diag.Message == "undefined variable: msg" {
+			undefined = true
+			if diag.Severity != DiagnosticSeverityError || diag.Range.Start.Line != 1 {
+				t.Errorf("Unexpected diagnostic for undefined variable: %+v", diag)
+			}
+		}
+		if strings.Contains(diag.Message, "path parameter 'id'") {
+			unused = true
+			if diag.Severity != DiagnosticSeverityWarning {
+				t.Errorf("Expected warning for unused path parameter, got %+v", diag)
+			}
+		}
+	}
+	if !undefined {
+		t.Error("Expected undefined variable diagnostic")
+	}
+	if !unused {
+		t.Error("Expected unused path parameter diagnostic")
+	}
+}
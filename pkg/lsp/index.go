@@ -0,0 +1,498 @@
+package lsp
+
+import (
+	"strings"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/glyphlang/glyph/pkg/server"
+)
+
+// symbolKind distinguishes the namespaces a name can live in
+type symbolKind int
+
+const (
+	symbolType symbolKind = iota
+	symbolFunction
+	symbolVariable
+)
+
+// occurrence is a declaration or use of a symbol in a document
+type occurrence struct {
+	kind  symbolKind
+	name  string
+	rng   Range
+	decl  int  // Index of the declaring occurrence, or -1 when unresolved
+	isDef bool // This occurrence is the declaration
+}
+
+// documentIndex records where the types, functions and variables of a
+// document are declared and used
+type documentIndex struct {
+	doc         *Document
+	occurrences []occurrence
+	types       map[string]int
+	functions   map[string]int
+	scopes      []map[string]int // Variables in the enclosing blocks, innermost last
+}
+
+// buildIndex indexes doc's AST. Declarations are resolved within the
+// document; names declared in imported files are left unresolved.
+func buildIndex(doc *Document) *documentIndex {
+	ix := &documentIndex{
+		doc:       doc,
+		types:     make(map[string]int),
+		functions: make(map[string]int),
+	}
+	if doc.AST == nil {
+		return ix
+	}
+
+	// Types and functions are visible everywhere, including before their
+	// declaration
+	for _, item := range doc.AST.Items {
+		switch it := item.(type) {
+		case *ast.TypeDef:
+			if i := ix.declare(symbolType, it.Name, it.Pos); i >= 0 {
+				ix.types[it.Name] = i
+			}
+		case *ast.Function:
+			if i := ix.declare(symbolFunction, it.Name, it.Pos); i >= 0 {
+				ix.functions[it.Name] = i
+			}
+		}
+	}
+
+	for _, item := range doc.AST.Items {
+		switch it := item.(type) {
+		case *ast.TypeDef:
+			for _, field := range it.Fields {
+				ix.typeRefs(field.TypeAnnotation)
+				ix.expr(field.Default)
+			}
+		case *ast.Function:
+			ix.push()
+			for _, param := range it.Params {
+				ix.typeRefs(param.TypeAnnotation)
+				ix.expr(param.Default)
+				ix.declareVariable(param.Name, param.Pos)
+			}
+			ix.typeRefs(it.ReturnType)
+			ix.statements(it.Body)
+			ix.pop()
+		case *ast.Route:
+			ix.push()
+			for _, param := range server.ExtractRouteParamNames(it.Path) {
+				ix.declarePathParam(param, it.Pos)
+			}
+			for _, param := range it.QueryParams {
+				ix.typeRefs(param.Type)
+				ix.expr(param.Default)
+				ix.declareVariable(param.Name, ast.Pos{})
+			}
+			ix.typeRefs(it.InputType)
+			ix.typeRefs(it.ReturnType)
+			ix.statements(it.Body)
+			ix.pop()
+		case *ast.Command:
+			ix.push()
+			for _, param := range it.Params {
+				ix.typeRefs(param.Type)
+				ix.expr(param.Default)
+				ix.declareVariable(param.Name, ast.Pos{})
+			}
+			ix.typeRefs(it.ReturnType)
+			ix.statements(it.Body)
+			ix.pop()
+		case *ast.ConstDecl:
+			ix.typeRefs(it.Type)
+			ix.expr(it.Value)
+		case *ast.CronTask:
+			ix.block(it.Body)
+		case *ast.EventHandler:
+			ix.block(it.Body)
+		case *ast.QueueWorker:
+			ix.block(it.Body)
+		case *ast.TestBlock:
+			ix.block(it.Body)
+		case *ast.SeedBlock:
+			ix.block(it.Body)
+		case *ast.MiddlewareDef:
+			ix.block(it.Body)
+		case *ast.ErrorHandler:
+			ix.block(it.Body)
+		}
+	}
+	return ix
+}
+
+// at returns the index of the occurrence containing pos, or -1
+func (ix *documentIndex) at(pos Position) int {
+	for i, occ := range ix.occurrences {
+		r := occ.rng
+		if r.Start.Line == pos.Line && r.Start.Character <= pos.Character && pos.Character <= r.End.Character {
+			return i
+		}
+	}
+	return -1
+}
+
+// definition returns the declaration the occurrence at i refers to
+func (ix *documentIndex) definition(i int) (occurrence, bool) {
+	decl := ix.occurrences[i].decl
+	if decl < 0 {
+		return occurrence{}, false
+	}
+	return ix.occurrences[decl], true
+}
+
+// references returns every occurrence of the symbol the occurrence at i
+// refers to, in document order
+func (ix *documentIndex) references(i int, includeDeclaration bool) []occurrence {
+	decl := ix.occurrences[i].decl
+	if decl < 0 {
+		return nil
+	}
+	var refs []occurrence
+	for _, occ := range ix.occurrences {
+		if occ.decl == decl && (includeDeclaration || !occ.isDef) {
+			refs = append(refs, occ)
+		}
+	}
+	return refs
+}
+
+// declare records a declaration of name found at or after pos on its line
+func (ix *documentIndex) declare(kind symbolKind, name string, pos ast.Pos) int {
+	rng, ok := ix.nameRange(name, pos)
+	if !ok {
+		return -1
+	}
+	i := len(ix.occurrences)
+	ix.occurrences = append(ix.occurrences, occurrence{kind: kind, name: name, rng: rng, decl: i, isDef: true})
+	return i
+}
+
+// reference records a use of name at or after pos that resolves to decl
+func (ix *documentIndex) reference(kind symbolKind, name string, pos ast.Pos, decl int) {
+	rng, ok := ix.nameRange(name, pos)
+	if !ok {
+		return
+	}
+	ix.occurrences = append(ix.occurrences, occurrence{kind: kind, name: name, rng: rng, decl: decl})
+}
+
+// nameRange locates name on pos's line, starting at pos's column. Nodes are
+// positioned at their leading token, which precedes the name for items and
+// method calls.
+func (ix *documentIndex) nameRange(name string, pos ast.Pos) (Range, bool) {
+	if !pos.HasPos() || pos.Line > len(ix.doc.Lines) {
+		return Range{}, false
+	}
+	line := ix.doc.Lines[pos.Line-1]
+	from := pos.Column - 1
+	if from < 0 || from > len(line) {
+		return Range{}, false
+	}
+	for {
+		idx := strings.Index(line[from:], name)
+		if idx < 0 {
+			return Range{}, false
+		}
+		start := from + idx
+		end := start + len(name)
+		if (start == 0 || !isIdentifierChar(line[start-1])) && (end == len(line) || !isIdentifierChar(line[end])) {
+			return Range{
+				Start: Position{Line: pos.Line - 1, Character: start},
+				End:   Position{Line: pos.Line - 1, Character: end},
+			}, true
+		}
+		from = end
+	}
+}
+
+func (ix *documentIndex) push() {
+	ix.scopes = append(ix.scopes, make(map[string]int))
+}
+
+func (ix *documentIndex) pop() {
+	ix.scopes = ix.scopes[:len(ix.scopes)-1]
+}
+
+// block indexes statements in a scope of their own
+func (ix *documentIndex) block(stmts []ast.Statement) {
+	ix.push()
+	ix.statements(stmts)
+	ix.pop()
+}
+
+// declareVariable binds name in the innermost scope. Bindings without a
+// position still shadow outer variables of the same name.
+func (ix *documentIndex) declareVariable(name string, pos ast.Pos) {
+	ix.scopes[len(ix.scopes)-1][name] = ix.declare(symbolVariable, name, pos)
+}
+
+// declarePathParam binds a route path parameter, declared at its :name
+// segment on the route's line
+func (ix *documentIndex) declarePathParam(name string, pos ast.Pos) {
+	if segment, ok := ix.nameRange(":"+name, pos); ok {
+		pos = ast.Pos{Line: pos.Line, Column: segment.Start.Character + 2}
+	} else {
+		pos = ast.Pos{}
+	}
+	ix.declareVariable(name, pos)
+}
+
+// lookup resolves a variable through the enclosing scopes
+func (ix *documentIndex) lookup(name string) (int, bool) {
+	for i := len(ix.scopes) - 1; i >= 0; i-- {
+		if decl, ok := ix.scopes[i][name]; ok {
+			return decl, true
+		}
+	}
+	return -1, false
+}
+
+// variableRef records a read or reassignment of name. Functions may be
+// referenced by name as values, e.g. map(double).
+func (ix *documentIndex) variableRef(name string, pos ast.Pos) {
+	if decl, ok := ix.lookup(name); ok {
+		ix.reference(symbolVariable, name, pos, decl)
+		return
+	}
+	if decl, ok := ix.functions[name]; ok {
+		ix.reference(symbolFunction, name, pos, decl)
+		return
+	}
+	ix.reference(symbolVariable, name, pos, -1)
+}
+
+// typeRefs records the named types used in t
+func (ix *documentIndex) typeRefs(t ast.Type) {
+	switch tt := t.(type) {
+	case ast.NamedType:
+		decl, ok := ix.types[tt.Name]
+		if !ok {
+			decl = -1
+		}
+		ix.reference(symbolType, tt.Name, tt.Pos, decl)
+	case ast.ArrayType:
+		ix.typeRefs(tt.ElementType)
+	case ast.OptionalType:
+		ix.typeRefs(tt.InnerType)
+	case ast.UnionType:
+		for _, member := range tt.Types {
+			ix.typeRefs(member)
+		}
+	case ast.GenericType:
+		ix.typeRefs(tt.BaseType)
+		for _, arg := range tt.TypeArgs {
+			ix.typeRefs(arg)
+		}
+	case ast.FunctionType:
+		for _, param := range tt.ParamTypes {
+			ix.typeRefs(param)
+		}
+		ix.typeRefs(tt.ReturnType)
+	}
+}
+
+func (ix *documentIndex) statements(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		ix.statement(stmt)
+	}
+}
+
+func (ix *documentIndex) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case ast.AssignStatement:
+		ix.expr(s.Value)
+		root, _, dotted := strings.Cut(s.Target, ".")
+		if _, ok := ix.lookup(root); ok || dotted {
+			// $ x = ... updates x when an enclosing scope declares it
+			ix.variableRef(root, s.Pos)
+			return
+		}
+		ix.declareVariable(s.Target, s.Pos)
+	case ast.ReassignStatement:
+		ix.expr(s.Value)
+		ix.variableRef(s.Target, s.Pos)
+	case ast.IndexAssignStatement:
+		ix.expr(s.Target)
+		ix.expr(s.Value)
+	case ast.DbQueryStatement:
+		for _, param := range s.Params {
+			ix.expr(param)
+		}
+		ix.declareVariable(s.Var, ast.Pos{})
+	case ast.ReturnStatement:
+		ix.expr(s.Value)
+	case ast.IfStatement:
+		ix.expr(s.Condition)
+		ix.block(s.ThenBlock)
+		ix.block(s.ElseBlock)
+	case ast.WhileStatement:
+		ix.expr(s.Condition)
+		ix.block(s.Body)
+	case ast.BackgroundStatement:
+		ix.block(s.Body)
+	case ast.ForStatement:
+		ix.expr(s.Iterable)
+		ix.push()
+		if s.KeyVar != "" {
+			ix.declareVariable(s.KeyVar, ast.Pos{})
+		}
+		ix.declareVariable(s.ValueVar, ast.Pos{})
+		ix.statements(s.Body)
+		ix.pop()
+	case ast.SwitchStatement:
+		ix.expr(s.Value)
+		for _, switchCase := range s.Cases {
+			ix.expr(switchCase.Value)
+			ix.block(switchCase.Body)
+		}
+		ix.block(s.Default)
+	case ast.WsSendStatement:
+		ix.expr(s.Client)
+		ix.expr(s.Message)
+	case ast.WsBroadcastStatement:
+		ix.expr(s.Message)
+		if s.Except != nil {
+			ix.expr(*s.Except)
+		}
+	case ast.WsCloseStatement:
+		ix.expr(s.Client)
+		ix.expr(s.Reason)
+	case ast.ValidationStatement:
+		ix.expr(s.Call)
+	case ast.ExpressionStatement:
+		ix.expr(s.Expr)
+	case ast.YieldStatement:
+		ix.expr(s.Value)
+	case ast.AssertStatement:
+		ix.expr(s.Condition)
+		ix.expr(s.Message)
+	}
+}
+
+func (ix *documentIndex) expr(expr ast.Expr) {
+	switch e := expr.(type) {
+	case ast.VariableExpr:
+		ix.variableRef(e.Name, e.Pos)
+	case ast.BinaryOpExpr:
+		ix.expr(e.Left)
+		ix.expr(e.Right)
+	case ast.UnaryOpExpr:
+		ix.expr(e.Right)
+	case ast.FieldAccessExpr:
+		ix.expr(e.Object)
+	case ast.ArrayIndexExpr:
+		ix.expr(e.Array)
+		ix.expr(e.Index)
+	case ast.FunctionCallExpr:
+		ix.call(e)
+	case ast.ObjectExpr:
+		for _, field := range e.Fields {
+			ix.expr(field.Value)
+		}
+	case ast.ArrayExpr:
+		for _, elem := range e.Elements {
+			ix.expr(elem)
+		}
+	case ast.LambdaExpr:
+		ix.push()
+		for _, param := range e.Params {
+			ix.declareVariable(param.Name, param.Pos)
+		}
+		ix.expr(e.Body)
+		ix.statements(e.Block)
+		ix.pop()
+	case ast.PipeExpr:
+		ix.expr(e.Left)
+		ix.expr(e.Right)
+	case ast.TernaryExpr:
+		ix.expr(e.Condition)
+		ix.expr(e.Then)
+		ix.expr(e.Else)
+	case ast.InterpolatedStringExpr:
+		for _, part := range e.Parts {
+			ix.expr(part)
+		}
+	case ast.SpreadExpr:
+		ix.expr(e.Value)
+	case ast.AsyncExpr:
+		ix.block(e.Body)
+	case ast.AwaitExpr:
+		ix.expr(e.Expr)
+	case ast.MatchExpr:
+		ix.expr(e.Value)
+		for _, matchCase := range e.Cases {
+			ix.push()
+			ix.bindPattern(matchCase.Pattern)
+			ix.expr(matchCase.Guard)
+			ix.expr(matchCase.Body)
+			ix.pop()
+		}
+	}
+}
+
+// call records a call to a function declared in the document. obj.method()
+// calls are named "obj.method" and positioned at the dot; their receiver is
+// recorded as a variable use.
+func (ix *documentIndex) call(call ast.FunctionCallExpr) {
+	for _, arg := range call.Args {
+		ix.expr(arg)
+	}
+	for _, arg := range call.NamedArgs {
+		ix.expr(arg.Value)
+	}
+	if root, _, dotted := strings.Cut(call.Name, "."); dotted {
+		if decl, ok := ix.lookup(root); ok {
+			rng, found := ix.receiverRange(root, call.Pos)
+			if found {
+				ix.occurrences = append(ix.occurrences, occurrence{kind: symbolVariable, name: root, rng: rng, decl: decl})
+			}
+		}
+		return
+	}
+	if _, shadowed := ix.lookup(call.Name); shadowed {
+		return
+	}
+	if decl, ok := ix.functions[call.Name]; ok {
+		ix.reference(symbolFunction, call.Name, call.Pos, decl)
+	}
+}
+
+// receiverRange locates the receiver of a method call that ends just before
+// the dot at pos
+func (ix *documentIndex) receiverRange(name string, dot ast.Pos) (Range, bool) {
+	start := dot.Column - 1 - len(name)
+	if start < 0 {
+		return Range{}, false
+	}
+	return ix.nameRange(name, ast.Pos{Line: dot.Line, Column: start + 1})
+}
+
+// bindPattern binds the variables a match pattern introduces
+func (ix *documentIndex) bindPattern(pattern ast.Pattern) {
+	switch p := pattern.(type) {
+	case ast.VariablePattern:
+		ix.declareVariable(p.Name, ast.Pos{})
+	case ast.ObjectPattern:
+		for _, field := range p.Fields {
+			if field.Pattern == nil {
+				ix.declareVariable(field.Key, ast.Pos{})
+			} else {
+				ix.bindPattern(field.Pattern)
+			}
+		}
+	case ast.TypePattern:
+		ix.bindPattern(ast.ObjectPattern{Fields: p.Fields})
+	case ast.ArrayPattern:
+		for _, elem := range p.Elements {
+			ix.bindPattern(elem)
+		}
+		if p.Rest != nil {
+			ix.declareVariable(*p.Rest, ast.Pos{})
+		}
+	}
+}
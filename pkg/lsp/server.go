@@ -141,9 +141,9 @@ func (s *Server) writeMessage(msg interface{}) error {
 
 // handleMessage dispatches a message to the appropriate handler
 func (s *Server) handleMessage(msg json.RawMessage) error {
-	// Try to parse as request
+	// Requests carry an id; notifications do not
 	var req Request
-	if err := json.Unmarshal(msg, &req); err == nil && req.Method != "" {
+	if err := json.Unmarshal(msg, &req); err == nil && req.Method != "" && req.ID != nil {
 		return s.handleRequest(&req)
 	}
 
@@ -1,9 +1,11 @@
 package lsp
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
 )
@@ -573,3 +575,136 @@ func TestPublishDiagnosticsParams(t *testing.T) {
 		t.Errorf("Expected 1 diagnostic, got %d", len(params.Diagnostics))
 	}
 }
+
+// readMessages splits the server's output into its framed JSON-RPC messages
+func readMessages(t *testing.T, output *bytes.Buffer) []json.RawMessage {
+	t.Helper()
+	var messages []json.RawMessage
+	reader := bufio.NewReader(output)
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return messages
+		}
+		if err != nil {
+			t.Fatalf("Failed to read header: %v", err)
+		}
+		var length int
+		if _, err := fmt.Sscanf(line, "Content-Length: %d", &length); err != nil {
+			t.Fatalf("Unexpected header %q", line)
+		}
+		if _, err := reader.ReadString('\n'); err != nil {
+			t.Fatalf("Failed to read header separator: %v", err)
+		}
+		content := make([]byte, length)
+		if _, err := io.ReadFull(reader, content); err != nil {
+			t.Fatalf("Failed to read content: %v", err)
+		}
+		messages = append(messages, content)
+	}
+}
+
+func TestServerDefinitionAndReferences(t *testing.T) {
+	source := `: User {
+  name: str!
+}
+
+! greet(u: User): str {
+  > "Hello, " + u.name
+}
+
+@ GET /users/:id -> User {
+  $ user = {name: id}
+  $ message = greet(user)
+  > user
+}
+`
+	uri := "file:///fixture.glyph"
+	tc := newTestClient()
+	tc.sendRequest(1, "initialize", InitializeParams{RootURI: "file:///"})
+	tc.sendNotification("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "glyph", Version: 1, Text: source},
+	})
+
+	at := func(line, character int) TextDocumentPositionParams {
+		return TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: line, Character: character},
+		}
+	}
+	references := func(line, character int, includeDeclaration bool) ReferenceParams {
+		return ReferenceParams{
+			TextDocumentPositionParams: at(line, character),
+			Context:                    ReferenceContext{IncludeDeclaration: includeDeclaration},
+		}
+	}
+	span := func(line, start, end int) Range {
+		return Range{Start: Position{Line: line, Character: start}, End: Position{Line: line, Character: end}}
+	}
+
+	requests := []struct {
+		name   string
+		method string
+		params interface{}
+		want   []Range
+	}{
+		{"route return type", "textDocument/definition", at(8, 21), []Range{span(0, 2, 6)}},
+		{"function call", "textDocument/definition", at(10, 15), []Range{span(4, 2, 7)}},
+		{"variable", "textDocument/definition", at(11, 5), []Range{span(9, 4, 8)}},
+		{"function parameter", "textDocument/definition", at(5, 16), []Range{span(4, 8, 9)}},
+		{"path parameter", "textDocument/definition", at(9, 19), []Range{span(8, 14, 16)}},
+		{"builtin type", "textDocument/definition", at(1, 9), nil},
+		{"type references", "textDocument/references", references(0, 3, true), []Range{span(0, 2, 6), span(4, 11, 15), span(8, 20, 24)}},
+		{"variable references", "textDocument/references", references(9, 5, false), []Range{span(10, 20, 24), span(11, 4, 8)}},
+		{"function references", "textDocument/references", references(4, 3, true), []Range{span(4, 2, 7), span(10, 14, 19)}},
+	}
+	for i, req := range requests {
+		tc.sendRequest(i+2, req.method, req.params)
+	}
+
+	server := NewServer(tc.input, tc.output, "")
+	if err := server.Start(); err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+
+	results := make(map[int][]Location)
+	for _, msg := range readMessages(t, tc.output) {
+		var resp struct {
+			ID     *int            `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  *RPCError       `json:"error"`
+		}
+		if err := json.Unmarshal(msg, &resp); err != nil {
+			t.Fatalf("Invalid message %s: %v", msg, err)
+		}
+		if resp.ID == nil || *resp.ID < 2 {
+			continue
+		}
+		if resp.Error != nil {
+			t.Fatalf("Request %d failed: %s", *resp.ID, resp.Error.Message)
+		}
+		var locations []Location
+		if err := json.Unmarshal(resp.Result, &locations); err != nil {
+			t.Fatalf("Invalid result %s: %v", resp.Result, err)
+		}
+		results[*resp.ID] = locations
+	}
+
+	for i, req := range requests {
+		locations, ok := results[i+2]
+		if !ok {
+			t.Errorf("%s: no response", req.name)
+			continue
+		}
+		var got []Range
+		for _, loc := range locations {
+			if loc.URI != uri {
+				t.Errorf("%s: expected URI %s, got %s", req.name, uri, loc.URI)
+			}
+			got = append(got, loc.Range)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(req.want) {
+			t.Errorf("%s: expected %v, got %v", req.name, req.want, got)
+		}
+	}
+}
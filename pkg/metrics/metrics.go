@@ -16,6 +16,13 @@ type Metrics struct {
 	requestsTotal   *prometheus.CounterVec
 	requestDuration *prometheus.HistogramVec
 	requestErrors   *prometheus.CounterVec
+	inFlight        prometheus.Gauge
+
+	// Compiled route metrics
+	vmDuration *prometheus.HistogramVec
+
+	// Route handlers that panicked
+	routePanics *prometheus.CounterVec
 
 	// Resource usage metrics
 	goroutines   prometheus.Gauge
@@ -30,7 +37,8 @@ type Metrics struct {
 	customGauges     map[string]*prometheus.GaugeVec
 	customHistograms map[string]*prometheus.HistogramVec
 
-	registry *prometheus.Registry
+	namespace string
+	registry  *prometheus.Registry
 }
 
 // Config holds configuration for metrics
@@ -63,6 +71,7 @@ func NewMetrics(config Config) *Metrics {
 	registry := prometheus.NewRegistry()
 
 	m := &Metrics{
+		namespace:        config.Namespace,
 		registry:         registry,
 		customCounters:   make(map[string]*prometheus.CounterVec),
 		customGauges:     make(map[string]*prometheus.GaugeVec),
@@ -103,6 +112,38 @@ func NewMetrics(config Config) *Metrics {
 		[]string{"method", "path", "status"},
 	)
 
+	m.inFlight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "requests_in_flight",
+			Help:      "Number of HTTP requests currently being served",
+		},
+	)
+
+	// Bytecode execution time of compiled routes
+	m.vmDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Subsystem: "vm",
+			Name:      "execution_duration_seconds",
+			Help:      "Time spent executing compiled route bytecode in seconds",
+			Buckets:   config.DurationBuckets,
+		},
+		[]string{"method", "path"},
+	)
+
+	// Panics recovered from route handlers
+	m.routePanics = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "route_panics_total",
+			Help:      "Total number of panics recovered while handling requests",
+		},
+		[]string{"method", "path"},
+	)
+
 	// Resource usage metrics
 	m.goroutines = prometheus.NewGauge(
 		prometheus.GaugeOpts{
@@ -163,6 +204,9 @@ func NewMetrics(config Config) *Metrics {
 		m.requestsTotal,
 		m.requestDuration,
 		m.requestErrors,
+		m.inFlight,
+		m.vmDuration,
+		m.routePanics,
 		m.goroutines,
 		m.memoryAlloc,
 		m.memoryTotal,
@@ -219,6 +263,32 @@ func (m *Metrics) RecordRequest(method, path string, statusCode int, duration ti
 	}
 }
 
+// RecordVMExecution records how long a compiled route's bytecode ran. path
+// is the route pattern, not the request path.
+func (m *Metrics) RecordVMExecution(method, path string, duration time.Duration) {
+	m.vmDuration.WithLabelValues(method, path).Observe(duration.Seconds())
+}
+
+// RecordPanic records a panic recovered while handling a request. path is
+// the route pattern, not the request path.
+func (m *Metrics) RecordPanic(method, path string) {
+	m.routePanics.WithLabelValues(method, path).Inc()
+}
+
+// TrackWebSocketConnections exports the value of count, read at scrape time,
+// as the open WebSocket connection gauge
+func (m *Metrics) TrackWebSocketConnections(count func() int) error {
+	return m.registry.Register(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: m.namespace,
+			Subsystem: "websocket",
+			Name:      "connections",
+			Help:      "Number of open WebSocket connections",
+		},
+		func() float64 { return float64(count()) },
+	))
+}
+
 // RegisterCustomCounter registers a custom counter metric
 func (m *Metrics) RegisterCustomCounter(name, help string, labels []string) error {
 	if _, exists := m.customCounters[name]; exists {
@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"runtime"
@@ -490,3 +491,78 @@ func BenchmarkMetricsMiddleware(b *testing.B) {
 		_ = wrappedHandler(ctx)
 	}
 }
+
+func TestRegisterMetricsRoute_Scrape(t *testing.T) {
+	m := NewMetrics(DefaultConfig())
+	connections := 2
+	require.NoError(t, m.TrackWebSocketConnections(func() int { return connections }))
+
+	router := server.NewRouter()
+	router.Use(MetricsMiddleware(m))
+	require.NoError(t, router.RegisterRoute(&server.Route{
+		Method: server.GET,
+		Path:   "/api/users/:id",
+		Handler: func(ctx *server.Context) error {
+			if ctx.PathParams["id"] == "0" {
+				ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
+				return nil
+			}
+			return server.SendJSON(ctx, http.StatusOK, map[string]string{"id": ctx.PathParams["id"]})
+		},
+	}))
+	require.NoError(t, RegisterMetricsRoute(router, m))
+
+	srv := httptest.NewServer(server.NewHandler(router, nil))
+	defer srv.Close()
+
+	for _, path := range []string{"/api/users/1", "/api/users/2", "/api/users/0"} {
+		resp, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	resp, err := http.Get(srv.URL + MetricsPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/plain")
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	scrape := string(body)
+
+	assert.Contains(t, scrape, `glyphlang_http_requests_total{method="GET",path="/api/users/:id",status="200"} 2`)
+	assert.Contains(t, scrape, `glyphlang_http_requests_total{method="GET",path="/api/users/:id",status="404"} 1`)
+	assert.Contains(t, scrape, `glyphlang_http_request_errors_total{method="GET",path="/api/users/:id",status="404"} 1`)
+	assert.Contains(t, scrape, `glyphlang_http_request_duration_seconds_bucket{method="GET",path="/api/users/:id",status="200",le="+Inf"} 2`)
+	assert.NotContains(t, scrape, "/api/users/1")
+	// The scrape itself is in flight while the metrics are gathered
+	assert.Contains(t, scrape, "glyphlang_http_requests_in_flight 1")
+	assert.Contains(t, scrape, "glyphlang_websocket_connections 2")
+}
+
+func TestRecordVMExecution(t *testing.T) {
+	m := NewMetrics(DefaultConfig())
+	m.RecordVMExecution("GET", "/api/users/:id", 2*time.Millisecond)
+
+	families, err := m.GetRegistry().Gather()
+	require.NoError(t, err)
+	var found bool
+	for _, family := range families {
+		if family.GetName() == "glyphlang_vm_execution_duration_seconds" {
+			found = true
+			require.Len(t, family.GetMetric(), 1)
+			assert.Equal(t, uint64(1), family.GetMetric()[0].GetHistogram().GetSampleCount())
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestRecordPanic(t *testing.T) {
+	m := NewMetrics(DefaultConfig())
+
+	m.RecordPanic("GET", "/users/:id")
+	m.RecordPanic("GET", "/users/:id")
+
+	count := testutil.ToFloat64(m.routePanics.WithLabelValues("GET", "/users/:id"))
+	assert.Equal(t, 2.0, count)
+}
@@ -1,16 +1,24 @@
 package metrics
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/glyphlang/glyph/pkg/server"
 )
 
-// MetricsMiddleware creates a middleware that automatically collects HTTP metrics
+// MetricsPath is where RegisterMetricsRoute serves the metrics
+const MetricsPath = "/metrics"
+
+// MetricsMiddleware creates a middleware that automatically collects HTTP metrics.
+// Requests are labeled with the matched route pattern (/api/users/:id) when
+// the context has one, so path parameters cannot create unbounded label values.
 func MetricsMiddleware(m *Metrics) server.Middleware {
 	return func(next server.RouteHandler) server.RouteHandler {
 		return func(ctx *server.Context) error {
 			start := time.Now()
+			m.inFlight.Inc()
+			defer m.inFlight.Dec()
 
 			// Call next handler
 			err := next(ctx)
@@ -18,22 +26,39 @@ func MetricsMiddleware(m *Metrics) server.Middleware {
 			// Calculate duration
 			duration := time.Since(start)
 
-			// Determine status code
+			// Determine status code, preferring the one actually written
 			status := ctx.StatusCode
-			if status == 0 {
-				if err != nil {
-					status = 500
-				} else {
-					status = 200
-				}
+			if err != nil {
+				status = http.StatusInternalServerError
+			} else if sw, ok := ctx.ResponseWriter.(*server.StatusWriter); ok {
+				status = sw.Status()
+			} else if status == 0 {
+				status = http.StatusOK
 			}
 
 			// Record metrics
 			method := ctx.Request.Method
-			path := ctx.Request.URL.Path
+			path := ctx.RoutePattern
+			if path == "" {
+				path = ctx.Request.URL.Path
+			}
 			m.RecordRequest(method, path, status, duration)
 
 			return err
 		}
 	}
 }
+
+// RegisterMetricsRoute serves m in the Prometheus text format at GET /metrics
+func RegisterMetricsRoute(router *server.Router, m *Metrics) error {
+	handler := m.Handler()
+	return router.RegisterRoute(&server.Route{
+		Method: server.GET,
+		Path:   MetricsPath,
+		Handler: func(ctx *server.Context) error {
+			m.UpdateRuntimeMetrics()
+			handler.ServeHTTP(ctx.ResponseWriter, ctx.Request)
+			return nil
+		},
+	})
+}
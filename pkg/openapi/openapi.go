@@ -10,7 +10,7 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// Spec represents an OpenAPI 3.0 specification.
+// Spec represents an OpenAPI 3.1 specification.
 type Spec struct {
 	OpenAPI    string                `json:"openapi" yaml:"openapi"`
 	Info       Info                  `json:"info" yaml:"info"`
@@ -73,16 +73,60 @@ type Response struct {
 	Content     map[string]MediaType `json:"content,omitempty" yaml:"content,omitempty"`
 }
 
-// Schema represents a JSON Schema object.
+// Schema represents a JSON Schema object. The zero Schema accepts any value.
 type Schema struct {
-	Type       string             `json:"type,omitempty" yaml:"type,omitempty"`
+	Type       string
+	Format     string
+	Properties map[string]*Schema
+	Required   []string
+	Items      *Schema
+	Ref        string
+	Nullable   bool // Serialized as a "null" entry in the type array
+	OneOf      []*Schema
+	Enum       []string
+}
+
+// schemaDocument is the serialized form of a Schema. OpenAPI 3.1 uses JSON
+// Schema 2020-12, which has no nullable keyword, so a nullable type is
+// written as type: [string, "null"].
+type schemaDocument struct {
+	Type       interface{}        `json:"type,omitempty" yaml:"type,omitempty"`
 	Format     string             `json:"format,omitempty" yaml:"format,omitempty"`
 	Properties map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
 	Required   []string           `json:"required,omitempty" yaml:"required,omitempty"`
 	Items      *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
 	Ref        string             `json:"$ref,omitempty" yaml:"$ref,omitempty"`
-	Nullable   bool               `json:"nullable,omitempty" yaml:"nullable,omitempty"`
 	OneOf      []*Schema          `json:"oneOf,omitempty" yaml:"oneOf,omitempty"`
+	Enum       []string           `json:"enum,omitempty" yaml:"enum,omitempty"`
+}
+
+func (s Schema) document() schemaDocument {
+	doc := schemaDocument{
+		Format:     s.Format,
+		Properties: s.Properties,
+		Required:   s.Required,
+		Items:      s.Items,
+		Ref:        s.Ref,
+		OneOf:      s.OneOf,
+		Enum:       s.Enum,
+	}
+	switch {
+	case s.Type != "" && s.Nullable:
+		doc.Type = []string{s.Type, "null"}
+	case s.Type != "":
+		doc.Type = s.Type
+	}
+	return doc
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Schema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.document())
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (s Schema) MarshalYAML() (interface{}, error) {
+	return s.document(), nil
 }
 
 // Components holds reusable schema definitions.
@@ -117,10 +161,10 @@ func NewGenerator(title, version string) *Generator {
 	}
 }
 
-// Generate produces an OpenAPI 3.0 spec from a parsed GlyphLang module.
+// Generate produces an OpenAPI 3.1 spec from a parsed GlyphLang module.
 func (g *Generator) Generate(module *ast.Module) *Spec {
 	spec := &Spec{
-		OpenAPI: "3.0.3",
+		OpenAPI: "3.1.0",
 		Info: Info{
 			Title:   g.title,
 			Version: g.version,
@@ -140,6 +184,9 @@ func (g *Generator) Generate(module *ast.Module) *Spec {
 		if td != nil {
 			spec.Components.Schemas[td.Name] = g.typeDefToSchema(td)
 		}
+		if ed, ok := item.(*ast.EnumDef); ok {
+			spec.Components.Schemas[ed.Name] = &Schema{Type: "string", Enum: ed.Values}
+		}
 	}
 
 	// Second pass: process routes
@@ -264,27 +311,21 @@ func (g *Generator) routeToOperation(route *ast.Route, schemas map[string]*Schem
 	if route.ReturnType != nil {
 		responseSchema := g.typeToSchema(route.ReturnType)
 
-		// Check for union type (e.g., User | NotFound)
+		// A union (e.g., User | Admin | NotFound) is a set of response
+		// variants: members named like errors answer with the status their
+		// name implies, and the others, with the first member, are
+		// alternatives of the successful response
 		if union, ok := route.ReturnType.(ast.UnionType); ok {
-			op.Responses["200"] = &Response{
-				Description: "Successful response",
-				Content: map[string]MediaType{
-					"application/json": {
-						Schema: g.typeToSchema(union.Types[0]),
-					},
-				},
-			}
-			// Add error responses for other union types
-			for i := 1; i < len(union.Types); i++ {
-				statusCode := inferStatusCode(union.Types[i])
-				op.Responses[statusCode] = &Response{
-					Description: inferDescription(union.Types[i]),
-					Content: map[string]MediaType{
-						"application/json": {
-							Schema: g.typeToSchema(union.Types[i]),
-						},
-					},
+			variants := make(map[string][]ast.Type)
+			for i, member := range union.Types {
+				status := inferStatusCode(member)
+				if i == 0 || status == "default" {
+					status = "200"
 				}
+				variants[status] = append(variants[status], member)
+			}
+			for status, members := range variants {
+				op.Responses[status] = g.variantResponse(status, members)
 			}
 		} else {
 			op.Responses["200"] = &Response{
@@ -297,11 +338,12 @@ func (g *Generator) routeToOperation(route *ast.Route, schemas map[string]*Schem
 			}
 		}
 	} else {
+		// Without a declared return type the response can be any JSON value
 		op.Responses["200"] = &Response{
 			Description: "Successful response",
 			Content: map[string]MediaType{
 				"application/json": {
-					Schema: &Schema{Type: "object"},
+					Schema: &Schema{},
 				},
 			},
 		}
@@ -324,6 +366,28 @@ func (g *Generator) routeToOperation(route *ast.Route, schemas map[string]*Schem
 	return op
 }
 
+// variantResponse returns the response with status for the union members
+// that answer with it, a oneOf of their schemas when there are several
+func (g *Generator) variantResponse(status string, members []ast.Type) *Response {
+	description := "Successful response"
+	if status != "200" {
+		description = inferDescription(members[0])
+	}
+	schema := g.typeToSchema(members[0])
+	if len(members) > 1 {
+		schema = &Schema{}
+		for _, member := range members {
+			schema.OneOf = append(schema.OneOf, g.typeToSchema(member))
+		}
+	}
+	return &Response{
+		Description: description,
+		Content: map[string]MediaType{
+			"application/json": {Schema: schema},
+		},
+	}
+}
+
 func (g *Generator) typeDefToSchema(td *ast.TypeDef) *Schema {
 	schema := &Schema{
 		Type:       "object",
@@ -370,6 +434,10 @@ func (g *Generator) typeToSchema(t ast.Type) *Schema {
 		}
 	case ast.OptionalType:
 		inner := g.typeToSchema(typ.InnerType)
+		if inner.Type == "" {
+			// References and unions cannot carry a type array
+			return &Schema{OneOf: []*Schema{inner, {Type: "null"}}}
+		}
 		inner.Nullable = true
 		return inner
 	case ast.NamedType:
@@ -11,8 +11,8 @@ func TestGenerator_EmptyModule(t *testing.T) {
 	module := &ast.Module{Items: []ast.Item{}}
 	spec := gen.Generate(module)
 
-	if spec.OpenAPI != "3.0.3" {
-		t.Errorf("expected OpenAPI 3.0.3, got %s", spec.OpenAPI)
+	if spec.OpenAPI != "3.1.0" {
+		t.Errorf("expected OpenAPI 3.1.0, got %s", spec.OpenAPI)
 	}
 	if spec.Info.Title != "Test API" {
 		t.Errorf("expected title Test API, got %s", spec.Info.Title)
@@ -25,6 +25,25 @@ func TestGenerator_EmptyModule(t *testing.T) {
 	}
 }
 
+func TestGenerator_EnumSchema(t *testing.T) {
+	gen := NewGenerator("Test API", "1.0.0")
+	module := &ast.Module{Items: []ast.Item{
+		&ast.EnumDef{Name: "Status", Values: []string{"pending", "shipped"}},
+	}}
+	spec := gen.Generate(module)
+
+	schema := spec.Components.Schemas["Status"]
+	if schema == nil {
+		t.Fatal("expected a Status schema")
+	}
+	if schema.Type != "string" {
+		t.Errorf("expected type string, got %s", schema.Type)
+	}
+	if len(schema.Enum) != 2 || schema.Enum[0] != "pending" || schema.Enum[1] != "shipped" {
+		t.Errorf("expected enum [pending shipped], got %v", schema.Enum)
+	}
+}
+
 func TestGenerator_SimpleGetRoute(t *testing.T) {
 	gen := NewGenerator("Test API", "1.0.0")
 	module := &ast.Module{
@@ -541,7 +560,7 @@ func TestExtractPathParams(t *testing.T) {
 
 func TestSpec_ToJSON(t *testing.T) {
 	spec := &Spec{
-		OpenAPI: "3.0.3",
+		OpenAPI: "3.1.0",
 		Info:    Info{Title: "Test", Version: "1.0.0"},
 		Paths:   map[string]*PathItem{},
 	}
@@ -556,14 +575,14 @@ func TestSpec_ToJSON(t *testing.T) {
 		t.Fatalf("invalid JSON: %v", err)
 	}
 
-	if parsed["openapi"] != "3.0.3" {
-		t.Errorf("expected openapi 3.0.3 in JSON output")
+	if parsed["openapi"] != "3.1.0" {
+		t.Errorf("expected openapi 3.1.0 in JSON output")
 	}
 }
 
 func TestSpec_ToYAML(t *testing.T) {
 	spec := &Spec{
-		OpenAPI: "3.0.3",
+		OpenAPI: "3.1.0",
 		Info:    Info{Title: "Test", Version: "1.0.0"},
 		Paths:   map[string]*PathItem{},
 	}
@@ -574,8 +593,8 @@ func TestSpec_ToYAML(t *testing.T) {
 	}
 
 	yamlStr := string(data)
-	if !containsSubstring(yamlStr, "openapi: 3.0.3") {
-		t.Error("expected openapi: 3.0.3 in YAML output")
+	if !containsSubstring(yamlStr, "openapi: 3.1.0") {
+		t.Error("expected openapi: 3.1.0 in YAML output")
 	}
 	if !containsSubstring(yamlStr, "title: Test") {
 		t.Error("expected title: Test in YAML output")
@@ -584,7 +603,7 @@ func TestSpec_ToYAML(t *testing.T) {
 
 func TestFormatSpec(t *testing.T) {
 	spec := &Spec{
-		OpenAPI: "3.0.3",
+		OpenAPI: "3.1.0",
 		Info:    Info{Title: "Test", Version: "1.0.0"},
 		Paths:   map[string]*PathItem{},
 	}
@@ -669,6 +688,94 @@ func TestGenerator_NoAuthNoSecuritySchemes(t *testing.T) {
 	}
 }
 
+func TestGenerator_RouteWithoutReturnType(t *testing.T) {
+	gen := NewGenerator("Test API", "1.0.0")
+	module := &ast.Module{
+		Items: []ast.Item{
+			ast.Route{Path: "/api/ping", Method: ast.Get},
+		},
+	}
+
+	spec := gen.Generate(module)
+
+	resp, ok := spec.Paths["/api/ping"].Get.Responses["200"]
+	if !ok {
+		t.Fatal("expected a default 200 response")
+	}
+	data, err := json.Marshal(resp.Content["application/json"].Schema)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if string(data) != "{}" {
+		t.Errorf("expected empty schema, got %s", data)
+	}
+}
+
+func TestGenerator_NestedNamedTypes(t *testing.T) {
+	gen := NewGenerator("Test API", "1.0.0")
+	module := &ast.Module{
+		Items: []ast.Item{
+			ast.TypeDef{
+				Name: "Order",
+				Fields: []ast.Field{
+					{Name: "customer", TypeAnnotation: ast.NamedType{Name: "User"}, Required: true},
+					{Name: "items", TypeAnnotation: ast.ArrayType{ElementType: ast.NamedType{Name: "Item"}}, Required: true},
+					{Name: "coupon", TypeAnnotation: ast.OptionalType{InnerType: ast.NamedType{Name: "Coupon"}}},
+				},
+			},
+		},
+	}
+
+	spec := gen.Generate(module)
+	order := spec.Components.Schemas["Order"]
+
+	if ref := order.Properties["customer"].Ref; ref != "#/components/schemas/User" {
+		t.Errorf("expected User ref, got %q", ref)
+	}
+	items := order.Properties["items"]
+	if items.Type != "array" || items.Items == nil || items.Items.Ref != "#/components/schemas/Item" {
+		t.Errorf("expected array of Item refs, got %+v", items)
+	}
+	coupon := order.Properties["coupon"]
+	if len(coupon.OneOf) != 2 || coupon.OneOf[0].Ref != "#/components/schemas/Coupon" || coupon.OneOf[1].Type != "null" {
+		t.Errorf("expected oneOf Coupon ref or null, got %+v", coupon)
+	}
+	if len(order.Required) != 2 || order.Required[0] != "customer" || order.Required[1] != "items" {
+		t.Errorf("expected required [customer items], got %v", order.Required)
+	}
+}
+
+func TestSchema_NullableSerialization(t *testing.T) {
+	schema := &Schema{Type: "string", Nullable: true}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if string(data) != `{"type":["string","null"]}` {
+		t.Errorf("expected type array, got %s", data)
+	}
+
+	spec := &Spec{
+		OpenAPI: "3.1.0",
+		Info:    Info{Title: "Test", Version: "1.0.0"},
+		Paths:   map[string]*PathItem{},
+		Components: &Components{
+			Schemas: map[string]*Schema{"Name": schema},
+		},
+	}
+	yamlData, err := spec.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML failed: %v", err)
+	}
+	if !containsSubstring(string(yamlData), "- \"null\"") {
+		t.Errorf("expected null in YAML type array, got:\n%s", yamlData)
+	}
+	if containsSubstring(string(yamlData), "nullable") {
+		t.Error("OpenAPI 3.1 output should not use nullable")
+	}
+}
+
 func containsSubstring(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsHelper(s, substr))
 }
@@ -681,3 +788,58 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func TestGenerator_UnionResponseVariants(t *testing.T) {
+	gen := NewGenerator("Test API", "1.0.0")
+	module := &ast.Module{
+		Items: []ast.Item{
+			&ast.TypeDef{Name: "User", Fields: []ast.Field{{Name: "id", TypeAnnotation: ast.IntType{}, Required: true}}},
+			&ast.TypeDef{Name: "Admin", Fields: []ast.Field{{Name: "level", TypeAnnotation: ast.IntType{}, Required: true}}},
+			&ast.Route{
+				Path:   "/users/:id",
+				Method: ast.Get,
+				ReturnType: ast.UnionType{
+					Types: []ast.Type{
+						ast.NamedType{Name: "User"},
+						ast.NamedType{Name: "Admin"},
+						ast.NamedType{Name: "NotFound"},
+						ast.NamedType{Name: "NotFoundError"},
+					},
+				},
+			},
+		},
+	}
+
+	spec := gen.Generate(module)
+	op := spec.Paths["/users/{id}"].Get
+	if op == nil {
+		t.Fatal("expected GET /users/{id}")
+	}
+	if len(op.Parameters) != 1 || op.Parameters[0].Name != "id" || op.Parameters[0].In != "path" || !op.Parameters[0].Required {
+		t.Errorf("expected a required id path parameter, got %+v", op.Parameters)
+	}
+	if _, ok := spec.Components.Schemas["User"]; !ok {
+		t.Error("expected a User component schema")
+	}
+	if _, ok := spec.Components.Schemas["Admin"]; !ok {
+		t.Error("expected an Admin component schema")
+	}
+
+	// Members that are not errors are alternatives of the 200 response
+	if len(op.Responses) != 2 {
+		t.Errorf("expected 200 and 404 responses, got %v", op.Responses)
+	}
+	ok := op.Responses["200"].Content["application/json"].Schema
+	if len(ok.OneOf) != 2 || ok.OneOf[0].Ref != "#/components/schemas/User" || ok.OneOf[1].Ref != "#/components/schemas/Admin" {
+		t.Errorf("expected 200 oneOf User or Admin, got %+v", ok)
+	}
+
+	// Members with the same status share its response
+	notFound := op.Responses["404"]
+	if notFound == nil || notFound.Description != "Not Found" {
+		t.Fatalf("expected a Not Found 404 response, got %+v", notFound)
+	}
+	if schema := notFound.Content["application/json"].Schema; len(schema.OneOf) != 2 {
+		t.Errorf("expected 404 oneOf NotFound or NotFoundError, got %+v", schema)
+	}
+}
@@ -0,0 +1,37 @@
+package parser
+
+import (
+	"github.com/glyphlang/glyph/pkg/ast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_BackgroundStatement(t *testing.T) {
+	for _, keyword := range []string{"background", "defer!"} {
+		t.Run(keyword, func(t *testing.T) {
+			tokens, err := NewLexer("@ POST /signup {\n  " + keyword + " {\n    $ r = send(input.email)\n  }\n  > {ok: true}\n}").Tokenize()
+			require.NoError(t, err)
+			module, err := NewParser(tokens).Parse()
+			require.NoError(t, err)
+
+			route := module.Items[0].(*ast.Route)
+			require.Len(t, route.Body, 2)
+			background, ok := route.Body[0].(ast.BackgroundStatement)
+			require.True(t, ok, "got %T", route.Body[0])
+			assert.Equal(t, ast.Pos{Line: 2, Column: 3}, background.Pos)
+			require.Len(t, background.Body, 1)
+			assert.IsType(t, ast.AssignStatement{}, background.Body[0])
+		})
+	}
+}
+
+func TestParser_BackgroundIsNotReserved(t *testing.T) {
+	tokens, err := NewLexer("@ GET /theme {\n  $ background = \"dark\"\n  > background\n}").Tokenize()
+	require.NoError(t, err)
+	module, err := NewParser(tokens).Parse()
+	require.NoError(t, err)
+	route := module.Items[0].(*ast.Route)
+	assert.Equal(t, ast.ReturnStatement{Value: ast.VariableExpr{Name: "background", Pos: ast.Pos{Line: 3, Column: 5}}}, route.Body[1])
+}
@@ -219,3 +219,25 @@ const DEFAULT_TIMEOUT = 30`
 	require.True(t, ok)
 	assert.Equal(t, "DEFAULT_TIMEOUT", constDecl2.Name)
 }
+
+func TestParser_ConstStatement(t *testing.T) {
+	input := `@ GET /area {
+  const PI: float = 3.14
+  > PI
+}`
+
+	lexer := NewLexer(input)
+	tokens, err := lexer.Tokenize()
+	require.NoError(t, err)
+
+	module, err := NewParser(tokens).Parse()
+	require.NoError(t, err)
+
+	route := module.Items[0].(*ast.Route)
+	stmt, ok := route.Body[0].(ast.AssignStatement)
+	require.True(t, ok, "got %T", route.Body[0])
+	assert.Equal(t, "PI", stmt.Target)
+	assert.True(t, stmt.Const)
+	assert.Equal(t, ast.LiteralExpr{Value: ast.FloatLiteral{Value: 3.14}}, stmt.Value)
+	assert.Equal(t, ast.Pos{Line: 2, Column: 9}, stmt.Pos)
+}
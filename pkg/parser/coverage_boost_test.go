@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -2059,3 +2060,31 @@ func TestParsePatternBoolFalse(t *testing.T) {
 		t.Error("expected false")
 	}
 }
+
+func TestParseTypePublic(t *testing.T) {
+	tests := []struct {
+		source string
+		want   ast.Type
+	}{
+		{"int", ast.IntType{}},
+		{"[User]", ast.ArrayType{ElementType: ast.NamedType{Name: "User", Pos: ast.Pos{Line: 1, Column: 2}}}},
+		{"str?", ast.OptionalType{InnerType: ast.StringType{}}},
+		{"int | str", ast.UnionType{Types: []ast.Type{ast.IntType{}, ast.StringType{}}}},
+	}
+	for _, tt := range tests {
+		typ, err := makeParser(t, tt.source).ParseType()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if !reflect.DeepEqual(typ, tt.want) {
+			t.Errorf("%s: expected %#v, got %#v", tt.source, tt.want, typ)
+		}
+	}
+
+	for _, source := range []string{"", "int str"} {
+		if _, err := makeParser(t, source).ParseType(); err == nil {
+			t.Errorf("%q: expected an error", source)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseErrorHandlers(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		expand bool
+	}{
+		{"compact", "@ notfound {\n  > {error: \"not here\"}\n}\n\n@ onerror {\n  $ status = err.status\n  > {error: err.message, status: status}\n}", false},
+		{"expanded", "route notfound {\n  return {error: \"not here\"}\n}\n\nroute onerror {\n  let status = err.status\n  return {error: err.message, status: status}\n}", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tokens []Token
+			var err error
+			if tt.expand {
+				tokens, err = NewExpandedLexer(tt.input).Tokenize()
+			} else {
+				tokens, err = NewLexer(tt.input).Tokenize()
+			}
+			require.NoError(t, err)
+
+			module, err := NewParserWithSource(tokens, tt.input).Parse()
+			require.NoError(t, err)
+			require.Len(t, module.Items, 2)
+
+			notFound, ok := module.Items[0].(*ast.ErrorHandler)
+			require.True(t, ok, "expected ErrorHandler, got %T", module.Items[0])
+			assert.Equal(t, ast.NotFoundHandler, notFound.Kind)
+			assert.Len(t, notFound.Body, 1)
+			assert.Equal(t, ast.Pos{Line: 1, Column: 1}, notFound.Pos)
+
+			onError, ok := module.Items[1].(*ast.ErrorHandler)
+			require.True(t, ok, "expected ErrorHandler, got %T", module.Items[1])
+			assert.Equal(t, ast.OnErrorHandler, onError.Kind)
+			assert.Len(t, onError.Body, 2)
+		})
+	}
+}
+
+func TestParseErrorHandlerMissingBrace(t *testing.T) {
+	input := "@ onerror > 1"
+	tokens, err := NewLexer(input).Tokenize()
+	require.NoError(t, err)
+
+	_, err = NewParserWithSource(tokens, input).Parse()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Expected '{' to start onerror body")
+}
@@ -18,6 +18,7 @@ type ExpandedLexer struct {
 	column            int
 	lastTokenWasValue bool
 	lastTokenLiteral  string
+	interpolations    []interpolation
 }
 
 // NewExpandedLexer creates a new ExpandedLexer for .glyphx files
@@ -61,6 +62,10 @@ func (l *ExpandedLexer) Tokenize() ([]Token, error) {
 		}
 
 		if l.ch == '\n' {
+			if len(l.interpolations) > 0 {
+				open := l.interpolations[0]
+				return nil, fmt.Errorf("unterminated string at line %d, column %d", open.line, open.column)
+			}
 			tokens = append(tokens, Token{
 				Type:   NEWLINE,
 				Line:   l.line,
@@ -93,7 +98,7 @@ func (l *ExpandedLexer) Tokenize() ([]Token, error) {
 		}
 
 		l.lastTokenWasValue = tok.Type == IDENT || tok.Type == INTEGER ||
-			tok.Type == FLOAT || tok.Type == STRING || tok.Type == RPAREN ||
+			tok.Type == FLOAT || tok.Type == STRING || tok.Type == STRING_END || tok.Type == RPAREN ||
 			tok.Type == RBRACKET || tok.Type == TRUE || tok.Type == FALSE
 		l.lastTokenLiteral = tok.Literal
 
@@ -104,6 +109,11 @@ func (l *ExpandedLexer) Tokenize() ([]Token, error) {
 		}
 	}
 
+	if len(l.interpolations) > 0 {
+		open := l.interpolations[0]
+		return nil, fmt.Errorf("unterminated string at line %d, column %d", open.line, open.column)
+	}
+
 	if len(tokens) == 0 || tokens[len(tokens)-1].Type != EOF {
 		tokens = append(tokens, Token{Type: EOF, Line: l.line, Column: l.column})
 	}
@@ -153,10 +163,22 @@ func (l *ExpandedLexer) nextToken() Token {
 		tok.Literal = string(l.ch)
 		l.readChar()
 	case '{':
+		if n := len(l.interpolations); n > 0 {
+			l.interpolations[n-1].braces++
+		}
 		tok.Type = LBRACE
 		tok.Literal = string(l.ch)
 		l.readChar()
 	case '}':
+		if n := len(l.interpolations); n > 0 {
+			if l.interpolations[n-1].braces == 0 {
+				open := l.interpolations[n-1]
+				l.interpolations = l.interpolations[:n-1]
+				l.readChar()
+				return l.readStringContent('"', STRING_END, STRING_MIDDLE, open.line, open.column)
+			}
+			l.interpolations[n-1].braces--
+		}
 		tok.Type = RBRACE
 		tok.Literal = string(l.ch)
 		l.readChar()
@@ -226,8 +248,21 @@ func (l *ExpandedLexer) nextToken() Token {
 			l.readChar()
 		}
 	case '?':
-		tok.Type = QUESTION
-		tok.Literal = string(l.ch)
+		switch l.peekChar() {
+		case '?':
+			ch := l.ch
+			l.readChar()
+			tok.Type = COALESCE
+			tok.Literal = string(ch) + string(l.ch)
+		case '.':
+			ch := l.ch
+			l.readChar()
+			tok.Type = QUESTION_DOT
+			tok.Literal = string(ch) + string(l.ch)
+		default:
+			tok.Type = QUESTION
+			tok.Literal = string(l.ch)
+		}
 		l.readChar()
 	case '&':
 		if l.peekChar() == '&' {
@@ -443,9 +478,17 @@ func (l *ExpandedLexer) readString() Token {
 	startColumn := l.column
 	quote := l.ch
 	l.readChar()
+	return l.readStringContent(quote, STRING, STRING_START, startLine, startColumn)
+}
 
+func (l *ExpandedLexer) readStringContent(quote byte, closed, open TokenType, startLine, startColumn int) Token {
 	var builder strings.Builder
 	for l.ch != quote && l.ch != 0 && l.ch != '\n' {
+		if l.ch == '{' && quote == '"' && !IsLiteralBrace(l.input, l.position) {
+			l.readChar()
+			l.interpolations = append(l.interpolations, interpolation{line: startLine, column: startColumn})
+			return Token{Type: open, Literal: builder.String(), Line: startLine, Column: startColumn}
+		}
 		if l.ch == '\\' {
 			l.readChar()
 			switch l.ch {
@@ -483,7 +526,7 @@ func (l *ExpandedLexer) readString() Token {
 	l.readChar()
 
 	return Token{
-		Type:    STRING,
+		Type:    closed,
 		Literal: builder.String(),
 		Line:    startLine,
 		Column:  startColumn,
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"github.com/glyphlang/glyph/pkg/ast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLexer_InterpolatedString(t *testing.T) {
+	tokens, err := NewLexer(`"Hi {user.name}, {n}!"`).Tokenize()
+	require.NoError(t, err)
+
+	var got []string
+	for _, tok := range tokens {
+		got = append(got, tok.Type.String()+":"+tok.Literal)
+	}
+	assert.Equal(t, []string{
+		"STRING_START:Hi ", "IDENT:user", ".:.", "IDENT:name",
+		"STRING_MIDDLE:, ", "IDENT:n", "STRING_END:!", "EOF:",
+	}, got)
+}
+
+func TestLexer_InterpolationLiteralBraces(t *testing.T) {
+	for _, tt := range []struct {
+		source string
+		want   string
+	}{
+		{`"\{literal}"`, "{literal}"},
+		{`'{single}'`, "{single}"},
+		{`"${param}"`, "${param}"},
+		{`"[A-Z]{3}[0-9]{2,}"`, "[A-Z]{3}[0-9]{2,}"},
+	} {
+		tokens, err := NewLexer(tt.source).Tokenize()
+		require.NoError(t, err)
+		require.Equal(t, STRING, tokens[0].Type, tt.source)
+		assert.Equal(t, tt.want, tokens[0].Literal, tt.source)
+	}
+}
+
+func TestLexer_UnterminatedInterpolation(t *testing.T) {
+	_, err := NewLexer("\"a {b\n").Tokenize()
+	assert.ErrorContains(t, err, "Unterminated string")
+	_, err = NewExpandedLexer(`"a {b`).Tokenize()
+	assert.ErrorContains(t, err, "unterminated string")
+}
+
+func TestParser_InterpolatedString(t *testing.T) {
+	expr := parseRouteExpr(t, `"Hello, {user.name}! {items[0]} {keys({a: 1})}"`)
+	str, ok := expr.(ast.InterpolatedStringExpr)
+	require.True(t, ok, "got %T", expr)
+	require.Len(t, str.Parts, 6)
+	assert.Equal(t, ast.LiteralExpr{Value: ast.StringLiteral{Value: "Hello, "}}, str.Parts[0])
+	assert.IsType(t, ast.FieldAccessExpr{}, str.Parts[1])
+	assert.Equal(t, ast.LiteralExpr{Value: ast.StringLiteral{Value: "! "}}, str.Parts[2])
+	assert.IsType(t, ast.ArrayIndexExpr{}, str.Parts[3])
+	assert.Equal(t, ast.LiteralExpr{Value: ast.StringLiteral{Value: " "}}, str.Parts[4])
+	assert.IsType(t, ast.FunctionCallExpr{}, str.Parts[5])
+
+	// Strings nest inside embedded expressions
+	expr = parseRouteExpr(t, `"a{"b{c}"}"`)
+	outer, ok := expr.(ast.InterpolatedStringExpr)
+	require.True(t, ok, "got %T", expr)
+	require.Len(t, outer.Parts, 2)
+	assert.IsType(t, ast.InterpolatedStringExpr{}, outer.Parts[1])
+}
+
+func TestParser_EmptyInterpolation(t *testing.T) {
+	tokens, err := NewLexer("@ GET /x {\n  > \"a {} b\"\n}").Tokenize()
+	require.NoError(t, err)
+	_, err = NewParser(tokens).Parse()
+	assert.ErrorContains(t, err, "Expected an expression inside {} in string")
+}
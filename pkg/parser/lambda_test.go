@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseReturnValue parses a route returning expr and returns the value
+func parseReturnValue(t *testing.T, expr string) ast.Expr {
+	t.Helper()
+	input := "@ GET /x {\n  > " + expr + "\n}"
+	tokens, err := NewLexer(input).Tokenize()
+	require.NoError(t, err)
+	module, err := NewParser(tokens).Parse()
+	require.NoError(t, err)
+	return module.Items[0].(*ast.Route).Body[0].(ast.ReturnStatement).Value
+}
+
+func TestParseLambda(t *testing.T) {
+	t.Run("expression body", func(t *testing.T) {
+		lambda, ok := parseReturnValue(t, "(x: int, y) => x * y").(ast.LambdaExpr)
+		require.True(t, ok)
+		require.Len(t, lambda.Params, 2)
+		assert.Equal(t, "x", lambda.Params[0].Name)
+		assert.Equal(t, ast.IntType{}, lambda.Params[0].TypeAnnotation)
+		assert.Equal(t, "y", lambda.Params[1].Name)
+		assert.Nil(t, lambda.Params[1].TypeAnnotation)
+		assert.IsType(t, ast.BinaryOpExpr{}, lambda.Body)
+		assert.Nil(t, lambda.Block)
+	})
+
+	t.Run("no parameters", func(t *testing.T) {
+		lambda, ok := parseReturnValue(t, "() => 0").(ast.LambdaExpr)
+		require.True(t, ok)
+		assert.Empty(t, lambda.Params)
+		assert.Equal(t, ast.LiteralExpr{Value: ast.IntLiteral{Value: 0}}, lambda.Body)
+	})
+
+	t.Run("block body", func(t *testing.T) {
+		lambda, ok := parseReturnValue(t, "() => {\n    count = count + 1\n    > count\n  }").(ast.LambdaExpr)
+		require.True(t, ok)
+		assert.Nil(t, lambda.Body)
+		require.Len(t, lambda.Block, 2)
+		assert.IsType(t, ast.ReassignStatement{}, lambda.Block[0])
+		assert.IsType(t, ast.ReturnStatement{}, lambda.Block[1])
+	})
+
+	t.Run("object literal body", func(t *testing.T) {
+		lambda, ok := parseReturnValue(t, "(x) => {value: x}").(ast.LambdaExpr)
+		require.True(t, ok)
+		assert.IsType(t, ast.ObjectExpr{}, lambda.Body)
+	})
+
+	t.Run("argument of a call", func(t *testing.T) {
+		call, ok := parseReturnValue(t, "map(items, (n) => n + 1)").(ast.FunctionCallExpr)
+		require.True(t, ok)
+		require.Len(t, call.Args, 2)
+		assert.IsType(t, ast.LambdaExpr{}, call.Args[1])
+	})
+
+	t.Run("grouping is not a lambda", func(t *testing.T) {
+		assert.IsType(t, ast.BinaryOpExpr{}, parseReturnValue(t, "(x) * 2"))
+		assert.IsType(t, ast.VariableExpr{}, parseReturnValue(t, "(x)"))
+	})
+}
+
+func TestParseMatchGuardIsNotLambda(t *testing.T) {
+	expr := parseReturnValue(t, "match n {\n    x when (big) => \"big\"\n    _ => \"small\"\n  }")
+	match, ok := expr.(ast.MatchExpr)
+	require.True(t, ok, "expected MatchExpr, got %T", expr)
+	require.Len(t, match.Cases, 2)
+	guard, ok := match.Cases[0].Guard.(ast.VariableExpr)
+	require.True(t, ok, "expected VariableExpr guard, got %T", match.Cases[0].Guard)
+	assert.Equal(t, "big", guard.Name)
+}
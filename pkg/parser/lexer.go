@@ -17,6 +17,13 @@ type Lexer struct {
 	column            int
 	lastTokenWasValue bool   // Track if last token was a value (for / disambiguation)
 	lastTokenLiteral  string // Track last token literal (for route keyword detection)
+	interpolations    []interpolation
+}
+
+// interpolation is an open {expr} of an interpolated string
+type interpolation struct {
+	braces       int // braces opened inside the expression and not yet closed
+	line, column int // start of the string
 }
 
 // New creates a new Lexer
@@ -64,6 +71,10 @@ func (l *Lexer) Tokenize() ([]Token, error) {
 
 		// Handle newlines
 		if l.ch == '\n' {
+			if len(l.interpolations) > 0 {
+				open := l.interpolations[0]
+				return nil, l.unterminatedStringError(open.line, open.column, '"')
+			}
 			tokens = append(tokens, Token{
 				Type:   NEWLINE,
 				Line:   l.line,
@@ -99,7 +110,7 @@ func (l *Lexer) Tokenize() ([]Token, error) {
 
 		// Track if token was a value (for / disambiguation)
 		l.lastTokenWasValue = tok.Type == IDENT || tok.Type == INTEGER ||
-			tok.Type == FLOAT || tok.Type == STRING || tok.Type == RPAREN ||
+			tok.Type == FLOAT || tok.Type == STRING || tok.Type == STRING_END || tok.Type == RPAREN ||
 			tok.Type == RBRACKET || tok.Type == TRUE || tok.Type == FALSE
 		l.lastTokenLiteral = tok.Literal
 
@@ -110,6 +121,11 @@ func (l *Lexer) Tokenize() ([]Token, error) {
 		}
 	}
 
+	if len(l.interpolations) > 0 {
+		open := l.interpolations[0]
+		return nil, l.unterminatedStringError(open.line, open.column, '"')
+	}
+
 	// Add EOF token if not already present
 	if len(tokens) == 0 || tokens[len(tokens)-1].Type != EOF {
 		tokens = append(tokens, Token{Type: EOF, Line: l.line, Column: l.column})
@@ -181,10 +197,23 @@ func (l *Lexer) nextToken() Token {
 		tok.Literal = string(l.ch)
 		l.readChar()
 	case '{':
+		if n := len(l.interpolations); n > 0 {
+			l.interpolations[n-1].braces++
+		}
 		tok.Type = LBRACE
 		tok.Literal = string(l.ch)
 		l.readChar()
 	case '}':
+		if n := len(l.interpolations); n > 0 {
+			if l.interpolations[n-1].braces == 0 {
+				// The end of an embedded expression: the string continues
+				open := l.interpolations[n-1]
+				l.interpolations = l.interpolations[:n-1]
+				l.readChar()
+				return l.readStringContent('"', STRING_END, STRING_MIDDLE, open.line, open.column)
+			}
+			l.interpolations[n-1].braces--
+		}
 		tok.Type = RBRACE
 		tok.Literal = string(l.ch)
 		l.readChar()
@@ -260,8 +289,21 @@ func (l *Lexer) nextToken() Token {
 			l.readChar()
 		}
 	case '?':
-		tok.Type = QUESTION
-		tok.Literal = string(l.ch)
+		switch l.peekChar() {
+		case '?':
+			ch := l.ch
+			l.readChar()
+			tok.Type = COALESCE
+			tok.Literal = string(ch) + string(l.ch)
+		case '.':
+			ch := l.ch
+			l.readChar()
+			tok.Type = QUESTION_DOT
+			tok.Literal = string(ch) + string(l.ch)
+		default:
+			tok.Type = QUESTION
+			tok.Literal = string(l.ch)
+		}
 		l.readChar()
 	case '&':
 		if l.peekChar() == '&' {
@@ -438,18 +480,33 @@ func (l *Lexer) readNumber() Token {
 	return tok
 }
 
-// readString reads a string literal
+// readString reads a string literal. In a double-quoted string, {expr}
+// embeds an expression; the string is then returned as a STRING_START token
+// and lexing continues with the expression.
 func (l *Lexer) readString() Token {
 	startLine := l.line
 	startColumn := l.column
 	quote := l.ch
 	l.readChar() // consume opening quote
+	return l.readStringContent(quote, STRING, STRING_START, startLine, startColumn)
+}
 
+// readStringContent reads the text of a string up to its closing quote,
+// returning a closed token, or up to an embedded expression, returning an
+// open token.
+func (l *Lexer) readStringContent(quote byte, closed, open TokenType, startLine, startColumn int) Token {
 	var builder strings.Builder
 	for l.ch != quote && l.ch != 0 && l.ch != '\n' {
+		if l.ch == '{' && quote == '"' && !IsLiteralBrace(l.input, l.position) {
+			l.readChar() // consume {
+			l.interpolations = append(l.interpolations, interpolation{line: startLine, column: startColumn})
+			return Token{Type: open, Literal: builder.String(), Line: startLine, Column: startColumn}
+		}
 		if l.ch == '\\' {
 			l.readChar()
 			switch l.ch {
+			case '{':
+				builder.WriteByte('{')
 			case 'n':
 				builder.WriteByte('\n')
 			case 't':
@@ -516,13 +573,30 @@ func (l *Lexer) readString() Token {
 	l.readChar() // consume closing quote
 
 	return Token{
-		Type:    STRING,
+		Type:    closed,
 		Literal: builder.String(),
 		Line:    startLine,
 		Column:  startColumn,
 	}
 }
 
+// IsLiteralBrace reports whether the { at s[i] in a double-quoted string is
+// text rather than the start of an embedded expression: in ${param}, which is
+// left to macros, and in a regex repetition count such as {3} or {2,4}.
+func IsLiteralBrace(s string, i int) bool {
+	if i > 0 && s[i-1] == '$' {
+		return true
+	}
+	j := i + 1
+	if j >= len(s) || !unicode.IsDigit(rune(s[j])) {
+		return false
+	}
+	for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == ',') {
+		j++
+	}
+	return j < len(s) && s[j] == '}'
+}
+
 // readHexDigits reads up to n hex digits from the input without consuming the
 // final character (the caller's readChar handles that). Returns the hex string.
 func (l *Lexer) readHexDigits(n int) string {
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMiddlewareDef(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		expand bool
+	}{
+		{"compact", "@ middleware requireAdmin {\n  if auth.user.role != \"admin\" {\n    error(403, \"forbidden\")\n  }\n  $ admin = true\n}", false},
+		{"expanded", "middleware requireAdmin {\n  if auth.user.role != \"admin\" {\n    error(403, \"forbidden\")\n  }\n  let admin = true\n}", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tokens []Token
+			var err error
+			if tt.expand {
+				tokens, err = NewExpandedLexer(tt.input).Tokenize()
+			} else {
+				tokens, err = NewLexer(tt.input).Tokenize()
+			}
+			require.NoError(t, err)
+
+			module, err := NewParserWithSource(tokens, tt.input).Parse()
+			require.NoError(t, err)
+			require.Len(t, module.Items, 1)
+			mw, ok := module.Items[0].(*ast.MiddlewareDef)
+			require.True(t, ok, "expected MiddlewareDef, got %T", module.Items[0])
+			assert.Equal(t, "requireAdmin", mw.Name)
+			assert.Len(t, mw.Body, 2)
+			assert.Equal(t, ast.Pos{Line: 1, Column: 1}, mw.Pos)
+		})
+	}
+}
+
+func TestParseRouteMiddlewareOrder(t *testing.T) {
+	input := `@ GET /admin {
+  + requireAdmin
+  + auth(jwt)
+  + audit("admin")
+  > "ok"
+}`
+	tokens, err := NewLexer(input).Tokenize()
+	require.NoError(t, err)
+
+	module, err := NewParserWithSource(tokens, input).Parse()
+	require.NoError(t, err)
+	route := module.Items[0].(*ast.Route)
+	require.NotNil(t, route.Auth)
+	assert.Equal(t, []ast.MiddlewareRef{
+		{Name: "requireAdmin", Pos: ast.Pos{Line: 2, Column: 5}},
+		{Name: "audit", Pos: ast.Pos{Line: 4, Column: 5}},
+	}, route.Middlewares)
+	assert.Len(t, route.Body, 1)
+}
+
+func TestParseMiddlewareDefMissingBrace(t *testing.T) {
+	input := "@ middleware requireAdmin > 1"
+	tokens, err := NewLexer(input).Tokenize()
+	require.NoError(t, err)
+
+	_, err = NewParserWithSource(tokens, input).Parse()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Expected '{' to start middleware body")
+}
@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNamedArguments(t *testing.T) {
+	input := "@ GET /x {\n  > greet(\"Bob\", greeting: \"Hi\", suffix: mark + \"!\")\n}"
+	tokens, err := NewLexer(input).Tokenize()
+	require.NoError(t, err)
+
+	module, err := NewParser(tokens).Parse()
+	require.NoError(t, err)
+
+	route := module.Items[0].(*ast.Route)
+	ret := route.Body[0].(ast.ReturnStatement)
+	call, ok := ret.Value.(ast.FunctionCallExpr)
+	require.True(t, ok, "expected FunctionCallExpr, got %T", ret.Value)
+	assert.Equal(t, "greet", call.Name)
+	require.Len(t, call.Args, 1)
+	require.Len(t, call.NamedArgs, 2)
+	assert.Equal(t, "greeting", call.NamedArgs[0].Name)
+	assert.Equal(t, ast.LiteralExpr{Value: ast.StringLiteral{Value: "Hi"}}, call.NamedArgs[0].Value)
+	assert.Equal(t, ast.Pos{Line: 2, Column: 18}, call.NamedArgs[0].Pos)
+	assert.Equal(t, "suffix", call.NamedArgs[1].Name)
+	assert.IsType(t, ast.BinaryOpExpr{}, call.NamedArgs[1].Value)
+}
+
+func TestParseNamedArgumentsOnNamespace(t *testing.T) {
+	input := "@ GET /x {\n  > utils.greet(name: \"Bob\")\n}"
+	tokens, err := NewLexer(input).Tokenize()
+	require.NoError(t, err)
+
+	module, err := NewParser(tokens).Parse()
+	require.NoError(t, err)
+
+	ret := module.Items[0].(*ast.Route).Body[0].(ast.ReturnStatement)
+	call, ok := ret.Value.(ast.FunctionCallExpr)
+	require.True(t, ok, "expected FunctionCallExpr, got %T", ret.Value)
+	assert.Equal(t, "utils.greet", call.Name)
+	assert.Empty(t, call.Args)
+	require.Len(t, call.NamedArgs, 1)
+	assert.Equal(t, "name", call.NamedArgs[0].Name)
+}
+
+func TestParseNamedArgumentErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"positional after named", "@ GET /x {\n  > greet(greeting: \"Hi\", \"Bob\")\n}", "Positional argument after named arguments"},
+		{"duplicate name", "@ GET /x {\n  > greet(name: \"a\", name: \"b\")\n}", "Duplicate named argument 'name'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := NewLexer(tt.input).Tokenize()
+			require.NoError(t, err)
+
+			_, err = NewParser(tokens).Parse()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"github.com/glyphlang/glyph/pkg/ast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLexer_CoalesceAndOptionalChaining(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []TokenType
+	}{
+		{"coalesce", "a ?? b", []TokenType{IDENT, COALESCE, IDENT}},
+		{"optional chaining", "a?.b?.c", []TokenType{IDENT, QUESTION_DOT, IDENT, QUESTION_DOT, IDENT}},
+		{"optional type is unchanged", "x: int?", []TokenType{IDENT, COLON, IDENT, QUESTION}},
+		{"validation is unchanged", "? check(x)", []TokenType{QUESTION, IDENT, LPAREN, IDENT, RPAREN}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, lexer := range []interface{ Tokenize() ([]Token, error) }{NewLexer(tt.input), NewExpandedLexer(tt.input)} {
+				tokens, err := lexer.Tokenize()
+				require.NoError(t, err)
+				var actual []TokenType
+				for _, tok := range tokens {
+					if tok.Type != EOF {
+						actual = append(actual, tok.Type)
+					}
+				}
+				assert.Equal(t, tt.expected, actual)
+			}
+		})
+	}
+}
+
+// parseRouteExpr parses source as the value of a let statement in a route
+func parseRouteExpr(t *testing.T, expr string) ast.Expr {
+	t.Helper()
+	tokens, err := NewLexer("@ GET /x {\n  $ v = " + expr + "\n  > v\n}").Tokenize()
+	require.NoError(t, err)
+	module, err := NewParser(tokens).Parse()
+	require.NoError(t, err)
+	route := module.Items[0].(*ast.Route)
+	return route.Body[0].(ast.AssignStatement).Value
+}
+
+func TestParser_OptionalChaining(t *testing.T) {
+	expr := parseRouteExpr(t, "user?.profile.bio")
+
+	outer, ok := expr.(ast.FieldAccessExpr)
+	require.True(t, ok, "got %T", expr)
+	assert.Equal(t, "bio", outer.Field)
+	assert.False(t, outer.Optional)
+
+	inner, ok := outer.Object.(ast.FieldAccessExpr)
+	require.True(t, ok, "got %T", outer.Object)
+	assert.Equal(t, "profile", inner.Field)
+	assert.True(t, inner.Optional)
+	assert.Equal(t, ast.VariableExpr{Name: "user", Pos: ast.Pos{Line: 2, Column: 9}}, inner.Object)
+}
+
+func TestParser_CoalescePrecedence(t *testing.T) {
+	// ?? binds more loosely than ||, so this is a ?? (b || c)
+	expr := parseRouteExpr(t, "a ?? b || c")
+	coalesce, ok := expr.(ast.BinaryOpExpr)
+	require.True(t, ok, "got %T", expr)
+	assert.Equal(t, ast.Coalesce, coalesce.Op)
+	or, ok := coalesce.Right.(ast.BinaryOpExpr)
+	require.True(t, ok, "got %T", coalesce.Right)
+	assert.Equal(t, ast.Or, or.Op)
+
+	// and more tightly than nothing else: a?.b ?? "x" + "y" is a?.b ?? ("x" + "y")
+	expr = parseRouteExpr(t, `a?.b ?? "x" + "y"`)
+	coalesce, ok = expr.(ast.BinaryOpExpr)
+	require.True(t, ok, "got %T", expr)
+	assert.Equal(t, ast.Coalesce, coalesce.Op)
+	assert.IsType(t, ast.FieldAccessExpr{}, coalesce.Left)
+	assert.IsType(t, ast.BinaryOpExpr{}, coalesce.Right)
+}
+
+func TestParser_OptionalChainingMethodCall(t *testing.T) {
+	tokens, err := NewLexer("@ GET /x {\n  > user?.name.upper()\n}").Tokenize()
+	require.NoError(t, err)
+	_, err = NewParser(tokens).Parse()
+	require.NoError(t, err, "?. before a plain field is fine")
+
+	tokens, err = NewLexer("@ GET /x {\n  > user?.greet()\n}").Tokenize()
+	require.NoError(t, err)
+	_, err = NewParser(tokens).Parse()
+	assert.ErrorContains(t, err, "Optional chaining is not supported on method calls")
+}
+
+func TestParser_KeywordFieldNames(t *testing.T) {
+	expr := parseRouteExpr(t, `{from: "a@example.com", match: 1, in: true}`)
+	obj, ok := expr.(ast.ObjectExpr)
+	require.True(t, ok, "got %T", expr)
+	require.Len(t, obj.Fields, 3)
+	assert.Equal(t, "from", obj.Fields[0].Key)
+	assert.Equal(t, "match", obj.Fields[1].Key)
+	assert.Equal(t, "in", obj.Fields[2].Key)
+
+	expr = parseRouteExpr(t, "message.from")
+	field, ok := expr.(ast.FieldAccessExpr)
+	require.True(t, ok, "got %T", expr)
+	assert.Equal(t, "from", field.Field)
+}
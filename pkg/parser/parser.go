@@ -1,8 +1,10 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"github.com/glyphlang/glyph/pkg/ast"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -16,23 +18,28 @@ type Parser struct {
 	position int
 	source   string // Original source code for error messages
 	depth    int    // Current recursion depth
+	// guardStart is the position of the first token of the match guard
+	// being parsed, where (x) => is the guard (x) and the case's arrow
+	guardStart int
 }
 
 // NewParser creates a new Parser
 func NewParser(tokens []Token) *Parser {
 	return &Parser{
-		tokens:   tokens,
-		position: 0,
-		source:   "",
+		tokens:     tokens,
+		position:   0,
+		source:     "",
+		guardStart: -1,
 	}
 }
 
 // NewParserWithSource creates a new Parser with source code for better error messages
 func NewParserWithSource(tokens []Token, source string) *Parser {
 	return &Parser{
-		tokens:   tokens,
-		position: 0,
-		source:   source,
+		tokens:     tokens,
+		position:   0,
+		source:     source,
+		guardStart: -1,
 	}
 }
 
@@ -50,6 +57,9 @@ func (p *Parser) Parse() (*ast.Module, error) {
 			break
 		}
 
+		start := p.current()
+		count := len(items)
+
 		switch p.current().Type {
 		case IMPORT:
 			// import "path" or import "path" as alias
@@ -123,6 +133,22 @@ func (p *Parser) Parse() (*ast.Module, error) {
 				return nil, err
 			}
 			items = append(items, item)
+		case PLUS:
+			// middleware name { body } in expanded syntax, where the
+			// middleware keyword lexes as +
+			if p.current().Literal != "middleware" {
+				return nil, p.errorWithHint(
+					fmt.Sprintf("Unexpected token %s", p.current().Type),
+					p.current(),
+					"Named middleware is defined with: @ middleware name { ... }",
+				)
+			}
+			p.advance() // consume middleware
+			item, err := p.parseMiddlewareDef()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
 		case MACRO:
 			// macro! name(params) { body }
 			item, err := p.parseMacroDef()
@@ -138,6 +164,13 @@ func (p *Parser) Parse() (*ast.Module, error) {
 					return nil, err
 				}
 				items = append(items, item)
+			} else if p.current().Literal == "seed" && !(p.peek(1).Type == BANG && p.peek(2).Type == LPAREN) {
+				// seed! name { body }, unless it is a seed!(args) macro call
+				item, err := p.parseSeedBlock()
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
 			} else if p.current().Literal == "type" {
 				// Check for "type" keyword as alternative syntax
 				p.advance() // consume "type"
@@ -181,7 +214,7 @@ func (p *Parser) Parse() (*ast.Module, error) {
 				return nil, p.errorWithHint(
 					fmt.Sprintf("Unexpected token %s", p.current().Type),
 					p.current(),
-					"Top-level items must start with ':', '@', '!', '*', '~', '&', 'macro', 'contract', 'trait', 'provider', 'import', 'from', 'module', 'const', or 'test'",
+					"Top-level items must start with ':', '@', '!', '*', '~', '&', 'macro', 'contract', 'trait', 'provider', 'import', 'from', 'module', 'const', 'test', or 'seed'",
 				)
 			}
 		case EOF:
@@ -190,14 +223,38 @@ func (p *Parser) Parse() (*ast.Module, error) {
 			return nil, p.errorWithHint(
 				fmt.Sprintf("Unexpected token %s", p.current().Type),
 				p.current(),
-				"Top-level items must start with ':', '@', '!', '*', '~', '&', 'macro', 'contract', 'trait', 'provider', 'import', 'from', 'module', 'const', or 'test'",
+				"Top-level items must start with ':', '@', '!', '*', '~', '&', 'macro', 'contract', 'trait', 'provider', 'import', 'from', 'module', 'const', 'test', or 'seed'",
 			)
 		}
+
+		if len(items) > count {
+			setItemPos(items[len(items)-1], ast.Pos{Line: start.Line, Column: start.Column})
+		}
 	}
 
 	return &ast.Module{Items: items}, nil
 }
 
+// setItemPos records where a top-level item starts, for items that track it
+func setItemPos(item ast.Item, pos ast.Pos) {
+	switch it := item.(type) {
+	case *ast.TypeDef:
+		it.Pos = pos
+	case *ast.Route:
+		it.Pos = pos
+	case *ast.Function:
+		it.Pos = pos
+	case *ast.Command:
+		it.Pos = pos
+	case *ast.ConstDecl:
+		it.Pos = pos
+	case *ast.MiddlewareDef:
+		it.Pos = pos
+	case *ast.ErrorHandler:
+		it.Pos = pos
+	}
+}
+
 // parseTypeDef parses a type definition: : TypeName { fields } or : TypeName impl Trait { fields, methods }
 func (p *Parser) parseTypeDef() (ast.Item, error) {
 	if err := p.expect(COLON); err != nil {
@@ -209,6 +266,11 @@ func (p *Parser) parseTypeDef() (ast.Item, error) {
 		return nil, err
 	}
 
+	// Enum: Name = "a" | "b"
+	if p.check(EQUALS) {
+		return p.parseEnumDef(name)
+	}
+
 	// Parse optional generic type parameters
 	typeParams, err := p.parseTypeParameters()
 	if err != nil {
@@ -241,6 +303,11 @@ func (p *Parser) parseTypeDefWithoutColon() (ast.Item, error) {
 		return nil, err
 	}
 
+	// Enum: Name = "a" | "b"
+	if p.check(EQUALS) {
+		return p.parseEnumDef(name)
+	}
+
 	// Parse optional generic type parameters
 	typeParams, err := p.parseTypeParameters()
 	if err != nil {
@@ -267,6 +334,48 @@ func (p *Parser) parseTypeDefWithoutColon() (ast.Item, error) {
 	return p.parseTypeDefBody(name, typeParams, traits)
 }
 
+// parseEnumDef parses the value list of an enum after its name:
+// = "pending" | "shipped" | "delivered"
+func (p *Parser) parseEnumDef(name string) (ast.Item, error) {
+	if err := p.expect(EQUALS); err != nil {
+		return nil, err
+	}
+
+	var values []string
+	seen := make(map[string]bool)
+	for {
+		if !p.check(STRING) {
+			return nil, p.errorWithHint(
+				"Expected a string value in enum "+name,
+				p.current(),
+				"Enum values are string literals separated by |, e.g. : Status = \"pending\" | \"shipped\"",
+			)
+		}
+		value := p.current().Literal
+		if seen[value] {
+			return nil, p.errorWithHint(
+				fmt.Sprintf("Duplicate value %q in enum %s", value, name),
+				p.current(),
+				"Each enum value may appear only once",
+			)
+		}
+		seen[value] = true
+		values = append(values, value)
+		p.advance()
+
+		// The list may continue on the next line with a leading |
+		saved := p.position
+		p.skipNewlines()
+		if !p.match(PIPE) {
+			p.position = saved
+			break
+		}
+		p.skipNewlines()
+	}
+
+	return &ast.EnumDef{Name: name, Values: values}, nil
+}
+
 // parseTypeDefBody parses the body of a type definition (shared between parseTypeDef and parseTypeDefWithoutColon).
 // It handles fields, methods, and trait implementations.
 func (p *Parser) parseTypeDefBody(name string, typeParams []ast.TypeParameter, traits []string) (ast.Item, error) {
@@ -587,6 +696,7 @@ func (p *Parser) parseField() (ast.Field, error) {
 
 // parseFieldWithContext parses a field with type parameter context: name: type! [= default]
 func (p *Parser) parseFieldWithContext(typeParamNames []string) (ast.Field, error) {
+	nameTok := p.current()
 	name, err := p.expectIdent()
 	if err != nil {
 		return ast.Field{}, err
@@ -635,6 +745,7 @@ func (p *Parser) parseFieldWithContext(typeParamNames []string) (ast.Field, erro
 		Required:       required,
 		Default:        defaultValue,
 		Annotations:    annotations,
+		Pos:            ast.Pos{Line: nameTok.Line, Column: nameTok.Column},
 	}, nil
 }
 
@@ -885,7 +996,8 @@ func (p *Parser) parseSingleType(typeParamNames []string) (ast.Type, error) {
 			p.current(),
 		)
 	} else {
-		typeName := p.current().Literal
+		typeTok := p.current()
+		typeName := typeTok.Literal
 		p.advance()
 
 		// Check if this is a type parameter reference
@@ -910,7 +1022,7 @@ func (p *Parser) parseSingleType(typeParamNames []string) (ast.Type, error) {
 			case "float":
 				baseType = ast.FloatType{}
 			default:
-				baseType = ast.NamedType{Name: typeName}
+				baseType = ast.NamedType{Name: typeName, Pos: ast.Pos{Line: typeTok.Line, Column: typeTok.Column}}
 			}
 		}
 
@@ -1002,7 +1114,8 @@ func (p *Parser) parseTypeWithContext(typeParamNames []string) (ast.Type, bool,
 			p.current(),
 		)
 	} else {
-		typeName := p.current().Literal
+		typeTok := p.current()
+		typeName := typeTok.Literal
 		p.advance()
 
 		// Check for qualified type name (e.g., module.TypeName)
@@ -1040,7 +1153,7 @@ func (p *Parser) parseTypeWithContext(typeParamNames []string) (ast.Type, bool,
 			case "float":
 				baseType = ast.FloatType{}
 			default:
-				baseType = ast.NamedType{Name: typeName}
+				baseType = ast.NamedType{Name: typeName, Pos: ast.Pos{Line: typeTok.Line, Column: typeTok.Column}}
 			}
 		}
 
@@ -1298,6 +1411,10 @@ func (p *Parser) parseRoute() (ast.Item, error) {
 		return p.parseGraphQLResolver(ast.GraphQLMutation)
 	case "subscription":
 		return p.parseGraphQLResolver(ast.GraphQLSubscription)
+	case "middleware":
+		return p.parseMiddlewareDef()
+	case "notfound", "onerror":
+		return p.parseErrorHandler(ast.ErrorHandlerKind(routeKw))
 	}
 
 	// Check for HTTP method shorthand: @ GET /path
@@ -1436,6 +1553,7 @@ func (p *Parser) parseRoute() (ast.Item, error) {
 	var rateLimit *ast.RateLimit
 	var injections []ast.Injection
 	var queryParams []ast.QueryParamDecl
+	var middlewares []ast.MiddlewareRef
 	var body []ast.Statement
 	var inputType ast.Type
 
@@ -1453,8 +1571,9 @@ func (p *Parser) parseRoute() (ast.Item, error) {
 	for !p.check(RBRACE) && !p.isAtEnd() {
 		switch p.current().Type {
 		case PLUS:
-			// Middleware: + auth(jwt) or + ratelimit(100/min)
+			// Middleware: + auth(jwt), + ratelimit(100/min) or + name
 			p.advance()
+			nameTok := p.current()
 			middlewareName, err := p.expectIdent()
 			if err != nil {
 				return nil, err
@@ -1472,7 +1591,12 @@ func (p *Parser) parseRoute() (ast.Item, error) {
 					return nil, err
 				}
 			default:
-				// Skip unknown middleware
+				// A named middleware defined with @ middleware; any
+				// arguments are skipped
+				middlewares = append(middlewares, ast.MiddlewareRef{
+					Name: middlewareName,
+					Pos:  ast.Pos{Line: nameTok.Line, Column: nameTok.Column},
+				})
 				if p.check(LPAREN) {
 					p.advance()
 					for !p.check(RPAREN) && !p.isAtEnd() {
@@ -1603,10 +1727,66 @@ func (p *Parser) parseRoute() (ast.Item, error) {
 		RateLimit:   rateLimit,
 		Injections:  injections,
 		QueryParams: queryParams,
+		Middlewares: middlewares,
 		Body:        body,
 	}, nil
 }
 
+// parseMiddlewareDef parses a named middleware definition after its keyword:
+// @ middleware name { body }
+func (p *Parser) parseMiddlewareDef() (ast.Item, error) {
+	name, err := p.expectIdent()
+	if err != nil {
+		return nil, p.errorWithHint(
+			"Expected middleware name",
+			p.current(),
+			"Example: @ middleware requireAdmin { ... }",
+		)
+	}
+
+	p.skipNewlines()
+	if !p.check(LBRACE) {
+		return nil, p.errorWithHint(
+			fmt.Sprintf("Expected '{' to start middleware body, got %s", p.current().Type),
+			p.current(),
+			"Middleware bodies must be enclosed in braces: @ middleware requireAdmin { ... }",
+		)
+	}
+
+	body, err := p.parseStatementBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.MiddlewareDef{
+		Name: name,
+		Body: body,
+	}, nil
+}
+
+// parseErrorHandler parses an error handler after its keyword:
+// @ notfound { body } or @ onerror { body }
+func (p *Parser) parseErrorHandler(kind ast.ErrorHandlerKind) (ast.Item, error) {
+	p.skipNewlines()
+	if !p.check(LBRACE) {
+		return nil, p.errorWithHint(
+			fmt.Sprintf("Expected '{' to start %s body, got %s", kind, p.current().Type),
+			p.current(),
+			fmt.Sprintf("Error handler bodies must be enclosed in braces: @ %s { ... }", kind),
+		)
+	}
+
+	body, err := p.parseStatementBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.ErrorHandler{
+		Kind: kind,
+		Body: body,
+	}, nil
+}
+
 // parseQueryParamDecl parses a query parameter declaration: ? name: type [= default]
 // Examples:
 //
@@ -1983,9 +2163,26 @@ func (p *Parser) parseStatement() (ast.Statement, error) {
 			},
 		}, nil
 
+	case CONST:
+		// const NAME = expr or const NAME: Type = expr
+		nameTok := p.peek(1)
+		item, err := p.parseConstDecl()
+		if err != nil {
+			return nil, err
+		}
+		decl := item.(*ast.ConstDecl)
+		return ast.AssignStatement{
+			Target: decl.Name,
+			Value:  decl.Value,
+			Const:  true,
+			Type:   decl.Type,
+			Pos:    ast.Pos{Line: nameTok.Line, Column: nameTok.Column},
+		}, nil
+
 	case DOLLAR:
 		// $ var = expr or $ obj.field = expr or $ var: Type = expr
 		p.advance()
+		targetTok := p.current()
 		varName, err := p.expectIdent()
 		if err != nil {
 			return nil, err
@@ -2007,6 +2204,7 @@ func (p *Parser) parseStatement() (ast.Statement, error) {
 				return ast.AssignStatement{
 					Target: varName,
 					Value:  ast.LiteralExpr{Value: ast.StringLiteral{Value: ""}},
+					Pos:    ast.Pos{Line: targetTok.Line, Column: targetTok.Column},
 				}, nil
 			}
 		}
@@ -2072,6 +2270,7 @@ func (p *Parser) parseStatement() (ast.Statement, error) {
 		return ast.AssignStatement{
 			Target: target,
 			Value:  value,
+			Pos:    ast.Pos{Line: targetTok.Line, Column: targetTok.Column},
 		}, nil
 
 	case GREATER:
@@ -2096,6 +2295,7 @@ func (p *Parser) parseStatement() (ast.Statement, error) {
 		if p.current().Literal == "let" {
 			p.advance() // consume "let"
 			// Parse as assignment statement
+			targetTok := p.current()
 			varName, err := p.expectIdent()
 			if err != nil {
 				return nil, err
@@ -2113,6 +2313,7 @@ func (p *Parser) parseStatement() (ast.Statement, error) {
 					return ast.AssignStatement{
 						Target: varName,
 						Value:  ast.LiteralExpr{Value: ast.StringLiteral{Value: ""}},
+						Pos:    ast.Pos{Line: targetTok.Line, Column: targetTok.Column},
 					}, nil
 				}
 			}
@@ -2129,6 +2330,7 @@ func (p *Parser) parseStatement() (ast.Statement, error) {
 			return ast.AssignStatement{
 				Target: varName,
 				Value:  value,
+				Pos:    ast.Pos{Line: targetTok.Line, Column: targetTok.Column},
 			}, nil
 		}
 
@@ -2142,6 +2344,19 @@ func (p *Parser) parseStatement() (ast.Statement, error) {
 			return ast.ReturnStatement{Value: value}, nil
 		}
 
+		// Check for "background { ... }" or "defer! { ... }"
+		if p.current().Literal == "background" && p.peek(1).Type == LBRACE {
+			return p.parseBackgroundStatement(1)
+		}
+		if p.current().Literal == "defer" && p.peek(1).Type == BANG && p.peek(2).Type == LBRACE {
+			return p.parseBackgroundStatement(2)
+		}
+
+		// Check for "try { ... } catch (e) { ... }"
+		if p.current().Literal == "try" && p.peek(1).Type == LBRACE {
+			return p.parseTryStatement()
+		}
+
 		// Check for "yield" keyword (SSE event emission)
 		if p.current().Literal == "yield" {
 			p.advance() // consume "yield"
@@ -2152,11 +2367,13 @@ func (p *Parser) parseStatement() (ast.Statement, error) {
 			return ast.YieldStatement{Value: value}, nil
 		}
 
-		// Speculative parse for index assignment: identifier[index] = expr
-		// If the next token is LBRACKET, try to parse an l-value chain followed by "=".
-		// On failure (parse error or no "=" after l-value), restore position and fall
-		// through to other statement paths (bare assignment, expression statement).
-		if p.peek(1).Type == LBRACKET {
+		// Speculative parse for field and index assignment: identifier[index] = expr,
+		// identifier.field = expr and chains of both such as items[0].name = expr.
+		// If the next token is LBRACKET or DOT, try to parse an l-value chain followed
+		// by "=". On failure (parse error or no "=" after l-value, as in a method call),
+		// restore position and fall through to other statement paths (bare assignment,
+		// expression statement).
+		if p.peek(1).Type == LBRACKET || p.peek(1).Type == DOT {
 			savedPos := p.position
 			name := p.current().Literal
 			p.advance() // consume identifier
@@ -2230,9 +2447,129 @@ func (p *Parser) parseStatement() (ast.Statement, error) {
 	}
 }
 
+// parseBackgroundStatement parses a background block: background { ... } or
+// defer! { ... }. keywordTokens is the number of tokens before the brace.
+func (p *Parser) parseBackgroundStatement(keywordTokens int) (ast.Statement, error) {
+	tok := p.current()
+	for n := 0; n < keywordTokens; n++ {
+		p.advance()
+	}
+
+	if err := p.expect(LBRACE); err != nil {
+		return nil, err
+	}
+
+	p.skipNewlines()
+
+	var body []ast.Statement
+	for !p.check(RBRACE) && !p.isAtEnd() {
+		p.skipNewlines()
+		if p.check(RBRACE) {
+			break
+		}
+
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		body = append(body, stmt)
+
+		p.skipNewlines()
+	}
+
+	if err := p.expect(RBRACE); err != nil {
+		return nil, err
+	}
+
+	return ast.BackgroundStatement{
+		Body: body,
+		Pos:  ast.Pos{Line: tok.Line, Column: tok.Column},
+	}, nil
+}
+
+// parseTryStatement parses try { ... } catch (e) { ... }. The parentheses
+// around the error variable are optional, and so is the variable:
+// try { ... } catch { ... } discards the error.
+func (p *Parser) parseTryStatement() (ast.Statement, error) {
+	tok := p.current()
+	p.advance() // consume "try"
+
+	body, err := p.parseStatementBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipNewlines()
+	if !p.check(IDENT) || p.current().Literal != "catch" {
+		return nil, p.errorWithHint(
+			fmt.Sprintf("Expected 'catch' after try block, but found %s", p.current().Type),
+			p.current(),
+			"Handle the error with: try { ... } catch (e) { ... }",
+		)
+	}
+	p.advance() // consume "catch"
+
+	var errorVar string
+	if p.match(LPAREN) {
+		if errorVar, err = p.expectIdent(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(RPAREN); err != nil {
+			return nil, err
+		}
+	} else if p.check(IDENT) {
+		errorVar = p.current().Literal
+		p.advance()
+	}
+
+	p.skipNewlines()
+	catchBlock, err := p.parseStatementBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.TryStatement{
+		Body:     body,
+		ErrorVar: errorVar,
+		Catch:    catchBlock,
+		Pos:      ast.Pos{Line: tok.Line, Column: tok.Column},
+	}, nil
+}
+
+// parseStatementBlock parses the statements of a { ... } block
+func (p *Parser) parseStatementBlock() ([]ast.Statement, error) {
+	if err := p.expect(LBRACE); err != nil {
+		return nil, err
+	}
+
+	p.skipNewlines()
+
+	var block []ast.Statement
+	for !p.check(RBRACE) && !p.isAtEnd() {
+		p.skipNewlines()
+		if p.check(RBRACE) {
+			break
+		}
+
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		block = append(block, stmt)
+
+		p.skipNewlines()
+	}
+
+	if err := p.expect(RBRACE); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
 // parseReassignment parses a simple variable reassignment: identifier = expr
 // Note: Field reassignment (obj.field = expr) uses the $ syntax: $ obj.field = expr
 func (p *Parser) parseReassignment() (ast.Statement, error) {
+	targetTok := p.current()
 	varName, err := p.expectIdent()
 	if err != nil {
 		return nil, err
@@ -2250,6 +2587,7 @@ func (p *Parser) parseReassignment() (ast.Statement, error) {
 	return ast.ReassignStatement{
 		Target: varName,
 		Value:  value,
+		Pos:    ast.Pos{Line: targetTok.Line, Column: targetTok.Column},
 	}, nil
 }
 
@@ -2615,17 +2953,56 @@ func (p *Parser) parseExpr() (ast.Expr, error) {
 	return p.parsePipeExpr()
 }
 
+// parseTernaryExpr parses conditional expressions: cond ? then : else.
+// They bind more loosely than every binary operator and are
+// right-associative: a ? b : c ? d : e parses as a ? b : (c ? d : e)
+func (p *Parser) parseTernaryExpr() (ast.Expr, error) {
+	condition, err := p.parseBinaryExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.check(QUESTION) {
+		return condition, nil
+	}
+
+	tok := p.current()
+	p.advance() // consume ?
+	thenExpr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.check(COLON) {
+		return nil, p.errorWithHint(
+			fmt.Sprintf("Expected ':' in conditional expression, but found %s", p.current().Type),
+			p.current(),
+			"A conditional expression needs both branches: cond ? a : b",
+		)
+	}
+	p.advance() // consume :
+	elseExpr, err := p.parseTernaryExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.TernaryExpr{
+		Condition: condition,
+		Then:      thenExpr,
+		Else:      elseExpr,
+		Pos:       ast.Pos{Line: tok.Line, Column: tok.Column},
+	}, nil
+}
+
 // parsePipeExpr parses pipe expressions (|>) with the lowest precedence
 // Pipes are left-associative: a |> b |> c parses as ((a |> b) |> c)
 func (p *Parser) parsePipeExpr() (ast.Expr, error) {
-	left, err := p.parseBinaryExpr(0)
+	left, err := p.parseTernaryExpr()
 	if err != nil {
 		return nil, err
 	}
 
 	for p.current().Type == PIPE_OP {
 		p.advance() // consume |>
-		right, err := p.parseBinaryExpr(0)
+		right, err := p.parseTernaryExpr()
 		if err != nil {
 			return nil, err
 		}
@@ -2699,6 +3076,8 @@ func (p *Parser) currentBinaryOp() (ast.BinOp, int) {
 		return ast.And, 3
 	case OR:
 		return ast.Or, 2
+	case COALESCE:
+		return ast.Coalesce, 1
 	default:
 		return ast.BinOp(-1), -1
 	}
@@ -2781,6 +3160,8 @@ func (p *Parser) currentCommandDefaultBinaryOp() (ast.BinOp, int) {
 		return ast.And, 3
 	case OR:
 		return ast.Or, 2
+	case COALESCE:
+		return ast.Coalesce, 1
 	default:
 		return ast.BinOp(-1), -1
 	}
@@ -2823,11 +3204,76 @@ func (p *Parser) parseUnary() (ast.Expr, error) {
 	return p.parsePrimary()
 }
 
+// parseInterpolatedString parses "text{expr}text...", lexed as STRING_START,
+// the tokens of each expression separated by STRING_MIDDLE, and STRING_END.
+// Empty text between expressions is left out of the parts.
+func (p *Parser) parseInterpolatedString() (ast.Expr, error) {
+	start := p.current()
+	var parts []ast.Expr
+	addText := func(text string) {
+		if text != "" {
+			parts = append(parts, ast.LiteralExpr{Value: ast.StringLiteral{Value: text}})
+		}
+	}
+	addText(start.Literal)
+	p.advance() // consume STRING_START
+
+	for {
+		if p.check(STRING_MIDDLE) || p.check(STRING_END) {
+			return nil, p.errorWithHint(
+				"Expected an expression inside {} in string",
+				p.current(),
+				"Write \\{ for a literal brace in a string",
+			)
+		}
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, expr)
+
+		tok := p.current()
+		switch tok.Type {
+		case STRING_MIDDLE:
+			addText(tok.Literal)
+			p.advance()
+		case STRING_END:
+			addText(tok.Literal)
+			p.advance()
+			return ast.InterpolatedStringExpr{
+				Parts: parts,
+				Pos:   ast.Pos{Line: start.Line, Column: start.Column},
+			}, nil
+		default:
+			return nil, p.errorWithHint(
+				fmt.Sprintf("Expected '}' after expression in string, but found %s", tok.Type),
+				tok,
+				"Each { in a string starts an expression that must end with }",
+			)
+		}
+	}
+}
+
+// parseIntLiteral converts the current INTEGER token to an int64, reporting
+// literals outside the int range instead of wrapping them
+func (p *Parser) parseIntLiteral() (int64, error) {
+	tok := p.current()
+	n, err := strconv.ParseInt(tok.Literal, 10, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, p.errorWithHint(
+			fmt.Sprintf("Integer literal %s is out of range for int (%d to %d)", tok.Literal, int64(math.MinInt64), int64(math.MaxInt64)),
+			tok,
+			fmt.Sprintf("Use a float literal such as %s.0 for larger numbers", tok.Literal),
+		)
+	}
+	return n, err
+}
+
 // parsePrimary parses a primary expression
 func (p *Parser) parsePrimary() (ast.Expr, error) {
 	switch p.current().Type {
 	case INTEGER:
-		n, err := strconv.ParseInt(p.current().Literal, 10, 64)
+		n, err := p.parseIntLiteral()
 		if err != nil {
 			return nil, err
 		}
@@ -2847,6 +3293,9 @@ func (p *Parser) parsePrimary() (ast.Expr, error) {
 		p.advance()
 		return ast.LiteralExpr{Value: ast.StringLiteral{Value: s}}, nil
 
+	case STRING_START:
+		return p.parseInterpolatedString()
+
 	case TRUE:
 		p.advance()
 		return ast.LiteralExpr{Value: ast.BoolLiteral{Value: true}}, nil
@@ -2865,41 +3314,33 @@ func (p *Parser) parsePrimary() (ast.Expr, error) {
 		identPos := ast.Pos{Line: identTok.Line, Column: identTok.Column}
 		p.advance()
 
-		// Check for field access: a.b.c
-		if p.check(DOT) {
+		// Check for field access: a.b.c or a?.b
+		if p.check(DOT) || p.check(QUESTION_DOT) {
 			return p.parseFieldAccess(name)
 		}
 
-		// Check for array indexing: a[0]
+		// Check for array indexing: a[0], a[0].b
 		if p.check(LBRACKET) {
-			return p.parseArrayIndex(ast.VariableExpr{Name: name, Pos: identPos})
+			indexed, err := p.parseArrayIndex(ast.VariableExpr{Name: name, Pos: identPos})
+			if err != nil {
+				return nil, err
+			}
+			return p.parseFieldChain(indexed)
 		}
 
 		// Check for function call: f(...)
 		if p.check(LPAREN) {
 			p.advance()
-			var args []ast.Expr
-
-			for !p.check(RPAREN) && !p.isAtEnd() {
-				arg, err := p.parseExpr()
-				if err != nil {
-					return nil, err
-				}
-				args = append(args, arg)
-
-				if !p.match(COMMA) {
-					break
-				}
-			}
-
-			if err := p.expect(RPAREN); err != nil {
+			args, named, err := p.parseCallArgs()
+			if err != nil {
 				return nil, err
 			}
 
 			return ast.FunctionCallExpr{
-				Name: name,
-				Args: args,
-				Pos:  identPos,
+				Name:      name,
+				Args:      args,
+				NamedArgs: named,
+				Pos:       identPos,
 			}, nil
 		}
 
@@ -2919,6 +3360,20 @@ func (p *Parser) parsePrimary() (ast.Expr, error) {
 				break
 			}
 
+			// Spread syntax: ...base
+			if p.check(DOTDOTDOT) {
+				spread, err := p.parseSpread()
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, ast.ObjectField{Value: spread})
+				if !p.match(COMMA) {
+					break
+				}
+				p.skipNewlines()
+				continue
+			}
+
 			var fieldName string
 			var err error
 
@@ -2934,7 +3389,7 @@ func (p *Parser) parsePrimary() (ast.Expr, error) {
 				}
 			} else {
 				// Standard syntax: field: value
-				fieldName, err = p.expectIdent()
+				fieldName, err = p.expectFieldName()
 				if err != nil {
 					return nil, err
 				}
@@ -2968,6 +3423,10 @@ func (p *Parser) parsePrimary() (ast.Expr, error) {
 		return ast.ObjectExpr{Fields: fields}, nil
 
 	case LPAREN:
+		if p.isLambdaStart() {
+			return p.parseLambda()
+		}
+
 		// Grouped expression
 		p.advance()
 		expr, err := p.parseExpr()
@@ -2993,7 +3452,13 @@ func (p *Parser) parsePrimary() (ast.Expr, error) {
 				break
 			}
 
-			element, err := p.parseExpr()
+			var element ast.Expr
+			var err error
+			if p.check(DOTDOTDOT) {
+				element, err = p.parseSpread()
+			} else {
+				element, err = p.parseExpr()
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -3030,6 +3495,160 @@ func (p *Parser) parsePrimary() (ast.Expr, error) {
 	}
 }
 
+// isLambdaStart reports whether the paren at the current token opens the
+// parameter list of a lambda: it holds nothing or starts with a name that
+// is followed by a comma, colon or the closing paren, and its matching
+// paren is followed by =>
+func (p *Parser) isLambdaStart() bool {
+	if p.position == p.guardStart {
+		return false
+	}
+	if p.peek(1).Type != RPAREN {
+		if p.peek(1).Type != IDENT {
+			return false
+		}
+		switch p.peek(2).Type {
+		case COMMA, COLON, RPAREN:
+		default:
+			return false
+		}
+	}
+	depth := 0
+	for offset := 0; ; offset++ {
+		switch p.peek(offset).Type {
+		case LPAREN:
+			depth++
+		case RPAREN:
+			depth--
+			if depth == 0 {
+				return p.peek(offset+1).Type == FATARROW
+			}
+		case EOF, NEWLINE:
+			return false
+		}
+	}
+}
+
+// parseLambda parses a lambda: (a, b: int) => expr, or with a block body
+// (a) => { statements }. A body of { followed by a key and a colon is an
+// object literal, as in (a) => {value: a}.
+func (p *Parser) parseLambda() (ast.Expr, error) {
+	if err := p.expect(LPAREN); err != nil {
+		return nil, err
+	}
+
+	var params []ast.Field
+	for !p.check(RPAREN) && !p.isAtEnd() {
+		name, err := p.expectIdent()
+		if err != nil {
+			return nil, err
+		}
+		param := ast.Field{Name: name}
+		if p.match(COLON) {
+			if param.TypeAnnotation, param.Required, err = p.parseType(); err != nil {
+				return nil, err
+			}
+		}
+		params = append(params, param)
+
+		if !p.match(COMMA) {
+			break
+		}
+	}
+	if err := p.expect(RPAREN); err != nil {
+		return nil, err
+	}
+	if err := p.expect(FATARROW); err != nil {
+		return nil, err
+	}
+
+	if p.check(LBRACE) && !p.isObjectLiteralStart() {
+		block, err := p.parseStatementBlock()
+		if err != nil {
+			return nil, err
+		}
+		return ast.LambdaExpr{Params: params, Block: block}, nil
+	}
+
+	body, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return ast.LambdaExpr{Params: params, Body: body}, nil
+}
+
+// isObjectLiteralStart reports whether the brace at the current token opens
+// an object literal rather than a block: it is empty or starts with a
+// spread, a :key shorthand or a key followed by a colon
+func (p *Parser) isObjectLiteralStart() bool {
+	offset := 1
+	for p.peek(offset).Type == NEWLINE {
+		offset++
+	}
+	switch p.peek(offset).Type {
+	case RBRACE, DOTDOTDOT, COLON:
+		return true
+	}
+	return p.peek(offset+1).Type == COLON
+}
+
+// parseCallArgs parses the arguments of a call up to and including its
+// closing paren. Positional arguments come first, then arguments named by
+// their parameter, e.g. f(1, y: 2); each name may be given once.
+func (p *Parser) parseCallArgs() ([]ast.Expr, []ast.NamedArg, error) {
+	var args []ast.Expr
+	var named []ast.NamedArg
+	seen := make(map[string]bool)
+
+	for !p.check(RPAREN) && !p.isAtEnd() {
+		if p.check(IDENT) && p.peek(1).Type == COLON {
+			nameTok := p.current()
+			if seen[nameTok.Literal] {
+				return nil, nil, p.errorWithHint(
+					fmt.Sprintf("Duplicate named argument '%s'", nameTok.Literal),
+					nameTok,
+					"Each parameter may be named once per call",
+				)
+			}
+			seen[nameTok.Literal] = true
+			p.advance()
+			p.advance()
+
+			value, err := p.parseExpr()
+			if err != nil {
+				return nil, nil, err
+			}
+			named = append(named, ast.NamedArg{
+				Name:  nameTok.Literal,
+				Value: value,
+				Pos:   ast.Pos{Line: nameTok.Line, Column: nameTok.Column},
+			})
+		} else {
+			if len(named) > 0 {
+				return nil, nil, p.errorWithHint(
+					"Positional argument after named arguments",
+					p.current(),
+					"Pass positional arguments first, e.g. f(1, y: 2)",
+				)
+			}
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, nil, err
+			}
+			args = append(args, arg)
+		}
+
+		if !p.match(COMMA) {
+			break
+		}
+	}
+
+	if err := p.expect(RPAREN); err != nil {
+		return nil, nil, err
+	}
+	return args, named, nil
+}
+
 // parseAsyncExpr parses an async block: async { statements }
 func (p *Parser) parseAsyncExpr() (ast.Expr, error) {
 	// Consume "async" keyword
@@ -3085,16 +3704,35 @@ func (p *Parser) parseAwaitExpr() (ast.Expr, error) {
 	return ast.AwaitExpr{Expr: expr}, nil
 }
 
-// parseFieldAccess parses field access: obj.field or obj.field.subfield
+// parseSpread parses ...value in an array or object literal
+func (p *Parser) parseSpread() (ast.Expr, error) {
+	tok := p.current()
+	p.advance()
+	value, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return ast.SpreadExpr{Value: value, Pos: ast.Pos{Line: tok.Line, Column: tok.Column}}, nil
+}
+
+// parseFieldAccess parses field access: obj.field or obj.field.subfield.
+// obj?.field is optional access, which gives null when obj is null.
 func (p *Parser) parseFieldAccess(base string) (ast.Expr, error) {
-	var object ast.Expr = ast.VariableExpr{Name: base}
+	// The base identifier was just consumed by the caller
+	baseTok := p.tokens[p.position-1]
+	return p.parseFieldChain(ast.VariableExpr{Name: base, Pos: ast.Pos{Line: baseTok.Line, Column: baseTok.Column}})
+}
 
-	for p.match(DOT) {
-		// The dot token was just consumed by match; retrieve it for position info
-		dotTok := p.tokens[p.position-1]
+// parseFieldChain parses .field, ?.field, method calls and [index] suffixes
+// applied to object
+func (p *Parser) parseFieldChain(object ast.Expr) (ast.Expr, error) {
+	for p.check(DOT) || p.check(QUESTION_DOT) {
+		dotTok := p.current()
 		dotPos := ast.Pos{Line: dotTok.Line, Column: dotTok.Column}
+		optional := dotTok.Type == QUESTION_DOT
+		p.advance()
 
-		field, err := p.expectIdent()
+		field, err := p.expectFieldName()
 		if err != nil {
 			return nil, err
 		}
@@ -3102,23 +3740,18 @@ func (p *Parser) parseFieldAccess(base string) (ast.Expr, error) {
 		// Method calls are currently treated as function calls on the field.
 		// Proper method dispatch with receiver binding is not yet implemented.
 		if p.check(LPAREN) {
+			if optional {
+				return nil, p.errorWithHint(
+					"Optional chaining is not supported on method calls",
+					dotTok,
+					"Use ?. on fields only, e.g. user?.profile?.bio",
+				)
+			}
 			p.advance()
-			var args []ast.Expr
 
 			// Parse actual arguments (not including the object)
-			for !p.check(RPAREN) && !p.isAtEnd() {
-				arg, err := p.parseExpr()
-				if err != nil {
-					return nil, err
-				}
-				args = append(args, arg)
-
-				if !p.match(COMMA) {
-					break
-				}
-			}
-
-			if err := p.expect(RPAREN); err != nil {
+			args, named, err := p.parseCallArgs()
+			if err != nil {
 				return nil, err
 			}
 
@@ -3126,28 +3759,31 @@ func (p *Parser) parseFieldAccess(base string) (ast.Expr, error) {
 			// If so, create a qualified function name (ws.method_name)
 			// This is needed for built-in namespaced functions
 			if varExpr, ok := object.(ast.VariableExpr); ok {
-				return ast.FunctionCallExpr{
-					Name: varExpr.Name + "." + field,
-					Args: args,
-					Pos:  dotPos,
-				}, nil
+				object = ast.FunctionCallExpr{
+					Name:      varExpr.Name + "." + field,
+					Args:      args,
+					NamedArgs: named,
+					Pos:       dotPos,
+				}
+			} else {
+				// For complex objects, add object as first arg
+				allArgs := make([]ast.Expr, 0, len(args)+1)
+				allArgs = append(allArgs, object)
+				allArgs = append(allArgs, args...)
+
+				object = ast.FunctionCallExpr{
+					Name:      field,
+					Args:      allArgs,
+					NamedArgs: named,
+					Pos:       dotPos,
+				}
 			}
-
-			// For complex objects, add object as first arg
-			allArgs := make([]ast.Expr, 0, len(args)+1)
-			allArgs = append(allArgs, object)
-			allArgs = append(allArgs, args...)
-
-			return ast.FunctionCallExpr{
-				Name: field,
-				Args: allArgs,
-				Pos:  dotPos,
-			}, nil
 		} else {
 			object = ast.FieldAccessExpr{
-				Object: object,
-				Field:  field,
-				Pos:    dotPos,
+				Object:   object,
+				Field:    field,
+				Optional: optional,
+				Pos:      dotPos,
 			}
 		}
 
@@ -3179,7 +3815,7 @@ func (p *Parser) parseLValueExpr(base ast.Expr) (ast.Expr, error) {
 			expr = ast.ArrayIndexExpr{Array: expr, Index: index}
 		} else if p.check(DOT) {
 			p.advance()
-			field, err := p.expectIdent()
+			field, err := p.expectFieldName()
 			if err != nil {
 				return nil, err
 			}
@@ -3292,6 +3928,17 @@ func (p *Parser) expectIdent() (string, error) {
 	return ident, nil
 }
 
+// expectFieldName consumes an object key or field name. Keywords are
+// allowed, so objects can have fields such as from or match.
+func (p *Parser) expectFieldName() (string, error) {
+	tok := p.current()
+	if tok.Type != IDENT && tok.Literal != "" && isIdentifierStart(tok.Literal[0]) {
+		p.advance()
+		return tok.Literal, nil
+	}
+	return p.expectIdent()
+}
+
 func (p *Parser) skipNewlines() {
 	for p.match(NEWLINE) {
 		// keep skipping
@@ -3302,7 +3949,7 @@ func (p *Parser) skipNewlines() {
 // If generic type parameters or parentheses are present, it's a function. Otherwise, it's a CLI command.
 // Examples:
 //
-//	! hello name: str! --greeting: str = "Hello"  (command)
+//	! hello name: str! --greeting: str = "Hello" "Greeting to use"  (command)
 //	! map<T, U>(arr: [T], fn: (T) -> U): [U] { body }  (generic function)
 //	! double(x: int): int { ... }  (regular function)
 func (p *Parser) parseCommand() (ast.Item, error) {
@@ -3382,6 +4029,12 @@ func (p *Parser) parseCommand() (ast.Item, error) {
 			param.Default = defaultValue
 		}
 
+		// Optional help text: name: str! "Who to greet"
+		if p.check(STRING) {
+			param.Description = p.current().Literal
+			p.advance()
+		}
+
 		params = append(params, param)
 		p.skipNewlines()
 	}
@@ -3635,6 +4288,7 @@ func (p *Parser) parseCronTask() (ast.Item, error) {
 	var injections []ast.Injection
 	var body []ast.Statement
 	var retries int
+	var overlap string
 
 	if p.check(LBRACE) {
 		p.advance()
@@ -3662,7 +4316,7 @@ func (p *Parser) parseCronTask() (ast.Item, error) {
 				})
 
 			case PLUS:
-				// Middleware-like config: + retries(3)
+				// Middleware-like config: + retries(3), + overlap(queue)
 				p.advance()
 				configName, _ := p.expectIdent()
 				if configName == "retries" && p.check(LPAREN) {
@@ -3674,6 +4328,20 @@ func (p *Parser) parseCronTask() (ast.Item, error) {
 					}
 					p.expect(RPAREN)
 				}
+				if configName == "overlap" && p.check(LPAREN) {
+					p.advance()
+					// Checked by literal: expanded syntax lexes queue as a keyword
+					if lit := p.current().Literal; lit != "skip" && lit != "queue" {
+						return nil, p.errorWithHint(
+							"Expected skip or queue for cron overlap",
+							p.current(),
+							"Example: + overlap(queue)",
+						)
+					}
+					overlap = p.current().Literal
+					p.advance()
+					p.expect(RPAREN)
+				}
 
 			case DOLLAR, GREATER:
 				stmt, err := p.parseStatement()
@@ -3710,6 +4378,7 @@ func (p *Parser) parseCronTask() (ast.Item, error) {
 		Schedule:   schedule,
 		Timezone:   timezone,
 		Retries:    retries,
+		Overlap:    overlap,
 		Injections: injections,
 		Body:       body,
 	}, nil
@@ -4998,7 +5667,10 @@ func (p *Parser) parseMatchExpr() (ast.Expr, error) {
 		var guard ast.Expr
 		if p.check(WHEN) {
 			p.advance()
+			savedGuardStart := p.guardStart
+			p.guardStart = p.position
 			guard, err = p.parseExpr()
+			p.guardStart = savedGuardStart
 			if err != nil {
 				return nil, err
 			}
@@ -5051,7 +5723,7 @@ func (p *Parser) parsePattern() (ast.Pattern, error) {
 	switch p.current().Type {
 	case INTEGER:
 		// Literal integer pattern
-		n, err := strconv.ParseInt(p.current().Literal, 10, 64)
+		n, err := p.parseIntLiteral()
 		if err != nil {
 			return nil, err
 		}
@@ -5092,6 +5764,15 @@ func (p *Parser) parsePattern() (ast.Pattern, error) {
 			p.advance()
 			return ast.WildcardPattern{}, nil
 		}
+		// Type pattern: Error {message}
+		if p.peek(1).Type == LBRACE {
+			p.advance()
+			object, err := p.parseObjectPattern()
+			if err != nil {
+				return nil, err
+			}
+			return ast.TypePattern{TypeName: name, Fields: object.(ast.ObjectPattern).Fields}, nil
+		}
 		// Variable binding pattern
 		p.advance()
 		return ast.VariablePattern{Name: name}, nil
@@ -5108,7 +5789,7 @@ func (p *Parser) parsePattern() (ast.Pattern, error) {
 		return nil, p.errorWithHint(
 			fmt.Sprintf("Unexpected token in pattern: %s", p.current().Type),
 			p.current(),
-			"Patterns can be literals, variables, _ (wildcard), {fields}, or [elements]",
+			"Patterns can be literals, variables, _ (wildcard), {fields}, Type {fields}, or [elements]",
 		)
 	}
 }
@@ -5249,19 +5930,21 @@ func (p *Parser) ParseExpression() (ast.Expr, error) {
 	return expr, nil
 }
 
-// parseTestBlock parses a test block: test "name" { body }
+// parseTestBlock parses a test block: test "name" { body } or, in compact
+// syntax, test! "name" { body }
 // "test" is parsed as an IDENT (not a dedicated token) to avoid conflicts with
 // identifiers like /test in route paths.
 func (p *Parser) parseTestBlock() (ast.Item, error) {
-	// Consume "test" identifier
+	// Consume "test" identifier and the optional !
 	p.advance()
+	p.match(BANG)
 
 	// Parse test name (string literal)
 	if !p.check(STRING) {
 		return nil, p.errorWithHint(
 			fmt.Sprintf("Expected test name (string), got %s", p.current().Type),
 			p.current(),
-			"Test blocks must have a name: test \"description\" { ... }",
+			"Test blocks must have a name: test \"description\" { ... } or test! \"description\" { ... }",
 		)
 	}
 	name := p.current().Literal
@@ -5296,6 +5979,39 @@ func (p *Parser) parseTestBlock() (ast.Item, error) {
 	}, nil
 }
 
+// parseSeedBlock parses a seed block: seed! name { body } or, in expanded
+// syntax, seed name { body }. The name is optional.
+func (p *Parser) parseSeedBlock() (ast.Item, error) {
+	// Consume "seed" identifier and the optional !
+	p.advance()
+	p.match(BANG)
+
+	var name string
+	if p.check(IDENT) {
+		name = p.current().Literal
+		p.advance()
+	}
+
+	p.skipNewlines()
+	if !p.check(LBRACE) {
+		return nil, p.errorWithHint(
+			fmt.Sprintf("Expected '{' to start seed block, got %s", p.current().Type),
+			p.current(),
+			"Seed blocks look like: seed! users { ... }",
+		)
+	}
+
+	body, err := p.parseStatementBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.SeedBlock{
+		Name: name,
+		Body: body,
+	}, nil
+}
+
 // parseAssertStatement parses an assert statement: assert(condition) or assert(condition, "message")
 func (p *Parser) parseAssertStatement() (ast.Statement, error) {
 	if err := p.expect(ASSERT); err != nil {
@@ -5353,3 +6069,26 @@ func (p *Parser) ParseStatement() (ast.Statement, error) {
 
 	return stmt, nil
 }
+
+// ParseType parses a single type annotation, such as "[int]" or "str | null",
+// from the token stream. Compiled functions use it to check their arguments
+// and results at runtime.
+func (p *Parser) ParseType() (ast.Type, error) {
+	p.skipNewlines()
+
+	if p.isAtEnd() {
+		return nil, fmt.Errorf("unexpected end of input")
+	}
+
+	typ, _, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipNewlines()
+	if !p.isAtEnd() {
+		return nil, fmt.Errorf("unexpected %s after type", p.current().Literal)
+	}
+
+	return typ, nil
+}
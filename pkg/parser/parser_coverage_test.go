@@ -1083,6 +1083,41 @@ func TestParseMethodCall(t *testing.T) {
 	}
 }
 
+func TestParseChainedMethodCall(t *testing.T) {
+	source := `@ GET /chained {
+  $ user = db.table("users").get(id)
+  > user
+}`
+
+	lexer := NewLexer(source)
+	tokens, err := lexer.Tokenize()
+	if err != nil {
+		t.Fatalf("lexer error: %v", err)
+	}
+
+	parser := NewParser(tokens)
+	module, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("parser error: %v", err)
+	}
+
+	route := module.Items[0].(*ast.Route)
+	assign := route.Body[0].(ast.AssignStatement)
+
+	// The receiver of get is the result of db.table("users")
+	callExpr, ok := assign.Value.(ast.FunctionCallExpr)
+	if !ok {
+		t.Fatalf("expected FunctionCallExpr, got %T", assign.Value)
+	}
+	if callExpr.Name != "get" || len(callExpr.Args) != 2 {
+		t.Fatalf("expected get(receiver, id), got %s with %d args", callExpr.Name, len(callExpr.Args))
+	}
+	receiver, ok := callExpr.Args[0].(ast.FunctionCallExpr)
+	if !ok || receiver.Name != "db.table" {
+		t.Errorf("expected db.table(...) receiver, got %#v", callExpr.Args[0])
+	}
+}
+
 func TestParseArrayIndex(t *testing.T) {
 	source := `@ GET /index {
   $ first = arr[0]
@@ -625,7 +625,7 @@ func TestParser_ReturnTypes(t *testing.T) {
 		returnTypeStr string
 		expectedType  ast.Type
 	}{
-		{"named type", "-> User", ast.NamedType{Name: "User"}},
+		{"named type", "-> User", ast.NamedType{Name: "User", Pos: ast.Pos{Line: 1, Column: 16}}},
 		{"int type", "-> int", ast.IntType{}},
 		{"str type", "-> str", ast.StringType{}},
 		{"bool type", "-> bool", ast.BoolType{}},
@@ -1975,6 +1975,33 @@ func TestParser_CronTask_WithRetries(t *testing.T) {
 	assert.Equal(t, 3, task.Retries)
 }
 
+func TestParser_CronTask_WithOverlap(t *testing.T) {
+	source := `* "*/5 * * * *" sync {
+  + overlap(queue)
+  > {status: "synced"}
+}`
+
+	lexer := NewLexer(source)
+	tokens, err := lexer.Tokenize()
+	require.NoError(t, err)
+
+	parser := NewParser(tokens)
+	module, err := parser.Parse()
+	require.NoError(t, err)
+
+	task, ok := module.Items[0].(*ast.CronTask)
+	require.True(t, ok)
+	assert.Equal(t, "queue", task.Overlap)
+
+	tokens, err = NewLexer(`* "*/5 * * * *" sync {
+  + overlap(parallel)
+  > {status: "synced"}
+}`).Tokenize()
+	require.NoError(t, err)
+	_, err = NewParser(tokens).Parse()
+	assert.ErrorContains(t, err, "Expected skip or queue")
+}
+
 // Test Event Handler Directive (~ syntax)
 func TestParser_EventHandler_TildeSyntax(t *testing.T) {
 	source := `~ "user.created" {
@@ -2404,6 +2431,94 @@ func TestParser_ArrayIndexing(t *testing.T) {
 	}
 }
 
+// TestParser_IndexExpressionShapes checks the trees built for index
+// expressions, including field access on an indexed element
+func TestParser_IndexExpressionShapes(t *testing.T) {
+	parseValue := func(t *testing.T, expr string) ast.Expr {
+		t.Helper()
+		tokens, err := NewLexer("@ GET /test {\n  $ v = " + expr + "\n  > v\n}").Tokenize()
+		require.NoError(t, err)
+		module, err := NewParser(tokens).Parse()
+		require.NoError(t, err)
+		route := module.Items[0].(*ast.Route)
+		return route.Body[0].(ast.AssignStatement).Value
+	}
+
+	t.Run("negative index", func(t *testing.T) {
+		index, ok := parseValue(t, "arr[-1]").(ast.ArrayIndexExpr)
+		require.True(t, ok)
+		assert.Equal(t, ast.VariableExpr{Name: "arr", Pos: ast.Pos{Line: 2, Column: 9}}, index.Array)
+		unary, ok := index.Index.(ast.UnaryOpExpr)
+		require.True(t, ok)
+		assert.Equal(t, ast.Neg, unary.Op)
+	})
+
+	t.Run("string key", func(t *testing.T) {
+		index, ok := parseValue(t, `obj["key"]`).(ast.ArrayIndexExpr)
+		require.True(t, ok)
+		assert.Equal(t, ast.LiteralExpr{Value: ast.StringLiteral{Value: "key"}}, index.Index)
+	})
+
+	t.Run("field of an indexed element", func(t *testing.T) {
+		field, ok := parseValue(t, "users[0].name").(ast.FieldAccessExpr)
+		require.True(t, ok)
+		assert.Equal(t, "name", field.Field)
+		_, ok = field.Object.(ast.ArrayIndexExpr)
+		assert.True(t, ok)
+	})
+
+	t.Run("index after a field of an indexed element", func(t *testing.T) {
+		index, ok := parseValue(t, "users[0].tags[1]").(ast.ArrayIndexExpr)
+		require.True(t, ok)
+		field, ok := index.Array.(ast.FieldAccessExpr)
+		require.True(t, ok)
+		assert.Equal(t, "tags", field.Field)
+	})
+}
+
+func TestParser_AssignmentTargets(t *testing.T) {
+	parseStatement := func(t *testing.T, stmt string) ast.Statement {
+		t.Helper()
+		tokens, err := NewLexer("@ PUT /test {\n  " + stmt + "\n  > null\n}").Tokenize()
+		require.NoError(t, err)
+		module, err := NewParser(tokens).Parse()
+		require.NoError(t, err)
+		return module.Items[0].(*ast.Route).Body[0]
+	}
+
+	t.Run("field", func(t *testing.T) {
+		assign, ok := parseStatement(t, `user.name = "ada"`).(ast.IndexAssignStatement)
+		require.True(t, ok)
+		field, ok := assign.Target.(ast.FieldAccessExpr)
+		require.True(t, ok)
+		assert.Equal(t, "name", field.Field)
+		assert.Equal(t, "user", field.Object.(ast.VariableExpr).Name)
+	})
+
+	t.Run("nested field with a keyword name", func(t *testing.T) {
+		assign, ok := parseStatement(t, `event.meta.type = "updated"`).(ast.IndexAssignStatement)
+		require.True(t, ok)
+		field, ok := assign.Target.(ast.FieldAccessExpr)
+		require.True(t, ok)
+		assert.Equal(t, "type", field.Field)
+		_, ok = field.Object.(ast.FieldAccessExpr)
+		assert.True(t, ok)
+	})
+
+	t.Run("index after a field", func(t *testing.T) {
+		assign, ok := parseStatement(t, `user.tags[0] = "admin"`).(ast.IndexAssignStatement)
+		require.True(t, ok)
+		index, ok := assign.Target.(ast.ArrayIndexExpr)
+		require.True(t, ok)
+		assert.Equal(t, "tags", index.Array.(ast.FieldAccessExpr).Field)
+	})
+
+	t.Run("method call is still an expression", func(t *testing.T) {
+		_, ok := parseStatement(t, `ws.send("hi")`).(ast.ExpressionStatement)
+		assert.True(t, ok)
+	})
+}
+
 // TestParser_RateLimitVariations tests different rate limit formats
 func TestParser_RateLimitVariations(t *testing.T) {
 	tests := []struct {
@@ -2516,6 +2631,39 @@ func TestParser_TypeDefWithoutColon(t *testing.T) {
 	assert.Len(t, typeDef.Fields, 3)
 }
 
+func TestParser_EnumDef(t *testing.T) {
+	for _, source := range []string{
+		`: Status = "pending" | "shipped" | "delivered"`,
+		`type Status = "pending"
+  | "shipped"
+  | "delivered"`,
+	} {
+		tokens, err := NewLexer(source).Tokenize()
+		require.NoError(t, err)
+		module, err := NewParser(tokens).Parse()
+		require.NoError(t, err, source)
+
+		require.Len(t, module.Items, 1)
+		enumDef, ok := module.Items[0].(*ast.EnumDef)
+		require.True(t, ok, "got %T", module.Items[0])
+		assert.Equal(t, "Status", enumDef.Name)
+		assert.Equal(t, []string{"pending", "shipped", "delivered"}, enumDef.Values)
+	}
+}
+
+func TestParser_EnumDefErrors(t *testing.T) {
+	for source, want := range map[string]string{
+		`: Status = "pending" | 3`:         "Expected a string value in enum Status",
+		`: Result = User | Error`:          "Expected a string value in enum Result",
+		`: Status = "pending" | "pending"`: `Duplicate value "pending" in enum Status`,
+	} {
+		tokens, err := NewLexer(source).Tokenize()
+		require.NoError(t, err)
+		_, err = NewParser(tokens).Parse()
+		assert.ErrorContains(t, err, want, source)
+	}
+}
+
 // TestParser_ValidationStatement tests ? validation syntax
 func TestParser_ValidationStatement(t *testing.T) {
 	source := `@ GET /validate {
@@ -3096,6 +3244,40 @@ func TestParser_MatchExpr_ObjectPattern(t *testing.T) {
 	assert.Equal(t, "age", objPattern.Fields[1].Key)
 }
 
+func TestParser_MatchExpr_TypePattern(t *testing.T) {
+	input := `@ GET /test {
+  $ result = match outcome {
+    Error {message, code: c} => message
+    NotFound {} => "missing"
+    value => value
+  }
+  > result
+}`
+
+	lexer := NewLexer(input)
+	tokens, err := lexer.Tokenize()
+	require.NoError(t, err)
+
+	parser := NewParser(tokens)
+	module, err := parser.Parse()
+	require.NoError(t, err)
+
+	route, ok := module.Items[0].(*ast.Route)
+	require.True(t, ok)
+	assignStmt, ok := route.Body[0].(ast.AssignStatement)
+	require.True(t, ok, "expected AssignStatement, got %T", route.Body[0])
+	matchExpr, ok := assignStmt.Value.(ast.MatchExpr)
+	require.True(t, ok)
+	require.Len(t, matchExpr.Cases, 3)
+
+	assert.Equal(t, ast.TypePattern{TypeName: "Error", Fields: []ast.ObjectPatternField{
+		{Key: "message"},
+		{Key: "code", Pattern: ast.VariablePattern{Name: "c"}},
+	}}, matchExpr.Cases[0].Pattern)
+	assert.Equal(t, ast.TypePattern{TypeName: "NotFound"}, matchExpr.Cases[1].Pattern)
+	assert.Equal(t, ast.VariablePattern{Name: "value"}, matchExpr.Cases[2].Pattern)
+}
+
 func TestParser_MatchExpr_ArrayPattern(t *testing.T) {
 	input := `@ GET /test {
   $ result = match arr {
@@ -3340,3 +3522,125 @@ func TestParser_YieldStatement(t *testing.T) {
 	_, ok = route.Body[0].(ast.YieldStatement)
 	assert.True(t, ok, "expected YieldStatement, got %T", route.Body[0])
 }
+
+// Test that declarations and references carry source positions
+func TestParser_Positions(t *testing.T) {
+	source := `: User {
+  name: str!
+}
+
+! greet(u: User): str {
+  $ label = u.name
+  label = label + "!"
+  > label
+}
+
+@ GET /users -> User {
+  > greet(input)
+}
+`
+	tokens, err := NewLexer(source).Tokenize()
+	require.NoError(t, err)
+	module, err := NewParser(tokens).Parse()
+	require.NoError(t, err)
+	require.Len(t, module.Items, 3)
+
+	typeDef := module.Items[0].(*ast.TypeDef)
+	assert.Equal(t, ast.Pos{Line: 1, Column: 1}, typeDef.Pos)
+	assert.Equal(t, ast.Pos{Line: 2, Column: 3}, typeDef.Fields[0].Pos)
+
+	fn := module.Items[1].(*ast.Function)
+	assert.Equal(t, ast.Pos{Line: 5, Column: 1}, fn.Pos)
+	assert.Equal(t, ast.Pos{Line: 5, Column: 9}, fn.Params[0].Pos)
+	assert.Equal(t, ast.NamedType{Name: "User", Pos: ast.Pos{Line: 5, Column: 12}}, fn.Params[0].TypeAnnotation)
+
+	assign := fn.Body[0].(ast.AssignStatement)
+	assert.Equal(t, ast.Pos{Line: 6, Column: 5}, assign.Pos)
+	access := assign.Value.(ast.FieldAccessExpr)
+	assert.Equal(t, ast.Pos{Line: 6, Column: 13}, access.Object.(ast.VariableExpr).Pos)
+
+	reassign := fn.Body[1].(ast.ReassignStatement)
+	assert.Equal(t, ast.Pos{Line: 7, Column: 3}, reassign.Pos)
+
+	route := module.Items[2].(*ast.Route)
+	assert.Equal(t, ast.Pos{Line: 11, Column: 1}, route.Pos)
+	assert.Equal(t, ast.Pos{Line: 11, Column: 17}, route.ReturnType.(ast.NamedType).Pos)
+}
+
+func TestParser_IntegerLiteralRange(t *testing.T) {
+	expr := parseRouteExpr(t, "9223372036854775807")
+	assert.Equal(t, ast.LiteralExpr{Value: ast.IntLiteral{Value: 9223372036854775807}}, expr)
+
+	err := parseSourceExpectError(t, "@ GET /x {\n  > 9223372036854775808\n}")
+	assert.Contains(t, err.Error(), "Integer literal 9223372036854775808 is out of range for int")
+	assert.Contains(t, err.Error(), "9223372036854775808.0")
+}
+
+func TestParser_CommandParamDescription(t *testing.T) {
+	source := `! greet "Greet someone" name: str! "Who to greet" --times: int = 1 "How many times" --loud: bool {
+  > name
+}`
+	lexer := NewLexer(source)
+	tokens, err := lexer.Tokenize()
+	require.NoError(t, err)
+
+	module, err := NewParser(tokens).Parse()
+	require.NoError(t, err)
+	require.Len(t, module.Items, 1)
+
+	cmd, ok := module.Items[0].(*ast.Command)
+	require.True(t, ok)
+	assert.Equal(t, "Greet someone", cmd.Description)
+	require.Len(t, cmd.Params, 3)
+	assert.Equal(t, "Who to greet", cmd.Params[0].Description)
+	assert.Equal(t, "How many times", cmd.Params[1].Description)
+	assert.NotNil(t, cmd.Params[1].Default)
+	assert.Empty(t, cmd.Params[2].Description)
+}
+
+func TestParser_TryStatement(t *testing.T) {
+	parseBody := func(t *testing.T, body string) ([]ast.Statement, error) {
+		t.Helper()
+		tokens, err := NewLexer("@ GET /test {\n" + body + "\n}").Tokenize()
+		require.NoError(t, err)
+		module, err := NewParser(tokens).Parse()
+		if err != nil {
+			return nil, err
+		}
+		return module.Items[0].(*ast.Route).Body, nil
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		errorVar string
+	}{
+		{"parenthesized variable", "try {\n  > 1 / 0\n} catch (e) {\n  > e.message\n}", "e"},
+		{"bare variable on the next line", "try {\n  > 1 / 0\n}\ncatch err {\n  > err.message\n}", "err"},
+		{"no variable", "try { > 1 / 0 } catch { > 0 }", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := parseBody(t, tt.body)
+			require.NoError(t, err)
+			require.Len(t, body, 1)
+			try, ok := body[0].(ast.TryStatement)
+			require.True(t, ok, "got %T", body[0])
+			assert.Len(t, try.Body, 1)
+			assert.Len(t, try.Catch, 1)
+			assert.Equal(t, tt.errorVar, try.ErrorVar)
+		})
+	}
+
+	t.Run("try and catch are still identifiers", func(t *testing.T) {
+		body, err := parseBody(t, "$ try = 1\n$ catch = {try: try}\n> catch.try")
+		require.NoError(t, err)
+		assert.Len(t, body, 3)
+	})
+
+	t.Run("catch is required", func(t *testing.T) {
+		_, err := parseBody(t, "try {\n  > 1\n}\n> 2")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Expected 'catch' after try block")
+	})
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/glyphlang/glyph/pkg/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSeedBlock(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   string
+		stmts  int
+		expand bool
+	}{
+		{"named", "seed! users {\n  db.users.firstOrCreate({email: \"a@b.c\"}, {name: \"ada\"})\n}", "users", 1, false},
+		{"anonymous", "seed! {\n  $ n = 1\n  db.users.create({n: n})\n}", "", 2, false},
+		{"expanded", "seed users {\n  let n = 1\n}", "users", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tokens []Token
+			var err error
+			if tt.expand {
+				tokens, err = NewExpandedLexer(tt.input).Tokenize()
+			} else {
+				tokens, err = NewLexer(tt.input).Tokenize()
+			}
+			require.NoError(t, err)
+
+			module, err := NewParserWithSource(tokens, tt.input).Parse()
+			require.NoError(t, err)
+			require.Len(t, module.Items, 1)
+			block, ok := module.Items[0].(*ast.SeedBlock)
+			require.True(t, ok, "expected SeedBlock, got %T", module.Items[0])
+			assert.Equal(t, tt.want, block.Name)
+			assert.Len(t, block.Body, tt.stmts)
+		})
+	}
+}
+
+func TestParseSeedBlockMissingBrace(t *testing.T) {
+	input := "seed! users db.users.create({})"
+	tokens, err := NewLexer(input).Tokenize()
+	require.NoError(t, err)
+
+	_, err = NewParserWithSource(tokens, input).Parse()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Expected '{' to start seed block")
+}
+
+func TestParseSeedMacroCall(t *testing.T) {
+	input := "macro! seed(n) {\n  $ seeded = n\n}\n\nseed!(1)"
+	tokens, err := NewLexer(input).Tokenize()
+	require.NoError(t, err)
+
+	module, err := NewParserWithSource(tokens, input).Parse()
+	require.NoError(t, err)
+	require.Len(t, module.Items, 2)
+	_, isSeed := module.Items[1].(*ast.SeedBlock)
+	assert.False(t, isSeed, "seed!(...) is a macro call, not a seed block")
+}
@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"github.com/glyphlang/glyph/pkg/ast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_ArraySpread(t *testing.T) {
+	expr := parseRouteExpr(t, "[...keys, key, ...more.items]")
+	arr, ok := expr.(ast.ArrayExpr)
+	require.True(t, ok, "got %T", expr)
+	require.Len(t, arr.Elements, 3)
+
+	first, ok := arr.Elements[0].(ast.SpreadExpr)
+	require.True(t, ok, "got %T", arr.Elements[0])
+	assert.Equal(t, "keys", first.Value.(ast.VariableExpr).Name)
+	assert.Equal(t, ast.Pos{Line: 2, Column: 10}, first.Pos)
+	assert.IsType(t, ast.VariableExpr{}, arr.Elements[1])
+	last, ok := arr.Elements[2].(ast.SpreadExpr)
+	require.True(t, ok, "got %T", arr.Elements[2])
+	assert.IsType(t, ast.FieldAccessExpr{}, last.Value)
+}
+
+func TestParser_ObjectSpread(t *testing.T) {
+	expr := parseRouteExpr(t, "{...base, extra: 1, ...overrides}")
+	obj, ok := expr.(ast.ObjectExpr)
+	require.True(t, ok, "got %T", expr)
+	require.Len(t, obj.Fields, 3)
+
+	assert.Equal(t, "", obj.Fields[0].Key)
+	assert.IsType(t, ast.SpreadExpr{}, obj.Fields[0].Value)
+	assert.Equal(t, "extra", obj.Fields[1].Key)
+	assert.Equal(t, "", obj.Fields[2].Key)
+	assert.IsType(t, ast.SpreadExpr{}, obj.Fields[2].Value)
+}
+
+func TestParser_SpreadOutsideLiteral(t *testing.T) {
+	tokens, err := NewLexer("@ GET /x {\n  > ...items\n}").Tokenize()
+	require.NoError(t, err)
+	_, err = NewParser(tokens).Parse()
+	assert.Error(t, err)
+}
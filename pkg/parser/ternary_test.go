@@ -0,0 +1,44 @@
+package parser
+
+import (
+	"github.com/glyphlang/glyph/pkg/ast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_TernaryPrecedence(t *testing.T) {
+	// ?: binds more loosely than || and ??, so the whole left side is the condition
+	expr := parseRouteExpr(t, `a || b ? "yes" : "no"`)
+	ternary, ok := expr.(ast.TernaryExpr)
+	require.True(t, ok, "got %T", expr)
+	or, ok := ternary.Condition.(ast.BinaryOpExpr)
+	require.True(t, ok, "got %T", ternary.Condition)
+	assert.Equal(t, ast.Or, or.Op)
+	assert.Equal(t, ast.LiteralExpr{Value: ast.StringLiteral{Value: "yes"}}, ternary.Then)
+	assert.Equal(t, ast.LiteralExpr{Value: ast.StringLiteral{Value: "no"}}, ternary.Else)
+
+	expr = parseRouteExpr(t, `x ?? y ? 1 : 2`)
+	ternary, ok = expr.(ast.TernaryExpr)
+	require.True(t, ok, "got %T", expr)
+	assert.IsType(t, ast.BinaryOpExpr{}, ternary.Condition)
+}
+
+func TestParser_TernaryRightAssociative(t *testing.T) {
+	expr := parseRouteExpr(t, `a ? 1 : b ? 2 : 3`)
+	outer, ok := expr.(ast.TernaryExpr)
+	require.True(t, ok, "got %T", expr)
+	assert.IsType(t, ast.VariableExpr{}, outer.Condition)
+	inner, ok := outer.Else.(ast.TernaryExpr)
+	require.True(t, ok, "got %T", outer.Else)
+	assert.IsType(t, ast.VariableExpr{}, inner.Condition)
+	assert.Equal(t, ast.LiteralExpr{Value: ast.IntLiteral{Value: 3}}, inner.Else)
+}
+
+func TestParser_TernaryMissingColon(t *testing.T) {
+	tokens, err := NewLexer("@ GET /x {\n  > a ? 1\n}").Tokenize()
+	require.NoError(t, err)
+	_, err = NewParser(tokens).Parse()
+	assert.ErrorContains(t, err, "Expected ':' in conditional expression")
+}
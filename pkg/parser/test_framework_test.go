@@ -241,6 +241,26 @@ func TestParseTestBlockExpandedSyntax(t *testing.T) {
 	assert.Len(t, tb.Body, 2)
 }
 
+// TestParseCompactTestBlock verifies the test! form of a test block
+func TestParseCompactTestBlock(t *testing.T) {
+	input := `test! "compact" {
+	assert(true)
+}`
+	lexer := NewLexer(input)
+	tokens, err := lexer.Tokenize()
+	require.NoError(t, err)
+
+	p := NewParserWithSource(tokens, input)
+	module, err := p.Parse()
+	require.NoError(t, err)
+
+	require.Len(t, module.Items, 1)
+	tb, ok := module.Items[0].(*ast.TestBlock)
+	require.True(t, ok, "expected TestBlock, got %T", module.Items[0])
+	assert.Equal(t, "compact", tb.Name)
+	assert.Len(t, tb.Body, 1)
+}
+
 // TestParseTestBlockMissingName verifies error for test without name
 func TestParseTestBlockMissingName(t *testing.T) {
 	input := `test {
@@ -10,26 +10,28 @@ const (
 	NEWLINE
 
 	// Symbols
-	AT         // @
-	COLON      // :
-	DOLLAR     // $
-	PLUS       // +
-	MINUS      // -
-	STAR       // *
-	SLASH      // /
-	PERCENT    // %
-	GREATER    // >
-	GREATER_EQ // >=
-	LESS       // <
-	LESS_EQ    // <=
-	BANG       // !
-	NOT_EQ     // !=
-	EQ_EQ      // ==
-	QUESTION   // ?
-	TILDE      // ~
-	AMPERSAND  // &
-	AND        // &&
-	OR         // ||
+	AT           // @
+	COLON        // :
+	DOLLAR       // $
+	PLUS         // +
+	MINUS        // -
+	STAR         // *
+	SLASH        // /
+	PERCENT      // %
+	GREATER      // >
+	GREATER_EQ   // >=
+	LESS         // <
+	LESS_EQ      // <=
+	BANG         // !
+	NOT_EQ       // !=
+	EQ_EQ        // ==
+	QUESTION     // ?
+	COALESCE     // ??
+	QUESTION_DOT // ?.
+	TILDE        // ~
+	AMPERSAND    // &
+	AND          // &&
+	OR           // ||
 
 	// Delimiters
 	LPAREN   // (
@@ -55,6 +57,12 @@ const (
 	NULL    // null
 	WHILE   // while
 
+	// Interpolated strings: "a{x}b{y}c" is lexed as STRING_START("a"), the
+	// tokens of x, STRING_MIDDLE("b"), the tokens of y, STRING_END("c")
+	STRING_START  // "text{
+	STRING_MIDDLE // }text{
+	STRING_END    // }text"
+
 	// Keywords
 	SWITCH    // switch
 	CASE      // case
@@ -129,6 +137,10 @@ func (t TokenType) String() string {
 		return "=="
 	case QUESTION:
 		return "?"
+	case COALESCE:
+		return "??"
+	case QUESTION_DOT:
+		return "?."
 	case TILDE:
 		return "~"
 	case AMPERSAND:
@@ -165,6 +177,12 @@ func (t TokenType) String() string {
 		return "IDENT"
 	case STRING:
 		return "STRING"
+	case STRING_START:
+		return "STRING_START"
+	case STRING_MIDDLE:
+		return "STRING_MIDDLE"
+	case STRING_END:
+		return "STRING_END"
 	case INTEGER:
 		return "INTEGER"
 	case FLOAT:
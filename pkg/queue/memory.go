@@ -0,0 +1,133 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// MemoryCapacity is the number of messages an in-memory queue holds before
+// Enqueue reports it full. Nack may exceed it so retries are never dropped.
+const MemoryCapacity = 1024
+
+// MemoryQueue is a Queue held in process memory. Its messages are lost when
+// the process exits.
+type MemoryQueue struct {
+	mu     sync.Mutex
+	queues map[string]*memoryList
+}
+
+type memoryList struct {
+	messages []*Message
+	inFlight map[string]*Message
+	ready    chan struct{} // Signalled when messages is non-empty
+}
+
+// NewMemoryQueue creates an empty in-memory queue
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{queues: make(map[string]*memoryList)}
+}
+
+// list returns the named queue, creating it if needed. m.mu must be held.
+func (m *MemoryQueue) list(name string) *memoryList {
+	l, ok := m.queues[name]
+	if !ok {
+		l = &memoryList{inFlight: make(map[string]*Message), ready: make(chan struct{}, 1)}
+		m.queues[name] = l
+	}
+	return l
+}
+
+// push appends msg and wakes a waiting Dequeue. m.mu must be held.
+func (l *memoryList) push(msg *Message) {
+	l.messages = append(l.messages, msg)
+	select {
+	case l.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue appends a message to the named queue
+func (m *MemoryQueue) Enqueue(ctx context.Context, queue string, body interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l := m.list(queue)
+	if len(l.messages) >= MemoryCapacity {
+		return fmt.Errorf("queue %q is full (%d messages)", queue, MemoryCapacity)
+	}
+	l.push(&Message{ID: uuid.NewString(), Queue: queue, Body: body})
+	return nil
+}
+
+// Dequeue waits for the next message on the named queue
+func (m *MemoryQueue) Dequeue(ctx context.Context, queue string) (*Message, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		m.mu.Lock()
+		l := m.list(queue)
+		if len(l.messages) > 0 {
+			msg := l.messages[0]
+			l.messages[0] = nil
+			l.messages = l.messages[1:]
+			l.inFlight[msg.ID] = msg
+			// Pass the signal on for the next waiting consumer
+			if len(l.messages) > 0 {
+				select {
+				case l.ready <- struct{}{}:
+				default:
+				}
+			}
+			m.mu.Unlock()
+			return msg, nil
+		}
+		ready := l.ready
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ready:
+		}
+	}
+}
+
+// Ack removes a delivered message
+func (m *MemoryQueue) Ack(ctx context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l := m.list(msg.Queue)
+	if _, ok := l.inFlight[msg.ID]; !ok {
+		return fmt.Errorf("message %s is not in flight on queue %q", msg.ID, msg.Queue)
+	}
+	delete(l.inFlight, msg.ID)
+	return nil
+}
+
+// Nack returns a message to the back of its queue
+func (m *MemoryQueue) Nack(ctx context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l := m.list(msg.Queue)
+	if _, ok := l.inFlight[msg.ID]; !ok {
+		return fmt.Errorf("message %s is not in flight on queue %q", msg.ID, msg.Queue)
+	}
+	delete(l.inFlight, msg.ID)
+	l.push(&Message{ID: msg.ID, Queue: msg.Queue, Body: msg.Body, Attempts: msg.Attempts + 1})
+	return nil
+}
+
+// Len returns the number of messages waiting on the named queue
+func (m *MemoryQueue) Len(queue string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.list(queue).messages)
+}
+
+// Close does nothing; it exists to satisfy Queue
+func (m *MemoryQueue) Close() error {
+	return nil
+}
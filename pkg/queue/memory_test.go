@@ -0,0 +1,109 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryQueue_EnqueueDequeueAck(t *testing.T) {
+	q := NewMemoryQueue()
+	ctx := context.Background()
+
+	require.NoError(t, q.Enqueue(ctx, "jobs", "first"))
+	require.NoError(t, q.Enqueue(ctx, "jobs", "second"))
+	assert.Equal(t, 2, q.Len("jobs"))
+
+	msg, err := q.Dequeue(ctx, "jobs")
+	require.NoError(t, err)
+	assert.Equal(t, "first", msg.Body)
+	assert.Equal(t, "jobs", msg.Queue)
+	assert.Zero(t, msg.Attempts)
+
+	require.NoError(t, q.Ack(ctx, msg))
+	assert.Error(t, q.Ack(ctx, msg), "a message can only be acknowledged once")
+	assert.Equal(t, 1, q.Len("jobs"))
+}
+
+func TestMemoryQueue_NackRequeues(t *testing.T) {
+	q := NewMemoryQueue()
+	ctx := context.Background()
+	require.NoError(t, q.Enqueue(ctx, "jobs", "first"))
+	require.NoError(t, q.Enqueue(ctx, "jobs", "next"))
+
+	first, err := q.Dequeue(ctx, "jobs")
+	require.NoError(t, err)
+	require.NoError(t, q.Nack(ctx, first))
+	assert.Error(t, q.Nack(ctx, first), "a nacked message is no longer in flight")
+
+	msg, err := q.Dequeue(ctx, "jobs")
+	require.NoError(t, err)
+	assert.Equal(t, "next", msg.Body)
+
+	retried, err := q.Dequeue(ctx, "jobs")
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, retried.ID)
+	assert.Equal(t, 1, retried.Attempts)
+}
+
+func TestMemoryQueue_Full(t *testing.T) {
+	q := NewMemoryQueue()
+	ctx := context.Background()
+	for n := 0; n < MemoryCapacity; n++ {
+		require.NoError(t, q.Enqueue(ctx, "jobs", n))
+	}
+	assert.ErrorContains(t, q.Enqueue(ctx, "jobs", "overflow"), "is full")
+
+	// A retry is accepted even when the queue is full
+	msg, err := q.Dequeue(ctx, "jobs")
+	require.NoError(t, err)
+	require.NoError(t, q.Enqueue(ctx, "jobs", "refill"))
+	assert.NoError(t, q.Nack(ctx, msg))
+	assert.Equal(t, MemoryCapacity+1, q.Len("jobs"))
+}
+
+func TestMemoryQueue_ConcurrentConsumers(t *testing.T) {
+	q := NewMemoryQueue()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const messages = 200
+	var mu sync.Mutex
+	seen := make(map[interface{}]int)
+	var wg sync.WaitGroup
+	for n := 0; n < 4; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				msg, err := q.Dequeue(ctx, "jobs")
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				seen[msg.Body]++
+				mu.Unlock()
+				assert.NoError(t, q.Ack(ctx, msg))
+			}
+		}()
+	}
+
+	for n := 0; n < messages; n++ {
+		require.NoError(t, q.Enqueue(ctx, "jobs", n))
+	}
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(seen) == messages
+	}, 5*time.Second, time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	for body, count := range seen {
+		assert.Equal(t, 1, count, "message %v delivered once", body)
+	}
+}
@@ -0,0 +1,57 @@
+// Package queue provides the message queues behind GlyphLang's @ queue
+// workers. A Queue delivers each message at least once: a dequeued message
+// stays in flight until it is acknowledged with Ack or returned with Nack.
+package queue
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// Message is a message dequeued from a Queue
+type Message struct {
+	ID    string
+	Queue string
+	Body  interface{}
+	// Attempts is the number of deliveries of this message that failed
+	Attempts int
+
+	raw string // Encoded form, used by RedisQueue to find the message in flight
+}
+
+// Queue is a set of named message queues
+type Queue interface {
+	// Enqueue appends a message to the named queue
+	Enqueue(ctx context.Context, queue string, body interface{}) error
+	// Dequeue waits for the next message on the named queue and marks it in
+	// flight. It returns ctx.Err() if ctx is done first.
+	Dequeue(ctx context.Context, queue string) (*Message, error)
+	// Ack removes a delivered message
+	Ack(ctx context.Context, msg *Message) error
+	// Nack returns a message to the back of its queue with its Attempts
+	// incremented
+	Nack(ctx context.Context, msg *Message) error
+	// Close releases the queue's resources
+	Close() error
+}
+
+// Open returns the queue for a URL: "memory://" or an empty string for an
+// in-process queue, or a "redis://" or "rediss://" URL for a Redis queue
+func Open(rawURL string) (Queue, error) {
+	if rawURL == "" {
+		return NewMemoryQueue(), nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid queue URL: %w", err)
+	}
+	switch u.Scheme {
+	case "memory":
+		return NewMemoryQueue(), nil
+	case "redis", "rediss":
+		return NewRedisQueueFromURL(rawURL)
+	default:
+		return nil, fmt.Errorf("unsupported queue URL scheme %q", u.Scheme)
+	}
+}
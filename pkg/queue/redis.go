@@ -0,0 +1,186 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/jsonvalue"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// RedisKeyPrefix prefixes the Redis keys of every queue
+const RedisKeyPrefix = "glyph:queue:"
+
+// redisPollInterval bounds each blocking pop so Dequeue notices a cancelled
+// context even if the client does not
+const redisPollInterval = time.Second
+
+// RedisClient is the subset of the go-redis client used by RedisQueue
+type RedisClient interface {
+	LPush(ctx context.Context, key string, values ...interface{}) *goredis.IntCmd
+	LRem(ctx context.Context, key string, count int64, value interface{}) *goredis.IntCmd
+	LMove(ctx context.Context, source, destination, srcpos, destpos string) *goredis.StringCmd
+	BLMove(ctx context.Context, source, destination, srcpos, destpos string, timeout time.Duration) *goredis.StringCmd
+	Close() error
+}
+
+// RedisQueue is a Queue stored in Redis lists using the reliable queue
+// pattern. Each queue is a list that producers push onto; Dequeue atomically
+// moves a message onto the queue's processing list, where it stays until it
+// is acknowledged, so a consumer that dies mid-message does not lose it (see
+// Recover). Message bodies are stored as JSON.
+type RedisQueue struct {
+	client RedisClient
+}
+
+// redisPayload is the stored form of a message
+type redisPayload struct {
+	ID       string          `json:"id"`
+	Attempts int             `json:"attempts"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// NewRedisQueue creates a queue that stores messages through client
+func NewRedisQueue(client RedisClient) *RedisQueue {
+	return &RedisQueue{client: client}
+}
+
+// NewRedisQueueFromURL creates a queue from a Redis URL (e.g., "redis://localhost:6379/0")
+func NewRedisQueueFromURL(redisURL string) (*RedisQueue, error) {
+	opts, err := goredis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	return NewRedisQueue(goredis.NewClient(opts)), nil
+}
+
+func redisKey(queue string) string {
+	return RedisKeyPrefix + queue
+}
+
+func redisProcessingKey(queue string) string {
+	return RedisKeyPrefix + queue + ":processing"
+}
+
+// encode returns the stored form of a message
+func encode(id string, attempts int, body interface{}) (string, error) {
+	rawBody, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("message body is not JSON-encodable: %w", err)
+	}
+	data, err := json.Marshal(redisPayload{ID: id, Attempts: attempts, Body: rawBody})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// decode parses a stored message. Whole numbers decode as int64 and other
+// numbers as float64, matching the interpreter's number types.
+func decode(queue, raw string) (*Message, error) {
+	var payload redisPayload
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		return nil, fmt.Errorf("invalid message on queue %q: %w", queue, err)
+	}
+	body, err := jsonvalue.Decode(payload.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message body on queue %q: %w", queue, err)
+	}
+	return &Message{
+		ID:       payload.ID,
+		Queue:    queue,
+		Body:     body,
+		Attempts: payload.Attempts,
+		raw:      raw,
+	}, nil
+}
+
+// Enqueue appends a message to the named queue
+func (q *RedisQueue) Enqueue(ctx context.Context, queue string, body interface{}) error {
+	raw, err := encode(uuid.NewString(), 0, body)
+	if err != nil {
+		return err
+	}
+	return q.client.LPush(ctx, redisKey(queue), raw).Err()
+}
+
+// Dequeue waits for the next message on the named queue and moves it onto
+// the queue's processing list
+func (q *RedisQueue) Dequeue(ctx context.Context, queue string) (*Message, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		raw, err := q.client.BLMove(ctx, redisKey(queue), redisProcessingKey(queue), "RIGHT", "LEFT", redisPollInterval).Result()
+		if errors.Is(err, goredis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		msg, err := decode(queue, raw)
+		if err != nil {
+			// Drop the unreadable message rather than failing on it forever
+			q.client.LRem(ctx, redisProcessingKey(queue), 1, raw)
+			return nil, err
+		}
+		return msg, nil
+	}
+}
+
+// Ack removes a delivered message from the processing list
+func (q *RedisQueue) Ack(ctx context.Context, msg *Message) error {
+	removed, err := q.client.LRem(ctx, redisProcessingKey(msg.Queue), 1, msg.raw).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return fmt.Errorf("message %s is not in flight on queue %q", msg.ID, msg.Queue)
+	}
+	return nil
+}
+
+// Nack returns a message to the back of its queue. The message is pushed
+// before it leaves the processing list, so a failure in between can
+// duplicate it but not lose it.
+func (q *RedisQueue) Nack(ctx context.Context, msg *Message) error {
+	raw, err := encode(msg.ID, msg.Attempts+1, msg.Body)
+	if err != nil {
+		return err
+	}
+	if err := q.client.LPush(ctx, redisKey(msg.Queue), raw).Err(); err != nil {
+		return err
+	}
+	return q.client.LRem(ctx, redisProcessingKey(msg.Queue), 1, msg.raw).Err()
+}
+
+// Recover moves the messages left in flight on the named queue, by
+// consumers that stopped without acknowledging them, back to the front of
+// the queue. It returns the number of messages moved. Call it before
+// consuming starts; messages still being handled elsewhere are delivered
+// again.
+func (q *RedisQueue) Recover(ctx context.Context, queue string) (int, error) {
+	moved := 0
+	for {
+		err := q.client.LMove(ctx, redisProcessingKey(queue), redisKey(queue), "LEFT", "RIGHT").Err()
+		if errors.Is(err, goredis.Nil) {
+			return moved, nil
+		}
+		if err != nil {
+			return moved, err
+		}
+		moved++
+	}
+}
+
+// Close closes the Redis connection
+func (q *RedisQueue) Close() error {
+	return q.client.Close()
+}
@@ -0,0 +1,247 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis implements the list commands used by RedisQueue in memory
+type fakeRedis struct {
+	mu     sync.Mutex
+	lists  map[string][]string // Index 0 is the left end
+	closed bool
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{lists: make(map[string][]string)}
+}
+
+func (f *fakeRedis) LPush(ctx context.Context, key string, values ...interface{}) *goredis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, v := range values {
+		f.lists[key] = append([]string{v.(string)}, f.lists[key]...)
+	}
+	cmd := goredis.NewIntCmd(ctx)
+	cmd.SetVal(int64(len(f.lists[key])))
+	return cmd
+}
+
+func (f *fakeRedis) LRem(ctx context.Context, key string, count int64, value interface{}) *goredis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var removed int64
+	list := f.lists[key]
+	for i := 0; i < len(list) && removed < count; {
+		if list[i] == value.(string) {
+			list = append(list[:i], list[i+1:]...)
+			removed++
+			continue
+		}
+		i++
+	}
+	f.lists[key] = list
+	cmd := goredis.NewIntCmd(ctx)
+	cmd.SetVal(removed)
+	return cmd
+}
+
+func (f *fakeRedis) LMove(ctx context.Context, source, destination, srcpos, destpos string) *goredis.StringCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cmd := goredis.NewStringCmd(ctx)
+	src := f.lists[source]
+	if len(src) == 0 {
+		cmd.SetErr(goredis.Nil)
+		return cmd
+	}
+	var v string
+	if srcpos == "LEFT" {
+		v, f.lists[source] = src[0], src[1:]
+	} else {
+		v, f.lists[source] = src[len(src)-1], src[:len(src)-1]
+	}
+	if destpos == "LEFT" {
+		f.lists[destination] = append([]string{v}, f.lists[destination]...)
+	} else {
+		f.lists[destination] = append(f.lists[destination], v)
+	}
+	cmd.SetVal(v)
+	return cmd
+}
+
+func (f *fakeRedis) BLMove(ctx context.Context, source, destination, srcpos, destpos string, timeout time.Duration) *goredis.StringCmd {
+	deadline := time.Now().Add(timeout)
+	for {
+		cmd := f.LMove(ctx, source, destination, srcpos, destpos)
+		if cmd.Err() != goredis.Nil {
+			return cmd
+		}
+		if time.Now().After(deadline) {
+			return cmd
+		}
+		select {
+		case <-ctx.Done():
+			cmd := goredis.NewStringCmd(ctx)
+			cmd.SetErr(ctx.Err())
+			return cmd
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func (f *fakeRedis) Close() error {
+	f.closed = true
+	return nil
+}
+
+func (f *fakeRedis) len(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.lists[key])
+}
+
+func TestRedisQueue_EnqueueDequeueAck(t *testing.T) {
+	fake := newFakeRedis()
+	q := NewRedisQueue(fake)
+	ctx := context.Background()
+
+	require.NoError(t, q.Enqueue(ctx, "email.send", map[string]interface{}{"to": "ada@example.com", "retry": int64(2)}))
+	require.NoError(t, q.Enqueue(ctx, "email.send", "second"))
+	assert.Equal(t, 2, fake.len("glyph:queue:email.send"))
+
+	msg, err := q.Dequeue(ctx, "email.send")
+	require.NoError(t, err)
+	assert.Equal(t, "email.send", msg.Queue)
+	assert.NotEmpty(t, msg.ID)
+	assert.Zero(t, msg.Attempts)
+	assert.Equal(t, map[string]interface{}{"to": "ada@example.com", "retry": int64(2)}, msg.Body, "FIFO, with whole numbers as int64")
+	assert.Equal(t, 1, fake.len("glyph:queue:email.send"))
+	assert.Equal(t, 1, fake.len("glyph:queue:email.send:processing"), "dequeued messages stay in flight until acknowledged")
+
+	require.NoError(t, q.Ack(ctx, msg))
+	assert.Equal(t, 0, fake.len("glyph:queue:email.send:processing"))
+	assert.Error(t, q.Ack(ctx, msg), "a message can only be acknowledged once")
+
+	msg, err = q.Dequeue(ctx, "email.send")
+	require.NoError(t, err)
+	assert.Equal(t, "second", msg.Body)
+}
+
+func TestRedisQueue_NackRequeues(t *testing.T) {
+	fake := newFakeRedis()
+	q := NewRedisQueue(fake)
+	ctx := context.Background()
+
+	require.NoError(t, q.Enqueue(ctx, "jobs", 1.5))
+	require.NoError(t, q.Enqueue(ctx, "jobs", "next"))
+
+	first, err := q.Dequeue(ctx, "jobs")
+	require.NoError(t, err)
+	require.NoError(t, q.Nack(ctx, first))
+	assert.Equal(t, 0, fake.len("glyph:queue:jobs:processing"))
+	assert.Equal(t, 2, fake.len("glyph:queue:jobs"))
+
+	// The nacked message goes behind the messages already waiting
+	msg, err := q.Dequeue(ctx, "jobs")
+	require.NoError(t, err)
+	assert.Equal(t, "next", msg.Body)
+	require.NoError(t, q.Ack(ctx, msg))
+
+	retried, err := q.Dequeue(ctx, "jobs")
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, retried.ID)
+	assert.Equal(t, 1.5, retried.Body)
+	assert.Equal(t, 1, retried.Attempts)
+}
+
+func TestRedisQueue_Recover(t *testing.T) {
+	fake := newFakeRedis()
+	q := NewRedisQueue(fake)
+	ctx := context.Background()
+
+	for _, body := range []string{"a", "b", "c"} {
+		require.NoError(t, q.Enqueue(ctx, "jobs", body))
+	}
+	// A consumer takes two messages and dies before acknowledging them
+	_, err := q.Dequeue(ctx, "jobs")
+	require.NoError(t, err)
+	_, err = q.Dequeue(ctx, "jobs")
+	require.NoError(t, err)
+
+	moved, err := q.Recover(ctx, "jobs")
+	require.NoError(t, err)
+	assert.Equal(t, 2, moved)
+	assert.Equal(t, 0, fake.len("glyph:queue:jobs:processing"))
+
+	// Recovered messages are delivered first, in their original order
+	for _, want := range []string{"a", "b", "c"} {
+		msg, err := q.Dequeue(ctx, "jobs")
+		require.NoError(t, err)
+		assert.Equal(t, want, msg.Body)
+	}
+}
+
+func TestRedisQueue_DequeueWaits(t *testing.T) {
+	q := NewRedisQueue(newFakeRedis())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := q.Dequeue(ctx, "empty")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	got := make(chan *Message, 1)
+	go func() {
+		msg, err := q.Dequeue(context.Background(), "later")
+		assert.NoError(t, err)
+		got <- msg
+	}()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, q.Enqueue(context.Background(), "later", "hello"))
+	select {
+	case msg := <-got:
+		assert.Equal(t, "hello", msg.Body)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Dequeue did not return the enqueued message")
+	}
+}
+
+func TestRedisQueue_DropsInvalidMessages(t *testing.T) {
+	fake := newFakeRedis()
+	q := NewRedisQueue(fake)
+	fake.LPush(context.Background(), "glyph:queue:jobs", "not json")
+
+	_, err := q.Dequeue(context.Background(), "jobs")
+	assert.ErrorContains(t, err, `invalid message on queue "jobs"`)
+	assert.Equal(t, 0, fake.len("glyph:queue:jobs:processing"))
+}
+
+func TestRedisQueue_EnqueueUnencodable(t *testing.T) {
+	q := NewRedisQueue(newFakeRedis())
+	err := q.Enqueue(context.Background(), "jobs", func() {})
+	assert.ErrorContains(t, err, "not JSON-encodable")
+}
+
+func TestOpen(t *testing.T) {
+	q, err := Open("")
+	require.NoError(t, err)
+	assert.IsType(t, &MemoryQueue{}, q)
+
+	q, err = Open("memory://")
+	require.NoError(t, err)
+	assert.IsType(t, &MemoryQueue{}, q)
+
+	q, err = Open("redis://localhost:6379/0")
+	require.NoError(t, err)
+	assert.IsType(t, &RedisQueue{}, q)
+	q.Close()
+
+	_, err = Open("amqp://localhost")
+	assert.ErrorContains(t, err, `unsupported queue URL scheme "amqp"`)
+}
@@ -0,0 +1,49 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// Client is the Redis API injected into routes as `% redis: Redis`.
+// Handler talks to a Redis server and MockHandler keeps everything in
+// memory, for development and tests.
+type Client interface {
+	Get(key string) (interface{}, error)
+	Set(args ...interface{}) (interface{}, error)
+	Del(keys ...interface{}) (int64, error)
+	Incr(key string) (int64, error)
+	Expire(key string, seconds interface{}) (bool, error)
+	HGet(key, field string) (interface{}, error)
+	HSet(args ...interface{}) (int64, error)
+	HGetAll(key string) (map[string]interface{}, error)
+	Publish(channel string, message interface{}) (int64, error)
+	Listen(ctx context.Context, fn func(channel string, message interface{}), channels ...string) error
+}
+
+var (
+	_ Client = (*Handler)(nil)
+	_ Client = (*MockHandler)(nil)
+)
+
+// Open returns the client for a URL: "memory://" or an empty string for an
+// in-memory MockHandler, or a "redis://" or "rediss://" URL for a Redis
+// server
+func Open(rawURL string) (Client, error) {
+	if rawURL == "" {
+		return NewMockHandler(), nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	switch u.Scheme {
+	case "memory":
+		return NewMockHandler(), nil
+	case "redis", "rediss":
+		return NewHandlerFromURL(rawURL)
+	default:
+		return nil, fmt.Errorf("unsupported redis URL scheme %q", u.Scheme)
+	}
+}
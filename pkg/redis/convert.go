@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/glyphlang/glyph/pkg/jsonvalue"
+)
+
+// encodeValue converts a GlyphLang value to the string stored in Redis.
+// Strings are stored as they are, so other Redis clients can read them and
+// INCR works on counters written with set; numbers, booleans, null, objects
+// and arrays are stored as JSON.
+func encodeValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case int:
+		return strconv.Itoa(v), nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("value is not JSON-encodable: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeValue converts a string read from Redis back to a GlyphLang value.
+// Anything that parses as JSON other than a JSON string is decoded, with
+// whole numbers as int64 and other numbers as float64; everything else,
+// including a JSON string, comes back as the string it is.
+func decodeValue(s string) interface{} {
+	if s == "" || s[0] == '"' {
+		return s
+	}
+	value, err := jsonvalue.Decode([]byte(s))
+	if err != nil {
+		return s
+	}
+	return value
+}
+
+// encodeValues encodes each of values
+func encodeValues(values []interface{}) ([]interface{}, error) {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		s, err := encodeValue(v)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+// decodeStrings decodes each of values
+func decodeStrings(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = decodeValue(v)
+	}
+	return out
+}
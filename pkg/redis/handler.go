@@ -10,7 +10,8 @@ import (
 
 // Handler manages Redis connections and operations for the interpreter.
 // It wraps a go-redis client and exposes methods that can be called
-// via reflection from GlyphLang's dependency injection system.
+// via reflection from GlyphLang's dependency injection system. Values are
+// converted as described by encodeValue and decodeValue.
 type Handler struct {
 	client *goredis.Client
 	ctx    context.Context
@@ -40,6 +41,12 @@ func NewHandlerFromOptions(opts *goredis.Options) *Handler {
 	return NewHandler(client)
 }
 
+// WithContext returns a handler whose commands run with ctx, so they are
+// cancelled with the request that issued them
+func (h *Handler) WithContext(ctx context.Context) interface{} {
+	return &Handler{client: h.client, ctx: ctx}
+}
+
 // Close closes the Redis connection.
 func (h *Handler) Close() error {
 	return h.client.Close()
@@ -61,7 +68,7 @@ func (h *Handler) Get(key string) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	return val, nil
+	return decodeValue(val), nil
 }
 
 // Set stores a key-value pair. Accepts an optional TTL in seconds.
@@ -74,7 +81,10 @@ func (h *Handler) Set(args ...interface{}) (interface{}, error) {
 	if !ok {
 		return nil, fmt.Errorf("redis.set: key must be a string")
 	}
-	value := args[1]
+	value, err := encodeValue(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("redis.set: %w", err)
+	}
 
 	var ttl time.Duration
 	if len(args) >= 3 {
@@ -90,8 +100,7 @@ func (h *Handler) Set(args ...interface{}) (interface{}, error) {
 		}
 	}
 
-	err := h.client.Set(h.ctx, key, value, ttl).Err()
-	if err != nil {
+	if err := h.client.Set(h.ctx, key, value, ttl).Err(); err != nil {
 		return nil, err
 	}
 	return "OK", nil
@@ -169,7 +178,7 @@ func (h *Handler) HGet(key, field string) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	return val, nil
+	return decodeValue(val), nil
 }
 
 // HSet sets one or more fields in a hash. Args: key, field, value [, field, value ...]
@@ -181,7 +190,11 @@ func (h *Handler) HSet(args ...interface{}) (int64, error) {
 	if !ok {
 		return 0, fmt.Errorf("redis.hset: key must be a string")
 	}
-	return h.client.HSet(h.ctx, key, args[1:]...).Result()
+	fields, err := encodeValues(args[1:])
+	if err != nil {
+		return 0, fmt.Errorf("redis.hset: %w", err)
+	}
+	return h.client.HSet(h.ctx, key, fields...).Result()
 }
 
 // HDel deletes one or more fields from a hash.
@@ -205,7 +218,7 @@ func (h *Handler) HGetAll(key string) (map[string]interface{}, error) {
 	}
 	out := make(map[string]interface{}, len(result))
 	for k, v := range result {
-		out[k] = v
+		out[k] = decodeValue(v)
 	}
 	return out, nil
 }
@@ -219,12 +232,20 @@ func (h *Handler) HExists(key, field string) (bool, error) {
 
 // LPush prepends one or more values to a list.
 func (h *Handler) LPush(key string, values ...interface{}) (int64, error) {
-	return h.client.LPush(h.ctx, key, values...).Result()
+	encoded, err := encodeValues(values)
+	if err != nil {
+		return 0, fmt.Errorf("redis.lpush: %w", err)
+	}
+	return h.client.LPush(h.ctx, key, encoded...).Result()
 }
 
 // RPush appends one or more values to a list.
 func (h *Handler) RPush(key string, values ...interface{}) (int64, error) {
-	return h.client.RPush(h.ctx, key, values...).Result()
+	encoded, err := encodeValues(values)
+	if err != nil {
+		return 0, fmt.Errorf("redis.rpush: %w", err)
+	}
+	return h.client.RPush(h.ctx, key, encoded...).Result()
 }
 
 // LPop removes and returns the first element of a list.
@@ -236,7 +257,7 @@ func (h *Handler) LPop(key string) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	return val, nil
+	return decodeValue(val), nil
 }
 
 // RPop removes and returns the last element of a list.
@@ -248,7 +269,7 @@ func (h *Handler) RPop(key string) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	return val, nil
+	return decodeValue(val), nil
 }
 
 // LLen returns the length of a list.
@@ -262,11 +283,7 @@ func (h *Handler) LRange(key string, start, stop int64) ([]interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	out := make([]interface{}, len(result))
-	for i, v := range result {
-		out[i] = v
-	}
-	return out, nil
+	return decodeStrings(result), nil
 }
 
 // --- Set operations ---
@@ -303,7 +320,38 @@ func (h *Handler) SIsMember(key string, member interface{}) (bool, error) {
 
 // Publish sends a message to a channel. Returns the number of clients that received the message.
 func (h *Handler) Publish(channel string, message interface{}) (int64, error) {
-	return h.client.Publish(h.ctx, channel, message).Result()
+	payload, err := encodeValue(message)
+	if err != nil {
+		return 0, fmt.Errorf("redis.publish: %w", err)
+	}
+	return h.client.Publish(h.ctx, channel, payload).Result()
+}
+
+// Listen subscribes to channels and, once the subscription is confirmed,
+// returns while fn is called in the background with each message published
+// on them, decoded like a value read with Get, until ctx is done.
+func (h *Handler) Listen(ctx context.Context, fn func(channel string, message interface{}), channels ...string) error {
+	sub := h.client.Subscribe(ctx, channels...)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return fmt.Errorf("redis subscribe: %w", err)
+	}
+	messages := sub.Channel()
+	go func() {
+		defer sub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				fn(msg.Channel, decodeValue(msg.Payload))
+			}
+		}
+	}()
+	return nil
 }
 
 // --- Key operations ---
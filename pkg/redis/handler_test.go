@@ -1,7 +1,10 @@
 package redis
 
 import (
+	"context"
+	"reflect"
 	"testing"
+	"time"
 )
 
 // Tests use MockHandler since they don't require a real Redis server.
@@ -463,3 +466,94 @@ func TestMockHandler_Publish(t *testing.T) {
 		t.Errorf("expected 0 for mock publish, got %d", count)
 	}
 }
+
+func TestMockHandler_ValueConversions(t *testing.T) {
+	m := NewMockHandler()
+
+	values := []interface{}{
+		"plain",
+		int64(42),
+		3.5,
+		true,
+		map[string]interface{}{"name": "ada", "visits": int64(2)},
+		[]interface{}{int64(1), "two"},
+	}
+	for _, want := range values {
+		if _, err := m.Set("key", want); err != nil {
+			t.Fatalf("Set(%v) failed: %v", want, err)
+		}
+		got, err := m.Get("key")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %#v, got %#v", want, got)
+		}
+	}
+
+	// A counter reads back as an integer
+	if _, err := m.Incr("counter"); err != nil {
+		t.Fatalf("Incr failed: %v", err)
+	}
+	if got, _ := m.Get("counter"); got != int64(1) {
+		t.Errorf("expected int64 1, got %#v", got)
+	}
+}
+
+func TestMockHandler_Listen(t *testing.T) {
+	m := NewMockHandler()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	type message struct {
+		channel string
+		value   interface{}
+	}
+	var received []message
+	err := m.Listen(ctx, func(channel string, value interface{}) {
+		received = append(received, message{channel, value})
+	}, "orders")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+
+	count, err := m.Publish("orders", map[string]interface{}{"id": int64(7)})
+	if err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 receiver, got %d", count)
+	}
+	if _, err := m.Publish("other", "ignored"); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	want := []message{{"orders", map[string]interface{}{"id": int64(7)}}}
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("expected %v, got %v", want, received)
+	}
+
+	// Once ctx is done the listener is removed
+	cancel()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		if count, _ := m.Publish("orders", "late"); count == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("listener still subscribed after its context was cancelled")
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	for _, rawURL := range []string{"", "memory://"} {
+		client, err := Open(rawURL)
+		if err != nil {
+			t.Fatalf("Open(%q) failed: %v", rawURL, err)
+		}
+		if _, ok := client.(*MockHandler); !ok {
+			t.Errorf("Open(%q) returned %T, expected *MockHandler", rawURL, client)
+		}
+	}
+	if _, err := Open("http://localhost"); err == nil {
+		t.Error("expected error for an unsupported scheme")
+	}
+}
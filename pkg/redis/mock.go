@@ -1,6 +1,7 @@
 package redis
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -8,12 +9,21 @@ import (
 
 // MockHandler provides an in-memory Redis mock for testing without a real Redis server.
 // It implements the same public methods as Handler so it can be used via reflection.
+// Messages published on it are delivered to its own listeners.
 type MockHandler struct {
-	mu     sync.RWMutex
-	data   map[string]mockEntry
-	lists  map[string][]string
-	hashes map[string]map[string]string
-	sets   map[string]map[string]struct{}
+	mu           sync.RWMutex
+	data         map[string]mockEntry
+	lists        map[string][]string
+	hashes       map[string]map[string]string
+	sets         map[string]map[string]struct{}
+	listeners    map[int]mockListener
+	nextListener int
+}
+
+// mockListener is a Listen call on a MockHandler
+type mockListener struct {
+	channels map[string]bool
+	fn       func(channel string, message interface{})
 }
 
 type mockEntry struct {
@@ -31,10 +41,11 @@ func (e mockEntry) isExpired() bool {
 // NewMockHandler creates a new mock Redis handler.
 func NewMockHandler() *MockHandler {
 	return &MockHandler{
-		data:   make(map[string]mockEntry),
-		lists:  make(map[string][]string),
-		hashes: make(map[string]map[string]string),
-		sets:   make(map[string]map[string]struct{}),
+		data:      make(map[string]mockEntry),
+		lists:     make(map[string][]string),
+		hashes:    make(map[string]map[string]string),
+		sets:      make(map[string]map[string]struct{}),
+		listeners: make(map[int]mockListener),
 	}
 }
 
@@ -53,7 +64,7 @@ func (m *MockHandler) Get(key string) (interface{}, error) {
 	if !ok || entry.isExpired() {
 		return nil, nil
 	}
-	return entry.value, nil
+	return decodeValue(entry.value), nil
 }
 
 func (m *MockHandler) Set(args ...interface{}) (interface{}, error) {
@@ -65,7 +76,10 @@ func (m *MockHandler) Set(args ...interface{}) (interface{}, error) {
 	if !ok {
 		return nil, fmt.Errorf("redis.set: key must be a string")
 	}
-	value := fmt.Sprintf("%v", args[1])
+	value, err := encodeValue(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("redis.set: %w", err)
+	}
 
 	var expiresAt time.Time
 	if len(args) >= 3 {
@@ -241,7 +255,7 @@ func (m *MockHandler) HGet(key, field string) (interface{}, error) {
 	if !ok {
 		return nil, nil
 	}
-	return val, nil
+	return decodeValue(val), nil
 }
 
 func (m *MockHandler) HSet(args ...interface{}) (int64, error) {
@@ -252,6 +266,10 @@ func (m *MockHandler) HSet(args ...interface{}) (int64, error) {
 	if !ok {
 		return 0, fmt.Errorf("redis.hset: key must be a string")
 	}
+	fields, err := encodeValues(args[1:])
+	if err != nil {
+		return 0, fmt.Errorf("redis.hset: %w", err)
+	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -261,9 +279,9 @@ func (m *MockHandler) HSet(args ...interface{}) (int64, error) {
 	}
 
 	var added int64
-	for i := 1; i+1 < len(args); i += 2 {
-		field := fmt.Sprintf("%v", args[i])
-		value := fmt.Sprintf("%v", args[i+1])
+	for i := 0; i+1 < len(fields); i += 2 {
+		field := fields[i].(string)
+		value := fields[i+1].(string)
 		if _, exists := m.hashes[key][field]; !exists {
 			added++
 		}
@@ -303,7 +321,7 @@ func (m *MockHandler) HGetAll(key string) (map[string]interface{}, error) {
 
 	out := make(map[string]interface{}, len(hash))
 	for k, v := range hash {
-		out[k] = v
+		out[k] = decodeValue(v)
 	}
 	return out, nil
 }
@@ -323,21 +341,31 @@ func (m *MockHandler) HExists(key, field string) (bool, error) {
 // --- List operations ---
 
 func (m *MockHandler) LPush(key string, values ...interface{}) (int64, error) {
+	encoded, err := encodeValues(values)
+	if err != nil {
+		return 0, fmt.Errorf("redis.lpush: %w", err)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	for _, v := range values {
-		m.lists[key] = append([]string{fmt.Sprintf("%v", v)}, m.lists[key]...)
+	for _, v := range encoded {
+		m.lists[key] = append([]string{v.(string)}, m.lists[key]...)
 	}
 	return int64(len(m.lists[key])), nil
 }
 
 func (m *MockHandler) RPush(key string, values ...interface{}) (int64, error) {
+	encoded, err := encodeValues(values)
+	if err != nil {
+		return 0, fmt.Errorf("redis.rpush: %w", err)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	for _, v := range values {
-		m.lists[key] = append(m.lists[key], fmt.Sprintf("%v", v))
+	for _, v := range encoded {
+		m.lists[key] = append(m.lists[key], v.(string))
 	}
 	return int64(len(m.lists[key])), nil
 }
@@ -352,7 +380,7 @@ func (m *MockHandler) LPop(key string) (interface{}, error) {
 	}
 	val := list[0]
 	m.lists[key] = list[1:]
-	return val, nil
+	return decodeValue(val), nil
 }
 
 func (m *MockHandler) RPop(key string) (interface{}, error) {
@@ -365,7 +393,7 @@ func (m *MockHandler) RPop(key string) (interface{}, error) {
 	}
 	val := list[len(list)-1]
 	m.lists[key] = list[:len(list)-1]
-	return val, nil
+	return decodeValue(val), nil
 }
 
 func (m *MockHandler) LLen(key string) (int64, error) {
@@ -402,11 +430,7 @@ func (m *MockHandler) LRange(key string, start, stop int64) ([]interface{}, erro
 		return []interface{}{}, nil
 	}
 
-	out := make([]interface{}, 0, stop-start+1)
-	for i := start; i <= stop; i++ {
-		out = append(out, list[i])
-	}
-	return out, nil
+	return decodeStrings(list[start : stop+1]), nil
 }
 
 // --- Set operations ---
@@ -478,10 +502,50 @@ func (m *MockHandler) SIsMember(key string, member interface{}) (bool, error) {
 	return exists, nil
 }
 
-// --- Pub/Sub (no-op for mock) ---
+// --- Pub/Sub ---
 
+// Publish delivers message to the listeners of channel before returning
 func (m *MockHandler) Publish(channel string, message interface{}) (int64, error) {
-	return 0, nil
+	payload, err := encodeValue(message)
+	if err != nil {
+		return 0, fmt.Errorf("redis.publish: %w", err)
+	}
+
+	m.mu.RLock()
+	var receivers []func(channel string, message interface{})
+	for _, l := range m.listeners {
+		if l.channels[channel] {
+			receivers = append(receivers, l.fn)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, fn := range receivers {
+		fn(channel, decodeValue(payload))
+	}
+	return int64(len(receivers)), nil
+}
+
+// Listen calls fn with each message published on channels until ctx is done
+func (m *MockHandler) Listen(ctx context.Context, fn func(channel string, message interface{}), channels ...string) error {
+	l := mockListener{channels: make(map[string]bool, len(channels)), fn: fn}
+	for _, channel := range channels {
+		l.channels[channel] = true
+	}
+
+	m.mu.Lock()
+	id := m.nextListener
+	m.nextListener++
+	m.listeners[id] = l
+	m.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		delete(m.listeners, id)
+		m.mu.Unlock()
+	}()
+	return nil
 }
 
 // --- Key operations ---
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
@@ -153,16 +154,16 @@ func (r *REPL) cmdLoad(args []string) error {
 		return fmt.Errorf("usage: :load <filename>")
 	}
 
-	filepath := args[0]
+	path := args[0]
 
 	// Add .glyph extension if not present
-	if !strings.HasSuffix(filepath, ".glyph") {
-		filepath += ".glyph"
+	if filepath.Ext(path) == "" {
+		path += ".glyph"
 	}
 
-	r.printf("Loading %s...\n", filepath)
+	r.printf("Loading %s...\n", path)
 
-	if err := r.LoadFile(filepath); err != nil {
+	if err := r.LoadFile(path); err != nil {
 		return err
 	}
 
@@ -232,12 +233,27 @@ func (r *REPL) cmdVars(args []string) error {
 
 // cmdTypes lists all defined types.
 func (r *REPL) cmdTypes(args []string) error {
-	// Get types from the interpreter
-	// Note: This requires access to interpreter internals
-	// For now, we'll show a message about checking via :load
-	r.printf("Type definitions are tracked internally.\n")
-	r.printf("Use :load <file> to load type definitions from a file.\n")
-	r.printf("Types can be defined inline: : TypeName { field: type! }\n")
+	typeDefs := r.interp.GetTypeDefs()
+
+	r.printf("Type definitions:\n")
+	if len(typeDefs) == 0 {
+		r.printf("  (none) - define one inline with : TypeName { field: type! } or :load a file\n")
+		return nil
+	}
+
+	names := make([]string, 0, len(typeDefs))
+	for name := range typeDefs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var fields []string
+		for _, field := range typeDefs[name].Fields {
+			fields = append(fields, field.Name+": "+formatType(field.TypeAnnotation))
+		}
+		r.printf("  %s { %s }\n", name, strings.Join(fields, ", "))
+	}
 	return nil
 }
 
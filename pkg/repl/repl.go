@@ -3,16 +3,23 @@ package repl
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 
+	"github.com/fatih/color"
 	"github.com/glyphlang/glyph/pkg/ast"
 	"github.com/glyphlang/glyph/pkg/interpreter"
 	"github.com/glyphlang/glyph/pkg/parser"
 )
 
+// errorColor matches the CLI's error output
+var errorColor = color.New(color.FgRed)
+
 // REPL provides an interactive programming environment for Glyph.
 type REPL struct {
 	interp  *interpreter.Interpreter
@@ -77,7 +84,7 @@ func (r *REPL) Start() error {
 		}
 
 		if err := r.processLine(line); err != nil {
-			r.printf("Error: %v\n", err)
+			r.printError(err)
 		}
 	}
 
@@ -97,6 +104,15 @@ func (r *REPL) processLine(line string) error {
 		return r.executeCommand(line)
 	}
 
+	// A blank line ends a multi-line block even when it is unbalanced, so
+	// a stray brace reports a parse error instead of waiting for more input
+	if strings.TrimSpace(line) == "" && r.inputBuffer.Len() > 0 {
+		input := strings.TrimSpace(r.inputBuffer.String())
+		r.inputBuffer.Reset()
+		r.lineNumber++
+		return r.evaluate(input)
+	}
+
 	// Add line to input buffer
 	if r.inputBuffer.Len() > 0 {
 		r.inputBuffer.WriteString("\n")
@@ -153,6 +169,10 @@ const (
 	inputTypeFunction
 )
 
+// assignmentPattern matches input that starts by assigning to a variable,
+// field or index rather than comparing with ==
+var assignmentPattern = regexp.MustCompile(`^[A-Za-z_]\w*(\s*(\.\w+|\[[^\]]*\]))*\s*[-+*/]?=($|[^=])`)
+
 // detectInputType determines what type of input the user has provided.
 func (r *REPL) detectInputType(input string) inputType {
 	trimmed := strings.TrimSpace(input)
@@ -190,6 +210,11 @@ func (r *REPL) detectInputType(input string) inputType {
 		return inputTypeStatement
 	}
 
+	// Reassignment: x = 1, user.name = "Ada", items[0] = 2
+	if assignmentPattern.MatchString(trimmed) {
+		return inputTypeStatement
+	}
+
 	// Return statement: starts with ">"
 	if strings.HasPrefix(trimmed, ">") {
 		return inputTypeStatement
@@ -264,7 +289,8 @@ func (r *REPL) evaluateStatement(input string) error {
 	}
 
 	// For assignment statements, show the assigned value
-	if _, ok := stmt.(ast.AssignStatement); ok {
+	switch stmt.(type) {
+	case ast.AssignStatement, ast.ReassignStatement:
 		r.printResult(result)
 	}
 
@@ -359,6 +385,17 @@ func (r *REPL) parseModule(input string) (*ast.Module, error) {
 	return p.Parse()
 }
 
+// parseExpandedModule parses a string of expanded (.glyphx) syntax as a module.
+func (r *REPL) parseExpandedModule(input string) (*ast.Module, error) {
+	tokens, err := parser.NewExpandedLexer(input).Tokenize()
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.NewParser(tokens)
+	return p.Parse()
+}
+
 // isInputComplete checks if the input has balanced braces and parentheses.
 func (r *REPL) isInputComplete(input string) bool {
 	braceCount := 0
@@ -436,6 +473,11 @@ func (r *REPL) printf(format string, args ...interface{}) {
 	fmt.Fprintf(r.writer, format, args...)
 }
 
+// printError prints an error in red.
+func (r *REPL) printError(err error) {
+	errorColor.Fprintf(r.writer, "Error: %v\n", err)
+}
+
 // printResult prints an evaluation result.
 func (r *REPL) printResult(result interface{}) {
 	if result == nil {
@@ -464,12 +506,24 @@ func formatValue(v interface{}) string {
 	case bool:
 		return fmt.Sprintf("%t", val)
 	case []interface{}:
+		// Arrays of objects or arrays are easier to read as indented JSON
+		for _, elem := range val {
+			switch elem.(type) {
+			case map[string]interface{}, []interface{}:
+				if out, ok := formatJSON(val); ok {
+					return out
+				}
+			}
+		}
 		var parts []string
 		for _, elem := range val {
 			parts = append(parts, formatValue(elem))
 		}
 		return "[" + strings.Join(parts, ", ") + "]"
 	case map[string]interface{}:
+		if out, ok := formatJSON(val); ok {
+			return out
+		}
 		var parts []string
 		for k, elem := range val {
 			parts = append(parts, fmt.Sprintf("%s: %s", k, formatValue(elem)))
@@ -480,6 +534,16 @@ func formatValue(v interface{}) string {
 	}
 }
 
+// formatJSON formats v as indented JSON. It reports false for values JSON
+// cannot represent, such as functions.
+func formatJSON(v interface{}) (string, bool) {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", false
+	}
+	return string(out), true
+}
+
 // GetEnvironment returns the current REPL environment.
 func (r *REPL) GetEnvironment() *interpreter.Environment {
 	return r.env
@@ -491,13 +555,18 @@ func (r *REPL) GetInterpreter() *interpreter.Interpreter {
 }
 
 // LoadFile loads and executes a Glyph file.
-func (r *REPL) LoadFile(filepath string) error {
-	source, err := os.ReadFile(filepath)
+func (r *REPL) LoadFile(path string) error {
+	source, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	module, err := r.parseModule(string(source))
+	var module *ast.Module
+	if filepath.Ext(path) == ".glyphx" {
+		module, err = r.parseExpandedModule(string(source))
+	} else {
+		module, err = r.parseModule(string(source))
+	}
 	if err != nil {
 		return fmt.Errorf("parse error: %w", err)
 	}
@@ -1463,3 +1463,84 @@ func TestStartEOFTermination(t *testing.T) {
 		t.Errorf("Expected 'Goodbye' on EOF, got %q", result)
 	}
 }
+
+// runSession drives a REPL through a reader/writer pair and returns its output.
+func runSession(t *testing.T, input string) string {
+	t.Helper()
+	output := &bytes.Buffer{}
+	r := New(strings.NewReader(input), output, "test")
+	if err := r.Start(); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	return output.String()
+}
+
+// TestREPLObjectsPrintedAsJSON tests that objects are pretty-printed as indented JSON.
+func TestREPLObjectsPrintedAsJSON(t *testing.T) {
+	result := runSession(t, "$ user = {name: \"Ada\", tags: [\"a\"]}\n[{id: 1}]\n:quit\n")
+
+	expected := "=> {\n  \"name\": \"Ada\",\n  \"tags\": [\n    \"a\"\n  ]\n}"
+	if !strings.Contains(result, expected) {
+		t.Errorf("Expected indented JSON object %q, got %q", expected, result)
+	}
+	if !strings.Contains(result, "=> [\n  {\n    \"id\": 1\n  }\n]") {
+		t.Errorf("Expected array of objects as indented JSON, got %q", result)
+	}
+}
+
+// TestREPLParseErrorKeepsSession tests that a parse error does not end the session.
+func TestREPLParseErrorKeepsSession(t *testing.T) {
+	result := runSession(t, "$ x = 5\n1 +\nx * 2\n:quit\n")
+
+	if !strings.Contains(result, "Error: parse error") {
+		t.Errorf("Expected parse error, got %q", result)
+	}
+	if !strings.Contains(result, "=> 10") {
+		t.Errorf("Expected session to continue after parse error, got %q", result)
+	}
+}
+
+// TestREPLBlankLineEndsBlock tests that a blank line ends an unbalanced multi-line block.
+func TestREPLBlankLineEndsBlock(t *testing.T) {
+	result := runSession(t, "{\n  x: 1\n\n2 + 2\n:quit\n")
+
+	if !strings.Contains(result, "... ") {
+		t.Errorf("Expected continuation prompt, got %q", result)
+	}
+	if !strings.Contains(result, "Error: parse error") {
+		t.Errorf("Expected parse error for unbalanced block, got %q", result)
+	}
+	if !strings.Contains(result, "=> 4") {
+		t.Errorf("Expected input after the block to be evaluated, got %q", result)
+	}
+}
+
+// TestREPLReassignment tests that x = value updates an existing variable.
+func TestREPLReassignment(t *testing.T) {
+	result := runSession(t, "$ count = 1\ncount = count + 1\ncount == 2\n:quit\n")
+
+	if !strings.Contains(result, "=> 2") || !strings.Contains(result, "=> true") {
+		t.Errorf("Expected reassignment to update count, got %q", result)
+	}
+}
+
+// TestREPLLoadThenCall tests that functions and types from :load stay available.
+func TestREPLLoadThenCall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lib.glyph")
+	source := ": Point {\n  x: int!\n}\n\n! triple(n: int): int {\n  > n * 3\n}\n"
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := runSession(t, ":load "+path+"\ntriple(4)\n:type triple(1)\n:types\n:quit\n")
+
+	if !strings.Contains(result, "=> 12") {
+		t.Errorf("Expected loaded function to be callable, got %q", result)
+	}
+	if !strings.Contains(result, "triple(1) :: int") {
+		t.Errorf("Expected :type to show int, got %q", result)
+	}
+	if !strings.Contains(result, "Point") {
+		t.Errorf("Expected loaded type to be listed, got %q", result)
+	}
+}
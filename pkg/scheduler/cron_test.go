@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestScheduleNext(t *testing.T) {
+	tests := []struct {
+		spec string
+		from string
+		want []string // Successive run times
+	}{
+		{"0 0 * * *", "2025-03-14 10:30", []string{"2025-03-15 00:00", "2025-03-16 00:00"}},
+		{"0 0 * * *", "2025-12-31 23:59", []string{"2026-01-01 00:00"}},
+		{"*/5 * * * *", "2025-03-14 10:31", []string{"2025-03-14 10:35", "2025-03-14 10:40"}},
+		{"*/5 * * * *", "2025-03-14 23:58", []string{"2025-03-15 00:00", "2025-03-15 00:05"}},
+		// A run time is never the starting time itself
+		{"*/5 * * * *", "2025-03-14 10:35", []string{"2025-03-14 10:40"}},
+		{"* * * * *", "2025-03-14 10:31", []string{"2025-03-14 10:32", "2025-03-14 10:33"}},
+		{"0 * * * *", "2025-03-14 10:31", []string{"2025-03-14 11:00", "2025-03-14 12:00"}},
+		{"30 9 * * 1-5", "2025-03-14 10:00", []string{"2025-03-17 09:30", "2025-03-18 09:30"}}, // Friday to Monday
+		{"0 9 * * 0", "2025-03-14 10:00", []string{"2025-03-16 09:00", "2025-03-23 09:00"}},
+		{"0 9 * * 7", "2025-03-14 10:00", []string{"2025-03-16 09:00"}},
+		{"0 9 * * sun", "2025-03-14 10:00", []string{"2025-03-16 09:00"}},
+		{"15 14 1 * *", "2025-03-14 10:00", []string{"2025-04-01 14:15", "2025-05-01 14:15"}},
+		{"0 0 29 2 *", "2025-03-14 10:00", []string{"2028-02-29 00:00"}},
+		{"0 0 1 jan,jul *", "2025-03-14 10:00", []string{"2025-07-01 00:00", "2026-01-01 00:00"}},
+		{"0 8-18/4 * * *", "2025-03-14 10:00", []string{"2025-03-14 12:00", "2025-03-14 16:00", "2025-03-15 08:00"}},
+		{"10/20 * * * *", "2025-03-14 10:00", []string{"2025-03-14 10:10", "2025-03-14 10:30", "2025-03-14 10:50"}},
+		// Both day fields restricted: either matches (the 13th, or a Friday)
+		{"0 0 13 * 5", "2025-03-10 00:00", []string{"2025-03-13 00:00", "2025-03-14 00:00", "2025-03-21 00:00"}},
+		{"@daily", "2025-03-14 10:30", []string{"2025-03-15 00:00"}},
+		{"@hourly", "2025-03-14 10:30", []string{"2025-03-14 11:00"}},
+		{"@weekly", "2025-03-14 10:30", []string{"2025-03-16 00:00"}},
+		{"@monthly", "2025-03-14 10:30", []string{"2025-04-01 00:00"}},
+		{"@yearly", "2025-03-14 10:30", []string{"2026-01-01 00:00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec+" from "+tt.from, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			require.NoError(t, err)
+			next := date(tt.from)
+			for _, want := range tt.want {
+				next = s.Next(next)
+				assert.Equal(t, date(want), next)
+			}
+		})
+	}
+}
+
+func TestScheduleNext_Impossible(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(date("2025-01-01 00:00")).IsZero())
+}
+
+func TestScheduleNext_Location(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	s, err := Parse("0 0 * * *")
+	require.NoError(t, err)
+
+	next := s.Next(date("2025-03-14 10:30").In(loc))
+	assert.Equal(t, time.Date(2025, 3, 15, 0, 0, 0, 0, loc), next)
+	assert.Equal(t, date("2025-03-14 22:00"), next.UTC())
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"", "expected 5 fields, got 0"},
+		{"* * * *", "expected 5 fields, got 4"},
+		{"* * * * * *", "expected 5 fields, got 6"},
+		{"60 * * * *", "minute value 60 out of range 0-59"},
+		{"* 24 * * *", "hour value 24 out of range 0-23"},
+		{"* * 0 * *", "day of month value 0 out of range 1-31"},
+		{"* * * 13 *", "month value 13 out of range 1-12"},
+		{"* * * * 8", "day of week value 8 out of range 0-7"},
+		{"*/0 * * * *", `invalid step "0"`},
+		{"*/x * * * *", `invalid step "x"`},
+		{"5-1 * * * *", `invalid range "5-1"`},
+		{"a * * * *", `invalid value "a" in minute field`},
+		{"* * * foo *", `invalid value "foo" in month field`},
+		{"@every", "expected 5 fields"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := Parse(tt.spec)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Overlap is what a job does when a run is due while its previous run is
+// still in progress
+type Overlap int
+
+const (
+	// SkipOverlap drops the due run
+	SkipOverlap Overlap = iota
+	// QueueOverlap runs once more as soon as the previous run returns. At
+	// most one run waits; further due runs while it waits are dropped.
+	QueueOverlap
+)
+
+// ParseOverlap converts "skip" or "queue" to an Overlap. An empty string is
+// SkipOverlap.
+func ParseOverlap(text string) (Overlap, error) {
+	switch text {
+	case "", "skip":
+		return SkipOverlap, nil
+	case "queue":
+		return QueueOverlap, nil
+	default:
+		return SkipOverlap, fmt.Errorf("invalid overlap %q: expected skip or queue", text)
+	}
+}
+
+// Scheduler runs jobs at the times given by their cron schedules. Each job
+// runs in its own goroutine, so a slow job never delays another; what
+// happens when a job is due while still running is set by its Overlap.
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    []*job
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+
+	now func() time.Time // Replaced in tests
+}
+
+type job struct {
+	name     string
+	schedule *Schedule
+	location *time.Location
+	overlap  Overlap
+	run      func(ctx context.Context)
+
+	mu      sync.Mutex
+	running bool
+	pending bool // A run is queued behind the current one
+}
+
+// New creates a scheduler with no jobs
+func New() *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{ctx: ctx, cancel: cancel, now: time.Now}
+}
+
+// Add schedules run according to the cron expression spec, evaluated in
+// loc (UTC if nil). Runs that fall due while the job is still running are
+// skipped. Jobs added after Start begin immediately.
+func (s *Scheduler) Add(name, spec string, loc *time.Location, run func(ctx context.Context)) error {
+	return s.AddWithOverlap(name, spec, loc, SkipOverlap, run)
+}
+
+// AddWithOverlap is Add with the given overlap policy
+func (s *Scheduler) AddWithOverlap(name, spec string, loc *time.Location, overlap Overlap, run func(ctx context.Context)) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	j := &job{name: name, schedule: schedule, location: loc, overlap: overlap, run: run}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, j)
+	if s.started {
+		s.startJob(j)
+	}
+	return nil
+}
+
+// Start begins running the scheduled jobs
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	for _, j := range s.jobs {
+		s.startJob(j)
+	}
+}
+
+// Stop stops scheduling jobs and waits for running jobs to return. The
+// context passed to running jobs is cancelled. Stop on a nil Scheduler does
+// nothing.
+func (s *Scheduler) Stop() {
+	if s == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Next returns when the named job runs next, or false if there is no such job
+func (s *Scheduler) Next(name string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.name == name {
+			return j.schedule.Next(s.now().In(j.location)), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// startJob runs j's schedule loop; s.mu must be held
+func (s *Scheduler) startJob(j *job) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		var last time.Time
+		for {
+			// Never schedule before the last run, even if the wall clock
+			// reads slightly earlier than the time the timer fired for
+			from := s.now()
+			if from.Before(last) {
+				from = last
+			}
+			next := j.schedule.Next(from.In(j.location))
+			if next.IsZero() {
+				return
+			}
+
+			timer := time.NewTimer(next.Sub(s.now()))
+			select {
+			case <-s.ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			last = next
+			s.trigger(j)
+		}
+	}()
+}
+
+// trigger starts a run of j, or applies j's overlap policy if a run is
+// already in progress
+func (s *Scheduler) trigger(j *job) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.running {
+		if j.overlap == QueueOverlap {
+			j.pending = true
+		}
+		return
+	}
+	j.running = true
+	s.wg.Add(1)
+	go s.runJob(j)
+}
+
+// runJob runs j, then any run queued behind it
+func (s *Scheduler) runJob(j *job) {
+	defer s.wg.Done()
+	for {
+		j.run(s.ctx)
+
+		j.mu.Lock()
+		if !j.pending || s.ctx.Err() != nil {
+			j.running = false
+			j.pending = false
+			j.mu.Unlock()
+			return
+		}
+		j.pending = false
+		j.mu.Unlock()
+	}
+}
@@ -0,0 +1,161 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clockBeforeMinute returns a clock that reads d before the next minute
+// boundary when called now, and advances in real time
+func clockBeforeMinute(d time.Duration) func() time.Time {
+	real := time.Now()
+	fake := real.Truncate(time.Minute).Add(time.Minute - d)
+	offset := fake.Sub(real)
+	return func() time.Time { return time.Now().Add(offset) }
+}
+
+func TestSchedulerRunsJobs(t *testing.T) {
+	s := New()
+	s.now = clockBeforeMinute(20 * time.Millisecond)
+
+	ran := make(chan struct{}, 1)
+	require.NoError(t, s.Add("every-minute", "* * * * *", nil, func(ctx context.Context) {
+		ran <- struct{}{}
+	}))
+	s.Start()
+	defer s.Stop()
+
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not run")
+	}
+
+	// The next run is a minute later, not immediately
+	select {
+	case <-ran:
+		t.Fatal("job ran twice for the same minute")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSchedulerStopCancelsRunningJobs(t *testing.T) {
+	s := New()
+	s.now = clockBeforeMinute(10 * time.Millisecond)
+
+	started := make(chan struct{})
+	var cancelled atomic.Bool
+	require.NoError(t, s.Add("slow", "* * * * *", nil, func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		cancelled.Store(true)
+	}))
+	s.Start()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not run")
+	}
+	s.Stop()
+	assert.True(t, cancelled.Load(), "Stop should cancel the job's context and wait for it")
+}
+
+func TestSchedulerStopBeforeRun(t *testing.T) {
+	s := New()
+	var runs atomic.Int32
+	require.NoError(t, s.Add("daily", "0 0 * * *", nil, func(ctx context.Context) {
+		runs.Add(1)
+	}))
+	s.Start()
+	s.Stop()
+	assert.Zero(t, runs.Load())
+
+	var nilScheduler *Scheduler
+	nilScheduler.Stop()
+}
+
+func TestSchedulerAddInvalidSchedule(t *testing.T) {
+	s := New()
+	err := s.Add("bad", "every day", nil, func(ctx context.Context) {})
+	assert.Error(t, err)
+	_, ok := s.Next("bad")
+	assert.False(t, ok)
+}
+
+func TestSchedulerNext(t *testing.T) {
+	s := New()
+	s.now = func() time.Time { return date("2025-03-14 10:30") }
+	loc := time.FixedZone("UTC-5", -5*60*60)
+	require.NoError(t, s.Add("report", "0 9 * * *", loc, func(ctx context.Context) {}))
+
+	next, ok := s.Next("report")
+	require.True(t, ok)
+	assert.Equal(t, date("2025-03-14 14:00"), next.UTC()) // 09:00 local, later the same day
+}
+
+// overlapJob adds a job whose runs block until released, and returns it with
+// a channel that receives once per run started
+func overlapJob(t *testing.T, s *Scheduler, overlap Overlap, release <-chan struct{}) (*job, <-chan struct{}) {
+	t.Helper()
+	started := make(chan struct{}, 10)
+	require.NoError(t, s.AddWithOverlap("slow", "0 0 * * *", nil, overlap, func(ctx context.Context) {
+		started <- struct{}{}
+		<-release
+	}))
+	return s.jobs[0], started
+}
+
+func TestSchedulerOverlapSkip(t *testing.T) {
+	s := New()
+	release := make(chan struct{})
+	j, started := overlapJob(t, s, SkipOverlap, release)
+
+	s.trigger(j)
+	<-started
+	s.trigger(j) // Due while the first run is going
+	close(release)
+	s.Stop()
+
+	assert.Len(t, started, 0, "the overlapping run is skipped")
+}
+
+func TestSchedulerOverlapQueue(t *testing.T) {
+	s := New()
+	release := make(chan struct{})
+	j, started := overlapJob(t, s, QueueOverlap, release)
+
+	s.trigger(j)
+	<-started
+	s.trigger(j)
+	s.trigger(j) // Only one run waits
+	close(release)
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued run did not start")
+	}
+	s.Stop()
+	assert.Len(t, started, 0)
+
+	// A job that finished can run again
+	j.mu.Lock()
+	assert.False(t, j.running)
+	j.mu.Unlock()
+}
+
+func TestParseOverlap(t *testing.T) {
+	for text, want := range map[string]Overlap{"": SkipOverlap, "skip": SkipOverlap, "queue": QueueOverlap} {
+		got, err := ParseOverlap(text)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, text)
+	}
+	_, err := ParseOverlap("parallel")
+	assert.ErrorContains(t, err, `invalid overlap "parallel"`)
+}
@@ -87,6 +87,8 @@ func (d *SQLInjectionDetector) checkExpression(expr ast.Expr, location string) {
 		for _, elem := range e.Elements {
 			d.checkExpression(elem, location)
 		}
+	case ast.SpreadExpr:
+		d.checkExpression(e.Value, location)
 	}
 }
 
@@ -91,6 +91,9 @@ func (d *XSSDetector) analyzeExpr(expr ast.Expr, inHTMLContext bool) {
 		for _, elem := range e.Elements {
 			d.analyzeExpr(elem, inHTMLContext)
 		}
+
+	case ast.SpreadExpr:
+		d.analyzeExpr(e.Value, inHTMLContext)
 	}
 }
 
@@ -278,6 +281,9 @@ func RequiresHTMLEscape(expr ast.Expr) bool {
 		}
 		return false
 
+	case ast.SpreadExpr:
+		return RequiresHTMLEscape(e.Value)
+
 	default:
 		return false
 	}
@@ -453,6 +459,9 @@ func exprToString(expr ast.Expr) string {
 
 	case ast.ObjectExpr:
 		return "{...}"
+
+	case ast.SpreadExpr:
+		return "..." + exprToString(e.Value)
 	}
 
 	return "unknown"
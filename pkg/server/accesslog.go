@@ -0,0 +1,274 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RequestIDHeader is the header used to propagate request IDs
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs
+const maxRequestIDLength = 128
+
+// LogFormat selects how access logs are written
+type LogFormat string
+
+const (
+	// LogFormatText is the human-readable (colorized in the CLI) format
+	LogFormatText LogFormat = "text"
+	// LogFormatJSON writes one JSON object per request
+	LogFormatJSON LogFormat = "json"
+)
+
+// ParseLogFormat validates a log format name
+func ParseLogFormat(s string) (LogFormat, error) {
+	switch LogFormat(s) {
+	case LogFormatText, "":
+		return LogFormatText, nil
+	case LogFormatJSON:
+		return LogFormatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown log format %q (expected text or json)", s)
+	}
+}
+
+// requestIDKey is the context key for the request ID
+type requestIDKey struct{}
+
+// NewRequestID generates a random 128-bit hex request ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// RequestID returns the request ID attached to r, or "" if none
+func RequestID(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	if id, ok := r.Context().Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// WithRequestID returns a copy of r carrying a request ID. An incoming
+// X-Request-ID header is propagated when valid; otherwise a new ID is generated.
+func WithRequestID(r *http.Request) (*http.Request, string) {
+	if id := RequestID(r); id != "" {
+		return r, id
+	}
+	id := r.Header.Get(RequestIDHeader)
+	if !validRequestID(id) {
+		id = NewRequestID()
+	}
+	r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+	r.Header.Set(RequestIDHeader, id)
+	return r, id
+}
+
+// validRequestID accepts short IDs made of printable, non-space ASCII
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDHandler attaches a request ID to every request and echoes it in
+// the X-Request-ID response header
+func RequestIDHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, id := WithRequestID(r)
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// StatusWriter wraps an http.ResponseWriter to record the status code and
+// number of body bytes written. It passes through Flush and Hijack so SSE and
+// WebSocket upgrades keep working behind it.
+type StatusWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+// NewStatusWriter wraps w, returning w itself if it is already a StatusWriter
+func NewStatusWriter(w http.ResponseWriter) *StatusWriter {
+	if sw, ok := w.(*StatusWriter); ok {
+		return sw
+	}
+	return &StatusWriter{ResponseWriter: w}
+}
+
+// WriteHeader records the status code
+func (sw *StatusWriter) WriteHeader(statusCode int) {
+	if !sw.wroteHeader {
+		sw.status = statusCode
+		sw.wroteHeader = true
+	}
+	sw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the number of bytes written
+func (sw *StatusWriter) Write(b []byte) (int, error) {
+	if !sw.wroteHeader {
+		sw.status = http.StatusOK
+		sw.wroteHeader = true
+	}
+	n, err := sw.ResponseWriter.Write(b)
+	sw.bytes += int64(n)
+	return n, err
+}
+
+// Status returns the status code written, or 200 if nothing was written yet
+func (sw *StatusWriter) Status() int {
+	if sw.status == 0 {
+		return http.StatusOK
+	}
+	return sw.status
+}
+
+// WroteHeader reports whether the response status has been sent
+func (sw *StatusWriter) WroteHeader() bool {
+	return sw.wroteHeader
+}
+
+// BytesWritten returns the number of body bytes written
+func (sw *StatusWriter) BytesWritten() int64 {
+	return sw.bytes
+}
+
+// Flush implements http.Flusher
+func (sw *StatusWriter) Flush() {
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker
+func (sw *StatusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := sw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("underlying ResponseWriter does not implement http.Hijacker")
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (sw *StatusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// AccessLogEntry is a single structured access log line
+type AccessLogEntry struct {
+	Time       string `json:"time"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Status     int    `json:"status"`
+	DurationMs int64  `json:"duration_ms"`
+	Bytes      int64  `json:"bytes"`
+	RequestID  string `json:"request_id"`
+	RemoteAddr string `json:"remote_addr"`
+}
+
+// AccessLogFunc receives one entry per completed request
+type AccessLogFunc func(entry AccessLogEntry)
+
+// AccessLogHandler assigns a request ID, captures the response status and size,
+// and reports one AccessLogEntry per request to logFn
+func AccessLogHandler(next http.Handler, logFn AccessLogFunc) http.Handler {
+	return RequestIDHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := NewStatusWriter(w)
+
+		next.ServeHTTP(sw, r)
+
+		logFn(AccessLogEntry{
+			Time:       start.UTC().Format(time.RFC3339Nano),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Status:     sw.Status(),
+			DurationMs: time.Since(start).Milliseconds(),
+			Bytes:      sw.BytesWritten(),
+			RequestID:  RequestID(r),
+			RemoteAddr: r.RemoteAddr,
+		})
+	}))
+}
+
+// JSONAccessLogger returns an AccessLogFunc that writes one JSON object per
+// line to out. Writes are serialized so lines never interleave.
+func JSONAccessLogger(out io.Writer) AccessLogFunc {
+	var mu sync.Mutex
+	return func(entry AccessLogEntry) {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		out.Write(append(line, '\n'))
+	}
+}
+
+// StructuredLoggingMiddleware writes one JSON AccessLogEntry per request to
+// out. The request ID is taken from the context (or X-Request-ID) and echoed
+// in the response header; the logged status is the one the handler actually
+// wrote, or 500 when the handler returned an error without writing one.
+func StructuredLoggingMiddleware(out io.Writer) Middleware {
+	logFn := JSONAccessLogger(out)
+	return func(next RouteHandler) RouteHandler {
+		return func(ctx *Context) error {
+			start := time.Now()
+
+			if ctx.RequestID == "" {
+				ctx.Request, ctx.RequestID = WithRequestID(ctx.Request)
+			}
+			ctx.ResponseWriter.Header().Set(RequestIDHeader, ctx.RequestID)
+
+			sw := NewStatusWriter(ctx.ResponseWriter)
+			ctx.ResponseWriter = sw
+
+			err := next(ctx)
+
+			status := sw.Status()
+			if err != nil && (!sw.wroteHeader || status < 400) {
+				status = http.StatusInternalServerError
+			}
+
+			logFn(AccessLogEntry{
+				Time:       start.UTC().Format(time.RFC3339Nano),
+				Method:     ctx.Request.Method,
+				Path:       ctx.Request.URL.Path,
+				Status:     status,
+				DurationMs: time.Since(start).Milliseconds(),
+				Bytes:      sw.BytesWritten(),
+				RequestID:  ctx.RequestID,
+				RemoteAddr: ctx.Request.RemoteAddr,
+			})
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseLogFormat tests log format validation
+func TestParseLogFormat(t *testing.T) {
+	f, err := ParseLogFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, LogFormatText, f)
+
+	f, err = ParseLogFormat("json")
+	require.NoError(t, err)
+	assert.Equal(t, LogFormatJSON, f)
+
+	_, err = ParseLogFormat("xml")
+	assert.Error(t, err)
+}
+
+// TestAccessLogJSONFields tests that one JSON line with all fields is written per request
+func TestAccessLogJSONFields(t *testing.T) {
+	var out bytes.Buffer
+	handler := AccessLogHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}), JSONAccessLogger(&out))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 1)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+
+	assert.Equal(t, "POST", entry["method"])
+	assert.Equal(t, "/api/users", entry["path"])
+	assert.Equal(t, float64(http.StatusCreated), entry["status"])
+	assert.Equal(t, float64(5), entry["bytes"])
+	assert.Equal(t, "10.0.0.1:1234", entry["remote_addr"])
+	assert.Contains(t, entry, "duration_ms")
+	assert.Contains(t, entry, "time")
+
+	id, _ := entry["request_id"].(string)
+	assert.NotEmpty(t, id)
+	assert.Equal(t, id, w.Header().Get(RequestIDHeader))
+}
+
+// TestRequestIDPropagation tests that an incoming X-Request-ID is reused
+func TestRequestIDPropagation(t *testing.T) {
+	var seen string
+	handler := RequestIDHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, "abc-123", seen)
+	assert.Equal(t, "abc-123", w.Header().Get(RequestIDHeader))
+}
+
+// TestRequestIDRejectsInvalid tests that unsafe client IDs are replaced
+func TestRequestIDRejectsInvalid(t *testing.T) {
+	for _, bad := range []string{"has space", "new\nline", strings.Repeat("a", maxRequestIDLength+1)} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(RequestIDHeader, bad)
+		_, id := WithRequestID(req)
+		assert.NotEqual(t, bad, id)
+		assert.Len(t, id, 32)
+	}
+}
+
+// TestHandlerSetsContextRequestID tests that the router handler exposes the ID on Context
+func TestHandlerSetsContextRequestID(t *testing.T) {
+	router := NewRouter()
+	var ctxID string
+	require.NoError(t, router.RegisterRoute(&Route{
+		Method: GET,
+		Path:   "/ping",
+		Handler: func(ctx *Context) error {
+			ctxID = ctx.RequestID
+			return Send(ctx, http.StatusTeapot, map[string]string{"ok": "yes"})
+		},
+	}))
+
+	var out bytes.Buffer
+	handler := AccessLogHandler(NewHandler(router, nil), JSONAccessLogger(&out))
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, "req-42", ctxID)
+
+	var entry AccessLogEntry
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "req-42", entry.RequestID)
+	assert.Equal(t, http.StatusTeapot, entry.Status)
+}
+
+// TestStatusWriterDefaults tests status capture when only Write is called
+func TestStatusWriterDefaults(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sw := NewStatusWriter(rec)
+	assert.Same(t, sw, NewStatusWriter(sw))
+
+	assert.Equal(t, http.StatusOK, sw.Status())
+	sw.Write([]byte("abc"))
+	sw.WriteHeader(http.StatusInternalServerError) // ignored after body written
+	assert.Equal(t, http.StatusOK, sw.Status())
+	assert.Equal(t, int64(3), sw.BytesWritten())
+}
+
+// TestStructuredLoggingMiddlewareStatus tests that the logged status matches what the handler set
+func TestStructuredLoggingMiddlewareStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  RouteHandler
+		expected int
+		bytes    int64
+	}{
+		{
+			name: "status set late via Send",
+			handler: func(ctx *Context) error {
+				return SendJSON(ctx, http.StatusCreated, map[string]int{"id": 1})
+			},
+			expected: http.StatusCreated,
+			bytes:    int64(len("{\"id\":1}\n")),
+		},
+		{
+			name: "direct WriteHeader",
+			handler: func(ctx *Context) error {
+				ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
+				return nil
+			},
+			expected: http.StatusNotFound,
+		},
+		{
+			name: "implicit 200 on write",
+			handler: func(ctx *Context) error {
+				_, err := ctx.ResponseWriter.Write([]byte("ok"))
+				return err
+			},
+			expected: http.StatusOK,
+			bytes:    2,
+		},
+		{
+			name: "error without response",
+			handler: func(ctx *Context) error {
+				return assert.AnError
+			},
+			expected: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			req := httptest.NewRequest(http.MethodGet, "/items", nil)
+			w := httptest.NewRecorder()
+			ctx := &Context{Request: req, ResponseWriter: w, StatusCode: http.StatusOK}
+
+			_ = StructuredLoggingMiddleware(&out)(tt.handler)(ctx)
+
+			var entry AccessLogEntry
+			require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+			assert.Equal(t, tt.expected, entry.Status)
+			assert.Equal(t, tt.bytes, entry.Bytes)
+			assert.Equal(t, "GET", entry.Method)
+			assert.Equal(t, "/items", entry.Path)
+			assert.NotEmpty(t, entry.RequestID)
+			assert.Equal(t, entry.RequestID, w.Header().Get(RequestIDHeader))
+			assert.GreaterOrEqual(t, entry.DurationMs, int64(0))
+		})
+	}
+}
+
+// TestStructuredLoggingMiddlewareReusesRequestID tests that an incoming ID is logged and echoed
+func TestStructuredLoggingMiddlewareReusesRequestID(t *testing.T) {
+	var out bytes.Buffer
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "upstream-1")
+	w := httptest.NewRecorder()
+	ctx := &Context{Request: req, ResponseWriter: w}
+
+	require.NoError(t, StructuredLoggingMiddleware(&out)(func(ctx *Context) error { return nil })(ctx))
+
+	var entry AccessLogEntry
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "upstream-1", entry.RequestID)
+	assert.Equal(t, "upstream-1", ctx.RequestID)
+	assert.Equal(t, "upstream-1", w.Header().Get(RequestIDHeader))
+}
@@ -0,0 +1,273 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Supported content codings
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// CompressionOptions configures CompressionMiddleware
+type CompressionOptions struct {
+	// Level is the compression level (gzip.DefaultCompression when 0)
+	Level int
+	// MinSize is the smallest body, in bytes, that is worth compressing
+	MinSize int
+	// SkipContentTypes lists content type prefixes that are already
+	// compressed and must be passed through untouched
+	SkipContentTypes []string
+}
+
+// DefaultCompressionOptions returns sensible defaults: default level, a 1KB
+// threshold, and common pre-compressed media types skipped
+func DefaultCompressionOptions() CompressionOptions {
+	return CompressionOptions{
+		Level:   gzip.DefaultCompression,
+		MinSize: 1024,
+		SkipContentTypes: []string{
+			"image/",
+			"video/",
+			"audio/",
+			"font/woff",
+			"application/zip",
+			"application/gzip",
+			"application/x-gzip",
+			"application/x-7z-compressed",
+			"application/x-rar-compressed",
+			"application/zstd",
+			"application/pdf",
+			"application/octet-stream",
+			"application/wasm",
+		},
+	}
+}
+
+// CompressionMiddleware compresses responses with gzip or deflate according
+// to the request's Accept-Encoding header. Bodies smaller than MinSize,
+// responses that already carry a Content-Encoding, and skipped content types
+// are written unmodified. Flushing starts compression immediately so
+// streaming and SSE responses are delivered incrementally.
+func CompressionMiddleware(opts CompressionOptions) Middleware {
+	if opts.Level == 0 {
+		opts.Level = gzip.DefaultCompression
+	}
+	if opts.MinSize < 0 {
+		opts.MinSize = 0
+	}
+
+	return func(next RouteHandler) RouteHandler {
+		return func(ctx *Context) error {
+			encoding := negotiateContentEncoding(ctx.Request.Header.Get("Accept-Encoding"))
+			ctx.ResponseWriter.Header().Add("Vary", "Accept-Encoding")
+			if encoding == "" || ctx.Request.Method == http.MethodHead {
+				return next(ctx)
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: ctx.ResponseWriter,
+				encoding:       encoding,
+				opts:           opts,
+			}
+			original := ctx.ResponseWriter
+			ctx.ResponseWriter = cw
+			defer func() { ctx.ResponseWriter = original }()
+
+			err := next(ctx)
+			if closeErr := cw.Close(); err == nil {
+				err = closeErr
+			}
+			return err
+		}
+	}
+}
+
+// negotiateContentEncoding picks gzip or deflate from an Accept-Encoding
+// header, preferring gzip when both are equally acceptable
+func negotiateContentEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if found && strings.TrimSpace(key) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if coding == "*" {
+			coding = EncodingGzip
+		}
+		if q <= 0 || (coding != EncodingGzip && coding != EncodingDeflate) {
+			continue
+		}
+		if q > bestQ || (q == bestQ && coding == EncodingGzip) {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of a response until it can decide
+// whether compression is worthwhile, then either compresses or passes through
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	opts     CompressionOptions
+
+	status  int
+	buf     bytes.Buffer
+	decided bool
+	writer  io.WriteCloser // nil when passing through
+}
+
+// WriteHeader defers the status until the compression decision is made
+func (cw *compressWriter) WriteHeader(statusCode int) {
+	if cw.status == 0 {
+		cw.status = statusCode
+	}
+}
+
+// Write buffers until MinSize bytes are available, then commits
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.decided {
+		if cw.writer != nil {
+			return cw.writer.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	n, _ := cw.buf.Write(b)
+	if cw.buf.Len() >= cw.opts.MinSize {
+		if err := cw.commit(true); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// Flush starts compression (if eligible) and flushes through to the client
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if err := cw.commit(true); err != nil {
+			return
+		}
+	}
+	if flusher, ok := cw.writer.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for WebSocket upgrades
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("underlying ResponseWriter does not implement http.Hijacker")
+	}
+	cw.decided = true
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close commits any buffered data and finishes the compressed stream
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		// The whole body is below the threshold
+		if err := cw.commit(false); err != nil {
+			return err
+		}
+	}
+	if cw.writer != nil {
+		return cw.writer.Close()
+	}
+	return nil
+}
+
+// commit writes headers and buffered data, compressing when allowed
+func (cw *compressWriter) commit(allowCompress bool) error {
+	cw.decided = true
+	header := cw.ResponseWriter.Header()
+	status := cw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	if allowCompress && cw.shouldCompress(status) {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+
+		var err error
+		switch cw.encoding {
+		case EncodingDeflate:
+			cw.writer, err = zlib.NewWriterLevel(cw.ResponseWriter, cw.opts.Level)
+		default:
+			cw.writer, err = gzip.NewWriterLevel(cw.ResponseWriter, cw.opts.Level)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create %s writer: %w", cw.encoding, err)
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(status)
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.writer != nil {
+		_, err = cw.writer.Write(cw.buf.Bytes())
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf.Bytes())
+	}
+	cw.buf.Reset()
+	return err
+}
+
+// shouldCompress reports whether the response is eligible for compression
+func (cw *compressWriter) shouldCompress(status int) bool {
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+
+	header := cw.ResponseWriter.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" && cw.buf.Len() > 0 {
+		contentType = http.DetectContentType(cw.buf.Bytes())
+		header.Set("Content-Type", contentType)
+	}
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range cw.opts.SkipContentTypes {
+		if strings.HasPrefix(contentType, strings.ToLower(prefix)) {
+			return false
+		}
+	}
+	return true
+}
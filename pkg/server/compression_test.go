@@ -0,0 +1,168 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runCompressed executes handler behind CompressionMiddleware
+func runCompressed(t *testing.T, opts CompressionOptions, acceptEncoding string, handler RouteHandler) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	ctx := &Context{Request: req, ResponseWriter: w, StatusCode: http.StatusOK}
+	require.NoError(t, CompressionMiddleware(opts)(handler)(ctx))
+	return w
+}
+
+// largeUserList builds a JSON-encodable payload well above the threshold
+func largeUserList() []map[string]interface{} {
+	users := make([]map[string]interface{}, 200)
+	for i := range users {
+		users[i] = map[string]interface{}{"id": i, "name": "User", "email": "user@example.com"}
+	}
+	return users
+}
+
+// TestCompressionLargeJSONIsGzipped tests that a large JSON body is compressed
+func TestCompressionLargeJSONIsGzipped(t *testing.T) {
+	w := runCompressed(t, DefaultCompressionOptions(), "gzip, deflate", func(ctx *Context) error {
+		return SendJSON(ctx, http.StatusOK, largeUserList())
+	})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Header().Values("Vary"), "Accept-Encoding")
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	zr, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(body), `[{"email":"user@example.com"`))
+	assert.Less(t, w.Body.Len(), len(body))
+}
+
+// TestCompressionDeflate tests deflate selection
+func TestCompressionDeflate(t *testing.T) {
+	w := runCompressed(t, DefaultCompressionOptions(), "deflate", func(ctx *Context) error {
+		return SendJSON(ctx, http.StatusCreated, largeUserList())
+	})
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "deflate", w.Header().Get("Content-Encoding"))
+	zr, err := zlib.NewReader(w.Body)
+	require.NoError(t, err)
+	_, err = io.ReadAll(zr)
+	require.NoError(t, err)
+}
+
+// TestCompressionSmallBodyPassesThrough tests that tiny bodies are not compressed
+func TestCompressionSmallBodyPassesThrough(t *testing.T) {
+	w := runCompressed(t, DefaultCompressionOptions(), "gzip", func(ctx *Context) error {
+		return SendJSON(ctx, http.StatusAccepted, map[string]string{"ok": "yes"})
+	})
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Header().Values("Vary"), "Accept-Encoding")
+	assert.JSONEq(t, `{"ok":"yes"}`, w.Body.String())
+}
+
+// TestCompressionWithoutAcceptEncoding tests that clients not asking for compression get identity
+func TestCompressionWithoutAcceptEncoding(t *testing.T) {
+	w := runCompressed(t, DefaultCompressionOptions(), "", func(ctx *Context) error {
+		return SendJSON(ctx, http.StatusOK, largeUserList())
+	})
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+
+	w = runCompressed(t, DefaultCompressionOptions(), "gzip;q=0, br", func(ctx *Context) error {
+		return SendJSON(ctx, http.StatusOK, largeUserList())
+	})
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
+
+// TestCompressionSkipsPrecompressedContent tests that compressed types are not double-compressed
+func TestCompressionSkipsPrecompressedContent(t *testing.T) {
+	payload := strings.Repeat("x", 4096)
+
+	w := runCompressed(t, DefaultCompressionOptions(), "gzip", func(ctx *Context) error {
+		ctx.ResponseWriter.Header().Set("Content-Type", "image/png")
+		_, err := ctx.ResponseWriter.Write([]byte(payload))
+		return err
+	})
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, payload, w.Body.String())
+
+	w = runCompressed(t, DefaultCompressionOptions(), "gzip", func(ctx *Context) error {
+		ctx.ResponseWriter.Header().Set("Content-Type", "text/plain")
+		ctx.ResponseWriter.Header().Set("Content-Encoding", "br")
+		_, err := ctx.ResponseWriter.Write([]byte(payload))
+		return err
+	})
+	assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, payload, w.Body.String())
+}
+
+// TestCompressionFlushStreams tests that flushed SSE events reach the client incrementally
+func TestCompressionFlushStreams(t *testing.T) {
+	router := NewRouter()
+	release := make(chan struct{})
+	require.NoError(t, router.RegisterRoute(&Route{
+		Method:      GET,
+		Path:        "/events",
+		Middlewares: []Middleware{CompressionMiddleware(DefaultCompressionOptions())},
+		Handler: func(ctx *Context) error {
+			ctx.ResponseWriter.Header().Set("Content-Type", "text/event-stream")
+			ctx.ResponseWriter.Write([]byte("data: first\n\n"))
+			ctx.ResponseWriter.(http.Flusher).Flush()
+			<-release
+			ctx.ResponseWriter.Write([]byte("data: second\n\n"))
+			return nil
+		},
+	}))
+	ts := httptest.NewServer(NewHandler(router, nil))
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+
+	zr, err := gzip.NewReader(resp.Body)
+	require.NoError(t, err)
+	reader := bufio.NewReader(zr)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: first\n", line)
+
+	close(release)
+	rest, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "\ndata: second\n\n", string(rest))
+}
+
+// TestNegotiateContentEncoding tests Accept-Encoding parsing
+func TestNegotiateContentEncoding(t *testing.T) {
+	assert.Equal(t, "gzip", negotiateContentEncoding("gzip"))
+	assert.Equal(t, "gzip", negotiateContentEncoding("deflate, gzip"))
+	assert.Equal(t, "deflate", negotiateContentEncoding("gzip;q=0.5, deflate"))
+	assert.Equal(t, "gzip", negotiateContentEncoding("*"))
+	assert.Equal(t, "", negotiateContentEncoding("br, identity"))
+	assert.Equal(t, "", negotiateContentEncoding(""))
+}
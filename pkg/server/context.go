@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// BindJSON decodes the request's JSON body into v, which must be a pointer.
+// Struct fields are matched by their json tag, then by their db tag, then by
+// name. A body value of the wrong type for its field is an error.
+func (ctx *Context) BindJSON(v interface{}) error {
+	if ctx.Body == nil {
+		return fmt.Errorf("request has no JSON body")
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("BindJSON needs a non-nil pointer, got %T", v)
+	}
+
+	data, err := json.Marshal(ctx.Body)
+	if err != nil {
+		return fmt.Errorf("failed to encode body: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	return ctx.bindDBTags(reflect.ValueOf(v).Elem())
+}
+
+// bindDBTags sets the fields of a struct that have a db tag but no json tag
+// from the body keys named by the db tag, which encoding/json does not know
+func (ctx *Context) bindDBTags(v reflect.Value) error {
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("db"), ",")[0]
+		if name == "" || name == "-" || field.Tag.Get("json") != "" || !field.IsExported() {
+			continue
+		}
+		value, ok := ctx.Body[name]
+		if !ok {
+			continue
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode body field %q: %w", name, err)
+		}
+		if err := json.Unmarshal(data, v.Field(i).Addr().Interface()); err != nil {
+			return fmt.Errorf("invalid JSON body: field %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Query returns the first value of the query parameter name, or "" if it
+// is absent
+func (ctx *Context) Query(name string) string {
+	if values := ctx.QueryParams[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// QueryDefault returns the first value of the query parameter name, or def
+// if it is absent or empty
+func (ctx *Context) QueryDefault(name, def string) string {
+	if value := ctx.Query(name); value != "" {
+		return value
+	}
+	return def
+}
+
+// QueryInt returns the query parameter name as an int, or def if it is
+// absent or empty. A value that is not an integer returns def and an error
+// that can be sent to the client as is.
+func (ctx *Context) QueryInt(name string, def int) (int, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return def, fmt.Errorf("query parameter %q must be an integer, got %q", name, value)
+	}
+	return n, nil
+}
+
+// QueryBool returns the query parameter name as a bool, or def if it is
+// absent or empty. Values are those strconv.ParseBool accepts, such as
+// true, false, 1 and 0.
+func (ctx *Context) QueryBool(name string, def bool) (bool, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return def, fmt.Errorf("query parameter %q must be true or false, got %q", name, value)
+	}
+	return b, nil
+}
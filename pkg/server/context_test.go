@@ -0,0 +1,106 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bindUser struct {
+	Name    string   `json:"name"`
+	Age     int      `json:"age"`
+	Email   string   `db:"email_address"`
+	Admin   bool     // matched by name
+	Tags    []string `json:"tags"`
+	private string
+}
+
+func TestContext_BindJSON(t *testing.T) {
+	var user bindUser
+	s := NewServer()
+	require.NoError(t, s.RegisterRoute(&Route{
+		Method: POST,
+		Path:   "/users",
+		Handler: func(ctx *Context) error {
+			if err := ctx.BindJSON(&user); err != nil {
+				return SendError(ctx, http.StatusBadRequest, err.Error())
+			}
+			return SendJSON(ctx, http.StatusCreated, user.Name)
+		},
+	}))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/users", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		s.GetHandler().ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"name": "ada", "age": 36, "email_address": "ada@example.com", "admin": true, "tags": ["math"], "private": "x"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, bindUser{Name: "ada", Age: 36, Email: "ada@example.com", Admin: true, Tags: []string{"math"}}, user)
+
+	w = post(`{"name": "bob", "age": "old"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid JSON body")
+
+	w = post(`{"email_address": 7}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `field \"email_address\"`)
+}
+
+func TestContext_BindJSONErrors(t *testing.T) {
+	var user bindUser
+	assert.ErrorContains(t, (&Context{}).BindJSON(&user), "no JSON body")
+
+	ctx := &Context{Body: map[string]interface{}{"name": "ada"}}
+	assert.ErrorContains(t, ctx.BindJSON(user), "non-nil pointer")
+
+	var fields map[string]interface{}
+	require.NoError(t, ctx.BindJSON(&fields))
+	assert.Equal(t, "ada", fields["name"])
+}
+
+func TestContext_QueryGetters(t *testing.T) {
+	ctx := &Context{QueryParams: map[string][]string{
+		"page":    {"3", "4"},
+		"limit":   {"ten"},
+		"active":  {"true"},
+		"deleted": {"maybe"},
+		"sort":    {""},
+	}}
+
+	assert.Equal(t, "3", ctx.Query("page"), "the first value is used")
+	assert.Equal(t, "", ctx.Query("missing"))
+	assert.Equal(t, "name", ctx.QueryDefault("sort", "name"), "an empty value takes the default")
+	assert.Equal(t, "3", ctx.QueryDefault("page", "1"))
+
+	page, err := ctx.QueryInt("page", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, page)
+
+	perPage, err := ctx.QueryInt("per_page", 20)
+	require.NoError(t, err)
+	assert.Equal(t, 20, perPage)
+
+	limit, err := ctx.QueryInt("limit", 10)
+	assert.EqualError(t, err, `query parameter "limit" must be an integer, got "ten"`)
+	assert.Equal(t, 10, limit)
+
+	active, err := ctx.QueryBool("active", false)
+	require.NoError(t, err)
+	assert.True(t, active)
+
+	archived, err := ctx.QueryBool("archived", true)
+	require.NoError(t, err)
+	assert.True(t, archived)
+
+	deleted, err := ctx.QueryBool("deleted", false)
+	assert.EqualError(t, err, `query parameter "deleted" must be true or false, got "maybe"`)
+	assert.False(t, deleted)
+}
@@ -0,0 +1,355 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Supported response media types
+const (
+	MIMEJSON    = "application/json"
+	MIMEXML     = "application/xml"
+	MIMEMsgPack = "application/msgpack"
+)
+
+// EncoderFunc writes data to w in a specific media type
+type EncoderFunc func(w io.Writer, data interface{}) error
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]EncoderFunc{
+		MIMEJSON:    encodeJSON,
+		MIMEXML:     encodeXML,
+		MIMEMsgPack: encodeMsgPack,
+	}
+	// mimeAliases maps alternative media type names to their canonical form
+	mimeAliases = map[string]string{
+		"text/xml":              MIMEXML,
+		"application/x-msgpack": MIMEMsgPack,
+		"text/json":             MIMEJSON,
+	}
+)
+
+// RegisterEncoder registers (or replaces) the encoder used for the given media type
+func RegisterEncoder(mime string, fn EncoderFunc) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	encoders[strings.ToLower(strings.TrimSpace(mime))] = fn
+}
+
+// RegisteredEncoders returns the media types that currently have an encoder, sorted
+func RegisteredEncoders() []string {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	mimes := make([]string, 0, len(encoders))
+	for mime := range encoders {
+		mimes = append(mimes, mime)
+	}
+	sort.Strings(mimes)
+	return mimes
+}
+
+// lookupEncoder returns the encoder registered for mime, resolving aliases
+func lookupEncoder(mime string) (EncoderFunc, string, bool) {
+	if canonical, ok := mimeAliases[mime]; ok {
+		mime = canonical
+	}
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	fn, ok := encoders[mime]
+	return fn, mime, ok
+}
+
+// acceptRange is a single media range parsed from an Accept header
+type acceptRange struct {
+	mime  string
+	q     float64
+	order int
+}
+
+// NegotiateEncoder selects the encoder for an Accept header value.
+// Media ranges are ranked by q-value, then by specificity, then by the order
+// they appear in. Wildcards, an empty header, or a header with no registered
+// match resolve to JSON.
+func NegotiateEncoder(accept string) (string, EncoderFunc) {
+	ranges := parseAccept(accept)
+	for _, r := range ranges {
+		if r.q <= 0 {
+			continue
+		}
+		if r.mime == "*/*" || r.mime == "application/*" {
+			break
+		}
+		if fn, mime, ok := lookupEncoder(r.mime); ok {
+			return mime, fn
+		}
+	}
+
+	fn, _, _ := lookupEncoder(MIMEJSON)
+	if fn == nil {
+		fn = encodeJSON
+	}
+	return MIMEJSON, fn
+}
+
+// PrefersHTML reports whether an Accept header value ranks text/html above
+// every other media type the client accepts, as browsers send it
+func PrefersHTML(accept string) bool {
+	for _, r := range parseAccept(accept) {
+		if r.q > 0 {
+			return r.mime == "text/html"
+		}
+	}
+	return false
+}
+
+// parseAccept parses an Accept header into media ranges sorted by preference
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for i, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mime := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mime == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || strings.ToLower(strings.TrimSpace(key)) != "q" {
+				continue
+			}
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+		ranges = append(ranges, acceptRange{mime: mime, q: q, order: i})
+	}
+
+	sort.SliceStable(ranges, func(a, b int) bool {
+		if ranges[a].q != ranges[b].q {
+			return ranges[a].q > ranges[b].q
+		}
+		return specificity(ranges[a].mime) > specificity(ranges[b].mime)
+	})
+	return ranges
+}
+
+// specificity ranks concrete types above subtype and full wildcards
+func specificity(mime string) int {
+	switch {
+	case mime == "*/*":
+		return 0
+	case strings.HasSuffix(mime, "/*"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// encodeJSON is the default JSON encoder
+func encodeJSON(w io.Writer, data interface{}) error {
+	return json.NewEncoder(w).Encode(data)
+}
+
+// normalize converts data into the generic JSON data model (maps, slices,
+// strings, float64, bool, nil) so that non-JSON encoders honour json tags
+func normalize(data interface{}) (interface{}, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+// encodeXML encodes data as XML wrapped in a <response> root element.
+// Object keys become child elements and array items become <item> elements.
+func encodeXML(w io.Writer, data interface{}) error {
+	generic, err := normalize(data)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if err := writeXMLElement(enc, "response", generic); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+// writeXMLElement writes a single named element for a generic value
+func writeXMLElement(enc *xml.Encoder, name string, value interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: xmlName(name)}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := writeXMLElement(enc, k, v[k]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := writeXMLElement(enc, "item", item); err != nil {
+				return err
+			}
+		}
+	case nil:
+		// Empty element
+	case float64:
+		if err := enc.EncodeToken(xml.CharData(strconv.FormatFloat(v, 'f', -1, 64))); err != nil {
+			return err
+		}
+	default:
+		if err := enc.EncodeToken(xml.CharData(fmt.Sprint(v))); err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// xmlName sanitizes an object key into a valid XML element name
+func xmlName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		valid := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+			(i > 0 && (r == '-' || r == '.' || (r >= '0' && r <= '9')))
+		if valid {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// encodeMsgPack encodes data using the MessagePack format
+func encodeMsgPack(w io.Writer, data interface{}) error {
+	generic, err := normalize(data)
+	if err != nil {
+		return err
+	}
+	buf := appendMsgPack(nil, generic)
+	_, err = w.Write(buf)
+	return err
+}
+
+// appendMsgPack appends the MessagePack encoding of a generic value to buf
+func appendMsgPack(buf []byte, value interface{}) []byte {
+	switch v := value.(type) {
+	case nil:
+		return append(buf, 0xc0)
+	case bool:
+		if v {
+			return append(buf, 0xc3)
+		}
+		return append(buf, 0xc2)
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return appendMsgPackInt(buf, int64(v))
+		}
+		buf = append(buf, 0xcb)
+		return binary.BigEndian.AppendUint64(buf, math.Float64bits(v))
+	case string:
+		n := len(v)
+		switch {
+		case n < 32:
+			buf = append(buf, 0xa0|byte(n))
+		case n <= math.MaxUint8:
+			buf = append(buf, 0xd9, byte(n))
+		case n <= math.MaxUint16:
+			buf = append(buf, 0xda)
+			buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+		default:
+			buf = append(buf, 0xdb)
+			buf = binary.BigEndian.AppendUint32(buf, uint32(n)) // #nosec G115 -- bounded by request body limits
+		}
+		return append(buf, v...)
+	case []interface{}:
+		n := len(v)
+		switch {
+		case n < 16:
+			buf = append(buf, 0x90|byte(n))
+		case n <= math.MaxUint16:
+			buf = append(buf, 0xdc)
+			buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+		default:
+			buf = append(buf, 0xdd)
+			buf = binary.BigEndian.AppendUint32(buf, uint32(n)) // #nosec G115 -- bounded by request body limits
+		}
+		for _, item := range v {
+			buf = appendMsgPack(buf, item)
+		}
+		return buf
+	case map[string]interface{}:
+		n := len(v)
+		switch {
+		case n < 16:
+			buf = append(buf, 0x80|byte(n))
+		case n <= math.MaxUint16:
+			buf = append(buf, 0xde)
+			buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+		default:
+			buf = append(buf, 0xdf)
+			buf = binary.BigEndian.AppendUint32(buf, uint32(n)) // #nosec G115 -- bounded by request body limits
+		}
+		keys := make([]string, 0, n)
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			buf = appendMsgPack(buf, k)
+			buf = appendMsgPack(buf, v[k])
+		}
+		return buf
+	default:
+		return appendMsgPack(buf, fmt.Sprint(v))
+	}
+}
+
+// appendMsgPackInt appends the most compact MessagePack integer encoding of v
+func appendMsgPackInt(buf []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v <= 127:
+		return append(buf, byte(v))
+	case v < 0 && v >= -32:
+		return append(buf, byte(v)) // #nosec G115 -- negative fixint
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		return append(buf, 0xd0, byte(v)) // #nosec G115 -- range checked
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		buf = append(buf, 0xd1)
+		return binary.BigEndian.AppendUint16(buf, uint16(v)) // #nosec G115 -- range checked
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		buf = append(buf, 0xd2)
+		return binary.BigEndian.AppendUint32(buf, uint32(v)) // #nosec G115 -- range checked
+	default:
+		buf = append(buf, 0xd3)
+		return binary.BigEndian.AppendUint64(buf, uint64(v)) // #nosec G115 -- two's complement encoding
+	}
+}
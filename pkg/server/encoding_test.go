@@ -0,0 +1,339 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNegotiateEncoder tests Accept-driven encoder selection
+func TestNegotiateEncoder(t *testing.T) {
+	tests := []struct {
+		name     string
+		accept   string
+		expected string
+	}{
+		{"empty header defaults to JSON", "", MIMEJSON},
+		{"wildcard defaults to JSON", "*/*", MIMEJSON},
+		{"application wildcard defaults to JSON", "application/*", MIMEJSON},
+		{"explicit JSON", "application/json", MIMEJSON},
+		{"explicit XML", "application/xml", MIMEXML},
+		{"text/xml alias", "text/xml", MIMEXML},
+		{"explicit msgpack", "application/msgpack", MIMEMsgPack},
+		{"x-msgpack alias", "application/x-msgpack", MIMEMsgPack},
+		{"unknown type falls back to JSON", "image/png", MIMEJSON},
+		{"first of equal preference wins", "application/xml, application/json", MIMEXML},
+		{"q-value ranking", "application/xml;q=0.5, application/msgpack;q=0.9", MIMEMsgPack},
+		{"specific beats wildcard", "*/*, application/xml", MIMEXML},
+		{"wildcard with higher q wins", "*/*;q=1.0, application/xml;q=0.1", MIMEJSON},
+		{"q=0 is not acceptable", "application/xml;q=0, */*;q=0.1", MIMEJSON},
+		{"browser style header", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", MIMEXML},
+		{"case insensitive", "Application/XML", MIMEXML},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mime, fn := NegotiateEncoder(tt.accept)
+			assert.Equal(t, tt.expected, mime)
+			assert.NotNil(t, fn)
+		})
+	}
+}
+
+// TestPrefersHTML tests detecting clients that want an HTML page
+func TestPrefersHTML(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"text/html", true},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", true},
+		{"application/json, text/html;q=0.9", false},
+		{"text/html;q=0, application/json", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			assert.Equal(t, tt.expected, PrefersHTML(tt.accept))
+		})
+	}
+}
+
+// TestSendContentNegotiation tests that Send honours the Accept header
+func TestSendContentNegotiation(t *testing.T) {
+	tests := []struct {
+		accept      string
+		contentType string
+	}{
+		{"", MIMEJSON},
+		{"*/*", MIMEJSON},
+		{"application/xml", MIMEXML},
+		{"application/msgpack", MIMEMsgPack},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			ctx := &Context{Request: req, ResponseWriter: w}
+
+			err := Send(ctx, http.StatusCreated, map[string]interface{}{"id": 1, "name": "Alice"})
+			require.NoError(t, err)
+
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.Equal(t, tt.contentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", w.Header().Get("Vary"))
+		})
+	}
+}
+
+// TestHandlerInterpreterContentNegotiation tests negotiation for interpreter-backed routes
+func TestHandlerInterpreterContentNegotiation(t *testing.T) {
+	router := NewRouter()
+	require.NoError(t, router.RegisterRoute(&Route{Method: GET, Path: "/api/user"}))
+	handler := NewHandler(router, &MockInterpreter{Response: map[string]interface{}{"name": "Alice"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+	req.Header.Set("Accept", "application/xml")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, MIMEXML, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "<response><name>Alice</name></response>")
+}
+
+// TestRegisterEncoder tests registering a custom encoder
+func TestRegisterEncoder(t *testing.T) {
+	const mime = "text/csv"
+	RegisterEncoder(mime, func(w io.Writer, data interface{}) error {
+		_, err := io.WriteString(w, "csv")
+		return err
+	})
+	defer func() {
+		encodersMu.Lock()
+		delete(encoders, mime)
+		encodersMu.Unlock()
+	}()
+
+	assert.Contains(t, RegisteredEncoders(), mime)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/csv")
+	w := httptest.NewRecorder()
+	require.NoError(t, Send(&Context{Request: req, ResponseWriter: w}, http.StatusOK, nil))
+
+	assert.Equal(t, mime, w.Header().Get("Content-Type"))
+	assert.Equal(t, "csv", w.Body.String())
+}
+
+// TestHealthResponseRoundTrip tests that HealthResponse survives every registered encoder
+func TestHealthResponseRoundTrip(t *testing.T) {
+	response := &HealthResponse{
+		Status: StatusDegraded,
+		Checks: map[string]*CheckResult{
+			"database": {Status: StatusHealthy, LatencyMs: 12, Message: "ok"},
+			"cache":    {Status: StatusUnhealthy, Error: "connection refused"},
+		},
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	expected, err := normalize(response)
+	require.NoError(t, err)
+
+	decoders := map[string]func([]byte) (interface{}, error){
+		MIMEJSON: func(b []byte) (interface{}, error) {
+			var v interface{}
+			err := json.Unmarshal(b, &v)
+			return v, err
+		},
+		MIMEXML: func(b []byte) (interface{}, error) {
+			return decodeTestXML(b)
+		},
+		MIMEMsgPack: func(b []byte) (interface{}, error) {
+			v, rest, err := decodeTestMsgPack(b)
+			if err == nil && len(rest) != 0 {
+				err = fmt.Errorf("%d trailing bytes", len(rest))
+			}
+			return v, err
+		},
+	}
+
+	for _, mime := range RegisteredEncoders() {
+		t.Run(mime, func(t *testing.T) {
+			decode, ok := decoders[mime]
+			require.True(t, ok, "no test decoder for %s", mime)
+
+			fn, _, ok := lookupEncoder(mime)
+			require.True(t, ok)
+
+			var buf bytes.Buffer
+			require.NoError(t, fn(&buf, response))
+
+			decoded, err := decode(buf.Bytes())
+			require.NoError(t, err)
+
+			if mime == MIMEXML {
+				// XML carries no type information, so compare string forms
+				assert.Equal(t, stringifyLeaves(expected), decoded)
+				return
+			}
+			assert.Equal(t, expected, decoded)
+		})
+	}
+}
+
+// stringifyLeaves converts every scalar in a generic value to its XML text form
+func stringifyLeaves(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = stringifyLeaves(item)
+		}
+		return out
+	case float64:
+		return fmt.Sprintf("%v", val)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// decodeTestXML decodes XML produced by encodeXML into nested maps of strings
+func decodeTestXML(b []byte) (interface{}, error) {
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return decodeTestXMLElement(dec, start)
+		}
+	}
+}
+
+func decodeTestXMLElement(dec *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	children := map[string]interface{}{}
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := decodeTestXMLElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			children[t.Name.Local] = child
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if len(children) > 0 {
+				return children, nil
+			}
+			return text.String(), nil
+		}
+	}
+}
+
+// decodeTestMsgPack decodes the subset of MessagePack produced by encodeMsgPack
+func decodeTestMsgPack(b []byte) (interface{}, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	tag, b := b[0], b[1:]
+	switch {
+	case tag <= 0x7f:
+		return float64(tag), b, nil
+	case tag >= 0xe0:
+		return float64(int8(tag)), b, nil
+	case tag&0xe0 == 0xa0:
+		n := int(tag & 0x1f)
+		return string(b[:n]), b[n:], nil
+	case tag&0xf0 == 0x90:
+		return decodeTestMsgPackArray(b, int(tag&0x0f))
+	case tag&0xf0 == 0x80:
+		return decodeTestMsgPackMap(b, int(tag&0x0f))
+	}
+
+	switch tag {
+	case 0xc0:
+		return nil, b, nil
+	case 0xc2:
+		return false, b, nil
+	case 0xc3:
+		return true, b, nil
+	case 0xcb:
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), b[8:], nil
+	case 0xd0:
+		return float64(int8(b[0])), b[1:], nil
+	case 0xd1:
+		return float64(int16(binary.BigEndian.Uint16(b))), b[2:], nil
+	case 0xd2:
+		return float64(int32(binary.BigEndian.Uint32(b))), b[4:], nil
+	case 0xd3:
+		return float64(int64(binary.BigEndian.Uint64(b))), b[8:], nil
+	case 0xd9:
+		n := int(b[0])
+		return string(b[1 : 1+n]), b[1+n:], nil
+	case 0xda:
+		n := int(binary.BigEndian.Uint16(b))
+		return string(b[2 : 2+n]), b[2+n:], nil
+	case 0xdc:
+		return decodeTestMsgPackArray(b[2:], int(binary.BigEndian.Uint16(b)))
+	case 0xde:
+		return decodeTestMsgPackMap(b[2:], int(binary.BigEndian.Uint16(b)))
+	}
+	return nil, nil, fmt.Errorf("unsupported msgpack tag 0x%x", tag)
+}
+
+func decodeTestMsgPackArray(b []byte, n int) (interface{}, []byte, error) {
+	out := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		var item interface{}
+		var err error
+		item, b, err = decodeTestMsgPack(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		out = append(out, item)
+	}
+	return out, b, nil
+}
+
+func decodeTestMsgPackMap(b []byte, n int) (interface{}, []byte, error) {
+	out := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		var key, value interface{}
+		var err error
+		if key, b, err = decodeTestMsgPack(b); err != nil {
+			return nil, nil, err
+		}
+		if value, b, err = decodeTestMsgPack(b); err != nil {
+			return nil, nil, err
+		}
+		out[fmt.Sprint(key)] = value
+	}
+	return out, b, nil
+}
@@ -102,12 +102,17 @@ func ExampleServer_customHandler() {
 		Method: server.POST,
 		Path:   "/api/users",
 		Handler: func(ctx *server.Context) error {
-			// Access request body
-			name := ctx.Body["name"].(string)
+			// Decode the request body
+			var input struct {
+				Name string `json:"name"`
+			}
+			if err := ctx.BindJSON(&input); err != nil {
+				return server.SendError(ctx, 400, err.Error())
+			}
 
 			// Send custom response
 			return server.SendJSON(ctx, 201, map[string]interface{}{
-				"message": fmt.Sprintf("User %s created", name),
+				"message": fmt.Sprintf("User %s created", input.Name),
 				"id":      "new-id-123",
 			})
 		},
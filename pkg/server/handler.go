@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -30,18 +31,30 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Try to match the route
 	route, pathParams, err := h.router.Match(method, r.URL.Path)
+	var notAllowed *MethodNotAllowedError
+	if errors.As(err, &notAllowed) {
+		w.Header().Set("Allow", notAllowed.AllowHeader())
+		h.handleError(w, r, http.StatusMethodNotAllowed, "method not allowed", err)
+		return
+	}
 	if err != nil {
 		h.handleError(w, r, http.StatusNotFound, "route not found", err)
 		return
 	}
 
+	// Attach a request ID and track the status actually written
+	r, requestID := WithRequestID(r)
+	w.Header().Set(RequestIDHeader, requestID)
+
 	// Create context
 	ctx := &Context{
 		Request:        r,
-		ResponseWriter: w,
+		ResponseWriter: NewStatusWriter(w),
 		PathParams:     pathParams,
 		QueryParams:    parseQueryParams(r),
 		StatusCode:     http.StatusOK,
+		RequestID:      requestID,
+		RoutePattern:   route.Path,
 	}
 
 	// Parse JSON body if present.
@@ -62,14 +75,11 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			if err != nil {
 				return err
 			}
-			return sendJSONResponse(ctx, result)
+			return sendResponse(ctx, result)
 		}
 	}
 
-	// Apply middlewares in reverse order
-	for i := len(route.Middlewares) - 1; i >= 0; i-- {
-		handler = route.Middlewares[i](handler)
-	}
+	handler = h.router.wrap(route, handler)
 
 	// Execute the handler
 	if err := handler(ctx); err != nil {
@@ -137,7 +147,35 @@ func sendJSONResponse(ctx *Context, data interface{}) error {
 	return nil
 }
 
-// SendJSON is a helper to send JSON responses from handlers
+// sendResponse encodes data using the encoder negotiated from the Accept header
+func sendResponse(ctx *Context, data interface{}) error {
+	accept := ""
+	if ctx.Request != nil {
+		accept = ctx.Request.Header.Get("Accept")
+	}
+	mime, encode := NegotiateEncoder(accept)
+
+	header := ctx.ResponseWriter.Header()
+	header.Set("Content-Type", mime)
+	header.Add("Vary", "Accept")
+	ctx.ResponseWriter.WriteHeader(ctx.StatusCode)
+
+	if err := encode(ctx.ResponseWriter, data); err != nil {
+		return fmt.Errorf("failed to encode %s response: %w", mime, err)
+	}
+
+	return nil
+}
+
+// Send is a helper to send responses from handlers, encoded according to the
+// request's Accept header (JSON by default)
+func Send(ctx *Context, statusCode int, data interface{}) error {
+	ctx.StatusCode = statusCode
+	return sendResponse(ctx, data)
+}
+
+// SendJSON is a helper to send JSON responses from handlers regardless of the
+// Accept header. Prefer Send for content negotiation.
 func SendJSON(ctx *Context, statusCode int, data interface{}) error {
 	ctx.StatusCode = statusCode
 	return sendJSONResponse(ctx, data)
@@ -145,7 +183,7 @@ func SendJSON(ctx *Context, statusCode int, data interface{}) error {
 
 // SendError is a helper to send error responses
 func SendError(ctx *Context, statusCode int, message string) error {
-	return SendJSON(ctx, statusCode, map[string]interface{}{
+	return Send(ctx, statusCode, map[string]interface{}{
 		"error":   true,
 		"message": message,
 		"code":    statusCode,
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"sync"
 	"time"
 )
@@ -35,6 +36,12 @@ type HealthResponse struct {
 	Status    HealthStatus            `json:"status"`
 	Checks    map[string]*CheckResult `json:"checks,omitempty"`
 	Timestamp time.Time               `json:"timestamp"`
+	// CheckedAt is when the checks ran; with periodic checks it is older
+	// than Timestamp by up to the check interval
+	CheckedAt time.Time `json:"checked_at"`
+	// Stale is set when periodic checks have not completed for more than
+	// two intervals, e.g. because a checker hangs
+	Stale bool `json:"stale,omitempty"`
 }
 
 // HealthChecker is an interface for components that can report their health
@@ -70,11 +77,27 @@ func NewHealthCheckFunc(name string, fn func(ctx context.Context) *CheckResult)
 	}
 }
 
+// HealthStatusChangeFunc is called when a component's status changes
+type HealthStatusChangeFunc func(component string, old, new HealthStatus)
+
 // HealthManager manages health checks and provides endpoints
 type HealthManager struct {
 	checkers map[string]HealthChecker
 	mu       sync.RWMutex
 	timeout  time.Duration
+
+	// interval is the period of background checks; 0 runs the checks on
+	// every request
+	interval time.Duration
+	cancel   context.CancelFunc // Stops the background checks
+	done     chan struct{}      // Closed once the background checks return
+
+	// stateMu guards the latest results, the last known status of each
+	// component and the change callbacks
+	stateMu   sync.Mutex
+	latest    *HealthResponse
+	statuses  map[string]HealthStatus
+	onChanges []HealthStatusChangeFunc
 }
 
 // HealthManagerOption is a functional option for configuring the health manager
@@ -87,20 +110,160 @@ func WithHealthCheckTimeout(timeout time.Duration) HealthManagerOption {
 	}
 }
 
+// WithPeriodicChecks runs the checks in the background every interval
+// instead of on each request. The health endpoints then serve the latest
+// results, so slow checks are not paid by every probe. Call Shutdown to stop
+// the background checks.
+func WithPeriodicChecks(interval time.Duration) HealthManagerOption {
+	return func(hm *HealthManager) {
+		hm.interval = interval
+	}
+}
+
 // NewHealthManager creates a new health manager
 func NewHealthManager(options ...HealthManagerOption) *HealthManager {
 	hm := &HealthManager{
 		checkers: make(map[string]HealthChecker),
 		timeout:  5 * time.Second, // Default 5 second timeout
+		statuses: make(map[string]HealthStatus),
 	}
 
 	for _, opt := range options {
 		opt(hm)
 	}
 
+	if hm.interval > 0 {
+		var ctx context.Context
+		ctx, hm.cancel = context.WithCancel(context.Background())
+		hm.done = make(chan struct{})
+		go hm.runPeriodicChecks(ctx)
+	}
+
 	return hm
 }
 
+// runPeriodicChecks runs the checks every interval until ctx is cancelled,
+// which also cancels a run in progress
+func (hm *HealthManager) runPeriodicChecks(ctx context.Context) {
+	defer close(hm.done)
+
+	ticker := time.NewTicker(hm.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			response := hm.runChecks(ctx)
+			// Results cut short by Shutdown are not a status change
+			if ctx.Err() != nil {
+				return
+			}
+			hm.record(response)
+		}
+	}
+}
+
+// Shutdown stops the periodic checks, waiting for a run in progress to
+// return. It is a no-op without WithPeriodicChecks and safe to call twice.
+func (hm *HealthManager) Shutdown() {
+	if hm.cancel == nil {
+		return
+	}
+	hm.cancel()
+	<-hm.done
+}
+
+// OnStatusChange registers fn to be called when a component's status
+// differs from the one its previous check reported. Callbacks run in the
+// order they were registered, for components in name order, after the
+// results of the run are stored; they must not call Check.
+func (hm *HealthManager) OnStatusChange(fn HealthStatusChangeFunc) {
+	hm.stateMu.Lock()
+	defer hm.stateMu.Unlock()
+	hm.onChanges = append(hm.onChanges, fn)
+}
+
+// Check runs every checker now, regardless of WithPeriodicChecks, and
+// returns the results. They become the results the health endpoints serve.
+func (hm *HealthManager) Check(ctx context.Context) *HealthResponse {
+	response := hm.runChecks(ctx)
+	hm.record(response)
+	return response
+}
+
+// runChecks runs every checker in parallel within the check timeout
+func (hm *HealthManager) runChecks(ctx context.Context) *HealthResponse {
+	checkCtx, cancel := context.WithTimeout(ctx, hm.timeout)
+	defer cancel()
+
+	// Get all checkers
+	hm.mu.RLock()
+	checkers := make([]HealthChecker, 0, len(hm.checkers))
+	for _, checker := range hm.checkers {
+		checkers = append(checkers, checker)
+	}
+	hm.mu.RUnlock()
+
+	checkedAt := time.Now().UTC()
+	results := hm.performChecks(checkCtx, checkers)
+	return &HealthResponse{
+		Status:    hm.aggregateStatus(results),
+		Checks:    results,
+		Timestamp: time.Now().UTC(),
+		CheckedAt: checkedAt,
+	}
+}
+
+// record stores response as the latest results, unless a run that started
+// later has already finished, and reports the status changes it contains
+func (hm *HealthManager) record(response *HealthResponse) {
+	hm.stateMu.Lock()
+	defer hm.stateMu.Unlock()
+
+	if hm.latest != nil && hm.latest.CheckedAt.After(response.CheckedAt) {
+		return
+	}
+	hm.latest = response
+
+	names := make([]string, 0, len(response.Checks))
+	for name := range response.Checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	previous := hm.statuses
+	hm.statuses = make(map[string]HealthStatus, len(names))
+	for _, name := range names {
+		status := response.Checks[name].Status
+		hm.statuses[name] = status
+		if old, ok := previous[name]; ok && old != status {
+			for _, fn := range hm.onChanges {
+				fn(name, old, status)
+			}
+		}
+	}
+}
+
+// currentHealth returns the results a health endpoint serves: the latest
+// periodic results, or a fresh run when checks are not periodic or have not
+// run yet
+func (hm *HealthManager) currentHealth(ctx context.Context) *HealthResponse {
+	if hm.interval > 0 {
+		hm.stateMu.Lock()
+		latest := hm.latest
+		hm.stateMu.Unlock()
+		if latest != nil {
+			response := *latest
+			response.Timestamp = time.Now().UTC()
+			response.Stale = response.Timestamp.Sub(latest.CheckedAt) > 2*hm.interval
+			return &response
+		}
+	}
+	return hm.Check(ctx)
+}
+
 // RegisterChecker registers a new health checker
 func (hm *HealthManager) RegisterChecker(checker HealthChecker) {
 	hm.mu.Lock()
@@ -125,7 +288,7 @@ func (hm *HealthManager) LivenessHandler() RouteHandler {
 			Timestamp: time.Now().UTC(),
 		}
 
-		return SendJSON(ctx, http.StatusOK, response)
+		return Send(ctx, http.StatusOK, response)
 	}
 }
 
@@ -134,37 +297,15 @@ func (hm *HealthManager) LivenessHandler() RouteHandler {
 // Returns 200 if ready, 503 if not ready
 func (hm *HealthManager) ReadinessHandler() RouteHandler {
 	return func(ctx *Context) error {
-		// Create context with timeout
-		checkCtx, cancel := context.WithTimeout(context.Background(), hm.timeout)
-		defer cancel()
-
-		// Get all checkers
-		hm.mu.RLock()
-		checkers := make([]HealthChecker, 0, len(hm.checkers))
-		for _, checker := range hm.checkers {
-			checkers = append(checkers, checker)
-		}
-		hm.mu.RUnlock()
-
-		// Perform all checks in parallel
-		results := hm.performChecks(checkCtx, checkers)
-
-		// Aggregate status
-		aggregatedStatus := hm.aggregateStatus(results)
-
-		response := &HealthResponse{
-			Status:    aggregatedStatus,
-			Checks:    results,
-			Timestamp: time.Now().UTC(),
-		}
+		response := hm.currentHealth(context.Background())
 
 		// Return 503 if not healthy
 		statusCode := http.StatusOK
-		if aggregatedStatus == StatusUnhealthy {
+		if response.Status == StatusUnhealthy {
 			statusCode = http.StatusServiceUnavailable
 		}
 
-		return SendJSON(ctx, statusCode, response)
+		return Send(ctx, statusCode, response)
 	}
 }
 
@@ -172,31 +313,8 @@ func (hm *HealthManager) ReadinessHandler() RouteHandler {
 // Similar to readiness but always returns 200 with detailed status
 func (hm *HealthManager) HealthHandler() RouteHandler {
 	return func(ctx *Context) error {
-		// Create context with timeout
-		checkCtx, cancel := context.WithTimeout(context.Background(), hm.timeout)
-		defer cancel()
-
-		// Get all checkers
-		hm.mu.RLock()
-		checkers := make([]HealthChecker, 0, len(hm.checkers))
-		for _, checker := range hm.checkers {
-			checkers = append(checkers, checker)
-		}
-		hm.mu.RUnlock()
-
-		// Perform all checks in parallel
-		results := hm.performChecks(checkCtx, checkers)
-
-		// Aggregate status
-		aggregatedStatus := hm.aggregateStatus(results)
-
-		response := &HealthResponse{
-			Status:    aggregatedStatus,
-			Checks:    results,
-			Timestamp: time.Now().UTC(),
-		}
-
-		return SendJSON(ctx, http.StatusOK, response)
+		response := hm.currentHealth(context.Background())
+		return Send(ctx, http.StatusOK, response)
 	}
 }
 
@@ -461,30 +579,10 @@ func LivenessHTTPHandler() http.HandlerFunc {
 // ReadinessHTTPHandler creates a standard http.Handler for readiness checks
 func ReadinessHTTPHandler(hm *HealthManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Create context with timeout
-		ctx, cancel := context.WithTimeout(r.Context(), hm.timeout)
-		defer cancel()
-
-		// Get all checkers
-		hm.mu.RLock()
-		checkers := make([]HealthChecker, 0, len(hm.checkers))
-		for _, checker := range hm.checkers {
-			checkers = append(checkers, checker)
-		}
-		hm.mu.RUnlock()
-
-		// Perform all checks
-		results := hm.performChecks(ctx, checkers)
-		aggregatedStatus := hm.aggregateStatus(results)
-
-		response := &HealthResponse{
-			Status:    aggregatedStatus,
-			Checks:    results,
-			Timestamp: time.Now().UTC(),
-		}
+		response := hm.currentHealth(r.Context())
 
 		statusCode := http.StatusOK
-		if aggregatedStatus == StatusUnhealthy {
+		if response.Status == StatusUnhealthy {
 			statusCode = http.StatusServiceUnavailable
 		}
 
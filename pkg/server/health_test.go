@@ -6,6 +6,8 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -699,6 +701,264 @@ func TestHealthResponse_JSON(t *testing.T) {
 	}
 }
 
+// controllableChecker reports whatever status a test sets and counts its runs.
+// While block is non-nil, Check waits on it or on ctx.
+type controllableChecker struct {
+	name   string
+	mu     sync.Mutex
+	status HealthStatus
+	calls  int
+	block  chan struct{}
+}
+
+func newControllableChecker(name string, status HealthStatus) *controllableChecker {
+	return &controllableChecker{name: name, status: status}
+}
+
+func (c *controllableChecker) Name() string { return c.name }
+
+func (c *controllableChecker) Check(ctx context.Context) *CheckResult {
+	c.mu.Lock()
+	c.calls++
+	status, block := c.status, c.block
+	c.mu.Unlock()
+
+	if block != nil {
+		select {
+		case <-block:
+		case <-ctx.Done():
+			return &CheckResult{Status: StatusUnhealthy, Error: ctx.Err().Error()}
+		}
+	}
+	return &CheckResult{Status: status}
+}
+
+func (c *controllableChecker) set(status HealthStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status = status
+}
+
+func (c *controllableChecker) callCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+// serveHealth runs handler and decodes its response
+func serveHealth(t *testing.T, handler RouteHandler) (int, HealthResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	ctx := &Context{
+		Request:        httptest.NewRequest("GET", "/health", nil),
+		ResponseWriter: w,
+		StatusCode:     http.StatusOK,
+	}
+	if err := handler(ctx); err != nil {
+		t.Fatalf("handler failed: %v", err)
+	}
+	var response HealthResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return w.Code, response
+}
+
+// TestHealthManager_PeriodicChecksServeCachedResults tests that periodic mode
+// serves the latest results instead of running the checkers per request
+func TestHealthManager_PeriodicChecksServeCachedResults(t *testing.T) {
+	// An interval that never elapses during the test, so only the requests
+	// and explicit Check calls run the checker
+	hm := NewHealthManager(WithPeriodicChecks(time.Hour))
+	defer hm.Shutdown()
+	db := newControllableChecker("database", StatusHealthy)
+	hm.RegisterChecker(db)
+
+	// Without results yet, the first request runs the checks
+	_, first := serveHealth(t, hm.HealthHandler())
+	if db.callCount() != 1 {
+		t.Fatalf("expected 1 check, got %d", db.callCount())
+	}
+
+	db.set(StatusUnhealthy)
+	for i := 0; i < 3; i++ {
+		code, response := serveHealth(t, hm.ReadinessHandler())
+		if code != http.StatusOK || response.Status != StatusHealthy {
+			t.Errorf("expected cached healthy 200, got %d %s", code, response.Status)
+		}
+		if !response.CheckedAt.Equal(first.CheckedAt) {
+			t.Errorf("expected checked_at %v, got %v", first.CheckedAt, response.CheckedAt)
+		}
+		if response.Stale {
+			t.Error("expected fresh results not to be stale")
+		}
+	}
+	if db.callCount() != 1 {
+		t.Errorf("expected cached results to skip the checker, got %d checks", db.callCount())
+	}
+
+	// Check forces a fresh run, which the endpoints then serve
+	if forced := hm.Check(context.Background()); forced.Status != StatusUnhealthy {
+		t.Errorf("expected forced check to be unhealthy, got %s", forced.Status)
+	}
+	if db.callCount() != 2 {
+		t.Errorf("expected 2 checks, got %d", db.callCount())
+	}
+	code, response := serveHealth(t, hm.ReadinessHandler())
+	if code != http.StatusServiceUnavailable || response.Status != StatusUnhealthy {
+		t.Errorf("expected unhealthy 503, got %d %s", code, response.Status)
+	}
+	if !response.CheckedAt.After(first.CheckedAt) {
+		t.Error("expected checked_at to advance after Check")
+	}
+}
+
+// TestHealthManager_PeriodicChecksRun tests that the ticker refreshes the
+// results and that Shutdown stops it
+func TestHealthManager_PeriodicChecksRun(t *testing.T) {
+	hm := NewHealthManager(WithPeriodicChecks(10 * time.Millisecond))
+	db := newControllableChecker("database", StatusHealthy)
+	hm.RegisterChecker(db)
+	hm.Check(context.Background())
+
+	changed := make(chan HealthStatus, 1)
+	hm.OnStatusChange(func(component string, old, new HealthStatus) {
+		select {
+		case changed <- new:
+		default:
+		}
+	})
+
+	db.set(StatusDegraded)
+	select {
+	case status := <-changed:
+		if status != StatusDegraded {
+			t.Errorf("expected degraded, got %s", status)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("periodic check did not run")
+	}
+	_, response := serveHealth(t, hm.HealthHandler())
+	if response.Status != StatusDegraded {
+		t.Errorf("expected the periodic results to be served, got %s", response.Status)
+	}
+
+	hm.Shutdown()
+	hm.Shutdown() // a second call is a no-op
+	calls := db.callCount()
+	time.Sleep(50 * time.Millisecond)
+	if db.callCount() != calls {
+		t.Errorf("expected no checks after Shutdown, got %d more", db.callCount()-calls)
+	}
+}
+
+// TestHealthManager_StaleResults tests that results older than two intervals
+// are flagged, and that Shutdown cancels a hung check
+func TestHealthManager_StaleResults(t *testing.T) {
+	hm := NewHealthManager(WithPeriodicChecks(10 * time.Millisecond))
+	db := newControllableChecker("database", StatusHealthy)
+	hm.RegisterChecker(db)
+	hm.Check(context.Background())
+
+	var changes int32
+	hm.OnStatusChange(func(component string, old, new HealthStatus) {
+		atomic.AddInt32(&changes, 1)
+	})
+
+	// The next periodic run hangs
+	db.mu.Lock()
+	db.block = make(chan struct{})
+	db.mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_, response := serveHealth(t, hm.HealthHandler())
+		if response.Stale {
+			if time.Since(response.CheckedAt) <= 20*time.Millisecond {
+				t.Errorf("expected stale results to be older than two intervals, checked at %v", response.CheckedAt)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("results never became stale")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		hm.Shutdown()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Shutdown did not cancel the hung check")
+	}
+	if n := atomic.LoadInt32(&changes); n != 0 {
+		t.Errorf("expected a check cut short by Shutdown not to report changes, got %d", n)
+	}
+}
+
+// TestHealthManager_OnStatusChange tests that callbacks fire only on
+// transitions, in registration order and component name order
+func TestHealthManager_OnStatusChange(t *testing.T) {
+	hm := NewHealthManager()
+	cache := newControllableChecker("cache", StatusHealthy)
+	db := newControllableChecker("database", StatusHealthy)
+	hm.RegisterChecker(db)
+	hm.RegisterChecker(cache)
+
+	var events []string
+	for _, label := range []string{"first", "second"} {
+		label := label
+		hm.OnStatusChange(func(component string, old, new HealthStatus) {
+			events = append(events, label+" "+component+" "+string(old)+"->"+string(new))
+		})
+	}
+
+	hm.Check(context.Background())
+	if len(events) != 0 {
+		t.Fatalf("expected no callbacks for the first results, got %v", events)
+	}
+
+	db.set(StatusUnhealthy)
+	cache.set(StatusDegraded)
+	hm.Check(context.Background())
+	hm.Check(context.Background()) // unchanged, no callbacks
+
+	db.set(StatusHealthy)
+	// A request runs the checks too when they are not periodic
+	serveHealth(t, hm.HealthHandler())
+
+	expected := []string{
+		"first cache healthy->degraded",
+		"second cache healthy->degraded",
+		"first database healthy->unhealthy",
+		"second database healthy->unhealthy",
+		"first database unhealthy->healthy",
+		"second database unhealthy->healthy",
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected events %v, got %v", expected, events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("event %d: expected %q, got %q", i, expected[i], events[i])
+		}
+	}
+}
+
+// TestHealthManager_ShutdownWithoutPeriodicChecks tests that Shutdown is a
+// no-op for a manager that checks on demand
+func TestHealthManager_ShutdownWithoutPeriodicChecks(t *testing.T) {
+	hm := NewHealthManager()
+	hm.Shutdown()
+	if response := hm.Check(context.Background()); response.Status != StatusHealthy {
+		t.Errorf("expected healthy, got %s", response.Status)
+	}
+}
+
 // Benchmark tests
 func BenchmarkHealthManager_LivenessHandler(b *testing.B) {
 	hm := NewHealthManager()
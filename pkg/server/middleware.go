@@ -5,12 +5,18 @@ import (
 	"crypto/subtle"
 	"encoding/hex"
 	"log"
+	"net"
 	"net/http"
 	"runtime/debug"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/glyphlang/glyph/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // sanitizeLog replaces newlines and carriage returns in user-controlled values
@@ -63,7 +69,7 @@ func RecoveryMiddleware() Middleware {
 					method := ctx.Request.Method
 					path := ctx.Request.URL.Path
 					// Log full panic details including stack trace to server logs
-					log.Printf("[PANIC] %s %s: %v\n%s", sanitizeLog(method), sanitizeLog(path), r, debug.Stack()) // #nosec G706 -- sanitized
+					log.Printf("[PANIC] %s %s (request %s): %v\n%s", sanitizeLog(method), sanitizeLog(path), sanitizeLog(ctx.RequestID), r, debug.Stack()) // #nosec G706 -- sanitized
 					// Return generic error to client - don't expose panic details
 					SendError(ctx, 500, "Internal Server Error")
 					// Return error to indicate a panic was recovered
@@ -170,10 +176,12 @@ func SecurityHeadersMiddleware() Middleware {
 	}
 }
 
-// ChainMiddlewares combines multiple middlewares into one
+// ChainMiddlewares combines multiple middlewares into one. The first is the
+// outermost, so ChainMiddlewares(A, B) runs A, then B, then the handler, and
+// returns through B before A.
 func ChainMiddlewares(middlewares ...Middleware) Middleware {
 	return func(next RouteHandler) RouteHandler {
-		// Apply middlewares in reverse order
+		// Wrap from the innermost out
 		handler := next
 		for i := len(middlewares) - 1; i >= 0; i-- {
 			handler = middlewares[i](handler)
@@ -421,6 +429,18 @@ func getClientIP(r *http.Request, trustProxy bool) string {
 	return r.RemoteAddr
 }
 
+// ClientIP returns the IP address of the client that sent r, without its
+// port. With trustProxy the X-Forwarded-For and X-Real-IP headers are
+// honored as for the rate limiters, so the IP is that of the client behind
+// a reverse proxy.
+func ClientIP(r *http.Request, trustProxy bool) string {
+	ip := getClientIP(r, trustProxy)
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		return host
+	}
+	return ip
+}
+
 // RateLimitMiddleware is a placeholder for rate limiting middleware
 // In production, this would use a proper rate limiter (e.g., token bucket, Redis)
 type RateLimiterConfig struct {
@@ -612,30 +632,54 @@ func (tw *timeoutWriter) Write(b []byte) (int, error) {
 	return tw.ResponseWriter.Write(b)
 }
 
-// TracingMiddleware creates a middleware that adds OpenTelemetry distributed tracing
-// This middleware integrates with the pkg/tracing package
-// It should be added early in the middleware chain to trace the entire request lifecycle
-//
-// Usage:
-//
-//	import "github.com/glyphlang/glyph/pkg/tracing"
+// TracingMiddleware starts a server span for each request, continuing the
+// trace of an incoming traceparent header. The span is named after the
+// method and route pattern ("GET /users/:id") and records the status code;
+// handlers find it in ctx.Request.Context() to start child spans. Health
+// and metrics endpoints are not traced.
 //
-//	config := tracing.DefaultMiddlewareConfig()
-//	server := NewServer(
-//	    WithMiddleware(TracingMiddleware(config)),
-//	)
-//
-// Note: This requires the tracing package to be initialized first:
-//
-//	tp, err := tracing.InitTracing(tracing.DefaultConfig())
-//	defer tp.Shutdown(context.Background())
-func TracingMiddleware(config interface{}) Middleware {
-	// The actual implementation is in pkg/tracing/integration.go
-	// This is just a placeholder that can be replaced when tracing is properly initialized
+// Spans are exported by the provider installed with tracing.InitTracing;
+// without one the global no-op provider discards them.
+func TracingMiddleware() Middleware {
+	excluded := tracing.ExcludedPaths()
 	return func(next RouteHandler) RouteHandler {
 		return func(ctx *Context) error {
-			// If tracing is not initialized, just pass through
-			return next(ctx)
+			if excluded[ctx.Request.URL.Path] {
+				return next(ctx)
+			}
+
+			route := ctx.RoutePattern
+			if route == "" {
+				route = ctx.Request.URL.Path
+			}
+			reqCtx := tracing.ExtractContext(ctx.Request.Context(), ctx.Request)
+			reqCtx, span := tracing.StartSpan(reqCtx, ctx.Request.Method+" "+route,
+				tracing.SpanKind.Server,
+				trace.WithAttributes(
+					attribute.String("http.method", ctx.Request.Method),
+					attribute.String("http.route", route),
+					attribute.String("http.target", ctx.Request.URL.Path),
+				))
+			defer span.End()
+			ctx.Request = ctx.Request.WithContext(reqCtx)
+
+			err := next(ctx)
+
+			status := ctx.StatusCode
+			if err != nil {
+				status = http.StatusInternalServerError
+			} else if sw, ok := ctx.ResponseWriter.(*StatusWriter); ok {
+				status = sw.Status()
+			} else if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(attribute.Int("http.status_code", status))
+			if err != nil {
+				tracing.SetError(reqCtx, err)
+			} else if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			return err
 		}
 	}
 }
@@ -8,6 +8,12 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // TestRecoveryMiddleware_PanicRecovery tests that panics are caught and converted to 500 errors
@@ -895,6 +901,26 @@ func TestGetClientIP(t *testing.T) {
 	}
 }
 
+// TestClientIP tests that ClientIP strips the port from RemoteAddr and
+// only honors proxy headers when trusted
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.RemoteAddr = "192.168.1.100:12345"
+	req.Header.Set("X-Forwarded-For", "203.0.113.195, 10.0.0.1")
+
+	if ip := ClientIP(req, false); ip != "192.168.1.100" {
+		t.Errorf("ClientIP(untrusted) = %q, want %q", ip, "192.168.1.100")
+	}
+	if ip := ClientIP(req, true); ip != "203.0.113.195" {
+		t.Errorf("ClientIP(trusted) = %q, want %q", ip, "203.0.113.195")
+	}
+
+	req.RemoteAddr = "[::1]:8080"
+	if ip := ClientIP(req, false); ip != "::1" {
+		t.Errorf("ClientIP(IPv6) = %q, want %q", ip, "::1")
+	}
+}
+
 // TestAuthRateLimiting tests auth rate limiting with lockout
 func TestAuthRateLimiting(t *testing.T) {
 	config := AuthRateLimitConfig{
@@ -981,3 +1007,74 @@ func TestDefaultAuthRateLimitConfig(t *testing.T) {
 		t.Errorf("ResetAfter = %v, want 15 minutes", config.ResetAfter)
 	}
 }
+
+// TestTracingMiddleware tests that each traced request gets a server span
+// named after its route pattern, and that health endpoints are skipped
+func TestTracingMiddleware(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(prev)
+
+	var handlerSpan trace.SpanContext
+	handler := TracingMiddleware()(func(ctx *Context) error {
+		handlerSpan = trace.SpanContextFromContext(ctx.Request.Context())
+		if ctx.PathParams["id"] == "0" {
+			return errors.New("lookup failed")
+		}
+		return nil
+	})
+
+	run := func(path string, params map[string]string) error {
+		ctx := &Context{
+			Request:        httptest.NewRequest(http.MethodGet, path, nil),
+			ResponseWriter: NewStatusWriter(httptest.NewRecorder()),
+			PathParams:     params,
+			RoutePattern:   "/users/:id",
+		}
+		return handler(ctx)
+	}
+
+	if err := run("/users/7", map[string]string{"id": "7"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := run("/users/0", map[string]string{"id": "0"}); err == nil {
+		t.Fatal("expected the handler error to be returned")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	for _, span := range spans {
+		if span.Name != "GET /users/:id" {
+			t.Errorf("expected span name 'GET /users/:id', got %q", span.Name)
+		}
+		if span.SpanKind != trace.SpanKindServer {
+			t.Errorf("expected a server span, got %v", span.SpanKind)
+		}
+	}
+	if spans[1].SpanContext.SpanID() != handlerSpan.SpanID() {
+		t.Error("expected the handler to see the request span in its context")
+	}
+	if spans[0].Status.Code != codes.Unset {
+		t.Errorf("expected an unset status for a 200, got %v", spans[0].Status.Code)
+	}
+	if spans[1].Status.Code != codes.Error {
+		t.Errorf("expected an error status, got %v", spans[1].Status.Code)
+	}
+
+	exporter.Reset()
+	ctx := &Context{
+		Request:        httptest.NewRequest(http.MethodGet, "/health", nil),
+		ResponseWriter: httptest.NewRecorder(),
+		RoutePattern:   "/health",
+	}
+	if err := handler(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := len(exporter.GetSpans()); n != 0 {
+		t.Errorf("expected /health not to be traced, got %d spans", n)
+	}
+}
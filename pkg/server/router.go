@@ -2,12 +2,17 @@ package server
 
 import (
 	"fmt"
+	"net/http"
+	"slices"
 	"strings"
 )
 
 // Router manages route registration and matching
 type Router struct {
-	routes map[HTTPMethod][]*RouteNode
+	routes      map[HTTPMethod][]*RouteNode
+	middlewares []Middleware
+	notFound    RouteHandler
+	onError     RouteHandler
 }
 
 // RouteNode represents a node in the route tree
@@ -52,29 +57,195 @@ func (r *Router) RegisterRoute(route *Route) error {
 	return nil
 }
 
-// Match finds a matching route for the given method and path
+// Use adds middlewares that run around every route's handler, outermost
+// first
+func (r *Router) Use(middlewares ...Middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// Handler returns route's handler wrapped in the router's middlewares and
+// then the route's own
+func (r *Router) Handler(route *Route) RouteHandler {
+	return r.wrap(route, route.Handler)
+}
+
+// wrap returns handler wrapped in the router's middlewares and then route's
+// own. Within each list the first middleware is the outermost: it is entered
+// first and exited last. The body of a response to a HEAD request is
+// discarded, leaving its status and headers.
+func (r *Router) wrap(route *Route, handler RouteHandler) RouteHandler {
+	chained := ChainMiddlewares(r.middlewares...)(ChainMiddlewares(route.Middlewares...)(handler))
+	return func(ctx *Context) error {
+		if ctx.Request == nil || ctx.Request.Method != http.MethodHead {
+			return chained(ctx)
+		}
+		hctx := *ctx
+		hctx.ResponseWriter = &headWriter{ResponseWriter: ctx.ResponseWriter}
+		err := chained(&hctx)
+		ctx.StatusCode = hctx.StatusCode
+		return err
+	}
+}
+
+// headWriter discards the body of a response to a HEAD request
+type headWriter struct {
+	http.ResponseWriter
+}
+
+// Write discards b
+func (w *headWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// SetNotFound sets the handler that answers requests no route matches.
+// It is not wrapped in the router's middlewares.
+func (r *Router) SetNotFound(handler RouteHandler) {
+	r.notFound = handler
+}
+
+// NotFound returns the handler set with SetNotFound, or nil
+func (r *Router) NotFound() RouteHandler {
+	return r.notFound
+}
+
+// SetErrorHandler sets the handler that answers in place of the built-in
+// error responses
+func (r *Router) SetErrorHandler(handler RouteHandler) {
+	r.onError = handler
+}
+
+// ErrorHandler returns the handler set with SetErrorHandler, or nil
+func (r *Router) ErrorHandler() RouteHandler {
+	return r.onError
+}
+
+// MethodNotAllowedError is the error of Match for a path that routes match,
+// none of them of the method requested. It is answered with a 405.
+type MethodNotAllowedError struct {
+	Method  HTTPMethod
+	Path    string
+	Allowed []HTTPMethod // The methods the path can be requested with
+}
+
+func (e *MethodNotAllowedError) Error() string {
+	return fmt.Sprintf("method %s not allowed for path %s", e.Method, e.Path)
+}
+
+// AllowHeader returns the value of the Allow header of the 405 response
+func (e *MethodNotAllowedError) AllowHeader() string {
+	return allowHeader(e.Allowed)
+}
+
+// Match finds a matching route for the given method and path. Without a
+// route of their own, HEAD requests match the GET route, whose response
+// body is discarded, and OPTIONS requests match a route that lists the
+// methods of the path in an Allow header. A path that routes of other
+// methods match is a *MethodNotAllowedError.
 func (r *Router) Match(method HTTPMethod, path string) (*Route, map[string]string, error) {
-	routes, exists := r.routes[method]
-	if !exists {
+	path = cleanPath(path)
+	if node, params := r.match(method, path); node != nil {
+		return node.route, params, nil
+	}
+
+	switch method {
+	case HEAD:
+		if node, params := r.match(GET, path); node != nil {
+			return node.route, params, nil
+		}
+	case OPTIONS:
+		if allowed, node, params := r.allowedMethods(path); node != nil {
+			return optionsRoute(node.route.Path, allowed), params, nil
+		}
+	}
+
+	if allowed := r.AllowedMethods(path); allowed != nil {
+		return nil, nil, &MethodNotAllowedError{Method: method, Path: path, Allowed: allowed}
+	}
+	if _, exists := r.routes[method]; !exists {
 		return nil, nil, fmt.Errorf("no routes registered for method %s", method)
 	}
+	return nil, nil, fmt.Errorf("no route matches path %s", path)
+}
 
-	// Clean the path
-	path = strings.TrimSpace(path)
-	if !strings.HasPrefix(path, "/") {
-		path = "/" + path
+// AllowedMethods returns the methods that path can be requested with, in
+// alphabetical order: those of the routes matching it, HEAD when one of
+// them is GET, and OPTIONS. It returns nil when no route matches path.
+func (r *Router) AllowedMethods(path string) []HTTPMethod {
+	allowed, _, _ := r.allowedMethods(cleanPath(path))
+	return allowed
+}
+
+// allowedMethods returns the methods path can be requested with, and the
+// route of the first of them to match it with its path parameters
+func (r *Router) allowedMethods(path string) ([]HTTPMethod, *RouteNode, map[string]string) {
+	var allowed []HTTPMethod
+	for method := range r.routes {
+		if node, _ := r.match(method, path); node != nil {
+			allowed = append(allowed, method)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil, nil, nil
+	}
+	if slices.Contains(allowed, GET) && !slices.Contains(allowed, HEAD) {
+		allowed = append(allowed, HEAD)
+	}
+	if !slices.Contains(allowed, OPTIONS) {
+		allowed = append(allowed, OPTIONS)
+	}
+	slices.Sort(allowed)
+	for _, method := range allowed {
+		if node, params := r.match(method, path); node != nil {
+			return allowed, node, params
+		}
 	}
+	return allowed, nil, nil
+}
 
+// match returns the first route of method matching path and its path
+// parameters, or nil
+func (r *Router) match(method HTTPMethod, path string) (*RouteNode, map[string]string) {
 	pathSegments := splitPath(path)
-
-	// Try to match routes in order
-	for _, node := range routes {
+	for _, node := range r.routes[method] {
 		if params, matched := matchRoute(node, pathSegments); matched {
-			return node.route, params, nil
+			return node, params
 		}
 	}
+	return nil, nil
+}
+
+// optionsRoute returns the route answering an OPTIONS request for the
+// route pattern path with a 204 whose Allow header lists allowed
+func optionsRoute(path string, allowed []HTTPMethod) *Route {
+	allow := allowHeader(allowed)
+	return &Route{
+		Method: OPTIONS,
+		Path:   path,
+		Handler: func(ctx *Context) error {
+			ctx.ResponseWriter.Header().Set("Allow", allow)
+			ctx.StatusCode = http.StatusNoContent
+			ctx.ResponseWriter.WriteHeader(http.StatusNoContent)
+			return nil
+		},
+	}
+}
 
-	return nil, nil, fmt.Errorf("no route matches path %s", path)
+// allowHeader returns the value of an Allow header listing allowed
+func allowHeader(allowed []HTTPMethod) string {
+	methods := make([]string, len(allowed))
+	for i, method := range allowed {
+		methods[i] = string(method)
+	}
+	return strings.Join(methods, ", ")
+}
+
+// cleanPath trims path and makes it start with a slash
+func cleanPath(path string) string {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
 }
 
 // GetRoutes returns all registered routes for a method
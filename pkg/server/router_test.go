@@ -99,11 +99,68 @@ func TestRouterNoRoutesForMethod(t *testing.T) {
 	router := NewRouter()
 	router.RegisterRoute(&Route{Method: GET, Path: "/api/users"})
 
-	_, _, err := router.Match(DELETE, "/api/users")
+	_, _, err := router.Match(DELETE, "/api/posts")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "no routes registered")
 }
 
+func TestRouterMethodNotAllowed(t *testing.T) {
+	router := NewRouter()
+	require.NoError(t, router.RegisterRoute(&Route{Method: GET, Path: "/health"}))
+	require.NoError(t, router.RegisterRoute(&Route{Method: POST, Path: "/api/users"}))
+
+	_, _, err := router.Match(DELETE, "/health")
+	var notAllowed *MethodNotAllowedError
+	require.ErrorAs(t, err, &notAllowed)
+	assert.Equal(t, []HTTPMethod{GET, HEAD, OPTIONS}, notAllowed.Allowed)
+	assert.Equal(t, "GET, HEAD, OPTIONS", notAllowed.AllowHeader())
+
+	// A path no route matches is not found
+	_, _, err = router.Match(DELETE, "/status")
+	require.Error(t, err)
+	assert.NotErrorAs(t, err, &notAllowed)
+	_, _, err = router.Match(GET, "/status")
+	require.Error(t, err)
+	assert.NotErrorAs(t, err, &notAllowed)
+}
+
+func TestRouterHeadMatchesGet(t *testing.T) {
+	router := NewRouter()
+	require.NoError(t, router.RegisterRoute(&Route{Method: GET, Path: "/api/users/:id"}))
+
+	matched, params, err := router.Match(HEAD, "/api/users/7")
+	require.NoError(t, err)
+	assert.Equal(t, GET, matched.Method)
+	assert.Equal(t, "7", params["id"])
+
+	_, _, err = router.Match(HEAD, "/api/posts")
+	assert.Error(t, err)
+}
+
+func TestRouterAllowedMethods(t *testing.T) {
+	router := NewRouter()
+	for _, route := range []*Route{
+		{Method: GET, Path: "/api/users/:id"},
+		{Method: PUT, Path: "/api/users/:id"},
+		{Method: DELETE, Path: "/api/users/:id"},
+		{Method: POST, Path: "/api/users"},
+	} {
+		require.NoError(t, router.RegisterRoute(route))
+	}
+
+	assert.Equal(t, []HTTPMethod{DELETE, GET, HEAD, OPTIONS, PUT}, router.AllowedMethods("/api/users/7"))
+	assert.Equal(t, []HTTPMethod{OPTIONS, POST}, router.AllowedMethods("/api/users"))
+	assert.Nil(t, router.AllowedMethods("/api/posts"))
+
+	matched, _, err := router.Match(OPTIONS, "/api/users/7")
+	require.NoError(t, err)
+	assert.Equal(t, OPTIONS, matched.Method)
+	assert.Equal(t, "/api/users/:id", matched.Path)
+
+	_, _, err = router.Match(OPTIONS, "/api/posts")
+	assert.Error(t, err)
+}
+
 func TestServerNewServerDefaults(t *testing.T) {
 	s := NewServer()
 	assert.NotNil(t, s)
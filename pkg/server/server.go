@@ -3,8 +3,10 @@ package server
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/glyphlang/glyph/pkg/config"
@@ -20,6 +22,7 @@ type Server struct {
 	middlewares []Middleware
 	addr        string
 	wsServer    *ws.Server // WebSocket server
+	accessLog   io.Writer  // JSON access log destination (nil disables)
 }
 
 // ServerOption is a functional option for configuring the server
@@ -59,18 +62,29 @@ func WithAddr(addr string) ServerOption {
 	}
 }
 
-// WithMiddleware adds a global middleware to the server
+// WithMiddleware adds a global middleware to the server. Global middlewares
+// run in the order they are added, the first outermost, and all of them run
+// around the route's own middlewares.
 func WithMiddleware(middleware Middleware) ServerOption {
 	return func(s *Server) {
 		s.middlewares = append(s.middlewares, middleware)
 	}
 }
 
+// WithAccessLog writes one JSON access log line per request to out
+func WithAccessLog(out io.Writer) ServerOption {
+	return func(s *Server) {
+		s.accessLog = out
+	}
+}
+
 // RegisterRoute registers a single route
 func (s *Server) RegisterRoute(route *Route) error {
-	// Add global middlewares to the route
+	// Add global middlewares to the route. The result is a new slice, since
+	// appending to s.middlewares could write into a backing array shared by
+	// every route and replace one route's middlewares with another's.
 	if len(s.middlewares) > 0 {
-		route.Middlewares = append(s.middlewares, route.Middlewares...)
+		route.Middlewares = slices.Concat(s.middlewares, route.Middlewares)
 	}
 
 	return s.router.RegisterRoute(route)
@@ -92,9 +106,14 @@ func (s *Server) Start(addr string) error {
 		s.addr = addr
 	}
 
+	var handler http.Handler = s.handler
+	if s.accessLog != nil {
+		handler = AccessLogHandler(handler, JSONAccessLogger(s.accessLog))
+	}
+
 	s.httpServer = &http.Server{
 		Addr:         s.addr,
-		Handler:      s.handler,
+		Handler:      handler,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -119,7 +138,9 @@ func (s *Server) Stop(ctx context.Context) error {
 
 	// Shutdown WebSocket server first
 	if s.wsServer != nil {
-		s.wsServer.Shutdown()
+		if err := s.wsServer.Shutdown(ctx); err != nil {
+			log.Printf("[SERVER] WebSocket shutdown error: %v", err)
+		}
 	}
 
 	if err := s.httpServer.Shutdown(ctx); err != nil {
@@ -349,9 +349,58 @@ func TestHandlerMethodNotAllowed(t *testing.T) {
 
 	server.GetHandler().ServeHTTP(w, req)
 
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, HEAD, OPTIONS", w.Header().Get("Allow"))
+
+	// A path without routes is still not found
+	req = httptest.NewRequest("POST", "/api/posts", nil)
+	w = httptest.NewRecorder()
+
+	server.GetHandler().ServeHTTP(w, req)
+
 	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
+// TestHandlerHead tests that HEAD is answered by the GET route with its
+// headers and without a body
+func TestHandlerHead(t *testing.T) {
+	server := NewServer(WithInterpreter(&MockInterpreter{}))
+	server.RegisterRoute(&Route{
+		Method: GET,
+		Path:   "/api/users",
+	})
+
+	req := httptest.NewRequest("HEAD", "/api/users", nil)
+	w := httptest.NewRecorder()
+
+	server.GetHandler().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Body.String())
+}
+
+// TestHandlerOptions tests that OPTIONS lists the methods of a path in the
+// Allow header
+func TestHandlerOptions(t *testing.T) {
+	server := NewServer(WithInterpreter(&MockInterpreter{}))
+	for _, method := range []HTTPMethod{GET, POST, DELETE} {
+		server.RegisterRoute(&Route{
+			Method: method,
+			Path:   "/api/users",
+		})
+	}
+
+	req := httptest.NewRequest("OPTIONS", "/api/users", nil)
+	w := httptest.NewRecorder()
+
+	server.GetHandler().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "DELETE, GET, HEAD, OPTIONS, POST", w.Header().Get("Allow"))
+	assert.Empty(t, w.Body.String())
+}
+
 // TestMiddlewareExecution tests middleware chain execution
 func TestMiddlewareExecution(t *testing.T) {
 	_ = NewRouter() // Placeholder for future use
@@ -400,6 +449,73 @@ func TestMiddlewareExecution(t *testing.T) {
 	}, executionOrder)
 }
 
+// recordingMiddleware appends name's entry and exit to order
+func recordingMiddleware(order *[]string, name string) Middleware {
+	return func(next RouteHandler) RouteHandler {
+		return func(ctx *Context) error {
+			*order = append(*order, name+"-enter")
+			err := next(ctx)
+			*order = append(*order, name+"-exit")
+			return err
+		}
+	}
+}
+
+// TestGlobalMiddlewareOrder checks that the first middleware given to
+// NewServer is the outermost
+func TestGlobalMiddlewareOrder(t *testing.T) {
+	var order []string
+	s := NewServer(
+		WithMiddleware(recordingMiddleware(&order, "A")),
+		WithMiddleware(recordingMiddleware(&order, "B")),
+	)
+	require.NoError(t, s.RegisterRoute(&Route{
+		Method:      GET,
+		Path:        "/test",
+		Middlewares: []Middleware{recordingMiddleware(&order, "route")},
+		Handler: func(ctx *Context) error {
+			order = append(order, "handler")
+			return SendJSON(ctx, http.StatusOK, "ok")
+		},
+	}))
+
+	w := httptest.NewRecorder()
+	s.GetHandler().ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{
+		"A-enter", "B-enter", "route-enter", "handler", "route-exit", "B-exit", "A-exit",
+	}, order)
+}
+
+// TestGlobalMiddlewaresKeepRouteMiddlewaresApart registers routes with
+// middlewares of their own after three global ones, which leaves spare
+// capacity in the global list that the routes must not share
+func TestGlobalMiddlewaresKeepRouteMiddlewaresApart(t *testing.T) {
+	var order []string
+	s := NewServer(
+		WithInterpreter(&MockInterpreter{Response: "ok"}),
+		WithMiddleware(recordingMiddleware(&order, "A")),
+		WithMiddleware(recordingMiddleware(&order, "B")),
+		WithMiddleware(recordingMiddleware(&order, "C")),
+	)
+	for _, path := range []string{"/one", "/two"} {
+		require.NoError(t, s.RegisterRoute(&Route{
+			Method:      GET,
+			Path:        path,
+			Middlewares: []Middleware{recordingMiddleware(&order, path)},
+		}))
+	}
+
+	w := httptest.NewRecorder()
+	s.GetHandler().ServeHTTP(w, httptest.NewRequest("GET", "/one", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{
+		"A-enter", "B-enter", "C-enter", "/one-enter", "/one-exit", "C-exit", "B-exit", "A-exit",
+	}, order)
+}
+
 // TestCustomHandler tests custom route handlers
 func TestCustomHandler(t *testing.T) {
 	_ = NewRouter() // Placeholder for future use
@@ -6,11 +6,13 @@ import "net/http"
 type HTTPMethod string
 
 const (
-	GET    HTTPMethod = "GET"
-	POST   HTTPMethod = "POST"
-	PUT    HTTPMethod = "PUT"
-	DELETE HTTPMethod = "DELETE"
-	PATCH  HTTPMethod = "PATCH"
+	GET     HTTPMethod = "GET"
+	POST    HTTPMethod = "POST"
+	PUT     HTTPMethod = "PUT"
+	DELETE  HTTPMethod = "DELETE"
+	PATCH   HTTPMethod = "PATCH"
+	HEAD    HTTPMethod = "HEAD"
+	OPTIONS HTTPMethod = "OPTIONS"
 )
 
 // Route represents a parsed GLYPH route definition
@@ -32,6 +34,8 @@ type Context struct {
 	QueryParams    map[string][]string // All values for each query param
 	Body           map[string]interface{}
 	StatusCode     int
+	RequestID      string // Value of X-Request-ID, generated when absent
+	RoutePattern   string // Registered pattern of the matched route, e.g. /users/:id
 }
 
 // Middleware is a function that wraps a handler
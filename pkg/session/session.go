@@ -0,0 +1,435 @@
+// Package session implements cookies and sessions for routes: the cookies a
+// request sent, the Set-Cookie headers a route adds, and a session kept in
+// an HMAC-signed cookie or, with a Store, on the server.
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/jsonvalue"
+)
+
+// DefaultCookieName is the cookie that holds the session
+const DefaultCookieName = "glyph_session"
+
+// DefaultMaxAge is how long a session lasts after it was last saved
+const DefaultMaxAge = 24 * time.Hour
+
+// maxCookieSize is the largest cookie browsers are guaranteed to keep
+const maxCookieSize = 4096
+
+// Store keeps sessions on the server, so the cookie holds only a signed
+// session ID. *cache.ValueCache satisfies it.
+type Store interface {
+	Get(key string) (interface{}, bool, error)
+	Set(key string, value interface{}, ttl time.Duration) error
+	Delete(key string) error
+}
+
+// Manager signs and verifies session cookies
+type Manager struct {
+	secret     []byte
+	cookieName string
+	maxAge     time.Duration
+	store      Store
+	secure     bool
+}
+
+// Option configures a Manager
+type Option func(*Manager)
+
+// WithStore keeps session data in store instead of in the cookie
+func WithStore(store Store) Option {
+	return func(m *Manager) { m.store = store }
+}
+
+// WithCookieName sets the session cookie's name
+func WithCookieName(name string) Option {
+	return func(m *Manager) { m.cookieName = name }
+}
+
+// WithMaxAge sets how long a session lasts after it was last saved
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(m *Manager) { m.maxAge = maxAge }
+}
+
+// WithSecure marks the session cookie Secure even on plain HTTP requests,
+// for servers behind a TLS-terminating proxy
+func WithSecure(secure bool) Option {
+	return func(m *Manager) { m.secure = secure }
+}
+
+// NewManager creates a manager that signs cookies with secret, which must
+// be at least 32 bytes
+func NewManager(secret []byte, opts ...Option) (*Manager, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("session secret must be at least 32 bytes, got %d", len(secret))
+	}
+	m := &Manager{secret: secret, cookieName: DefaultCookieName, maxAge: DefaultMaxAge}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+// Begin starts the cookie and session state of one request
+func (m *Manager) Begin(r *http.Request) *Request {
+	return &Request{m: m, r: r}
+}
+
+// Request is the cookie and session state of one request. Its methods may
+// be called from several goroutines.
+type Request struct {
+	m *Manager
+	r *http.Request
+
+	mu      sync.Mutex
+	pending []*http.Cookie
+	loaded  bool
+	data    map[string]interface{}
+	id      string // Server-side session ID, when the manager has a store
+	dirty   bool
+}
+
+// Cookies returns the cookies the request sent, by name
+func (q *Request) Cookies() map[string]string {
+	cookies := make(map[string]string)
+	for _, c := range q.r.Cookies() {
+		if _, seen := cookies[c.Name]; !seen {
+			cookies[c.Name] = c.Value
+		}
+	}
+	return cookies
+}
+
+// SetCookie adds a Set-Cookie header to the response. options may hold
+// maxAge (seconds), httpOnly, secure, sameSite ("lax", "strict" or "none"),
+// path and domain. Cookies default to path "/", HttpOnly, SameSite=Lax, and
+// Secure on HTTPS requests.
+func (q *Request) SetCookie(name, value string, options map[string]interface{}) error {
+	if !validCookieName(name) {
+		return fmt.Errorf("invalid cookie name %q", name)
+	}
+	c := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   q.r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if err := applyOptions(c, options); err != nil {
+		return err
+	}
+	if err := c.Valid(); err != nil {
+		return fmt.Errorf("invalid cookie %q: %w", name, err)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, c)
+	return nil
+}
+
+// ClearCookie tells the browser to delete a cookie set with path "/"
+func (q *Request) ClearCookie(name string) error {
+	if !validCookieName(name) {
+		return fmt.Errorf("invalid cookie name %q", name)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, expiredCookie(name))
+	return nil
+}
+
+// Session returns the request's session
+func (q *Request) Session() *Session {
+	return &Session{q: q}
+}
+
+// Apply saves a changed session and adds the pending Set-Cookie headers to
+// w. It must be called before the response header is written; later calls
+// add only what changed since.
+func (q *Request) Apply(w http.ResponseWriter) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.dirty {
+		if err := q.save(); err != nil {
+			return err
+		}
+		q.dirty = false
+	}
+	for _, c := range q.pending {
+		w.Header().Add("Set-Cookie", c.String())
+	}
+	q.pending = nil
+	return nil
+}
+
+// Session is a request's session data
+type Session struct {
+	q *Request
+}
+
+// Get returns the value stored under key, or nil
+func (s *Session) Get(key string) (interface{}, error) {
+	s.q.mu.Lock()
+	defer s.q.mu.Unlock()
+	if err := s.q.load(); err != nil {
+		return nil, err
+	}
+	return s.q.data[key], nil
+}
+
+// Set stores a value in the session; nil removes the key
+func (s *Session) Set(key string, value interface{}) error {
+	s.q.mu.Lock()
+	defer s.q.mu.Unlock()
+	if err := s.q.load(); err != nil {
+		return err
+	}
+	if value == nil {
+		delete(s.q.data, key)
+	} else {
+		s.q.data[key] = value
+	}
+	s.q.dirty = true
+	return nil
+}
+
+// Regenerate keeps the session's data under a new ID, so an ID that was
+// known before, such as one planted ahead of a login, no longer reaches it.
+// A session kept in its cookie is signed again.
+func (s *Session) Regenerate() error {
+	s.q.mu.Lock()
+	defer s.q.mu.Unlock()
+	if err := s.q.load(); err != nil {
+		return err
+	}
+	if s.q.id != "" && s.q.m.store != nil {
+		if err := s.q.m.store.Delete(s.q.storeKey()); err != nil {
+			return fmt.Errorf("session store: %w", err)
+		}
+	}
+	s.q.id = ""
+	s.q.dirty = true
+	return nil
+}
+
+// Destroy empties the session and deletes its cookie, so the next request
+// starts a new one
+func (s *Session) Destroy() error {
+	s.q.mu.Lock()
+	defer s.q.mu.Unlock()
+	if err := s.q.load(); err != nil {
+		return err
+	}
+	if s.q.id != "" && s.q.m.store != nil {
+		if err := s.q.m.store.Delete(s.q.storeKey()); err != nil {
+			return fmt.Errorf("session store: %w", err)
+		}
+	}
+	s.q.data = make(map[string]interface{})
+	s.q.id = ""
+	s.q.dirty = false
+	s.q.pending = append(s.q.pending, expiredCookie(s.q.m.cookieName))
+	return nil
+}
+
+// load reads the session from the request's cookie the first time it is
+// needed. A missing, tampered or expired cookie gives an empty session.
+func (q *Request) load() error {
+	if q.loaded {
+		return nil
+	}
+	q.loaded = true
+	q.data = make(map[string]interface{})
+	c, err := q.r.Cookie(q.m.cookieName)
+	if err != nil {
+		return nil
+	}
+	payload, ok := q.m.verify(c.Value)
+	if !ok {
+		return nil
+	}
+
+	if q.m.store == nil {
+		var stored cookieSession
+		if err := jsonvalue.Unmarshal(payload, &stored); err != nil || time.Now().Unix() > stored.Expires {
+			return nil
+		}
+		if data, ok := jsonvalue.Normalize(stored.Data).(map[string]interface{}); ok {
+			q.data = data
+		}
+		return nil
+	}
+
+	q.id = string(payload)
+	value, found, err := q.m.store.Get(q.storeKey())
+	if err != nil {
+		return fmt.Errorf("session store: %w", err)
+	}
+	if data, ok := value.(map[string]interface{}); found && ok {
+		q.data = data
+	} else {
+		q.id = ""
+	}
+	return nil
+}
+
+// save writes the session to its cookie or store and queues the cookie
+func (q *Request) save() error {
+	c := &http.Cookie{
+		Name:     q.m.cookieName,
+		Path:     "/",
+		MaxAge:   int(q.m.maxAge / time.Second),
+		HttpOnly: true,
+		Secure:   q.m.secure || q.r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if q.m.store == nil {
+		payload, err := json.Marshal(cookieSession{Data: q.data, Expires: time.Now().Add(q.m.maxAge).Unix()})
+		if err != nil {
+			return fmt.Errorf("session: %w", err)
+		}
+		c.Value = q.m.sign(payload)
+		if len(c.String()) > maxCookieSize {
+			return fmt.Errorf("session is too large for a cookie (%d bytes); configure a session store", len(c.String()))
+		}
+	} else {
+		if q.id == "" {
+			var b [32]byte
+			if _, err := rand.Read(b[:]); err != nil {
+				return fmt.Errorf("session: %w", err)
+			}
+			q.id = hex.EncodeToString(b[:])
+		}
+		if err := q.m.store.Set(q.storeKey(), q.data, q.m.maxAge); err != nil {
+			return fmt.Errorf("session store: %w", err)
+		}
+		c.Value = q.m.sign([]byte(q.id))
+	}
+	q.pending = append(q.pending, c)
+	return nil
+}
+
+func (q *Request) storeKey() string {
+	return "session:" + q.id
+}
+
+// cookieSession is the signed payload of a session kept in its cookie
+type cookieSession struct {
+	Data    map[string]interface{} `json:"d"`
+	Expires int64                  `json:"e"`
+}
+
+// sign returns payload and its HMAC-SHA256, both base64url-encoded
+func (m *Manager) sign(payload []byte) string {
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(m.mac(encoded))
+}
+
+// verify returns the payload of a value made by sign, if its signature
+// is valid
+func (m *Manager) verify(value string) ([]byte, bool) {
+	encoded, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, false
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, m.mac(encoded)) {
+		return nil, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false
+	}
+	return payload, true
+}
+
+func (m *Manager) mac(encoded string) []byte {
+	h := hmac.New(sha256.New, m.secret)
+	h.Write([]byte(encoded))
+	return h.Sum(nil)
+}
+
+// applyOptions sets a cookie's attributes from setCookie's options object
+func applyOptions(c *http.Cookie, options map[string]interface{}) error {
+	for key, value := range options {
+		switch key {
+		case "maxAge":
+			seconds, ok := value.(int64)
+			if !ok {
+				return fmt.Errorf("cookie maxAge must be an integer (seconds), got %T", value)
+			}
+			// MaxAge 0 means no Max-Age attribute; a negative one deletes
+			c.MaxAge = int(seconds)
+			if seconds == 0 {
+				c.MaxAge = -1
+			}
+		case "httpOnly", "secure":
+			b, ok := value.(bool)
+			if !ok {
+				return fmt.Errorf("cookie %s must be a boolean, got %T", key, value)
+			}
+			if key == "httpOnly" {
+				c.HttpOnly = b
+			} else {
+				c.Secure = b
+			}
+		case "sameSite":
+			s, _ := value.(string)
+			switch strings.ToLower(s) {
+			case "lax":
+				c.SameSite = http.SameSiteLaxMode
+			case "strict":
+				c.SameSite = http.SameSiteStrictMode
+			case "none":
+				c.SameSite = http.SameSiteNoneMode
+				c.Secure = true
+			default:
+				return fmt.Errorf(`cookie sameSite must be "lax", "strict" or "none", got %v`, value)
+			}
+		case "path", "domain":
+			s, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("cookie %s must be a string, got %T", key, value)
+			}
+			if key == "path" {
+				c.Path = s
+			} else {
+				c.Domain = s
+			}
+		default:
+			return fmt.Errorf("unknown cookie option %q", key)
+		}
+	}
+	return nil
+}
+
+// expiredCookie deletes the cookie name set with path "/"
+func expiredCookie(name string) *http.Cookie {
+	return &http.Cookie{Name: name, Path: "/", MaxAge: -1, HttpOnly: true}
+}
+
+// validCookieName reports whether name is a valid cookie name token
+func validCookieName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`()<>@,;:\"/[]?={}`, r) {
+			return false
+		}
+	}
+	return true
+}
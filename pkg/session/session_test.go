@@ -0,0 +1,208 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glyphlang/glyph/pkg/cache"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// roundTrip runs fn against a request carrying cookies and returns the
+// cookies it set
+func roundTrip(t *testing.T, m *Manager, cookies []*http.Cookie, fn func(*Request)) []*http.Cookie {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	q := m.Begin(r)
+	fn(q)
+	rec := httptest.NewRecorder()
+	if err := q.Apply(rec); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	return rec.Result().Cookies()
+}
+
+func sessionValue(t *testing.T, m *Manager, cookies []*http.Cookie, key string) interface{} {
+	t.Helper()
+	var value interface{}
+	roundTrip(t, m, cookies, func(q *Request) {
+		var err error
+		if value, err = q.Session().Get(key); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+	})
+	return value
+}
+
+func TestNewManager_ShortSecret(t *testing.T) {
+	if _, err := NewManager([]byte("short")); err == nil {
+		t.Error("expected an error for a short secret")
+	}
+}
+
+func TestCookieSession_RoundTrip(t *testing.T) {
+	m, err := NewManager(testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := roundTrip(t, m, nil, func(q *Request) {
+		if err := q.Session().Set("user", map[string]interface{}{"id": int64(7), "score": 1.5}); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	})
+	if len(cookies) != 1 || cookies[0].Name != DefaultCookieName {
+		t.Fatalf("expected the session cookie, got %v", cookies)
+	}
+
+	user, ok := sessionValue(t, m, cookies, "user").(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected the stored object, got %v", user)
+	}
+	if user["id"] != int64(7) || user["score"] != 1.5 {
+		t.Errorf("numbers not restored: %#v", user)
+	}
+
+	// Another secret cannot read it
+	other, _ := NewManager([]byte("fedcba9876543210fedcba9876543210"))
+	if v := sessionValue(t, other, cookies, "user"); v != nil {
+		t.Errorf("expected no session with another secret, got %v", v)
+	}
+}
+
+func TestCookieSession_Expired(t *testing.T) {
+	m, _ := NewManager(testSecret, WithMaxAge(-time.Second))
+	cookies := roundTrip(t, m, nil, func(q *Request) {
+		q.Session().Set("user", "ada")
+	})
+	// The browser would have dropped it; a replayed cookie is refused too
+	cookies[0].MaxAge = 0
+	if v := sessionValue(t, m, cookies, "user"); v != nil {
+		t.Errorf("expected an expired session to be empty, got %v", v)
+	}
+}
+
+func TestCookieSession_TooLarge(t *testing.T) {
+	m, _ := NewManager(testSecret)
+	q := m.Begin(httptest.NewRequest(http.MethodGet, "/", nil))
+	q.Session().Set("blob", strings.Repeat("x", maxCookieSize))
+	err := q.Apply(httptest.NewRecorder())
+	if err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("expected a too large error, got %v", err)
+	}
+}
+
+func TestStoreSession(t *testing.T) {
+	store := cache.NewValueCache(cache.NewMemoryStore(10))
+	defer store.Close()
+	m, _ := NewManager(testSecret, WithStore(store))
+
+	cookies := roundTrip(t, m, nil, func(q *Request) {
+		q.Session().Set("user", "ada")
+	})
+	if len(cookies) != 1 || strings.Contains(cookies[0].Value, "ada") {
+		t.Fatalf("expected a cookie holding only the session ID, got %v", cookies)
+	}
+	if v := sessionValue(t, m, cookies, "user"); v != "ada" {
+		t.Errorf("expected ada, got %v", v)
+	}
+
+	destroyed := roundTrip(t, m, cookies, func(q *Request) {
+		if err := q.Session().Destroy(); err != nil {
+			t.Fatalf("Destroy: %v", err)
+		}
+	})
+	if len(destroyed) != 1 || destroyed[0].MaxAge != -1 {
+		t.Errorf("expected the cookie to be deleted, got %v", destroyed)
+	}
+	// The old cookie no longer finds a session
+	if v := sessionValue(t, m, cookies, "user"); v != nil {
+		t.Errorf("expected a destroyed session to be empty, got %v", v)
+	}
+}
+
+func TestStoreSession_Regenerate(t *testing.T) {
+	store := cache.NewValueCache(cache.NewMemoryStore(10))
+	defer store.Close()
+	m, _ := NewManager(testSecret, WithStore(store))
+
+	cookies := roundTrip(t, m, nil, func(q *Request) {
+		q.Session().Set("cart", "book")
+	})
+	regenerated := roundTrip(t, m, cookies, func(q *Request) {
+		if err := q.Session().Regenerate(); err != nil {
+			t.Fatalf("Regenerate: %v", err)
+		}
+		q.Session().Set("user", "ada")
+	})
+	if len(regenerated) != 1 || regenerated[0].Value == cookies[0].Value {
+		t.Fatalf("expected a cookie with a new session ID, got %v", regenerated)
+	}
+	if v := sessionValue(t, m, regenerated, "cart"); v != "book" {
+		t.Errorf("expected the data to move to the new ID, got %v", v)
+	}
+	if v := sessionValue(t, m, regenerated, "user"); v != "ada" {
+		t.Errorf("expected ada, got %v", v)
+	}
+	// The old ID no longer reaches the session
+	if v := sessionValue(t, m, cookies, "user"); v != nil {
+		t.Errorf("expected the old session ID to be empty, got %v", v)
+	}
+	if v := sessionValue(t, m, cookies, "cart"); v != nil {
+		t.Errorf("expected the old session ID to be empty, got %v", v)
+	}
+}
+
+func TestSetCookie_Options(t *testing.T) {
+	m, _ := NewManager(testSecret)
+	tests := []struct {
+		options map[string]interface{}
+		want    string
+		wantErr string
+	}{
+		{nil, "a=1; Path=/; HttpOnly; SameSite=Lax", ""},
+		{map[string]interface{}{"maxAge": int64(60), "httpOnly": false, "path": "/app"}, "a=1; Path=/app; Max-Age=60; SameSite=Lax", ""},
+		{map[string]interface{}{"sameSite": "none"}, "a=1; Path=/; HttpOnly; Secure; SameSite=None", ""},
+		{map[string]interface{}{"sameSite": "sometimes"}, "", "sameSite"},
+		{map[string]interface{}{"maxAge": "60"}, "", "maxAge"},
+		{map[string]interface{}{"expires": int64(1)}, "", "unknown cookie option"},
+	}
+	for _, tt := range tests {
+		q := m.Begin(httptest.NewRequest(http.MethodGet, "/", nil))
+		err := q.SetCookie("a", "1", tt.options)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%v: expected error containing %q, got %v", tt.options, tt.wantErr, err)
+			}
+			continue
+		}
+		rec := httptest.NewRecorder()
+		if err != nil || q.Apply(rec) != nil {
+			t.Fatalf("%v: %v", tt.options, err)
+		}
+		if got := rec.Header().Get("Set-Cookie"); got != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.options, got, tt.want)
+		}
+	}
+
+	q := m.Begin(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err := q.SetCookie("bad name", "1", nil); err == nil {
+		t.Error("expected an error for an invalid cookie name")
+	}
+}
+
+func TestCookies(t *testing.T) {
+	m, _ := NewManager(testSecret)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", "theme=dark; lang=en; theme=light")
+	cookies := m.Begin(r).Cookies()
+	if len(cookies) != 2 || cookies["theme"] != "dark" || cookies["lang"] != "en" {
+		t.Errorf("unexpected cookies %v", cookies)
+	}
+}
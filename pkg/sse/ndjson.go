@@ -0,0 +1,57 @@
+package sse
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// NDJSONWriter streams events as newline-delimited JSON, one value per line,
+// for clients that would rather read a chunked response than parse SSE.
+type NDJSONWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	mu      sync.Mutex
+}
+
+// NewNDJSONWriter creates a new NDJSONWriter and sets the response headers.
+// Returns an error if the ResponseWriter does not support flushing.
+func NewNDJSONWriter(w http.ResponseWriter) (*NDJSONWriter, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("response writer does not support flushing (required for NDJSON streaming)")
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &NDJSONWriter{
+		w:       w,
+		flusher: flusher,
+	}, nil
+}
+
+// SendEvent writes data as one JSON line and flushes the response. A named
+// event is written as {"event": eventType, "data": data}.
+func (nw *NDJSONWriter) SendEvent(data interface{}, eventType string) error {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+
+	if eventType != "" {
+		data = map[string]interface{}{"event": eventType, "data": data}
+	}
+	line, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to format event data: %w", err)
+	}
+	if _, err := nw.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+
+	nw.flusher.Flush()
+	return nil
+}
@@ -152,3 +152,18 @@ func TestWriter_MultipleEvents(t *testing.T) {
 	assert.Contains(t, body, "data: beginning\n")
 	assert.Contains(t, body, "data: done\n")
 }
+
+func TestNDJSONWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	nw, err := NewNDJSONWriter(rec)
+	require.NoError(t, err)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+
+	require.NoError(t, nw.SendEvent(map[string]interface{}{"count": 1}, ""))
+	require.NoError(t, nw.SendEvent("line\nbreak", ""))
+	require.NoError(t, nw.SendEvent(2, "tick"))
+
+	assert.Equal(t, "{\"count\":1}\n\"line\\nbreak\"\n{\"data\":2,\"event\":\"tick\"}\n", rec.Body.String())
+	assert.True(t, rec.Flushed)
+}
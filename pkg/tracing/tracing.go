@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
 	"go.opentelemetry.io/otel"
@@ -68,6 +69,34 @@ func DefaultConfig() *Config {
 	}
 }
 
+// ConfigFromEnv returns the configuration given by the standard OpenTelemetry
+// environment variables. Tracing is enabled only when
+// OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set,
+// in which case spans are exported over OTLP to that endpoint.
+// OTEL_SERVICE_NAME names the service, OTEL_TRACES_SAMPLER_ARG sets the
+// sampling rate, and OTEL_SDK_DISABLED=true turns tracing off.
+func ConfigFromEnv() *Config {
+	config := DefaultConfig()
+	config.ExporterType = "otlp"
+	config.Enabled = otlpEndpointFromEnv() && IsTracingEnabled()
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		config.ServiceName = name
+	}
+	if arg := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); arg != "" {
+		if rate, err := strconv.ParseFloat(arg, 64); err == nil {
+			config.SamplingRate = rate
+		}
+	}
+	return config
+}
+
+// otlpEndpointFromEnv reports whether an OTLP endpoint is set in the
+// environment, which the OTLP exporter reads itself
+func otlpEndpointFromEnv() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" ||
+		os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
 // TracerProvider wraps the OpenTelemetry tracer provider
 type TracerProvider struct {
 	provider *sdktrace.TracerProvider
@@ -99,13 +128,19 @@ func InitTracing(config *Config) (*TracerProvider, error) {
 			stdouttrace.WithPrettyPrint(),
 		)
 	case "otlp":
-		if config.OTLPEndpoint == "" {
+		// Without an explicit endpoint the client reads the OTEL_EXPORTER_OTLP_*
+		// variables, which also choose between TLS and plaintext
+		if config.OTLPEndpoint == "" && !otlpEndpointFromEnv() {
 			config.OTLPEndpoint = "localhost:4317"
 		}
-		client := otlptracegrpc.NewClient(
-			otlptracegrpc.WithEndpoint(config.OTLPEndpoint),
-			otlptracegrpc.WithInsecure(),
-		)
+		var opts []otlptracegrpc.Option
+		if config.OTLPEndpoint != "" {
+			opts = append(opts,
+				otlptracegrpc.WithEndpoint(config.OTLPEndpoint),
+				otlptracegrpc.WithInsecure(),
+			)
+		}
+		client := otlptracegrpc.NewClient(opts...)
 		exporter, err = otlptrace.New(context.Background(), client)
 	default:
 		return nil, fmt.Errorf("unsupported exporter type: %s", config.ExporterType)
@@ -164,7 +164,7 @@ func (v *Validator) validateSemantics(module *ast.Module, result *ValidationResu
 		"int": true, "str": true, "string": true, "bool": true,
 		"float": true, "timestamp": true, "any": true, "object": true,
 		"List": true, "Map": true, "Result": true,
-		"Database": true, "Redis": true, "MongoDB": true, "LLM": true,
+		"Database": true, "Redis": true, "MongoDB": true, "LLM": true, "Cache": true, "Mailer": true,
 	}
 
 	// Process imports to collect types from imported modules
@@ -186,6 +186,18 @@ func (v *Validator) validateSemantics(module *ast.Module, result *ValidationResu
 				result.Valid = false
 			}
 			definedTypes[it.Name] = true
+		case *ast.EnumDef:
+			if definedTypes[it.Name] {
+				result.Errors = append(result.Errors, &ValidationError{
+					Type:      ErrTypeDuplicate,
+					Message:   fmt.Sprintf("duplicate type definition: %s", it.Name),
+					Severity:  "error",
+					RelatedTo: it.Name,
+					FixHint:   fmt.Sprintf("rename one of the '%s' type definitions or remove the duplicate", it.Name),
+				})
+				result.Valid = false
+			}
+			definedTypes[it.Name] = true
 		case *ast.ProviderDef:
 			if definedProviders[it.Name] {
 				result.Errors = append(result.Errors, &ValidationError{
@@ -345,7 +357,7 @@ func (v *Validator) validateRoute(route *ast.Route, defined, builtin, providers
 				Message:   fmt.Sprintf("undefined provider type: %s", provType),
 				Severity:  "error",
 				RelatedTo: fmt.Sprintf("route %s %s", route.Method, route.Path),
-				FixHint:   fmt.Sprintf("define 'provider %s { ... }' or use a builtin provider (Database, Redis, MongoDB, LLM)", provType),
+				FixHint:   fmt.Sprintf("define 'provider %s { ... }' or use a builtin provider (Database, Redis, MongoDB, LLM, Cache)", provType),
 			})
 			result.Valid = false
 		}
@@ -383,10 +395,10 @@ func resolveProviderTypeName(t ast.Type) string {
 	}
 }
 
-// isBuiltinProvider returns true for the four standard provider types
+// isBuiltinProvider returns true for the standard provider types
 func isBuiltinProvider(name string) bool {
 	switch name {
-	case "Database", "Redis", "MongoDB", "LLM":
+	case "Database", "Redis", "MongoDB", "LLM", "Cache", "Mailer":
 		return true
 	default:
 		return false
@@ -462,7 +474,7 @@ func (v *Validator) checkCommonIssues(module *ast.Module, result *ValidationResu
 func (v *Validator) collectStats(module *ast.Module, stats *ValidationStats) {
 	for _, item := range module.Items {
 		switch item.(type) {
-		case *ast.TypeDef:
+		case *ast.TypeDef, *ast.EnumDef:
 			stats.Types++
 		case *ast.Route:
 			stats.Routes++
@@ -2,6 +2,7 @@ package vm
 
 import (
 	"encoding/binary"
+	"errors"
 	"math"
 	"strings"
 	"testing"
@@ -722,11 +723,11 @@ func TestOpGetIndexExtended(t *testing.T) {
 	t.Run("negative_index", func(t *testing.T) {
 		vm := NewVM()
 		vm.Push(ArrayValue{Val: []Value{IntValue{Val: 10}}})
-		vm.Push(IntValue{Val: -1})
+		vm.Push(IntValue{Val: -2})
 
 		err := vm.execGetIndex()
 		if err == nil {
-			t.Error("Expected error for negative index")
+			t.Error("Expected error for negative index past the start")
 		}
 	})
 
@@ -1142,6 +1143,9 @@ type MockWebSocketHandler struct {
 	roomMessages      map[string][]interface{}
 	joinedRooms       []string
 	leftRooms         []string
+	clientRooms       map[string][]string    // Rooms joined by other clients
+	state             map[string]interface{} // Keyed by "client/key"
+	rejectCode        int
 	closeReason       string
 	closed            bool
 	rooms             []string
@@ -1153,6 +1157,8 @@ type MockWebSocketHandler struct {
 func NewMockWebSocketHandler() *MockWebSocketHandler {
 	return &MockWebSocketHandler{
 		roomMessages:    make(map[string][]interface{}),
+		clientRooms:     make(map[string][]string),
+		state:           make(map[string]interface{}),
 		clients:         make(map[string][]string),
 		rooms:           []string{"room1", "room2"},
 		connectionCount: 5,
@@ -1185,6 +1191,37 @@ func (m *MockWebSocketHandler) LeaveRoom(room string) error {
 	return nil
 }
 
+func (m *MockWebSocketHandler) JoinClientRoom(client, room string) error {
+	m.clientRooms[client] = append(m.clientRooms[client], room)
+	return nil
+}
+
+func (m *MockWebSocketHandler) LeaveClientRoom(client, room string) error {
+	rooms := m.clientRooms[client]
+	for i, r := range rooms {
+		if r == room {
+			m.clientRooms[client] = append(rooms[:i], rooms[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (m *MockWebSocketHandler) SetState(client, key string, value interface{}) error {
+	m.state[client+"/"+key] = value
+	return nil
+}
+
+func (m *MockWebSocketHandler) GetState(client, key string) (interface{}, error) {
+	return m.state[client+"/"+key], nil
+}
+
+func (m *MockWebSocketHandler) Reject(code int, reason string) error {
+	m.rejectCode = code
+	m.closeReason = reason
+	return errors.New("rejected: " + reason)
+}
+
 func (m *MockWebSocketHandler) Close(reason string) error {
 	m.closeReason = reason
 	m.closed = true
@@ -1278,6 +1315,97 @@ func TestWebSocketOperationsWithHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("ws_join_and_leave_client", func(t *testing.T) {
+		vm := NewVM()
+		handler := NewMockWebSocketHandler()
+		vm.SetWebSocketHandler(handler)
+
+		vm.Push(StringValue{Val: "conn-1"})
+		vm.Push(StringValue{Val: "room:42"})
+		if err := vm.execWsClientRoom(true); err != nil {
+			t.Fatalf("execWsClientRoom(join) error: %v", err)
+		}
+		if rooms := handler.clientRooms["conn-1"]; len(rooms) != 1 || rooms[0] != "room:42" {
+			t.Errorf("Expected conn-1 to join room:42, got %v", rooms)
+		}
+
+		vm.Push(StringValue{Val: "conn-1"})
+		vm.Push(StringValue{Val: "room:42"})
+		if err := vm.execWsClientRoom(false); err != nil {
+			t.Fatalf("execWsClientRoom(leave) error: %v", err)
+		}
+		if rooms := handler.clientRooms["conn-1"]; len(rooms) != 0 {
+			t.Errorf("Expected conn-1 to leave room:42, got %v", rooms)
+		}
+
+		vm.Push(IntValue{Val: 1}) // Non-string client ID
+		vm.Push(StringValue{Val: "room:42"})
+		if err := vm.execWsClientRoom(true); err == nil {
+			t.Error("Expected error for non-string client ID")
+		}
+	})
+
+	t.Run("ws_set_and_get_state", func(t *testing.T) {
+		vm := NewVM()
+		handler := NewMockWebSocketHandler()
+		vm.SetWebSocketHandler(handler)
+
+		vm.Push(StringValue{Val: "conn-1"})
+		vm.Push(StringValue{Val: "user"})
+		vm.Push(ObjectValue{Val: map[string]Value{"id": IntValue{Val: 7}}})
+		if err := vm.execWsSetState(); err != nil {
+			t.Fatalf("execWsSetState() error: %v", err)
+		}
+		vm.Pop()
+
+		vm.Push(StringValue{Val: "conn-1"})
+		vm.Push(StringValue{Val: "user"})
+		if err := vm.execWsGetState(); err != nil {
+			t.Fatalf("execWsGetState() error: %v", err)
+		}
+		user, _ := vm.Pop()
+		obj, ok := user.(ObjectValue)
+		if !ok || obj.Val["id"] != (IntValue{Val: 7}) {
+			t.Errorf("Expected {id: 7}, got %v", user)
+		}
+
+		vm.Push(StringValue{Val: "conn-1"})
+		vm.Push(StringValue{Val: "missing"})
+		if err := vm.execWsGetState(); err != nil {
+			t.Fatalf("execWsGetState() error: %v", err)
+		}
+		if missing, _ := vm.Pop(); missing != (NullValue{}) {
+			t.Errorf("Expected null for an unset key, got %v", missing)
+		}
+
+		vm.Push(StringValue{Val: "conn-1"})
+		vm.Push(IntValue{Val: 1}) // Non-string key
+		if err := vm.execWsGetState(); err == nil {
+			t.Error("Expected error for non-string key")
+		}
+	})
+
+	t.Run("ws_reject", func(t *testing.T) {
+		vm := NewVM()
+		handler := NewMockWebSocketHandler()
+		vm.SetWebSocketHandler(handler)
+
+		vm.Push(IntValue{Val: 4001})
+		vm.Push(StringValue{Val: "invalid token"})
+		if err := vm.execWsReject(); err == nil {
+			t.Error("Expected the handler's rejection error")
+		}
+		if handler.rejectCode != 4001 || handler.closeReason != "invalid token" {
+			t.Errorf("Expected rejection 4001 invalid token, got %d %s", handler.rejectCode, handler.closeReason)
+		}
+
+		vm.Push(IntValue{Val: 99})
+		vm.Push(StringValue{Val: "bad code"})
+		if err := vm.execWsReject(); err == nil || !strings.Contains(err.Error(), "between 1000 and 4999") {
+			t.Errorf("Expected close code range error, got %v", err)
+		}
+	})
+
 	t.Run("ws_join_room", func(t *testing.T) {
 		vm := NewVM()
 		handler := NewMockWebSocketHandler()
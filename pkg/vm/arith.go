@@ -0,0 +1,56 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+)
+
+// Checked integer arithmetic, shared by the VM and the compiler's constant
+// folder. An operation that overflows int64 is an error, as it is in the
+// interpreter, rather than wrapping around.
+
+// AddInt returns a + b
+func AddInt(a, b int64) (int64, error) {
+	sum := a + b
+	if (a >= 0) == (b >= 0) && (sum >= 0) != (a >= 0) {
+		return 0, fmt.Errorf("integer overflow: %d + %d", a, b)
+	}
+	return sum, nil
+}
+
+// SubInt returns a - b
+func SubInt(a, b int64) (int64, error) {
+	diff := a - b
+	if (a >= 0) != (b >= 0) && (diff >= 0) != (a >= 0) {
+		return 0, fmt.Errorf("integer overflow: %d - %d", a, b)
+	}
+	return diff, nil
+}
+
+// MulInt returns a * b
+func MulInt(a, b int64) (int64, error) {
+	product := a * b
+	if a != 0 && (product/a != b || (a == -1 && b == math.MinInt64)) {
+		return 0, fmt.Errorf("integer overflow: %d * %d", a, b)
+	}
+	return product, nil
+}
+
+// DivInt returns a / b, truncated toward zero
+func DivInt(a, b int64) (int64, error) {
+	if b == 0 {
+		return 0, fmt.Errorf("division by zero")
+	}
+	if a == math.MinInt64 && b == -1 {
+		return 0, fmt.Errorf("integer overflow: %d / %d", a, b)
+	}
+	return a / b, nil
+}
+
+// NegInt returns -a
+func NegInt(a int64) (int64, error) {
+	if a == math.MinInt64 {
+		return 0, fmt.Errorf("integer overflow: -(%d)", a)
+	}
+	return -a, nil
+}
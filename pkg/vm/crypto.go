@@ -0,0 +1,97 @@
+package vm
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// registerCryptoBuiltins registers hash.sha256(str), hmac.sha256(key, msg)
+// and the bcrypt password functions bcrypt.hash(password) and
+// bcrypt.verify(password, hash). crypto.hash and crypto.verify are the
+// password functions of the language spec.
+func (vm *VM) registerCryptoBuiltins() {
+	vm.builtins["hash.sha256"] = func(args []Value) (Value, error) {
+		values, err := stringArgs("hash.sha256", []string{"str"}, args)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256([]byte(values[0]))
+		return StringValue{Val: hex.EncodeToString(sum[:])}, nil
+	}
+	vm.builtins["hmac.sha256"] = func(args []Value) (Value, error) {
+		values, err := stringArgs("hmac.sha256", []string{"key", "msg"}, args)
+		if err != nil {
+			return nil, err
+		}
+		mac := hmac.New(sha256.New, []byte(values[0]))
+		mac.Write([]byte(values[1]))
+		return StringValue{Val: hex.EncodeToString(mac.Sum(nil))}, nil
+	}
+	for _, prefix := range []string{"bcrypt", "crypto"} {
+		vm.builtins[prefix+".hash"] = bcryptHash(prefix + ".hash")
+		vm.builtins[prefix+".verify"] = bcryptVerify(prefix + ".verify")
+	}
+}
+
+// stringArgs checks that the arguments of name(), which takes len(params)
+// string arguments named by params, are strings and returns them
+func stringArgs(name string, params []string, args []Value) ([]string, error) {
+	if len(args) != len(params) {
+		if len(params) == 1 {
+			return nil, fmt.Errorf("%s() expects 1 argument (%s), got %d", name, params[0], len(args))
+		}
+		return nil, fmt.Errorf("%s() expects %d arguments (%s), got %d", name, len(params), strings.Join(params, ", "), len(args))
+	}
+	values := make([]string, len(args))
+	for idx, arg := range args {
+		str, ok := arg.(StringValue)
+		if !ok {
+			return nil, fmt.Errorf("%s() %s must be a string, got %s", name, params[idx], arg.Type())
+		}
+		values[idx] = str.Val
+	}
+	return values, nil
+}
+
+// bcryptHash returns name(password): a bcrypt hash of password at the
+// default cost, suitable for storing
+func bcryptHash(name string) BuiltinFunc {
+	return func(args []Value) (Value, error) {
+		values, err := stringArgs(name, []string{"password"}, args)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(values[0]), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("%s() failed: %w", name, err)
+		}
+		return StringValue{Val: string(hash)}, nil
+	}
+}
+
+// bcryptVerify returns name(password, hash): whether password matches a
+// hash from bcrypt.hash. A malformed hash is an error rather than a
+// mismatch.
+func bcryptVerify(name string) BuiltinFunc {
+	return func(args []Value) (Value, error) {
+		values, err := stringArgs(name, []string{"password", "hash"}, args)
+		if err != nil {
+			return nil, err
+		}
+		err = bcrypt.CompareHashAndPassword([]byte(values[1]), []byte(values[0]))
+		switch {
+		case err == nil:
+			return BoolValue{Val: true}, nil
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return BoolValue{Val: false}, nil
+		default:
+			return nil, fmt.Errorf("%s() invalid hash: %w", name, err)
+		}
+	}
+}
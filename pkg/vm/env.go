@@ -0,0 +1,54 @@
+package vm
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SetEnvPrefixes restricts env() to environment variables whose names start
+// with one of prefixes, e.g. "APP_". With no prefixes every variable is
+// readable.
+func (vm *VM) SetEnvPrefixes(prefixes []string) {
+	vm.envPrefixes = prefixes
+}
+
+// envAllowed reports whether env() may read the variable name
+func (vm *VM) envAllowed(name string) bool {
+	if len(vm.envPrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range vm.envPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// registerEnvBuiltins registers env(name, default?), the value of an
+// environment variable as a string, or default (null if omitted) when it is
+// unset
+func (vm *VM) registerEnvBuiltins() {
+	vm.builtins["env"] = func(args []Value) (Value, error) {
+		if len(args) < 1 || len(args) > 2 {
+			return nil, fmt.Errorf("env() expects 1-2 arguments (name, default?), got %d", len(args))
+		}
+		name, ok := args[0].(StringValue)
+		if !ok {
+			return nil, fmt.Errorf("env() expects a string name, got %s", args[0].Type())
+		}
+		if !vm.envAllowed(name.Val) {
+			return nil, fmt.Errorf("env() cannot read '%s': only variables starting with %s are allowed",
+				name.Val, strings.Join(vm.envPrefixes, ", "))
+		}
+
+		if value, ok := os.LookupEnv(name.Val); ok {
+			return StringValue{Val: value}, nil
+		}
+		if len(args) == 2 {
+			return args[1], nil
+		}
+		return NullValue{}, nil
+	}
+}
@@ -0,0 +1,74 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// registerJSONBuiltins registers json.parse(str), the decoded object, array
+// or scalar, and json.stringify(value), value encoded as a compact JSON
+// string. json.parse gives ints for whole numbers and floats otherwise.
+func (vm *VM) registerJSONBuiltins() {
+	vm.builtins["json.parse"] = func(args []Value) (Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("json.parse() expects 1 argument, got %d", len(args))
+		}
+		str, ok := args[0].(StringValue)
+		if !ok {
+			return nil, fmt.Errorf("json.parse() expects a string, got %s", args[0].Type())
+		}
+
+		dec := json.NewDecoder(strings.NewReader(str.Val))
+		dec.UseNumber()
+		var decoded interface{}
+		if err := dec.Decode(&decoded); err != nil {
+			return nil, fmt.Errorf("json.parse() invalid JSON: %v", err)
+		}
+		if dec.More() {
+			return nil, fmt.Errorf("json.parse() invalid JSON: unexpected data after value")
+		}
+		return jsonToValue(decoded), nil
+	}
+	vm.builtins["json.stringify"] = func(args []Value) (Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("json.stringify() expects 1 argument, got %d", len(args))
+		}
+
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(valueToInterface(args[0])); err != nil {
+			return nil, fmt.Errorf("json.stringify() cannot encode %s: %v", args[0].Type(), err)
+		}
+		return StringValue{Val: strings.TrimSuffix(buf.String(), "\n")}, nil
+	}
+}
+
+// jsonToValue converts a value decoded with json.Decoder.UseNumber to a VM
+// value: an IntValue for a whole number, a FloatValue otherwise
+func jsonToValue(v interface{}) Value {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return IntValue{Val: n}
+		}
+		f, _ := v.Float64()
+		return FloatValue{Val: f}
+	case map[string]interface{}:
+		obj := make(map[string]Value, len(v))
+		for key, val := range v {
+			obj[key] = jsonToValue(val)
+		}
+		return ObjectValue{Val: obj}
+	case []interface{}:
+		arr := make([]Value, len(v))
+		for idx, val := range v {
+			arr[idx] = jsonToValue(val)
+		}
+		return ArrayValue{Val: arr}
+	default:
+		return interfaceToValue(v)
+	}
+}
@@ -0,0 +1,51 @@
+package vm
+
+import "fmt"
+
+// Mailer is the mailer behind the mail.* builtins, the methods of an
+// injected `% mail: Mailer`. Send reports failed deliveries in its result
+// object rather than as an error.
+type Mailer interface {
+	Send(args interface{}) (map[string]interface{}, error)
+	Render(template string, data interface{}) (string, error)
+}
+
+// SetMailer sets the mailer used by the mail.* builtins
+func (vm *VM) SetMailer(mailer Mailer) {
+	vm.mailer = mailer
+}
+
+// registerMailBuiltins registers mail.send(message) and
+// mail.render(template, data)
+func (vm *VM) registerMailBuiltins() {
+	vm.builtins["mail.send"] = func(args []Value) (Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("mail.send() expects 1 argument (message), got %d", len(args))
+		}
+		if vm.mailer == nil {
+			return nil, fmt.Errorf("mail.send(): no mailer is configured")
+		}
+		result, err := vm.mailer.Send(valueToInterface(args[0]))
+		if err != nil {
+			return nil, err
+		}
+		return interfaceToValue(result), nil
+	}
+	vm.builtins["mail.render"] = func(args []Value) (Value, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("mail.render() expects 2 arguments (template, data), got %d", len(args))
+		}
+		if vm.mailer == nil {
+			return nil, fmt.Errorf("mail.render(): no mailer is configured")
+		}
+		template, ok := args[0].(StringValue)
+		if !ok {
+			return nil, fmt.Errorf("mail.render() template must be a string, got %T", args[0])
+		}
+		rendered, err := vm.mailer.Render(template.Val, valueToInterface(args[1]))
+		if err != nil {
+			return nil, err
+		}
+		return StringValue{Val: rendered}, nil
+	}
+}
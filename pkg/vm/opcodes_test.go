@@ -45,6 +45,12 @@ func TestOpNeg(t *testing.T) {
 			input:    FloatValue{Val: 0.0},
 			expected: FloatValue{Val: 0.0},
 		},
+		{
+			name:        "negate minimum integer overflows",
+			input:       IntValue{Val: math.MinInt64},
+			expectError: true,
+			errorMsg:    "integer overflow: -(-9223372036854775808)",
+		},
 		{
 			name:        "negate string error",
 			input:       StringValue{Val: "hello"},
@@ -425,9 +431,15 @@ func TestOpGetIndex(t *testing.T) {
 			errorMsg:    "index out of bounds",
 		},
 		{
-			name:        "negative index error",
+			name:     "negative index counts from the end",
+			array:    ArrayValue{Val: []Value{IntValue{Val: 10}, IntValue{Val: 20}, IntValue{Val: 30}}},
+			index:    IntValue{Val: -1},
+			expected: IntValue{Val: 30},
+		},
+		{
+			name:        "negative index past the start",
 			array:       ArrayValue{Val: []Value{IntValue{Val: 10}}},
-			index:       IntValue{Val: -1},
+			index:       IntValue{Val: -2},
 			expectError: true,
 			errorMsg:    "index out of bounds",
 		},
@@ -485,8 +497,86 @@ func TestOpGetIndexNonArray(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error when indexing non-array")
 	}
-	if !strings.Contains(err.Error(), "can only index arrays") {
-		t.Errorf("Expected 'can only index arrays' error, got '%s'", err.Error())
+	if !strings.Contains(err.Error(), "cannot index string") {
+		t.Errorf("Expected 'cannot index string' error, got '%s'", err.Error())
+	}
+}
+
+func TestOpSetIndex(t *testing.T) {
+	arr := ArrayValue{Val: []Value{IntValue{Val: 10}, IntValue{Val: 20}, IntValue{Val: 30}}}
+	obj := ObjectValue{Val: map[string]Value{"a": IntValue{Val: 1}}}
+
+	tests := []struct {
+		name      string
+		container Value
+		index     Value
+		errorMsg  string
+	}{
+		{name: "array element", container: arr, index: IntValue{Val: 1}},
+		{name: "negative array index", container: arr, index: IntValue{Val: -1}},
+		{name: "new object key", container: obj, index: StringValue{Val: "b"}},
+		{name: "out of bounds", container: arr, index: IntValue{Val: 3}, errorMsg: "array index out of bounds: 3 (length: 3)"},
+		{name: "non-integer array index", container: arr, index: StringValue{Val: "1"}, errorMsg: "array index must be an integer, got string"},
+		{name: "non-string object key", container: obj, index: IntValue{Val: 0}, errorMsg: "map key must be a string, got int"},
+		{name: "non-collection", container: StringValue{Val: "abc"}, index: IntValue{Val: 0}, errorMsg: "cannot index-assign to string"},
+		{name: "null", container: NullValue{}, index: IntValue{Val: 0}, errorMsg: "cannot index-assign to null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := NewVM()
+			vm.Push(IntValue{Val: 99})
+			vm.Push(tt.container)
+			vm.Push(tt.index)
+
+			err := vm.execSetIndex()
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing '%s', got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(vm.stack) != 0 {
+				t.Errorf("Expected an empty stack, got %v", vm.stack)
+			}
+		})
+	}
+
+	// The stores changed the shared containers in place
+	if arr.Val[1] != (IntValue{Val: 99}) || arr.Val[2] != (IntValue{Val: 99}) {
+		t.Errorf("Expected array elements 1 and 2 to be 99, got %v", arr.Val)
+	}
+	if obj.Val["b"] != (IntValue{Val: 99}) {
+		t.Errorf("Expected object key b to be 99, got %v", obj.Val)
+	}
+}
+
+func TestOpSetField(t *testing.T) {
+	obj := ObjectValue{Val: map[string]Value{"name": StringValue{Val: "ada"}}}
+	vm := NewVM()
+	vm.Push(StringValue{Val: "grace"})
+	vm.Push(obj)
+	vm.Push(StringValue{Val: "name"})
+	if err := vm.execSetField(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if obj.Val["name"] != (StringValue{Val: "grace"}) {
+		t.Errorf("Expected name to be grace, got %v", obj.Val["name"])
+	}
+
+	for _, target := range []Value{NullValue{}, IntValue{Val: 1}} {
+		vm := NewVM()
+		vm.Push(StringValue{Val: "grace"})
+		vm.Push(target)
+		vm.Push(StringValue{Val: "name"})
+		err := vm.execSetField()
+		want := "cannot assign field 'name' on " + target.Type()
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error containing '%s', got %v", want, err)
+		}
 	}
 }
 
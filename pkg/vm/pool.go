@@ -0,0 +1,43 @@
+package vm
+
+// maxPooledStack is the largest stack capacity a pooled VM may keep.
+// VMs whose stack grew beyond it are left to the garbage collector.
+const maxPooledStack = 1024
+
+// Pool reuses VMs across executions, such as one per HTTP request or
+// WebSocket message, to avoid allocating a VM and its builtin table each
+// time. It holds at most a fixed number of idle VMs. A Pool is safe for
+// concurrent use.
+type Pool struct {
+	idle chan *VM
+}
+
+// NewPool creates a pool that keeps up to size idle VMs
+func NewPool(size int) *Pool {
+	return &Pool{idle: make(chan *VM, size)}
+}
+
+// Acquire returns an idle VM, or a new one if none is available. The VM is
+// in the same state as one returned by NewVM.
+func (p *Pool) Acquire() *VM {
+	select {
+	case vm := <-p.idle:
+		return vm
+	default:
+		return NewVM()
+	}
+}
+
+// Release resets vm and returns it to the pool. The caller must not use vm
+// afterwards; values it returned from Execute remain valid.
+func (p *Pool) Release(vm *VM) {
+	if vm == nil || cap(vm.stack) > maxPooledStack {
+		return
+	}
+	vm.Reset()
+	select {
+	case p.idle <- vm:
+	default:
+		// Pool is full
+	}
+}
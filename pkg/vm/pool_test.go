@@ -0,0 +1,160 @@
+package vm
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// poisonProgram stores a local, leaves values on the stack and starts an
+// iterator, so a reused VM would observe them
+var poisonProgram = program(
+	[]Value{StringValue{Val: "leak"}},
+	op(OpPush, 0), op(OpStoreVar, 0),
+	op(OpPush, 0), op(OpBuildArray, 1), op(OpGetIter),
+	op(OpPush, 0), op(OpPush, 0),
+)
+
+// readLeakProgram loads the local stored by poisonProgram
+var readLeakProgram = program([]Value{StringValue{Val: "leak"}}, op(OpLoadVar, 0))
+
+func TestResetRestoresNewVMState(t *testing.T) {
+	vm := NewVM()
+	vm.SetLocal("secret", StringValue{Val: "token"})
+	vm.globals["config"] = IntValue{Val: 1}
+	vm.SetWebSocketHandler(NewMockWebSocketHandler())
+	vm.SetMaxSteps(10)
+	if _, err := vm.Execute(poisonProgram); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+
+	vm.Reset()
+	fresh := NewVM()
+
+	// Compare every field with a new VM, so a field added to VM without
+	// being cleared by Reset fails this test
+	got, want := reflect.ValueOf(vm).Elem(), reflect.ValueOf(fresh).Elem()
+	for i := 0; i < got.NumField(); i++ {
+		name := got.Type().Field(i).Name
+		g, w := got.Field(i), want.Field(i)
+		switch g.Kind() {
+		case reflect.Map, reflect.Slice:
+			if name == "builtins" {
+				if g.Len() != w.Len() {
+					t.Errorf("builtins: got %d, want %d", g.Len(), w.Len())
+				}
+				continue
+			}
+			if g.Len() != w.Len() {
+				t.Errorf("%s: got %d entries after Reset, want %d", name, g.Len(), w.Len())
+			}
+		case reflect.Interface, reflect.Pointer:
+			if g.IsNil() != w.IsNil() {
+				t.Errorf("%s: not reset", name)
+			}
+		default:
+			if !g.Equal(w) {
+				t.Errorf("%s: got %v after Reset, want %v", name, g, w)
+			}
+		}
+	}
+
+	// Popped values must not be kept alive by the stack's backing array
+	for i, v := range vm.stack[:cap(vm.stack)] {
+		if v != nil {
+			t.Fatalf("stack slot %d still holds %v", i, v)
+		}
+	}
+}
+
+func TestPoolDoesNotLeakLocals(t *testing.T) {
+	pool := NewPool(1)
+
+	first := pool.Acquire()
+	first.SetLocal("user", StringValue{Val: "alice"})
+	if _, err := first.Execute(poisonProgram); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	pool.Release(first)
+
+	second := pool.Acquire()
+	if second != first {
+		t.Fatal("Expected the released VM to be reused")
+	}
+	if n := second.LocalsCount(); n != 0 {
+		t.Errorf("Expected no locals, got %d", n)
+	}
+	if n := second.StackSize(); n != 0 {
+		t.Errorf("Expected an empty stack, got %d values", n)
+	}
+	if n := second.IteratorCount(); n != 0 {
+		t.Errorf("Expected no iterators, got %d", n)
+	}
+	_, err := second.Execute(readLeakProgram)
+	if err == nil || !strings.Contains(err.Error(), "undefined variable") {
+		t.Errorf("Expected undefined variable error, got: %v", err)
+	}
+}
+
+func TestPoolClearsWebSocketHandler(t *testing.T) {
+	pool := NewPool(1)
+
+	first := pool.Acquire()
+	first.SetWebSocketHandler(NewMockWebSocketHandler())
+	pool.Release(first)
+
+	// A request that does not set a handler must not use the previous one
+	second := pool.Acquire()
+	constants := []Value{StringValue{Val: "hi"}}
+	_, err := second.Execute(program(constants, op(OpPush, 0), op(OpWsSend)))
+	if err == nil || !strings.Contains(err.Error(), "WebSocket handler not available") {
+		t.Errorf("Expected missing handler error, got: %v", err)
+	}
+}
+
+func TestPoolBounds(t *testing.T) {
+	pool := NewPool(1)
+	a, b := pool.Acquire(), pool.Acquire()
+	pool.Release(a)
+	pool.Release(b) // Dropped, the pool is full
+	if got := pool.Acquire(); got != a {
+		t.Error("Expected the first released VM")
+	}
+	if got := pool.Acquire(); got == a || got == b {
+		t.Error("Expected a new VM once the pool is empty")
+	}
+
+	large := NewVM()
+	large.stack = make([]Value, 0, maxPooledStack+1)
+	pool.Release(large)
+	if got := pool.Acquire(); got == large {
+		t.Error("Expected a VM with a large stack not to be pooled")
+	}
+}
+
+func TestPoolConcurrentUse(t *testing.T) {
+	pool := NewPool(4)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				vm := pool.Acquire()
+				if vm.LocalsCount() != 0 {
+					t.Errorf("Acquired VM has %d locals", vm.LocalsCount())
+				}
+				vm.SetLocal("leak", IntValue{Val: int64(g)})
+				result, err := vm.Execute(readLeakProgram)
+				if err != nil {
+					t.Errorf("Execute() error: %v", err)
+				} else if result != (IntValue{Val: int64(g)}) {
+					t.Errorf("Expected %d, got %v", g, result)
+				}
+				pool.Release(vm)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
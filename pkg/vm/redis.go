@@ -0,0 +1,91 @@
+package vm
+
+import "fmt"
+
+// Redis is the client behind the redis.* builtins, the methods of an
+// injected `% redis: Redis`. It takes and returns plain Go values: int64,
+// float64, string, bool, nil, []interface{} and map[string]interface{}.
+type Redis interface {
+	Get(key string) (interface{}, error)
+	Set(args ...interface{}) (interface{}, error)
+	Del(keys ...interface{}) (int64, error)
+	Incr(key string) (int64, error)
+	Expire(key string, seconds interface{}) (bool, error)
+	HGet(key, field string) (interface{}, error)
+	HSet(args ...interface{}) (int64, error)
+	HGetAll(key string) (map[string]interface{}, error)
+	Publish(channel string, message interface{}) (int64, error)
+}
+
+// SetRedis sets the client used by the redis.* builtins
+func (vm *VM) SetRedis(redis Redis) {
+	vm.redis = redis
+}
+
+// registerRedisBuiltins registers redis.get(key), redis.set(key, value,
+// ttlSeconds?), redis.del(key, ...), redis.incr(key), redis.expire(key,
+// seconds), redis.hget(key, field), redis.hset(key, field, value, ...),
+// redis.hgetall(key) and redis.publish(channel, message)
+func (vm *VM) registerRedisBuiltins() {
+	vm.redisBuiltin("get", 1, 1, func(r Redis, args []interface{}) (interface{}, error) {
+		return r.Get(args[0].(string))
+	})
+	vm.redisBuiltin("set", 2, 3, func(r Redis, args []interface{}) (interface{}, error) {
+		return r.Set(args...)
+	})
+	vm.redisBuiltin("del", 1, -1, func(r Redis, args []interface{}) (interface{}, error) {
+		return r.Del(args...)
+	})
+	vm.redisBuiltin("incr", 1, 1, func(r Redis, args []interface{}) (interface{}, error) {
+		return r.Incr(args[0].(string))
+	})
+	vm.redisBuiltin("expire", 2, 2, func(r Redis, args []interface{}) (interface{}, error) {
+		return r.Expire(args[0].(string), args[1])
+	})
+	vm.redisBuiltin("hget", 2, 2, func(r Redis, args []interface{}) (interface{}, error) {
+		field, ok := args[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("redis.hget() field must be a string, got %T", args[1])
+		}
+		return r.HGet(args[0].(string), field)
+	})
+	vm.redisBuiltin("hset", 3, -1, func(r Redis, args []interface{}) (interface{}, error) {
+		if len(args)%2 != 1 {
+			return nil, fmt.Errorf("redis.hset() expects a key followed by field, value pairs")
+		}
+		return r.HSet(args...)
+	})
+	vm.redisBuiltin("hgetall", 1, 1, func(r Redis, args []interface{}) (interface{}, error) {
+		return r.HGetAll(args[0].(string))
+	})
+	vm.redisBuiltin("publish", 2, 2, func(r Redis, args []interface{}) (interface{}, error) {
+		return r.Publish(args[0].(string), args[1])
+	})
+}
+
+// redisBuiltin registers redis.<method>, which takes between minArgs and
+// maxArgs arguments (maxArgs -1 for no limit), the first a string key or
+// channel. call receives the arguments as Go values.
+func (vm *VM) redisBuiltin(method string, minArgs, maxArgs int, call func(Redis, []interface{}) (interface{}, error)) {
+	vm.builtins["redis."+method] = func(args []Value) (Value, error) {
+		if len(args) < minArgs || (maxArgs >= 0 && len(args) > maxArgs) {
+			return nil, fmt.Errorf("redis.%s() got the wrong number of arguments: %d", method, len(args))
+		}
+		if vm.redis == nil {
+			return nil, fmt.Errorf("redis.%s(): no Redis is configured", method)
+		}
+		if _, ok := args[0].(StringValue); !ok {
+			return nil, fmt.Errorf("redis.%s() key must be a string, got %T", method, args[0])
+		}
+		goArgs := make([]interface{}, len(args))
+		for i, arg := range args {
+			goArgs[i] = valueToInterface(arg)
+		}
+		// The client's errors already name the method
+		result, err := call(vm.redis, goArgs)
+		if err != nil {
+			return nil, err
+		}
+		return interfaceToValue(result), nil
+	}
+}
@@ -0,0 +1,142 @@
+package vm
+
+import "fmt"
+
+// ResponseCookies is the response behind the setCookie and clearCookie
+// builtins
+type ResponseCookies interface {
+	SetCookie(name, value string, options map[string]interface{}) error
+	ClearCookie(name string) error
+}
+
+// Session is the request session behind the session.* builtins. Values
+// are plain Go values, as for Cache.
+type Session interface {
+	Get(key string) (interface{}, error)
+	Set(key string, value interface{}) error
+	Regenerate() error
+	Destroy() error
+}
+
+// SetResponseCookies sets the response used by setCookie and clearCookie
+func (vm *VM) SetResponseCookies(cookies ResponseCookies) {
+	vm.cookies = cookies
+}
+
+// SetSession sets the session used by the session.* builtins
+func (vm *VM) SetSession(session Session) {
+	vm.session = session
+}
+
+// registerSessionBuiltins registers setCookie(name, value, options?),
+// clearCookie(name), session.get(key), session.set(key, value),
+// session.regenerate() and session.destroy()
+func (vm *VM) registerSessionBuiltins() {
+	vm.builtins["setCookie"] = func(args []Value) (Value, error) {
+		if len(args) < 2 || len(args) > 3 {
+			return nil, fmt.Errorf("setCookie() expects 2 or 3 arguments (name, value, options?), got %d", len(args))
+		}
+		if vm.cookies == nil {
+			return nil, fmt.Errorf("setCookie() can only be called while handling a request")
+		}
+		name, ok := args[0].(StringValue)
+		if !ok {
+			return nil, fmt.Errorf("setCookie() name must be a string, got %T", args[0])
+		}
+		value, ok := args[1].(StringValue)
+		if !ok {
+			return nil, fmt.Errorf("setCookie() value must be a string, got %T", args[1])
+		}
+		var options map[string]interface{}
+		if len(args) == 3 {
+			obj, ok := args[2].(ObjectValue)
+			if !ok {
+				return nil, fmt.Errorf("setCookie() options must be an object, got %T", args[2])
+			}
+			options = valueToInterface(obj).(map[string]interface{})
+		}
+		if err := vm.cookies.SetCookie(name.Val, value.Val, options); err != nil {
+			return nil, fmt.Errorf("setCookie(): %w", err)
+		}
+		return NullValue{}, nil
+	}
+	vm.builtins["clearCookie"] = func(args []Value) (Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("clearCookie() expects 1 argument (name), got %d", len(args))
+		}
+		if vm.cookies == nil {
+			return nil, fmt.Errorf("clearCookie() can only be called while handling a request")
+		}
+		name, ok := args[0].(StringValue)
+		if !ok {
+			return nil, fmt.Errorf("clearCookie() name must be a string, got %T", args[0])
+		}
+		if err := vm.cookies.ClearCookie(name.Val); err != nil {
+			return nil, fmt.Errorf("clearCookie(): %w", err)
+		}
+		return NullValue{}, nil
+	}
+	vm.builtins["session.get"] = func(args []Value) (Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("session.get() expects 1 argument (key), got %d", len(args))
+		}
+		key, err := vm.sessionKey("get", args[0])
+		if err != nil {
+			return nil, err
+		}
+		value, err := vm.session.Get(key)
+		if err != nil {
+			return nil, fmt.Errorf("session.get(): %w", err)
+		}
+		return interfaceToValue(value), nil
+	}
+	vm.builtins["session.set"] = func(args []Value) (Value, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("session.set() expects 2 arguments (key, value), got %d", len(args))
+		}
+		key, err := vm.sessionKey("set", args[0])
+		if err != nil {
+			return nil, err
+		}
+		if err := vm.session.Set(key, valueToInterface(args[1])); err != nil {
+			return nil, fmt.Errorf("session.set(): %w", err)
+		}
+		return NullValue{}, nil
+	}
+	vm.builtins["session.regenerate"] = func(args []Value) (Value, error) {
+		if len(args) != 0 {
+			return nil, fmt.Errorf("session.regenerate() expects no arguments, got %d", len(args))
+		}
+		if vm.session == nil {
+			return nil, fmt.Errorf("session.regenerate() can only be called while handling a request")
+		}
+		if err := vm.session.Regenerate(); err != nil {
+			return nil, fmt.Errorf("session.regenerate(): %w", err)
+		}
+		return NullValue{}, nil
+	}
+	vm.builtins["session.destroy"] = func(args []Value) (Value, error) {
+		if len(args) != 0 {
+			return nil, fmt.Errorf("session.destroy() expects no arguments, got %d", len(args))
+		}
+		if vm.session == nil {
+			return nil, fmt.Errorf("session.destroy() can only be called while handling a request")
+		}
+		if err := vm.session.Destroy(); err != nil {
+			return nil, fmt.Errorf("session.destroy(): %w", err)
+		}
+		return NullValue{}, nil
+	}
+}
+
+// sessionKey checks that there is a session and that key is a string
+func (vm *VM) sessionKey(method string, key Value) (string, error) {
+	if vm.session == nil {
+		return "", fmt.Errorf("session.%s() can only be called while handling a request", method)
+	}
+	s, ok := key.(StringValue)
+	if !ok {
+		return "", fmt.Errorf("session.%s() key must be a string, got %T", method, key)
+	}
+	return s.Val, nil
+}
@@ -0,0 +1,74 @@
+package vm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Templates renders the HTML templates behind render(name, data) and
+// renderString(template, data)
+type Templates interface {
+	Render(name string, data interface{}) (string, error)
+	RenderString(template string, data interface{}) (string, error)
+}
+
+// HTMLValue is a page rendered by render(), which a route returns as an
+// HTML response rather than as JSON
+type HTMLValue struct {
+	Val string
+}
+
+func (v HTMLValue) Type() string { return "html" }
+
+func (v HTMLValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.Val)
+}
+
+// SetTemplates sets the templates used by render() and renderString()
+func (vm *VM) SetTemplates(templates Templates) {
+	vm.templates = templates
+}
+
+// registerTemplateBuiltins registers render(name, data), the page rendered
+// from a template file, and renderString(template, data), the string
+// rendered from an inline template
+func (vm *VM) registerTemplateBuiltins() {
+	vm.builtins["render"] = func(args []Value) (Value, error) {
+		name, data, err := vm.templateArgs("render", "name", args)
+		if err != nil {
+			return nil, err
+		}
+		page, err := vm.templates.Render(name, data)
+		if err != nil {
+			return nil, err
+		}
+		return HTMLValue{Val: page}, nil
+	}
+	vm.builtins["renderString"] = func(args []Value) (Value, error) {
+		template, data, err := vm.templateArgs("renderString", "template", args)
+		if err != nil {
+			return nil, err
+		}
+		rendered, err := vm.templates.RenderString(template, data)
+		if err != nil {
+			return nil, err
+		}
+		return StringValue{Val: rendered}, nil
+	}
+}
+
+// templateArgs checks the arguments of the template builtin fn, whose first
+// argument, called param, is a string, and returns them as Go values
+func (vm *VM) templateArgs(fn, param string, args []Value) (string, interface{}, error) {
+	if len(args) != 2 {
+		return "", nil, fmt.Errorf("%s() expects 2 arguments (%s, data), got %d", fn, param, len(args))
+	}
+	if vm.templates == nil {
+		return "", nil, fmt.Errorf("%s(): no templates are configured", fn)
+	}
+	str, ok := args[0].(StringValue)
+	if !ok {
+		return "", nil, fmt.Errorf("%s() %s must be a string, got %T", fn, param, args[0])
+	}
+	return str.Val, valueToInterface(args[1]), nil
+}
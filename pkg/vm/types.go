@@ -0,0 +1,75 @@
+package vm
+
+import "fmt"
+
+// TypeChecker checks values against the parameter and return types of
+// compiled functions, which the compiler records as they are written in
+// source, e.g. "[User]" or "int | str"
+type TypeChecker interface {
+	CheckType(value interface{}, typ string) error
+}
+
+// Flags of OpCheckType
+const (
+	// TypeCheckArgument marks the check of an argument: a whole float
+	// passed for an int parameter becomes an int, as JSON numbers do in the
+	// interpreter
+	TypeCheckArgument uint32 = 1 << iota
+	// TypeCheckOptional lets null through, for parameters that may be
+	// omitted
+	TypeCheckOptional
+)
+
+// SetTypeChecker sets the checker used by OpCheckType. Without one, the
+// types of compiled functions are not checked.
+func (vm *VM) SetTypeChecker(types TypeChecker) {
+	vm.types = types
+}
+
+// execCheckType pops a type and a label and checks the value below them
+// against the type, failing with the label when it does not match. The
+// value stays on the stack.
+func (vm *VM) execCheckType() error {
+	flags, err := vm.readOperand()
+	if err != nil {
+		return err
+	}
+	typ, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+	label, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+	typName, ok := typ.(StringValue)
+	if !ok {
+		return fmt.Errorf("type check expects a type name, got %s", typ.Type())
+	}
+	labelText, ok := label.(StringValue)
+	if !ok {
+		return fmt.Errorf("type check expects a label, got %s", label.Type())
+	}
+	if len(vm.stack) == 0 {
+		return fmt.Errorf("stack underflow")
+	}
+	top := len(vm.stack) - 1
+	value := vm.stack[top]
+
+	if flags&TypeCheckArgument != 0 && typName.Val == "int" {
+		if f, ok := value.(FloatValue); ok && f.Val == float64(int64(f.Val)) {
+			value = IntValue{Val: int64(f.Val)}
+			vm.stack[top] = value
+		}
+	}
+	if _, isNull := value.(NullValue); isNull && flags&TypeCheckOptional != 0 {
+		return nil
+	}
+	if vm.types == nil {
+		return nil
+	}
+	if err := vm.types.CheckType(valueToInterface(value), typName.Val); err != nil {
+		return fmt.Errorf("%s: %v", labelText.Val, err)
+	}
+	return nil
+}
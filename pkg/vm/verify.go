@@ -0,0 +1,322 @@
+package vm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// opcodeNames maps opcodes to the mnemonics used in verification errors
+var opcodeNames = map[Opcode]string{
+	OpPush:            "PUSH",
+	OpPop:             "POP",
+	OpAdd:             "ADD",
+	OpSub:             "SUB",
+	OpMul:             "MUL",
+	OpDiv:             "DIV",
+	OpMod:             "MOD",
+	OpEq:              "EQ",
+	OpNe:              "NE",
+	OpLt:              "LT",
+	OpGt:              "GT",
+	OpGe:              "GE",
+	OpLe:              "LE",
+	OpAnd:             "AND",
+	OpOr:              "OR",
+	OpNot:             "NOT",
+	OpNeg:             "NEG",
+	OpLoadVar:         "LOAD_VAR",
+	OpStoreVar:        "STORE_VAR",
+	OpJump:            "JUMP",
+	OpJumpIfFalse:     "JUMP_IF_FALSE",
+	OpJumpIfTrue:      "JUMP_IF_TRUE",
+	OpGetIter:         "GET_ITER",
+	OpIterNext:        "ITER_NEXT",
+	OpIterHasNext:     "ITER_HAS_NEXT",
+	OpGetIndex:        "GET_INDEX",
+	OpSetIndex:        "SET_INDEX",
+	OpReturn:          "RETURN",
+	OpCall:            "CALL",
+	OpCallFunc:        "CALL_FUNC",
+	OpEnter:           "ENTER",
+	OpRet:             "RET",
+	OpCheckType:       "CHECK_TYPE",
+	OpBuildObject:     "BUILD_OBJECT",
+	OpGetField:        "GET_FIELD",
+	OpGetFieldOpt:     "GET_FIELD_OPT",
+	OpSetField:        "SET_FIELD",
+	OpBuildArray:      "BUILD_ARRAY",
+	OpSpread:          "SPREAD",
+	OpHttpReturn:      "HTTP_RETURN",
+	OpWsSend:          "WS_SEND",
+	OpWsBroadcast:     "WS_BROADCAST",
+	OpWsBroadcastRoom: "WS_BROADCAST_ROOM",
+	OpWsJoinRoom:      "WS_JOIN_ROOM",
+	OpWsLeaveRoom:     "WS_LEAVE_ROOM",
+	OpWsClose:         "WS_CLOSE",
+	OpWsGetRooms:      "WS_GET_ROOMS",
+	OpWsGetClients:    "WS_GET_CLIENTS",
+	OpWsGetConnCount:  "WS_GET_CONN_COUNT",
+	OpWsGetUptime:     "WS_GET_UPTIME",
+	OpWsJoinClient:    "WS_JOIN_CLIENT",
+	OpWsLeaveClient:   "WS_LEAVE_CLIENT",
+	OpWsSetState:      "WS_SET_STATE",
+	OpWsGetState:      "WS_GET_STATE",
+	OpWsReject:        "WS_REJECT",
+	OpAsync:           "ASYNC",
+	OpAwait:           "AWAIT",
+	OpBackground:      "BACKGROUND",
+	OpYield:           "YIELD",
+	OpHalt:            "HALT",
+}
+
+// opcodeName returns the mnemonic for op, or its hex value if it is unknown
+func opcodeName(op Opcode) string {
+	if name, ok := opcodeNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%02x", byte(op))
+}
+
+// String returns the opcode's mnemonic, as shown in disassembly
+func (op Opcode) String() string {
+	return opcodeName(op)
+}
+
+// hasOperand reports whether op is followed by a 4-byte operand
+func hasOperand(op Opcode) bool {
+	switch op {
+	case OpPush, OpLoadVar, OpStoreVar, OpJump, OpJumpIfFalse, OpJumpIfTrue,
+		OpIterNext, OpCall, OpCallFunc, OpEnter, OpCheckType, OpBuildObject, OpBuildArray, OpAsync, OpBackground:
+		return true
+	}
+	return false
+}
+
+// VerifyError reports an instruction rejected by Verify
+type VerifyError struct {
+	Offset int // Offset of the instruction in the bytecode
+	Opcode Opcode
+	Reason string
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("invalid bytecode at offset %d (%s): %s", e.Offset, opcodeName(e.Opcode), e.Reason)
+}
+
+// Verify checks that bytecode is well formed before it is executed: the
+// header and constant pool must parse, every instruction must be known and
+// carry its operand, constant indices must be in range, jumps must land on
+// an instruction boundary and calls on a function's OpEnter, and no path
+// through the code may pop more values than it has pushed. Execute calls Verify, so a malformed file is
+// rejected up front instead of failing halfway through a run.
+//
+// The stack check is conservative: where paths with different stack depths
+// meet, the shallower depth is assumed.
+func Verify(bytecode []byte) error {
+	if len(bytecode) < 4 {
+		return fmt.Errorf("invalid bytecode: too short")
+	}
+	if string(bytecode[0:4]) != "GLYP" {
+		return fmt.Errorf("invalid bytecode: bad magic bytes")
+	}
+
+	// The instruction count in the header is informational; the VM runs
+	// until it halts or reaches the end of the code
+	header := &VM{}
+	offset := 4
+	if err := header.parseBytecode(bytecode, &offset); err != nil {
+		return err
+	}
+
+	v := &verifier{code: bytecode, constants: header.constants}
+	return v.region(offset, len(bytecode), 0)
+}
+
+// verifier checks the instructions of a parsed bytecode file
+type verifier struct {
+	code      []byte
+	constants []Value
+}
+
+// instruction is a decoded instruction; next is the offset of the
+// instruction that follows it, which skips the body of an async block
+type instruction struct {
+	op      Opcode
+	operand uint32
+	next    int
+}
+
+// region verifies the instructions in code[start:end], which run in one VM:
+// the main program or the body of an async block. Jump operands are
+// relative to base, which is 0 for the main program (its jumps are
+// absolute) and the start of the body for async blocks, matching how
+// executeRaw runs them.
+func (v *verifier) region(start, end, base int) error {
+	instrs := make(map[int]instruction)
+	var order []int
+	for pc := start; pc < end; {
+		in := instruction{op: Opcode(v.code[pc]), next: pc + 1}
+		if _, ok := opcodeNames[in.op]; !ok {
+			return &VerifyError{Offset: pc, Opcode: in.op, Reason: "unknown opcode"}
+		}
+		if hasOperand(in.op) {
+			if pc+5 > end {
+				return &VerifyError{Offset: pc, Opcode: in.op, Reason: "truncated operand"}
+			}
+			in.operand = binary.LittleEndian.Uint32(v.code[pc+1 : pc+5])
+			in.next = pc + 5
+		}
+		if in.op == OpAsync || in.op == OpBackground {
+			if uint64(in.next)+uint64(in.operand) > uint64(end) {
+				return &VerifyError{Offset: pc, Opcode: in.op, Reason: fmt.Sprintf("%s body of %d bytes extends past the end of the code", strings.ToLower(opcodeName(in.op)), in.operand)}
+			}
+			bodyEnd := in.next + int(in.operand)
+			if err := v.region(in.next, bodyEnd, in.next); err != nil {
+				return err
+			}
+			in.next = bodyEnd
+		}
+		if err := v.checkOperand(pc, in); err != nil {
+			return err
+		}
+		instrs[pc] = in
+		order = append(order, pc)
+		pc = in.next
+	}
+
+	// Jump targets must be instructions of this region, or its end
+	target := func(pc int, in instruction) (int, error) {
+		t := uint64(base) + uint64(in.operand)
+		if _, ok := instrs[int(t)]; !ok && t != uint64(end) {
+			return 0, &VerifyError{Offset: pc, Opcode: in.op, Reason: fmt.Sprintf("jump target %d is not an instruction boundary", in.operand)}
+		}
+		return int(t), nil
+	}
+	for _, pc := range order {
+		if in := instrs[pc]; in.op == OpJump || in.op == OpJumpIfFalse || in.op == OpJumpIfTrue {
+			if _, err := target(pc, in); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Calls must land on the OpEnter of a function, which starts with its
+	// arguments on the stack
+	depths := map[int]int{start: 0}
+	worklist := []int{start}
+	params := make(map[int]int) // Parameter count of each call's function
+	for _, pc := range order {
+		in := instrs[pc]
+		if in.op != OpCallFunc {
+			continue
+		}
+		t, err := target(pc, in)
+		if err != nil {
+			return err
+		}
+		enter, ok := instrs[t]
+		if !ok || enter.op != OpEnter {
+			return &VerifyError{Offset: pc, Opcode: in.op, Reason: fmt.Sprintf("call target %d is not a function", in.operand)}
+		}
+		params[pc] = int(enter.operand)
+		if _, seen := depths[t]; !seen {
+			depths[t] = int(enter.operand)
+			worklist = append(worklist, t)
+		}
+	}
+
+	// Propagate the minimum stack depth along every path from the start and
+	// from each function's entry
+	for len(worklist) > 0 {
+		pc := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+		in, ok := instrs[pc]
+		if !ok {
+			continue // End of the region
+		}
+
+		pops, pushes := stackEffect(in)
+		if in.op == OpCallFunc {
+			pops, pushes = params[pc], 1
+		}
+		depth := depths[pc]
+		if depth < pops {
+			return &VerifyError{Offset: pc, Opcode: in.op, Reason: fmt.Sprintf("stack underflow: needs %d values but only %d are on the stack", pops, depth)}
+		}
+		depth += pushes - pops
+		if depth > maxStackSize {
+			return &VerifyError{Offset: pc, Opcode: in.op, Reason: fmt.Sprintf("stack depth exceeds %d", maxStackSize)}
+		}
+
+		var successors []int
+		switch in.op {
+		case OpJump:
+			t, _ := target(pc, in)
+			successors = append(successors, t)
+		case OpJumpIfFalse, OpJumpIfTrue:
+			t, _ := target(pc, in)
+			successors = append(successors, in.next, t)
+		case OpReturn, OpRet, OpHalt, OpHttpReturn:
+		default:
+			successors = append(successors, in.next)
+		}
+		for _, next := range successors {
+			if known, seen := depths[next]; !seen || depth < known {
+				depths[next] = depth
+				worklist = append(worklist, next)
+			}
+		}
+	}
+	return nil
+}
+
+// checkOperand validates the constant-pool references of an instruction
+func (v *verifier) checkOperand(pc int, in instruction) error {
+	switch in.op {
+	case OpPush, OpLoadVar, OpStoreVar:
+		if uint64(in.operand) >= uint64(len(v.constants)) {
+			return &VerifyError{Offset: pc, Opcode: in.op, Reason: fmt.Sprintf("constant index out of bounds: %d (pool has %d)", in.operand, len(v.constants))}
+		}
+		if in.op != OpPush {
+			if _, ok := v.constants[in.operand].(StringValue); !ok {
+				return &VerifyError{Offset: pc, Opcode: in.op, Reason: fmt.Sprintf("constant %d is not a variable name", in.operand)}
+			}
+		}
+	}
+	return nil
+}
+
+// stackEffect returns how many values an instruction pops and pushes
+func stackEffect(in instruction) (pops, pushes int) {
+	n := int(in.operand)
+	switch in.op {
+	case OpPush, OpLoadVar, OpAsync, OpWsGetRooms, OpWsGetConnCount, OpWsGetUptime:
+		return 0, 1
+	case OpPop, OpStoreVar, OpJumpIfFalse, OpJumpIfTrue, OpYield, OpRet:
+		return 1, 0
+	case OpAdd, OpSub, OpMul, OpDiv, OpMod, OpEq, OpNe, OpLt, OpGt, OpGe, OpLe,
+		OpAnd, OpOr, OpGetIndex, OpGetField, OpGetFieldOpt, OpSpread, OpWsBroadcastRoom, OpWsJoinClient, OpWsLeaveClient,
+		OpWsGetState, OpWsReject:
+		return 2, 1
+	case OpWsSetState, OpCheckType:
+		return 3, 1
+	case OpSetIndex, OpSetField:
+		return 3, 0
+	case OpNot, OpNeg, OpGetIter, OpIterHasNext, OpHttpReturn, OpAwait,
+		OpWsSend, OpWsBroadcast, OpWsJoinRoom, OpWsLeaveRoom, OpWsClose, OpWsGetClients:
+		return 1, 1
+	case OpIterNext:
+		if in.operand != 0 {
+			return 1, 2
+		}
+		return 1, 1
+	case OpCall:
+		return n + 1, 1
+	case OpBuildObject:
+		return 2 * n, 1
+	case OpBuildArray:
+		return n, 1
+	}
+	return 0, 0 // Jump, Return, Halt, Background, Enter (CallFunc depends on its target)
+}
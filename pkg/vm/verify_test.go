@@ -0,0 +1,267 @@
+package vm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// program builds bytecode from constants and instructions; each instruction
+// is an opcode optionally followed by its operand
+func program(constants []Value, instrs ...[]uint32) []byte {
+	bytecode := createBytecodeHeader(constants)
+	for _, in := range instrs {
+		var operand *uint32
+		if len(in) > 1 {
+			operand = &in[1]
+		}
+		bytecode = addInstruction(bytecode, Opcode(in[0]), operand)
+	}
+	return bytecode
+}
+
+func op(opcode Opcode, operand ...uint32) []uint32 {
+	return append([]uint32{uint32(opcode)}, operand...)
+}
+
+var verifyConstants = []Value{IntValue{Val: 1}, StringValue{Val: "x"}, BoolValue{Val: true}}
+
+// verifyCode is the offset of the first instruction of a program built
+// from verifyConstants
+var verifyCode = uint32(len(createBytecodeHeader(verifyConstants)))
+
+func TestVerifyAcceptsValidPrograms(t *testing.T) {
+	c := verifyCode
+	tests := []struct {
+		name     string
+		bytecode []byte
+	}{
+		{"empty", program(verifyConstants)},
+		{"arithmetic", program(verifyConstants, op(OpPush, 0), op(OpPush, 0), op(OpAdd), op(OpReturn))},
+		{"variables", program(verifyConstants, op(OpPush, 0), op(OpStoreVar, 1), op(OpLoadVar, 1), op(OpHalt))},
+		{
+			// if true { 1 } else { 1 }, jumping to the end of the code
+			name: "branches",
+			bytecode: program(verifyConstants,
+				op(OpPush, 2), op(OpJumpIfFalse, c+20),
+				op(OpPush, 0), op(OpJump, c+25),
+				op(OpPush, 0)),
+		},
+		{
+			name: "calls and collections",
+			bytecode: program(verifyConstants,
+				op(OpPush, 1), op(OpPush, 0), op(OpCall, 1),
+				op(OpPush, 1), op(OpPush, 0), op(OpBuildObject, 1),
+				op(OpBuildArray, 2), op(OpHttpReturn)),
+		},
+		{
+			name: "loop",
+			bytecode: program(verifyConstants,
+				op(OpPush, 2), op(OpJumpIfFalse, c+15), op(OpJump, c)),
+		},
+		{
+			// A function starts with its two arguments on the stack
+			name: "function call",
+			bytecode: program(verifyConstants,
+				op(OpPush, 0), op(OpPush, 0), op(OpCallFunc, c+16), op(OpReturn),
+				op(OpEnter, 2), op(OpStoreVar, 1), op(OpStoreVar, 1), op(OpLoadVar, 1), op(OpRet)),
+		},
+		{
+			// Jumps in an async body are relative to the start of the body
+			name: "async body",
+			bytecode: program(verifyConstants,
+				op(OpAsync, 10), op(OpPush, 2), op(OpJumpIfTrue, 10),
+				op(OpAwait), op(OpReturn)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.bytecode); err != nil {
+				t.Errorf("Verify() error: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsMalformedPrograms(t *testing.T) {
+	c := verifyCode
+	badVersion := program(verifyConstants)
+	badVersion[4] = 2
+
+	tests := []struct {
+		name       string
+		bytecode   []byte
+		want       string
+		wantOffset uint32 // Offset of the rejected instruction, 0 for header errors
+		wantOpcode Opcode
+	}{
+		{name: "too short", bytecode: []byte("GL"), want: "too short"},
+		{name: "bad magic", bytecode: []byte("NOPE\x01\x00\x00\x00"), want: "bad magic"},
+		{name: "bad version", bytecode: badVersion, want: "unsupported bytecode version"},
+		{name: "truncated constant", bytecode: program(verifyConstants)[:20], want: "truncated"},
+		{
+			name:       "unknown opcode",
+			bytecode:   program(verifyConstants, op(OpPush, 0), op(0xEE)),
+			want:       "unknown opcode",
+			wantOffset: c + 5,
+			wantOpcode: 0xEE,
+		},
+		{
+			name:       "truncated operand",
+			bytecode:   append(program(verifyConstants), byte(OpPush), 0x00, 0x00),
+			want:       "truncated operand",
+			wantOffset: c,
+			wantOpcode: OpPush,
+		},
+		{
+			name:       "constant index",
+			bytecode:   program(verifyConstants, op(OpPush, 3)),
+			want:       "constant index out of bounds: 3",
+			wantOffset: c,
+			wantOpcode: OpPush,
+		},
+		{
+			name:       "variable name not a string",
+			bytecode:   program(verifyConstants, op(OpLoadVar, 0)),
+			want:       "not a variable name",
+			wantOffset: c,
+			wantOpcode: OpLoadVar,
+		},
+		{
+			name:       "jump into operand",
+			bytecode:   program(verifyConstants, op(OpPush, 0), op(OpJump, c+1)),
+			want:       "not an instruction boundary",
+			wantOffset: c + 5,
+			wantOpcode: OpJump,
+		},
+		{
+			name:       "jump into header",
+			bytecode:   program(verifyConstants, op(OpJump, 0)),
+			want:       "not an instruction boundary",
+			wantOffset: c,
+			wantOpcode: OpJump,
+		},
+		{
+			name:       "jump past end",
+			bytecode:   program(verifyConstants, op(OpJump, c+6)),
+			want:       "not an instruction boundary",
+			wantOffset: c,
+			wantOpcode: OpJump,
+		},
+		{
+			name: "jump into async body",
+			bytecode: program(verifyConstants,
+				op(OpJump, c+10), op(OpAsync, 5), op(OpPush, 0)),
+			want:       "not an instruction boundary",
+			wantOffset: c,
+			wantOpcode: OpJump,
+		},
+		{
+			name:       "call target not a function",
+			bytecode:   program(verifyConstants, op(OpPush, 0), op(OpCallFunc, c)),
+			want:       "call target",
+			wantOffset: c + 5,
+			wantOpcode: OpCallFunc,
+		},
+		{
+			name: "function returns nothing",
+			bytecode: program(verifyConstants,
+				op(OpCallFunc, c+6), op(OpReturn), op(OpEnter, 0), op(OpRet)),
+			want:       "stack underflow",
+			wantOffset: c + 11,
+			wantOpcode: OpRet,
+		},
+		{
+			name:       "stack underflow",
+			bytecode:   program(verifyConstants, op(OpPush, 0), op(OpAdd)),
+			want:       "stack underflow",
+			wantOffset: c + 5,
+			wantOpcode: OpAdd,
+		},
+		{
+			// Only the path that skips the push underflows
+			name: "underflow on one branch",
+			bytecode: program(verifyConstants,
+				op(OpPush, 2), op(OpJumpIfFalse, c+15), op(OpPush, 0), op(OpPop)),
+			want:       "stack underflow",
+			wantOffset: c + 15,
+			wantOpcode: OpPop,
+		},
+		{
+			name:       "huge array",
+			bytecode:   program(verifyConstants, op(OpBuildArray, 0xFFFFFFFF)),
+			want:       "stack underflow",
+			wantOffset: c,
+			wantOpcode: OpBuildArray,
+		},
+		{
+			name:       "async body past end",
+			bytecode:   program(verifyConstants, op(OpAsync, 100), op(OpPush, 0)),
+			want:       "extends past the end",
+			wantOffset: c,
+			wantOpcode: OpAsync,
+		},
+		{
+			name:       "underflow in async body",
+			bytecode:   program(verifyConstants, op(OpAsync, 1), op(OpPop)),
+			want:       "stack underflow",
+			wantOffset: c + 5,
+			wantOpcode: OpPop,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.bytecode)
+			if err == nil {
+				t.Fatal("Expected verification error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+
+			var verr *VerifyError
+			if tt.wantOffset == 0 {
+				if errors.As(err, &verr) {
+					t.Errorf("Expected a header error, got: %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &verr) {
+				t.Fatalf("Expected *VerifyError, got %T", err)
+			}
+			if verr.Offset != int(tt.wantOffset) || verr.Opcode != tt.wantOpcode {
+				t.Errorf("Expected offset %d (%s), got %d (%s)",
+					tt.wantOffset, opcodeName(tt.wantOpcode), verr.Offset, opcodeName(verr.Opcode))
+			}
+		})
+	}
+}
+
+func TestExecuteVerifiesBytecode(t *testing.T) {
+	vm := NewVM()
+	_, err := vm.Execute(program(verifyConstants, op(OpPush, 0), op(OpJump, verifyCode+1)))
+	if err == nil || !strings.Contains(err.Error(), "invalid bytecode at offset") {
+		t.Errorf("Expected verification error, got: %v", err)
+	}
+}
+
+func FuzzVerify(f *testing.F) {
+	c := verifyCode
+	f.Add(program(verifyConstants))
+	f.Add(program(verifyConstants, op(OpPush, 0), op(OpPush, 0), op(OpAdd), op(OpReturn)))
+	f.Add(program(verifyConstants, op(OpPush, 2), op(OpJumpIfFalse, c+15), op(OpJump, c)))
+	f.Add(program(verifyConstants, op(OpAsync, 10), op(OpPush, 2), op(OpJumpIfTrue, 10), op(OpAwait)))
+	f.Add(program(verifyConstants, op(OpPush, 1), op(OpPush, 0), op(OpBuildObject, 1), op(OpHttpReturn)))
+
+	f.Fuzz(func(t *testing.T, bytecode []byte) {
+		if Verify(bytecode) != nil {
+			return
+		}
+		// Verified bytecode may still fail at run time, but must not panic
+		vm := NewVM()
+		vm.SetMaxSteps(1000)
+		_, _ = vm.Execute(bytecode)
+	})
+}
@@ -6,6 +6,8 @@ import (
 	"math"
 	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Opcode represents a bytecode operation
@@ -38,11 +40,19 @@ const (
 	OpIterNext    Opcode = 0x54
 	OpIterHasNext Opcode = 0x55
 	OpGetIndex    Opcode = 0x56
+	OpSetIndex    Opcode = 0x57 // Store a value at an array index or object key
 	OpReturn      Opcode = 0x61
 	OpCall        Opcode = 0x62
+	OpCallFunc    Opcode = 0x63 // Call a compiled function (operand: offset of its OpEnter)
+	OpEnter       Opcode = 0x64 // First instruction of a compiled function (operand: parameter count)
+	OpRet         Opcode = 0x65 // Return from a compiled function to its caller
+	OpCheckType   Opcode = 0x66 // Check a value against a type (operand: TypeCheck flags)
 	OpBuildObject Opcode = 0x70
 	OpGetField    Opcode = 0x71
+	OpGetFieldOpt Opcode = 0x72 // Get field, null if the object is null or lacks it
+	OpSetField    Opcode = 0x73 // Store a value in an object field
 	OpBuildArray  Opcode = 0x80
+	OpSpread      Opcode = 0x81 // Append an array to the array below it, or merge an object into the object below it
 	OpHttpReturn  Opcode = 0x90
 
 	// WebSocket opcodes
@@ -56,11 +66,22 @@ const (
 	OpWsGetClients    Opcode = 0xA7 // Get clients in room
 	OpWsGetConnCount  Opcode = 0xA8 // Get total connection count
 	OpWsGetUptime     Opcode = 0xA9 // Get server uptime in seconds
+	OpWsJoinClient    Opcode = 0xAA // Add a client to a room
+	OpWsLeaveClient   Opcode = 0xAB // Remove a client from a room
+	OpWsSetState      Opcode = 0xAC // Store a value in a client's state
+	OpWsGetState      Opcode = 0xAD // Load a value from a client's state
+	OpWsReject        Opcode = 0xAE // Refuse the current connection
 
 	// Async/await opcodes
 	OpAsync Opcode = 0xB0 // Create async future (operand: body length)
 	OpAwait Opcode = 0xB1 // Await a future
 
+	// Background block opcode
+	OpBackground Opcode = 0xB2 // Run a block after the response (operand: body length)
+
+	// Streaming opcode
+	OpYield Opcode = 0xB3 // Send a value as an event on the response stream
+
 	OpHalt Opcode = 0xFF
 )
 
@@ -82,6 +103,11 @@ type WebSocketHandler interface {
 	BroadcastToRoom(room string, message interface{}) error
 	JoinRoom(room string) error
 	LeaveRoom(room string) error
+	JoinClientRoom(client, room string) error  // Add any connected client to a room
+	LeaveClientRoom(client, room string) error // Remove any connected client from a room
+	SetState(client, key string, value interface{}) error
+	GetState(client, key string) (interface{}, error) // nil if the key is unset
+	Reject(code int, reason string) error             // Refuse the connection from a connect handler
 	Close(reason string) error
 	GetRooms() []string
 	GetRoomClients(room string) []string
@@ -90,6 +116,32 @@ type WebSocketHandler interface {
 	GetUptime() int64 // uptime in seconds
 }
 
+// QueuePublisher publishes messages for queue.publish() and enqueue()
+type QueuePublisher interface {
+	Enqueue(queueName string, message interface{}) error
+}
+
+// EventEmitter delivers the events passed to emit() to the program's event
+// handlers
+type EventEmitter interface {
+	Emit(eventType string, data interface{}) error
+}
+
+// Cache stores the values of cache.get(), cache.set() and cache.delete().
+// A ttl of zero means the value does not expire.
+type Cache interface {
+	Get(key string) (interface{}, bool, error)
+	Set(key string, value interface{}, ttl time.Duration) error
+	Delete(key string) error
+}
+
+// StreamWriter sends the values passed to yield as events on a streaming
+// response. It has the same method as interpreter.SSEWriter, so one writer
+// serves both execution modes.
+type StreamWriter interface {
+	SendEvent(data interface{}, eventType string) error
+}
+
 // VM represents the virtual machine
 type VM struct {
 	stack      []Value
@@ -102,10 +154,46 @@ type VM struct {
 	pc         int // program counter
 	code       []byte
 	halted     bool
+	frames     []callFrame // Callers of the compiled function being executed
 
 	// WebSocket context (set when executing WebSocket handlers)
 	wsHandler WebSocketHandler
 
+	// Queue publisher (set when the program has a queue runtime)
+	queue QueuePublisher
+
+	// Event emitter (set when the program has event handlers)
+	events EventEmitter
+
+	// Cache for the cache.* builtins (set when the host has one)
+	cache Cache
+
+	// Client for the redis.* builtins (set when the host has one)
+	redis Redis
+
+	// Mailer for the mail.* builtins (set when the host has one)
+	mailer Mailer
+
+	// Templates for render() and renderString() (set when the host has them)
+	templates Templates
+
+	// Checker for the types of compiled functions' parameters and results
+	// (set when the host has one)
+	types TypeChecker
+
+	// Prefixes of the environment variables env() may read (empty = all)
+	envPrefixes []string
+
+	// Response cookies and session of the current request (set by the host)
+	cookies ResponseCookies
+	session Session
+
+	// Stream that yield sends events to (set for SSE routes)
+	stream StreamWriter
+
+	// Background blocks reached by the current execution
+	background []BackgroundTask
+
 	// Maximum number of execution steps (0 = unlimited)
 	maxSteps int
 }
@@ -127,15 +215,40 @@ func NewVM() *VM {
 	return vm
 }
 
-// Execute runs bytecode
-func (vm *VM) Execute(bytecode []byte) (Value, error) {
-	if len(bytecode) < 4 {
-		return nil, fmt.Errorf("invalid bytecode: too short")
-	}
+// NewVMWithLimit creates a virtual machine that stops with a
+// *StepLimitError after executing maxSteps instructions. Reset clears the
+// limit along with the VM's other settings.
+func NewVMWithLimit(maxSteps int) *VM {
+	vm := NewVM()
+	vm.maxSteps = maxSteps
+	return vm
+}
 
-	// Verify magic bytes
-	if string(bytecode[0:4]) != "GLYP" {
-		return nil, fmt.Errorf("invalid bytecode: bad magic bytes")
+// StepLimitError is returned when execution runs more instructions than
+// the VM's step limit allows, such as in an infinite loop
+type StepLimitError struct {
+	Limit int
+}
+
+func (e *StepLimitError) Error() string {
+	return fmt.Sprintf("execution exceeded maximum step limit (%d steps)", e.Limit)
+}
+
+// ErrorResponse is returned by error(status, message) to end the route with
+// the status and {"error": message}
+type ErrorResponse struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *ErrorResponse) Error() string {
+	return fmt.Sprintf("%d: %s", e.StatusCode, e.Message)
+}
+
+// Execute verifies and runs bytecode
+func (vm *VM) Execute(bytecode []byte) (Value, error) {
+	if err := Verify(bytecode); err != nil {
+		return nil, err
 	}
 
 	// Parse bytecode
@@ -147,6 +260,8 @@ func (vm *VM) Execute(bytecode []byte) (Value, error) {
 	vm.code = bytecode
 	vm.pc = offset
 	vm.halted = false
+	vm.frames = vm.frames[:0]
+	vm.background = nil
 
 	return vm.runLoop()
 }
@@ -171,7 +286,7 @@ func (vm *VM) runLoop() (Value, error) {
 		}
 		steps++
 		if vm.maxSteps > 0 && steps > vm.maxSteps {
-			return nil, fmt.Errorf("execution exceeded maximum step limit (%d steps)", vm.maxSteps)
+			return nil, &StepLimitError{Limit: vm.maxSteps}
 		}
 	}
 
@@ -339,17 +454,33 @@ func (vm *VM) executeInstruction(opcode Opcode) error {
 		return vm.execIterHasNext()
 	case OpGetIndex:
 		return vm.execGetIndex()
+	case OpSetIndex:
+		return vm.execSetIndex()
 	case OpReturn:
 		vm.halted = true
 		return nil
 	case OpCall:
 		return vm.execCall()
+	case OpCallFunc:
+		return vm.execCallFunc()
+	case OpEnter:
+		return vm.execEnter()
+	case OpRet:
+		return vm.execRet()
+	case OpCheckType:
+		return vm.execCheckType()
 	case OpBuildObject:
 		return vm.execBuildObject()
 	case OpGetField:
 		return vm.execGetField()
+	case OpGetFieldOpt:
+		return vm.execGetFieldOpt()
+	case OpSetField:
+		return vm.execSetField()
 	case OpBuildArray:
 		return vm.execBuildArray()
+	case OpSpread:
+		return vm.execSpread()
 	case OpHttpReturn:
 		return vm.execHttpReturn()
 	case OpWsSend:
@@ -372,10 +503,24 @@ func (vm *VM) executeInstruction(opcode Opcode) error {
 		return vm.execWsGetConnCount()
 	case OpWsGetUptime:
 		return vm.execWsGetUptime()
+	case OpWsJoinClient:
+		return vm.execWsClientRoom(true)
+	case OpWsLeaveClient:
+		return vm.execWsClientRoom(false)
+	case OpWsSetState:
+		return vm.execWsSetState()
+	case OpWsGetState:
+		return vm.execWsGetState()
+	case OpWsReject:
+		return vm.execWsReject()
 	case OpAsync:
 		return vm.execAsync()
 	case OpAwait:
 		return vm.execAwait()
+	case OpBackground:
+		return vm.execBackground()
+	case OpYield:
+		return vm.execYield()
 	case OpHalt:
 		vm.halted = true
 		return nil
@@ -413,7 +558,11 @@ func (vm *VM) execAdd() error {
 	switch av := a.(type) {
 	case IntValue:
 		if bv, ok := b.(IntValue); ok {
-			vm.Push(IntValue{Val: av.Val + bv.Val})
+			result, err := AddInt(av.Val, bv.Val)
+			if err != nil {
+				return err
+			}
+			vm.Push(IntValue{Val: result})
 			return nil
 		}
 		if bv, ok := b.(FloatValue); ok {
@@ -462,7 +611,11 @@ func (vm *VM) execSub() error {
 	switch av := a.(type) {
 	case IntValue:
 		if bv, ok := b.(IntValue); ok {
-			vm.Push(IntValue{Val: av.Val - bv.Val})
+			result, err := SubInt(av.Val, bv.Val)
+			if err != nil {
+				return err
+			}
+			vm.Push(IntValue{Val: result})
 			return nil
 		}
 		if bv, ok := b.(FloatValue); ok {
@@ -497,7 +650,11 @@ func (vm *VM) execMul() error {
 	switch av := a.(type) {
 	case IntValue:
 		if bv, ok := b.(IntValue); ok {
-			vm.Push(IntValue{Val: av.Val * bv.Val})
+			result, err := MulInt(av.Val, bv.Val)
+			if err != nil {
+				return err
+			}
+			vm.Push(IntValue{Val: result})
 			return nil
 		}
 		if bv, ok := b.(FloatValue); ok {
@@ -532,10 +689,11 @@ func (vm *VM) execDiv() error {
 	switch av := a.(type) {
 	case IntValue:
 		if bv, ok := b.(IntValue); ok {
-			if bv.Val == 0 {
-				return fmt.Errorf("division by zero")
+			result, err := DivInt(av.Val, bv.Val)
+			if err != nil {
+				return err
 			}
-			vm.Push(IntValue{Val: av.Val / bv.Val})
+			vm.Push(IntValue{Val: result})
 			return nil
 		}
 		if bv, ok := b.(FloatValue); ok {
@@ -865,7 +1023,7 @@ func (vm *VM) execIterNext() error {
 	return nil
 }
 
-// execGetIndex gets an element from an array by index
+// execGetIndex gets an element of an array by index or of an object by key
 func (vm *VM) execGetIndex() error {
 	index, err := vm.Pop()
 	if err != nil {
@@ -880,24 +1038,73 @@ func (vm *VM) execGetIndex() error {
 	case ArrayValue:
 		indexInt, ok := index.(IntValue)
 		if !ok {
-			return fmt.Errorf("type error: array index must be an integer, got %s", index.Type())
+			return fmt.Errorf("array index must be an integer, got %s", index.Type())
 		}
-		if indexInt.Val < 0 || indexInt.Val >= int64(len(container.Val)) {
-			return fmt.Errorf("index out of bounds: %d", indexInt.Val)
+		// Negative indices count from the end, as in the interpreter
+		pos := indexInt.Val
+		if pos < 0 {
+			pos += int64(len(container.Val))
 		}
-		vm.Push(container.Val[indexInt.Val])
+		if pos < 0 || pos >= int64(len(container.Val)) {
+			return fmt.Errorf("array index out of bounds: %d (length: %d)", indexInt.Val, len(container.Val))
+		}
+		vm.Push(container.Val[pos])
 	case ObjectValue:
 		keyStr, ok := index.(StringValue)
 		if !ok {
-			return fmt.Errorf("type error: object key must be a string, got %s", index.Type())
+			return fmt.Errorf("map key must be a string, got %s", index.Type())
 		}
-		if val, exists := container.Val[keyStr.Val]; exists {
-			vm.Push(val)
-		} else {
-			vm.Push(NullValue{})
+		val, exists := container.Val[keyStr.Val]
+		if !exists {
+			return fmt.Errorf("key %q not found in object", keyStr.Val)
+		}
+		vm.Push(val)
+	default:
+		return fmt.Errorf("cannot index %s", arr.Type())
+	}
+	return nil
+}
+
+// execSetIndex stores a value at an index of an array or a key of an object
+// (arr[0] = value). The stack holds the value, then the container, then the
+// index, so the value is evaluated first as it is in the interpreter. Arrays
+// and objects share their storage, so every reference sees the change.
+func (vm *VM) execSetIndex() error {
+	index, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+	arr, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+	value, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+
+	switch container := arr.(type) {
+	case ArrayValue:
+		indexInt, ok := index.(IntValue)
+		if !ok {
+			return fmt.Errorf("array index must be an integer, got %s", index.Type())
+		}
+		pos := indexInt.Val
+		if pos < 0 {
+			pos += int64(len(container.Val))
 		}
+		if pos < 0 || pos >= int64(len(container.Val)) {
+			return fmt.Errorf("array index out of bounds: %d (length: %d)", indexInt.Val, len(container.Val))
+		}
+		container.Val[pos] = value
+	case ObjectValue:
+		keyStr, ok := index.(StringValue)
+		if !ok {
+			return fmt.Errorf("map key must be a string, got %s", index.Type())
+		}
+		container.Val[keyStr.Val] = value
 	default:
-		return fmt.Errorf("type error: can only index arrays or objects, got %s", arr.Type())
+		return fmt.Errorf("cannot index-assign to %s", arr.Type())
 	}
 	return nil
 }
@@ -957,6 +1164,45 @@ func (vm *VM) execBuildArray() error {
 	return nil
 }
 
+// execSpread spreads the value on top of the stack into the literal being
+// built below it: the elements of an array are appended to an array, and the
+// fields of an object are copied over an object's
+func (vm *VM) execSpread() error {
+	val, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+	target, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+
+	switch t := target.(type) {
+	case ArrayValue:
+		if v, ok := val.(ArrayValue); ok {
+			result := make([]Value, 0, len(t.Val)+len(v.Val))
+			result = append(result, t.Val...)
+			result = append(result, v.Val...)
+			vm.Push(ArrayValue{Val: result})
+			return nil
+		}
+	case ObjectValue:
+		if v, ok := val.(ObjectValue); ok {
+			result := make(map[string]Value, len(t.Val)+len(v.Val))
+			for k, fv := range t.Val {
+				result[k] = fv
+			}
+			for k, fv := range v.Val {
+				result[k] = fv
+			}
+			vm.Push(ObjectValue{Val: result})
+			return nil
+		}
+	}
+
+	return fmt.Errorf("type error: cannot spread %s into %s", val.Type(), target.Type())
+}
+
 // execHttpReturn handles HTTP return
 func (vm *VM) execHttpReturn() error {
 	val, err := vm.Pop()
@@ -1157,7 +1403,11 @@ func (vm *VM) execNeg() error {
 
 	switch av := a.(type) {
 	case IntValue:
-		vm.Push(IntValue{Val: -av.Val})
+		result, err := NegInt(av.Val)
+		if err != nil {
+			return err
+		}
+		vm.Push(IntValue{Val: result})
 	case FloatValue:
 		vm.Push(FloatValue{Val: -av.Val})
 	default:
@@ -1214,7 +1464,7 @@ func (vm *VM) execJumpIfTrue() error {
 	return nil
 }
 
-// execGetField gets a field from an object
+// execGetField gets a field from an object; a missing field is null
 func (vm *VM) execGetField() error {
 	key, err := vm.Pop()
 	if err != nil {
@@ -1230,17 +1480,80 @@ func (vm *VM) execGetField() error {
 		return fmt.Errorf("type error: field name must be a string")
 	}
 
+	switch objVal := obj.(type) {
+	case NullValue:
+		return fmt.Errorf("cannot access field %s on null", keyStr.Val)
+	case ObjectValue:
+		// Missing fields are null, as in the interpreter (e.g. input.user_id)
+		if fieldVal, exists := objVal.Val[keyStr.Val]; exists {
+			vm.Push(fieldVal)
+		} else {
+			vm.Push(NullValue{})
+		}
+	default:
+		return fmt.Errorf("cannot access field %s on %s", keyStr.Val, obj.Type())
+	}
+	return nil
+}
+
+// execSetField stores a value in a field of an object (user.name = value).
+// The stack holds the value, then the object, then the field name.
+func (vm *VM) execSetField() error {
+	key, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+	obj, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+	value, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+
+	keyStr, ok := key.(StringValue)
+	if !ok {
+		return fmt.Errorf("type error: field name must be a string")
+	}
+
 	objVal, ok := obj.(ObjectValue)
 	if !ok {
-		return fmt.Errorf("type error: can only get field from object")
+		return fmt.Errorf("cannot assign field '%s' on %s", keyStr.Val, obj.Type())
 	}
+	objVal.Val[keyStr.Val] = value
+	return nil
+}
 
-	fieldVal, exists := objVal.Val[keyStr.Val]
-	if !exists {
-		return fmt.Errorf("field not found: %s", keyStr.Val)
+// execGetFieldOpt gets a field for optional access (obj?.field): a null
+// object or a missing field gives null
+func (vm *VM) execGetFieldOpt() error {
+	key, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+	obj, err := vm.Pop()
+	if err != nil {
+		return err
 	}
 
-	vm.Push(fieldVal)
+	keyStr, ok := key.(StringValue)
+	if !ok {
+		return fmt.Errorf("type error: field name must be a string")
+	}
+
+	switch objVal := obj.(type) {
+	case NullValue:
+		vm.Push(NullValue{})
+	case ObjectValue:
+		if fieldVal, exists := objVal.Val[keyStr.Val]; exists {
+			vm.Push(fieldVal)
+		} else {
+			vm.Push(NullValue{})
+		}
+	default:
+		return fmt.Errorf("cannot access field %s on %s", keyStr.Val, obj.Type())
+	}
 	return nil
 }
 
@@ -1289,6 +1602,69 @@ func (vm *VM) execCall() error {
 	return fmt.Errorf("undefined function: %s", fnName.Val)
 }
 
+// MaxCallDepth is how deeply compiled functions may call each other before
+// execution fails, which stops runaway recursion
+const MaxCallDepth = 1000
+
+// callFrame is the state of a caller saved by OpCallFunc
+type callFrame struct {
+	returnPC int
+	locals   map[string]Value
+}
+
+// execCallFunc calls the compiled function at the operand's offset. Its
+// arguments stay on the stack for the function to store in its own locals.
+func (vm *VM) execCallFunc() error {
+	target, err := vm.readOperand()
+	if err != nil {
+		return err
+	}
+	if int(target) >= len(vm.code) || Opcode(vm.code[target]) != OpEnter {
+		return fmt.Errorf("call target %d is not a function", target)
+	}
+	if len(vm.frames) >= MaxCallDepth {
+		return fmt.Errorf("maximum call depth exceeded (%d calls)", MaxCallDepth)
+	}
+	vm.frames = append(vm.frames, callFrame{returnPC: vm.pc, locals: vm.locals})
+	vm.locals = make(map[string]Value)
+	vm.pc = int(target)
+	return nil
+}
+
+// execEnter starts a compiled function, whose arguments must be on the
+// stack
+func (vm *VM) execEnter() error {
+	params, err := vm.readOperand()
+	if err != nil {
+		return err
+	}
+	if len(vm.frames) == 0 {
+		return fmt.Errorf("function entered without a call")
+	}
+	if len(vm.stack) < int(params) {
+		return fmt.Errorf("function expects %d arguments but the stack holds %d", params, len(vm.stack))
+	}
+	return nil
+}
+
+// execRet returns the value on top of the stack to the caller of the
+// current function, restoring the caller's locals
+func (vm *VM) execRet() error {
+	if len(vm.frames) == 0 {
+		return fmt.Errorf("return outside of a function")
+	}
+	result, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+	frame := vm.frames[len(vm.frames)-1]
+	vm.frames = vm.frames[:len(vm.frames)-1]
+	vm.locals = frame.locals
+	vm.pc = frame.returnPC
+	vm.Push(result)
+	return nil
+}
+
 // readOperand reads a 4-byte operand
 func (vm *VM) readOperand() (uint32, error) {
 	if vm.pc+4 > len(vm.code) {
@@ -1370,16 +1746,37 @@ func (vm *VM) LocalsCount() int {
 	return len(vm.locals)
 }
 
-// Reset clears the VM state for reuse
+// Reset clears the VM state for reuse, leaving it equivalent to a VM
+// returned by NewVM. Allocated capacity is kept, but every value from the
+// previous execution is released, including the WebSocket handler and the
+// step limit.
 func (vm *VM) Reset() {
+	clear(vm.stack[:cap(vm.stack)]) // Drop references to popped values too
 	vm.stack = vm.stack[:0]
-	vm.locals = make(map[string]Value)
+	clear(vm.locals)
+	clear(vm.globals)
+	clear(vm.constants[:cap(vm.constants)])
 	vm.constants = vm.constants[:0]
-	vm.iterators = make(map[int]*Iterator)
+	clear(vm.iterators)
 	vm.nextIterID = 0
 	vm.pc = 0
 	vm.code = nil
 	vm.halted = false
+	vm.frames = vm.frames[:0]
+	vm.wsHandler = nil
+	vm.queue = nil
+	vm.events = nil
+	vm.cache = nil
+	vm.redis = nil
+	vm.mailer = nil
+	vm.templates = nil
+	vm.types = nil
+	vm.envPrefixes = nil
+	vm.cookies = nil
+	vm.session = nil
+	vm.stream = nil
+	vm.background = nil
+	vm.maxSteps = 0
 }
 
 // registerBuiltins registers all built-in functions
@@ -1400,6 +1797,14 @@ func (vm *VM) registerBuiltins() {
 		return IntValue{Val: time.Now().Unix()}, nil
 	}
 
+	// uuid() - returns a random (version 4) UUID string
+	vm.builtins["uuid"] = func(args []Value) (Value, error) {
+		if len(args) != 0 {
+			return nil, fmt.Errorf("uuid() takes no arguments, got %d", len(args))
+		}
+		return StringValue{Val: uuid.NewString()}, nil
+	}
+
 	// length() - returns length of array or string
 	vm.builtins["length"] = func(args []Value) (Value, error) {
 		if len(args) != 1 {
@@ -1562,6 +1967,275 @@ func (vm *VM) registerBuiltins() {
 		}
 		return StringValue{Val: string(runes[start.Val:end.Val])}, nil
 	}
+
+	// queue.publish(queue, message) - publishes a message to a queue
+	// worker; enqueue() is the same builtin
+	vm.builtins["queue.publish"] = vm.queuePublishBuiltin("queue.publish")
+	vm.builtins["enqueue"] = vm.queuePublishBuiltin("enqueue")
+
+	// error(status, message) - ends the route with an error response
+	vm.builtins["error"] = func(args []Value) (Value, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("error() expects 2 arguments (status, message), got %d", len(args))
+		}
+		status, ok := args[0].(IntValue)
+		if !ok {
+			return nil, fmt.Errorf("error() status code must be an int, got %T", args[0])
+		}
+		if status.Val < 400 || status.Val > 599 {
+			return nil, fmt.Errorf("error() status code must be between 400 and 599, got %d", status.Val)
+		}
+		message, ok := args[1].(StringValue)
+		if !ok {
+			return nil, fmt.Errorf("error() message must be a string, got %T", args[1])
+		}
+		return nil, &ErrorResponse{StatusCode: int(status.Val), Message: message.Val}
+	}
+
+	// request.header(name) - a header of the request being handled,
+	// whatever the case of name, or null if it was not sent
+	vm.builtins["request.header"] = func(args []Value) (Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("request.header() expects 1 argument (name), got %d", len(args))
+		}
+		name, ok := args[0].(StringValue)
+		if !ok {
+			return nil, fmt.Errorf("request.header() name must be a string, got %T", args[0])
+		}
+		request, _ := vm.locals["request"].(ObjectValue)
+		headers, ok := request.Val["headers"].(ObjectValue)
+		if !ok {
+			return nil, fmt.Errorf("request.header() can only be called while handling a request")
+		}
+		for key, value := range headers.Val {
+			if strings.EqualFold(key, name.Val) {
+				return value, nil
+			}
+		}
+		return NullValue{}, nil
+	}
+
+	// emit(eventType, data) - emits an event to the program's event
+	// handlers; without an emitter there are no handlers to run
+	vm.builtins["emit"] = func(args []Value) (Value, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("emit() expects 2 arguments (event, data), got %d", len(args))
+		}
+		eventType, ok := args[0].(StringValue)
+		if !ok {
+			return nil, fmt.Errorf("emit() event type must be a string, got %T", args[0])
+		}
+		if vm.events != nil {
+			if err := vm.events.Emit(eventType.Val, valueToInterface(args[1])); err != nil {
+				return nil, fmt.Errorf("emit(): %w", err)
+			}
+		}
+		return BoolValue{Val: true}, nil
+	}
+
+	// cache.get(key), cache.set(key, value, ttlSeconds?) and
+	// cache.delete(key) - the methods of an injected cache. A ttl of 0
+	// means no expiry.
+	vm.builtins["cache.get"] = func(args []Value) (Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("cache.get() expects 1 argument (key), got %d", len(args))
+		}
+		key, err := vm.cacheKey("get", args[0])
+		if err != nil {
+			return nil, err
+		}
+		value, ok, err := vm.cache.Get(key)
+		if err != nil {
+			return nil, fmt.Errorf("cache.get(): %w", err)
+		}
+		if !ok {
+			return NullValue{}, nil
+		}
+		return interfaceToValue(value), nil
+	}
+	vm.builtins["cache.set"] = func(args []Value) (Value, error) {
+		if len(args) != 2 && len(args) != 3 {
+			return nil, fmt.Errorf("cache.set() expects 2 or 3 arguments (key, value, ttlSeconds), got %d", len(args))
+		}
+		key, err := vm.cacheKey("set", args[0])
+		if err != nil {
+			return nil, err
+		}
+		var ttl time.Duration
+		if len(args) == 3 {
+			seconds, ok := args[2].(IntValue)
+			if !ok || seconds.Val < 0 {
+				return nil, fmt.Errorf("cache.set() ttlSeconds must be a non-negative integer, got %v", valueToString(args[2]))
+			}
+			ttl = time.Duration(seconds.Val) * time.Second
+		}
+		if err := vm.cache.Set(key, valueToInterface(args[1]), ttl); err != nil {
+			return nil, fmt.Errorf("cache.set(): %w", err)
+		}
+		return BoolValue{Val: true}, nil
+	}
+	vm.builtins["cache.delete"] = func(args []Value) (Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("cache.delete() expects 1 argument (key), got %d", len(args))
+		}
+		key, err := vm.cacheKey("delete", args[0])
+		if err != nil {
+			return nil, err
+		}
+		if err := vm.cache.Delete(key); err != nil {
+			return nil, fmt.Errorf("cache.delete(): %w", err)
+		}
+		return BoolValue{Val: true}, nil
+	}
+
+	vm.registerMathBuiltins()
+	vm.registerRedisBuiltins()
+	vm.registerMailBuiltins()
+	vm.registerTemplateBuiltins()
+	vm.registerSessionBuiltins()
+	vm.registerEnvBuiltins()
+	vm.registerJSONBuiltins()
+	vm.registerCryptoBuiltins()
+}
+
+// registerMathBuiltins registers the math.* builtins. They accept ints and
+// floats: floor, ceil, round and abs keep the argument's type, min and max
+// give an int for two ints and a float otherwise, and pow and sqrt always
+// give a float.
+func (vm *VM) registerMathBuiltins() {
+	rounding := map[string]func(float64) float64{
+		"floor": math.Floor,
+		"ceil":  math.Ceil,
+		"round": math.Round,
+	}
+	for name, round := range rounding {
+		vm.builtins["math."+name] = func(args []Value) (Value, error) {
+			if err := mathArgs(name, 1, args); err != nil {
+				return nil, err
+			}
+			if f, ok := args[0].(FloatValue); ok {
+				return FloatValue{Val: round(f.Val)}, nil
+			}
+			return args[0], nil
+		}
+	}
+
+	vm.builtins["math.abs"] = func(args []Value) (Value, error) {
+		if err := mathArgs("abs", 1, args); err != nil {
+			return nil, err
+		}
+		if f, ok := args[0].(FloatValue); ok {
+			return FloatValue{Val: math.Abs(f.Val)}, nil
+		}
+		n := args[0].(IntValue).Val
+		if n == math.MinInt64 {
+			return nil, fmt.Errorf("math.abs() overflow: cannot negate minimum int64 value")
+		}
+		if n < 0 {
+			return IntValue{Val: -n}, nil
+		}
+		return args[0], nil
+	}
+
+	minMax := func(name string, smaller bool) BuiltinFunc {
+		return func(args []Value) (Value, error) {
+			if err := mathArgs(name, 2, args); err != nil {
+				return nil, err
+			}
+			a, aInt := args[0].(IntValue)
+			b, bInt := args[1].(IntValue)
+			if aInt && bInt {
+				if (a.Val < b.Val) == smaller {
+					return a, nil
+				}
+				return b, nil
+			}
+			if smaller {
+				return FloatValue{Val: math.Min(mathFloat(args[0]), mathFloat(args[1]))}, nil
+			}
+			return FloatValue{Val: math.Max(mathFloat(args[0]), mathFloat(args[1]))}, nil
+		}
+	}
+	vm.builtins["math.min"] = minMax("min", true)
+	vm.builtins["math.max"] = minMax("max", false)
+
+	vm.builtins["math.pow"] = func(args []Value) (Value, error) {
+		if err := mathArgs("pow", 2, args); err != nil {
+			return nil, err
+		}
+		return FloatValue{Val: math.Pow(mathFloat(args[0]), mathFloat(args[1]))}, nil
+	}
+
+	vm.builtins["math.sqrt"] = func(args []Value) (Value, error) {
+		if err := mathArgs("sqrt", 1, args); err != nil {
+			return nil, err
+		}
+		f := mathFloat(args[0])
+		if f < 0 {
+			return nil, fmt.Errorf("math.sqrt() of negative number %s", valueToString(args[0]))
+		}
+		return FloatValue{Val: math.Sqrt(f)}, nil
+	}
+}
+
+// mathArgs checks that math.name() got want numeric arguments
+func mathArgs(name string, want int, args []Value) error {
+	if len(args) != want {
+		if want == 1 {
+			return fmt.Errorf("math.%s() expects 1 argument, got %d", name, len(args))
+		}
+		return fmt.Errorf("math.%s() expects %d arguments, got %d", name, want, len(args))
+	}
+	for _, arg := range args {
+		switch arg.(type) {
+		case IntValue, FloatValue:
+		default:
+			return fmt.Errorf("math.%s() expects a numeric argument, got %s", name, arg.Type())
+		}
+	}
+	return nil
+}
+
+// mathFloat converts an IntValue or FloatValue to float64
+func mathFloat(v Value) float64 {
+	if n, ok := v.(IntValue); ok {
+		return float64(n.Val)
+	}
+	return v.(FloatValue).Val
+}
+
+// cacheKey checks that a cache is set and key is a string, for the cache.*
+// builtins
+func (vm *VM) cacheKey(method string, key Value) (string, error) {
+	if vm.cache == nil {
+		return "", fmt.Errorf("cache.%s(): no cache is configured", method)
+	}
+	str, ok := key.(StringValue)
+	if !ok {
+		return "", fmt.Errorf("cache.%s() key must be a string, got %T", method, key)
+	}
+	return str.Val, nil
+}
+
+// queuePublishBuiltin returns the builtin that publishes to vm.queue under
+// the given name
+func (vm *VM) queuePublishBuiltin(name string) BuiltinFunc {
+	return func(args []Value) (Value, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s() expects 2 arguments (queue, message), got %d", name, len(args))
+		}
+		queueName, ok := args[0].(StringValue)
+		if !ok {
+			return nil, fmt.Errorf("%s() queue name must be a string, got %T", name, args[0])
+		}
+		if vm.queue == nil {
+			return nil, fmt.Errorf("%s(): no queue runner is running", name)
+		}
+		if err := vm.queue.Enqueue(queueName.Val, valueToInterface(args[1])); err != nil {
+			return nil, fmt.Errorf("%s(): %w", name, err)
+		}
+		return BoolValue{Val: true}, nil
+	}
 }
 
 // valueToString converts a Value to a string representation
@@ -1620,6 +2294,26 @@ func (vm *VM) SetMaxSteps(maxSteps int) {
 	vm.maxSteps = maxSteps
 }
 
+// SetQueuePublisher sets where queue.publish() sends messages
+func (vm *VM) SetQueuePublisher(queue QueuePublisher) {
+	vm.queue = queue
+}
+
+// SetEventEmitter sets where emit() sends events
+func (vm *VM) SetEventEmitter(events EventEmitter) {
+	vm.events = events
+}
+
+// SetCache sets the cache used by the cache.* builtins
+func (vm *VM) SetCache(cache Cache) {
+	vm.cache = cache
+}
+
+// SetStreamWriter sets where yield sends events
+func (vm *VM) SetStreamWriter(stream StreamWriter) {
+	vm.stream = stream
+}
+
 // SetWebSocketHandler sets the WebSocket handler for WS operations
 func (vm *VM) SetWebSocketHandler(handler WebSocketHandler) {
 	vm.wsHandler = handler
@@ -1744,6 +2438,142 @@ func (vm *VM) execWsLeaveRoom() error {
 	return nil
 }
 
+// execWsClientRoom adds a client to a room, or removes it if join is false
+func (vm *VM) execWsClientRoom(join bool) error {
+	if vm.wsHandler == nil {
+		return fmt.Errorf("WebSocket handler not available")
+	}
+
+	roomVal, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+	clientVal, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+
+	room, ok := roomVal.(StringValue)
+	if !ok {
+		return fmt.Errorf("room name must be a string, got %T", roomVal)
+	}
+	client, ok := clientVal.(StringValue)
+	if !ok {
+		return fmt.Errorf("client ID must be a string, got %T", clientVal)
+	}
+
+	if join {
+		err = vm.wsHandler.JoinClientRoom(client.Val, room.Val)
+	} else {
+		err = vm.wsHandler.LeaveClientRoom(client.Val, room.Val)
+	}
+	if err != nil {
+		return err
+	}
+	// Push null so POP after expression statement works correctly
+	vm.Push(NullValue{})
+	return nil
+}
+
+// execWsSetState stores a value in a client's state
+func (vm *VM) execWsSetState() error {
+	if vm.wsHandler == nil {
+		return fmt.Errorf("WebSocket handler not available")
+	}
+
+	value, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+	client, key, err := vm.popClientKey()
+	if err != nil {
+		return err
+	}
+
+	if err := vm.wsHandler.SetState(client, key, valueToInterface(value)); err != nil {
+		return err
+	}
+	// Push null so POP after expression statement works correctly
+	vm.Push(NullValue{})
+	return nil
+}
+
+// execWsGetState loads a value from a client's state
+func (vm *VM) execWsGetState() error {
+	if vm.wsHandler == nil {
+		return fmt.Errorf("WebSocket handler not available")
+	}
+
+	client, key, err := vm.popClientKey()
+	if err != nil {
+		return err
+	}
+
+	value, err := vm.wsHandler.GetState(client, key)
+	if err != nil {
+		return err
+	}
+	vm.Push(interfaceToValue(value))
+	return nil
+}
+
+// popClientKey pops a state key and then a client ID
+func (vm *VM) popClientKey() (client, key string, err error) {
+	keyVal, err := vm.Pop()
+	if err != nil {
+		return "", "", err
+	}
+	clientVal, err := vm.Pop()
+	if err != nil {
+		return "", "", err
+	}
+
+	keyStr, ok := keyVal.(StringValue)
+	if !ok {
+		return "", "", fmt.Errorf("state key must be a string, got %T", keyVal)
+	}
+	clientStr, ok := clientVal.(StringValue)
+	if !ok {
+		return "", "", fmt.Errorf("client ID must be a string, got %T", clientVal)
+	}
+	return clientStr.Val, keyStr.Val, nil
+}
+
+// execWsReject refuses the current connection with a close code and reason.
+// The handler's error stops execution.
+func (vm *VM) execWsReject() error {
+	if vm.wsHandler == nil {
+		return fmt.Errorf("WebSocket handler not available")
+	}
+
+	reasonVal, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+	codeVal, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+
+	code, ok := codeVal.(IntValue)
+	if !ok {
+		return fmt.Errorf("close code must be an integer, got %T", codeVal)
+	}
+	if code.Val < 1000 || code.Val > 4999 {
+		return fmt.Errorf("close code must be between 1000 and 4999, got %d", code.Val)
+	}
+	reason := ""
+	if str, ok := reasonVal.(StringValue); ok {
+		reason = str.Val
+	}
+
+	if err := vm.wsHandler.Reject(int(code.Val), reason); err != nil {
+		return err
+	}
+	vm.Push(NullValue{})
+	return nil
+}
+
 // execWsClose closes the WebSocket connection
 func (vm *VM) execWsClose() error {
 	if vm.wsHandler == nil {
@@ -1840,6 +2670,12 @@ func (vm *VM) execWsGetUptime() error {
 	return nil
 }
 
+// ValueToInterface converts a VM Value to the Go value the interpreter
+// would hold for it
+func ValueToInterface(v Value) interface{} {
+	return valueToInterface(v)
+}
+
 // valueToInterface converts a VM Value to a Go interface{}
 func valueToInterface(v Value) interface{} {
 	switch val := v.(type) {
@@ -1849,6 +2685,8 @@ func valueToInterface(v Value) interface{} {
 		return val.Val
 	case StringValue:
 		return val.Val
+	case HTMLValue:
+		return val.Val
 	case BoolValue:
 		return val.Val
 	case NullValue:
@@ -1870,6 +2708,37 @@ func valueToInterface(v Value) interface{} {
 	}
 }
 
+// interfaceToValue converts a Go interface{} to a VM Value; it reverses
+// valueToInterface
+func interfaceToValue(v interface{}) Value {
+	switch val := v.(type) {
+	case int:
+		return IntValue{Val: int64(val)}
+	case int64:
+		return IntValue{Val: val}
+	case float64:
+		return FloatValue{Val: val}
+	case string:
+		return StringValue{Val: val}
+	case bool:
+		return BoolValue{Val: val}
+	case []interface{}:
+		arr := make([]Value, len(val))
+		for i, elem := range val {
+			arr[i] = interfaceToValue(elem)
+		}
+		return ArrayValue{Val: arr}
+	case map[string]interface{}:
+		obj := make(map[string]Value, len(val))
+		for k, elem := range val {
+			obj[k] = interfaceToValue(elem)
+		}
+		return ObjectValue{Val: obj}
+	default:
+		return NullValue{}
+	}
+}
+
 // execAsync executes an async block and creates a future
 // The async body bytecode follows immediately after the opcode
 // Format: OpAsync [bodyLen:4 bytes] [body bytecode]
@@ -1912,6 +2781,7 @@ func (vm *VM) execAsync() error {
 	for k, v := range vm.builtins {
 		builtinsCopy[k] = v
 	}
+	maxSteps := vm.maxSteps
 
 	go func() {
 		defer close(future.Done)
@@ -1922,7 +2792,7 @@ func (vm *VM) execAsync() error {
 		}()
 
 		// Create a new VM for the async execution
-		asyncVM := NewVM()
+		asyncVM := NewVMWithLimit(maxSteps)
 		asyncVM.constants = constantsCopy
 		asyncVM.locals = localsCopy
 		asyncVM.globals = globalsCopy
@@ -1966,3 +2836,119 @@ func (vm *VM) execAwait() error {
 	vm.Push(result)
 	return nil
 }
+
+// BackgroundTask is a background block reached by a route, with a copy of
+// the route's variables. The host runs it once the response has been sent.
+type BackgroundTask struct {
+	body        []byte
+	constants   []Value
+	locals      map[string]Value
+	globals     map[string]Value
+	queue       QueuePublisher
+	events      EventEmitter
+	cache       Cache
+	redis       Redis
+	mailer      Mailer
+	templates   Templates
+	envPrefixes []string
+	maxSteps    int
+}
+
+// Run executes the block on a VM of its own
+func (t BackgroundTask) Run() error {
+	bgVM := NewVMWithLimit(t.maxSteps)
+	bgVM.constants = t.constants
+	bgVM.locals = t.locals
+	bgVM.globals = t.globals
+	bgVM.queue = t.queue
+	bgVM.events = t.events
+	bgVM.cache = t.cache
+	bgVM.redis = t.redis
+	bgVM.mailer = t.mailer
+	bgVM.templates = t.templates
+	bgVM.envPrefixes = t.envPrefixes
+	_, err := bgVM.executeRaw(t.body)
+	return err
+}
+
+// execYield sends the value on top of the stack as a streamed event. An
+// error from the stream, such as the client having disconnected, stops the
+// route.
+func (vm *VM) execYield() error {
+	value, err := vm.Pop()
+	if err != nil {
+		return err
+	}
+	if vm.stream == nil {
+		return fmt.Errorf("yield can only be used inside an SSE route")
+	}
+	if err := vm.stream.SendEvent(valueToInterface(value), ""); err != nil {
+		return fmt.Errorf("failed to send SSE event: %w", err)
+	}
+	return nil
+}
+
+// BackgroundTasks returns the background blocks reached by the last Execute,
+// in the order they were reached
+func (vm *VM) BackgroundTasks() []BackgroundTask {
+	return vm.background
+}
+
+// execBackground records a background block and skips past it
+// Format: OpBackground [bodyLen:4 bytes] [body bytecode]
+func (vm *VM) execBackground() error {
+	bodyLen, err := vm.readOperand()
+	if err != nil {
+		return err
+	}
+	if vm.pc+int(bodyLen) > len(vm.code) {
+		return fmt.Errorf("background body extends beyond bytecode")
+	}
+	body := vm.code[vm.pc : vm.pc+int(bodyLen)]
+	vm.pc += int(bodyLen)
+
+	// Objects and arrays are copied deeply, so assignments after the block,
+	// and fields the block sets, are not shared with the route
+	task := BackgroundTask{
+		body:        body,
+		constants:   append([]Value(nil), vm.constants...),
+		locals:      make(map[string]Value, len(vm.locals)),
+		globals:     make(map[string]Value, len(vm.globals)),
+		queue:       vm.queue,
+		events:      vm.events,
+		cache:       vm.cache,
+		redis:       vm.redis,
+		mailer:      vm.mailer,
+		templates:   vm.templates,
+		envPrefixes: vm.envPrefixes,
+		maxSteps:    vm.maxSteps,
+	}
+	for k, v := range vm.locals {
+		task.locals[k] = copyValue(v)
+	}
+	for k, v := range vm.globals {
+		task.globals[k] = copyValue(v)
+	}
+	vm.background = append(vm.background, task)
+	return nil
+}
+
+// copyValue returns a deep copy of the objects and arrays in v
+func copyValue(v Value) Value {
+	switch val := v.(type) {
+	case ObjectValue:
+		result := make(map[string]Value, len(val.Val))
+		for k, item := range val.Val {
+			result[k] = copyValue(item)
+		}
+		return ObjectValue{Val: result}
+	case ArrayValue:
+		result := make([]Value, len(val.Val))
+		for n, item := range val.Val {
+			result[n] = copyValue(item)
+		}
+		return ArrayValue{Val: result}
+	default:
+		return v
+	}
+}
@@ -222,3 +222,38 @@ func BenchmarkVMBooleanOperations(b *testing.B) {
 		vm.Pop()
 	}
 }
+
+// BenchmarkRequestVM compares a new VM per request with VMs from a Pool,
+// for a handler that binds locals and returns an object
+func BenchmarkRequestVM(b *testing.B) {
+	constants := []Value{StringValue{Val: "id"}, StringValue{Val: "ok"}, BoolValue{Val: true}}
+	bytecode := program(constants,
+		op(OpPush, 0), op(OpLoadVar, 0),
+		op(OpPush, 1), op(OpPush, 2),
+		op(OpBuildObject, 2), op(OpReturn))
+
+	handle := func(b *testing.B, vm *VM) {
+		vm.SetLocal("id", StringValue{Val: "42"})
+		vm.SetLocal("input", NullValue{})
+		if _, err := vm.Execute(bytecode); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("new", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			handle(b, NewVM())
+		}
+	})
+
+	b.Run("pool", func(b *testing.B) {
+		pool := NewPool(1)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			vm := pool.Acquire()
+			handle(b, vm)
+			pool.Release(vm)
+		}
+	})
+}
@@ -2,6 +2,9 @@ package vm
 
 import (
 	"encoding/binary"
+	"errors"
+	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -310,6 +313,12 @@ func TestMaxSteps_InfiniteLoop(t *testing.T) {
 	if !strings.Contains(err.Error(), "maximum step limit") {
 		t.Errorf("Expected step limit error, got: %v", err)
 	}
+
+	_, err = NewVMWithLimit(50).Execute(bytecode)
+	var stepErr *StepLimitError
+	if !errors.As(err, &stepErr) || stepErr.Limit != 50 {
+		t.Errorf("Expected a *StepLimitError with limit 50, got: %v", err)
+	}
 }
 
 func TestMaxSteps_NormalExecution(t *testing.T) {
@@ -1171,3 +1180,516 @@ func TestBytecode_JumpIfTrue(t *testing.T) {
 		t.Errorf("Expected IntValue{42}, got %v", result)
 	}
 }
+
+// --- queue.publish ---
+
+type recordingPublisher struct {
+	queue   string
+	message interface{}
+}
+
+func (p *recordingPublisher) Enqueue(queueName string, message interface{}) error {
+	p.queue = queueName
+	p.message = message
+	return nil
+}
+
+func TestQueuePublish_WithPublisher(t *testing.T) {
+	vm := NewVM()
+	publisher := &recordingPublisher{}
+	vm.SetQueuePublisher(publisher)
+
+	message := ObjectValue{Val: map[string]Value{"to": StringValue{Val: "ada@example.com"}}}
+	result, err := vm.builtins["queue.publish"]([]Value{StringValue{Val: "email.send"}, message})
+	if err != nil {
+		t.Fatalf("queue.publish() error: %v", err)
+	}
+	if b, ok := result.(BoolValue); !ok || !b.Val {
+		t.Errorf("Expected BoolValue{true}, got %v", result)
+	}
+	if publisher.queue != "email.send" {
+		t.Errorf("Expected queue email.send, got %q", publisher.queue)
+	}
+	if m, ok := publisher.message.(map[string]interface{}); !ok || m["to"] != "ada@example.com" {
+		t.Errorf("Expected message {to: ada@example.com}, got %v", publisher.message)
+	}
+
+	// Reset clears the publisher, so a pooled VM cannot publish to a stale queue
+	vm.Reset()
+	if _, err := vm.builtins["queue.publish"]([]Value{StringValue{Val: "email.send"}, message}); err == nil || !strings.Contains(err.Error(), "no queue runner is running") {
+		t.Errorf("Expected no queue runner error after Reset, got %v", err)
+	}
+}
+
+func TestQueuePublish_Errors(t *testing.T) {
+	vm := NewVM()
+	if _, err := vm.builtins["queue.publish"]([]Value{StringValue{Val: "jobs"}}); err == nil {
+		t.Error("Expected error for wrong argument count")
+	}
+	if _, err := vm.builtins["enqueue"]([]Value{IntValue{Val: 1}, IntValue{Val: 2}}); err == nil || !strings.Contains(err.Error(), "enqueue() queue name must be a string") {
+		t.Errorf("Expected queue name error, got %v", err)
+	}
+	if _, err := vm.builtins["queue.publish"]([]Value{StringValue{Val: "jobs"}, IntValue{Val: 1}}); err == nil || !strings.Contains(err.Error(), "no queue runner is running") {
+		t.Errorf("Expected no queue runner error, got %v", err)
+	}
+}
+
+// --- emit ---
+
+type recordingEmitter struct {
+	eventType string
+	data      interface{}
+}
+
+func (e *recordingEmitter) Emit(eventType string, data interface{}) error {
+	e.eventType = eventType
+	e.data = data
+	return nil
+}
+
+func TestEmit_WithEmitter(t *testing.T) {
+	vm := NewVM()
+	emitter := &recordingEmitter{}
+	vm.SetEventEmitter(emitter)
+
+	data := ObjectValue{Val: map[string]Value{"id": IntValue{Val: 7}}}
+	result, err := vm.builtins["emit"]([]Value{StringValue{Val: "user.created"}, data})
+	if err != nil {
+		t.Fatalf("emit() error: %v", err)
+	}
+	if b, ok := result.(BoolValue); !ok || !b.Val {
+		t.Errorf("Expected BoolValue{true}, got %v", result)
+	}
+	if emitter.eventType != "user.created" {
+		t.Errorf("Expected event user.created, got %q", emitter.eventType)
+	}
+	if m, ok := emitter.data.(map[string]interface{}); !ok || m["id"] != int64(7) {
+		t.Errorf("Expected data {id: 7}, got %v", emitter.data)
+	}
+
+	// Reset clears the emitter; with no handlers emit() does nothing
+	vm.Reset()
+	emitter.eventType = ""
+	if _, err := vm.builtins["emit"]([]Value{StringValue{Val: "user.created"}, data}); err != nil {
+		t.Errorf("emit() without an emitter error: %v", err)
+	}
+	if emitter.eventType != "" {
+		t.Error("Expected no event after Reset")
+	}
+}
+
+func TestEmit_Errors(t *testing.T) {
+	vm := NewVM()
+	if _, err := vm.builtins["emit"]([]Value{StringValue{Val: "user.created"}}); err == nil {
+		t.Error("Expected error for wrong argument count")
+	}
+	if _, err := vm.builtins["emit"]([]Value{IntValue{Val: 1}, IntValue{Val: 2}}); err == nil || !strings.Contains(err.Error(), "emit() event type must be a string") {
+		t.Errorf("Expected event type error, got %v", err)
+	}
+}
+
+type recordingStream struct {
+	events []interface{}
+	err    error
+}
+
+func (s *recordingStream) SendEvent(data interface{}, eventType string) error {
+	s.events = append(s.events, data)
+	return s.err
+}
+
+func TestYield(t *testing.T) {
+	vm := NewVM()
+	vm.constants = []Value{IntValue{Val: 1}, StringValue{Val: "two"}}
+	code := []byte{
+		byte(OpPush), 0, 0, 0, 0, byte(OpYield),
+		byte(OpPush), 1, 0, 0, 0, byte(OpYield),
+		byte(OpHalt),
+	}
+
+	// Without a stream yield fails instead of dropping the event
+	if _, err := vm.executeRaw(code); err == nil || !strings.Contains(err.Error(), "SSE route") {
+		t.Errorf("Expected an error without a stream, got %v", err)
+	}
+
+	stream := &recordingStream{}
+	vm.SetStreamWriter(stream)
+	if _, err := vm.executeRaw(code); err != nil {
+		t.Fatalf("yield error: %v", err)
+	}
+	if len(stream.events) != 2 || stream.events[0] != int64(1) || stream.events[1] != "two" {
+		t.Errorf("Expected events [1 two], got %v", stream.events)
+	}
+
+	// A failed send, such as to a disconnected client, stops execution
+	stream = &recordingStream{err: errors.New("client disconnected")}
+	vm.SetStreamWriter(stream)
+	if _, err := vm.executeRaw(code); err == nil || !strings.Contains(err.Error(), "client disconnected") {
+		t.Errorf("Expected the send error, got %v", err)
+	}
+	if len(stream.events) != 1 {
+		t.Errorf("Expected execution to stop after the failed send, got %d events", len(stream.events))
+	}
+}
+
+// fakeRedis implements Redis with counters and published messages only
+type fakeRedis struct {
+	counters  map[string]int64
+	published map[string]interface{}
+}
+
+func (r *fakeRedis) Get(key string) (interface{}, error)          { return nil, nil }
+func (r *fakeRedis) Set(args ...interface{}) (interface{}, error) { return "OK", nil }
+func (r *fakeRedis) Del(keys ...interface{}) (int64, error)       { return int64(len(keys)), nil }
+func (r *fakeRedis) Incr(key string) (int64, error) {
+	r.counters[key]++
+	return r.counters[key], nil
+}
+func (r *fakeRedis) Expire(key string, seconds interface{}) (bool, error) { return true, nil }
+func (r *fakeRedis) HGet(key, field string) (interface{}, error)          { return nil, nil }
+func (r *fakeRedis) HSet(args ...interface{}) (int64, error)              { return 1, nil }
+func (r *fakeRedis) HGetAll(key string) (map[string]interface{}, error) {
+	return map[string]interface{}{"key": key}, nil
+}
+func (r *fakeRedis) Publish(channel string, message interface{}) (int64, error) {
+	r.published[channel] = message
+	return 1, nil
+}
+
+func TestRedisBuiltins(t *testing.T) {
+	vm := NewVM()
+	key := StringValue{Val: "hits"}
+
+	// Without a client the builtins fail instead of silently doing nothing
+	if _, err := vm.builtins["redis.incr"]([]Value{key}); err == nil || !strings.Contains(err.Error(), "no Redis is configured") {
+		t.Errorf("Expected an error without a client, got %v", err)
+	}
+
+	redis := &fakeRedis{counters: map[string]int64{}, published: map[string]interface{}{}}
+	vm.SetRedis(redis)
+	for want := int64(1); want <= 2; want++ {
+		result, err := vm.builtins["redis.incr"]([]Value{key})
+		if err != nil {
+			t.Fatalf("redis.incr() error: %v", err)
+		}
+		if i, ok := result.(IntValue); !ok || i.Val != want {
+			t.Errorf("Expected IntValue{%d}, got %v", want, result)
+		}
+	}
+
+	data := ObjectValue{Val: map[string]Value{"id": IntValue{Val: 7}}}
+	if _, err := vm.builtins["redis.publish"]([]Value{StringValue{Val: "orders"}, data}); err != nil {
+		t.Fatalf("redis.publish() error: %v", err)
+	}
+	if m, ok := redis.published["orders"].(map[string]interface{}); !ok || m["id"] != int64(7) {
+		t.Errorf("Expected {id: 7} published, got %v", redis.published["orders"])
+	}
+
+	result, err := vm.builtins["redis.hgetall"]([]Value{key})
+	if err != nil {
+		t.Fatalf("redis.hgetall() error: %v", err)
+	}
+	if obj, ok := result.(ObjectValue); !ok || obj.Val["key"] != (StringValue{Val: "hits"}) {
+		t.Errorf("Expected {key: hits}, got %v", result)
+	}
+
+	if _, err := vm.builtins["redis.get"]([]Value{IntValue{Val: 1}}); err == nil || !strings.Contains(err.Error(), "key must be a string") {
+		t.Errorf("Expected key type error, got %v", err)
+	}
+	if _, err := vm.builtins["redis.hset"]([]Value{key, StringValue{Val: "a"}, IntValue{Val: 1}, StringValue{Val: "b"}}); err == nil {
+		t.Error("Expected error for an unpaired hset field")
+	}
+
+	// Reset clears the client
+	vm.Reset()
+	if _, err := vm.builtins["redis.incr"]([]Value{key}); err == nil {
+		t.Error("Expected an error after Reset")
+	}
+}
+
+// fakeMailer records the messages given to Send
+type fakeMailer struct {
+	sent []interface{}
+}
+
+func (m *fakeMailer) Send(args interface{}) (map[string]interface{}, error) {
+	m.sent = append(m.sent, args)
+	return map[string]interface{}{"sent": true}, nil
+}
+
+func (m *fakeMailer) Render(template string, data interface{}) (string, error) {
+	return template + "!", nil
+}
+
+func TestMailBuiltins(t *testing.T) {
+	vm := NewVM()
+	msg := ObjectValue{Val: map[string]Value{"to": StringValue{Val: "ada@example.com"}}}
+	if _, err := vm.builtins["mail.send"]([]Value{msg}); err == nil || !strings.Contains(err.Error(), "no mailer is configured") {
+		t.Errorf("Expected an error without a mailer, got %v", err)
+	}
+
+	mailer := &fakeMailer{}
+	vm.SetMailer(mailer)
+	result, err := vm.builtins["mail.send"]([]Value{msg})
+	if err != nil {
+		t.Fatalf("mail.send() error: %v", err)
+	}
+	if obj, ok := result.(ObjectValue); !ok || obj.Val["sent"] != (BoolValue{Val: true}) {
+		t.Errorf("Expected {sent: true}, got %v", result)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].(map[string]interface{})["to"] != "ada@example.com" {
+		t.Errorf("Expected the message as a Go map, got %v", mailer.sent)
+	}
+
+	result, err = vm.builtins["mail.render"]([]Value{StringValue{Val: "hi"}, ObjectValue{Val: map[string]Value{}}})
+	if err != nil {
+		t.Fatalf("mail.render() error: %v", err)
+	}
+	if result != (StringValue{Val: "hi!"}) {
+		t.Errorf("Expected hi!, got %v", result)
+	}
+	if _, err := vm.builtins["mail.render"]([]Value{IntValue{Val: 1}, NullValue{}}); err == nil {
+		t.Error("Expected error for a non-string template")
+	}
+}
+
+// fakeTemplates renders the name or template followed by the data's name
+// field
+type fakeTemplates struct{}
+
+func (fakeTemplates) Render(name string, data interface{}) (string, error) {
+	return name + ":" + data.(map[string]interface{})["name"].(string), nil
+}
+
+func (fakeTemplates) RenderString(template string, data interface{}) (string, error) {
+	return template + ":" + data.(map[string]interface{})["name"].(string), nil
+}
+
+func TestTemplateBuiltins(t *testing.T) {
+	vm := NewVM()
+	data := ObjectValue{Val: map[string]Value{"name": StringValue{Val: "ada"}}}
+	if _, err := vm.builtins["render"]([]Value{StringValue{Val: "users"}, data}); err == nil || !strings.Contains(err.Error(), "no templates are configured") {
+		t.Errorf("Expected an error without templates, got %v", err)
+	}
+
+	vm.SetTemplates(fakeTemplates{})
+	result, err := vm.builtins["render"]([]Value{StringValue{Val: "users"}, data})
+	if err != nil {
+		t.Fatalf("render() error: %v", err)
+	}
+	if result != (HTMLValue{Val: "users:ada"}) {
+		t.Errorf("Expected the page as an HTMLValue, got %#v", result)
+	}
+
+	result, err = vm.builtins["renderString"]([]Value{StringValue{Val: "hi"}, data})
+	if err != nil {
+		t.Fatalf("renderString() error: %v", err)
+	}
+	if result != (StringValue{Val: "hi:ada"}) {
+		t.Errorf("Expected hi:ada, got %#v", result)
+	}
+	if _, err := vm.builtins["render"]([]Value{IntValue{Val: 1}, data}); err == nil {
+		t.Error("Expected error for a non-string name")
+	}
+	if _, err := vm.builtins["renderString"]([]Value{StringValue{Val: "hi"}}); err == nil {
+		t.Error("Expected error for a missing data argument")
+	}
+}
+
+// fakeSession records cookies and keeps session values in a map
+type fakeSession struct {
+	cookies     map[string]string
+	options     map[string]interface{}
+	data        map[string]interface{}
+	regenerated bool
+	destroyed   bool
+}
+
+func (s *fakeSession) SetCookie(name, value string, options map[string]interface{}) error {
+	s.cookies[name] = value
+	s.options = options
+	return nil
+}
+
+func (s *fakeSession) ClearCookie(name string) error {
+	delete(s.cookies, name)
+	return nil
+}
+
+func (s *fakeSession) Get(key string) (interface{}, error) { return s.data[key], nil }
+
+func (s *fakeSession) Set(key string, value interface{}) error {
+	s.data[key] = value
+	return nil
+}
+
+func (s *fakeSession) Regenerate() error {
+	s.regenerated = true
+	return nil
+}
+
+func (s *fakeSession) Destroy() error {
+	s.data = map[string]interface{}{}
+	s.destroyed = true
+	return nil
+}
+
+func TestSessionBuiltins(t *testing.T) {
+	vm := NewVM()
+	if _, err := vm.builtins["session.get"]([]Value{StringValue{Val: "user"}}); err == nil || !strings.Contains(err.Error(), "while handling a request") {
+		t.Errorf("Expected an error outside a request, got %v", err)
+	}
+
+	s := &fakeSession{cookies: map[string]string{}, data: map[string]interface{}{}}
+	vm.SetResponseCookies(s)
+	vm.SetSession(s)
+
+	options := ObjectValue{Val: map[string]Value{"maxAge": IntValue{Val: 60}}}
+	if _, err := vm.builtins["setCookie"]([]Value{StringValue{Val: "theme"}, StringValue{Val: "dark"}, options}); err != nil {
+		t.Fatalf("setCookie() error: %v", err)
+	}
+	if s.cookies["theme"] != "dark" || s.options["maxAge"] != int64(60) {
+		t.Errorf("Expected the cookie and its options as Go values, got %v %v", s.cookies, s.options)
+	}
+	if _, err := vm.builtins["setCookie"]([]Value{StringValue{Val: "theme"}, IntValue{Val: 1}}); err == nil {
+		t.Error("Expected error for a non-string value")
+	}
+	if _, err := vm.builtins["clearCookie"]([]Value{StringValue{Val: "theme"}}); err != nil || len(s.cookies) != 0 {
+		t.Errorf("clearCookie() did not clear the cookie: %v", err)
+	}
+
+	user := ObjectValue{Val: map[string]Value{"id": IntValue{Val: 7}}}
+	if _, err := vm.builtins["session.set"]([]Value{StringValue{Val: "user"}, user}); err != nil {
+		t.Fatalf("session.set() error: %v", err)
+	}
+	got, err := vm.builtins["session.get"]([]Value{StringValue{Val: "user"}})
+	if err != nil {
+		t.Fatalf("session.get() error: %v", err)
+	}
+	if obj, ok := got.(ObjectValue); !ok || obj.Val["id"] != (IntValue{Val: 7}) {
+		t.Errorf("Expected the stored user, got %v", got)
+	}
+	if _, err := vm.builtins["session.regenerate"](nil); err != nil || !s.regenerated {
+		t.Errorf("session.regenerate() did not regenerate the session: %v", err)
+	}
+	if _, err := vm.builtins["session.destroy"](nil); err != nil || !s.destroyed {
+		t.Errorf("session.destroy() did not destroy the session: %v", err)
+	}
+	if got, _ := vm.builtins["session.get"]([]Value{StringValue{Val: "user"}}); got != (NullValue{}) {
+		t.Errorf("Expected null after destroy, got %v", got)
+	}
+}
+
+func TestEnvBuiltin(t *testing.T) {
+	t.Setenv("APP_NAME", "glyph")
+	t.Setenv("GLYPH_TEST_EMPTY", "")
+	os.Unsetenv("GLYPH_TEST_MISSING")
+
+	vm := NewVM()
+	env := vm.builtins["env"]
+	for _, tc := range []struct {
+		args []Value
+		want Value
+	}{
+		{[]Value{StringValue{Val: "APP_NAME"}}, StringValue{Val: "glyph"}},
+		{[]Value{StringValue{Val: "APP_NAME"}, StringValue{Val: "fallback"}}, StringValue{Val: "glyph"}},
+		{[]Value{StringValue{Val: "GLYPH_TEST_EMPTY"}, StringValue{Val: "fallback"}}, StringValue{Val: ""}},
+		{[]Value{StringValue{Val: "GLYPH_TEST_MISSING"}, IntValue{Val: 8080}}, IntValue{Val: 8080}},
+		{[]Value{StringValue{Val: "GLYPH_TEST_MISSING"}}, NullValue{}},
+	} {
+		got, err := env(tc.args)
+		if err != nil {
+			t.Fatalf("env(%v) error: %v", tc.args, err)
+		}
+		if got != tc.want {
+			t.Errorf("env(%v): expected %#v, got %#v", tc.args, tc.want, got)
+		}
+	}
+	if _, err := env([]Value{IntValue{Val: 1}}); err == nil {
+		t.Error("Expected error for a non-string name")
+	}
+
+	vm.SetEnvPrefixes([]string{"APP_"})
+	if got, err := env([]Value{StringValue{Val: "APP_NAME"}}); err != nil || got != (StringValue{Val: "glyph"}) {
+		t.Errorf("Expected APP_NAME to be readable, got %v, %v", got, err)
+	}
+	if _, err := env([]Value{StringValue{Val: "HOME"}}); err == nil || !strings.Contains(err.Error(), "only variables starting with APP_") {
+		t.Errorf("Expected HOME to be denied, got %v", err)
+	}
+}
+
+func TestJSONBuiltins(t *testing.T) {
+	vm := NewVM()
+	user := ObjectValue{Val: map[string]Value{
+		"name":    StringValue{Val: "<Ada>"},
+		"age":     IntValue{Val: 36},
+		"score":   FloatValue{Val: 9.5},
+		"tags":    ArrayValue{Val: []Value{StringValue{Val: "a"}}},
+		"manager": NullValue{},
+	}}
+	str, err := vm.builtins["json.stringify"]([]Value{user})
+	if err != nil {
+		t.Fatalf("json.stringify() error: %v", err)
+	}
+	want := `{"age":36,"manager":null,"name":"<Ada>","score":9.5,"tags":["a"]}`
+	if str != (StringValue{Val: want}) {
+		t.Errorf("Expected %s, got %v", want, str)
+	}
+
+	decoded, err := vm.builtins["json.parse"]([]Value{str})
+	if err != nil {
+		t.Fatalf("json.parse() error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, user) {
+		t.Errorf("Expected %#v after a round trip, got %#v", user, decoded)
+	}
+
+	for _, input := range []string{`{"a": }`, `{} {}`, ``} {
+		if _, err := vm.builtins["json.parse"]([]Value{StringValue{Val: input}}); err == nil || !strings.Contains(err.Error(), "invalid JSON") {
+			t.Errorf("json.parse(%q): expected an invalid JSON error, got %v", input, err)
+		}
+	}
+	if _, err := vm.builtins["json.parse"]([]Value{IntValue{Val: 1}}); err == nil {
+		t.Error("Expected error for a non-string argument")
+	}
+}
+
+func TestCryptoBuiltins(t *testing.T) {
+	vm := NewVM()
+	call := func(name string, args ...string) (Value, error) {
+		values := make([]Value, len(args))
+		for n, arg := range args {
+			values[n] = StringValue{Val: arg}
+		}
+		return vm.builtins[name](values)
+	}
+
+	if got, _ := call("hash.sha256", "abc"); got != (StringValue{Val: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"}) {
+		t.Errorf("hash.sha256(abc): got %v", got)
+	}
+	if got, _ := call("hmac.sha256", "key", "The quick brown fox jumps over the lazy dog"); got != (StringValue{Val: "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"}) {
+		t.Errorf("hmac.sha256: got %v", got)
+	}
+
+	stored, err := call("bcrypt.hash", "correct horse")
+	if err != nil {
+		t.Fatalf("bcrypt.hash() error: %v", err)
+	}
+	hash := stored.(StringValue).Val
+	if got, _ := call("bcrypt.verify", "correct horse", hash); got != (BoolValue{Val: true}) {
+		t.Errorf("Expected the password to match, got %v", got)
+	}
+	if got, _ := call("crypto.verify", "wrong horse", hash); got != (BoolValue{Val: false}) {
+		t.Errorf("Expected a wrong password not to match, got %v", got)
+	}
+
+	if _, err := vm.builtins["hash.sha256"]([]Value{IntValue{Val: 42}}); err == nil || !strings.Contains(err.Error(), "hash.sha256() str must be a string, got int") {
+		t.Errorf("Expected a type error, got %v", err)
+	}
+	if _, err := call("hmac.sha256", "key"); err == nil || !strings.Contains(err.Error(), "expects 2 arguments (key, msg), got 1") {
+		t.Errorf("Expected an argument count error, got %v", err)
+	}
+	if _, err := call("bcrypt.verify", "pw", "not-hash"); err == nil || !strings.Contains(err.Error(), "bcrypt.verify() invalid hash") {
+		t.Errorf("Expected an invalid hash error, got %v", err)
+	}
+}
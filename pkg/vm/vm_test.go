@@ -3,6 +3,7 @@ package vm
 import (
 	"encoding/binary"
 	"math"
+	"strings"
 	"testing"
 )
 
@@ -225,6 +226,55 @@ func TestOpDivByZero(t *testing.T) {
 	}
 }
 
+func TestIntegerOverflow(t *testing.T) {
+	const maxInt, minInt = int64(9223372036854775807), int64(-9223372036854775808)
+	tests := []struct {
+		name  string
+		left  int64
+		op    Opcode
+		right int64
+		want  string
+	}{
+		{"add", maxInt, OpAdd, 1, "integer overflow: 9223372036854775807 + 1"},
+		{"sub", minInt, OpSub, 1, "integer overflow: -9223372036854775808 - 1"},
+		{"mul", maxInt, OpMul, 2, "integer overflow: 9223372036854775807 * 2"},
+		{"mul min by -1", -1, OpMul, minInt, "integer overflow: -1 * -9223372036854775808"},
+		{"div", minInt, OpDiv, -1, "integer overflow: -9223372036854775808 / -1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bytecode := createBytecodeHeader([]Value{IntValue{Val: tt.left}, IntValue{Val: tt.right}})
+			operand0 := uint32(0)
+			operand1 := uint32(1)
+			bytecode = addInstruction(bytecode, OpPush, &operand0)
+			bytecode = addInstruction(bytecode, OpPush, &operand1)
+			bytecode = addInstruction(bytecode, tt.op, nil)
+			bytecode = addInstruction(bytecode, OpHalt, nil)
+
+			_, err := NewVM().Execute(bytecode)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// Results at the edge of the range are still ints
+	bytecode := createBytecodeHeader([]Value{IntValue{Val: maxInt - 1}, IntValue{Val: 1}})
+	operand0 := uint32(0)
+	operand1 := uint32(1)
+	bytecode = addInstruction(bytecode, OpPush, &operand0)
+	bytecode = addInstruction(bytecode, OpPush, &operand1)
+	bytecode = addInstruction(bytecode, OpAdd, nil)
+	bytecode = addInstruction(bytecode, OpHalt, nil)
+	result, err := NewVM().Execute(bytecode)
+	if err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if result != (IntValue{Val: maxInt}) {
+		t.Errorf("Expected IntValue{%d}, got %v", maxInt, result)
+	}
+}
+
 func TestOpEq(t *testing.T) {
 	constants := []Value{IntValue{Val: 42}, IntValue{Val: 42}}
 	bytecode := createBytecodeHeader(constants)
@@ -919,10 +969,14 @@ func TestOpGetFieldNotFound(t *testing.T) {
 	bytecode = addInstruction(bytecode, OpGetField, nil)   // Get field
 	bytecode = addInstruction(bytecode, OpHalt, nil)
 
+	// A missing field is null, as in the interpreter
 	vm := NewVM()
-	_, err := vm.Execute(bytecode)
-	if err == nil {
-		t.Error("Expected error for non-existent field")
+	result, err := vm.Execute(bytecode)
+	if err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if _, ok := result.(NullValue); !ok {
+		t.Errorf("Expected null for non-existent field, got %v", result)
 	}
 }
 
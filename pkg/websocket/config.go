@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
@@ -18,7 +19,10 @@ type Config struct {
 	MaxConnectionsPerHub  int
 	MaxConnectionsPerRoom int
 
-	// Heartbeat/Ping-Pong settings
+	// Heartbeat/Ping-Pong settings. While heartbeats are enabled, each
+	// connection is pinged every HeartbeatInterval and closed if no pong or
+	// other frame arrives within PongWaitTimeout, or after MaxMissedPongs
+	// unanswered pings.
 	EnableHeartbeat   bool
 	HeartbeatInterval time.Duration
 	HeartbeatTimeout  time.Duration
@@ -110,7 +114,9 @@ type ConnectionState struct {
 	BufferedMessages [][]byte
 }
 
-// Validate validates the configuration
+// Validate validates the configuration, replacing invalid values with
+// defaults. It returns an error describing any setting it had to correct
+// that was not simply left unset.
 func (c *Config) Validate() error {
 	if c.HeartbeatInterval <= 0 {
 		c.HeartbeatInterval = 30 * time.Second
@@ -128,6 +134,14 @@ func (c *Config) Validate() error {
 		c.MaxMissedPongs = 3
 	}
 
+	// Pings must go out before the read deadline they extend passes
+	var err error
+	if c.PongWaitTimeout <= c.HeartbeatInterval {
+		err = fmt.Errorf("PongWaitTimeout (%v) must exceed HeartbeatInterval (%v); using %v",
+			c.PongWaitTimeout, c.HeartbeatInterval, 2*c.HeartbeatInterval)
+		c.PongWaitTimeout = 2 * c.HeartbeatInterval
+	}
+
 	if c.MessageQueueSize <= 0 {
 		c.MessageQueueSize = 256
 	}
@@ -148,7 +162,7 @@ func (c *Config) Validate() error {
 		c.MessageQueueStrategy = QueueStrategyDropOldest
 	}
 
-	return nil
+	return err
 }
 
 // CheckOrigin returns a function that validates the Origin header of a
@@ -123,6 +123,19 @@ func TestConfig_Validate(t *testing.T) {
 		}
 	})
 
+	t.Run("pong wait must exceed heartbeat interval", func(t *testing.T) {
+		cfg := &Config{
+			HeartbeatInterval: 30 * time.Second,
+			PongWaitTimeout:   10 * time.Second,
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Error("Validate() should report PongWaitTimeout <= HeartbeatInterval")
+		}
+		if cfg.PongWaitTimeout != 60*time.Second {
+			t.Errorf("PongWaitTimeout = %v, want 60s", cfg.PongWaitTimeout)
+		}
+	})
+
 	t.Run("valid custom values preserved", func(t *testing.T) {
 		cfg := &Config{
 			HeartbeatInterval: 45 * time.Second,
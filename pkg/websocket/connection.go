@@ -2,14 +2,21 @@ package websocket
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"net"
+	"net/http"
+	"net/url"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-// Removed constants - now using configuration
+// closeAckWait is how long the write pump waits, after sending a close
+// frame, for the client to answer with its own before closing the TCP
+// connection
+const closeAckWait = time.Second
 
 // Connection represents a WebSocket connection
 type Connection struct {
@@ -28,18 +35,37 @@ type Connection struct {
 	// Custom data associated with the connection
 	Data map[string]interface{}
 
-	// Mutex for protecting Data
+	// Handler state, exposed to GLYPH handlers by ws.setState/ws.getState
+	state map[string]interface{}
+
+	// Mutex for protecting Data and state
 	mu sync.RWMutex
 
 	// Rooms this connection has joined
 	rooms map[string]bool
 
-	// Mutex for protecting rooms
+	// Mutex for protecting rooms and detached
 	roomsMu sync.RWMutex
 
+	// detached is set when the hub unregisters the connection, after which
+	// it can no longer join rooms
+	detached bool
+
 	// Path parameters extracted from the WebSocket route pattern (e.g., :room from /chat/:room)
 	PathParams map[string]string
 
+	// Query parameters and headers of the upgrade request
+	Query  url.Values
+	Header http.Header
+
+	// Close code and reason sent when the hub closes a rejected connection
+	closeCode   int
+	closeReason string
+
+	// readDone is closed when the read pump returns, which it does once the
+	// client acknowledges a close frame
+	readDone chan struct{}
+
 	// routePattern is the original route pattern this connection matched (e.g., /chat/:room)
 	// Used internally to filter handlers when multiple WebSocket routes exist
 	routePattern string
@@ -77,16 +103,21 @@ func NewConnection(id string, conn *websocket.Conn, hub *Hub) *Connection {
 		send:         make(chan []byte, queueSize),
 		hub:          hub,
 		Data:         make(map[string]interface{}),
+		state:        make(map[string]interface{}),
 		rooms:        make(map[string]bool),
 		PathParams:   make(map[string]string),
+		Query:        url.Values{},
+		Header:       http.Header{},
 		lastPongTime: time.Now(),
 		messageQueue: make([][]byte, 0),
+		readDone:     make(chan struct{}),
 	}
 }
 
 // ReadPump pumps messages from the WebSocket connection to the hub
 func (c *Connection) ReadPump() {
 	defer func() {
+		close(c.readDone)
 		c.hub.unregister <- c
 		c.conn.Close()
 		c.hub.connWg.Done()
@@ -96,14 +127,20 @@ func (c *Connection) ReadPump() {
 	pongWait := config.PongWaitTimeout
 	maxMessageSize := config.MaxMessageSize
 
-	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	// With heartbeats on, a connection that sends nothing, not even a pong,
+	// within pongWait is dead; without them, idle connections are left open
+	if config.EnableHeartbeat {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	}
 	c.conn.SetPongHandler(func(string) error {
 		c.heartbeatMu.Lock()
 		c.lastPongTime = time.Now()
 		c.missedPongs = 0
 		c.heartbeatMu.Unlock()
 
-		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		if config.EnableHeartbeat {
+			c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		}
 		c.hub.metrics.IncrementSuccessfulPongs()
 		return nil
 	})
@@ -113,7 +150,12 @@ func (c *Connection) ReadPump() {
 	for {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				log.Printf("[WS] Connection %s timeout (no pong within %v)", c.ID, pongWait)
+				c.hub.metrics.IncrementMissedPongs()
+				c.hub.metrics.IncrementConnectionMissedPongs(c.ID)
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("[WS] Connection %s read error: %v", c.ID, err)
 				c.hub.metrics.IncrementReadErrors()
 			}
@@ -143,6 +185,18 @@ func (c *Connection) ReadPump() {
 	}
 }
 
+// awaitCloseAck waits up to closeAckWait for the client to answer a close
+// frame, so the connection ends with a closing handshake instead of a reset.
+// The read pump returns when the answer arrives.
+func (c *Connection) awaitCloseAck() {
+	timer := time.NewTimer(closeAckWait)
+	defer timer.Stop()
+	select {
+	case <-c.readDone:
+	case <-timer.C:
+	}
+}
+
 // WritePump pumps messages from the hub to the WebSocket connection
 func (c *Connection) WritePump() {
 	config := c.hub.config
@@ -167,7 +221,13 @@ func (c *Connection) WritePump() {
 			c.conn.SetWriteDeadline(time.Now().Add(config.WriteWait))
 			if !ok {
 				// Hub closed the channel
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				closeMessage := []byte{}
+				if c.closeCode != 0 {
+					closeMessage = websocket.FormatCloseMessage(c.closeCode, c.closeReason)
+				}
+				if err := c.conn.WriteMessage(websocket.CloseMessage, closeMessage); err == nil {
+					c.awaitCloseAck()
+				}
 				return
 			}
 
@@ -298,20 +358,61 @@ func (c *Connection) GetData(key string) (interface{}, bool) {
 	return value, ok
 }
 
+// Set stores a handler state value on the connection. Unlike Data, which
+// the hub uses internally, state is what GLYPH handlers see.
+func (c *Connection) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state[key] = value
+}
+
+// Get returns a handler state value stored with Set
+func (c *Connection) Get(key string) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	value, ok := c.state[key]
+	return value, ok
+}
+
 // JoinRoom adds this connection to a room
 func (c *Connection) JoinRoom(roomName string) {
+	if err := c.joinRoom(roomName); err != nil {
+		log.Printf("[WS] Failed to join room %s: %v", roomName, err)
+	} else {
+		log.Printf("[WS] Connection %s joined room %s", c.ID, roomName)
+	}
+}
+
+// joinRoom adds this connection to a room, failing if the room is full or
+// the connection has been unregistered
+func (c *Connection) joinRoom(roomName string) error {
+	// roomsMu is held while joining so detach cannot run in between and
+	// leave a closed connection in the room
 	c.roomsMu.Lock()
-	c.rooms[roomName] = true
-	c.roomsMu.Unlock()
+	defer c.roomsMu.Unlock()
+	if c.detached {
+		return ErrConnectionClosed
+	}
 
 	// Add to room manager synchronously to ensure the room exists
 	// before any subsequent operations (like broadcast_to_room)
-	rm := c.hub.GetRoomManager()
-	if err := rm.AddConnectionToRoom(c, roomName); err != nil {
-		log.Printf("[WS] Failed to join room %s: %v", roomName, err)
-	} else {
-		log.Printf("[WS] Connection %s joined room %s", c.ID, roomName)
+	if err := c.hub.GetRoomManager().AddConnectionToRoom(c, roomName); err != nil {
+		return err
 	}
+	c.rooms[roomName] = true
+	return nil
+}
+
+// detach removes the connection from every room and closes its send
+// channel. Removing it first ensures no room broadcast sends on the closed
+// channel.
+func (c *Connection) detach() {
+	c.roomsMu.Lock()
+	c.detached = true
+	c.roomsMu.Unlock()
+
+	c.hub.roomManager.RemoveConnectionFromAllRooms(c)
+	close(c.send)
 }
 
 // LeaveRoom removes this connection from a room
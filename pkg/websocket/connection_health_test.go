@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"context"
 	"sync"
 	"testing"
 	"time"
@@ -11,7 +12,7 @@ func TestConnection_IsHealthy_HeartbeatDisabled(t *testing.T) {
 	config.EnableHeartbeat = false
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := &Connection{
 		ID:           "healthy-no-hb",
@@ -35,7 +36,7 @@ func TestConnection_IsHealthy_HealthyConnection(t *testing.T) {
 	config.HeartbeatTimeout = 90 * time.Second
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := &Connection{
 		ID:           "healthy-conn",
@@ -59,7 +60,7 @@ func TestConnection_IsHealthy_AtMaxMissedPongs(t *testing.T) {
 	config.HeartbeatTimeout = 90 * time.Second
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	// Exactly at max -- should still be healthy (check is >)
 	conn := &Connection{
@@ -84,7 +85,7 @@ func TestConnection_IsHealthy_ExceededMaxMissedPongs(t *testing.T) {
 	config.HeartbeatTimeout = 90 * time.Second
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := &Connection{
 		ID:           "exceeded-pongs",
@@ -108,7 +109,7 @@ func TestConnection_IsHealthy_PongTimeout(t *testing.T) {
 	config.HeartbeatTimeout = 100 * time.Millisecond
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := &Connection{
 		ID:           "timeout-conn",
@@ -132,7 +133,7 @@ func TestConnection_IsHealthy_BothConditionsUnhealthy(t *testing.T) {
 	config.HeartbeatTimeout = 100 * time.Millisecond
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := &Connection{
 		ID:           "both-unhealthy",
@@ -153,7 +154,7 @@ func TestConnection_GetMissedPongs(t *testing.T) {
 	config := DefaultConfig()
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := &Connection{
 		ID:          "pong-tracking",
@@ -191,7 +192,7 @@ func TestConnection_GetLastPongTime(t *testing.T) {
 	config := DefaultConfig()
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	initialTime := time.Now()
 	conn := &Connection{
@@ -224,7 +225,7 @@ func TestConnection_GetLastPongTime_ZeroValue(t *testing.T) {
 	config := DefaultConfig()
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := &Connection{
 		ID:           "pong-zero",
@@ -248,7 +249,7 @@ func TestConnection_HealthMethods_ConcurrentAccess(t *testing.T) {
 	config.HeartbeatTimeout = 90 * time.Second
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := &Connection{
 		ID:           "concurrent-health",
@@ -304,7 +305,7 @@ func TestConnection_HealthTransition(t *testing.T) {
 	config.HeartbeatTimeout = 90 * time.Second
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := &Connection{
 		ID:           "transition",
@@ -354,7 +355,7 @@ func TestConnection_NewConnection_LastPongTime(t *testing.T) {
 	config := DefaultConfig()
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	before := time.Now()
 	conn := NewConnection("new-conn", nil, hub)
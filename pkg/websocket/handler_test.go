@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 	"time"
@@ -97,7 +98,7 @@ func TestHandlerOnEvent(t *testing.T) {
 func TestHandlerJoinRoomHandler(t *testing.T) {
 	hub := NewHub()
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	handler := hub.handler
 
@@ -131,7 +132,7 @@ func TestHandlerJoinRoomHandler(t *testing.T) {
 func TestHandlerLeaveRoomHandler(t *testing.T) {
 	hub := NewHub()
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	handler := hub.handler
 
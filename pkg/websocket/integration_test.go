@@ -1,9 +1,11 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -40,7 +42,7 @@ func pollCondition(condition func() bool, timeout time.Duration) bool {
 // TestFullWebSocketFlow tests complete WebSocket communication flow
 func TestFullWebSocketFlow(t *testing.T) {
 	server := NewServer()
-	defer server.Shutdown()
+	defer server.Shutdown(context.Background())
 
 	connected := make(chan struct{})
 	server.OnConnect(func(conn *Connection) error {
@@ -77,7 +79,7 @@ func TestFullWebSocketFlow(t *testing.T) {
 // TestMultipleClientsAndBroadcast tests multiple clients and broadcasting
 func TestMultipleClientsAndBroadcast(t *testing.T) {
 	server := NewServer()
-	defer server.Shutdown()
+	defer server.Shutdown(context.Background())
 
 	var connCount int32
 	allConnected := make(chan struct{})
@@ -130,7 +132,7 @@ func TestMultipleClientsAndBroadcast(t *testing.T) {
 // TestRoomBasedMessaging tests room-based message routing
 func TestRoomBasedMessaging(t *testing.T) {
 	server := NewServer()
-	defer server.Shutdown()
+	defer server.Shutdown(context.Background())
 
 	var connCount int32
 	allConnected := make(chan struct{})
@@ -193,7 +195,7 @@ func TestRoomBasedMessaging(t *testing.T) {
 // TestCustomEventHandling tests custom event handlers
 func TestCustomEventHandling(t *testing.T) {
 	server := NewServer()
-	defer server.Shutdown()
+	defer server.Shutdown(context.Background())
 
 	connected := make(chan struct{})
 	eventHandled := make(chan struct{})
@@ -245,7 +247,7 @@ func TestCustomEventHandling(t *testing.T) {
 // TestConnectionLifecycleHandlers tests onConnect and onDisconnect handlers
 func TestConnectionLifecycleHandlers(t *testing.T) {
 	server := NewServer()
-	defer server.Shutdown()
+	defer server.Shutdown(context.Background())
 
 	connected := make(chan struct{})
 	disconnected := make(chan struct{})
@@ -277,7 +279,7 @@ func TestConnectionLifecycleHandlers(t *testing.T) {
 // TestPingPongMechanism tests ping/pong keep-alive
 func TestPingPongMechanism(t *testing.T) {
 	server := NewServer()
-	defer server.Shutdown()
+	defer server.Shutdown(context.Background())
 
 	connected := make(chan struct{})
 	server.OnConnect(func(conn *Connection) error {
@@ -319,7 +321,7 @@ func TestPingPongMechanism(t *testing.T) {
 // TestRoomLeaving tests leaving a room
 func TestRoomLeaving(t *testing.T) {
 	server := NewServer()
-	defer server.Shutdown()
+	defer server.Shutdown(context.Background())
 
 	connected := make(chan struct{})
 	server.OnConnect(func(conn *Connection) error {
@@ -371,7 +373,7 @@ func TestRoomLeaving(t *testing.T) {
 // TestConcurrentConnections tests handling many concurrent connections
 func TestConcurrentConnections(t *testing.T) {
 	server := NewServer()
-	defer server.Shutdown()
+	defer server.Shutdown(context.Background())
 
 	numClients := 10
 	var connCount int32
@@ -438,3 +440,391 @@ func TestFromJSON(t *testing.T) {
 	assert.True(t, exists)
 	assert.Equal(t, "value", val)
 }
+
+// dialClients connects n clients to server and returns them with their
+// connection IDs, in connection order
+func dialClients(t *testing.T, server *Server, n int) ([]*websocket.Conn, []string) {
+	t.Helper()
+	ids := make(chan string, n)
+	server.OnConnect(func(conn *Connection) error {
+		ids <- conn.ID
+		return nil
+	})
+
+	ts := httptest.NewServer(http.HandlerFunc(server.HandleWebSocket))
+	t.Cleanup(ts.Close)
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	clients := make([]*websocket.Conn, n)
+	connIDs := make([]string, n)
+	for i := range clients {
+		client, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+		clients[i] = client
+		select {
+		case connIDs[i] = <-ids:
+		case <-time.After(2 * time.Second):
+			t.Fatal("connection timed out")
+		}
+	}
+	return clients, connIDs
+}
+
+// readText reads one message from client, or returns "" if none arrives
+func readText(client *websocket.Conn, timeout time.Duration) string {
+	client.SetReadDeadline(time.Now().Add(timeout))
+	_, data, err := client.ReadMessage()
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// TestHubRooms tests the chat scenario with two rooms: broadcasts reach only
+// the members of the target room, and disconnecting leaves every room
+func TestHubRooms(t *testing.T) {
+	server := NewServer()
+	defer server.Shutdown(context.Background())
+	hub := server.GetHub()
+
+	clients, ids := dialClients(t, server, 3)
+	require.NoError(t, hub.Join(ids[0], "room:1"))
+	require.NoError(t, hub.Join(ids[1], "room:1"))
+	require.NoError(t, hub.Join(ids[1], "room:2"))
+	require.NoError(t, hub.Join(ids[2], "room:2"))
+	assert.ErrorIs(t, hub.Join("no-such-conn", "room:1"), ErrConnectionNotFound)
+
+	room1 := []string{ids[0], ids[1]}
+	room2 := []string{ids[1], ids[2]}
+	sort.Strings(room1)
+	sort.Strings(room2)
+	assert.Equal(t, room1, hub.RoomMembers("room:1"))
+	assert.Equal(t, room2, hub.RoomMembers("room:2"))
+	assert.Empty(t, hub.RoomMembers("room:3"))
+
+	hub.BroadcastToRoom("room:1", []byte("to room 1"), nil)
+	assert.Equal(t, "to room 1", readText(clients[0], 2*time.Second))
+	assert.Equal(t, "to room 1", readText(clients[1], 2*time.Second))
+	assert.Equal(t, "", readText(clients[2], 100*time.Millisecond), "client 3 is not in room 1")
+
+	require.NoError(t, hub.Leave(ids[1], "room:2"))
+	assert.Equal(t, []string{ids[2]}, hub.RoomMembers("room:2"))
+
+	// Disconnecting removes the client from all its rooms
+	require.NoError(t, hub.Join(ids[0], "room:2"))
+	clients[0].Close()
+	require.True(t, pollCondition(func() bool {
+		return len(hub.RoomMembers("room:1")) == 1 && len(hub.RoomMembers("room:2")) == 1
+	}, 2*time.Second), "disconnected client still in a room")
+	assert.Equal(t, []string{ids[1]}, hub.RoomMembers("room:1"))
+	assert.ErrorIs(t, hub.Join(ids[0], "room:1"), ErrConnectionNotFound)
+	assert.NoError(t, hub.Leave(ids[0], "room:1"), "leaving after disconnect is a no-op")
+}
+
+// TestHubRoomsConcurrent joins, leaves and broadcasts from many goroutines
+// while clients disconnect; run with -race
+func TestHubRoomsConcurrent(t *testing.T) {
+	server := NewServer()
+	defer server.Shutdown(context.Background())
+	hub := server.GetHub()
+
+	clients, ids := dialClients(t, server, 8)
+	rooms := []string{"room:1", "room:2"}
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				room := rooms[(i+n)%len(rooms)]
+				hub.Join(id, room)
+				hub.BroadcastToRoom(room, []byte("ping"), nil)
+				hub.RoomMembers(room)
+				if n%3 == 0 {
+					hub.Leave(id, room)
+				}
+			}
+		}()
+	}
+	// Disconnect half the clients while the others are busy
+	for _, client := range clients[:4] {
+		client.Close()
+	}
+	wg.Wait()
+
+	require.True(t, pollCondition(func() bool { return hub.GetConnectionCount() == 4 }, 2*time.Second))
+	for _, room := range rooms {
+		for _, member := range hub.RoomMembers(room) {
+			_, connected := hub.GetConnection(member)
+			assert.True(t, connected, "disconnected client %s still in %s", member, room)
+		}
+	}
+}
+
+// TestConnectRejection tests that an onConnect handler can refuse a
+// connection, using the upgrade request's query and headers
+func TestConnectRejection(t *testing.T) {
+	server := NewServer()
+	defer server.Shutdown(context.Background())
+	hub := server.GetHub()
+
+	var disconnects atomic.Int32
+	server.OnConnect(func(conn *Connection) error {
+		if conn.Query.Get("token") != "secret" {
+			return &RejectError{Code: 4001, Reason: "invalid token"}
+		}
+		conn.Set("userID", conn.Header.Get("X-User"))
+		return nil
+	})
+	server.OnDisconnect(func(conn *Connection) error {
+		disconnects.Add(1)
+		return nil
+	})
+
+	ts := httptest.NewServer(http.HandlerFunc(server.HandleWebSocket))
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	rejected, _, err := websocket.DefaultDialer.Dial(wsURL+"?token=wrong", nil)
+	require.NoError(t, err)
+	defer rejected.Close()
+	rejected.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = rejected.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, 4001, closeErr.Code)
+	assert.Equal(t, "invalid token", closeErr.Text)
+	assert.Equal(t, 0, hub.GetConnectionCount())
+	assert.Equal(t, int32(0), disconnects.Load(), "a rejected connection never connected")
+
+	accepted, _, err := websocket.DefaultDialer.Dial(wsURL+"?token=secret", http.Header{"X-User": {"ada"}})
+	require.NoError(t, err)
+	defer accepted.Close()
+	require.True(t, pollCondition(func() bool { return hub.GetConnectionCount() == 1 }, 2*time.Second))
+	conn := hub.GetConnections()[0]
+	userID, ok := conn.Get("userID")
+	assert.True(t, ok)
+	assert.Equal(t, "ada", userID)
+	_, ok = conn.GetData("userID")
+	assert.False(t, ok, "handler state is separate from Data")
+}
+
+// keepaliveServer starts a server that pings every 20ms and gives up on a
+// connection after 100ms without a pong
+func keepaliveServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.HeartbeatInterval = 20 * time.Millisecond
+	cfg.PongWaitTimeout = 100 * time.Millisecond
+	server := NewServer(cfg)
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+
+	ts := httptest.NewServer(http.HandlerFunc(server.HandleWebSocket))
+	t.Cleanup(ts.Close)
+	return server, "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+// TestKeepaliveReapsUnresponsiveClient tests that a client that never
+// answers pings is closed, and disconnected exactly once
+func TestKeepaliveReapsUnresponsiveClient(t *testing.T) {
+	server, wsURL := keepaliveServer(t)
+	hub := server.GetHub()
+
+	var disconnects atomic.Int32
+	server.OnDisconnect(func(conn *Connection) error {
+		disconnects.Add(1)
+		return nil
+	})
+
+	// gorilla only answers pings while reading, so this client never does
+	client, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer client.Close()
+	require.True(t, pollCondition(func() bool { return hub.GetConnectionCount() == 1 }, 2*time.Second))
+
+	require.True(t, pollCondition(func() bool { return disconnects.Load() == 1 }, 2*time.Second), "unresponsive client was not reaped")
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(1), disconnects.Load(), "OnDisconnect fires once")
+	assert.Equal(t, 0, hub.GetConnectionCount())
+	assert.Equal(t, 0, NewVMStatsHandler(hub).GetConnectionCount())
+	assert.Positive(t, hub.GetMetrics().GetMissedPongs())
+}
+
+// TestKeepaliveKeepsRespondingClient tests that a client that answers pings
+// outlives many pong timeouts
+func TestKeepaliveKeepsRespondingClient(t *testing.T) {
+	server, wsURL := keepaliveServer(t)
+	hub := server.GetHub()
+
+	client, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer client.Close()
+	pings := make(chan struct{}, 100)
+	client.SetPingHandler(func(data string) error {
+		select {
+		case pings <- struct{}{}:
+		default:
+		}
+		return client.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	go func() {
+		for {
+			if _, _, err := client.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(t, 1, hub.GetConnectionCount(), "responding client was reaped")
+	assert.GreaterOrEqual(t, len(pings), 5, "server sends pings every interval")
+}
+
+// TestShutdownSendsGoingAway tests that Shutdown closes open connections
+// with a going-away close frame and waits for their disconnect handlers
+func TestShutdownSendsGoingAway(t *testing.T) {
+	server := NewServer()
+	hub := server.GetHub()
+
+	var disconnects atomic.Int32
+	server.OnDisconnect(func(conn *Connection) error {
+		disconnects.Add(1)
+		return nil
+	})
+
+	ts := httptest.NewServer(http.HandlerFunc(server.HandleWebSocket))
+	defer ts.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	require.NoError(t, err)
+	defer client.Close()
+	require.True(t, pollCondition(func() bool { return hub.GetConnectionCount() == 1 }, 2*time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	require.NoError(t, server.Shutdown(ctx))
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Equal(t, int32(1), disconnects.Load(), "disconnect handlers finish before Shutdown returns")
+	assert.Equal(t, 0, hub.GetConnectionCount())
+
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = client.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, websocket.CloseGoingAway, closeErr.Code)
+	assert.Equal(t, shutdownCloseReason, closeErr.Text)
+
+	assert.NoError(t, server.Shutdown(ctx), "second Shutdown is a no-op")
+}
+
+// TestReloadSendsNoticeAndServiceRestart tests that Reload sends open
+// connections a reload message before a service-restart close frame
+func TestReloadSendsNoticeAndServiceRestart(t *testing.T) {
+	server := NewServer()
+	hub := server.GetHub()
+
+	ts := httptest.NewServer(http.HandlerFunc(server.HandleWebSocket))
+	defer ts.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	require.NoError(t, err)
+	defer client.Close()
+	require.True(t, pollCondition(func() bool { return hub.GetConnectionCount() == 1 }, 2*time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, server.Reload(ctx))
+	assert.Equal(t, 0, hub.GetConnectionCount())
+
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := client.ReadMessage()
+	require.NoError(t, err)
+	msg, err := FromJSON(data)
+	require.NoError(t, err)
+	assert.Equal(t, MessageTypeReload, msg.Type)
+
+	_, _, err = client.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, websocket.CloseServiceRestart, closeErr.Code)
+	assert.Equal(t, reloadCloseReason, closeErr.Text)
+}
+
+// TestShutdownWaitsForCloseAck tests that a client reading its connection
+// completes the closing handshake, so Shutdown need not wait out
+// closeAckWait, while one that never answers is closed once it has
+func TestShutdownWaitsForCloseAck(t *testing.T) {
+	dial := func(t *testing.T) (*Server, *websocket.Conn) {
+		server := NewServer()
+		ts := httptest.NewServer(http.HandlerFunc(server.HandleWebSocket))
+		t.Cleanup(ts.Close)
+		client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+		require.True(t, pollCondition(func() bool { return server.GetHub().GetConnectionCount() == 1 }, 2*time.Second))
+		return server, client
+	}
+
+	t.Run("answering client", func(t *testing.T) {
+		server, client := dial(t)
+
+		// Reading answers the close frame, as browsers do
+		closeErrs := make(chan error, 1)
+		go func() {
+			_, _, err := client.ReadMessage()
+			closeErrs <- err
+		}()
+
+		start := time.Now()
+		require.NoError(t, server.Shutdown(context.Background()))
+		assert.Less(t, time.Since(start), closeAckWait/2)
+
+		var closeErr *websocket.CloseError
+		require.ErrorAs(t, <-closeErrs, &closeErr)
+		assert.Equal(t, websocket.CloseGoingAway, closeErr.Code)
+	})
+
+	t.Run("silent client", func(t *testing.T) {
+		server, _ := dial(t)
+
+		start := time.Now()
+		require.NoError(t, server.Shutdown(context.Background()))
+		assert.GreaterOrEqual(t, time.Since(start), closeAckWait)
+	})
+
+	t.Run("silent client past the deadline", func(t *testing.T) {
+		server, _ := dial(t)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		assert.ErrorIs(t, server.Shutdown(ctx), context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), closeAckWait, "the deadline cuts the wait short")
+	})
+}
+
+// TestShutdownContextExpired tests that Shutdown still stops the hub and
+// reports the context's error when ctx is already done
+func TestShutdownContextExpired(t *testing.T) {
+	server := NewServer()
+	hub := server.GetHub()
+
+	ts := httptest.NewServer(http.HandlerFunc(server.HandleWebSocket))
+	defer ts.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	require.NoError(t, err)
+	defer client.Close()
+	require.True(t, pollCondition(func() bool { return hub.GetConnectionCount() == 1 }, 2*time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, server.Shutdown(ctx), context.Canceled)
+
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = client.ReadMessage()
+	assert.Error(t, err, "connection is closed")
+}
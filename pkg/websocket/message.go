@@ -3,6 +3,7 @@ package websocket
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -14,6 +15,18 @@ var (
 	ErrConnectionNotFound = errors.New("connection not found")
 )
 
+// RejectError is returned by an onConnect handler to refuse a connection.
+// The hub closes the connection with Code and Reason and runs no further
+// connect or disconnect handlers for it.
+type RejectError struct {
+	Code   int
+	Reason string
+}
+
+func (e *RejectError) Error() string {
+	return fmt.Sprintf("connection rejected (%d): %s", e.Code, e.Reason)
+}
+
 // MessageType represents the type of WebSocket message
 type MessageType string
 
@@ -30,6 +43,7 @@ const (
 	MessageTypeBroadcast  MessageType = "broadcast"
 	MessageTypePing       MessageType = "ping"
 	MessageTypePong       MessageType = "pong"
+	MessageTypeReload     MessageType = "reload"
 )
 
 // Message represents a WebSocket message
@@ -1,12 +1,15 @@
 package websocket
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -72,9 +75,11 @@ type Hub struct {
 	// Started channel - closed when Run() is ready to receive
 	started chan struct{}
 
-	// running indicates if Run() has been started
-	running bool
-	runMu   sync.Mutex
+	// running indicates if Run() has been started, stopping that
+	// Shutdown() has been called
+	running  bool
+	stopping bool
+	runMu    sync.Mutex
 
 	// WaitGroup for graceful shutdown (hub.Run)
 	wg sync.WaitGroup
@@ -170,66 +175,14 @@ func (h *Hub) Run() {
 			// Call onConnect handlers (protected by handlerMu)
 			// routeOnConnect is also protected by handlerMu (see OnConnectForRoute)
 			h.handlerMu.RLock()
-			// Global handlers (for all routes)
-			for _, handler := range h.onConnect {
-				if err := handler(conn); err != nil {
-					log.Printf("[WS] onConnect handler error: %v", err)
-					h.metrics.IncrementHandlerErrors()
-				}
-			}
-			// Route-specific handlers
-			if routePattern := conn.RoutePattern(); routePattern != "" {
-				for _, handler := range h.routeOnConnect[routePattern] {
-					if err := handler(conn); err != nil {
-						log.Printf("[WS] onConnect handler error for route %s: %v", routePattern, err)
-						h.metrics.IncrementHandlerErrors()
-					}
-				}
-			}
+			rejected := h.runConnectHandlers(conn)
 			h.handlerMu.RUnlock()
+			if rejected != nil {
+				h.reject(conn, rejected)
+			}
 
 		case conn := <-h.unregister:
-			h.connMu.Lock()
-			if _, ok := h.connections[conn]; ok {
-				delete(h.connections, conn)
-				h.connMu.Unlock()
-
-				close(conn.send)
-				h.roomManager.RemoveConnectionFromAllRooms(conn)
-				h.metrics.DecrementConnections()
-				h.metrics.UnregisterConnection(conn.ID)
-
-				// Save connection state for reconnection
-				if h.config.EnableReconnection && h.config.PreserveClientState {
-					h.saveConnectionState(conn)
-				}
-
-				log.Printf("[WS] Connection unregistered: %s (total: %d)", conn.ID, len(h.connections))
-
-				// Call onDisconnect handlers (protected by handlerMu)
-				// Note: routeOnDisconnect is also protected by handlerMu (see OnDisconnectForRoute)
-				// conn.RoutePattern() is immutable after connection setup
-				h.handlerMu.RLock()
-				// Global handlers (for all routes)
-				for _, handler := range h.onDisconnect {
-					if err := handler(conn); err != nil {
-						log.Printf("[WS] onDisconnect handler error: %v", err)
-						h.metrics.IncrementHandlerErrors()
-					}
-				}
-				// Route-specific handlers
-				if routePattern := conn.RoutePattern(); routePattern != "" {
-					for _, handler := range h.routeOnDisconnect[routePattern] {
-						if err := handler(conn); err != nil {
-							log.Printf("[WS] onDisconnect handler error for route %s: %v", routePattern, err)
-							h.metrics.IncrementHandlerErrors()
-						}
-					}
-				}
-				h.handlerMu.RUnlock()
-			} else {
-				h.connMu.Unlock()
-			}
+			h.removeConnection(conn)
 
 		case msgCtx := <-h.handleMessage:
 			// Route message to handler
@@ -239,17 +192,20 @@ func (h *Hub) Run() {
 			}
 
 		case message := <-h.broadcast:
-			h.connMu.Lock()
+			var slow []*Connection
+			h.connMu.RLock()
 			for conn := range h.connections {
 				select {
 				case conn.send <- message:
 				default:
-					close(conn.send)
-					delete(h.connections, conn)
-					h.roomManager.RemoveConnectionFromAllRooms(conn)
+					slow = append(slow, conn)
 				}
 			}
-			h.connMu.Unlock()
+			h.connMu.RUnlock()
+			// Drop connections that cannot keep up
+			for _, conn := range slow {
+				h.removeConnection(conn)
+			}
 
 		case roomMsg := <-h.broadcastToRoom:
 			if room, exists := h.roomManager.GetRoom(roomMsg.RoomName); exists {
@@ -274,43 +230,219 @@ func (h *Hub) Run() {
 	}
 }
 
-// Shutdown gracefully shuts down the hub
-func (h *Hub) Shutdown() {
+// removeConnection unregisters conn and runs its onDisconnect handlers. It
+// does nothing for a connection that is not registered, so the handlers run
+// once however many times a connection is removed.
+func (h *Hub) removeConnection(conn *Connection) {
+	h.connMu.Lock()
+	if _, ok := h.connections[conn]; !ok {
+		h.connMu.Unlock()
+		return
+	}
+	delete(h.connections, conn)
+	remaining := len(h.connections)
+	h.connMu.Unlock()
+
+	conn.detach()
+	h.metrics.DecrementConnections()
+	h.metrics.UnregisterConnection(conn.ID)
+
+	// Save connection state for reconnection
+	if h.config.EnableReconnection && h.config.PreserveClientState {
+		h.saveConnectionState(conn)
+	}
+
+	log.Printf("[WS] Connection unregistered: %s (total: %d)", conn.ID, remaining)
+
+	// Call onDisconnect handlers (protected by handlerMu)
+	// Note: routeOnDisconnect is also protected by handlerMu (see OnDisconnectForRoute)
+	// conn.RoutePattern() is immutable after connection setup
+	h.handlerMu.RLock()
+	defer h.handlerMu.RUnlock()
+	// Global handlers (for all routes)
+	for _, handler := range h.onDisconnect {
+		if err := handler(conn); err != nil {
+			log.Printf("[WS] onDisconnect handler error: %v", err)
+			h.metrics.IncrementHandlerErrors()
+		}
+	}
+	// Route-specific handlers
+	if routePattern := conn.RoutePattern(); routePattern != "" {
+		for _, handler := range h.routeOnDisconnect[routePattern] {
+			if err := handler(conn); err != nil {
+				log.Printf("[WS] onDisconnect handler error for route %s: %v", routePattern, err)
+				h.metrics.IncrementHandlerErrors()
+			}
+		}
+	}
+}
+
+// runConnectHandlers calls the global and then the route-specific onConnect
+// handlers for conn, stopping at the first that rejects it. h.handlerMu must
+// be held.
+func (h *Hub) runConnectHandlers(conn *Connection) *RejectError {
+	var rejected *RejectError
+	// Global handlers (for all routes)
+	for _, handler := range h.onConnect {
+		if err := handler(conn); err != nil {
+			if errors.As(err, &rejected) {
+				return rejected
+			}
+			log.Printf("[WS] onConnect handler error: %v", err)
+			h.metrics.IncrementHandlerErrors()
+		}
+	}
+	// Route-specific handlers
+	if routePattern := conn.RoutePattern(); routePattern != "" {
+		for _, handler := range h.routeOnConnect[routePattern] {
+			if err := handler(conn); err != nil {
+				if errors.As(err, &rejected) {
+					return rejected
+				}
+				log.Printf("[WS] onConnect handler error for route %s: %v", routePattern, err)
+				h.metrics.IncrementHandlerErrors()
+			}
+		}
+	}
+	return nil
+}
+
+// maxCloseReasonLength is the longest close reason that fits in a close
+// frame after its 2-byte code
+const maxCloseReasonLength = 123
+
+// reject unregisters a connection refused by an onConnect handler and closes
+// it with the handler's code and reason
+func (h *Hub) reject(conn *Connection, rejected *RejectError) {
+	h.connMu.Lock()
+	delete(h.connections, conn)
+	h.connMu.Unlock()
+
+	conn.closeCode = rejected.Code
+	conn.closeReason = rejected.Reason
+	if len(conn.closeReason) > maxCloseReasonLength {
+		conn.closeReason = conn.closeReason[:maxCloseReasonLength]
+	}
+	conn.detach()
+	h.metrics.DecrementConnections()
+	h.metrics.UnregisterConnection(conn.ID)
+	h.metrics.IncrementRejectedConnections()
+	log.Printf("[WS] Connection %s rejected: %v", conn.ID, rejected)
+}
+
+// shutdownCloseReason is the close reason sent to clients when the hub shuts
+// down
+const shutdownCloseReason = "server shutting down"
+
+// reloadCloseReason is the close reason sent to clients when the hub is
+// replaced by a server reload
+const reloadCloseReason = "server reloading"
+
+// Shutdown gracefully shuts down the hub. Every connection is sent a
+// going-away close frame and given up to closeAckWait to answer it, then
+// Shutdown waits for the connection goroutines and their disconnect
+// handlers to finish before stopping the hub. If ctx ends first, the
+// remaining connections are closed without waiting and ctx's error is
+// returned once the hub has stopped. Calls after the first return
+// immediately.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	return h.stop(ctx, websocket.CloseGoingAway, shutdownCloseReason, nil)
+}
+
+// Reload shuts the hub down for a server reload. Every client is first sent
+// a reload message, {"type": "reload", "data": {"reason": ...}}, and then a
+// service-restart (1012) close frame instead of a going-away one, so client
+// code knows to reconnect. Otherwise it behaves like Shutdown.
+func (h *Hub) Reload(ctx context.Context) error {
+	notice, err := NewMessage(MessageTypeReload, map[string]interface{}{
+		"reason": reloadCloseReason,
+	}).ToJSON()
+	if err != nil {
+		return err
+	}
+	return h.stop(ctx, websocket.CloseServiceRestart, reloadCloseReason, notice)
+}
+
+// stop closes every connection with code and reason, after queueing notice
+// for it if notice is non-nil, and stops the hub; see Shutdown
+func (h *Hub) stop(ctx context.Context, code int, reason string, notice []byte) error {
 	// Check if Run() was ever started
 	h.runMu.Lock()
-	wasRunning := h.running
+	wasRunning, alreadyStopping := h.running, h.stopping
+	h.stopping = true
 	h.runMu.Unlock()
 
-	if !wasRunning {
-		// Run() was never called, nothing to shutdown
-		return
+	if !wasRunning || alreadyStopping {
+		// Run() was never called or the hub is already shutting down
+		return nil
 	}
 
 	// Wait for Run() to start (ensures wg.Add(1) has been called)
 	<-h.started
 
-	// Close all WebSocket connections first (while hub is still running)
-	// Hold lock while iterating to avoid race with Run()
-	h.connMu.RLock()
+	// Queue the notice and set the close code while the connections are
+	// still registered: the send channel is only closed after a connection
+	// is removed, so the write pump sends the notice and then sees the code
+	// once it finds the channel closed. A client too slow to take the
+	// notice just gets the close frame.
+	h.connMu.Lock()
 	connsToClose := make([]*Connection, 0, len(h.connections))
 	for conn := range h.connections {
+		if notice != nil {
+			select {
+			case conn.send <- notice:
+			default:
+			}
+		}
+		conn.closeCode = code
+		conn.closeReason = reason
 		connsToClose = append(connsToClose, conn)
 	}
-	h.connMu.RUnlock()
+	h.connMu.Unlock()
 
-	// Close connections outside the lock to avoid deadlock
+	// Unregister through Run() so disconnect handlers run on the hub
+	// goroutine as usual; each write pump then sends the close frame and
+	// closes its connection, which ends the read pump
+	var err error
 	for _, conn := range connsToClose {
-		if conn.conn != nil {
-			conn.conn.Close() // Close the underlying websocket, not conn.Close() which sends to unregister
+		select {
+		case h.unregister <- conn:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if err != nil {
+			break
 		}
 	}
 
-	// Wait for all connection goroutines to finish (they will unregister themselves)
-	h.connWg.Wait()
+	// Wait for all connection goroutines to finish
+	done := make(chan struct{})
+	go func() {
+		h.connWg.Wait()
+		close(done)
+	}()
+	if err == nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	if err != nil {
+		// Out of time: close the underlying websockets, which ends their
+		// pumps straight away
+		for _, conn := range append(connsToClose, h.GetConnections()...) {
+			if conn.conn != nil {
+				conn.conn.Close()
+			}
+		}
+		<-done
+	}
 
 	// Now shutdown the hub
 	close(h.shutdown)
 	h.wg.Wait()
+	return err
 }
 
 // GetConnections returns all active connections
@@ -379,6 +511,44 @@ func (h *Hub) BroadcastJSONToRoom(roomName string, v interface{}, exclude *Conne
 	return nil
 }
 
+// Join adds the connection with the given ID to a room
+func (h *Hub) Join(connID, roomName string) error {
+	conn, ok := h.GetConnection(connID)
+	if !ok {
+		return ErrConnectionNotFound
+	}
+	if err := conn.joinRoom(roomName); err != nil {
+		return err
+	}
+	log.Printf("[WS] Connection %s joined room %s", connID, roomName)
+	return nil
+}
+
+// Leave removes the connection with the given ID from a room. Leaving for a
+// connection that has gone is not an error: disconnect handlers run after
+// the hub has already removed it from every room.
+func (h *Hub) Leave(connID, roomName string) error {
+	if conn, ok := h.GetConnection(connID); ok {
+		conn.LeaveRoom(roomName)
+	}
+	return nil
+}
+
+// RoomMembers returns the sorted IDs of the connections in a room
+func (h *Hub) RoomMembers(roomName string) []string {
+	room, exists := h.roomManager.GetRoom(roomName)
+	if !exists {
+		return []string{}
+	}
+	conns := room.Connections()
+	ids := make([]string, len(conns))
+	for i, conn := range conns {
+		ids[i] = conn.ID
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 // OnConnect registers a handler for connection events
 func (h *Hub) OnConnect(handler EventHandler) {
 	h.handlerMu.Lock()
@@ -444,7 +614,7 @@ func NewServer(cfgs ...*Config) *Server {
 		log.Printf("[WS] Config validation warning: %v", err)
 	}
 
-	hub := NewHub()
+	hub := NewHubWithConfig(cfg)
 	go hub.Run()
 
 	return &Server{
@@ -466,13 +636,17 @@ func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 	// Create connection wrapper
 	wsConn := NewConnection(id, conn, s.hub)
+	wsConn.Query = r.URL.Query()
+	wsConn.Header = r.Header.Clone()
+
+	// Track connection goroutines for graceful shutdown. Adding before
+	// registering means Shutdown, which finds connections through the
+	// hub, always waits for them.
+	s.hub.connWg.Add(2)
 
 	// Register connection
 	s.hub.register <- wsConn
 
-	// Track connection goroutines for graceful shutdown
-	s.hub.connWg.Add(2)
-
 	// Start read/write pumps
 	go wsConn.WritePump()
 	go wsConn.ReadPump()
@@ -528,25 +702,33 @@ func (s *Server) HandleWebSocketWithPattern(pattern string) http.HandlerFunc {
 
 		// Extract and store path parameters from the request URL
 		wsConn.PathParams = extractPathParams(pattern, r.URL.Path)
+		wsConn.Query = r.URL.Query()
+		wsConn.Header = r.Header.Clone()
 
 		// Store the route pattern so handlers can filter by route
 		wsConn.SetRoutePattern(pattern)
 
+		// Track connection goroutines for graceful shutdown (before
+		// registering, see HandleWebSocket)
+		s.hub.connWg.Add(2)
+
 		// Register connection
 		s.hub.register <- wsConn
 
-		// Track connection goroutines for graceful shutdown
-		s.hub.connWg.Add(2)
-
 		// Start read/write pumps
 		go wsConn.WritePump()
 		go wsConn.ReadPump()
 	}
 }
 
-// Shutdown gracefully shuts down the server
-func (s *Server) Shutdown() {
-	s.hub.Shutdown()
+// Shutdown gracefully shuts down the server's hub, see Hub.Shutdown
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.hub.Shutdown(ctx)
+}
+
+// Reload shuts down the server's hub for a server reload, see Hub.Reload
+func (s *Server) Reload(ctx context.Context) error {
+	return s.hub.Reload(ctx)
 }
 
 // OnConnect registers a connection event handler
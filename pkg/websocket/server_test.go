@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -26,12 +27,12 @@ func TestNewServer(t *testing.T) {
 	server := NewServer()
 	assert.NotNil(t, server)
 	assert.NotNil(t, server.hub)
-	defer server.Shutdown()
+	defer server.Shutdown(context.Background())
 }
 
 func TestServerWebSocketUpgrade(t *testing.T) {
 	server := NewServer()
-	defer server.Shutdown()
+	defer server.Shutdown(context.Background())
 
 	// Set up connection signal
 	connected := make(chan struct{})
@@ -70,7 +71,7 @@ func TestHubConnectionRegistration(t *testing.T) {
 	})
 
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	// Create mock connection
 	mockConn := &Connection{
@@ -110,7 +111,7 @@ func TestHubConnectionUnregistration(t *testing.T) {
 	})
 
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	mockConn := &Connection{
 		ID:    "test-conn",
@@ -149,7 +150,7 @@ func TestHubBroadcast(t *testing.T) {
 	})
 
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	// Create two mock connections
 	conn1 := &Connection{
@@ -218,7 +219,7 @@ func TestConnectionSetGetData(t *testing.T) {
 func TestConnectionSendJSON(t *testing.T) {
 	hub := NewHub()
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := &Connection{
 		ID:   "test-conn",
@@ -254,7 +255,7 @@ func TestHubGetConnection(t *testing.T) {
 	})
 
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := &Connection{
 		ID:    "test-conn-123",
@@ -288,7 +289,7 @@ func TestHubOnConnectHandler(t *testing.T) {
 	})
 
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := &Connection{
 		ID:    "test-conn",
@@ -318,7 +319,7 @@ func TestHubOnDisconnectHandler(t *testing.T) {
 	})
 
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := &Connection{
 		ID:    "test-conn",
@@ -354,7 +355,7 @@ func TestHubBroadcastJSON(t *testing.T) {
 	})
 
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := &Connection{
 		ID:    "test-conn",
@@ -467,7 +468,7 @@ func TestHandleWebSocketWithPattern(t *testing.T) {
 	t.Run("handler extracts params from URL", func(t *testing.T) {
 		hub := NewHub()
 		go hub.Run()
-		defer hub.Shutdown()
+		defer hub.Shutdown(context.Background())
 
 		// Verify extractPathParams works correctly
 		params := extractPathParams("/chat/:room", "/chat/testroom")
@@ -476,7 +477,7 @@ func TestHandleWebSocketWithPattern(t *testing.T) {
 
 	t.Run("handler returns http.HandlerFunc", func(t *testing.T) {
 		server := NewServer()
-		defer server.Shutdown()
+		defer server.Shutdown(context.Background())
 
 		handler := server.HandleWebSocketWithPattern("/chat/:room")
 		assert.NotNil(t, handler)
@@ -487,7 +488,7 @@ func TestHandleWebSocketWithPattern(t *testing.T) {
 func TestConnectionPathParamsInitialized(t *testing.T) {
 	hub := NewHub()
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := NewConnection("test-id", nil, hub)
 
@@ -498,3 +499,50 @@ func TestConnectionPathParamsInitialized(t *testing.T) {
 	conn.PathParams["room"] = "general"
 	assert.Equal(t, "general", conn.PathParams["room"])
 }
+
+// TestHubDropsSlowConsumerOnce tests that a connection dropped for falling
+// behind a broadcast is disconnected once, even when it unregisters later
+func TestHubDropsSlowConsumerOnce(t *testing.T) {
+	hub := NewHub()
+	connected := make(chan struct{})
+	disconnects := make(chan string, 2)
+	hub.OnConnect(func(conn *Connection) error {
+		close(connected)
+		return nil
+	})
+	hub.OnDisconnect(func(conn *Connection) error {
+		disconnects <- conn.ID
+		return nil
+	})
+
+	go hub.Run()
+	defer hub.Shutdown(context.Background())
+
+	slowConn := &Connection{
+		ID:    "slow-conn",
+		send:  make(chan []byte, 1),
+		hub:   hub,
+		Data:  make(map[string]interface{}),
+		rooms: make(map[string]bool),
+	}
+	hub.register <- slowConn
+	require.True(t, waitWithTimeout(connected, 2*time.Second), "connection registration timed out")
+
+	// Nothing drains the send buffer, so the second broadcast finds it full
+	hub.Broadcast([]byte("first"))
+	hub.Broadcast([]byte("second"))
+	select {
+	case id := <-disconnects:
+		assert.Equal(t, "slow-conn", id)
+	case <-time.After(2 * time.Second):
+		t.Fatal("slow consumer was not disconnected")
+	}
+	assert.Equal(t, 0, hub.GetConnectionCount())
+
+	hub.unregister <- slowConn
+	select {
+	case <-disconnects:
+		t.Fatal("OnDisconnect fired twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
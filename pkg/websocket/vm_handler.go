@@ -59,6 +59,41 @@ func (h *VMStatsHandler) LeaveRoom(room string) error {
 	return errors.New("ws.leave not available in HTTP routes")
 }
 
+// JoinClientRoom is not available in stats-only mode
+func (h *VMStatsHandler) JoinClientRoom(client, room string) error {
+	return errors.New("ws.join not available in HTTP routes")
+}
+
+// LeaveClientRoom is not available in stats-only mode
+func (h *VMStatsHandler) LeaveClientRoom(client, room string) error {
+	return errors.New("ws.leave not available in HTTP routes")
+}
+
+// SetState stores a value in the state of the connection with the given client ID
+func (h *VMStatsHandler) SetState(client, key string, value interface{}) error {
+	conn, ok := h.hub.GetConnection(client)
+	if !ok {
+		return ErrConnectionNotFound
+	}
+	conn.Set(key, value)
+	return nil
+}
+
+// GetState returns a value from the state of the connection with the given client ID
+func (h *VMStatsHandler) GetState(client, key string) (interface{}, error) {
+	conn, ok := h.hub.GetConnection(client)
+	if !ok {
+		return nil, ErrConnectionNotFound
+	}
+	value, _ := conn.Get(key)
+	return value, nil
+}
+
+// Reject is not available in stats-only mode
+func (h *VMStatsHandler) Reject(code int, reason string) error {
+	return errors.New("ws.reject not available in HTTP routes")
+}
+
 // Close is not available in stats-only mode
 func (h *VMStatsHandler) Close(reason string) error {
 	return errors.New("ws.close not available in HTTP routes")
@@ -72,18 +107,7 @@ func (h *VMStatsHandler) GetRooms() []string {
 
 // GetRoomClients returns the list of client IDs in a room
 func (h *VMStatsHandler) GetRoomClients(room string) []string {
-	rm := h.hub.GetRoomManager()
-	r, exists := rm.GetRoom(room)
-	if !exists {
-		return []string{}
-	}
-
-	conns := r.Connections()
-	clients := make([]string, len(conns))
-	for i, conn := range conns {
-		clients[i] = conn.ID
-	}
-	return clients
+	return h.hub.RoomMembers(room)
 }
 
 // GetConnectionID returns empty string in stats-only mode
@@ -142,6 +166,57 @@ func (h *VMHandler) LeaveRoom(room string) error {
 	return nil
 }
 
+// JoinClientRoom adds the connection with the given client ID to a room
+func (h *VMHandler) JoinClientRoom(client, room string) error {
+	return h.hub.Join(client, room)
+}
+
+// LeaveClientRoom removes the connection with the given client ID from a room
+func (h *VMHandler) LeaveClientRoom(client, room string) error {
+	return h.hub.Leave(client, room)
+}
+
+// stateConn returns the connection with the given client ID. The current
+// connection is always found, even in its disconnect handler, after the hub
+// has let go of it.
+func (h *VMHandler) stateConn(client string) (*Connection, error) {
+	if client == h.conn.ID {
+		return h.conn, nil
+	}
+	conn, ok := h.hub.GetConnection(client)
+	if !ok {
+		return nil, ErrConnectionNotFound
+	}
+	return conn, nil
+}
+
+// SetState stores a value in the state of the connection with the given client ID
+func (h *VMHandler) SetState(client, key string, value interface{}) error {
+	conn, err := h.stateConn(client)
+	if err != nil {
+		return err
+	}
+	conn.Set(key, value)
+	return nil
+}
+
+// GetState returns a value from the state of the connection with the given client ID
+func (h *VMHandler) GetState(client, key string) (interface{}, error) {
+	conn, err := h.stateConn(client)
+	if err != nil {
+		return nil, err
+	}
+	value, _ := conn.Get(key)
+	return value, nil
+}
+
+// Reject refuses the current connection. It returns a *RejectError, which
+// stops the handler; returned from a connect handler, it makes the hub close
+// the connection with code and reason.
+func (h *VMHandler) Reject(code int, reason string) error {
+	return &RejectError{Code: code, Reason: reason}
+}
+
 // Close closes the current WebSocket connection
 func (h *VMHandler) Close(reason string) error {
 	// Send close reason if provided
@@ -161,18 +236,7 @@ func (h *VMHandler) GetRooms() []string {
 
 // GetRoomClients returns the list of client IDs in a room
 func (h *VMHandler) GetRoomClients(room string) []string {
-	rm := h.hub.GetRoomManager()
-	r, exists := rm.GetRoom(room)
-	if !exists {
-		return []string{}
-	}
-
-	conns := r.Connections()
-	clients := make([]string, len(conns))
-	for i, conn := range conns {
-		clients[i] = conn.ID
-	}
-	return clients
+	return h.hub.RoomMembers(room)
 }
 
 // GetConnectionID returns the current connection's ID
@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -44,7 +45,7 @@ func TestWebSocketPackage(t *testing.T) {
 		t.Fatal("Metrics not initialized")
 	}
 
-	server.Shutdown()
+	server.Shutdown(context.Background())
 }
 
 // TestMessageTypes verifies all message types are defined
@@ -154,7 +155,7 @@ func TestConfig(t *testing.T) {
 			t.Errorf("Expected MaxConnectionsPerHub to be 100, got %d", hub.config.MaxConnectionsPerHub)
 		}
 
-		hub.Shutdown()
+		hub.Shutdown(context.Background())
 	})
 }
 
@@ -359,7 +360,7 @@ func TestRoomWithLimits(t *testing.T) {
 
 		hub := NewHubWithConfig(config)
 		go hub.Run()
-		defer hub.Shutdown()
+		defer hub.Shutdown(context.Background())
 
 		// Create a room
 		room := hub.roomManager.CreateRoom("test-room")
@@ -407,7 +408,7 @@ func TestConnectionState(t *testing.T) {
 
 		hub := NewHubWithConfig(config)
 		go hub.Run()
-		defer hub.Shutdown()
+		defer hub.Shutdown(context.Background())
 
 		// Wait for hub to be ready
 		<-hub.started
@@ -478,7 +479,7 @@ func TestConnectionState(t *testing.T) {
 
 		hub := NewHubWithConfig(config)
 		go hub.Run()
-		defer hub.Shutdown()
+		defer hub.Shutdown(context.Background())
 
 		// Save a connection state
 		hub.stateMu.Lock()
@@ -515,7 +516,7 @@ func TestQueueStrategies(t *testing.T) {
 
 		hub := NewHubWithConfig(config)
 		go hub.Run()
-		defer hub.Shutdown()
+		defer hub.Shutdown(context.Background())
 
 		conn := &Connection{
 			ID:   "conn1",
@@ -560,7 +561,7 @@ func TestQueueStrategies(t *testing.T) {
 
 		hub := NewHubWithConfig(config)
 		go hub.Run()
-		defer hub.Shutdown()
+		defer hub.Shutdown(context.Background())
 
 		conn := &Connection{
 			ID:   "conn1",
@@ -595,7 +596,7 @@ func TestVMStatsHandler(t *testing.T) {
 	server := NewServer()
 	hub := server.GetHub()
 	go hub.Run()
-	defer server.Shutdown()
+	defer server.Shutdown(context.Background())
 
 	handler := NewVMStatsHandler(hub)
 
@@ -795,7 +796,7 @@ func TestHubGetConnections(t *testing.T) {
 	config := DefaultConfig()
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	// Initially no connections
 	conns := hub.GetConnections()
@@ -809,7 +810,7 @@ func TestHubRoomBroadcast(t *testing.T) {
 	config := DefaultConfig()
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	// Create a room and broadcast to it
 	hub.roomManager.CreateRoom("test-room")
@@ -822,7 +823,7 @@ func TestVMHandler(t *testing.T) {
 	config := DefaultConfig()
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	// Create a mock connection
 	conn := &Connection{
@@ -911,7 +912,7 @@ func TestConnectionHealthMethods(t *testing.T) {
 	config := DefaultConfig()
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := &Connection{
 		ID:           "test-conn",
@@ -950,7 +951,7 @@ func TestOnMessage(t *testing.T) {
 	config := DefaultConfig()
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	called := false
 	hub.OnMessage(MessageTypeText, func(ctx *MessageContext) error {
@@ -967,7 +968,7 @@ func TestServerOnMessage(t *testing.T) {
 	server := NewServer()
 	hub := server.GetHub()
 	go hub.Run()
-	defer server.Shutdown()
+	defer server.Shutdown(context.Background())
 
 	called := false
 	server.OnMessage(MessageTypeText, func(ctx *MessageContext) error {
@@ -1178,7 +1179,7 @@ func TestFromJSONInvalid(t *testing.T) {
 func TestMessageContextBroadcast(t *testing.T) {
 	hub := NewHub()
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	// Wait for hub to be ready
 	<-hub.started
@@ -1224,7 +1225,7 @@ func TestMessageContextBroadcast(t *testing.T) {
 func TestMessageContextBroadcastToRoom(t *testing.T) {
 	hub := NewHub()
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	// Wait for hub to be ready
 	<-hub.started
@@ -1274,7 +1275,7 @@ func TestMessageContextBroadcastToRoom(t *testing.T) {
 func TestConnectionSetRoutePattern(t *testing.T) {
 	hub := NewHub()
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := &Connection{
 		ID:    "route-pattern-conn",
@@ -1299,7 +1300,7 @@ func TestConnectionIsHealthyUnhealthy(t *testing.T) {
 
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	t.Run("ExceededMaxMissedPongs", func(t *testing.T) {
 		conn := &Connection{
@@ -1336,7 +1337,7 @@ func TestConnectionIsHealthyUnhealthy(t *testing.T) {
 		noHeartbeatConfig.EnableHeartbeat = false
 		noHBHub := NewHubWithConfig(noHeartbeatConfig)
 		go noHBHub.Run()
-		defer noHBHub.Shutdown()
+		defer noHBHub.Shutdown(context.Background())
 
 		conn := &Connection{
 			ID:           "no-hb-conn",
@@ -1364,7 +1365,7 @@ func TestHubOnConnectForRoute(t *testing.T) {
 	})
 
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 	<-hub.started
 
 	conn := &Connection{
@@ -1402,7 +1403,7 @@ func TestHubOnDisconnectForRoute(t *testing.T) {
 	})
 
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 	<-hub.started
 
 	conn := &Connection{
@@ -1443,7 +1444,7 @@ func TestHubOnConnectHandlerError(t *testing.T) {
 	})
 
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 	<-hub.started
 
 	conn := &Connection{
@@ -1486,7 +1487,7 @@ func TestHubOnDisconnectHandlerError(t *testing.T) {
 	})
 
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 	<-hub.started
 
 	conn := &Connection{
@@ -1525,7 +1526,7 @@ func TestHubRunMessageHandling(t *testing.T) {
 	})
 
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 	<-hub.started
 
 	conn := &Connection{
@@ -1563,7 +1564,7 @@ func TestHubRunMessageHandlerError(t *testing.T) {
 	})
 
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 	<-hub.started
 
 	conn := &Connection{
@@ -1608,7 +1609,7 @@ func TestHubConnectionLimit(t *testing.T) {
 	})
 
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 	<-hub.started
 
 	conn1 := &Connection{
@@ -1652,7 +1653,7 @@ func TestNewConnectionCustomQueueSize(t *testing.T) {
 	config.MessageQueueSize = 0 // Should fallback to 256
 	hub := NewHubWithConfig(config)
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	conn := NewConnection("test-id", nil, hub)
 	if conn == nil {
@@ -1671,7 +1672,7 @@ func TestNewConnectionCustomQueueSize(t *testing.T) {
 func TestHubDoubleRun(t *testing.T) {
 	hub := NewHub()
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 	<-hub.started
 
 	// Calling Run() again should return immediately
@@ -1693,7 +1694,7 @@ func TestHubDoubleRun(t *testing.T) {
 func TestHubShutdownWithoutRun(t *testing.T) {
 	hub := NewHub()
 	// Should not panic or hang
-	hub.Shutdown()
+	hub.Shutdown(context.Background())
 }
 
 // TestNewHubWithNilConfig tests that NewHubWithConfig handles nil config
@@ -1705,7 +1706,7 @@ func TestNewHubWithNilConfig(t *testing.T) {
 	if hub.config == nil {
 		t.Fatal("Hub config should be set to default when nil is passed")
 	}
-	hub.Shutdown()
+	hub.Shutdown(context.Background())
 }
 
 // TestHubOnConnectRouteHandlerError tests route-specific handler error tracking
@@ -1719,7 +1720,7 @@ func TestHubOnConnectRouteHandlerError(t *testing.T) {
 	})
 
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 	<-hub.started
 
 	conn := &Connection{
@@ -1760,7 +1761,7 @@ func TestHubOnDisconnectRouteHandlerError(t *testing.T) {
 	})
 
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 	<-hub.started
 
 	conn := &Connection{
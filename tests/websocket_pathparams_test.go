@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"github.com/glyphlang/glyph/pkg/ast"
 	"net/http"
 	"net/http/httptest"
@@ -185,7 +186,7 @@ func extractPathParams(pattern, actualPath string) map[string]string {
 // TestWebSocketServerPathParamsIntegration tests the full server integration
 func TestWebSocketServerPathParamsIntegration(t *testing.T) {
 	wsServer := glyphws.NewServer()
-	defer wsServer.Shutdown()
+	defer wsServer.Shutdown(context.Background())
 
 	// Track received path params
 	var receivedRoom string
@@ -238,7 +239,7 @@ func TestWebSocketServerPathParamsIntegration(t *testing.T) {
 // TestWebSocketPathParamsMultiple tests multiple path parameters
 func TestWebSocketPathParamsMultiple(t *testing.T) {
 	wsServer := glyphws.NewServer()
-	defer wsServer.Shutdown()
+	defer wsServer.Shutdown(context.Background())
 
 	var receivedParams map[string]string
 	var mu sync.Mutex
@@ -331,7 +332,7 @@ func TestWebSocketPathParamsInBytecodeExecution(t *testing.T) {
 	// Create a test connection with path params
 	hub := glyphws.NewHub()
 	go hub.Run()
-	defer hub.Shutdown()
+	defer hub.Shutdown(context.Background())
 
 	// Wait for hub to start
 	time.Sleep(50 * time.Millisecond)
@@ -389,6 +390,26 @@ func (h *mockWSHandler) LeaveRoom(room string) error {
 	return nil
 }
 
+func (h *mockWSHandler) JoinClientRoom(client, room string) error {
+	return nil
+}
+
+func (h *mockWSHandler) LeaveClientRoom(client, room string) error {
+	return nil
+}
+
+func (h *mockWSHandler) SetState(client, key string, value interface{}) error {
+	return nil
+}
+
+func (h *mockWSHandler) GetState(client, key string) (interface{}, error) {
+	return nil, nil
+}
+
+func (h *mockWSHandler) Reject(code int, reason string) error {
+	return nil
+}
+
 func (h *mockWSHandler) Close(reason string) error {
 	return nil
 }
@@ -605,7 +626,7 @@ func TestWebSocketPathParamsIssue64Verification(t *testing.T) {
 // This is the end-to-end test that verifies messages are actually delivered.
 func TestWebSocketPathParamsMessageDelivery(t *testing.T) {
 	wsServer := glyphws.NewServer()
-	defer wsServer.Shutdown()
+	defer wsServer.Shutdown(context.Background())
 
 	var connCount int
 	var mu sync.Mutex